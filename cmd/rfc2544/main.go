@@ -8,33 +8,101 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
 	"encoding/csv"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"math"
+	"net"
+	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"syscall"
+	"text/tabwriter"
 	"time"
 
+	"github.com/krisarmstrong/rfc2544-master/pkg/addrpool"
+	"github.com/krisarmstrong/rfc2544-master/pkg/aqm"
+	"github.com/krisarmstrong/rfc2544-master/pkg/backend"
+	"github.com/krisarmstrong/rfc2544-master/pkg/certify"
+	"github.com/krisarmstrong/rfc2544-master/pkg/checkpoint"
+	"github.com/krisarmstrong/rfc2544-master/pkg/compare"
 	"github.com/krisarmstrong/rfc2544-master/pkg/config"
 	"github.com/krisarmstrong/rfc2544-master/pkg/dataplane"
+	"github.com/krisarmstrong/rfc2544-master/pkg/demo"
+	"github.com/krisarmstrong/rfc2544-master/pkg/drift"
+	"github.com/krisarmstrong/rfc2544-master/pkg/dutconfig"
+	"github.com/krisarmstrong/rfc2544-master/pkg/flows"
+	"github.com/krisarmstrong/rfc2544-master/pkg/gnmi"
+	"github.com/krisarmstrong/rfc2544-master/pkg/heatmap"
+	"github.com/krisarmstrong/rfc2544-master/pkg/htmlreport"
+	"github.com/krisarmstrong/rfc2544-master/pkg/impair"
+	"github.com/krisarmstrong/rfc2544-master/pkg/importer"
+	"github.com/krisarmstrong/rfc2544-master/pkg/latcurve"
+	"github.com/krisarmstrong/rfc2544-master/pkg/mgmt"
+	"github.com/krisarmstrong/rfc2544-master/pkg/microburst"
+	"github.com/krisarmstrong/rfc2544-master/pkg/packet"
+	"github.com/krisarmstrong/rfc2544-master/pkg/power"
+	"github.com/krisarmstrong/rfc2544-master/pkg/preflight"
+	"github.com/krisarmstrong/rfc2544-master/pkg/prequal"
+	"github.com/krisarmstrong/rfc2544-master/pkg/qos"
+	"github.com/krisarmstrong/rfc2544-master/pkg/redact"
+	"github.com/krisarmstrong/rfc2544-master/pkg/report"
+	"github.com/krisarmstrong/rfc2544-master/pkg/schema"
+	"github.com/krisarmstrong/rfc2544-master/pkg/sdk"
+	"github.com/krisarmstrong/rfc2544-master/pkg/search"
+	"github.com/krisarmstrong/rfc2544-master/pkg/selfprof"
+	"github.com/krisarmstrong/rfc2544-master/pkg/sla"
+	"github.com/krisarmstrong/rfc2544-master/pkg/threshold"
+	"github.com/krisarmstrong/rfc2544-master/pkg/ticket"
+	"github.com/krisarmstrong/rfc2544-master/pkg/trex"
 	"github.com/krisarmstrong/rfc2544-master/pkg/tui"
+	"github.com/krisarmstrong/rfc2544-master/pkg/udpecho"
 	"github.com/krisarmstrong/rfc2544-master/pkg/web"
+	"github.com/krisarmstrong/rfc2544-master/pkg/wiring"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 var (
 	version      = "2.0.0"
 	cfgFile      string
+	strictConfig bool
+	profileName  string
+	circuitID    string
+	inventory    string
 	iface        string
+	rxIface      string
 	testType     string
 	frameSize    uint32
+	sweepSizes   []uint
+	jumboSizes   []uint
 	webAddr      string
 	useTUI       bool
+	tuiPlain     bool
 	verbose      bool
+	latencyHist  bool
+	latencyCap   uint32
+	capture      string
+	captureLimit uint32
+	captureSnap  uint32
+	payloadCRC   bool
+	speedProfile string
+	dpWatchdog   time.Duration
 	outputFormat string
 	outputFile   string
 
@@ -45,13 +113,200 @@ var (
 	y1564FLR         float64
 	y1564PerfMinutes uint32
 
+	// Throughput and latency options
+	throughputInitialPct     float64
+	throughputResolutionPct  float64
+	throughputMaxIterations  uint32
+	throughputAcceptableLoss float64
+	throughputSearch         string
+	throughputStepPct        float64
+	latencyLoads             []float64
+	latencySubtract          bool
+	probePPS                 uint32
+	probePCP                 uint8
+	probeDSCP                uint8
+
+	// Frame loss sweep and partial drop rate search options
+	frameLossThreshold  float64
+	frameLossLossless   uint32
+	frameLossResolution float64
+
 	// System Recovery test options
 	recoveryOverloadSec uint32
 	recoveryThroughput  float64
 
+	// Section 11 modifier options
+	broadcastPct float64
+	mgmtTarget   string
+
+	// Section 12 address options
+	addressPairs uint32
+	learnRate    uint32
+	streamCount  uint32
+
+	// Control-plane policing options
+	cppTarget string
+	cppICMP   uint32
+	cppARP    uint32
+	cppBGP    uint32
+
+	// Framing options
+	etherType string
+	llcSNAP   bool
+	packetTpl string
+	vlanID    uint16
+	vlanPCP   uint8
+	vlanTPID  string
+	outerVLAN uint16
+	outerPCP  uint8
+	outerTPID string
+	mplsStack []string
+	mplsCoS   bool
+
+	// IP version options
+	ipVersion string
+	ipv6Src   string
+	ipv6Dst   string
+	ipv6TC    uint8
+	flowLabel uint32
+	v6Share   uint8
+
+	// Header field options
+	srcMAC  string
+	dstMAC  string
+	srcIP   string
+	dstIP   string
+	srcPort string
+	dstPort string
+	dscp    uint8
+	payload string
+
+	// Ethernet OAM options
+	oamLoopback bool
+	oamPeer     string
+
+	// Soak options
+	soakDuration time.Duration
+	soakRate     float64
+	soakBucket   time.Duration
+	soakSample   time.Duration
+	soakSeries   string
+
+	// QoS options
+	qosMarking string
+	qosLoadPct float64
+	qosVLAN    uint16
+
+	// AQM options
+	aqmStart        float64
+	aqmEnd          float64
+	aqmStep         float64
+	aqmStepDuration time.Duration
+
+	// Microburst options
+	microStartFrames uint32
+	microMaxFrames   uint32
+	microStepFrames  uint32
+	microResolution  uint32
+	microBursts      uint32
+	microGaps        []time.Duration
+	microRatePct     float64
+	microEgressMbps  float64
+
+	// Self-test options
+	selfTestInterface string
+	selfTestLoadPct   float64
+	selfTestDuration  time.Duration
+
+	// Latency heatmap options
+	heatmapFile string
+
+	// UDP echo options
+	udpEchoTarget   string
+	udpEchoPPS      uint32
+	udpEchoDuration time.Duration
+	reflectorAddr   string
+
+	// Power measurement options
+	powerCmd  string
+	powerIPMI bool
+
+	// gNMI telemetry options
+	gnmiAddr string
+
+	// Self-profiling options
+	pprofAddr string
+
+	// TRex traffic generator options
+	trexServer string
+	trexTxPort uint8
+	trexRxPort uint8
+
+	// Socket mode options
+	socketTarget string
+	socketMaxPPS uint32
+
+	// Checkpoint options
+	stateFile  string
+	resumeFile string
+
+	// Dry run: check the setup without sending traffic
+	dryRun bool
+
+	// Certification run: the suite with the RFC's parameters, and the
+	// settings that replaced the config's
+	certifying       bool
+	certifyOverrides []certify.Override
+
+	// Demo mode: the bundled sample run to show, and where to extract them
+	demoRun string
+	demoDir string
+
+	// Accept the traffic of a run on an in-service interface
+	ackImpact bool
+
+	// Pre-qualification options
+	preQualTargets  []string
+	preQualRequired bool
+
+	// DUT metadata
+	dutName      string
+	dutSSH       string
+	dutSnapshots []string
+
+	// Report command options
+	compareRuns  []string
+	importFormat string
+
+	// SLA report options
+	slaFrom         string
+	slaTo           string
+	slaPercentile   float64
+	slaWindow       int
+	slaUnavailLoss  float64
+	slaAvailability float64
+
+	// Redaction options for results shared outside the lab
+	redactMode string
+	redactSalt string
+
+	// Schema command options
+	schemaDir string
+
+	// SDK command options
+	sdkDir string
+
+	// Secrets command options
+	secretsKeyFile string
+
+	// Plan import options
+	planIncludes []string
+	planContinue bool
+
 	// RFC 2889 options
 	rfc2889PortCount    uint32
 	rfc2889AddressCount uint32
+	rfc2889Orientations []string
 
 	// RFC 6349 options
 	rfc6349MSS             uint32
@@ -85,50 +340,23 @@ func main() {
 		Short: "RFC2544 Test Master - Network benchmark testing",
 		Long: `RFC2544 Test Master v2
 
-Network benchmark testing per RFC 2544:
-  - throughput: Binary search for max rate with 0% loss
-  - latency: Round-trip time at various loads
-  - frame_loss: Loss percentage vs offered load
-  - back_to_back: Burst capacity testing
-  - system_recovery: Recovery time after overload
-  - reset: Device reset recovery time
-
-ITU-T Y.1564 (EtherSAM) testing:
-  - y1564_config: Service Configuration Test (step test)
-  - y1564_perf: Service Performance Test (sustained)
-  - y1564: Full Y.1564 test (both config and perf)
-
-RFC 2889 LAN Switch Benchmarking:
-  - rfc2889_forwarding: Forwarding rate
-  - rfc2889_caching: Address caching capacity
-  - rfc2889_learning: Address learning rate
-  - rfc2889_broadcast: Broadcast forwarding
-  - rfc2889_congestion: Congestion control
-
-RFC 6349 TCP Throughput Testing:
-  - rfc6349_throughput: TCP throughput measurement
-  - rfc6349_path: Path analysis (RTT, bottleneck BW)
-
-ITU-T Y.1731 Ethernet OAM:
-  - y1731_delay: Delay measurement (DMM/DMR)
-  - y1731_loss: Loss measurement (LMM/LMR)
-  - y1731_slm: Synthetic loss measurement
-  - y1731_loopback: Loopback test (LBM/LBR)
-
-MEF Service Activation:
-  - mef_config: Configuration test (step)
-  - mef_perf: Performance test (sustained)
-  - mef: Full MEF test
-
-IEEE 802.1Qbv TSN Testing:
-  - tsn_timing: Gate timing accuracy
-  - tsn_isolation: Traffic class isolation
-  - tsn_latency: Scheduled latency
-  - tsn: Full TSN test suite
-
-Examples:
-  # Run throughput test on eth0
-  rfc2544 -i eth0 -t throughput
+Network benchmark testing per RFC 2544, ITU-T Y.1564, RFC 2889, RFC 6349,
+ITU-T Y.1731, MEF service activation and IEEE 802.1Qbv TSN. Each test is a
+subcommand with its own flags; see "rfc2544 <test> --help".`,
+		Example: `  # Run throughput test on eth0
+  rfc2544 throughput -i eth0
+
+  # Throughput with a coarser search resolution
+  rfc2544 throughput -i eth0 --resolution 1
+
+  # Sweep custom frame sizes, e.g. adjusted for tunnel overhead
+  rfc2544 throughput -i eth0 --frame-sizes 64,512,1400,1450
+
+  # Standard sizes plus 9000, 9216 and super-jumbo 16000 byte frames
+  rfc2544 throughput -i eth0 --jumbo-sizes 9000,9216,16000
+
+  # Latency at selected loads
+  rfc2544 latency -i eth0 --loads 50,90,100
 
   # Run all tests with TUI
   rfc2544 -i eth0 --tui
@@ -136,73 +364,220 @@ Examples:
   # Run with Web UI
   rfc2544 -i eth0 --web :8080
 
+  # Terminal UI on a tester running the Web UI
+  rfc2544 attach http://tester:8080
+
+  # Web UI daemon that reloads config.yaml on edits (or POST /api/config)
+  rfc2544 -c config.yaml --web :8080
+
+  # Continue an interrupted sweep from its state file
+  rfc2544 throughput -i eth0 --resume /tmp/rfc2544-throughput-20240101-120000.state.json
+
+  # Check reachability of the far end before testing
+  rfc2544 throughput -i eth0 --prequal 192.0.2.1,2001:db8::1
+
+  # Record the tester's own CPU and memory use, with pprof on localhost
+  rfc2544 throughput -i eth0 --pprof localhost:6060 -o json --output-file run.json
+
+  # Customer-ready single-file HTML report
+  rfc2544 suite -i eth0 --dut edge-r1 -o html --output-file report.html
+
+  # Run the tests under plan: in order, one results file per step
+  rfc2544 -c plan.yaml -o json --output-file run.json
+
+  # Gate a CI job on the thresholds: section (exit code 3 on a violation)
+  rfc2544 throughput -c ci.yaml -o json --output-file run.json || exit $?
+
+  # Signed acceptance certificate (circuit under certificate:, limits under thresholds:)
+  rfc2544 y1564 -c circuit.yaml -o pdf --output-file CKT-0042.pdf
+
+  # Catch DUT reconfiguration during the run (running-config diffed in the report)
+  rfc2544 suite -i eth0 --dut-ssh admin@edge-r1 --dut-snapshot 'show running-config'
+
+  # Compare runs against different DUTs
+  rfc2544 throughput -i eth0 --dut vendor-a -o json --output-file a.json
+  rfc2544 report compare --runs a.json,b.json,c.json
+
+  # Import another tester's results and compare them with a native run
+  rfc2544 report import --dut vendor-b exfo-export.csv --output-file b.json
+  rfc2544 report compare --runs a.json,b.json,trex-ndr.json
+
+  # Hash addresses and host names before sending results to a vendor
+  rfc2544 report redact --mode hash a.json --output-file a-shared.json
+
+  # Monthly MEF 10.3 SLA report from scheduled Y.1564 performance runs
+  rfc2544 report sla y1564-*.json --from 2026-09-01 --to 2026-10-01 -o csv
+
+  # Stream live counters to a gNMI collector
+  rfc2544 throughput -i eth0 --gnmi :9339
+
   # Run Y.1564 test with quick settings
-  rfc2544 -i eth0 -t y1564 --cir 100 --fd 10 --fdv 5 --flr 0.01
+  rfc2544 y1564 -i eth0 --cir 100 --fd 10 --fdv 5 --flr 0.01
 
   # Run RFC 2889 forwarding test
-  rfc2544 -i eth0 -t rfc2889_forwarding --ports 2
+  rfc2544 rfc2889-forwarding -i eth0 --ports 2
 
   # Run Y.1731 delay measurement
-  rfc2544 -i eth0 -t y1731_delay --mep-id 1 --probes 100
+  rfc2544 y1731-delay -i eth0 --mep-id 1 --probes 100
 
   # Run MEF service activation
-  rfc2544 -i eth0 -t mef --mef-cir 100 --mef-fd 10
+  rfc2544 mef -i eth0 --cir 100 --fd 10
+
+  # Run an 8 hour soak at 90% with 15 minute drift buckets
+  rfc2544 soak -i eth0 -s 512 --duration 8h --rate 90
+
+  # Send on eth0 and receive on eth1 through the DUT
+  rfc2544 throughput -i eth0 --rx-interface eth1
+
+  # Soak with a latency heatmap to spot periodic spikes
+  rfc2544 soak -i eth0 -s 512 --duration 8h --heatmap soak.html
+
+  # Three day soak logging loss and latency every minute, to follow it live
+  rfc2544 soak -i eth0 -s 512 --duration 72h --sample 1m --series soak.csv
+
+  # QoS scheduling matrix: eight DSCP classes at once through a TRex port
+  rfc2544 qos --trex trex1 -s 512
+
+  # WRED/AQM drop profile of the AF21 queue, 1% steps from 70% to 100%
+  rfc2544 aqm -i eth0 -s 512 --start 70 --step 1 --packet 'eth/ipv4(tos=0x48)/udp'
+
+  # Egress buffer depth of a 10G to 1G DUT port, for 100us and 1ms gaps
+  rfc2544 microburst -i eth0 -s 1518 --gaps 100us,1ms --egress-mbps 1000
+
+  # Check the tester measures netem delay, jitter, loss and reordering
+  # injected on the reflector's end of a veth pair
+  rfc2544 self-test -i veth0 --impair-interface veth1 -s 512
+
+  # Latency and loss across a routed path, reflector at the far end
+  rfc2544 reflector --listen :3842
+  rfc2544 udp-echo --reflector 198.51.100.7 -s 512 --pps 5000
+
+  # Unprivileged latency/loss monitoring from a VM, capped at 2000 pps
+  rfc2544 latency --socket 198.51.100.7 --socket-max-pps 2000
+
+  # Use config file (runs the test_type it sets)
+  rfc2544 -c config.yaml
 
-  # Use config file
-  rfc2544 -c config.yaml`,
+  # Container deployment without a config file (see rfc2544 env)
+  RFC2544_INTERFACE=eth0 RFC2544_TRIAL_DURATION=30s rfc2544 throughput
+
+  # Start from a preset; the config file and flags override its settings
+  rfc2544 profiles list
+  rfc2544 --profile rfc2544-standard -c lab.yaml -i eth0
+
+  # Test a circuit with the port, VLAN, SLA profile and far end of its
+  # inventory entry
+  rfc2544 run --circuit CKT-1234 --inventory circuits.yaml
+
+  # Check the config, interface and privileges and show the plan without sending traffic
+  rfc2544 validate -c config.yaml
+
+  # Catch misspelled keys in config files (e.g. in CI), and export the schema for editors
+  rfc2544 config validate lab/*.yaml
+  rfc2544 config schema > config.schema.json`,
 		Run: runMain,
 	}
 
-	// Flags
-	rootCmd.Flags().StringVarP(&cfgFile, "config", "c", "", "Config file (YAML)")
-	rootCmd.Flags().StringVarP(&iface, "interface", "i", "", "Network interface")
-	rootCmd.Flags().StringVarP(&testType, "test", "t", "throughput", "Test type: throughput, latency, frame_loss, back_to_back, system_recovery, reset, y1564_config, y1564_perf, y1564")
-	rootCmd.Flags().Uint32VarP(&frameSize, "frame-size", "s", 0, "Frame size (0 = all standard sizes)")
-	rootCmd.Flags().StringVar(&webAddr, "web", "", "Enable Web UI on address (e.g., :8080)")
-	rootCmd.Flags().BoolVar(&useTUI, "tui", false, "Enable terminal UI")
-	rootCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Verbose output")
-	rootCmd.Flags().StringVarP(&outputFormat, "output", "o", "text", "Output format: text, json, csv")
-	rootCmd.Flags().StringVar(&outputFile, "output-file", "", "Output file (default: stdout)")
-
-	// Y.1564 specific flags
-	rootCmd.Flags().Float64Var(&y1564CIR, "cir", 100.0, "Y.1564: Committed Information Rate (Mbps)")
-	rootCmd.Flags().Float64Var(&y1564FD, "fd", 10.0, "Y.1564: Frame Delay threshold (ms)")
-	rootCmd.Flags().Float64Var(&y1564FDV, "fdv", 5.0, "Y.1564: Frame Delay Variation threshold (ms)")
-	rootCmd.Flags().Float64Var(&y1564FLR, "flr", 0.01, "Y.1564: Frame Loss Ratio threshold (%)")
-	rootCmd.Flags().Uint32Var(&y1564PerfMinutes, "perf-duration", 15, "Y.1564: Performance test duration (minutes)")
-
-	// System Recovery test flags (Section 26.5)
-	rootCmd.Flags().Uint32Var(&recoveryOverloadSec, "overload-sec", 60, "System Recovery: Overload duration in seconds")
-	rootCmd.Flags().Float64Var(&recoveryThroughput, "recovery-throughput", 0, "System Recovery: Throughput % to use (0 = auto-detect)")
-
-	// RFC 2889 flags
-	rootCmd.Flags().Uint32Var(&rfc2889PortCount, "ports", 2, "RFC 2889: Number of ports")
-	rootCmd.Flags().Uint32Var(&rfc2889AddressCount, "addresses", 8192, "RFC 2889: MAC addresses for caching test")
-
-	// RFC 6349 flags
-	rootCmd.Flags().Uint32Var(&rfc6349MSS, "mss", 1460, "RFC 6349: Maximum Segment Size")
-	rootCmd.Flags().Uint32Var(&rfc6349RWND, "rwnd", 65535, "RFC 6349: Receive Window Size")
-	rootCmd.Flags().Uint32Var(&rfc6349ParallelStreams, "streams", 1, "RFC 6349: Parallel streams")
-
-	// Y.1731 flags
-	rootCmd.Flags().Uint32Var(&y1731MEPID, "mep-id", 1, "Y.1731: MEP identifier")
-	rootCmd.Flags().Uint8Var(&y1731MEGLevel, "meg-level", 4, "Y.1731: MEG level (0-7)")
-	rootCmd.Flags().Uint32Var(&y1731ProbeCount, "probes", 100, "Y.1731: Number of probes")
-	rootCmd.Flags().Uint32Var(&y1731IntervalMs, "probe-interval", 1000, "Y.1731: Interval between probes (ms)")
-
-	// MEF flags
-	rootCmd.Flags().Float64Var(&mefCIR, "mef-cir", 100.0, "MEF: Committed Information Rate (Mbps)")
-	rootCmd.Flags().Float64Var(&mefEIR, "mef-eir", 0, "MEF: Excess Information Rate (Mbps)")
-	rootCmd.Flags().Float64Var(&mefFD, "mef-fd", 10000.0, "MEF: Frame Delay threshold (us)")
-	rootCmd.Flags().Float64Var(&mefFDV, "mef-fdv", 5000.0, "MEF: Frame Delay Variation (us)")
-	rootCmd.Flags().Float64Var(&mefFLR, "mef-flr", 0.01, "MEF: Frame Loss Ratio threshold (%)")
-	rootCmd.Flags().Uint32Var(&mefPerfMinutes, "mef-perf-duration", 15, "MEF: Performance test duration (minutes)")
-
-	// TSN flags
-	rootCmd.Flags().Uint32Var(&tsnNumClasses, "tsn-classes", 8, "TSN: Number of traffic classes")
-	rootCmd.Flags().Uint64Var(&tsnCycleTimeUs, "tsn-cycle", 1000, "TSN: GCL cycle time (us)")
-	rootCmd.Flags().Uint64Var(&tsnMaxLatencyUs, "tsn-latency", 100, "TSN: Maximum latency threshold (us)")
-	rootCmd.Flags().Uint64Var(&tsnMaxJitterUs, "tsn-jitter", 10, "TSN: Maximum jitter threshold (us)")
+	// Flags. Each command that runs tests takes the run flags itself, so
+	// they stay off the help of demo, schema, report and the rest.
+	addRunFlags(rootCmd.Flags())
+	rootCmd.Flags().StringVarP(&testType, "test", "t", "", "Test type when no subcommand is given (default from config: throughput)")
+
+	// Test-specific root flags predate the test subcommands. They still
+	// apply with -t but are hidden from the root help.
+	legacy := pflag.NewFlagSet("legacy", pflag.ContinueOnError)
+	addRecoveryFlags(legacy, "recovery-")
+	addSoakFlags(legacy, "soak-")
+	addY1564Flags(legacy)
+	addRFC2889Flags(legacy)
+	addRFC6349Flags(legacy)
+	addY1731Flags(legacy)
+	addMEFFlags(legacy, "mef-")
+	addTSNFlags(legacy, "tsn-")
+	legacy.VisitAll(func(f *pflag.Flag) {
+		f.Hidden = true
+		f.Deprecated = "use the test subcommands instead (see rfc2544 --help)"
+	})
+	rootCmd.Flags().AddFlagSet(legacy)
+
+	// Test subcommands, listed in testCommands order
+	cobra.EnableCommandSorting = false
+	for _, g := range testGroups {
+		rootCmd.AddGroup(&cobra.Group{ID: g.id, Title: g.title})
+	}
+	for _, tc := range testCommands {
+		rootCmd.AddCommand(newTestCommand(tc))
+	}
+
+	// RFC 2544 certification: the suite with the RFC's parameters
+	certifyCmd := &cobra.Command{
+		Use:     "certify",
+		Short:   "Certification run: all six RFC 2544 tests with the RFC's parameters, reported by Section 26",
+		GroupID: "rfc2544",
+		Long: `Runs the RFC 2544 suite with the parameters the RFC recommends in place
+of the configured ones: the standard frame sizes, 60 second trials, a
+zero-loss binary search of at least 20 trials, 20 latency trials at the
+throughput, a frame loss sweep in 10% steps down to 2 lossless trials,
+50 back-to-back trials and a 60 second recovery overload. The settings
+it replaces are listed in the report.
+
+The report is keyed by RFC section (26.1 to 26.6) and gives each the
+reporting fields Section 26 asks for; a section not measured at every
+frame size is marked incomplete with the reason.
+
+  rfc2544 certify -i eth0 -o pdf --output-file cert.pdf`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			testType = string(config.TestSuite)
+			certifying = true
+			runMain(cmd, args)
+		},
+	}
+	addRunFlags(certifyCmd.Flags())
+	certifyCmd.Flags().Uint32Var(&recoveryOverloadSec, "overload-sec", certify.OverloadSec, "System Recovery: Overload duration in seconds (at least 60)")
+	rootCmd.AddCommand(certifyCmd)
+
+	// Training and evaluation without a tester
+	demoCmd := &cobra.Command{
+		Use:   "demo",
+		Short: "Load bundled sample results into the terminal UI, Web UI or reports, without sending traffic",
+		Long: `Shows a bundled sample result set instead of running tests, for training
+and evaluation without a tester or DUT: two RFC 2544 suite runs against
+1 Gbit/s switches, switch-a meeting its acceptance thresholds and switch-b
+failing them.
+
+With --tui the history page lists both runs and starting a test replays
+the selected one; with --web both runs are in the run list, ready for
+reports and share links, and starting a test replays the selected one.
+Otherwise the selected run is printed, or written with -o json, csv, html
+or pdf. --dir extracts the runs and their config file, e.g. for report
+compare.
+
+  rfc2544 demo --web :8080
+  rfc2544 demo --run switch-b -o pdf --output-file demo.pdf
+  rfc2544 demo --dir demo && rfc2544 report compare --runs demo/switch-a.json,demo/switch-b.json`,
+		Args: cobra.NoArgs,
+		RunE: runDemo,
+	}
+	demoCmd.Flags().StringVar(&demoRun, "run", "switch-a", "Sample run to show or replay: "+strings.Join(demo.Names(), " or "))
+	demoCmd.Flags().StringVar(&demoDir, "dir", "", "Write the sample runs and their config file to this directory")
+	demoCmd.Flags().StringVar(&webAddr, "web", "", "Serve the sample runs in the Web UI on address (e.g., :8080)")
+	demoCmd.Flags().BoolVar(&useTUI, "tui", false, "Show the sample runs in the terminal UI")
+	demoCmd.Flags().BoolVar(&tuiPlain, "tui-plain", false, "Terminal UI without colors or Unicode graphics (implies --tui)")
+	demoCmd.Flags().StringVarP(&outputFormat, "output", "o", "text", "Output format: text, json, csv, html, pdf")
+	demoCmd.Flags().StringVar(&outputFile, "output-file", "", "Output file (default: stdout)")
+	addRedactFlags(demoCmd.Flags())
+	rootCmd.AddCommand(demoCmd)
+
+	// Run what the config selects, typically a circuit's SLA profile
+	runCmd := &cobra.Command{
+		Use:   "run",
+		Short: "Run the test the config file, profile or circuit selects (e.g. run --circuit CKT-1234)",
+		Args:  cobra.NoArgs,
+		Run:   runMain,
+	}
+	addRunFlags(runCmd.Flags())
+	rootCmd.AddCommand(runCmd)
 
 	// Version command
 	rootCmd.AddCommand(&cobra.Command{
@@ -210,112 +585,790 @@ Examples:
 		Short: "Print version",
 		Run: func(cmd *cobra.Command, args []string) {
 			fmt.Printf("RFC2544 Test Master v%s\n", version)
+			fmt.Printf("Dataplane: %s\n", dataplane.Backend)
+			if lib := dataplane.Library(); lib != nil {
+				fmt.Printf("Library:   %s, built for ABI %d\n", lib, lib.ExpectedABI)
+				if err := lib.Check(); err != nil {
+					fmt.Printf("           %v\n", err)
+				}
+			}
+		},
+	})
+
+	// Interface listing command
+	listCmd := &cobra.Command{
+		Use:   "list-interfaces",
+		Short: "List network interfaces with link state, driver and test capabilities",
+		Args:  cobra.NoArgs,
+		RunE:  runListInterfaces,
+	}
+	listCmd.Flags().StringVarP(&outputFormat, "output", "o", "text", "Output format: text, json, csv")
+	listCmd.Flags().StringVar(&outputFile, "output-file", "", "Output file (default: stdout)")
+	rootCmd.AddCommand(listCmd)
+
+	// UDP reflector for udp-echo runs across routed paths
+	reflectorCmd := &cobra.Command{
+		Use:   "reflector",
+		Short: "Return udp-echo datagrams to their sender (run at the far end of a routed path)",
+		Args:  cobra.NoArgs,
+		RunE:  runReflector,
+	}
+	reflectorCmd.Flags().StringVar(&reflectorAddr, "listen", ":"+udpecho.DefaultPort, "UDP address to listen on")
+	rootCmd.AddCommand(reflectorCmd)
+
+	// Remote TUI for a running --web instance
+	attachCmd := &cobra.Command{
+		Use:   "attach URL",
+		Short: "Open the terminal UI on a running --web instance, e.g. http://tester:8080",
+		Long: `Show the live stats, status and results of a running --web instance in
+the terminal UI, and start, stop and cancel its tests. The remote instance
+runs the tests with its own config; the local config only sets the tui
+keys, labels and title.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runAttach,
+	}
+	attachCmd.Flags().StringVarP(&cfgFile, "config", "c", "", "Config file for the tui section (keys, labels, title)")
+	attachCmd.Flags().BoolVar(&tuiPlain, "tui-plain", false, "Terminal UI without colors or Unicode graphics")
+	rootCmd.AddCommand(attachCmd)
+
+	// Validate command: the same checks as --dry-run
+	validateCmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Check the config, interface, MTU and privileges and print the test plan without sending traffic",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			dryRun = true
+			runMain(cmd, args)
+		},
+	}
+	addRunFlags(validateCmd.Flags())
+	rootCmd.AddCommand(validateCmd)
+
+	// Schema command
+	schemaCmd := &cobra.Command{
+		Use:   "schema [config|web-config|results]",
+		Short: "Print JSON Schemas for the config file, web API config and JSON results",
+		Args:  cobra.MaximumNArgs(1),
+		RunE:  runSchema,
+	}
+	schemaCmd.Flags().StringVar(&schemaDir, "dir", "", "Write all schemas to this directory")
+	rootCmd.AddCommand(schemaCmd)
+
+	// Client SDK command
+	sdkCmd := &cobra.Command{
+		Use:   "sdk [python|typescript]",
+		Short: "Print Python and TypeScript clients of the web API",
+		Args:  cobra.MaximumNArgs(1),
+		RunE:  runSDK,
+	}
+	sdkCmd.Flags().StringVar(&sdkDir, "dir", "", "Write all clients under this directory")
+	rootCmd.AddCommand(sdkCmd)
+
+	// Config file commands
+	configCmd := &cobra.Command{
+		Use:   "config",
+		Short: "Export the config file schema and check config files",
+	}
+	configCmd.AddCommand(&cobra.Command{
+		Use:   "schema",
+		Short: "Print the JSON Schema of the YAML config file",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runSchema(cmd, []string{schema.NameConfig})
+		},
+	})
+	configCmd.AddCommand(&cobra.Command{
+		Use:          "validate FILE...",
+		Short:        "Check config files strictly: unknown keys and invalid values are errors, with line numbers",
+		Args:         cobra.MinimumNArgs(1),
+		SilenceUsage: true, // The per-file errors are the useful output
+		RunE:         runConfigValidate,
+	})
+	importPlanCmd := &cobra.Command{
+		Use:   "import-plan FILE",
+		Short: "Convert a CSV test matrix into a plan config file",
+		Long: `Converts a spreadsheet test matrix, exported as CSV (comma, semicolon
+or tab separated), into a plan run by 'rfc2544 -c FILE'. Each row is one
+step; rows without a test type, or with No in a Run column, are skipped.
+
+Columns, matched case-insensitively; only Test Type is required:
+  Test Type            throughput, latency, Back-to-Back, Y.1564 Perf, ...
+  Name                 step name (also: Test ID, Step)
+  Frame Sizes          e.g. "64, 512, 1518" (default: the configured sizes)
+  Duration             trial length, or the Y.1564/soak/TSN test length;
+                       s by default, e.g. "Duration (min)", or "2h"
+  Acceptable Loss (%)  throughput search loss tolerance
+  Min Throughput (%)   or (Mbps), pass threshold
+  Max Latency (us)     or (ms); also Max Latency P99
+  Max Frame Loss (%)   pass threshold at the highest offered load
+  Must Pass            every Y.1564 service must pass its SLA
+  Run                  yes/no
+
+Other columns, e.g. notes, are ignored. The plan runs over the rest of
+the configuration; --include names the base config file to run over.
+
+  rfc2544 config import-plan --include site.yaml matrix.csv > plan.yaml
+  rfc2544 -c plan.yaml`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE:         runImportPlan,
+	}
+	importPlanCmd.Flags().StringSliceVar(&planIncludes, "include", nil, "Config file the plan includes")
+	importPlanCmd.Flags().BoolVar(&planContinue, "continue-on-failure", false, "Run the remaining steps after one fails")
+	importPlanCmd.Flags().StringVar(&outputFile, "output-file", "", "Output file (default: stdout)")
+	configCmd.AddCommand(importPlanCmd)
+	secretsCmd := &cobra.Command{
+		Use:   "secrets",
+		Short: "Manage the encrypted secrets file read by ${secret:NAME} references",
+		Long: `Credentials in a config file can be references resolved at load time:
+${env:NAME} reads an environment variable, ${file:PATH} a file and
+${secret:NAME} an entry of the encrypted secrets file named by secrets.file.
+
+The secrets file is sealed with AES-256-GCM. Its key comes from --key-file
+(secrets.key_file when loading a config) or $` + config.SecretsKeyEnv + `.
+
+  rfc2544 config secrets keygen > /etc/rfc2544/secrets.key
+  printf %s "$PW" | rfc2544 config secrets set --key-file /etc/rfc2544/secrets.key secrets.enc ipmi`,
+	}
+	secretsCmd.PersistentFlags().StringVar(&secretsKeyFile, "key-file", "", "File holding the secrets key (default: $"+config.SecretsKeyEnv+")")
+	secretsCmd.AddCommand(&cobra.Command{
+		Use:   "keygen",
+		Short: "Print a new random secrets key",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			key, err := config.GenerateSecretsKey()
+			if err != nil {
+				return err
+			}
+			fmt.Println(key)
+			return nil
+		},
+	})
+	secretsCmd.AddCommand(&cobra.Command{
+		Use:   "set FILE NAME",
+		Short: "Store the value read from stdin as NAME, creating FILE if needed",
+		Args:  cobra.ExactArgs(2),
+		RunE:  runSecretsSet,
+	})
+	secretsCmd.AddCommand(&cobra.Command{
+		Use:   "list FILE",
+		Short: "List the names in a secrets file, without their values",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			values, err := readSecrets(args[0])
+			if err != nil {
+				return err
+			}
+			for _, name := range config.SecretNames(values) {
+				fmt.Println(name)
+			}
+			return nil
+		},
+	})
+	configCmd.AddCommand(secretsCmd)
+	rootCmd.AddCommand(configCmd)
+
+	// Environment variable listing
+	rootCmd.AddCommand(&cobra.Command{
+		Use:   "env",
+		Short: "List the RFC2544_* environment variables that override config keys",
+		Long: `Every config key can be set from the environment, e.g. for containers.
+The variable is RFC2544_ and the key's YAML path in upper case, joined by
+underscores. Precedence: defaults < --profile < config file < environment < flags.
+
+Lists may be comma-separated (RFC2544_FRAME_SIZES=64,512,1518); lists of
+structs and maps use YAML flow syntax.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			for _, v := range config.EnvVars() {
+				fmt.Printf("%-52s %-12s %s\n", v.Name, v.Type, v.Key)
+			}
 		},
 	})
 
+	// Profile commands
+	profilesCmd := &cobra.Command{
+		Use:   "profiles",
+		Short: "List and show the built-in presets and user profiles for --profile",
+	}
+	profilesCmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List presets and the profiles in the user profile directory",
+		Args:  cobra.NoArgs,
+		RunE:  runProfilesList,
+	})
+	profilesCmd.AddCommand(&cobra.Command{
+		Use:   "show NAME",
+		Short: "Print a profile's settings as YAML",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runProfilesShow,
+	})
+	rootCmd.AddCommand(profilesCmd)
+
+	// Report commands
+	reportCmd := &cobra.Command{
+		Use:   "report",
+		Short: "Build reports from saved JSON results",
+	}
+	compareCmd := &cobra.Command{
+		Use:   "compare --runs a.json,b.json[,...]",
+		Short: "Side-by-side matrix per frame size and metric across runs (e.g. DUT bake-offs)",
+		Args:  cobra.NoArgs,
+		RunE:  runCompare,
+	}
+	compareCmd.Flags().StringSliceVar(&compareRuns, "runs", nil, "Results files: -o json output, EXFO/Viavi CSV or TRex JSON exports")
+	compareCmd.Flags().StringVarP(&outputFormat, "output", "o", "text", "Output format: text, json, csv")
+	compareCmd.Flags().StringVar(&outputFile, "output-file", "", "Output file (default: stdout)")
+	addRedactFlags(compareCmd.Flags())
+	compareCmd.MarkFlagRequired("runs")
+	reportCmd.AddCommand(compareCmd)
+	importCmd := &cobra.Command{
+		Use:   "import FILE",
+		Short: "Convert another tester's results (EXFO/Viavi CSV, TRex JSON) to JSON results",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runImport,
+	}
+	importCmd.Flags().StringVar(&importFormat, "format", "", "Input format: "+strings.Join(importer.Formats(), ", ")+" (default: detect)")
+	importCmd.Flags().Uint32VarP(&frameSize, "frame-size", "s", 0, "Frame size for results that do not record one")
+	importCmd.Flags().StringVar(&outputFile, "output-file", "", "Output file (default: stdout)")
+	addRedactFlags(importCmd.Flags())
+	reportCmd.AddCommand(importCmd)
+	redactCmd := &cobra.Command{
+		Use:   "redact FILE",
+		Short: "Strip or hash MAC/IP addresses, host names and site details from saved JSON results or reports",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runRedact,
+	}
+	redactCmd.Flags().StringVar(&redactMode, "mode", "", "Redaction: "+strings.Join(redact.Modes(), " or ")+" (default strip)")
+	redactCmd.Flags().StringVar(&redactSalt, "salt", "", "Hash mode: Key for the hashes, so tokens match across files (default: random)")
+	redactCmd.Flags().StringVar(&outputFile, "output-file", "", "Output file (default: stdout)")
+	reportCmd.AddCommand(redactCmd)
+	slaCmd := &cobra.Command{
+		Use:   "sla FILE...",
+		Short: "MEF 10.3 performance attributes (FD, MFD, IFDV, FLR, availability) per CoS from Y.1564 performance runs over a period",
+		Long: `Builds the MEF 10.3 performance attribute report of a period, per class
+of service, from Y.1564 performance runs repeated on a schedule (each saved
+with -o json). Each run's result is one measurement interval; runs that
+started within --from and --to are included.`,
+		Example: `  # Monthly report from runs every 15 minutes
+  rfc2544 report sla /var/lib/rfc2544/y1564-*.json --from 2026-09-01 --to 2026-10-01 --availability 99.95`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: runSLAReport,
+	}
+	defaults := sla.DefaultParams()
+	slaCmd.Flags().StringVar(&slaFrom, "from", "", "Start of the period, RFC 3339 or YYYY-MM-DD (default: the first run)")
+	slaCmd.Flags().StringVar(&slaTo, "to", "", "End of the period, exclusive (default: the end of the last run)")
+	slaCmd.Flags().Float64Var(&slaPercentile, "percentile", defaults.Percentile, "Percentile of the FD and IFDV attributes")
+	slaCmd.Flags().IntVar(&slaWindow, "window", defaults.Window, "Consecutive intervals that change availability (MEF 10.3 n)")
+	slaCmd.Flags().Float64Var(&slaUnavailLoss, "unavail-loss", defaults.UnavailLossPct, "Loss % above which an interval counts towards unavailability (MEF 10.3 C)")
+	slaCmd.Flags().Float64Var(&slaAvailability, "availability", 0, "Availability objective in % (0 = not checked)")
+	slaCmd.Flags().StringVarP(&outputFormat, "output", "o", "text", "Output format: text, json, csv")
+	slaCmd.Flags().StringVar(&outputFile, "output-file", "", "Output file (default: stdout)")
+	reportCmd.AddCommand(slaCmd)
+	rootCmd.AddCommand(reportCmd)
+
 	if err := rootCmd.Execute(); err != nil {
 		os.Exit(1)
 	}
 }
 
+// addRedactFlags adds the options for redacting results before sharing
+func addRedactFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&redactMode, "redact", "", "Redact MAC/IP addresses, host names and site details for sharing: "+strings.Join(redact.Modes(), " or "))
+	fs.StringVar(&redactSalt, "redact-salt", "", "Hash redaction: Key for the hashes, so tokens match across files (default: random)")
+}
+
+// addRunFlags adds the flags shared by every test: interface, output, run
+// modifiers and telemetry
+func addRunFlags(fs *pflag.FlagSet) {
+	fs.StringVarP(&cfgFile, "config", "c", "", "Config file (YAML, JSON or TOML, by extension)")
+	fs.BoolVar(&strictConfig, "strict", false, "Reject unknown keys in the config file instead of ignoring them")
+	fs.StringVar(&profileName, "profile", "", "Named preset or user profile applied under the config file (see rfc2544 profiles list)")
+	fs.StringVar(&circuitID, "circuit", "", "Circuit ID whose interface, VLAN, SLA profile and far end the inventory gives")
+	fs.StringVar(&inventory, "inventory", "", "Circuit inventory file or URL (default from config: inventory.source)")
+	fs.StringVarP(&iface, "interface", "i", "", "Network interface")
+	fs.StringVar(&rxIface, "rx-interface", "", "Receive interface through the DUT; -i then only transmits")
+	fs.Uint32VarP(&frameSize, "frame-size", "s", 0, "Frame size (0 = all standard sizes)")
+	fs.UintSliceVar(&sweepSizes, "frame-sizes", nil, "Custom frame sizes to sweep instead of the standard sizes (e.g., 64,512,1400)")
+	fs.UintSliceVar(&jumboSizes, "jumbo-sizes", nil, "Jumbo frame sizes to add to the standard sizes, up to 16000 (e.g., 9000,9216)")
+	fs.StringVar(&webAddr, "web", "", "Enable Web UI on address (e.g., :8080)")
+	fs.BoolVar(&useTUI, "tui", false, "Enable terminal UI")
+	fs.BoolVar(&tuiPlain, "tui-plain", false, "Terminal UI without colors or Unicode graphics, for serial consoles and braille displays (implies --tui)")
+	fs.BoolVarP(&verbose, "verbose", "v", false, "Verbose output")
+	fs.StringVarP(&outputFormat, "output", "o", "text", "Output format: text, json, csv, html, pdf")
+	fs.StringVar(&outputFile, "output-file", "", "Output file (default: stdout)")
+	fs.BoolVar(&latencyHist, "latency-histogram", false, "Add each result's full latency distribution to JSON output")
+	fs.Uint32Var(&latencyCap, "latency-sample-capacity", 0, "Latency samples kept per trial (default 10000)")
+	fs.StringVar(&capture, "capture", "", "Save frames sent and received to pcap files: tx.pcap,rx.pcap (either may be empty)")
+	fs.Uint32Var(&captureLimit, "capture-limit", 0, "Frames each capture file takes (default 100000)")
+	fs.Uint32Var(&captureSnap, "capture-snaplen", 0, "Bytes kept of each captured frame, e.g. 64 for headers only (default whole frames)")
+	fs.BoolVar(&payloadCRC, "verify-payload", false, "Seal a CRC-32 into each frame's payload and count frames received corrupted")
+	fs.StringVar(&speedProfile, "speed-profile", "", "TX batch/pacing tuning: 1g, 2.5g, 5g, 10g, 25g, 40g, 50g, 100g, 200g or 400g (default: from the line rate)")
+	fs.DurationVar(&dpWatchdog, "dataplane-watchdog", 0, "Restart the dataplane when its trial loop stalls this long, and go on with the next frame size; 0 disables (default from config: 30s)")
+	addRedactFlags(fs)
+
+	// Section 11 modifier flags
+	fs.Float64Var(&broadcastPct, "broadcast-pct", 0, "Section 11.1: Repeat tests with % of broadcast frames (e.g., 1)")
+	fs.StringVar(&mgmtTarget, "mgmt-target", "", "Section 11.2: Repeat tests while polling DUT SNMP agent (host[:port])")
+
+	// Section 12 address flags
+	fs.Uint32Var(&addressPairs, "address-pairs", 0, "Section 12: Distinct MAC/IP address pairs rotated round-robin (e.g., 256)")
+	fs.Uint32Var(&learnRate, "learn-rate", 0, "Introduce address pairs at this many per second before the first trial (0 = all at once)")
+	fs.Uint32Var(&streamCount, "flows", 0, "Spread traffic across this many streams with distinct MAC/IP/UDP tuples, reporting each one")
+
+	// Control-plane policing flags
+	fs.StringVar(&cppTarget, "cpp-target", "", "Control-plane stress: repeat tests while sending ICMP/ARP/BGP traffic to this DUT IPv4 address")
+	fs.Uint32Var(&cppICMP, "cpp-icmp", 0, "Control-plane stress: ICMP echo requests per second")
+	fs.Uint32Var(&cppARP, "cpp-arp", 0, "Control-plane stress: ARP requests per second")
+	fs.Uint32Var(&cppBGP, "cpp-bgp", 0, "Control-plane stress: TCP SYNs to port 179 per second")
+
+	// Framing flags
+	fs.StringVar(&etherType, "ethertype", "", "EtherType of generated frames (e.g., 0x8864 for PPPoE; use --mpls-label for MPLS)")
+	fs.BoolVar(&llcSNAP, "llc-snap", false, "Use 802.3 LLC/SNAP encapsulation instead of Ethernet II")
+	fs.Uint16Var(&vlanID, "vlan-id", 0, "Tag generated frames with this 802.1Q VLAN ID (the tag counts toward the frame size)")
+	fs.Uint8Var(&vlanPCP, "pcp", 0, "802.1p priority of the VLAN tag (0-7; without --vlan-id, priority-tagged)")
+	fs.StringVar(&vlanTPID, "tpid", "", "TPID of the VLAN tag (default 0x8100; 0x88a8, 0x9100, 0x9200 or 0x9300)")
+	fs.Uint16Var(&outerVLAN, "outer-vlan-id", 0, "QinQ: Stack an 802.1ad S-tag with this VLAN ID over the VLAN tag (4 more bytes of the frame size)")
+	fs.Uint8Var(&outerPCP, "outer-pcp", 0, "QinQ: 802.1p priority of the S-tag (0-7)")
+	fs.StringVar(&outerTPID, "outer-tpid", "", "QinQ: TPID of the S-tag (default 0x88a8; 0x8100, 0x9100, 0x9200 or 0x9300)")
+	fs.StringSliceVar(&mplsStack, "mpls-label", nil, "Push MPLS labels, outermost first, as label[:exp[:ttl]] (e.g., 16001:5,100:5:32; 4 bytes of the frame size each)")
+	fs.BoolVar(&mplsCoS, "mpls-exp-from-cos", false, "Y.1564: Mark the EXP bits of each label with the service CoS")
+	fs.StringVar(&packetTpl, "packet", "", "Frame headers as layers, e.g. 'eth/dot1q(vlan=100)/ipv6/udp(dport=3842)'")
+
+	// IP version flags
+	fs.StringVar(&ipVersion, "ip-version", "", "IP version of generated frames: 4, 6 or dual (IPv6 takes 20 bytes of the frame size)")
+	fs.StringVar(&ipv6Src, "ipv6-src", "", "Source address of IPv6 frames (default 2001:2::1)")
+	fs.StringVar(&ipv6Dst, "ipv6-dst", "", "Destination address of IPv6 frames (default 2001:2::2)")
+	fs.Uint8Var(&ipv6TC, "ipv6-tc", 0, "Traffic class of IPv6 frames (DSCP << 2, e.g. 184 for EF)")
+	fs.Uint32Var(&flowLabel, "flow-label", 0, "Flow label of IPv6 frames (0-1048575)")
+	fs.Uint8Var(&v6Share, "dual-stack-pct", 0, "Dual-stack: % of frames that are IPv6 (default 50)")
+
+	// Header field flags
+	fs.StringVar(&srcMAC, "src-mac", "", "Source MAC of generated frames (default: the interface's)")
+	fs.StringVar(&dstMAC, "dst-mac", "", "Destination MAC of generated frames: the DUT port or next-hop router (default 02:00:00:00:00:02)")
+	fs.StringVar(&srcIP, "src-ip", "", "Source IPv4 address or range cycled per frame, e.g. 10.1.0.1-10.1.0.50 or 10.1.0.0/24 (default 10.0.0.1)")
+	fs.StringVar(&dstIP, "dst-ip", "", "Destination IPv4 address or range (default 10.0.0.2)")
+	fs.StringVar(&srcPort, "src-port", "", "UDP source port or range cycled per frame, e.g. 5000-5099 (default 12345)")
+	fs.StringVar(&dstPort, "dst-port", "", "UDP destination port or range (default 3842)")
+	fs.Uint8Var(&dscp, "dscp", 0, "DSCP of generated frames, 0-63 (e.g., 46 for EF); Y.1564 services mark their cos")
+	fs.StringVar(&payload, "payload-pattern", "", "Padding of generated frames: incrementing, zeros, ones, random[:seed], prbs31 or hex:<bytes>")
+
+	// Ethernet OAM flags
+	fs.BoolVar(&oamLoopback, "oam-loopback", false, "Place the far end into 802.3ah remote loopback before testing")
+	fs.StringVar(&oamPeer, "oam-peer", "", "OAM: Far-end MAC to loop (default: first discovered capable peer)")
+
+	// Power measurement flags
+	fs.StringVar(&powerCmd, "power-cmd", "", "Sample DUT power with a command printing watts (e.g., PDU snmpget)")
+	fs.BoolVar(&powerIPMI, "power-ipmi", false, "Sample DUT power via ipmitool DCMI (BMC set in config, default in-band)")
+
+	// DUT metadata flags
+	fs.StringVar(&dutName, "dut", "", "Name of the device under test, recorded in results for comparison")
+	fs.StringSliceVar(&dutSnapshots, "dut-snapshot", nil, "Record the output of these DUT commands before and after the run and diff them (e.g., 'show running-config')")
+	fs.StringVar(&dutSSH, "dut-ssh", "", "Run --dut-snapshot commands on the DUT over ssh as user@host (default: run locally)")
+
+	// Pre-qualification flags
+	fs.StringSliceVar(&preQualTargets, "prequal", nil, "Ping, traceroute and path MTU check these IPv4/IPv6 targets before testing")
+	fs.BoolVar(&preQualRequired, "prequal-required", false, "Abort if any pre-qualification target is unreachable")
+
+	// gNMI telemetry flags
+	fs.StringVar(&gnmiAddr, "gnmi", "", "Expose live stats as a gNMI target on address (e.g., :9339)")
+
+	// Self-profiling flags
+	fs.StringVar(&pprofAddr, "pprof", "", "Serve Go pprof on address (e.g., localhost:6060) and record the tester's CPU and memory use with the run")
+
+	// TRex flags
+	fs.StringVar(&trexServer, "trex", "", "Generate traffic on a TRex server (host[:port]) instead of the local interface")
+	fs.Uint8Var(&trexTxPort, "trex-tx-port", 0, "TRex: Port sending toward the DUT (default from config: 0)")
+	fs.Uint8Var(&trexRxPort, "trex-rx-port", 0, "TRex: Port receiving from the DUT (default from config: 1)")
+
+	// Socket mode flags
+	fs.StringVar(&socketTarget, "socket", "", "Run trials over plain UDP sockets against a reflector (host[:port]); no root needed, low rates only")
+	fs.Uint32Var(&socketMaxPPS, "socket-max-pps", 0, "Socket mode: Rate treated as 100% of line rate (default from config: 10000)")
+
+	// Latency heatmap flags
+	fs.StringVar(&heatmapFile, "heatmap", "", "Write a latency heatmap (.png, .svg or .html) for soak and Y.1564 performance runs")
+
+	// Checkpoint flags
+	fs.StringVar(&stateFile, "state-file", "", "Save run progress to this file (default: a new file in the temp directory)")
+	fs.StringVar(&resumeFile, "resume", "", "Resume an interrupted run from its state file")
+
+	fs.BoolVar(&dryRun, "dry-run", false, "Check the config, interface and privileges and print the test plan without sending traffic")
+	fs.BoolVar(&ackImpact, "ack-impact", false, "Run on an interface listed in in_service, accepting the traffic the run prints it will send")
+}
+
+// testGroup is a heading for test subcommands in the help output
+type testGroup struct {
+	id    string
+	title string
+}
+
+var testGroups = []testGroup{
+	{"rfc2544", "RFC 2544 Tests:"},
+	{"y1564", "ITU-T Y.1564 (EtherSAM) Tests:"},
+	{"rfc2889", "RFC 2889 LAN Switch Tests:"},
+	{"rfc6349", "RFC 6349 TCP Throughput Tests:"},
+	{"y1731", "ITU-T Y.1731 Ethernet OAM Tests:"},
+	{"mef", "MEF Service Activation Tests:"},
+	{"tsn", "IEEE 802.1Qbv TSN Tests:"},
+	{"routed", "Routed Path Tests:"},
+	{"verify", "Tester Verification:"},
+}
+
+// testCommand describes the subcommand running one test type
+type testCommand struct {
+	test  config.TestType
+	group string
+	short string
+	flags func(fs *pflag.FlagSet) // Test-specific flags, may be nil
+}
+
+var testCommands = []testCommand{
+	{config.TestThroughput, "rfc2544", "Binary search for max rate with 0% loss", addThroughputFlags},
+	{config.TestLatency, "rfc2544", "Round-trip time at various loads", addLatencyFlags},
+	{config.TestFrameLoss, "rfc2544", "Loss percentage vs offered load", addFrameLossFlags},
+	{config.TestBackToBack, "rfc2544", "Burst capacity testing", nil},
+	{config.TestSystemRecovery, "rfc2544", "Recovery time after overload", func(fs *pflag.FlagSet) { addRecoveryFlags(fs, "") }},
+	{config.TestReset, "rfc2544", "Device reset recovery time", nil},
+	{config.TestSuite, "rfc2544", "All six RFC 2544 tests in sequence", addSuiteFlags},
+	{config.TestCharacterize, "rfc2544", "Throughput, then latency at shares of the measured rate", addCharacterizeFlags},
+	{config.TestSoak, "rfc2544", "Fixed-rate soak with throughput/latency drift tracking", func(fs *pflag.FlagSet) { addSoakFlags(fs, "") }},
+	{config.TestQoS, "rfc2544", "QoS scheduling matrix: per-class share, loss and latency under saturation (TRex)", addQoSFlags},
+	{config.TestAQM, "rfc2544", "WRED/AQM characterization: loss and queueing delay over a load ramp", addAQMFlags},
+	{config.TestMicroburst, "rfc2544", "Egress buffer depth: the burst size trains of line-rate bursts start losing at", addMicroburstFlags},
+	{config.TestY1564Config, "y1564", "Service Configuration Test (step test)", addY1564Flags},
+	{config.TestY1564Perf, "y1564", "Service Performance Test (sustained)", addY1564Flags},
+	{config.TestY1564Full, "y1564", "Full Y.1564 test (both config and perf)", addY1564Flags},
+	{config.TestRFC2889Forwarding, "rfc2889", "Forwarding rate", addRFC2889Flags},
+	{config.TestRFC2889Caching, "rfc2889", "Address caching capacity", addRFC2889Flags},
+	{config.TestRFC2889Learning, "rfc2889", "Address learning rate", addRFC2889Flags},
+	{config.TestRFC2889Broadcast, "rfc2889", "Broadcast forwarding", addRFC2889Flags},
+	{config.TestRFC2889Congestion, "rfc2889", "Congestion control", addRFC2889Flags},
+	{config.TestRFC6349Throughput, "rfc6349", "TCP throughput measurement", addRFC6349Flags},
+	{config.TestRFC6349Path, "rfc6349", "Path analysis (RTT, bottleneck BW)", addRFC6349Flags},
+	{config.TestY1731Delay, "y1731", "Delay measurement (DMM/DMR)", addY1731Flags},
+	{config.TestY1731Loss, "y1731", "Loss measurement (LMM/LMR)", addY1731Flags},
+	{config.TestY1731SLM, "y1731", "Synthetic loss measurement", addY1731Flags},
+	{config.TestY1731Loopback, "y1731", "Loopback test (LBM/LBR)", addY1731Flags},
+	{config.TestMEFConfig, "mef", "Configuration test (step)", func(fs *pflag.FlagSet) { addMEFFlags(fs, "") }},
+	{config.TestMEFPerf, "mef", "Performance test (sustained)", func(fs *pflag.FlagSet) { addMEFFlags(fs, "") }},
+	{config.TestMEFFull, "mef", "Full MEF test", func(fs *pflag.FlagSet) { addMEFFlags(fs, "") }},
+	{config.TestTSNTiming, "tsn", "Gate timing accuracy", func(fs *pflag.FlagSet) { addTSNFlags(fs, "") }},
+	{config.TestTSNIsolation, "tsn", "Traffic class isolation", func(fs *pflag.FlagSet) { addTSNFlags(fs, "") }},
+	{config.TestTSNLatency, "tsn", "Scheduled latency", func(fs *pflag.FlagSet) { addTSNFlags(fs, "") }},
+	{config.TestTSNFull, "tsn", "Full TSN test suite", func(fs *pflag.FlagSet) { addTSNFlags(fs, "") }},
+	{config.TestUDPEcho, "routed", "UDP round-trip latency/loss via an rfc2544 reflector (reduced accuracy)", addUDPEchoFlags},
+	{config.TestSelfTest, "verify", "Check the measured delay, jitter, loss and reordering of netem impairments on a veth path", addSelfTestFlags},
+}
+
+// newTestCommand creates the subcommand for one test type. Names use
+// hyphens (frame-loss); the config spelling (frame_loss) is an alias.
+func newTestCommand(tc testCommand) *cobra.Command {
+	name := strings.ReplaceAll(string(tc.test), "_", "-")
+	cmd := &cobra.Command{
+		Use:     name,
+		Short:   tc.short,
+		GroupID: tc.group,
+		Args:    cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			testType = string(tc.test)
+			runMain(cmd, args)
+		},
+	}
+	if name != string(tc.test) {
+		cmd.Aliases = []string{string(tc.test)}
+	}
+	addRunFlags(cmd.Flags())
+	if tc.flags != nil {
+		tc.flags(cmd.Flags())
+	}
+	return cmd
+}
+
+// Throughput flags (Section 26.1). Defaults are shown from the built-in
+// config; a config file value is only overridden when a flag is given.
+func addThroughputFlags(fs *pflag.FlagSet) {
+	def := config.DefaultConfig().Throughput
+	fs.Float64Var(&throughputInitialPct, "initial-rate", def.InitialRatePct, "Starting rate of the binary search (% of line rate)")
+	fs.Float64Var(&throughputResolutionPct, "resolution", def.ResolutionPct, "Stop searching when the step is below this (% of line rate)")
+	fs.Uint32Var(&throughputMaxIterations, "max-iterations", def.MaxIterations, "Maximum binary search iterations")
+	fs.Float64Var(&throughputAcceptableLoss, "acceptable-loss", def.AcceptableLoss, "Loss tolerated in a passing trial (%)")
+	fs.StringVar(&throughputSearch, "search", string(def.SearchAlgorithm), "Search algorithm: binary, linear (every step, reporting the rate/loss curve) or hybrid")
+	fs.Float64Var(&throughputStepPct, "step", def.StepPct, "Linear and hybrid search step (% of line rate)")
+}
+
+// Latency flags (Section 26.2)
+func addLatencyFlags(fs *pflag.FlagSet) {
+	fs.Float64SliceVar(&latencyLoads, "loads", nil, "Load levels to measure at in % of throughput (default from config: 10,20,...,100)")
+	fs.BoolVar(&latencySubtract, "subtract-serialization", false, "Report the latency vs frame size curve less each frame's serialization delay")
+	fs.Uint32Var(&probePPS, "probe-pps", 0, "Send a latency probe stream at this many frames/s alongside the load, reporting its latency apart")
+	fs.Uint8Var(&probePCP, "probe-pcp", 0, "Latency probe: 802.1p priority of its VLAN tags (0-7)")
+	fs.Uint8Var(&probeDSCP, "probe-dscp", 0, "Latency probe: DSCP of its IPv4 header (e.g., 46 for EF)")
+}
+
+// Frame loss flags (Section 26.3). A threshold replaces the step sweep
+// with a binary search for the partial drop rate.
+func addFrameLossFlags(fs *pflag.FlagSet) {
+	def := config.DefaultConfig().FrameLoss
+	fs.Float64Var(&frameLossThreshold, "search-threshold", 0, "Search for the highest load with loss at or below this (%, 0 = step sweep)")
+	fs.Float64Var(&frameLossResolution, "search-resolution", def.SearchResolutionPct, "Stop the search when the load range is below this (% of line rate)")
+	fs.Uint32Var(&frameLossLossless, "lossless-trials", def.LosslessTrials, "End the sweep after this many trials in a row without loss (Section 26.3: 2, 0 = sweep to end_pct)")
+}
+
+// System Recovery flags (Section 26.5)
+func addRecoveryFlags(fs *pflag.FlagSet, prefix string) {
+	fs.Uint32Var(&recoveryOverloadSec, "overload-sec", config.DefaultConfig().Recovery.OverloadSec, "System Recovery: Overload duration in seconds")
+	fs.Float64Var(&recoveryThroughput, prefix+"throughput", 0, "System Recovery: Throughput % to use (0 = auto-detect)")
+}
+
+// Suite flags: the throughput search and latency loads, plus the recovery
+// overload; recovery runs at the measured throughput
+func addSuiteFlags(fs *pflag.FlagSet) {
+	addThroughputFlags(fs)
+	addLatencyFlags(fs)
+	fs.Uint32Var(&recoveryOverloadSec, "overload-sec", config.DefaultConfig().Recovery.OverloadSec, "System Recovery: Overload duration in seconds")
+}
+
+// Characterization flags: the throughput search, and the latency loads
+// taken as shares of the throughput it finds
+func addCharacterizeFlags(fs *pflag.FlagSet) {
+	addThroughputFlags(fs)
+	addLatencyFlags(fs)
+}
+
+func addSoakFlags(fs *pflag.FlagSet, prefix string) {
+	fs.DurationVar(&soakDuration, prefix+"duration", 0, "Soak: Total duration (default from config: 24h)")
+	fs.Float64Var(&soakRate, prefix+"rate", 0, "Soak: Offered load in % of line rate (default from config: 90)")
+	fs.DurationVar(&soakBucket, prefix+"bucket", 0, "Soak: Drift reporting interval (default from config: 15m)")
+	fs.DurationVar(&soakSample, prefix+"sample", 0, "Soak: Length of each sample, a row of the time series (default from config: 1m)")
+	fs.StringVar(&soakSeries, prefix+"series", "", "Soak: Write every sample to this .csv or .jsonl file as it is taken (default: beside --output-file)")
+}
+
+func addQoSFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&qosMarking, "marking", "", "QoS: Class marking, dscp or pcp (default from config: dscp)")
+	fs.Float64Var(&qosLoadPct, "load", 0, "QoS: Total offered load in % of line rate (default from config: 100)")
+	fs.Uint16Var(&qosVLAN, "vlan", 0, "QoS: VLAN ID of PCP-marked frames (default from config: 0, priority-tagged)")
+}
+
+func addAQMFlags(fs *pflag.FlagSet) {
+	fs.Float64Var(&aqmStart, "start", 0, "AQM: First offered load in % of line rate (default from config: 50)")
+	fs.Float64Var(&aqmEnd, "end", 0, "AQM: Last offered load in % of line rate (default from config: 100)")
+	fs.Float64Var(&aqmStep, "step", 0, "AQM: Load increase per step in % (default from config: 2)")
+	fs.DurationVar(&aqmStepDuration, "step-duration", 0, "AQM: Trial length of each step (default from config: 10s)")
+}
+
+func addMicroburstFlags(fs *pflag.FlagSet) {
+	fs.Uint32Var(&microStartFrames, "start-frames", 0, "Microburst: First burst size in frames (default from config: 16)")
+	fs.Uint32Var(&microMaxFrames, "max-frames", 0, "Microburst: Largest burst size in frames (default from config: 100000)")
+	fs.Uint32Var(&microStepFrames, "step-frames", 0, "Microburst: Burst growth per train until loss (default from config: 0, double)")
+	fs.Uint32Var(&microResolution, "resolution-frames", 0, "Microburst: Bisect the burst size to within this many frames (default from config: 8)")
+	fs.Uint32Var(&microBursts, "bursts", 0, "Microburst: Bursts per train, at most 1024 (default from config: 10)")
+	fs.DurationSliceVar(&microGaps, "gaps", nil, "Microburst: Idle times between bursts, one search each (default from config: 1ms)")
+	fs.Float64Var(&microRatePct, "burst-rate", 0, "Microburst: Rate within a burst in % of line rate (default from config: 100)")
+	fs.Float64Var(&microEgressMbps, "egress-mbps", 0, "Microburst: DUT egress rate in Mbps (default from config: 0, measure)")
+}
+
+func addSelfTestFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&selfTestInterface, "impair-interface", "", "Self-test: Reflector-side interface netem impairs, e.g. the far end of the veth pair")
+	fs.Float64Var(&selfTestLoadPct, "load", 0, "Self-test: Offered load of each trial in % of line rate (default from config: 1)")
+	fs.DurationVar(&selfTestDuration, "case-duration", 0, "Self-test: Trial length of the baseline and each case (default from config: 10s)")
+}
+
+func addY1564Flags(fs *pflag.FlagSet) {
+	fs.Float64Var(&y1564CIR, "cir", 100.0, "Y.1564: Committed Information Rate (Mbps)")
+	fs.Float64Var(&y1564FD, "fd", 10.0, "Y.1564: Frame Delay threshold (ms)")
+	fs.Float64Var(&y1564FDV, "fdv", 5.0, "Y.1564: Frame Delay Variation threshold (ms)")
+	fs.Float64Var(&y1564FLR, "flr", 0.01, "Y.1564: Frame Loss Ratio threshold (%)")
+	fs.Uint32Var(&y1564PerfMinutes, "perf-duration", 15, "Y.1564: Performance test duration (minutes)")
+}
+
+func addRFC2889Flags(fs *pflag.FlagSet) {
+	fs.Uint32Var(&rfc2889PortCount, "ports", 2, "RFC 2889: Number of ports (2 for the forwarding test, one flow TX port to RX port)")
+	fs.Uint32Var(&rfc2889AddressCount, "addresses", 8192, "RFC 2889: MAC addresses for caching test")
+	fs.StringSliceVar(&rfc2889Orientations, "orientation", nil, "RFC 2889: Traffic orientations (one_to_many, many_to_one) of the two ports; one flow, TX port to RX port, is measured")
+}
+
+func addRFC6349Flags(fs *pflag.FlagSet) {
+	fs.Uint32Var(&rfc6349MSS, "mss", 1460, "RFC 6349: Maximum Segment Size")
+	fs.Uint32Var(&rfc6349RWND, "rwnd", 65535, "RFC 6349: Receive Window Size")
+	fs.Uint32Var(&rfc6349ParallelStreams, "streams", 1, "RFC 6349: Parallel streams")
+}
+
+func addY1731Flags(fs *pflag.FlagSet) {
+	fs.Uint32Var(&y1731MEPID, "mep-id", 1, "Y.1731: MEP identifier")
+	fs.Uint8Var(&y1731MEGLevel, "meg-level", 4, "Y.1731: MEG level (0-7)")
+	fs.Uint32Var(&y1731ProbeCount, "probes", 100, "Y.1731: Number of probes")
+	fs.Uint32Var(&y1731IntervalMs, "probe-interval", 1000, "Y.1731: Interval between probes (ms)")
+}
+
+func addMEFFlags(fs *pflag.FlagSet, prefix string) {
+	fs.Float64Var(&mefCIR, prefix+"cir", 100.0, "MEF: Committed Information Rate (Mbps)")
+	fs.Float64Var(&mefEIR, prefix+"eir", 0, "MEF: Excess Information Rate (Mbps)")
+	fs.Float64Var(&mefFD, prefix+"fd", 10000.0, "MEF: Frame Delay threshold (us)")
+	fs.Float64Var(&mefFDV, prefix+"fdv", 5000.0, "MEF: Frame Delay Variation (us)")
+	fs.Float64Var(&mefFLR, prefix+"flr", 0.01, "MEF: Frame Loss Ratio threshold (%)")
+	fs.Uint32Var(&mefPerfMinutes, prefix+"perf-duration", 15, "MEF: Performance test duration (minutes)")
+}
+
+func addTSNFlags(fs *pflag.FlagSet, prefix string) {
+	fs.Uint32Var(&tsnNumClasses, prefix+"classes", 8, "TSN: Number of traffic classes")
+	fs.Uint64Var(&tsnCycleTimeUs, prefix+"cycle", 1000, "TSN: GCL cycle time (us)")
+	fs.Uint64Var(&tsnMaxLatencyUs, prefix+"latency", 100, "TSN: Maximum latency threshold (us)")
+	fs.Uint64Var(&tsnMaxJitterUs, prefix+"jitter", 10, "TSN: Maximum jitter threshold (us)")
+}
+
+func addUDPEchoFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&udpEchoTarget, "reflector", "", "UDP echo: Reflector host[:port] (default port 3842)")
+	fs.Uint32Var(&udpEchoPPS, "pps", 0, "UDP echo: Datagrams per second (default from config: 1000)")
+	fs.DurationVar(&udpEchoDuration, "duration", 0, "UDP echo: Sending time per frame size (default from config: 60s)")
+}
+
 func runMain(cmd *cobra.Command, args []string) {
 	// Load config
 	var cfg *config.Config
 	var err error
 
-	if cfgFile != "" {
-		cfg, err = config.Load(cfgFile)
+	// Layers: defaults < profile < config file < environment < circuit < flags
+	var preset *config.Preset
+	if profileName != "" {
+		presets, err := loadPresets()
 		if err != nil {
-			log.Fatalf("Failed to load config: %v", err)
+			log.Fatalf("Failed to load profiles: %v", err)
+		}
+		if preset, err = presets.Get(profileName); err != nil {
+			log.Fatal(err)
 		}
-	} else {
-		cfg = config.DefaultConfig()
 	}
-
-	// Override with CLI flags
-	if iface != "" {
-		cfg.Interface = iface
+	cfg, err = config.LoadLayers(config.Layers{Preset: preset, File: cfgFile, Environ: os.Environ(), Strict: strictConfig})
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
 	}
-	if testType != "" {
-		cfg.TestType = config.TestType(testType)
+	var circuit *config.Circuit
+	if circuitID != "" {
+		if circuit, err = lookupCircuit(cfg); err != nil {
+			log.Fatal(err)
+		}
+		// The circuit's SLA profile, unless --profile names another
+		if preset == nil && circuit.Profile != "" {
+			presets, err := loadPresets()
+			if err != nil {
+				log.Fatalf("Failed to load profiles: %v", err)
+			}
+			if preset, err = presets.Get(circuit.Profile); err != nil {
+				log.Fatalf("Circuit %s: %v", circuit.ID, err)
+			}
+			if cfg, err = config.LoadLayers(config.Layers{Preset: preset, File: cfgFile, Environ: os.Environ(), Strict: strictConfig}); err != nil {
+				log.Fatalf("Failed to load config: %v", err)
+			}
+		}
+		circuit.Apply(cfg)
+		log.Printf("Circuit %s", circuit)
 	}
-	if frameSize != 0 {
-		cfg.FrameSize = frameSize
+	envOverrides := config.EnvNames(os.Environ())
+	if verbose && len(envOverrides) > 0 {
+		log.Printf("Environment overrides: %s", strings.Join(envOverrides, ", "))
 	}
-	if webAddr != "" {
-		cfg.WebUI.Enabled = true
-		cfg.WebUI.Address = webAddr
+
+	// Override with CLI flags
+	applyFlags(cmd, cfg)
+	if tuiPlain {
+		useTUI = true
 	}
-	cfg.Verbose = verbose
 
-	// Apply Y.1564 CLI options if running Y.1564 test
-	if cfg.TestType == config.TestY1564Config || cfg.TestType == config.TestY1564Perf || cfg.TestType == config.TestY1564Full {
-		// Create a default service from CLI options
-		defaultSvc := config.Y1564Service{
-			ServiceID:   1,
-			ServiceName: "CLI Service",
-			FrameSize:   512,
-			CoS:         0,
-			Enabled:     true,
-			SLA: config.Y1564SLA{
-				CIRMbps:         y1564CIR,
-				EIRMbps:         0,
-				CBSBytes:        12000,
-				EBSBytes:        0,
-				FDThresholdMs:   y1564FD,
-				FDVThresholdMs:  y1564FDV,
-				FLRThresholdPct: y1564FLR,
-			},
+	// A certification runs the RFC's parameters over whatever the layers set
+	if certifying {
+		if useTUI || cfg.WebUI.Enabled || cfg.Plan.Enabled() {
+			log.Fatal("certify runs in CLI mode only, without a test plan")
 		}
-		if frameSize != 0 {
-			defaultSvc.FrameSize = frameSize
+		certifyOverrides = certify.Apply(cfg)
+		for _, o := range certifyOverrides {
+			log.Printf("Certification: %s", o)
 		}
-		cfg.Y1564.Services = []config.Y1564Service{defaultSvc}
-		cfg.Y1564.PerfDuration = time.Duration(y1564PerfMinutes) * time.Minute
 	}
 
-	// Apply RFC 2889 CLI options
-	if isRFC2889Test(cfg.TestType) {
-		cfg.RFC2889.PortCount = rfc2889PortCount
-		cfg.RFC2889.AddressCount = rfc2889AddressCount
+	// Validate
+	if (resumeFile != "" || stateFile != "") && (useTUI || cfg.WebUI.Enabled) {
+		log.Fatal("--state-file and --resume apply to CLI mode only")
 	}
-
-	// Apply RFC 6349 CLI options
-	if isRFC6349Test(cfg.TestType) {
-		cfg.RFC6349.MSS = rfc6349MSS
-		cfg.RFC6349.RWND = rfc6349RWND
-		cfg.RFC6349.ParallelStreams = rfc6349ParallelStreams
+	if dryRun && (useTUI || cfg.WebUI.Enabled) {
+		log.Fatal("--dry-run applies to CLI mode only")
 	}
-
-	// Apply Y.1731 CLI options
-	if isY1731Test(cfg.TestType) {
-		cfg.Y1731.MEPID = y1731MEPID
-		cfg.Y1731.MEGLevel = y1731MEGLevel
-		cfg.Y1731.ProbeCount = y1731ProbeCount
-		cfg.Y1731.ProbeInterval = time.Duration(y1731IntervalMs) * time.Millisecond
+	if redactMode != "" {
+		if _, err := newRedactor(); err != nil {
+			log.Fatal(err)
+		}
+		if outputFormat == "text" || outputFormat == "html" || outputFormat == "pdf" || useTUI || cfg.WebUI.Enabled {
+			log.Fatal("--redact applies to -o json and csv results")
+		}
+	}
+	if outputFormat == "pdf" && outputFile == "" {
+		log.Fatal("-o pdf needs --output-file")
+	}
+	if cfg.Plan.Enabled() {
+		if useTUI || cfg.WebUI.Enabled {
+			log.Fatal("Test plans run in CLI mode only; in the Web UI queue a campaign instead")
+		}
+		if resumeFile != "" {
+			log.Fatal("--resume applies to a single test, not a plan")
+		}
+	} else if resumeFile != "" && !checkpointed(cfg.TestType) {
+		log.Fatalf("Test type %s cannot be resumed", cfg.TestType)
+	}
+	if len(cfg.Gates) > 0 && !useTUI {
+		log.Fatal("Operator gates need --tui; in the Web UI set a prompt on campaign items instead")
+	}
+	if cfg.DUTSnapshot.Enabled() && (useTUI || cfg.WebUI.Enabled) {
+		log.Fatal("DUT config snapshots apply to CLI mode only")
+	}
+	if cfg.Packet.Enabled() && (useTUI || cfg.WebUI.Enabled) {
+		log.Fatal("Packet templates apply to CLI mode only")
+	}
+	if cfg.TestType == config.TestQoS && !cfg.TRex.Enabled() {
+		log.Fatal("The QoS test sends all classes at once and needs TRex; use --trex <server>")
+	}
+	if cfg.TRex.Enabled() {
+		if useTUI || cfg.WebUI.Enabled {
+			log.Fatal("TRex runs in CLI mode only")
+		}
+	} else if cfg.Socket.Enabled() {
+		if useTUI || cfg.WebUI.Enabled {
+			log.Fatal("Socket mode runs in CLI mode only")
+		}
+	} else if cfg.TestType == config.TestUDPEcho {
+		if useTUI || cfg.WebUI.Enabled {
+			log.Fatal("UDP echo runs in CLI mode only")
+		}
+	} else if cfg.Interface == "" && !cfg.WebUI.Enabled {
+		log.Fatal("Interface is required. Use -i <interface>, --trex <server>, --socket <reflector> or --web for API mode")
+	}
+	// Flags are the last layer, so only the merged config can be checked;
+	// a dry run reports the problems instead
+	if !dryRun {
+		if err := cfg.Validate(); err != nil {
+			log.Fatalf("Invalid configuration: %v", err)
+		}
 	}
 
-	// Apply MEF CLI options
-	if isMEFTest(cfg.TestType) {
-		cfg.MEF.CIRMbps = mefCIR
-		cfg.MEF.EIRMbps = mefEIR
-		cfg.MEF.FDThresholdUs = mefFD
-		cfg.MEF.FDVThresholdUs = mefFDV
-		cfg.MEF.FLRThresholdPct = mefFLR
-		cfg.MEF.PerfDuration = time.Duration(mefPerfMinutes) * time.Minute
+	// Report on the setup and stop before the dataplane is opened
+	if dryRun {
+		if !runDryRunPlan(cfg) {
+			os.Exit(1)
+		}
+		return
 	}
 
-	// Apply TSN CLI options
-	if isTSNTest(cfg.TestType) {
-		cfg.TSN.NumClasses = tsnNumClasses
-		cfg.TSN.CycleTimeNs = tsnCycleTimeUs * 1000 // Convert us to ns
-		cfg.TSN.MaxLatencyNs = tsnMaxLatencyUs * 1000
-		cfg.TSN.MaxJitterNs = tsnMaxJitterUs * 1000
+	if cfg.Interface != "" && !cfg.TRex.Enabled() && !cfg.Socket.Enabled() && cfg.TestType != config.TestUDPEcho {
+		checkInterface(cfg)
+	}
+	// The web daemon asks each start request instead
+	if !cfg.WebUI.Enabled {
+		if err := checkInService(cfg); err != nil {
+			log.Fatal(err)
+		}
 	}
 
-	// Validate
-	if cfg.Interface == "" && !cfg.WebUI.Enabled {
-		log.Fatal("Interface is required. Use -i <interface> or --web for API mode")
+	// Profiling endpoints stay up for the life of the process
+	if cfg.Profile.Enabled() {
+		startPprof(cfg)
 	}
 
 	// Signal handling
@@ -326,21 +1379,496 @@ func runMain(cmd *cobra.Command, args []string) {
 	if useTUI {
 		runTUI(cfg, sigCh)
 	} else if cfg.WebUI.Enabled {
-		runWebOnly(cfg, sigCh)
-	} else {
-		runCLI(cfg, sigCh)
+		// A reload rebuilds every layer, so the flags still win over the file
+		runWebOnly(cfg, sigCh, func(data []byte) (*config.Config, error) {
+			next, err := config.LoadLayers(config.Layers{Preset: preset, File: cfgFile, Data: data, Environ: os.Environ(), Strict: strictConfig})
+			if err != nil {
+				return nil, err
+			}
+			if circuit != nil {
+				circuit.Apply(next)
+			}
+			applyFlags(cmd, next)
+			if err := next.Validate(); err != nil {
+				return nil, fmt.Errorf("validate config: %w", err)
+			}
+			return next, nil
+		})
+	} else if cfg.Plan.Enabled() {
+		if !runPlan(cfg, sigCh) {
+			log.Printf("Plan steps failed; exiting with code %d", exitThresholds)
+			os.Exit(exitThresholds)
+		}
+	} else if !runCLI(cfg, sigCh).Passed {
+		failed := "Thresholds violated or self-test failed"
+		if certifying {
+			failed = "Certification incomplete, thresholds violated or self-test failed"
+		}
+		log.Printf("%s; exiting with code %d", failed, exitThresholds)
+		os.Exit(exitThresholds)
 	}
 }
 
-func runTUI(cfg *config.Config, sigCh chan os.Signal) {
-	app := tui.New()
-
-	// Dataplane context (initialized on start)
-	var dpCtx *dataplane.Context
-	var cancelTest atomic.Bool
-
+// applyFlags overrides cfg with the command-line flags, the top layer of
+// the configuration
+func applyFlags(cmd *cobra.Command, cfg *config.Config) {
+	if iface != "" {
+		cfg.Interface = iface
+		if cfg.Ports.TX != "" {
+			cfg.Ports.TX = iface
+		}
+	}
+	if rxIface != "" {
+		cfg.Ports.RX = rxIface
+	}
+	if speedProfile != "" {
+		cfg.SpeedProfile = speedProfile
+	}
+	if cmd.Flags().Changed("dataplane-watchdog") {
+		cfg.DataplaneWatchdog = dpWatchdog
+	}
+	if tuiPlain {
+		cfg.TUI.Plain = true
+	}
+	if cfg.Interface == "" {
+		cfg.Interface = cfg.Ports.TX
+	}
+	if testType != "" {
+		cfg.TestType = config.TestType(testType)
+	}
+	if frameSize != 0 && len(sweepSizes) > 0 {
+		log.Fatal("Use -s for one frame size or --frame-sizes for a list, not both")
+	}
+	if frameSize != 0 {
+		cfg.FrameSize = frameSize
+		cfg.FrameSizes = nil
+	}
+	if len(sweepSizes) > 0 {
+		cfg.FrameSize = 0
+		cfg.FrameSizes = nil
+		for _, fs := range sweepSizes {
+			cfg.FrameSizes = append(cfg.FrameSizes, uint32(fs))
+		}
+	}
+	if len(jumboSizes) > 0 {
+		cfg.IncludeJumbo = true
+		cfg.JumboSizes = nil
+		for _, fs := range jumboSizes {
+			cfg.JumboSizes = append(cfg.JumboSizes, uint32(fs))
+		}
+	}
+	if webAddr != "" {
+		cfg.WebUI.Enabled = true
+		cfg.WebUI.Address = webAddr
+	}
+	cfg.Verbose = verbose
+	if latencyHist {
+		cfg.LatencyHistogram = true
+	}
+	if latencyCap != 0 {
+		cfg.LatencySampleCapacity = latencyCap
+	}
+	if capture != "" {
+		tx, rx, _ := strings.Cut(capture, ",")
+		cfg.Capture.TX, cfg.Capture.RX = tx, rx
+	}
+	if captureLimit != 0 {
+		cfg.Capture.Limit = captureLimit
+	}
+	if captureSnap != 0 {
+		cfg.Capture.Snaplen = captureSnap
+	}
+	if payloadCRC {
+		cfg.VerifyPayload = true
+	}
+	if broadcastPct != 0 {
+		cfg.Modifiers.BroadcastPct = broadcastPct
+	}
+	if mgmtTarget != "" {
+		cfg.Modifiers.ManagementTarget = mgmtTarget
+	}
+	if addressPairs != 0 {
+		cfg.Addressing.Pairs = addressPairs
+	}
+	if learnRate != 0 {
+		cfg.Addressing.LearnRate = learnRate
+	}
+	if streamCount != 0 {
+		cfg.Addressing.Streams = streamCount
+	}
+	if cppTarget != "" {
+		cfg.ControlPlane.DUTIP = cppTarget
+	}
+	if cppICMP != 0 {
+		cfg.ControlPlane.ICMPPerSec = cppICMP
+	}
+	if cppARP != 0 {
+		cfg.ControlPlane.ARPPerSec = cppARP
+	}
+	if cppBGP != 0 {
+		cfg.ControlPlane.BGPPerSec = cppBGP
+	}
+	if etherType != "" {
+		v, err := strconv.ParseUint(etherType, 0, 16)
+		if err != nil {
+			log.Fatalf("Invalid EtherType %q: %v", etherType, err)
+		}
+		cfg.Framing.EtherType = uint16(v)
+	}
+	if llcSNAP {
+		cfg.Framing.Encapsulation = config.EncapLLCSNAP
+	}
+	if vlanID != 0 {
+		cfg.Framing.VLANID = vlanID
+	}
+	if vlanPCP != 0 {
+		cfg.Framing.PCP = vlanPCP
+	}
+	if vlanTPID != "" {
+		v, err := strconv.ParseUint(vlanTPID, 0, 16)
+		if err != nil {
+			log.Fatalf("Invalid TPID %q: %v", vlanTPID, err)
+		}
+		cfg.Framing.TPID = uint16(v)
+	}
+	if outerVLAN != 0 {
+		cfg.Framing.OuterVLANID = outerVLAN
+	}
+	if outerPCP != 0 {
+		cfg.Framing.OuterPCP = outerPCP
+	}
+	if outerTPID != "" {
+		v, err := strconv.ParseUint(outerTPID, 0, 16)
+		if err != nil {
+			log.Fatalf("Invalid S-tag TPID %q: %v", outerTPID, err)
+		}
+		cfg.Framing.OuterTPID = uint16(v)
+	}
+	if len(mplsStack) > 0 {
+		cfg.Framing.MPLSLabels = nil
+		for _, s := range mplsStack {
+			l, err := parseMPLSLabel(s)
+			if err != nil {
+				log.Fatalf("Invalid MPLS label %q: %v", s, err)
+			}
+			cfg.Framing.MPLSLabels = append(cfg.Framing.MPLSLabels, l)
+		}
+	}
+	if mplsCoS {
+		cfg.Framing.EXPFromCoS = true
+	}
+	if packetTpl != "" {
+		cfg.Packet.Template = packetTpl
+	}
+	if ipVersion != "" {
+		cfg.IPVersion = config.IPVersion(ipVersion)
+	}
+	if ipv6Src != "" {
+		cfg.IPv6.Src = ipv6Src
+	}
+	if ipv6Dst != "" {
+		cfg.IPv6.Dst = ipv6Dst
+	}
+	if ipv6TC != 0 {
+		cfg.IPv6.TrafficClass = ipv6TC
+	}
+	if flowLabel != 0 {
+		cfg.IPv6.FlowLabel = flowLabel
+	}
+	if v6Share != 0 {
+		cfg.IPv6.DualStackPct = v6Share
+	}
+	if srcMAC != "" {
+		cfg.Headers.SrcMAC = srcMAC
+	}
+	if dstMAC != "" {
+		cfg.Headers.DstMAC = dstMAC
+	}
+	if srcIP != "" {
+		cfg.Headers.SrcIP = srcIP
+	}
+	if dstIP != "" {
+		cfg.Headers.DstIP = dstIP
+	}
+	if srcPort != "" {
+		cfg.Headers.SrcPort = srcPort
+	}
+	if dstPort != "" {
+		cfg.Headers.DstPort = dstPort
+	}
+	if cmd.Flags().Changed("dscp") {
+		cfg.DSCP = dscp
+	}
+	if payload != "" {
+		cfg.PayloadPattern = payload
+	}
+	if oamLoopback {
+		cfg.OAM.RemoteLoopback = true
+	}
+	if oamPeer != "" {
+		cfg.OAM.PeerMAC = oamPeer
+	}
+	if soakDuration != 0 {
+		cfg.Soak.Duration = soakDuration
+	}
+	if soakRate != 0 {
+		cfg.Soak.RatePct = soakRate
+	}
+	if soakBucket != 0 {
+		cfg.Soak.BucketInterval = soakBucket
+	}
+	if soakSample != 0 {
+		cfg.Soak.SampleDuration = soakSample
+	}
+	if soakSeries != "" {
+		cfg.Soak.SeriesFile = soakSeries
+	}
+	if qosMarking != "" {
+		cfg.QoS.Marking = config.QoSMarking(qosMarking)
+	}
+	if qosLoadPct != 0 {
+		cfg.QoS.LoadPct = qosLoadPct
+	}
+	if qosVLAN != 0 {
+		cfg.QoS.VLAN = qosVLAN
+	}
+	if aqmStart != 0 {
+		cfg.AQM.StartPct = aqmStart
+	}
+	if aqmEnd != 0 {
+		cfg.AQM.EndPct = aqmEnd
+	}
+	if aqmStep != 0 {
+		cfg.AQM.StepPct = aqmStep
+	}
+	if aqmStepDuration != 0 {
+		cfg.AQM.StepDuration = aqmStepDuration
+	}
+	if microStartFrames != 0 {
+		cfg.Microburst.StartFrames = microStartFrames
+	}
+	if microMaxFrames != 0 {
+		cfg.Microburst.MaxFrames = microMaxFrames
+	}
+	if microStepFrames != 0 {
+		cfg.Microburst.StepFrames = microStepFrames
+	}
+	if microResolution != 0 {
+		cfg.Microburst.ResolutionFrames = microResolution
+	}
+	if microBursts != 0 {
+		cfg.Microburst.Bursts = microBursts
+	}
+	if len(microGaps) > 0 {
+		cfg.Microburst.Gaps = microGaps
+	}
+	if microRatePct != 0 {
+		cfg.Microburst.BurstRatePct = microRatePct
+	}
+	if microEgressMbps != 0 {
+		cfg.Microburst.EgressMbps = microEgressMbps
+	}
+	if selfTestInterface != "" {
+		cfg.SelfTest.Interface = selfTestInterface
+	}
+	if selfTestLoadPct != 0 {
+		cfg.SelfTest.RatePct = selfTestLoadPct
+	}
+	if selfTestDuration != 0 {
+		cfg.SelfTest.Duration = selfTestDuration
+	}
+	if udpEchoTarget != "" {
+		cfg.UDPEcho.Target = udpEchoTarget
+	}
+	if udpEchoPPS != 0 {
+		cfg.UDPEcho.RatePPS = udpEchoPPS
+	}
+	if udpEchoDuration != 0 {
+		cfg.UDPEcho.Duration = udpEchoDuration
+	}
+	if powerCmd != "" {
+		cfg.Power.Source = config.PowerSourceCommand
+		cfg.Power.Command = powerCmd
+	}
+	if powerIPMI {
+		cfg.Power.Source = config.PowerSourceIPMI
+	}
+	if gnmiAddr != "" {
+		cfg.GNMI.Address = gnmiAddr
+	}
+	if pprofAddr != "" {
+		cfg.Profile.PprofAddress = pprofAddr
+	}
+	if dutName != "" {
+		cfg.DUT.Name = dutName
+	}
+	if len(dutSnapshots) > 0 {
+		cfg.DUTSnapshot.Commands = dutSnapshots
+	}
+	if dutSSH != "" {
+		cfg.DUTSnapshot.SSH = dutSSH
+	}
+	if len(preQualTargets) > 0 {
+		cfg.PreQual.Targets = preQualTargets
+	}
+	if preQualRequired {
+		cfg.PreQual.Required = true
+	}
+
+	// Test subcommand flags override the config file only when given
+	flags := cmd.Flags()
+	if flags.Changed("initial-rate") {
+		cfg.Throughput.InitialRatePct = throughputInitialPct
+	}
+	if flags.Changed("resolution") {
+		cfg.Throughput.ResolutionPct = throughputResolutionPct
+	}
+	if flags.Changed("max-iterations") {
+		cfg.Throughput.MaxIterations = throughputMaxIterations
+	}
+	if flags.Changed("acceptable-loss") {
+		cfg.Throughput.AcceptableLoss = throughputAcceptableLoss
+	}
+	if flags.Changed("search") {
+		cfg.Throughput.SearchAlgorithm = config.SearchAlgorithm(throughputSearch)
+	}
+	if flags.Changed("step") {
+		cfg.Throughput.StepPct = throughputStepPct
+	}
+	if flags.Changed("loads") {
+		cfg.Latency.LoadLevels = latencyLoads
+	}
+	if flags.Changed("subtract-serialization") {
+		cfg.Latency.SubtractSerialization = latencySubtract
+	}
+	if flags.Changed("probe-pps") {
+		cfg.LatencyProbe.PPS = probePPS
+	}
+	if flags.Changed("probe-pcp") {
+		cfg.LatencyProbe.PCP = probePCP
+	}
+	if flags.Changed("probe-dscp") {
+		cfg.LatencyProbe.DSCP = probeDSCP
+	}
+	if flags.Changed("search-threshold") {
+		cfg.FrameLoss.SearchThresholdPct = frameLossThreshold
+	}
+	if flags.Changed("search-resolution") {
+		cfg.FrameLoss.SearchResolutionPct = frameLossResolution
+	}
+	if flags.Changed("lossless-trials") {
+		cfg.FrameLoss.LosslessTrials = frameLossLossless
+	}
+	if flags.Changed("overload-sec") {
+		cfg.Recovery.OverloadSec = recoveryOverloadSec
+	}
+	if trexServer != "" {
+		cfg.TRex.Server = trexServer
+	}
+	if flags.Changed("trex-tx-port") {
+		cfg.TRex.TxPort = trexTxPort
+	}
+	if flags.Changed("trex-rx-port") {
+		cfg.TRex.RxPort = trexRxPort
+	}
+	if socketTarget != "" {
+		cfg.Socket.Target = socketTarget
+	}
+	if socketMaxPPS != 0 {
+		cfg.Socket.MaxPPS = socketMaxPPS
+	}
+	if heatmapFile != "" {
+		cfg.Heatmap.File = heatmapFile
+	}
+
+	// Apply Y.1564 CLI options if running Y.1564 test
+	if cfg.TestType == config.TestY1564Config || cfg.TestType == config.TestY1564Perf || cfg.TestType == config.TestY1564Full {
+		// Create a default service from CLI options
+		defaultSvc := config.Y1564Service{
+			ServiceID:   1,
+			ServiceName: "CLI Service",
+			FrameSize:   512,
+			CoS:         0,
+			Enabled:     true,
+			SLA: config.Y1564SLA{
+				CIRMbps:         y1564CIR,
+				EIRMbps:         0,
+				CBSBytes:        12000,
+				EBSBytes:        0,
+				FDThresholdMs:   y1564FD,
+				FDVThresholdMs:  y1564FDV,
+				FLRThresholdPct: y1564FLR,
+			},
+		}
+		if frameSize != 0 {
+			defaultSvc.FrameSize = frameSize
+		}
+		cfg.Y1564.Services = []config.Y1564Service{defaultSvc}
+		cfg.Y1564.PerfDuration = time.Duration(y1564PerfMinutes) * time.Minute
+	}
+
+	// Apply RFC 2889 CLI options
+	if isRFC2889Test(cfg.TestType) {
+		cfg.RFC2889.PortCount = rfc2889PortCount
+		cfg.RFC2889.AddressCount = rfc2889AddressCount
+		if len(rfc2889Orientations) > 0 {
+			cfg.RFC2889.Orientations = nil
+			for _, o := range rfc2889Orientations {
+				cfg.RFC2889.Orientations = append(cfg.RFC2889.Orientations, config.TrafficOrientation(o))
+			}
+		}
+	}
+
+	// Apply RFC 6349 CLI options
+	if isRFC6349Test(cfg.TestType) {
+		cfg.RFC6349.MSS = rfc6349MSS
+		cfg.RFC6349.RWND = rfc6349RWND
+		cfg.RFC6349.ParallelStreams = rfc6349ParallelStreams
+	}
+
+	// Apply Y.1731 CLI options
+	if isY1731Test(cfg.TestType) {
+		cfg.Y1731.MEPID = y1731MEPID
+		cfg.Y1731.MEGLevel = y1731MEGLevel
+		cfg.Y1731.ProbeCount = y1731ProbeCount
+		cfg.Y1731.ProbeInterval = time.Duration(y1731IntervalMs) * time.Millisecond
+	}
+
+	// Apply MEF CLI options
+	if isMEFTest(cfg.TestType) {
+		cfg.MEF.CIRMbps = mefCIR
+		cfg.MEF.EIRMbps = mefEIR
+		cfg.MEF.FDThresholdUs = mefFD
+		cfg.MEF.FDVThresholdUs = mefFDV
+		cfg.MEF.FLRThresholdPct = mefFLR
+		cfg.MEF.PerfDuration = time.Duration(mefPerfMinutes) * time.Minute
+	}
+
+	// Apply TSN CLI options
+	if isTSNTest(cfg.TestType) {
+		cfg.TSN.NumClasses = tsnNumClasses
+		cfg.TSN.CycleTimeNs = tsnCycleTimeUs * 1000 // Convert us to ns
+		cfg.TSN.MaxLatencyNs = tsnMaxLatencyUs * 1000
+		cfg.TSN.MaxJitterNs = tsnMaxJitterUs * 1000
+	}
+}
+
+func runTUI(cfg *config.Config, sigCh chan os.Signal) {
+	app := tui.New(tuiOptions(cfg)...)
+	app.SetWiring(wiringDiagram(cfg).String())
+	app.SetConfig(tuiConfigText(cfg))
+	app.OnHistory = func() []tui.HistoryEntry {
+		return tuiHistory(cfg.TUI.HistoryDir)
+	}
+
+	// Dataplane context (initialized on start), and the cancel of the
+	// running test's context
+	var dpCtx *dataplane.Context
+	var stopTest context.CancelFunc
+
 	// Set up callbacks
 	app.OnStart = func() {
+		app.ShowPage(tui.PageLive)
 		app.LogInfo("Starting %s test on %s", cfg.TestType, cfg.Interface)
 		app.UpdateStats(tui.Stats{
 			TestType:  tui.TestType(cfg.TestType),
@@ -352,11 +1880,13 @@ func runTUI(cfg *config.Config, sigCh chan os.Signal) {
 		// Initialize dataplane
 		dpCfg := dataplane.Config{
 			Interface:      cfg.Interface,
+			RXInterface:    cfg.Ports.RX,
 			LineRate:       cfg.LineRateMbps * 1000000,
 			AutoDetect:     cfg.AutoDetect,
 			TestType:       dataplane.TestType(getTestTypeInt(cfg.TestType)),
 			FrameSize:      cfg.FrameSize,
 			IncludeJumbo:   cfg.IncludeJumbo,
+			JumboSizes:     cfg.JumboSizes,
 			TrialDuration:  cfg.TrialDuration,
 			WarmupPeriod:   cfg.WarmupPeriod,
 			InitialRatePct: cfg.Throughput.InitialRatePct,
@@ -365,10 +1895,34 @@ func runTUI(cfg *config.Config, sigCh chan os.Signal) {
 			AcceptableLoss: cfg.Throughput.AcceptableLoss,
 			HWTimestamp:    cfg.HWTimestamp,
 			MeasureLatency: cfg.MeasureLatency,
+
+			LatencyHistogram: cfg.LatencyHistogram,
+			LatencySamples:   cfg.LatencySampleCapacity,
+			VerifyPayload:    cfg.VerifyPayload,
+			Streams:          cfg.Addressing.Streams,
+			VLANID:           cfg.Framing.VLANID,
+			PCP:              cfg.Framing.PCP,
+			OuterVLANID:      cfg.Framing.OuterVLANID,
+			OuterPCP:         cfg.Framing.OuterPCP,
+			OuterTPID:        cfg.Framing.OuterTPID,
+			TPID:             cfg.Framing.TPID,
+			MPLSLabels:       dataplaneLabels(cfg.Framing.MPLSLabels),
+			MPLSEXPFromCoS:   cfg.Framing.EXPFromCoS,
+			LatencyProbe:     dataplaneProbe(cfg.LatencyProbe),
+			IP:               dataplaneIP(cfg),
+			Headers:          dataplaneHeaders(cfg),
+			Payload:          dataplanePayload(cfg),
+			Capture:          dataplaneCapture(cfg),
+			SpeedProfile:     cfg.SpeedProfile,
 		}
 
 		var err error
 		dpCtx, err = dataplane.New(dpCfg)
+		if err == nil {
+			if err = dataplaneSupports(dpCtx.Capabilities(), cfg); err != nil {
+				dpCtx.Close()
+			}
+		}
 		if err != nil {
 			app.LogError("Failed to init dataplane: %v", err)
 			app.UpdateStats(tui.Stats{State: "Error"})
@@ -376,27 +1930,32 @@ func runTUI(cfg *config.Config, sigCh chan os.Signal) {
 		}
 
 		// Run tests in background
-		go runTUITests(app, dpCtx, cfg, &cancelTest)
+		var runCtx context.Context
+		runCtx, stopTest = context.WithCancel(context.Background())
+		go runTUITests(runCtx, stopTest, app, dpCtx, cfg, func() (*dataplane.Context, error) {
+			return dataplane.New(dpCfg)
+		})
 	}
 
 	app.OnStop = func() {
 		app.LogInfo("Stopping test...")
-		cancelTest.Store(true)
-		if dpCtx != nil {
-			dpCtx.Cancel()
+		if stopTest != nil {
+			stopTest()
 		}
 	}
 
 	app.OnCancel = func() {
 		app.LogWarn("Test cancelled")
-		cancelTest.Store(true)
-		if dpCtx != nil {
-			dpCtx.Cancel()
+		if stopTest != nil {
+			stopTest()
 		}
 	}
 
 	app.OnQuit = func() {
 		app.LogInfo("Shutting down...")
+		if stopTest != nil {
+			stopTest()
+		}
 		if dpCtx != nil {
 			dpCtx.Close()
 		}
@@ -408,12 +1967,19 @@ func runTUI(cfg *config.Config, sigCh chan os.Signal) {
 		app.LogInfo("RFC2544 Test Master v%s", version)
 		app.LogInfo("Interface: %s", cfg.Interface)
 		app.LogInfo("Test type: %s", cfg.TestType)
-		if cfg.FrameSize == 0 {
+		switch {
+		case cfg.StandardSweep():
 			app.LogInfo("Frame sizes: All standard (64-1518)")
-		} else {
+		case len(cfg.FrameSizes) > 0:
+			app.LogInfo("Frame sizes: %v", cfg.FrameSizes)
+		default:
 			app.LogInfo("Frame size: %d bytes", cfg.FrameSize)
 		}
-		app.Log("Press F1 to start, F10 to quit")
+		if dataplane.PureGo {
+			app.LogWarn("Dataplane: %s", dataplane.Backend)
+		}
+		app.Log("Press %s to start, %s for help and wiring, %s to quit",
+			app.KeyText(tui.ActionStart), app.KeyText(tui.ActionHelp), app.KeyText(tui.ActionQuit))
 	}()
 
 	// Handle signals
@@ -427,23 +1993,159 @@ func runTUI(cfg *config.Config, sigCh chan os.Signal) {
 	}
 }
 
-func runTUITests(app *tui.App, ctx *dataplane.Context, cfg *config.Config, cancelled *atomic.Bool) {
-	defer func() {
-		app.UpdateStats(tui.Stats{State: "Complete"})
-		ctx.Close()
-	}()
+// tuiOptions applies the tui section of the config. Validate has checked
+// the keys.
+func tuiOptions(cfg *config.Config) []tui.Option {
+	keymap, _ := tui.Keymap(cfg.TUI.Keys.Names())
+	l := cfg.TUI.Labels
+	return []tui.Option{
+		tui.WithKeymap(keymap),
+		tui.WithTitle(cfg.TUI.Title),
+		tui.WithPlain(cfg.TUI.Plain),
+		tui.WithLabel(tui.ActionStart, l.Start),
+		tui.WithLabel(tui.ActionStop, l.Stop),
+		tui.WithLabel(tui.ActionHelp, l.Help),
+		tui.WithLabel(tui.ActionQuit, l.Quit),
+	}
+}
 
-	frameSizes := []uint32{cfg.FrameSize}
-	if cfg.FrameSize == 0 {
-		frameSizes = config.StandardFrameSizes(cfg.IncludeJumbo)
+// tuiConfigText summarizes the run's settings for the config page. It
+// lists settings rather than dumping the config, which holds resolved
+// secrets.
+func tuiConfigText(cfg *config.Config) string {
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	line := func(name, format string, a ...interface{}) {
+		fmt.Fprintf(w, "%s\t%s\n", name, fmt.Sprintf(format, a...))
+	}
+	line("Interface:", "%s", cfg.Interface)
+	if cfg.Ports.RX != "" {
+		line("RX interface:", "%s", cfg.Ports.RX)
 	}
+	line("Test type:", "%s", cfg.TestType)
+	line("Frame sizes:", "%v", cfg.TestFrameSizes())
+	if cfg.LineRateMbps > 0 {
+		line("Line rate:", "%d Mbps", cfg.LineRateMbps)
+	} else {
+		line("Line rate:", "auto-detect")
+	}
+	line("Trial duration:", "%s", cfg.TrialDuration)
+	line("Warmup:", "%s", cfg.WarmupPeriod)
+	switch cfg.TestType {
+	case config.TestThroughput:
+		line("Search:", "start %.1f%%, resolution %.2f%%, max %d iterations",
+			cfg.Throughput.InitialRatePct, cfg.Throughput.ResolutionPct, cfg.Throughput.MaxIterations)
+		line("Acceptable loss:", "%.4f%%", cfg.Throughput.AcceptableLoss)
+	case config.TestLatency:
+		line("Load levels:", "%v %%", cfg.Latency.LoadLevels)
+	case config.TestFrameLoss:
+		if fl := cfg.FrameLoss; fl.Search() {
+			line("PDR search:", "%.0f%% to %.0f%%, loss <= %.4g%%, resolution %.2f%%", fl.StartPct, fl.EndPct, fl.SearchThresholdPct, fl.SearchResolutionPct)
+		} else {
+			line("Load range:", "%.0f%% to %.0f%% by %.0f%%", fl.StartPct, fl.EndPct, fl.StepPct)
+		}
+	case config.TestBackToBack:
+		line("Bursts:", "from %d frames, %d trials", cfg.BackToBack.InitialBurst, cfg.BackToBack.Trials)
+	case config.TestY1564Config, config.TestY1564Perf, config.TestY1564Full:
+		for _, svc := range cfg.Y1564.Services {
+			if svc.Enabled {
+				line("Service:", "%d %s, CIR %.1f Mbps", svc.ServiceID, svc.ServiceName, svc.SLA.CIRMbps)
+			}
+		}
+	}
+	line("Latency:", "%v (hardware timestamps %v)", cfg.MeasureLatency, cfg.HWTimestamp)
+	line("Dataplane:", "%s", dataplane.Backend)
+	if caps, err := dataplane.QueryCapabilities(cfg.Interface, false); err == nil {
+		line("Max frame size:", "%d bytes", caps.MaxFrameSize)
+		if off := unavailableTests(caps); len(off) > 0 {
+			line("Unavailable:", "%s", strings.Join(off, ", "))
+		}
+	}
+	w.Flush()
+	return sb.String()
+}
 
-	for _, fs := range frameSizes {
-		if cancelled.Load() {
-			return
+// tuiHistory lists the JSON results files in dir for the history page,
+// newest first. Files that are not results are skipped.
+func tuiHistory(dir string) []tui.HistoryEntry {
+	if dir == "" {
+		dir = "."
+	}
+	paths, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	var entries []tui.HistoryEntry
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		run, err := compare.Load(path)
+		if err != nil {
+			continue
+		}
+		var sb strings.Builder
+		w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "Metric\tFrame\tValue")
+		for _, row := range compare.Build([]*compare.Run{run}).Rows {
+			frame := "-"
+			if row.FrameSize > 0 {
+				frame = strconv.FormatUint(uint64(row.FrameSize), 10)
+			}
+			if v := row.Values[0]; v != nil {
+				fmt.Fprintf(w, "%s\t%s\t%.4g\n", row.Metric, frame, *v)
+			}
+		}
+		w.Flush()
+		entries = append(entries, tui.HistoryEntry{
+			Time:     info.ModTime(),
+			Label:    run.Label,
+			TestType: run.TestType,
+			Source:   path,
+			Detail:   sb.String(),
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Time.After(entries[j].Time)
+	})
+	return entries
+}
+
+// runTUITests runs the test at each frame size until runCtx ends, under
+// the dataplane watchdog if enabled, which reopens the context with open.
+// Declining an operator prompt stops the run through stop.
+func runTUITests(runCtx context.Context, stop context.CancelFunc, app *tui.App, dpCtx *dataplane.Context, cfg *config.Config, open func() (*dataplane.Context, error)) {
+	var ctx backend.Local = dpCtx
+	watchdog := watchDataplane(dpCtx, cfg, open, func(t backend.InvalidTrial, restarts int) {
+		app.LogWarn("Dataplane restarted (%d so far); the %d byte %s is invalid", restarts, t.FrameSize, t.Test)
+	})
+	if watchdog != nil {
+		ctx = watchdog
+	}
+
+	liveCtx, stopLive := context.WithCancel(runCtx)
+	live := showTUIStats(app, dpCtx.Subscribe(liveCtx), cfg)
+	defer func() {
+		stopLive()
+		<-live
+		app.UpdateStats(tui.Stats{State: "Complete"})
+		if watchdog != nil {
+			watchdog.Close()
+		}
+		dpCtx.Close()
+	}()
+
+	for _, fs := range cfg.TestFrameSizes() {
+		if runCtx.Err() != nil {
+			return
+		}
+		if !tuiGate(runCtx, stop, app, cfg, func(g config.GateConfig) bool { return g.FrameSize == fs }) {
+			return
 		}
 
 		ctx.SetFrameSize(fs)
+		if err := ctx.SetAcceptableLoss(cfg.Throughput.AcceptableLossFor(cfg.TestType, fs)); err != nil {
+			app.LogError("Acceptable loss: %v", err)
+			continue
+		}
 		app.LogInfo("Testing %d byte frames...", fs)
 		app.UpdateStats(tui.Stats{
 			FrameSize: fs,
@@ -452,8 +2154,8 @@ func runTUITests(app *tui.App, ctx *dataplane.Context, cfg *config.Config, cance
 
 		switch cfg.TestType {
 		case config.TestThroughput:
-			app.LogInfo("Running throughput test...")
-			result, err := ctx.RunThroughputTest()
+			app.LogInfo("Running throughput test (%s)...", searchDescription(cfg.Throughput))
+			result, err := runThroughput(runCtx, ctx, cfg, cfg.TestType, fs)
 			if err != nil {
 				app.LogError("Throughput error: %v", err)
 				continue
@@ -471,7 +2173,7 @@ func runTUITests(app *tui.App, ctx *dataplane.Context, cfg *config.Config, cance
 
 		case config.TestLatency:
 			app.LogInfo("Running latency test...")
-			results, err := ctx.RunLatencyTest(cfg.Latency.LoadLevels)
+			results, err := ctx.RunLatencyTest(runCtx, cfg.Latency.LoadLevels)
 			if err != nil {
 				app.LogError("Latency error: %v", err)
 				continue
@@ -483,19 +2185,19 @@ func runTUITests(app *tui.App, ctx *dataplane.Context, cfg *config.Config, cance
 
 		case config.TestFrameLoss:
 			app.LogInfo("Running frame loss test...")
-			results, err := ctx.RunFrameLossTest(cfg.FrameLoss.StartPct, cfg.FrameLoss.EndPct, cfg.FrameLoss.StepPct)
+			results, err := runFrameLoss(runCtx, ctx, cfg)
 			if err != nil {
 				app.LogError("Frame loss error: %v", err)
 				continue
 			}
 			for _, r := range results {
-				app.LogInfo("Load %.0f%%: loss=%.4f%% (tx=%d rx=%d)",
-					r.OfferedPct, r.LossPct, r.FramesTx, r.FramesRx)
+				app.LogInfo("Load %.1f%%: loss=%.4f%% (tx=%d rx=%d)%s",
+					r.OfferedPct, r.LossPct, r.FramesTx, r.FramesRx, pdrMark(r))
 			}
 
 		case config.TestBackToBack:
 			app.LogInfo("Running back-to-back test...")
-			result, err := ctx.RunBackToBackTest(cfg.BackToBack.InitialBurst, cfg.BackToBack.Trials)
+			result, err := ctx.RunBackToBackTest(runCtx, cfg.BackToBack.InitialBurst, cfg.BackToBack.Trials)
 			if err != nil {
 				app.LogError("Back-to-back error: %v", err)
 				continue
@@ -503,18 +2205,88 @@ func runTUITests(app *tui.App, ctx *dataplane.Context, cfg *config.Config, cance
 			app.LogInfo("Max burst: %d frames (%.2f us)", result.MaxBurstFrames, float64(result.BurstDurationUs))
 
 		case config.TestY1564Config, config.TestY1564Perf, config.TestY1564Full:
-			runTUIY1564Tests(app, ctx, cfg, cancelled)
+			runTUIY1564Tests(runCtx, stop, app, ctx, cfg)
 		}
 	}
 
 	app.LogInfo("Test complete")
 }
 
-func runTUIY1564Tests(app *tui.App, ctx *dataplane.Context, cfg *config.Config, cancelled *atomic.Bool) {
+// showTUIStats shows the dataplane's live snapshots on the TUI until the
+// channel closes, which closes the returned channel
+func showTUIStats(app *tui.App, live <-chan dataplane.Stats, cfg *config.Config) <-chan struct{} {
+	frameSizes := cfg.TestFrameSizes()
+	maxIter := 0
+	if cfg.TestType == config.TestThroughput {
+		maxIter = int(cfg.Throughput.MaxIterations)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for s := range live {
+			app.UpdateStats(tui.Stats{
+				TestType:    tui.TestType(cfg.TestType),
+				FrameSize:   s.FrameSize,
+				Progress:    frameProgress(frameSizes, s.FrameSize),
+				State:       "Running",
+				Iteration:   int(s.Iteration),
+				MaxIter:     maxIter,
+				TxPackets:   s.TxPackets,
+				TxBytes:     s.TxBytes,
+				RxPackets:   s.RxPackets,
+				RxBytes:     s.RxBytes,
+				TxRate:      s.TxMbps,
+				RxRate:      s.RxMbps,
+				TxPPS:       s.TxPPS,
+				RxPPS:       s.RxPPS,
+				OfferedRate: s.CurrentRate,
+				LossPct:     s.LastLossPct,
+				LatencyMin:  s.LastLatency.MinNs,
+				LatencyMax:  s.LastLatency.MaxNs,
+				LatencyAvg:  s.LastLatency.AvgNs,
+				LatencyP99:  s.LastLatency.P99Ns,
+				Duration:    s.Elapsed,
+			})
+		}
+	}()
+	return done
+}
+
+// frameProgress is the share of the frame sizes done before fs, in percent
+func frameProgress(frameSizes []uint32, fs uint32) float64 {
+	for i, f := range frameSizes {
+		if f == fs {
+			return float64(i) / float64(len(frameSizes)) * 100
+		}
+	}
+	return 0
+}
+
+// tuiGate holds the run on each operator prompt configured for a phase. It
+// reports false, and cancels the run, if the operator declines.
+func tuiGate(runCtx context.Context, stop context.CancelFunc, app *tui.App, cfg *config.Config, before func(config.GateConfig) bool) bool {
+	for _, g := range cfg.Gates {
+		if !before(g) {
+			continue
+		}
+		app.LogInfo("Waiting for operator: %s", g.Message)
+		if !app.Prompt(g.Message) {
+			app.LogWarn("Cancelled at operator prompt")
+			stop()
+			return false
+		}
+	}
+	return runCtx.Err() == nil
+}
+
+func runTUIY1564Tests(runCtx context.Context, stop context.CancelFunc, app *tui.App, ctx backend.Local, cfg *config.Config) {
 	for _, svc := range cfg.Y1564.Services {
-		if cancelled.Load() || !svc.Enabled {
+		if runCtx.Err() != nil || !svc.Enabled {
 			continue
 		}
+		if !tuiGate(runCtx, stop, app, cfg, func(g config.GateConfig) bool { return g.ServiceID == svc.ServiceID }) {
+			return
+		}
 
 		app.LogInfo("Service %d: %s (CIR: %.2f Mbps)", svc.ServiceID, svc.ServiceName, svc.SLA.CIRMbps)
 
@@ -524,6 +2296,11 @@ func runTUIY1564Tests(app *tui.App, ctx *dataplane.Context, cfg *config.Config,
 			FrameSize:   svc.FrameSize,
 			CoS:         svc.CoS,
 			Enabled:     svc.Enabled,
+			VLANID:      svc.VLANID,
+			PCP:         svc.PCP,
+			OuterVLANID: svc.OuterVLANID,
+			OuterPCP:    svc.OuterPCP,
+			IPVersion:   svc.IPVersion,
 			SLA: dataplane.Y1564SLA{
 				CIRMbps:         svc.SLA.CIRMbps,
 				EIRMbps:         svc.SLA.EIRMbps,
@@ -532,13 +2309,16 @@ func runTUIY1564Tests(app *tui.App, ctx *dataplane.Context, cfg *config.Config,
 				FDThresholdMs:   svc.SLA.FDThresholdMs,
 				FDVThresholdMs:  svc.SLA.FDVThresholdMs,
 				FLRThresholdPct: svc.SLA.FLRThresholdPct,
+				EIRSAC:          stepSAC(svc.SLA, svc.SLA.EIRSAC),
+				PolicingSAC:     stepSAC(svc.SLA, svc.SLA.PolicingSAC),
 			},
+			PolicingStep: cfg.Y1564.PolicingStep,
 		}
 
 		// Config test
 		if cfg.TestType == config.TestY1564Config || cfg.TestType == config.TestY1564Full {
 			app.LogInfo("Running Configuration Test...")
-			result, err := ctx.RunY1564ConfigTest(dpSvc)
+			result, err := ctx.RunY1564ConfigTest(runCtx, dpSvc)
 			if err != nil {
 				app.LogError("Config test error: %v", err)
 			} else {
@@ -548,8 +2328,8 @@ func runTUIY1564Tests(app *tui.App, ctx *dataplane.Context, cfg *config.Config,
 				}
 				app.LogInfo("Config Test: %s", passStr)
 				for _, step := range result.Steps {
-					app.LogInfo("  Step %d: FLR=%.4f%% FD=%.2fms FDV=%.2fms",
-						step.Step, step.FLRPct, step.FDAvgMs, step.FDVMs)
+					app.LogInfo("  Step %d (%s): FLR=%.4f%% FD=%.2fms FDV=%.2fms",
+						step.Step, step.Phase, step.FLRPct, step.FDAvgMs, step.FDVMs)
 				}
 			}
 		}
@@ -558,7 +2338,7 @@ func runTUIY1564Tests(app *tui.App, ctx *dataplane.Context, cfg *config.Config,
 		if cfg.TestType == config.TestY1564Perf || cfg.TestType == config.TestY1564Full {
 			durationSec := uint32(cfg.Y1564.PerfDuration.Seconds())
 			app.LogInfo("Running Performance Test (%d min)...", durationSec/60)
-			result, err := ctx.RunY1564PerfTest(dpSvc, durationSec)
+			result, err := ctx.RunY1564PerfTest(runCtx, dpSvc, durationSec)
 			if err != nil {
 				app.LogError("Perf test error: %v", err)
 			} else {
@@ -576,15 +2356,179 @@ func runTUIY1564Tests(app *tui.App, ctx *dataplane.Context, cfg *config.Config,
 // Active test context for web mode
 var (
 	webDpCtx    *dataplane.Context
+	webDpWatch  *backend.Watchdog // Restarts webDpCtx if it wedges, if enabled
 	webDpMu     sync.Mutex
 	webTestDone chan struct{}
+	webStopTest context.CancelFunc // Ends the running test's context
+	webSoakRate chan float64       // Retunes the running soak, if one is
+)
+
+// Daemon configuration for web mode. Each test starts from it; a reload
+// of the config file or POST /api/config replaces it.
+var (
+	webConfig   *config.Config
+	webConfigMu sync.Mutex
 )
 
-func runWebOnly(cfg *config.Config, sigCh chan os.Signal) {
-	srv := web.New(cfg.WebUI.Address)
+// configSettle is how long web mode lets edits to the config file settle
+// before reloading it
+const configSettle = 200 * time.Millisecond
+
+// webDaemonConfig returns the current daemon configuration
+func webDaemonConfig() *config.Config {
+	webConfigMu.Lock()
+	defer webConfigMu.Unlock()
+	return webConfig
+}
+
+// closeWebDataplane closes the last web test's dataplane, and the context
+// the watchdog replaced it with after a restart. webDpMu must be held.
+func closeWebDataplane() {
+	if webDpWatch != nil {
+		webDpWatch.Close()
+	}
+	webDpCtx.Close()
+	webDpCtx, webDpWatch = nil, nil
+}
+
+// webTestRunning reports whether the dataplane is running a test
+func webTestRunning() bool {
+	webDpMu.Lock()
+	defer webDpMu.Unlock()
+	if webDpCtx == nil {
+		return false
+	}
+	select {
+	case <-webTestDone:
+		return false
+	default:
+		return true
+	}
+}
+
+// reloadWebConfig loads a new daemon configuration, with data in place of
+// the config file unless nil, and swaps it in if the running daemon can
+// apply it. It returns the top-level keys that changed.
+func reloadWebConfig(load func(data []byte) (*config.Config, error), data []byte) ([]string, error) {
+	next, err := load(data)
+	if err != nil {
+		return nil, err
+	}
+
+	webConfigMu.Lock()
+	defer webConfigMu.Unlock()
+	changed, err := config.CheckReload(webConfig, next, webTestRunning())
+	if err != nil {
+		return changed, fmt.Errorf("%w: %v", web.ErrReloadRefused, err)
+	}
+	webConfig = next
+	return changed, nil
+}
+
+// changedKeys lists reloaded config keys for the log
+func changedKeys(changed []string) string {
+	if len(changed) == 0 {
+		return "nothing"
+	}
+	return strings.Join(changed, ", ")
+}
+
+// webY1564 converts the daemon's Y.1564 services for a web run
+func webY1564(y config.Y1564Config) *web.Y1564Config {
+	wy := &web.Y1564Config{
+		ConfigSteps:     y.ConfigSteps,
+		StepDurationSec: int(y.StepDuration / time.Second),
+		PerfDurationMin: int(y.PerfDuration / time.Minute),
+		RunConfigTest:   y.RunConfigTest,
+		RunPerfTest:     y.RunPerfTest,
+	}
+	for _, svc := range y.Services {
+		wy.Services = append(wy.Services, web.Y1564Service{
+			ServiceID:   svc.ServiceID,
+			ServiceName: svc.ServiceName,
+			FrameSize:   svc.FrameSize,
+			CoS:         svc.CoS,
+			Enabled:     svc.Enabled,
+			VLANID:      svc.VLANID,
+			PCP:         svc.PCP,
+			OuterVLANID: svc.OuterVLANID,
+			OuterPCP:    svc.OuterPCP,
+			SLA: web.Y1564SLA{
+				CIRMbps:         svc.SLA.CIRMbps,
+				EIRMbps:         svc.SLA.EIRMbps,
+				CBSBytes:        svc.SLA.CBSBytes,
+				EBSBytes:        svc.SLA.EBSBytes,
+				FDThresholdMs:   svc.SLA.FDThresholdMs,
+				FDVThresholdMs:  svc.SLA.FDVThresholdMs,
+				FLRThresholdPct: svc.SLA.FLRThresholdPct,
+			},
+		})
+	}
+	return wy
+}
+
+// runWebOnly serves the Web UI and API. load rebuilds the configuration
+// from its layers for a reload.
+func runWebOnly(cfg *config.Config, sigCh chan os.Signal, load func(data []byte) (*config.Config, error)) {
+	webConfigMu.Lock()
+	webConfig = cfg
+	webConfigMu.Unlock()
+
+	opts := []web.Option{web.WithSchemas(publishedSchemas()), web.WithHealth(healthInfo())}
+	if cfg.WebUI.ShareSecret != "" {
+		opts = append(opts, web.WithShareSecret([]byte(cfg.WebUI.ShareSecret)))
+	}
+	if cfg.WebUI.PublicURL != "" {
+		opts = append(opts, web.WithPublicURL(cfg.WebUI.PublicURL))
+	}
+	srv := web.New(cfg.WebUI.Address, opts...)
+
+	// Settings a start request leaves unset come from the daemon config
+	srv.Defaults = func(webCfg *web.Config) {
+		cfg := webDaemonConfig()
+		if webCfg.Interface == "" {
+			webCfg.Interface = cfg.Interface
+		}
+		if webCfg.TrialDuration == 0 {
+			webCfg.TrialDuration = cfg.TrialDuration
+		}
+		if webCfg.LineRateMbps == 0 {
+			webCfg.LineRateMbps = cfg.LineRateMbps
+		}
+		if webCfg.InitialRatePct == 0 {
+			webCfg.InitialRatePct = cfg.Throughput.InitialRatePct
+		}
+		if webCfg.ResolutionPct == 0 {
+			webCfg.ResolutionPct = cfg.Throughput.ResolutionPct
+		}
+		switch webCfg.TestType {
+		case getTestTypeInt(config.TestY1564Config), getTestTypeInt(config.TestY1564Perf), getTestTypeInt(config.TestY1564Full):
+			if webCfg.Y1564 == nil && len(cfg.Y1564.Services) > 0 {
+				webCfg.Y1564 = webY1564(cfg.Y1564)
+			}
+		}
+	}
+
+	srv.OnCapabilities = func(iface string) (web.Capabilities, error) {
+		if iface == "" {
+			iface = webDaemonConfig().Interface
+		}
+		return webCapabilities(iface)
+	}
+
+	srv.OnReload = func(data []byte) ([]string, error) {
+		changed, err := reloadWebConfig(load, data)
+		if err != nil {
+			log.Printf("[main] Config reload via API rejected: %v", err)
+		} else {
+			log.Printf("[main] Config reloaded via API; changed: %s", changedKeys(changed))
+		}
+		return changed, err
+	}
 
 	srv.OnStart = func(webCfg web.Config) error {
 		log.Printf("[main] Starting test: %+v", webCfg)
+		cfg := webDaemonConfig()
 
 		// Convert web config to dataplane config
 		dpCfg := dataplane.Config{
@@ -595,41 +2539,119 @@ func runWebOnly(cfg *config.Config, sigCh chan os.Signal) {
 			FrameSize:      webCfg.FrameSize,
 			IncludeJumbo:   webCfg.IncludeJumbo,
 			TrialDuration:  webCfg.TrialDuration,
-			WarmupPeriod:   2 * time.Second,
-			InitialRatePct: 100.0,
-			ResolutionPct:  0.1,
-			MaxIterations:  20,
-			AcceptableLoss: 0.0,
-			HWTimestamp:    webCfg.HWTimestamp,
+			WarmupPeriod:   cfg.WarmupPeriod,
+			InitialRatePct: webCfg.InitialRatePct,
+			ResolutionPct:  webCfg.ResolutionPct,
+			MaxIterations:  cfg.Throughput.MaxIterations,
+			AcceptableLoss: cfg.Throughput.AcceptableLoss,
+			HWTimestamp:    webCfg.HWTimestamp || cfg.HWTimestamp,
 			MeasureLatency: true,
+
+			LatencyHistogram: cfg.LatencyHistogram,
+			LatencySamples:   cfg.LatencySampleCapacity,
+			VerifyPayload:    cfg.VerifyPayload,
+			Streams:          cfg.Addressing.Streams,
+			VLANID:           cfg.Framing.VLANID,
+			PCP:              cfg.Framing.PCP,
+			OuterVLANID:      cfg.Framing.OuterVLANID,
+			OuterPCP:         cfg.Framing.OuterPCP,
+			OuterTPID:        cfg.Framing.OuterTPID,
+			TPID:             cfg.Framing.TPID,
+			MPLSLabels:       dataplaneLabels(cfg.Framing.MPLSLabels),
+			MPLSEXPFromCoS:   cfg.Framing.EXPFromCoS,
+			LatencyProbe:     dataplaneProbe(cfg.LatencyProbe),
+			IP:               dataplaneIP(cfg),
+			Headers:          dataplaneHeaders(cfg),
+			Payload:          dataplanePayload(cfg),
+			Capture:          dataplaneCapture(cfg),
+			SpeedProfile:     cfg.SpeedProfile,
+		}
+		// The daemon's port pair applies to tests on its transmit interface
+		if cfg.Ports.Enabled() && webCfg.Interface == cfg.Interface {
+			dpCfg.RXInterface = cfg.Ports.RX
+		}
+		if webCfg.Soak != nil {
+			// The soak's samples are fixed-rate throughput trials
+			if dpCfg.TestType != dataplane.TestThroughput {
+				return fmt.Errorf("a soak runs throughput trials, not test type %d", webCfg.TestType)
+			}
+			if err := webSoakConfig(cfg, webCfg).Validate(); err != nil {
+				return err
+			}
+		}
+		if caps, err := dataplane.QueryCapabilities(dpCfg.Interface, false); err == nil {
+			if reason := caps.Unsupported(dpCfg.TestType); reason != "" {
+				return fmt.Errorf("%s%s", reason, pureGoHint())
+			}
+		}
+		if err := webInService(cfg, webCfg); err != nil {
+			return err
 		}
 
 		var err error
 		webDpMu.Lock()
+		if webDpCtx != nil {
+			// Campaigns start tests back to back; release the last one's
+			// interface first, but never run two at once
+			select {
+			case <-webTestDone:
+				closeWebDataplane()
+			default:
+				webDpMu.Unlock()
+				return fmt.Errorf("a test is already running")
+			}
+		}
 		webDpCtx, err = dataplane.New(dpCfg)
 		if err != nil {
 			webDpMu.Unlock()
 			return fmt.Errorf("init dataplane: %w", err)
 		}
+		webDpWatch = watchDataplane(webDpCtx, cfg, func() (*dataplane.Context, error) {
+			return dataplane.New(dpCfg)
+		}, func(t backend.InvalidTrial, restarts int) {
+			log.Printf("[main] Dataplane restarted (%d so far); the %d byte %s is invalid", restarts, t.FrameSize, t.Test)
+		})
 		webTestDone = make(chan struct{})
+		runCtx, stop := context.WithCancel(context.Background())
+		webStopTest = stop
 		webDpMu.Unlock()
 
 		// Run test in background
-		go runWebTest(srv, webCfg)
+		go runWebTest(runCtx, srv, webCfg, cfg)
 
 		return nil
 	}
 
+	srv.OnSoakRate = func(ratePct float64) error {
+		webDpMu.Lock()
+		defer webDpMu.Unlock()
+		if webSoakRate == nil {
+			return web.ErrNoSoak
+		}
+		// Replace a rate the soak has yet to take
+		for {
+			select {
+			case webSoakRate <- ratePct:
+				log.Printf("[main] Soak retuned via API to %.1f%%", ratePct)
+				return nil
+			default:
+				select {
+				case <-webSoakRate:
+				default:
+				}
+			}
+		}
+	}
+
 	srv.OnStop = func() error {
 		log.Printf("[main] Stopping test")
 		webDpMu.Lock()
 		if webDpCtx != nil {
-			webDpCtx.Cancel()
+			webStopTest()
 			webDpMu.Unlock()
 			<-webTestDone // Wait for test to finish
 			webDpMu.Lock()
-			webDpCtx.Close()
-			webDpCtx = nil
+			closeWebDataplane()
 		}
 		webDpMu.Unlock()
 		return nil
@@ -639,11 +2661,30 @@ func runWebOnly(cfg *config.Config, sigCh chan os.Signal) {
 		log.Printf("[main] Cancelling test")
 		webDpMu.Lock()
 		if webDpCtx != nil {
-			webDpCtx.Cancel()
+			webStopTest()
 		}
 		webDpMu.Unlock()
 	}
 
+	// Apply edits to the config file without a restart
+	watchCtx, stopWatch := context.WithCancel(context.Background())
+	defer stopWatch()
+	if cfgFile != "" {
+		go func() {
+			err := config.Watch(watchCtx, cfgFile, configSettle, func() {
+				changed, err := reloadWebConfig(load, nil)
+				if err != nil {
+					log.Printf("[main] Config file changed; reload rejected: %v", err)
+					return
+				}
+				log.Printf("[main] Config file reloaded; changed: %s", changedKeys(changed))
+			})
+			if err != nil {
+				log.Printf("[main] Not watching the config file for changes: %v", err)
+			}
+		}()
+	}
+
 	// Handle signals
 	go func() {
 		<-sigCh
@@ -659,57 +2700,167 @@ func runWebOnly(cfg *config.Config, sigCh chan os.Signal) {
 	}
 }
 
-func runWebTest(srv *web.Server, webCfg web.Config) {
+// webTestNames names the web UI's tests as its results do
+var webTestNames = map[dataplane.TestType]string{
+	dataplane.TestThroughput: "throughput",
+	dataplane.TestLatency:    "latency",
+	dataplane.TestFrameLoss:  "frame_loss",
+	dataplane.TestBackToBack: "back_to_back",
+}
+
+// webTestName names a web start request's test as its results do
+func webTestName(webCfg web.Config) string {
+	if webCfg.Soak != nil {
+		return "soak"
+	}
+	return webTestNames[dataplane.TestType(webCfg.TestType)]
+}
+
+// webSoakConfig is the daemon configuration cfg for the soak a web start
+// request asks for, with the request's interface, rate and duration
+func webSoakConfig(cfg *config.Config, webCfg web.Config) *config.Config {
+	soak := *cfg
+	soak.Interface, soak.TestType = webCfg.Interface, config.TestSoak
+	if webCfg.Soak.RatePct != 0 {
+		soak.Soak.RatePct = webCfg.Soak.RatePct
+	}
+	if webCfg.Soak.Duration != 0 {
+		soak.Soak.Duration = webCfg.Soak.Duration
+	}
+	return &soak
+}
+
+// showWebStats serves the dataplane's live snapshots on /api/stats until
+// the channel closes, which closes the returned channel
+func showWebStats(srv *web.Server, live <-chan dataplane.Stats, test string, frameSizes []uint32) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for s := range live {
+			srv.UpdateStats(web.Stats{
+				TestType:    test,
+				FrameSize:   s.FrameSize,
+				State:       web.StatusRunning,
+				Progress:    frameProgress(frameSizes, s.FrameSize),
+				Iteration:   int(s.Iteration),
+				TxPackets:   s.TxPackets,
+				TxBytes:     s.TxBytes,
+				RxPackets:   s.RxPackets,
+				RxBytes:     s.RxBytes,
+				TxRate:      s.TxMbps,
+				RxRate:      s.RxMbps,
+				TxPPS:       s.TxPPS,
+				RxPPS:       s.RxPPS,
+				OfferedRate: s.CurrentRate,
+				LossPct:     s.LastLossPct,
+				LatencyMin:  s.LastLatency.MinNs,
+				LatencyMax:  s.LastLatency.MaxNs,
+				LatencyAvg:  s.LastLatency.AvgNs,
+				LatencyP99:  s.LastLatency.P99Ns,
+				Uptime:      s.Elapsed.Seconds(),
+				Timestamp:   s.Timestamp.Unix(),
+
+				ReorderedFrames: s.LastOrder.ReorderedFrames,
+				DuplicateFrames: s.LastOrder.DuplicateFrames,
+				MaxReorderDepth: s.LastOrder.MaxReorderDepth,
+				CorruptedFrames: s.LastOrder.CorruptedFrames,
+
+				InTrial:       s.InTrial,
+				Warmup:        s.Warmup,
+				TrialProgress: s.TrialProgress,
+			})
+		}
+	}()
+	return done
+}
+
+// addFrameOrder adds a web result's reorder, duplicate and corrupted
+// counts, when the DUT reordered, duplicated or mangled any frames
+func addFrameOrder(data map[string]interface{}, o dataplane.FrameOrder) {
+	if o == (dataplane.FrameOrder{}) {
+		return
+	}
+	data["reordered_frames"] = o.ReorderedFrames
+	data["duplicate_frames"] = o.DuplicateFrames
+	data["max_reorder_depth"] = o.MaxReorderDepth
+	data["corrupted_frames"] = o.CorruptedFrames
+}
+
+// runWebTest runs the test started from the web UI until runCtx ends
+func runWebTest(runCtx context.Context, srv *web.Server, webCfg web.Config, cfg *config.Config) {
 	defer func() {
 		close(webTestDone)
 		srv.UpdateStatus(web.StatusComplete, "Test complete", 100)
 	}()
 
 	webDpMu.Lock()
-	ctx := webDpCtx
+	dpCtx, watchdog := webDpCtx, webDpWatch
 	webDpMu.Unlock()
-	if ctx == nil {
+	if dpCtx == nil {
 		return
 	}
+	var ctx backend.Backend = dpCtx
+	if watchdog != nil {
+		ctx = watchdog
+	}
 
 	frameSizes := []uint32{webCfg.FrameSize}
 	if webCfg.FrameSize == 0 {
 		frameSizes = config.StandardFrameSizes(webCfg.IncludeJumbo)
 	}
 
+	// Stop the live stats before the final status is set
+	liveCtx, stopLive := context.WithCancel(runCtx)
+	live := showWebStats(srv, dpCtx.Subscribe(liveCtx), webTestName(webCfg), frameSizes)
+	defer func() {
+		stopLive()
+		<-live
+	}()
+
 	totalSteps := len(frameSizes)
 	currentStep := 0
 
 	for _, fs := range frameSizes {
 		ctx.SetFrameSize(fs)
 		pct := float64(currentStep) / float64(totalSteps) * 100
+		if err := ctx.SetAcceptableLoss(cfg.Throughput.AcceptableLossFor(config.TestThroughput, fs)); err != nil {
+			srv.UpdateStatus(web.StatusError, fmt.Sprintf("Error: %v", err), pct)
+			return
+		}
 		srv.UpdateStatus(web.StatusRunning, fmt.Sprintf("Testing %d byte frames", fs), pct)
 
 		switch dataplane.TestType(webCfg.TestType) {
 		case dataplane.TestThroughput:
-			result, err := ctx.RunThroughputTest()
+			if webCfg.Soak != nil {
+				if err := runWebSoak(runCtx, srv, ctx, webCfg, cfg, fs); err != nil {
+					srv.UpdateStatus(web.StatusError, fmt.Sprintf("Error: %v", err), pct)
+					return
+				}
+				break
+			}
+			result, err := runThroughput(runCtx, ctx, cfg, config.TestThroughput, fs)
 			if err != nil {
 				srv.UpdateStatus(web.StatusError, fmt.Sprintf("Error: %v", err), pct)
 				return
 			}
+			data := map[string]interface{}{
+				"max_rate_pct":  result.MaxRatePct,
+				"max_rate_mbps": result.MaxRateMbps,
+				"max_rate_pps":  result.MaxRatePPS,
+				"iterations":    result.Iterations,
+				"latency_avg":   result.Latency.AvgNs,
+				"latency_min":   result.Latency.MinNs,
+				"latency_max":   result.Latency.MaxNs,
+			}
+			addFrameOrder(data, result.FrameOrder)
 			srv.AddResult(web.TestResult{
 				TestType:  "throughput",
 				FrameSize: fs,
-				Data: map[string]interface{}{
-					"max_rate_pct":  result.MaxRatePct,
-					"max_rate_mbps": result.MaxRateMbps,
-					"max_rate_pps":  result.MaxRatePPS,
-					"iterations":    result.Iterations,
-					"latency_avg":   result.Latency.AvgNs,
-					"latency_min":   result.Latency.MinNs,
-					"latency_max":   result.Latency.MaxNs,
-				},
+				Data:      data,
 			})
 
 		case dataplane.TestLatency:
-			// Default load levels
-			loadLevels := []float64{10, 20, 30, 40, 50, 60, 70, 80, 90, 100}
-			results, err := ctx.RunLatencyTest(loadLevels)
+			results, err := ctx.RunLatencyTest(runCtx, cfg.Latency.LoadLevels)
 			if err != nil {
 				srv.UpdateStatus(web.StatusError, fmt.Sprintf("Error: %v", err), pct)
 				return
@@ -729,26 +2880,31 @@ func runWebTest(srv *web.Server, webCfg web.Config) {
 			}
 
 		case dataplane.TestFrameLoss:
-			results, err := ctx.RunFrameLossTest(100, 10, 10)
+			results, err := runFrameLoss(runCtx, ctx, cfg)
 			if err != nil {
 				srv.UpdateStatus(web.StatusError, fmt.Sprintf("Error: %v", err), pct)
 				return
 			}
 			for _, r := range results {
+				data := map[string]interface{}{
+					"offered_pct": r.OfferedPct,
+					"frames_tx":   r.FramesTx,
+					"frames_rx":   r.FramesRx,
+					"loss_pct":    r.LossPct,
+				}
+				if r.PartialDropRate {
+					data["partial_drop_rate"] = true
+				}
+				addFrameOrder(data, r.FrameOrder)
 				srv.AddResult(web.TestResult{
 					TestType:  "frame_loss",
 					FrameSize: fs,
-					Data: map[string]interface{}{
-						"offered_pct": r.OfferedPct,
-						"frames_tx":   r.FramesTx,
-						"frames_rx":   r.FramesRx,
-						"loss_pct":    r.LossPct,
-					},
+					Data:      data,
 				})
 			}
 
 		case dataplane.TestBackToBack:
-			result, err := ctx.RunBackToBackTest(1000, 50)
+			result, err := ctx.RunBackToBackTest(runCtx, cfg.BackToBack.InitialBurst, cfg.BackToBack.Trials)
 			if err != nil {
 				srv.UpdateStatus(web.StatusError, fmt.Sprintf("Error: %v", err), pct)
 				return
@@ -768,472 +2924,5245 @@ func runWebTest(srv *web.Server, webCfg web.Config) {
 	}
 }
 
-func runCLI(cfg *config.Config, sigCh chan os.Signal) {
-	fmt.Printf("RFC2544 Test Master v%s\n", version)
-	fmt.Printf("Interface: %s\n", cfg.Interface)
-	fmt.Printf("Test: %s\n", cfg.TestType)
-	fmt.Println()
+// runWebSoak runs the soak webCfg asks for at fs-byte frames under the
+// daemon config cfg, and adds its result. A reload of the daemon config or
+// POST /api/soak/rate retunes it while it runs.
+func runWebSoak(runCtx context.Context, srv *web.Server, ctx backend.Backend, webCfg web.Config, cfg *config.Config, fs uint32) error {
+	rates := make(chan float64, 1)
+	webDpMu.Lock()
+	webSoakRate = rates
+	webDpMu.Unlock()
+	defer func() {
+		webDpMu.Lock()
+		webSoakRate = nil
+		webDpMu.Unlock()
+	}()
 
-	// Get frame sizes to test
-	frameSizes := []uint32{cfg.FrameSize}
-	if cfg.FrameSize == 0 {
-		frameSizes = config.StandardFrameSizes(cfg.IncludeJumbo)
+	retune := &soakRetune{base: cfg, live: webDaemonConfig, rates: rates}
+	result, err := runSoakTest(runCtx, ctx, webSoakConfig(cfg, webCfg), fs, retune)
+	if err != nil {
+		return err
 	}
+	srv.AddResult(web.TestResult{
+		TestType:  "soak",
+		FrameSize: fs,
+		Data: map[string]interface{}{
+			"samples":              result.Samples,
+			"buckets":              len(result.Buckets),
+			"throughput_drift_pct": result.Summary.ThroughputDriftPct,
+			"p99_drift_pct":        result.Summary.P99DriftPct,
+			"degraded":             result.Summary.Degraded,
+			"verdict":              result.Summary.Verdict,
+			"changes":              result.Changes,
+		},
+	})
+	return nil
+}
 
-	fmt.Printf("Testing frame sizes: %v\n", frameSizes)
-	fmt.Printf("Trial duration: %v\n", cfg.TrialDuration)
-	fmt.Println()
+// attachTimeout bounds the requests to a remote instance that should
+// answer at once
+const attachTimeout = 10 * time.Second
 
-	// Initialize dataplane context
-	dpCfg := dataplane.Config{
-		Interface:      cfg.Interface,
-		LineRate:       cfg.LineRateMbps * 1000000, // Convert to bps
-		AutoDetect:     cfg.AutoDetect,
-		TestType:       dataplane.TestType(int(getTestTypeInt(cfg.TestType))),
-		FrameSize:      cfg.FrameSize,
-		IncludeJumbo:   cfg.IncludeJumbo,
-		TrialDuration:  cfg.TrialDuration,
-		WarmupPeriod:   cfg.WarmupPeriod,
-		InitialRatePct: cfg.Throughput.InitialRatePct,
-		ResolutionPct:  cfg.Throughput.ResolutionPct,
-		MaxIterations:  cfg.Throughput.MaxIterations,
-		AcceptableLoss: cfg.Throughput.AcceptableLoss,
-		HWTimestamp:    cfg.HWTimestamp,
-		MeasureLatency: cfg.MeasureLatency,
+// attachRetry is the longest wait between attempts to reconnect to a
+// remote instance's event stream
+const attachRetry = 10 * time.Second
+
+// attachTestTypes maps the web API's test names to the TUI's
+var attachTestTypes = map[string]tui.TestType{
+	"throughput":   tui.TestThroughput,
+	"latency":      tui.TestLatency,
+	"frame_loss":   tui.TestFrameLoss,
+	"back_to_back": tui.TestBackToBack,
+}
+
+// demoReplayStep is the pause between the trials of a replayed sample run
+const demoReplayStep = 300 * time.Millisecond
+
+// demoSample is a bundled sample run with its results decoded
+type demoSample = demo.Sample[jsonReport]
+
+// decodeReport decodes results -o json wrote, typing them as the run kept
+// them
+func decodeReport(data []byte) (jsonReport, error) {
+	var doc struct {
+		jsonReport
+		Results []json.RawMessage `json:"results"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return jsonReport{}, err
+	}
+	report := doc.jsonReport
+	for _, raw := range doc.Results {
+		result, err := decodeResult(report.Metadata.TestType, raw)
+		if err != nil {
+			return jsonReport{}, err
+		}
+		report.Results = append(report.Results, result)
 	}
+	return report, nil
+}
 
-	ctx, err := dataplane.New(dpCfg)
+// runDemo shows a bundled sample run in the TUI, the Web UI or as results
+// output. It opens no interface and sends no traffic.
+func runDemo(cmd *cobra.Command, args []string) error {
+	if demoDir != "" {
+		return writeDemo(demoDir)
+	}
+	samples, err := demo.Load(decodeReport)
 	if err != nil {
-		log.Fatalf("Failed to initialize dataplane: %v", err)
+		return err
+	}
+	sample := demo.Find(samples, demoRun)
+	if sample == nil {
+		return fmt.Errorf("unknown demo run %q (%s)", demoRun, strings.Join(demo.Names(), ", "))
+	}
+	cfg, err := demo.Config()
+	if err != nil {
+		return err
+	}
+	if dut := sample.Report.Metadata.DUT; dut != nil {
+		cfg.DUT = *dut
+	}
+	if tuiPlain {
+		useTUI = true
+		cfg.TUI.Plain = true
+	}
+	if webAddr != "" {
+		cfg.WebUI.Enabled = true
+		cfg.WebUI.Address = webAddr
 	}
-	defer ctx.Close()
-
-	// Handle cancel
-	var cancelled atomic.Bool
-	go func() {
-		<-sigCh
-		cancelled.Store(true)
-		fmt.Println("\nCancelling...")
-		ctx.Cancel()
-	}()
 
-	// Results storage
-	var allResults []interface{}
+	switch {
+	case useTUI:
+		return runDemoTUI(cfg, samples, sample)
+	case cfg.WebUI.Enabled:
+		return runDemoWeb(cfg, samples, sample)
+	case outputFormat == "pdf" && outputFile == "":
+		return fmt.Errorf("-o pdf needs --output-file")
+	case outputFormat == "text":
+		printDemoRun(sample)
+	}
+	return outputResults(sample.Report, cfg)
+}
 
-	// Run tests
-	for _, fs := range frameSizes {
-		if cancelled.Load() {
-			break
+// writeDemo extracts the sample runs and their config file to dir
+func writeDemo(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	files := []demo.Run{{Name: "demo.yaml", Data: demo.ConfigYAML()}}
+	for _, r := range append(files, demo.Runs()...) {
+		path := filepath.Join(dir, r.Name)
+		if err := os.WriteFile(path, r.Data, 0644); err != nil {
+			return err
 		}
+		fmt.Printf("Wrote %s\n", path)
+	}
+	return nil
+}
 
-		fmt.Printf("\nTesting %d byte frames...\n", fs)
-		ctx.SetFrameSize(fs)
+// printDemoRun prints a sample run as the run printed it
+func printDemoRun(sample *demoSample) {
+	r := sample.Report
+	fmt.Printf("Demo: sample run %s of %s on %s, %s (no traffic sent)\n",
+		sample.Name, r.Metadata.DUT.Label(), r.Metadata.Started.Format("2006-01-02 15:04"), r.Metadata.TestType)
+	for _, result := range r.Results {
+		s, ok := result.(*suiteResult)
+		if !ok {
+			continue
+		}
+		fmt.Printf("\nFrame size %d bytes:\n", s.FrameSize)
+		if s.Throughput != nil {
+			printThroughputResult(s.Throughput, s.FrameSize)
+		}
+		if s.Latency != nil {
+			printLatencyResults(s.Latency, s.FrameSize)
+		}
+		if s.FrameLoss != nil {
+			printFrameLossResults(s.FrameLoss, s.FrameSize)
+		}
+		if s.BackToBack != nil {
+			printBackToBackResult(s.BackToBack, s.FrameSize)
+		}
+		if s.Recovery != nil {
+			printRecoveryResult(s.Recovery, s.FrameSize)
+		}
+		if s.Reset != nil {
+			printResetResult(s.Reset, s.FrameSize)
+		}
+		printSuiteResult(s)
+	}
+	printLatencyCurve(r.LatencyCurve)
+	printDeviations(r.Deviations)
+	printCompliance(r.Compliance)
+	printThresholds(r.Thresholds)
+}
 
-		switch cfg.TestType {
-		case config.TestThroughput:
-			fmt.Printf("  Running throughput test (binary search)...\n")
-			result, err := ctx.RunThroughputTest()
-			if err != nil {
-				log.Printf("  Error: %v", err)
-				continue
+// replayDemo steps through a sample run's throughput searches, calling
+// trial for each trial and done with each frame size's results, until ctx
+// ends. It reports whether the replay finished.
+func replayDemo(ctx context.Context, report jsonReport, trial func(s *suiteResult, i int, t dataplane.ThroughputTrial), done func(s *suiteResult)) bool {
+	pause := func() bool {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(demoReplayStep):
+			return true
+		}
+	}
+	for _, result := range report.Results {
+		s, ok := result.(*suiteResult)
+		if !ok {
+			continue
+		}
+		if s.Throughput != nil {
+			for i, t := range s.Throughput.Trials {
+				trial(s, i, t)
+				if !pause() {
+					return false
+				}
 			}
-			printThroughputResult(result, fs)
-			allResults = append(allResults, result)
+		}
+		done(s)
+		if !pause() {
+			return false
+		}
+	}
+	return true
+}
 
-		case config.TestLatency:
-			fmt.Printf("  Running latency test...\n")
-			results, err := ctx.RunLatencyTest(cfg.Latency.LoadLevels)
-			if err != nil {
-				log.Printf("  Error: %v", err)
-				continue
+// acceptedLoss is the loss of the throughput search's trial at the rate it
+// found
+func acceptedLoss(t *dataplane.ThroughputResultCLI) float64 {
+	for _, trial := range t.Trials {
+		if trial.Passed && trial.RatePct == t.MaxRatePct {
+			return trial.LossPct
+		}
+	}
+	return 0
+}
+
+// runDemoTUI opens the TUI on the sample runs: both on the history page,
+// and the selected one replayed on start
+func runDemoTUI(cfg *config.Config, samples []demoSample, sample *demoSample) error {
+	history, err := os.MkdirTemp("", "rfc2544-demo-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(history)
+	for _, s := range samples {
+		path := filepath.Join(history, s.Name+".json")
+		if err := os.WriteFile(path, s.Data, 0644); err != nil {
+			return err
+		}
+		// The history page dates runs by their file
+		started := s.Report.Metadata.Started
+		os.Chtimes(path, started, started)
+	}
+
+	app := tui.New(tuiOptions(cfg)...)
+	app.SetWiring(wiringDiagram(cfg).String())
+	app.SetConfig(tuiConfigText(cfg))
+	app.OnHistory = func() []tui.HistoryEntry {
+		return tuiHistory(history)
+	}
+
+	frameSizes := cfg.TestFrameSizes()
+	var stopReplay context.CancelFunc
+	app.OnStart = func() {
+		if stopReplay != nil {
+			stopReplay()
+		}
+		var replayCtx context.Context
+		replayCtx, stopReplay = context.WithCancel(context.Background())
+		app.ClearResults()
+		app.ShowPage(tui.PageLive)
+		app.LogWarn("Demo: replaying sample run %s of %s; no traffic is sent", sample.Name, cfg.DUT.Label())
+		app.UpdateStats(tui.Stats{
+			TestType:  tui.TestType(cfg.TestType),
+			State:     "Running",
+			StartTime: time.Now(),
+		})
+		go func() {
+			finished := replayDemo(replayCtx, sample.Report, func(s *suiteResult, i int, t dataplane.ThroughputTrial) {
+				app.UpdateStats(tui.Stats{
+					TestType:    tui.TestType(cfg.TestType),
+					FrameSize:   s.FrameSize,
+					Progress:    frameProgress(frameSizes, s.FrameSize),
+					State:       "Running",
+					Iteration:   i + 1,
+					MaxIter:     int(cfg.Throughput.MaxIterations),
+					TxPackets:   t.FramesTx,
+					RxPackets:   t.FramesRx,
+					OfferedRate: t.RatePct,
+					LossPct:     t.LossPct,
+				})
+			}, func(s *suiteResult) {
+				t := s.Throughput
+				if t == nil {
+					return
+				}
+				app.AddResult(tui.Result{
+					FrameSize:    s.FrameSize,
+					MaxRatePct:   t.MaxRatePct,
+					MaxRateMbps:  t.MaxRateMbps,
+					LossPct:      acceptedLoss(t),
+					LatencyAvgNs: t.Latency.AvgNs,
+					Timestamp:    time.Now(),
+				})
+				app.LogInfo("%d bytes: max rate %.2f Mbps (%.2f%%)", s.FrameSize, t.MaxRateMbps, t.MaxRatePct)
+			})
+			app.UpdateStats(tui.Stats{State: "Complete"})
+			if finished {
+				app.LogInfo("Demo complete")
 			}
-			printLatencyResults(results, fs)
-			allResults = append(allResults, results)
+		}()
+	}
+	app.OnStop = func() {
+		app.LogInfo("Stopping demo...")
+		if stopReplay != nil {
+			stopReplay()
+		}
+	}
+	app.OnCancel = func() {
+		app.LogWarn("Demo cancelled")
+		if stopReplay != nil {
+			stopReplay()
+		}
+	}
+	app.OnQuit = func() {
+		if stopReplay != nil {
+			stopReplay()
+		}
+	}
 
-		case config.TestFrameLoss:
-			fmt.Printf("  Running frame loss test...\n")
-			results, err := ctx.RunFrameLossTest(cfg.FrameLoss.StartPct, cfg.FrameLoss.EndPct, cfg.FrameLoss.StepPct)
-			if err != nil {
-				log.Printf("  Error: %v", err)
-				continue
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		app.LogInfo("RFC2544 Test Master v%s", version)
+		app.LogWarn("Demo mode: sample results, no interface is opened and no traffic is sent")
+		for _, s := range samples {
+			app.LogInfo("Sample run %s: %s, %s", s.Name, s.Report.Metadata.DUT.Label(), s.Report.Metadata.TestType)
+		}
+		app.Log("Press %s to replay %s, %s for help and wiring, %s to quit",
+			app.KeyText(tui.ActionStart), sample.Name, app.KeyText(tui.ActionHelp), app.KeyText(tui.ActionQuit))
+	}()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		app.Stop()
+	}()
+
+	if err := app.Run(); err != nil {
+		return fmt.Errorf("TUI error: %w", err)
+	}
+	return nil
+}
+
+// runDemoWeb serves the Web UI with the sample runs in its run list, the
+// selected one the latest, and replays it on start
+func runDemoWeb(cfg *config.Config, samples []demoSample, sample *demoSample) error {
+	srv := web.New(cfg.WebUI.Address, web.WithSchemas(publishedSchemas()), web.WithHealth(healthInfo()))
+	for _, s := range samples {
+		if s.Name != sample.Name {
+			srv.ImportRun(webDemoRun(cfg, s.Report))
+		}
+	}
+	srv.ImportRun(webDemoRun(cfg, sample.Report))
+
+	frameSizes := cfg.TestFrameSizes()
+	var mu sync.Mutex
+	var stopReplay context.CancelFunc // Set while a replay runs
+	srv.OnStart = func(web.Config) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if stopReplay != nil {
+			return fmt.Errorf("a test is already running")
+		}
+		var replayCtx context.Context
+		replayCtx, stopReplay = context.WithCancel(context.Background())
+		log.Printf("[main] Demo: replaying sample run %s; no traffic is sent", sample.Name)
+		go func() {
+			var pct float64
+			finished := replayDemo(replayCtx, sample.Report, func(s *suiteResult, i int, t dataplane.ThroughputTrial) {
+				pct = frameProgress(frameSizes, s.FrameSize)
+				srv.UpdateStats(web.Stats{
+					TestType:    "throughput",
+					FrameSize:   s.FrameSize,
+					State:       web.StatusRunning,
+					Progress:    pct,
+					Iteration:   i + 1,
+					TxPackets:   t.FramesTx,
+					RxPackets:   t.FramesRx,
+					OfferedRate: t.RatePct,
+					LossPct:     t.LossPct,
+					Timestamp:   time.Now().Unix(),
+				})
+			}, func(s *suiteResult) {
+				result, tests := webSuiteResults(s)
+				result.Timestamp = time.Now().Unix()
+				srv.AddLegacyResult(result)
+				for _, t := range tests {
+					srv.AddResult(t)
+				}
+				srv.UpdateStatus(web.StatusRunning, fmt.Sprintf("Tested %d byte frames", s.FrameSize), pct)
+			})
+			if finished {
+				srv.UpdateStatus(web.StatusComplete, "Demo complete", 100)
+			} else {
+				srv.UpdateStatus(web.StatusCancelled, "Demo stopped", pct)
 			}
-			printFrameLossResults(results, fs)
-			allResults = append(allResults, results)
+			mu.Lock()
+			stopReplay()
+			stopReplay = nil
+			mu.Unlock()
+		}()
+		return nil
+	}
+	stop := func() {
+		mu.Lock()
+		if stopReplay != nil {
+			stopReplay()
+		}
+		mu.Unlock()
+	}
+	srv.OnStop = func() error {
+		stop()
+		return nil
+	}
+	srv.OnCancel = stop
 
-		case config.TestBackToBack:
-			fmt.Printf("  Running back-to-back test...\n")
-			result, err := ctx.RunBackToBackTest(cfg.BackToBack.InitialBurst, cfg.BackToBack.Trials)
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		log.Println("[main] Shutting down...")
+		srv.Stop()
+	}()
+
+	log.Printf("RFC2544 Test Master v%s", version)
+	log.Printf("Demo mode: sample runs %s; no interface is opened and no traffic is sent", strings.Join(demo.Names(), ", "))
+	log.Printf("Web UI: http://localhost%s", cfg.WebUI.Address)
+	if err := srv.Start(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("web server error: %w", err)
+	}
+	return nil
+}
+
+// webDemoRun converts a sample run to a completed run of the Web UI
+func webDemoRun(cfg *config.Config, report jsonReport) web.Run {
+	started := report.Metadata.Started
+	run := web.Run{
+		Config: web.Config{
+			Interface:      cfg.Interface,
+			TestType:       getTestTypeInt(config.TestThroughput),
+			TrialDuration:  cfg.TrialDuration,
+			LineRateMbps:   cfg.LineRateMbps,
+			InitialRatePct: cfg.Throughput.InitialRatePct,
+			ResolutionPct:  cfg.Throughput.ResolutionPct,
+		},
+		Message: fmt.Sprintf("Sample run of %s", report.Metadata.DUT.Label()),
+		Started: started,
+	}
+	for _, result := range report.Results {
+		s, ok := result.(*suiteResult)
+		if !ok {
+			continue
+		}
+		legacy, tests := webSuiteResults(s)
+		legacy.Timestamp = started.Unix()
+		run.Results = append(run.Results, legacy)
+		for _, t := range tests {
+			t.Timestamp = started.Unix()
+			run.TestResults = append(run.TestResults, t)
+		}
+	}
+	return run
+}
+
+// webSuiteResults converts a suite's results at one frame size to the Web
+// UI's: the throughput row, and a result per measurement as a web run
+// posts them
+func webSuiteResults(s *suiteResult) (web.Result, []web.TestResult) {
+	result := web.Result{FrameSize: s.FrameSize}
+	var tests []web.TestResult
+	add := func(testType string, data map[string]interface{}) {
+		tests = append(tests, web.TestResult{TestType: testType, FrameSize: s.FrameSize, Data: data})
+	}
+	if t := s.Throughput; t != nil {
+		result.MaxRatePct = t.MaxRatePct
+		result.MaxRateMbps = t.MaxRateMbps
+		result.MaxRatePps = t.MaxRatePPS
+		result.LossPct = acceptedLoss(t)
+		result.LatencyAvgNs = t.Latency.AvgNs
+		result.LatencyMinNs = t.Latency.MinNs
+		result.LatencyMaxNs = t.Latency.MaxNs
+		result.LatencyP99Ns = t.Latency.P99Ns
+		add("throughput", map[string]interface{}{
+			"max_rate_pct":  t.MaxRatePct,
+			"max_rate_mbps": t.MaxRateMbps,
+			"max_rate_pps":  t.MaxRatePPS,
+			"iterations":    t.Iterations,
+			"latency_avg":   t.Latency.AvgNs,
+			"latency_min":   t.Latency.MinNs,
+			"latency_max":   t.Latency.MaxNs,
+		})
+	}
+	for _, l := range s.Latency {
+		add("latency", map[string]interface{}{
+			"load_pct":    l.LoadPct,
+			"latency_avg": l.Latency.AvgNs,
+			"latency_min": l.Latency.MinNs,
+			"latency_max": l.Latency.MaxNs,
+			"jitter":      l.Latency.JitterNs,
+		})
+	}
+	for _, l := range s.FrameLoss {
+		add("frame_loss", map[string]interface{}{
+			"offered_pct": l.OfferedPct,
+			"frames_tx":   l.FramesTx,
+			"frames_rx":   l.FramesRx,
+			"loss_pct":    l.LossPct,
+		})
+	}
+	if b := s.BackToBack; b != nil {
+		add("back_to_back", map[string]interface{}{
+			"max_burst":   b.MaxBurstFrames,
+			"duration_us": b.BurstDurationUs,
+			"trials":      b.Trials,
+		})
+	}
+	return result, tests
+}
+
+// runAttach runs the TUI on a remote --web instance: its event stream
+// drives the live page and results, and the start, stop and cancel keys
+// call its API
+func runAttach(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadLayers(config.Layers{File: cfgFile, Environ: os.Environ()})
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	if tuiPlain {
+		cfg.TUI.Plain = true
+	}
+	if _, err := tui.Keymap(cfg.TUI.Keys.Names()); err != nil {
+		return fmt.Errorf("tui.keys: %w", err)
+	}
+
+	client := web.NewClient(args[0])
+	reqCtx, cancel := context.WithTimeout(context.Background(), attachTimeout)
+	remote, err := client.Config(reqCtx)
+	cancel()
+	if err != nil {
+		return fmt.Errorf("attach %s: %w", client.URL(), err)
+	}
+
+	app := tui.New(tuiOptions(cfg)...)
+	app.SetConfig(attachConfigText(client.URL(), remote))
+
+	attachCtx, detach := context.WithCancel(context.Background())
+	defer detach()
+
+	// call runs a request off the UI goroutine and logs a failure. Stop
+	// waits for the test to finish, so there is no timeout; quitting ends
+	// the request.
+	call := func(what string, req func(context.Context) error) {
+		go func() {
+			if err := req(attachCtx); err != nil && attachCtx.Err() == nil {
+				app.LogError("%s failed: %v", what, err)
+			}
+		}()
+	}
+	app.OnStart = func() {
+		app.ShowPage(tui.PageLive)
+		call("Start", func(ctx context.Context) error {
+			// Rerun the remote's current settings; it fills in the rest
+			last, err := client.Config(ctx)
 			if err != nil {
-				log.Printf("  Error: %v", err)
-				continue
+				return err
 			}
-			printBackToBackResult(result, fs)
-			allResults = append(allResults, result)
+			app.SetConfig(attachConfigText(client.URL(), last))
+			return client.Start(ctx, last)
+		})
+	}
+	app.OnStop = func() {
+		app.LogInfo("Stopping test...")
+		call("Stop", client.Stop)
+	}
+	app.OnCancel = func() {
+		app.LogWarn("Cancelling test")
+		call("Cancel", client.Cancel)
+	}
+	app.OnQuit = detach
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		app.LogInfo("RFC2544 Test Master v%s", version)
+		app.LogInfo("Attached to %s", client.URL())
+		app.Log("Press %s to start, %s for help, %s to quit (the remote test keeps running)",
+			app.KeyText(tui.ActionStart), app.KeyText(tui.ActionHelp), app.KeyText(tui.ActionQuit))
+		followRemote(attachCtx, app, client)
+	}()
 
-		case config.TestSystemRecovery:
-			fmt.Printf("  Running system recovery test (Section 26.5)...\n")
-			// Use provided throughput or default to 100%
-			throughputPct := recoveryThroughput
-			if throughputPct == 0 {
-				throughputPct = 100.0
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		app.Stop()
+	}()
+
+	return app.Run()
+}
+
+// followRemote shows a remote instance's events on the TUI until ctx
+// ends, reconnecting with backoff when the stream drops
+func followRemote(ctx context.Context, app *tui.App, client *web.Client) {
+	wait := time.Second
+	for {
+		events, err := client.Events(ctx)
+		if err == nil {
+			wait = time.Second
+			showRemoteEvents(app, events)
+			if ctx.Err() != nil {
+				return
 			}
-			result, err := ctx.RunSystemRecoveryTest(throughputPct, recoveryOverloadSec)
-			if err != nil {
-				log.Printf("  Error: %v", err)
-				continue
+			app.LogWarn("Lost connection to %s; reconnecting", client.URL())
+		} else if ctx.Err() == nil {
+			app.LogWarn("Event stream: %v; retrying in %s", err, wait)
+		}
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return
+		}
+		if wait *= 2; wait > attachRetry {
+			wait = attachRetry
+		}
+	}
+}
+
+// showRemoteEvents applies an event stream to the TUI until it closes
+func showRemoteEvents(app *tui.App, events <-chan web.Event) {
+	status := ""
+	for ev := range events {
+		switch ev.Type {
+		case web.EventStats:
+			if ev.Stats != nil && ev.Stats.TestType != "" {
+				app.UpdateStats(attachStats(*ev.Stats))
 			}
-			printRecoveryResult(result, fs)
-			allResults = append(allResults, result)
 
-		case config.TestReset:
-			fmt.Printf("  Running reset test (Section 26.6)...\n")
-			fmt.Printf("  NOTE: This test requires manual device reset trigger\n")
-			result, err := ctx.RunResetTest()
-			if err != nil {
-				log.Printf("  Error: %v", err)
-				continue
+		case web.EventStatus:
+			// The server clears its results when a run starts
+			if ev.Status == web.StatusRunning && status != "" && status != web.StatusRunning {
+				app.ClearResults()
+			}
+			status = ev.Status
+			switch {
+			case ev.Message == "":
+			case ev.Status == web.StatusError:
+				app.LogError("%s", ev.Message)
+			case ev.Status == web.StatusCancelled:
+				app.LogWarn("%s", ev.Message)
+			default:
+				app.LogInfo("%s", ev.Message)
 			}
-			printResetResult(result, fs)
-			allResults = append(allResults, result)
 
-		case config.TestY1564Config, config.TestY1564Perf, config.TestY1564Full:
-			runY1564Tests(ctx, cfg, &allResults, &cancelled)
+		case web.EventResult:
+			if ev.Result != nil {
+				showRemoteResult(app, *ev.Result)
+			}
+		}
+	}
+}
 
-		// RFC 2889 LAN Switch Tests
-		case config.TestRFC2889Forwarding, config.TestRFC2889Caching, config.TestRFC2889Learning,
-			config.TestRFC2889Broadcast, config.TestRFC2889Congestion:
-			runRFC2889Tests(ctx, cfg, &allResults, &cancelled)
+// attachStats converts a remote stats snapshot for the live page
+func attachStats(s web.Stats) tui.Stats {
+	testType, ok := attachTestTypes[s.TestType]
+	if !ok {
+		testType = tui.TestType(s.TestType)
+	}
+	state := s.State
+	if state != "" {
+		state = strings.ToUpper(state[:1]) + state[1:]
+	}
+	return tui.Stats{
+		TestType:    testType,
+		FrameSize:   s.FrameSize,
+		Progress:    s.Progress,
+		State:       state,
+		Iteration:   s.Iteration,
+		MaxIter:     s.MaxIter,
+		TxPackets:   s.TxPackets,
+		TxBytes:     s.TxBytes,
+		RxPackets:   s.RxPackets,
+		RxBytes:     s.RxBytes,
+		TxRate:      s.TxRate,
+		RxRate:      s.RxRate,
+		TxPPS:       s.TxPPS,
+		RxPPS:       s.RxPPS,
+		OfferedRate: s.OfferedRate,
+		LossPct:     s.LossPct,
+		LatencyMin:  s.LatencyMin,
+		LatencyMax:  s.LatencyMax,
+		LatencyAvg:  s.LatencyAvg,
+		LatencyP99:  s.LatencyP99,
+		Duration:    time.Duration(s.Uptime * float64(time.Second)),
+	}
+}
 
-		// RFC 6349 TCP Tests
-		case config.TestRFC6349Throughput, config.TestRFC6349Path:
-			runRFC6349Tests(ctx, cfg, &allResults, &cancelled)
+// showRemoteResult adds a throughput result to the results page and logs
+// the others, which have no table of their own
+func showRemoteResult(app *tui.App, r web.TestResult) {
+	num := func(key string) float64 {
+		v, _ := r.Data[key].(float64)
+		return v
+	}
+	switch r.TestType {
+	case "throughput":
+		app.AddResult(tui.Result{
+			FrameSize:    r.FrameSize,
+			MaxRatePct:   num("max_rate_pct"),
+			MaxRateMbps:  num("max_rate_mbps"),
+			LatencyAvgNs: num("latency_avg"),
+			Timestamp:    time.Unix(r.Timestamp, 0),
+		})
+		app.LogInfo("%d bytes: %.2f%% (%.2f Mbps)", r.FrameSize, num("max_rate_pct"), num("max_rate_mbps"))
+	case "latency":
+		app.LogInfo("%d bytes at %.0f%%: latency avg %.2f us, jitter %.2f us",
+			r.FrameSize, num("load_pct"), num("latency_avg")/1000, num("jitter")/1000)
+	case "frame_loss":
+		app.LogInfo("%d bytes at %.0f%%: %.4f%% loss", r.FrameSize, num("offered_pct"), num("loss_pct"))
+	case "back_to_back":
+		app.LogInfo("%d bytes: max burst %.0f frames", r.FrameSize, num("max_burst"))
+	default:
+		app.LogInfo("%s result for %d bytes", r.TestType, r.FrameSize)
+	}
+}
 
-		// Y.1731 OAM Tests
-		case config.TestY1731Delay, config.TestY1731Loss, config.TestY1731SLM, config.TestY1731Loopback:
-			runY1731Tests(ctx, cfg, &allResults, &cancelled)
+// attachConfigText summarizes a remote instance's settings for the config
+// page
+func attachConfigText(url string, c web.Config) string {
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	line := func(name, format string, a ...interface{}) {
+		fmt.Fprintf(w, "%s\t%s\n", name, fmt.Sprintf(format, a...))
+	}
+	line("Remote:", "%s", url)
+	line("Interface:", "%s", c.Interface)
+	if name, ok := webTestNames[dataplane.TestType(c.TestType)]; ok {
+		line("Test type:", "%s", name)
+	} else {
+		line("Test type:", "%d", c.TestType)
+	}
+	if c.FrameSize == 0 {
+		line("Frame sizes:", "%v", config.StandardFrameSizes(c.IncludeJumbo))
+	} else {
+		line("Frame size:", "%d bytes", c.FrameSize)
+	}
+	if c.LineRateMbps > 0 {
+		line("Line rate:", "%d Mbps", c.LineRateMbps)
+	} else {
+		line("Line rate:", "auto-detect")
+	}
+	line("Trial duration:", "%s", c.TrialDuration)
+	if dataplane.TestType(c.TestType) == dataplane.TestThroughput {
+		line("Search:", "start %.1f%%, resolution %.2f%%", c.InitialRatePct, c.ResolutionPct)
+	}
+	line("Hardware timestamps:", "%v", c.HWTimestamp)
+	w.Flush()
+	return sb.String()
+}
 
-		// MEF Service Activation Tests
-		case config.TestMEFConfig, config.TestMEFPerf, config.TestMEFFull:
-			runMEFTests(ctx, cfg, &allResults, &cancelled)
+// errRunStopped ends a run that was cancelled, or that pre-qualification
+// stopped, once it has said so
+var errRunStopped = errors.New("run stopped")
+
+// runCLI runs the configured test and writes its results. It reports
+// whether the results met the configured thresholds. A failed run exits
+// only once runCLITests has returned, so the far end is out of loopback
+// and the dataplane closed first.
+func runCLI(cfg *config.Config, sigCh chan os.Signal) runOutcome {
+	outcome, err := runCLITests(cfg, sigCh)
+	if errors.Is(err, errRunStopped) {
+		os.Exit(1)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	return outcome
+}
 
-		// TSN Tests
-		case config.TestTSNTiming, config.TestTSNIsolation, config.TestTSNLatency, config.TestTSNFull:
-			runTSNTests(ctx, cfg, &allResults, &cancelled)
+// runCLITests is the body of runCLI. Everything it sets up is released in
+// its defers, which is why it returns errors instead of exiting.
+func runCLITests(cfg *config.Config, sigCh chan os.Signal) (runOutcome, error) {
+	started := time.Now()
+	fmt.Printf("RFC2544 Test Master v%s\n", version)
+	if cfg.TRex.Enabled() {
+		fmt.Printf("TRex: %s (port %d -> %d)\n", cfg.TRex.Server, cfg.TRex.TxPort, cfg.TRex.RxPort)
+	} else if cfg.Socket.Enabled() {
+		fmt.Printf("Socket mode: %s, 100%% = %d pps (reduced accuracy: UDP sockets, round-trip software timestamps)\n", cfg.Socket.Target, cfg.Socket.MaxPPS)
+	} else if cfg.TestType == config.TestUDPEcho {
+		fmt.Printf("Reflector: %s (%d pps for %v)\n", cfg.UDPEcho.Target, cfg.UDPEcho.RatePPS, cfg.UDPEcho.Duration)
+	} else if cfg.Ports.Enabled() {
+		fmt.Printf("Ports: TX %s -> DUT -> RX %s\n", cfg.Interface, cfg.Ports.RX)
+	} else {
+		fmt.Printf("Interface: %s\n", cfg.Interface)
+	}
+	if label := cfg.DUT.Label(); label != "" {
+		fmt.Printf("DUT: %s\n", label)
+	}
+	fmt.Printf("Test: %s\n", cfg.TestType)
+	fmt.Println()
+
+	frameSizes, err := testFrameSizes(cfg)
+	if err != nil {
+		return runOutcome{}, err
+	}
+	fmt.Printf("Testing frame sizes: %v\n", frameSizes)
+	if cfg.TestType != config.TestUDPEcho {
+		fmt.Printf("Trial duration: %v\n", cfg.TrialDuration)
+	}
+	if cfg.Addressing.Pairs > 1 {
+		fmt.Printf("Address pairs: %d\n", cfg.Addressing.Pairs)
+	}
+	if cfg.Addressing.LearnRate > 0 {
+		fmt.Printf("Learning ramp: %d address pairs/s\n", cfg.Addressing.LearnRate)
+	}
+	if cfg.Addressing.Streams > 1 {
+		fmt.Printf("Streams: %d\n", cfg.Addressing.Streams)
+	}
+	if !cfg.Framing.IsDefault() {
+		fmt.Printf("Framing: %s\n", cfg.Framing)
+	}
+	if cfg.DSCP != 0 {
+		fmt.Printf("DSCP: %d\n", cfg.DSCP)
+	}
+	if cfg.Patterned() {
+		fmt.Printf("Payload pattern: %s\n", payloadPatternMetadata(cfg))
+	}
+	if cfg.VerifyPayload {
+		fmt.Printf("Payload verification: CRC-32 in each frame\n")
+	}
+	if cfg.Packet.Enabled() {
+		fmt.Printf("Packet template: %s\n", cfg.Packet.Template)
+	}
+	if cfg.Power.Enabled() {
+		fmt.Printf("Power sampling: %s every %v\n", cfg.Power.Source, cfg.Power.Interval)
+	}
+	if cfg.GNMI.Enabled() {
+		fmt.Printf("gNMI target: %s\n", cfg.GNMI.Address)
+	}
+	if cfg.Profile.Enabled() {
+		fmt.Printf("Self-profiling: pprof on http://%s/debug/pprof/, resources every %v\n", cfg.Profile.PprofAddress, cfg.Profile.Interval)
+	}
+	deviations := cfg.Deviations()
+	printDeviations(deviations)
+	fmt.Println()
+
+	// Record the tester's own resource use for the whole run
+	var profiler *selfprof.Sampler
+	if cfg.Profile.Enabled() {
+		profiler = selfprof.NewSampler(cfg.Profile.Interval)
+		profiler.Start()
+	}
+
+	// Check basic reachability before the dataplane takes the interface
+	var preQual *prequal.Report
+	if cfg.PreQual.Enabled() {
+		var cancelled bool
+		preQual, cancelled = runPreQualification(cfg, sigCh)
+		if cancelled {
+			return runOutcome{Passed: true, Cancelled: true}, nil
+		}
+		if !preQual.Passed && cfg.PreQual.Required {
+			fmt.Println("Pre-qualification failed; skipping tests")
+			report := jsonReport{
+				Metadata: runMetadata{Started: started, Interface: cfg.Interface, TestType: cfg.TestType, DUT: dutMetadata(cfg)},
+				PreQual:  preQual,
+			}
+			if err := outputResults(report, cfg); err != nil {
+				log.Printf("Error writing results: %v", err)
+			}
+			return runOutcome{}, errRunStopped
+		}
+	}
+
+	// Record the DUT configuration, compared again once the tests finish
+	var snapshotter *dutconfig.Snapshotter
+	var dutBefore dutconfig.Snapshot
+	if cfg.DUTSnapshot.Enabled() {
+		snapshotter = newSnapshotter(cfg)
+		fmt.Printf("DUT config snapshot (%d commands)...\n", len(cfg.DUTSnapshot.Commands))
+		dutBefore = takeSnapshot(snapshotter)
+	}
+
+	// Open the state file before anything is set up, so a bad or stale
+	// checkpoint stops the run before the far end is looped
+	var state *runState
+	if checkpointed(cfg.TestType) {
+		var err error
+		if state, err = openState(cfg, frameSizes); err != nil {
+			return runOutcome{}, err
+		}
+	}
+
+	// Traffic runs on the local dataplane or on a TRex server
+	var ctx *dataplane.Context
+	var traffic backend.Backend
+	var oam *oamRun
+	var trexInfo *backend.TRexRun
+	var trexGen *backend.TRex
+	var socket *backend.Socket
+	var watchdog *backend.Watchdog
+	var local backend.Local // The local dataplane, for the tests only it runs
+	var addrPools *addrpool.Usage
+	if cfg.TRex.Enabled() {
+		gen, err := backend.ConnectTRex(cfg)
+		if err != nil {
+			return runOutcome{}, fmt.Errorf("failed to connect to TRex: %w", err)
+		}
+		defer gen.Close()
+		traffic = gen
+		trexGen = gen
+		trexInfo = gen.Run()
+		fmt.Printf("TRex line rate: %.0f Mbps\n", trexInfo.LineRateMbps)
+	} else if cfg.Socket.Enabled() {
+		var err error
+		socket, err = backend.NewSocket(cfg)
+		if err != nil {
+			return runOutcome{}, fmt.Errorf("failed to start socket mode: %w", err)
+		}
+		traffic = socket
+	} else if cfg.TestType != config.TestUDPEcho {
+		// UDP echo uses the kernel's UDP stack instead of the dataplane
+		var err error
+		ctx, err = initDataplane(cfg)
+		if err != nil {
+			return runOutcome{}, err
+		}
+		defer ctx.Close()
+		traffic, local = ctx, ctx
+		if addrPools, err = setAddressPools(ctx, cfg); err != nil {
+			return runOutcome{}, err
+		}
+
+		// Restart a wedged dataplane instead of losing the run with it
+		watchdog = watchDataplane(ctx, cfg, func() (*dataplane.Context, error) {
+			c, err := openDataplane(cfg)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := setAddressPools(c, cfg); err != nil {
+				c.Close()
+				return nil, err
+			}
+			return c, nil
+		}, func(t backend.InvalidTrial, restarts int) {
+			fmt.Printf("  Dataplane restarted (%d so far); the %d byte %s is invalid\n", restarts, t.FrameSize, t.Test)
+		})
+		if watchdog != nil {
+			defer watchdog.Close()
+			traffic, local = watchdog, watchdog
+		}
+
+		// Loop the far end via OAM, released when the run ends
+		if cfg.OAM.RemoteLoopback {
+			var err error
+			oam, err = enableRemoteLoopback(ctx, cfg)
+			if err != nil {
+				return runOutcome{}, fmt.Errorf("failed to enable remote loopback: %w", err)
+			}
+			defer func() { releaseRemoteLoopback(ctx, cfg, oam) }()
+		}
+
+		// Publish live counters to telemetry collectors
+		if cfg.GNMI.Enabled() {
+			stop := startGNMI(func() *dataplane.Context {
+				if watchdog != nil {
+					return watchdog.Context(ctx)
+				}
+				return ctx
+			}, cfg)
+			defer stop()
+		}
+	}
+
+	// Save progress so an interrupted run can resume
+	if state != nil {
+		traffic = &checkpointBackend{Backend: traffic, state: state}
+	}
+
+	// Handle cancel. Every test stops through runCtx.
+	var cancelled atomic.Bool
+	runCtx, stopRun := context.WithCancel(context.Background())
+	defer stopRun()
+	go func() {
+		// Stop waiting once the run ends, so the next plan step gets the signal
+		select {
+		case <-sigCh:
+		case <-runCtx.Done():
+			return
+		}
+		cancelled.Store(true)
+		fmt.Println("\nCancelling...")
+		stopRun()
+	}()
+
+	// Results storage
+	var allResults []interface{}
+	var modifierRuns []modifierRun
+	var controlPlaneRuns []controlPlaneRun
+	var flowReports []flows.Report
+	var powerRuns []powerRun
+	var selfTestFailed bool
+
+	// Run tests
+	for _, fs := range frameSizes {
+		if cancelled.Load() {
+			break
+		}
+		if watchdog != nil {
+			ctx = watchdog.Context(ctx)
+		}
+
+		if f := state.frame(fs); f != nil {
+			fmt.Printf("\nSkipping %d byte frames (completed before resume)\n", fs)
+			if err := restoreFrame(cfg, f, &allResults, &modifierRuns, &controlPlaneRuns, &powerRuns); err != nil {
+				return runOutcome{}, fmt.Errorf("failed to restore %d byte results from %s: %w", fs, state.Path(), err)
+			}
+			continue
+		}
+
+		fmt.Printf("\nTesting %d byte frames...\n", fs)
+		if traffic != nil {
+			traffic.SetFrameSize(fs)
+			if err := traffic.SetAcceptableLoss(cfg.Throughput.AcceptableLossFor(cfg.TestType, fs)); err != nil {
+				log.Printf("  Error: %v", err)
+				continue
+			}
+		}
+		frame := &stateFrame{Frame: checkpoint.Frame{FrameSize: fs}}
+
+		switch cfg.TestType {
+		case config.TestThroughput, config.TestLatency, config.TestFrameLoss,
+			config.TestBackToBack, config.TestSystemRecovery, config.TestReset, config.TestSuite,
+			config.TestCharacterize:
+			sampler := startPowerSampler(cfg)
+			result, err := runRFC2544Test(runCtx, traffic, cfg, fs)
+			if run := stopPowerSampler(sampler, fs, result); run != nil && err == nil {
+				powerRuns = append(powerRuns, *run)
+				frame.Power = frame.encode(run)
+			}
+			if err != nil {
+				log.Printf("  Error: %v", err)
+				continue
+			}
+			allResults = append(allResults, result)
+			frame.Result = frame.encode(result)
+
+			// Section 11: repeat with modifiers, reported separately
+			if cfg.Modifiers.Enabled() && !cancelled.Load() {
+				run, err := runModifiedTest(runCtx, ctx, cfg, fs, result)
+				if err != nil {
+					log.Printf("  Modifier run error: %v", err)
+					continue
+				}
+				modifierRuns = append(modifierRuns, *run)
+				frame.Modifier = frame.encode(run)
+			}
+
+			// Control-plane policing: repeat under control-plane stress
+			if cfg.ControlPlane.Enabled() && !cancelled.Load() {
+				run, err := runControlPlaneTest(runCtx, ctx, cfg, fs, result)
+				if err != nil {
+					log.Printf("  Control-plane run error: %v", err)
+					continue
+				}
+				controlPlaneRuns = append(controlPlaneRuns, *run)
+				frame.ControlPlane = frame.encode(run)
+			}
+			state.complete(frame, &cancelled)
+
+		case config.TestSoak:
+			sampler := startPowerSampler(cfg)
+			result, err := runSoakTest(runCtx, traffic, cfg, fs, nil)
+			if run := stopPowerSampler(sampler, fs, result); run != nil && err == nil {
+				powerRuns = append(powerRuns, *run)
+				frame.Power = frame.encode(run)
+			}
+			if err != nil {
+				log.Printf("  Error: %v", err)
+				continue
+			}
+			allResults = append(allResults, result)
+			frame.Result = frame.encode(result)
+			state.complete(frame, &cancelled)
+
+		case config.TestQoS:
+			result, err := runQoSTest(runCtx, trexGen, cfg, fs)
+			if err != nil {
+				log.Printf("  Error: %v", err)
+				continue
+			}
+			allResults = append(allResults, result)
+
+		case config.TestAQM:
+			result, err := runAQMTest(runCtx, traffic, cfg, fs)
+			if err != nil {
+				log.Printf("  Error: %v", err)
+				continue
+			}
+			allResults = append(allResults, result)
+
+		case config.TestMicroburst:
+			result, err := runMicroburstTest(runCtx, traffic, cfg, fs)
+			if err != nil {
+				log.Printf("  Error: %v", err)
+				continue
+			}
+			allResults = append(allResults, result)
+
+		case config.TestSelfTest:
+			result, err := runSelfTest(runCtx, traffic, cfg, fs)
+			if err != nil {
+				log.Printf("  Error: %v", err)
+				selfTestFailed = true
+				continue
+			}
+			if !result.Passed {
+				selfTestFailed = true
+			}
+			allResults = append(allResults, result)
+
+		case config.TestUDPEcho:
+			result, err := runUDPEchoTest(runCtx, cfg, fs)
+			if err != nil {
+				log.Printf("  Error: %v", err)
+				continue
+			}
+			allResults = append(allResults, result)
+
+		case config.TestY1564Config, config.TestY1564Perf, config.TestY1564Full:
+			if report := runY1564Tests(runCtx, local, cfg, &allResults); report != nil {
+				flowReports = append(flowReports, *report)
+			}
+
+		// RFC 2889 LAN Switch Tests
+		case config.TestRFC2889Forwarding, config.TestRFC2889Caching, config.TestRFC2889Learning,
+			config.TestRFC2889Broadcast, config.TestRFC2889Congestion:
+			runRFC2889Tests(runCtx, ctx, cfg, fs, &allResults)
+
+		// RFC 6349 TCP Tests
+		case config.TestRFC6349Throughput, config.TestRFC6349Path:
+			runRFC6349Tests(runCtx, ctx, cfg, &allResults)
+
+		// Y.1731 OAM Tests
+		case config.TestY1731Delay, config.TestY1731Loss, config.TestY1731SLM, config.TestY1731Loopback:
+			runY1731Tests(runCtx, ctx, cfg, &allResults)
+
+		// MEF Service Activation Tests
+		case config.TestMEFConfig, config.TestMEFPerf, config.TestMEFFull:
+			runMEFTests(runCtx, ctx, cfg, &allResults)
+
+		// TSN Tests
+		case config.TestTSNTiming, config.TestTSNIsolation, config.TestTSNLatency, config.TestTSNFull:
+			runTSNTests(runCtx, ctx, cfg, &allResults)
+
+		default:
+			fmt.Printf("  Unknown test type: %s\n", cfg.TestType)
+		}
+	}
+
+	var invalid []backend.InvalidTrial
+	if watchdog != nil {
+		ctx = watchdog.Context(ctx)
+		invalid = watchdog.Invalid()
+	}
+
+	if cancelled.Load() {
+		fmt.Println("\nTest cancelled")
+		if state != nil {
+			fmt.Printf("Progress saved; continue with --resume %s\n", state.Path())
+		}
+		return runOutcome{Cancelled: true}, errRunStopped
+	}
+
+	var dutConfig *dutconfig.Report
+	if snapshotter != nil {
+		fmt.Println("\nDUT config snapshot after the run...")
+		dutConfig = snapshotter.Compare(dutBefore, takeSnapshot(snapshotter))
+	}
+
+	// Methodology compliance (RFC 2544 tests only)
+	compliance := cfg.Compliance()
+	thresholds := threshold.Evaluate(allResults, cfg, frameSizes)
+	curve := latencyCurve(allResults, cfg)
+	var certified *certify.Report
+	if certifying {
+		certified = certification(cfg, frameSizes, allResults)
+	}
+	if outputFormat == "text" {
+		printLatencyCurve(curve)
+		printDUTConfig(dutConfig)
+		printFlowReports(flowReports)
+		printInvalidTrials(invalid)
+		printDeviations(deviations)
+		printCompliance(compliance)
+		if certified != nil {
+			fmt.Println()
+			certified.WriteText(os.Stdout)
+		}
+		printThresholds(thresholds)
+	}
+
+	var socketInfo *backend.SocketRun
+	if socket != nil {
+		socketInfo = socket.Finish()
+	}
+	// The ramp that introduced the address pairs to the DUT
+	var learning *dataplane.AddressLearning
+	if ctx != nil && cfg.Addressing.LearnRate > 0 {
+		learning = ctx.AddressLearning()
+		if outputFormat == "text" && learning != nil {
+			fmt.Printf("\nLearning ramp: %d address pairs at %d/s in %.1fs\n",
+				learning.Addresses, learning.RatePerSec, learning.DurationSec)
+		}
+	}
+	// Frames saved for post-mortem
+	var captured *captureRun
+	if ctx != nil && cfg.Capture.Enabled() {
+		captured = captureMetadata(ctx, cfg)
+		if outputFormat == "text" {
+			printCapture(captured)
+		}
+	}
+	// Frames sent on the TX port against frames seen on the RX port
+	var ports []dataplane.PortStats
+	if ctx != nil && cfg.Ports.Enabled() {
+		ports = ctx.PortStats()
+		if outputFormat == "text" && len(ports) > 0 {
+			printPortStats(ports)
+		}
+	}
+	var selfProfile *selfprof.Report
+	if profiler != nil {
+		selfProfile = profiler.Stop()
+		if outputFormat == "text" {
+			printSelfProfile(selfProfile)
+		}
+	}
+
+	// Output results in requested format
+	report := jsonReport{
+		Metadata: runMetadata{
+			Started:      started,
+			Version:      version,
+			Interface:    cfg.Interface,
+			Dataplane:    dataplaneBackend(ctx),
+			Library:      dataplaneLibrary(ctx),
+			Link:         linkMetadata(ctx, cfg.Interface),
+			TestType:     cfg.TestType,
+			Services:     slaServices(cfg),
+			DUT:          dutMetadata(cfg),
+			TRex:         trexInfo,
+			Socket:       socketInfo,
+			AddressPairs: cfg.Addressing.Pairs,
+			Streams:      cfg.Addressing.Streams,
+			AddressPools: addrPools,
+			Learning:     learning,
+			Framing:      cfg.Framing.String(),
+			MPLSLabels:   cfg.Framing.LabelStack(),
+			IPVersion:    cfg.IPVersion,
+			TxMarking:    txMarkingMetadata(ctx, cfg),
+			Payload:      payloadPatternMetadata(cfg),
+			Packet:       cfg.Packet.Template,
+			OAM:          oam,
+			Ports:        ports,
+			Capture:      captured,
+			Wiring:       wiringDiagram(cfg),
+		},
+		Deviations:    deviations,
+		PreQual:       preQual,
+		DUTConfig:     dutConfig,
+		Results:       allResults,
+		Invalid:       invalid,
+		LatencyCurve:  curve,
+		Modifiers:     modifierRuns,
+		ControlPlane:  controlPlaneRuns,
+		Power:         powerRuns,
+		FlowFailures:  flowReports,
+		Compliance:    compliance,
+		Certification: certified,
+		SelfProfile:   selfProfile,
+		Thresholds:    thresholds,
+	}
+	if err := outputResults(report, cfg); err != nil {
+		log.Printf("Error writing results: %v", err)
+	} else if state != nil {
+		if err := state.Remove(); err != nil {
+			log.Printf("Warning: failed to remove state file: %v", err)
+		}
+	}
+	if thresholds != nil && !thresholds.Passed && len(cfg.Ticketing) > 0 {
+		fileTickets(report, cfg)
+	}
+
+	fmt.Println("\nTest complete")
+	return runOutcome{
+		Passed:    (thresholds == nil || thresholds.Passed) && !selfTestFailed && (certified == nil || certified.Complete),
+		Cancelled: cancelled.Load(),
+		Results:   len(allResults),
+	}, nil
+}
+
+// latencyCurve assembles latency against frame size at the throughput
+// rate from the run's throughput, latency or suite results: the latency of
+// the throughput search's best trial, or of the highest latency load. It is
+// nil unless at least two frame sizes measured latency.
+func latencyCurve(results []interface{}, cfg *config.Config) *latcurve.Curve {
+	var points []latcurve.Point
+	lineRate := float64(cfg.LineRateMbps)
+	add := func(fs uint32, loadPct float64, l dataplane.LatencyStats) {
+		if l.Count == 0 {
+			return
+		}
+		points = append(points, latcurve.Point{
+			FrameSize: fs,
+			LoadPct:   loadPct,
+			AvgUs:     l.AvgNs / 1000,
+			MinUs:     l.MinNs / 1000,
+			MaxUs:     l.MaxNs / 1000,
+		})
+	}
+	throughput := func(t *dataplane.ThroughputResultCLI) {
+		if t.MaxRatePct > 0 {
+			lineRate = t.MaxRateMbps / t.MaxRatePct * 100
+		}
+	}
+	highest := func(lrs []dataplane.LatencyResultCLI) *dataplane.LatencyResultCLI {
+		var top *dataplane.LatencyResultCLI
+		for i := range lrs {
+			if top == nil || lrs[i].LoadPct > top.LoadPct {
+				top = &lrs[i]
+			}
+		}
+		return top
+	}
+
+	for _, r := range results {
+		switch v := r.(type) {
+		case *dataplane.ThroughputResultCLI:
+			throughput(v)
+			add(v.FrameSize, v.MaxRatePct, v.Latency)
+		case []dataplane.LatencyResultCLI:
+			if top := highest(v); top != nil {
+				add(top.FrameSize, top.LoadPct, top.Latency)
+			}
+		case *suiteResult:
+			if v.Throughput != nil {
+				throughput(v.Throughput)
+			}
+			if top := highest(v.Latency); top != nil && top.Latency.Count > 0 {
+				add(v.FrameSize, top.LoadPct, top.Latency)
+			} else if v.Throughput != nil {
+				add(v.FrameSize, v.Throughput.MaxRatePct, v.Throughput.Latency)
+			}
+		case *characterizeResult:
+			throughput(v.Throughput)
+			lrs, _ := v.Result(config.TestLatency).([]dataplane.LatencyResultCLI)
+			if top := highest(lrs); top != nil && top.Latency.Count > 0 {
+				add(v.FrameSize, top.LoadPct, top.Latency)
+			} else {
+				add(v.FrameSize, v.Throughput.MaxRatePct, v.Throughput.Latency)
+			}
+		}
+	}
+	return latcurve.Build(points, lineRate, cfg.Latency.SubtractSerialization)
+}
+
+func printLatencyCurve(c *latcurve.Curve) {
+	if c == nil {
+		return
+	}
+	fmt.Println("\nLatency vs frame size (at the throughput rate):")
+	if c.SerializationSubtracted {
+		fmt.Printf("  %6s %8s %10s %10s %10s %12s %10s\n", "Size", "Load%", "Min(us)", "Avg(us)", "Max(us)", "Serial(us)", "Net(us)")
+	} else {
+		fmt.Printf("  %6s %8s %10s %10s %10s %12s\n", "Size", "Load%", "Min(us)", "Avg(us)", "Max(us)", "Serial(us)")
+	}
+	for _, p := range c.Points {
+		fmt.Printf("  %6d %8.2f %10.2f %10.2f %10.2f %12.3f", p.FrameSize, p.LoadPct, p.MinUs, p.AvgUs, p.MaxUs, p.SerializationUs)
+		if c.SerializationSubtracted {
+			fmt.Printf(" %10.2f", p.NetAvgUs)
+		}
+		fmt.Println()
+	}
+	fmt.Printf("  Slope: %.3f ns/byte", c.SlopeNsPerByte)
+	if c.SerializationNsPerByte > 0 {
+		fmt.Printf(" (serialization %.3f ns/byte)", c.SerializationNsPerByte)
+	}
+	fmt.Println()
+	if c.Forwarding != "" {
+		fmt.Printf("  DUT behaves as %s\n", c.Forwarding)
+	}
+	for _, e := range c.Evidence {
+		fmt.Printf("    - %s\n", e)
+	}
+	if len(c.Segments) > 0 {
+		fmt.Printf("  %13s %12s %8s  %s\n", "Segment", "ns/byte", "Ratio", "Mode")
+		for _, seg := range c.Segments {
+			fmt.Printf("  %13s %12.3f %8.2f  %s\n", fmt.Sprintf("%d-%d", seg.FromSize, seg.ToSize), seg.SlopeNsPerByte, seg.SlopeRatio, seg.Forwarding)
+		}
+	}
+}
+
+// runOutcome is how a CLI run ended
+type runOutcome struct {
+	Passed    bool // Thresholds held, or none were set
+	Cancelled bool
+	Results   int // Results recorded
+}
+
+// failed reports whether a plan step that ended this way failed
+func (o runOutcome) failed() string {
+	switch {
+	case o.Cancelled:
+		return "cancelled"
+	case !o.Passed:
+		return "thresholds violated"
+	case o.Results == 0:
+		return "no results"
+	}
+	return ""
+}
+
+// runPlan runs the plan's steps in order, each as a CLI run with its own
+// results; --output-file gets the step number and name. A failed step
+// stops the plan unless continue_on_failure is set, and a cancelled one
+// always does. It reports whether every step passed.
+func runPlan(cfg *config.Config, sigCh chan os.Signal) bool {
+	steps := make([]*config.Config, len(cfg.Plan.Steps))
+	for i := range cfg.Plan.Steps {
+		step, err := cfg.Step(i)
+		if err == nil {
+			err = step.Validate()
+		}
+		if err != nil {
+			log.Fatalf("Invalid plan: %v", err)
+		}
+		if step.TestType == config.TestQoS && !step.TRex.Enabled() {
+			log.Fatalf("Plan step %s: the QoS test needs TRex", cfg.Plan.Steps[i].Label(i))
+		}
+		steps[i] = step
+	}
+
+	baseOutput := outputFile
+	defer func() { outputFile = baseOutput }()
+	status := make([]string, len(steps))
+	took := make([]time.Duration, len(steps))
+	stopped := false
+	for i, step := range steps {
+		label := cfg.Plan.Steps[i].Label(i)
+		if stopped {
+			status[i] = "skipped"
+			continue
+		}
+		fmt.Printf("=== Plan step %d of %d: %s (%s) ===\n", i+1, len(steps), cfg.Plan.Steps[i].DisplayName(), step.TestType)
+		if baseOutput != "" {
+			outputFile = planOutputFile(baseOutput, label)
+		}
+
+		start := time.Now()
+		outcome := runCLI(step, sigCh)
+		took[i] = time.Since(start).Round(time.Second)
+		status[i] = outcome.failed()
+		if status[i] != "" {
+			fmt.Printf("Plan step %s failed: %s\n", label, status[i])
+			stopped = outcome.Cancelled || !cfg.Plan.ContinueOnFailure
+		}
+		fmt.Println()
+	}
+
+	fmt.Println("Plan summary:")
+	passed := true
+	for i, st := range status {
+		label := cfg.Plan.Steps[i].Label(i)
+		switch st {
+		case "":
+			fmt.Printf("  [PASS   ] %s (%v)\n", label, took[i])
+		case "skipped":
+			passed = false
+			fmt.Printf("  [SKIPPED] %s\n", label)
+		default:
+			passed = false
+			fmt.Printf("  [FAIL   ] %s: %s (%v)\n", label, st, took[i])
+		}
+	}
+	return passed
+}
+
+// planOutputFile names a plan step's results after the run's output file,
+// e.g. report-2-soak.html for step "2 soak" of report.html
+func planOutputFile(path, label string) string {
+	ext := filepath.Ext(path)
+	name := strings.Map(func(r rune) rune {
+		if r == ' ' || r == '/' || r == filepath.Separator {
+			return '-'
+		}
+		return r
+	}, label)
+	return strings.TrimSuffix(path, ext) + "-" + name + ext
+}
+
+// checkpointed reports whether a test type saves its progress. These are
+// the tests that loop over frame sizes on the traffic backend.
+func checkpointed(t config.TestType) bool {
+	return config.IsRFC2544Test(t) || t == config.TestSoak
+}
+
+// runState is the checkpoint state of a run, or nil when the test type is
+// not checkpointed
+type runState struct {
+	*checkpoint.State
+}
+
+// openState loads the state file given with --resume, or creates a new one
+func openState(cfg *config.Config, frameSizes []uint32) (*runState, error) {
+	target := cfg.Interface
+	if cfg.TRex.Enabled() {
+		target = cfg.TRex.Server
+	} else if cfg.Socket.Enabled() {
+		target = cfg.Socket.Target
+	}
+	run := checkpoint.Run{TestType: string(cfg.TestType), Target: target, FrameSizes: frameSizes}
+
+	if resumeFile != "" {
+		s, err := checkpoint.Load(resumeFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resume: %w", err)
+		}
+		if err := s.Check(run); err != nil {
+			return nil, fmt.Errorf("failed to resume: %w", err)
+		}
+		fmt.Printf("Resuming from %s: %d of %d frame sizes complete\n", resumeFile, len(s.Completed), len(frameSizes))
+		return &runState{s}, nil
+	}
+
+	path := stateFile
+	if path == "" {
+		path = filepath.Join(os.TempDir(), fmt.Sprintf("rfc2544-%s-%s.state.json",
+			cfg.TestType, time.Now().Format("20060102-150405")))
+	}
+	s, err := checkpoint.New(path, run)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create state file: %w", err)
+	}
+	fmt.Printf("State file: %s\n", path)
+	return &runState{s}, nil
+}
+
+// frame returns the frame size if it completed before the run resumed
+func (s *runState) frame(fs uint32) *checkpoint.Frame {
+	if s == nil {
+		return nil
+	}
+	return s.Frame(fs)
+}
+
+// complete records a finished frame size, unless the run was cancelled
+// part-way through it
+func (s *runState) complete(f *stateFrame, cancelled *atomic.Bool) {
+	if s == nil || cancelled.Load() {
+		return
+	}
+	if f.err != nil {
+		log.Printf("Warning: %d bytes not saved to the state file: %v", f.FrameSize, f.err)
+		return
+	}
+	if err := s.Complete(f.Frame); err != nil {
+		log.Printf("Warning: failed to save progress: %v", err)
+	}
+}
+
+// restoreFrame adds a completed frame size's results from the state file
+func restoreFrame(cfg *config.Config, f *checkpoint.Frame, results *[]interface{},
+	modifierRuns *[]modifierRun, controlPlaneRuns *[]controlPlaneRun, powerRuns *[]powerRun) error {
+	result, err := decodeResult(cfg.TestType, f.Result)
+	if err != nil {
+		return err
+	}
+	*results = append(*results, result)
+
+	// Per-run reports carry a result of the same type
+	var nested struct {
+		Result json.RawMessage `json:"result"`
+	}
+	if f.Modifier != nil {
+		var run modifierRun
+		if err := json.Unmarshal(f.Modifier, &run); err != nil {
+			return err
+		}
+		json.Unmarshal(f.Modifier, &nested)
+		if run.Result, err = decodeResult(cfg.TestType, nested.Result); err != nil {
+			return err
+		}
+		*modifierRuns = append(*modifierRuns, run)
+	}
+	if f.ControlPlane != nil {
+		var run controlPlaneRun
+		if err := json.Unmarshal(f.ControlPlane, &run); err != nil {
+			return err
+		}
+		json.Unmarshal(f.ControlPlane, &nested)
+		if run.Result, err = decodeResult(cfg.TestType, nested.Result); err != nil {
+			return err
+		}
+		*controlPlaneRuns = append(*controlPlaneRuns, run)
+	}
+	if f.Power != nil {
+		var run powerRun
+		if err := json.Unmarshal(f.Power, &run); err != nil {
+			return err
+		}
+		*powerRuns = append(*powerRuns, run)
+	}
+	return nil
+}
+
+// decodeResult decodes a saved result into the type its test returns
+func decodeResult(t config.TestType, data json.RawMessage) (interface{}, error) {
+	var err error
+	switch t {
+	case config.TestThroughput:
+		var r *dataplane.ThroughputResultCLI
+		err = json.Unmarshal(data, &r)
+		return r, err
+	case config.TestLatency:
+		var r []dataplane.LatencyResultCLI
+		err = json.Unmarshal(data, &r)
+		return r, err
+	case config.TestFrameLoss:
+		var r []dataplane.FrameLossResultCLI
+		err = json.Unmarshal(data, &r)
+		return r, err
+	case config.TestBackToBack:
+		var r *dataplane.BackToBackResultCLI
+		err = json.Unmarshal(data, &r)
+		return r, err
+	case config.TestSystemRecovery:
+		var r *dataplane.RecoveryResultCLI
+		err = json.Unmarshal(data, &r)
+		return r, err
+	case config.TestReset:
+		var r *dataplane.ResetResultCLI
+		err = json.Unmarshal(data, &r)
+		return r, err
+	case config.TestSuite:
+		var r *suiteResult
+		err = json.Unmarshal(data, &r)
+		return r, err
+	case config.TestCharacterize:
+		var r *characterizeResult
+		err = json.Unmarshal(data, &r)
+		return r, err
+	case config.TestSoak:
+		var r *drift.Report
+		err = json.Unmarshal(data, &r)
+		return r, err
+	}
+	return nil, fmt.Errorf("test type %s is not checkpointed", t)
+}
+
+// stateFrame is a frame size's entry of the state file as it is filled
+// in. A result JSON cannot encode, such as one holding NaN, leaves the
+// frame size unsaved rather than ending the run.
+type stateFrame struct {
+	checkpoint.Frame
+	err error
+}
+
+// encode encodes v for the state file, keeping the first error
+func (f *stateFrame) encode(v interface{}) json.RawMessage {
+	data, err := json.Marshal(v)
+	if err != nil && f.err == nil {
+		f.err = err
+	}
+	return data
+}
+
+// checkpointBackend saves the throughput search after every iteration, and
+// continues a search saved by an interrupted run
+type checkpointBackend struct {
+	backend.Backend
+	state     *runState
+	frameSize uint32
+}
+
+func (b *checkpointBackend) SetFrameSize(frameSize uint32) {
+	b.frameSize = frameSize
+	b.Backend.SetFrameSize(frameSize)
+}
+
+func (b *checkpointBackend) RunThroughputTest(ctx context.Context) (*dataplane.ThroughputResultCLI, error) {
+	var from *dataplane.ThroughputSearch
+	var saved dataplane.ThroughputSearch
+	ok, err := b.state.Search(b.frameSize, &saved)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		fmt.Printf("  Resuming throughput search after %d iterations (%.2f%% - %.2f%%)\n",
+			saved.Iterations, saved.LowPct, saved.HighPct)
+		from = &saved
+	}
+	return b.RunThroughputSearch(ctx, from, func(s *dataplane.ThroughputSearch) {
+		if err := b.state.Progress(b.frameSize, s); err != nil {
+			log.Printf("Warning: failed to save progress: %v", err)
+		}
+	})
+}
+
+// testFrameSizes returns the frame sizes the run tests
+func testFrameSizes(cfg *config.Config) ([]uint32, error) {
+	frameSizes := cfg.TestFrameSizes()
+
+	// Standard sizes too small for a packet template's headers are skipped
+	if cfg.Packet.Enabled() && cfg.StandardSweep() {
+		return templateFrameSizes(cfg, frameSizes)
+	}
+	return frameSizes, nil
+}
+
+// templateFrameSizes drops the frame sizes that cannot hold the packet
+// template's headers and the RFC 2544 payload
+func templateFrameSizes(cfg *config.Config, frameSizes []uint32) ([]uint32, error) {
+	h, err := packet.Compile(cfg.Packet.Template)
+	if err != nil {
+		return nil, fmt.Errorf("invalid packet template: %w", err)
+	}
+	var sizes []uint32
+	for _, fs := range frameSizes {
+		if fs < h.MinFrameSize() {
+			fmt.Printf("Skipping %d byte frames: the packet template needs at least %d\n", fs, h.MinFrameSize())
+			continue
+		}
+		sizes = append(sizes, fs)
+	}
+	return sizes, nil
+}
+
+// initDataplane creates the local dataplane context for the run
+func initDataplane(cfg *config.Config) (*dataplane.Context, error) {
+	ctx, err := openDataplane(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize dataplane: %w", err)
+	}
+	if dataplane.PureGo {
+		log.Printf("Warning: %s dataplane, rates are limited by the host's socket performance", dataplane.Backend)
+	}
+	return ctx, nil
+}
+
+// watchDataplane puts ctx under the dataplane watchdog when cfg enables
+// it, reopening the context with open after a restart and passing each
+// test cut short to report. It returns nil when the watchdog is off.
+func watchDataplane(ctx *dataplane.Context, cfg *config.Config, open func() (*dataplane.Context, error), report func(t backend.InvalidTrial, restarts int)) *backend.Watchdog {
+	if cfg.DataplaneWatchdog <= 0 {
+		return nil
+	}
+	watchdog := backend.NewWatchdog(ctx, cfg.DataplaneWatchdog, open)
+	watchdog.OnInvalid = report
+	return watchdog
+}
+
+// openDataplane creates and configures a local dataplane context, again
+// for each restart by the dataplane watchdog
+func openDataplane(cfg *config.Config) (*dataplane.Context, error) {
+	dpCfg := dataplane.Config{
+		Interface:      cfg.Interface,
+		RXInterface:    cfg.Ports.RX,
+		LineRate:       cfg.LineRateMbps * 1000000, // Convert to bps
+		AutoDetect:     cfg.AutoDetect,
+		TestType:       dataplane.TestType(int(getTestTypeInt(cfg.TestType))),
+		FrameSize:      cfg.FrameSize,
+		IncludeJumbo:   cfg.IncludeJumbo,
+		JumboSizes:     cfg.JumboSizes,
+		TrialDuration:  cfg.TrialDuration,
+		WarmupPeriod:   cfg.WarmupPeriod,
+		InitialRatePct: cfg.Throughput.InitialRatePct,
+		ResolutionPct:  cfg.Throughput.ResolutionPct,
+		MaxIterations:  cfg.Throughput.MaxIterations,
+		AcceptableLoss: cfg.Throughput.AcceptableLoss,
+		HWTimestamp:    cfg.HWTimestamp,
+		MeasureLatency: cfg.MeasureLatency,
+
+		LatencyHistogram: cfg.LatencyHistogram,
+		LatencySamples:   cfg.LatencySampleCapacity,
+		VerifyPayload:    cfg.VerifyPayload,
+		Streams:          cfg.Addressing.Streams,
+		VLANID:           cfg.Framing.VLANID,
+		PCP:              cfg.Framing.PCP,
+		OuterVLANID:      cfg.Framing.OuterVLANID,
+		OuterPCP:         cfg.Framing.OuterPCP,
+		OuterTPID:        cfg.Framing.OuterTPID,
+		TPID:             cfg.Framing.TPID,
+		MPLSLabels:       dataplaneLabels(cfg.Framing.MPLSLabels),
+		MPLSEXPFromCoS:   cfg.Framing.EXPFromCoS,
+		LatencyProbe:     dataplaneProbe(cfg.LatencyProbe),
+		IP:               dataplaneIP(cfg),
+		Headers:          dataplaneHeaders(cfg),
+		Payload:          dataplanePayload(cfg),
+		Capture:          dataplaneCapture(cfg),
+		SpeedProfile:     cfg.SpeedProfile,
+	}
+
+	ctx, err := dataplane.New(dpCfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := dataplaneSupports(ctx.Capabilities(), cfg); err != nil {
+		ctx.Close()
+		return nil, err
+	}
+
+	if err := ctx.SetAddressPairs(cfg.Addressing.Pairs); err != nil {
+		ctx.Close()
+		return nil, fmt.Errorf("configure address pairs: %w", err)
+	}
+	if err := ctx.SetAddressLearnRate(cfg.Addressing.LearnRate); err != nil {
+		ctx.Close()
+		return nil, fmt.Errorf("configure address learning ramp: %w", err)
+	}
+	framing := dataplane.Framing{
+		LLCSNAP:   cfg.Framing.Encapsulation == config.EncapLLCSNAP,
+		EtherType: cfg.Framing.EtherType,
+	}
+	if err := ctx.SetFraming(framing); err != nil {
+		ctx.Close()
+		return nil, fmt.Errorf("configure framing: %w", err)
+	}
+	if cfg.Packet.Enabled() {
+		h, err := packet.Compile(cfg.Packet.Template)
+		if err != nil {
+			ctx.Close()
+			return nil, fmt.Errorf("packet template: %w", err)
+		}
+		err = ctx.SetPacketTemplate(dataplane.PacketTemplate{
+			Header:     h.Bytes,
+			IPv4Offset: h.IPv4Offset,
+			IPv6Offset: h.IPv6Offset,
+			UDPOffset:  h.UDPOffset,
+			FillSrcMAC: h.FillSrcMAC,
+			FillDstMAC: h.FillDstMAC,
+		})
+		if err != nil {
+			ctx.Close()
+			return nil, fmt.Errorf("configure packet template: %w", err)
+		}
+	}
+	return ctx, nil
+}
+
+// setAddressPools draws the address pairs from the configured pools for
+// the run, returning the pool usage, or nil without pools
+func setAddressPools(ctx *dataplane.Context, cfg *config.Config) (*addrpool.Usage, error) {
+	if !cfg.Addressing.Pools.Enabled() {
+		return nil, nil
+	}
+	pools, err := cfg.Addressing.Pools.Parse()
+	if err != nil {
+		return nil, fmt.Errorf("invalid address pools: %w", err)
+	}
+	alloc, err := addrpool.Allocate(pools, cfg.Addressing.Pairs, cfg.Addressing.Seed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to allocate addresses: %w", err)
+	}
+	table := make([]dataplane.AddressPair, len(alloc.Pairs))
+	for i, p := range alloc.Pairs {
+		table[i] = dataplane.AddressPair{SrcMAC: p.SrcMAC, DstMAC: p.DstMAC, SrcIP: p.SrcIP, DstIP: p.DstIP}
+	}
+	if err := ctx.SetAddressTable(table); err != nil {
+		return nil, fmt.Errorf("failed to configure address pools: %w", err)
+	}
+	fmt.Printf("Address pools: %s\n", alloc.Usage)
+	return &alloc.Usage, nil
+}
+
+// ethFrameOverhead is the bytes of a frame outside its MTU: the MAC
+// header and FCS
+const ethFrameOverhead = 18
+
+// testIPMTU is the IP MTU the run's largest frame needs
+func testIPMTU(cfg *config.Config) int {
+	var largest uint32
+	for _, fs := range cfg.TestFrameSizes() {
+		largest = max(largest, fs)
+	}
+	return int(largest) - ethFrameOverhead - cfg.Framing.Tags()*config.VLANTagLen - len(cfg.Framing.MPLSLabels)*config.MPLSLabelLen
+}
+
+// runPreQualification pings, traces and probes the path MTU to each target.
+// A jumbo sweep probes up to the MTU its largest frame needs and warns of
+// a path that carries less. It reports cancelled if interrupted.
+func runPreQualification(cfg *config.Config, sigCh chan os.Signal) (*prequal.Report, bool) {
+	pq := cfg.PreQual
+	needMTU := testIPMTU(cfg)
+	checker := prequal.NewChecker(prequal.Options{
+		Count:      pq.Count,
+		Timeout:    pq.Timeout,
+		Traceroute: pq.Traceroute,
+		MaxHops:    pq.MaxHops,
+		PathMTU:    pq.PathMTU,
+		MaxMTU:     max(pq.MaxMTU, needMTU),
+	})
+
+	fmt.Printf("Pre-qualification (%d targets)...\n", len(pq.Targets))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan *prequal.Report, 1)
+	go func() { done <- checker.Check(ctx, pq.Targets) }()
+
+	var report *prequal.Report
+	select {
+	case report = <-done:
+	case <-sigCh:
+		fmt.Println("\nCancelling...")
+		cancel()
+		<-done
+		return nil, true
+	}
+
+	for _, r := range report.Targets {
+		status := "OK"
+		if !r.Reachable {
+			status = "FAIL"
+		}
+		fmt.Printf("  [%-4s] %-24s %s\n", status, r.Target, r.Summary())
+		if r.PathMTU > 0 && r.PathMTU < needMTU {
+			log.Printf("Warning: the path to %s carries a %d-byte MTU, below the %d the largest test frames need",
+				r.Target, r.PathMTU, needMTU)
+		}
+	}
+	fmt.Println()
+	return report, false
+}
+
+// newSnapshotter builds the DUT config snapshotter from the config
+func newSnapshotter(cfg *config.Config) *dutconfig.Snapshotter {
+	opts := dutconfig.Options{
+		SSH:      cfg.DUTSnapshot.SSH,
+		Commands: cfg.DUTSnapshot.Commands,
+		Timeout:  cfg.DUTSnapshot.Timeout,
+	}
+	for _, re := range cfg.DUTSnapshot.Ignore {
+		opts.Ignore = append(opts.Ignore, regexp.MustCompile(re)) // Checked by Validate
+	}
+	return dutconfig.New(opts)
+}
+
+// takeSnapshot runs the snapshot commands, warning about any that fail
+func takeSnapshot(s *dutconfig.Snapshotter) dutconfig.Snapshot {
+	snap := s.Take(context.Background())
+	for _, o := range snap.Outputs {
+		if o.Error != "" {
+			log.Printf("Warning: DUT snapshot %q: %s", o.Command, o.Error)
+		}
+	}
+	return snap
+}
+
+// enableRemoteLoopback discovers far-end OAM peers and places the configured
+// (or first capable) peer into 802.3ah remote loopback
+func enableRemoteLoopback(ctx *dataplane.Context, cfg *config.Config) (*oamRun, error) {
+	fmt.Printf("Discovering OAM peers (MEG level %d)...\n", cfg.OAM.MEGLevel)
+	peers, err := ctx.DiscoverOAMPeers(cfg.OAM.MEGLevel, cfg.OAM.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	run := &oamRun{Peers: peers}
+	for _, p := range peers {
+		fmt.Printf("  %s  802.3ah=%v remote_loopback=%v y1731_lbr=%v\n",
+			p.MAC, p.Dot3ah, p.RemoteLoopback, p.Y1731Loopback)
+	}
+
+	peer, err := selectLoopbackPeer(peers, cfg.OAM.PeerMAC)
+	if err != nil {
+		return run, err
+	}
+	if err := ctx.SetRemoteLoopback(peer, true, cfg.OAM.Timeout); err != nil {
+		return run, err
+	}
+	run.LoopedPeer = peer
+	fmt.Printf("Remote loopback active on %s\n\n", peer)
+	return run, nil
+}
+
+// releaseRemoteLoopback takes the far end out of loopback. It is safe to
+// call more than once.
+func releaseRemoteLoopback(ctx *dataplane.Context, cfg *config.Config, run *oamRun) {
+	if run == nil || run.LoopedPeer == "" || run.Released {
+		return
+	}
+	if err := ctx.SetRemoteLoopback(run.LoopedPeer, false, cfg.OAM.Timeout); err != nil {
+		log.Printf("Warning: far end %s may still be in loopback: %v", run.LoopedPeer, err)
+		return
+	}
+	run.Released = true
+	fmt.Printf("Remote loopback released on %s\n", run.LoopedPeer)
+}
+
+// startPprof serves the pprof endpoints in the background. A failure to
+// listen is logged rather than fatal, since profiling is a diagnostic.
+func startPprof(cfg *config.Config) {
+	srv := &http.Server{Addr: cfg.Profile.PprofAddress, Handler: selfprof.Handler()}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Printf("pprof server error: %v", err)
+		}
+	}()
+}
+
+// printSelfProfile summarizes the tester's own resource use, so a result
+// can be shown not to be limited by the generator host
+func printPortStats(ports []dataplane.PortStats) {
+	fmt.Println("\nPort counters (all frames):")
+	for _, p := range ports {
+		fmt.Printf("  %-12s TX %d  RX %d  errors TX %d / RX %d\n", p.Interface, p.TxPackets, p.RxPackets, p.TxErrors, p.RxErrors)
+	}
+}
+
+func printSelfProfile(r *selfprof.Report) {
+	const mib = 1 << 20
+	fmt.Printf("\nTester resources (%d samples, %d CPUs):\n", len(r.Samples), r.NumCPU)
+	fmt.Printf("  CPU: avg %.1f%%  peak %.1f%% (100%% = one core)\n", r.AvgCPUPct, r.PeakCPUPct)
+	fmt.Printf("  Memory: peak RSS %.1f MiB  peak Go heap %.1f MiB  goroutines %d\n",
+		float64(r.PeakRSSBytes)/mib, float64(r.PeakHeapBytes)/mib, r.MaxGoroutines)
+}
+
+// startGNMI serves the live counters of the dataplane context current
+// returns as a gNMI target, polling them once a second. The returned
+// function stops the poller and server.
+func startGNMI(current func() *dataplane.Context, cfg *config.Config) func() {
+	var opts []gnmi.Option
+	if cfg.GNMI.CertFile != "" {
+		opts = append(opts, gnmi.WithTLSFiles(cfg.GNMI.CertFile, cfg.GNMI.KeyFile))
+	}
+	srv := gnmi.NewServer(cfg.GNMI.Address, opts...)
+	go func() {
+		if err := srv.Start(); err != nil && err != http.ErrServerClosed {
+			log.Printf("gNMI target error: %v", err)
+		}
+	}()
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		ctx := current()
+		prev, prevAt := ctx.LiveStats(), time.Now()
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				// A context the watchdog restarted counts from zero
+				if c := current(); c != ctx {
+					ctx, prev, prevAt = c, c.LiveStats(), now
+					continue
+				}
+				live := ctx.LiveStats()
+				srv.Update(gnmiSnapshot(cfg, ctx.State(), live, prev, now.Sub(prevAt), now))
+				prev, prevAt = live, now
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		srv.Stop()
+	}
+}
+
+// gnmiSnapshot converts live counters to the gNMI data model, deriving
+// rates from the change since the previous poll
+func gnmiSnapshot(cfg *config.Config, state dataplane.TestState, live, prev dataplane.LiveStats,
+	elapsed time.Duration, now time.Time) gnmi.Snapshot {
+	snap := gnmi.Snapshot{
+		Interface:      cfg.Interface,
+		TestType:       string(cfg.TestType),
+		Status:         testStateName(state),
+		FrameSize:      live.FrameSize,
+		OfferedRatePct: live.OfferedRatePct,
+		Trials:         live.Trials,
+		TxPackets:      live.TxPackets,
+		TxOctets:       live.TxBytes,
+		RxPackets:      live.RxPackets,
+		RxOctets:       live.RxBytes,
+		LossPct:        live.LastLossPct,
+		LatencyMinNs:   live.LastLatency.MinNs,
+		LatencyAvgNs:   live.LastLatency.AvgNs,
+		LatencyMaxNs:   live.LastLatency.MaxNs,
+		LatencyP99Ns:   live.LastLatency.P99Ns,
+		Timestamp:      now,
+	}
+	if secs := elapsed.Seconds(); secs > 0 && live.TxBytes >= prev.TxBytes && live.RxBytes >= prev.RxBytes {
+		snap.TxMbps = float64(live.TxBytes-prev.TxBytes) * 8 / secs / 1e6
+		snap.RxMbps = float64(live.RxBytes-prev.RxBytes) * 8 / secs / 1e6
+	}
+	return snap
+}
+
+// testStateName returns the telemetry name of a dataplane state
+func testStateName(state dataplane.TestState) string {
+	switch state {
+	case dataplane.StateRunning:
+		return "running"
+	case dataplane.StateCompleted:
+		return "complete"
+	case dataplane.StateFailed:
+		return "error"
+	case dataplane.StateCancelled:
+		return "cancelled"
+	}
+	return "idle"
+}
+
+// selectLoopbackPeer returns want if it was discovered with remote loopback
+// support, or the first such peer when want is empty
+func selectLoopbackPeer(peers []dataplane.OAMPeer, want string) (string, error) {
+	if want != "" {
+		hw, err := net.ParseMAC(want)
+		if err != nil {
+			return "", fmt.Errorf("invalid peer MAC %q", want)
+		}
+		want = hw.String()
+	}
+	for _, p := range peers {
+		if !p.RemoteLoopback {
+			continue
+		}
+		if want == "" || p.MAC == want {
+			return p.MAC, nil
+		}
+	}
+	if want != "" {
+		return "", fmt.Errorf("peer %s not found or does not support remote loopback", want)
+	}
+	return "", fmt.Errorf("no peer supporting 802.3ah remote loopback found")
+}
+
+// runRFC2544Test runs one RFC 2544 test at the current frame size and prints its result
+func runRFC2544Test(runCtx context.Context, ctx backend.Backend, cfg *config.Config, fs uint32) (interface{}, error) {
+	switch cfg.TestType {
+	case config.TestThroughput:
+		fmt.Printf("  Running throughput test (%s)...\n", searchDescription(cfg.Throughput))
+		result, err := runThroughput(runCtx, ctx, cfg, cfg.TestType, fs)
+		if err != nil {
+			return nil, err
+		}
+		printThroughputResult(result, fs)
+		return result, nil
+
+	case config.TestLatency:
+		fmt.Printf("  Running latency test...\n")
+		results, err := ctx.RunLatencyTest(runCtx, cfg.Latency.LoadLevels)
+		if err != nil {
+			return nil, err
+		}
+		printLatencyResults(results, fs)
+		return results, nil
+
+	case config.TestFrameLoss:
+		if fl := cfg.FrameLoss; fl.Search() {
+			fmt.Printf("  Running frame loss search for loss <= %.4g%%...\n", fl.SearchThresholdPct)
+		} else {
+			fmt.Printf("  Running frame loss test...\n")
+		}
+		results, err := runFrameLoss(runCtx, ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+		printFrameLossResults(results, fs)
+		if cfg.FrameLoss.Search() && !hasPartialDropRate(results) {
+			fmt.Printf("    No load from %.1f%% down meets the %.4g%% loss threshold\n", cfg.FrameLoss.EndPct, cfg.FrameLoss.SearchThresholdPct)
+		}
+		return results, nil
+
+	case config.TestBackToBack:
+		fmt.Printf("  Running back-to-back test...\n")
+		result, err := ctx.RunBackToBackTest(runCtx, cfg.BackToBack.InitialBurst, cfg.BackToBack.Trials)
+		if err != nil {
+			return nil, err
+		}
+		printBackToBackResult(result, fs)
+		return result, nil
+
+	case config.TestSystemRecovery:
+		fmt.Printf("  Running system recovery test (Section 26.5)...\n")
+		// Use provided throughput or default to 100%
+		throughputPct := recoveryThroughput
+		if throughputPct == 0 {
+			throughputPct = 100.0
+		}
+		result, err := ctx.RunSystemRecoveryTest(runCtx, throughputPct, cfg.Recovery.OverloadSec)
+		if err != nil {
+			return nil, err
+		}
+		printRecoveryResult(result, fs)
+		return result, nil
+
+	case config.TestReset:
+		fmt.Printf("  Running reset test (Section 26.6)...\n")
+		fmt.Printf("  NOTE: This test requires manual device reset trigger\n")
+		result, err := ctx.RunResetTest(runCtx)
+		if err != nil {
+			return nil, err
+		}
+		printResetResult(result, fs)
+		return result, nil
+
+	case config.TestSuite:
+		result := runSuite(runCtx, ctx, cfg, fs)
+		printSuiteResult(result)
+		return result, nil
+
+	case config.TestCharacterize:
+		result, err := runCharacterize(runCtx, ctx, cfg, fs)
+		if err != nil {
+			return nil, err
+		}
+		printCharacterizeResult(result)
+		return result, nil
+	}
+
+	return nil, fmt.Errorf("not an RFC 2544 test: %s", cfg.TestType)
+}
+
+// runThroughput runs the throughput test with the configured search, for
+// test's acceptable loss. The binary search is the backend's own; linear
+// and hybrid step through fixed-rate trials.
+func runThroughput(runCtx context.Context, ctx backend.Backend, cfg *config.Config, test config.TestType, fs uint32) (*dataplane.ThroughputResultCLI, error) {
+	tp := cfg.Throughput
+	if tp.SearchAlgorithm != config.SearchLinear && tp.SearchAlgorithm != config.SearchHybrid {
+		return ctx.RunThroughputTest(runCtx)
+	}
+	return search.Throughput(runCtx, ctx, tp, tp.AcceptableLossFor(test, fs), cfg.TrialDuration, fs)
+}
+
+// searchDescription names the throughput search for progress output
+func searchDescription(tp config.ThroughputConfig) string {
+	switch tp.SearchAlgorithm {
+	case config.SearchLinear, config.SearchHybrid:
+		return fmt.Sprintf("%s search, %.4g%% steps", tp.SearchAlgorithm, tp.StepPct)
+	}
+	return "binary search"
+}
+
+// nonMonotonic finds a failed trial below the highest passing rate, where
+// loss does not grow with rate and a binary search is unreliable
+func nonMonotonic(trials []dataplane.ThroughputTrial) (passPct, failPct float64, ok bool) {
+	for _, t := range trials {
+		if t.Passed && t.RatePct > passPct {
+			passPct = t.RatePct
+		}
+	}
+	for _, t := range trials {
+		if !t.Passed && t.RatePct < passPct && t.RatePct > failPct {
+			failPct, ok = t.RatePct, true
+		}
+	}
+	return passPct, failPct, ok
+}
+
+// runFrameLoss runs the frame loss test: the step sweep, or with a search
+// threshold the partial drop rate search
+func runFrameLoss(runCtx context.Context, ctx backend.Backend, cfg *config.Config) ([]dataplane.FrameLossResultCLI, error) {
+	fl := cfg.FrameLoss
+	switch {
+	case fl.Search():
+		return search.PartialDropRate(runCtx, ctx, fl, cfg.TrialDuration)
+	case fl.LosslessTrials > 0:
+		return sweepToLossless(runCtx, ctx, fl, cfg.TrialDuration)
+	}
+	return ctx.RunFrameLossTest(runCtx, fl.StartPct, fl.EndPct, fl.StepPct)
+}
+
+// sweepToLossless steps start_pct down to end_pct one trial at a time,
+// stopping once lossless_trials in a row lose no frames
+func sweepToLossless(runCtx context.Context, ctx backend.Backend, fl config.FrameLossConfig, duration time.Duration) ([]dataplane.FrameLossResultCLI, error) {
+	var results []dataplane.FrameLossResultCLI
+	var lossless uint32
+	for pct := fl.StartPct; pct > 0 && pct >= fl.EndPct && lossless < fl.LosslessTrials; pct -= fl.StepPct {
+		r, err := ctx.RunFixedRateTrial(runCtx, pct, duration)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, dataplane.FrameLossResultCLI{
+			FrameSize:  r.FrameSize,
+			OfferedPct: pct,
+			FramesTx:   r.FramesTx,
+			FramesRx:   r.FramesRx,
+			LossPct:    r.LossPct,
+			FrameOrder: r.FrameOrder,
+			Streams:    r.Streams,
+		})
+		if r.LossPct == 0 {
+			lossless++
+		} else {
+			lossless = 0
+		}
+	}
+	return results, nil
+}
+
+// hasPartialDropRate reports whether a frame loss search found a load
+// within its threshold
+func hasPartialDropRate(results []dataplane.FrameLossResultCLI) bool {
+	for _, r := range results {
+		if r.PartialDropRate {
+			return true
+		}
+	}
+	return false
+}
+
+// pdrMark labels the partial drop rate row of frame loss output
+func pdrMark(r dataplane.FrameLossResultCLI) string {
+	if r.PartialDropRate {
+		return "  <- partial drop rate"
+	}
+	return ""
+}
+
+// throughputOf returns the throughput measured by a result, or nil
+func throughputOf(result interface{}) *dataplane.ThroughputResultCLI {
+	switch r := result.(type) {
+	case *dataplane.ThroughputResultCLI:
+		return r
+	case *suiteResult:
+		return r.Throughput
+	case *characterizeResult:
+		return r.Throughput
+	}
+	return nil
+}
+
+// suiteTests are the tests a suite runs, in order
+var suiteTests = []config.TestType{
+	config.TestThroughput, config.TestLatency, config.TestFrameLoss,
+	config.TestBackToBack, config.TestSystemRecovery, config.TestReset,
+}
+
+// suiteResult is the full RFC 2544 suite at one frame size. Latency loads
+// are the configured levels scaled to the measured throughput, so they
+// are reported in % of line rate like the recovery overload rate.
+type suiteResult struct {
+	FrameSize     uint32                         `json:"frame_size"`
+	ThroughputPct float64                        `json:"throughput_pct"` // Carried into latency and recovery
+	Throughput    *dataplane.ThroughputResultCLI `json:"throughput,omitempty"`
+	Latency       []dataplane.LatencyResultCLI   `json:"latency,omitempty"`
+	FrameLoss     []dataplane.FrameLossResultCLI `json:"frame_loss,omitempty"`
+	BackToBack    *dataplane.BackToBackResultCLI `json:"back_to_back,omitempty"`
+	Recovery      *dataplane.RecoveryResultCLI   `json:"system_recovery,omitempty"`
+	Reset         *dataplane.ResetResultCLI      `json:"reset,omitempty"`
+	Errors        map[config.TestType]string     `json:"errors,omitempty"` // Tests that failed or were skipped
+}
+
+// Tests returns suiteTests
+func (s *suiteResult) Tests() []config.TestType { return suiteTests }
+
+// Result returns the suite's result for one test in the form the
+// standalone test stores it, or nil if it did not run
+func (s *suiteResult) Result(t config.TestType) interface{} {
+	switch {
+	case t == config.TestThroughput && s.Throughput != nil:
+		return s.Throughput
+	case t == config.TestLatency && s.Latency != nil:
+		return s.Latency
+	case t == config.TestFrameLoss && s.FrameLoss != nil:
+		return s.FrameLoss
+	case t == config.TestBackToBack && s.BackToBack != nil:
+		return s.BackToBack
+	case t == config.TestSystemRecovery && s.Recovery != nil:
+		return s.Recovery
+	case t == config.TestReset && s.Reset != nil:
+		return s.Reset
+	}
+	return nil
+}
+
+// Failures lists the tests that failed or were skipped, in suite order
+func (s *suiteResult) Failures() []htmlreport.Failure {
+	var f []htmlreport.Failure
+	for _, t := range suiteTests {
+		if msg, ok := s.Errors[t]; ok {
+			f = append(f, htmlreport.Failure{FrameSize: s.FrameSize, Test: t, Error: msg})
+		}
+	}
+	return f
+}
+
+func (s *suiteResult) fail(t config.TestType, err error) {
+	log.Printf("  %s error: %v", t, err)
+	s.note(t, err.Error())
+}
+
+// skip records a test that needs a throughput result when there is none
+func (s *suiteResult) skip(t config.TestType) {
+	fmt.Printf("  Skipping %s test: no throughput measured\n", t)
+	s.note(t, "skipped: no throughput measured")
+}
+
+func (s *suiteResult) note(t config.TestType, msg string) {
+	if s.Errors == nil {
+		s.Errors = make(map[config.TestType]string)
+	}
+	s.Errors[t] = msg
+}
+
+// runSuite runs the six RFC 2544 tests in sequence. The throughput found
+// first is the rate latency (Section 26.2) and system recovery (Section
+// 26.5) are measured against; if it fails, those two are skipped and the
+// remaining tests still run.
+func runSuite(runCtx context.Context, ctx backend.Backend, cfg *config.Config, fs uint32) *suiteResult {
+	suite := &suiteResult{FrameSize: fs}
+	run := func(t config.TestType) (interface{}, error) {
+		c := *cfg
+		c.TestType = t
+		return runRFC2544Test(runCtx, ctx, &c, fs)
+	}
+
+	if r, err := run(config.TestThroughput); err != nil {
+		suite.fail(config.TestThroughput, err)
+	} else {
+		suite.Throughput = r.(*dataplane.ThroughputResultCLI)
+		suite.ThroughputPct = suite.Throughput.MaxRatePct
+	}
+
+	if suite.ThroughputPct > 0 {
+		fmt.Printf("  Running latency test at %.2f%% throughput...\n", suite.ThroughputPct)
+		loads := make([]float64, len(cfg.Latency.LoadLevels))
+		for i, l := range cfg.Latency.LoadLevels {
+			loads[i] = l * suite.ThroughputPct / 100
+		}
+		if results, err := ctx.RunLatencyTest(runCtx, loads); err != nil {
+			suite.fail(config.TestLatency, err)
+		} else {
+			printLatencyResults(results, fs)
+			suite.Latency = results
+		}
+	} else {
+		suite.skip(config.TestLatency)
+	}
+
+	if r, err := run(config.TestFrameLoss); err != nil {
+		suite.fail(config.TestFrameLoss, err)
+	} else {
+		suite.FrameLoss = r.([]dataplane.FrameLossResultCLI)
+	}
+
+	if r, err := run(config.TestBackToBack); err != nil {
+		suite.fail(config.TestBackToBack, err)
+	} else {
+		suite.BackToBack = r.(*dataplane.BackToBackResultCLI)
+	}
+
+	if suite.ThroughputPct > 0 {
+		fmt.Printf("  Running system recovery test at %.2f%% throughput (Section 26.5)...\n", suite.ThroughputPct)
+		if r, err := ctx.RunSystemRecoveryTest(runCtx, suite.ThroughputPct, cfg.Recovery.OverloadSec); err != nil {
+			suite.fail(config.TestSystemRecovery, err)
+		} else {
+			printRecoveryResult(r, fs)
+			suite.Recovery = r
+		}
+	} else {
+		suite.skip(config.TestSystemRecovery)
+	}
+
+	if r, err := run(config.TestReset); err != nil {
+		suite.fail(config.TestReset, err)
+	} else {
+		suite.Reset = r.(*dataplane.ResetResultCLI)
+	}
+
+	return suite
+}
+
+// multiResult holds the results of several tests at one frame size, as
+// a suite or a characterization does
+type multiResult interface {
+	Result(t config.TestType) interface{}
+}
+
+// characterizeTests are the tests a characterization runs, in order
+var characterizeTests = []config.TestType{config.TestThroughput, config.TestLatency}
+
+// characterizeResult is the throughput at one frame size and the latency
+// at each of the configured latency loads as a share of it: what a
+// throughput run looks like followed by a latency run configured from
+// its result.
+type characterizeResult struct {
+	FrameSize     uint32                         `json:"frame_size"`
+	ThroughputPct float64                        `json:"throughput_pct"` // Rate the latency loads are shares of
+	Throughput    *dataplane.ThroughputResultCLI `json:"throughput"`
+	Latency       []characterizePoint            `json:"latency"`
+}
+
+// characterizePoint is the latency at a share of the throughput. LoadPct
+// is the load it makes, in % of line rate.
+type characterizePoint struct {
+	SharePct float64 `json:"share_pct"` // Of the throughput
+	dataplane.LatencyResultCLI
+}
+
+// Tests returns characterizeTests
+func (r *characterizeResult) Tests() []config.TestType { return characterizeTests }
+
+// Failures returns nil: a characterization that fails stores no result
+func (r *characterizeResult) Failures() []htmlreport.Failure { return nil }
+
+// Result returns the characterization's result for one test in the form
+// the standalone test stores it, or nil if it has none
+func (r *characterizeResult) Result(t config.TestType) interface{} {
+	switch {
+	case t == config.TestThroughput && r.Throughput != nil:
+		return r.Throughput
+	case t == config.TestLatency && len(r.Latency) > 0:
+		lrs := make([]dataplane.LatencyResultCLI, len(r.Latency))
+		for i, p := range r.Latency {
+			lrs[i] = p.LatencyResultCLI
+		}
+		return lrs
+	}
+	return nil
+}
+
+// runCharacterize searches for the throughput, then measures the latency
+// at each latency load level taken as a share of it
+func runCharacterize(runCtx context.Context, ctx backend.Backend, cfg *config.Config, fs uint32) (*characterizeResult, error) {
+	fmt.Printf("  Running throughput test (%s)...\n", searchDescription(cfg.Throughput))
+	t, err := runThroughput(runCtx, ctx, cfg, cfg.TestType, fs)
+	if err != nil {
+		return nil, err
+	}
+	printThroughputResult(t, fs)
+	if t.MaxRatePct <= 0 {
+		return nil, fmt.Errorf("no throughput measured to characterize latency at")
+	}
+
+	loads := make([]float64, len(cfg.Latency.LoadLevels))
+	shares := make(map[float64]float64, len(loads))
+	for i, l := range cfg.Latency.LoadLevels {
+		loads[i] = l * t.MaxRatePct / 100
+		shares[loads[i]] = l
+	}
+	fmt.Printf("  Running latency test at %.2f%% throughput...\n", t.MaxRatePct)
+	lrs, err := ctx.RunLatencyTest(runCtx, loads)
+	if err != nil {
+		return nil, fmt.Errorf("latency at %.2f%% throughput: %w", t.MaxRatePct, err)
+	}
+
+	r := &characterizeResult{FrameSize: fs, ThroughputPct: t.MaxRatePct, Throughput: t}
+	for _, lr := range lrs {
+		r.Latency = append(r.Latency, characterizePoint{SharePct: shares[lr.LoadPct], LatencyResultCLI: lr})
+	}
+	return r, nil
+}
+
+// modifierRun is an RFC 2544 test repeated under the Section 11 modifiers
+type modifierRun struct {
+	FrameSize    uint32      `json:"frame_size"`
+	BroadcastPct float64     `json:"broadcast_pct"`
+	Management   *mgmt.Stats `json:"management,omitempty"`
+	Result       interface{} `json:"result"`
+}
+
+// runModifiedTest repeats the test with broadcast and management traffic
+// applied, then prints the effect against the baseline result
+func runModifiedTest(runCtx context.Context, ctx *dataplane.Context, cfg *config.Config, fs uint32, baseline interface{}) (*modifierRun, error) {
+	mods := cfg.Modifiers
+	fmt.Printf("  Repeating with Section 11 modifiers (broadcast %.2f%%)...\n", mods.BroadcastPct)
+
+	if err := ctx.SetModifiers(dataplane.Modifiers{BroadcastPct: mods.BroadcastPct}); err != nil {
+		return nil, err
+	}
+	defer ctx.SetModifiers(dataplane.Modifiers{})
+
+	var poller *mgmt.Poller
+	if mods.ManagementTarget != "" {
+		poller = mgmt.NewPoller(mods.ManagementTarget, mods.Community, mods.ManagementPerSec)
+		if err := poller.Start(); err != nil {
+			return nil, fmt.Errorf("management traffic: %w", err)
+		}
+	}
+
+	result, err := runRFC2544Test(runCtx, ctx, cfg, fs)
+
+	run := &modifierRun{FrameSize: fs, BroadcastPct: mods.BroadcastPct, Result: result}
+	if poller != nil {
+		stats := poller.Stop()
+		run.Management = &stats
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	printModifierEffect(run, baseline)
+	return run, nil
+}
+
+// controlPlaneRun is one RFC 2544 test repeated under control-plane stress
+type controlPlaneRun struct {
+	FrameSize      uint32                      `json:"frame_size"`
+	Stats          dataplane.ControlPlaneStats `json:"stats"`
+	DegradationPct *float64                    `json:"throughput_degradation_pct,omitempty"`
+	Pass           bool                        `json:"pass"`
+	Result         interface{}                 `json:"result"`
+}
+
+// runControlPlaneTest repeats the test while ICMP, ARP and BGP-port traffic
+// is aimed at the DUT, then compares forwarding against the baseline
+func runControlPlaneTest(runCtx context.Context, ctx *dataplane.Context, cfg *config.Config, fs uint32, baseline interface{}) (*controlPlaneRun, error) {
+	cp := cfg.ControlPlane
+	fmt.Printf("  Repeating under control-plane stress (ICMP %d, ARP %d, BGP %d pps to %s)...\n",
+		cp.ICMPPerSec, cp.ARPPerSec, cp.BGPPerSec, cp.DUTIP)
+
+	stress := dataplane.ControlPlaneStress{
+		DUTIP:      net.ParseIP(cp.DUTIP),
+		ICMPPerSec: cp.ICMPPerSec,
+		ARPPerSec:  cp.ARPPerSec,
+		BGPPerSec:  cp.BGPPerSec,
+	}
+	if cp.DUTMAC != "" {
+		stress.DUTMAC, _ = net.ParseMAC(cp.DUTMAC)
+	}
+	if err := ctx.SetControlPlaneStress(stress); err != nil {
+		return nil, err
+	}
+	defer ctx.SetControlPlaneStress(dataplane.ControlPlaneStress{})
+
+	result, err := runRFC2544Test(runCtx, ctx, cfg, fs)
+	if err != nil {
+		return nil, err
+	}
+
+	run := &controlPlaneRun{FrameSize: fs, Stats: ctx.ControlPlaneStats(), Pass: true, Result: result}
+	if b := throughputOf(baseline); b != nil {
+		if m := throughputOf(result); m != nil && b.MaxRatePct > 0 {
+			drop := 100.0 * (b.MaxRatePct - m.MaxRatePct) / b.MaxRatePct
+			run.DegradationPct = &drop
+			run.Pass = drop <= cp.MaxDegradationPct
+		}
+	}
+
+	printControlPlaneEffect(run, cp.MaxDegradationPct)
+	return run, nil
+}
+
+// powerRun is the DUT power drawn while one frame size was tested
+type powerRun struct {
+	FrameSize      uint32      `json:"frame_size"`
+	Power          power.Stats `json:"power"`
+	ThroughputMbps float64     `json:"throughput_mbps,omitempty"`
+	MbpsPerWatt    float64     `json:"mbps_per_watt,omitempty"`
+}
+
+// startPowerSampler starts sampling DUT power if configured, or returns nil
+func startPowerSampler(cfg *config.Config) *power.Sampler {
+	p := cfg.Power
+	var src power.Source
+	switch p.Source {
+	case config.PowerSourceIPMI:
+		src = power.NewIPMISource(p.IPMIHost, p.IPMIUser, p.IPMIPassword)
+	case config.PowerSourceCommand:
+		src = &power.CommandSource{Command: p.Command, Scale: p.Scale}
+	default:
+		return nil
+	}
+	sampler := power.NewSampler(src, p.Interval)
+	sampler.Start()
+	return sampler
+}
+
+// stopPowerSampler stops sampling and relates the power drawn to the
+// throughput the result achieved
+func stopPowerSampler(sampler *power.Sampler, fs uint32, result interface{}) *powerRun {
+	if sampler == nil {
+		return nil
+	}
+	run := &powerRun{FrameSize: fs, Power: sampler.Stop()}
+
+	switch r := result.(type) {
+	case *dataplane.ThroughputResultCLI, *suiteResult, *characterizeResult:
+		if t := throughputOf(r); t != nil {
+			run.ThroughputMbps = t.MaxRateMbps
+		}
+	case *drift.Report:
+		for _, b := range r.Buckets {
+			run.ThroughputMbps += b.ThroughputMbps / float64(len(r.Buckets))
+		}
+	}
+	run.MbpsPerWatt = run.Power.MbpsPerWatt(run.ThroughputMbps)
+
+	printPowerRun(run)
+	return run
+}
+
+// runSoakTest offers traffic at a fixed rate in back-to-back sample trials
+// for the soak duration, tracking throughput and latency per bucket so
+// thermal or resource-related degradation shows up as drift. With retune,
+// a change of rate or frame size applies from the next sample without
+// ending the soak.
+func runSoakTest(runCtx context.Context, ctx backend.Backend, cfg *config.Config, fs uint32, retune *soakRetune) (*drift.Report, error) {
+	soak := cfg.Soak
+	rate := soak.RatePct
+	sizes, err := testFrameSizes(cfg)
+	if err != nil {
+		return nil, err
+	}
+	sweep := len(sizes) > 1
+	fmt.Printf("  Running soak test: %.1f%% for %s (%s samples, %s buckets)...\n",
+		soak.RatePct, soak.Duration, soak.SampleDuration, soak.BucketInterval)
+
+	tracker := drift.NewTracker(soak.BucketInterval)
+	report := &drift.Report{FrameSize: fs, RatePct: soak.RatePct}
+	deadline := time.Now().Add(soak.Duration)
+	bucketsSeen := 0
+	var latency []heatmap.Sample
+
+	var series *drift.Series
+	if path := soakSeriesFile(cfg, fs, sweep); path != "" {
+		s, err := drift.CreateSeries(path)
+		if err != nil {
+			return nil, fmt.Errorf("soak time series: %w", err)
+		}
+		defer s.Close()
+		series, report.SeriesFile = s, path
+		fmt.Printf("  Time series: %s\n", path)
+	}
+
+	for time.Now().Before(deadline) && runCtx.Err() == nil {
+		if c, ok := retune.next(cfg.Interface, rate, fs); ok {
+			c.Sample = report.Samples
+			ctx.SetFrameSize(c.FrameSize)
+			rate, fs = c.RatePct, c.FrameSize
+			tracker.Mark(c.Time)
+			report.Changes = append(report.Changes, c)
+			fmt.Printf("  Soak reconfigured at sample %d: %.1f%% in %d-byte frames\n", c.Sample, rate, fs)
+		}
+
+		start := time.Now()
+		r, err := ctx.RunFixedRateTrial(runCtx, rate, soak.SampleDuration)
+		if err != nil {
+			if report.Samples == 0 {
+				return nil, err
+			}
+			log.Printf("  Soak sample error: %v", err)
+			break
+		}
+		report.Samples++
+		sample := drift.Sample{
+			Time:           start,
+			OfferedPct:     r.OfferedPct,
+			ThroughputMbps: r.DeliveredMbps,
+			FramesTx:       r.FramesTx,
+			FramesRx:       r.FramesRx,
+			LossPct:        r.LossPct,
+			P50Us:          r.Latency.P50Ns / 1000,
+			P95Us:          r.Latency.P95Ns / 1000,
+			P99Us:          r.Latency.P99Ns / 1000,
+			MaxUs:          r.Latency.MaxNs / 1000,
+		}
+		tracker.Add(sample)
+		if series != nil {
+			if err := series.Write(sample); err != nil {
+				// Keep soaking; the buckets still get the samples
+				log.Printf("  Soak time series error, no longer writing it: %v", err)
+				series = nil
+			}
+		}
+		if cfg.Heatmap.Enabled() {
+			latency = append(latency, heatmapSample(start, r.Latency))
+		}
+
+		// Report each bucket once it closes
+		if b := tracker.Buckets(); len(b) > bucketsSeen+1 {
+			for _, done := range b[bucketsSeen : len(b)-1] {
+				printSoakBucket(done)
+			}
+			bucketsSeen = len(b) - 1
+		}
+	}
+
+	report.Buckets = tracker.Buckets()
+	report.Summary = tracker.Summarize(drift.Thresholds{
+		MaxThroughputDriftPct: soak.MaxThroughputDriftPct,
+		MaxLatencyDriftPct:    soak.MaxLatencyDriftPct,
+	})
+	if len(report.Buckets) > bucketsSeen {
+		printSoakBucket(report.Buckets[len(report.Buckets)-1])
+	}
+	printSoakSummary(report)
+	if cfg.Heatmap.Enabled() {
+		suffix := ""
+		if sweep {
+			suffix = fmt.Sprintf("%dB", report.FrameSize)
+		}
+		writeHeatmap(cfg, soakHeatmapTitle(report), latency, suffix)
+	}
+	return report, nil
+}
+
+// soakRetune is what retunes a running soak in web mode: reloads of the
+// daemon configuration, which was base when the soak started, and the
+// rates POST /api/soak/rate sends
+type soakRetune struct {
+	base  *config.Config
+	live  func() *config.Config
+	rates <-chan float64
+}
+
+// next returns the rate and frame size a soak on iface, now at rate and
+// fs, takes from its next sample, and whether they changed. A nil
+// soakRetune changes nothing.
+func (t *soakRetune) next(iface string, rate float64, fs uint32) (drift.Change, bool) {
+	c, ok := drift.Change{Time: time.Now(), RatePct: rate, FrameSize: fs}, false
+	if t == nil {
+		return c, false
+	}
+	if next := t.live(); next != t.base {
+		c, ok = soakChange(t.base, next, iface, rate, fs)
+		t.base = next
+	}
+	select {
+	case r := <-t.rates:
+		c.RatePct = r
+		ok = c.RatePct != rate || c.FrameSize != fs
+	default:
+	}
+	return c, ok
+}
+
+// soakHeatmapTitle titles a soak's latency heatmap with the rate and frame
+// size it ended at, and those it started at when it was retuned
+func soakHeatmapTitle(r *drift.Report) string {
+	if len(r.Changes) == 0 {
+		return fmt.Sprintf("Soak latency, %d-byte frames at %.1f%%", r.FrameSize, r.RatePct)
+	}
+	last := r.Changes[len(r.Changes)-1]
+	return fmt.Sprintf("Soak latency, %d-byte frames at %.1f%% (retuned %d times from %d-byte at %.1f%%)",
+		last.FrameSize, last.RatePct, len(r.Changes), r.FrameSize, r.RatePct)
+}
+
+// soakChange returns the rate and frame size a reload from cur to next
+// gives a running soak on iface, now at rate and fs. Only what the reload
+// changed applies: the start request, not the daemon config, chose the
+// frame size. A new frame size must pass the checks a soak starting with
+// it would.
+func soakChange(cur, next *config.Config, iface string, rate float64, fs uint32) (drift.Change, bool) {
+	c := drift.Change{Time: time.Now(), RatePct: rate, FrameSize: fs}
+	if r := next.Soak.RatePct; r != cur.Soak.RatePct {
+		if r > 0 && r <= 100 {
+			c.RatePct = r
+		} else {
+			log.Printf("  Soak rate_pct %.1f%% ignored: must be between 0 and 100%%", r)
+		}
+	}
+	if f := next.FrameSize; f != cur.FrameSize && f != 0 {
+		if err := checkSoakFrameSize(next, iface, f); err != nil {
+			log.Printf("  Soak frame_size %d ignored: %v", f, err)
+		} else {
+			c.FrameSize = f
+		}
+	}
+	return c, c.RatePct != rate || c.FrameSize != fs
+}
+
+// checkSoakFrameSize validates fs-byte frames for a soak on iface under
+// cfg, as config validation and the dataplane's capability check would at
+// the start of a run
+func checkSoakFrameSize(cfg *config.Config, iface string, fs uint32) error {
+	soak := *cfg
+	soak.Interface, soak.TestType = iface, config.TestSoak
+	soak.FrameSize, soak.FrameSizes, soak.IncludeJumbo = fs, nil, false
+	if err := soak.Validate(); err != nil {
+		return err
+	}
+	caps, err := dataplane.QueryCapabilities(iface, false)
+	if err != nil {
+		return err
+	}
+	return dataplaneSupports(caps, &soak)
+}
+
+// soakSeriesFile returns the path of a soak's time series at frame size
+// fs: soak.series_file, else beside the output file, e.g. report-series.csv
+// for report.json; "" for none. Each frame size of a sweep gets its own.
+func soakSeriesFile(cfg *config.Config, fs uint32, sweep bool) string {
+	path := cfg.Soak.SeriesFile
+	if path == "" {
+		if outputFile == "" {
+			return ""
+		}
+		path = strings.TrimSuffix(outputFile, filepath.Ext(outputFile)) + "-series.csv"
+	}
+	if sweep {
+		ext := filepath.Ext(path)
+		path = fmt.Sprintf("%s-%dB%s", strings.TrimSuffix(path, ext), fs, ext)
+	}
+	return path
+}
+
+// runQoSTest sends every QoS class at once on TRex and reports how the
+// DUT shares its egress between them
+func runQoSTest(runCtx context.Context, gen *backend.TRex, cfg *config.Config, fs uint32) (*qos.Report, error) {
+	q := cfg.QoS
+	classes := q.ClassList()
+	fmt.Printf("  Running QoS test: %d %s classes at %.1f%% for %v...\n", len(classes), q.Marking, q.LoadPct, cfg.TrialDuration)
+
+	var streams []trex.Class
+	for _, c := range classes {
+		streams = append(streams, trex.Class{
+			Name:     c.Name,
+			Marking:  trex.Marking{Tagged: q.Marking == config.MarkPCP, VLAN: q.VLAN, PCP: c.PCP, DSCP: c.DSCP},
+			SharePct: c.SharePct,
+		})
+	}
+	defer context.AfterFunc(runCtx, gen.Generator().Cancel)()
+	r, err := gen.Generator().QoS(q.LoadPct, streams)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &qos.Report{
+		FrameSize: fs, Marking: q.Marking, LoadPct: q.LoadPct,
+		FramesTx: r.FramesTx, FramesRx: r.FramesRx, LossPct: r.LossPct, DeliveredMbps: r.DeliveredMbps,
+	}
+	for i, c := range r.Classes {
+		report.Classes = append(report.Classes, qos.Class{
+			Name:              c.Name,
+			PCP:               classes[i].PCP,
+			DSCP:              classes[i].DSCP,
+			OfferedSharePct:   c.Class.SharePct,
+			OfferedMbps:       c.OfferedMbps,
+			FramesTx:          c.FramesTx,
+			FramesRx:          c.FramesRx,
+			LossPct:           c.LossPct,
+			DeliveredMbps:     c.DeliveredMbps,
+			DeliveredSharePct: c.SharePct,
+			Latency:           backend.TRexLatency(c.Latency),
+		})
+	}
+	printQoSResult(report)
+	return report, nil
+}
+
+// runAQMTest raises the offered load in fixed-rate steps and records loss
+// and latency at each, then locates the DUT's drop thresholds and slope
+func runAQMTest(runCtx context.Context, ctx backend.Backend, cfg *config.Config, fs uint32) (*aqm.Report, error) {
+	a := cfg.AQM
+	fmt.Printf("  Running AQM test: %.1f%% to %.1f%% in %.1f%% steps of %v...\n", a.StartPct, a.EndPct, a.StepPct, a.StepDuration)
+
+	report := &aqm.Report{FrameSize: fs}
+	for load := a.StartPct; load <= a.EndPct+1e-9 && runCtx.Err() == nil; load += a.StepPct {
+		r, err := ctx.RunFixedRateTrial(runCtx, load, a.StepDuration)
+		if err != nil {
+			if len(report.Steps) == 0 {
+				return nil, err
+			}
+			log.Printf("  AQM step error at %.1f%%: %v", load, err)
+			break
+		}
+		st := aqm.Step{LoadPct: load, LossPct: r.LossPct, AvgUs: r.Latency.AvgNs / 1000, P99Us: r.Latency.P99Ns / 1000}
+		fmt.Printf("    %6.1f%%  loss %8.4f%%  avg %9.2fus  p99 %9.2fus\n", st.LoadPct, st.LossPct, st.AvgUs, st.P99Us)
+		report.Steps = append(report.Steps, st)
+	}
+	report.Summary = aqm.Analyze(report.Steps, a.LossFloorPct)
+	printAQMSummary(report)
+	return report, nil
+}
+
+// runMicroburstTest searches, for each gap, the largest burst size a
+// train of bursts crosses the DUT without loss, and estimates its buffer
+// from it
+func runMicroburstTest(runCtx context.Context, ctx backend.Backend, cfg *config.Config, fs uint32) (*microburst.Report, error) {
+	m := cfg.Microburst
+	fmt.Printf("  Running microburst test: trains of %d bursts at %.1f%%, %d to %d frames, gaps %v...\n",
+		m.Bursts, m.BurstRatePct, m.StartFrames, m.MaxFrames, m.Gaps)
+
+	report := &microburst.Report{FrameSize: fs, Bursts: m.Bursts, RatePct: m.BurstRatePct}
+	report.BurstMbps = preflight.LineMbps(cfg) * m.BurstRatePct / 100
+	for _, gap := range m.Gaps {
+		g := microburst.Gap{Gap: gap}
+		search := microburst.NewSearch(m.StartFrames, m.MaxFrames, m.StepFrames, m.ResolutionFrames)
+		for runCtx.Err() == nil {
+			frames, ok := search.Next()
+			if !ok {
+				break
+			}
+			r, err := ctx.RunBurstTrain(runCtx, dataplane.BurstTrain{Frames: frames, Bursts: m.Bursts, Gap: gap, RatePct: m.BurstRatePct})
+			if err != nil {
+				if len(report.Gaps) == 0 && len(g.Trains) == 0 {
+					return nil, err
+				}
+				log.Printf("  Microburst train error at %d frames: %v", frames, err)
+				break
+			}
+			bursts := make([]microburst.Burst, len(r.Bursts))
+			for i, b := range r.Bursts {
+				bursts[i] = microburst.NewBurst(fs, b.FramesTx, b.FramesRx, b.MaxLatencyNs, b.RxSpanNs)
+			}
+			t := microburst.NewTrain(frames, bursts)
+			fmt.Printf("    gap %-8v %7d frames  loss %8.4f%%  %d/%d bursts lossy\n", gap, frames, t.LossPct, t.LossyBursts, len(bursts))
+			g.Trains = append(g.Trains, t)
+			search.Record(frames, t.Lossless())
+		}
+		if len(g.Trains) == 0 {
+			break
+		}
+		g.Summary = microburst.Analyze(g.Trains, search, fs, report.BurstMbps, m.EgressMbps, gap)
+		report.Gaps = append(report.Gaps, g)
+	}
+	printMicroburstSummary(report)
+	return report, nil
+}
+
+// runSelfTest measures a baseline trial through the clean path and then a
+// trial through each netem impairment of the suite, checking each against
+// what was injected. The impairment is always removed again, even when a
+// trial fails or the run is cancelled.
+func runSelfTest(runCtx context.Context, ctx backend.Backend, cfg *config.Config, fs uint32) (*impair.Report, error) {
+	st := cfg.SelfTest
+	inj := impair.NewInjector(st.Interface)
+	fmt.Printf("  Running self-test: %d cases at %.1f%% for %v, netem on %s...\n", len(st.Suite()), st.RatePct, st.Duration, st.Interface)
+
+	// Cleanup must run after a cancel, so it gets its own context
+	restore := func() error {
+		cctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		return inj.Clear(cctx)
+	}
+	if err := restore(); err != nil {
+		return nil, err
+	}
+	trial := func() (impair.Measured, error) {
+		r, err := ctx.RunFixedRateTrial(runCtx, st.RatePct, st.Duration)
+		if err != nil {
+			return impair.Measured{}, err
+		}
+		return impair.Measured{
+			Frames:    r.FramesRx,
+			AvgUs:     r.Latency.AvgNs / 1000,
+			JitterUs:  r.Latency.JitterNs / 1000,
+			LossPct:   r.LossPct,
+			Reordered: r.ReorderedFrames,
+		}, nil
+	}
+
+	base, err := trial()
+	if err != nil {
+		return nil, fmt.Errorf("baseline: %w", err)
+	}
+	if base.Frames == 0 {
+		return nil, fmt.Errorf("baseline: no frames came back; is the reflector running on %s?", st.Interface)
+	}
+	fmt.Printf("    %-12s latency %9.1fus  jitter %8.1fus  loss %7.3f%%  reordered %d\n", "baseline", base.AvgUs, base.JitterUs, base.LossPct, base.Reordered)
+
+	report := &impair.Report{FrameSize: fs, Interface: st.Interface, Tolerance: st.Tolerance, Baseline: base, Passed: true}
+	for _, c := range st.Suite() {
+		if runCtx.Err() != nil {
+			break
+		}
+		if err := inj.Apply(runCtx, c.Netem); err != nil {
+			return nil, err
+		}
+		m, err := trial()
+		if cerr := restore(); cerr != nil {
+			log.Printf("  Warning: %v", cerr)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", c.Name, err)
+		}
+		r := impair.Check(c, base, m, st.Tolerance)
+		report.Cases = append(report.Cases, r)
+		report.Passed = report.Passed && r.Passed
+		fmt.Printf("    %-12s latency %9.1fus  jitter %8.1fus  loss %7.3f%%  reordered %d\n", c.Name, m.AvgUs, m.JitterUs, m.LossPct, m.Reordered)
+	}
+	printSelfTest(report)
+	return report, nil
+}
+
+// heatmapSample converts a trial's latency statistics to a heatmap sample,
+// using the average where no median was measured
+func heatmapSample(at time.Time, l dataplane.LatencyStats) heatmap.Sample {
+	p50 := l.P50Ns
+	if p50 == 0 {
+		p50 = l.AvgNs
+	}
+	return heatmap.Sample{
+		Time:  at,
+		Count: l.Count,
+		MinUs: l.MinNs / 1000,
+		P50Us: p50 / 1000,
+		P95Us: l.P95Ns / 1000,
+		P99Us: l.P99Ns / 1000,
+		MaxUs: l.MaxNs / 1000,
+	}
+}
+
+// writeHeatmap renders latency samples to the configured heatmap file,
+// adding suffix to the name when a run writes more than one
+func writeHeatmap(cfg *config.Config, title string, samples []heatmap.Sample, suffix string) {
+	h, err := heatmap.Build(title, samples, cfg.Heatmap.Rows)
+	if err != nil {
+		log.Printf("  Heatmap error: %v", err)
+		return
+	}
+	path := cfg.Heatmap.File
+	if suffix != "" {
+		ext := filepath.Ext(path)
+		path = strings.TrimSuffix(path, ext) + "-" + suffix + ext
+	}
+	if err := h.Write(path); err != nil {
+		log.Printf("  Heatmap error: %v", err)
+		return
+	}
+	fmt.Printf("  Latency heatmap: %s\n", path)
+}
+
+// runUDPEchoTest bounces datagrams sized for fs off the reflector
+func runUDPEchoTest(ctx context.Context, cfg *config.Config, fs uint32) (*udpecho.Result, error) {
+	ue := cfg.UDPEcho
+	fmt.Printf("  Running UDP echo test: %d pps for %v...\n", ue.RatePPS, ue.Duration)
+	result, err := udpecho.Run(ctx, udpecho.Options{
+		Target:    ue.Target,
+		FrameSize: fs,
+		RatePPS:   ue.RatePPS,
+		Duration:  ue.Duration,
+		Timeout:   ue.Timeout,
+	})
+	if err != nil {
+		return nil, err
+	}
+	printUDPEchoResult(result)
+	return result, nil
+}
+
+func runY1564Tests(runCtx context.Context, ctx backend.Local, cfg *config.Config, allResults *[]interface{}) *flows.Report {
+	var flowList []flows.Flow
+	for _, svc := range cfg.Y1564.Services {
+		if svc.Enabled {
+			flowList = append(flowList, flows.Flow{ID: svc.ServiceID, RateMbps: svc.SLA.CIRMbps})
+		}
+	}
+	tracker := flows.NewTracker(cfg.Y1564.FlowFailure, flowList)
+
+	for _, svc := range cfg.Y1564.Services {
+		if runCtx.Err() != nil || !svc.Enabled {
+			continue
+		}
+		if tracker.Aborted() {
+			fmt.Printf("\n  Service %d: skipped (run aborted by flow failure policy)\n", svc.ServiceID)
+			continue
+		}
+
+		// Load rebalanced from a failed flow is offered on top; the SLA stays at the configured CIR
+		extra := tracker.Extra(svc.ServiceID)
+		if extra > 0 {
+			fmt.Printf("\n  Service %d: %s (CIR: %.2f Mbps, +%.2f Mbps rebalanced)\n", svc.ServiceID, svc.ServiceName, svc.SLA.CIRMbps, extra)
+		} else {
+			fmt.Printf("\n  Service %d: %s (CIR: %.2f Mbps)\n", svc.ServiceID, svc.ServiceName, svc.SLA.CIRMbps)
+		}
+
+		dpSvc := &dataplane.Y1564Service{
+			ServiceID:   svc.ServiceID,
+			ServiceName: svc.ServiceName,
+			FrameSize:   svc.FrameSize,
+			CoS:         svc.CoS,
+			Enabled:     svc.Enabled,
+			VLANID:      svc.VLANID,
+			PCP:         svc.PCP,
+			OuterVLANID: svc.OuterVLANID,
+			OuterPCP:    svc.OuterPCP,
+			IPVersion:   svc.IPVersion,
+			SLA: dataplane.Y1564SLA{
+				CIRMbps:         svc.SLA.CIRMbps,
+				EIRMbps:         svc.SLA.EIRMbps,
+				CBSBytes:        svc.SLA.CBSBytes,
+				EBSBytes:        svc.SLA.EBSBytes,
+				FDThresholdMs:   svc.SLA.FDThresholdMs,
+				FDVThresholdMs:  svc.SLA.FDVThresholdMs,
+				FLRThresholdPct: svc.SLA.FLRThresholdPct,
+				EIRSAC:          stepSAC(svc.SLA, svc.SLA.EIRSAC),
+				PolicingSAC:     stepSAC(svc.SLA, svc.SLA.PolicingSAC),
+			},
+			PolicingStep: cfg.Y1564.PolicingStep,
+			ExtraMbps:    extra,
+		}
+
+		// Run Configuration Test
+		if cfg.TestType == config.TestY1564Config || cfg.TestType == config.TestY1564Full {
+			fmt.Printf("    Running Configuration Test (step test)...\n")
+			configResult, err := ctx.RunY1564ConfigTest(runCtx, dpSvc)
+			if err != nil {
+				log.Printf("    Config test error: %v", err)
+			} else {
+				printY1564ConfigResult(configResult, &svc)
+				*allResults = append(*allResults, configResult)
+				for _, step := range configResult.Steps {
+					if tracker.Observe(svc.ServiceID, step.FLRPct) {
+						fmt.Printf("    Service %d FAILED: persistent 100%% loss (policy: %s)\n",
+							svc.ServiceID, cfg.Y1564.FlowFailure.Policy)
+						break
+					}
+				}
+			}
+		}
+
+		// Run Performance Test (skipped once the flow has failed)
+		if (cfg.TestType == config.TestY1564Perf || cfg.TestType == config.TestY1564Full) && !tracker.Failed(svc.ServiceID) {
+			durationSec := uint32(cfg.Y1564.PerfDuration.Seconds())
+			fmt.Printf("    Running Performance Test (%d minutes)...\n", durationSec/60)
+			var perfResult *dataplane.Y1564PerfResult
+			var err error
+			if cfg.Heatmap.Enabled() {
+				var latency []heatmap.Sample
+				perfResult, latency, err = runY1564PerfSegments(runCtx, ctx, dpSvc, cfg.Y1564.PerfDuration, cfg.Heatmap.Segment)
+				if err == nil {
+					suffix := ""
+					if len(flowList) > 1 {
+						suffix = fmt.Sprintf("service%d", svc.ServiceID)
+					}
+					writeHeatmap(cfg, fmt.Sprintf("Y.1564 service %d (%s) frame delay", svc.ServiceID, svc.ServiceName), latency, suffix)
+				}
+			} else {
+				perfResult, err = ctx.RunY1564PerfTest(runCtx, dpSvc, durationSec)
+			}
+			if err != nil {
+				log.Printf("    Perf test error: %v", err)
+			} else {
+				printY1564PerfResult(perfResult, &svc)
+				*allResults = append(*allResults, perfResult)
+				if tracker.Observe(svc.ServiceID, perfResult.FLRPct) {
+					fmt.Printf("    Service %d FAILED: persistent 100%% loss (policy: %s)\n",
+						svc.ServiceID, cfg.Y1564.FlowFailure.Policy)
+				}
+			}
+		}
+
+		tracker.Complete(svc.ServiceID)
+	}
+
+	report := tracker.Report()
+	if len(report.Events) == 0 {
+		return nil
+	}
+	return &report
+}
+
+// stepSAC resolves the acceptance criteria of a configuration test step
+// above CIR for the dataplane
+func stepSAC(sla config.Y1564SLA, sac config.Y1564SAC) dataplane.Y1564SAC {
+	fd, fdv, flr := sla.Criteria(sac)
+	return dataplane.Y1564SAC{FDThresholdMs: fd, FDVThresholdMs: fdv, FLRThresholdPct: flr}
+}
+
+// runY1564PerfSegments runs the performance test as back-to-back segments
+// so frame delay can be followed over time. The segments are combined into
+// one result judged against the SLA as a single run would be.
+func runY1564PerfSegments(runCtx context.Context, ctx backend.Local, svc *dataplane.Y1564Service, duration, segment time.Duration) (*dataplane.Y1564PerfResult, []heatmap.Sample, error) {
+	total := &dataplane.Y1564PerfResult{ServiceID: svc.ServiceID}
+	var samples []heatmap.Sample
+	var fdSum, fdvSum float64 // Weighted by frames received
+	start := time.Now()
+
+	for left := duration; left > 0 && runCtx.Err() == nil; left -= segment {
+		seg := segment
+		if left < seg {
+			seg = left
+		}
+		r, err := ctx.RunY1564PerfTest(runCtx, svc, uint32(seg.Seconds()))
+		if err != nil {
+			if len(samples) == 0 {
+				return nil, nil, err
+			}
+			log.Printf("    Perf segment error: %v", err)
+			break
+		}
+
+		// Columns sit at the nominal segment start; the warmup before each
+		// segment is not part of the measurement
+		samples = append(samples, heatmap.Sample{
+			Time:  start.Add(duration - left),
+			Count: r.FramesRx,
+			MinUs: r.FDMinMs * 1000,
+			P50Us: r.FDAvgMs * 1000,
+			MaxUs: r.FDMaxMs * 1000,
+		})
+		if r.FramesRx > 0 {
+			if total.FramesRx == 0 || r.FDMinMs < total.FDMinMs {
+				total.FDMinMs = r.FDMinMs
+			}
+			total.FDMaxMs = math.Max(total.FDMaxMs, r.FDMaxMs)
+			fdSum += r.FDAvgMs * float64(r.FramesRx)
+			fdvSum += r.FDVMs * float64(r.FramesRx)
+		}
+		total.DurationSec += r.DurationSec
+		total.FramesTx += r.FramesTx
+		total.FramesRx += r.FramesRx
+	}
+	if len(samples) == 0 {
+		return nil, nil, runCtx.Err()
+	}
+
+	if total.FramesTx > 0 && total.FramesRx < total.FramesTx {
+		total.FLRPct = float64(total.FramesTx-total.FramesRx) * 100 / float64(total.FramesTx)
+	}
+	if total.FramesRx > 0 {
+		total.FDAvgMs = fdSum / float64(total.FramesRx)
+		total.FDVMs = fdvSum / float64(total.FramesRx)
+	}
+	total.FLRPass = total.FLRPct <= svc.SLA.FLRThresholdPct
+	total.FDPass = total.FDAvgMs <= svc.SLA.FDThresholdMs
+	total.FDVPass = total.FDVMs <= svc.SLA.FDVThresholdMs
+	total.ServicePass = total.FLRPass && total.FDPass && total.FDVPass
+	return total, samples, nil
+}
+
+// RFC 2889 LAN Switch Benchmarking Tests
+func runRFC2889Tests(runCtx context.Context, ctx *dataplane.Context, cfg *config.Config, fs uint32, allResults *[]interface{}) {
+	if runCtx.Err() != nil {
+		return
+	}
+
+	fmt.Printf("  RFC 2889 Configuration:\n")
+	fmt.Printf("    Port Count: %d\n", cfg.RFC2889.PortCount)
+	fmt.Printf("    Address Count: %d\n", cfg.RFC2889.AddressCount)
+	fmt.Printf("    Trial Duration: %v\n", cfg.RFC2889.TrialDuration)
+	fmt.Printf("    Acceptable Loss: %.2f%%\n", cfg.RFC2889.AcceptableLossPct)
+
+	switch cfg.TestType {
+	case config.TestRFC2889Forwarding:
+		fmt.Printf("  Running Forwarding Rate test...\n")
+		var results []*dataplane.RFC2889ForwardingResult
+		for _, o := range cfg.RFC2889.Orientations {
+			if runCtx.Err() != nil {
+				break
+			}
+			fmt.Printf("    Orientation: %s\n", o)
+			result, err := ctx.RunRFC2889ForwardingTest(runCtx, dataplane.RFC2889Config{
+				Pattern:           trafficPattern(o),
+				PortCount:         cfg.RFC2889.PortCount,
+				FrameSize:         fs,
+				TrialDuration:     cfg.RFC2889.TrialDuration,
+				WarmupPeriod:      cfg.WarmupPeriod,
+				AddressCount:      cfg.RFC2889.AddressCount,
+				AcceptableLossPct: cfg.RFC2889.AcceptableLossPct,
+			})
+			if err != nil {
+				log.Printf("    Error: %v", err)
+				continue
+			}
+			results = append(results, result)
+			*allResults = append(*allResults, result)
+		}
+		printRFC2889ForwardingResults(results)
+	case config.TestRFC2889Caching:
+		fmt.Printf("  Running Address Caching Capacity test...\n")
+		fmt.Printf("    Testing with %d MAC addresses\n", cfg.RFC2889.AddressCount)
+	case config.TestRFC2889Learning:
+		fmt.Printf("  Running Address Learning Rate test...\n")
+	case config.TestRFC2889Broadcast:
+		fmt.Printf("  Running Broadcast Forwarding test...\n")
+	case config.TestRFC2889Congestion:
+		fmt.Printf("  Running Congestion Control test...\n")
+	}
+
+	fmt.Printf("  RFC 2889 test complete\n")
+}
+
+// trafficPattern maps a configured orientation to the C traffic pattern
+func trafficPattern(o config.TrafficOrientation) dataplane.TrafficPattern {
+	if o == config.OrientationManyToOne {
+		return dataplane.PatternManyToOne
+	}
+	return dataplane.PatternOneToMany
+}
+
+// RFC 6349 TCP Throughput Tests
+func runRFC6349Tests(runCtx context.Context, ctx *dataplane.Context, cfg *config.Config, allResults *[]interface{}) {
+	if runCtx.Err() != nil {
+		return
+	}
+
+	fmt.Printf("  RFC 6349 Configuration:\n")
+	fmt.Printf("    Target Rate: %.2f Mbps\n", cfg.RFC6349.TargetRateMbps)
+	fmt.Printf("    MSS: %d bytes\n", cfg.RFC6349.MSS)
+	fmt.Printf("    RWND: %d bytes\n", cfg.RFC6349.RWND)
+	fmt.Printf("    Parallel Streams: %d\n", cfg.RFC6349.ParallelStreams)
+	fmt.Printf("    Test Duration: %v\n", cfg.RFC6349.TestDuration)
+
+	switch cfg.TestType {
+	case config.TestRFC6349Throughput:
+		fmt.Printf("  Running TCP Throughput test...\n")
+		fmt.Printf("    [Test executing via C dataplane]\n")
+	case config.TestRFC6349Path:
+		fmt.Printf("  Running Path Analysis test...\n")
+		fmt.Printf("    Measuring RTT and bottleneck bandwidth...\n")
+	}
+
+	fmt.Printf("  RFC 6349 test complete\n")
+}
+
+// Y.1731 Ethernet OAM Tests
+func runY1731Tests(runCtx context.Context, ctx *dataplane.Context, cfg *config.Config, allResults *[]interface{}) {
+	if runCtx.Err() != nil {
+		return
+	}
+
+	fmt.Printf("  Y.1731 Configuration:\n")
+	fmt.Printf("    MEP ID: %d\n", cfg.Y1731.MEPID)
+	fmt.Printf("    MEG Level: %d\n", cfg.Y1731.MEGLevel)
+	fmt.Printf("    MEG ID: %s\n", cfg.Y1731.MEGID)
+	fmt.Printf("    Probe Count: %d\n", cfg.Y1731.ProbeCount)
+	fmt.Printf("    Probe Interval: %v\n", cfg.Y1731.ProbeInterval)
+
+	switch cfg.TestType {
+	case config.TestY1731Delay:
+		fmt.Printf("  Running Delay Measurement (DMM/DMR)...\n")
+		fmt.Printf("    [Test executing via C dataplane]\n")
+	case config.TestY1731Loss:
+		fmt.Printf("  Running Loss Measurement (LMM/LMR)...\n")
+	case config.TestY1731SLM:
+		fmt.Printf("  Running Synthetic Loss Measurement...\n")
+	case config.TestY1731Loopback:
+		fmt.Printf("  Running Loopback test (LBM/LBR)...\n")
+	}
+
+	fmt.Printf("  Y.1731 test complete\n")
+}
+
+// MEF Service Activation Tests
+func runMEFTests(runCtx context.Context, ctx *dataplane.Context, cfg *config.Config, allResults *[]interface{}) {
+	if runCtx.Err() != nil {
+		return
+	}
+
+	fmt.Printf("  MEF Configuration:\n")
+	fmt.Printf("    CIR: %.2f Mbps\n", cfg.MEF.CIRMbps)
+	fmt.Printf("    EIR: %.2f Mbps\n", cfg.MEF.EIRMbps)
+	fmt.Printf("    CBS: %d bytes\n", cfg.MEF.CBSBytes)
+	fmt.Printf("    EBS: %d bytes\n", cfg.MEF.EBSBytes)
+	fmt.Printf("    FD Threshold: %.2f us\n", cfg.MEF.FDThresholdUs)
+	fmt.Printf("    FDV Threshold: %.2f us\n", cfg.MEF.FDVThresholdUs)
+	fmt.Printf("    FLR Threshold: %.4f%%\n", cfg.MEF.FLRThresholdPct)
+	fmt.Printf("    Availability: %.2f%%\n", cfg.MEF.AvailThresholdPct)
+
+	switch cfg.TestType {
+	case config.TestMEFConfig:
+		fmt.Printf("  Running MEF Configuration Test (step test)...\n")
+		fmt.Printf("    [Test executing via C dataplane]\n")
+	case config.TestMEFPerf:
+		fmt.Printf("  Running MEF Performance Test (%v)...\n", cfg.MEF.PerfDuration)
+	case config.TestMEFFull:
+		fmt.Printf("  Running Full MEF Test Suite...\n")
+		fmt.Printf("    Phase 1: Configuration Test\n")
+		fmt.Printf("    Phase 2: Performance Test (%v)\n", cfg.MEF.PerfDuration)
+	}
+
+	fmt.Printf("  MEF test complete\n")
+}
+
+// TSN (IEEE 802.1Qbv) Tests
+func runTSNTests(runCtx context.Context, ctx *dataplane.Context, cfg *config.Config, allResults *[]interface{}) {
+	if runCtx.Err() != nil {
+		return
+	}
+
+	fmt.Printf("  TSN Configuration:\n")
+	fmt.Printf("    Traffic Classes: %d\n", cfg.TSN.NumClasses)
+	fmt.Printf("    Cycle Time: %d ns\n", cfg.TSN.CycleTimeNs)
+	fmt.Printf("    Max Latency: %d ns\n", cfg.TSN.MaxLatencyNs)
+	fmt.Printf("    Max Jitter: %d ns\n", cfg.TSN.MaxJitterNs)
+	fmt.Printf("    Max Sync Offset: %d ns\n", cfg.TSN.MaxSyncOffsetNs)
+	fmt.Printf("    Frame Size: %d bytes\n", cfg.TSN.FrameSize)
+	fmt.Printf("    Test Duration: %v\n", cfg.TSN.TestDuration)
+
+	switch cfg.TestType {
+	case config.TestTSNTiming:
+		fmt.Printf("  Running Gate Timing Accuracy test...\n")
+		fmt.Printf("    [Test executing via C dataplane]\n")
+	case config.TestTSNIsolation:
+		fmt.Printf("  Running Traffic Class Isolation test...\n")
+	case config.TestTSNLatency:
+		fmt.Printf("  Running Scheduled Latency test...\n")
+	case config.TestTSNFull:
+		fmt.Printf("  Running Full TSN Test Suite...\n")
+		fmt.Printf("    Phase 1: Gate Timing Accuracy\n")
+		fmt.Printf("    Phase 2: Traffic Class Isolation\n")
+		fmt.Printf("    Phase 3: Scheduled Latency\n")
+	}
+
+	fmt.Printf("  TSN test complete\n")
+}
+
+func printModifierEffect(run *modifierRun, baseline interface{}) {
+	fmt.Printf("  Section 11 modifier effect for %d bytes:\n", run.FrameSize)
+	if b := throughputOf(baseline); b != nil {
+		if m := throughputOf(run.Result); m != nil {
+			fmt.Printf("    Max Rate: %.2f%% baseline, %.2f%% modified (%+.2f%%)\n",
+				b.MaxRatePct, m.MaxRatePct, m.MaxRatePct-b.MaxRatePct)
+		}
+	}
+	if s := run.Management; s != nil {
+		fmt.Printf("    Management: %d/%d queries answered (%.1f%%), avg RTT %.2f ms, max %.2f ms\n",
+			s.Answered, s.Sent, s.AnsweredPct(), s.AvgRTTMs, s.MaxRTTMs)
+	}
+}
+
+func printControlPlaneEffect(run *controlPlaneRun, maxDegradationPct float64) {
+	s := run.Stats
+	fmt.Printf("  Control-plane stress effect for %d bytes:\n", run.FrameSize)
+	fmt.Printf("    ICMP: %d/%d answered, ARP: %d/%d answered, BGP: %d/%d answered\n",
+		s.ICMPReplies, s.ICMPSent, s.ARPReplies, s.ARPSent, s.BGPReplies, s.BGPSent)
+	if run.DegradationPct != nil {
+		fmt.Printf("    Throughput degradation: %.2f%% (limit %.2f%%) - %s\n",
+			*run.DegradationPct, maxDegradationPct, passFailStr(run.Pass))
+	}
+}
+
+func printPowerRun(run *powerRun) {
+	p := run.Power
+	if p.Samples == 0 {
+		fmt.Printf("  Power: no readings from %s (%d errors)\n", p.Source, p.Errors)
+		return
+	}
+	fmt.Printf("  Power: avg %.1f W (min %.1f, max %.1f, %d samples)", p.AvgWatts, p.MinWatts, p.MaxWatts, p.Samples)
+	if run.MbpsPerWatt > 0 {
+		fmt.Printf(", %.2f Mbps/W", run.MbpsPerWatt)
+	}
+	fmt.Println()
+}
+
+func printSoakBucket(b drift.Bucket) {
+	fmt.Printf("    [%s] %8.2f Mbps  loss %.4f%%  p50 %.2fus  p95 %.2fus  p99 %.2fus\n",
+		b.Start.Format("15:04:05"), b.ThroughputMbps, b.LossPct, b.P50Us, b.P95Us, b.P99Us)
+}
+
+func printSoakSummary(r *drift.Report) {
+	s := r.Summary
+	fmt.Printf("  Soak results for %d bytes (%d samples, %d buckets):\n", r.FrameSize, r.Samples, s.Buckets)
+	if s.Buckets >= 2 {
+		var tput, p99 []float64
+		for _, b := range r.Buckets {
+			tput = append(tput, b.ThroughputMbps)
+			p99 = append(p99, b.P99Us)
+		}
+		fmt.Printf("    Throughput: %s  drift %+.2f%% (%+.3f%%/h)\n",
+			drift.Sparkline(tput), s.ThroughputDriftPct, s.ThroughputSlopePctPerHour)
+		fmt.Printf("    P99 latency: %s  drift %+.1f%%\n", drift.Sparkline(p99), s.P99DriftPct)
+	}
+	for _, c := range r.Changes {
+		fmt.Printf("    Reconfigured at sample %d (%s): %.1f%% in %d-byte frames\n", c.Sample, c.Time.Format("15:04:05"), c.RatePct, c.FrameSize)
+	}
+	if len(r.Changes) > 0 {
+		fmt.Printf("    Drift is measured since the last reconfiguration\n")
+	}
+	fmt.Printf("    Verdict: %s\n", s.Verdict)
+}
+
+func printQoSResult(r *qos.Report) {
+	fmt.Printf("  QoS matrix for %d bytes at %.1f%% (delivered %.2f Mbps, loss %.4f%%):\n", r.FrameSize, r.LoadPct, r.DeliveredMbps, r.LossPct)
+	fmt.Printf("    %-8s %3s %4s %9s %12s %12s %9s %9s %10s %10s\n",
+		"Class", "PCP", "DSCP", "Offered%", "Offered Mbps", "Deliv. Mbps", "Share%", "Loss%", "Avg (us)", "Max (us)")
+	for _, c := range r.Classes {
+		lat := fmt.Sprintf("%10s %10s", "-", "-")
+		if c.Latency.Count > 0 {
+			lat = fmt.Sprintf("%10.2f %10.2f", c.Latency.AvgNs/1000, c.Latency.MaxNs/1000)
+		}
+		fmt.Printf("    %-8s %3d %4d %9.2f %12.2f %12.2f %9.2f %9.4f %s\n",
+			c.Name, c.PCP, c.DSCP, c.OfferedSharePct, c.OfferedMbps, c.DeliveredMbps, c.DeliveredSharePct, c.LossPct, lat)
+	}
+}
+
+func printAQMSummary(r *aqm.Report) {
+	s := r.Summary
+	fmt.Printf("  AQM results for %d bytes (%d steps, baseline latency %.2fus):\n", r.FrameSize, len(r.Steps), s.BaselineUs)
+	if s.Min != nil {
+		fmt.Printf("    Min threshold: %.1f%% load, queue %.2fus, loss %.4f%%\n", s.Min.LoadPct, s.Min.QueueUs, s.Min.LossPct)
+		fmt.Printf("    Max threshold: %.1f%% load, queue %.2fus, loss %.4f%%\n", s.Max.LoadPct, s.Max.QueueUs, s.Max.LossPct)
+	}
+	if s.Profile == aqm.ProfileWRED {
+		fmt.Printf("    Drop slope: %.3f%% loss per %% load\n", s.SlopePct)
+	}
+	fmt.Printf("    Verdict: %s\n", s.Verdict)
+}
+
+// microburstListBursts is the most bursts of a lossy train printed one by
+// one; longer trains print only their lossy bursts
+const microburstListBursts = 32
+
+func printMicroburstSummary(r *microburst.Report) {
+	fmt.Printf("  Microburst results for %d bytes (%d bursts per train at %.1f%%):\n", r.FrameSize, r.Bursts, r.RatePct)
+	for _, g := range r.Gaps {
+		s := g.Summary
+		fmt.Printf("    Gap %v: largest lossless burst %d frames (%.1fus, %.1f%% duty cycle)", g.Gap, s.MaxLosslessFrames, s.BurstUs, s.DutyCyclePct)
+		if s.FirstLossFrames > 0 {
+			fmt.Printf(", loss from %d", s.FirstLossFrames)
+		}
+		fmt.Println()
+		if s.DrainMbps > 0 {
+			how := "configured"
+			if s.DrainMeasured {
+				how = "measured"
+			}
+			fmt.Printf("      Egress %.1f Mbps (%s), buffer ~%.0f frames, %.0f bytes, %.1fus\n", s.DrainMbps, how, s.BufferFrames, s.BufferBytes, s.BufferUs)
+		}
+		if t := g.FirstLossTrain(); t != nil {
+			fmt.Printf("      Per-burst loss at %d frames (first lossy burst %d):\n", t.Frames, t.FirstLossBurst)
+			for i, b := range t.Bursts {
+				if len(t.Bursts) > microburstListBursts && b.LossPct == 0 {
+					continue
+				}
+				fmt.Printf("        Burst %4d: %d/%d frames, loss %.4f%%, max %.2fus\n", i+1, b.FramesRx, b.FramesTx, b.LossPct, b.MaxUs)
+			}
+		}
+		fmt.Printf("      Verdict: %s\n", s.Verdict)
+	}
+}
+
+func printSelfTest(r *impair.Report) {
+	fmt.Printf("  Self-test results for %d bytes (netem on %s):\n", r.FrameSize, r.Interface)
+	for _, c := range r.Cases {
+		verdict := "PASS"
+		if !c.Passed {
+			verdict = "FAIL"
+		}
+		fmt.Printf("    %s  %-12s %s\n", verdict, c.Name, c.Netem)
+		for _, f := range c.Failures {
+			fmt.Printf("            %s\n", f)
+		}
+	}
+	if r.Passed {
+		fmt.Println("    Verdict: the injected impairments were measured within tolerance")
+	} else {
+		fmt.Println("    Verdict: measurements disagree with the injected impairments")
+	}
+}
+
+func printUDPEchoResult(r *udpecho.Result) {
+	fmt.Printf("  UDP echo results for %d bytes (%d byte payload):\n", r.FrameSize, r.PayloadLen)
+	fmt.Printf("    Sent: %d  Received: %d  Loss: %.4f%%  Reordered: %d  Duplicates: %d\n",
+		r.Sent, r.Received, r.LossPct, r.Reordered, r.Duplicates)
+	if r.Received > 0 {
+		fmt.Printf("    RTT: min=%.2fus avg=%.2fus max=%.2fus jitter=%.2fus p99=%.2fus\n",
+			r.MinUs, r.AvgUs, r.MaxUs, r.JitterUs, r.P99Us)
+	}
+	fmt.Printf("    Reduced accuracy: %s\n", strings.Join(r.ReducedAccuracy, ", "))
+}
+
+func printThroughputResult(r *dataplane.ThroughputResultCLI, frameSize uint32) {
+	fmt.Printf("  Results for %d bytes:\n", frameSize)
+	fmt.Printf("    Max Rate: %.2f%% (%.2f Mbps, %.0f pps)\n", r.MaxRatePct, r.MaxRateMbps, r.MaxRatePPS)
+	fmt.Printf("    Iterations: %d\n", r.Iterations)
+	fmt.Printf("    Acceptable Loss: %.4g%%\n", r.AcceptableLossPct)
+	if r.Latency.Count > 0 {
+		fmt.Printf("    Latency: min=%.2fus avg=%.2fus max=%.2fus\n",
+			r.Latency.MinNs/1000, r.Latency.AvgNs/1000, r.Latency.MaxNs/1000)
+	}
+	if o := r.FrameOrder; o.ReorderedFrames > 0 || o.DuplicateFrames > 0 {
+		fmt.Printf("    Reordered: %d frames (max depth %d), Duplicates: %d\n", o.ReorderedFrames, o.MaxReorderDepth, o.DuplicateFrames)
+	}
+	if r.CorruptedFrames > 0 {
+		fmt.Printf("    Corrupted: %d frames received with a payload failing its CRC-32\n", r.CorruptedFrames)
+	}
+	printStreams(r.Streams)
+	printFamilies(r.Families)
+	if pass, fail, ok := nonMonotonic(r.Trials); ok {
+		fmt.Printf("    Loss not monotonic: passed at %.2f%% but failed at %.2f%%\n", pass, fail)
+	}
+	// A linear search's trials are its result, the rate/loss curve
+	if (verbose || r.Search == string(config.SearchLinear)) && len(r.Trials) > 0 {
+		fmt.Printf("    %4s %8s %12s %12s %12s %10s %8s\n", "Iter", "Rate%", "TX", "RX", "Loss%", "Avg(us)", "Result")
+		for i, t := range r.Trials {
+			avg := "-"
+			if t.Latency.Count > 0 {
+				avg = fmt.Sprintf("%.2f", t.Latency.AvgNs/1000)
+			}
+			fmt.Printf("    %4d %8.2f %12d %12d %12.4f %10s %8s\n", i+1, t.RatePct, t.FramesTx, t.FramesRx, t.LossPct, avg, trialDecision(t))
+		}
+	}
+}
+
+// printStreams summarizes per-stream counters, listing every stream with
+// --verbose. A DUT hashing flows unevenly loses frames on some streams only.
+func printStreams(streams []dataplane.StreamStats) {
+	if len(streams) == 0 {
+		return
+	}
+	worst, lossy := streams[0], 0
+	for _, st := range streams {
+		if st.LossPct > 0 {
+			lossy++
+		}
+		if st.LossPct > worst.LossPct {
+			worst = st
+		}
+	}
+	fmt.Printf("    Streams: %d, %d with loss", len(streams), lossy)
+	if lossy > 0 {
+		fmt.Printf(" (worst: stream %d at %.4f%%)", worst.Stream, worst.LossPct)
+	}
+	fmt.Println()
+	if !verbose {
+		return
+	}
+	fmt.Printf("    %6s %12s %12s %12s %12s %12s\n", "Stream", "TX", "RX", "Loss%", "Avg(us)", "Max(us)")
+	for _, st := range streams {
+		fmt.Printf("    %6d %12d %12d %12.4f %12.2f %12.2f\n",
+			st.Stream, st.FramesTx, st.FramesRx, st.LossPct, st.Latency.AvgNs/1000, st.Latency.MaxNs/1000)
+	}
+}
+
+// printFamilies sets a dual-stack trial's IPv4 and IPv6 frames side by
+// side; a DUT forwarding one family on a slower path shows here only
+func printFamilies(families []dataplane.FamilyStats) {
+	if len(families) == 0 {
+		return
+	}
+	fmt.Printf("    %6s %12s %12s %12s %12s %12s\n", "Family", "TX", "RX", "Loss%", "Avg(us)", "Max(us)")
+	for _, f := range families {
+		fmt.Printf("    %6s %12d %12d %12.4f %12.2f %12.2f\n",
+			f.Family, f.FramesTx, f.FramesRx, f.LossPct, f.Latency.AvgNs/1000, f.Latency.MaxNs/1000)
+	}
+}
+
+// trialDecision names which way a throughput trial moved the search
+func trialDecision(t dataplane.ThroughputTrial) string {
+	if t.Passed {
+		return "pass"
+	}
+	return "fail"
+}
+
+func printLatencyResults(results []dataplane.LatencyResultCLI, frameSize uint32) {
+	fmt.Printf("  Latency results for %d bytes:\n", frameSize)
+	fmt.Printf("    %8s %12s %12s %12s %12s\n", "Load%", "Min(us)", "Avg(us)", "Max(us)", "Jitter(us)")
+	for _, r := range results {
+		fmt.Printf("    %8.1f %12.2f %12.2f %12.2f %12.2f\n",
+			r.LoadPct, r.Latency.MinNs/1000, r.Latency.AvgNs/1000, r.Latency.MaxNs/1000, r.Latency.JitterNs/1000)
+	}
+	for _, r := range results {
+		if len(r.Streams) > 0 || len(r.Families) > 0 {
+			fmt.Printf("    At %.1f%%:\n", r.LoadPct)
+			printStreams(r.Streams)
+			printFamilies(r.Families)
+		}
+	}
+	printProbes(results)
+}
+
+// printProbes sets the latency probe's latency beside the load's at each
+// load level
+func printProbes(results []dataplane.LatencyResultCLI) {
+	for i, r := range results {
+		p := r.Probe
+		if p == nil {
+			continue
+		}
+		if i == 0 {
+			fmt.Printf("    Latency probe (PCP %d, DSCP %d) vs load:\n", p.PCP, p.DSCP)
+			fmt.Printf("    %8s %12s %12s %12s %12s %12s\n", "Load%", "Probe(us)", "Max(us)", "Jitter(us)", "Loss%", "Load(us)")
+		}
+		fmt.Printf("    %8.1f %12.2f %12.2f %12.2f %12.4f %12.2f\n", r.LoadPct,
+			p.Latency.AvgNs/1000, p.Latency.MaxNs/1000, p.Latency.JitterNs/1000, p.LossPct, r.Latency.AvgNs/1000)
+	}
+}
+
+func printFrameLossResults(results []dataplane.FrameLossResultCLI, frameSize uint32) {
+	fmt.Printf("  Frame loss results for %d bytes:\n", frameSize)
+	fmt.Printf("    %8s %12s %12s %12s\n", "Load%", "TX", "RX", "Loss%")
+	for _, r := range results {
+		fmt.Printf("    %8.1f %12d %12d %12.4f%s\n", r.OfferedPct, r.FramesTx, r.FramesRx, r.LossPct, pdrMark(r))
+	}
+	// Reordering, duplication and corruption hide behind a loss of 0%
+	for _, r := range results {
+		if o := r.FrameOrder; o != (dataplane.FrameOrder{}) {
+			fmt.Printf("    At %.1f%%: %d reordered (max depth %d), %d duplicated, %d corrupted\n",
+				r.OfferedPct, o.ReorderedFrames, o.MaxReorderDepth, o.DuplicateFrames, o.CorruptedFrames)
+		}
+	}
+	for _, r := range results {
+		if len(r.Streams) > 0 {
+			fmt.Printf("    At %.1f%%:\n", r.OfferedPct)
+			printStreams(r.Streams)
+		}
+	}
+}
+
+func printBackToBackResult(r *dataplane.BackToBackResultCLI, frameSize uint32) {
+	fmt.Printf("  Back-to-back results for %d bytes:\n", frameSize)
+	fmt.Printf("    Max Burst: %d frames\n", r.MaxBurstFrames)
+	fmt.Printf("    Burst Duration: %.2f us\n", float64(r.BurstDurationUs))
+	fmt.Printf("    Trials: %d\n", r.Trials)
+}
+
+func printRecoveryResult(r *dataplane.RecoveryResultCLI, frameSize uint32) {
+	fmt.Printf("  System Recovery results for %d bytes:\n", frameSize)
+	fmt.Printf("    Overload Rate: %.1f%% for %d seconds\n", r.OverloadRatePct, r.OverloadSec)
+	fmt.Printf("    Recovery Rate: %.1f%%\n", r.RecoveryRatePct)
+	if r.RecoveryTimeMs >= 0 {
+		fmt.Printf("    Recovery Time: %.2f ms\n", r.RecoveryTimeMs)
+	} else {
+		fmt.Printf("    Recovery Time: DID NOT RECOVER\n")
+	}
+	fmt.Printf("    Frames Lost: %d\n", r.FramesLost)
+	fmt.Printf("    Trials: %d\n", r.Trials)
+}
+
+func printResetResult(r *dataplane.ResetResultCLI, frameSize uint32) {
+	fmt.Printf("  Reset test results for %d bytes:\n", frameSize)
+	if r.ResetTimeMs >= 0 {
+		fmt.Printf("    Reset Time: %.2f ms\n", r.ResetTimeMs)
+	} else {
+		fmt.Printf("    Reset Time: NOT DETECTED OR DID NOT RECOVER\n")
+	}
+	fmt.Printf("    Frames Lost: %d\n", r.FramesLost)
+	fmt.Printf("    Trials: %d\n", r.Trials)
+	fmt.Printf("    Manual Reset: %t\n", r.ManualReset)
+}
+
+func printSuiteResult(r *suiteResult) {
+	fmt.Printf("  Suite results for %d bytes: %d of %d tests completed\n",
+		r.FrameSize, len(suiteTests)-len(r.Errors), len(suiteTests))
+	for _, t := range suiteTests {
+		if msg, ok := r.Errors[t]; ok {
+			fmt.Printf("    %s: %s\n", t, msg)
+		}
+	}
+}
+
+func printCharacterizeResult(r *characterizeResult) {
+	fmt.Printf("  Latency for %d bytes at shares of the %.2f%% (%.2f Mbps) throughput:\n",
+		r.FrameSize, r.ThroughputPct, r.Throughput.MaxRateMbps)
+	fmt.Printf("    %8s %8s %12s %12s %12s %12s %12s\n", "Share%", "Load%", "Min(us)", "Avg(us)", "P99(us)", "Max(us)", "Jitter(us)")
+	for _, p := range r.Latency {
+		l := p.Latency
+		fmt.Printf("    %8.1f %8.2f %12.2f %12.2f %12.2f %12.2f %12.2f\n",
+			p.SharePct, p.LoadPct, l.MinNs/1000, l.AvgNs/1000, l.P99Ns/1000, l.MaxNs/1000, l.JitterNs/1000)
+	}
+	if lrs, ok := r.Result(config.TestLatency).([]dataplane.LatencyResultCLI); ok {
+		printProbes(lrs)
+	}
+}
+
+func printRFC2889ForwardingResults(results []*dataplane.RFC2889ForwardingResult) {
+	if len(results) == 0 {
+		return
+	}
+	fmt.Printf("    Per-pattern results (one flow measured):\n")
+	fmt.Printf("      %-13s %10s %12s %10s\n", "Pattern", "Rate%", "Mbps", "Loss%")
+	for _, r := range results {
+		fmt.Printf("      %-13s %10.2f %12.2f %10.4f\n", r.Pattern, r.MaxRatePct, r.RateMbps, r.LossPct)
+	}
+}
+
+func printY1564ConfigResult(r *dataplane.Y1564ConfigResult, svc *config.Y1564Service) {
+	passStr := "PASS"
+	if !r.ServicePass {
+		passStr = "FAIL"
+	}
+	fmt.Printf("    Configuration Test: %s\n", passStr)
+	fmt.Printf("      %8s %9s %10s %10s %10s %10s %8s\n", "Step", "Phase", "Rate%", "FLR%", "FD(ms)", "FDV(ms)", "Result")
+	for _, step := range r.Steps {
+		stepPass := "PASS"
+		if !step.StepPass {
+			stepPass = "FAIL"
+		}
+		fmt.Printf("      %8d %9s %10.1f %10.4f %10.2f %10.2f %8s\n",
+			step.Step, step.Phase, step.OfferedRatePct, step.FLRPct, step.FDAvgMs, step.FDVMs, stepPass)
+	}
+}
+
+func printY1564PerfResult(r *dataplane.Y1564PerfResult, svc *config.Y1564Service) {
+	passStr := "PASS"
+	if !r.ServicePass {
+		passStr = "FAIL"
+	}
+	fmt.Printf("    Performance Test (%ds): %s\n", r.DurationSec, passStr)
+	fmt.Printf("      Frames: TX=%d RX=%d\n", r.FramesTx, r.FramesRx)
+	fmt.Printf("      FLR: %.4f%% (threshold: %.4f%%) - %s\n", r.FLRPct, svc.SLA.FLRThresholdPct, passFailStr(r.FLRPass))
+	fmt.Printf("      FD:  %.2f ms (threshold: %.2f ms) - %s\n", r.FDAvgMs, svc.SLA.FDThresholdMs, passFailStr(r.FDPass))
+	fmt.Printf("      FDV: %.2f ms (threshold: %.2f ms) - %s\n", r.FDVMs, svc.SLA.FDVThresholdMs, passFailStr(r.FDVPass))
+}
+
+func passFailStr(pass bool) string {
+	if pass {
+		return "PASS"
+	}
+	return "FAIL"
+}
+
+func printFlowReports(reports []flows.Report) {
+	for _, r := range reports {
+		fmt.Printf("\nFlow Failure Timeline (policy: %s, after %d trials at 100%% loss):\n", r.Policy, r.PersistTrials)
+		for _, e := range r.Events {
+			fmt.Printf("  %s  flow %-4d %-10s %s\n", e.Time.Format("15:04:05"), e.FlowID, e.Action, e.Detail)
+		}
+	}
+}
+
+// printDeviations lists the overrides that take the run outside its
+// methodology
+func printDeviations(devs []config.Deviation) {
+	if len(devs) == 0 {
+		return
+	}
+	fmt.Printf("\nMethodology deviations: these results are not to the standard\n")
+	for _, d := range devs {
+		fmt.Printf("  [DEVIATION] %s %-4s %s: %s (%s)\n", d.Standard, d.Section, d.Setting, d.Configured, d.Recommendation)
+	}
+}
+
+func printCompliance(checks []config.ComplianceCheck) {
+	if len(checks) == 0 {
+		return
+	}
+	fmt.Printf("\nRFC 2544 Methodology Compliance:\n")
+	for _, c := range checks {
+		status := "OK"
+		if !c.Honored {
+			status = "DEVIATION"
+		}
+		fmt.Printf("  [%-9s] %-6s %s (%s)\n", status, c.Section, c.Recommendation, c.Detail)
+	}
+}
+
+// certification builds the certification report of a certify run from
+// its suite results
+func certification(cfg *config.Config, frameSizes []uint32, results []interface{}) *certify.Report {
+	r := certify.New(cfg, frameSizes, preflight.LineMbps(cfg), certifyOverrides)
+	for _, res := range results {
+		if s, ok := res.(*suiteResult); ok {
+			r.AddSuite(certify.Suite{FrameSize: s.FrameSize, ThroughputPct: s.ThroughputPct,
+				Throughput: s.Throughput, Latency: s.Latency, FrameLoss: s.FrameLoss,
+				BackToBack: s.BackToBack, Recovery: s.Recovery, Reset: s.Reset, Errors: s.Errors})
+		}
+	}
+	r.Finish()
+	return r
+}
+
+func printInvalidTrials(invalid []backend.InvalidTrial) {
+	if len(invalid) == 0 {
+		return
+	}
+	fmt.Printf("\nInvalid trials (dataplane restarted):\n")
+	for _, t := range invalid {
+		fmt.Printf("  %d bytes: %s, %s\n", t.FrameSize, t.Test, t.Error)
+	}
+}
+
+// exitThresholds is the exit code of a run that completed but violated a
+// configured threshold or failed the self-test. Runs that fail to set up
+// or complete exit with 1.
+const exitThresholds = 3
+
+// printDUTConfig reports whether the DUT configuration changed during the
+// run, with the diff of each changed command
+func printDUTConfig(r *dutconfig.Report) {
+	if r == nil {
+		return
+	}
+	if !r.Changed {
+		fmt.Printf("\nDUT configuration: unchanged (%d commands)\n", len(r.Before.Outputs))
+		return
+	}
+	fmt.Println("\nDUT configuration: CHANGED during the run; results may not be valid")
+	for _, c := range r.Changes {
+		fmt.Printf("  %s:\n", c.Command)
+		for _, l := range c.Diff {
+			fmt.Printf("    %s\n", l)
+		}
+	}
+}
+
+// ticketTimeout bounds filing a ticket with one connector
+const ticketTimeout = 30 * time.Second
+
+// reportTypes are the MIME types of the output formats attached to tickets
+var reportTypes = map[string]string{
+	"json": "application/json",
+	"csv":  "text/csv",
+	"html": "text/html",
+	"pdf":  "application/pdf",
+}
+
+// fileTickets files a run that failed its thresholds with each ticketing
+// connector matching the circuit's tags, attaching the output file when
+// one was written and the JSON report otherwise. A connector that fails
+// is logged; the run's outcome stands.
+func fileTickets(report jsonReport, cfg *config.Config) {
+	key := cfg.Certificate.CircuitID
+	if key == "" {
+		key = cfg.DUT.Label()
+	}
+	if key == "" {
+		key = cfg.Interface
+	}
+	issue := ticket.Issue{
+		Key:         key,
+		Summary:     fmt.Sprintf("%s validation failed: %s", cfg.TestType, key),
+		Description: ticketDescription(report.Thresholds, cfg),
+	}
+	if outputFile != "" && reportTypes[outputFormat] != "" {
+		data, err := os.ReadFile(outputFile)
+		if err != nil {
+			log.Printf("Warning: ticketing: read report: %v", err)
+		}
+		issue.Report, issue.ReportName, issue.ReportType = data, filepath.Base(outputFile), reportTypes[outputFormat]
+	} else {
+		var buf bytes.Buffer
+		if err := writeJSON(&buf, report); err != nil {
+			log.Printf("Warning: ticketing: encode report: %v", err)
+		}
+		issue.Report, issue.ReportType = buf.Bytes(), reportTypes["json"]
+		issue.ReportName = fmt.Sprintf("rfc2544-%s.json", time.Now().Format("20060102-150405"))
+	}
+
+	for _, t := range cfg.Ticketing {
+		if !t.Matches(cfg.Certificate.Tags) {
+			continue
+		}
+		name := t.Name
+		if name == "" {
+			name = t.URL
+		}
+		var conn ticket.Connector
+		if t.System == config.TicketJira {
+			j := ticket.NewJira(t.URL, t.User, t.Password, t.Project)
+			j.IssueType = t.IssueType
+			conn = j
+		} else {
+			s := ticket.NewServiceNow(t.URL, t.User, t.Password)
+			s.Table, s.AssignmentGroup = t.Table, t.AssignmentGroup
+			conn = s
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), ticketTimeout)
+		tk, err := conn.File(ctx, issue)
+		cancel()
+		if err != nil {
+			log.Printf("Warning: ticketing %s: %v", name, err)
+			continue
+		}
+		action := "Updated"
+		if tk.Created {
+			action = "Opened"
+		}
+		fmt.Printf("%s %s ticket %s: %s\n", action, t.System, tk.ID, tk.URL)
+	}
+}
+
+// ticketDescription lists what a run failed, for the ticket
+func ticketDescription(r *threshold.Report, cfg *config.Config) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Test: %s on %s at %s\n", cfg.TestType, cfg.Interface, time.Now().Format(time.RFC3339))
+	if label := cfg.DUT.Label(); label != "" {
+		fmt.Fprintf(&b, "DUT: %s\n", label)
+	}
+	if c := cfg.Certificate; c.Customer != "" || c.Location != "" {
+		fmt.Fprintf(&b, "Customer: %s, location: %s\n", c.Customer, c.Location)
+	}
+	b.WriteString("\nFailed:\n")
+	for _, c := range r.Checks {
+		if c.Pass {
+			continue
+		}
+		size := ""
+		if c.FrameSize > 0 {
+			size = fmt.Sprintf("%d bytes: ", c.FrameSize)
+		}
+		fmt.Fprintf(&b, "- %s%s %s (limit %s)\n", size, c.Criterion, c.Measured, c.Limit)
+	}
+	for _, m := range r.NotMeasured {
+		fmt.Fprintf(&b, "- %s not measured\n", m)
+	}
+	return b.String()
+}
+
+// printThresholds lists the threshold checks, failures first
+func printThresholds(r *threshold.Report) {
+	if r == nil {
+		return
+	}
+	fmt.Printf("\nThresholds: %s\n", passFailStr(r.Passed))
+	for _, pass := range []bool{false, true} {
+		for _, c := range r.Checks {
+			if c.Pass != pass {
+				continue
+			}
+			size := ""
+			if c.FrameSize > 0 {
+				size = fmt.Sprintf("%d bytes: ", c.FrameSize)
+			}
+			fmt.Printf("  [%s] %s%s %s (limit %s)\n", passFailStr(c.Pass), size, c.Criterion, c.Measured, c.Limit)
+		}
+	}
+	for _, m := range r.NotMeasured {
+		fmt.Printf("  [FAIL] %s not measured\n", m)
+	}
+}
+
+// runMetadata describes the conditions a run was made under
+type runMetadata struct {
+	Started      time.Time                  `json:"started"`
+	Version      string                     `json:"version"` // Of this control plane
+	Interface    string                     `json:"interface"`
+	Dataplane    string                     `json:"dataplane,omitempty"`         // Local dataplane backend, when used
+	Library      *dataplane.LibraryVersion  `json:"dataplane_library,omitempty"` // C library behind it, when one is
+	Link         *linkRun                   `json:"link,omitempty"`              // Local link, when the dataplane was used
+	TestType     config.TestType            `json:"test_type"`
+	Services     []sla.Service              `json:"services,omitempty"` // Y.1564 services, for SLA reports
+	DUT          *config.DUTConfig          `json:"dut,omitempty"`
+	AddressPairs uint32                     `json:"address_pairs"`
+	Streams      uint32                     `json:"streams,omitempty"`
+	AddressPools *addrpool.Usage            `json:"address_pools,omitempty"`    // Seed and share of each pool drawn
+	Learning     *dataplane.AddressLearning `json:"address_learning,omitempty"` // Ramp that introduced the pairs
+	Framing      string                     `json:"framing"`
+	MPLSLabels   []config.MPLSLabel         `json:"mpls_labels,omitempty"` // Label stack of the test frames, outermost first
+	IPVersion    config.IPVersion           `json:"ip_version,omitempty"`  // IP version of the test frames ("" = 4)
+	TxMarking    *txMarking                 `json:"tx_marking,omitempty"`  // Marking written into the built-in frames
+	Payload      string                     `json:"payload_pattern,omitempty"`
+	Packet       string                     `json:"packet,omitempty"`
+	OAM          *oamRun                    `json:"oam,omitempty"`
+	TRex         *backend.TRexRun           `json:"trex,omitempty"`
+	Socket       *backend.SocketRun         `json:"socket,omitempty"`
+	Ports        []dataplane.PortStats      `json:"ports,omitempty"`   // TX then RX port counters
+	Capture      *captureRun                `json:"capture,omitempty"` // Pcap files of the run's frames
+	Wiring       *wiring.Diagram            `json:"wiring,omitempty"`
+}
+
+// captureRun records the pcap files of a run and the frames they took
+type captureRun struct {
+	TX       string `json:"tx,omitempty"`
+	RX       string `json:"rx,omitempty"`
+	TxFrames uint64 `json:"tx_frames"`
+	RxFrames uint64 `json:"rx_frames"`
+}
+
+func captureMetadata(ctx *dataplane.Context, cfg *config.Config) *captureRun {
+	s := ctx.CaptureStats()
+	return &captureRun{TX: cfg.Capture.TX, RX: cfg.Capture.RX, TxFrames: s.TxFrames, RxFrames: s.RxFrames}
+}
+
+func printCapture(c *captureRun) {
+	fmt.Println()
+	if c.TX != "" {
+		fmt.Printf("Captured %d frames sent to %s\n", c.TxFrames, c.TX)
+	}
+	if c.RX != "" {
+		fmt.Printf("Captured %d frames received to %s\n", c.RxFrames, c.RX)
+	}
+}
+
+// dataplaneBackend names the local dataplane for the report, or "" when
+// traffic ran elsewhere
+func dataplaneBackend(ctx *dataplane.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	return dataplane.Backend
+}
+
+// dataplaneLibrary is the version of the C library behind the local
+// dataplane, or nil without one
+func dataplaneLibrary(ctx *dataplane.Context) *dataplane.LibraryVersion {
+	if ctx == nil {
+		return nil
+	}
+	return dataplane.Library()
+}
+
+// dataplaneSupports refuses, before any traffic, a test or frame size the
+// local dataplane cannot run on the configured ports
+func dataplaneSupports(caps dataplane.Capabilities, cfg *config.Config) error {
+	if reason := unsupportedTest(caps, cfg.TestType); reason != "" {
+		return fmt.Errorf("%s test: %s%s", cfg.TestType, reason, pureGoHint())
+	}
+	for _, fs := range cfg.TestFrameSizes() {
+		if caps.MaxFrameSize != 0 && fs > caps.MaxFrameSize {
+			return fmt.Errorf("%d-byte frames: the test ports carry at most %d bytes; raise their MTU to %d or drop the size",
+				fs, caps.MaxFrameSize, fs-ethFrameOverhead)
+		}
+	}
+	return nil
+}
+
+// pureGoHint tells how to get the tests the pure-Go dataplane lacks
+func pureGoHint() string {
+	if dataplane.PureGo {
+		return "; build with CGO and librfc2544 for it"
+	}
+	return ""
+}
+
+// unsupportedTest says why the local dataplane cannot run a test type, or
+// returns "" if it can
+func unsupportedTest(caps dataplane.Capabilities, t config.TestType) string {
+	if isRFC2889Test(t) {
+		if !caps.RFC2889 {
+			return "RFC 2889 is not available in this dataplane"
+		}
+		return ""
+	}
+	return caps.Unsupported(dataplane.TestType(getTestTypeInt(t)))
+}
+
+// unavailableTests lists the test types the local dataplane cannot run
+func unavailableTests(caps dataplane.Capabilities) []string {
+	var off []string
+	for _, t := range config.TestTypes() {
+		if unsupportedTest(caps, t) != "" {
+			off = append(off, string(t))
+		}
+	}
+	return off
+}
+
+// webCapabilities reports for the web UI what the dataplane can do on
+// iface, with the web API's test type numbers
+func webCapabilities(iface string) (web.Capabilities, error) {
+	caps, err := dataplane.QueryCapabilities(iface, false)
+	if err != nil {
+		return web.Capabilities{}, err
+	}
+	wc := web.Capabilities{
+		Interface:    iface,
+		Dataplane:    dataplane.Backend,
+		HWTimestamp:  caps.HWTimestamp,
+		MaxStreams:   caps.MaxStreams,
+		MaxFrameSize: caps.MaxFrameSize,
+		TestTypes:    []int{},
+	}
+	for t := dataplane.TestThroughput; t <= dataplane.TestY1564Full; t++ {
+		if caps.Unsupported(t) == "" {
+			wc.TestTypes = append(wc.TestTypes, int(t))
+		}
+	}
+	wc.Soak = caps.Unsupported(dataplane.TestThroughput) == ""
+	return wc, nil
+}
+
+// healthInfo is what /api/health reports beside liveness: the versions of
+// this binary and of the dataplane it drives
+func healthInfo() map[string]interface{} {
+	info := map[string]interface{}{
+		"version":   version,
+		"dataplane": dataplane.Backend,
+	}
+	if lib := dataplane.Library(); lib != nil {
+		info["dataplane_library"] = lib
+	}
+	return info
+}
+
+// txMarking is the marking the dataplane wrote into the test frames
+type txMarking struct {
+	DSCP             uint8  `json:"dscp"`                         // Of the IPv4 header
+	IPv6TrafficClass *uint8 `json:"ipv6_traffic_class,omitempty"` // With IPv6 frames
+}
+
+// String describes the marking, e.g. "DSCP 46, IPv6 traffic class 184"
+func (m *txMarking) String() string {
+	s := fmt.Sprintf("DSCP %d", m.DSCP)
+	if m.IPv6TrafficClass != nil {
+		s += fmt.Sprintf(", IPv6 traffic class %d", *m.IPv6TrafficClass)
+	}
+	return s
+}
+
+// txMarkingMetadata records the marking of the built-in frames, or nil
+// when the local dataplane did not build them. Y.1564 services record
+// their own cos.
+func txMarkingMetadata(ctx *dataplane.Context, cfg *config.Config) *txMarking {
+	switch {
+	case ctx == nil, cfg.Packet.Enabled():
+		return nil
+	case cfg.TestType == config.TestY1564Config || cfg.TestType == config.TestY1564Perf || cfg.TestType == config.TestY1564Full:
+		return nil
+	}
+	m := &txMarking{DSCP: cfg.DSCP}
+	if cfg.IPVersion.HasIPv6() {
+		tc := cfg.IPv6TrafficClass()
+		m.IPv6TrafficClass = &tc
+	}
+	return m
+}
+
+// linkRun describes the local link traffic ran on
+type linkRun struct {
+	SpeedBps uint64                 `json:"speed_bps"`
+	Speed    string                 `json:"speed"`         // e.g. "2.5 Gbps"
+	FEC      string                 `json:"fec,omitempty"` // Active FEC encoding, as ethtool reports it
+	Profile  dataplane.SpeedProfile `json:"speed_profile"` // TX tuning the trials ran with
+}
+
+// parseMPLSLabel parses a --mpls-label entry, label[:exp[:ttl]]
+func parseMPLSLabel(s string) (config.MPLSLabel, error) {
+	var l config.MPLSLabel
+	parts := strings.Split(s, ":")
+	if len(parts) > 3 {
+		return l, fmt.Errorf("want label[:exp[:ttl]]")
+	}
+	v, err := strconv.ParseUint(parts[0], 10, 20)
+	if err != nil {
+		return l, err
+	}
+	l.Label = uint32(v)
+	if len(parts) > 1 {
+		v, err := strconv.ParseUint(parts[1], 10, 3)
+		if err != nil {
+			return l, fmt.Errorf("exp: %w", err)
+		}
+		l.EXP = uint8(v)
+	}
+	if len(parts) > 2 {
+		v, err := strconv.ParseUint(parts[2], 10, 8)
+		if err != nil {
+			return l, fmt.Errorf("ttl: %w", err)
+		}
+		l.TTL = uint8(v)
+	}
+	return l, nil
+}
+
+// dataplaneLabels converts the framing's label stack for the dataplane
+func dataplaneLabels(labels []config.MPLSLabel) []dataplane.MPLSLabel {
+	if len(labels) == 0 {
+		return nil
+	}
+	out := make([]dataplane.MPLSLabel, len(labels))
+	for i, l := range labels {
+		out[i] = dataplane.MPLSLabel{Label: l.Label, EXP: l.EXP, TTL: l.TTL}
+	}
+	return out
+}
+
+// dataplaneProbe converts the latency probe settings for the dataplane
+func dataplaneProbe(p config.LatencyProbeConfig) dataplane.LatencyProbe {
+	return dataplane.LatencyProbe{PPS: p.PPS, PCP: p.PCP, DSCP: p.DSCP}
+}
+
+// dataplaneIP converts the IP version settings for the dataplane
+func dataplaneIP(cfg *config.Config) dataplane.IPStack {
+	ip := dataplane.IPStack{V6SharePct: cfg.IPv6.DualStackPct}
+	switch cfg.IPVersion {
+	case config.IPv6:
+		ip.Mode = dataplane.IPModeV6
+	case config.DualStack:
+		ip.Mode = dataplane.IPModeDual
+	}
+	src, dst := cfg.IPv6.Addrs()
+	ip.IPv6 = dataplane.IPv6{
+		Src:          src,
+		Dst:          dst,
+		TrafficClass: cfg.IPv6TrafficClass(),
+		FlowLabel:    cfg.IPv6.FlowLabel,
+		HopLimit:     cfg.IPv6.HopLimit,
+	}
+	return ip
+}
+
+// payloadPatternMetadata is the padding pattern of the run's frames, or ""
+// for the incrementing fill
+func payloadPatternMetadata(cfg *config.Config) string {
+	if !cfg.Patterned() {
+		return ""
+	}
+	p, err := cfg.Pattern()
+	if err != nil {
+		return cfg.PayloadPattern
+	}
+	return p.String()
+}
+
+// dataplanePayload converts payload_pattern for the dataplane. It was
+// validated with the config, so a parse error leaves the incrementing fill.
+func dataplanePayload(cfg *config.Config) dataplane.PayloadPattern {
+	p, err := cfg.Pattern()
+	if err != nil {
+		return dataplane.PayloadPattern{}
+	}
+	kinds := map[config.PatternKind]dataplane.PatternKind{
+		config.PatternZeros:  dataplane.PatternZeros,
+		config.PatternOnes:   dataplane.PatternOnes,
+		config.PatternRandom: dataplane.PatternRandom,
+		config.PatternPRBS31: dataplane.PatternPRBS31,
+		config.PatternHex:    dataplane.PatternCustom,
+	}
+	return dataplane.PayloadPattern{Kind: kinds[p.Kind], Seed: p.Seed, Bytes: p.Bytes}
+}
+
+// dataplaneCapture converts the capture settings for the dataplane
+func dataplaneCapture(cfg *config.Config) dataplane.Capture {
+	c := cfg.Capture
+	return dataplane.Capture{TX: c.TX, RX: c.RX, Limit: c.Limit, Snaplen: c.Snaplen}
+}
+
+// dataplaneHeaders converts the header fields and DSCP for the dataplane.
+// They were validated with the config, so a parse error leaves the fields
+// built-in.
+func dataplaneHeaders(cfg *config.Config) dataplane.HeaderFields {
+	h, err := cfg.Headers.Parse()
+	if err != nil {
+		return dataplane.HeaderFields{DSCP: cfg.DSCP}
+	}
+	ip := func(r addrpool.Range) dataplane.IPRange {
+		if r.Size() == 0 {
+			return dataplane.IPRange{}
+		}
+		v := r.First
+		return dataplane.IPRange{First: net.IPv4(byte(v>>24), byte(v>>16), byte(v>>8), byte(v)).To4(), Count: uint32(r.Size())}
+	}
+	port := func(r config.PortRange) dataplane.PortRange {
+		return dataplane.PortRange{First: r.First, Count: uint16(r.Size())}
+	}
+	return dataplane.HeaderFields{
+		SrcMAC:  h.SrcMAC,
+		DstMAC:  h.DstMAC,
+		SrcIP:   ip(h.SrcIP),
+		DstIP:   ip(h.DstIP),
+		SrcPort: port(h.SrcPort),
+		DstPort: port(h.DstPort),
+		DSCP:    cfg.DSCP,
+	}
+}
+
+// linkMetadata records the local link's speed, FEC and tuning profile, or
+// nil when traffic ran elsewhere
+func linkMetadata(ctx *dataplane.Context, iface string) *linkRun {
+	if ctx == nil {
+		return nil
+	}
+	rate := dataplane.GetLineRate(iface)
+	return &linkRun{
+		SpeedBps: rate,
+		Speed:    dataplane.FormatLinkSpeed(rate),
+		FEC:      dataplane.LinkFEC(iface),
+		Profile:  ctx.SpeedProfile(),
+	}
+}
+
+// wiringDiagram draws how the run expects the tester, DUT and far end to
+// be cabled
+func wiringDiagram(cfg *config.Config) *wiring.Diagram {
+	s := wiring.Setup{Topology: wiring.SinglePort, TX: cfg.Interface, DUT: cfg.DUT.Label()}
+	switch {
+	case cfg.TRex.Enabled():
+		s.Topology = wiring.PortPair
+		s.TX = fmt.Sprintf("TRex port %d", cfg.TRex.TxPort)
+		s.RX = fmt.Sprintf("TRex port %d", cfg.TRex.RxPort)
+	case cfg.Socket.Enabled():
+		s.Topology = wiring.TwoBox
+		s.TX = "this host"
+		s.FarEnd = cfg.Socket.Target
+	case cfg.TestType == config.TestUDPEcho:
+		s.Topology = wiring.TwoBox
+		s.TX = "this host"
+		if cfg.Interface != "" {
+			s.TX = cfg.Interface
+		}
+		s.FarEnd = cfg.UDPEcho.Target
+	case cfg.Ports.Enabled():
+		s.Topology = wiring.PortPair
+		s.RX = cfg.Ports.RX
+	case cfg.OAM.RemoteLoopback:
+		s.FarEnd = "OAM loopback"
+		if cfg.OAM.PeerMAC != "" {
+			s.FarEnd += " " + cfg.OAM.PeerMAC
+		}
+	}
+	d := wiring.Draw(s)
+	return &d
+}
+
+// dutMetadata returns the configured DUT, or nil when none is set
+func dutMetadata(cfg *config.Config) *config.DUTConfig {
+	if cfg.DUT.IsZero() {
+		return nil
+	}
+	dut := cfg.DUT
+	return &dut
+}
+
+// oamRun records OAM discovery and the far end looped for the run
+type oamRun struct {
+	Peers      []dataplane.OAMPeer `json:"peers"`
+	LoopedPeer string              `json:"looped_peer,omitempty"`
+	Released   bool                `json:"released"`
+}
+
+// resultsSchemaVersion is the shape of the -o json document. Version 1
+// was a bare array of results; version 2 wraps them with the run metadata.
+const resultsSchemaVersion = 2
+
+// jsonReport is the top-level JSON document written by -o json
+type jsonReport struct {
+	SchemaVersion int                      `json:"schema_version"`
+	Metadata      runMetadata              `json:"metadata"`
+	Deviations    []config.Deviation       `json:"deviations,omitempty"` // Overrides outside RFC 2544 or Y.1564
+	PreQual       *prequal.Report          `json:"prequalification,omitempty"`
+	DUTConfig     *dutconfig.Report        `json:"dut_config,omitempty"`
+	Results       []interface{}            `json:"results"`
+	Invalid       []backend.InvalidTrial   `json:"invalid_trials,omitempty"` // Cut short by a dataplane restart
+	LatencyCurve  *latcurve.Curve          `json:"latency_curve,omitempty"`
+	Modifiers     []modifierRun            `json:"modifier_results,omitempty"`
+	ControlPlane  []controlPlaneRun        `json:"control_plane_results,omitempty"`
+	Power         []powerRun               `json:"power_results,omitempty"`
+	FlowFailures  []flows.Report           `json:"flow_failures,omitempty"`
+	Compliance    []config.ComplianceCheck `json:"compliance,omitempty"`
+	Certification *certify.Report          `json:"certification,omitempty"` // Of a certify run, by RFC section
+	SelfProfile   *selfprof.Report         `json:"self_profile,omitempty"`
+	Thresholds    *threshold.Report        `json:"thresholds,omitempty"`
+}
+
+// resultTypes are the values runCLI stores in jsonReport.Results and in
+// the per-run Result fields
+var resultTypes = []reflect.Type{
+	reflect.TypeOf(&dataplane.ThroughputResultCLI{}),
+	reflect.TypeOf([]dataplane.LatencyResultCLI{}),
+	reflect.TypeOf([]dataplane.FrameLossResultCLI{}),
+	reflect.TypeOf(&dataplane.BackToBackResultCLI{}),
+	reflect.TypeOf(&dataplane.RecoveryResultCLI{}),
+	reflect.TypeOf(&dataplane.ResetResultCLI{}),
+	reflect.TypeOf(&suiteResult{}),
+	reflect.TypeOf(&characterizeResult{}),
+	reflect.TypeOf(&drift.Report{}),
+	reflect.TypeOf(&qos.Report{}),
+	reflect.TypeOf(&aqm.Report{}),
+	reflect.TypeOf(&microburst.Report{}),
+	reflect.TypeOf(&impair.Report{}),
+	reflect.TypeOf(&udpecho.Result{}),
+	reflect.TypeOf(&dataplane.Y1564ConfigResult{}),
+	reflect.TypeOf(&dataplane.Y1564PerfResult{}),
+	reflect.TypeOf(&dataplane.RFC2889ForwardingResult{}),
+}
+
+// resultsSchema describes the document written by -o json
+func resultsSchema() *schema.Schema {
+	g := schema.NewGenerator("json")
+	g.Required = true
+	g.Interfaces = resultTypes
+	return g.Document(jsonReport{}, schema.FileName(schema.NameResults), "RFC2544 Test Master results")
+}
+
+// publishedSchemas returns every schema by name
+func publishedSchemas() map[string]interface{} {
+	return map[string]interface{}{
+		schema.NameConfig:    schema.Config(),
+		schema.NameWebConfig: schema.WebConfig(),
+		schema.NameResults:   resultsSchema(),
+	}
+}
+
+// runCompare loads saved runs and writes the comparison matrix
+func runCompare(cmd *cobra.Command, args []string) error {
+	if len(compareRuns) < 2 {
+		return fmt.Errorf("at least two runs are required")
+	}
+	var runs []*compare.Run
+	for _, path := range compareRuns {
+		run, err := compare.Load(path)
+		if err != nil {
+			return err
+		}
+		runs = append(runs, run)
+	}
+	matrix := compare.Build(runs)
+	r, err := newRedactor()
+	if err != nil {
+		return err
+	}
+	if r != nil {
+		matrix.Redact(r)
+	}
+
+	output := os.Stdout
+	if outputFile != "" {
+		f, err := os.Create(outputFile)
+		if err != nil {
+			return fmt.Errorf("create output file: %w", err)
+		}
+		defer f.Close()
+		output = f
+	}
+
+	switch outputFormat {
+	case "json":
+		return matrix.WriteJSON(output)
+	case "csv":
+		return matrix.WriteCSV(output)
+	case "text":
+		return matrix.WriteText(output)
+	}
+	return fmt.Errorf("unknown output format: %s", outputFormat)
+}
+
+func runSLAReport(cmd *cobra.Command, args []string) error {
+	from, err := parseReportTime(slaFrom)
+	if err != nil {
+		return fmt.Errorf("--from: %w", err)
+	}
+	to, err := parseReportTime(slaTo)
+	if err != nil {
+		return fmt.Errorf("--to: %w", err)
+	}
+	var runs []*sla.Run
+	for _, path := range args {
+		run, err := sla.Load(path)
+		if err != nil {
+			return err
+		}
+		runs = append(runs, run)
+	}
+	rep, err := sla.Build(runs, from, to, sla.Params{
+		Percentile:      slaPercentile,
+		Window:          slaWindow,
+		UnavailLossPct:  slaUnavailLoss,
+		AvailabilityPct: slaAvailability,
+	})
+	if err != nil {
+		return err
+	}
+
+	output := os.Stdout
+	if outputFile != "" {
+		f, err := os.Create(outputFile)
+		if err != nil {
+			return fmt.Errorf("create output file: %w", err)
+		}
+		defer f.Close()
+		output = f
+	}
+
+	switch outputFormat {
+	case "json":
+		return rep.WriteJSON(output)
+	case "csv":
+		return rep.WriteCSV(output)
+	case "text":
+		return rep.WriteText(output)
+	}
+	return fmt.Errorf("unknown output format: %s", outputFormat)
+}
+
+// parseReportTime parses an RFC 3339 time or a date, in local time; ""
+// is the zero time
+func parseReportTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// slaServices records the Y.1564 services tested, so runs repeated on a
+// schedule can be reported per class of service
+func slaServices(cfg *config.Config) []sla.Service {
+	switch cfg.TestType {
+	case config.TestY1564Perf, config.TestY1564Full:
+	default:
+		return nil
+	}
+	var services []sla.Service
+	for _, svc := range cfg.Y1564.Services {
+		if svc.Enabled {
+			services = append(services, sla.Service{
+				ServiceID:       svc.ServiceID,
+				Name:            svc.ServiceName,
+				CoS:             svc.CoS,
+				FDThresholdMs:   svc.SLA.FDThresholdMs,
+				FDVThresholdMs:  svc.SLA.FDVThresholdMs,
+				FLRThresholdPct: svc.SLA.FLRThresholdPct,
+			})
+		}
+	}
+	return services
+}
+
+// checkInterface probes the test interface before the dataplane opens it,
+// failing early if it does not exist and warning about missing capabilities
+func checkInterface(cfg *config.Config) {
+	for _, name := range preflight.PortNames(cfg) {
+		nic, err := dataplane.DetectNIC(name)
+		if err != nil {
+			log.Fatalf("%v (see 'rfc2544 list-interfaces')", err)
+		}
+		if !nic.Up {
+			log.Printf("Warning: interface %s link is %s", nic.Name, nic.OperState)
+		}
+		if cfg.HWTimestamp && !nic.HWTimestamp && !cfg.Ports.Enabled() {
+			log.Printf("Warning: %s does not support hardware timestamping, latency uses software timestamps", nic.Name)
+		}
+		if cfg.LineRateMbps == 0 && nic.LinkSpeed == 0 {
+			log.Printf("Warning: link speed of %s is unknown, set line_rate_mbps in the config", nic.Name)
+		}
+	}
+}
+
+// runDryRunPlan dry-runs the configuration, or each plan step in turn
+func runDryRunPlan(cfg *config.Config) bool {
+	if !cfg.Plan.Enabled() {
+		return runDryRun(cfg)
+	}
+	ok := true
+	for i, st := range cfg.Plan.Steps {
+		fmt.Printf("=== Plan step %d of %d: %s ===\n", i+1, len(cfg.Plan.Steps), st.DisplayName())
+		step, err := cfg.Step(i)
+		if err != nil {
+			fmt.Printf("  [FAIL] %v\n\n", err)
+			ok = false
+			continue
+		}
+		ok = runDryRun(step) && ok
+		fmt.Println()
+	}
+	return ok
+}
+
+// runDryRun checks the config, interface and privileges and prints the test
+// plan. The dataplane is not opened, so no traffic is sent. It reports
+// whether every check passed.
+func runDryRun(cfg *config.Config) bool {
+	fmt.Printf("RFC2544 Test Master v%s (dry run)\n", version)
+	fmt.Println()
+	fmt.Println("Checks:")
+
+	frameSizes, err := testFrameSizes(cfg)
+	if err != nil {
+		// The configuration check gives the reason
+		frameSizes = cfg.TestFrameSizes()
+	}
+	c := preflight.Check(cfg, frameSizes)
+	for _, r := range c.Results {
+		fmt.Printf("  %-6s%s\n", r.Status, r.Message)
+	}
+
+	fmt.Println()
+	fmt.Println("Test plan:")
+	fmt.Printf("  Test: %s\n", cfg.TestType)
+	if label := cfg.DUT.Label(); label != "" {
+		fmt.Printf("  DUT: %s\n", label)
+	}
+	fmt.Printf("  Frame sizes: %v\n", frameSizes)
+	fmt.Printf("  Trial duration: %v (warmup %v)\n", cfg.TrialDuration, cfg.WarmupPeriod)
+	if cfg.Addressing.Pairs > 1 {
+		fmt.Printf("  Address pairs: %d\n", cfg.Addressing.Pairs)
+	}
+	if cfg.Addressing.Streams > 1 {
+		fmt.Printf("  Streams: %d\n", cfg.Addressing.Streams)
+	}
+	if !cfg.Framing.IsDefault() {
+		fmt.Printf("  Framing: %s\n", cfg.Framing)
+	}
+	if cfg.Packet.Enabled() {
+		fmt.Printf("  Packet template: %s\n", cfg.Packet.Template)
+	}
+	if cfg.Modifiers.Enabled() {
+		fmt.Println("  Repeated with Section 11 modifiers")
+	}
+	if cfg.ControlPlane.Enabled() {
+		fmt.Printf("  Repeated under control-plane stress toward %s\n", cfg.ControlPlane.DUTIP)
+	}
+	switch d, complete := preflight.Duration(cfg, frameSizes); {
+	case d == 0:
+		fmt.Printf("  Estimated duration: not available for %s\n", cfg.TestType)
+	case complete:
+		fmt.Printf("  Estimated duration: %v\n", d)
+	default:
+		fmt.Printf("  Estimated duration: at least %v (back-to-back bursts grow until the DUT drops frames)\n", d)
+	}
+	lineMbps := preflight.LineMbps(cfg)
+	printImpact(preflight.Impact(cfg, frameSizes, lineMbps), lineMbps, "  ")
+	printDeviations(cfg.Deviations())
+
+	fmt.Println()
+	fmt.Println("Wiring:")
+	for _, l := range strings.Split(strings.TrimRight(wiringDiagram(cfg).String(), "\n"), "\n") {
+		fmt.Printf("  %s\n", l)
+	}
+
+	fmt.Println()
+	if c.Failed > 0 {
+		fmt.Printf("%d check(s) failed\n", c.Failed)
+		return false
+	}
+	fmt.Println("Ready to run")
+	return true
+}
 
-		default:
-			fmt.Printf("  Unknown test type: %s\n", cfg.TestType)
+// printImpact prints the expected traffic of each test of the run and
+// their total
+func printImpact(phases []preflight.Phase, lineMbps float64, indent string) {
+	total := preflight.Total(phases)
+	if lineMbps > 0 {
+		fmt.Printf("%sExpected traffic (at most, %.0f Mbps line rate):\n", indent, lineMbps)
+	} else {
+		fmt.Printf("%sExpected traffic (line rate unknown, set line_rate_mbps for rates and volume):\n", indent)
+	}
+	row := func(name string, p preflight.Phase) {
+		d := "not estimated"
+		if p.Duration > 0 {
+			d = p.Duration.String()
 		}
+		fmt.Printf("%s  %-16s %14s %12.1f Mbps %12s\n", indent, name, d, p.PeakMbps, formatVolume(p.Bytes))
+	}
+	for _, p := range phases {
+		row(string(p.Test), p)
 	}
+	if len(phases) > 1 {
+		row("total", total)
+	}
+}
 
-	if cancelled.Load() {
-		fmt.Println("\nTest cancelled")
-		os.Exit(1)
+// webInService refuses a web test on an in-service interface of the
+// daemon's config unless the start request acknowledged its impact,
+// which the error gives
+func webInService(cfg *config.Config, webCfg web.Config) error {
+	test := *cfg
+	test.Interface = webCfg.Interface
+	if webCfg.Interface != cfg.Interface {
+		test.Ports.RX = ""
+	}
+	ports := test.InServicePorts()
+	if len(ports) == 0 || webCfg.AcknowledgeImpact {
+		return nil
 	}
 
-	// Output results in requested format
-	if err := outputResults(allResults, cfg.TestType); err != nil {
-		log.Printf("Error writing results: %v", err)
+	// The first match: getTestTypeInt gives tests the web UI lacks 0 too
+	for _, t := range config.TestTypes() {
+		if getTestTypeInt(t) == webCfg.TestType {
+			test.TestType = t
+			break
+		}
+	}
+	if webCfg.Soak != nil {
+		test = *webSoakConfig(&test, webCfg)
 	}
+	test.FrameSize, test.FrameSizes, test.IncludeJumbo = webCfg.FrameSize, nil, webCfg.IncludeJumbo
+	test.TrialDuration = webCfg.TrialDuration
+	test.Throughput.InitialRatePct = webCfg.InitialRatePct
+	test.Throughput.ResolutionPct = webCfg.ResolutionPct
+	test.LineRateMbps = webCfg.LineRateMbps
+	total := preflight.Total(preflight.Impact(&test, test.TestFrameSizes(), preflight.LineMbps(&test)))
+	return fmt.Errorf("%w: %s in service; the test sends up to %s at up to %.1f Mbps for %v, start it with acknowledge_impact to accept that",
+		web.ErrImpactNotAcknowledged, strings.Join(ports, ", "), formatVolume(total.Bytes), total.PeakMbps, total.Duration)
+}
 
-	fmt.Println("\nTest complete")
+// checkInService refuses a run on an in-service interface unless its
+// impact was acknowledged, printing the impact either way
+func checkInService(cfg *config.Config) error {
+	ports := cfg.InServicePorts()
+	if len(ports) == 0 {
+		return nil
+	}
+	lineMbps := preflight.LineMbps(cfg)
+	fmt.Printf("WARNING: %s in service (in_service in the config)\n", strings.Join(ports, ", "))
+	frameSizes, err := testFrameSizes(cfg)
+	if err != nil {
+		return err
+	}
+	printImpact(preflight.Impact(cfg, frameSizes, lineMbps), lineMbps, "")
+	fmt.Println()
+	if !ackImpact {
+		return fmt.Errorf("refusing to load in-service %s; rerun with --ack-impact to accept the traffic above", strings.Join(ports, ", "))
+	}
+	fmt.Println("Impact acknowledged with --ack-impact")
+	return nil
 }
 
-func runY1564Tests(ctx *dataplane.Context, cfg *config.Config, allResults *[]interface{}, cancelled *atomic.Bool) {
-	for _, svc := range cfg.Y1564.Services {
-		if cancelled.Load() || !svc.Enabled {
-			continue
-		}
+// runReflector returns udp-echo datagrams until interrupted
+func runReflector(cmd *cobra.Command, args []string) error {
+	r, err := udpecho.Listen(reflectorAddr)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Reflecting UDP echo datagrams on %s (Ctrl-C to stop)\n", r.Addr())
 
-		fmt.Printf("\n  Service %d: %s (CIR: %.2f Mbps)\n", svc.ServiceID, svc.ServiceName, svc.SLA.CIRMbps)
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		r.Close()
+	}()
 
-		dpSvc := &dataplane.Y1564Service{
-			ServiceID:   svc.ServiceID,
-			ServiceName: svc.ServiceName,
-			FrameSize:   svc.FrameSize,
-			CoS:         svc.CoS,
-			Enabled:     svc.Enabled,
-			SLA: dataplane.Y1564SLA{
-				CIRMbps:         svc.SLA.CIRMbps,
-				EIRMbps:         svc.SLA.EIRMbps,
-				CBSBytes:        svc.SLA.CBSBytes,
-				EBSBytes:        svc.SLA.EBSBytes,
-				FDThresholdMs:   svc.SLA.FDThresholdMs,
-				FDVThresholdMs:  svc.SLA.FDVThresholdMs,
-				FLRThresholdPct: svc.SLA.FLRThresholdPct,
-			},
-		}
+	err = r.Serve()
+	fmt.Printf("\nReflected %d datagrams\n", r.Reflected())
+	return err
+}
 
-		// Run Configuration Test
-		if cfg.TestType == config.TestY1564Config || cfg.TestType == config.TestY1564Full {
-			fmt.Printf("    Running Configuration Test (step test)...\n")
-			configResult, err := ctx.RunY1564ConfigTest(dpSvc)
-			if err != nil {
-				log.Printf("    Config test error: %v", err)
-			} else {
-				printY1564ConfigResult(configResult, &svc)
-				*allResults = append(*allResults, configResult)
-			}
+// runListInterfaces prints the capabilities of every interface
+func runListInterfaces(cmd *cobra.Command, args []string) error {
+	nics, err := dataplane.ListInterfaces()
+	if err != nil {
+		return err
+	}
+	recommended := ""
+	if nic, err := dataplane.RecommendInterface(); err == nil {
+		recommended = nic.Name
+	}
+
+	output := os.Stdout
+	if outputFile != "" {
+		f, err := os.Create(outputFile)
+		if err != nil {
+			return fmt.Errorf("create output file: %w", err)
 		}
+		defer f.Close()
+		output = f
+	}
 
-		// Run Performance Test
-		if cfg.TestType == config.TestY1564Perf || cfg.TestType == config.TestY1564Full {
-			durationSec := uint32(cfg.Y1564.PerfDuration.Seconds())
-			fmt.Printf("    Running Performance Test (%d minutes)...\n", durationSec/60)
-			perfResult, err := ctx.RunY1564PerfTest(dpSvc, durationSec)
-			if err != nil {
-				log.Printf("    Perf test error: %v", err)
-			} else {
-				printY1564PerfResult(perfResult, &svc)
-				*allResults = append(*allResults, perfResult)
+	switch outputFormat {
+	case "json":
+		enc := json.NewEncoder(output)
+		enc.SetIndent("", "  ")
+		return enc.Encode(nics)
+	case "csv":
+		w := csv.NewWriter(output)
+		w.Write([]string{"interface", "state", "speed_mbps", "mtu", "mac", "driver", "bus_info", "firmware", "hw_timestamp", "af_xdp", "dpdk_driver"})
+		for _, n := range nics {
+			w.Write([]string{n.Name, n.OperState, strconv.FormatUint(n.LinkSpeed/1000000, 10),
+				strconv.FormatUint(uint64(n.MTU), 10), n.MAC, n.Driver, n.BusInfo, n.Firmware,
+				strconv.FormatBool(n.HWTimestamp), strconv.FormatBool(n.XDP), n.DPDKDriver})
+		}
+		w.Flush()
+		return w.Error()
+	case "text":
+		tw := tabwriter.NewWriter(output, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "INTERFACE\tSTATE\tSPEED\tMTU\tDRIVER\tBUS\tHW-TS\tAF_XDP\tDPDK")
+		for _, n := range nics {
+			name := n.Name
+			if name == recommended {
+				name += " *"
+			}
+			dpdk := "no"
+			if n.DPDK {
+				dpdk = n.DPDKDriver
 			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\t%s\t%s\t%s\t%s\n", name, orDash(n.OperState),
+				dataplane.FormatLinkSpeed(n.LinkSpeed), n.MTU, orDash(n.Driver), orDash(n.BusInfo),
+				yesNo(n.HWTimestamp), yesNo(n.XDP), dpdk)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		if recommended != "" {
+			fmt.Fprintf(output, "\n* recommended for testing\n")
 		}
+		return nil
 	}
+	return fmt.Errorf("unknown output format: %s", outputFormat)
 }
 
-// RFC 2889 LAN Switch Benchmarking Tests
-func runRFC2889Tests(ctx *dataplane.Context, cfg *config.Config, allResults *[]interface{}, cancelled *atomic.Bool) {
-	if cancelled.Load() {
-		return
+// formatVolume formats a traffic volume in bytes, in GB from 1 GB up
+func formatVolume(bytes float64) string {
+	switch {
+	case bytes >= 1e9:
+		return fmt.Sprintf("%.2f GB", bytes/1e9)
+	case bytes >= 1e6:
+		return fmt.Sprintf("%.1f MB", bytes/1e6)
 	}
+	return fmt.Sprintf("%.0f kB", bytes/1e3)
+}
 
-	fmt.Printf("  RFC 2889 Configuration:\n")
-	fmt.Printf("    Port Count: %d\n", cfg.RFC2889.PortCount)
-	fmt.Printf("    Address Count: %d\n", cfg.RFC2889.AddressCount)
-	fmt.Printf("    Trial Duration: %v\n", cfg.RFC2889.TrialDuration)
-	fmt.Printf("    Acceptable Loss: %.2f%%\n", cfg.RFC2889.AcceptableLossPct)
-
-	switch cfg.TestType {
-	case config.TestRFC2889Forwarding:
-		fmt.Printf("  Running Forwarding Rate test...\n")
-		fmt.Printf("    [Test executing via C dataplane]\n")
-	case config.TestRFC2889Caching:
-		fmt.Printf("  Running Address Caching Capacity test...\n")
-		fmt.Printf("    Testing with %d MAC addresses\n", cfg.RFC2889.AddressCount)
-	case config.TestRFC2889Learning:
-		fmt.Printf("  Running Address Learning Rate test...\n")
-	case config.TestRFC2889Broadcast:
-		fmt.Printf("  Running Broadcast Forwarding test...\n")
-	case config.TestRFC2889Congestion:
-		fmt.Printf("  Running Congestion Control test...\n")
+func orDash(s string) string {
+	if s == "" {
+		return "-"
 	}
-
-	fmt.Printf("  RFC 2889 test complete\n")
+	return s
 }
 
-// RFC 6349 TCP Throughput Tests
-func runRFC6349Tests(ctx *dataplane.Context, cfg *config.Config, allResults *[]interface{}, cancelled *atomic.Bool) {
-	if cancelled.Load() {
-		return
+func yesNo(b bool) string {
+	if b {
+		return "yes"
 	}
+	return "no"
+}
 
-	fmt.Printf("  RFC 6349 Configuration:\n")
-	fmt.Printf("    Target Rate: %.2f Mbps\n", cfg.RFC6349.TargetRateMbps)
-	fmt.Printf("    MSS: %d bytes\n", cfg.RFC6349.MSS)
-	fmt.Printf("    RWND: %d bytes\n", cfg.RFC6349.RWND)
-	fmt.Printf("    Parallel Streams: %d\n", cfg.RFC6349.ParallelStreams)
-	fmt.Printf("    Test Duration: %v\n", cfg.RFC6349.TestDuration)
-
-	switch cfg.TestType {
-	case config.TestRFC6349Throughput:
-		fmt.Printf("  Running TCP Throughput test...\n")
-		fmt.Printf("    [Test executing via C dataplane]\n")
-	case config.TestRFC6349Path:
-		fmt.Printf("  Running Path Analysis test...\n")
-		fmt.Printf("    Measuring RTT and bottleneck bandwidth...\n")
+// runImport converts a third-party results file for report compare
+func runImport(cmd *cobra.Command, args []string) error {
+	var dut *config.DUTConfig
+	if dutName != "" {
+		dut = &config.DUTConfig{Name: dutName}
+	}
+	report, err := importer.Import(args[0], importer.Options{Format: importFormat, FrameSize: frameSize, DUT: dut})
+	if err != nil {
+		return err
 	}
 
-	fmt.Printf("  RFC 6349 test complete\n")
+	output := os.Stdout
+	if outputFile != "" {
+		f, err := os.Create(outputFile)
+		if err != nil {
+			return fmt.Errorf("create output file: %w", err)
+		}
+		defer f.Close()
+		output = f
+	}
+	return writeJSON(output, report)
 }
 
-// Y.1731 Ethernet OAM Tests
-func runY1731Tests(ctx *dataplane.Context, cfg *config.Config, allResults *[]interface{}, cancelled *atomic.Bool) {
-	if cancelled.Load() {
-		return
+// runRedact redacts a saved results or report file
+func runRedact(cmd *cobra.Command, args []string) error {
+	if redactMode == "" {
+		redactMode = redact.ModeStrip
 	}
-
-	fmt.Printf("  Y.1731 Configuration:\n")
-	fmt.Printf("    MEP ID: %d\n", cfg.Y1731.MEPID)
-	fmt.Printf("    MEG Level: %d\n", cfg.Y1731.MEGLevel)
-	fmt.Printf("    MEG ID: %s\n", cfg.Y1731.MEGID)
-	fmt.Printf("    Probe Count: %d\n", cfg.Y1731.ProbeCount)
-	fmt.Printf("    Probe Interval: %v\n", cfg.Y1731.ProbeInterval)
-
-	switch cfg.TestType {
-	case config.TestY1731Delay:
-		fmt.Printf("  Running Delay Measurement (DMM/DMR)...\n")
-		fmt.Printf("    [Test executing via C dataplane]\n")
-	case config.TestY1731Loss:
-		fmt.Printf("  Running Loss Measurement (LMM/LMR)...\n")
-	case config.TestY1731SLM:
-		fmt.Printf("  Running Synthetic Loss Measurement...\n")
-	case config.TestY1731Loopback:
-		fmt.Printf("  Running Loopback test (LBM/LBR)...\n")
+	r, err := newRedactor()
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+	out, err := r.JSON(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", args[0], err)
 	}
 
-	fmt.Printf("  Y.1731 test complete\n")
+	output := os.Stdout
+	if outputFile != "" {
+		f, err := os.Create(outputFile)
+		if err != nil {
+			return fmt.Errorf("create output file: %w", err)
+		}
+		defer f.Close()
+		output = f
+	}
+	_, err = output.Write(append(out, '\n'))
+	return err
 }
 
-// MEF Service Activation Tests
-func runMEFTests(ctx *dataplane.Context, cfg *config.Config, allResults *[]interface{}, cancelled *atomic.Bool) {
-	if cancelled.Load() {
-		return
+// runSchema prints one schema, lists them, or writes all of them to --dir
+// lookupCircuit finds --circuit in --inventory, or the inventory the config
+// names
+func lookupCircuit(cfg *config.Config) (*config.Circuit, error) {
+	inv := cfg.Inventory
+	if inventory != "" {
+		inv.Source = inventory
 	}
+	return inv.LookupCircuit(context.Background(), circuitID)
+}
 
-	fmt.Printf("  MEF Configuration:\n")
-	fmt.Printf("    CIR: %.2f Mbps\n", cfg.MEF.CIRMbps)
-	fmt.Printf("    EIR: %.2f Mbps\n", cfg.MEF.EIRMbps)
-	fmt.Printf("    CBS: %d bytes\n", cfg.MEF.CBSBytes)
-	fmt.Printf("    EBS: %d bytes\n", cfg.MEF.EBSBytes)
-	fmt.Printf("    FD Threshold: %.2f us\n", cfg.MEF.FDThresholdUs)
-	fmt.Printf("    FDV Threshold: %.2f us\n", cfg.MEF.FDVThresholdUs)
-	fmt.Printf("    FLR Threshold: %.4f%%\n", cfg.MEF.FLRThresholdPct)
-	fmt.Printf("    Availability: %.2f%%\n", cfg.MEF.AvailThresholdPct)
-
-	switch cfg.TestType {
-	case config.TestMEFConfig:
-		fmt.Printf("  Running MEF Configuration Test (step test)...\n")
-		fmt.Printf("    [Test executing via C dataplane]\n")
-	case config.TestMEFPerf:
-		fmt.Printf("  Running MEF Performance Test (%v)...\n", cfg.MEF.PerfDuration)
-	case config.TestMEFFull:
-		fmt.Printf("  Running Full MEF Test Suite...\n")
-		fmt.Printf("    Phase 1: Configuration Test\n")
-		fmt.Printf("    Phase 2: Performance Test (%v)\n", cfg.MEF.PerfDuration)
+// loadPresets returns the built-in presets and the user's profiles
+func loadPresets() (*config.Presets, error) {
+	dir, err := config.ProfileDir()
+	if err != nil {
+		// No home directory: the built-ins still work
+		dir = ""
 	}
-
-	fmt.Printf("  MEF test complete\n")
+	return config.LoadPresets(dir)
 }
 
-// TSN (IEEE 802.1Qbv) Tests
-func runTSNTests(ctx *dataplane.Context, cfg *config.Config, allResults *[]interface{}, cancelled *atomic.Bool) {
-	if cancelled.Load() {
-		return
+func runProfilesList(cmd *cobra.Command, args []string) error {
+	presets, err := loadPresets()
+	if err != nil {
+		return err
 	}
-
-	fmt.Printf("  TSN Configuration:\n")
-	fmt.Printf("    Traffic Classes: %d\n", cfg.TSN.NumClasses)
-	fmt.Printf("    Cycle Time: %d ns\n", cfg.TSN.CycleTimeNs)
-	fmt.Printf("    Max Latency: %d ns\n", cfg.TSN.MaxLatencyNs)
-	fmt.Printf("    Max Jitter: %d ns\n", cfg.TSN.MaxJitterNs)
-	fmt.Printf("    Max Sync Offset: %d ns\n", cfg.TSN.MaxSyncOffsetNs)
-	fmt.Printf("    Frame Size: %d bytes\n", cfg.TSN.FrameSize)
-	fmt.Printf("    Test Duration: %v\n", cfg.TSN.TestDuration)
-
-	switch cfg.TestType {
-	case config.TestTSNTiming:
-		fmt.Printf("  Running Gate Timing Accuracy test...\n")
-		fmt.Printf("    [Test executing via C dataplane]\n")
-	case config.TestTSNIsolation:
-		fmt.Printf("  Running Traffic Class Isolation test...\n")
-	case config.TestTSNLatency:
-		fmt.Printf("  Running Scheduled Latency test...\n")
-	case config.TestTSNFull:
-		fmt.Printf("  Running Full TSN Test Suite...\n")
-		fmt.Printf("    Phase 1: Gate Timing Accuracy\n")
-		fmt.Printf("    Phase 2: Traffic Class Isolation\n")
-		fmt.Printf("    Phase 3: Scheduled Latency\n")
+	for _, p := range presets.List() {
+		desc := p.Description
+		if !p.Builtin() {
+			desc += " (" + p.Path + ")"
+		}
+		fmt.Printf("%-18s %s\n", p.Name, strings.TrimSpace(desc))
+	}
+	if dir, err := config.ProfileDir(); err == nil {
+		fmt.Printf("\nUser profiles: %s/NAME.yaml\n", dir)
 	}
+	return nil
+}
 
-	fmt.Printf("  TSN test complete\n")
+func runProfilesShow(cmd *cobra.Command, args []string) error {
+	presets, err := loadPresets()
+	if err != nil {
+		return err
+	}
+	p, err := presets.Get(args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Print(p.YAML)
+	return nil
 }
 
-func printThroughputResult(r *dataplane.ThroughputResultCLI, frameSize uint32) {
-	fmt.Printf("  Results for %d bytes:\n", frameSize)
-	fmt.Printf("    Max Rate: %.2f%% (%.2f Mbps, %.0f pps)\n", r.MaxRatePct, r.MaxRateMbps, r.MaxRatePPS)
-	fmt.Printf("    Iterations: %d\n", r.Iterations)
-	if r.Latency.Count > 0 {
-		fmt.Printf("    Latency: min=%.2fus avg=%.2fus max=%.2fus\n",
-			r.Latency.MinNs/1000, r.Latency.AvgNs/1000, r.Latency.MaxNs/1000)
+// runConfigValidate loads each file in strict mode, without the profile,
+// environment or flags a run would apply on top
+func runConfigValidate(cmd *cobra.Command, args []string) error {
+	failed := 0
+	for _, path := range args {
+		if _, err := config.LoadLayers(config.Layers{File: path, Strict: true}); err != nil {
+			fmt.Printf("%s: %v\n", path, err)
+			failed++
+			continue
+		}
+		fmt.Printf("%s: OK\n", path)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d config file(s) invalid", failed, len(args))
 	}
+	return nil
 }
 
-func printLatencyResults(results []dataplane.LatencyResultCLI, frameSize uint32) {
-	fmt.Printf("  Latency results for %d bytes:\n", frameSize)
-	fmt.Printf("    %8s %12s %12s %12s %12s\n", "Load%", "Min(us)", "Avg(us)", "Max(us)", "Jitter(us)")
-	for _, r := range results {
-		fmt.Printf("    %8.1f %12.2f %12.2f %12.2f %12.2f\n",
-			r.LoadPct, r.Latency.MinNs/1000, r.Latency.AvgNs/1000, r.Latency.MaxNs/1000, r.Latency.JitterNs/1000)
+// runImportPlan converts a CSV test matrix into a plan config file
+func runImportPlan(cmd *cobra.Command, args []string) error {
+	f, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	plan, ignored, err := config.ParsePlanCSV(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", args[0], err)
 	}
-}
+	if len(ignored) > 0 {
+		fmt.Fprintf(os.Stderr, "%s: ignoring columns %s\n", args[0], strings.Join(ignored, ", "))
+	}
+	plan.ContinueOnFailure = planContinue
 
-func printFrameLossResults(results []dataplane.FrameLossResultCLI, frameSize uint32) {
-	fmt.Printf("  Frame loss results for %d bytes:\n", frameSize)
-	fmt.Printf("    %8s %12s %12s %12s\n", "Load%", "TX", "RX", "Loss%")
-	for _, r := range results {
-		fmt.Printf("    %8.1f %12d %12d %12.4f\n", r.OfferedPct, r.FramesTx, r.FramesRx, r.LossPct)
+	data, err := plan.File(planIncludes)
+	if err != nil {
+		return err
 	}
-}
+	data = append([]byte("# Imported from "+filepath.Base(args[0])+"\n"), data...)
 
-func printBackToBackResult(r *dataplane.BackToBackResultCLI, frameSize uint32) {
-	fmt.Printf("  Back-to-back results for %d bytes:\n", frameSize)
-	fmt.Printf("    Max Burst: %d frames\n", r.MaxBurstFrames)
-	fmt.Printf("    Burst Duration: %.2f us\n", float64(r.BurstDurationUs))
-	fmt.Printf("    Trials: %d\n", r.Trials)
+	if outputFile == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(outputFile, data, 0644)
 }
 
-func printRecoveryResult(r *dataplane.RecoveryResultCLI, frameSize uint32) {
-	fmt.Printf("  System Recovery results for %d bytes:\n", frameSize)
-	fmt.Printf("    Overload Rate: %.1f%% for %d seconds\n", r.OverloadRatePct, r.OverloadSec)
-	fmt.Printf("    Recovery Rate: %.1f%%\n", r.RecoveryRatePct)
-	if r.RecoveryTimeMs >= 0 {
-		fmt.Printf("    Recovery Time: %.2f ms\n", r.RecoveryTimeMs)
-	} else {
-		fmt.Printf("    Recovery Time: DID NOT RECOVER\n")
+// readSecrets decrypts a secrets file with the --key-file or environment key
+func readSecrets(path string) (map[string]string, error) {
+	key, err := config.SecretsConfig{KeyFile: secretsKeyFile}.Key("")
+	if err != nil {
+		return nil, err
 	}
-	fmt.Printf("    Frames Lost: %d\n", r.FramesLost)
-	fmt.Printf("    Trials: %d\n", r.Trials)
+	return config.ReadSecretsFile(path, key)
 }
 
-func printResetResult(r *dataplane.ResetResultCLI, frameSize uint32) {
-	fmt.Printf("  Reset test results for %d bytes:\n", frameSize)
-	if r.ResetTimeMs >= 0 {
-		fmt.Printf("    Reset Time: %.2f ms\n", r.ResetTimeMs)
-	} else {
-		fmt.Printf("    Reset Time: NOT DETECTED OR DID NOT RECOVER\n")
+// runSecretsSet stores one secret. The value comes from stdin, not an
+// argument, so it stays out of the shell history and process list.
+func runSecretsSet(cmd *cobra.Command, args []string) error {
+	path, name := args[0], args[1]
+	key, err := config.SecretsConfig{KeyFile: secretsKeyFile}.Key("")
+	if err != nil {
+		return err
 	}
-	fmt.Printf("    Frames Lost: %d\n", r.FramesLost)
-	fmt.Printf("    Trials: %d\n", r.Trials)
-	fmt.Printf("    Manual Reset: %t\n", r.ManualReset)
+	values := make(map[string]string)
+	if _, err := os.Stat(path); err == nil {
+		if values, err = config.ReadSecretsFile(path, key); err != nil {
+			return err
+		}
+	}
+
+	if fi, err := os.Stdin.Stat(); err == nil && fi.Mode()&os.ModeCharDevice != 0 {
+		fmt.Fprintf(os.Stderr, "Value for %s: ", name)
+	}
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return err
+	}
+	value := strings.TrimRight(string(data), "\r\n")
+	if value == "" {
+		return fmt.Errorf("no value on stdin for %s", name)
+	}
+	values[name] = value
+	if err := config.WriteSecretsFile(path, key, values); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Stored %s in %s (%d secrets)\n", name, path, len(values))
+	return nil
 }
 
-func printY1564ConfigResult(r *dataplane.Y1564ConfigResult, svc *config.Y1564Service) {
-	passStr := "PASS"
-	if !r.ServicePass {
-		passStr = "FAIL"
+func runSchema(cmd *cobra.Command, args []string) error {
+	schemas := publishedSchemas()
+
+	if schemaDir != "" {
+		if err := os.MkdirAll(schemaDir, 0755); err != nil {
+			return err
+		}
+		for name, doc := range schemas {
+			data, err := json.MarshalIndent(doc, "", "  ")
+			if err != nil {
+				return err
+			}
+			path := filepath.Join(schemaDir, schema.FileName(name))
+			if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+				return err
+			}
+			fmt.Printf("Wrote %s\n", path)
+		}
+		return nil
 	}
-	fmt.Printf("    Configuration Test: %s\n", passStr)
-	fmt.Printf("      %8s %10s %10s %10s %10s %8s\n", "Step", "Rate%", "FLR%", "FD(ms)", "FDV(ms)", "Result")
-	for _, step := range r.Steps {
-		stepPass := "PASS"
-		if !step.StepPass {
-			stepPass = "FAIL"
+
+	if len(args) == 0 {
+		for _, name := range []string{schema.NameConfig, schema.NameWebConfig, schema.NameResults} {
+			fmt.Printf("%-12s %s\n", name, schema.FileName(name))
 		}
-		fmt.Printf("      %8d %10.1f %10.4f %10.2f %10.2f %8s\n",
-			step.Step, step.OfferedRatePct, step.FLRPct, step.FDAvgMs, step.FDVMs, stepPass)
+		return nil
 	}
-}
 
-func printY1564PerfResult(r *dataplane.Y1564PerfResult, svc *config.Y1564Service) {
-	passStr := "PASS"
-	if !r.ServicePass {
-		passStr = "FAIL"
+	doc, ok := schemas[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown schema %q (config, web-config, results)", args[0])
 	}
-	fmt.Printf("    Performance Test (%ds): %s\n", r.DurationSec, passStr)
-	fmt.Printf("      Frames: TX=%d RX=%d\n", r.FramesTx, r.FramesRx)
-	fmt.Printf("      FLR: %.4f%% (threshold: %.4f%%) - %s\n", r.FLRPct, svc.SLA.FLRThresholdPct, passFailStr(r.FLRPass))
-	fmt.Printf("      FD:  %.2f ms (threshold: %.2f ms) - %s\n", r.FDAvgMs, svc.SLA.FDThresholdMs, passFailStr(r.FDPass))
-	fmt.Printf("      FDV: %.2f ms (threshold: %.2f ms) - %s\n", r.FDVMs, svc.SLA.FDVThresholdMs, passFailStr(r.FDVPass))
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(doc)
 }
 
-func passFailStr(pass bool) string {
-	if pass {
-		return "PASS"
+func runSDK(cmd *cobra.Command, args []string) error {
+	if sdkDir != "" {
+		for _, lang := range sdk.Languages() {
+			src, err := sdk.Generate(lang)
+			if err != nil {
+				return err
+			}
+			path := filepath.Join(sdkDir, sdk.FileName(lang))
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return err
+			}
+			if err := os.WriteFile(path, src, 0644); err != nil {
+				return err
+			}
+			fmt.Printf("Wrote %s\n", path)
+		}
+		return nil
 	}
-	return "FAIL"
+
+	if len(args) == 0 {
+		for _, lang := range sdk.Languages() {
+			fmt.Printf("%-12s %s\n", lang, sdk.FileName(lang))
+		}
+		return nil
+	}
+
+	src, err := sdk.Generate(args[0])
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(src)
+	return err
 }
 
-func outputResults(results []interface{}, testType config.TestType) error {
-	if len(results) == 0 {
+func outputResults(report jsonReport, cfg *config.Config) error {
+	if len(report.Results) == 0 && report.PreQual == nil {
 		return nil
 	}
 
@@ -1252,19 +8181,49 @@ func outputResults(results []interface{}, testType config.TestType) error {
 
 	switch outputFormat {
 	case "json":
-		return outputJSON(output, results)
+		return outputJSON(output, report)
 	case "csv":
-		return outputCSV(output, results, testType)
+		return outputCSV(output, report.Results, cfg.TestType)
+	case "html":
+		return outputHTML(output, report, cfg)
+	case "pdf":
+		return outputPDF(output, report, cfg)
 	default:
 		// Text output already printed
 		return nil
 	}
 }
 
-func outputJSON(w *os.File, results []interface{}) error {
-	encoder := json.NewEncoder(w)
-	encoder.SetIndent("", "  ")
-	return encoder.Encode(results)
+// newRedactor returns the redactor selected by --redact, or nil
+func newRedactor() (*redact.Redactor, error) {
+	if redactMode == "" {
+		return nil, nil
+	}
+	return redact.New(redactMode, redactSalt)
+}
+
+// writeJSON writes v as indented JSON, redacted when --redact is set
+func writeJSON(w io.Writer, v interface{}) error {
+	r, err := newRedactor()
+	if err != nil {
+		return err
+	}
+	if r == nil {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	}
+	data, err := r.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+func outputJSON(w *os.File, report jsonReport) error {
+	report.SchemaVersion = resultsSchemaVersion
+	return writeJSON(w, report)
 }
 
 func outputCSV(w *os.File, results []interface{}, testType config.TestType) error {
@@ -1273,9 +8232,18 @@ func outputCSV(w *os.File, results []interface{}, testType config.TestType) erro
 
 	switch testType {
 	case config.TestThroughput:
-		writer.Write([]string{"FrameSize", "MaxRatePct", "MaxRateMbps", "MaxRatePPS", "Iterations", "LatencyMinUs", "LatencyAvgUs", "LatencyMaxUs"})
+		// Trials lists the search's iterations as rate:loss:pass|fail, and
+		// TrialLatencyAvgUs their average latencies in the same order
+		writer.Write([]string{"FrameSize", "MaxRatePct", "MaxRateMbps", "MaxRatePPS", "Iterations", "LatencyMinUs", "LatencyAvgUs", "LatencyMaxUs", "AcceptableLossPct",
+			"ReorderedFrames", "DuplicateFrames", "MaxReorderDepth", "CorruptedFrames", "Trials", "TrialLatencyAvgUs"})
 		for _, r := range results {
 			if tr, ok := r.(*dataplane.ThroughputResultCLI); ok {
+				trials := make([]string, len(tr.Trials))
+				latency := make([]string, len(tr.Trials))
+				for i, t := range tr.Trials {
+					trials[i] = fmt.Sprintf("%.4f:%.4f:%s", t.RatePct, t.LossPct, trialDecision(t))
+					latency[i] = fmt.Sprintf("%.2f", t.Latency.AvgNs/1000)
+				}
 				writer.Write([]string{
 					fmt.Sprintf("%d", tr.FrameSize),
 					fmt.Sprintf("%.4f", tr.MaxRatePct),
@@ -1285,6 +8253,13 @@ func outputCSV(w *os.File, results []interface{}, testType config.TestType) erro
 					fmt.Sprintf("%.2f", tr.Latency.MinNs/1000),
 					fmt.Sprintf("%.2f", tr.Latency.AvgNs/1000),
 					fmt.Sprintf("%.2f", tr.Latency.MaxNs/1000),
+					fmt.Sprintf("%.4g", tr.AcceptableLossPct),
+					fmt.Sprintf("%d", tr.ReorderedFrames),
+					fmt.Sprintf("%d", tr.DuplicateFrames),
+					fmt.Sprintf("%d", tr.MaxReorderDepth),
+					fmt.Sprintf("%d", tr.CorruptedFrames),
+					strings.Join(trials, " "),
+					strings.Join(latency, " "),
 				})
 			}
 		}
@@ -1310,7 +8285,7 @@ func outputCSV(w *os.File, results []interface{}, testType config.TestType) erro
 		}
 
 	case config.TestFrameLoss:
-		writer.Write([]string{"FrameSize", "OfferedPct", "FramesTx", "FramesRx", "LossPct"})
+		writer.Write([]string{"FrameSize", "OfferedPct", "FramesTx", "FramesRx", "LossPct", "ReorderedFrames", "DuplicateFrames", "MaxReorderDepth", "CorruptedFrames"})
 		for _, r := range results {
 			if flrs, ok := r.([]dataplane.FrameLossResultCLI); ok {
 				for _, fl := range flrs {
@@ -1320,6 +8295,10 @@ func outputCSV(w *os.File, results []interface{}, testType config.TestType) erro
 						fmt.Sprintf("%d", fl.FramesTx),
 						fmt.Sprintf("%d", fl.FramesRx),
 						fmt.Sprintf("%.4f", fl.LossPct),
+						fmt.Sprintf("%d", fl.ReorderedFrames),
+						fmt.Sprintf("%d", fl.DuplicateFrames),
+						fmt.Sprintf("%d", fl.MaxReorderDepth),
+						fmt.Sprintf("%d", fl.CorruptedFrames),
 					})
 				}
 			}
@@ -1368,6 +8347,155 @@ func outputCSV(w *os.File, results []interface{}, testType config.TestType) erro
 			}
 		}
 
+	case config.TestSuite, config.TestCharacterize:
+		// One table per test, each under a title row naming the test
+		tests := suiteTests
+		if testType == config.TestCharacterize {
+			tests = characterizeTests
+		}
+		for i, t := range tests {
+			var section []interface{}
+			for _, r := range results {
+				if mr, ok := r.(multiResult); ok {
+					if tr := mr.Result(t); tr != nil {
+						section = append(section, tr)
+					}
+				}
+			}
+			if len(section) == 0 {
+				continue
+			}
+			if i > 0 {
+				writer.Write(nil)
+			}
+			writer.Write([]string{string(t)})
+			writer.Flush()
+			if err := outputCSV(w, section, t); err != nil {
+				return err
+			}
+		}
+
+	case config.TestQoS:
+		writer.Write([]string{"FrameSize", "Class", "PCP", "DSCP", "OfferedSharePct", "OfferedMbps", "FramesTx", "FramesRx",
+			"LossPct", "DeliveredMbps", "DeliveredSharePct", "LatencyAvgUs", "LatencyMaxUs", "JitterUs"})
+		for _, r := range results {
+			qr, ok := r.(*qos.Report)
+			if !ok {
+				continue
+			}
+			for _, c := range qr.Classes {
+				writer.Write([]string{
+					fmt.Sprintf("%d", qr.FrameSize),
+					c.Name,
+					fmt.Sprintf("%d", c.PCP),
+					fmt.Sprintf("%d", c.DSCP),
+					fmt.Sprintf("%.2f", c.OfferedSharePct),
+					fmt.Sprintf("%.2f", c.OfferedMbps),
+					fmt.Sprintf("%d", c.FramesTx),
+					fmt.Sprintf("%d", c.FramesRx),
+					fmt.Sprintf("%.4f", c.LossPct),
+					fmt.Sprintf("%.2f", c.DeliveredMbps),
+					fmt.Sprintf("%.2f", c.DeliveredSharePct),
+					fmt.Sprintf("%.2f", c.Latency.AvgNs/1000),
+					fmt.Sprintf("%.2f", c.Latency.MaxNs/1000),
+					fmt.Sprintf("%.2f", c.Latency.JitterNs/1000),
+				})
+			}
+		}
+
+	case config.TestAQM:
+		writer.Write([]string{"FrameSize", "LoadPct", "LossPct", "LatencyAvgUs", "LatencyP99Us", "QueueUs"})
+		for _, r := range results {
+			ar, ok := r.(*aqm.Report)
+			if !ok {
+				continue
+			}
+			for _, st := range ar.Steps {
+				writer.Write([]string{
+					fmt.Sprintf("%d", ar.FrameSize),
+					fmt.Sprintf("%.2f", st.LoadPct),
+					fmt.Sprintf("%.4f", st.LossPct),
+					fmt.Sprintf("%.2f", st.AvgUs),
+					fmt.Sprintf("%.2f", st.P99Us),
+					fmt.Sprintf("%.2f", st.QueueUs),
+				})
+			}
+		}
+
+	case config.TestMicroburst:
+		writer.Write([]string{"FrameSize", "GapUs", "BurstFrames", "Burst", "FramesTx", "FramesRx", "LossPct", "MaxLatencyUs", "DrainMbps"})
+		for _, r := range results {
+			mr, ok := r.(*microburst.Report)
+			if !ok {
+				continue
+			}
+			for _, g := range mr.Gaps {
+				for _, t := range g.Trains {
+					for i, b := range t.Bursts {
+						writer.Write([]string{
+							fmt.Sprintf("%d", mr.FrameSize),
+							fmt.Sprintf("%.1f", g.Summary.GapUs),
+							fmt.Sprintf("%d", t.Frames),
+							fmt.Sprintf("%d", i+1),
+							fmt.Sprintf("%d", b.FramesTx),
+							fmt.Sprintf("%d", b.FramesRx),
+							fmt.Sprintf("%.4f", b.LossPct),
+							fmt.Sprintf("%.2f", b.MaxUs),
+							fmt.Sprintf("%.2f", b.DrainMbps),
+						})
+					}
+				}
+			}
+		}
+
+	case config.TestSelfTest:
+		writer.Write([]string{"FrameSize", "Case", "Netem", "LatencyUs", "ExpectedLatencyUs", "JitterUs", "ExpectedJitterUs",
+			"LossPct", "ExpectedLossPct", "Reordered", "Passed"})
+		for _, r := range results {
+			sr, ok := r.(*impair.Report)
+			if !ok {
+				continue
+			}
+			for _, c := range sr.Cases {
+				writer.Write([]string{
+					fmt.Sprintf("%d", sr.FrameSize),
+					c.Name,
+					c.Netem.String(),
+					fmt.Sprintf("%.2f", c.Measured.AvgUs),
+					fmt.Sprintf("%.2f", c.Expected.AvgUs),
+					fmt.Sprintf("%.2f", c.Measured.JitterUs),
+					fmt.Sprintf("%.2f", c.Expected.JitterUs),
+					fmt.Sprintf("%.4f", c.Measured.LossPct),
+					fmt.Sprintf("%.4f", c.Expected.LossPct),
+					fmt.Sprintf("%d", c.Measured.Reordered),
+					fmt.Sprintf("%t", c.Passed),
+				})
+			}
+		}
+
+	case config.TestUDPEcho:
+		writer.Write([]string{"FrameSize", "RatePPS", "Sent", "Received", "LossPct", "Reordered", "Duplicates",
+			"MinUs", "AvgUs", "MaxUs", "JitterUs", "P99Us", "ReducedAccuracy"})
+		for _, r := range results {
+			if ur, ok := r.(*udpecho.Result); ok {
+				writer.Write([]string{
+					fmt.Sprintf("%d", ur.FrameSize),
+					fmt.Sprintf("%d", ur.RatePPS),
+					fmt.Sprintf("%d", ur.Sent),
+					fmt.Sprintf("%d", ur.Received),
+					fmt.Sprintf("%.4f", ur.LossPct),
+					fmt.Sprintf("%d", ur.Reordered),
+					fmt.Sprintf("%d", ur.Duplicates),
+					fmt.Sprintf("%.2f", ur.MinUs),
+					fmt.Sprintf("%.2f", ur.AvgUs),
+					fmt.Sprintf("%.2f", ur.MaxUs),
+					fmt.Sprintf("%.2f", ur.JitterUs),
+					fmt.Sprintf("%.2f", ur.P99Us),
+					strings.Join(ur.ReducedAccuracy, ";"),
+				})
+			}
+		}
+
 	case config.TestY1564Config, config.TestY1564Perf, config.TestY1564Full:
 		writer.Write([]string{"ServiceID", "TestPhase", "Step", "FLRPct", "FDMs", "FDVMs", "Pass"})
 		for _, r := range results {
@@ -1401,6 +8529,352 @@ func outputCSV(w *os.File, results []interface{}, testType config.TestType) erro
 	return nil
 }
 
+// outputHTML writes the run as a self-contained HTML report
+func outputHTML(w *os.File, report jsonReport, cfg *config.Config) error {
+	return htmlReport(report, cfg).Write(w)
+}
+
+// outputPDF writes the run as a PDF acceptance certificate
+func outputPDF(w *os.File, run jsonReport, cfg *config.Config) error {
+	cert, err := certificate(run, cfg)
+	if err != nil {
+		return err
+	}
+	return cert.Write(w)
+}
+
+// certificate lays out a run as an acceptance certificate: the HTML
+// report's sections without charts, plus the circuit identification, the
+// thresholds the results were judged against and a sign-off block. The
+// digest covers the run's JSON document, so the PDF can be matched to the
+// -o json results of the same run.
+func certificate(run jsonReport, cfg *config.Config) (*report.Certificate, error) {
+	data, err := json.Marshal(run)
+	if err != nil {
+		return nil, fmt.Errorf("encode results: %w", err)
+	}
+	digest := sha256.Sum256(data)
+
+	c := &report.Certificate{
+		Title:      fmt.Sprintf("Test Certificate: %s", cfg.TestType),
+		Digest:     hex.EncodeToString(digest[:]),
+		Signatures: []string{"Tested by", "Accepted by"},
+	}
+	switch {
+	case cfg.TestType == config.TestY1564Config || cfg.TestType == config.TestY1564Perf || cfg.TestType == config.TestY1564Full:
+		c.Title = "Service Activation Certificate"
+		c.Standard = "ITU-T Y.1564 Ethernet service activation test methodology"
+		c.Criteria = []report.Table{y1564SLATable(cfg)}
+	case config.IsRFC2544Test(cfg.TestType):
+		c.Title = "Acceptance Test Certificate"
+		c.Standard = "RFC 2544 Benchmarking Methodology for Network Interconnect Devices"
+		if run.Certification != nil {
+			c.Title = "RFC 2544 Certification"
+		}
+	}
+	if cfg.Thresholds.Enabled() {
+		c.Criteria = append(c.Criteria, thresholdsTable(cfg.Thresholds))
+	}
+
+	// Circuit and DUT identify the certificate; the rest is test setup
+	cc := cfg.Certificate
+	for _, f := range []report.Field{{Name: "Circuit ID", Value: cc.CircuitID}, {Name: "Customer", Value: cc.Customer}, {Name: "Location", Value: cc.Location}, {Name: "Technician", Value: cc.Technician}} {
+		if f.Value != "" {
+			c.Identity = append(c.Identity, f)
+		}
+	}
+	for _, f := range dutFields(run.Metadata.DUT) {
+		c.Identity = append(c.Identity, report.Field(f))
+	}
+	meta := run.Metadata
+	meta.DUT = nil
+	for _, f := range htmlConfig(meta, cfg) {
+		c.Setup = append(c.Setup, report.Field(f))
+	}
+
+	h := htmlReport(run, cfg)
+	c.Caveats = h.Caveats
+	for _, hs := range h.Sections {
+		s := report.Section{Title: hs.Title, Verdict: certificateVerdict(hs.Verdict), Detail: hs.Detail}
+		for _, ht := range hs.Tables {
+			t := report.Table{Caption: ht.Caption, Header: ht.Header, Rows: ht.Rows}
+			for _, v := range ht.Verdicts {
+				t.Verdicts = append(t.Verdicts, certificateVerdict(v))
+			}
+			s.Tables = append(s.Tables, t)
+		}
+		c.Sections = append(c.Sections, s)
+	}
+	return c, nil
+}
+
+func certificateVerdict(v htmlreport.Verdict) report.Verdict {
+	switch v {
+	case htmlreport.VerdictPass:
+		return report.VerdictPass
+	case htmlreport.VerdictFail:
+		return report.VerdictFail
+	}
+	return report.VerdictNone
+}
+
+// y1564SLATable lists each enabled service's SLA thresholds
+func y1564SLATable(cfg *config.Config) report.Table {
+	t := report.Table{
+		Caption: "Service level agreement",
+		Header:  []string{"Service", "Name", "Frame size", "CoS", "CIR (Mbps)", "EIR (Mbps)", "FD max (ms)", "FDV max (ms)", "FLR max %"},
+	}
+	for _, svc := range cfg.Y1564.Services {
+		if !svc.Enabled {
+			continue
+		}
+		t.Rows = append(t.Rows, []string{
+			fmt.Sprintf("%d", svc.ServiceID), svc.ServiceName, fmt.Sprintf("%d", svc.FrameSize), fmt.Sprintf("%d", svc.CoS),
+			fmt.Sprintf("%.2f", svc.SLA.CIRMbps), fmt.Sprintf("%.2f", svc.SLA.EIRMbps),
+			fmt.Sprintf("%.2f", svc.SLA.FDThresholdMs), fmt.Sprintf("%.2f", svc.SLA.FDVThresholdMs), fmt.Sprintf("%.4g", svc.SLA.FLRThresholdPct),
+		})
+	}
+	return t
+}
+
+// thresholdsTable lists the thresholds that are set
+func thresholdsTable(t config.ThresholdsConfig) report.Table {
+	tab := report.Table{Caption: "Thresholds", Header: []string{"Criterion", "Limit"}}
+	var sizes []uint32
+	for fs := range t.MinThroughputMbps {
+		sizes = append(sizes, fs)
+	}
+	sort.Slice(sizes, func(i, j int) bool { return sizes[i] < sizes[j] })
+	for _, fs := range sizes {
+		tab.Rows = append(tab.Rows, []string{fmt.Sprintf("Throughput, %d-byte frames", fs), fmt.Sprintf(">= %.2f Mbps", t.MinThroughputMbps[fs])})
+	}
+	if t.MinThroughputPct > 0 {
+		tab.Rows = append(tab.Rows, []string{"Throughput, each frame size", fmt.Sprintf(">= %.2f%% of line rate", t.MinThroughputPct)})
+	}
+	if t.MaxLatencyAvgUs > 0 {
+		tab.Rows = append(tab.Rows, []string{"Average latency", fmt.Sprintf("<= %.2f us", t.MaxLatencyAvgUs)})
+	}
+	if t.MaxLatencyP99Us > 0 {
+		tab.Rows = append(tab.Rows, []string{"P99 latency", fmt.Sprintf("<= %.2f us", t.MaxLatencyP99Us)})
+	}
+	if t.MaxFrameLossPct >= 0 {
+		tab.Rows = append(tab.Rows, []string{"Frame loss at the highest offered load", fmt.Sprintf("<= %.4g%%", t.MaxFrameLossPct)})
+	}
+	if t.Y1564MustPass {
+		tab.Rows = append(tab.Rows, []string{"Y.1564 services", "Every service meets its SLA"})
+	}
+	return tab
+}
+
+// htmlReport lays out a run for the HTML report: configuration first, then
+// pre-qualification, one section per test and the policy and compliance
+// notes
+func htmlReport(report jsonReport, cfg *config.Config) *htmlreport.Report {
+	r := &htmlreport.Report{
+		Title:  fmt.Sprintf("Test Report: %s", cfg.TestType),
+		Config: htmlConfig(report.Metadata, cfg),
+	}
+	for _, d := range report.Deviations {
+		r.Caveats = append(r.Caveats, fmt.Sprintf("%s section %s: %s, but %s (%s)",
+			d.Standard, d.Section, d.Recommendation, d.Configured, d.Setting))
+	}
+	if d := report.Metadata.Wiring; d != nil {
+		r.Figures = append(r.Figures, htmlreport.Figure{Title: "Wiring", Lines: d.Lines, Notes: d.Steps})
+	}
+	if dut := report.Metadata.DUT; dut != nil {
+		var parts []string
+		for _, s := range []string{dut.Name, dut.Vendor, dut.Model, dut.Firmware} {
+			if s != "" {
+				parts = append(parts, s)
+			}
+		}
+		r.Subtitle = strings.Join(parts, " / ")
+	}
+
+	if report.PreQual != nil {
+		r.Sections = append(r.Sections, htmlreport.PreQualSection(report.PreQual))
+	}
+	if report.DUTConfig != nil {
+		r.Sections = append(r.Sections, htmlreport.DUTConfigSection(report.DUTConfig))
+	}
+	if report.Thresholds != nil {
+		r.Sections = append(r.Sections, thresholdsSection(report.Thresholds))
+	}
+	sections := htmlreport.Sections(report.Results, cfg.TestType)
+	if report.Certification != nil {
+		r.Title = "Certification Report: RFC 2544"
+		sections = htmlreport.CertificationSections(report.Certification)
+	}
+	if len(sections) == 0 && len(report.Results) > 0 {
+		sections = append(sections, htmlreport.Section{
+			Title:  string(cfg.TestType),
+			Detail: "This test has no report layout; see -o json for its results.",
+		})
+	}
+	r.Sections = append(r.Sections, sections...)
+
+	for _, fr := range report.FlowFailures {
+		r.Sections = append(r.Sections, htmlreport.FlowFailuresSection(fr))
+	}
+	if p := report.SelfProfile; p != nil {
+		r.Sections = append(r.Sections, htmlreport.SelfProfileSection(p))
+	}
+	if len(report.Compliance) > 0 {
+		r.Sections = append(r.Sections, htmlreport.ComplianceSection(report.Compliance))
+	}
+	return r
+}
+
+// htmlConfig lists the settings a customer needs to read the results
+func htmlConfig(meta runMetadata, cfg *config.Config) []htmlreport.Field {
+	f := []htmlreport.Field{{Name: "Test", Value: string(cfg.TestType)}}
+	add := func(name, format string, args ...interface{}) {
+		f = append(f, htmlreport.Field{Name: name, Value: fmt.Sprintf(format, args...)})
+	}
+
+	switch {
+	case meta.TRex != nil:
+		add("Traffic generator", "TRex %s", cfg.TRex.Server)
+	case meta.Socket != nil:
+		add("Traffic generator", "UDP sockets to %s (max %d pps)", meta.Socket.Target, meta.Socket.MaxPPS)
+	case cfg.TestType == config.TestUDPEcho:
+		add("Reflector", "%s", cfg.UDPEcho.Target)
+	case cfg.Ports.Enabled():
+		add("Ports", "TX %s, RX %s", meta.Interface, cfg.Ports.RX)
+	default:
+		add("Interface", "%s", meta.Interface)
+	}
+	f = append(f, dutFields(meta.DUT)...)
+
+	var sizes []string
+	frameSizes, _ := testFrameSizes(cfg) // Checked before the run
+	for _, fs := range frameSizes {
+		sizes = append(sizes, fmt.Sprintf("%d", fs))
+	}
+	if len(sizes) > 0 && cfg.TestType != config.TestY1564Config && cfg.TestType != config.TestY1564Perf && cfg.TestType != config.TestY1564Full {
+		add("Frame sizes", "%s bytes", strings.Join(sizes, ", "))
+	}
+
+	switch {
+	case config.IsRFC2544Test(cfg.TestType):
+		add("Trial duration", "%v", cfg.TrialDuration)
+		add("Warmup", "%v", cfg.WarmupPeriod)
+		add("Acceptable loss", "%.4g%%", cfg.Throughput.AcceptableLoss)
+		if len(cfg.Throughput.AcceptableLossBySize) > 0 || len(cfg.Throughput.AcceptableLossByTest) > 0 {
+			add("Acceptable loss overrides", "per frame size or test; each throughput row shows the value used")
+		}
+		add("Throughput search", "from %.1f%%, resolution %.2f%%, at most %d iterations",
+			cfg.Throughput.InitialRatePct, cfg.Throughput.ResolutionPct, cfg.Throughput.MaxIterations)
+		if cfg.TestType == config.TestLatency || cfg.TestType == config.TestSuite || cfg.TestType == config.TestCharacterize {
+			var loads []string
+			for _, l := range cfg.Latency.LoadLevels {
+				loads = append(loads, fmt.Sprintf("%.0f%%", l))
+			}
+			add("Latency loads", "%s of throughput", strings.Join(loads, ", "))
+		}
+		if cfg.TestType == config.TestFrameLoss || cfg.TestType == config.TestSuite {
+			if fl := cfg.FrameLoss; fl.Search() {
+				add("Frame loss search", "%.0f%% to %.0f%% for loss <= %.4g%%, resolution %.2f%%", fl.StartPct, fl.EndPct, fl.SearchThresholdPct, fl.SearchResolutionPct)
+			} else if fl.LosslessTrials > 0 {
+				add("Frame loss steps", "%.0f%% down in %.0f%% steps until %d trials in a row lose nothing", fl.StartPct, fl.StepPct, fl.LosslessTrials)
+			} else {
+				add("Frame loss steps", "%.0f%% to %.0f%% in %.0f%% steps", fl.StartPct, fl.EndPct, fl.StepPct)
+			}
+		}
+	case cfg.TestType == config.TestSoak:
+		add("Soak", "%.1f%% for %v (%v samples, %v buckets)", cfg.Soak.RatePct, cfg.Soak.Duration, cfg.Soak.SampleDuration, cfg.Soak.BucketInterval)
+	case cfg.TestType == config.TestQoS:
+		add("QoS load", "%.1f%% of line rate for %v, marked by %s", cfg.QoS.LoadPct, cfg.TrialDuration, cfg.QoS.Marking)
+		for _, c := range cfg.QoS.ClassList() {
+			add("Class "+c.Name, "PCP %d, DSCP %d, %.2f%% of the load", c.PCP, c.DSCP, c.SharePct)
+		}
+	case cfg.TestType == config.TestAQM:
+		add("AQM ramp", "%.1f%% to %.1f%% in %.1f%% steps of %v", cfg.AQM.StartPct, cfg.AQM.EndPct, cfg.AQM.StepPct, cfg.AQM.StepDuration)
+		add("Loss floor", "%.4g%%", cfg.AQM.LossFloorPct)
+	case cfg.TestType == config.TestMicroburst:
+		m := cfg.Microburst
+		add("Microburst", "%d bursts per train at %.1f%%, %d to %d frames, gaps %v", m.Bursts, m.BurstRatePct, m.StartFrames, m.MaxFrames, m.Gaps)
+		if m.EgressMbps > 0 {
+			add("Egress rate", "%.1f Mbps", m.EgressMbps)
+		}
+	case cfg.TestType == config.TestSelfTest:
+		st := cfg.SelfTest
+		add("Self-test", "%.1f%% for %v per case, netem on %s", st.RatePct, st.Duration, st.Interface)
+		for _, c := range st.Suite() {
+			add("Case "+c.Name, "%s", c.Netem)
+		}
+		add("Tolerance", "latency +/- %.0fus, jitter +/- %.0fus, loss +/- %.3g%%", st.Tolerance.DelayUs, st.Tolerance.JitterUs, st.Tolerance.LossPct)
+	case cfg.TestType == config.TestUDPEcho:
+		add("Rate", "%d pps for %v", cfg.UDPEcho.RatePPS, cfg.UDPEcho.Duration)
+	case cfg.TestType == config.TestY1564Config || cfg.TestType == config.TestY1564Perf || cfg.TestType == config.TestY1564Full:
+		for _, svc := range cfg.Y1564.Services {
+			if svc.Enabled {
+				add(fmt.Sprintf("Service %d", svc.ServiceID), "%s: %d bytes, CIR %.2f Mbps, FD <= %.2f ms, FDV <= %.2f ms, FLR <= %.4g%%",
+					svc.ServiceName, svc.FrameSize, svc.SLA.CIRMbps, svc.SLA.FDThresholdMs, svc.SLA.FDVThresholdMs, svc.SLA.FLRThresholdPct)
+			}
+		}
+		if cfg.TestType != config.TestY1564Config {
+			add("Performance duration", "%v", cfg.Y1564.PerfDuration)
+		}
+	}
+
+	if meta.Framing != "" && !cfg.Framing.IsDefault() {
+		add("Framing", "%s", meta.Framing)
+	}
+	if m := meta.TxMarking; m != nil && (m.DSCP != 0 || m.IPv6TrafficClass != nil && *m.IPv6TrafficClass != 0) {
+		add("Marking", "%s", m)
+	}
+	if meta.Payload != "" {
+		add("Payload pattern", "%s", meta.Payload)
+	}
+	if meta.Packet != "" {
+		add("Packet template", "%s", meta.Packet)
+	}
+	if meta.AddressPairs > 1 {
+		add("Address pairs", "%d", meta.AddressPairs)
+	}
+	if meta.AddressPools != nil {
+		add("Address pools", "%s", meta.AddressPools)
+	}
+	if l := meta.Learning; l != nil {
+		add("Learning ramp", "%d address pairs at %d/s in %.1fs", l.Addresses, l.RatePerSec, l.DurationSec)
+	}
+	return f
+}
+
+// dutFields lists the DUT metadata that is set
+func dutFields(dut *config.DUTConfig) []htmlreport.Field {
+	if dut == nil {
+		return nil
+	}
+	var f []htmlreport.Field
+	for _, d := range []htmlreport.Field{{Name: "DUT", Value: dut.Name}, {Name: "Vendor", Value: dut.Vendor}, {Name: "Model", Value: dut.Model}, {Name: "Firmware", Value: dut.Firmware}} {
+		if d.Value != "" {
+			f = append(f, d)
+		}
+	}
+	return f
+}
+
+// thresholdsSection shows each threshold check, so the verdict leads with
+// what gated the run
+func thresholdsSection(r *threshold.Report) htmlreport.Section {
+	t := htmlreport.Table{Header: []string{"Frame size", "Criterion", "Measured", "Limit", "Result"}}
+	for _, c := range r.Checks {
+		size := "-"
+		if c.FrameSize > 0 {
+			size = fmt.Sprintf("%d", c.FrameSize)
+		}
+		t.Rows = append(t.Rows, []string{size, c.Criterion, c.Measured, c.Limit, passFailStr(c.Pass)})
+		t.Verdicts = append(t.Verdicts, htmlreport.Of(c.Pass))
+	}
+	s := htmlreport.Section{Title: "Thresholds", Verdict: htmlreport.Of(r.Passed), Tables: []htmlreport.Table{t}}
+	if len(r.NotMeasured) > 0 {
+		s.Detail = fmt.Sprintf("Not measured by this run: %s.", strings.Join(r.NotMeasured, ", "))
+	}
+	return s
+}
+
 // getTestTypeInt converts config.TestType to int
 func getTestTypeInt(t config.TestType) int {
 	switch t {
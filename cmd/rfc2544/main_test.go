@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"math"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/krisarmstrong/rfc2544-master/pkg/backend"
+	"github.com/krisarmstrong/rfc2544-master/pkg/checkpoint"
+	"github.com/krisarmstrong/rfc2544-master/pkg/config"
+	"github.com/krisarmstrong/rfc2544-master/pkg/dataplane"
+	"github.com/krisarmstrong/rfc2544-master/pkg/drift"
+)
+
+// Address pairs given only as flags get the same /8 check as a config file's
+func TestFlagAddressPairsValidated(t *testing.T) {
+	cmd := newTestCommand(testCommands[0])
+	args := []string{"-i", "lo", "--address-pairs", "300", "--src-ip", "10.255.255.1"}
+	if err := cmd.ParseFlags(args); err != nil {
+		t.Fatalf("ParseFlags() error = %v", err)
+	}
+	defer func() { iface, addressPairs, srcIP = "", 0, "" }()
+
+	cfg := config.DefaultConfig()
+	applyFlags(cmd, cfg)
+	if cfg.Addressing.Pairs != 300 || cfg.Headers.SrcIP != "10.255.255.1" {
+		t.Fatalf("flags not applied: pairs %d, src_ip %q", cfg.Addressing.Pairs, cfg.Headers.SrcIP)
+	}
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "out of its /8") {
+		t.Errorf("Validate() error = %v, want the pairs refused", err)
+	}
+}
+
+// A result JSON cannot encode leaves its frame size out of the state file
+// instead of ending the run
+func TestStateFrameUnencodable(t *testing.T) {
+	cp, err := checkpoint.New(filepath.Join(t.TempDir(), "state.json"), checkpoint.Run{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := &runState{cp}
+	var cancelled atomic.Bool
+
+	frame := &stateFrame{Frame: checkpoint.Frame{FrameSize: 64}}
+	frame.Result = frame.encode(&dataplane.ThroughputResultCLI{FrameSize: 64, MaxRatePct: math.NaN()})
+	state.complete(frame, &cancelled)
+	if frame.err == nil || state.frame(64) != nil {
+		t.Errorf("NaN result: err %v, saved %v", frame.err, state.frame(64) != nil)
+	}
+
+	frame = &stateFrame{Frame: checkpoint.Frame{FrameSize: 128}}
+	frame.Result = frame.encode(&dataplane.ThroughputResultCLI{FrameSize: 128, MaxRatePct: 99})
+	state.complete(frame, &cancelled)
+	if frame.err != nil || state.frame(128) == nil {
+		t.Errorf("result: err %v, saved %v", frame.err, state.frame(128) != nil)
+	}
+}
+
+// A reload applies only what it changed, and only what a soak accepts
+func TestSoakChange(t *testing.T) {
+	cur := config.DefaultConfig()
+	reload := func(edit func(c *config.Config)) *config.Config {
+		next := *cur
+		edit(&next)
+		return &next
+	}
+	tests := []struct {
+		name     string
+		next     *config.Config
+		wantRate float64
+		wantFS   uint32
+		wantOK   bool
+	}{
+		{"unchanged", reload(func(c *config.Config) { c.TrialDuration = time.Minute }), 80, 128, false},
+		{"rate", reload(func(c *config.Config) { c.Soak.RatePct = 50 }), 50, 128, true},
+		{"rate out of range", reload(func(c *config.Config) { c.Soak.RatePct = 150 }), 80, 128, false},
+		{"invalid frame size", reload(func(c *config.Config) { c.FrameSize = 32 }), 80, 128, false},
+		{"rate with invalid frame size", reload(func(c *config.Config) { c.Soak.RatePct, c.FrameSize = 60, 32 }), 60, 128, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The soak runs at 80% in 128-byte frames, not the config's own
+			c, ok := soakChange(cur, tt.next, "lo", 80, 128)
+			if c.RatePct != tt.wantRate || c.FrameSize != tt.wantFS || ok != tt.wantOK {
+				t.Errorf("soakChange() = %.1f%%, %d bytes, %t; want %.1f%%, %d bytes, %t",
+					c.RatePct, c.FrameSize, ok, tt.wantRate, tt.wantFS, tt.wantOK)
+			}
+		})
+	}
+}
+
+// soakBackend takes a soak's samples without a dataplane, calling sample
+// after each with the number taken
+type soakBackend struct {
+	backend.Backend
+	frameSize uint32
+	rates     []float64
+	sample    func(n int)
+}
+
+func (b *soakBackend) SetFrameSize(frameSize uint32) { b.frameSize = frameSize }
+
+func (b *soakBackend) RunFixedRateTrial(ctx context.Context, ratePct float64, duration time.Duration) (*dataplane.FixedRateResult, error) {
+	b.rates = append(b.rates, ratePct)
+	b.sample(len(b.rates))
+	return &dataplane.FixedRateResult{FrameSize: b.frameSize, OfferedPct: ratePct, FramesTx: 1000, FramesRx: 1000, DeliveredMbps: ratePct * 10}, nil
+}
+
+// Reloads and API rates retune a soak from its next sample
+func TestSoakRetune(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Interface, cfg.TestType, cfg.FrameSize = "lo", config.TestSoak, 64
+	cfg.Soak.Duration = time.Hour
+
+	daemon := cfg
+	rates := make(chan float64, 1)
+	runCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	b := &soakBackend{frameSize: 64}
+	b.sample = func(n int) {
+		switch n {
+		case 2:
+			next := *daemon
+			next.Soak.RatePct = 50
+			daemon = &next
+		case 3:
+			rates <- 70
+		case 4:
+			// Rejected, and the rate it leaves alone stays as the API set it
+			next := *daemon
+			next.FrameSize = 32
+			daemon = &next
+		case 5:
+			cancel()
+		}
+	}
+
+	retune := &soakRetune{base: cfg, live: func() *config.Config { return daemon }, rates: rates}
+	report, err := runSoakTest(runCtx, b, cfg, 64, retune)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []float64{90, 90, 50, 70, 70}; !reflect.DeepEqual(b.rates, want) {
+		t.Errorf("sample rates = %v, want %v", b.rates, want)
+	}
+	want := []drift.Change{{Sample: 2, RatePct: 50, FrameSize: 64}, {Sample: 3, RatePct: 70, FrameSize: 64}}
+	if len(report.Changes) != len(want) {
+		t.Fatalf("changes = %+v, want %+v", report.Changes, want)
+	}
+	for i, c := range report.Changes {
+		c.Time = time.Time{}
+		if c != want[i] {
+			t.Errorf("change %d = %+v, want %+v", i, c, want[i])
+		}
+	}
+	if title, want := soakHeatmapTitle(report), "Soak latency, 64-byte frames at 70.0% (retuned 2 times from 64-byte at 90.0%)"; title != want {
+		t.Errorf("heatmap title = %q, want %q", title, want)
+	}
+}
@@ -120,6 +120,10 @@
 #if !defined(__linux__) && !defined(ETH_P_IP)
 #define ETH_P_IP 0x0800
 #endif
+#if !defined(__linux__) && !defined(ETH_P_IPV6)
+#define ETH_P_IPV6 0x86DD
+#endif
+#define IPV6_HEADER_LEN 40
 
 /* ============================================================================
  * Performance Targets
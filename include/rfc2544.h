@@ -29,6 +29,25 @@ extern "C" {
 #define RFC2544_VERSION_MINOR 0
 #define RFC2544_VERSION_PATCH 0
 
+/* ABI version of the structs and calls the Go bindings mirror. Bumped
+ * whenever one of them changes; RFC2544_ABI_COMPAT is the oldest ABI
+ * whose callers still work with this library. */
+#define RFC2544_ABI_VERSION 4
+#define RFC2544_ABI_COMPAT 4
+
+/* Optional parts of the library, by rfc2544_version_t.features bit */
+#define RFC2544_FEATURE_AF_XDP (1u << 0)
+#define RFC2544_FEATURE_DPDK (1u << 1)
+
+/* What the dataplane can do, by rfc2544_capabilities_t.flags bit */
+#define RFC2544_CAP_HW_TIMESTAMP (1u << 0) /* The interface stamps frames in hardware */
+#define RFC2544_CAP_DPDK (1u << 1)         /* DPDK built in */
+#define RFC2544_CAP_AF_XDP (1u << 2)       /* AF_XDP built in */
+#define RFC2544_CAP_Y1564 (1u << 3)        /* Y.1564 service activation */
+#define RFC2544_CAP_RFC2889 (1u << 4)      /* RFC 2889 LAN switch tests */
+#define RFC2544_CAP_OAM (1u << 5)          /* Ethernet OAM discovery and loopback */
+#define RFC2544_CAP_RECOVERY (1u << 6)     /* System recovery and reset tests */
+
 /* Signature for custom RFC2544 packets - 7 bytes like ITO */
 #define RFC2544_SIGNATURE "RFC2544"
 #define RFC2544_SIG_LEN 7
@@ -59,6 +78,15 @@ typedef enum {
  * Note: True 64-byte frame testing would require a compact payload format.
  */
 #define RFC2544_MIN_FRAME_SIZE 66
+#define RFC2544_MAX_FRAME_SIZE 16000 /* Largest super-jumbo frame AF_PACKET sends */
+
+/*
+ * Functions return 0 (or a count) on success and a negative errno value on
+ * failure. Failures with no fitting errno return one of these codes,
+ * negated; they lie above the errno range.
+ */
+#define RFC2544_EDPDKINIT 4097 /* DPDK EAL or port initialization failed */
+#define RFC2544_ENOHWTS 4098   /* Hardware timestamping not supported by the NIC */
 
 /* Standard frame sizes array - starts at 128 for full payload support */
 #define RFC2544_FRAME_SIZES                                                                        \
@@ -110,6 +138,15 @@ typedef struct {
 	double p99_ns;    /* 99th percentile */
 } latency_stats_t;
 
+/* Frame order of a trial, from the sequence numbers received, and the
+ * frames whose payload failed verification */
+typedef struct {
+	uint64_t reordered;          /* Frames received after a later-sent frame */
+	uint64_t duplicates;         /* Extra copies of frames already received */
+	uint32_t max_reorder_depth;  /* Most frames a reordered frame arrived behind */
+	uint64_t corrupted;          /* Received with a payload failing its CRC-32 */
+} frame_order_t;
+
 /* Frame loss result for a single load level */
 typedef struct {
 	double offered_rate_pct; /* Offered load as % of line rate */
@@ -117,6 +154,7 @@ typedef struct {
 	uint64_t frames_sent;    /* Frames transmitted */
 	uint64_t frames_recv;    /* Frames received */
 	double loss_pct;         /* Frame loss percentage */
+	frame_order_t order;     /* Reordered and duplicate frames */
 } frame_loss_point_t;
 
 /* Throughput test result for a single frame size */
@@ -130,6 +168,28 @@ typedef struct {
 	latency_stats_t latency; /* Latency at max throughput */
 } throughput_result_t;
 
+/* One throughput binary search iteration */
+typedef struct {
+	double rate_pct;         /* Offered rate as % of line rate */
+	uint64_t frames_tx;      /* Frames transmitted */
+	uint64_t frames_rx;      /* Frames received */
+	double loss_pct;         /* Frame loss percentage */
+	bool passed;             /* Loss within acceptable_loss */
+	frame_order_t order;     /* Reordered and duplicate frames */
+	latency_stats_t latency; /* Latency of the trial's frames */
+} throughput_trial_t;
+
+/* Throughput binary search state, saved between iterations to resume a run */
+typedef struct {
+	double low_pct;          /* Highest rate that passed (search floor) */
+	double high_pct;         /* Lowest rate that failed (search ceiling) */
+	double best_rate_pct;    /* Best passing rate so far */
+	uint32_t iterations;     /* Iterations completed */
+	uint64_t frames_tested;  /* Total frames transmitted */
+	latency_stats_t latency; /* Latency at best rate */
+	throughput_trial_t last; /* Trial of the last iteration */
+} throughput_search_t;
+
 /* Latency test result for a single load level */
 typedef struct {
 	uint32_t frame_size;     /* Frame size tested */
@@ -165,6 +225,51 @@ typedef struct {
 	bool manual_reset;          /* True if reset was triggered manually */
 } reset_result_t;
 
+/* Single trial at a fixed offered load (soak sampling) */
+typedef struct {
+	uint32_t frame_size;        /* Frame size tested */
+	double offered_rate_pct;    /* Offered load as % of line rate */
+	uint64_t frames_sent;       /* Frames transmitted */
+	uint64_t frames_recv;       /* Frames received */
+	double loss_pct;            /* Frame loss percentage */
+	double delivered_mbps;      /* Received throughput in Mbps */
+	double elapsed_sec;         /* Measured trial duration */
+	latency_stats_t latency;    /* Latency statistics */
+	frame_order_t order;        /* Reordered and duplicate frames */
+} fixed_rate_result_t;
+
+/* Most bursts a burst train reports one by one */
+#define RFC2544_MAX_TRAIN_BURSTS 1024
+
+/* Train of equal bursts separated by idle gaps (microburst test) */
+typedef struct {
+	uint32_t burst_frames;      /* Frames per burst */
+	uint32_t bursts;            /* Bursts in the train, at most RFC2544_MAX_TRAIN_BURSTS */
+	uint64_t gap_ns;            /* Idle time from a burst's last frame to the next's first */
+	double rate_pct;            /* Rate within a burst as % of line rate (0 = 100) */
+} burst_train_t;
+
+/* One burst of a train */
+typedef struct {
+	uint64_t frames_sent;       /* Frames of the burst, counting any the port refused */
+	uint64_t frames_recv;       /* Frames of the burst received */
+	uint64_t max_latency_ns;    /* Latency of its slowest frame */
+	uint64_t first_rx_ns;       /* Receive time of the first of its frames returned */
+	uint64_t last_rx_ns;        /* Receive time of the last */
+} burst_count_t;
+
+/* Burst train result */
+typedef struct {
+	uint32_t frame_size;        /* Frame size tested */
+	uint64_t frames_sent;       /* Frames of all bursts */
+	uint64_t frames_recv;       /* Frames received */
+	double loss_pct;            /* Frame loss percentage */
+	double elapsed_sec;         /* First frame sent to the last burst's end */
+	latency_stats_t latency;    /* Latency statistics over the train */
+	uint32_t burst_count;       /* Bursts in bursts */
+	burst_count_t bursts[RFC2544_MAX_TRAIN_BURSTS];
+} burst_train_result_t;
+
 /* ============================================================================
  * ITU-T Y.1564 (EtherSAM) Types
  * ============================================================================
@@ -182,6 +287,21 @@ typedef struct {
 #define Y1564_SIG_LEN 7
 #define Y1564_MAX_SERVICES 8
 #define Y1564_CONFIG_STEPS 4
+#define Y1564_MAX_STEPS (Y1564_CONFIG_STEPS + 2) /* CIR steps, EIR step, policing step */
+
+/* Y.1564 Configuration Test step phases */
+typedef enum {
+	Y1564_STEP_CIR = 0,      /* At or below CIR */
+	Y1564_STEP_EIR = 1,      /* CIR + EIR */
+	Y1564_STEP_POLICING = 2, /* 125% of CIR + EIR, checks the excess is policed */
+} y1564_step_phase_t;
+
+/* Y.1564 Service Acceptance Criteria for one step (negative = not checked) */
+typedef struct {
+	double fd_threshold_ms;   /* Frame Delay threshold (milliseconds) */
+	double fdv_threshold_ms;  /* Frame Delay Variation threshold (ms) */
+	double flr_threshold_pct; /* Frame Loss Ratio threshold (%) */
+} y1564_sac_t;
 
 /* Y.1564 Service SLA Configuration */
 typedef struct {
@@ -192,6 +312,8 @@ typedef struct {
 	double fd_threshold_ms;   /* Frame Delay threshold (milliseconds) */
 	double fdv_threshold_ms;  /* Frame Delay Variation threshold (ms) */
 	double flr_threshold_pct; /* Frame Loss Ratio threshold (%) */
+	y1564_sac_t eir_sac;      /* Criteria for the EIR step (default: FD/FDV, loss not checked) */
+	y1564_sac_t policing_sac; /* Criteria for the policing step (default: as eir_sac) */
 } y1564_sla_t;
 
 /* Y.1564 Service Configuration */
@@ -202,12 +324,20 @@ typedef struct {
 	uint32_t frame_size;      /* Test frame size */
 	uint8_t cos;              /* Class of Service (DSCP value) */
 	bool enabled;             /* Service enabled for test */
+	bool policing_step;       /* Add the traffic policing step to the Config test */
+	uint16_t vlan_id;         /* 802.1Q VLAN ID (0 and pcp 0 = the context's tag) */
+	uint8_t pcp;              /* 802.1p priority of the tag */
+	uint16_t s_vlan_id;       /* Outer S-tag VLAN ID (0 and s_pcp 0 = the context's S-tag) */
+	uint8_t s_pcp;            /* 802.1p priority of the S-tag */
+	uint8_t ip_version;       /* 4 or 6 (0 = IPv6 with an IPv6-only context, else IPv4) */
+	double extra_mbps;        /* Load taken over from failed services, offered on top of
+	                             every step; the SLA and step percentages stay on cir_mbps */
 } y1564_service_t;
 
 /* Y.1564 Step Result (one step of Config test) */
 typedef struct {
-	uint32_t step;             /* Step number (1-4) */
-	double offered_rate_pct;   /* % of CIR (25, 50, 75, 100) */
+	uint32_t step;             /* Step number (1-6) */
+	double offered_rate_pct;   /* % of CIR (25, 50, 75, 100, then EIR/policing) */
 	double achieved_rate_mbps; /* Actual rate achieved */
 	uint64_t frames_tx;        /* Frames transmitted */
 	uint64_t frames_rx;        /* Frames received */
@@ -220,13 +350,15 @@ typedef struct {
 	bool fd_pass;              /* FD within threshold */
 	bool fdv_pass;             /* FDV within threshold */
 	bool step_pass;            /* Overall step pass/fail */
+	y1564_step_phase_t phase;  /* CIR, EIR or policing step */
 } y1564_step_result_t;
 
 /* Y.1564 Service Configuration Test Result */
 typedef struct {
 	uint32_t service_id;                        /* Service ID */
 	char service_name[32];                      /* Service name */
-	y1564_step_result_t steps[Y1564_CONFIG_STEPS]; /* 25%, 50%, 75%, 100% */
+	y1564_step_result_t steps[Y1564_MAX_STEPS]; /* CIR steps, then EIR and policing */
+	uint32_t step_count;                        /* Steps run */
 	bool service_pass;                          /* All steps passed */
 } y1564_config_result_t;
 
@@ -373,6 +505,255 @@ typedef struct {
 	uint8_t hop_limit;       /* Hop limit (TTL equivalent) */
 } ipv6_config_t;
 
+#define IPV6_MAX_FLOW_LABEL 0xFFFFF
+
+/* IP version of the built-in test frames. Dual-stack interleaves IPv4
+ * and IPv6 frames in one paced stream, splitting the offered load. */
+typedef struct {
+	ip_mode_t mode;
+	ipv6_config_t ipv6;      /* Addresses and marking of the IPv6 frames */
+	uint8_t v6_share_pct;    /* Dual-stack: share of the frames sent as IPv6 (0 = 50) */
+} ip_stack_config_t;
+
+/* One address family's frames over a trial's measurement */
+typedef struct {
+	uint64_t frames_sent;
+	uint64_t frames_recv;
+	double loss_pct;
+	latency_stats_t latency; /* Over the family's latency samples */
+} ip_family_stats_t;
+
+/* ============================================================================
+ * RFC 2544 Section 11/12 Modifier Types
+ * ============================================================================
+ */
+
+/* Largest broadcast share: at least half the frames stay measured unicast */
+#define MAX_BROADCAST_PCT 50.0
+
+/* Test modifiers (Section 11) */
+typedef struct {
+	double broadcast_pct;    /* Share of test frames sent to ff:ff:ff:ff:ff:ff (0 = off) */
+} modifier_config_t;
+
+/* Maximum distinct address pairs (Section 12) */
+#define MAX_ADDRESS_PAIRS 65536
+
+/* Addresses of one flow drawn from the configured pools; an all-zero
+ * address keeps the built-in one */
+typedef struct {
+	uint8_t src_mac[6];
+	uint8_t dst_mac[6];
+	uint32_t src_ip;         /* Network byte order */
+	uint32_t dst_ip;         /* Network byte order */
+} address_pair_t;
+
+/* Address pair rotation (Section 12) */
+typedef struct {
+	uint32_t pair_count;     /* Distinct src/dst pairs rotated round-robin (0/1 = single pair) */
+	const address_pair_t *pairs; /* pair_count pairs to rotate through, copied; NULL = derived */
+} address_config_t;
+
+/* Addresses, UDP ports and marking of built-in test frames in place of the
+ * built-in 02:00:00:00:00:01 -> :02, 10.0.0.1 -> 10.0.0.2, 12345 -> 3842.
+ * A field left zero keeps the built-in value. A count over 1 makes it a
+ * range, frame n taking the first value + n % count; address pairs and
+ * streams rotate from the first value. */
+typedef struct {
+	uint8_t src_mac[6];
+	uint8_t dst_mac[6];      /* The DUT's MAC, or the next hop's through a router */
+	uint32_t src_ip;         /* IPv4, network byte order */
+	uint32_t dst_ip;
+	uint32_t src_ip_count;   /* Consecutive addresses (0/1 = single) */
+	uint32_t dst_ip_count;
+	uint16_t src_port;       /* UDP, host byte order */
+	uint16_t dst_port;
+	uint16_t src_port_count; /* Consecutive ports (0/1 = single) */
+	uint16_t dst_port_count;
+	uint8_t dscp;            /* DSCP of the IPv4 header (0-63); Y.1564 frames mark their CoS */
+} header_fields_t;
+
+/* Content of the padding after the payload of generated frames. The TX
+ * frame's padding is filled once, so every frame of a trial carries the
+ * same bytes; the pattern restarts at the first padding byte. */
+typedef enum {
+	PAYLOAD_INCREMENTING = 0, /* 00 01 02 .. ff 00 .. (the built-in fill) */
+	PAYLOAD_ZEROS = 1,
+	PAYLOAD_ONES = 2,         /* All ff */
+	PAYLOAD_RANDOM = 3,       /* Pseudo-random (SplitMix64) from seed, repeatable */
+	PAYLOAD_PRBS31 = 4,       /* PRBS-31 (x^31 + x^28 + 1) from all ones, MSB first */
+	PAYLOAD_CUSTOM = 5        /* bytes[0..len) repeated */
+} payload_pattern_kind_t;
+
+#define MAX_PAYLOAD_PATTERN 256
+
+typedef struct {
+	payload_pattern_kind_t kind;
+	uint64_t seed;                      /* PAYLOAD_RANDOM */
+	uint32_t len;                       /* PAYLOAD_CUSTOM: 1-MAX_PAYLOAD_PATTERN */
+	uint8_t bytes[MAX_PAYLOAD_PATTERN];
+} payload_pattern_t;
+
+/* Maximum flows of multi-stream traffic */
+#define MAX_STREAMS 1024
+
+/* Statistics of one flow of a multi-stream trial. Latency is over the
+ * stream's share of the trial's latency samples; corrupted frames, whose
+ * stream ID cannot be trusted, are received by no stream. */
+typedef struct {
+	uint64_t frames_sent;    /* Frames transmitted */
+	uint64_t frames_recv;    /* Frames received */
+	double loss_pct;         /* Frame loss percentage */
+	latency_stats_t latency; /* Round-trip latency with measure_latency */
+} stream_stats_t;
+
+/* Learning ramp that introduced the address pairs before the first trial */
+typedef struct {
+	uint32_t addresses;      /* Pairs introduced, one frame each */
+	uint32_t rate;           /* Pairs introduced per second (0 = not run yet) */
+	double duration_sec;     /* Time the ramp took */
+} address_learn_stats_t;
+
+/* Generated frame encapsulation */
+typedef enum {
+	FRAMING_ETHERNET_II = 0, /* DIX header carrying the EtherType below */
+	FRAMING_LLC_SNAP = 1     /* 802.3 length + LLC (AA-AA-03) + SNAP OUI 00-00-00 */
+} framing_mode_t;
+
+#define LLC_SNAP_HEADER_LEN 8
+#define MAX_8023_LENGTH 1500
+#define VLAN_TAG_LEN 4
+#define VLAN_MAX_ID 4094
+#define TPID_8021Q 0x8100  /* Customer tag */
+#define TPID_8021AD 0x88A8 /* Service tag */
+
+/* 802.1ad outer service tag (S-tag) stacked over the 802.1Q tag (QinQ) */
+typedef struct {
+	uint16_t s_tpid;    /* Outer TPID (0 = TPID_8021AD; 0x9100-0x9300 for pre-802.1ad bridges) */
+	uint16_t s_vlan_id; /* 0-4094; s_vlan_id and s_pcp 0 = no outer tag */
+	uint8_t s_pcp;      /* 802.1p priority of the outer tag */
+	uint16_t c_tpid;    /* TPID of the inner tag (0 = TPID_8021Q) */
+} qinq_config_t;
+
+#define MPLS_LABEL_LEN 4
+#define MAX_MPLS_LABELS 8
+#define MPLS_MAX_LABEL 0xFFFFF
+#define ETHERTYPE_MPLS 0x8847 /* MPLS unicast */
+#define ETHERTYPE_MPLS_MC 0x8848
+
+/* One MPLS label stack entry */
+typedef struct {
+	uint32_t label; /* 20-bit label (16-1048575; 0-15 are reserved) */
+	uint8_t exp;    /* Traffic class (EXP) bits, 0-7 */
+	uint8_t ttl;    /* 0 = 64 */
+} mpls_label_t;
+
+/* MPLS label stack pushed between the L2 header and IPv4 */
+typedef struct {
+	mpls_label_t labels[MAX_MPLS_LABELS]; /* Outermost first */
+	uint32_t count;                       /* 0 = unlabeled */
+	bool exp_from_cos;                    /* Y.1564: EXP of every label from the service CoS */
+} mpls_config_t;
+
+/* Frame encapsulation options */
+typedef struct {
+	framing_mode_t mode;
+	uint16_t ethertype;      /* EtherType or SNAP protocol ID (0 = IPv4) */
+} framing_config_t;
+
+/* Largest header a packet template may define */
+#define MAX_TEMPLATE_HEADER 128
+
+#define TEMPLATE_FILL_SRC_MAC 0x01 /* Write the interface MAC as source */
+#define TEMPLATE_FILL_DST_MAC 0x02 /* Write the DUT MAC as destination */
+
+/* User-defined test frame headers, compiled from the packet template
+ * language. Lengths and checksums are filled in per frame size. */
+typedef struct {
+	uint8_t header[MAX_TEMPLATE_HEADER]; /* Headers preceding the RFC 2544 payload */
+	uint16_t header_len;     /* Header bytes (0 = built-in Ethernet/IPv4/UDP) */
+	int16_t ipv4_offset;     /* Offset of the IPv4 header, -1 if none */
+	int16_t ipv6_offset;     /* Offset of the IPv6 header, -1 if none */
+	int16_t udp_offset;      /* Offset of the UDP header, ending the header */
+	uint8_t flags;           /* TEMPLATE_FILL_* */
+} header_template_t;
+
+/* Control-plane frame kinds aimed at the DUT's own addresses */
+typedef enum {
+	CPP_FRAME_NONE = 0,
+	CPP_FRAME_ICMP = 1,      /* ICMP echo request */
+	CPP_FRAME_ARP = 2,       /* ARP request for the DUT address */
+	CPP_FRAME_BGP = 3        /* TCP SYN to port 179 */
+} cpp_frame_t;
+
+#define CPP_BGP_PORT 179
+#define CPP_FRAME_LEN 60
+
+/* Control-plane policing stress, sent alongside test traffic */
+typedef struct {
+	uint32_t dut_ip;         /* DUT address (network order) */
+	uint8_t dut_mac[6];      /* DUT MAC (all zero = test destination MAC) */
+	uint32_t icmp_pps;       /* ICMP echo requests per second (0 = off) */
+	uint32_t arp_pps;        /* ARP requests per second (0 = off) */
+	uint32_t bgp_pps;        /* TCP SYNs to port 179 per second (0 = off) */
+} cpp_config_t;
+
+/* Control-plane frames sent and answered since the last configure */
+typedef struct {
+	uint64_t icmp_sent;
+	uint64_t icmp_replies;
+	uint64_t arp_sent;
+	uint64_t arp_replies;
+	uint64_t bgp_sent;
+	uint64_t bgp_replies;    /* SYN-ACK or RST */
+} cpp_stats_t;
+
+/* Stream ID of latency probe frames, beyond any multi-stream flow */
+#define RFC2544_PROBE_STREAM 0xFFFFFFFFu
+
+/* Low-rate latency probe sent alongside the load of latency trials, at a
+ * priority of its own, as voice rides a congested link */
+typedef struct {
+	uint32_t pps;            /* Probe frames per second (0 = off) */
+	uint8_t pcp;             /* 802.1p priority written into its VLAN tags */
+	uint8_t dscp;            /* DSCP of its IPv4 header (0-63) */
+} latency_probe_config_t;
+
+/* The probe's frames over the last latency trial's measurement */
+typedef struct {
+	uint64_t frames_sent;
+	uint64_t frames_recv;
+	double loss_pct;
+	latency_stats_t latency; /* Of the probe alone; the trial's is the load's */
+} latency_probe_stats_t;
+
+/* Live counters, updated while trials run */
+typedef struct {
+	uint32_t frame_size;          /* Frame size of the current trial */
+	double offered_rate_pct;      /* Offered load of the current trial */
+	uint64_t tx_packets;          /* Test frames sent since init (incl. warmup) */
+	uint64_t tx_bytes;
+	uint64_t rx_packets;          /* Test frames received since init */
+	uint64_t rx_bytes;
+	uint64_t trials;              /* Completed trials */
+	double last_loss_pct;         /* Frame loss of the last completed trial */
+	latency_stats_t last_latency; /* Latency of the last completed trial */
+	frame_order_t last_order;     /* Frame order of the last completed trial */
+	bool in_trial;                /* A trial is running */
+	uint32_t trial_warmup_sec;    /* Warmup of the current trial */
+	uint32_t trial_duration_sec;  /* Measurement of the current trial, after the warmup */
+	uint64_t trial_elapsed_ns;    /* Time into the current trial, warmup included */
+} live_stats_t;
+
+/* Frame counters of one opened port */
+typedef struct {
+	char interface[64];
+	uint64_t tx_packets;          /* Frames sent, all traffic */
+	uint64_t rx_packets;          /* Frames received, all traffic */
+	uint64_t tx_errors;
+	uint64_t rx_errors;
+} port_stats_t;
+
 /* ============================================================================
  * Y.1564 Color-Aware Metering Types
  * ============================================================================
@@ -409,6 +790,7 @@ typedef struct {
 typedef struct {
 	/* Interface */
 	char interface[64];   /* Network interface name */
+	char rx_interface[64]; /* Receive port through the DUT (empty = interface) */
 	uint64_t line_rate;   /* Line rate in bits/sec (e.g., 10e9 for 10G) */
 	bool auto_detect_nic; /* Auto-detect NIC capabilities */
 
@@ -484,10 +866,45 @@ typedef struct rfc2544_ctx rfc2544_ctx_t;
 /* Test progress callback */
 typedef void (*progress_callback_t)(const rfc2544_ctx_t *ctx, const char *message, double pct);
 
+/* Version of the library in use, which may differ from the header a caller
+ * was built against */
+typedef struct {
+	uint32_t major;
+	uint32_t minor;
+	uint32_t patch;
+	uint32_t abi;        /* RFC2544_ABI_VERSION */
+	uint32_t abi_compat; /* RFC2544_ABI_COMPAT */
+	uint32_t features;   /* RFC2544_FEATURE_* built in */
+} rfc2544_version_t;
+
+/* What the dataplane can do on an interface */
+typedef struct {
+	uint32_t flags;          /* RFC2544_CAP_* */
+	uint32_t max_streams;    /* Flows a trial can generate */
+	uint32_t max_frame_size; /* Largest frame, FCS included, the platform and port carry */
+} rfc2544_capabilities_t;
+
 /* ============================================================================
  * Core API
  * ============================================================================ */
 
+/**
+ * Get the version and ABI of the library, for callers to refuse one
+ * older than they were built for. Needs no context.
+ * @param version Filled with the library's version
+ */
+void rfc2544_get_version(rfc2544_version_t *version);
+
+/**
+ * Get what the dataplane can do on an interface, for callers to offer only
+ * the tests and settings that will run. Needs no context.
+ * @param interface Interface to probe, or NULL for the library alone
+ * @param use_dpdk Report for the DPDK platform rather than the default one
+ * @param caps Filled with the capabilities
+ * @return 0 on success, -ENOENT if the interface does not exist
+ */
+int rfc2544_get_capabilities(const char *interface, bool use_dpdk, rfc2544_capabilities_t *caps);
+
 /**
  * Initialize RFC2544 test context
  * @param ctx Pointer to context to initialize
@@ -511,6 +928,14 @@ int rfc2544_configure(rfc2544_ctx_t *ctx, const rfc2544_config_t *config);
  */
 void rfc2544_set_progress_callback(rfc2544_ctx_t *ctx, progress_callback_t callback);
 
+/**
+ * Set the frame loss a throughput trial may show and still pass
+ * @param ctx Test context
+ * @param loss_pct Acceptable loss (0-100%)
+ * @return 0 on success, negative on error
+ */
+int rfc2544_set_acceptable_loss(rfc2544_ctx_t *ctx, double loss_pct);
+
 /**
  * Run configured test
  * @param ctx Test context
@@ -524,6 +949,12 @@ int rfc2544_run(rfc2544_ctx_t *ctx);
  */
 void rfc2544_cancel(rfc2544_ctx_t *ctx);
 
+/**
+ * Clear a cancellation, so the next test called directly runs
+ * @param ctx Test context
+ */
+void rfc2544_clear_cancel(rfc2544_ctx_t *ctx);
+
 /**
  * Get current test state
  * @param ctx Test context
@@ -531,6 +962,94 @@ void rfc2544_cancel(rfc2544_ctx_t *ctx);
  */
 test_state_t rfc2544_get_state(const rfc2544_ctx_t *ctx);
 
+/**
+ * Get live counters. Safe to call from another thread while a test runs.
+ * @param ctx Test context
+ * @param stats Output snapshot
+ * @return 0 on success, negative on error
+ */
+int rfc2544_get_live_stats(rfc2544_ctx_t *ctx, live_stats_t *stats);
+
+/**
+ * Get the heartbeat counter, which the trial loop bumps as it runs. Safe
+ * to call from another thread; a counter that stops advancing while a
+ * test runs means the dataplane is wedged.
+ * @param ctx Test context
+ * @return Heartbeat count, 0 without a context
+ */
+uint64_t rfc2544_get_heartbeat(const rfc2544_ctx_t *ctx);
+
+/**
+ * Get the frame counters of each opened port: the TX port first, then
+ * the RX port when rx_interface is set
+ * @param ctx Test context
+ * @param stats Array to populate (caller allocates)
+ * @param max_count Maximum ports to return
+ * @return Number of ports, negative on error
+ */
+int rfc2544_get_port_stats(rfc2544_ctx_t *ctx, port_stats_t *stats, uint32_t max_count);
+
+/**
+ * Get the latency samples of the last completed trial, in nanoseconds and
+ * in arrival order, for histograms and percentiles beyond latency_stats_t.
+ * A trial keeps at most DEFAULT_LATENCY_SAMPLES, or those of
+ * rfc2544_latency_samples_configure; none without latency measurement.
+ * @param ctx Test context
+ * @param samples Array to populate (caller allocates)
+ * @param max_count Maximum samples to return
+ * @return Number of samples, negative on error
+ */
+int rfc2544_get_latency_samples(rfc2544_ctx_t *ctx, uint64_t *samples, uint32_t max_count);
+
+#define DEFAULT_LATENCY_SAMPLES 10000
+#define MAX_LATENCY_SAMPLES 10000000
+
+/**
+ * Set the latency samples each subsequent trial keeps for its statistics
+ * and rfc2544_get_latency_samples. The sample arrays are allocated by the
+ * first trial needing them and reused by later trials.
+ * @param ctx Test context
+ * @param capacity Samples per trial (0 = DEFAULT_LATENCY_SAMPLES)
+ * @return 0 on success, -EINVAL over MAX_LATENCY_SAMPLES, -ENOMEM
+ */
+int rfc2544_latency_samples_configure(rfc2544_ctx_t *ctx, uint32_t capacity);
+
+/* Frames saved to each capture file without a limit */
+#define DEFAULT_CAPTURE_LIMIT 100000
+
+/* Capture of the frames trials send and receive, as pcap files with
+ * nanosecond timestamps, for post-mortem of frame loss */
+typedef struct {
+	char tx_path[256]; /* Frames sent; "" captures none */
+	char rx_path[256]; /* Frames received on the RX port, test frames or not */
+	uint32_t limit;    /* Frames saved to each file (0 = DEFAULT_CAPTURE_LIMIT) */
+	uint32_t snaplen;  /* Bytes kept of each frame, e.g. 64 for the headers (0 = whole frames) */
+} capture_config_t;
+
+typedef struct {
+	uint64_t tx_frames; /* Saved to each file */
+	uint64_t rx_frames;
+} capture_stats_t;
+
+/**
+ * Capture the frames of subsequent trials and Y.1564 steps, until each file
+ * holds its limit. The files are created, replacing existing ones, and
+ * closed on reconfiguration or rfc2544_cleanup.
+ * @param ctx Test context
+ * @param config Files and limits; NULL or no paths stops capturing
+ * @return 0 on success, -EINVAL for a snaplen over 262144, or the
+ *         negative errno of a file that cannot be created
+ */
+int rfc2544_capture_configure(rfc2544_ctx_t *ctx, const capture_config_t *config);
+
+/**
+ * Get the frames saved by the capture, or by the last one once closed
+ * @param ctx Test context
+ * @param stats Output statistics
+ * @return 0 on success, negative on error
+ */
+int rfc2544_capture_get_stats(const rfc2544_ctx_t *ctx, capture_stats_t *stats);
+
 /**
  * Clean up and free context
  * @param ctx Test context
@@ -553,6 +1072,35 @@ void rfc2544_cleanup(rfc2544_ctx_t *ctx);
 int rfc2544_throughput_test(rfc2544_ctx_t *ctx, uint32_t frame_size, throughput_result_t *result,
                             uint32_t *result_count);
 
+/**
+ * Start a throughput binary search at the configured initial rate
+ * @param ctx Test context
+ * @param search Search state (caller allocates)
+ */
+void rfc2544_throughput_search_init(rfc2544_ctx_t *ctx, throughput_search_t *search);
+
+/**
+ * Run one throughput binary search iteration, updating the search state
+ * @param ctx Test context
+ * @param frame_size Frame size to test
+ * @param search Search state from rfc2544_throughput_search_init or a
+ *               previous step
+ * @return 0 after a trial, 1 if the search is complete, negative on error
+ */
+int rfc2544_throughput_search_step(rfc2544_ctx_t *ctx, uint32_t frame_size,
+                                   throughput_search_t *search);
+
+/**
+ * Fill a throughput result from a completed search
+ * @param ctx Test context
+ * @param frame_size Frame size tested
+ * @param search Completed search state
+ * @param result Result structure (caller allocates)
+ */
+void rfc2544_throughput_search_result(rfc2544_ctx_t *ctx, uint32_t frame_size,
+                                      const throughput_search_t *search,
+                                      throughput_result_t *result);
+
 /**
  * Run latency test (Section 26.2)
  * Measure round-trip latency at specified load levels
@@ -565,6 +1113,32 @@ int rfc2544_throughput_test(rfc2544_ctx_t *ctx, uint32_t frame_size, throughput_
 int rfc2544_latency_test(rfc2544_ctx_t *ctx, uint32_t frame_size, double load_pct,
                          latency_result_t *result);
 
+/**
+ * Run one trial at a fixed offered load with latency measurement
+ * Used to sample long-running (soak) tests
+ * @param ctx Test context
+ * @param frame_size Frame size to test
+ * @param rate_pct Offered load as % of line rate
+ * @param duration_sec Trial duration in seconds (no warmup)
+ * @param result Result structure (caller allocates)
+ * @return 0 on success, negative on error
+ */
+int rfc2544_fixed_rate_trial(rfc2544_ctx_t *ctx, uint32_t frame_size, double rate_pct,
+                             uint32_t duration_sec, fixed_rate_result_t *result);
+
+/**
+ * Send a train of bursts with idle gaps between them, counting each
+ * burst's returned frames apart by their sequence numbers
+ * Used to map the DUT's egress buffer (microburst test)
+ * @param ctx Test context
+ * @param frame_size Frame size to test
+ * @param train Burst size, count, gap and rate within a burst
+ * @param result Result structure (caller allocates)
+ * @return 0 on success, negative on error
+ */
+int rfc2544_burst_train(rfc2544_ctx_t *ctx, uint32_t frame_size, const burst_train_t *train,
+                        burst_train_result_t *result);
+
 /**
  * Run frame loss test (Section 26.3)
  * Measure frame loss at various offered loads
@@ -760,6 +1334,315 @@ int rfc2544_ipv6_configure(rfc2544_ctx_t *ctx, const ipv6_config_t *config);
  */
 int rfc2544_parse_ipv6(const char *str, uint8_t addr[16]);
 
+/**
+ * Select the IP version of built-in test frames: IPv4, IPv6 from the
+ * given header fields, or dual-stack. rfc2544_ipv6_configure selects
+ * IPv6-only.
+ * @param ctx Test context
+ * @param config IP version, IPv6 header fields and dual-stack share
+ * @return 0 on success, -EINVAL on an unknown mode, share or flow label
+ */
+int rfc2544_ip_configure(rfc2544_ctx_t *ctx, const ip_stack_config_t *config);
+
+/**
+ * Get each address family's frames in the last trial
+ * @param ctx Test context
+ * @param v4 Output IPv4 frames
+ * @param v6 Output IPv6 frames
+ * @return 0 on success, negative on error
+ */
+int rfc2544_ip_family_get_stats(const rfc2544_ctx_t *ctx, ip_family_stats_t *v4,
+                                ip_family_stats_t *v6);
+
+/**
+ * Rewrite a built-in Ethernet/IPv4/UDP frame as Ethernet/IPv6/UDP
+ *
+ * The frame keeps its size: the IPv6 header is 20 bytes longer, so UDP
+ * and payload move back and the padding loses its last 20 bytes. Call
+ * before framing, labels and tags.
+ * @param buffer Packet buffer (from create_packet_template)
+ * @param frame_size Frame size in bytes (including FCS)
+ * @param config IPv6 header fields; hop_limit 0 = 64
+ * @return Bytes the payload moved by, or negative on error
+ */
+int rfc2544_convert_ipv6(uint8_t *buffer, uint32_t frame_size, const ipv6_config_t *config);
+
+/**
+ * IP version of a received frame, behind its L2 header
+ * @return 4, 6, or 0 if neither
+ */
+int rfc2544_ip_version(const uint8_t *data, uint32_t len);
+
+/* ============================================================================
+ * RFC 2544 Section 11/12 Modifier Functions
+ * ============================================================================ */
+
+/**
+ * Configure Section 11 test modifiers applied to subsequent trials
+ * @param ctx Test context
+ * @param config Modifier configuration (broadcast_pct 0-MAX_BROADCAST_PCT)
+ * @return 0 on success, negative on error
+ */
+int rfc2544_modifiers_configure(rfc2544_ctx_t *ctx, const modifier_config_t *config);
+
+/**
+ * Configure the number of distinct address pairs used by subsequent trials
+ * @param ctx Test context
+ * @param config Address configuration (pair_count up to MAX_ADDRESS_PAIRS).
+ *               Without a pairs table, pair i uses the source MAC + i and
+ *               addresses in the i-th /24.
+ * @return 0 on success, negative on error
+ */
+int rfc2544_addresses_configure(rfc2544_ctx_t *ctx, const address_config_t *config);
+
+/**
+ * Set the addresses, UDP ports and DSCP of built-in frames of subsequent trials
+ * and Y.1564 steps. MACs also address templated frames.
+ * @param ctx Test context
+ * @param config Header fields; all zero restores the built-in ones
+ * @return 0 on success, -EINVAL if a range runs past the last address or
+ *         port, or the DSCP is over 63
+ */
+int rfc2544_headers_configure(rfc2544_ctx_t *ctx, const header_fields_t *config);
+
+/**
+ * Set the padding content of frames generated by subsequent trials and
+ * Y.1564 steps, for DUTs whose compression or deduplication depends on it
+ * @param ctx Test context
+ * @param pattern Padding pattern; NULL restores the incrementing fill
+ * @return 0 on success, -EINVAL for an unknown kind or a custom pattern
+ *         of 0 or over MAX_PAYLOAD_PATTERN bytes
+ */
+int rfc2544_payload_pattern_configure(rfc2544_ctx_t *ctx, const payload_pattern_t *pattern);
+
+/**
+ * Fill a frame's padding with a pattern
+ * @param buf Padding
+ * @param len Bytes to fill
+ * @param pattern Padding pattern (NULL = incrementing)
+ */
+void rfc2544_fill_pattern(uint8_t *buf, uint32_t len, const payload_pattern_t *pattern);
+
+/**
+ * Spread the frames of subsequent trials round-robin across flows with
+ * distinct tuples, to exercise LAG and ECMP hashing. Stream i uses the
+ * source MAC + i, source and destination IPs + i and UDP source port + i,
+ * and carries i as its payload stream ID; it replaces the address pairs.
+ * Built-in headers only.
+ * @param ctx Test context
+ * @param stream_count Flows (0/1 = single flow, up to MAX_STREAMS)
+ * @return 0 on success, negative on error
+ */
+int rfc2544_streams_configure(rfc2544_ctx_t *ctx, uint32_t stream_count);
+
+/**
+ * Get the per-stream statistics of the last completed trial
+ * @param ctx Test context
+ * @param stats Array to populate (caller allocates)
+ * @param max_count Maximum streams to return
+ * @return Number of streams (0 for a single flow), negative on error
+ */
+int rfc2544_get_stream_stats(rfc2544_ctx_t *ctx, stream_stats_t *stats, uint32_t max_count);
+
+/**
+ * Introduce the address pairs at a limited rate before the first trial
+ * after they are configured, so thousands of new source MACs do not trip
+ * the DUT's MAC-learning protection. Each pair gets one untracked frame.
+ * @param ctx Test context
+ * @param rate Pairs introduced per second (0 = all at once, no ramp)
+ * @return 0 on success, negative on error
+ */
+int rfc2544_addresses_set_learn_rate(rfc2544_ctx_t *ctx, uint32_t rate);
+
+/**
+ * Get the learning ramp run since the addresses were last configured
+ * @param ctx Test context
+ * @param stats Output ramp statistics
+ * @return 0 on success, negative on error
+ */
+int rfc2544_addresses_get_learn_stats(const rfc2544_ctx_t *ctx, address_learn_stats_t *stats);
+
+/**
+ * Configure the encapsulation of frames generated by subsequent trials
+ * @param ctx Test context
+ * @param config Framing configuration (ethertype 0 or >= 0x0600)
+ * @return 0 on success, negative on error
+ */
+int rfc2544_framing_configure(rfc2544_ctx_t *ctx, const framing_config_t *config);
+
+/**
+ * Tag frames generated by subsequent trials with an 802.1Q header. The
+ * tag is part of the frame size: it takes 4 bytes from the padding.
+ * @param ctx Test context
+ * @param vlan_id VLAN ID (0-4094; 0 with a pcp = priority-tagged)
+ * @param pcp 802.1p priority (0-7); vlan_id and pcp 0 = untagged
+ * @return 0 on success, negative on error
+ */
+int rfc2544_vlan_configure(rfc2544_ctx_t *ctx, uint16_t vlan_id, uint8_t pcp);
+
+/**
+ * Stack an 802.1ad S-tag over the tag of rfc2544_vlan_configure, and set
+ * the TPIDs of both. The 8 bytes of a double tag come from the padding.
+ * @param ctx Test context
+ * @param config QinQ configuration (TPIDs 0x8100, 0x88a8 or 0x9100-0x9300)
+ * @return 0 on success, negative on error
+ */
+int rfc2544_qinq_configure(rfc2544_ctx_t *ctx, const qinq_config_t *config);
+
+/**
+ * Push an MPLS label stack onto frames generated by subsequent trials.
+ * The stack is part of the frame size: 4 bytes a label from the padding.
+ * @param ctx Test context
+ * @param config Label stack (count 0 = unlabeled); needs Ethernet II IPv4 framing
+ * @return 0 on success, negative on error
+ */
+int rfc2544_mpls_configure(rfc2544_ctx_t *ctx, const mpls_config_t *config);
+
+/**
+ * Select the TX tuning profile of subsequent trials
+ * @param ctx Test context
+ * @param name Profile name; NULL or "" picks it from the detected line rate
+ * @return 0 on success, -EINVAL for an unknown profile
+ */
+int rfc2544_speed_profile_configure(rfc2544_ctx_t *ctx, const char *name);
+
+/**
+ * Seal a CRC-32 into the payload of frames generated by subsequent trials
+ * and count received frames that fail it as corrupted
+ * @param ctx Test context
+ * @param enabled Verify payloads (frames need 4 bytes of padding)
+ * @return 0 on success, negative on error
+ */
+int rfc2544_verify_payload_configure(rfc2544_ctx_t *ctx, bool enabled);
+
+/**
+ * Configure control-plane policing stress applied to subsequent trials
+ * @param ctx Test context
+ * @param config Rates and DUT address (all rates 0 = off); resets stats
+ * @return 0 on success, negative on error
+ */
+int rfc2544_cpp_configure(rfc2544_ctx_t *ctx, const cpp_config_t *config);
+
+/**
+ * Get control-plane frames sent and answered since the last configure
+ */
+int rfc2544_cpp_get_stats(const rfc2544_ctx_t *ctx, cpp_stats_t *stats);
+
+/**
+ * Send a latency probe alongside the load of subsequent trials that
+ * measure latency, its frames excluded from the trial's counters
+ * @param ctx Test context
+ * @param config Probe rate and marking (pps 0 = off); resets stats
+ * @return 0 on success, negative on error
+ */
+int rfc2544_latency_probe_configure(rfc2544_ctx_t *ctx, const latency_probe_config_t *config);
+
+/**
+ * Get the latency probe's counters over the last trial that sent it
+ */
+int rfc2544_latency_probe_get_stats(const rfc2544_ctx_t *ctx, latency_probe_stats_t *stats);
+
+/**
+ * Build a control-plane frame into buf (CPP_FRAME_LEN bytes)
+ * @return Frame length, negative on error
+ */
+int rfc2544_build_control_frame(uint8_t *buf, uint32_t len, cpp_frame_t type,
+                                const uint8_t *src_mac, const uint8_t *dst_mac,
+                                uint32_t src_ip, uint32_t dst_ip, uint16_t seq);
+
+/**
+ * Classify a received frame as a DUT reply to a control-plane frame
+ * @param dut_ip DUT address (network order)
+ * @return Kind of frame answered, CPP_FRAME_NONE if not a reply
+ */
+cpp_frame_t rfc2544_classify_control_reply(const uint8_t *data, uint32_t len, uint32_t dut_ip);
+
+/**
+ * Configure the headers of frames generated by subsequent trials from a
+ * compiled packet template, replacing the built-in headers and framing
+ * @param ctx Test context
+ * @param tpl Header template (header_len 0 restores the built-in headers)
+ * @return 0 on success, negative on error
+ */
+int rfc2544_template_configure(rfc2544_ctx_t *ctx, const header_template_t *tpl);
+
+/**
+ * Re-encapsulate a packet template per framing
+ * @param buffer Packet buffer (from create_packet_template)
+ * @param frame_size Frame size in bytes
+ * @param framing Framing configuration
+ * @return Bytes the payload moved by (0 or LLC_SNAP_HEADER_LEN), negative on error
+ */
+int rfc2544_apply_framing(uint8_t *buffer, uint32_t frame_size, const framing_config_t *framing);
+
+/**
+ * Insert an 802.1Q tag after the MAC addresses of a packet template,
+ * moving the rest back and shortening the padding
+ * @param buffer Packet buffer (from create_packet_template, after framing)
+ * @param frame_size Frame size in bytes
+ * @param vlan_id VLAN ID (0-4094)
+ * @param pcp 802.1p priority (0-7)
+ * @return Bytes the payload moved by (VLAN_TAG_LEN), negative on error
+ */
+int rfc2544_insert_vlan_tag(uint8_t *buffer, uint32_t frame_size, uint16_t vlan_id, uint8_t pcp);
+
+/**
+ * Insert a VLAN tag with any TPID after the MAC addresses. A tag already
+ * present ends up inside the new one.
+ * @param buffer Packet buffer (from create_packet_template, after framing)
+ * @param frame_size Frame size in bytes
+ * @param tpid Tag protocol identifier (see rfc2544_valid_tpid)
+ * @param vlan_id VLAN ID (0-4094)
+ * @param pcp 802.1p priority (0-7)
+ * @return Bytes the payload moved by (VLAN_TAG_LEN), negative on error
+ */
+int rfc2544_insert_tag(uint8_t *buffer, uint32_t frame_size, uint16_t tpid, uint16_t vlan_id,
+                       uint8_t pcp);
+
+/**
+ * Insert the 802.1Q tag, then the QinQ S-tag over it, each when set
+ * @param buffer Packet buffer (from create_packet_template, after framing)
+ * @param frame_size Frame size in bytes
+ * @param qinq S-tag and TPIDs (NULL = single 802.1Q tag)
+ * @param vlan_id Inner VLAN ID (vlan_id and pcp 0 = no inner tag)
+ * @param pcp Inner 802.1p priority
+ * @return Bytes the payload moved by (0, 4 or 8), negative on error
+ */
+int rfc2544_insert_tags(uint8_t *buffer, uint32_t frame_size, const qinq_config_t *qinq,
+                        uint16_t vlan_id, uint8_t pcp);
+
+/**
+ * Insert an MPLS label stack between the L2 header (and any VLAN tags)
+ * and the IP header, setting the EtherType to ETHERTYPE_MPLS and the
+ * bottom-of-stack bit of the last label
+ * @param buffer Packet buffer (from create_packet_template, Ethernet II IPv4 or IPv6)
+ * @param frame_size Frame size in bytes
+ * @param labels Label stack, outermost first
+ * @param count Labels in the stack (1-MAX_MPLS_LABELS)
+ * @return Bytes the payload moved by (count * MPLS_LABEL_LEN), negative on error
+ */
+int rfc2544_insert_mpls(uint8_t *buffer, uint32_t frame_size, const mpls_label_t *labels,
+                        uint32_t count);
+
+/**
+ * Mark a frame's priority: the PCP of each VLAN tag and the DSCP of the
+ * IPv4 header or IPv6 traffic class, keeping its ECN bits
+ * @param buffer Frame (from create_packet_template, after tags)
+ * @param frame_size Frame size in bytes
+ * @param pcp 802.1p priority (0-7)
+ * @param dscp DSCP (0-63)
+ * @return 0 on success, negative on error
+ */
+int rfc2544_mark_priority(uint8_t *buffer, uint32_t frame_size, uint8_t pcp, uint8_t dscp);
+
+/**
+ * Check a TPID is one the dataplane tags frames with and recognizes on
+ * receive: 0x8100, 0x88a8 or the pre-802.1ad 0x9100, 0x9200 and 0x9300
+ * @param tpid Tag protocol identifier
+ * @return true if valid
+ */
+bool rfc2544_valid_tpid(uint16_t tpid);
+
 /* ============================================================================
  * Y.1564 Color-Aware Metering Functions
  * ============================================================================ */
@@ -799,6 +1682,12 @@ typedef struct {
 	bool is_up;              /* Interface is up */
 	uint32_t mtu;            /* Maximum transmission unit */
 	uint8_t mac[6];          /* MAC address */
+	char operstate[16];      /* Link state (up, down, dormant, unknown) */
+	char driver[32];         /* Kernel driver (ethtool -i) */
+	char bus_info[32];       /* Bus address, e.g. PCI 0000:01:00.0 */
+	char fw_version[32];     /* Firmware version */
+	bool supports_dpdk;      /* PCI device with a DPDK-compatible kernel module loaded */
+	char dpdk_driver[16];    /* Module to bind for DPDK (vfio-pci, igb_uio, uio_pci_generic) */
 } nic_info_t;
 
 /**
@@ -849,6 +1738,32 @@ uint64_t rfc2544_get_line_rate(const char *interface);
  */
 uint64_t rfc2544_calc_pps(uint64_t line_rate, uint32_t frame_size);
 
+/**
+ * TX tuning for a class of line rate. A profile applies from its line
+ * rate up to the next one's.
+ */
+typedef struct {
+	const char *name;     /* e.g. "25g" */
+	uint64_t line_rate;   /* Bits/sec the profile starts at */
+	uint32_t batch_size;  /* Frames sent back to back per pacing wait */
+	bool busy_wait;       /* Spin instead of sleeping between batches */
+	uint32_t max_backlog; /* Frames the pacer may fall behind before resetting */
+} speed_profile_t;
+
+/**
+ * Get the TX tuning profile for a line rate
+ * @param line_rate Line rate in bits/sec
+ * @return Profile of the fastest class not above line_rate
+ */
+const speed_profile_t *rfc2544_speed_profile(uint64_t line_rate);
+
+/**
+ * Get a TX tuning profile by name
+ * @param name Profile name, e.g. "100g"
+ * @return Profile, or NULL if there is none of that name
+ */
+const speed_profile_t *rfc2544_speed_profile_by_name(const char *name);
+
 /**
  * Get default configuration
  * @param config Configuration to populate
@@ -877,9 +1792,15 @@ void rfc2544_print_results(const rfc2544_ctx_t *ctx);
  * 7       4       Sequence number (uint32_t, network order)
  * 11      8       TX timestamp (uint64_t nanoseconds, network order)
  * 19      4       Stream ID (uint32_t, for multi-stream tests)
- * 23      1       Flags (bit 0: request timestamp, bit 1: is response)
+ * 23      1       Flags (bit 0: request timestamp, bit 1: is response,
+ *                 bit 2: CRC-32 trailer)
  * 24      N       Padding to reach frame size
  *
+ * With payload verification the last 4 bytes of the padding carry a
+ * CRC-32 (IEEE 802.3) in network order, over the rest of the padding and
+ * then bytes 0-22. The flags byte, which a reflector may rewrite, is not
+ * covered. The payload runs to the end of the UDP datagram.
+ *
  * Total payload: 24 bytes minimum + padding
  * Minimum frame: 64 bytes (14 ETH + 20 IP + 8 UDP + 22 payload)
  */
@@ -893,6 +1814,9 @@ void rfc2544_print_results(const rfc2544_ctx_t *ctx);
 
 #define RFC2544_FLAG_REQ_TIMESTAMP 0x01
 #define RFC2544_FLAG_IS_RESPONSE 0x02
+#define RFC2544_FLAG_CRC32 0x04
+
+#define RFC2544_CRC_LEN 4
 
 #define RFC2544_MIN_PAYLOAD 24
 #define RFC2544_MIN_FRAME 64
@@ -916,9 +1840,15 @@ void rfc2544_print_results(const rfc2544_ctx_t *ctx);
  * 7       4       Sequence number (uint32_t, network order)
  * 11      8       TX timestamp (uint64_t nanoseconds, network order)
  * 19      4       Service ID (uint32_t, 1-8 for multi-service)
- * 23      1       Flags (bit 0: request timestamp, bit 1: is response)
+ * 23      1       Flags (bit 0: request timestamp, bit 1: is response,
+ *                 bit 2: CRC-32 trailer)
  * 24      N       Padding to reach frame size
  *
+ * With payload verification the last 4 bytes of the padding carry a
+ * CRC-32 (IEEE 802.3) in network order, over the rest of the padding and
+ * then bytes 0-22. The flags byte, which a reflector may rewrite, is not
+ * covered. The payload runs to the end of the UDP datagram.
+ *
  * DSCP is set in the IP header ToS field for CoS marking.
  */
 
@@ -984,12 +1914,23 @@ typedef struct {
 	traffic_pattern_t pattern;     /* Traffic pattern used */
 	double max_rate_pct;           /* Maximum forwarding rate (% of line rate) */
 	double max_rate_fps;           /* Maximum forwarding rate (frames/sec) */
-	double aggregate_rate_mbps;    /* Aggregate throughput across all ports */
+	double aggregate_rate_mbps;    /* Throughput of the measured flow */
 	uint64_t frames_tx;            /* Total frames transmitted */
 	uint64_t frames_rx;            /* Total frames received */
 	double loss_pct;               /* Frame loss percentage */
+	uint32_t flow_count;           /* Flows in the traffic pattern */
+	uint32_t ingress_ports;        /* Ports sourcing traffic */
+	uint32_t egress_ports;         /* Ports receiving traffic */
 } rfc2889_fwd_result_t;
 
+/* Flow plan for a traffic pattern */
+typedef struct {
+	uint32_t flow_count;           /* Source/destination port flows */
+	uint32_t ingress_ports;        /* Ports sourcing traffic */
+	uint32_t egress_ports;         /* Ports receiving traffic */
+	double ingress_load_scale;     /* Per-ingress load so no egress exceeds 100% */
+} rfc2889_pattern_plan_t;
+
 /* Address caching result (Section 5.2) */
 typedef struct {
 	uint32_t frame_size;           /* Frame size used */
@@ -1287,6 +2228,31 @@ typedef struct {
 	bool connectivity_ok;          /* Connectivity status */
 } y1731_session_status_t;
 
+/* ============================================================================
+ * Ethernet OAM Remote Loopback Types (IEEE 802.3ah / ITU-T Y.1731)
+ * ============================================================================
+ *
+ * Discovers far-end devices that can reflect test traffic and, where the
+ * peer supports 802.3ah remote loopback, places its port into loopback
+ * before a test and releases it afterwards.
+ */
+
+#define OAM_MAX_PEERS 16
+#define OAM_FRAME_LEN 60               /* Minimum Ethernet frame, no FCS */
+
+/* Peer capabilities (bit flags) */
+#define OAM_CAP_8023AH        0x01     /* Answers 802.3ah Information OAMPDUs */
+#define OAM_CAP_REMOTE_LB     0x02     /* Advertises 802.3ah remote loopback */
+#define OAM_CAP_Y1731_LB      0x04     /* Answers Y.1731 LBM with LBR */
+
+/* Discovered far-end OAM peer */
+typedef struct {
+	uint8_t mac[6];                /* Peer MAC address */
+	uint32_t capabilities;         /* OAM_CAP_* flags */
+	bool in_loopback;              /* 802.3ah parser is in loopback */
+	uint8_t meg_level;             /* MEG level of the LBR (Y.1731 peers) */
+} oam_peer_t;
+
 /* ============================================================================
  * MEF 48/49 - Carrier Ethernet Performance Testing Types
  * ============================================================================
@@ -1701,6 +2667,25 @@ int rfc2889_broadcast_test(rfc2544_ctx_t *ctx, const rfc2889_config_t *config,
 int rfc2889_congestion_test(rfc2544_ctx_t *ctx, const rfc2889_config_t *config,
                             rfc2889_congestion_result_t *result);
 
+/**
+ * Plan flows for a traffic pattern
+ * @param pattern Traffic pattern
+ * @param port_count Number of ports (>= 2, even for pair-wise)
+ * @param plan Output flow plan
+ * @return 0 on success, negative on error
+ */
+int rfc2889_pattern_plan(traffic_pattern_t pattern, uint32_t port_count,
+                         rfc2889_pattern_plan_t *plan);
+
+/**
+ * Check a flow plan can be measured: a trial drives one flow, from the TX
+ * port to the RX port (or back to the TX port through a reflector)
+ * @param plan Flow plan
+ * @return true if the plan is a single flow between one ingress and one
+ *         egress port
+ */
+bool rfc2889_pattern_measurable(const rfc2889_pattern_plan_t *plan);
+
 /**
  * Get default RFC 2889 configuration
  */
@@ -1811,6 +2796,56 @@ void y1731_print_delay_results(const y1731_delay_result_t *result);
  */
 void y1731_print_loss_results(const y1731_loss_result_t *result);
 
+/* ============================================================================
+ * Ethernet OAM Remote Loopback API Functions
+ * ============================================================================ */
+
+/**
+ * Discover far-end OAM peers on the test interface
+ * @param ctx Test context
+ * @param meg_level MEG level for the Y.1731 multicast LBM
+ * @param timeout_ms Time to wait for replies
+ * @param peers Output array of discovered peers
+ * @param max_peers Size of peers array
+ * @param count Output number of peers found
+ * @return 0 on success, negative on error
+ */
+int oam_discover(rfc2544_ctx_t *ctx, uint8_t meg_level, uint32_t timeout_ms,
+                 oam_peer_t *peers, uint32_t max_peers, uint32_t *count);
+
+/**
+ * Place a far-end port into (or release it from) 802.3ah remote loopback
+ * @param ctx Test context
+ * @param peer_mac Peer MAC address from discovery
+ * @param enable true to enter loopback, false to release it
+ * @param timeout_ms Time to wait for the peer to confirm the new state
+ * @return 0 on success, -ETIMEDOUT if the peer did not confirm
+ */
+int oam_remote_loopback(rfc2544_ctx_t *ctx, const uint8_t *peer_mac, bool enable,
+                        uint32_t timeout_ms);
+
+/**
+ * Build an 802.3ah Information OAMPDU into buf (OAM_FRAME_LEN bytes)
+ */
+int oam_build_info(uint8_t *buf, uint32_t len, const uint8_t *src_mac);
+
+/**
+ * Build an 802.3ah Loopback Control OAMPDU into buf (OAM_FRAME_LEN bytes)
+ */
+int oam_build_loopback_ctl(uint8_t *buf, uint32_t len, const uint8_t *src_mac, bool enable);
+
+/**
+ * Build a Y.1731 multicast LBM into buf (OAM_FRAME_LEN bytes)
+ */
+int oam_build_lbm(uint8_t *buf, uint32_t len, const uint8_t *src_mac, uint8_t meg_level,
+                  uint32_t transaction_id);
+
+/**
+ * Parse a received OAM frame into peer
+ * @return true if the frame is an 802.3ah Information OAMPDU or Y.1731 LBR
+ */
+bool oam_parse_frame(const uint8_t *data, uint32_t len, oam_peer_t *peer);
+
 /* ============================================================================
  * MEF 48/49 API Functions
  * ============================================================================ */
@@ -11,6 +11,7 @@
 #include "rfc2544.h"
 #include "platform_config.h"
 #include <pthread.h>
+#include <stdio.h>
 
 /* Forward declarations */
 typedef struct platform_ops platform_ops_t;
@@ -19,6 +20,7 @@ typedef struct platform_ops platform_ops_t;
 typedef struct {
 	int worker_id;
 	int queue_id;
+	char interface[64]; /* Port the worker opens */
 	void *pctx; /* Platform-specific context */
 
 	/* Stats */
@@ -30,6 +32,20 @@ typedef struct {
 	uint64_t rx_errors;
 } worker_ctx_t;
 
+/* An open pcap capture file */
+typedef struct {
+	FILE *file;
+	uint64_t frames;
+	uint32_t limit;
+	uint32_t snaplen;
+} capture_file_t;
+
+/* A buffer kept from trial to trial, grown on demand */
+typedef struct {
+	void *data;
+	size_t size;
+} pool_buf_t;
+
 /* Main test context structure */
 struct rfc2544_ctx {
 	/* Configuration */
@@ -38,6 +54,7 @@ struct rfc2544_ctx {
 	/* State */
 	test_state_t state;
 	volatile bool cancel_requested;
+	volatile uint64_t heartbeat; /* Bumped as trials run, read by the Go watchdog */
 
 	/* Platform */
 	const platform_ops_t *platform;
@@ -78,8 +95,78 @@ struct rfc2544_ctx {
 	uint32_t latency_sample_count;
 	uint32_t latency_sample_capacity;
 	pthread_mutex_t latency_lock;
+
+	/* Section 11 modifiers */
+	modifier_config_t modifiers;
+
+	/* Addresses and UDP ports of built-in frames (zero = built-in) */
+	header_fields_t headers;
+	payload_pattern_t pattern; /* Padding of generated frames */
+
+	/* Section 12 address pairs */
+	address_config_t addresses;
+	uint32_t learn_rate;             /* Pairs introduced per second (0 = no ramp) */
+	address_learn_stats_t learn_stats;
+
+	/* Frame encapsulation */
+	framing_config_t framing;
+	uint16_t vlan_id; /* 802.1Q tag; vlan_id and vlan_pcp 0 = untagged */
+	uint8_t vlan_pcp;
+	qinq_config_t qinq; /* S-tag over it and the TPIDs */
+	mpls_config_t mpls; /* Label stack after the tags */
+
+	/* TX tuning of the trials, from the line rate unless configured */
+	const speed_profile_t *speed_profile;
+
+	/* CRC-32 sealed into each frame's payload and checked on receive */
+	bool verify_payload;
+
+	/* Latency samples a trial keeps (0 = DEFAULT_LATENCY_SAMPLES). Their
+	 * arrays and the sequence tracker are allocated by the first trial
+	 * needing them and reused by the next, until cleanup. */
+	uint32_t sample_capacity;
+	pool_buf_t pool_samples[4]; /* Load, IPv4, IPv6 and probe samples */
+	pool_buf_t pool_sample_streams;
+	struct seq_tracker *pool_tracker;
+
+	/* PCAP capture of sent and received frames */
+	capture_file_t capture_tx;
+	capture_file_t capture_rx;
+	capture_stats_t capture_stats; /* Of the last closed capture */
+
+	/* Multi-stream traffic; per-stream stats of the last trial under latency_lock */
+	uint32_t stream_count;
+	stream_stats_t *stream_stats; /* stream_count entries */
+	uint32_t stream_stats_count;
+
+	/* User-defined frame headers (header_len 0 = built-in) */
+	header_template_t tpl;
+
+	/* Control-plane policing stress */
+	cpp_config_t cpp;
+	cpp_stats_t cpp_stats;
+
+	/* Latency probe alongside the load */
+	latency_probe_config_t probe;
+	latency_probe_stats_t probe_stats;
+
+	/* IP version of built-in frames, and each family's last trial */
+	ip_stack_config_t ip;
+	ip_family_stats_t family_stats[2];
+
+	/* Live counters for telemetry, published periodically by run_trial */
+	live_stats_t live;
+	uint64_t live_trial_start_ns; /* When the current trial started */
+	pthread_mutex_t live_lock;
 };
 
+/* Open the TX port, and the RX port when rx_interface is set; a no-op
+ * once open (implemented in core.c) */
+int rfc2544_open_ports(rfc2544_ctx_t *ctx);
+
+/* Worker that receives test frames: the RX port if open, else the TX port */
+worker_ctx_t *rfc2544_rx_worker(rfc2544_ctx_t *ctx);
+
 /* Logging function (implemented in core.c) */
 void rfc2544_log(log_level_t level, const char *fmt, ...);
 
@@ -92,11 +179,13 @@ typedef struct {
 	uint64_t packets_sent;
 	uint64_t packets_recv;
 	uint64_t bytes_sent;
+	uint64_t broadcast_sent; /* Section 11.1 broadcast frames (not in packets_sent) */
 	double loss_pct;
 	double elapsed_sec;
 	double achieved_pps;
 	double achieved_mbps;
 	latency_stats_t latency;
+	frame_order_t order;
 } trial_result_t;
 
 /**
@@ -135,7 +224,20 @@ int run_trial_custom(rfc2544_ctx_t *ctx, uint32_t frame_size, double rate_pct,
 /* Calculate max PPS for given line rate and frame size */
 uint64_t calc_max_pps(uint64_t line_rate_bps, uint32_t frame_size);
 
+/* Section 11.1: whether the next frame slot carries a broadcast frame,
+ * spreading pct% of the slots evenly; acc carries the share over slots */
+bool rfc2544_broadcast_slot(double *acc, double pct);
+
 /* Report progress to callback */
 void report_progress(rfc2544_ctx_t *ctx, const char *message, double pct);
 
+/* Save a frame to a capture file, until it holds its limit (capture.c) */
+void rfc2544_capture_frame(capture_file_t *f, const uint8_t *data, uint32_t len);
+
+/* Flush the capture files at the end of a trial */
+void rfc2544_capture_flush(rfc2544_ctx_t *ctx);
+
+/* Close the capture files */
+void rfc2544_capture_close(rfc2544_ctx_t *ctx);
+
 #endif /* RFC2544_INTERNAL_H */
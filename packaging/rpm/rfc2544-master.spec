@@ -95,7 +95,7 @@ echo "║  Enable at boot: sudo systemctl enable rfc2544            ║"
 echo "║  View logs:      journalctl -u rfc2544 -f                 ║"
 echo "║  Web UI:         http://localhost:8080                    ║"
 echo "║                                                           ║"
-echo "║  CLI usage:      rfc2544 <interface> --test <type>        ║"
+echo "║  CLI usage:      rfc2544 <test> -i <interface>            ║"
 echo "║  Test types:     throughput, latency, loss, burst         ║"
 echo "╚═══════════════════════════════════════════════════════════╝"
 echo ""
@@ -0,0 +1,257 @@
+// Package addrpool draws the MAC and IPv4 addresses of test flows at
+// random from configured ranges, so each run exercises different table
+// entries in the DUT. No address is drawn twice from a pool, and none is
+// used both as a source and as a destination.
+package addrpool
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"net"
+	"strings"
+	"time"
+)
+
+// Range is an inclusive range of MAC (48-bit) or IPv4 addresses
+type Range struct {
+	First, Last uint64
+}
+
+// Size is the number of addresses in the range; 0 for an unset range
+func (r Range) Size() uint64 {
+	if r.Last < r.First || r.First == 0 {
+		return 0
+	}
+	return r.Last - r.First + 1
+}
+
+func (r Range) contains(a uint64) bool {
+	return a >= r.First && a <= r.Last
+}
+
+func (r Range) format(text func(uint64) string) string {
+	if r.First == r.Last {
+		return text(r.First)
+	}
+	return text(r.First) + "-" + text(r.Last)
+}
+
+// ParseMACRange parses "FIRST-LAST" or a single MAC address. Multicast
+// addresses cannot be sources or destinations of test frames, so a range
+// that includes any is rejected.
+func ParseMACRange(s string) (Range, error) {
+	first, last, _ := strings.Cut(s, "-")
+	if last == "" {
+		last = first
+	}
+	var r Range
+	for i, part := range []string{first, last} {
+		mac, err := net.ParseMAC(strings.TrimSpace(part))
+		if err != nil || len(mac) != 6 {
+			return Range{}, fmt.Errorf("invalid MAC range %q", s)
+		}
+		v := binary.BigEndian.Uint64(append([]byte{0, 0}, mac...))
+		if i == 0 {
+			r.First = v
+		} else {
+			r.Last = v
+		}
+	}
+	if r.Last < r.First {
+		return Range{}, fmt.Errorf("MAC range %q ends before it starts", s)
+	}
+	if r.First == 0 {
+		return Range{}, fmt.Errorf("MAC range %q includes 00:00:00:00:00:00", s)
+	}
+	for b := r.First >> 40; b <= r.Last>>40; b++ {
+		if b&1 != 0 {
+			return Range{}, fmt.Errorf("MAC range %q includes multicast addresses", s)
+		}
+	}
+	return r, nil
+}
+
+// ParseIPRange parses an IPv4 CIDR, "FIRST-LAST" or a single address. A
+// CIDR shorter than /31 leaves out its network and broadcast addresses.
+func ParseIPRange(s string) (Range, error) {
+	ip4 := func(text string) (uint64, bool) {
+		ip := net.ParseIP(strings.TrimSpace(text)).To4()
+		if ip == nil {
+			return 0, false
+		}
+		return uint64(binary.BigEndian.Uint32(ip)), true
+	}
+
+	var r Range
+	if _, ipnet, err := net.ParseCIDR(s); err == nil {
+		ones, bits := ipnet.Mask.Size()
+		if bits != 32 {
+			return Range{}, fmt.Errorf("IP range %q is not IPv4", s)
+		}
+		r.First = uint64(binary.BigEndian.Uint32(ipnet.IP.To4()))
+		r.Last = r.First + 1<<(32-ones) - 1
+		if ones < 31 {
+			r.First++
+			r.Last--
+		}
+	} else {
+		first, last, _ := strings.Cut(s, "-")
+		if last == "" {
+			last = first
+		}
+		var ok1, ok2 bool
+		r.First, ok1 = ip4(first)
+		r.Last, ok2 = ip4(last)
+		if !ok1 || !ok2 {
+			return Range{}, fmt.Errorf("invalid IPv4 range %q", s)
+		}
+	}
+	if r.Last < r.First {
+		return Range{}, fmt.Errorf("IP range %q ends before it starts", s)
+	}
+	if r.First == 0 {
+		return Range{}, fmt.Errorf("IP range %q includes 0.0.0.0", s)
+	}
+	return r, nil
+}
+
+// Pools are the ranges flows draw their addresses from. An unset pool
+// leaves that address to the dataplane's built-in one.
+type Pools struct {
+	SrcMAC, DstMAC, SrcIP, DstIP Range
+}
+
+// Pair is one flow's addresses; nil where the pool is unset
+type Pair struct {
+	SrcMAC, DstMAC net.HardwareAddr
+	SrcIP, DstIP   net.IP
+}
+
+// PoolUsage records how much of one pool a run drew
+type PoolUsage struct {
+	Pool  string `json:"pool"` // e.g. src_mac
+	Range string `json:"range"`
+	Size  uint64 `json:"size"`
+	Used  uint64 `json:"used"` // Distinct addresses drawn
+}
+
+// Usage records the draw so a run can be repeated with the same addresses
+type Usage struct {
+	Seed  int64       `json:"seed"`
+	Pairs uint32      `json:"pairs"`
+	Pools []PoolUsage `json:"pools"`
+}
+
+// Allocation is the addresses of every flow and the pool usage
+type Allocation struct {
+	Pairs []Pair
+	Usage Usage
+}
+
+// Allocate draws addresses for pairs flows (0 counts as one). Each pool
+// gives every flow its own address while it has enough, and otherwise
+// shares the ones it has round-robin; a pool with one address gives all
+// flows the same one. Source pools draw first, and destinations skip the
+// addresses they took. A seed of 0 picks a new one, recorded in the usage.
+func Allocate(p Pools, pairs uint32, seed int64) (*Allocation, error) {
+	if pairs == 0 {
+		pairs = 1
+	}
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	rng := rand.New(rand.NewSource(seed))
+	a := &Allocation{Pairs: make([]Pair, pairs), Usage: Usage{Seed: seed, Pairs: pairs}}
+
+	macs, ips := make(map[uint64]bool), make(map[uint64]bool)
+	pools := []struct {
+		name string
+		r    Range
+		used map[uint64]bool
+		set  func(p *Pair, v uint64)
+		text func(v uint64) string
+	}{
+		{"src_mac", p.SrcMAC, macs, func(pr *Pair, v uint64) { pr.SrcMAC = macOf(v) }, func(v uint64) string { return macOf(v).String() }},
+		{"src_ip", p.SrcIP, ips, func(pr *Pair, v uint64) { pr.SrcIP = ipOf(v) }, func(v uint64) string { return ipOf(v).String() }},
+		{"dst_mac", p.DstMAC, macs, func(pr *Pair, v uint64) { pr.DstMAC = macOf(v) }, func(v uint64) string { return macOf(v).String() }},
+		{"dst_ip", p.DstIP, ips, func(pr *Pair, v uint64) { pr.DstIP = ipOf(v) }, func(v uint64) string { return ipOf(v).String() }},
+	}
+	for _, pool := range pools {
+		if pool.r.Size() == 0 {
+			continue
+		}
+		var taken uint64
+		for v := range pool.used {
+			if pool.r.contains(v) {
+				taken++
+			}
+		}
+		free := pool.r.Size() - taken
+		if free == 0 {
+			return nil, fmt.Errorf("%s pool has no addresses left after the source pools", pool.name)
+		}
+		n := uint64(pairs)
+		if free < n {
+			n = free
+		}
+		drawn := draw(rng, pool.r, n, pool.used)
+		for i := range a.Pairs {
+			pool.set(&a.Pairs[i], drawn[i%len(drawn)])
+		}
+		a.Usage.Pools = append(a.Usage.Pools, PoolUsage{
+			Pool:  pool.name,
+			Range: pool.r.format(pool.text),
+			Size:  pool.r.Size(),
+			Used:  n,
+		})
+	}
+	return a, nil
+}
+
+// draw picks n distinct addresses of r not in used, in random order, and
+// adds them to used
+func draw(rng *rand.Rand, r Range, n uint64, used map[uint64]bool) []uint64 {
+	var out []uint64
+	if r.Size() <= 4*n+uint64(len(used)) {
+		// Dense: shuffle the free addresses
+		for v := r.First; v <= r.Last; v++ {
+			if !used[v] {
+				out = append(out, v)
+			}
+		}
+		rng.Shuffle(len(out), func(i, j int) { out[i], out[j] = out[j], out[i] })
+		out = out[:n]
+	} else {
+		// Sparse: retry the rare repeat
+		for uint64(len(out)) < n {
+			if v := r.First + uint64(rng.Int63n(int64(r.Size()))); !used[v] {
+				used[v] = true
+				out = append(out, v)
+			}
+		}
+	}
+	for _, v := range out {
+		used[v] = true
+	}
+	return out
+}
+
+func macOf(v uint64) net.HardwareAddr {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	return net.HardwareAddr(b[2:])
+}
+
+func ipOf(v uint64) net.IP {
+	return net.IPv4(byte(v>>24), byte(v>>16), byte(v>>8), byte(v)).To4()
+}
+
+// String summarises the usage, e.g. "seed 42: src_mac 256 of 65534"
+func (u Usage) String() string {
+	parts := make([]string, 0, len(u.Pools))
+	for _, p := range u.Pools {
+		parts = append(parts, fmt.Sprintf("%s %d of %d", p.Pool, p.Used, p.Size))
+	}
+	return fmt.Sprintf("seed %d: %s", u.Seed, strings.Join(parts, ", "))
+}
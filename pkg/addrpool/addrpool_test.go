@@ -0,0 +1,116 @@
+package addrpool
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseRanges(t *testing.T) {
+	r, err := ParseMACRange("02:00:00:00:00:01-02:00:00:00:01:00")
+	if err != nil || r.Size() != 256 {
+		t.Errorf("MAC range = %+v, %v", r, err)
+	}
+	if r, err := ParseMACRange("02:00:00:00:00:05"); err != nil || r.Size() != 1 {
+		t.Errorf("single MAC = %+v, %v", r, err)
+	}
+	for _, bad := range []string{"01:00:5e:00:00:01", "02:00:00:00:00:00-04:00:00:00:00:00", "02:00:00:00:00:09-02:00:00:00:00:01", "nope"} {
+		if _, err := ParseMACRange(bad); err == nil {
+			t.Errorf("ParseMACRange(%q) should fail", bad)
+		}
+	}
+
+	tests := []struct {
+		in          string
+		first, size uint64
+	}{
+		{"10.1.0.0/24", 0x0a010001, 254},
+		{"10.1.0.0/31", 0x0a010000, 2},
+		{"10.1.0.10-10.1.0.19", 0x0a01000a, 10},
+		{"192.0.2.1", 0xc0000201, 1},
+	}
+	for _, tt := range tests {
+		r, err := ParseIPRange(tt.in)
+		if err != nil || r.First != tt.first || r.Size() != tt.size {
+			t.Errorf("ParseIPRange(%q) = %+v (size %d), %v", tt.in, r, r.Size(), err)
+		}
+	}
+	for _, bad := range []string{"2001:db8::/64", "10.0.0.9-10.0.0.1", "0.0.0.0-0.0.0.9", "10.0.0"} {
+		if _, err := ParseIPRange(bad); err == nil {
+			t.Errorf("ParseIPRange(%q) should fail", bad)
+		}
+	}
+}
+
+func mustMAC(t *testing.T, s string) Range {
+	t.Helper()
+	r, err := ParseMACRange(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func mustIP(t *testing.T, s string) Range {
+	t.Helper()
+	r, err := ParseIPRange(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestAllocate(t *testing.T) {
+	// Sources and destinations share one MAC range, which must not collide
+	pools := Pools{
+		SrcMAC: mustMAC(t, "02:00:00:00:00:01-02:00:00:00:02:00"),
+		DstMAC: mustMAC(t, "02:00:00:00:00:01-02:00:00:00:02:00"),
+		SrcIP:  mustIP(t, "10.1.0.0/16"),
+		DstIP:  mustIP(t, "10.2.0.1"),
+	}
+	a, err := Allocate(pools, 256, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(a.Pairs) != 256 || a.Usage.Seed != 42 {
+		t.Fatalf("%d pairs, seed %d", len(a.Pairs), a.Usage.Seed)
+	}
+	src, dst, ips := map[string]bool{}, map[string]bool{}, map[string]bool{}
+	for _, p := range a.Pairs {
+		src[p.SrcMAC.String()] = true
+		dst[p.DstMAC.String()] = true
+		ips[p.SrcIP.String()] = true
+		if p.DstIP.String() != "10.2.0.1" {
+			t.Errorf("dst IP = %s, want the pool's only address", p.DstIP)
+		}
+	}
+	if len(src) != 256 || len(dst) != 256 || len(ips) != 256 {
+		t.Errorf("distinct: %d src MACs, %d dst MACs, %d src IPs", len(src), len(dst), len(ips))
+	}
+	for mac := range src {
+		if dst[mac] {
+			t.Errorf("%s drawn as both source and destination", mac)
+		}
+	}
+	want := []PoolUsage{
+		{Pool: "src_mac", Range: "02:00:00:00:00:01-02:00:00:00:02:00", Size: 512, Used: 256},
+		{Pool: "src_ip", Range: "10.1.0.1-10.1.255.254", Size: 65534, Used: 256},
+		{Pool: "dst_mac", Range: "02:00:00:00:00:01-02:00:00:00:02:00", Size: 512, Used: 256},
+		{Pool: "dst_ip", Range: "10.2.0.1", Size: 1, Used: 1},
+	}
+	if !reflect.DeepEqual(a.Usage.Pools, want) {
+		t.Errorf("usage = %+v", a.Usage.Pools)
+	}
+
+	// The same seed draws the same addresses
+	b, _ := Allocate(pools, 256, 42)
+	if !reflect.DeepEqual(a.Pairs, b.Pairs) {
+		t.Error("same seed should repeat the draw")
+	}
+
+	// A destination pool the sources used up
+	pools.SrcMAC = mustMAC(t, "02:00:00:00:00:01-02:00:00:00:01:00")
+	pools.DstMAC = mustMAC(t, "02:00:00:00:00:01")
+	if _, err := Allocate(pools, 256, 42); err == nil {
+		t.Error("expected an error for an exhausted dst_mac pool")
+	}
+}
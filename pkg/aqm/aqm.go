@@ -0,0 +1,137 @@
+// Package aqm characterizes a DUT's active queue management from a slow
+// ramp of offered load: where drops begin, where the queue stops growing
+// and how steeply the drop probability rises in between, which locates the
+// WRED minimum and maximum thresholds and drop slope
+package aqm
+
+import "fmt"
+
+// Step is one fixed-rate trial of the ramp
+type Step struct {
+	LoadPct float64 `json:"load_pct"`
+	LossPct float64 `json:"loss_pct"`
+	AvgUs   float64 `json:"avg_us"`
+	P99Us   float64 `json:"p99_us"`
+	QueueUs float64 `json:"queue_us"` // Average latency above the unloaded baseline
+}
+
+// Profile is the drop behaviour the ramp shows
+type Profile string
+
+const (
+	ProfileNoDrops  Profile = "no_drops"  // No loss over the ramp
+	ProfileTailDrop Profile = "tail_drop" // Drops begin only once the queue is full
+	ProfileWRED     Profile = "wred"      // Drops begin while the queue still grows
+)
+
+// Threshold is a point on the ramp in both load and queueing delay
+type Threshold struct {
+	LoadPct float64 `json:"load_pct"`
+	QueueUs float64 `json:"queue_us"`
+	LossPct float64 `json:"loss_pct"`
+}
+
+// Summary of the drop behaviour found on the ramp
+type Summary struct {
+	Profile    Profile    `json:"profile"`
+	BaselineUs float64    `json:"baseline_us"`             // Latency with an empty queue
+	Min        *Threshold `json:"min_threshold,omitempty"` // First step with drops
+	Max        *Threshold `json:"max_threshold,omitempty"` // First step with the queue at its limit
+	SlopePct   float64    `json:"drop_slope"`              // Loss % per % of load between the thresholds
+	MaxDropPct float64    `json:"max_drop_pct"`            // Loss at the maximum threshold
+	Verdict    string     `json:"verdict"`
+}
+
+// Report is the load ramp at one frame size and the drop profile it shows
+type Report struct {
+	FrameSize uint32  `json:"frame_size"`
+	Steps     []Step  `json:"steps"`
+	Summary   Summary `json:"summary"`
+}
+
+// queueFull is the share of the deepest queue seen that counts as full
+const queueFull = 0.9
+
+// Analyze fills in each step's queueing delay and summarises the ramp.
+// Loss at or below lossFloorPct is treated as none. Steps must be in
+// increasing load order.
+func Analyze(steps []Step, lossFloorPct float64) Summary {
+	s := Summary{Profile: ProfileNoDrops, Verdict: "Insufficient data"}
+	if len(steps) < 2 {
+		return s
+	}
+
+	// The fastest drop-free step has the emptiest queue
+	s.BaselineUs = -1
+	for _, st := range steps {
+		if st.LossPct <= lossFloorPct && st.AvgUs > 0 && (s.BaselineUs < 0 || st.AvgUs < s.BaselineUs) {
+			s.BaselineUs = st.AvgUs
+		}
+	}
+	if s.BaselineUs < 0 {
+		s.BaselineUs = steps[0].AvgUs
+	}
+	var deepest float64
+	for i := range steps {
+		steps[i].QueueUs = 0
+		if q := steps[i].AvgUs - s.BaselineUs; q > 0 {
+			steps[i].QueueUs = q
+		}
+		if steps[i].QueueUs > deepest {
+			deepest = steps[i].QueueUs
+		}
+	}
+
+	first := -1
+	for i, st := range steps {
+		if st.LossPct > lossFloorPct {
+			first = i
+			break
+		}
+	}
+	if first < 0 {
+		s.Verdict = fmt.Sprintf("No drops up to %.1f%% load", steps[len(steps)-1].LoadPct)
+		return s
+	}
+	full := first
+	for i := first; i < len(steps); i++ {
+		if steps[i].QueueUs >= queueFull*deepest {
+			full = i
+			break
+		}
+	}
+	at := func(st Step) *Threshold {
+		return &Threshold{LoadPct: st.LoadPct, QueueUs: st.QueueUs, LossPct: st.LossPct}
+	}
+	s.Min, s.Max = at(steps[first]), at(steps[full])
+	s.MaxDropPct = steps[full].LossPct
+
+	if full == first {
+		s.Profile = ProfileTailDrop
+		s.Verdict = fmt.Sprintf("Tail drop: loss starts at %.1f%% load with the queue full (%.1f us)",
+			s.Min.LoadPct, s.Min.QueueUs)
+		return s
+	}
+	s.Profile = ProfileWRED
+	s.SlopePct = lossSlope(steps[first : full+1])
+	s.Verdict = fmt.Sprintf("Early drop: %.1f us (%.1f%% load) to %.1f us (%.1f%% load), %.3f%% loss per %% load",
+		s.Min.QueueUs, s.Min.LoadPct, s.Max.QueueUs, s.Max.LoadPct, s.SlopePct)
+	return s
+}
+
+// lossSlope returns the least-squares slope of loss against load
+func lossSlope(steps []Step) float64 {
+	var sx, sy, sxx, sxy float64
+	for _, st := range steps {
+		sx += st.LoadPct
+		sy += st.LossPct
+		sxx += st.LoadPct * st.LoadPct
+		sxy += st.LoadPct * st.LossPct
+	}
+	n := float64(len(steps))
+	den := n*sxx - sx*sx
+	if den == 0 {
+		return 0
+	}
+	return (n*sxy - sx*sy) / den
+}
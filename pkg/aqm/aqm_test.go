@@ -0,0 +1,64 @@
+package aqm
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAnalyzeNoDrops(t *testing.T) {
+	steps := []Step{{LoadPct: 50, AvgUs: 10}, {LoadPct: 60, AvgUs: 11}, {LoadPct: 70, AvgUs: 12}}
+	s := Analyze(steps, 0.01)
+	if s.Profile != ProfileNoDrops || s.Min != nil {
+		t.Errorf("Summary = %+v, want no drops", s)
+	}
+	if s.BaselineUs != 10 || steps[2].QueueUs != 2 {
+		t.Errorf("baseline %.1f, queue at 70%% = %.1f", s.BaselineUs, steps[2].QueueUs)
+	}
+
+	if s := Analyze(steps[:1], 0.01); s.Verdict != "Insufficient data" {
+		t.Errorf("one step: %q", s.Verdict)
+	}
+}
+
+func TestAnalyzeWRED(t *testing.T) {
+	// Drops begin at 80% while the queue is still filling, and reach the
+	// maximum threshold at 90% where the queue stops growing
+	steps := []Step{
+		{LoadPct: 70, AvgUs: 10},
+		{LoadPct: 75, AvgUs: 60},
+		{LoadPct: 80, AvgUs: 110, LossPct: 1},
+		{LoadPct: 85, AvgUs: 160, LossPct: 3},
+		{LoadPct: 90, AvgUs: 210, LossPct: 5},
+		{LoadPct: 95, AvgUs: 212, LossPct: 10},
+	}
+	s := Analyze(steps, 0.01)
+	if s.Profile != ProfileWRED {
+		t.Fatalf("profile = %s (%s)", s.Profile, s.Verdict)
+	}
+	if s.Min.LoadPct != 80 || s.Min.QueueUs != 100 {
+		t.Errorf("min threshold = %+v", s.Min)
+	}
+	if s.Max.LoadPct != 90 || s.Max.QueueUs != 200 || s.MaxDropPct != 5 {
+		t.Errorf("max threshold = %+v, max drop %.1f", s.Max, s.MaxDropPct)
+	}
+	if math.Abs(s.SlopePct-0.4) > 1e-9 {
+		t.Errorf("slope = %.3f, want 0.4%% loss per %% load", s.SlopePct)
+	}
+}
+
+func TestAnalyzeTailDrop(t *testing.T) {
+	// The queue fills without loss; drops start once it is full
+	steps := []Step{
+		{LoadPct: 80, AvgUs: 10},
+		{LoadPct: 90, AvgUs: 400},
+		{LoadPct: 95, AvgUs: 500, LossPct: 2},
+		{LoadPct: 100, AvgUs: 505, LossPct: 7},
+	}
+	s := Analyze(steps, 0.01)
+	if s.Profile != ProfileTailDrop || s.Min.LoadPct != 95 || s.Max.LoadPct != 95 {
+		t.Errorf("Summary = %+v, want tail drop at 95%%", s)
+	}
+	if s.SlopePct != 0 {
+		t.Errorf("slope = %.3f, want none for tail drop", s.SlopePct)
+	}
+}
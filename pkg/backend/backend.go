@@ -0,0 +1,38 @@
+// Package backend runs the RFC 2544 trials on a traffic generator other
+// than a bare dataplane context: a TRex server, UDP sockets against a
+// reflector, or the local dataplane under its watchdog. Each reports its
+// results in the dataplane's form, so a run treats them alike.
+package backend
+
+import (
+	"context"
+	"time"
+
+	"github.com/krisarmstrong/rfc2544-master/pkg/dataplane"
+)
+
+// Backend runs the RFC 2544 trials for the CLI. The local dataplane
+// context implements it, and so do TRex for a TRex server, Socket for
+// unprivileged UDP sockets and Watchdog for a context that may wedge.
+// Every run stops when its context ends.
+type Backend interface {
+	SetFrameSize(frameSize uint32)
+	SetAcceptableLoss(lossPct float64) error
+	RunThroughputTest(ctx context.Context) (*dataplane.ThroughputResultCLI, error)
+	RunThroughputSearch(ctx context.Context, search *dataplane.ThroughputSearch, step func(*dataplane.ThroughputSearch)) (*dataplane.ThroughputResultCLI, error)
+	RunLatencyTest(ctx context.Context, loadLevels []float64) ([]dataplane.LatencyResultCLI, error)
+	RunFrameLossTest(ctx context.Context, startPct, endPct, stepPct float64) ([]dataplane.FrameLossResultCLI, error)
+	RunBackToBackTest(ctx context.Context, initialBurst uint64, trials uint32) (*dataplane.BackToBackResultCLI, error)
+	RunSystemRecoveryTest(ctx context.Context, throughputPct float64, overloadSec uint32) (*dataplane.RecoveryResultCLI, error)
+	RunResetTest(ctx context.Context) (*dataplane.ResetResultCLI, error)
+	RunFixedRateTrial(ctx context.Context, ratePct float64, duration time.Duration) (*dataplane.FixedRateResult, error)
+	RunBurstTrain(ctx context.Context, train dataplane.BurstTrain) (*dataplane.BurstTrainResult, error)
+}
+
+// Local is a Backend that also runs the Y.1564 tests, which only the local
+// dataplane does: its context implements it, and so does Watchdog
+type Local interface {
+	Backend
+	RunY1564ConfigTest(ctx context.Context, service *dataplane.Y1564Service) (*dataplane.Y1564ConfigResult, error)
+	RunY1564PerfTest(ctx context.Context, service *dataplane.Y1564Service, durationSec uint32) (*dataplane.Y1564PerfResult, error)
+}
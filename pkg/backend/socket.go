@@ -0,0 +1,226 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/krisarmstrong/rfc2544-master/pkg/config"
+	"github.com/krisarmstrong/rfc2544-master/pkg/dataplane"
+	"github.com/krisarmstrong/rfc2544-master/pkg/udpecho"
+)
+
+// SocketRun labels a socket mode run: rates are relative to MaxPPS, not
+// line rate, and latency is software-timestamped round trip
+type SocketRun struct {
+	Target          string   `json:"target"`
+	MaxPPS          uint32   `json:"max_pps"`
+	ReducedAccuracy []string `json:"reduced_accuracy"`
+}
+
+// Socket runs trials as UDP echo runs against a reflector, for
+// environments without root, raw sockets or XDP
+type Socket struct {
+	gen       *udpecho.Generator
+	run       *SocketRun
+	frameSize uint32
+}
+
+// NewSocket creates a socket mode backend for the reflector of cfg
+func NewSocket(cfg *config.Config) (*Socket, error) {
+	s := cfg.Socket
+	gen, err := udpecho.NewGenerator(udpecho.GeneratorOptions{
+		Target:         s.Target,
+		MaxPPS:         s.MaxPPS,
+		Timeout:        s.Timeout,
+		TrialDuration:  cfg.TrialDuration,
+		Warmup:         cfg.WarmupPeriod,
+		InitialRatePct: cfg.Throughput.InitialRatePct,
+		ResolutionPct:  cfg.Throughput.ResolutionPct,
+		MaxIterations:  cfg.Throughput.MaxIterations,
+		AcceptableLoss: cfg.Throughput.AcceptableLoss,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Socket{gen: gen, run: &SocketRun{Target: s.Target, MaxPPS: s.MaxPPS}}, nil
+}
+
+func socketLatency(l udpecho.Latency) dataplane.LatencyStats {
+	return dataplane.LatencyStats{
+		Count: l.Count, MinNs: l.MinNs, AvgNs: l.AvgNs, MaxNs: l.MaxNs, JitterNs: l.JitterNs,
+		P50Ns: l.P50Ns, P95Ns: l.P95Ns, P99Ns: l.P99Ns,
+	}
+}
+
+func socketSearchLatency(l dataplane.LatencyStats) udpecho.Latency {
+	return udpecho.Latency{
+		Count: l.Count, MinNs: l.MinNs, AvgNs: l.AvgNs, MaxNs: l.MaxNs, JitterNs: l.JitterNs,
+		P50Ns: l.P50Ns, P95Ns: l.P95Ns, P99Ns: l.P99Ns,
+	}
+}
+
+// socketTrials converts the iterations of a socket-mode throughput search
+func socketTrials(trials []udpecho.SearchTrial) []dataplane.ThroughputTrial {
+	var out []dataplane.ThroughputTrial
+	for _, t := range trials {
+		out = append(out, dataplane.ThroughputTrial{
+			RatePct:  t.RatePct,
+			FramesTx: t.FramesTx,
+			FramesRx: t.FramesRx,
+			LossPct:  t.LossPct,
+			Passed:   t.Passed,
+			Latency:  socketLatency(t.Latency),
+		})
+	}
+	return out
+}
+
+// socketSearch converts a saved throughput search for the generator to
+// continue
+func socketSearch(search *dataplane.ThroughputSearch) *udpecho.Search {
+	if search == nil {
+		return nil
+	}
+	from := &udpecho.Search{
+		LowPct:     search.LowPct,
+		HighPct:    search.HighPct,
+		BestPct:    search.BestRatePct,
+		Iterations: search.Iterations,
+		Latency:    socketSearchLatency(search.Latency),
+	}
+	for _, t := range search.Trials {
+		from.Trials = append(from.Trials, udpecho.SearchTrial{
+			Trial:  udpecho.Trial{RatePct: t.RatePct, FramesTx: t.FramesTx, FramesRx: t.FramesRx, LossPct: t.LossPct, Latency: socketSearchLatency(t.Latency)},
+			Passed: t.Passed,
+		})
+	}
+	return from
+}
+
+// Finish records the reduced-accuracy flags raised during the run
+func (b *Socket) Finish() *SocketRun {
+	b.run.ReducedAccuracy = b.gen.ReducedAccuracy()
+	return b.run
+}
+
+func (b *Socket) SetFrameSize(frameSize uint32) {
+	b.frameSize = frameSize
+	b.gen.SetFrameSize(frameSize)
+}
+
+func (b *Socket) SetAcceptableLoss(lossPct float64) error {
+	b.gen.SetAcceptableLoss(lossPct)
+	return nil
+}
+
+func (b *Socket) RunThroughputTest(ctx context.Context) (*dataplane.ThroughputResultCLI, error) {
+	return b.RunThroughputSearch(ctx, nil, nil)
+}
+
+func (b *Socket) RunThroughputSearch(ctx context.Context, search *dataplane.ThroughputSearch, step func(*dataplane.ThroughputSearch)) (*dataplane.ThroughputResultCLI, error) {
+	var onStep func(*udpecho.Search)
+	if step != nil {
+		onStep = func(s *udpecho.Search) {
+			step(&dataplane.ThroughputSearch{
+				LowPct:      s.LowPct,
+				HighPct:     s.HighPct,
+				BestRatePct: s.BestPct,
+				Iterations:  s.Iterations,
+				Latency:     socketLatency(s.Latency),
+				Trials:      socketTrials(s.Trials),
+			})
+		}
+	}
+	defer context.AfterFunc(ctx, b.gen.Cancel)()
+	r, err := b.gen.ThroughputFrom(socketSearch(search), onStep)
+	if err != nil {
+		return nil, err
+	}
+	return &dataplane.ThroughputResultCLI{
+		FrameSize:   r.FrameSize,
+		MaxRatePct:  r.MaxRatePct,
+		MaxRateMbps: r.MaxRateMbps,
+		MaxRatePPS:  r.MaxRatePPS,
+		Iterations:  r.Iterations,
+		Latency:     socketLatency(r.Latency),
+
+		AcceptableLossPct: r.AcceptableLoss,
+		Trials:            socketTrials(r.Trials),
+	}, nil
+}
+
+func (b *Socket) RunLatencyTest(ctx context.Context, loadLevels []float64) ([]dataplane.LatencyResultCLI, error) {
+	defer context.AfterFunc(ctx, b.gen.Cancel)()
+	var results []dataplane.LatencyResultCLI
+	for _, load := range loadLevels {
+		t, err := b.gen.Latency(load)
+		if err != nil {
+			if errors.Is(err, udpecho.ErrCancelled) {
+				return nil, err
+			}
+			log.Printf("  Latency at %.1f%%: %v", load, err)
+			continue
+		}
+		results = append(results, dataplane.LatencyResultCLI{FrameSize: b.frameSize, LoadPct: load, Latency: socketLatency(t.Latency)})
+	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("no latency results")
+	}
+	return results, nil
+}
+
+func (b *Socket) RunFrameLossTest(ctx context.Context, startPct, endPct, stepPct float64) ([]dataplane.FrameLossResultCLI, error) {
+	defer context.AfterFunc(ctx, b.gen.Cancel)()
+	trials, err := b.gen.FrameLoss(startPct, endPct, stepPct)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]dataplane.FrameLossResultCLI, len(trials))
+	for i, t := range trials {
+		results[i] = dataplane.FrameLossResultCLI{
+			FrameSize:  b.frameSize,
+			OfferedPct: t.RatePct,
+			FramesTx:   t.FramesTx,
+			FramesRx:   t.FramesRx,
+			LossPct:    t.LossPct,
+		}
+	}
+	return results, nil
+}
+
+func (b *Socket) RunBackToBackTest(ctx context.Context, initialBurst uint64, trials uint32) (*dataplane.BackToBackResultCLI, error) {
+	return nil, fmt.Errorf("back-to-back test is not supported in socket mode")
+}
+
+func (b *Socket) RunSystemRecoveryTest(ctx context.Context, throughputPct float64, overloadSec uint32) (*dataplane.RecoveryResultCLI, error) {
+	return nil, fmt.Errorf("system recovery test is not supported in socket mode")
+}
+
+func (b *Socket) RunResetTest(ctx context.Context) (*dataplane.ResetResultCLI, error) {
+	return nil, fmt.Errorf("reset test is not supported in socket mode")
+}
+
+func (b *Socket) RunFixedRateTrial(ctx context.Context, ratePct float64, duration time.Duration) (*dataplane.FixedRateResult, error) {
+	defer context.AfterFunc(ctx, b.gen.Cancel)()
+	t, err := b.gen.Trial(ratePct, duration)
+	if err != nil {
+		return nil, err
+	}
+	return &dataplane.FixedRateResult{
+		FrameSize:     b.frameSize,
+		OfferedPct:    t.RatePct,
+		FramesTx:      t.FramesTx,
+		FramesRx:      t.FramesRx,
+		LossPct:       t.LossPct,
+		DeliveredMbps: t.DeliveredMbps,
+		ElapsedSec:    t.Elapsed.Seconds(),
+		Latency:       socketLatency(t.Latency),
+	}, nil
+}
+
+func (b *Socket) RunBurstTrain(ctx context.Context, train dataplane.BurstTrain) (*dataplane.BurstTrainResult, error) {
+	return nil, fmt.Errorf("microburst test is not supported in socket mode")
+}
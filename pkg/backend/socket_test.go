@@ -0,0 +1,76 @@
+package backend
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/krisarmstrong/rfc2544-master/pkg/config"
+	"github.com/krisarmstrong/rfc2544-master/pkg/dataplane"
+	"github.com/krisarmstrong/rfc2544-master/pkg/udpecho"
+)
+
+func newTestSocket(t *testing.T) *Socket {
+	t.Helper()
+	r, err := udpecho.Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go r.Serve()
+	t.Cleanup(func() { r.Close() })
+
+	cfg := config.DefaultConfig()
+	cfg.Socket = config.SocketConfig{Target: r.Addr().String(), MaxPPS: 1000, Timeout: 100 * time.Millisecond}
+	cfg.TrialDuration = 100 * time.Millisecond
+	cfg.WarmupPeriod = 0
+	cfg.Throughput.InitialRatePct = 100
+	cfg.Throughput.ResolutionPct = 10
+	b, err := NewSocket(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestSocketThroughput(t *testing.T) {
+	b := newTestSocket(t)
+	b.SetFrameSize(128)
+
+	var steps []*dataplane.ThroughputSearch
+	r, err := b.RunThroughputSearch(context.Background(), nil, func(s *dataplane.ThroughputSearch) {
+		steps = append(steps, s)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 50, 75, 87.5 and 93.75% all pass on loopback
+	if r.FrameSize != 128 || r.MaxRatePct != 93.75 || r.Iterations != 4 || len(r.Trials) != 4 {
+		t.Fatalf("result = %+v", r)
+	}
+	if len(steps) != 4 || steps[3].BestRatePct != 93.75 || len(steps[3].Trials) != 4 {
+		t.Fatalf("%d steps, last %+v", len(steps), steps[len(steps)-1])
+	}
+
+	// A search continued from its second step repeats none of the first two
+	r, err = b.RunThroughputSearch(context.Background(), steps[1], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.MaxRatePct != 93.75 || r.Iterations != 4 || r.Trials[0].RatePct != 50 {
+		t.Errorf("continued result = %+v", r)
+	}
+
+	if run := b.Finish(); run.MaxPPS != 1000 || len(run.ReducedAccuracy) == 0 {
+		t.Errorf("Finish() = %+v", run)
+	}
+}
+
+func TestSocketUnsupported(t *testing.T) {
+	b := newTestSocket(t)
+	if _, err := b.RunBackToBackTest(context.Background(), 1000, 1); err == nil {
+		t.Error("back-to-back should not be supported in socket mode")
+	}
+	if _, err := b.RunBurstTrain(context.Background(), dataplane.BurstTrain{}); err == nil {
+		t.Error("burst trains should not be supported in socket mode")
+	}
+}
@@ -0,0 +1,248 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/krisarmstrong/rfc2544-master/pkg/config"
+	"github.com/krisarmstrong/rfc2544-master/pkg/dataplane"
+	"github.com/krisarmstrong/rfc2544-master/pkg/trex"
+)
+
+// TRexRun records the TRex server and ports used for the run
+type TRexRun struct {
+	Server       string  `json:"server"`
+	TxPort       uint8   `json:"tx_port"`
+	RxPort       uint8   `json:"rx_port"`
+	LineRateMbps float64 `json:"line_rate_mbps"`
+}
+
+// TRex runs trials on a TRex server, reporting results in the same form
+// as the local dataplane
+type TRex struct {
+	gen       *trex.Generator
+	run       *TRexRun
+	frameSize uint32
+}
+
+// ConnectTRex connects to the TRex server of cfg and takes its ports
+func ConnectTRex(cfg *config.Config) (*TRex, error) {
+	t := cfg.TRex
+	gen, err := trex.Connect(trex.Options{
+		Server:         t.Server,
+		TxPort:         t.TxPort,
+		RxPort:         t.RxPort,
+		User:           t.User,
+		Force:          t.Force,
+		LineRateMbps:   cfg.LineRateMbps,
+		Timeout:        t.Timeout,
+		TrialDuration:  cfg.TrialDuration,
+		Warmup:         cfg.WarmupPeriod,
+		Drain:          t.Drain,
+		Latency:        cfg.MeasureLatency,
+		InitialRatePct: cfg.Throughput.InitialRatePct,
+		ResolutionPct:  cfg.Throughput.ResolutionPct,
+		MaxIterations:  cfg.Throughput.MaxIterations,
+		AcceptableLoss: cfg.Throughput.AcceptableLoss,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &TRex{
+		gen: gen,
+		run: &TRexRun{Server: t.Server, TxPort: t.TxPort, RxPort: t.RxPort, LineRateMbps: gen.LineRateMbps()},
+	}, nil
+}
+
+// Run returns the server and ports the trials run on
+func (b *TRex) Run() *TRexRun {
+	return b.run
+}
+
+// Generator returns the TRex generator, for tests only TRex can run
+func (b *TRex) Generator() *trex.Generator {
+	return b.gen
+}
+
+// TRexLatency converts a TRex latency summary
+func TRexLatency(l trex.Latency) dataplane.LatencyStats {
+	return dataplane.LatencyStats{Count: l.Count, MinNs: l.MinNs, AvgNs: l.AvgNs, MaxNs: l.MaxNs, JitterNs: l.JitterNs}
+}
+
+func trexSearchLatency(l dataplane.LatencyStats) trex.Latency {
+	return trex.Latency{Count: l.Count, MinNs: l.MinNs, AvgNs: l.AvgNs, MaxNs: l.MaxNs, JitterNs: l.JitterNs}
+}
+
+// trexTrials converts the iterations of a TRex throughput search
+func trexTrials(trials []trex.SearchTrial) []dataplane.ThroughputTrial {
+	var out []dataplane.ThroughputTrial
+	for _, t := range trials {
+		out = append(out, dataplane.ThroughputTrial{
+			RatePct:  t.RatePct,
+			FramesTx: t.FramesTx,
+			FramesRx: t.FramesRx,
+			LossPct:  t.LossPct,
+			Passed:   t.Passed,
+			Latency:  TRexLatency(t.Latency),
+		})
+	}
+	return out
+}
+
+// trexSearch converts a saved throughput search for TRex to continue
+func trexSearch(search *dataplane.ThroughputSearch) *trex.Search {
+	if search == nil {
+		return nil
+	}
+	from := &trex.Search{
+		LowPct:     search.LowPct,
+		HighPct:    search.HighPct,
+		BestPct:    search.BestRatePct,
+		Iterations: search.Iterations,
+		Latency:    trexSearchLatency(search.Latency),
+	}
+	for _, t := range search.Trials {
+		from.Trials = append(from.Trials, trex.SearchTrial{
+			Trial:  trex.Trial{RatePct: t.RatePct, FramesTx: t.FramesTx, FramesRx: t.FramesRx, LossPct: t.LossPct, Latency: trexSearchLatency(t.Latency)},
+			Passed: t.Passed,
+		})
+	}
+	return from
+}
+
+func (b *TRex) SetFrameSize(frameSize uint32) {
+	b.frameSize = frameSize
+	b.gen.SetFrameSize(frameSize)
+}
+
+func (b *TRex) SetAcceptableLoss(lossPct float64) error {
+	b.gen.SetAcceptableLoss(lossPct)
+	return nil
+}
+
+func (b *TRex) RunThroughputTest(ctx context.Context) (*dataplane.ThroughputResultCLI, error) {
+	return b.RunThroughputSearch(ctx, nil, nil)
+}
+
+func (b *TRex) RunThroughputSearch(ctx context.Context, search *dataplane.ThroughputSearch, step func(*dataplane.ThroughputSearch)) (*dataplane.ThroughputResultCLI, error) {
+	var onStep func(*trex.Search)
+	if step != nil {
+		onStep = func(s *trex.Search) {
+			step(&dataplane.ThroughputSearch{
+				LowPct:      s.LowPct,
+				HighPct:     s.HighPct,
+				BestRatePct: s.BestPct,
+				Iterations:  s.Iterations,
+				Latency:     TRexLatency(s.Latency),
+				Trials:      trexTrials(s.Trials),
+			})
+		}
+	}
+	defer context.AfterFunc(ctx, b.gen.Cancel)()
+	r, err := b.gen.ThroughputFrom(trexSearch(search), onStep)
+	if err != nil {
+		return nil, err
+	}
+	return &dataplane.ThroughputResultCLI{
+		FrameSize:   r.FrameSize,
+		MaxRatePct:  r.MaxRatePct,
+		MaxRateMbps: r.MaxRateMbps,
+		MaxRatePPS:  r.MaxRatePPS,
+		Iterations:  r.Iterations,
+		Latency:     TRexLatency(r.Latency),
+
+		AcceptableLossPct: r.AcceptableLoss,
+		Trials:            trexTrials(r.Trials),
+	}, nil
+}
+
+func (b *TRex) RunLatencyTest(ctx context.Context, loadLevels []float64) ([]dataplane.LatencyResultCLI, error) {
+	defer context.AfterFunc(ctx, b.gen.Cancel)()
+	var results []dataplane.LatencyResultCLI
+	for _, load := range loadLevels {
+		t, err := b.gen.Latency(load)
+		if err != nil {
+			if errors.Is(err, trex.ErrCancelled) {
+				return nil, err
+			}
+			log.Printf("  Latency at %.1f%%: %v", load, err)
+			continue
+		}
+		results = append(results, dataplane.LatencyResultCLI{FrameSize: b.frameSize, LoadPct: load, Latency: TRexLatency(t.Latency)})
+	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("no latency results")
+	}
+	return results, nil
+}
+
+func (b *TRex) RunFrameLossTest(ctx context.Context, startPct, endPct, stepPct float64) ([]dataplane.FrameLossResultCLI, error) {
+	defer context.AfterFunc(ctx, b.gen.Cancel)()
+	trials, err := b.gen.FrameLoss(startPct, endPct, stepPct)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]dataplane.FrameLossResultCLI, len(trials))
+	for i, t := range trials {
+		results[i] = dataplane.FrameLossResultCLI{
+			FrameSize:  b.frameSize,
+			OfferedPct: t.RatePct,
+			FramesTx:   t.FramesTx,
+			FramesRx:   t.FramesRx,
+			LossPct:    t.LossPct,
+		}
+	}
+	return results, nil
+}
+
+func (b *TRex) RunBackToBackTest(ctx context.Context, initialBurst uint64, trials uint32) (*dataplane.BackToBackResultCLI, error) {
+	defer context.AfterFunc(ctx, b.gen.Cancel)()
+	r, err := b.gen.BackToBack(initialBurst, trials)
+	if err != nil {
+		return nil, err
+	}
+	return &dataplane.BackToBackResultCLI{
+		FrameSize:       r.FrameSize,
+		MaxBurstFrames:  r.MaxBurstFrames,
+		BurstDurationUs: uint64(r.BurstDurationUs),
+		Trials:          r.Trials,
+	}, nil
+}
+
+func (b *TRex) RunSystemRecoveryTest(ctx context.Context, throughputPct float64, overloadSec uint32) (*dataplane.RecoveryResultCLI, error) {
+	return nil, fmt.Errorf("system recovery test is not supported with trex")
+}
+
+func (b *TRex) RunResetTest(ctx context.Context) (*dataplane.ResetResultCLI, error) {
+	return nil, fmt.Errorf("reset test is not supported with trex")
+}
+
+func (b *TRex) RunFixedRateTrial(ctx context.Context, ratePct float64, duration time.Duration) (*dataplane.FixedRateResult, error) {
+	defer context.AfterFunc(ctx, b.gen.Cancel)()
+	t, err := b.gen.Trial(ratePct, duration)
+	if err != nil {
+		return nil, err
+	}
+	return &dataplane.FixedRateResult{
+		FrameSize:     b.frameSize,
+		OfferedPct:    t.RatePct,
+		FramesTx:      t.FramesTx,
+		FramesRx:      t.FramesRx,
+		LossPct:       t.LossPct,
+		DeliveredMbps: t.DeliveredMbps,
+		ElapsedSec:    t.Elapsed.Seconds(),
+		Latency:       TRexLatency(t.Latency),
+	}, nil
+}
+
+func (b *TRex) RunBurstTrain(ctx context.Context, train dataplane.BurstTrain) (*dataplane.BurstTrainResult, error) {
+	return nil, fmt.Errorf("microburst test is not supported with trex")
+}
+
+// Close releases the TRex ports
+func (b *TRex) Close() {
+	b.gen.Close()
+}
@@ -0,0 +1,44 @@
+package backend
+
+import (
+	"testing"
+
+	"github.com/krisarmstrong/rfc2544-master/pkg/dataplane"
+	"github.com/krisarmstrong/rfc2544-master/pkg/trex"
+)
+
+func TestTRexSearchRoundTrip(t *testing.T) {
+	if trexSearch(nil) != nil {
+		t.Fatal("a search from scratch should stay nil")
+	}
+
+	lat := dataplane.LatencyStats{Count: 10, MinNs: 1000, AvgNs: 1500, MaxNs: 2000, JitterNs: 100}
+	saved := &dataplane.ThroughputSearch{
+		LowPct: 50, HighPct: 75, BestRatePct: 50, Iterations: 2, Latency: lat,
+		Trials: []dataplane.ThroughputTrial{
+			{RatePct: 100, FramesTx: 1000, FramesRx: 900, LossPct: 10, Passed: false, Latency: lat},
+			{RatePct: 50, FramesTx: 500, FramesRx: 500, Passed: true, Latency: lat},
+		},
+	}
+	from := trexSearch(saved)
+	if from.LowPct != 50 || from.HighPct != 75 || from.BestPct != 50 || from.Iterations != 2 || from.Latency.AvgNs != 1500 {
+		t.Fatalf("trexSearch() = %+v", from)
+	}
+
+	trials := trexTrials(from.Trials)
+	if len(trials) != 2 {
+		t.Fatalf("%d trials, want 2", len(trials))
+	}
+	for i, tr := range trials {
+		if tr != saved.Trials[i] {
+			t.Errorf("trial %d = %+v, want %+v", i, tr, saved.Trials[i])
+		}
+	}
+}
+
+func TestTRexLatency(t *testing.T) {
+	l := TRexLatency(trex.Latency{Count: 3, MinNs: 1, AvgNs: 2, MaxNs: 3, JitterNs: 1})
+	if l.Count != 3 || l.MinNs != 1 || l.AvgNs != 2 || l.MaxNs != 3 || l.JitterNs != 1 {
+		t.Errorf("TRexLatency() = %+v", l)
+	}
+}
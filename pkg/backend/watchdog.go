@@ -0,0 +1,232 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/krisarmstrong/rfc2544-master/pkg/dataplane"
+)
+
+// InvalidTrial is a test the dataplane watchdog cut short, leaving its
+// frame size without a result
+type InvalidTrial struct {
+	FrameSize uint32    `json:"frame_size"`
+	Test      string    `json:"test"`
+	Error     string    `json:"error"`
+	Time      time.Time `json:"time"`
+}
+
+// Watchdog runs each test on the local dataplane under the dataplane
+// watchdog. A test cut short by a restart fails with its frame size
+// recorded as an invalid trial, and the run goes on with the next.
+type Watchdog struct {
+	// OnInvalid, if set, is called for each invalid trial with the number
+	// of restarts so far
+	OnInvalid func(t InvalidTrial, restarts int)
+
+	wd        *dataplane.Watchdog
+	frameSize uint32
+	lossPct   float64
+	invalid   []InvalidTrial
+}
+
+// NewWatchdog watches the tests run on c, restarting a context whose
+// heartbeat stops for timeout. open must return a context with all of c's
+// settings but the frame size and acceptable loss, which are reapplied.
+func NewWatchdog(c *dataplane.Context, timeout time.Duration, open func() (*dataplane.Context, error)) *Watchdog {
+	b := &Watchdog{}
+	b.wd = dataplane.NewWatchdog(c, func() (*dataplane.Context, error) {
+		c, err := open()
+		if err != nil {
+			return nil, err
+		}
+		c.SetFrameSize(b.frameSize)
+		if err := c.SetAcceptableLoss(b.lossPct); err != nil {
+			c.Close()
+			return nil, err
+		}
+		return c, nil
+	}, timeout)
+	return b
+}
+
+// Context returns the dataplane context to use outside the watchdog: the
+// current one, or last if a restart left none
+func (b *Watchdog) Context(last *dataplane.Context) *dataplane.Context {
+	if c := b.wd.Context(); c != nil {
+		return c
+	}
+	return last
+}
+
+// Invalid returns the trials cut short so far
+func (b *Watchdog) Invalid() []InvalidTrial {
+	return b.invalid
+}
+
+// Close closes the current context
+func (b *Watchdog) Close() {
+	b.wd.Close()
+}
+
+func (b *Watchdog) SetFrameSize(frameSize uint32) {
+	b.frameSize = frameSize
+	if c := b.wd.Context(); c != nil {
+		c.SetFrameSize(frameSize)
+	}
+}
+
+func (b *Watchdog) SetAcceptableLoss(lossPct float64) error {
+	b.lossPct = lossPct
+	if c := b.wd.Context(); c != nil {
+		return c.SetAcceptableLoss(lossPct)
+	}
+	return nil
+}
+
+// do runs fn under the watchdog. fn's results may only be read when do
+// returns nil: a call the watchdog gave up on may still write them.
+func (b *Watchdog) do(ctx context.Context, test string, fn func(*dataplane.Context) error) error {
+	err := b.wd.Do(ctx, test, fn)
+	if errors.Is(err, dataplane.ErrStalled) || errors.Is(err, dataplane.ErrFault) {
+		t := InvalidTrial{FrameSize: b.frameSize, Test: test, Error: err.Error(), Time: time.Now()}
+		b.invalid = append(b.invalid, t)
+		if b.OnInvalid != nil {
+			b.OnInvalid(t, b.wd.Restarts())
+		}
+	}
+	return err
+}
+
+func (b *Watchdog) RunThroughputTest(ctx context.Context) (*dataplane.ThroughputResultCLI, error) {
+	var r *dataplane.ThroughputResultCLI
+	err := b.do(ctx, "throughput test", func(c *dataplane.Context) (err error) {
+		r, err = c.RunThroughputTest(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (b *Watchdog) RunThroughputSearch(ctx context.Context, search *dataplane.ThroughputSearch, step func(*dataplane.ThroughputSearch)) (*dataplane.ThroughputResultCLI, error) {
+	var r *dataplane.ThroughputResultCLI
+	err := b.do(ctx, "throughput test", func(c *dataplane.Context) (err error) {
+		r, err = c.RunThroughputSearch(ctx, search, step)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (b *Watchdog) RunLatencyTest(ctx context.Context, loadLevels []float64) ([]dataplane.LatencyResultCLI, error) {
+	var r []dataplane.LatencyResultCLI
+	err := b.do(ctx, "latency test", func(c *dataplane.Context) (err error) {
+		r, err = c.RunLatencyTest(ctx, loadLevels)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (b *Watchdog) RunFrameLossTest(ctx context.Context, startPct, endPct, stepPct float64) ([]dataplane.FrameLossResultCLI, error) {
+	var r []dataplane.FrameLossResultCLI
+	err := b.do(ctx, "frame loss test", func(c *dataplane.Context) (err error) {
+		r, err = c.RunFrameLossTest(ctx, startPct, endPct, stepPct)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (b *Watchdog) RunBackToBackTest(ctx context.Context, initialBurst uint64, trials uint32) (*dataplane.BackToBackResultCLI, error) {
+	var r *dataplane.BackToBackResultCLI
+	err := b.do(ctx, "back-to-back test", func(c *dataplane.Context) (err error) {
+		r, err = c.RunBackToBackTest(ctx, initialBurst, trials)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (b *Watchdog) RunSystemRecoveryTest(ctx context.Context, throughputPct float64, overloadSec uint32) (*dataplane.RecoveryResultCLI, error) {
+	var r *dataplane.RecoveryResultCLI
+	err := b.do(ctx, "system recovery test", func(c *dataplane.Context) (err error) {
+		r, err = c.RunSystemRecoveryTest(ctx, throughputPct, overloadSec)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (b *Watchdog) RunResetTest(ctx context.Context) (*dataplane.ResetResultCLI, error) {
+	var r *dataplane.ResetResultCLI
+	err := b.do(ctx, "reset test", func(c *dataplane.Context) (err error) {
+		r, err = c.RunResetTest(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (b *Watchdog) RunFixedRateTrial(ctx context.Context, ratePct float64, duration time.Duration) (*dataplane.FixedRateResult, error) {
+	var r *dataplane.FixedRateResult
+	err := b.do(ctx, "trial", func(c *dataplane.Context) (err error) {
+		r, err = c.RunFixedRateTrial(ctx, ratePct, duration)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (b *Watchdog) RunBurstTrain(ctx context.Context, train dataplane.BurstTrain) (*dataplane.BurstTrainResult, error) {
+	var r *dataplane.BurstTrainResult
+	err := b.do(ctx, "burst train", func(c *dataplane.Context) (err error) {
+		r, err = c.RunBurstTrain(ctx, train)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (b *Watchdog) RunY1564ConfigTest(ctx context.Context, service *dataplane.Y1564Service) (*dataplane.Y1564ConfigResult, error) {
+	var r *dataplane.Y1564ConfigResult
+	err := b.do(ctx, "Y.1564 configuration test", func(c *dataplane.Context) (err error) {
+		r, err = c.RunY1564ConfigTest(ctx, service)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (b *Watchdog) RunY1564PerfTest(ctx context.Context, service *dataplane.Y1564Service, durationSec uint32) (*dataplane.Y1564PerfResult, error) {
+	var r *dataplane.Y1564PerfResult
+	err := b.do(ctx, "Y.1564 performance test", func(c *dataplane.Context) (err error) {
+		r, err = c.RunY1564PerfTest(ctx, service, durationSec)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
@@ -0,0 +1,53 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/krisarmstrong/rfc2544-master/pkg/dataplane"
+)
+
+func TestWatchdogInvalidTrial(t *testing.T) {
+	// No context and none to open: every test fails without running
+	opens := 0
+	b := NewWatchdog(nil, time.Second, func() (*dataplane.Context, error) {
+		opens++
+		return nil, errors.New("no such interface")
+	})
+	var reported []InvalidTrial
+	b.OnInvalid = func(t InvalidTrial, restarts int) { reported = append(reported, t) }
+
+	b.SetFrameSize(512)
+	if err := b.SetAcceptableLoss(0.1); err != nil {
+		t.Fatalf("SetAcceptableLoss() = %v", err)
+	}
+	if _, err := b.RunThroughputTest(context.Background()); !errors.Is(err, dataplane.ErrFault) {
+		t.Fatalf("RunThroughputTest() = %v, want ErrFault", err)
+	}
+	b.SetFrameSize(1518)
+	if _, err := b.RunLatencyTest(context.Background(), []float64{50}); !errors.Is(err, dataplane.ErrFault) {
+		t.Fatalf("RunLatencyTest() = %v, want ErrFault", err)
+	}
+	if _, err := b.RunY1564PerfTest(context.Background(), &dataplane.Y1564Service{ServiceID: 1}, 60); !errors.Is(err, dataplane.ErrFault) {
+		t.Fatalf("RunY1564PerfTest() = %v, want ErrFault", err)
+	}
+
+	invalid := b.Invalid()
+	if len(invalid) != 3 || opens != 3 || len(reported) != 3 {
+		t.Fatalf("%d invalid, %d opens, %d reported", len(invalid), opens, len(reported))
+	}
+	if invalid[0].FrameSize != 512 || invalid[0].Test != "throughput test" || invalid[0].Error == "" {
+		t.Errorf("first invalid trial = %+v", invalid[0])
+	}
+	if invalid[1].FrameSize != 1518 || invalid[1].Test != "latency test" {
+		t.Errorf("second invalid trial = %+v", invalid[1])
+	}
+	if invalid[2].Test != "Y.1564 performance test" {
+		t.Errorf("third invalid trial = %+v", invalid[2])
+	}
+	if b.Context(nil) != nil {
+		t.Error("Context() should fall back to last without a context")
+	}
+}
@@ -0,0 +1,590 @@
+// Package certify lays out an RFC 2544 certification run: the six
+// benchmarks of Section 26 run with the parameters the RFC recommends and
+// the repetitions it requires, then reported section by section with the
+// fields each section asks a report to give. Auditors read the report
+// against the RFC, so it follows the RFC's order rather than the order the
+// tests ran in, and says where a section fell short of it.
+package certify
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/krisarmstrong/rfc2544-master/pkg/config"
+	"github.com/krisarmstrong/rfc2544-master/pkg/dataplane"
+)
+
+// Parameters a certification run enforces
+const (
+	TrialDuration    = config.RFC2544MinTrialDuration    // Section 24: at least
+	SearchIterations = 20                                // Section 26.1: throughput search trials, at least
+	LatencyTrials    = 20                                // Section 26.2: repetitions averaged
+	FrameLossStepPct = config.RFC2544MaxFrameLossStepPct // Section 26.3: at most
+	LosslessTrials   = 2                                 // Section 26.3: trials in a row without loss that end it
+	BackToBackTrials = config.RFC2544MinBackToBackTrials // Section 26.4: at least
+	OverloadSec      = 60                                // Section 26.5: at least
+)
+
+// wireOverhead is the preamble, SFD and inter-frame gap of each frame
+const wireOverhead = 20
+
+// Override is a setting of the config a certification run replaced
+type Override struct {
+	Setting string `json:"setting"` // Config key, e.g. "trial_duration"
+	From    string `json:"from"`
+	To      string `json:"to"`
+}
+
+func (o Override) String() string {
+	return fmt.Sprintf("%s %s -> %s", o.Setting, o.From, o.To)
+}
+
+// Apply sets cfg up for a certification run: the suite at the standard
+// frame sizes with the parameters above. Settings already stricter than the RFC asks, such as
+// longer trials, are kept. It returns the settings it changed.
+func Apply(cfg *config.Config) []Override {
+	var overrides []Override
+	set := func(setting string, from, to interface{}) {
+		if !reflect.DeepEqual(from, to) {
+			overrides = append(overrides, Override{Setting: setting, From: fmt.Sprint(from), To: fmt.Sprint(to)})
+		}
+	}
+	def := config.DefaultConfig()
+
+	cfg.TestType = config.TestSuite
+	set("frame_size", cfg.FrameSize, uint32(0))
+	cfg.FrameSize = 0
+	if len(cfg.FrameSizes) > 0 {
+		set("frame_sizes", cfg.FrameSizes, "standard")
+		cfg.FrameSizes = nil
+	}
+	set("include_jumbo", cfg.IncludeJumbo, false)
+	cfg.IncludeJumbo, cfg.JumboSizes = false, nil
+
+	set("trial_duration", cfg.TrialDuration, max(cfg.TrialDuration, TrialDuration))
+	cfg.TrialDuration = max(cfg.TrialDuration, TrialDuration)
+	if cfg.WarmupPeriod <= 0 {
+		set("warmup_period", cfg.WarmupPeriod, def.WarmupPeriod)
+		cfg.WarmupPeriod = def.WarmupPeriod
+	}
+
+	tp := &cfg.Throughput
+	set("throughput.search_algorithm", tp.SearchAlgorithm, config.SearchBinary)
+	tp.SearchAlgorithm = config.SearchBinary
+	set("throughput.max_iterations", tp.MaxIterations, max(tp.MaxIterations, SearchIterations))
+	tp.MaxIterations = max(tp.MaxIterations, SearchIterations)
+	set("throughput.acceptable_loss", tp.AcceptableLoss, 0.0)
+	tp.AcceptableLoss = 0
+	if len(tp.AcceptableLossBySize) > 0 || len(tp.AcceptableLossByTest) > 0 {
+		set("throughput.acceptable_loss_by_size", "overrides", "none")
+		tp.AcceptableLossBySize, tp.AcceptableLossByTest = nil, nil
+	}
+
+	// Each repetition is a trial at 100% of the throughput
+	loads := make([]float64, LatencyTrials)
+	for i := range loads {
+		loads[i] = 100
+	}
+	if !slices.Equal(cfg.Latency.LoadLevels, loads) {
+		set("latency.load_levels", cfg.Latency.LoadLevels, fmt.Sprintf("%d x 100", LatencyTrials))
+		cfg.Latency.LoadLevels = loads
+	}
+
+	fl := &cfg.FrameLoss
+	set("frame_loss.start_pct", fl.StartPct, 100.0)
+	fl.StartPct = 100
+	set("frame_loss.step_pct", fl.StepPct, min(fl.StepPct, FrameLossStepPct))
+	fl.StepPct = min(fl.StepPct, FrameLossStepPct)
+	set("frame_loss.search_threshold_pct", fl.SearchThresholdPct, 0.0)
+	fl.SearchThresholdPct = 0
+	set("frame_loss.lossless_trials", fl.LosslessTrials, uint32(LosslessTrials))
+	fl.LosslessTrials = LosslessTrials
+
+	set("back_to_back.trials", cfg.BackToBack.Trials, max(cfg.BackToBack.Trials, BackToBackTrials))
+	cfg.BackToBack.Trials = max(cfg.BackToBack.Trials, BackToBackTrials)
+
+	set("recovery.overload_sec", cfg.Recovery.OverloadSec, max(cfg.Recovery.OverloadSec, OverloadSec))
+	cfg.Recovery.OverloadSec = max(cfg.Recovery.OverloadSec, OverloadSec)
+	return overrides
+}
+
+// MaxFPS is the theoretical maximum frame rate of a frame size at a line
+// rate in Mbit/s, which Section 26.1 reports the throughput against
+func MaxFPS(lineMbps float64, frameSize uint32) float64 {
+	return lineMbps * 1e6 / (float64(frameSize+wireOverhead) * 8)
+}
+
+// Parameter is one setting the run used, with the section asking for it
+type Parameter struct {
+	Section string `json:"section"`
+	Name    string `json:"name"`
+	Value   string `json:"value"`
+}
+
+// Section is what every benchmark's part of the report has
+type Section struct {
+	Title     string   `json:"title"`
+	Procedure string   `json:"procedure"`       // How it was measured
+	Complete  bool     `json:"complete"`        // Measured at every frame size as the RFC requires
+	Notes     []string `json:"notes,omitempty"` // Where it fell short, and why
+}
+
+func (s *Section) note(format string, args ...interface{}) {
+	s.Notes = append(s.Notes, fmt.Sprintf(format, args...))
+}
+
+// ThroughputRow is Section 26.1's throughput of one frame size, against
+// the theoretical maximum rate
+type ThroughputRow struct {
+	FrameSize      uint32  `json:"frame_size"`
+	FramesPerSec   float64 `json:"frames_per_sec"`
+	TheoreticalFPS float64 `json:"theoretical_frames_per_sec"`
+	RatePct        float64 `json:"rate_pct"` // Of line rate
+	Mbps           float64 `json:"mbps"`
+	Trials         uint32  `json:"trials"` // Search trials run
+}
+
+// LatencyTrial is one repetition of the latency test
+type LatencyTrial struct {
+	MinUs float64 `json:"min_us"`
+	AvgUs float64 `json:"avg_us"`
+	MaxUs float64 `json:"max_us"`
+}
+
+// LatencyRow is Section 26.2's latency of one frame size: the average of
+// its repetitions at the throughput rate
+type LatencyRow struct {
+	FrameSize uint32         `json:"frame_size"`
+	RatePct   float64        `json:"rate_pct"` // The throughput, % of line rate
+	AvgUs     float64        `json:"avg_us"`   // Average of the trials' averages
+	StdDevUs  float64        `json:"stddev_us"`
+	MinUs     float64        `json:"min_us"`
+	MaxUs     float64        `json:"max_us"`
+	Trials    []LatencyTrial `json:"trials"`
+}
+
+// LossTrial is one offered load of the frame loss test
+type LossTrial struct {
+	OfferedPct float64 `json:"offered_pct"`
+	FramesTx   uint64  `json:"frames_tx"`
+	FramesRx   uint64  `json:"frames_rx"`
+	LossPct    float64 `json:"loss_pct"`
+}
+
+// FrameLossRow is Section 26.3's loss rate of one frame size at each load
+type FrameLossRow struct {
+	FrameSize      uint32      `json:"frame_size"`
+	LosslessTrials int         `json:"lossless_trials"` // Trials in a row without loss it ended with
+	Trials         []LossTrial `json:"trials"`          // Highest load first
+}
+
+// BackToBackRow is Section 26.4's longest lossless burst of one frame size
+type BackToBackRow struct {
+	FrameSize   uint32  `json:"frame_size"`
+	BurstFrames uint64  `json:"burst_frames"`
+	BurstUs     float64 `json:"burst_us"`
+	Trials      uint32  `json:"trials"` // Lossless repetitions each burst size needed
+}
+
+// RecoveryRow is Section 26.5's recovery time of one frame size
+type RecoveryRow struct {
+	FrameSize     uint32  `json:"frame_size"`
+	ThroughputPct float64 `json:"throughput_pct"` // Rate used as the throughput
+	OverloadPct   float64 `json:"overload_pct"`
+	OverloadSec   uint32  `json:"overload_sec"`
+	RecoveryMs    float64 `json:"recovery_ms"`
+	FramesLost    uint64  `json:"frames_lost"`
+}
+
+// ResetRow is Section 26.6's reset time, measured at one frame size
+type ResetRow struct {
+	FrameSize  uint32  `json:"frame_size"`
+	ResetType  string  `json:"reset_type"` // "manual" or "software"
+	ResetMs    float64 `json:"reset_ms"`
+	FramesLost uint64  `json:"frames_lost"`
+}
+
+// Per-section results
+type (
+	ThroughputSection struct {
+		Section
+		Rows []ThroughputRow `json:"rows"`
+	}
+	LatencySection struct {
+		Section
+		Definition string       `json:"definition"` // RFC 1242 latency definition used
+		Rows       []LatencyRow `json:"rows"`
+	}
+	FrameLossSection struct {
+		Section
+		Rows []FrameLossRow `json:"rows"`
+	}
+	BackToBackSection struct {
+		Section
+		Rows []BackToBackRow `json:"rows"`
+	}
+	RecoverySection struct {
+		Section
+		Rows []RecoveryRow `json:"rows"`
+	}
+	ResetSection struct {
+		Section
+		Rows []ResetRow `json:"rows"`
+	}
+)
+
+// LatencyDefinition is the RFC 1242 definition the tester's timestamps
+// measure: both are taken at the same point of the frame
+const LatencyDefinition = "RFC 1242 bit forwarding (FIFO): transmit to receive timestamp of each frame at the tester"
+
+// Report is a certification run keyed by RFC 2544 section
+type Report struct {
+	Parameters []Parameter `json:"parameters"`
+	Overrides  []Override  `json:"overrides,omitempty"` // Config settings the run replaced
+	FrameSizes []uint32    `json:"frame_sizes"`
+
+	Throughput ThroughputSection `json:"26.1"`
+	Latency    LatencySection    `json:"26.2"`
+	FrameLoss  FrameLossSection  `json:"26.3"`
+	BackToBack BackToBackSection `json:"26.4"`
+	Recovery   RecoverySection   `json:"26.5"`
+	Reset      ResetSection      `json:"26.6"`
+
+	Complete bool `json:"complete"` // Every section is
+
+	lineMbps         float64
+	backToBackTrials uint32
+}
+
+// New starts the report of a run of cfg, as Apply left it, at frameSizes.
+// lineMbps gives the theoretical rates; 0 derives them from the results.
+func New(cfg *config.Config, frameSizes []uint32, lineMbps float64, overrides []Override) *Report {
+	fl := cfg.FrameLoss
+	r := &Report{
+		Parameters: []Parameter{
+			{"9.1", "Frame sizes", fmt.Sprint(frameSizes)},
+			{"23", "Learning frames before each trial", cfg.WarmupPeriod.String()},
+			{"24", "Trial duration", cfg.TrialDuration.String()},
+			{"26.1", "Throughput search", fmt.Sprintf("binary, up to %d trials, zero loss", cfg.Throughput.MaxIterations)},
+			{"26.2", "Latency repetitions", fmt.Sprint(len(cfg.Latency.LoadLevels))},
+			{"26.3", "Frame loss steps", fmt.Sprintf("%g%% down from %g%% until %d trials in a row lose nothing", fl.StepPct, fl.StartPct, fl.LosslessTrials)},
+			{"26.4", "Back-to-back repetitions", fmt.Sprint(cfg.BackToBack.Trials)},
+			{"26.5", "Overload duration", (time.Duration(cfg.Recovery.OverloadSec) * time.Second).String()},
+		},
+		Overrides:        overrides,
+		FrameSizes:       frameSizes,
+		lineMbps:         lineMbps,
+		backToBackTrials: cfg.BackToBack.Trials,
+	}
+	r.Throughput.Section = Section{Title: "Throughput",
+		Procedure: fmt.Sprintf("Binary search of up to %d %v trials for the highest rate at which the DUT forwards every frame",
+			cfg.Throughput.MaxIterations, cfg.TrialDuration)}
+	r.Latency.Section = Section{Title: "Latency",
+		Procedure: fmt.Sprintf("%d %v trials at the throughput rate, reported as the average of their average latencies",
+			len(cfg.Latency.LoadLevels), cfg.TrialDuration)}
+	r.Latency.Definition = LatencyDefinition
+	r.FrameLoss.Section = Section{Title: "Frame loss rate",
+		Procedure: fmt.Sprintf("%v trials from %g%% of line rate down in steps of %g%% until %d in a row lose no frames",
+			cfg.TrialDuration, fl.StartPct, fl.StepPct, fl.LosslessTrials)}
+	r.BackToBack.Section = Section{Title: "Back-to-back frames",
+		Procedure: fmt.Sprintf("Longest burst at the minimum inter-frame gap that %d trials in a row forward without loss",
+			cfg.BackToBack.Trials)}
+	r.Recovery.Section = Section{Title: "System recovery",
+		Procedure: fmt.Sprintf("Overload above the throughput for %ds, then the load is reduced; recovery time runs from the reduction to the last lost frame",
+			cfg.Recovery.OverloadSec)}
+	r.Reset.Section = Section{Title: "Reset",
+		Procedure: "DUT reset during a trial at the throughput rate; reset time is the gap between the last frame before it and the first after"}
+	return r
+}
+
+// AddThroughput adds a frame size's throughput, filling in its theoretical
+// rate
+func (r *Report) AddThroughput(row ThroughputRow) {
+	switch {
+	case r.lineMbps > 0:
+		row.TheoreticalFPS = MaxFPS(r.lineMbps, row.FrameSize)
+	case row.RatePct > 0:
+		row.TheoreticalFPS = row.FramesPerSec * 100 / row.RatePct
+	}
+	r.Throughput.Rows = append(r.Throughput.Rows, row)
+}
+
+// AddLatency adds a frame size's latency trials, averaging them
+func (r *Report) AddLatency(frameSize uint32, ratePct float64, trials []LatencyTrial) {
+	if len(trials) == 0 {
+		return
+	}
+	row := LatencyRow{FrameSize: frameSize, RatePct: ratePct, MinUs: math.Inf(1), Trials: trials}
+	for _, t := range trials {
+		row.AvgUs += t.AvgUs / float64(len(trials))
+		row.MinUs = min(row.MinUs, t.MinUs)
+		row.MaxUs = max(row.MaxUs, t.MaxUs)
+	}
+	for _, t := range trials {
+		row.StdDevUs += (t.AvgUs - row.AvgUs) * (t.AvgUs - row.AvgUs) / float64(len(trials))
+	}
+	row.StdDevUs = math.Sqrt(row.StdDevUs)
+	r.Latency.Rows = append(r.Latency.Rows, row)
+}
+
+// AddFrameLoss adds a frame size's frame loss trials, highest load first
+func (r *Report) AddFrameLoss(frameSize uint32, trials []LossTrial) {
+	row := FrameLossRow{FrameSize: frameSize, Trials: trials}
+	for i := len(trials) - 1; i >= 0 && trials[i].LossPct == 0; i-- {
+		row.LosslessTrials++
+	}
+	r.FrameLoss.Rows = append(r.FrameLoss.Rows, row)
+}
+
+// AddBackToBack adds a frame size's back-to-back result
+func (r *Report) AddBackToBack(row BackToBackRow) {
+	r.BackToBack.Rows = append(r.BackToBack.Rows, row)
+}
+
+// AddRecovery adds a frame size's system recovery result
+func (r *Report) AddRecovery(row RecoveryRow) {
+	r.Recovery.Rows = append(r.Recovery.Rows, row)
+}
+
+// AddReset adds a reset measured at a frame size
+func (r *Report) AddReset(row ResetRow) {
+	r.Reset.Rows = append(r.Reset.Rows, row)
+}
+
+// Suite is the suite's results at one frame size, as the run stores them
+type Suite struct {
+	FrameSize     uint32
+	ThroughputPct float64 // Carried into latency and recovery
+	Throughput    *dataplane.ThroughputResultCLI
+	Latency       []dataplane.LatencyResultCLI
+	FrameLoss     []dataplane.FrameLossResultCLI
+	BackToBack    *dataplane.BackToBackResultCLI
+	Recovery      *dataplane.RecoveryResultCLI
+	Reset         *dataplane.ResetResultCLI
+	Errors        map[config.TestType]string // Tests that failed or were skipped
+}
+
+// sections are the suite's tests in Section 26 order
+var sections = []struct {
+	test config.TestType
+	id   string
+}{
+	{config.TestThroughput, "26.1"},
+	{config.TestLatency, "26.2"},
+	{config.TestFrameLoss, "26.3"},
+	{config.TestBackToBack, "26.4"},
+	{config.TestSystemRecovery, "26.5"},
+	{config.TestReset, "26.6"},
+}
+
+// AddSuite adds each test of a frame size's suite to its section, and the
+// tests that failed or were skipped as missing
+func (r *Report) AddSuite(s Suite) {
+	fs := s.FrameSize
+	if t := s.Throughput; t != nil {
+		r.AddThroughput(ThroughputRow{FrameSize: fs, FramesPerSec: t.MaxRatePPS, RatePct: t.MaxRatePct, Mbps: t.MaxRateMbps, Trials: t.Iterations})
+	}
+	if len(s.Latency) > 0 {
+		trials := make([]LatencyTrial, len(s.Latency))
+		for i, l := range s.Latency {
+			trials[i] = LatencyTrial{MinUs: l.Latency.MinNs / 1000, AvgUs: l.Latency.AvgNs / 1000, MaxUs: l.Latency.MaxNs / 1000}
+		}
+		r.AddLatency(fs, s.ThroughputPct, trials)
+	}
+	if s.FrameLoss != nil {
+		trials := make([]LossTrial, len(s.FrameLoss))
+		for i, l := range s.FrameLoss {
+			trials[i] = LossTrial{OfferedPct: l.OfferedPct, FramesTx: l.FramesTx, FramesRx: l.FramesRx, LossPct: l.LossPct}
+		}
+		r.AddFrameLoss(fs, trials)
+	}
+	// The result counts the burst sizes that passed, not the repetitions
+	// of each, which are the configured trials
+	if b := s.BackToBack; b != nil {
+		r.AddBackToBack(BackToBackRow{FrameSize: fs, BurstFrames: b.MaxBurstFrames, BurstUs: float64(b.BurstDurationUs), Trials: r.backToBackTrials})
+	}
+	if rec := s.Recovery; rec != nil {
+		r.AddRecovery(RecoveryRow{FrameSize: fs, ThroughputPct: s.ThroughputPct, OverloadPct: rec.OverloadRatePct,
+			OverloadSec: rec.OverloadSec, RecoveryMs: rec.RecoveryTimeMs, FramesLost: rec.FramesLost})
+	}
+	if rst := s.Reset; rst != nil {
+		resetType := "software"
+		if rst.ManualReset {
+			resetType = "manual"
+		}
+		r.AddReset(ResetRow{FrameSize: fs, ResetType: resetType, ResetMs: rst.ResetTimeMs, FramesLost: rst.FramesLost})
+	}
+	for _, sec := range sections {
+		if msg, ok := s.Errors[sec.test]; ok {
+			r.Missing(sec.id, fs, msg)
+		}
+	}
+}
+
+// Missing records why a section has no result at a frame size
+func (r *Report) Missing(section string, frameSize uint32, reason string) {
+	if s := r.section(section); s != nil {
+		s.note("%d bytes: %s", frameSize, reason)
+	}
+}
+
+func (r *Report) section(id string) *Section {
+	switch id {
+	case "26.1":
+		return &r.Throughput.Section
+	case "26.2":
+		return &r.Latency.Section
+	case "26.3":
+		return &r.FrameLoss.Section
+	case "26.4":
+		return &r.BackToBack.Section
+	case "26.5":
+		return &r.Recovery.Section
+	case "26.6":
+		return &r.Reset.Section
+	}
+	return nil
+}
+
+// Finish checks each section against what the RFC requires of it
+func (r *Report) Finish() {
+	covered := func(s *Section, sizes []uint32) {
+		for _, fs := range r.FrameSizes {
+			if !slices.Contains(sizes, fs) && !slices.ContainsFunc(s.Notes, func(n string) bool { return hasSize(n, fs) }) {
+				s.note("%d bytes: not measured", fs)
+			}
+		}
+	}
+	var sizes []uint32
+	for _, row := range r.Throughput.Rows {
+		sizes = append(sizes, row.FrameSize)
+	}
+	covered(&r.Throughput.Section, sizes)
+
+	sizes = nil
+	for _, row := range r.Latency.Rows {
+		sizes = append(sizes, row.FrameSize)
+		if len(row.Trials) < LatencyTrials {
+			r.Latency.note("%d bytes: %d of the %d trials required", row.FrameSize, len(row.Trials), LatencyTrials)
+		}
+	}
+	covered(&r.Latency.Section, sizes)
+
+	sizes = nil
+	for _, row := range r.FrameLoss.Rows {
+		sizes = append(sizes, row.FrameSize)
+		switch {
+		case len(row.Trials) == 0:
+			r.FrameLoss.note("%d bytes: no trials", row.FrameSize)
+		case row.LosslessTrials < LosslessTrials:
+			r.FrameLoss.note("%d bytes: no %d trials in a row without loss down to %g%%",
+				row.FrameSize, LosslessTrials, row.Trials[len(row.Trials)-1].OfferedPct)
+		}
+	}
+	covered(&r.FrameLoss.Section, sizes)
+
+	sizes = nil
+	for _, row := range r.BackToBack.Rows {
+		sizes = append(sizes, row.FrameSize)
+		if row.Trials < BackToBackTrials {
+			r.BackToBack.note("%d bytes: %d of the %d trials required", row.FrameSize, row.Trials, BackToBackTrials)
+		}
+	}
+	covered(&r.BackToBack.Section, sizes)
+
+	sizes = nil
+	for _, row := range r.Recovery.Rows {
+		sizes = append(sizes, row.FrameSize)
+		if row.OverloadSec < OverloadSec {
+			r.Recovery.note("%d bytes: overload of %ds, under the %ds required", row.FrameSize, row.OverloadSec, OverloadSec)
+		}
+	}
+	covered(&r.Recovery.Section, sizes)
+
+	// One reset of each type is enough; it need not repeat per frame size
+	if len(r.Reset.Rows) == 0 && len(r.Reset.Notes) == 0 {
+		r.Reset.note("not measured")
+	}
+
+	r.Complete = true
+	for _, sec := range sections {
+		s := r.section(sec.id)
+		s.Complete = len(s.Notes) == 0 || sec.id == "26.6" && len(r.Reset.Rows) > 0
+		r.Complete = r.Complete && s.Complete
+	}
+}
+
+// hasSize reports whether a note is about a frame size
+func hasSize(note string, frameSize uint32) bool {
+	prefix := fmt.Sprintf("%d bytes:", frameSize)
+	return len(note) >= len(prefix) && note[:len(prefix)] == prefix
+}
+
+// WriteText writes the report section by section, with what each one
+// measured at every frame size and where it fell short
+func (r *Report) WriteText(w io.Writer) error {
+	fmt.Fprintf(w, "RFC 2544 Certification (Section 26): %s\n", completeness(r.Complete))
+	for _, o := range r.Overrides {
+		fmt.Fprintf(w, "  Replaced %s\n", o)
+	}
+	section := func(id string, s Section) {
+		fmt.Fprintf(w, "\n  %s %s: %s\n", id, s.Title, completeness(s.Complete))
+		fmt.Fprintf(w, "    %s\n", s.Procedure)
+	}
+	notes := func(s Section) {
+		for _, n := range s.Notes {
+			fmt.Fprintf(w, "    [INCOMPLETE] %s\n", n)
+		}
+	}
+
+	section("26.1", r.Throughput.Section)
+	for _, row := range r.Throughput.Rows {
+		fmt.Fprintf(w, "    %5d bytes: %.0f fps of %.0f theoretical (%.2f%%, %.2f Mbps), %d trials\n",
+			row.FrameSize, row.FramesPerSec, row.TheoreticalFPS, row.RatePct, row.Mbps, row.Trials)
+	}
+	notes(r.Throughput.Section)
+	section("26.2", r.Latency.Section)
+	for _, row := range r.Latency.Rows {
+		fmt.Fprintf(w, "    %5d bytes at %.2f%%: avg %.2f us (stddev %.2f, min %.2f, max %.2f) over %d trials\n",
+			row.FrameSize, row.RatePct, row.AvgUs, row.StdDevUs, row.MinUs, row.MaxUs, len(row.Trials))
+	}
+	notes(r.Latency.Section)
+	section("26.3", r.FrameLoss.Section)
+	for _, row := range r.FrameLoss.Rows {
+		loads := make([]string, len(row.Trials))
+		for i, t := range row.Trials {
+			loads[i] = fmt.Sprintf("%g%%: %.3f%%", t.OfferedPct, t.LossPct)
+		}
+		fmt.Fprintf(w, "    %5d bytes: %s\n", row.FrameSize, strings.Join(loads, ", "))
+	}
+	notes(r.FrameLoss.Section)
+	section("26.4", r.BackToBack.Section)
+	for _, row := range r.BackToBack.Rows {
+		fmt.Fprintf(w, "    %5d bytes: %d frames (%.0f us)\n", row.FrameSize, row.BurstFrames, row.BurstUs)
+	}
+	notes(r.BackToBack.Section)
+	section("26.5", r.Recovery.Section)
+	for _, row := range r.Recovery.Rows {
+		fmt.Fprintf(w, "    %5d bytes: %.2f ms after %ds at %.2f%% (throughput %.2f%%), %d frames lost\n",
+			row.FrameSize, row.RecoveryMs, row.OverloadSec, row.OverloadPct, row.ThroughputPct, row.FramesLost)
+	}
+	notes(r.Recovery.Section)
+	section("26.6", r.Reset.Section)
+	for _, row := range r.Reset.Rows {
+		fmt.Fprintf(w, "    %5d bytes: %s reset, %.2f ms, %d frames lost\n", row.FrameSize, row.ResetType, row.ResetMs, row.FramesLost)
+	}
+	notes(r.Reset.Section)
+	return nil
+}
+
+func completeness(complete bool) string {
+	if complete {
+		return "COMPLETE"
+	}
+	return "INCOMPLETE"
+}
@@ -0,0 +1,208 @@
+package certify
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/krisarmstrong/rfc2544-master/pkg/config"
+	"github.com/krisarmstrong/rfc2544-master/pkg/dataplane"
+)
+
+func TestApply(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Interface = "eth0"
+	cfg.FrameSize = 512
+	cfg.TrialDuration = 10 * time.Second
+	cfg.Throughput.AcceptableLoss = 0.1
+	cfg.Throughput.MaxIterations = 8
+	cfg.FrameLoss.StepPct = 20
+	cfg.BackToBack.Trials = 100
+	cfg.Recovery.OverloadSec = 10
+
+	got := make(map[string]Override)
+	for _, o := range Apply(cfg) {
+		got[o.Setting] = o
+	}
+	for _, setting := range []string{"frame_size", "trial_duration", "throughput.acceptable_loss",
+		"throughput.max_iterations", "latency.load_levels", "frame_loss.step_pct", "frame_loss.lossless_trials", "recovery.overload_sec"} {
+		if _, ok := got[setting]; !ok {
+			t.Errorf("no override of %s in %v", setting, got)
+		}
+	}
+	if o := got["trial_duration"]; o.From != "10s" || o.To != "1m0s" {
+		t.Errorf("trial duration override %+v", o)
+	}
+	// More repetitions than the RFC asks for are kept
+	if _, ok := got["back_to_back.trials"]; ok || cfg.BackToBack.Trials != 100 {
+		t.Errorf("back-to-back trials %d", cfg.BackToBack.Trials)
+	}
+
+	if cfg.TestType != config.TestSuite || len(cfg.Latency.LoadLevels) != LatencyTrials || cfg.FrameLoss.LosslessTrials != LosslessTrials {
+		t.Errorf("config %s, %d latency loads, %d lossless trials", cfg.TestType, len(cfg.Latency.LoadLevels), cfg.FrameLoss.LosslessTrials)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("validate: %v", err)
+	}
+	if devs := cfg.Deviations(); len(devs) != 0 {
+		t.Errorf("deviations after Apply: %v", devs)
+	}
+	if again := Apply(cfg); len(again) != 0 {
+		t.Errorf("second Apply changed %v", again)
+	}
+}
+
+// TestApplySections checks each section's required settings against a
+// config that starts short of all of them
+func TestApplySections(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Interface = "eth0"
+	cfg.TrialDuration = 2 * time.Second
+	cfg.WarmupPeriod = 0
+	cfg.Throughput.SearchAlgorithm = config.SearchLinear
+	cfg.Throughput.MaxIterations = 5
+	cfg.Throughput.AcceptableLoss = 0.5
+	cfg.Latency.LoadLevels = []float64{50, 100}
+	cfg.FrameLoss.StartPct = 80
+	cfg.FrameLoss.StepPct = 25
+	cfg.FrameLoss.SearchThresholdPct = 1
+	cfg.BackToBack.Trials = 5
+	cfg.Recovery.OverloadSec = 5
+	Apply(cfg)
+
+	tp, fl := cfg.Throughput, cfg.FrameLoss
+	for _, c := range []struct {
+		section string
+		ok      bool
+	}{
+		{"24", cfg.TrialDuration == TrialDuration},
+		{"23", cfg.WarmupPeriod > 0},
+		{"26.1", tp.SearchAlgorithm == config.SearchBinary && tp.MaxIterations == SearchIterations && tp.AcceptableLoss == 0},
+		{"26.2", len(cfg.Latency.LoadLevels) == LatencyTrials && cfg.Latency.LoadLevels[0] == 100},
+		{"26.3", fl.StartPct == 100 && fl.StepPct == FrameLossStepPct && fl.SearchThresholdPct == 0 && fl.LosslessTrials == LosslessTrials},
+		{"26.4", cfg.BackToBack.Trials == BackToBackTrials},
+		{"26.5", cfg.Recovery.OverloadSec == OverloadSec},
+	} {
+		if !c.ok {
+			t.Errorf("section %s settings not applied: %+v", c.section, cfg)
+		}
+	}
+}
+
+func TestReport(t *testing.T) {
+	cfg := config.DefaultConfig()
+	Apply(cfg)
+	r := New(cfg, []uint32{64, 1518}, 1000, nil)
+
+	r.AddThroughput(ThroughputRow{FrameSize: 64, FramesPerSec: 1488095, RatePct: 100, Mbps: 1000, Trials: 1})
+	r.AddThroughput(ThroughputRow{FrameSize: 1518, FramesPerSec: 81274, RatePct: 100, Mbps: 1000, Trials: 1})
+	if fps := r.Throughput.Rows[0].TheoreticalFPS; math.Abs(fps-1488095.2) > 0.1 {
+		t.Errorf("theoretical %.1f fps", fps)
+	}
+
+	trials := make([]LatencyTrial, LatencyTrials)
+	for i := range trials {
+		trials[i] = LatencyTrial{MinUs: 1, AvgUs: float64(9 + 2*(i%2)), MaxUs: 20}
+	}
+	r.AddLatency(64, 100, trials)
+	r.AddLatency(1518, 100, trials[:5])
+	if row := r.Latency.Rows[0]; row.AvgUs != 10 || row.StdDevUs != 1 || row.MinUs != 1 || row.MaxUs != 20 {
+		t.Errorf("latency row %+v", row)
+	}
+
+	r.AddFrameLoss(64, []LossTrial{{OfferedPct: 100, LossPct: 3}, {OfferedPct: 90}, {OfferedPct: 80}})
+	r.AddFrameLoss(1518, []LossTrial{{OfferedPct: 100, LossPct: 1}, {OfferedPct: 90}, {OfferedPct: 80, LossPct: 1}})
+	r.AddBackToBack(BackToBackRow{FrameSize: 64, BurstFrames: 1000, Trials: BackToBackTrials})
+	r.AddBackToBack(BackToBackRow{FrameSize: 1518, BurstFrames: 1000, Trials: BackToBackTrials})
+	r.AddRecovery(RecoveryRow{FrameSize: 64, ThroughputPct: 100, OverloadSec: OverloadSec})
+	r.Missing("26.5", 1518, "skipped: no throughput measured")
+	r.AddReset(ResetRow{FrameSize: 64, ResetType: "manual", ResetMs: 5000})
+	r.Missing("26.6", 1518, "reset not triggered")
+	r.Finish()
+
+	if !r.Throughput.Complete || !r.BackToBack.Complete || !r.Reset.Complete {
+		t.Errorf("complete sections: %+v %+v %+v", r.Throughput.Section, r.BackToBack.Section, r.Reset.Section)
+	}
+	for _, s := range []Section{r.Latency.Section, r.FrameLoss.Section, r.Recovery.Section} {
+		if s.Complete || len(s.Notes) != 1 || !strings.HasPrefix(s.Notes[0], "1518 bytes:") {
+			t.Errorf("%s: complete %v, notes %q", s.Title, s.Complete, s.Notes)
+		}
+	}
+	if r.Complete || r.FrameLoss.Rows[0].LosslessTrials != 2 || r.FrameLoss.Rows[1].LosslessTrials != 0 {
+		t.Errorf("report complete %v, frame loss %+v", r.Complete, r.FrameLoss.Rows)
+	}
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keyed map[string]json.RawMessage
+	json.Unmarshal(data, &keyed)
+	for _, section := range []string{"26.1", "26.2", "26.3", "26.4", "26.5", "26.6"} {
+		if _, ok := keyed[section]; !ok {
+			t.Errorf("report has no section %s", section)
+		}
+	}
+}
+
+func TestReportNotMeasured(t *testing.T) {
+	cfg := config.DefaultConfig()
+	Apply(cfg)
+	r := New(cfg, []uint32{64}, 0, nil)
+	r.AddThroughput(ThroughputRow{FrameSize: 64, FramesPerSec: 50, RatePct: 50})
+	r.Finish()
+
+	if fps := r.Throughput.Rows[0].TheoreticalFPS; fps != 100 {
+		t.Errorf("theoretical rate from the result %.1f fps", fps)
+	}
+	if r.Complete || r.Reset.Complete || r.Latency.Notes[0] != "64 bytes: not measured" {
+		t.Errorf("latency notes %q, reset %+v", r.Latency.Notes, r.Reset.Section)
+	}
+}
+
+func TestAddSuite(t *testing.T) {
+	cfg := config.DefaultConfig()
+	Apply(cfg)
+	r := New(cfg, []uint32{64, 128}, 1000, nil)
+	latency := make([]dataplane.LatencyResultCLI, LatencyTrials)
+	for i := range latency {
+		latency[i].Latency = dataplane.LatencyStats{MinNs: 1000, AvgNs: 2000, MaxNs: 3000}
+	}
+	r.AddSuite(Suite{
+		FrameSize:     64,
+		ThroughputPct: 90,
+		Throughput:    &dataplane.ThroughputResultCLI{MaxRatePct: 90, MaxRatePPS: 1339285, Iterations: 12},
+		Latency:       latency,
+		FrameLoss:     []dataplane.FrameLossResultCLI{{OfferedPct: 100, LossPct: 10}, {OfferedPct: 90}, {OfferedPct: 80}},
+		BackToBack:    &dataplane.BackToBackResultCLI{MaxBurstFrames: 5000},
+		Recovery:      &dataplane.RecoveryResultCLI{OverloadSec: OverloadSec, RecoveryTimeMs: 12},
+		Reset:         &dataplane.ResetResultCLI{ManualReset: true, ResetTimeMs: 4000},
+	})
+	r.AddSuite(Suite{FrameSize: 128, Errors: map[config.TestType]string{config.TestThroughput: "link down"}})
+	r.Finish()
+
+	if row := r.Latency.Rows[0]; math.Abs(row.AvgUs-2) > 1e-9 || row.MinUs != 1 || row.RatePct != 90 {
+		t.Errorf("latency row %+v", row)
+	}
+	if row := r.BackToBack.Rows[0]; row.Trials != cfg.BackToBack.Trials {
+		t.Errorf("back-to-back trials %d, want the configured %d", row.Trials, cfg.BackToBack.Trials)
+	}
+	if row := r.Reset.Rows[0]; row.ResetType != "manual" {
+		t.Errorf("reset row %+v", row)
+	}
+	if notes := r.Throughput.Notes; len(notes) != 1 || notes[0] != "128 bytes: link down" {
+		t.Errorf("throughput notes %q", notes)
+	}
+
+	var b strings.Builder
+	if err := r.WriteText(&b); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Certification (Section 26): INCOMPLETE", "26.1 Throughput: INCOMPLETE", "[INCOMPLETE] 128 bytes: link down", "manual reset"} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("text report has no %q:\n%s", want, b.String())
+		}
+	}
+}
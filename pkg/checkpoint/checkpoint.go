@@ -0,0 +1,156 @@
+// Package checkpoint saves the progress of a multi-frame-size run
+//
+// The state file is rewritten after every completed frame size and every
+// throughput search iteration, so a run that dies loses at most one trial.
+// A resumed run skips the frame sizes already completed and continues the
+// interrupted search. Results are kept as raw JSON; the caller decodes them
+// into its own result types.
+package checkpoint
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Version is the state file format version
+const Version = 1
+
+// Run identifies the run a state file belongs to
+type Run struct {
+	TestType   string   `json:"test_type"`
+	Target     string   `json:"target"` // Interface or TRex server
+	FrameSizes []uint32 `json:"frame_sizes"`
+}
+
+// Frame is a completed frame size and everything reported for it
+type Frame struct {
+	FrameSize    uint32          `json:"frame_size"`
+	Result       json.RawMessage `json:"result"`
+	Modifier     json.RawMessage `json:"modifier,omitempty"`
+	ControlPlane json.RawMessage `json:"control_plane,omitempty"`
+	Power        json.RawMessage `json:"power,omitempty"`
+}
+
+// Progress is the frame size in progress and its throughput search state
+type Progress struct {
+	FrameSize uint32          `json:"frame_size"`
+	Search    json.RawMessage `json:"search"`
+}
+
+// State is the content of a state file
+type State struct {
+	Version   int       `json:"version"`
+	Run       Run       `json:"run"`
+	Updated   time.Time `json:"updated"`
+	Completed []Frame   `json:"completed"`
+	Current   *Progress `json:"current,omitempty"`
+
+	path string
+}
+
+// New creates the state file for a new run
+func New(path string, run Run) (*State, error) {
+	s := &State{Version: Version, Run: run, Completed: []Frame{}, path: path}
+	if err := s.save(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Load reads a state file written by an earlier run
+func Load(path string) (*State, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+	var s State
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse state file %s: %w", path, err)
+	}
+	if s.Version != Version {
+		return nil, fmt.Errorf("state file %s has version %d, want %d", path, s.Version, Version)
+	}
+	s.path = path
+	return &s, nil
+}
+
+// Path returns the state file's path
+func (s *State) Path() string {
+	return s.path
+}
+
+// Check reports whether the state file belongs to run
+func (s *State) Check(run Run) error {
+	if s.Run.TestType != run.TestType || s.Run.Target != run.Target {
+		return fmt.Errorf("state file is for a %s run on %s, not %s on %s",
+			s.Run.TestType, s.Run.Target, run.TestType, run.Target)
+	}
+	if fmt.Sprint(s.Run.FrameSizes) != fmt.Sprint(run.FrameSizes) {
+		return fmt.Errorf("state file is for frame sizes %v, not %v", s.Run.FrameSizes, run.FrameSizes)
+	}
+	return nil
+}
+
+// Frame returns the completed frame size, or nil if it has not completed
+func (s *State) Frame(frameSize uint32) *Frame {
+	for i := range s.Completed {
+		if s.Completed[i].FrameSize == frameSize {
+			return &s.Completed[i]
+		}
+	}
+	return nil
+}
+
+// Complete records a finished frame size, ending its search
+func (s *State) Complete(f Frame) error {
+	s.Completed = append(s.Completed, f)
+	s.Current = nil
+	return s.save()
+}
+
+// Progress records the search state of the frame size in progress
+func (s *State) Progress(frameSize uint32, search interface{}) error {
+	data, err := json.Marshal(search)
+	if err != nil {
+		return err
+	}
+	s.Current = &Progress{FrameSize: frameSize, Search: data}
+	return s.save()
+}
+
+// Search decodes the saved search state for frameSize into v, reporting
+// whether there was one
+func (s *State) Search(frameSize uint32, v interface{}) (bool, error) {
+	if s.Current == nil || s.Current.FrameSize != frameSize {
+		return false, nil
+	}
+	if err := json.Unmarshal(s.Current.Search, v); err != nil {
+		return false, fmt.Errorf("invalid search state: %w", err)
+	}
+	return true, nil
+}
+
+// Remove deletes the state file once the run has completed
+func (s *State) Remove() error {
+	return os.Remove(s.path)
+}
+
+// save writes the state through a temporary file so that a crash mid-write
+// leaves the previous state intact
+func (s *State) save() error {
+	s.Updated = time.Now().UTC()
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package checkpoint
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+)
+
+type search struct {
+	LowPct     float64
+	HighPct    float64
+	Iterations uint32
+}
+
+func TestResume(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.state.json")
+	run := Run{TestType: "throughput", Target: "eth0", FrameSizes: []uint32{64, 128, 256}}
+
+	s, err := New(path, run)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Complete(Frame{FrameSize: 64, Result: json.RawMessage(`{"MaxRatePct":95}`)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Progress(128, search{LowPct: 50, HighPct: 75, Iterations: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := loaded.Check(run); err != nil {
+		t.Fatal(err)
+	}
+	var result struct{ MaxRatePct float64 }
+	if f := loaded.Frame(64); f == nil {
+		t.Error("Frame(64) should be completed")
+	} else if err := json.Unmarshal(f.Result, &result); err != nil || result.MaxRatePct != 95 {
+		t.Errorf("Frame(64) result = %s", f.Result)
+	}
+	if f := loaded.Frame(128); f != nil {
+		t.Errorf("Frame(128) = %+v, want in progress", f)
+	}
+
+	var got search
+	if ok, err := loaded.Search(256, &got); ok || err != nil {
+		t.Errorf("Search(256) = %v, %v, want none", ok, err)
+	}
+	if ok, err := loaded.Search(128, &got); !ok || err != nil {
+		t.Fatalf("Search(128) = %v, %v", ok, err)
+	}
+	if got != (search{LowPct: 50, HighPct: 75, Iterations: 2}) {
+		t.Errorf("search = %+v", got)
+	}
+
+	// Completing the frame size ends its search
+	if err := loaded.Complete(Frame{FrameSize: 128, Result: json.RawMessage(`{}`)}); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := loaded.Search(128, &got); ok {
+		t.Error("Search should be cleared by Complete")
+	}
+
+	if err := loaded.Remove(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("Expected error loading a removed state file")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	s := &State{Run: Run{TestType: "throughput", Target: "eth0", FrameSizes: []uint32{64, 128}}}
+
+	tests := []Run{
+		{TestType: "latency", Target: "eth0", FrameSizes: []uint32{64, 128}},
+		{TestType: "throughput", Target: "eth1", FrameSizes: []uint32{64, 128}},
+		{TestType: "throughput", Target: "eth0", FrameSizes: []uint32{64}},
+	}
+	for _, run := range tests {
+		if err := s.Check(run); err == nil {
+			t.Errorf("Check(%+v) should fail", run)
+		}
+	}
+}
+
+func TestLoadVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.state.json")
+	s, err := New(path, Run{TestType: "throughput"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Version = Version + 1
+	if err := s.save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("Expected error for a newer state file version")
+	}
+}
@@ -0,0 +1,463 @@
+// Package compare builds side-by-side comparison matrices from saved runs
+//
+// Each run is a JSON results file written with -o json, or another tester's
+// export the importer understands. Results are read
+// generically, so any result type compares: numeric fields become metrics,
+// keyed by the result kind and any load dimension (offered rate, service),
+// and rows are matched across runs by metric and frame size. Runs are
+// labelled by the DUT metadata they were tagged with, for vendor bake-offs.
+package compare
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/krisarmstrong/rfc2544-master/pkg/config"
+	"github.com/krisarmstrong/rfc2544-master/pkg/importer"
+	"github.com/krisarmstrong/rfc2544-master/pkg/redact"
+)
+
+// Run is one loaded results file
+type Run struct {
+	Label    string            `json:"label"`
+	Source   string            `json:"source"`
+	TestType string            `json:"test_type,omitempty"`
+	DUT      *config.DUTConfig `json:"dut,omitempty"`
+
+	results []interface{}
+}
+
+// Better indicates which direction of a metric is an improvement
+type Better int
+
+// Metric directions
+const (
+	BetterUnknown Better = iota
+	BetterHigher
+	BetterLower
+)
+
+// Row is one metric at one frame size across all runs
+type Row struct {
+	Metric    string     `json:"metric"`
+	FrameSize uint32     `json:"frame_size,omitempty"`
+	Values    []*float64 `json:"values"` // One per run, nil when missing
+	Best      int        `json:"best"`   // Index of the best run, -1 if undetermined
+}
+
+// Matrix is the comparison report
+type Matrix struct {
+	Runs []Run `json:"runs"`
+	Rows []Row `json:"rows"`
+}
+
+// Load reads a JSON results file. Other testers' exports are converted
+// with the importer, so mixed-tool runs compare directly.
+func Load(path string) (*Run, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if !importer.IsNative(data) {
+		report, err := importer.Read(path, data, importer.Options{})
+		if err != nil {
+			return nil, err
+		}
+		if data, err = json.Marshal(report); err != nil {
+			return nil, err
+		}
+	}
+	var doc struct {
+		Metadata struct {
+			TestType string            `json:"test_type"`
+			DUT      *config.DUTConfig `json:"dut"`
+		} `json:"metadata"`
+		Results []interface{} `json:"results"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	run := &Run{
+		Source:   path,
+		TestType: doc.Metadata.TestType,
+		DUT:      doc.Metadata.DUT,
+		results:  doc.Results,
+	}
+	if run.DUT != nil {
+		run.Label = run.DUT.Label()
+	}
+	if run.Label == "" {
+		run.Label = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	return run, nil
+}
+
+// Build matches metrics across runs. Duplicate labels are made unique by
+// appending the source file name.
+func Build(runs []*Run) *Matrix {
+	m := &Matrix{}
+	seen := make(map[string]int)
+	for _, r := range runs {
+		seen[r.Label]++
+	}
+	for _, r := range runs {
+		if seen[r.Label] > 1 {
+			r.Label = fmt.Sprintf("%s (%s)", r.Label, filepath.Base(r.Source))
+		}
+		m.Runs = append(m.Runs, *r)
+	}
+
+	type key struct {
+		metric string
+		fs     uint32
+	}
+	index := make(map[key]int)
+	order := make(map[string]int) // Metric first-appearance order
+	for i, r := range runs {
+		for _, s := range samples(r.results) {
+			k := key{s.metric, s.frameSize}
+			row, ok := index[k]
+			if !ok {
+				row = len(m.Rows)
+				index[k] = row
+				m.Rows = append(m.Rows, Row{Metric: s.metric, FrameSize: s.frameSize, Values: make([]*float64, len(runs))})
+				if _, ok := order[s.metric]; !ok {
+					order[s.metric] = len(order)
+				}
+			}
+			v := s.value
+			m.Rows[row].Values[i] = &v
+		}
+	}
+
+	sort.SliceStable(m.Rows, func(a, b int) bool {
+		ra, rb := m.Rows[a], m.Rows[b]
+		if order[ra.Metric] != order[rb.Metric] {
+			return order[ra.Metric] < order[rb.Metric]
+		}
+		return ra.FrameSize < rb.FrameSize
+	})
+	for i := range m.Rows {
+		m.Rows[i].Best = best(m.Rows[i])
+	}
+	return m
+}
+
+// Redact hides the run labels, source files and DUT names before the
+// matrix is shared. Labels built from vendor and model alone are kept; in
+// strip mode the others become "run N" so the columns stay distinct.
+func (m *Matrix) Redact(r *redact.Redactor) {
+	for i := range m.Runs {
+		run := &m.Runs[i]
+		switch {
+		case run.DUT != nil && run.DUT.Name == "" && run.DUT.Label() == run.Label:
+		case r.Mode() == redact.ModeStrip:
+			run.Label = fmt.Sprintf("run %d", i+1)
+		default:
+			run.Label = r.Host(run.Label)
+		}
+		run.Source = r.Host(run.Source)
+		if run.DUT != nil {
+			dut := *run.DUT
+			dut.Name = r.Host(dut.Name)
+			run.DUT = &dut
+		}
+	}
+}
+
+// sample is one numeric value extracted from a result
+type sample struct {
+	metric    string
+	frameSize uint32
+	value     float64
+}
+
+// Fields identifying a result rather than measuring it
+var identityFields = map[string]bool{
+	"FrameSize": true, "frame_size": true,
+}
+
+// Fields splitting one frame size into several measurements
+var dimensionFields = []string{"LoadPct", "OfferedPct", "ServiceID", "rate_pct"}
+
+// kinds identifies result types by a field only they carry, most specific
+// first
+var kinds = []struct{ field, kind string }{
+	{"RateMbps", "rfc2889_forwarding"},
+	{"ServicePass", "y1564_config"},
+	{"DurationSec", "y1564_perf"},
+	{"drift", "soak"},
+	{"MaxRatePct", "throughput"},
+	{"MaxRateMbps", "throughput"}, // Imported results may lack a % rate
+	{"MaxRatePPS", "throughput"},
+	{"LoadPct", "latency"},
+	{"OfferedPct", "frame_loss"},
+	{"MaxBurstFrames", "back_to_back"},
+	{"RecoveryTimeMs", "recovery"},
+	{"ResetTimeMs", "reset"},
+}
+
+func samples(results []interface{}) []sample {
+	var out []sample
+	for _, r := range results {
+		switch v := r.(type) {
+		case map[string]interface{}:
+			out = append(out, entrySamples(v)...)
+		case []interface{}:
+			for _, e := range v {
+				if obj, ok := e.(map[string]interface{}); ok {
+					out = append(out, entrySamples(obj)...)
+				}
+			}
+		}
+	}
+	return out
+}
+
+func entrySamples(obj map[string]interface{}) []sample {
+	kind := "result"
+	for _, k := range kinds {
+		if _, ok := obj[k.field]; ok {
+			kind = k.kind
+			break
+		}
+	}
+	prefix := kind
+	for _, d := range dimensionFields {
+		if v, ok := obj[d].(float64); ok {
+			prefix += fmt.Sprintf("[%s=%s]", d, strconv.FormatFloat(v, 'f', -1, 64))
+		}
+	}
+
+	var fs uint32
+	for f := range identityFields {
+		if v, ok := obj[f].(float64); ok {
+			fs = uint32(v)
+		}
+	}
+
+	skip := make(map[string]bool)
+	for _, d := range dimensionFields {
+		skip[d] = true
+	}
+	var out []sample
+	flatten(obj, prefix, skip, func(metric string, v float64) {
+		out = append(out, sample{metric: metric, frameSize: fs, value: v})
+	})
+	return out
+}
+
+// flatten walks nested objects, reporting numeric leaves as prefix.a.b.
+// Arrays are skipped; they have no stable identity across runs.
+func flatten(obj map[string]interface{}, prefix string, skip map[string]bool, emit func(string, float64)) {
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if identityFields[k] || skip[k] {
+			continue
+		}
+		name := prefix + "." + k
+		switch v := obj[k].(type) {
+		case float64:
+			emit(name, v)
+		case map[string]interface{}:
+			flatten(v, name, nil, emit)
+		}
+	}
+}
+
+// Direction returns whether larger values of a metric are better
+func Direction(metric string) Better {
+	name := metric
+	if i := strings.LastIndex(metric, "."); i >= 0 {
+		name = metric[i+1:]
+	}
+	lower := strings.ToLower(name)
+	switch {
+	case strings.Contains(lower, "burst"):
+		return BetterHigher
+	case strings.Contains(lower, "loss"), strings.Contains(lower, "lost"),
+		strings.Contains(lower, "jitter"), strings.Contains(lower, "drift"),
+		hasUnitSuffix(name, "Ns", "Ms", "Us"):
+		return BetterLower
+	case strings.Contains(lower, "rate"), strings.Contains(lower, "mbps"),
+		strings.Contains(lower, "pps"), strings.Contains(lower, "fps"):
+		return BetterHigher
+	}
+	return BetterUnknown
+}
+
+// hasUnitSuffix matches CamelCase (AvgNs) and snake_case (avg_ns) units
+func hasUnitSuffix(name string, units ...string) bool {
+	for _, u := range units {
+		if strings.HasSuffix(name, u) || strings.HasSuffix(name, "_"+strings.ToLower(u)) {
+			return true
+		}
+	}
+	return false
+}
+
+// best returns the index of the best value, or -1 if the direction is
+// unknown, fewer than two runs have values, or all values tie
+func best(r Row) int {
+	dir := Direction(r.Metric)
+	if dir == BetterUnknown {
+		return -1
+	}
+	idx, count, tie := -1, 0, true
+	for i, v := range r.Values {
+		if v == nil {
+			continue
+		}
+		count++
+		if idx < 0 {
+			idx = i
+			continue
+		}
+		cur := *r.Values[idx]
+		if *v != cur {
+			tie = false
+		}
+		if (dir == BetterHigher && *v > cur) || (dir == BetterLower && *v < cur) {
+			idx = i
+		}
+	}
+	if count < 2 || tie {
+		return -1
+	}
+	return idx
+}
+
+// DeltaPct returns the change of run i relative to the first run, or NaN
+func (r Row) DeltaPct(i int) float64 {
+	if r.Values[0] == nil || r.Values[i] == nil || *r.Values[0] == 0 {
+		return math.NaN()
+	}
+	return (*r.Values[i] - *r.Values[0]) / *r.Values[0] * 100
+}
+
+// WriteText prints the matrix as an aligned table, marking the best run
+// with '*' and showing changes relative to the first run
+func (m *Matrix) WriteText(w io.Writer) error {
+	headers := []string{"Metric", "Frame"}
+	for _, r := range m.Runs {
+		headers = append(headers, r.Label)
+	}
+	table := [][]string{headers}
+	for _, row := range m.Rows {
+		cells := []string{row.Metric, frameCell(row.FrameSize)}
+		for i, v := range row.Values {
+			cell := "-"
+			if v != nil {
+				cell = formatValue(*v)
+				if d := row.DeltaPct(i); i > 0 && !math.IsNaN(d) {
+					cell += fmt.Sprintf(" (%+.1f%%)", d)
+				}
+				if row.Best == i {
+					cell += " *"
+				}
+			}
+			cells = append(cells, cell)
+		}
+		table = append(table, cells)
+	}
+
+	widths := make([]int, len(headers))
+	for _, cells := range table {
+		for i, c := range cells {
+			if len(c) > widths[i] {
+				widths[i] = len(c)
+			}
+		}
+	}
+	for n, cells := range table {
+		var sb strings.Builder
+		for i, c := range cells {
+			if i > 0 {
+				sb.WriteString("  ")
+			}
+			if i < 2 {
+				fmt.Fprintf(&sb, "%-*s", widths[i], c)
+			} else {
+				fmt.Fprintf(&sb, "%*s", widths[i], c)
+			}
+		}
+		if _, err := fmt.Fprintln(w, strings.TrimRight(sb.String(), " ")); err != nil {
+			return err
+		}
+		if n == 0 {
+			total := len(widths)*2 - 2
+			for _, wd := range widths {
+				total += wd
+			}
+			fmt.Fprintln(w, strings.Repeat("-", total))
+		}
+	}
+	_, err := fmt.Fprintln(w, "\n* best value; percentages are relative to", m.Runs[0].Label)
+	return err
+}
+
+// WriteCSV writes one line per row with a column per run
+func (m *Matrix) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	headers := []string{"metric", "frame_size"}
+	for _, r := range m.Runs {
+		headers = append(headers, r.Label)
+	}
+	headers = append(headers, "best")
+	if err := cw.Write(headers); err != nil {
+		return err
+	}
+	for _, row := range m.Rows {
+		rec := []string{row.Metric, strconv.FormatUint(uint64(row.FrameSize), 10)}
+		for _, v := range row.Values {
+			if v == nil {
+				rec = append(rec, "")
+			} else {
+				rec = append(rec, strconv.FormatFloat(*v, 'f', -1, 64))
+			}
+		}
+		bestLabel := ""
+		if row.Best >= 0 {
+			bestLabel = m.Runs[row.Best].Label
+		}
+		if err := cw.Write(append(rec, bestLabel)); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteJSON writes the matrix as indented JSON
+func (m *Matrix) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(m)
+}
+
+func frameCell(fs uint32) string {
+	if fs == 0 {
+		return "-"
+	}
+	return strconv.FormatUint(uint64(fs), 10)
+}
+
+func formatValue(v float64) string {
+	if v == math.Trunc(v) && math.Abs(v) < 1e15 {
+		return strconv.FormatFloat(v, 'f', 0, 64)
+	}
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
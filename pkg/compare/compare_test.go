@@ -0,0 +1,208 @@
+package compare
+
+import (
+	"bytes"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/krisarmstrong/rfc2544-master/pkg/redact"
+)
+
+const runA = `{
+  "metadata": {"test_type": "throughput", "dut": {"name": "vendor-a"}},
+  "results": [
+    {"FrameSize": 64, "MaxRatePct": 95.5, "MaxRateMbps": 955, "Iterations": 8, "Latency": {"AvgNs": 1200}},
+    {"FrameSize": 1518, "MaxRatePct": 100, "MaxRateMbps": 1000, "Iterations": 7, "Latency": {"AvgNs": 3000}},
+    [{"FrameSize": 64, "LoadPct": 90, "Latency": {"AvgNs": 1100}}]
+  ]
+}`
+
+const runB = `{
+  "metadata": {"test_type": "throughput", "dut": {"vendor": "Acme", "model": "X1"}},
+  "results": [
+    {"FrameSize": 64, "MaxRatePct": 99, "MaxRateMbps": 990, "Iterations": 9, "Latency": {"AvgNs": 900}}
+  ]
+}`
+
+func writeRun(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func loadRuns(t *testing.T, contents ...string) []*Run {
+	t.Helper()
+	dir := t.TempDir()
+	var runs []*Run
+	for i, c := range contents {
+		r, err := Load(writeRun(t, dir, string(rune('a'+i))+".json", c))
+		if err != nil {
+			t.Fatalf("Load: %v", err)
+		}
+		runs = append(runs, r)
+	}
+	return runs
+}
+
+func findRow(m *Matrix, metric string, fs uint32) *Row {
+	for i := range m.Rows {
+		if m.Rows[i].Metric == metric && m.Rows[i].FrameSize == fs {
+			return &m.Rows[i]
+		}
+	}
+	return nil
+}
+
+func TestLoadLabels(t *testing.T) {
+	runs := loadRuns(t, runA, runB, `{"metadata": {}, "results": []}`)
+	want := []string{"vendor-a", "Acme X1", "c"}
+	for i, r := range runs {
+		if r.Label != want[i] {
+			t.Errorf("run %d label = %q, want %q", i, r.Label, want[i])
+		}
+	}
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("Expected error for missing file")
+	}
+}
+
+func TestBuild(t *testing.T) {
+	m := Build(loadRuns(t, runA, runB))
+	if len(m.Runs) != 2 {
+		t.Fatalf("runs = %d", len(m.Runs))
+	}
+
+	row := findRow(m, "throughput.MaxRatePct", 64)
+	if row == nil || *row.Values[0] != 95.5 || *row.Values[1] != 99 {
+		t.Fatalf("MaxRatePct@64 = %+v", row)
+	}
+	if row.Best != 1 {
+		t.Errorf("Higher rate should win, best = %d", row.Best)
+	}
+
+	if row := findRow(m, "throughput.Latency.AvgNs", 64); row == nil || row.Best != 1 {
+		t.Errorf("Lower latency should win: %+v", row)
+	}
+	if row := findRow(m, "throughput.Iterations", 64); row == nil || row.Best != -1 {
+		t.Errorf("Iterations has no direction: %+v", row)
+	}
+
+	row = findRow(m, "throughput.MaxRatePct", 1518)
+	if row == nil || row.Values[1] != nil || row.Best != -1 {
+		t.Errorf("Missing values should be nil without a best: %+v", row)
+	}
+	if findRow(m, "latency[LoadPct=90].Latency.AvgNs", 64) == nil {
+		t.Error("Latency results should be keyed by load")
+	}
+
+	// Rows are grouped by metric and sorted by frame size
+	if m.Rows[0].Metric != m.Rows[1].Metric || m.Rows[0].FrameSize > m.Rows[1].FrameSize {
+		t.Errorf("rows not ordered: %+v %+v", m.Rows[0], m.Rows[1])
+	}
+}
+
+func TestImportedRun(t *testing.T) {
+	dir := t.TempDir()
+	native, err := Load(writeRun(t, dir, "native.json", runB))
+	if err != nil {
+		t.Fatal(err)
+	}
+	imported, err := Load(writeRun(t, dir, "trex.csv", "Frame Size,Throughput (Mbps),Avg Latency (us)\n64,980,1.5\n"))
+	if err != nil {
+		t.Fatalf("Load imported: %v", err)
+	}
+	if imported.Label != "trex" || imported.TestType != "throughput" {
+		t.Errorf("imported run = %+v", imported)
+	}
+
+	m := Build([]*Run{native, imported})
+	if row := findRow(m, "throughput.MaxRateMbps", 64); row == nil || row.Values[1] == nil || *row.Values[1] != 980 {
+		t.Errorf("Imported rate should match native rows: %+v", row)
+	}
+	if row := findRow(m, "throughput.Latency.AvgNs", 64); row == nil || row.Values[1] == nil || *row.Values[1] != 1500 {
+		t.Errorf("Imported latency should be in ns: %+v", row)
+	}
+}
+
+func TestDuplicateLabels(t *testing.T) {
+	m := Build(loadRuns(t, runA, runA))
+	if m.Runs[0].Label == m.Runs[1].Label {
+		t.Errorf("Labels should be unique: %q", m.Runs[0].Label)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	m := Build(loadRuns(t, runA, runB))
+	r, err := redact.New(redact.ModeStrip, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Redact(r)
+
+	if m.Runs[0].Label != "run 1" || m.Runs[0].DUT.Name != redact.Placeholder {
+		t.Errorf("run 0 = %+v", m.Runs[0])
+	}
+	if m.Runs[1].Label != "Acme X1" {
+		t.Errorf("vendor and model label should be kept, got %q", m.Runs[1].Label)
+	}
+	for _, run := range m.Runs {
+		if run.Source != redact.Placeholder {
+			t.Errorf("source = %q", run.Source)
+		}
+	}
+	if row := findRow(m, "throughput.MaxRatePct", 64); row == nil || *row.Values[0] != 95.5 {
+		t.Errorf("values changed: %+v", row)
+	}
+}
+
+func TestDirection(t *testing.T) {
+	tests := map[string]Better{
+		"throughput.MaxRateMbps":       BetterHigher,
+		"frame_loss.LossPct":           BetterLower,
+		"throughput.Latency.JitterNs":  BetterLower,
+		"soak.drift.p99_drift_pct":     BetterLower,
+		"back_to_back.BurstDurationUs": BetterHigher,
+		"throughput.Iterations":        BetterUnknown,
+	}
+	for metric, want := range tests {
+		if got := Direction(metric); got != want {
+			t.Errorf("Direction(%s) = %v, want %v", metric, got, want)
+		}
+	}
+}
+
+func TestWriters(t *testing.T) {
+	m := Build(loadRuns(t, runA, runB))
+
+	var text bytes.Buffer
+	if err := m.WriteText(&text); err != nil {
+		t.Fatal(err)
+	}
+	out := text.String()
+	if !strings.Contains(out, "vendor-a") || !strings.Contains(out, "99 (+3.7%) *") {
+		t.Errorf("text output:\n%s", out)
+	}
+
+	var buf bytes.Buffer
+	if err := m.WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	recs, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != len(m.Rows)+1 || recs[0][2] != "vendor-a" || recs[0][4] != "best" {
+		t.Errorf("csv header = %v, rows = %d", recs[0], len(recs))
+	}
+
+	buf.Reset()
+	if err := m.WriteJSON(&buf); err != nil || !strings.Contains(buf.String(), `"metric"`) {
+		t.Errorf("json output = %s, err = %v", buf.String(), err)
+	}
+}
@@ -0,0 +1,206 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// ComplianceCheck records whether one RFC 2544 methodology recommendation
+// was honored by the effective configuration
+type ComplianceCheck struct {
+	Section        string `json:"section" yaml:"section"`               // RFC 2544 section, e.g. "24"
+	Setting        string `json:"setting" yaml:"setting"`               // Config key that decides it, e.g. "trial_duration"
+	Recommendation string `json:"recommendation" yaml:"recommendation"` // What the RFC asks for
+	Honored        bool   `json:"honored" yaml:"honored"`               // True if the run follows the recommendation
+	Detail         string `json:"detail" yaml:"detail"`                 // What was actually configured
+}
+
+// RFC 2544 recommended minimums
+const (
+	RFC2544MinTrialDuration    = 60 * time.Second // Section 24
+	RFC2544MinBackToBackTrials = 50               // Section 26.4
+	RFC2544MaxFrameLossStepPct = 10.0             // Section 26.3
+	RFC2544AddressPairs        = 256              // Section 12
+)
+
+// IsRFC2544Test reports whether the test type is one of the RFC 2544 Section 26 tests
+func IsRFC2544Test(t TestType) bool {
+	switch t {
+	case TestThroughput, TestLatency, TestFrameLoss, TestBackToBack,
+		TestSystemRecovery, TestReset, TestSuite, TestCharacterize:
+		return true
+	}
+	return false
+}
+
+// runs reports whether the selected test type includes test t
+func runs(selected, t TestType) bool {
+	if selected == TestCharacterize {
+		return t == TestThroughput || t == TestLatency
+	}
+	return selected == t || selected == TestSuite
+}
+
+// Compliance evaluates the configuration against the RFC 2544 methodology
+// recommendations relevant to the selected test. It returns nil for
+// non-RFC 2544 test types.
+func (c *Config) Compliance() []ComplianceCheck {
+	if !IsRFC2544Test(c.TestType) {
+		return nil
+	}
+
+	var checks []ComplianceCheck
+
+	// Section 24: trial duration of at least 60 seconds
+	checks = append(checks, ComplianceCheck{
+		Section:        "24",
+		Setting:        "trial_duration",
+		Recommendation: fmt.Sprintf("Trial duration of at least %v", RFC2544MinTrialDuration),
+		Honored:        c.TrialDuration >= RFC2544MinTrialDuration,
+		Detail:         fmt.Sprintf("Trial duration %v", c.TrialDuration),
+	})
+
+	// Section 9.1: all standard frame sizes
+	sizesDetail, sizesSetting := "All standard frame sizes", "frame_sizes"
+	allStandard := c.FrameSize == 0
+	if c.FrameSize != 0 {
+		sizesDetail, sizesSetting = fmt.Sprintf("Single frame size %d bytes", c.FrameSize), "frame_size"
+	} else if len(c.FrameSizes) > 0 {
+		sizesDetail = fmt.Sprintf("Custom frame sizes %v", c.FrameSizes)
+		allStandard = includesAll(c.FrameSizes, StandardFrameSizes(false))
+	}
+	checks = append(checks, ComplianceCheck{
+		Section:        "9.1",
+		Setting:        sizesSetting,
+		Recommendation: "Test all standard frame sizes (64-1518 bytes)",
+		Honored:        allStandard,
+		Detail:         sizesDetail,
+	})
+
+	// Section 23: learning frames sent before each trial
+	learnDetail := fmt.Sprintf("Warmup period %v", c.WarmupPeriod)
+	if c.Addressing.LearnRate > 0 {
+		learnDetail += fmt.Sprintf(", learning ramp %d address pairs/s", c.Addressing.LearnRate)
+	}
+	checks = append(checks, ComplianceCheck{
+		Section:        "23",
+		Setting:        "warmup_period",
+		Recommendation: "Send address learning frames before each trial",
+		Honored:        c.WarmupPeriod > 0,
+		Detail:         learnDetail,
+	})
+
+	// Section 11.1: broadcast frame modifier
+	bcastDetail := "Broadcast frames not included"
+	if c.Modifiers.BroadcastPct > 0 {
+		bcastDetail = fmt.Sprintf("%.2f%% broadcast frames", c.Modifiers.BroadcastPct)
+	}
+	checks = append(checks, ComplianceCheck{
+		Section:        "11.1",
+		Setting:        "modifiers.broadcast_pct",
+		Recommendation: "Repeat tests with broadcast frames included in the stream",
+		Honored:        c.Modifiers.BroadcastPct > 0,
+		Detail:         bcastDetail,
+	})
+
+	// Section 11.2: management frame modifier
+	mgmtDetail := "Management queries not sent"
+	if c.Modifiers.ManagementTarget != "" {
+		mgmtDetail = fmt.Sprintf("%d SNMP queries/s to %s", c.Modifiers.ManagementPerSec, c.Modifiers.ManagementTarget)
+	}
+	checks = append(checks, ComplianceCheck{
+		Section:        "11.2",
+		Setting:        "modifiers.management_target",
+		Recommendation: "Repeat tests with management queries sent to the DUT",
+		Honored:        c.Modifiers.ManagementTarget != "",
+		Detail:         mgmtDetail,
+	})
+
+	// Section 12: many distinct address pairs
+	checks = append(checks, ComplianceCheck{
+		Section:        "12",
+		Setting:        "addressing.pairs",
+		Recommendation: fmt.Sprintf("Repeat tests with %d distinct address pairs", RFC2544AddressPairs),
+		Honored:        c.Addressing.Pairs >= RFC2544AddressPairs,
+		Detail:         fmt.Sprintf("%d address pairs", c.Addressing.Pairs),
+	})
+
+	if runs(c.TestType, TestThroughput) {
+		// Section 26.1: throughput is the rate with zero frame loss, at
+		// every frame size once overrides apply
+		var loss float64
+		for _, fs := range c.TestFrameSizes() {
+			loss = math.Max(loss, c.Throughput.AcceptableLossFor(c.TestType, fs))
+		}
+		checks = append(checks, ComplianceCheck{
+			Section:        "26.1",
+			Setting:        "throughput.acceptable_loss",
+			Recommendation: "Throughput is the highest rate with zero frame loss",
+			Honored:        loss == 0,
+			Detail:         fmt.Sprintf("Acceptable loss %.4f%%", loss),
+		})
+	}
+
+	if runs(c.TestType, TestFrameLoss) {
+		// Section 26.3: start at 100% and step down by no more than 10%
+		checks = append(checks, ComplianceCheck{
+			Section:        "26.3",
+			Setting:        "frame_loss.start_pct",
+			Recommendation: "Start at 100% of line rate",
+			Honored:        c.FrameLoss.StartPct >= 100,
+			Detail:         fmt.Sprintf("Start at %.1f%%", c.FrameLoss.StartPct),
+		})
+		step := ComplianceCheck{
+			Section:        "26.3",
+			Setting:        "frame_loss.step_pct",
+			Recommendation: fmt.Sprintf("Reduce load in steps of no more than %.0f%%", RFC2544MaxFrameLossStepPct),
+			Honored:        c.FrameLoss.StepPct <= RFC2544MaxFrameLossStepPct,
+			Detail:         fmt.Sprintf("Step %.1f%%", c.FrameLoss.StepPct),
+		}
+		if c.FrameLoss.Search() {
+			// The partial drop rate search skips the sweep's loads
+			step.Honored = false
+			step.Setting = "frame_loss.search_threshold_pct"
+			step.Detail = fmt.Sprintf("Partial drop rate search for loss <= %.4g%%", c.FrameLoss.SearchThresholdPct)
+		}
+		checks = append(checks, step)
+	}
+
+	if runs(c.TestType, TestBackToBack) {
+		// Section 26.4: trial repeated at least 50 times
+		checks = append(checks, ComplianceCheck{
+			Section:        "26.4",
+			Setting:        "back_to_back.trials",
+			Recommendation: fmt.Sprintf("Repeat each burst trial at least %d times", RFC2544MinBackToBackTrials),
+			Honored:        c.BackToBack.Trials >= RFC2544MinBackToBackTrials,
+			Detail:         fmt.Sprintf("%d trials", c.BackToBack.Trials),
+		})
+	}
+
+	return checks
+}
+
+// Compliant reports whether every check was honored
+func Compliant(checks []ComplianceCheck) bool {
+	for _, c := range checks {
+		if !c.Honored {
+			return false
+		}
+	}
+	return true
+}
+
+// includesAll reports whether sizes contains every size in want
+func includesAll(sizes, want []uint32) bool {
+	have := make(map[uint32]bool, len(sizes))
+	for _, s := range sizes {
+		have[s] = true
+	}
+	for _, w := range want {
+		if !have[w] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,180 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func findCheck(checks []ComplianceCheck, section string) *ComplianceCheck {
+	for i := range checks {
+		if checks[i].Section == section {
+			return &checks[i]
+		}
+	}
+	return nil
+}
+
+func TestComplianceDefaults(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Interface = "eth0"
+
+	checks := cfg.Compliance()
+	if len(checks) == 0 {
+		t.Fatal("Expected compliance checks for throughput test")
+	}
+
+	for _, section := range []string{"24", "9.1", "23", "26.1"} {
+		c := findCheck(checks, section)
+		if c == nil {
+			t.Errorf("Missing check for section %s", section)
+			continue
+		}
+		if !c.Honored {
+			t.Errorf("Section %s should be honored by defaults: %s", section, c.Detail)
+		}
+	}
+}
+
+func TestComplianceShortTrial(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.TrialDuration = 10 * time.Second
+	cfg.FrameSize = 1518
+	cfg.Throughput.AcceptableLoss = 0.1
+
+	checks := cfg.Compliance()
+	for _, section := range []string{"24", "9.1", "26.1"} {
+		c := findCheck(checks, section)
+		if c == nil || c.Honored {
+			t.Errorf("Section %s should be reported as a deviation", section)
+		}
+	}
+
+	if Compliant(checks) {
+		t.Error("Expected non-compliant result")
+	}
+}
+
+func TestComplianceLossOverride(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Throughput.AcceptableLossBySize = map[uint32]float64{64: 0.001}
+	if c := findCheck(cfg.Compliance(), "26.1"); c == nil || c.Honored {
+		t.Errorf("Loss allowed at 64 bytes should be a deviation: %+v", c)
+	}
+
+	cfg.FrameSize = 1518
+	if c := findCheck(cfg.Compliance(), "26.1"); c == nil || !c.Honored {
+		t.Errorf("The 64-byte override should not apply to 1518 bytes: %+v", c)
+	}
+}
+
+func TestComplianceCustomFrameSizes(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.FrameSizes = []uint32{64, 1400}
+	if c := findCheck(cfg.Compliance(), "9.1"); c == nil || c.Honored {
+		t.Error("Section 9.1 should be a deviation when standard sizes are missing")
+	}
+
+	cfg.FrameSizes = []uint32{64, 128, 256, 512, 1024, 1280, 1400, 1518}
+	if c := findCheck(cfg.Compliance(), "9.1"); c == nil || !c.Honored {
+		t.Error("Section 9.1 should be honored when every standard size is included")
+	}
+}
+
+func TestComplianceFrameLoss(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.TestType = TestFrameLoss
+	cfg.FrameLoss.StepPct = 20
+
+	deviation := false
+	for _, c := range cfg.Compliance() {
+		if c.Section == "26.3" && !c.Honored {
+			deviation = true
+		}
+	}
+	if !deviation {
+		t.Error("Expected 26.3 step size deviation")
+	}
+}
+
+func TestComplianceBackToBack(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.TestType = TestBackToBack
+	cfg.BackToBack.Trials = 10
+
+	c := findCheck(cfg.Compliance(), "26.4")
+	if c == nil || c.Honored {
+		t.Error("Expected 26.4 trials deviation")
+	}
+}
+
+func TestComplianceSuite(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.TestType = TestSuite
+
+	checks := cfg.Compliance()
+	for _, section := range []string{"26.1", "26.3", "26.4"} {
+		if findCheck(checks, section) == nil {
+			t.Errorf("Expected a %s check for the suite", section)
+		}
+	}
+}
+
+func TestComplianceCharacterize(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.TestType = TestCharacterize
+
+	checks := cfg.Compliance()
+	if findCheck(checks, "26.1") == nil {
+		t.Error("Expected a 26.1 check for the characterization")
+	}
+	for _, section := range []string{"26.3", "26.4"} {
+		if findCheck(checks, section) != nil {
+			t.Errorf("Unexpected %s check for the characterization", section)
+		}
+	}
+}
+
+func TestComplianceNonRFC2544(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.TestType = TestY1564Full
+
+	if checks := cfg.Compliance(); checks != nil {
+		t.Errorf("Expected no checks for Y.1564, got %d", len(checks))
+	}
+}
+
+func TestComplianceModifiers(t *testing.T) {
+	cfg := DefaultConfig()
+
+	checks := cfg.Compliance()
+	for _, section := range []string{"11.1", "11.2"} {
+		if c := findCheck(checks, section); c == nil || c.Honored {
+			t.Errorf("Section %s should be a deviation without modifiers", section)
+		}
+	}
+
+	cfg.Modifiers.BroadcastPct = 1
+	cfg.Modifiers.ManagementTarget = "192.0.2.1"
+	if !cfg.Modifiers.Enabled() {
+		t.Fatal("Expected modifiers to be enabled")
+	}
+
+	checks = cfg.Compliance()
+	for _, section := range []string{"11.1", "11.2"} {
+		if c := findCheck(checks, section); c == nil || !c.Honored {
+			t.Errorf("Section %s should be honored with modifiers configured", section)
+		}
+	}
+}
+
+func TestComplianceAddressPairs(t *testing.T) {
+	cfg := DefaultConfig()
+	if c := findCheck(cfg.Compliance(), "12"); c == nil || c.Honored {
+		t.Error("Section 12 should be a deviation with a single address pair")
+	}
+
+	cfg.Addressing.Pairs = RFC2544AddressPairs
+	if c := findCheck(cfg.Compliance(), "12"); c == nil || !c.Honored {
+		t.Error("Section 12 should be honored with 256 address pairs")
+	}
+}
@@ -2,11 +2,28 @@
 package config
 
 import (
+	"bytes"
+	"encoding/hex"
 	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/url"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
 	"gopkg.in/yaml.v3"
+
+	"github.com/krisarmstrong/rfc2544-master/pkg/addrpool"
+	"github.com/krisarmstrong/rfc2544-master/pkg/heatmap"
+	"github.com/krisarmstrong/rfc2544-master/pkg/impair"
+	"github.com/krisarmstrong/rfc2544-master/pkg/mgmt"
+	"github.com/krisarmstrong/rfc2544-master/pkg/packet"
+	"github.com/krisarmstrong/rfc2544-master/pkg/tui"
 )
 
 // TestType represents the RFC 2544 test types
@@ -14,17 +31,35 @@ type TestType string
 
 const (
 	// RFC 2544 Tests
-	TestThroughput      TestType = "throughput"       // Section 26.1
-	TestLatency         TestType = "latency"          // Section 26.2
-	TestFrameLoss       TestType = "frame_loss"       // Section 26.3
-	TestBackToBack      TestType = "back_to_back"     // Section 26.4
-	TestSystemRecovery  TestType = "system_recovery"  // Section 26.5
-	TestReset           TestType = "reset"            // Section 26.6
+	TestThroughput     TestType = "throughput"      // Section 26.1
+	TestLatency        TestType = "latency"         // Section 26.2
+	TestFrameLoss      TestType = "frame_loss"      // Section 26.3
+	TestBackToBack     TestType = "back_to_back"    // Section 26.4
+	TestSystemRecovery TestType = "system_recovery" // Section 26.5
+	TestReset          TestType = "reset"           // Section 26.6
+	TestSuite          TestType = "suite"           // Sections 26.1-26.6 in sequence
+	TestCharacterize   TestType = "characterize"    // Throughput, then latency at shares of it
+
+	// Long-duration tests
+	TestSoak TestType = "soak" // Fixed-rate soak with drift tracking
+
+	// QoS tests
+	TestQoS TestType = "qos" // Per-class scheduling matrix under saturation
+	TestAQM TestType = "aqm" // WRED/AQM drop profile from a load ramp
+
+	// Buffer tests
+	TestMicroburst TestType = "microburst" // Egress buffer depth from burst trains
+
+	// Tester verification
+	TestSelfTest TestType = "self_test" // Measure impairments injected with netem
+
+	// Routed path tests
+	TestUDPEcho TestType = "udp_echo" // UDP round-trip latency/loss via a reflector
 
 	// ITU-T Y.1564 (EtherSAM) Tests
-	TestY1564Config     TestType = "y1564_config"     // Service Configuration Test
-	TestY1564Perf       TestType = "y1564_perf"       // Service Performance Test
-	TestY1564Full       TestType = "y1564"            // Full Test (Config + Perf)
+	TestY1564Config TestType = "y1564_config" // Service Configuration Test
+	TestY1564Perf   TestType = "y1564_perf"   // Service Performance Test
+	TestY1564Full   TestType = "y1564"        // Full Test (Config + Perf)
 
 	// RFC 2889 LAN Switch Tests
 	TestRFC2889Forwarding TestType = "rfc2889_forwarding" // Forwarding Rate
@@ -55,6 +90,26 @@ const (
 	TestTSNFull      TestType = "tsn"           // Full TSN Test Suite
 )
 
+// TestTypes lists every test type accepted by Validate
+func TestTypes() []TestType {
+	return []TestType{
+		TestThroughput, TestLatency, TestFrameLoss, TestBackToBack, TestSystemRecovery, TestReset,
+		TestSuite, TestCharacterize,
+		TestSoak,
+		TestQoS,
+		TestAQM,
+		TestMicroburst,
+		TestSelfTest,
+		TestUDPEcho,
+		TestY1564Config, TestY1564Perf, TestY1564Full,
+		TestRFC2889Forwarding, TestRFC2889Caching, TestRFC2889Learning, TestRFC2889Broadcast, TestRFC2889Congestion,
+		TestRFC6349Throughput, TestRFC6349Path,
+		TestY1731Delay, TestY1731Loss, TestY1731SLM, TestY1731Loopback,
+		TestMEFConfig, TestMEFPerf, TestMEFFull,
+		TestTSNTiming, TestTSNIsolation, TestTSNLatency, TestTSNFull,
+	}
+}
+
 // OutputFormat for results
 type OutputFormat string
 
@@ -66,15 +121,30 @@ const (
 
 // Config represents the full configuration
 type Config struct {
+	// Files merged under this one, relative to it; see decodeConfig
+	Include []string `yaml:"include,omitempty"`
+
 	// Interface settings
 	Interface    string `yaml:"interface"`
 	LineRateMbps uint64 `yaml:"line_rate_mbps"` // 0 = auto-detect
 	AutoDetect   bool   `yaml:"auto_detect_nic"`
 
+	// Separate transmit and receive ports through the DUT
+	Ports PortsConfig `yaml:"ports"`
+
+	// Interfaces carrying production traffic. A run on one shows the
+	// traffic it will offer, and starts only once that is acknowledged.
+	InService []string `yaml:"in_service"`
+
 	// Test selection
 	TestType     TestType `yaml:"test_type"`
-	FrameSize    uint32   `yaml:"frame_size"`     // 0 = all standard sizes
-	IncludeJumbo bool     `yaml:"include_jumbo"`  // Include 9000 byte frames
+	FrameSize    uint32   `yaml:"frame_size"`    // 0 = all standard sizes
+	FrameSizes   []uint32 `yaml:"frame_sizes"`   // Custom sweep instead of the standard sizes
+	IncludeJumbo bool     `yaml:"include_jumbo"` // Include 9000 byte frames
+
+	// Jumbo sizes include_jumbo adds to the standard sweep instead of 9000,
+	// e.g. [9000, 9216] or a super-jumbo 16000
+	JumboSizes []uint32 `yaml:"jumbo_sizes"`
 
 	// Timing
 	TrialDuration time.Duration `yaml:"trial_duration"` // Default: 60s
@@ -86,15 +156,123 @@ type Config struct {
 	// Latency test (Section 26.2)
 	Latency LatencyConfig `yaml:"latency"`
 
+	// High-priority probe stream alongside the load of latency trials
+	LatencyProbe LatencyProbeConfig `yaml:"latency_probe"`
+
 	// Frame loss test (Section 26.3)
 	FrameLoss FrameLossConfig `yaml:"frame_loss"`
 
 	// Back-to-back test (Section 26.4)
 	BackToBack BackToBackConfig `yaml:"back_to_back"`
 
+	// System recovery test (Section 26.5)
+	Recovery RecoveryConfig `yaml:"recovery"`
+
+	// Test modifiers (Section 11)
+	Modifiers ModifiersConfig `yaml:"modifiers"`
+
+	// Address pairs (Section 12)
+	Addressing AddressingConfig `yaml:"addressing"`
+
+	// Addresses and UDP ports of the test frames
+	Headers HeadersConfig `yaml:"headers"`
+
+	// Frame encapsulation
+	Framing FramingConfig `yaml:"framing"`
+
+	// IP version of the built-in test frames: "4" (default), "6" or "dual"
+	IPVersion IPVersion `yaml:"ip_version"`
+
+	// IPv6 header fields, with ip_version 6 or dual
+	IPv6 IPv6Config `yaml:"ipv6"`
+
+	// DSCP of the built-in test frames: their IPv4 header, and IPv6 frames
+	// whose ipv6 traffic_class is 0. Y.1564 services mark their cos.
+	DSCP uint8 `yaml:"dscp"`
+
+	// Padding of generated frames: incrementing (default), zeros, ones,
+	// random or random:<seed>, prbs31, or hex:<bytes> repeated
+	PayloadPattern string `yaml:"payload_pattern"`
+
+	// User-defined frame headers
+	Packet PacketConfig `yaml:"packet"`
+
+	// Control-plane policing stress
+	ControlPlane ControlPlaneConfig `yaml:"control_plane"`
+
+	// Far-end loopback via Ethernet OAM
+	OAM OAMConfig `yaml:"oam"`
+
+	// Soak test
+	Soak SoakConfig `yaml:"soak"`
+
+	// QoS scheduling matrix test
+	QoS QoSConfig `yaml:"qos"`
+
+	// WRED/AQM characterization test
+	AQM AQMConfig `yaml:"aqm"`
+
+	// Microburst buffer-depth test
+	Microburst MicroburstConfig `yaml:"microburst"`
+
+	// Tester self-test through netem impairments
+	SelfTest SelfTestConfig `yaml:"self_test"`
+
+	// UDP echo test over routed paths
+	UDPEcho UDPEchoConfig `yaml:"udp_echo"`
+
+	// Latency heatmap for soak and Y.1564 performance runs
+	Heatmap HeatmapConfig `yaml:"heatmap"`
+
+	// DUT power measurement
+	Power PowerConfig `yaml:"power"`
+
+	// External TRex traffic generator
+	TRex TRexConfig `yaml:"trex"`
+
+	// Unprivileged UDP socket mode
+	Socket SocketConfig `yaml:"socket"`
+
+	// Pre-test connectivity checks
+	PreQual PreQualConfig `yaml:"prequal"`
+
+	// Device under test, recorded with the results
+	DUT DUTConfig `yaml:"dut"`
+
+	// DUT configuration snapshots before and after the run
+	DUTSnapshot DUTSnapshotConfig `yaml:"dut_snapshot"`
+
+	// Acceptance certificate (-o pdf)
+	Certificate CertificateConfig `yaml:"certificate"`
+
+	// Circuit inventory --circuit resolves circuit IDs in
+	Inventory InventoryConfig `yaml:"inventory"`
+
+	// Pass limits for automated gating
+	Thresholds ThresholdsConfig `yaml:"thresholds"`
+
+	// Ticketing connectors filing runs that fail their thresholds
+	Ticketing []TicketingConfig `yaml:"ticketing"`
+
+	// Operator prompts between TUI phases
+	Gates []GateConfig `yaml:"gates"`
+
+	// Ordered sequence of tests for one CLI run
+	Plan PlanConfig `yaml:"plan"`
+
 	// Features
-	HWTimestamp    bool `yaml:"hw_timestamp"`
-	MeasureLatency bool `yaml:"measure_latency"`
+	HWTimestamp      bool `yaml:"hw_timestamp"`
+	MeasureLatency   bool `yaml:"measure_latency"`
+	LatencyHistogram bool `yaml:"latency_histogram"` // Full latency distribution in each result
+	VerifyPayload    bool `yaml:"verify_payload"`    // CRC-32 in each frame's payload, counting corrupted frames
+
+	// Latency samples each trial keeps for its statistics and histogram
+	// (0 = 10000, at most MaxLatencySamples), in buffers reused by every
+	// trial. latency.samples is unrelated.
+	LatencySampleCapacity uint32 `yaml:"latency_sample_capacity"`
+
+	// PCAP capture of the frames trials send and receive
+	Capture CaptureConfig `yaml:"capture"`
 
 	// Output
 	OutputFormat OutputFormat `yaml:"output_format"`
@@ -105,11 +283,29 @@ type Config struct {
 	DPDKArgs string `yaml:"dpdk_args"`
 
 	// Rate control
-	UsePacing bool   `yaml:"use_pacing"`
-	BatchSize uint32 `yaml:"batch_size"`
+	UsePacing    bool   `yaml:"use_pacing"`
+	BatchSize    uint32 `yaml:"batch_size"`
+	SpeedProfile string `yaml:"speed_profile"` // TX batch/pacing tuning, e.g. "100g"; "" = from the line rate
+
+	// Heartbeat silence after which a run, from the CLI, the TUI or the web
+	// UI, restarts the wedged dataplane and fails the test it stalled;
+	// default 30s, 0 = off
+	DataplaneWatchdog time.Duration `yaml:"dataplane_watchdog"`
 
 	// Web UI
-	WebUI    WebUIConfig `yaml:"web_ui"`
+	WebUI WebUIConfig `yaml:"web_ui"`
+
+	// Terminal UI keys and status bar text
+	TUI TUIConfig `yaml:"tui"`
+
+	// Encrypted credentials for ${secret:NAME} references; see secrets.go
+	Secrets SecretsConfig `yaml:"secrets"`
+
+	// gNMI telemetry target
+	GNMI GNMIConfig `yaml:"gnmi"`
+
+	// Tester self-profiling
+	Profile ProfileConfig `yaml:"profile"`
 
 	// ITU-T Y.1564 (EtherSAM) configuration
 	Y1564 Y1564Config `yaml:"y1564"`
@@ -122,288 +318,1568 @@ type Config struct {
 	TSN     TSNConfig     `yaml:"tsn"`     // TSN tests
 }
 
-// ThroughputConfig for binary search throughput test
+// SearchAlgorithm selects how the throughput test looks for the highest
+// rate without loss
+type SearchAlgorithm string
+
+const (
+	SearchBinary SearchAlgorithm = "binary" // Halve the interval each trial
+	SearchLinear SearchAlgorithm = "linear" // Every step down from the initial rate
+	SearchHybrid SearchAlgorithm = "hybrid" // Step down to a pass, then binary search the step above it
+)
+
+// ThroughputConfig for the throughput test search. Binary search assumes
+// loss only grows with rate; for a DUT that passes at 80% but fails at
+// 70%, linear tries every step and reports the whole curve.
 type ThroughputConfig struct {
-	InitialRatePct float64 `yaml:"initial_rate_pct"` // Default: 100
-	ResolutionPct  float64 `yaml:"resolution_pct"`   // Default: 0.1
-	MaxIterations  uint32  `yaml:"max_iterations"`   // Default: 20
-	AcceptableLoss float64 `yaml:"acceptable_loss"`  // Default: 0.0
+	InitialRatePct  float64         `yaml:"initial_rate_pct"` // Default: 100
+	ResolutionPct   float64         `yaml:"resolution_pct"`   // Default: 0.1
+	MaxIterations   uint32          `yaml:"max_iterations"`   // Default: 20
+	AcceptableLoss  float64         `yaml:"acceptable_loss"`  // Default: 0.0
+	SearchAlgorithm SearchAlgorithm `yaml:"search_algorithm"` // Default: binary
+	StepPct         float64         `yaml:"step_pct"`         // Linear and hybrid step, % of line rate (default 10)
+
+	// Overrides of acceptable_loss, e.g. a spec that allows small loss at
+	// 64 bytes only. A test's own sizes win over its default, which wins
+	// over the sizes here.
+	AcceptableLossBySize map[uint32]float64        `yaml:"acceptable_loss_by_size"`
+	AcceptableLossByTest map[TestType]LossOverride `yaml:"acceptable_loss_by_test"`
+}
+
+// LossOverride is the acceptable loss for one test type
+type LossOverride struct {
+	AcceptableLoss *float64           `yaml:"acceptable_loss"` // Unset keeps the throughput value
+	BySize         map[uint32]float64 `yaml:"acceptable_loss_by_size"`
+}
+
+// AcceptableLossFor returns the loss tolerated in a passing trial of the
+// throughput search for a test type and frame size
+func (t ThroughputConfig) AcceptableLossFor(test TestType, frameSize uint32) float64 {
+	if o, ok := t.AcceptableLossByTest[test]; ok {
+		if pct, ok := o.BySize[frameSize]; ok {
+			return pct
+		}
+		if o.AcceptableLoss != nil {
+			return *o.AcceptableLoss
+		}
+	}
+	if pct, ok := t.AcceptableLossBySize[frameSize]; ok {
+		return pct
+	}
+	return t.AcceptableLoss
 }
 
 // LatencyConfig for latency test
 type LatencyConfig struct {
 	Samples    uint32    `yaml:"samples"`     // Number of samples per trial
 	LoadLevels []float64 `yaml:"load_levels"` // Load levels to test (% of throughput)
-}
 
-// FrameLossConfig for frame loss test
-type FrameLossConfig struct {
-	StartPct float64 `yaml:"start_pct"` // Starting offered load %
-	EndPct   float64 `yaml:"end_pct"`   // Ending offered load %
-	StepPct  float64 `yaml:"step_pct"`  // Step size
+	// SubtractSerialization also reports the latency vs frame size curve
+	// less each frame's serialization delay at line rate
+	SubtractSerialization bool `yaml:"subtract_serialization"`
 }
 
-// BackToBackConfig for burst capacity test
-type BackToBackConfig struct {
-	InitialBurst uint64 `yaml:"initial_burst"` // Starting burst size
-	Trials       uint32 `yaml:"trials"`        // Trials per burst size
+// LatencyProbeConfig sends a low-rate stream marked with a higher
+// priority alongside the load of each latency trial, reporting the
+// probe's latency and the load's apart, as voice over a congested link
+// is assessed
+type LatencyProbeConfig struct {
+	PPS  uint32 `yaml:"pps"`  // Probe frames per second (0 = off)
+	PCP  uint8  `yaml:"pcp"`  // 802.1p priority of its VLAN tags (VLAN-tagged framing only)
+	DSCP uint8  `yaml:"dscp"` // DSCP of its IPv4 header, e.g. 46 (EF)
 }
 
-// WebUIConfig for web interface
-type WebUIConfig struct {
-	Enabled bool   `yaml:"enabled"`
-	Address string `yaml:"address"` // e.g., ":8080"
+// Enabled reports whether a latency probe is configured
+func (p LatencyProbeConfig) Enabled() bool {
+	return p.PPS > 0
 }
 
-// Y1564SLA defines SLA parameters for Y.1564 testing
-type Y1564SLA struct {
-	CIRMbps         float64 `yaml:"cir_mbps"`          // Committed Information Rate
-	EIRMbps         float64 `yaml:"eir_mbps"`          // Excess Information Rate
-	CBSBytes        uint32  `yaml:"cbs_bytes"`         // Committed Burst Size
-	EBSBytes        uint32  `yaml:"ebs_bytes"`         // Excess Burst Size
-	FDThresholdMs   float64 `yaml:"fd_threshold_ms"`   // Frame Delay threshold (ms)
-	FDVThresholdMs  float64 `yaml:"fdv_threshold_ms"`  // Frame Delay Variation threshold (ms)
-	FLRThresholdPct float64 `yaml:"flr_threshold_pct"` // Frame Loss Ratio threshold (%)
+// CaptureConfig saves the frames of trials to pcap files, for post-mortem
+// of frame loss in Wireshark or tcpdump. Each file takes the first limit
+// frames of the run; a restart by the dataplane watchdog starts it over.
+type CaptureConfig struct {
+	TX      string `yaml:"tx"`      // Frames sent ("" = not captured)
+	RX      string `yaml:"rx"`      // Frames received, test frames or not ("" = not captured)
+	Limit   uint32 `yaml:"limit"`   // Frames a file (0 = 100000)
+	Snaplen uint32 `yaml:"snaplen"` // Bytes kept of each frame, e.g. 64 for headers only (0 = whole frames)
 }
 
-// Y1564Service defines a service for Y.1564 testing
-type Y1564Service struct {
-	ServiceID   uint32   `yaml:"service_id"`
-	ServiceName string   `yaml:"service_name"`
-	SLA         Y1564SLA `yaml:"sla"`
-	FrameSize   uint32   `yaml:"frame_size"`
-	CoS         uint8    `yaml:"cos"` // Class of Service (DSCP value)
-	Enabled     bool     `yaml:"enabled"`
+// Enabled reports whether any frames are captured
+func (p CaptureConfig) Enabled() bool {
+	return p.TX != "" || p.RX != ""
 }
 
-// Y1564Config for ITU-T Y.1564 testing
-type Y1564Config struct {
-	Services        []Y1564Service `yaml:"services"`
-	ConfigSteps     []float64      `yaml:"config_steps"`      // Step percentages (default: 25, 50, 75, 100)
-	StepDuration    time.Duration  `yaml:"step_duration"`     // Duration per step (default: 60s)
-	PerfDuration    time.Duration  `yaml:"perf_duration"`     // Performance test duration (default: 15m)
-	RunConfigTest   bool           `yaml:"run_config_test"`   // Run configuration test
-	RunPerfTest     bool           `yaml:"run_perf_test"`     // Run performance test
-}
+// IPVersion selects the IP version of the built-in test frames
+type IPVersion string
 
-// RFC2889Config for LAN switch benchmarking tests
-type RFC2889Config struct {
-	PortCount         uint32        `yaml:"port_count"`          // Number of ports
-	AddressCount      uint32        `yaml:"address_count"`       // MAC addresses for caching test
-	TrialDuration     time.Duration `yaml:"trial_duration"`      // Duration per trial
-	AcceptableLossPct float64       `yaml:"acceptable_loss_pct"` // Acceptable loss percentage
+const (
+	IPv4      IPVersion = "4"
+	IPv6      IPVersion = "6"
+	DualStack IPVersion = "dual" // IPv4 and IPv6 frames interleaved, each family reported apart
+)
+
+// HasIPv6 reports whether any test frames are IPv6
+func (v IPVersion) HasIPv6() bool {
+	return v == IPv6 || v == DualStack
 }
 
-// RFC6349Config for TCP throughput testing
-type RFC6349Config struct {
-	TargetRateMbps   float64       `yaml:"target_rate_mbps"`   // Target rate (0 = auto)
-	MSS              uint32        `yaml:"mss"`                // Maximum Segment Size
-	RWND             uint32        `yaml:"rwnd"`               // Receive Window Size
-	TestDuration     time.Duration `yaml:"test_duration"`      // Test duration
-	ParallelStreams  uint32        `yaml:"parallel_streams"`   // Number of parallel streams
+// IPv6Config are the addresses and marking of IPv6 test frames
+type IPv6Config struct {
+	Src          string `yaml:"src"`            // Default: 2001:2::1 (RFC 5180 benchmarking range)
+	Dst          string `yaml:"dst"`            // Default: 2001:2::2
+	TrafficClass uint8  `yaml:"traffic_class"`  // DSCP << 2 | ECN, e.g. 184 (EF)
+	FlowLabel    uint32 `yaml:"flow_label"`     // 0-1048575
+	HopLimit     uint8  `yaml:"hop_limit"`      // 0 = 64
+	DualStackPct uint8  `yaml:"dual_stack_pct"` // Share of frames that are IPv6 with ip_version dual (0 = 50)
 }
 
-// Y1731Config for Ethernet OAM testing
-type Y1731Config struct {
-	MEPID       uint32        `yaml:"mep_id"`       // MEP identifier
-	MEGLevel    uint8         `yaml:"meg_level"`    // MEG level (0-7)
-	MEGID       string        `yaml:"meg_id"`       // MEG identifier
-	CCMInterval uint32        `yaml:"ccm_interval"` // CCM interval (ms)
-	ProbeCount  uint32        `yaml:"probe_count"`  // Number of probes
-	ProbeInterval time.Duration `yaml:"probe_interval"` // Interval between probes
+// Default IPv6 test addresses, from the RFC 5180 benchmarking range
+const (
+	DefaultIPv6Src = "2001:2::1"
+	DefaultIPv6Dst = "2001:2::2"
+)
+
+// Addrs returns the source and destination addresses, defaulted
+func (v IPv6Config) Addrs() (src, dst net.IP) {
+	src, dst = net.ParseIP(v.Src), net.ParseIP(v.Dst)
+	if v.Src == "" {
+		src = net.ParseIP(DefaultIPv6Src)
+	}
+	if v.Dst == "" {
+		dst = net.ParseIP(DefaultIPv6Dst)
+	}
+	return src, dst
 }
 
-// MEFConfig for service activation testing
-type MEFConfig struct {
-	CIRMbps          float64       `yaml:"cir_mbps"`           // Committed Information Rate
-	EIRMbps          float64       `yaml:"eir_mbps"`           // Excess Information Rate
-	CBSBytes         uint32        `yaml:"cbs_bytes"`          // Committed Burst Size
-	EBSBytes         uint32        `yaml:"ebs_bytes"`          // Excess Burst Size
-	FDThresholdUs    float64       `yaml:"fd_threshold_us"`    // Frame Delay threshold (us)
-	FDVThresholdUs   float64       `yaml:"fdv_threshold_us"`   // Frame Delay Variation (us)
-	FLRThresholdPct  float64       `yaml:"flr_threshold_pct"`  // Frame Loss Ratio threshold
-	AvailThresholdPct float64      `yaml:"avail_threshold_pct"` // Availability threshold
-	ConfigDuration   time.Duration `yaml:"config_duration"`    // Config test duration
-	PerfDuration     time.Duration `yaml:"perf_duration"`      // Perf test duration
+// IPv6TrafficClass is the traffic class of IPv6 test frames: ipv6
+// traffic_class, else the dscp with ECN 0
+func (c *Config) IPv6TrafficClass() uint8 {
+	if c.IPv6.TrafficClass != 0 {
+		return c.IPv6.TrafficClass
+	}
+	return c.DSCP << 2
 }
 
-// TSNConfig for Time-Sensitive Networking testing
-type TSNConfig struct {
-	NumClasses       uint32        `yaml:"num_classes"`        // Number of traffic classes
-	CycleTimeNs      uint64        `yaml:"cycle_time_ns"`      // GCL cycle time
-	MaxLatencyNs     uint64        `yaml:"max_latency_ns"`     // Maximum latency threshold
-	MaxJitterNs      uint64        `yaml:"max_jitter_ns"`      // Maximum jitter threshold
-	MaxSyncOffsetNs  uint64        `yaml:"max_sync_offset_ns"` // Maximum PTP sync offset
-	TestDuration     time.Duration `yaml:"test_duration"`      // Test duration
-	FrameSize        uint32        `yaml:"frame_size"`         // Frame size for testing
+// PatternKind names a payload padding pattern
+type PatternKind string
+
+const (
+	PatternIncrementing PatternKind = "incrementing" // 00 01 02 .. ff 00 ..
+	PatternZeros        PatternKind = "zeros"
+	PatternOnes         PatternKind = "ones"   // All ff
+	PatternRandom       PatternKind = "random" // Pseudo-random from a seed, the same every run
+	PatternPRBS31       PatternKind = "prbs31" // x^31 + x^28 + 1
+	PatternHex          PatternKind = "hex"    // User bytes repeated
+)
+
+// MaxPatternBytes is the longest hex pattern
+const MaxPatternBytes = 256
+
+// Pattern is a parsed payload_pattern
+type Pattern struct {
+	Kind  PatternKind
+	Seed  uint64 // PatternRandom
+	Bytes []byte // PatternHex
 }
 
-// DefaultRFC2889Config returns default RFC 2889 configuration
-func DefaultRFC2889Config() RFC2889Config {
-	return RFC2889Config{
-		PortCount:         2,
-		AddressCount:      8192,
-		TrialDuration:     60 * time.Second,
-		AcceptableLossPct: 0.0,
-	}
+// Pattern parses payload_pattern; "" is incrementing
+func (c *Config) Pattern() (Pattern, error) {
+	return ParsePattern(c.PayloadPattern)
 }
 
-// DefaultRFC6349Config returns default RFC 6349 configuration
-func DefaultRFC6349Config() RFC6349Config {
-	return RFC6349Config{
-		TargetRateMbps:  0, // Auto-detect
-		MSS:             1460,
-		RWND:            65535,
-		TestDuration:    30 * time.Second,
-		ParallelStreams: 1,
-	}
+// Patterned reports whether payload_pattern replaces the incrementing fill
+func (c *Config) Patterned() bool {
+	p, err := c.Pattern()
+	return err != nil || p.Kind != PatternIncrementing
 }
 
-// DefaultY1731Config returns default Y.1731 configuration
-func DefaultY1731Config() Y1731Config {
-	return Y1731Config{
-		MEPID:         1,
-		MEGLevel:      4,
-		MEGID:         "DEFAULT-MEG",
-		CCMInterval:   1000,
-		ProbeCount:    100,
-		ProbeInterval: time.Second,
+// ParsePattern parses a payload pattern, e.g. "prbs31", "random:7" or
+// "hex:de ad be ef"
+func ParsePattern(s string) (Pattern, error) {
+	name, arg, hasArg := strings.Cut(strings.TrimSpace(s), ":")
+	switch kind := PatternKind(strings.ToLower(strings.TrimSpace(name))); kind {
+	case "", PatternIncrementing, PatternZeros, PatternOnes, PatternPRBS31, "prbs-31":
+		if hasArg {
+			return Pattern{}, fmt.Errorf("payload_pattern %s takes no argument", kind)
+		}
+		switch kind {
+		case "":
+			kind = PatternIncrementing
+		case "prbs-31":
+			kind = PatternPRBS31
+		}
+		return Pattern{Kind: kind}, nil
+	case PatternRandom:
+		p := Pattern{Kind: PatternRandom}
+		if hasArg {
+			seed, err := strconv.ParseUint(strings.TrimSpace(arg), 0, 64)
+			if err != nil {
+				return Pattern{}, fmt.Errorf("payload_pattern random: invalid seed %q", arg)
+			}
+			p.Seed = seed
+		}
+		return p, nil
+	case PatternHex:
+		digits := strings.NewReplacer(" ", "", ":", "", "-", "").Replace(arg)
+		digits = strings.TrimPrefix(strings.TrimPrefix(digits, "0x"), "0X")
+		b, err := hex.DecodeString(digits)
+		if err != nil {
+			return Pattern{}, fmt.Errorf("payload_pattern hex: invalid bytes %q", arg)
+		}
+		if len(b) == 0 || len(b) > MaxPatternBytes {
+			return Pattern{}, fmt.Errorf("payload_pattern hex: %d bytes, must be 1-%d", len(b), MaxPatternBytes)
+		}
+		return Pattern{Kind: PatternHex, Bytes: b}, nil
 	}
+	return Pattern{}, fmt.Errorf("unknown payload_pattern %q (incrementing, zeros, ones, random, prbs31 or hex:<bytes>)", s)
 }
 
-// DefaultMEFConfig returns default MEF configuration
-func DefaultMEFConfig() MEFConfig {
-	return MEFConfig{
-		CIRMbps:          100.0,
-		EIRMbps:          0,
-		CBSBytes:         12000,
-		EBSBytes:         0,
-		FDThresholdUs:    10000, // 10ms
-		FDVThresholdUs:   5000,  // 5ms
-		FLRThresholdPct:  0.01,
-		AvailThresholdPct: 99.99,
-		ConfigDuration:   60 * time.Second,
-		PerfDuration:     15 * time.Minute,
+// String formats the pattern as payload_pattern takes it
+func (p Pattern) String() string {
+	switch p.Kind {
+	case PatternRandom:
+		return fmt.Sprintf("random:%d", p.Seed)
+	case PatternHex:
+		return "hex:" + hex.EncodeToString(p.Bytes)
+	case "":
+		return string(PatternIncrementing)
 	}
+	return string(p.Kind)
 }
 
-// DefaultTSNConfig returns default TSN configuration
-func DefaultTSNConfig() TSNConfig {
-	return TSNConfig{
-		NumClasses:      8,
-		CycleTimeNs:     1000000, // 1ms
-		MaxLatencyNs:    100000,  // 100us
-		MaxJitterNs:     10000,   // 10us
-		MaxSyncOffsetNs: 1000,    // 1us
-		TestDuration:    60 * time.Second,
-		FrameSize:       128,
-	}
+// FrameLossConfig for frame loss test
+type FrameLossConfig struct {
+	StartPct float64 `yaml:"start_pct"` // Starting offered load %
+	EndPct   float64 `yaml:"end_pct"`   // Ending offered load %
+	StepPct  float64 `yaml:"step_pct"`  // Step size
+
+	// End the sweep after this many trials in a row lose no frames, as
+	// Section 26.3 has it with 2 (0 = sweep down to end_pct)
+	LosslessTrials uint32 `yaml:"lossless_trials"`
+
+	// Partial drop rate search: binary-search start_pct down to end_pct
+	// for the highest load with loss at or below the threshold, instead of
+	// the step sweep
+	SearchThresholdPct  float64 `yaml:"search_threshold_pct"`  // Loss threshold % (0 = step sweep)
+	SearchResolutionPct float64 `yaml:"search_resolution_pct"` // Stop when the load range is below this %
 }
 
-// DefaultY1564SLA returns default SLA parameters
-func DefaultY1564SLA() Y1564SLA {
-	return Y1564SLA{
-		CIRMbps:         100.0,
-		EIRMbps:         0.0,
-		CBSBytes:        12000,
-		EBSBytes:        0,
-		FDThresholdMs:   10.0,
-		FDVThresholdMs:  5.0,
-		FLRThresholdPct: 0.01,
-	}
+// Search reports whether the partial drop rate search replaces the sweep
+func (f FrameLossConfig) Search() bool {
+	return f.SearchThresholdPct > 0
 }
 
-// DefaultY1564Config returns default Y.1564 configuration
-func DefaultY1564Config() Y1564Config {
-	return Y1564Config{
-		Services:      []Y1564Service{},
-		ConfigSteps:   []float64{25, 50, 75, 100},
-		StepDuration:  60 * time.Second,
-		PerfDuration:  15 * time.Minute,
-		RunConfigTest: true,
-		RunPerfTest:   true,
-	}
+// BackToBackConfig for burst capacity test
+type BackToBackConfig struct {
+	InitialBurst uint64 `yaml:"initial_burst"` // Starting burst size
+	Trials       uint32 `yaml:"trials"`        // Trials per burst size
 }
 
-// DefaultConfig returns a configuration with RFC 2544 recommended defaults
-func DefaultConfig() *Config {
-	return &Config{
-		AutoDetect:     true,
-		TestType:       TestThroughput,
-		FrameSize:      0, // All standard sizes
-		IncludeJumbo:   false,
-		TrialDuration:  60 * time.Second,
-		WarmupPeriod:   2 * time.Second,
+// RecoveryConfig for system recovery test
+type RecoveryConfig struct {
+	OverloadSec uint32 `yaml:"overload_sec"` // Overload duration in seconds
+}
 
-		Throughput: ThroughputConfig{
-			InitialRatePct: 100.0,
-			ResolutionPct:  0.1,
-			MaxIterations:  20,
-			AcceptableLoss: 0.0,
-		},
+// ModifiersConfig for RFC 2544 Section 11 test modifiers. When enabled,
+// each RFC 2544 test is repeated with the modifiers applied and the
+// modified results are reported alongside the baseline.
+type ModifiersConfig struct {
+	BroadcastPct     float64 `yaml:"broadcast_pct"`      // Section 11.1: % of frames sent to broadcast (RFC suggests 1)
+	ManagementTarget string  `yaml:"management_target"`  // Section 11.2: DUT SNMP agent host[:port]
+	ManagementPerSec uint32  `yaml:"management_per_sec"` // Section 11.2: management queries per second
+	Community        string  `yaml:"community"`          // SNMP community for management queries
+}
 
-		Latency: LatencyConfig{
-			Samples:    1000,
-			LoadLevels: []float64{10, 20, 30, 40, 50, 60, 70, 80, 90, 100},
-		},
+// Enabled reports whether any Section 11 modifier is configured
+func (m ModifiersConfig) Enabled() bool {
+	return m.BroadcastPct > 0 || m.ManagementTarget != ""
+}
 
-		FrameLoss: FrameLossConfig{
-			StartPct: 100.0,
-			EndPct:   10.0,
-			StepPct:  10.0,
-		},
+// PortsConfig sends test frames on one interface and counts them on
+// another, for a DUT that forwards between two ports instead of looping
+// traffic back. Latency then uses software timestamps: two NICs do not
+// share a hardware clock.
+type PortsConfig struct {
+	TX string `yaml:"tx"` // Transmit interface (empty = interface)
+	RX string `yaml:"rx"` // Receive interface
+}
 
-		BackToBack: BackToBackConfig{
-			InitialBurst: 1000,
-			Trials:       50,
-		},
+// Enabled reports whether a receive port is configured
+func (p PortsConfig) Enabled() bool {
+	return p.RX != ""
+}
 
-		HWTimestamp:    true,
-		MeasureLatency: true,
-		OutputFormat:   FormatText,
-		Verbose:        false,
-		UseDPDK:        false,
-		UsePacing:      true,
-		BatchSize:      32,
+// MaxBroadcastPct mirrors the C library's MAX_BROADCAST_PCT: at least
+// half the frames stay unicast, so the trial still measures loss
+const MaxBroadcastPct = 50
 
-		WebUI: WebUIConfig{
-			Enabled: false,
-			Address: ":8080",
-		},
+// MaxAddressPairs mirrors the C library's MAX_ADDRESS_PAIRS
+const MaxAddressPairs = 65536
 
-		Y1564: DefaultY1564Config(),
+// Built-in test frame addresses, mirroring the C library's DEFAULT_SRC_IP
+// and DEFAULT_DST_IP
+const (
+	defaultSrcIP = 0x0a000001 // 10.0.0.1
+	defaultDstIP = 0x0a000002 // 10.0.0.2
+)
 
-		// Extended protocol test defaults
-		RFC2889: DefaultRFC2889Config(),
-		RFC6349: DefaultRFC6349Config(),
-		Y1731:   DefaultY1731Config(),
-		MEF:     DefaultMEFConfig(),
-		TSN:     DefaultTSNConfig(),
-	}
+// MaxStreams mirrors the C library's MAX_STREAMS
+const MaxStreams = 1024
+
+// MaxLatencySamples mirrors the C library's MAX_LATENCY_SAMPLES
+const MaxLatencySamples = 10000000
+
+// MaxCaptureSnaplen is the longest capture.snaplen, as pcap allows
+const MaxCaptureSnaplen = 262144
+
+// AddressingConfig for RFC 2544 Section 12 address distribution. Without
+// pools, pair i uses the base source MAC + i and source/destination IPs in
+// the i-th /24 above the base addresses, which must stay in their /8.
+type AddressingConfig struct {
+	Pairs uint32       `yaml:"pairs"` // Distinct src/dst pairs rotated round-robin (RFC suggests 256)
+	Pools AddressPools `yaml:"pools"`
+	Seed  int64        `yaml:"seed"` // Pool draw seed (0 = new each run, recorded in the results)
+	// New pairs introduced per second before the first trial, one frame
+	// each, so MAC-learning protection on the DUT (e.g. port security or
+	// learning rate limits) does not drop the flood of new source MACs.
+	// 0 introduces them all at once with the test traffic.
+	LearnRate uint32 `yaml:"learn_rate"`
+	// Flows each trial is spread across round-robin, stream i adding i to
+	// the source MAC, both IPs and the UDP source port, reported one by
+	// one. Exercises the DUT's hashing and load balancing (0 or 1 = one flow).
+	Streams uint32 `yaml:"streams"`
 }
 
-// Load reads configuration from a YAML file
-func Load(path string) (*Config, error) {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil, fmt.Errorf("read config: %w", err)
+// fitPairs checks that rotating pairs without pools keeps every pair's
+// addresses in the /8 of the base addresses, so the /24 shift never
+// carries into the network octet
+func (a AddressingConfig) fitPairs(h Headers) error {
+	if a.Pairs <= 1 || a.Pools.Enabled() {
+		return nil
 	}
-
-	cfg := DefaultConfig()
-	if err := yaml.Unmarshal(data, cfg); err != nil {
-		return nil, fmt.Errorf("parse config: %w", err)
+	for _, base := range []struct {
+		name  string
+		first uint64
+		def   uint64
+	}{{"src_ip", h.SrcIP.First, defaultSrcIP}, {"dst_ip", h.DstIP.First, defaultDstIP}} {
+		ip := base.first
+		if ip == 0 {
+			ip = base.def
+		}
+		if room := 0x10000 - (ip>>8)&0xffff; uint64(a.Pairs) > room {
+			return fmt.Errorf("addressing pairs %d carry %s %d.%d.%d.%d out of its /8: at most %d pairs fit above it",
+				a.Pairs, base.name, ip>>24, ip>>16&0xff, ip>>8&0xff, ip&0xff, room)
+		}
 	}
+	return nil
+}
 
-	if err := cfg.Validate(); err != nil {
-		return nil, fmt.Errorf("validate config: %w", err)
-	}
+// AddressPools are ranges each run draws its pairs' addresses from at
+// random, without repeats and with no address both a source and a
+// destination. A pool left empty keeps the built-in address.
+type AddressPools struct {
+	SrcMAC string `yaml:"src_mac"` // e.g. 02:00:00:00:00:01-02:00:00:00:ff:ff
+	DstMAC string `yaml:"dst_mac"`
+	SrcIP  string `yaml:"src_ip"` // e.g. 10.1.0.0/16 or 10.1.0.1-10.1.0.200
+	DstIP  string `yaml:"dst_ip"`
+}
 
-	return cfg, nil
+// Enabled reports whether any pool is set
+func (p AddressPools) Enabled() bool {
+	return p.SrcMAC != "" || p.DstMAC != "" || p.SrcIP != "" || p.DstIP != ""
 }
 
-// Save writes configuration to a YAML file
-func (c *Config) Save(path string) error {
-	data, err := yaml.Marshal(c)
+// Parse returns the pools' ranges
+func (p AddressPools) Parse() (addrpool.Pools, error) {
+	var pools addrpool.Pools
+	for _, f := range []struct {
+		name, text string
+		r          *addrpool.Range
+		parse      func(string) (addrpool.Range, error)
+	}{
+		{"src_mac", p.SrcMAC, &pools.SrcMAC, addrpool.ParseMACRange},
+		{"dst_mac", p.DstMAC, &pools.DstMAC, addrpool.ParseMACRange},
+		{"src_ip", p.SrcIP, &pools.SrcIP, addrpool.ParseIPRange},
+		{"dst_ip", p.DstIP, &pools.DstIP, addrpool.ParseIPRange},
+	} {
+		if f.text == "" {
+			continue
+		}
+		r, err := f.parse(f.text)
+		if err != nil {
+			return pools, fmt.Errorf("addressing pools %s: %w", f.name, err)
+		}
+		*f.r = r
+	}
+	return pools, nil
+}
+
+// HeadersConfig sets the addresses and UDP ports of the built-in test
+// frames, e.g. the next hop's MAC through a routed DUT or the 5-tuple a
+// firewall admits. A field left empty keeps the built-in one; a range is
+// cycled through frame by frame.
+type HeadersConfig struct {
+	SrcMAC  string `yaml:"src_mac"`  // Default: the interface's MAC
+	DstMAC  string `yaml:"dst_mac"`  // DUT port or next-hop router (default 02:00:00:00:00:02)
+	SrcIP   string `yaml:"src_ip"`   // IPv4 address or range, e.g. 10.1.0.1-10.1.0.50 or 10.1.0.0/24 (default 10.0.0.1)
+	DstIP   string `yaml:"dst_ip"`   // Default: 10.0.0.2
+	SrcPort string `yaml:"src_port"` // UDP port or range, e.g. 5000-5099 (default 12345)
+	DstPort string `yaml:"dst_port"` // Default: 3842
+}
+
+// Headers are the parsed header fields; a zero field keeps the built-in one
+type Headers struct {
+	SrcMAC, DstMAC   net.HardwareAddr
+	SrcIP, DstIP     addrpool.Range
+	SrcPort, DstPort PortRange
+}
+
+// PortRange is an inclusive range of UDP ports
+type PortRange struct {
+	First, Last uint16
+}
+
+// Size is the number of ports in the range; 0 for an unset range
+func (r PortRange) Size() uint32 {
+	if r.First == 0 || r.Last < r.First {
+		return 0
+	}
+	return uint32(r.Last-r.First) + 1
+}
+
+// Enabled reports whether any header field is set
+func (h HeadersConfig) Enabled() bool {
+	return h != HeadersConfig{}
+}
+
+// Ranged reports whether any field is a range of addresses or ports
+func (h Headers) Ranged() bool {
+	return h.SrcIP.Size() > 1 || h.DstIP.Size() > 1 || h.SrcPort.Size() > 1 || h.DstPort.Size() > 1
+}
+
+// Parse returns the header fields
+func (h HeadersConfig) Parse() (Headers, error) {
+	var out Headers
+	for _, f := range []struct {
+		name, text string
+		mac        *net.HardwareAddr
+	}{{"src_mac", h.SrcMAC, &out.SrcMAC}, {"dst_mac", h.DstMAC, &out.DstMAC}} {
+		if f.text == "" {
+			continue
+		}
+		mac, err := net.ParseMAC(f.text)
+		if err != nil || len(mac) != 6 {
+			return out, fmt.Errorf("headers %s: invalid MAC address %q", f.name, f.text)
+		}
+		if mac[0]&1 != 0 || bytes.Equal(mac, make(net.HardwareAddr, 6)) {
+			return out, fmt.Errorf("headers %s: %s is not a unicast address", f.name, f.text)
+		}
+		*f.mac = mac
+	}
+	for _, f := range []struct {
+		name, text string
+		r          *addrpool.Range
+	}{{"src_ip", h.SrcIP, &out.SrcIP}, {"dst_ip", h.DstIP, &out.DstIP}} {
+		if f.text == "" {
+			continue
+		}
+		r, err := addrpool.ParseIPRange(f.text)
+		if err != nil {
+			return out, fmt.Errorf("headers %s: %w", f.name, err)
+		}
+		*f.r = r
+	}
+	for _, f := range []struct {
+		name, text string
+		r          *PortRange
+	}{{"src_port", h.SrcPort, &out.SrcPort}, {"dst_port", h.DstPort, &out.DstPort}} {
+		if f.text == "" {
+			continue
+		}
+		r, err := ParsePortRange(f.text)
+		if err != nil {
+			return out, fmt.Errorf("headers %s: %w", f.name, err)
+		}
+		*f.r = r
+	}
+	return out, nil
+}
+
+// ParsePortRange parses "FIRST-LAST" or a single UDP port
+func ParsePortRange(s string) (PortRange, error) {
+	first, last, _ := strings.Cut(s, "-")
+	if last == "" {
+		last = first
+	}
+	var r PortRange
+	for i, part := range []string{first, last} {
+		port, err := strconv.ParseUint(strings.TrimSpace(part), 10, 16)
+		if err != nil || port == 0 {
+			return PortRange{}, fmt.Errorf("invalid port range %q", s)
+		}
+		if i == 0 {
+			r.First = uint16(port)
+		} else {
+			r.Last = uint16(port)
+		}
+	}
+	if r.Last < r.First {
+		return PortRange{}, fmt.Errorf("port range %q ends before it starts", s)
+	}
+	return r, nil
+}
+
+// Encapsulation selects how generated frames are framed
+type Encapsulation string
+
+const (
+	EncapEthernetII Encapsulation = "ethernet_ii" // DIX header with EtherType
+	EncapLLCSNAP    Encapsulation = "llc_snap"    // 802.3 length + LLC/SNAP header
+)
+
+// FramingConfig sets the EtherType and encapsulation of test frames so
+// protocol-specific forwarding policies (e.g., PPPoE EtherType filters or
+// MPLS label switching) can be exercised
+type FramingConfig struct {
+	Encapsulation Encapsulation `yaml:"encapsulation"`     // ethernet_ii (default) or llc_snap
+	EtherType     uint16        `yaml:"ethertype"`         // EtherType or SNAP protocol ID (0 = IPv4)
+	VLANID        uint16        `yaml:"vlan_id"`           // 802.1Q tag, counted in the frame size (0 = untagged unless pcp is set)
+	PCP           uint8         `yaml:"pcp"`               // 802.1p priority of the tag (0-7)
+	TPID          uint16        `yaml:"tpid"`              // TPID of that tag (0 = 0x8100)
+	OuterVLANID   uint16        `yaml:"outer_vlan_id"`     // QinQ S-tag over it, 4 more bytes of the frame (0 = none unless outer_pcp is set)
+	OuterPCP      uint8         `yaml:"outer_pcp"`         // 802.1p priority of the S-tag
+	OuterTPID     uint16        `yaml:"outer_tpid"`        // TPID of the S-tag (0 = 0x88a8)
+	MPLSLabels    []MPLSLabel   `yaml:"mpls_labels"`       // Label stack after the tags, outermost first, 4 bytes a label
+	EXPFromCoS    bool          `yaml:"mpls_exp_from_cos"` // Y.1564: mark each label's EXP with the service CoS (DSCP >> 3)
+}
+
+// MPLSLabel is one entry of the label stack test frames carry into an LSP
+type MPLSLabel struct {
+	Label uint32 `yaml:"label" json:"label"` // 20-bit label (16-1048575)
+	EXP   uint8  `yaml:"exp" json:"exp"`     // Traffic class bits (0-7)
+	TTL   uint8  `yaml:"ttl" json:"ttl"`     // 0 = 64
+}
+
+// IsDefault reports whether frames are plain untagged Ethernet II IPv4
+func (f FramingConfig) IsDefault() bool {
+	return f.Encapsulation != EncapLLCSNAP && (f.EtherType == 0 || f.EtherType == 0x0800) && !f.Tagged() &&
+		len(f.MPLSLabels) == 0
+}
+
+// Tagged reports whether frames carry a VLAN tag
+func (f FramingConfig) Tagged() bool {
+	return f.Tags() > 0
+}
+
+// Tags returns how many VLAN tags frames carry: 0, 1, or 2 with QinQ
+func (f FramingConfig) Tags() int {
+	n := 0
+	if f.VLANID != 0 || f.PCP != 0 {
+		n++
+	}
+	if f.OuterVLANID != 0 || f.OuterPCP != 0 {
+		n++
+	}
+	return n
+}
+
+// String describes the framing, e.g. "llc_snap/0x0800"
+func (f FramingConfig) String() string {
+	enc := f.Encapsulation
+	if enc == "" {
+		enc = EncapEthernetII
+	}
+	etherType := f.EtherType
+	if etherType == 0 {
+		etherType = 0x0800
+	}
+	s := fmt.Sprintf("%s/0x%04x", enc, etherType)
+	if f.VLANID != 0 || f.PCP != 0 {
+		s += fmt.Sprintf("/vlan %d pcp %d", f.VLANID, f.PCP)
+		if f.TPID != 0 && f.TPID != 0x8100 {
+			s += fmt.Sprintf(" tpid 0x%04x", f.TPID)
+		}
+	}
+	if f.OuterVLANID != 0 || f.OuterPCP != 0 {
+		tpid := f.OuterTPID
+		if tpid == 0 {
+			tpid = 0x88a8
+		}
+		s += fmt.Sprintf("/s-vlan %d pcp %d tpid 0x%04x", f.OuterVLANID, f.OuterPCP, tpid)
+	}
+	for _, l := range f.LabelStack() {
+		s += fmt.Sprintf("/mpls %d exp %d ttl %d", l.Label, l.EXP, l.TTL)
+	}
+	return s
+}
+
+// LabelStack returns the MPLS labels as frames carry them, TTL 0 as 64
+func (f FramingConfig) LabelStack() []MPLSLabel {
+	if len(f.MPLSLabels) == 0 {
+		return nil
+	}
+	labels := make([]MPLSLabel, len(f.MPLSLabels))
+	for i, l := range f.MPLSLabels {
+		if l.TTL == 0 {
+			l.TTL = 64
+		}
+		labels[i] = l
+	}
+	return labels
+}
+
+// PacketConfig replaces the built-in headers of RFC 2544 test frames with a
+// layer expression, e.g. "eth/dot1q(vlan=100,pcp=5)/ipv6/udp(dport=3842)",
+// instead of a config knob per header field
+type PacketConfig struct {
+	Template string `yaml:"template"`
+}
+
+// Enabled reports whether a packet template is set
+func (p PacketConfig) Enabled() bool {
+	return p.Template != ""
+}
+
+// ControlPlaneConfig for the control-plane policing stress test. When
+// enabled, each RFC 2544 test is repeated while ICMP, ARP and BGP-port
+// traffic is aimed at the DUT's own address, and the forwarding result is
+// compared with the baseline.
+type ControlPlaneConfig struct {
+	DUTIP             string  `yaml:"dut_ip"`              // DUT management/interface IPv4 address
+	DUTMAC            string  `yaml:"dut_mac"`             // DUT MAC (empty = test destination MAC)
+	ICMPPerSec        uint32  `yaml:"icmp_pps"`            // ICMP echo requests per second
+	ARPPerSec         uint32  `yaml:"arp_pps"`             // ARP requests per second
+	BGPPerSec         uint32  `yaml:"bgp_pps"`             // TCP SYNs to port 179 per second
+	MaxDegradationPct float64 `yaml:"max_degradation_pct"` // Allowed throughput drop before failing
+}
+
+// Enabled reports whether control-plane stress is configured
+func (c ControlPlaneConfig) Enabled() bool {
+	return c.DUTIP != "" && (c.ICMPPerSec > 0 || c.ARPPerSec > 0 || c.BGPPerSec > 0)
+}
+
+// OAMConfig for Ethernet OAM far-end loopback. When RemoteLoopback is set,
+// the far-end port is placed into 802.3ah remote loopback before testing
+// and released afterwards.
+type OAMConfig struct {
+	RemoteLoopback bool          `yaml:"remote_loopback"` // Loop the far end via OAM before testing
+	PeerMAC        string        `yaml:"peer_mac"`        // Far-end MAC (empty = first discovered capable peer)
+	MEGLevel       uint8         `yaml:"meg_level"`       // MEG level for Y.1731 LBM discovery
+	Timeout        time.Duration `yaml:"timeout"`         // Discovery and loopback confirmation timeout
+}
+
+// SoakConfig for long-duration soak tests. Traffic is offered at a fixed
+// rate in back-to-back sample trials, and results are averaged into buckets
+// so throughput and latency drift over the run can be reported.
+type SoakConfig struct {
+	Duration              time.Duration `yaml:"duration"`                 // Total soak time
+	RatePct               float64       `yaml:"rate_pct"`                 // Offered load (% of line rate)
+	SampleDuration        time.Duration `yaml:"sample_duration"`          // Length of each sample trial
+	BucketInterval        time.Duration `yaml:"bucket_interval"`          // Drift reporting interval
+	MaxThroughputDriftPct float64       `yaml:"max_throughput_drift_pct"` // Allowed throughput drop, first to last bucket
+	MaxLatencyDriftPct    float64       `yaml:"max_latency_drift_pct"`    // Allowed P99 latency rise, first to last bucket
+
+	// SeriesFile receives every sample as it is taken, a row per
+	// sample_duration, as CSV or with a .jsonl name JSON lines. Empty
+	// writes it beside the output file, if there is one.
+	SeriesFile string `yaml:"series_file"`
+}
+
+// QoSMarking selects the headers that carry a QoS class
+type QoSMarking string
+
+const (
+	MarkDSCP QoSMarking = "dscp" // IPv4 DSCP in untagged frames
+	MarkPCP  QoSMarking = "pcp"  // 802.1Q PCP, with the DSCP set as well
+)
+
+// QoSClass is one marked stream of the QoS test
+type QoSClass struct {
+	Name     string  `yaml:"name"`
+	PCP      uint8   `yaml:"pcp"`       // 802.1p priority, 0-7
+	DSCP     uint8   `yaml:"dscp"`      // 0-63
+	SharePct float64 `yaml:"share_pct"` // Share of the offered load
+}
+
+// QoSConfig for the QoS scheduling matrix test. One stream per class is
+// sent at once, together offering load_pct of line rate; under saturation
+// each class's share of the delivered traffic, loss and latency show how
+// the DUT schedules its queues. Per-class counters need TRex.
+type QoSConfig struct {
+	Marking QoSMarking `yaml:"marking"`  // dscp or pcp
+	VLAN    uint16     `yaml:"vlan"`     // VLAN ID of PCP-marked frames (0 = priority-tagged)
+	LoadPct float64    `yaml:"load_pct"` // Total offered load (% of line rate)
+	Classes []QoSClass `yaml:"classes"`  // Empty = eight classes, PCP 0-7 / CS0-CS7, equal shares
+}
+
+// ClassList returns the configured classes, or the eight default ones
+func (q QoSConfig) ClassList() []QoSClass {
+	if len(q.Classes) > 0 {
+		return q.Classes
+	}
+	classes := make([]QoSClass, 8)
+	for i := range classes {
+		classes[i] = QoSClass{Name: fmt.Sprintf("CS%d", i), PCP: uint8(i), DSCP: uint8(i * 8), SharePct: 12.5}
+	}
+	return classes
+}
+
+// AQMConfig for the WRED/AQM characterization test. Offered load rises
+// in small fixed-rate steps; loss and the average latency above the
+// unloaded baseline at each step locate where the DUT starts dropping,
+// where its queue stops growing and the drop slope in between. The class
+// under test is the one the frames are marked for: set the marking with a
+// packet template on the local dataplane.
+type AQMConfig struct {
+	StartPct     float64       `yaml:"start_pct"`      // First offered load (% of line rate)
+	EndPct       float64       `yaml:"end_pct"`        // Last offered load (% of line rate)
+	StepPct      float64       `yaml:"step_pct"`       // Load increase per step
+	StepDuration time.Duration `yaml:"step_duration"`  // Length of each step's trial
+	LossFloorPct float64       `yaml:"loss_floor_pct"` // Loss at or below this counts as none
+}
+
+// MicroburstConfig for the microburst test. Trains of equal bursts, sent
+// at burst_rate_pct within each burst and separated by an idle gap, grow
+// until a train loses frames; the largest lossless burst and the rate the
+// DUT drains at give its egress buffer depth. The search repeats for each
+// gap, since a gap too short for the queue to empty builds it up over the
+// train. Each burst's loss is reported, so it shows which bursts of the
+// first lossy train overflowed.
+type MicroburstConfig struct {
+	StartFrames      uint32          `yaml:"start_frames"`      // First burst size tried
+	MaxFrames        uint32          `yaml:"max_frames"`        // Largest burst size tried
+	StepFrames       uint32          `yaml:"step_frames"`       // Growth per train until loss (0 = double)
+	ResolutionFrames uint32          `yaml:"resolution_frames"` // Bisect until the lossless and lossy sizes are this close
+	Bursts           uint32          `yaml:"bursts"`            // Bursts per train
+	Gaps             []time.Duration `yaml:"gaps"`              // Idle time between bursts, one search each
+	BurstRatePct     float64         `yaml:"burst_rate_pct"`    // Rate within a burst (% of line rate)
+	EgressMbps       float64         `yaml:"egress_mbps"`       // DUT egress rate (0 = measure from the frames received)
+}
+
+// SelfTestConfig for the tester self-test. A baseline trial and then one
+// trial per case run at a fixed rate through a path impaired with Linux
+// netem, and each case passes when the measured latency, jitter, loss and
+// reordering match what was injected. The tester's frames bypass its own
+// qdisc, so netem goes on the egress of the reflector's end of the path,
+// normally the far end of a veth pair; tc must be installed.
+type SelfTestConfig struct {
+	Interface string           `yaml:"interface"` // Reflector-side interface netem impairs
+	RatePct   float64          `yaml:"rate_pct"`  // Offered load of each trial (% of line rate)
+	Duration  time.Duration    `yaml:"duration"`  // Length of each trial
+	Cases     []impair.Case    `yaml:"cases"`     // Empty = the built-in suite
+	Tolerance impair.Tolerance `yaml:"tolerance"` // Allowed error of each measurement
+}
+
+// Suite returns the cases to run
+func (s SelfTestConfig) Suite() []impair.Case {
+	if len(s.Cases) == 0 {
+		return impair.DefaultSuite
+	}
+	return s.Cases
+}
+
+// HeatmapConfig for the latency heatmap written after soak and Y.1564
+// performance runs. Soak columns are the sample trials; Y.1564
+// performance tests are split into segments to get columns, and each
+// segment repeats the test's 5s warmup.
+type HeatmapConfig struct {
+	File    string        `yaml:"file"`    // Output .png, .svg or .html (empty = disabled)
+	Rows    int           `yaml:"rows"`    // Latency rows, log-spaced between the lowest and highest latency
+	Segment time.Duration `yaml:"segment"` // Y.1564 performance column width
+}
+
+// Enabled reports whether a heatmap is written
+func (h HeatmapConfig) Enabled() bool {
+	return h.File != ""
+}
+
+// UDPEchoConfig for the UDP echo test. Datagrams sized for each frame size
+// are sent to an rfc2544 reflector, which returns them over any routed or
+// NATed path. Results are round trip with software timestamps.
+type UDPEchoConfig struct {
+	Target   string        `yaml:"target"`   // Reflector host[:port] (default port 3842)
+	RatePPS  uint32        `yaml:"rate_pps"` // Datagrams per second
+	Duration time.Duration `yaml:"duration"` // Sending time per frame size
+	Timeout  time.Duration `yaml:"timeout"`  // Wait for late replies
+}
+
+// Power sources
+const (
+	PowerSourceIPMI    = "ipmi"    // ipmitool DCMI power reading
+	PowerSourceCommand = "command" // Any command printing watts (e.g. PDU snmpget)
+)
+
+// PowerConfig for sampling DUT power draw during tests, used to report
+// throughput per watt for each frame size
+type PowerConfig struct {
+	Source       string        `yaml:"source"`    // "", ipmi or command
+	Command      string        `yaml:"command"`   // Command for the command source
+	Scale        float64       `yaml:"scale"`     // Multiplier for command output (e.g. 0.1 for deciwatts)
+	IPMIHost     string        `yaml:"ipmi_host"` // BMC address (empty = local in-band)
+	IPMIUser     string        `yaml:"ipmi_user"`
+	IPMIPassword string        `yaml:"ipmi_password"`
+	Interval     time.Duration `yaml:"interval"` // Sampling interval
+}
+
+// Enabled reports whether power sampling is configured
+func (p PowerConfig) Enabled() bool {
+	return p.Source != ""
+}
+
+// TRexConfig for generating traffic on a TRex server instead of the local
+// dataplane. Trials run on the server's ports; the interface is not used.
+type TRexConfig struct {
+	Server  string        `yaml:"server"`  // host[:port] of the RPC server (empty = local dataplane)
+	TxPort  uint8         `yaml:"tx_port"` // Port sending toward the DUT
+	RxPort  uint8         `yaml:"rx_port"` // Port receiving from the DUT (same as tx_port for loopback)
+	User    string        `yaml:"user"`    // Owner name shown by TRex
+	Force   bool          `yaml:"force"`   // Take ports acquired by another user
+	Timeout time.Duration `yaml:"timeout"` // RPC timeout
+	Drain   time.Duration `yaml:"drain"`   // Wait for in-flight frames after each trial
+}
+
+// Enabled reports whether a TRex server is configured
+func (t TRexConfig) Enabled() bool {
+	return t.Server != ""
+}
+
+// MaxSocketPPS caps socket mode at a rate ordinary UDP sockets can pace
+// reliably from a small VM
+const MaxSocketPPS = 50000
+
+// SocketConfig for running trials over ordinary UDP sockets against a
+// reflector, without raw sockets, XDP or root. Rates are a percentage of
+// max_pps rather than line rate, and latency is round trip with software
+// timestamps, so results are labeled reduced accuracy.
+type SocketConfig struct {
+	Target  string        `yaml:"target"`  // Reflector host[:port] (empty = disabled)
+	MaxPPS  uint32        `yaml:"max_pps"` // Rate treated as 100% of line rate
+	Timeout time.Duration `yaml:"timeout"` // Wait for late replies after each trial
+}
+
+// Enabled reports whether socket mode is configured
+func (s SocketConfig) Enabled() bool {
+	return s.Target != ""
+}
+
+// PreQualConfig for the ping, traceroute and path MTU checks run before
+// the tests, so failures can be attributed to reachability
+type PreQualConfig struct {
+	Targets    []string      `yaml:"targets"` // IPv4/IPv6 addresses or host names (empty = disabled)
+	Count      int           `yaml:"count"`   // Echo requests per target
+	Timeout    time.Duration `yaml:"timeout"` // Per-probe reply timeout
+	Traceroute bool          `yaml:"traceroute"`
+	MaxHops    int           `yaml:"max_hops"`
+	PathMTU    bool          `yaml:"path_mtu"`
+	MaxMTU     int           `yaml:"max_mtu"`  // Upper bound for the path MTU search
+	Required   bool          `yaml:"required"` // Abort when any target is unreachable
+}
+
+// Enabled reports whether pre-qualification is configured
+func (p PreQualConfig) Enabled() bool {
+	return len(p.Targets) > 0
+}
+
+// DUTConfig describes the device under test. It is written to the results
+// metadata so runs against different devices can be compared.
+type DUTConfig struct {
+	Name     string `json:"name,omitempty" yaml:"name"` // Label used in comparison reports
+	Vendor   string `json:"vendor,omitempty" yaml:"vendor"`
+	Model    string `json:"model,omitempty" yaml:"model"`
+	Firmware string `json:"firmware,omitempty" yaml:"firmware"`
+
+	// Largest frame, FCS included, the DUT's ports forward, e.g. 9216 on
+	// most jumbo switches. Larger test frames are refused. 0 = not checked.
+	MaxFrameSize uint32 `json:"max_frame_size,omitempty" yaml:"max_frame_size"`
+}
+
+// DUTSnapshotConfig fetches the DUT configuration before and after a run,
+// so a change made mid-run is caught. Commands run on the DUT over ssh, or
+// locally when SSH is empty (e.g. netconf-console --host r1 --get-config).
+type DUTSnapshotConfig struct {
+	SSH      string        `yaml:"ssh"`      // user@host, key authentication only
+	Commands []string      `yaml:"commands"` // e.g. "show running-config"
+	Ignore   []string      `yaml:"ignore"`   // Regexps for lines that change on their own, e.g. timestamps
+	Timeout  time.Duration `yaml:"timeout"`  // Per command
+}
+
+// Enabled reports whether snapshots are configured
+func (d DUTSnapshotConfig) Enabled() bool {
+	return len(d.Commands) > 0
+}
+
+// IsZero reports whether no DUT metadata is set
+func (d DUTConfig) IsZero() bool {
+	return d == DUTConfig{}
+}
+
+// Label returns the name, or vendor and model when no name is set
+func (d DUTConfig) Label() string {
+	if d.Name != "" {
+		return d.Name
+	}
+	return strings.TrimSpace(d.Vendor + " " + d.Model)
+}
+
+// CertificateConfig identifies the circuit on the PDF acceptance
+// certificate. Results are judged against the thresholds section and, for
+// Y.1564, each service's own SLA.
+type CertificateConfig struct {
+	CircuitID  string   `yaml:"circuit_id"`
+	Customer   string   `yaml:"customer"`
+	Location   string   `yaml:"location"`
+	Technician string   `yaml:"technician"`
+	Tags       []string `yaml:"tags"` // e.g. gold, metro-east; select the ticketing connectors
+}
+
+// Ticketing systems a connector files failed validations with
+const (
+	TicketServiceNow = "servicenow"
+	TicketJira       = "jira"
+)
+
+// TicketingConfig is a connector that opens a ticket with the report
+// attached when a run fails its thresholds, e.g. a scheduled SLA
+// validation, or updates the ticket still open for the circuit
+type TicketingConfig struct {
+	Name     string   `yaml:"name"`     // Shown in the log (default: the URL)
+	System   string   `yaml:"system"`   // servicenow or jira
+	URL      string   `yaml:"url"`      // e.g. https://example.service-now.com
+	User     string   `yaml:"user"`     // Jira Cloud: account email; empty sends password as a bearer token
+	Password string   `yaml:"password"` // Password or API token, e.g. ${secret:JIRA_TOKEN}
+	Tags     []string `yaml:"tags"`     // Circuits tagged with any of these (empty = every circuit)
+
+	Table           string `yaml:"table"`            // ServiceNow table (default: incident)
+	AssignmentGroup string `yaml:"assignment_group"` // ServiceNow
+	Project         string `yaml:"project"`          // Jira project key
+	IssueType       string `yaml:"issue_type"`       // Jira (default: Bug)
+}
+
+// Matches reports whether the connector files for a circuit with tags
+func (t TicketingConfig) Matches(tags []string) bool {
+	if len(t.Tags) == 0 {
+		return true
+	}
+	for _, want := range t.Tags {
+		for _, tag := range tags {
+			if strings.EqualFold(want, tag) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ThresholdsConfig sets pass limits on the results. A run that completes
+// but violates one exits with a distinct code, so scripts can gate on
+// benchmark regressions without parsing output.
+type ThresholdsConfig struct {
+	MinThroughputMbps map[uint32]float64 `yaml:"min_throughput_mbps"` // Per frame size
+	MinThroughputPct  float64            `yaml:"min_throughput_pct"`  // Every frame size, % of line rate (0 = not checked)
+	MaxLatencyAvgUs   float64            `yaml:"max_latency_avg_us"`  // 0 = not checked
+	MaxLatencyP99Us   float64            `yaml:"max_latency_p99_us"`  // 0 = not checked
+	MaxFrameLossPct   float64            `yaml:"max_frame_loss_pct"`  // At the highest offered load (negative = not checked)
+	Y1564MustPass     bool               `yaml:"y1564_must_pass"`     // Every Y.1564 service must pass its SLA
+}
+
+// Enabled reports whether any threshold is set
+func (t ThresholdsConfig) Enabled() bool {
+	return len(t.MinThroughputMbps) > 0 || t.MinThroughputPct > 0 || t.MaxLatencyAvgUs > 0 ||
+		t.MaxLatencyP99Us > 0 || t.MaxFrameLossPct >= 0 || t.Y1564MustPass
+}
+
+// GateConfig pauses a TUI run before one phase until the operator
+// continues, for procedures that re-cable between stages. Set either
+// FrameSize or ServiceID.
+type GateConfig struct {
+	FrameSize uint32 `yaml:"frame_size"` // Before this frame size's trials
+	ServiceID uint32 `yaml:"service_id"` // Before this Y.1564 service
+	Message   string `yaml:"message"`    // e.g. "Re-patch to port 2 and press Continue"
+}
+
+// WebUIConfig for web interface
+type WebUIConfig struct {
+	Enabled     bool   `yaml:"enabled"`
+	Address     string `yaml:"address"`      // e.g., ":8080"
+	ShareSecret string `yaml:"share_secret"` // Key signing share links (empty = random per start)
+	PublicURL   string `yaml:"public_url"`   // Base URL for share links (empty = request host)
+}
+
+// TUIConfig customizes the terminal UI: the keys of each action, for
+// terminals that take F1 or F10 for themselves, the status bar text,
+// e.g. in the operator's language, where the history page finds saved
+// runs, and plain mode for consoles that cannot show colors or Unicode
+type TUIConfig struct {
+	Title      string    `yaml:"title"` // Status bar title (default: RFC2544 Test Master)
+	Keys       TUIKeys   `yaml:"keys"`
+	Labels     TUILabels `yaml:"labels"`
+	HistoryDir string    `yaml:"history_dir"` // JSON results files (-o json) to browse (default: current directory)
+	Plain      bool      `yaml:"plain"`       // No colors or Unicode graphics (--tui-plain)
+}
+
+// TUIKeys binds the TUI actions to keys such as F5, Ctrl-S or q. A list
+// replaces the action's defaults.
+type TUIKeys struct {
+	Start []string `yaml:"start"` // Default: F1, s
+	Stop  []string `yaml:"stop"`  // Default: F2, x
+	Help  []string `yaml:"help"`  // Default: F3, ?
+	Quit  []string `yaml:"quit"`  // Default: F10, Esc, q
+}
+
+// Names returns the keys by action, for tui.Keymap
+func (k TUIKeys) Names() map[tui.Action][]string {
+	return map[tui.Action][]string{
+		tui.ActionStart: k.Start,
+		tui.ActionStop:  k.Stop,
+		tui.ActionHelp:  k.Help,
+		tui.ActionQuit:  k.Quit,
+	}
+}
+
+// TUILabels name the TUI actions in the status bar and help (default:
+// Start, Stop, Help, Quit)
+type TUILabels struct {
+	Start string `yaml:"start"`
+	Stop  string `yaml:"stop"`
+	Help  string `yaml:"help"`
+	Quit  string `yaml:"quit"`
+}
+
+// GNMIConfig for streaming live statistics to telemetry collectors
+type GNMIConfig struct {
+	Address  string `yaml:"address"`   // e.g., ":9339" (empty = disabled)
+	CertFile string `yaml:"cert_file"` // TLS certificate (empty = self-signed)
+	KeyFile  string `yaml:"key_file"`
+}
+
+// Enabled reports whether the gNMI target is configured
+func (g GNMIConfig) Enabled() bool {
+	return g.Address != ""
+}
+
+// ProfileConfig enables pprof endpoints and samples the tester's own CPU
+// and memory use, recorded with the run
+type ProfileConfig struct {
+	PprofAddress string        `yaml:"pprof_address"` // e.g., "localhost:6060" (empty = disabled)
+	Interval     time.Duration `yaml:"interval"`      // Resource sampling interval
+}
+
+// Enabled reports whether self-profiling is configured
+func (p ProfileConfig) Enabled() bool {
+	return p.PprofAddress != ""
+}
+
+// Y1564SLA defines SLA parameters for Y.1564 testing
+type Y1564SLA struct {
+	CIRMbps         float64 `yaml:"cir_mbps"`          // Committed Information Rate
+	EIRMbps         float64 `yaml:"eir_mbps"`          // Excess Information Rate
+	CBSBytes        uint32  `yaml:"cbs_bytes"`         // Committed Burst Size
+	EBSBytes        uint32  `yaml:"ebs_bytes"`         // Excess Burst Size
+	FDThresholdMs   float64 `yaml:"fd_threshold_ms"`   // Frame Delay threshold (ms)
+	FDVThresholdMs  float64 `yaml:"fdv_threshold_ms"`  // Frame Delay Variation threshold (ms)
+	FLRThresholdPct float64 `yaml:"flr_threshold_pct"` // Frame Loss Ratio threshold (%)
+
+	// Acceptance criteria for the configuration test steps above CIR
+	EIRSAC      Y1564SAC `yaml:"eir_sac"`      // CIR + EIR step
+	PolicingSAC Y1564SAC `yaml:"policing_sac"` // Policing step at 125% of CIR + EIR
+}
+
+// Y1564SAC overrides the service acceptance criteria for a configuration
+// test step above CIR. Delay thresholds left at 0 are the SLA's; loss is
+// only checked when flr_threshold_pct is given, since frames above CIR may
+// be dropped.
+type Y1564SAC struct {
+	FDThresholdMs   float64  `yaml:"fd_threshold_ms"`
+	FDVThresholdMs  float64  `yaml:"fdv_threshold_ms"`
+	FLRThresholdPct *float64 `yaml:"flr_threshold_pct"`
+}
+
+// Criteria resolves a step's acceptance criteria against the SLA. An FLR
+// threshold below 0 means loss is not checked.
+func (s Y1564SLA) Criteria(sac Y1564SAC) (fdMs, fdvMs, flrPct float64) {
+	fdMs, fdvMs, flrPct = s.FDThresholdMs, s.FDVThresholdMs, -1
+	if sac.FDThresholdMs > 0 {
+		fdMs = sac.FDThresholdMs
+	}
+	if sac.FDVThresholdMs > 0 {
+		fdvMs = sac.FDVThresholdMs
+	}
+	if sac.FLRThresholdPct != nil {
+		flrPct = *sac.FLRThresholdPct
+	}
+	return fdMs, fdvMs, flrPct
+}
+
+// Y1564Service defines a service for Y.1564 testing
+type Y1564Service struct {
+	ServiceID   uint32   `yaml:"service_id"`
+	ServiceName string   `yaml:"service_name"`
+	SLA         Y1564SLA `yaml:"sla"`
+	FrameSize   uint32   `yaml:"frame_size"`
+	CoS         uint8    `yaml:"cos"` // Class of Service (DSCP value)
+	Enabled     bool     `yaml:"enabled"`
+	VLANID      uint16   `yaml:"vlan_id"` // 802.1Q tag of the service's frames (vlan_id and pcp 0 = framing's tag)
+	PCP         uint8    `yaml:"pcp"`
+	OuterVLANID uint16   `yaml:"outer_vlan_id"` // QinQ S-tag over it (outer_vlan_id and outer_pcp 0 = framing's S-tag)
+	OuterPCP    uint8    `yaml:"outer_pcp"`
+	IPVersion   uint8    `yaml:"ip_version"` // 4 or 6 (0 = 6 with ip_version 6, else 4); IPv6 takes the ipv6 addresses
+}
+
+// Y1564Config for ITU-T Y.1564 testing
+type Y1564Config struct {
+	Services      []Y1564Service    `yaml:"services"`
+	ConfigSteps   []float64         `yaml:"config_steps"`    // Step percentages (default: 25, 50, 75, 100)
+	StepDuration  time.Duration     `yaml:"step_duration"`   // Duration per step (default: 60s)
+	PerfDuration  time.Duration     `yaml:"perf_duration"`   // Performance test duration (default: 15m)
+	RunConfigTest bool              `yaml:"run_config_test"` // Run configuration test
+	RunPerfTest   bool              `yaml:"run_perf_test"`   // Run performance test
+	PolicingStep  bool              `yaml:"policing_step"`   // Add a step at 125% of CIR + EIR to the configuration test
+	FlowFailure   FlowFailureConfig `yaml:"flow_failure"`    // Policy when a service flow fails
+}
+
+// FlowFailurePolicy selects what happens when a flow's path fails
+type FlowFailurePolicy string
+
+const (
+	FlowPolicyMarkFailed FlowFailurePolicy = "mark_failed" // Skip the failed flow, continue with the rest
+	FlowPolicyRebalance  FlowFailurePolicy = "rebalance"   // Redistribute its rate to remaining flows
+	FlowPolicyAbort      FlowFailurePolicy = "abort"       // Stop the run
+)
+
+// FlowFailureConfig for multi-flow tests. A flow fails after PersistTrials
+// consecutive trials with 100% loss.
+type FlowFailureConfig struct {
+	Policy        FlowFailurePolicy `yaml:"policy"`         // Default: mark_failed
+	PersistTrials int               `yaml:"persist_trials"` // Default: 2
+}
+
+// TrafficOrientation selects how traffic flows between the RFC 2889 ports.
+// One trial drives one flow, TX port to RX port, so the orientations are
+// those of two ports; mesh and pair-wise patterns need a port per flow.
+type TrafficOrientation string
+
+const (
+	OrientationOneToMany TrafficOrientation = "one_to_many" // First port to the other
+	OrientationManyToOne TrafficOrientation = "many_to_one" // The other port to the last
+)
+
+// RFC2889Config for LAN switch benchmarking tests
+type RFC2889Config struct {
+	PortCount         uint32               `yaml:"port_count"`          // Number of ports
+	AddressCount      uint32               `yaml:"address_count"`       // MAC addresses for caching test
+	TrialDuration     time.Duration        `yaml:"trial_duration"`      // Duration per trial
+	AcceptableLossPct float64              `yaml:"acceptable_loss_pct"` // Acceptable loss percentage
+	Orientations      []TrafficOrientation `yaml:"orientations"`        // Patterns to run (default: one_to_many)
+}
+
+// RFC6349Config for TCP throughput testing
+type RFC6349Config struct {
+	TargetRateMbps  float64       `yaml:"target_rate_mbps"` // Target rate (0 = auto)
+	MSS             uint32        `yaml:"mss"`              // Maximum Segment Size
+	RWND            uint32        `yaml:"rwnd"`             // Receive Window Size
+	TestDuration    time.Duration `yaml:"test_duration"`    // Test duration
+	ParallelStreams uint32        `yaml:"parallel_streams"` // Number of parallel streams
+}
+
+// Y1731Config for Ethernet OAM testing
+type Y1731Config struct {
+	MEPID         uint32        `yaml:"mep_id"`         // MEP identifier
+	MEGLevel      uint8         `yaml:"meg_level"`      // MEG level (0-7)
+	MEGID         string        `yaml:"meg_id"`         // MEG identifier
+	CCMInterval   uint32        `yaml:"ccm_interval"`   // CCM interval (ms)
+	ProbeCount    uint32        `yaml:"probe_count"`    // Number of probes
+	ProbeInterval time.Duration `yaml:"probe_interval"` // Interval between probes
+}
+
+// MEFConfig for service activation testing
+type MEFConfig struct {
+	CIRMbps           float64       `yaml:"cir_mbps"`            // Committed Information Rate
+	EIRMbps           float64       `yaml:"eir_mbps"`            // Excess Information Rate
+	CBSBytes          uint32        `yaml:"cbs_bytes"`           // Committed Burst Size
+	EBSBytes          uint32        `yaml:"ebs_bytes"`           // Excess Burst Size
+	FDThresholdUs     float64       `yaml:"fd_threshold_us"`     // Frame Delay threshold (us)
+	FDVThresholdUs    float64       `yaml:"fdv_threshold_us"`    // Frame Delay Variation (us)
+	FLRThresholdPct   float64       `yaml:"flr_threshold_pct"`   // Frame Loss Ratio threshold
+	AvailThresholdPct float64       `yaml:"avail_threshold_pct"` // Availability threshold
+	ConfigDuration    time.Duration `yaml:"config_duration"`     // Config test duration
+	PerfDuration      time.Duration `yaml:"perf_duration"`       // Perf test duration
+}
+
+// TSNConfig for Time-Sensitive Networking testing
+type TSNConfig struct {
+	NumClasses      uint32        `yaml:"num_classes"`        // Number of traffic classes
+	CycleTimeNs     uint64        `yaml:"cycle_time_ns"`      // GCL cycle time
+	MaxLatencyNs    uint64        `yaml:"max_latency_ns"`     // Maximum latency threshold
+	MaxJitterNs     uint64        `yaml:"max_jitter_ns"`      // Maximum jitter threshold
+	MaxSyncOffsetNs uint64        `yaml:"max_sync_offset_ns"` // Maximum PTP sync offset
+	TestDuration    time.Duration `yaml:"test_duration"`      // Test duration
+	FrameSize       uint32        `yaml:"frame_size"`         // Frame size for testing
+}
+
+// DefaultRFC2889Config returns default RFC 2889 configuration
+func DefaultRFC2889Config() RFC2889Config {
+	return RFC2889Config{
+		PortCount:         2,
+		AddressCount:      8192,
+		TrialDuration:     60 * time.Second,
+		AcceptableLossPct: 0.0,
+		Orientations:      []TrafficOrientation{OrientationOneToMany},
+	}
+}
+
+// DefaultRFC6349Config returns default RFC 6349 configuration
+func DefaultRFC6349Config() RFC6349Config {
+	return RFC6349Config{
+		TargetRateMbps:  0, // Auto-detect
+		MSS:             1460,
+		RWND:            65535,
+		TestDuration:    30 * time.Second,
+		ParallelStreams: 1,
+	}
+}
+
+// DefaultY1731Config returns default Y.1731 configuration
+func DefaultY1731Config() Y1731Config {
+	return Y1731Config{
+		MEPID:         1,
+		MEGLevel:      4,
+		MEGID:         "DEFAULT-MEG",
+		CCMInterval:   1000,
+		ProbeCount:    100,
+		ProbeInterval: time.Second,
+	}
+}
+
+// DefaultMEFConfig returns default MEF configuration
+func DefaultMEFConfig() MEFConfig {
+	return MEFConfig{
+		CIRMbps:           100.0,
+		EIRMbps:           0,
+		CBSBytes:          12000,
+		EBSBytes:          0,
+		FDThresholdUs:     10000, // 10ms
+		FDVThresholdUs:    5000,  // 5ms
+		FLRThresholdPct:   0.01,
+		AvailThresholdPct: 99.99,
+		ConfigDuration:    60 * time.Second,
+		PerfDuration:      15 * time.Minute,
+	}
+}
+
+// DefaultTSNConfig returns default TSN configuration
+func DefaultTSNConfig() TSNConfig {
+	return TSNConfig{
+		NumClasses:      8,
+		CycleTimeNs:     1000000, // 1ms
+		MaxLatencyNs:    100000,  // 100us
+		MaxJitterNs:     10000,   // 10us
+		MaxSyncOffsetNs: 1000,    // 1us
+		TestDuration:    60 * time.Second,
+		FrameSize:       128,
+	}
+}
+
+// DefaultY1564SLA returns default SLA parameters
+func DefaultY1564SLA() Y1564SLA {
+	return Y1564SLA{
+		CIRMbps:         100.0,
+		EIRMbps:         0.0,
+		CBSBytes:        12000,
+		EBSBytes:        0,
+		FDThresholdMs:   10.0,
+		FDVThresholdMs:  5.0,
+		FLRThresholdPct: 0.01,
+	}
+}
+
+// DefaultY1564Config returns default Y.1564 configuration
+func DefaultY1564Config() Y1564Config {
+	return Y1564Config{
+		Services:      []Y1564Service{},
+		ConfigSteps:   []float64{25, 50, 75, 100},
+		StepDuration:  60 * time.Second,
+		PerfDuration:  15 * time.Minute,
+		RunConfigTest: true,
+		RunPerfTest:   true,
+		FlowFailure: FlowFailureConfig{
+			Policy:        FlowPolicyMarkFailed,
+			PersistTrials: 2,
+		},
+	}
+}
+
+// DefaultConfig returns a configuration with RFC 2544 recommended defaults
+func DefaultConfig() *Config {
+	return &Config{
+		AutoDetect:    true,
+		TestType:      TestThroughput,
+		FrameSize:     0, // All standard sizes
+		IncludeJumbo:  false,
+		TrialDuration: 60 * time.Second,
+		WarmupPeriod:  2 * time.Second,
+
+		Throughput: ThroughputConfig{
+			InitialRatePct:  100.0,
+			ResolutionPct:   0.1,
+			MaxIterations:   20,
+			AcceptableLoss:  0.0,
+			SearchAlgorithm: SearchBinary,
+			StepPct:         10.0,
+		},
+
+		Latency: LatencyConfig{
+			Samples:    1000,
+			LoadLevels: []float64{10, 20, 30, 40, 50, 60, 70, 80, 90, 100},
+		},
+
+		FrameLoss: FrameLossConfig{
+			StartPct: 100.0,
+			EndPct:   10.0,
+			StepPct:  10.0,
+
+			SearchResolutionPct: 0.5,
+		},
+
+		BackToBack: BackToBackConfig{
+			InitialBurst: 1000,
+			Trials:       50,
+		},
+
+		Recovery: RecoveryConfig{
+			OverloadSec: 60,
+		},
+
+		Addressing: AddressingConfig{
+			Pairs: 1,
+		},
+
+		Framing: FramingConfig{
+			Encapsulation: EncapEthernetII,
+			EtherType:     0, // IPv4
+		},
+
+		ControlPlane: ControlPlaneConfig{
+			MaxDegradationPct: 1.0,
+		},
+
+		OAM: OAMConfig{
+			RemoteLoopback: false,
+			MEGLevel:       4,
+			Timeout:        3 * time.Second,
+		},
+
+		Power: PowerConfig{
+			Interval: time.Second,
+		},
+
+		DUTSnapshot: DUTSnapshotConfig{
+			Timeout: 30 * time.Second,
+		},
+
+		Thresholds: ThresholdsConfig{
+			MaxFrameLossPct: -1,
+		},
+
+		Profile: ProfileConfig{
+			Interval: 5 * time.Second,
+		},
+
+		TRex: TRexConfig{
+			RxPort:  1,
+			User:    "rfc2544",
+			Timeout: 10 * time.Second,
+			Drain:   2 * time.Second,
+		},
+
+		Heatmap: HeatmapConfig{
+			Rows:    heatmap.DefaultRows,
+			Segment: time.Minute,
+		},
+
+		Socket: SocketConfig{
+			MaxPPS:  10000,
+			Timeout: 2 * time.Second,
+		},
+
+		PreQual: PreQualConfig{
+			Count:      5,
+			Timeout:    time.Second,
+			Traceroute: true,
+			MaxHops:    30,
+			PathMTU:    true,
+			MaxMTU:     1500,
+		},
+
+		Soak: SoakConfig{
+			Duration:              24 * time.Hour,
+			RatePct:               90.0,
+			SampleDuration:        60 * time.Second,
+			BucketInterval:        15 * time.Minute,
+			MaxThroughputDriftPct: 1.0,
+			MaxLatencyDriftPct:    25.0,
+		},
+
+		QoS: QoSConfig{
+			Marking: MarkDSCP,
+			LoadPct: 100.0,
+		},
+
+		AQM: AQMConfig{
+			StartPct:     50.0,
+			EndPct:       100.0,
+			StepPct:      2.0,
+			StepDuration: 10 * time.Second,
+			LossFloorPct: 0.01,
+		},
+
+		Microburst: MicroburstConfig{
+			StartFrames:      16,
+			MaxFrames:        100000,
+			ResolutionFrames: 8,
+			Bursts:           10,
+			Gaps:             []time.Duration{time.Millisecond},
+			BurstRatePct:     100.0,
+		},
+
+		SelfTest: SelfTestConfig{
+			RatePct:  1.0,
+			Duration: 10 * time.Second,
+			Tolerance: impair.Tolerance{
+				DelayUs:  1000,
+				JitterUs: 500,
+				LossPct:  0.5,
+			},
+		},
+
+		UDPEcho: UDPEchoConfig{
+			RatePPS:  1000,
+			Duration: 60 * time.Second,
+			Timeout:  2 * time.Second,
+		},
+
+		Modifiers: ModifiersConfig{
+			BroadcastPct:     0, // Disabled
+			ManagementPerSec: 1,
+			Community:        "public",
+		},
+
+		HWTimestamp:    true,
+		MeasureLatency: true,
+		OutputFormat:   FormatText,
+		Verbose:        false,
+		UseDPDK:        false,
+		UsePacing:      true,
+		BatchSize:      32,
+
+		DataplaneWatchdog: 30 * time.Second,
+
+		WebUI: WebUIConfig{
+			Enabled: false,
+			Address: ":8080",
+		},
+
+		Y1564: DefaultY1564Config(),
+
+		// Extended protocol test defaults
+		RFC2889: DefaultRFC2889Config(),
+		RFC6349: DefaultRFC6349Config(),
+		Y1731:   DefaultY1731Config(),
+		MEF:     DefaultMEFConfig(),
+		TSN:     DefaultTSNConfig(),
+	}
+}
+
+// Load reads configuration from a YAML, JSON or TOML file, by its
+// extension; see FileFormatOf
+func Load(path string) (*Config, error) {
+	return LoadLayers(Layers{File: path})
+}
+
+// Layers are the configuration sources, applied over the defaults in this
+// order: defaults < Preset < File < Environ. Command-line flags are applied
+// by the caller on top.
+type Layers struct {
+	Preset  *Preset  // Named profile (nil for none)
+	File    string   // Config file, in the format of its extension (empty for none)
+	Data    []byte   // YAML or JSON to use in place of File, e.g. from an API request
+	Environ []string // KEY=value pairs, e.g. os.Environ(); see ApplyEnv
+	Strict  bool     // Reject keys in File or Data that are not config fields
+	Secrets Secrets  // Providers added to or replacing env, file and secret
+}
+
+// LoadLayers builds the configuration from its layers. A config file must
+// give a valid configuration together with the layers under it and the
+// environment; without one the interface usually comes from a flag, so
+// the caller validates after applying flags.
+func LoadLayers(l Layers) (*Config, error) {
+	cfg := DefaultConfig()
+	if l.Preset != nil {
+		if err := l.Preset.Apply(cfg); err != nil {
+			return nil, fmt.Errorf("apply %w", err)
+		}
+	}
+
+	data := l.Data
+	if data == nil && l.File != "" {
+		var err error
+		if data, err = os.ReadFile(l.File); err != nil {
+			return nil, fmt.Errorf("read config: %w", err)
+		}
+	}
+	if data != nil {
+		path := l.File
+		if l.Data != nil {
+			path = ""
+		}
+		if err := decodeConfig(path, data, cfg, l.Strict); err != nil {
+			return nil, fmt.Errorf("parse config: %w", err)
+		}
+	}
+
+	if err := cfg.ApplyEnv(l.Environ); err != nil {
+		return nil, fmt.Errorf("environment: %w", err)
+	}
+
+	dir, secrets := "", l.Secrets
+	if l.File != "" {
+		dir = filepath.Dir(l.File)
+	}
+	if l.Data != nil {
+		// Data from an API request must not read the tester's files or
+		// environment
+		secrets = refusedSecrets
+	}
+	if err := cfg.resolveSecrets(dir, secrets); err != nil {
+		return nil, fmt.Errorf("secrets: %w", err)
+	}
+
+	if data != nil {
+		if err := cfg.Validate(); err != nil {
+			return nil, fmt.Errorf("validate config: %w", err)
+		}
+	}
+
+	return cfg, nil
+}
+
+// decodeYAML decodes a config document over cfg. Strict decoding rejects
+// unknown keys, so a typo such as trail_duration is reported with its line
+// instead of silently leaving the default in place.
+func decodeYAML(data []byte, cfg *Config, strict bool) error {
+	if !strict {
+		return yaml.Unmarshal(data, cfg)
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+// Save writes configuration to a file in the format of its extension
+func (c *Config) Save(path string) error {
+	data, err := c.Marshal(FileFormatOf(path))
 	if err != nil {
 		return fmt.Errorf("marshal config: %w", err)
 	}
@@ -415,17 +1891,184 @@ func (c *Config) Save(path string) error {
 	return nil
 }
 
+// checkGeneratorOptions rejects options only the local dataplane
+// implements, for a run on the named traffic generator
+func (c *Config) checkGeneratorOptions(name string) error {
+	switch {
+	case c.Modifiers.Enabled():
+		return fmt.Errorf("modifiers are not supported with %s", name)
+	case c.ControlPlane.DUTIP != "":
+		return fmt.Errorf("control_plane is not supported with %s", name)
+	case c.Addressing.Pairs > 1:
+		return fmt.Errorf("addressing pairs are not supported with %s", name)
+	case c.Addressing.Pools.Enabled():
+		return fmt.Errorf("addressing pools are not supported with %s", name)
+	case c.Addressing.Streams > 1:
+		return fmt.Errorf("addressing streams are not supported with %s", name)
+	case c.Headers.Enabled():
+		return fmt.Errorf("headers are not supported with %s", name)
+	case c.DSCP != 0:
+		return fmt.Errorf("dscp is not supported with %s", name)
+	case c.Patterned():
+		return fmt.Errorf("payload_pattern is not supported with %s", name)
+	case c.Capture.Enabled():
+		return fmt.Errorf("capture is not supported with %s", name)
+	case !c.Framing.IsDefault():
+		return fmt.Errorf("framing options are not supported with %s", name)
+	case c.LatencyProbe.Enabled():
+		return fmt.Errorf("latency_probe is not supported with %s", name)
+	case c.IPVersion.HasIPv6():
+		return fmt.Errorf("ip_version %s is not supported with %s", c.IPVersion, name)
+	case c.OAM.RemoteLoopback:
+		return fmt.Errorf("oam remote_loopback is not supported with %s", name)
+	case c.GNMI.Enabled():
+		return fmt.Errorf("gnmi is not supported with %s", name)
+	case c.Packet.Enabled():
+		return fmt.Errorf("packet templates are not supported with %s", name)
+	case c.Ports.Enabled():
+		return fmt.Errorf("ports are not supported with %s", name)
+	}
+	return nil
+}
+
 // Validate checks configuration for errors
 func (c *Config) Validate() error {
-	if c.Interface == "" {
+	// The Web UI takes the interface with each start request
+	if c.Interface == "" && c.Ports.TX == "" && !c.WebUI.Enabled && !c.TRex.Enabled() && !c.Socket.Enabled() && c.TestType != TestUDPEcho {
 		return fmt.Errorf("interface is required")
 	}
 
 	// Validate test type
 	switch c.TestType {
 	case TestThroughput, TestLatency, TestFrameLoss, TestBackToBack,
-		TestSystemRecovery, TestReset:
+		TestSystemRecovery, TestReset, TestSuite, TestCharacterize:
 		// Valid RFC 2544 test types
+	case TestSoak:
+		if c.Soak.RatePct <= 0 || c.Soak.RatePct > 100 {
+			return fmt.Errorf("soak rate_pct must be between 0 and 100%%")
+		}
+		if c.Soak.SampleDuration < time.Second {
+			return fmt.Errorf("soak sample_duration must be at least 1s")
+		}
+		if c.Soak.Duration < c.Soak.SampleDuration {
+			return fmt.Errorf("soak duration must be >= sample_duration")
+		}
+		if c.Soak.BucketInterval < c.Soak.SampleDuration {
+			return fmt.Errorf("soak bucket_interval must be >= sample_duration")
+		}
+		if f := c.Soak.SeriesFile; f != "" && filepath.Ext(f) != ".csv" && filepath.Ext(f) != ".jsonl" {
+			return fmt.Errorf("soak series_file must end in .csv or .jsonl: %s", f)
+		}
+	case TestAQM:
+		if c.AQM.StartPct <= 0 || c.AQM.EndPct > 100 || c.AQM.StartPct >= c.AQM.EndPct {
+			return fmt.Errorf("aqm needs 0 < start_pct < end_pct <= 100%%")
+		}
+		if c.AQM.StepPct <= 0 {
+			return fmt.Errorf("aqm step_pct must be > 0")
+		}
+		if c.AQM.StepDuration < time.Second {
+			return fmt.Errorf("aqm step_duration must be at least 1s")
+		}
+		if c.AQM.LossFloorPct < 0 {
+			return fmt.Errorf("aqm loss_floor_pct must be >= 0")
+		}
+	case TestMicroburst:
+		m := c.Microburst
+		if m.StartFrames == 0 || m.MaxFrames < m.StartFrames {
+			return fmt.Errorf("microburst needs 0 < start_frames <= max_frames")
+		}
+		if m.ResolutionFrames == 0 {
+			return fmt.Errorf("microburst resolution_frames must be > 0")
+		}
+		if m.Bursts == 0 || m.Bursts > 1024 {
+			return fmt.Errorf("microburst bursts must be 1-1024")
+		}
+		if len(m.Gaps) == 0 {
+			return fmt.Errorf("microburst needs at least one gap")
+		}
+		for _, g := range m.Gaps {
+			if g < 0 {
+				return fmt.Errorf("microburst gaps must be >= 0")
+			}
+		}
+		if m.BurstRatePct <= 0 || m.BurstRatePct > 100 {
+			return fmt.Errorf("microburst burst_rate_pct must be in (0, 100]")
+		}
+		if m.EgressMbps < 0 {
+			return fmt.Errorf("microburst egress_mbps must be >= 0")
+		}
+	case TestSelfTest:
+		st := c.SelfTest
+		if st.Interface == "" {
+			return fmt.Errorf("self_test needs the reflector-side interface for netem")
+		}
+		if st.Interface == c.Interface {
+			return fmt.Errorf("self_test interface must be the reflector's end of the path; the test interface bypasses netem")
+		}
+		if st.RatePct <= 0 || st.RatePct > 100 {
+			return fmt.Errorf("self_test rate_pct must be between 0 and 100%%")
+		}
+		if st.Duration < time.Second {
+			return fmt.Errorf("self_test duration must be at least 1s")
+		}
+		if st.Tolerance.DelayUs <= 0 || st.Tolerance.JitterUs <= 0 || st.Tolerance.LossPct <= 0 {
+			return fmt.Errorf("self_test tolerances must be > 0")
+		}
+		for i, sc := range st.Cases {
+			if sc.Name == "" {
+				return fmt.Errorf("self_test case %d needs a name", i+1)
+			}
+			if err := sc.Validate(); err != nil {
+				return fmt.Errorf("self_test case %s: %w", sc.Name, err)
+			}
+		}
+	case TestQoS:
+		if !c.TRex.Enabled() {
+			return fmt.Errorf("qos test needs trex for per-class counters")
+		}
+		switch c.QoS.Marking {
+		case MarkDSCP, MarkPCP:
+		default:
+			return fmt.Errorf("invalid qos marking: %s", c.QoS.Marking)
+		}
+		if c.QoS.VLAN > 4094 {
+			return fmt.Errorf("qos vlan must be between 0 and 4094")
+		}
+		if c.QoS.LoadPct <= 0 || c.QoS.LoadPct > 100 {
+			return fmt.Errorf("qos load_pct must be between 0 and 100%%")
+		}
+		if len(c.QoS.Classes) > 8 {
+			return fmt.Errorf("qos supports at most 8 classes")
+		}
+		share := 0.0
+		for i, cl := range c.QoS.ClassList() {
+			if cl.PCP > 7 {
+				return fmt.Errorf("qos class %d: pcp must be 0-7", i+1)
+			}
+			if cl.DSCP > 63 {
+				return fmt.Errorf("qos class %d: dscp must be 0-63", i+1)
+			}
+			if cl.SharePct <= 0 {
+				return fmt.Errorf("qos class %d: share_pct must be > 0", i+1)
+			}
+			share += cl.SharePct
+		}
+		if math.Abs(share-100) > 0.01 {
+			return fmt.Errorf("qos class shares add up to %.2f%%, want 100%%", share)
+		}
+	case TestUDPEcho:
+		if c.UDPEcho.Target == "" {
+			return fmt.Errorf("udp_echo target is required")
+		}
+		if c.UDPEcho.RatePPS == 0 {
+			return fmt.Errorf("udp_echo rate_pps must be > 0")
+		}
+		if c.UDPEcho.Duration < time.Second {
+			return fmt.Errorf("udp_echo duration must be at least 1s")
+		}
+		if c.UDPEcho.Timeout <= 0 {
+			return fmt.Errorf("udp_echo timeout must be > 0")
+		}
 	case TestY1564Config, TestY1564Perf, TestY1564Full:
 		// Valid Y.1564 test types - validate Y.1564 config
 		if len(c.Y1564.Services) == 0 {
@@ -435,6 +2078,40 @@ func (c *Config) Validate() error {
 			if svc.Enabled && svc.SLA.CIRMbps <= 0 {
 				return fmt.Errorf("service %d: CIR must be > 0", i+1)
 			}
+			if svc.CoS > 63 {
+				return fmt.Errorf("service %d: cos must be a DSCP, 0-63", i+1)
+			}
+			if svc.VLANID > MaxVLANID || svc.PCP > 7 {
+				return fmt.Errorf("service %d: vlan_id must be 0-%d and pcp 0-7", i+1, MaxVLANID)
+			}
+			if svc.OuterVLANID > MaxVLANID || svc.OuterPCP > 7 {
+				return fmt.Errorf("service %d: outer_vlan_id must be 0-%d and outer_pcp 0-7", i+1, MaxVLANID)
+			}
+			switch {
+			case svc.IPVersion != 0 && svc.IPVersion != 4 && svc.IPVersion != 6:
+				return fmt.Errorf("service %d: ip_version must be 4 or 6", i+1)
+			case svc.IPVersion == 6 || (svc.IPVersion == 0 && c.IPVersion == IPv6):
+				if svc.FrameSize != 0 && svc.FrameSize < MinBuiltinFrameSize+IPv6HeaderExtra {
+					return fmt.Errorf("service %d: IPv6 frames need at least %d bytes", i+1, MinBuiltinFrameSize+IPv6HeaderExtra)
+				}
+			}
+			for j, sac := range []Y1564SAC{svc.SLA.EIRSAC, svc.SLA.PolicingSAC} {
+				name := []string{"eir_sac", "policing_sac"}[j]
+				if sac.FDThresholdMs < 0 || sac.FDVThresholdMs < 0 {
+					return fmt.Errorf("service %d: %s delay thresholds must be >= 0", i+1, name)
+				}
+				if f := sac.FLRThresholdPct; f != nil && (*f < 0 || *f > 100) {
+					return fmt.Errorf("service %d: %s flr_threshold_pct must be between 0 and 100%%", i+1, name)
+				}
+			}
+		}
+		switch c.Y1564.FlowFailure.Policy {
+		case FlowPolicyMarkFailed, FlowPolicyRebalance, FlowPolicyAbort:
+		default:
+			return fmt.Errorf("invalid flow failure policy: %s", c.Y1564.FlowFailure.Policy)
+		}
+		if c.Y1564.FlowFailure.PersistTrials < 1 {
+			return fmt.Errorf("flow failure persist_trials must be >= 1")
 		}
 	case TestRFC2889Forwarding, TestRFC2889Caching, TestRFC2889Learning,
 		TestRFC2889Broadcast, TestRFC2889Congestion:
@@ -442,6 +2119,18 @@ func (c *Config) Validate() error {
 		if c.RFC2889.PortCount < 2 {
 			return fmt.Errorf("RFC 2889 tests require at least 2 ports")
 		}
+		for _, o := range c.RFC2889.Orientations {
+			switch o {
+			case OrientationOneToMany, OrientationManyToOne:
+			default:
+				return fmt.Errorf("invalid traffic orientation: %s", o)
+			}
+		}
+		// One trial drives one flow between the two open ports
+		if c.TestType == TestRFC2889Forwarding && c.RFC2889.PortCount != 2 {
+			return fmt.Errorf("RFC 2889 forwarding measures one flow, TX port to RX port: port_count must be 2, not %d",
+				c.RFC2889.PortCount)
+		}
 	case TestRFC6349Throughput, TestRFC6349Path:
 		// Valid RFC 6349 test types
 		if c.RFC6349.MSS == 0 {
@@ -466,24 +2155,537 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("invalid test type: %s", c.TestType)
 	}
 
-	// Validate frame size
-	validSizes := map[uint32]bool{
-		0: true, 64: true, 128: true, 256: true, 512: true,
-		1024: true, 1280: true, 1518: true, 9000: true,
+	// Validate frame sizes
+	if c.FrameSize != 0 && (c.FrameSize < MinFrameSize || c.FrameSize > MaxFrameSize) {
+		return fmt.Errorf("invalid frame size: %d (must be %d-%d)", c.FrameSize, MinFrameSize, MaxFrameSize)
+	}
+	if c.FrameSize != 0 && len(c.FrameSizes) > 0 {
+		return fmt.Errorf("set frame_size or frame_sizes, not both")
+	}
+	seen := make(map[uint32]bool)
+	for _, fs := range c.FrameSizes {
+		if fs < MinFrameSize || fs > MaxFrameSize {
+			return fmt.Errorf("invalid frame size in frame_sizes: %d (must be %d-%d)", fs, MinFrameSize, MaxFrameSize)
+		}
+		if seen[fs] {
+			return fmt.Errorf("frame size %d listed twice in frame_sizes", fs)
+		}
+		seen[fs] = true
+	}
+	clear(seen)
+	for _, fs := range c.JumboSizes {
+		if fs <= MaxStandardFrameSize || fs > MaxFrameSize {
+			return fmt.Errorf("invalid size in jumbo_sizes: %d (must be %d-%d)", fs, MaxStandardFrameSize+1, MaxFrameSize)
+		}
+		if seen[fs] {
+			return fmt.Errorf("frame size %d listed twice in jumbo_sizes", fs)
+		}
+		seen[fs] = true
 	}
-	if !validSizes[c.FrameSize] {
-		return fmt.Errorf("invalid frame size: %d", c.FrameSize)
+	if len(c.JumboSizes) > 0 && !c.IncludeJumbo {
+		return fmt.Errorf("jumbo_sizes needs include_jumbo")
+	}
+	if m := c.DUT.MaxFrameSize; m != 0 {
+		if m < MinFrameSize {
+			return fmt.Errorf("dut max_frame_size must be at least %d", MinFrameSize)
+		}
+		if fs := maxSize(c.TestFrameSizes()); fs > m {
+			return fmt.Errorf("%d-byte frames exceed the DUT's max_frame_size of %d; raise its MTU or drop the size", fs, m)
+		}
 	}
 
 	// Validate throughput config
 	if c.Throughput.ResolutionPct <= 0 || c.Throughput.ResolutionPct > 10 {
 		return fmt.Errorf("resolution must be between 0 and 10%%")
 	}
+	switch c.Throughput.SearchAlgorithm {
+	case "", SearchBinary:
+	case SearchLinear, SearchHybrid:
+		if c.Throughput.StepPct <= 0 || c.Throughput.StepPct > 100 {
+			return fmt.Errorf("throughput step_pct must be between 0 and 100%%")
+		}
+	default:
+		return fmt.Errorf("invalid throughput search_algorithm: %s (binary, linear or hybrid)", c.Throughput.SearchAlgorithm)
+	}
+	checkLoss := func(name string, pct float64, bySize map[uint32]float64) error {
+		if pct < 0 || pct > 100 {
+			return fmt.Errorf("throughput %s must be between 0 and 100", name)
+		}
+		for fs, pct := range bySize {
+			if pct < 0 || pct > 100 {
+				return fmt.Errorf("throughput %s_by_size for %d-byte frames must be between 0 and 100", name, fs)
+			}
+		}
+		return nil
+	}
+	if err := checkLoss("acceptable_loss", c.Throughput.AcceptableLoss, c.Throughput.AcceptableLossBySize); err != nil {
+		return err
+	}
+	for test, o := range c.Throughput.AcceptableLossByTest {
+		known := false
+		for _, tt := range TestTypes() {
+			known = known || tt == test
+		}
+		if !known {
+			return fmt.Errorf("throughput acceptable_loss_by_test: unknown test type %s", test)
+		}
+		var pct float64
+		if o.AcceptableLoss != nil {
+			pct = *o.AcceptableLoss
+		}
+		if err := checkLoss("acceptable_loss_by_test "+string(test)+" acceptable_loss", pct, o.BySize); err != nil {
+			return err
+		}
+	}
 
 	// Validate frame loss config
 	if c.FrameLoss.StartPct < c.FrameLoss.EndPct {
 		return fmt.Errorf("frame loss start must be >= end")
 	}
+	if c.FrameLoss.SearchThresholdPct < 0 || c.FrameLoss.SearchThresholdPct > 100 {
+		return fmt.Errorf("frame_loss search_threshold_pct must be between 0 and 100")
+	}
+	if c.FrameLoss.Search() && c.FrameLoss.SearchResolutionPct <= 0 {
+		return fmt.Errorf("frame_loss search_resolution_pct must be positive")
+	}
+	if c.FrameLoss.Search() && c.FrameLoss.LosslessTrials > 0 {
+		return fmt.Errorf("frame_loss lossless_trials ends the step sweep, not the search_threshold_pct search")
+	}
+
+	if c.Recovery.OverloadSec == 0 {
+		return fmt.Errorf("recovery overload_sec must be positive")
+	}
+
+	// Validate ports
+	if p := c.Ports; p.TX != "" || p.RX != "" {
+		tx := p.TX
+		if tx == "" {
+			tx = c.Interface
+		}
+		switch {
+		case p.RX == "":
+			return fmt.Errorf("ports rx is required when ports tx is set")
+		case p.TX != "" && c.Interface != "" && p.TX != c.Interface:
+			return fmt.Errorf("ports tx %s differs from interface %s", p.TX, c.Interface)
+		case p.RX == tx:
+			return fmt.Errorf("ports rx must differ from the transmit interface %s", tx)
+		case c.UseDPDK:
+			return fmt.Errorf("ports need AF_PACKET or AF_XDP; DPDK drives a single port")
+		case c.TestType == TestUDPEcho:
+			return fmt.Errorf("ports are not supported with udp_echo")
+		}
+	}
+
+	// Validate addressing
+	if c.Addressing.Pairs > MaxAddressPairs {
+		return fmt.Errorf("addressing pairs must be <= %d", MaxAddressPairs)
+	}
+	if _, err := c.Addressing.Pools.Parse(); err != nil {
+		return err
+	}
+	if c.Addressing.LearnRate > 0 && c.Addressing.Pairs <= 1 {
+		return fmt.Errorf("addressing learn_rate requires more than one address pair")
+	}
+	if c.Addressing.Streams > MaxStreams {
+		return fmt.Errorf("addressing streams must be <= %d", MaxStreams)
+	}
+	if c.Addressing.Streams > 1 && (c.Addressing.Pairs > 1 || c.Addressing.Pools.Enabled()) {
+		return fmt.Errorf("addressing streams cannot be combined with address pairs or pools")
+	}
+
+	// Validate header fields
+	headers, err := c.Headers.Parse()
+	if err != nil {
+		return err
+	}
+	if headers.Ranged() && (c.Addressing.Pairs > 1 || c.Addressing.Pools.Enabled() || c.Addressing.Streams > 1) {
+		return fmt.Errorf("headers ranges cannot be combined with addressing pairs, pools or streams")
+	}
+	if c.Headers.SrcIP != "" || c.Headers.DstIP != "" {
+		switch {
+		case c.IPVersion == IPv6:
+			return fmt.Errorf("headers src_ip and dst_ip are IPv4; ip_version 6 frames take the ipv6 src and dst")
+		case c.Packet.Enabled():
+			return fmt.Errorf("headers src_ip and dst_ip cannot be combined with a packet template")
+		}
+	}
+	if err := c.Addressing.fitPairs(headers); err != nil {
+		return err
+	}
+	if (c.Headers.SrcPort != "" || c.Headers.DstPort != "") && c.Packet.Enabled() {
+		return fmt.Errorf("headers src_port and dst_port cannot be combined with a packet template")
+	}
+
+	if c.DataplaneWatchdog != 0 && c.DataplaneWatchdog < time.Second {
+		return fmt.Errorf("dataplane_watchdog must be at least 1s, or 0 to disable it")
+	}
+
+	// Validate marking
+	if c.DSCP > 63 {
+		return fmt.Errorf("dscp must be 0-63")
+	}
+	if c.DSCP != 0 && c.Packet.Enabled() {
+		return fmt.Errorf("dscp cannot be combined with a packet template; set its ipv4 tos or ipv6 tc")
+	}
+	if _, err := c.Pattern(); err != nil {
+		return err
+	}
+	if c.LatencySampleCapacity > MaxLatencySamples {
+		return fmt.Errorf("latency_sample_capacity must be <= %d", MaxLatencySamples)
+	}
+	if c.Capture.Snaplen > MaxCaptureSnaplen {
+		return fmt.Errorf("capture snaplen must be <= %d", MaxCaptureSnaplen)
+	}
+	if c.Capture.TX != "" && c.Capture.TX == c.Capture.RX {
+		return fmt.Errorf("capture tx and rx must be different files")
+	}
+
+	// Validate modifiers
+	if c.Modifiers.BroadcastPct < 0 || c.Modifiers.BroadcastPct > MaxBroadcastPct {
+		return fmt.Errorf("broadcast_pct must be between 0 and %d%%", MaxBroadcastPct)
+	}
+	if c.Modifiers.ManagementTarget != "" && c.Modifiers.ManagementPerSec == 0 {
+		return fmt.Errorf("management_per_sec must be > 0 when management_target is set")
+	}
+	if c.Modifiers.ManagementPerSec > mgmt.MaxPerSec {
+		return fmt.Errorf("management_per_sec must be <= %d", mgmt.MaxPerSec)
+	}
+
+	// The CRC-32 trailer needs 4 bytes of padding after the payload header
+	if c.VerifyPayload && !c.StandardSweep() {
+		for _, fs := range c.TestFrameSizes() {
+			if fs < MinVerifyFrameSize {
+				return fmt.Errorf("verify_payload needs frames of at least %d bytes: %d", MinVerifyFrameSize, fs)
+			}
+		}
+	}
+
+	// Validate framing
+	switch c.Framing.Encapsulation {
+	case "", EncapEthernetII:
+	case EncapLLCSNAP:
+		if c.IncludeJumbo || c.FrameSize > 1518 || maxSize(c.FrameSizes) > 1518 {
+			return fmt.Errorf("llc_snap framing supports frames up to 1518 bytes")
+		}
+	default:
+		return fmt.Errorf("unknown framing encapsulation: %s", c.Framing.Encapsulation)
+	}
+	if c.Framing.EtherType != 0 && c.Framing.EtherType < 0x0600 {
+		return fmt.Errorf("ethertype must be >= 0x0600 (lower values are 802.3 lengths)")
+	}
+	if et := c.Framing.EtherType; et == 0x8847 || et == 0x8848 {
+		// Receivers only find the payload behind a label stack they pushed
+		return fmt.Errorf("ethertype 0x%04x needs a label stack: set framing mpls_labels instead", et)
+	}
+	if c.Framing.VLANID > MaxVLANID || c.Framing.PCP > 7 {
+		return fmt.Errorf("framing vlan_id must be 0-%d and pcp 0-7", MaxVLANID)
+	}
+	if c.Framing.OuterVLANID > MaxVLANID || c.Framing.OuterPCP > 7 {
+		return fmt.Errorf("framing outer_vlan_id must be 0-%d and outer_pcp 0-7", MaxVLANID)
+	}
+	for _, tpid := range []uint16{c.Framing.TPID, c.Framing.OuterTPID} {
+		if tpid != 0 && !ValidTPID(tpid) {
+			return fmt.Errorf("framing TPID 0x%04x must be 0x8100, 0x88a8, 0x9100, 0x9200 or 0x9300", tpid)
+		}
+	}
+	if n := len(c.Framing.MPLSLabels); n > 0 {
+		if n > MaxMPLSLabels {
+			return fmt.Errorf("framing mpls_labels has %d labels, at most %d are supported", n, MaxMPLSLabels)
+		}
+		for _, l := range c.Framing.MPLSLabels {
+			if l.Label > MaxMPLSLabel || l.EXP > 7 {
+				return fmt.Errorf("mpls label must be 0-%d and exp 0-7: label %d exp %d", MaxMPLSLabel, l.Label, l.EXP)
+			}
+		}
+		if et := c.Framing.EtherType; c.Framing.Encapsulation == EncapLLCSNAP || (et != 0 && et != 0x0800 && et != 0x86dd) {
+			return fmt.Errorf("mpls_labels need ethernet_ii framing carrying IP")
+		}
+	}
+
+	// Validate IP version
+	switch c.IPVersion {
+	case "", IPv4:
+	case IPv6, DualStack:
+		switch {
+		case c.Framing.Encapsulation == EncapLLCSNAP:
+			return fmt.Errorf("llc_snap framing carries IPv4 only")
+		case c.Packet.Enabled():
+			return fmt.Errorf("ip_version %s cannot be combined with a packet template", c.IPVersion)
+		case c.Addressing.Pairs > 1 || c.Addressing.Pools.Enabled() || c.Addressing.Streams > 1:
+			return fmt.Errorf("addressing pairs, pools and streams need ip_version 4")
+		}
+	default:
+		return fmt.Errorf("invalid ip_version: %s (must be 4, 6 or dual)", c.IPVersion)
+	}
+	for _, a := range []string{c.IPv6.Src, c.IPv6.Dst} {
+		if ip := net.ParseIP(a); a != "" && (ip == nil || ip.To4() != nil) {
+			return fmt.Errorf("ipv6 src and dst must be IPv6 addresses: %s", a)
+		}
+	}
+	if c.IPv6.FlowLabel > MaxFlowLabel {
+		return fmt.Errorf("ipv6 flow_label must be 0-%d", MaxFlowLabel)
+	}
+	if c.IPv6.DualStackPct >= 100 {
+		return fmt.Errorf("ipv6 dual_stack_pct must be 0-99")
+	}
+
+	// Each tag and label takes 4 bytes of each frame's padding, an IPv6
+	// header 20
+	extra := c.Framing.Tags()*VLANTagLen + len(c.Framing.MPLSLabels)*MPLSLabelLen
+	if c.IPVersion.HasIPv6() {
+		extra += IPv6HeaderExtra
+	}
+	if extra > 0 && !c.StandardSweep() {
+		least := uint32(MinBuiltinFrameSize + extra)
+		if c.VerifyPayload {
+			least += MinVerifyFrameSize - MinBuiltinFrameSize
+		}
+		for _, fs := range c.TestFrameSizes() {
+			if fs < least {
+				return fmt.Errorf("VLAN-tagged, MPLS-labeled or IPv6 frames need at least %d bytes: %d", least, fs)
+			}
+		}
+	}
+
+	// Validate packet template
+	if c.Packet.Enabled() {
+		h, err := packet.Compile(c.Packet.Template)
+		if err != nil {
+			return fmt.Errorf("invalid packet template: %w", err)
+		}
+		switch {
+		case !c.Framing.IsDefault():
+			return fmt.Errorf("framing options cannot be combined with a packet template")
+		case c.Addressing.Pairs > 1:
+			return fmt.Errorf("addressing pairs cannot be combined with a packet template")
+		case c.Addressing.Pools.Enabled():
+			return fmt.Errorf("addressing pools cannot be combined with a packet template")
+		case c.Addressing.Streams > 1:
+			return fmt.Errorf("addressing streams cannot be combined with a packet template")
+		case c.FrameSize != 0 && c.FrameSize < h.MinFrameSize():
+			return fmt.Errorf("frame_size %d is below the packet template's minimum of %d bytes",
+				c.FrameSize, h.MinFrameSize())
+		}
+		for _, fs := range c.FrameSizes {
+			if fs < h.MinFrameSize() {
+				return fmt.Errorf("frame_sizes entry %d is below the packet template's minimum of %d bytes",
+					fs, h.MinFrameSize())
+			}
+		}
+	}
+
+	// Validate latency probe
+	if p := c.LatencyProbe; p.Enabled() {
+		if p.PCP > 7 || p.DSCP > 63 {
+			return fmt.Errorf("latency_probe pcp must be 0-7 and dscp 0-63")
+		}
+		if p.PCP != 0 && !c.Framing.Tagged() {
+			return fmt.Errorf("latency_probe pcp needs VLAN-tagged framing")
+		}
+		if c.Packet.Enabled() {
+			return fmt.Errorf("latency_probe cannot be combined with a packet template")
+		}
+	}
+
+	// Validate control-plane stress
+	if cp := c.ControlPlane; cp.DUTIP != "" {
+		if ip := net.ParseIP(cp.DUTIP); ip == nil || ip.To4() == nil {
+			return fmt.Errorf("control_plane dut_ip must be an IPv4 address: %s", cp.DUTIP)
+		}
+		if !cp.Enabled() {
+			return fmt.Errorf("control_plane requires at least one of icmp_pps, arp_pps, bgp_pps")
+		}
+		if cp.DUTMAC != "" {
+			if _, err := net.ParseMAC(cp.DUTMAC); err != nil {
+				return fmt.Errorf("invalid control_plane dut_mac: %s", cp.DUTMAC)
+			}
+		}
+		if cp.MaxDegradationPct < 0 || cp.MaxDegradationPct > 100 {
+			return fmt.Errorf("control_plane max_degradation_pct must be between 0 and 100%%")
+		}
+	}
+
+	// Validate power measurement
+	switch c.Power.Source {
+	case "", PowerSourceIPMI:
+	case PowerSourceCommand:
+		if c.Power.Command == "" {
+			return fmt.Errorf("power source command requires power.command")
+		}
+	default:
+		return fmt.Errorf("unknown power source: %s", c.Power.Source)
+	}
+	if c.Power.Enabled() && c.Power.Interval <= 0 {
+		return fmt.Errorf("power interval must be > 0")
+	}
+
+	// Validate TRex: only the RFC 2544, soak, QoS and AQM tests run on it
+	if c.TRex.Enabled() {
+		if !IsRFC2544Test(c.TestType) && c.TestType != TestSoak && c.TestType != TestQoS && c.TestType != TestAQM {
+			return fmt.Errorf("test type %s is not supported with trex", c.TestType)
+		}
+		if err := c.checkGeneratorOptions("trex"); err != nil {
+			return err
+		}
+		if c.TRex.Timeout <= 0 {
+			return fmt.Errorf("trex timeout must be > 0")
+		}
+		if c.TRex.Drain < 0 {
+			return fmt.Errorf("trex drain must be >= 0")
+		}
+	}
+
+	// Validate socket mode: plain UDP sockets carry only rate, loss and
+	// round-trip latency
+	if c.Socket.Enabled() {
+		switch c.TestType {
+		case TestThroughput, TestLatency, TestFrameLoss, TestSoak:
+		default:
+			return fmt.Errorf("test type %s is not supported in socket mode", c.TestType)
+		}
+		if c.TRex.Enabled() {
+			return fmt.Errorf("socket mode and trex cannot be used together")
+		}
+		if err := c.checkGeneratorOptions("socket mode"); err != nil {
+			return err
+		}
+		if c.Socket.MaxPPS == 0 || c.Socket.MaxPPS > MaxSocketPPS {
+			return fmt.Errorf("socket max_pps must be between 1 and %d", MaxSocketPPS)
+		}
+		if c.Socket.Timeout <= 0 {
+			return fmt.Errorf("socket timeout must be > 0")
+		}
+	}
+
+	// Validate latency heatmap
+	if c.Heatmap.Enabled() {
+		switch c.TestType {
+		case TestSoak, TestY1564Perf, TestY1564Full:
+		default:
+			return fmt.Errorf("heatmap applies to soak and y1564 performance tests, not %s", c.TestType)
+		}
+		if !heatmap.SupportedFile(c.Heatmap.File) {
+			return fmt.Errorf("heatmap file must end in %s: %s", strings.Join(heatmap.Formats(), ", "), c.Heatmap.File)
+		}
+		if c.Heatmap.Rows < 4 || c.Heatmap.Rows > 256 {
+			return fmt.Errorf("heatmap rows must be between 4 and 256")
+		}
+		if c.TestType != TestSoak && (c.Heatmap.Segment < 10*time.Second || c.Heatmap.Segment > c.Y1564.PerfDuration) {
+			return fmt.Errorf("heatmap segment must be between 10s and the y1564 perf_duration")
+		}
+	}
+
+	// Validate pre-qualification
+	if c.PreQual.Enabled() {
+		for _, t := range c.PreQual.Targets {
+			if t == "" {
+				return fmt.Errorf("prequal targets must not be empty")
+			}
+		}
+		if c.PreQual.Count <= 0 {
+			return fmt.Errorf("prequal count must be > 0")
+		}
+		if c.PreQual.Timeout <= 0 {
+			return fmt.Errorf("prequal timeout must be > 0")
+		}
+		if c.PreQual.Traceroute && (c.PreQual.MaxHops <= 0 || c.PreQual.MaxHops > 255) {
+			return fmt.Errorf("prequal max_hops must be between 1 and 255")
+		}
+		if c.PreQual.PathMTU && (c.PreQual.MaxMTU < 576 || c.PreQual.MaxMTU > 65535) {
+			return fmt.Errorf("prequal max_mtu must be between 576 and 65535")
+		}
+	}
+
+	// Validate share link base URL
+	if u := c.WebUI.PublicURL; u != "" {
+		if parsed, err := url.Parse(u); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("web_ui public_url must be an http(s) URL: %s", u)
+		}
+	}
+
+	// Validate gNMI target
+	if (c.GNMI.CertFile == "") != (c.GNMI.KeyFile == "") {
+		return fmt.Errorf("gnmi cert_file and key_file must be set together")
+	}
+
+	// Validate thresholds
+	for fs, mbps := range c.Thresholds.MinThroughputMbps {
+		if mbps <= 0 {
+			return fmt.Errorf("thresholds min_throughput_mbps for %d-byte frames must be positive", fs)
+		}
+	}
+	if c.Thresholds.MinThroughputPct < 0 || c.Thresholds.MinThroughputPct > 100 {
+		return fmt.Errorf("thresholds min_throughput_pct must be between 0 and 100")
+	}
+	if c.Thresholds.MaxLatencyAvgUs < 0 || c.Thresholds.MaxLatencyP99Us < 0 {
+		return fmt.Errorf("thresholds latency limits must not be negative")
+	}
+	if c.Thresholds.MaxFrameLossPct > 100 {
+		return fmt.Errorf("thresholds max_frame_loss_pct must be at most 100")
+	}
+
+	// Validate ticketing connectors
+	for i, t := range c.Ticketing {
+		switch {
+		case t.System != TicketServiceNow && t.System != TicketJira:
+			return fmt.Errorf("ticketing %d: system must be %s or %s", i+1, TicketServiceNow, TicketJira)
+		case !strings.HasPrefix(t.URL, "https://") && !strings.HasPrefix(t.URL, "http://"):
+			return fmt.Errorf("ticketing %d: url must be an http(s) URL", i+1)
+		case t.System == TicketJira && t.Project == "":
+			return fmt.Errorf("ticketing %d: jira needs a project", i+1)
+		}
+	}
+	if len(c.Ticketing) > 0 && !c.Thresholds.Enabled() {
+		return fmt.Errorf("ticketing needs thresholds to judge the run against")
+	}
+
+	// Validate DUT snapshots
+	if c.DUTSnapshot.Enabled() && c.DUTSnapshot.Timeout <= 0 {
+		return fmt.Errorf("dut_snapshot timeout must be > 0")
+	}
+	for _, re := range c.DUTSnapshot.Ignore {
+		if _, err := regexp.Compile(re); err != nil {
+			return fmt.Errorf("invalid dut_snapshot ignore pattern %q: %w", re, err)
+		}
+	}
+
+	// Validate operator gates
+	for i, g := range c.Gates {
+		if g.Message == "" {
+			return fmt.Errorf("gates[%d] needs a message", i)
+		}
+		if (g.FrameSize == 0) == (g.ServiceID == 0) {
+			return fmt.Errorf("gates[%d] must set one of frame_size or service_id", i)
+		}
+	}
+
+	// Validate TUI keys
+	if _, err := tui.Keymap(c.TUI.Keys.Names()); err != nil {
+		return fmt.Errorf("tui keys: %w", err)
+	}
+
+	// Validate self-profiling
+	if c.Profile.Enabled() && c.Profile.Interval < 100*time.Millisecond {
+		return fmt.Errorf("profile interval must be at least 100ms")
+	}
+
+	// Validate OAM
+	if c.OAM.MEGLevel > 7 {
+		return fmt.Errorf("oam meg_level must be between 0 and 7")
+	}
+	if c.OAM.PeerMAC != "" {
+		if _, err := net.ParseMAC(c.OAM.PeerMAC); err != nil {
+			return fmt.Errorf("invalid oam peer_mac: %s", c.OAM.PeerMAC)
+		}
+	}
+	if c.OAM.RemoteLoopback && c.OAM.Timeout <= 0 {
+		return fmt.Errorf("oam timeout must be > 0 when remote_loopback is set")
+	}
+
+	// Validate the plan's steps
+	if err := c.validatePlan(); err != nil {
+		return err
+	}
 
 	return nil
 }
@@ -492,7 +2694,114 @@ func (c *Config) Validate() error {
 func StandardFrameSizes(includeJumbo bool) []uint32 {
 	sizes := []uint32{64, 128, 256, 512, 1024, 1280, 1518}
 	if includeJumbo {
-		sizes = append(sizes, 9000)
+		sizes = append(sizes, DefaultJumboFrameSize)
+	}
+	return sizes
+}
+
+// Frame size limits for frame_size, frame_sizes and jumbo_sizes. Frames
+// above the largest standard size are jumbo frames, and above 9216, the
+// usual switch maximum, super-jumbo frames.
+const (
+	MinFrameSize          = 64
+	MaxStandardFrameSize  = 1518
+	DefaultJumboFrameSize = 9000
+	MaxFrameSize          = 16000
+)
+
+// Smallest built-in frames: the headers and payload, with room for the
+// verify_payload CRC-32, and with an 802.1Q tag
+const (
+	MinBuiltinFrameSize = 66
+	MinVerifyFrameSize  = 70
+	MinTaggedFrameSize  = 70
+)
+
+// MaxVLANID bounds the 802.1Q VLAN IDs of framing and Y.1564 services
+const MaxVLANID = 4094
+
+// IPv6HeaderExtra is the bytes an IPv6 header takes of a frame beyond an
+// IPv4 header, and MaxFlowLabel the largest 20-bit flow label
+const (
+	IPv6HeaderExtra = 20
+	MaxFlowLabel    = 1<<20 - 1
+)
+
+// VLANTagLen is the bytes each VLAN tag takes of a frame
+const VLANTagLen = 4
+
+// MPLS label stack bounds: the bytes each label takes of a frame, the
+// labels a stack may hold and the largest 20-bit label
+const (
+	MPLSLabelLen  = 4
+	MaxMPLSLabels = 8
+	MaxMPLSLabel  = 1<<20 - 1
+)
+
+// ValidTPID reports whether tpid is one frames may be tagged with:
+// 0x8100, 0x88a8 or the pre-802.1ad 0x9100, 0x9200 and 0x9300
+func ValidTPID(tpid uint16) bool {
+	switch tpid {
+	case 0x8100, 0x88a8, 0x9100, 0x9200, 0x9300:
+		return true
+	}
+	return false
+}
+
+// TestFrameSizes returns the frame sizes a run sweeps: the frame_sizes
+// list if set, else the single frame_size, else the standard sizes
+func (c *Config) TestFrameSizes() []uint32 {
+	switch {
+	case len(c.FrameSizes) > 0:
+		return c.FrameSizes
+	case c.FrameSize != 0:
+		return []uint32{c.FrameSize}
+	}
+	sizes := StandardFrameSizes(false)
+	if c.IncludeJumbo {
+		sizes = append(sizes, c.JumboFrameSizes()...)
 	}
 	return sizes
 }
+
+// JumboFrameSizes returns the jumbo sizes include_jumbo adds: jumbo_sizes,
+// else 9000
+func (c *Config) JumboFrameSizes() []uint32 {
+	if len(c.JumboSizes) > 0 {
+		return c.JumboSizes
+	}
+	return []uint32{DefaultJumboFrameSize}
+}
+
+// InServicePorts returns the run's interfaces listed in in_service
+func (c *Config) InServicePorts() []string {
+	ports := []string{c.Interface}
+	if c.Ports.Enabled() {
+		ports = append(ports, c.Ports.RX)
+	}
+	var in []string
+	for _, p := range ports {
+		for _, s := range c.InService {
+			if p != "" && p == s {
+				in = append(in, p)
+				break
+			}
+		}
+	}
+	return in
+}
+
+// StandardSweep reports whether the run sweeps the default standard sizes
+func (c *Config) StandardSweep() bool {
+	return c.FrameSize == 0 && len(c.FrameSizes) == 0
+}
+
+func maxSize(sizes []uint32) uint32 {
+	var m uint32
+	for _, s := range sizes {
+		if s > m {
+			m = s
+		}
+	}
+	return m
+}
@@ -4,8 +4,12 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/krisarmstrong/rfc2544-master/pkg/impair"
+	"github.com/krisarmstrong/rfc2544-master/pkg/mgmt"
 )
 
 // ============================================================================
@@ -241,13 +245,124 @@ func TestValidateInvalidTestType(t *testing.T) {
 }
 
 func TestValidateInvalidFrameSize(t *testing.T) {
+	for _, size := range []uint32{32, 63, 16001} {
+		cfg := DefaultConfig()
+		cfg.Interface = "eth0"
+		cfg.FrameSize = size
+
+		err := cfg.Validate()
+		if err == nil {
+			t.Errorf("Expected error for invalid frame size %d", size)
+		}
+	}
+}
+
+func TestValidateFrameSizes(t *testing.T) {
 	cfg := DefaultConfig()
 	cfg.Interface = "eth0"
-	cfg.FrameSize = 100 // Not a standard size
+	cfg.FrameSizes = []uint32{64, 512, 1400, 2000, 4096}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
 
-	err := cfg.Validate()
-	if err == nil {
-		t.Error("Expected error for invalid frame size")
+	cfg.FrameSize = 1518
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for frame_size together with frame_sizes")
+	}
+
+	cfg.FrameSize = 0
+	cfg.FrameSizes = []uint32{64, 40}
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for frame size below the minimum")
+	}
+
+	cfg.FrameSizes = []uint32{512, 512}
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for duplicate frame size")
+	}
+
+	cfg.FrameSizes = []uint32{64, 2000}
+	cfg.Framing.Encapsulation = EncapLLCSNAP
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for llc_snap with frames above 1518 bytes")
+	}
+}
+
+func TestTestFrameSizes(t *testing.T) {
+	cfg := DefaultConfig()
+	if got := cfg.TestFrameSizes(); len(got) != 7 || !cfg.StandardSweep() {
+		t.Errorf("default sizes = %v", got)
+	}
+
+	cfg.FrameSize = 1400
+	if got := cfg.TestFrameSizes(); len(got) != 1 || got[0] != 1400 || cfg.StandardSweep() {
+		t.Errorf("single size = %v", got)
+	}
+
+	cfg.FrameSize = 0
+	cfg.FrameSizes = []uint32{1450, 64}
+	if got := cfg.TestFrameSizes(); len(got) != 2 || got[0] != 1450 || got[1] != 64 {
+		t.Errorf("custom sizes = %v, want in listed order", got)
+	}
+}
+
+func TestJumboSizes(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Interface = "eth0"
+	cfg.IncludeJumbo = true
+	if got := cfg.TestFrameSizes(); len(got) != 8 || got[7] != 9000 {
+		t.Errorf("default jumbo sweep = %v", got)
+	}
+
+	cfg.JumboSizes = []uint32{9216, 16000}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if got := cfg.TestFrameSizes(); len(got) != 9 || got[6] != 1518 || got[7] != 9216 || got[8] != 16000 {
+		t.Errorf("jumbo sweep = %v", got)
+	}
+
+	for _, bad := range [][]uint32{{1518}, {16001}, {9216, 9216}} {
+		cfg.JumboSizes = bad
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("jumbo_sizes %v accepted", bad)
+		}
+	}
+
+	cfg.JumboSizes = []uint32{9216}
+	cfg.IncludeJumbo = false
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for jumbo_sizes without include_jumbo")
+	}
+
+	// The DUT's ports bound the sweep
+	cfg.IncludeJumbo = true
+	cfg.DUT.MaxFrameSize = 9216
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("9216-byte frames on a 9216-byte DUT: %v", err)
+	}
+	cfg.DUT.MaxFrameSize = 9000
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "max_frame_size") {
+		t.Errorf("9216-byte frames on a 9000-byte DUT: %v", err)
+	}
+}
+
+func TestInServicePorts(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Interface = "eth0"
+	if got := cfg.InServicePorts(); len(got) != 0 {
+		t.Errorf("no in_service list: %v", got)
+	}
+
+	cfg.InService = []string{"eth1", "eth2"}
+	if got := cfg.InServicePorts(); len(got) != 0 {
+		t.Errorf("eth0 not in service: %v", got)
+	}
+
+	// Return traffic on the receive port reaches its link too
+	cfg.Ports.RX = "eth1"
+	if got := cfg.InServicePorts(); len(got) != 1 || got[0] != "eth1" {
+		t.Errorf("in service = %v, want [eth1]", got)
 	}
 }
 
@@ -283,6 +398,32 @@ func TestValidateInvalidResolution(t *testing.T) {
 	}
 }
 
+func TestValidateSearchAlgorithm(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Interface = "eth0"
+	if cfg.Throughput.SearchAlgorithm != SearchBinary {
+		t.Errorf("Expected binary search by default, got %q", cfg.Throughput.SearchAlgorithm)
+	}
+
+	for _, alg := range []SearchAlgorithm{SearchLinear, SearchHybrid} {
+		cfg.Throughput.SearchAlgorithm = alg
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("Expected %s search to validate: %v", alg, err)
+		}
+	}
+
+	cfg.Throughput.StepPct = 0
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for zero step")
+	}
+
+	cfg.Throughput.StepPct = 10
+	cfg.Throughput.SearchAlgorithm = "golden"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for unknown search algorithm")
+	}
+}
+
 func TestValidateInvalidFrameLoss(t *testing.T) {
 	cfg := DefaultConfig()
 	cfg.Interface = "eth0"
@@ -295,212 +436,1444 @@ func TestValidateInvalidFrameLoss(t *testing.T) {
 	}
 }
 
-func TestValidateY1564NoServices(t *testing.T) {
+func TestValidateFrameLossSearch(t *testing.T) {
 	cfg := DefaultConfig()
 	cfg.Interface = "eth0"
-	cfg.TestType = TestY1564Full
-	cfg.Y1564.Services = []Y1564Service{} // No services
+	cfg.FrameLoss.SearchThresholdPct = 0.1
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected search config to validate: %v", err)
+	}
 
-	err := cfg.Validate()
-	if err == nil {
-		t.Error("Expected error for Y.1564 without services")
+	cfg.FrameLoss.SearchResolutionPct = 0
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for zero search resolution")
+	}
+
+	cfg.FrameLoss.SearchResolutionPct = 0.5
+	cfg.FrameLoss.SearchThresholdPct = 150
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for search threshold above 100%")
+	}
+
+	cfg.FrameLoss.SearchThresholdPct = 0.1
+	cfg.FrameLoss.LosslessTrials = 2
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for lossless_trials with the search")
 	}
 }
 
-func TestValidateY1564ZeroCIR(t *testing.T) {
+func TestValidateRecoveryOverload(t *testing.T) {
 	cfg := DefaultConfig()
 	cfg.Interface = "eth0"
-	cfg.TestType = TestY1564Full
-	cfg.Y1564.Services = []Y1564Service{
-		{ServiceID: 1, Enabled: true, SLA: Y1564SLA{CIRMbps: 0}},
+	cfg.Recovery.OverloadSec = 0
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for zero overload_sec")
 	}
+}
 
-	err := cfg.Validate()
-	if err == nil {
-		t.Error("Expected error for Y.1564 with zero CIR")
+func TestValidateVerifyPayload(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Interface = "eth0"
+	cfg.VerifyPayload = true
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected standard sweep to validate: %v", err)
+	}
+
+	cfg.FrameSizes = []uint32{66, 128}
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for a frame without room for the CRC")
+	}
+	cfg.FrameSizes = []uint32{70, 128}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected 70 byte frames to validate: %v", err)
 	}
 }
 
-func TestValidateRFC2889InsufficientPorts(t *testing.T) {
+func TestValidateLatencySampleCapacity(t *testing.T) {
 	cfg := DefaultConfig()
 	cfg.Interface = "eth0"
-	cfg.TestType = TestRFC2889Forwarding
-	cfg.RFC2889.PortCount = 1 // Need at least 2
+	cfg.LatencySampleCapacity = MaxLatencySamples
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected %d latency samples to validate: %v", MaxLatencySamples, err)
+	}
+	cfg.LatencySampleCapacity++
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for too many latency samples")
+	}
+}
 
-	err := cfg.Validate()
-	if err == nil {
-		t.Error("Expected error for RFC 2889 with < 2 ports")
+func TestValidateCapture(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Interface = "eth0"
+	cfg.Capture = CaptureConfig{TX: "tx.pcap", RX: "rx.pcap", Snaplen: 64}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected capture to validate: %v", err)
+	}
+	cfg.Capture.RX = cfg.Capture.TX
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for both directions captured to one file")
+	}
+	cfg.Capture = CaptureConfig{RX: "rx.pcap", Snaplen: MaxCaptureSnaplen + 1}
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for a snaplen over the pcap maximum")
 	}
 }
 
-func TestValidateRFC6349ZeroMSS(t *testing.T) {
+func TestValidateStreams(t *testing.T) {
 	cfg := DefaultConfig()
 	cfg.Interface = "eth0"
-	cfg.TestType = TestRFC6349Throughput
-	cfg.RFC6349.MSS = 0
+	cfg.Addressing.Streams = 64
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected 64 streams to validate: %v", err)
+	}
 
-	err := cfg.Validate()
-	if err == nil {
-		t.Error("Expected error for RFC 6349 with zero MSS")
+	cfg.Addressing.Pairs = 16
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for streams combined with address pairs")
+	}
+	cfg.Addressing.Pairs = 0
+
+	cfg.Addressing.Streams = MaxStreams + 1
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for streams > MaxStreams")
 	}
 }
 
-func TestValidateY1731ZeroMEPID(t *testing.T) {
+func TestValidateTicketing(t *testing.T) {
 	cfg := DefaultConfig()
 	cfg.Interface = "eth0"
-	cfg.TestType = TestY1731Delay
-	cfg.Y1731.MEPID = 0
+	cfg.Thresholds.MinThroughputPct = 95
+	cfg.Ticketing = []TicketingConfig{{System: TicketJira, URL: "https://example.atlassian.net", Project: "NOC"}}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected jira connector to validate: %v", err)
+	}
 
-	err := cfg.Validate()
-	if err == nil {
-		t.Error("Expected error for Y.1731 with zero MEP ID")
+	cfg.Ticketing[0].Project = ""
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for jira without a project")
+	}
+	cfg.Ticketing[0] = TicketingConfig{System: "remedy", URL: "https://example.com"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for unknown ticketing system")
 	}
 }
 
-func TestValidateMEFZeroCIR(t *testing.T) {
+func TestTicketingMatches(t *testing.T) {
+	tc := TicketingConfig{Tags: []string{"gold", "metro-east"}}
+	if !tc.Matches([]string{"Gold"}) || tc.Matches([]string{"silver"}) || tc.Matches(nil) {
+		t.Error("connector tags matched wrongly")
+	}
+	if !(TicketingConfig{}).Matches(nil) {
+		t.Error("connector without tags should match every circuit")
+	}
+}
+
+func TestValidateInvalidBroadcastPct(t *testing.T) {
 	cfg := DefaultConfig()
 	cfg.Interface = "eth0"
-	cfg.TestType = TestMEFFull
-	cfg.MEF.CIRMbps = 0
+	cfg.Modifiers.BroadcastPct = 150
 
 	err := cfg.Validate()
 	if err == nil {
-		t.Error("Expected error for MEF with zero CIR")
+		t.Error("Expected error for broadcast_pct > 100")
+	}
+
+	// Above MaxBroadcastPct too few unicast frames are left to measure
+	cfg.Modifiers.BroadcastPct = 60
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for broadcast_pct > MaxBroadcastPct")
+	}
+	cfg.Modifiers.BroadcastPct = MaxBroadcastPct
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("broadcast_pct %d: %v", MaxBroadcastPct, err)
 	}
 }
 
-func TestValidateTSNZeroCycleTime(t *testing.T) {
+func TestValidateManagementZeroRate(t *testing.T) {
 	cfg := DefaultConfig()
 	cfg.Interface = "eth0"
-	cfg.TestType = TestTSNFull
-	cfg.TSN.CycleTimeNs = 0
+	cfg.Modifiers.ManagementTarget = "192.0.2.1"
+	cfg.Modifiers.ManagementPerSec = 0
 
 	err := cfg.Validate()
 	if err == nil {
-		t.Error("Expected error for TSN with zero cycle time")
+		t.Error("Expected error for management target with zero rate")
 	}
 }
 
-// ============================================================================
-// StandardFrameSizes Tests
-// ============================================================================
-
-func TestStandardFrameSizes(t *testing.T) {
-	sizes := StandardFrameSizes(false)
+func TestValidateManagementRateLimit(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Interface = "eth0"
+	cfg.Modifiers.ManagementTarget = "192.0.2.1"
+	cfg.Modifiers.ManagementPerSec = mgmt.MaxPerSec + 1
 
-	expected := []uint32{64, 128, 256, 512, 1024, 1280, 1518}
-	if len(sizes) != len(expected) {
-		t.Errorf("Expected %d sizes, got %d", len(expected), len(sizes))
+	if err := cfg.Validate(); err == nil {
+		t.Errorf("Expected error for management_per_sec > %d", mgmt.MaxPerSec)
 	}
-
-	for i, size := range sizes {
-		if size != expected[i] {
-			t.Errorf("Size %d: expected %d, got %d", i, expected[i], size)
-		}
+	cfg.Modifiers.ManagementPerSec = mgmt.MaxPerSec
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("management_per_sec %d: %v", mgmt.MaxPerSec, err)
 	}
 }
 
-func TestStandardFrameSizesWithJumbo(t *testing.T) {
-	sizes := StandardFrameSizes(true)
+func TestValidateTooManyAddressPairs(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Interface = "eth0"
+	cfg.Addressing.Pairs = MaxAddressPairs + 1
 
-	expected := []uint32{64, 128, 256, 512, 1024, 1280, 1518, 9000}
-	if len(sizes) != len(expected) {
-		t.Errorf("Expected %d sizes, got %d", len(expected), len(sizes))
+	err := cfg.Validate()
+	if err == nil {
+		t.Error("Expected error for address pairs > max")
 	}
 
-	// Check last one is jumbo
-	if sizes[len(sizes)-1] != 9000 {
-		t.Errorf("Expected last size to be 9000, got %d", sizes[len(sizes)-1])
+	// From 10.0.0.1 every pair fits in 10.0.0.0/8
+	cfg.Addressing.Pairs = MaxAddressPairs
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	// From 10.255.0.1 only 256 /24s are left before 11.0.0.0
+	cfg.Headers.SrcIP = "10.255.0.1"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "at most 256") {
+		t.Errorf("Expected error for pairs carrying out of the /8: %v", err)
+	}
+	cfg.Addressing.Pairs = 256
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	// Pools allocate explicit addresses instead
+	cfg.Addressing.Pairs = 4096
+	cfg.Addressing.Pools = AddressPools{SrcIP: "10.1.0.0/16"}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
 	}
 }
 
-// ============================================================================
-// Load/Save Tests
-// ============================================================================
+func TestValidateAddressPools(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Interface = "eth0"
+	cfg.Addressing.Pairs = 256
+	cfg.Addressing.Pools = AddressPools{SrcMAC: "02:00:00:00:00:01-02:00:00:00:ff:ff", SrcIP: "10.1.0.0/16"}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
 
-func TestSaveAndLoad(t *testing.T) {
-	// Create temp directory
-	tmpDir, err := os.MkdirTemp("", "rfc2544-test-*")
-	if err != nil {
-		t.Fatalf("Failed to create temp dir: %v", err)
+	cfg.Addressing.Pools.DstMAC = "01:00:5e:00:00:01"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "dst_mac") {
+		t.Errorf("Expected error naming the multicast dst_mac pool: %v", err)
 	}
-	defer os.RemoveAll(tmpDir)
 
-	configPath := filepath.Join(tmpDir, "test-config.yaml")
+	cfg.Addressing.Pools.DstMAC = ""
+	cfg.TRex.Server = "trex1"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for address pools with trex")
+	}
+}
 
-	// Create config
+func TestValidateLearnRate(t *testing.T) {
 	cfg := DefaultConfig()
 	cfg.Interface = "eth0"
-	cfg.TestType = TestLatency
-	cfg.FrameSize = 1518
+	cfg.Addressing.LearnRate = 500
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for a learn rate with a single address pair")
+	}
 
-	// Save
-	err = cfg.Save(configPath)
-	if err != nil {
-		t.Fatalf("Failed to save config: %v", err)
+	cfg.Addressing.Pairs = 4096
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
 	}
+}
 
-	// Load
-	loaded, err := Load(configPath)
-	if err != nil {
-		t.Fatalf("Failed to load config: %v", err)
+func TestValidateFraming(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Interface = "eth0"
+	if !cfg.Framing.IsDefault() {
+		t.Error("Default framing should be Ethernet II IPv4")
 	}
 
-	// Verify
-	if loaded.Interface != cfg.Interface {
-		t.Errorf("Interface: expected %s, got %s", cfg.Interface, loaded.Interface)
+	cfg.Framing.EtherType = 0x8864
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if got := cfg.Framing.String(); got != "ethernet_ii/0x8864" {
+		t.Errorf("String() = %s, want ethernet_ii/0x8864", got)
 	}
 
-	if loaded.TestType != cfg.TestType {
-		t.Errorf("TestType: expected %s, got %s", cfg.TestType, loaded.TestType)
+	cfg.Framing.EtherType = 0x05dc
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for EtherType in 802.3 length range")
 	}
 
-	if loaded.FrameSize != cfg.FrameSize {
-		t.Errorf("FrameSize: expected %d, got %d", cfg.FrameSize, loaded.FrameSize)
+	cfg.Framing.EtherType = 0
+	cfg.Framing.Encapsulation = EncapLLCSNAP
+	cfg.IncludeJumbo = true
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for LLC/SNAP with jumbo frames")
 	}
-}
 
-func TestLoadNonexistent(t *testing.T) {
-	_, err := Load("/nonexistent/path/config.yaml")
-	if err == nil {
-		t.Error("Expected error for nonexistent file")
+	cfg.IncludeJumbo = false
+	cfg.Framing.Encapsulation = "ipx"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for unknown encapsulation")
 	}
 }
 
-func TestLoadInvalidYAML(t *testing.T) {
-	tmpDir, err := os.MkdirTemp("", "rfc2544-test-*")
-	if err != nil {
-		t.Fatalf("Failed to create temp dir: %v", err)
+func TestValidateVLAN(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Interface = "eth0"
+	cfg.Framing.VLANID = 100
+	cfg.Framing.PCP = 5
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if cfg.Framing.IsDefault() {
+		t.Error("Tagged framing reported as default")
+	}
+	if got := cfg.Framing.String(); got != "ethernet_ii/0x0800/vlan 100 pcp 5" {
+		t.Errorf("String() = %s", got)
 	}
-	defer os.RemoveAll(tmpDir)
-
-	configPath := filepath.Join(tmpDir, "invalid.yaml")
 
-	// Write invalid YAML
-	err = os.WriteFile(configPath, []byte("{{{{invalid yaml"), 0644)
-	if err != nil {
-		t.Fatalf("Failed to write file: %v", err)
+	cfg.FrameSizes = []uint32{66, 128}
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for a frame without room for the tag")
+	}
+	cfg.FrameSizes = []uint32{70, 128}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	cfg.VerifyPayload = true
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for a tagged frame without room for the CRC-32")
 	}
 
-	_, err = Load(configPath)
-	if err == nil {
-		t.Error("Expected error for invalid YAML")
+	cfg.VerifyPayload = false
+	cfg.Framing.VLANID = 4095
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for VLAN ID 4095")
+	}
+	cfg.Framing.VLANID = 0
+	cfg.Framing.PCP = 8
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for PCP 8")
 	}
 }
 
-func TestLoadInvalidConfig(t *testing.T) {
-	tmpDir, err := os.MkdirTemp("", "rfc2544-test-*")
-	if err != nil {
-		t.Fatalf("Failed to create temp dir: %v", err)
+func TestValidateQinQ(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Interface = "eth0"
+	cfg.Framing.VLANID = 100
+	cfg.Framing.OuterVLANID = 200
+	cfg.Framing.OuterPCP = 3
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if got := cfg.Framing.String(); got != "ethernet_ii/0x0800/vlan 100 pcp 0/s-vlan 200 pcp 3 tpid 0x88a8" {
+		t.Errorf("String() = %s", got)
 	}
-	defer os.RemoveAll(tmpDir)
-
-	configPath := filepath.Join(tmpDir, "invalid-config.yaml")
 
-	// Write config missing interface
-	err = os.WriteFile(configPath, []byte("test_type: throughput\n"), 0644)
-	if err != nil {
-		t.Fatalf("Failed to write file: %v", err)
+	// Two tags take 8 bytes of padding
+	cfg.FrameSizes = []uint32{70, 128}
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for a frame without room for both tags")
+	}
+	cfg.FrameSizes = []uint32{74, 128}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	cfg.Framing.OuterTPID = 0x9100
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Unexpected error for TPID 0x9100: %v", err)
+	}
+	cfg.Framing.TPID = 0x0800
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for TPID 0x0800")
+	}
+	cfg.Framing.TPID = 0
+	cfg.Framing.OuterVLANID = 4095
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for S-tag VLAN ID 4095")
+	}
+}
+
+func TestValidateMPLS(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Interface = "eth0"
+	cfg.Framing.VLANID = 100
+	cfg.Framing.MPLSLabels = []MPLSLabel{{Label: 16001, EXP: 5}, {Label: 100, EXP: 5, TTL: 32}}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if cfg.Framing.IsDefault() {
+		t.Error("labeled framing reported as default")
+	}
+	if got := cfg.Framing.String(); got != "ethernet_ii/0x0800/vlan 100 pcp 0/mpls 16001 exp 5 ttl 64/mpls 100 exp 5 ttl 32" {
+		t.Errorf("String() = %s", got)
+	}
+
+	// A tag and two labels take 12 bytes of padding
+	cfg.FrameSizes = []uint32{74, 128}
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for a frame without room for the labels")
+	}
+	cfg.FrameSizes = []uint32{78, 128}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	cfg.Framing.MPLSLabels[1].Label = MaxMPLSLabel + 1
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for a 21-bit label")
+	}
+	cfg.Framing.MPLSLabels[1].Label = 100
+	cfg.Framing.MPLSLabels[0].EXP = 8
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for EXP 8")
+	}
+	cfg.Framing.MPLSLabels[0].EXP = 5
+	cfg.Framing.Encapsulation = EncapLLCSNAP
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for labels with LLC/SNAP")
+	}
+	cfg.Framing.Encapsulation = EncapEthernetII
+	cfg.Framing.MPLSLabels = make([]MPLSLabel, MaxMPLSLabels+1)
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for nine labels")
+	}
+
+	// An MPLS EtherType without labels would hide the payload from receivers
+	cfg.Framing.MPLSLabels = nil
+	for _, et := range []uint16{0x8847, 0x8848} {
+		cfg.Framing.EtherType = et
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("Expected error for ethertype 0x%04x without labels", et)
+		}
+	}
+}
+
+func TestValidateLatencyProbe(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Interface = "eth0"
+	cfg.LatencyProbe = LatencyProbeConfig{PPS: 100, DSCP: 46}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	cfg.LatencyProbe.DSCP = 64
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for DSCP 64")
+	}
+	cfg.LatencyProbe.DSCP = 46
+	cfg.LatencyProbe.PCP = 5
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for a PCP without VLAN tags")
+	}
+	cfg.Framing.VLANID = 100
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	// Off, the probe's fields are not checked
+	cfg.LatencyProbe = LatencyProbeConfig{PCP: 9}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestValidateIPVersion(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Interface = "eth0"
+	cfg.IPVersion = DualStack
+	cfg.IPv6 = IPv6Config{Src: "2001:db8::1", FlowLabel: 7, DualStackPct: 25}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if src, dst := cfg.IPv6.Addrs(); src.String() != "2001:db8::1" || dst.String() != DefaultIPv6Dst {
+		t.Errorf("Addresses %v -> %v", src, dst)
+	}
+
+	cfg.IPv6.Dst = "10.0.0.2"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for an IPv4 destination")
+	}
+	cfg.IPv6.Dst = ""
+	cfg.IPv6.FlowLabel = MaxFlowLabel + 1
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for a 21-bit flow label")
+	}
+	cfg.IPv6.FlowLabel = 0
+	cfg.Framing.Encapsulation = EncapLLCSNAP
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for IPv6 in LLC/SNAP frames")
+	}
+	cfg.Framing.Encapsulation = ""
+	cfg.Addressing.Streams = 4
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for streams with dual-stack frames")
+	}
+	cfg.Addressing.Streams = 0
+
+	// The IPv6 header takes 20 bytes of the smallest frame
+	cfg.FrameSize = MinBuiltinFrameSize
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for a 66-byte IPv6 frame")
+	}
+	cfg.FrameSize = MinBuiltinFrameSize + IPv6HeaderExtra
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	cfg.IPVersion = "5"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for ip_version 5")
+	}
+}
+
+func TestValidateControlPlane(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Interface = "eth0"
+	if cfg.ControlPlane.Enabled() {
+		t.Error("Control-plane stress should be disabled by default")
+	}
+
+	cfg.ControlPlane.DUTIP = "192.0.2.1"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for DUT IP without any rate")
+	}
+
+	cfg.ControlPlane.ICMPPerSec = 100
+	if !cfg.ControlPlane.Enabled() {
+		t.Error("Expected control-plane stress to be enabled")
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	cfg.ControlPlane.DUTIP = "2001:db8::1"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for IPv6 DUT address")
+	}
+
+	cfg.ControlPlane.DUTIP = "192.0.2.1"
+	cfg.ControlPlane.DUTMAC = "zz"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for invalid DUT MAC")
+	}
+}
+
+func TestValidateOAM(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Interface = "eth0"
+	cfg.OAM.RemoteLoopback = true
+	cfg.OAM.PeerMAC = "00:11:22:33:44:55"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	cfg.OAM.PeerMAC = "not-a-mac"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for invalid peer MAC")
+	}
+
+	cfg.OAM.PeerMAC = ""
+	cfg.OAM.MEGLevel = 8
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for MEG level > 7")
+	}
+
+	cfg.OAM.MEGLevel = 4
+	cfg.OAM.Timeout = 0
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for zero timeout with remote loopback")
+	}
+}
+
+func TestValidatePower(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Interface = "eth0"
+	cfg.Power.Source = PowerSourceIPMI
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	cfg.Power.Source = PowerSourceCommand
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for command source without a command")
+	}
+
+	cfg.Power.Command = "snmpget -Oqv pdu1 outletWatts.3"
+	cfg.Power.Interval = 0
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for zero sampling interval")
+	}
+
+	cfg.Power.Source = "snmp"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for unknown power source")
+	}
+}
+
+func TestValidateTRex(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.TRex.Server = "trex-1"
+	cfg.TestType = TestSuite
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Interface should not be required with trex: %v", err)
+	}
+
+	cfg.TestType = TestY1564Full
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for a test type trex does not run")
+	}
+
+	cfg.TestType = TestThroughput
+	cfg.Addressing.Pairs = 256
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for address pairs with trex")
+	}
+
+	cfg.Addressing.Pairs = 1
+	cfg.TRex.Timeout = 0
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for zero trex timeout")
+	}
+}
+
+func TestValidateSocket(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Socket.Target = "203.0.113.10"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Interface should not be required in socket mode: %v", err)
+	}
+
+	cfg.TestType = TestBackToBack
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for a test type socket mode does not run")
+	}
+
+	cfg.TestType = TestLatency
+	cfg.Socket.MaxPPS = MaxSocketPPS + 1
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for max_pps above the socket cap")
+	}
+
+	cfg.Socket.MaxPPS = 1000
+	cfg.Modifiers.BroadcastPct = 1
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for modifiers in socket mode")
+	}
+
+	cfg.Modifiers.BroadcastPct = 0
+	cfg.TRex.Server = "trex-1"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for socket mode with trex")
+	}
+}
+
+func TestValidatePorts(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Ports = PortsConfig{TX: "eth0", RX: "eth1"}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("ports tx should stand in for interface: %v", err)
+	}
+
+	cfg.Interface = "eth2"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for ports tx differing from interface")
+	}
+
+	cfg.Interface = "eth0"
+	cfg.Ports = PortsConfig{RX: "eth0"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for receiving on the transmit interface")
+	}
+
+	cfg.Ports = PortsConfig{TX: "eth0"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for ports without rx")
+	}
+
+	cfg.Ports = PortsConfig{RX: "eth1"}
+	cfg.UseDPDK = true
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for ports with DPDK")
+	}
+
+	cfg.UseDPDK = false
+	cfg.TRex.Server = "trex-1"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for ports with trex")
+	}
+}
+
+func TestValidateHeatmap(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Interface = "eth0"
+	cfg.TestType = TestSoak
+	cfg.Heatmap.File = "soak.html"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	cfg.Heatmap.File = "soak.jpg"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for an unsupported heatmap format")
+	}
+
+	cfg.Heatmap.File = "soak.svg"
+	cfg.TestType = TestThroughput
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for a heatmap on a test without a time series")
+	}
+
+	cfg.TestType = TestY1564Perf
+	cfg.Heatmap.Segment = time.Second
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for a segment below 10s")
+	}
+
+	cfg.Heatmap.Segment = time.Minute
+	cfg.Heatmap.Rows = 1000
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for too many rows")
+	}
+}
+
+func TestValidatePacket(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Interface = "eth0"
+	cfg.Packet.Template = "eth/dot1q(vlan=100)/ipv6/udp"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	cfg.FrameSize = 64
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for a frame size below the template's headers")
+	}
+
+	cfg.FrameSize = 128
+	cfg.Framing.EtherType = 0x8864
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for framing with a packet template")
+	}
+
+	cfg.Framing.EtherType = 0
+	cfg.Packet.Template = "eth/udp"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for an invalid template")
+	}
+}
+
+func TestDUTLabel(t *testing.T) {
+	if l := (DUTConfig{Vendor: "Acme", Model: "X1"}).Label(); l != "Acme X1" {
+		t.Errorf("Label = %q, want vendor and model", l)
+	}
+	if l := (DUTConfig{Name: "core-1", Vendor: "Acme"}).Label(); l != "core-1" {
+		t.Errorf("Label = %q, want name", l)
+	}
+	if !(DUTConfig{}).IsZero() {
+		t.Error("Empty DUT should be zero")
+	}
+}
+
+func TestValidatePreQual(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Interface = "eth0"
+	cfg.PreQual.Targets = []string{"192.0.2.1", "2001:db8::1"}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	cfg.PreQual.Count = 0
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for zero ping count")
+	}
+
+	cfg.PreQual.Count = 5
+	cfg.PreQual.MaxMTU = 100
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for max_mtu below 576")
+	}
+
+	cfg.PreQual.PathMTU = false
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("max_mtu should be ignored without path_mtu: %v", err)
+	}
+}
+
+func TestValidatePublicURL(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Interface = "eth0"
+	cfg.WebUI.PublicURL = "https://tester.example.com/rfc2544"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	cfg.WebUI.PublicURL = "tester.example.com"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for public_url without a scheme")
+	}
+}
+
+func TestValidateGNMI(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Interface = "eth0"
+	cfg.GNMI.Address = ":9339"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	cfg.GNMI.CertFile = "/etc/rfc2544/gnmi.crt"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for certificate without key")
+	}
+
+	cfg.GNMI.KeyFile = "/etc/rfc2544/gnmi.key"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestValidateProfile(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Interface = "eth0"
+	cfg.Profile.PprofAddress = "localhost:6060"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	cfg.Profile.Interval = 10 * time.Millisecond
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for a sampling interval below 100ms")
+	}
+
+	// The interval is not checked while profiling is off
+	cfg.Profile.PprofAddress = ""
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestValidateThresholds(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Interface = "eth0"
+	if cfg.Thresholds.Enabled() {
+		t.Error("Default config should set no thresholds")
+	}
+
+	cfg.Thresholds.MaxFrameLossPct = 0 // Zero loss required
+	if !cfg.Thresholds.Enabled() {
+		t.Error("A zero frame loss limit is a threshold")
+	}
+	cfg.Thresholds.MinThroughputMbps = map[uint32]float64{64: 760, 1518: 985}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	cfg.Thresholds.MinThroughputMbps[512] = 0
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for a zero per-size throughput threshold")
+	}
+	delete(cfg.Thresholds.MinThroughputMbps, 512)
+
+	cfg.Thresholds.MinThroughputPct = 120
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for a throughput threshold above 100%")
+	}
+
+	cfg.Thresholds.MinThroughputPct = 95
+	cfg.Thresholds.MaxLatencyP99Us = -5
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for a negative latency threshold")
+	}
+}
+
+func TestValidateDUTSnapshot(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Interface = "eth0"
+	if cfg.DUTSnapshot.Enabled() {
+		t.Error("Default config should take no DUT snapshots")
+	}
+
+	cfg.DUTSnapshot.SSH = "admin@r1"
+	cfg.DUTSnapshot.Commands = []string{"show running-config"}
+	cfg.DUTSnapshot.Ignore = []string{`^! Last configuration change`}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	cfg.DUTSnapshot.Ignore = []string{"("}
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for an invalid ignore pattern")
+	}
+
+	cfg.DUTSnapshot.Ignore = nil
+	cfg.DUTSnapshot.Timeout = 0
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for a zero snapshot timeout")
+	}
+}
+
+func TestValidateGates(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Interface = "eth0"
+	cfg.Gates = []GateConfig{
+		{FrameSize: 1518, Message: "Re-patch to port 2"},
+		{ServiceID: 2, Message: "Move to the second UNI"},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	cfg.Gates[0].Message = ""
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for a gate without a message")
+	}
+
+	cfg.Gates[0] = GateConfig{FrameSize: 64, ServiceID: 1, Message: "Both"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for a gate naming both a frame size and a service")
+	}
+
+	cfg.Gates[0] = GateConfig{Message: "Neither"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for a gate naming no phase")
+	}
+}
+
+func TestValidateSoak(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Interface = "eth0"
+	cfg.TestType = TestSoak
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	cfg.Soak.RatePct = 0
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for zero soak rate")
+	}
+
+	cfg.Soak.RatePct = 90
+	cfg.Soak.BucketInterval = 30 * time.Second
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for bucket interval shorter than a sample")
+	}
+
+	cfg.Soak.BucketInterval = 15 * time.Minute
+	cfg.Soak.Duration = 10 * time.Second
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for duration shorter than a sample")
+	}
+
+	cfg.Soak.Duration = time.Hour
+	cfg.Soak.SeriesFile = "soak.jsonl"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Unexpected error for a .jsonl series: %v", err)
+	}
+	cfg.Soak.SeriesFile = "soak.xlsx"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for a series file neither .csv nor .jsonl")
+	}
+}
+
+func TestValidateQoS(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.TestType = TestQoS
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for qos without trex")
+	}
+
+	cfg.TRex.Server = "trex1"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if classes := cfg.QoS.ClassList(); len(classes) != 8 || classes[5].DSCP != 40 || classes[5].PCP != 5 {
+		t.Errorf("default classes = %+v", classes)
+	}
+
+	cfg.QoS.Marking = "cos"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for unknown marking")
+	}
+
+	cfg.QoS.Marking = MarkPCP
+	cfg.QoS.Classes = []QoSClass{
+		{Name: "EF", PCP: 5, DSCP: 46, SharePct: 30},
+		{Name: "BE", PCP: 0, DSCP: 0, SharePct: 60},
+	}
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for shares not adding up to 100%")
+	}
+
+	cfg.QoS.Classes[1].SharePct = 70
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	cfg.QoS.Classes[0].DSCP = 64
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for DSCP above 63")
+	}
+}
+
+func TestValidateAQM(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Interface = "eth0"
+	cfg.TestType = TestAQM
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	cfg.AQM.StartPct = 100
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for start_pct not below end_pct")
+	}
+
+	cfg.AQM.StartPct = 50
+	cfg.AQM.StepPct = 0
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for zero step_pct")
+	}
+
+	cfg.AQM.StepPct = 1
+	cfg.AQM.StepDuration = 100 * time.Millisecond
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for step_duration below 1s")
+	}
+
+	cfg.AQM.StepDuration = 5 * time.Second
+	cfg.TRex.Server = "trex1"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("aqm on trex: %v", err)
+	}
+}
+
+func TestValidateMicroburst(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Interface = "eth0"
+	cfg.TestType = TestMicroburst
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	cfg.Microburst.MaxFrames = 8
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for max_frames below start_frames")
+	}
+
+	cfg.Microburst.MaxFrames = 1000
+	cfg.Microburst.Bursts = 2000
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for more than 1024 bursts")
+	}
+
+	cfg.Microburst.Bursts = 10
+	cfg.Microburst.Gaps = nil
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for no gaps")
+	}
+
+	cfg.Microburst.Gaps = []time.Duration{0, 100 * time.Microsecond}
+	cfg.Microburst.BurstRatePct = 0
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for zero burst_rate_pct")
+	}
+
+	cfg.Microburst.BurstRatePct = 50
+	cfg.TRex.Server = "trex1"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for microburst on trex")
+	}
+}
+
+func TestValidateSelfTest(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Interface = "veth-tx"
+	cfg.TestType = TestSelfTest
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error without the netem interface")
+	}
+
+	cfg.SelfTest.Interface = "veth-rx"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if len(cfg.SelfTest.Suite()) != len(impair.DefaultSuite) {
+		t.Error("Expected the built-in suite without cases")
+	}
+
+	cfg.SelfTest.Interface = "veth-tx"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for netem on the test interface")
+	}
+
+	cfg.SelfTest.Interface = "veth-rx"
+	cfg.SelfTest.Cases = []impair.Case{{Name: "reorder", Netem: impair.Netem{ReorderPct: 5}}}
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for reordering without a delay")
+	}
+
+	cfg.SelfTest.Cases = []impair.Case{{Name: "wan", Netem: impair.Netem{Delay: 40 * time.Millisecond}}}
+	cfg.SelfTest.Tolerance.LossPct = 0
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for a zero tolerance")
+	}
+}
+
+func TestAcceptableLossFor(t *testing.T) {
+	latency := 0.05
+	tp := ThroughputConfig{
+		AcceptableLoss:       0.001,
+		AcceptableLossBySize: map[uint32]float64{64: 0.01},
+		AcceptableLossByTest: map[TestType]LossOverride{
+			TestLatency:        {AcceptableLoss: &latency, BySize: map[uint32]float64{1518: 0}},
+			TestSystemRecovery: {BySize: map[uint32]float64{128: 0.1}},
+		},
+	}
+	tests := []struct {
+		test TestType
+		fs   uint32
+		want float64
+	}{
+		{TestThroughput, 512, 0.001},
+		{TestThroughput, 64, 0.01},
+		{TestLatency, 64, 0.05},
+		{TestLatency, 1518, 0},
+		{TestSystemRecovery, 128, 0.1},
+		{TestSystemRecovery, 64, 0.01},
+		{TestSystemRecovery, 512, 0.001},
+	}
+	for _, tt := range tests {
+		if got := tp.AcceptableLossFor(tt.test, tt.fs); got != tt.want {
+			t.Errorf("AcceptableLossFor(%s, %d) = %v, want %v", tt.test, tt.fs, got, tt.want)
+		}
+	}
+
+	cfg := DefaultConfig()
+	cfg.Interface = "eth0"
+	cfg.Throughput = tp
+	cfg.Throughput.ResolutionPct = 0.1
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	cfg.Throughput.AcceptableLossBySize[64] = 101
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for acceptable loss above 100%")
+	}
+	cfg.Throughput.AcceptableLossBySize[64] = 0.01
+	cfg.Throughput.AcceptableLossByTest["latncy"] = LossOverride{}
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for an unknown test type")
+	}
+}
+
+func TestValidateUDPEcho(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.TestType = TestUDPEcho
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for missing udp_echo target")
+	}
+
+	// No interface needed: datagrams go through the kernel's UDP stack
+	cfg.UDPEcho.Target = "192.0.2.1"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	cfg.UDPEcho.RatePPS = 0
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for zero udp_echo rate")
+	}
+
+	cfg.UDPEcho.RatePPS = 1000
+	cfg.UDPEcho.Duration = 100 * time.Millisecond
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for udp_echo duration below 1s")
+	}
+
+	cfg.UDPEcho.Duration = time.Minute
+	cfg.TRex.Server = "trex1"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for udp_echo with trex")
+	}
+}
+
+func TestValidateY1564NoServices(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Interface = "eth0"
+	cfg.TestType = TestY1564Full
+	cfg.Y1564.Services = []Y1564Service{} // No services
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Error("Expected error for Y.1564 without services")
+	}
+}
+
+func TestValidateY1564ZeroCIR(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Interface = "eth0"
+	cfg.TestType = TestY1564Full
+	cfg.Y1564.Services = []Y1564Service{
+		{ServiceID: 1, Enabled: true, SLA: Y1564SLA{CIRMbps: 0}},
+	}
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Error("Expected error for Y.1564 with zero CIR")
+	}
+}
+
+func TestY1564StepCriteria(t *testing.T) {
+	sla := DefaultY1564SLA()
+	if fd, fdv, flr := sla.Criteria(sla.EIRSAC); fd != 10 || fdv != 5 || flr >= 0 {
+		t.Errorf("default EIR criteria = %v/%v/%v, want the SLA's delay and no loss check", fd, fdv, flr)
+	}
+
+	loss := 20.0
+	sla.PolicingSAC = Y1564SAC{FDThresholdMs: 50, FLRThresholdPct: &loss}
+	if fd, fdv, flr := sla.Criteria(sla.PolicingSAC); fd != 50 || fdv != 5 || flr != 20 {
+		t.Errorf("policing criteria = %v/%v/%v", fd, fdv, flr)
+	}
+
+	cfg := DefaultConfig()
+	cfg.Interface = "eth0"
+	cfg.TestType = TestY1564Config
+	cfg.Y1564.Services = []Y1564Service{{ServiceID: 1, Enabled: true, SLA: sla}}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	loss = 120
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for a policing FLR threshold above 100%")
+	}
+}
+
+func TestValidateY1564FlowFailurePolicy(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Interface = "eth0"
+	cfg.TestType = TestY1564Full
+	cfg.Y1564.Services = []Y1564Service{
+		{ServiceID: 1, Enabled: true, SLA: Y1564SLA{CIRMbps: 100}},
+	}
+	cfg.Y1564.FlowFailure.Policy = "retry"
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Error("Expected error for unknown flow failure policy")
+	}
+}
+
+func TestValidateRFC2889InsufficientPorts(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Interface = "eth0"
+	cfg.TestType = TestRFC2889Forwarding
+	cfg.RFC2889.PortCount = 1 // Need at least 2
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Error("Expected error for RFC 2889 with < 2 ports")
+	}
+}
+
+func TestValidateRFC2889Orientations(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Interface = "eth0"
+	cfg.TestType = TestRFC2889Forwarding
+	cfg.RFC2889.Orientations = []TrafficOrientation{OrientationOneToMany, OrientationManyToOne}
+
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected valid orientations, got: %v", err)
+	}
+
+	// More ports need more flows than one trial measures
+	cfg.RFC2889.PortCount = 4
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for forwarding across 4 ports")
+	}
+	// Other RFC 2889 tests do not drive the ports' flows
+	cfg.TestType = TestRFC2889Caching
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected valid orientations for caching, got: %v", err)
+	}
+
+	for _, o := range []TrafficOrientation{"mesh", "partial_mesh", "pairs", "ring"} {
+		cfg.RFC2889.Orientations = []TrafficOrientation{o}
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("Expected error for orientation %s", o)
+		}
+	}
+}
+
+func TestValidateRFC6349ZeroMSS(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Interface = "eth0"
+	cfg.TestType = TestRFC6349Throughput
+	cfg.RFC6349.MSS = 0
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Error("Expected error for RFC 6349 with zero MSS")
+	}
+}
+
+func TestValidateY1731ZeroMEPID(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Interface = "eth0"
+	cfg.TestType = TestY1731Delay
+	cfg.Y1731.MEPID = 0
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Error("Expected error for Y.1731 with zero MEP ID")
+	}
+}
+
+func TestValidateMEFZeroCIR(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Interface = "eth0"
+	cfg.TestType = TestMEFFull
+	cfg.MEF.CIRMbps = 0
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Error("Expected error for MEF with zero CIR")
+	}
+}
+
+func TestValidateTSNZeroCycleTime(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Interface = "eth0"
+	cfg.TestType = TestTSNFull
+	cfg.TSN.CycleTimeNs = 0
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Error("Expected error for TSN with zero cycle time")
+	}
+}
+
+// ============================================================================
+// StandardFrameSizes Tests
+// ============================================================================
+
+func TestStandardFrameSizes(t *testing.T) {
+	sizes := StandardFrameSizes(false)
+
+	expected := []uint32{64, 128, 256, 512, 1024, 1280, 1518}
+	if len(sizes) != len(expected) {
+		t.Errorf("Expected %d sizes, got %d", len(expected), len(sizes))
+	}
+
+	for i, size := range sizes {
+		if size != expected[i] {
+			t.Errorf("Size %d: expected %d, got %d", i, expected[i], size)
+		}
+	}
+}
+
+func TestStandardFrameSizesWithJumbo(t *testing.T) {
+	sizes := StandardFrameSizes(true)
+
+	expected := []uint32{64, 128, 256, 512, 1024, 1280, 1518, 9000}
+	if len(sizes) != len(expected) {
+		t.Errorf("Expected %d sizes, got %d", len(expected), len(sizes))
+	}
+
+	// Check last one is jumbo
+	if sizes[len(sizes)-1] != 9000 {
+		t.Errorf("Expected last size to be 9000, got %d", sizes[len(sizes)-1])
+	}
+}
+
+// ============================================================================
+// Load/Save Tests
+// ============================================================================
+
+func TestSaveAndLoad(t *testing.T) {
+	// Create temp directory
+	tmpDir, err := os.MkdirTemp("", "rfc2544-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	configPath := filepath.Join(tmpDir, "test-config.yaml")
+
+	// Create config
+	cfg := DefaultConfig()
+	cfg.Interface = "eth0"
+	cfg.TestType = TestLatency
+	cfg.FrameSize = 1518
+
+	// Save
+	err = cfg.Save(configPath)
+	if err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+
+	// Load
+	loaded, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	// Verify
+	if loaded.Interface != cfg.Interface {
+		t.Errorf("Interface: expected %s, got %s", cfg.Interface, loaded.Interface)
+	}
+
+	if loaded.TestType != cfg.TestType {
+		t.Errorf("TestType: expected %s, got %s", cfg.TestType, loaded.TestType)
+	}
+
+	if loaded.FrameSize != cfg.FrameSize {
+		t.Errorf("FrameSize: expected %d, got %d", cfg.FrameSize, loaded.FrameSize)
+	}
+}
+
+func TestLoadNonexistent(t *testing.T) {
+	_, err := Load("/nonexistent/path/config.yaml")
+	if err == nil {
+		t.Error("Expected error for nonexistent file")
+	}
+}
+
+func TestLoadInvalidYAML(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "rfc2544-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	configPath := filepath.Join(tmpDir, "invalid.yaml")
+
+	// Write invalid YAML
+	err = os.WriteFile(configPath, []byte("{{{{invalid yaml"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	_, err = Load(configPath)
+	if err == nil {
+		t.Error("Expected error for invalid YAML")
+	}
+}
+
+func TestLoadInvalidConfig(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "rfc2544-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	configPath := filepath.Join(tmpDir, "invalid-config.yaml")
+
+	// Write config missing interface
+	err = os.WriteFile(configPath, []byte("test_type: throughput\n"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write file: %v", err)
 	}
 
 	_, err = Load(configPath)
@@ -509,6 +1882,36 @@ func TestLoadInvalidConfig(t *testing.T) {
 	}
 }
 
+func TestLoadStrict(t *testing.T) {
+	data := []byte("interface: eth0\ntrail_duration: 5s\nthroughput:\n  resolution: 0.5\n")
+
+	// Without strict mode the typos leave the defaults in place
+	cfg, err := LoadLayers(Layers{Data: data})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cfg.TrialDuration != DefaultConfig().TrialDuration {
+		t.Errorf("trial duration = %v", cfg.TrialDuration)
+	}
+
+	_, err = LoadLayers(Layers{Data: data, Strict: true})
+	if err == nil {
+		t.Fatal("Expected error for unknown keys")
+	}
+	for _, want := range []string{"line 2: field trail_duration not found", "line 4: field resolution not found"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not contain %q", err, want)
+		}
+	}
+
+	if _, err := LoadLayers(Layers{Data: []byte("interface: eth0\ntrial_duration: 5s\n"), Strict: true}); err != nil {
+		t.Errorf("valid config: %v", err)
+	}
+	if _, err := LoadLayers(Layers{Data: []byte("# nothing set\n"), Strict: true}); err == nil {
+		t.Error("Expected validation error for an empty config")
+	}
+}
+
 // ============================================================================
 // Test Type Tests
 // ============================================================================
@@ -539,6 +1942,23 @@ func TestTestTypeConstants(t *testing.T) {
 // Output Format Tests
 // ============================================================================
 
+func TestTestTypesAccepted(t *testing.T) {
+	seen := make(map[TestType]bool)
+	for _, tt := range TestTypes() {
+		if seen[tt] {
+			t.Errorf("Duplicate test type %s", tt)
+		}
+		seen[tt] = true
+
+		cfg := DefaultConfig()
+		cfg.Interface = "eth0"
+		cfg.TestType = tt
+		if err := cfg.Validate(); err != nil && strings.Contains(err.Error(), "invalid test type") {
+			t.Errorf("TestTypes() lists %s but Validate rejects it", tt)
+		}
+	}
+}
+
 func TestOutputFormatConstants(t *testing.T) {
 	formats := map[OutputFormat]string{
 		FormatText: "text",
@@ -572,3 +1992,164 @@ func BenchmarkValidate(b *testing.B) {
 		_ = cfg.Validate()
 	}
 }
+
+func TestValidateTUIKeys(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Interface = "eth0"
+	cfg.TUI.Keys.Start = []string{"F5", "Ctrl+S"}
+	cfg.TUI.Keys.Quit = []string{"Ctrl-Q"}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("remapped keys: %v", err)
+	}
+
+	for _, bad := range [][]string{{"F99"}, {"Ctrl-C"}, {"x"}} {
+		cfg.TUI.Keys.Start = bad
+		if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "tui keys") {
+			t.Errorf("start %v: %v", bad, err)
+		}
+	}
+}
+
+func TestValidateHeaders(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Interface = "eth0"
+	cfg.Headers = HeadersConfig{DstMAC: "00:1b:21:0a:0b:0c", SrcIP: "192.0.2.0/29", DstIP: "198.51.100.1", DstPort: "5000-5009"}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	h, err := cfg.Headers.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.SrcIP.Size() != 6 || h.DstIP.Size() != 1 || h.DstPort != (PortRange{5000, 5009}) || h.SrcPort.Size() != 0 || !h.Ranged() {
+		t.Errorf("Parsed %+v", h)
+	}
+
+	for _, bad := range []HeadersConfig{
+		{DstMAC: "01:00:5e:00:00:01"},
+		{SrcPort: "0"},
+		{SrcPort: "6000-5000"},
+		{DstIP: "2001:db8::1"},
+	} {
+		cfg.Headers = bad
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("Expected error for %+v", bad)
+		}
+	}
+
+	// A range would overwrite the addresses the pairs rotate
+	cfg.Headers = HeadersConfig{SrcPort: "5000-5099"}
+	cfg.Addressing.Streams = 4
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for a port range with streams")
+	}
+	cfg.Addressing.Streams = 0
+	cfg.Headers = HeadersConfig{SrcIP: "192.0.2.1"}
+	cfg.IPVersion = IPv6
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for an IPv4 source with IPv6 frames")
+	}
+}
+
+func TestValidateDSCP(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Interface = "eth0"
+	cfg.DSCP = 46
+	cfg.IPVersion = DualStack
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if tc := cfg.IPv6TrafficClass(); tc != 184 {
+		t.Errorf("IPv6 traffic class %d, want the DSCP's 184", tc)
+	}
+	cfg.IPv6.TrafficClass = 104
+	if tc := cfg.IPv6TrafficClass(); tc != 104 {
+		t.Errorf("IPv6 traffic class %d, want the configured 104", tc)
+	}
+
+	cfg.DSCP = 64
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for DSCP 64")
+	}
+	cfg.DSCP = 46
+	cfg.IPVersion = IPv4
+	cfg.Packet.Template = "eth/ipv4(tos=0xb8)/udp"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for a DSCP with a packet template")
+	}
+	cfg.Packet.Template = ""
+	cfg.TRex.Server = "trex1"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for a DSCP with TRex")
+	}
+
+	// Y.1564 services mark their cos instead
+	cfg.TRex.Server = ""
+	cfg.TestType = TestY1564Config
+	cfg.Y1564.Services = []Y1564Service{{ServiceID: 1, Enabled: true, CoS: 64, SLA: Y1564SLA{CIRMbps: 100}}}
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for service cos 64")
+	}
+}
+
+func TestValidateDataplaneWatchdog(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Interface = "eth0"
+	if cfg.DataplaneWatchdog != 30*time.Second {
+		t.Errorf("Default watchdog %v", cfg.DataplaneWatchdog)
+	}
+	cfg.DataplaneWatchdog = 0
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Unexpected error for a disabled watchdog: %v", err)
+	}
+	cfg.DataplaneWatchdog = 100 * time.Millisecond
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for a 100ms watchdog")
+	}
+}
+
+func TestParsePattern(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{"", "incrementing"},
+		{"Zeros", "zeros"},
+		{"PRBS-31", "prbs31"},
+		{"random", "random:0"},
+		{"random:0x2a", "random:42"},
+		{"hex:DE AD:be-ef", "hex:deadbeef"},
+		{"hex:0x00ff", "hex:00ff"},
+	}
+	for _, c := range cases {
+		p, err := ParsePattern(c.in)
+		if err != nil || p.String() != c.want {
+			t.Errorf("%q: %v, %v; want %s", c.in, p, err, c.want)
+		}
+	}
+	for _, bad := range []string{"noise", "zeros:1", "random:x", "hex:", "hex:abc", "hex:" + strings.Repeat("00", MaxPatternBytes+1)} {
+		if _, err := ParsePattern(bad); err == nil {
+			t.Errorf("expected an error for %q", bad)
+		}
+	}
+
+	cfg := DefaultConfig()
+	cfg.Interface = "eth0"
+	cfg.PayloadPattern = "prbs31"
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	cfg.PayloadPattern = "hex:xyz"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for an invalid hex pattern")
+	}
+	cfg.PayloadPattern = "zeros"
+	cfg.TRex.Server = "trex1"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for a payload pattern with TRex")
+	}
+	cfg.PayloadPattern = "incrementing"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("the default pattern with TRex: %v", err)
+	}
+}
@@ -0,0 +1,101 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Methodologies a deviation departs from
+const (
+	StandardRFC2544 = "RFC 2544"
+	StandardY1564   = "ITU-T Y.1564"
+)
+
+// Y.1564 recommended minimums
+const (
+	Y1564MinConfigSteps  = 4                // Section 8.1: steps up to the CIR, e.g. 25, 50, 75 and 100%
+	Y1564MinStepDuration = 60 * time.Second // Section 8.1
+	Y1564MinPerfDuration = 15 * time.Minute // Section 8.2: the shortest of its 15 minute, 2 hour and 24 hour runs
+)
+
+// Deviation is a setting overridden from a default that follows the
+// methodology to a value that does not. The results are still measured,
+// but are not the standard's result, so reports carry the list with them.
+type Deviation struct {
+	Standard       string `json:"standard" yaml:"standard"` // StandardRFC2544 or StandardY1564
+	Section        string `json:"section" yaml:"section"`
+	Setting        string `json:"setting" yaml:"setting"`               // Config key, e.g. "trial_duration"
+	Recommendation string `json:"recommendation" yaml:"recommendation"` // What the standard asks for
+	Configured     string `json:"configured" yaml:"configured"`         // What the run used
+}
+
+func (d Deviation) String() string {
+	return fmt.Sprintf("%s %s: %s (%s: %s)", d.Standard, d.Section, d.Recommendation, d.Setting, d.Configured)
+}
+
+// Deviations lists the checks the configuration fails that the defaults
+// pass for the same test: the overrides that take a run outside RFC 2544
+// or Y.1564. A recommendation the defaults do not follow either, such as
+// repeating tests with broadcast frames, is an omission the compliance
+// checks report rather than a deviation.
+func (c *Config) Deviations() []Deviation {
+	d := DefaultConfig()
+	d.TestType = c.TestType
+
+	var devs []Deviation
+	add := func(standard string, checks, defaults []ComplianceCheck) {
+		// The same test type gives the same checks in the same order
+		for i, chk := range checks {
+			if !chk.Honored && i < len(defaults) && defaults[i].Honored {
+				devs = append(devs, Deviation{
+					Standard:       standard,
+					Section:        chk.Section,
+					Setting:        chk.Setting,
+					Recommendation: chk.Recommendation,
+					Configured:     chk.Detail,
+				})
+			}
+		}
+	}
+	add(StandardRFC2544, c.Compliance(), d.Compliance())
+	add(StandardY1564, c.y1564Compliance(), d.y1564Compliance())
+	return devs
+}
+
+// y1564Compliance checks the Y.1564 test phases the selected test runs
+func (c *Config) y1564Compliance() []ComplianceCheck {
+	y := c.Y1564
+	var checks []ComplianceCheck
+	if c.TestType == TestY1564Config || c.TestType == TestY1564Full {
+		steps := make([]string, len(y.ConfigSteps))
+		var top float64
+		for i, s := range y.ConfigSteps {
+			steps[i] = fmt.Sprintf("%g", s)
+			top = max(top, s)
+		}
+		checks = append(checks, ComplianceCheck{
+			Section:        "8.1",
+			Setting:        "y1564.config_steps",
+			Recommendation: fmt.Sprintf("Step up to the CIR in at least %d steps", Y1564MinConfigSteps),
+			Honored:        len(y.ConfigSteps) >= Y1564MinConfigSteps && top >= 100,
+			Detail:         fmt.Sprintf("Steps %s%% of CIR", strings.Join(steps, ", ")),
+		}, ComplianceCheck{
+			Section:        "8.1",
+			Setting:        "y1564.step_duration",
+			Recommendation: fmt.Sprintf("Hold each step for at least %v", Y1564MinStepDuration),
+			Honored:        y.StepDuration >= Y1564MinStepDuration,
+			Detail:         fmt.Sprintf("Step duration %v", y.StepDuration),
+		})
+	}
+	if c.TestType == TestY1564Perf || c.TestType == TestY1564Full {
+		checks = append(checks, ComplianceCheck{
+			Section:        "8.2",
+			Setting:        "y1564.perf_duration",
+			Recommendation: fmt.Sprintf("Run the performance test for at least %v", Y1564MinPerfDuration),
+			Honored:        y.PerfDuration >= Y1564MinPerfDuration,
+			Detail:         fmt.Sprintf("Performance test duration %v", y.PerfDuration),
+		})
+	}
+	return checks
+}
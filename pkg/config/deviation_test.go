@@ -0,0 +1,74 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDeviationsDefaults(t *testing.T) {
+	for _, tt := range []TestType{TestThroughput, TestSuite, TestY1564Full} {
+		cfg := DefaultConfig()
+		cfg.TestType = tt
+		if devs := cfg.Deviations(); len(devs) != 0 {
+			t.Errorf("%s defaults deviate: %v", tt, devs)
+		}
+	}
+}
+
+func TestDeviationsRFC2544(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.TestType = TestSuite
+	cfg.TrialDuration = 10 * time.Second
+	cfg.Throughput.AcceptableLoss = 0.1
+	cfg.FrameLoss.StepPct = 20
+	cfg.BackToBack.Trials = 5
+
+	got := make(map[string]Deviation)
+	for _, d := range cfg.Deviations() {
+		if d.Standard != StandardRFC2544 {
+			t.Errorf("standard %q", d.Standard)
+		}
+		got[d.Setting] = d
+	}
+	for _, setting := range []string{"trial_duration", "throughput.acceptable_loss", "frame_loss.step_pct", "back_to_back.trials"} {
+		if _, ok := got[setting]; !ok {
+			t.Errorf("no deviation for %s in %v", setting, got)
+		}
+	}
+	// The defaults send no broadcast frames either, so that is no override
+	if _, ok := got["modifiers.broadcast_pct"]; ok || len(got) != 4 {
+		t.Errorf("deviations %v", got)
+	}
+	if d := got["trial_duration"]; d.Section != "24" || d.Configured != "Trial duration 10s" {
+		t.Errorf("trial duration deviation %+v", d)
+	}
+}
+
+func TestDeviationsY1564(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.TestType = TestY1564Full
+	cfg.Y1564.ConfigSteps = []float64{50, 100}
+	cfg.Y1564.StepDuration = 10 * time.Second
+	cfg.Y1564.PerfDuration = time.Minute
+
+	devs := cfg.Deviations()
+	if len(devs) != 3 {
+		t.Fatalf("deviations %v", devs)
+	}
+	if d := devs[0]; d.Standard != StandardY1564 || d.Setting != "y1564.config_steps" || d.Configured != "Steps 50, 100% of CIR" {
+		t.Errorf("steps deviation %+v", d)
+	}
+
+	cfg.TestType = TestY1564Perf
+	if devs := cfg.Deviations(); len(devs) != 1 || devs[0].Setting != "y1564.perf_duration" {
+		t.Errorf("perf test deviations %v", devs)
+	}
+
+	// Four steps that stop short of the CIR never test it
+	cfg.TestType = TestY1564Config
+	cfg.Y1564.ConfigSteps = []float64{10, 20, 30, 40}
+	cfg.Y1564.StepDuration = time.Minute
+	if devs := cfg.Deviations(); len(devs) != 1 || devs[0].Setting != "y1564.config_steps" {
+		t.Errorf("config test deviations %v", devs)
+	}
+}
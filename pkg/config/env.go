@@ -0,0 +1,152 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix starts every environment variable that sets a config key. The
+// rest of the name is the key's YAML path in upper case, joined by
+// underscores: trial_duration is RFC2544_TRIAL_DURATION and
+// throughput.resolution_pct is RFC2544_THROUGHPUT_RESOLUTION_PCT.
+const EnvPrefix = "RFC2544_"
+
+// envReserved are RFC2544_* variables with other uses, e.g. the extra
+// arguments the service unit passes to the binary
+var envReserved = map[string]bool{"RFC2544_ARGS": true, SecretsKeyEnv: true}
+
+// EnvVar is one environment variable and the config key it sets
+type EnvVar struct {
+	Name string // e.g. RFC2544_TRIAL_DURATION
+	Key  string // YAML path, e.g. trial_duration
+	Type string // e.g. duration, []uint32
+}
+
+// envField is a settable config field found by walking the struct
+type envField struct {
+	EnvVar
+	index []int
+}
+
+// envFields lists every leaf of Config by its environment variable name
+func envFields() map[string]envField {
+	fields := make(map[string]envField)
+	var walk func(t reflect.Type, path []string, index []int)
+	walk = func(t reflect.Type, path []string, index []int) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			key, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+			if key == "" || key == "-" || !f.IsExported() {
+				continue
+			}
+			p := append(append([]string(nil), path...), key)
+			idx := append(append([]int(nil), index...), i)
+			if f.Type.Kind() == reflect.Struct && f.Type != reflect.TypeOf(time.Duration(0)) {
+				walk(f.Type, p, idx)
+				continue
+			}
+			name := EnvPrefix + strings.ToUpper(strings.Join(p, "_"))
+			if _, dup := fields[name]; dup {
+				panic("config: two keys map to " + name)
+			}
+			fields[name] = envField{EnvVar{Name: name, Key: strings.Join(p, "."), Type: envType(f.Type)}, idx}
+		}
+	}
+	walk(reflect.TypeOf(Config{}), nil, nil)
+	return fields
+}
+
+// envType names a field's type for the variable listing
+func envType(t reflect.Type) string {
+	if t == reflect.TypeOf(time.Duration(0)) {
+		return "duration"
+	}
+	switch t.Kind() {
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Struct {
+			return "YAML list"
+		}
+		return "[]" + envType(t.Elem())
+	case reflect.Map:
+		return "YAML map"
+	case reflect.String:
+		return "string"
+	}
+	return t.Kind().String()
+}
+
+// EnvVars lists the environment variables for every config key, by name
+func EnvVars() []EnvVar {
+	fields := envFields()
+	vars := make([]EnvVar, 0, len(fields))
+	for _, f := range fields {
+		vars = append(vars, f.EnvVar)
+	}
+	sort.Slice(vars, func(i, j int) bool { return vars[i].Name < vars[j].Name })
+	return vars
+}
+
+// ApplyEnv sets config keys from the RFC2544_* variables in environ, given
+// as KEY=value pairs like os.Environ. Values are YAML scalars; lists may
+// be comma-separated ("64,512,1518") and lists of structs and maps are
+// YAML flow syntax. An unknown RFC2544_* name is an error, to catch typos.
+func (c *Config) ApplyEnv(environ []string) error {
+	fields := envFields()
+	for _, kv := range environ {
+		name, value, ok := envPair(kv)
+		if !ok {
+			continue
+		}
+		f, known := fields[name]
+		if !known {
+			return fmt.Errorf("unknown environment variable %s", name)
+		}
+		if err := setEnvField(reflect.ValueOf(c).Elem().FieldByIndex(f.index), value); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// EnvNames returns the sorted names of the RFC2544_* variables in environ
+// that ApplyEnv applies
+func EnvNames(environ []string) []string {
+	var names []string
+	for _, kv := range environ {
+		if name, _, ok := envPair(kv); ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// envPair splits a KEY=value pair, reporting whether it is a config variable
+func envPair(kv string) (name, value string, ok bool) {
+	name, value, ok = strings.Cut(kv, "=")
+	return name, value, ok && strings.HasPrefix(name, EnvPrefix) && !envReserved[name]
+}
+
+// setEnvField decodes value into the field v
+func setEnvField(v reflect.Value, value string) error {
+	// Strings are taken as-is so values like "on" or "1:2" stay literal
+	if v.Kind() == reflect.String {
+		v.SetString(value)
+		return nil
+	}
+	if v.Kind() == reflect.Slice && !strings.HasPrefix(strings.TrimSpace(value), "[") {
+		value = "[" + value + "]"
+	}
+	// Decode into a fresh value so lists and maps replace the old ones
+	fresh := reflect.New(v.Type())
+	if err := yaml.Unmarshal([]byte(value), fresh.Interface()); err != nil {
+		return fmt.Errorf("invalid %s value %q", envType(v.Type()), value)
+	}
+	v.Set(fresh.Elem())
+	return nil
+}
@@ -0,0 +1,84 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEnvVars(t *testing.T) {
+	byName := map[string]EnvVar{}
+	for _, v := range EnvVars() {
+		byName[v.Name] = v
+	}
+	for name, want := range map[string]EnvVar{
+		"RFC2544_INTERFACE":                 {Key: "interface", Type: "string"},
+		"RFC2544_TRIAL_DURATION":            {Key: "trial_duration", Type: "duration"},
+		"RFC2544_THROUGHPUT_RESOLUTION_PCT": {Key: "throughput.resolution_pct", Type: "float64"},
+		"RFC2544_FRAME_SIZES":               {Key: "frame_sizes", Type: "[]uint32"},
+		"RFC2544_Y1564_SERVICES":            {Key: "y1564.services", Type: "YAML list"},
+	} {
+		got, ok := byName[name]
+		if !ok {
+			t.Errorf("%s not listed", name)
+			continue
+		}
+		if got.Key != want.Key || got.Type != want.Type {
+			t.Errorf("%s = %+v, want key %s type %s", name, got, want.Key, want.Type)
+		}
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	cfg := DefaultConfig()
+	env := []string{
+		"PATH=/usr/bin",
+		"RFC2544_ARGS=--web :8080", // Service unit arguments, not a key
+		"RFC2544_INTERFACE=eth1",
+		"RFC2544_TEST_TYPE=latency",
+		"RFC2544_TRIAL_DURATION=15s",
+		"RFC2544_FRAME_SIZES=64,512,1518",
+		"RFC2544_LATENCY_LOAD_LEVELS=[50, 100]",
+		"RFC2544_THROUGHPUT_RESOLUTION_PCT=0.5",
+		"RFC2544_HW_TIMESTAMP=false",
+		"RFC2544_THRESHOLDS_MIN_THROUGHPUT_MBPS={64: 760}",
+		"RFC2544_Y1564_SERVICES=[{service_id: 1, service_name: EVPL, enabled: true, sla: {cir_mbps: 100}}]",
+	}
+	if err := cfg.ApplyEnv(env); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Interface != "eth1" || cfg.TestType != TestLatency || cfg.TrialDuration != 15*time.Second {
+		t.Errorf("scalars: interface %q, test %s, trial %v", cfg.Interface, cfg.TestType, cfg.TrialDuration)
+	}
+	if !reflect.DeepEqual(cfg.FrameSizes, []uint32{64, 512, 1518}) || !reflect.DeepEqual(cfg.Latency.LoadLevels, []float64{50, 100}) {
+		t.Errorf("lists: sizes %v, loads %v", cfg.FrameSizes, cfg.Latency.LoadLevels)
+	}
+	if cfg.Throughput.ResolutionPct != 0.5 || cfg.HWTimestamp {
+		t.Errorf("resolution %v, hw timestamp %v", cfg.Throughput.ResolutionPct, cfg.HWTimestamp)
+	}
+	if cfg.Thresholds.MinThroughputMbps[64] != 760 {
+		t.Errorf("map: %v", cfg.Thresholds.MinThroughputMbps)
+	}
+	if len(cfg.Y1564.Services) != 1 || cfg.Y1564.Services[0].SLA.CIRMbps != 100 {
+		t.Errorf("services: %+v", cfg.Y1564.Services)
+	}
+
+	names := EnvNames(env)
+	if len(names) != len(env)-2 || names[0] != "RFC2544_FRAME_SIZES" {
+		t.Errorf("EnvNames() = %v", names)
+	}
+}
+
+func TestApplyEnvErrors(t *testing.T) {
+	for _, kv := range []string{
+		"RFC2544_TRIAL_DURATON=10s",
+		"RFC2544_TRIAL_DURATION=ten seconds",
+		"RFC2544_FRAME_SIZES=64,big",
+	} {
+		err := DefaultConfig().ApplyEnv([]string{kv})
+		if name, _, _ := strings.Cut(kv, "="); err == nil || !strings.Contains(err.Error(), name) {
+			t.Errorf("%s: error = %v, want one naming the variable", kv, err)
+		}
+	}
+}
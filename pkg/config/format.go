@@ -0,0 +1,187 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileFormat is a config file syntax. The yaml tags name the keys in
+// every format, so a setting is spelled the same in all three.
+type FileFormat string
+
+const (
+	FileYAML FileFormat = "yaml"
+	FileJSON FileFormat = "json"
+	FileTOML FileFormat = "toml"
+)
+
+// FileFormatOf returns a config file's format from its extension: .json,
+// .toml, and YAML for anything else
+func FileFormatOf(path string) FileFormat {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return FileJSON
+	case ".toml":
+		return FileTOML
+	}
+	return FileYAML
+}
+
+// Marshal encodes the configuration in a format
+func (c *Config) Marshal(f FileFormat) ([]byte, error) {
+	data, err := yaml.Marshal(c)
+	if err != nil || f == FileYAML {
+		return data, err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	switch f {
+	case FileJSON:
+		err = writeJSON(&buf, doc.Content[0], "")
+		buf.WriteByte('\n')
+	case FileTOML:
+		err = writeTOML(&buf, nil, doc.Content[0])
+	default:
+		err = fmt.Errorf("unknown config format %q", f)
+	}
+	return buf.Bytes(), err
+}
+
+// yamlDocument is a config document translated to YAML for decoding.
+// lines maps lines of the translation back to the source, so errors name
+// the line a TOML setting is on.
+type yamlDocument struct {
+	data  []byte
+	lines map[int]int
+}
+
+// toYAML translates a config document in the format of path to YAML. JSON
+// is already YAML and keeps its lines; it is only checked to be JSON.
+func toYAML(path string, data []byte) (*yamlDocument, error) {
+	switch FileFormatOf(path) {
+	case FileJSON:
+		var v interface{}
+		if err := json.Unmarshal(data, &v); err != nil {
+			if se, ok := err.(*json.SyntaxError); ok {
+				return nil, fmt.Errorf("json: line %d: %v", 1+bytes.Count(data[:se.Offset], []byte("\n")), err)
+			}
+			return nil, fmt.Errorf("json: %w", err)
+		}
+	case FileTOML:
+		root, err := parseTOML(data)
+		if err != nil {
+			return nil, err
+		}
+		out, err := yaml.Marshal(root)
+		if err != nil {
+			return nil, err
+		}
+		var doc yaml.Node
+		if err := yaml.Unmarshal(out, &doc); err != nil {
+			return nil, err
+		}
+		lines := make(map[int]int)
+		mapLines(doc.Content[0], root, lines)
+		return &yamlDocument{data: out, lines: lines}, nil
+	}
+	return &yamlDocument{data: data}, nil
+}
+
+// mapLines records the source line of each node of a translation
+func mapLines(out, src *yaml.Node, lines map[int]int) {
+	if out.Line != 0 && src.Line != 0 {
+		if _, ok := lines[out.Line]; !ok {
+			lines[out.Line] = src.Line
+		}
+	}
+	for i := range out.Content {
+		if i < len(src.Content) {
+			mapLines(out.Content[i], src.Content[i], lines)
+		}
+	}
+}
+
+var errorLine = regexp.MustCompile(`^line (\d+): `)
+
+// relocate rewrites the line numbers of a decoding error from the
+// translation to the source
+func (d *yamlDocument) relocate(err error) error {
+	te, ok := err.(*yaml.TypeError)
+	if !ok || d.lines == nil {
+		return err
+	}
+	out := &yaml.TypeError{Errors: make([]string, len(te.Errors))}
+	for i, e := range te.Errors {
+		out.Errors[i] = errorLine.ReplaceAllStringFunc(e, func(m string) string {
+			n, _ := strconv.Atoi(errorLine.FindStringSubmatch(m)[1])
+			if src, ok := d.lines[n]; ok {
+				n = src
+			}
+			return fmt.Sprintf("line %d: ", n)
+		})
+	}
+	return out
+}
+
+// writeJSON writes a YAML node as indented JSON, keeping key order
+func writeJSON(buf *bytes.Buffer, n *yaml.Node, indent string) error {
+	switch n.Kind {
+	case yaml.MappingNode, yaml.SequenceNode:
+		open, close, step := "{", "}", 2
+		if n.Kind == yaml.SequenceNode {
+			open, close, step = "[", "]", 1
+		}
+		if len(n.Content) == 0 {
+			buf.WriteString(open + close)
+			return nil
+		}
+		buf.WriteString(open)
+		inner := indent + "  "
+		for i := 0; i < len(n.Content); i += step {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			buf.WriteString("\n" + inner)
+			v := n.Content[i]
+			if step == 2 {
+				key, _ := json.Marshal(v.Value)
+				buf.Write(key)
+				buf.WriteString(": ")
+				v = n.Content[i+1]
+			}
+			if err := writeJSON(buf, v, inner); err != nil {
+				return err
+			}
+		}
+		buf.WriteString("\n" + indent + close)
+		return nil
+	case yaml.ScalarNode:
+		switch n.Tag {
+		case "!!null":
+			buf.WriteString("null")
+		case "!!bool", "!!int":
+			buf.WriteString(n.Value)
+		case "!!float":
+			f, err := strconv.ParseFloat(n.Value, 64)
+			if err != nil {
+				return fmt.Errorf("json has no float %s", n.Value)
+			}
+			buf.WriteString(strconv.FormatFloat(f, 'g', -1, 64))
+		default:
+			s, _ := json.Marshal(n.Value)
+			buf.Write(s)
+		}
+		return nil
+	}
+	return fmt.Errorf("cannot write YAML node kind %d as JSON", n.Kind)
+}
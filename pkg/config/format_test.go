@@ -0,0 +1,118 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadFormats(t *testing.T) {
+	dir := writeConfigs(t, map[string]string{
+		"lab.yaml": `interface: eth0
+trial_duration: 30s
+frame_sizes: [64, 1518]
+throughput:
+  resolution_pct: 0.5
+y1564:
+  services:
+    - service_id: 1
+      service_name: "data \"gold\""
+`,
+		"lab.json": `{
+	"interface": "eth0",
+	"trial_duration": "30s",
+	"frame_sizes": [64, 1518],
+	"throughput": {"resolution_pct": 0.5},
+	"y1564": {"services": [{"service_id": 1, "service_name": "data \"gold\""}]}
+}
+`,
+		"lab.toml": `# Lab circuit
+interface = "eth0"
+trial_duration = "30s"
+frame_sizes = [
+  64,
+  1_518, # jumbo off
+]
+throughput.resolution_pct = 0.5
+
+[[y1564.services]]
+service_id = 1
+service_name = 'data "gold"'
+`,
+	})
+
+	for _, name := range []string{"lab.yaml", "lab.json", "lab.toml"} {
+		cfg, err := LoadLayers(Layers{File: filepath.Join(dir, name), Strict: true})
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if cfg.Interface != "eth0" || cfg.TrialDuration != 30*time.Second || !reflect.DeepEqual(cfg.FrameSizes, []uint32{64, 1518}) {
+			t.Errorf("%s: interface %q, trial %v, sizes %v", name, cfg.Interface, cfg.TrialDuration, cfg.FrameSizes)
+		}
+		if cfg.Throughput.ResolutionPct != 0.5 || cfg.Throughput.MaxIterations != DefaultConfig().Throughput.MaxIterations {
+			t.Errorf("%s: throughput %+v", name, cfg.Throughput)
+		}
+		if len(cfg.Y1564.Services) != 1 || cfg.Y1564.Services[0].ServiceName != `data "gold"` {
+			t.Errorf("%s: services %+v", name, cfg.Y1564.Services)
+		}
+	}
+}
+
+func TestSaveFormats(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Interface = "eth0"
+	cfg.FrameSizes = []uint32{64, 9000}
+	cfg.Throughput.ResolutionPct = 1
+	cfg.QoS.Classes = []QoSClass{{Name: "voice", DSCP: 46}}
+	cfg.Y1564.Services = []Y1564Service{{ServiceID: 1, ServiceName: "tab\there"}}
+	want, err := cfg.Marshal(FileYAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	for _, name := range []string{"saved.json", "saved.toml", "saved.yml"} {
+		path := filepath.Join(dir, name)
+		if err := cfg.Save(path); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		back, err := LoadLayers(Layers{File: path, Strict: true})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		got, _ := back.Marshal(FileYAML)
+		if string(got) != string(want) {
+			t.Errorf("%s does not load back to the saved configuration", name)
+		}
+	}
+}
+
+func TestTOMLErrors(t *testing.T) {
+	dir := writeConfigs(t, map[string]string{
+		"typo.toml":   "interface = \"eth0\"\n\n[throughput]\nresolution_pct = 0.5\nresolutoin = 1\n",
+		"twice.toml":  "interface = \"eth0\"\ninterface = \"eth1\"\n",
+		"table.toml":  "[throughput]\n[throughput]\n",
+		"date.toml":   "interface = \"eth0\"\ntrial_duration = 1979-05-27\n",
+		"string.toml": "interface = \"eth0\n",
+		"bad.json":    "{\"interface\": \"eth0\",\n}\n",
+	})
+	tests := []struct {
+		file, want string
+	}{
+		{"typo.toml", "line 5: field resolutoin not found"},
+		{"twice.toml", "toml: line 2: interface is set twice"},
+		{"table.toml", "toml: line 2: table [throughput] defined twice"},
+		{"date.toml", "dates and times are not supported"},
+		{"string.toml", "unterminated string"},
+		{"bad.json", "json: line 2:"},
+	}
+	for _, tt := range tests {
+		_, err := LoadLayers(Layers{File: filepath.Join(dir, tt.file), Strict: true})
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: %v, want %q", tt.file, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// additiveLists are the lists a config file adds to those of the files it
+// includes instead of replacing them, so a site overlay can add a service
+// to a shared base. Every other list, e.g. frame_sizes, is replaced.
+var additiveLists = []func(c *Config) reflect.Value{
+	func(c *Config) reflect.Value { return reflect.ValueOf(&c.Y1564.Services).Elem() },
+	func(c *Config) reflect.Value { return reflect.ValueOf(&c.QoS.Classes).Elem() },
+	func(c *Config) reflect.Value { return reflect.ValueOf(&c.Gates).Elem() },
+}
+
+// includeFile is a config file on the include stack
+type includeFile struct {
+	name string // As included, for errors
+	abs  string // For cycle detection
+}
+
+// merger decodes a config document and the files it includes over one
+// configuration, in include order
+type merger struct {
+	cfg    *Config
+	strict bool
+	top    string          // Path of the document loaded
+	seen   map[string]bool // Files already merged
+	files  []string        // Absolute paths of the files merged, in order
+	set    []bool          // additiveLists a file has set
+}
+
+// decodeConfig decodes a config document over cfg after the files it
+// lists under include, each merged over the ones before it: nested
+// settings merge key by key and the additiveLists add up. Includes are
+// relative to the including file; path is empty for data from elsewhere,
+// whose includes are relative to the working directory. A file included
+// twice is merged once. Each file's format comes from its extension, so a
+// TOML site file may include a YAML base.
+func decodeConfig(path string, data []byte, cfg *Config, strict bool) error {
+	m := &merger{cfg: cfg, strict: strict, top: path, seen: make(map[string]bool), set: make([]bool, len(additiveLists))}
+	var stack []includeFile
+	if path != "" {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return err
+		}
+		stack = append(stack, includeFile{name: path, abs: abs})
+	}
+	if err := m.decode(path, data, stack); err != nil {
+		return err
+	}
+	cfg.Include = nil
+	return nil
+}
+
+// decode merges the includes of the document at the top of stack, then
+// the document itself
+func (m *merger) decode(path string, data []byte, stack []includeFile) error {
+	doc, err := toYAML(path, data)
+	if err != nil {
+		return m.located(path, err)
+	}
+	data = doc.data
+
+	var head struct {
+		Include []string `yaml:"include"`
+	}
+	if err := yaml.Unmarshal(data, &head); err != nil {
+		return m.located(path, err)
+	}
+	for _, inc := range head.Include {
+		p := inc
+		if !filepath.IsAbs(p) && path != "" {
+			p = filepath.Join(filepath.Dir(path), p)
+		}
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			return err
+		}
+		for i, f := range stack {
+			if f.abs == abs {
+				var names []string
+				for _, f := range stack[i:] {
+					names = append(names, f.name)
+				}
+				return fmt.Errorf("include cycle: %s -> %s", strings.Join(names, " -> "), p)
+			}
+		}
+		if m.seen[abs] {
+			continue
+		}
+		incData, err := os.ReadFile(p)
+		if err != nil {
+			return m.located(path, fmt.Errorf("include %s: %w", inc, err))
+		}
+		if err := m.decode(p, incData, append(stack, includeFile{name: p, abs: abs})); err != nil {
+			return err
+		}
+	}
+
+	// Lists this document sets add to those set by the files before it
+	below := make([]reflect.Value, len(additiveLists))
+	for i, list := range additiveLists {
+		v := list(m.cfg)
+		below[i] = reflect.ValueOf(v.Interface())
+		v.Set(reflect.Zero(v.Type()))
+	}
+	err = decodeYAML(data, m.cfg, m.strict)
+	for i, list := range additiveLists {
+		v := list(m.cfg)
+		switch {
+		case v.Len() == 0:
+			v.Set(below[i])
+		case m.set[i]:
+			v.Set(reflect.AppendSlice(below[i], v))
+		default:
+			m.set[i] = true
+		}
+	}
+	if err != nil {
+		return m.located(path, doc.relocate(err))
+	}
+	if len(stack) > 0 {
+		abs := stack[len(stack)-1].abs
+		m.seen[abs] = true
+		m.files = append(m.files, abs)
+	}
+	return nil
+}
+
+// Files returns the absolute paths of the config file at path and of the
+// files it includes, each include before the file including it. A file
+// that fails to load ends the list with an error, after the files that
+// loaded before it.
+func Files(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	m := &merger{cfg: DefaultConfig(), top: path, seen: make(map[string]bool), set: make([]bool, len(additiveLists))}
+	err = m.decode(path, data, []includeFile{{name: path, abs: abs}})
+	return m.files, err
+}
+
+// located names the file an error is in when it is an included one; the
+// caller already knows the document it loaded
+func (m *merger) located(path string, err error) error {
+	if path != m.top {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return err
+}
@@ -0,0 +1,109 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeConfigs writes name: YAML files under a temporary directory and
+// returns it
+func writeConfigs(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoadInclude(t *testing.T) {
+	dir := writeConfigs(t, map[string]string{
+		"base.yaml": `interface: eth0
+trial_duration: 30s
+frame_sizes: [64, 128, 256]
+throughput:
+  resolution_pct: 0.5
+  max_iterations: 12
+y1564:
+  services:
+    - service_id: 1
+      service_name: data
+`,
+		"services/voice.yaml": `include: [../base.yaml]
+y1564:
+  services:
+    - service_id: 2
+      service_name: voice
+`,
+		"site.yaml": `include: [base.yaml, services/voice.yaml]
+frame_sizes: [512]
+throughput:
+  max_iterations: 8
+y1564:
+  services:
+    - service_id: 3
+      service_name: video
+`,
+	})
+
+	cfg, err := LoadLayers(Layers{File: filepath.Join(dir, "site.yaml"), Strict: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Interface != "eth0" || cfg.TrialDuration != 30*time.Second {
+		t.Errorf("base settings: interface %q, trial %v", cfg.Interface, cfg.TrialDuration)
+	}
+	if cfg.Throughput.ResolutionPct != 0.5 || cfg.Throughput.MaxIterations != 8 {
+		t.Errorf("throughput should merge key by key: %+v", cfg.Throughput)
+	}
+	if len(cfg.FrameSizes) != 1 || cfg.FrameSizes[0] != 512 {
+		t.Errorf("frame_sizes should be replaced: %v", cfg.FrameSizes)
+	}
+	// base.yaml is included twice but merged once
+	var names []string
+	for _, s := range cfg.Y1564.Services {
+		names = append(names, s.ServiceName)
+	}
+	if got := strings.Join(names, ","); got != "data,voice,video" {
+		t.Errorf("services = %s, want data,voice,video", got)
+	}
+	if cfg.Include != nil {
+		t.Errorf("Include = %v, want cleared after merging", cfg.Include)
+	}
+}
+
+func TestLoadIncludeErrors(t *testing.T) {
+	dir := writeConfigs(t, map[string]string{
+		"a.yaml":       "include: [b.yaml]\ninterface: eth0\n",
+		"b.yaml":       "include: [a.yaml]\n",
+		"missing.yaml": "include: [nope.yaml]\ninterface: eth0\n",
+		"typo.yaml":    "include: [bad.yaml]\ninterface: eth0\n",
+		"bad.yaml":     "trial_duration: 5s\ntrail_duration: 10s\n",
+	})
+	load := func(name string) error {
+		_, err := LoadLayers(Layers{File: filepath.Join(dir, name), Strict: true})
+		return err
+	}
+
+	a, b := filepath.Join(dir, "a.yaml"), filepath.Join(dir, "b.yaml")
+	if err := load("a.yaml"); err == nil || !strings.Contains(err.Error(), "include cycle: "+a+" -> "+b+" -> "+a) {
+		t.Errorf("cycle: %v", err)
+	}
+	if err := load("missing.yaml"); err == nil || !strings.Contains(err.Error(), "include nope.yaml") {
+		t.Errorf("missing include: %v", err)
+	}
+	err := load("typo.yaml")
+	if err == nil || !strings.Contains(err.Error(), filepath.Join(dir, "bad.yaml")+": ") ||
+		!strings.Contains(err.Error(), "line 2: field trail_duration not found") {
+		t.Errorf("strict error should name the included file: %v", err)
+	}
+}
@@ -0,0 +1,259 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// InventoryConfig locates the circuit inventory --circuit looks IDs up in
+type InventoryConfig struct {
+	// A YAML or JSON file of circuits, or an http(s) URL returning one
+	// circuit as JSON; {id} in the URL is replaced by the circuit ID,
+	// which is otherwise appended as a path element
+	Source string `yaml:"source"`
+	Token  string `yaml:"token"` // Bearer token for a URL, e.g. ${secret:INVENTORY_TOKEN}
+}
+
+// IsURL reports whether the inventory is looked up over HTTP
+func (i InventoryConfig) IsURL() bool {
+	return strings.HasPrefix(i.Source, "http://") || strings.HasPrefix(i.Source, "https://")
+}
+
+// Circuit is one circuit or EVC of the inventory: where to test it from,
+// how it is framed, the SLA profile it is tested against and the test head
+// at its far end. Applied over the config file, so a flag still overrides
+// any setting it makes.
+type Circuit struct {
+	ID          string   `yaml:"id"`
+	Description string   `yaml:"description"`
+	Customer    string   `yaml:"customer"`
+	Location    string   `yaml:"location"`
+	Tags        []string `yaml:"tags"`
+
+	Interface string `yaml:"interface"`  // Test port the circuit is handed off on
+	VLAN      uint16 `yaml:"vlan"`       // C-tag (0 = untagged)
+	OuterVLAN uint16 `yaml:"outer_vlan"` // S-tag of a QinQ handoff (0 = none)
+	Profile   string `yaml:"profile"`    // SLA profile: a built-in or user profile name
+
+	FarEnd FarEnd `yaml:"far_end"`
+}
+
+// FarEnd is the test head or loopback device at a circuit's far end
+type FarEnd struct {
+	MAC       string `yaml:"mac"`       // The frames' destination MAC
+	IP        string `yaml:"ip"`        // The frames' destination IPv4 address
+	Loopback  bool   `yaml:"loopback"`  // Loop it via OAM before testing (peer: mac)
+	Reflector string `yaml:"reflector"` // host:port of a UDP reflector, for socket tests
+}
+
+// Inventory is a file of circuits
+type Inventory struct {
+	Circuits []Circuit `yaml:"circuits"`
+}
+
+// inventoryTimeout bounds a lookup over HTTP
+const inventoryTimeout = 15 * time.Second
+
+// LoadInventory reads a YAML or JSON file of circuits
+func LoadInventory(path string) (*Inventory, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read inventory: %w", err)
+	}
+	var inv Inventory
+	if err := yaml.Unmarshal(data, &inv); err != nil {
+		return nil, fmt.Errorf("parse inventory %s: %w", path, err)
+	}
+	seen := make(map[string]bool)
+	for i, c := range inv.Circuits {
+		if err := c.Validate(); err != nil {
+			return nil, fmt.Errorf("inventory %s circuit %d: %w", path, i+1, err)
+		}
+		id := strings.ToUpper(c.ID)
+		if seen[id] {
+			return nil, fmt.Errorf("inventory %s: circuit %s listed twice", path, c.ID)
+		}
+		seen[id] = true
+	}
+	return &inv, nil
+}
+
+// Get returns the circuit with an ID, ignoring case, or nil
+func (inv *Inventory) Get(id string) *Circuit {
+	for i := range inv.Circuits {
+		if strings.EqualFold(inv.Circuits[i].ID, id) {
+			return &inv.Circuits[i]
+		}
+	}
+	return nil
+}
+
+// LookupCircuit finds a circuit in the inventory, reading the file or
+// asking the URL
+func (i InventoryConfig) LookupCircuit(ctx context.Context, id string) (*Circuit, error) {
+	if i.Source == "" {
+		return nil, fmt.Errorf("circuit %s: no inventory (set --inventory or inventory.source)", id)
+	}
+	if !i.IsURL() {
+		inv, err := LoadInventory(i.Source)
+		if err != nil {
+			return nil, err
+		}
+		c := inv.Get(id)
+		if c == nil {
+			return nil, fmt.Errorf("circuit %s not in inventory %s", id, i.Source)
+		}
+		return c, nil
+	}
+
+	u := i.Source
+	if strings.Contains(u, "{id}") {
+		u = strings.ReplaceAll(u, "{id}", url.PathEscape(id))
+	} else {
+		u = strings.TrimRight(u, "/") + "/" + url.PathEscape(id)
+	}
+	ctx, cancel := context.WithTimeout(ctx, inventoryTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("inventory: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if i.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+i.Token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("inventory: %w", err)
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("circuit %s not in inventory %s", id, i.Source)
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return nil, fmt.Errorf("inventory: GET %s: %s", u, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("inventory: %w", err)
+	}
+	// JSON is YAML, so the circuit decodes with the file's field names
+	var c Circuit
+	if err := yaml.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("inventory: circuit %s: %w", id, err)
+	}
+	if c.ID == "" {
+		c.ID = id
+	}
+	if err := c.Validate(); err != nil {
+		return nil, fmt.Errorf("inventory: circuit %s: %w", id, err)
+	}
+	return &c, nil
+}
+
+// Validate checks the circuit's fields
+func (c *Circuit) Validate() error {
+	if c.ID == "" {
+		return fmt.Errorf("circuit id is required")
+	}
+	if c.VLAN > 4094 || c.OuterVLAN > 4094 {
+		return fmt.Errorf("circuit %s: vlan must be 0-4094", c.ID)
+	}
+	if c.FarEnd.MAC != "" {
+		if _, err := net.ParseMAC(c.FarEnd.MAC); err != nil {
+			return fmt.Errorf("circuit %s: invalid far_end mac: %s", c.ID, c.FarEnd.MAC)
+		}
+	}
+	if c.FarEnd.IP != "" && net.ParseIP(c.FarEnd.IP) == nil {
+		return fmt.Errorf("circuit %s: invalid far_end ip: %s", c.ID, c.FarEnd.IP)
+	}
+	if c.FarEnd.Loopback && c.FarEnd.MAC == "" {
+		return fmt.Errorf("circuit %s: far_end loopback needs its mac", c.ID)
+	}
+	if c.FarEnd.Reflector != "" {
+		if _, _, err := net.SplitHostPort(c.FarEnd.Reflector); err != nil {
+			return fmt.Errorf("circuit %s: invalid far_end reflector: %w", c.ID, err)
+		}
+	}
+	return nil
+}
+
+// Apply sets the circuit's settings in cfg. Its profile is not applied
+// here: it is a layer under the config file, see LoadLayers.
+func (c *Circuit) Apply(cfg *Config) {
+	if c.Interface != "" {
+		cfg.Interface = c.Interface
+	}
+	if c.VLAN != 0 {
+		cfg.Framing.VLANID = c.VLAN
+	}
+	if c.OuterVLAN != 0 {
+		cfg.Framing.OuterVLANID = c.OuterVLAN
+	}
+	if c.FarEnd.MAC != "" {
+		cfg.Headers.DstMAC = c.FarEnd.MAC
+	}
+	if c.FarEnd.IP != "" {
+		cfg.Headers.DstIP = c.FarEnd.IP
+	}
+	if c.FarEnd.Loopback {
+		cfg.OAM.RemoteLoopback = true
+		cfg.OAM.PeerMAC = c.FarEnd.MAC
+	}
+	if c.FarEnd.Reflector != "" {
+		cfg.Socket.Target = c.FarEnd.Reflector
+	}
+
+	cfg.Certificate.CircuitID = c.ID
+	if c.Customer != "" {
+		cfg.Certificate.Customer = c.Customer
+	}
+	if c.Location != "" {
+		cfg.Certificate.Location = c.Location
+	}
+	if len(c.Tags) > 0 {
+		cfg.Certificate.Tags = c.Tags
+	}
+}
+
+// String summarizes where and how the circuit is tested
+func (c *Circuit) String() string {
+	s := c.ID
+	if c.Customer != "" {
+		s += " (" + c.Customer + ")"
+	}
+	var parts []string
+	if c.Interface != "" {
+		parts = append(parts, c.Interface)
+	}
+	if c.OuterVLAN != 0 {
+		parts = append(parts, fmt.Sprintf("S-VLAN %d", c.OuterVLAN))
+	}
+	if c.VLAN != 0 {
+		parts = append(parts, fmt.Sprintf("VLAN %d", c.VLAN))
+	}
+	if c.Profile != "" {
+		parts = append(parts, "profile "+c.Profile)
+	}
+	switch {
+	case c.FarEnd.Loopback:
+		parts = append(parts, "looped at "+c.FarEnd.MAC)
+	case c.FarEnd.Reflector != "":
+		parts = append(parts, "reflector "+c.FarEnd.Reflector)
+	case c.FarEnd.MAC != "":
+		parts = append(parts, "far end "+c.FarEnd.MAC)
+	}
+	if len(parts) > 0 {
+		s += ": " + strings.Join(parts, ", ")
+	}
+	return s
+}
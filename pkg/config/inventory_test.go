@@ -0,0 +1,121 @@
+package config
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testInventory = `circuits:
+  - id: CKT-1234
+    customer: Acme
+    tags: [gold]
+    interface: eth1
+    vlan: 120
+    profile: carrier-ethernet
+    far_end:
+      mac: 00:11:22:33:44:55
+      ip: 192.0.2.10
+      loopback: true
+  - id: CKT-5678
+    interface: eth2
+    far_end:
+      reflector: 192.0.2.20:7
+`
+
+func TestInventoryFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "inventory.yaml")
+	if err := os.WriteFile(path, []byte(testInventory), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	inv := InventoryConfig{Source: path}
+	c, err := inv.LookupCircuit(context.Background(), "ckt-1234")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.ID != "CKT-1234" || c.Profile != "carrier-ethernet" {
+		t.Fatalf("circuit = %+v", c)
+	}
+
+	cfg := DefaultConfig()
+	cfg.Certificate.Technician = "jo"
+	c.Apply(cfg)
+	if cfg.Interface != "eth1" || cfg.Framing.VLANID != 120 {
+		t.Errorf("interface %s vlan %d", cfg.Interface, cfg.Framing.VLANID)
+	}
+	if cfg.Headers.DstMAC != "00:11:22:33:44:55" || cfg.Headers.DstIP != "192.0.2.10" {
+		t.Errorf("headers = %+v", cfg.Headers)
+	}
+	if !cfg.OAM.RemoteLoopback || cfg.OAM.PeerMAC != "00:11:22:33:44:55" {
+		t.Errorf("oam = %+v", cfg.OAM)
+	}
+	if cfg.Certificate.CircuitID != "CKT-1234" || cfg.Certificate.Customer != "Acme" || cfg.Certificate.Technician != "jo" {
+		t.Errorf("certificate = %+v", cfg.Certificate)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("validate: %v", err)
+	}
+
+	c, _ = inv.LookupCircuit(context.Background(), "CKT-5678")
+	cfg = DefaultConfig()
+	c.Apply(cfg)
+	if cfg.Socket.Target != "192.0.2.20:7" || cfg.Framing.VLANID != 0 {
+		t.Errorf("socket %s vlan %d", cfg.Socket.Target, cfg.Framing.VLANID)
+	}
+
+	if _, err := inv.LookupCircuit(context.Background(), "CKT-9"); err == nil || !strings.Contains(err.Error(), "not in inventory") {
+		t.Errorf("unknown circuit: %v", err)
+	}
+	if _, err := (InventoryConfig{}).LookupCircuit(context.Background(), "CKT-1234"); err == nil {
+		t.Error("lookup without an inventory succeeded")
+	}
+
+	for _, bad := range []string{
+		"circuits:\n  - id: A\n  - id: a\n",
+		"circuits:\n  - interface: eth0\n",
+		"circuits:\n  - id: A\n    vlan: 4095\n",
+		"circuits:\n  - id: A\n    far_end: {loopback: true}\n",
+		"circuits:\n  - id: A\n    far_end: {mac: nope}\n",
+	} {
+		os.WriteFile(path, []byte(bad), 0o644)
+		if _, err := LoadInventory(path); err == nil {
+			t.Errorf("inventory %q loaded", bad)
+		}
+	}
+}
+
+func TestInventoryURL(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tok" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/circuits/CKT-1234" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"customer": "Acme", "interface": "eth1", "vlan": 120, "far_end": {"mac": "00:11:22:33:44:55"}}`))
+	}))
+	defer srv.Close()
+
+	for _, source := range []string{srv.URL + "/circuits/{id}", srv.URL + "/circuits/"} {
+		inv := InventoryConfig{Source: source, Token: "tok"}
+		c, err := inv.LookupCircuit(context.Background(), "CKT-1234")
+		if err != nil {
+			t.Fatalf("%s: %v", source, err)
+		}
+		if c.ID != "CKT-1234" || c.Interface != "eth1" || c.VLAN != 120 || c.FarEnd.MAC != "00:11:22:33:44:55" {
+			t.Errorf("%s: circuit = %+v", source, c)
+		}
+		if _, err := inv.LookupCircuit(context.Background(), "CKT-9"); err == nil || !strings.Contains(err.Error(), "not in inventory") {
+			t.Errorf("%s: unknown circuit: %v", source, err)
+		}
+	}
+	if _, err := (InventoryConfig{Source: srv.URL + "/circuits/{id}"}).LookupCircuit(context.Background(), "CKT-1234"); err == nil {
+		t.Error("lookup without the token succeeded")
+	}
+}
@@ -0,0 +1,103 @@
+package config
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PlanConfig is an ordered list of tests run one after another in a
+// single invocation, e.g. throughput at 64 and 1518 bytes, then a 2h
+// Y.1564 performance test, then frame loss. Each step runs over the rest
+// of the configuration.
+type PlanConfig struct {
+	Steps             []PlanStep `yaml:"steps"`
+	ContinueOnFailure bool       `yaml:"continue_on_failure"` // Run the remaining steps after one fails
+}
+
+// Enabled reports whether a plan is set
+func (p PlanConfig) Enabled() bool {
+	return len(p.Steps) > 0
+}
+
+// PlanStep is one test of a plan. Set holds the step's other settings as
+// config keys, e.g. {y1564: {perf_duration: 2h}}; unknown keys are
+// rejected.
+type PlanStep struct {
+	Name       string    `yaml:"name"` // Default: the test type
+	TestType   TestType  `yaml:"test_type"`
+	FrameSizes []uint32  `yaml:"frame_sizes"` // Default: the configured sizes
+	Set        yaml.Node `yaml:"set,omitempty"`
+}
+
+// File is a config file holding just the plan, run over the files it
+// includes
+func (p PlanConfig) File(include []string) ([]byte, error) {
+	return yaml.Marshal(&struct {
+		Include []string   `yaml:"include,omitempty"`
+		Plan    PlanConfig `yaml:"plan"`
+	}{include, p})
+}
+
+// DisplayName is the step's name, or its test type
+func (s PlanStep) DisplayName() string {
+	if s.Name == "" {
+		return string(s.TestType)
+	}
+	return s.Name
+}
+
+// Label names step i in output, e.g. "2 y1564_perf"
+func (s PlanStep) Label(i int) string {
+	return fmt.Sprintf("%d %s", i+1, s.DisplayName())
+}
+
+// Step returns the configuration plan step i runs with: a copy of this
+// configuration, without the plan, with the step's settings applied
+func (c *Config) Step(i int) (*Config, error) {
+	s := c.Plan.Steps[i]
+	if s.TestType == "" {
+		return nil, fmt.Errorf("plan step %s: test_type is required", s.Label(i))
+	}
+
+	// A round trip through YAML leaves the plan's maps and lists unshared
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return nil, fmt.Errorf("plan step %s: %w", s.Label(i), err)
+	}
+	step := &Config{}
+	if err := yaml.Unmarshal(data, step); err != nil {
+		return nil, fmt.Errorf("plan step %s: %w", s.Label(i), err)
+	}
+
+	if !s.Set.IsZero() {
+		set, err := yaml.Marshal(&s.Set)
+		if err != nil {
+			return nil, fmt.Errorf("plan step %s: %w", s.Label(i), err)
+		}
+		if err := decodeYAML(set, step, true); err != nil {
+			return nil, fmt.Errorf("plan step %s set: %w", s.Label(i), err)
+		}
+	}
+	step.Plan = PlanConfig{}
+	step.TestType = s.TestType
+	if len(s.FrameSizes) > 0 {
+		step.FrameSize = 0
+		step.FrameSizes = s.FrameSizes
+	}
+	return step, nil
+}
+
+// validatePlan checks every step's configuration
+func (c *Config) validatePlan() error {
+	for i, s := range c.Plan.Steps {
+		step, err := c.Step(i)
+		if err != nil {
+			return err
+		}
+		if err := step.Validate(); err != nil {
+			return fmt.Errorf("plan step %s: %w", s.Label(i), err)
+		}
+	}
+	return nil
+}
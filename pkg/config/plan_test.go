@@ -0,0 +1,91 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPlanSteps(t *testing.T) {
+	dir := writeConfigs(t, map[string]string{
+		"plan.yaml": `interface: eth0
+frame_sizes: [64, 128, 256]
+y1564:
+  perf_duration: 15m
+  services:
+    - service_id: 1
+      enabled: true
+      sla:
+        cir_mbps: 100
+plan:
+  continue_on_failure: true
+  steps:
+    - test_type: throughput
+      frame_sizes: [64, 1518]
+    - name: soak-2h
+      test_type: y1564_perf
+      set:
+        y1564:
+          perf_duration: 2h
+    - test_type: frame_loss
+`,
+	})
+	cfg, err := LoadLayers(Layers{File: filepath.Join(dir, "plan.yaml"), Strict: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.Plan.Enabled() || !cfg.Plan.ContinueOnFailure || len(cfg.Plan.Steps) != 3 {
+		t.Fatalf("plan = %+v", cfg.Plan)
+	}
+
+	first, err := cfg.Step(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.TestType != TestThroughput || len(first.FrameSizes) != 2 || first.FrameSizes[1] != 1518 {
+		t.Errorf("step 1: %s at %v", first.TestType, first.FrameSizes)
+	}
+	if first.Plan.Enabled() {
+		t.Error("a step's configuration should not carry the plan")
+	}
+
+	second, err := cfg.Step(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Y1564.PerfDuration != 2*time.Hour || len(second.FrameSizes) != 3 {
+		t.Errorf("step 2: perf %v at %v", second.Y1564.PerfDuration, second.FrameSizes)
+	}
+	if cfg.Y1564.PerfDuration != 15*time.Minute {
+		t.Errorf("a step's settings changed the base perf_duration to %v", cfg.Y1564.PerfDuration)
+	}
+	if got := cfg.Plan.Steps[1].Label(1); got != "2 soak-2h" {
+		t.Errorf("Label = %q", got)
+	}
+	if got := cfg.Plan.Steps[2].Label(2); got != "3 frame_loss" {
+		t.Errorf("Label = %q", got)
+	}
+}
+
+func TestValidatePlan(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Interface = "eth0"
+	cfg.Plan.Steps = []PlanStep{{TestType: TestThroughput}, {Name: "bad"}}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "plan step 2 bad: test_type is required") {
+		t.Errorf("missing test type: %v", err)
+	}
+
+	cfg.Plan.Steps[1] = PlanStep{TestType: "nope"}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "plan step 2 nope") {
+		t.Errorf("unknown test type: %v", err)
+	}
+
+	cfg.Plan.Steps[1] = PlanStep{TestType: TestLatency}
+	if err := cfg.Plan.Steps[1].Set.Encode(map[string]interface{}{"trail_duration": "5s"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "trail_duration") {
+		t.Errorf("unknown key in set: %v", err)
+	}
+}
@@ -0,0 +1,419 @@
+package config
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Test matrix columns. A plan spreadsheet has a header row naming them;
+// column names are matched case-insensitively, ignoring punctuation, and a
+// unit in parentheses, e.g. "Max Latency (ms)", scales the column's values.
+const (
+	planColTest              = "test"
+	planColName              = "name"
+	planColFrameSizes        = "frame_sizes"
+	planColDuration          = "duration"
+	planColAcceptableLoss    = "acceptable_loss"
+	planColMinThroughputPct  = "min_throughput_pct"
+	planColMinThroughputMbps = "min_throughput_mbps"
+	planColMaxLatencyAvg     = "max_latency_avg_us"
+	planColMaxLatencyP99     = "max_latency_p99_us"
+	planColMaxFrameLoss      = "max_frame_loss_pct"
+	planColMustPass          = "y1564_must_pass"
+	planColRun               = "run"
+)
+
+// planColumns maps the accepted column names to their column
+var planColumns = map[string]string{
+	"test": planColTest, "test_type": planColTest, "type": planColTest, "test_case": planColTest,
+	"name": planColName, "step": planColName, "step_name": planColName, "label": planColName, "id": planColName, "test_id": planColName,
+	"frame_size": planColFrameSizes, "frame_sizes": planColFrameSizes, "frame": planColFrameSizes, "frames": planColFrameSizes,
+	"size": planColFrameSizes, "sizes": planColFrameSizes, "frame_size_bytes": planColFrameSizes, "frame_sizes_bytes": planColFrameSizes,
+	"duration": planColDuration, "trial_duration": planColDuration, "test_duration": planColDuration, "time": planColDuration,
+	"acceptable_loss": planColAcceptableLoss, "acceptable_loss_pct": planColAcceptableLoss,
+	"loss_tolerance": planColAcceptableLoss, "loss_tolerance_pct": planColAcceptableLoss,
+	"min_throughput": planColMinThroughputPct, "min_throughput_pct": planColMinThroughputPct, "min_rate_pct": planColMinThroughputPct,
+	"min_throughput_mbps": planColMinThroughputMbps, "min_rate_mbps": planColMinThroughputMbps,
+	"max_latency": planColMaxLatencyAvg, "max_latency_us": planColMaxLatencyAvg, "max_latency_avg": planColMaxLatencyAvg,
+	"max_latency_avg_us": planColMaxLatencyAvg, "max_avg_latency": planColMaxLatencyAvg, "max_avg_latency_us": planColMaxLatencyAvg,
+	"max_latency_p99": planColMaxLatencyP99, "max_latency_p99_us": planColMaxLatencyP99,
+	"max_p99_latency": planColMaxLatencyP99, "max_p99_latency_us": planColMaxLatencyP99,
+	"max_frame_loss": planColMaxFrameLoss, "max_frame_loss_pct": planColMaxFrameLoss, "max_loss": planColMaxFrameLoss, "max_loss_pct": planColMaxFrameLoss,
+	"y1564_must_pass": planColMustPass, "must_pass": planColMustPass, "sla_must_pass": planColMustPass,
+	"run": planColRun, "enabled": planColRun, "include": planColRun,
+}
+
+// latencyUnits scales a latency column's values to microseconds
+var latencyUnits = map[string]float64{"": 1, "us": 1, "ns": 1e-3, "ms": 1e3, "s": 1e6}
+
+// durationUnits are the units of a duration column's plain numbers
+var durationUnits = map[string]time.Duration{
+	"": time.Second, "s": time.Second, "sec": time.Second, "secs": time.Second, "seconds": time.Second,
+	"ms": time.Millisecond, "m": time.Minute, "min": time.Minute, "mins": time.Minute, "minutes": time.Minute,
+	"h": time.Hour, "hr": time.Hour, "hrs": time.Hour, "hours": time.Hour,
+}
+
+// planColumn is one recognised column of a test matrix
+type planColumn struct {
+	index int
+	key   string
+	unit  string
+}
+
+// ParsePlanCSV converts a CSV test matrix, one test per row, into a plan.
+// The test column is required; the others are optional and an empty cell
+// keeps the configured value. Durations go to the key the row's test type
+// runs for (y1564.perf_duration for y1564_perf, trial_duration for the RFC
+// 2544 tests) and thresholds to the step's thresholds. Comma, semicolon
+// and tab separated files are accepted. The names of columns that were
+// not recognised are returned, so notes columns can stay in the sheet.
+func ParsePlanCSV(r io.Reader) (PlanConfig, []string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return PlanConfig{}, nil, err
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")) // Spreadsheet exports often start with a BOM
+
+	cr := csv.NewReader(bytes.NewReader(data))
+	cr.Comma = planDelimiter(data)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return PlanConfig{}, nil, errors.New("test matrix is empty")
+	}
+	if err != nil {
+		return PlanConfig{}, nil, err
+	}
+	var cols []planColumn
+	var ignored []string
+	seen := make(map[string]bool)
+	for i, h := range header {
+		col, ok := planHeader(h)
+		if !ok {
+			if strings.TrimSpace(h) != "" {
+				ignored = append(ignored, strings.TrimSpace(h))
+			}
+			continue
+		}
+		if seen[col.key] {
+			return PlanConfig{}, nil, fmt.Errorf("column %q: %s given twice", h, col.key)
+		}
+		seen[col.key] = true
+		col.index = i
+		cols = append(cols, col)
+	}
+	if !seen[planColTest] {
+		return PlanConfig{}, nil, errors.New("test matrix has no test type column")
+	}
+
+	var plan PlanConfig
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return PlanConfig{}, nil, err
+		}
+		line, _ := cr.FieldPos(0)
+		step, ok, err := planRow(record, cols)
+		if err != nil {
+			return PlanConfig{}, nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if ok {
+			plan.Steps = append(plan.Steps, step)
+		}
+	}
+	if !plan.Enabled() {
+		return PlanConfig{}, nil, errors.New("test matrix has no tests")
+	}
+	return plan, ignored, nil
+}
+
+// planDelimiter picks the separator that splits the header row most
+func planDelimiter(data []byte) rune {
+	first, _, _ := bytes.Cut(data, []byte("\n"))
+	best, count := ',', bytes.Count(first, []byte(","))
+	for _, d := range []rune{';', '\t'} {
+		if n := bytes.Count(first, []byte(string(d))); n > count {
+			best, count = d, n
+		}
+	}
+	return best
+}
+
+// planHeader recognises a column name, e.g. "Max Latency (ms)"
+func planHeader(h string) (planColumn, bool) {
+	name, unit := strings.TrimSpace(h), ""
+	if open := strings.LastIndexByte(name, '('); open >= 0 && strings.HasSuffix(name, ")") {
+		name, unit = name[:open], strings.ToLower(strings.TrimSpace(name[open+1:len(name)-1]))
+		if unit == "%" {
+			unit = "pct"
+		}
+	}
+	name = planName(name)
+	if unit != "" {
+		if key, ok := planColumns[name+"_"+unit]; ok {
+			return planColumn{key: key}, true
+		}
+	}
+	key, ok := planColumns[name]
+	return planColumn{key: key, unit: unit}, ok
+}
+
+// planName lowers s and joins its words with underscores
+func planName(s string) string {
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9')
+	})
+	return strings.Join(words, "_")
+}
+
+// planRow converts one row. Rows without a test type, and rows whose run
+// column says no, are skipped.
+func planRow(record []string, cols []planColumn) (PlanStep, bool, error) {
+	cell := func(key string) (string, planColumn) {
+		for _, c := range cols {
+			if c.key == key && c.index < len(record) {
+				return strings.TrimSpace(record[c.index]), c
+			}
+		}
+		return "", planColumn{}
+	}
+
+	test, _ := cell(planColTest)
+	if test == "" {
+		return PlanStep{}, false, nil
+	}
+	if run, _ := cell(planColRun); run != "" {
+		on, err := parsePlanBool(run)
+		if err != nil {
+			return PlanStep{}, false, fmt.Errorf("run: %w", err)
+		}
+		if !on {
+			return PlanStep{}, false, nil
+		}
+	}
+
+	var step PlanStep
+	var err error
+	if step.TestType, err = parsePlanTestType(test); err != nil {
+		return PlanStep{}, false, err
+	}
+	step.Name, _ = cell(planColName)
+	if sizes, _ := cell(planColFrameSizes); sizes != "" {
+		if step.FrameSizes, err = parsePlanSizes(sizes); err != nil {
+			return PlanStep{}, false, fmt.Errorf("frame sizes: %w", err)
+		}
+	}
+
+	set := make(map[string]any)
+	thresholds := make(map[string]any)
+	if v, col := cell(planColDuration); v != "" {
+		d, err := parsePlanDuration(v, col.unit)
+		if err != nil {
+			return PlanStep{}, false, fmt.Errorf("duration: %w", err)
+		}
+		section, key := planDurationKey(step.TestType)
+		if key == "" {
+			return PlanStep{}, false, fmt.Errorf("duration: not supported for %s", step.TestType)
+		}
+		setPlanKey(set, section, key, d.String())
+	}
+	if v, _ := cell(planColAcceptableLoss); v != "" {
+		pct, err := parsePlanNumber(v)
+		if err != nil {
+			return PlanStep{}, false, fmt.Errorf("acceptable loss: %w", err)
+		}
+		if isRFC2889(step.TestType) {
+			setPlanKey(set, "rfc2889", "acceptable_loss_pct", pct)
+		} else {
+			setPlanKey(set, "throughput", "acceptable_loss", pct)
+		}
+	}
+	for _, key := range []string{planColMinThroughputPct, planColMaxFrameLoss} {
+		if v, _ := cell(key); v != "" {
+			n, err := parsePlanNumber(v)
+			if err != nil {
+				return PlanStep{}, false, fmt.Errorf("%s: %w", key, err)
+			}
+			thresholds[key] = n
+		}
+	}
+	for _, key := range []string{planColMaxLatencyAvg, planColMaxLatencyP99} {
+		if v, col := cell(key); v != "" {
+			n, err := parsePlanNumber(v)
+			if err != nil {
+				return PlanStep{}, false, fmt.Errorf("%s: %w", key, err)
+			}
+			scale, ok := latencyUnits[col.unit]
+			if !ok {
+				return PlanStep{}, false, fmt.Errorf("%s: unknown unit %q", key, col.unit)
+			}
+			thresholds[key] = n * scale
+		}
+	}
+	if v, _ := cell(planColMinThroughputMbps); v != "" {
+		n, err := parsePlanNumber(v)
+		if err != nil {
+			return PlanStep{}, false, fmt.Errorf("%s: %w", planColMinThroughputMbps, err)
+		}
+		if len(step.FrameSizes) == 0 {
+			return PlanStep{}, false, fmt.Errorf("%s: needs the row's frame sizes", planColMinThroughputMbps)
+		}
+		bySize := make(map[uint32]float64)
+		for _, size := range step.FrameSizes {
+			bySize[size] = n
+		}
+		thresholds[planColMinThroughputMbps] = bySize
+	}
+	if v, _ := cell(planColMustPass); v != "" {
+		on, err := parsePlanBool(v)
+		if err != nil {
+			return PlanStep{}, false, fmt.Errorf("%s: %w", planColMustPass, err)
+		}
+		thresholds[planColMustPass] = on
+	}
+	if len(thresholds) > 0 {
+		set["thresholds"] = thresholds
+	}
+
+	if len(set) > 0 {
+		if err := step.Set.Encode(set); err != nil {
+			return PlanStep{}, false, err
+		}
+	}
+	return step, true, nil
+}
+
+// setPlanKey sets key in set, or in its section when section is not ""
+func setPlanKey(set map[string]any, section, key string, value any) {
+	if section == "" {
+		set[key] = value
+		return
+	}
+	m, ok := set[section].(map[string]any)
+	if !ok {
+		m = make(map[string]any)
+		set[section] = m
+	}
+	m[key] = value
+}
+
+// planDurationKey is the config key a test type's duration column sets,
+// or "" when the test has no duration
+func planDurationKey(t TestType) (section, key string) {
+	switch t {
+	case TestSoak:
+		return "soak", "duration"
+	case TestY1564Config:
+		return "y1564", "step_duration"
+	case TestY1564Perf, TestY1564Full:
+		return "y1564", "perf_duration"
+	case TestRFC6349Throughput, TestRFC6349Path:
+		return "rfc6349", "test_duration"
+	case TestMEFConfig:
+		return "mef", "config_duration"
+	case TestMEFPerf, TestMEFFull:
+		return "mef", "perf_duration"
+	case TestTSNTiming, TestTSNIsolation, TestTSNLatency, TestTSNFull:
+		return "tsn", "test_duration"
+	case TestY1731Delay, TestY1731Loss, TestY1731SLM, TestY1731Loopback:
+		return "", ""
+	}
+	if isRFC2889(t) {
+		return "rfc2889", "trial_duration"
+	}
+	return "", "trial_duration"
+}
+
+// isRFC2889 reports whether t is an RFC 2889 test
+func isRFC2889(t TestType) bool {
+	return strings.HasPrefix(string(t), "rfc2889_")
+}
+
+// parsePlanTestType accepts a test type as named in the config, or as a
+// spreadsheet would write it, e.g. "Back-to-Back" or "Y.1564 Perf"
+func parsePlanTestType(s string) (TestType, error) {
+	squash := func(s string) string { return strings.ReplaceAll(planName(s), "_", "") }
+	want := strings.TrimPrefix(squash(s), "rfc2544")
+	switch want {
+	case "b2b":
+		return TestBackToBack, nil
+	case "y1564full":
+		return TestY1564Full, nil
+	}
+	for _, t := range TestTypes() {
+		if squash(string(t)) == want {
+			return t, nil
+		}
+	}
+	return "", fmt.Errorf("unknown test type %q", s)
+}
+
+// parsePlanSizes reads a list of frame sizes, e.g. "64, 512, 1518" or
+// "64/1518"
+func parsePlanSizes(s string) ([]uint32, error) {
+	var sizes []uint32
+	for _, f := range strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ';' || r == '/' || r == '|' || r == ' '
+	}) {
+		n, err := strconv.ParseUint(f, 10, 32)
+		if err != nil || n == 0 {
+			return nil, fmt.Errorf("invalid frame size %q", f)
+		}
+		sizes = append(sizes, uint32(n))
+	}
+	return sizes, nil
+}
+
+// parsePlanDuration reads "90s", "2h", "15 min", or a number in the
+// column's unit
+func parsePlanDuration(s, unit string) (time.Duration, error) {
+	scale, ok := durationUnits[unit]
+	if !ok {
+		return 0, fmt.Errorf("unknown unit %q", unit)
+	}
+	if n, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Duration(n * float64(scale)), nil
+	}
+	compact := strings.ToLower(strings.ReplaceAll(s, " ", ""))
+	if d, err := time.ParseDuration(compact); err == nil {
+		return d, nil
+	}
+	number := strings.TrimRightFunc(compact, func(r rune) bool { return r >= 'a' && r <= 'z' })
+	if scale, ok := durationUnits[compact[len(number):]]; ok && number != "" {
+		if n, err := strconv.ParseFloat(number, 64); err == nil {
+			return time.Duration(n * float64(scale)), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid duration %q", s)
+}
+
+// parsePlanNumber reads a number, allowing a trailing %
+func parsePlanNumber(s string) (float64, error) {
+	n, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(s, "%")), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", s)
+	}
+	return n, nil
+}
+
+// parsePlanBool reads the yes/no spellings spreadsheets use
+func parsePlanBool(s string) (bool, error) {
+	switch strings.ToLower(s) {
+	case "y", "yes", "true", "1", "x", "on":
+		return true, nil
+	case "n", "no", "false", "0", "off", "-":
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid yes/no %q", s)
+}
@@ -0,0 +1,99 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParsePlanCSV(t *testing.T) {
+	matrix := "\xef\xbb\xbfTest ID;Test Type;Frame Sizes;Duration (min);Min Throughput (%);Max Latency (ms);Max Frame Loss (%);Run;Notes\n" +
+		"TC-01;Throughput;64, 1518;1;95%;;;yes;baseline\n" +
+		"TC-02;Latency;512;;;0.25;;yes;\n" +
+		";;;;;;;;section break\n" +
+		"TC-03;Back-to-Back;;;;;;no;skipped\n" +
+		"TC-04;Y.1564 Perf;;120;;;0.1;x;\n"
+
+	plan, ignored, err := ParsePlanCSV(strings.NewReader(matrix))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ignored) != 1 || ignored[0] != "Notes" {
+		t.Errorf("ignored columns %v", ignored)
+	}
+	if len(plan.Steps) != 3 {
+		t.Fatalf("%d steps", len(plan.Steps))
+	}
+
+	cfg := DefaultConfig()
+	cfg.Interface = "eth0"
+	cfg.Y1564.Services = []Y1564Service{{ServiceID: 1, Enabled: true, SLA: Y1564SLA{CIRMbps: 100}}}
+	cfg.Plan = plan
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := cfg.Step(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.TestType != TestThroughput || first.Plan.Steps != nil || len(first.FrameSizes) != 2 {
+		t.Errorf("step 1: %s at %v", first.TestType, first.FrameSizes)
+	}
+	if first.TrialDuration != time.Minute || first.Thresholds.MinThroughputPct != 95 {
+		t.Errorf("step 1: trial %v, min %v%%", first.TrialDuration, first.Thresholds.MinThroughputPct)
+	}
+
+	second, err := cfg.Step(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Thresholds.MaxLatencyAvgUs != 250 || second.TrialDuration != cfg.TrialDuration {
+		t.Errorf("step 2: max latency %vus, trial %v", second.Thresholds.MaxLatencyAvgUs, second.TrialDuration)
+	}
+
+	third, err := cfg.Step(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Steps[2].Name != "TC-04" || third.TestType != TestY1564Perf ||
+		third.Y1564.PerfDuration != 2*time.Hour || third.Thresholds.MaxFrameLossPct != 0.1 {
+		t.Errorf("step 3 %q: %s for %v, max loss %v", plan.Steps[2].Name, third.TestType,
+			third.Y1564.PerfDuration, third.Thresholds.MaxFrameLossPct)
+	}
+
+	for _, bad := range []string{
+		"",
+		"name,frame size\nx,64\n",
+		"test\n",
+		"test\nthroughput-ish\n",
+		"test,frame size\nthroughput,64k\n",
+		"test,duration\nthroughput,soon\n",
+		"test,duration\ny1731_delay,10s\n",
+		"test,min throughput (mbps)\nthroughput,900\n",
+		"test,type\nthroughput,latency\n",
+	} {
+		if _, _, err := ParsePlanCSV(strings.NewReader(bad)); err == nil {
+			t.Errorf("expected an error for %q", bad)
+		}
+	}
+}
+
+func TestParsePlanDuration(t *testing.T) {
+	cases := []struct {
+		in   string
+		unit string
+		want time.Duration
+	}{
+		{"90", "", 90 * time.Second},
+		{"1.5", "h", 90 * time.Minute},
+		{"2h", "min", 2 * time.Hour},
+		{"15 min", "", 15 * time.Minute},
+		{"500ms", "", 500 * time.Millisecond},
+	}
+	for _, c := range cases {
+		if got, err := parsePlanDuration(c.in, c.unit); err != nil || got != c.want {
+			t.Errorf("%q (%s): %v, %v", c.in, c.unit, got, err)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Preset is a named partial configuration. It is applied over the defaults
+// and under the config file, so a file or flag still overrides any setting
+// it makes. The first line of its YAML, if a comment, describes it.
+type Preset struct {
+	Name        string
+	Description string
+	Path        string // Empty for a built-in preset
+	YAML        string
+}
+
+// Builtin reports whether the preset ships with the tool
+func (p *Preset) Builtin() bool {
+	return p.Path == ""
+}
+
+// Apply merges the preset's settings into cfg
+func (p *Preset) Apply(cfg *Config) error {
+	if err := yaml.Unmarshal([]byte(p.YAML), cfg); err != nil {
+		return fmt.Errorf("profile %s: %w", p.Name, err)
+	}
+	return nil
+}
+
+// builtinPresets ship with the tool
+var builtinPresets = []string{
+	`# Short smoke test: three frame sizes, 10s trials, coarse search
+trial_duration: 10s
+warmup_period: 1s
+frame_sizes: [64, 512, 1518]
+throughput:
+  resolution_pct: 1.0
+  max_iterations: 10
+latency:
+  samples: 100
+  load_levels: [50, 100]
+frame_loss:
+  step_pct: 20.0
+back_to_back:
+  trials: 5
+`,
+	`# Full RFC 2544 suite with the methodology's recommended settings
+test_type: suite
+frame_size: 0
+trial_duration: 60s
+warmup_period: 2s
+addressing:
+  pairs: 256
+throughput:
+  resolution_pct: 0.1
+  acceptable_loss: 0.0
+frame_loss:
+  start_pct: 100.0
+  step_pct: 10.0
+back_to_back:
+  trials: 50
+`,
+	`# Y.1564 activation of a 100 Mbps EVPL, failing on any SLA breach
+test_type: y1564
+y1564:
+  services:
+    - service_id: 1
+      service_name: EVPL
+      frame_size: 512
+      enabled: true
+      sla:
+        cir_mbps: 100
+        fd_threshold_ms: 10
+        fdv_threshold_ms: 5
+        flr_threshold_pct: 0.01
+  config_steps: [25, 50, 75, 100]
+  step_duration: 60s
+  perf_duration: 15m
+  run_config_test: true
+  run_perf_test: true
+thresholds:
+  y1564_must_pass: true
+`,
+	`# 25G data centre leaf: jumbo frames, many flows, 30s trials
+line_rate_mbps: 25000
+include_jumbo: true
+trial_duration: 30s
+addressing:
+  pairs: 256
+batch_size: 256
+throughput:
+  resolution_pct: 0.5
+latency:
+  load_levels: [10, 50, 90, 100]
+`,
+}
+
+// builtinNames names builtinPresets, index for index
+var builtinNames = []string{"quick", "rfc2544-standard", "carrier-ethernet", "datacenter-25g"}
+
+// ProfileDir returns the directory holding user-defined profiles,
+// ~/.config/rfc2544/profiles on Linux
+func ProfileDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("locate profile directory: %w", err)
+	}
+	return filepath.Join(dir, "rfc2544", "profiles"), nil
+}
+
+// Presets is the registry of built-in presets and user profiles
+type Presets struct {
+	byName map[string]*Preset
+}
+
+// LoadPresets builds the registry from the built-in presets and the
+// *.yaml files in dir. A user profile replaces a built-in of the same
+// name. A missing dir is not an error.
+func LoadPresets(dir string) (*Presets, error) {
+	r := &Presets{byName: make(map[string]*Preset)}
+	for i, data := range builtinPresets {
+		r.add(&Preset{Name: builtinNames[i], YAML: data})
+	}
+	if dir == "" {
+		return r, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read profiles: %w", err)
+	}
+	for _, e := range entries {
+		ext := filepath.Ext(e.Name())
+		if e.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read profile: %w", err)
+		}
+		p := &Preset{Name: strings.TrimSuffix(e.Name(), ext), Path: path, YAML: string(data)}
+		// Catch a broken profile when it is listed, not when it is used
+		if err := p.Apply(DefaultConfig()); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		r.add(p)
+	}
+	return r, nil
+}
+
+func (r *Presets) add(p *Preset) {
+	if first, _, _ := strings.Cut(p.YAML, "\n"); strings.HasPrefix(first, "#") {
+		p.Description = strings.TrimSpace(strings.TrimPrefix(first, "#"))
+	}
+	r.byName[p.Name] = p
+}
+
+// List returns the presets, built-ins first, each group sorted by name
+func (r *Presets) List() []*Preset {
+	list := make([]*Preset, 0, len(r.byName))
+	for _, p := range r.byName {
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Builtin() != list[j].Builtin() {
+			return list[i].Builtin()
+		}
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// Get returns the named preset
+func (r *Presets) Get(name string) (*Preset, error) {
+	p, ok := r.byName[name]
+	if !ok {
+		names := make([]string, 0, len(r.byName))
+		for _, p := range r.List() {
+			names = append(names, p.Name)
+		}
+		return nil, fmt.Errorf("unknown profile %q (available: %s)", name, strings.Join(names, ", "))
+	}
+	return p, nil
+}
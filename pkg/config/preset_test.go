@@ -0,0 +1,141 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuiltinPresets(t *testing.T) {
+	r, err := LoadPresets("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	list := r.List()
+	if len(list) != len(builtinNames) {
+		t.Fatalf("List() = %d presets, want %d", len(list), len(builtinNames))
+	}
+	for _, p := range list {
+		if !p.Builtin() || p.Description == "" {
+			t.Errorf("preset %s: builtin=%v description=%q", p.Name, p.Builtin(), p.Description)
+		}
+		// Every built-in must give a valid config once an interface is set
+		cfg := DefaultConfig()
+		if err := p.Apply(cfg); err != nil {
+			t.Fatalf("preset %s: %v", p.Name, err)
+		}
+		cfg.Interface = "eth0"
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("preset %s: %v", p.Name, err)
+		}
+	}
+
+	p, _ := r.Get("rfc2544-standard")
+	cfg := DefaultConfig()
+	p.Apply(cfg)
+	cfg.Interface = "eth0"
+	if cfg.TestType != TestSuite {
+		t.Errorf("rfc2544-standard test type = %s", cfg.TestType)
+	}
+	for _, c := range cfg.Compliance() {
+		// Modifier runs are separate passes, not part of one suite
+		if !c.Honored && c.Section != "11.1" && c.Section != "11.2" {
+			t.Errorf("rfc2544-standard does not honor section %s: %s", c.Section, c.Detail)
+		}
+	}
+
+	if _, err := r.Get("nope"); err == nil || !strings.Contains(err.Error(), "quick") {
+		t.Errorf("Get(unknown) error = %v, want the available names", err)
+	}
+}
+
+func TestUserPresets(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, data string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("lab.yaml", "# Lab bench\ninterface: eth1\ntrial_duration: 5s\n")
+	write("quick.yml", "trial_duration: 2s\n")
+	write("notes.txt", "not a profile")
+
+	r, err := LoadPresets(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lab, err := r.Get("lab")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lab.Builtin() || lab.Description != "Lab bench" {
+		t.Errorf("lab = %+v", lab)
+	}
+	if q, _ := r.Get("quick"); q.Builtin() {
+		t.Error("a user profile should replace the built-in of the same name")
+	}
+	if _, err := r.Get("notes"); err == nil {
+		t.Error("non-YAML files should be ignored")
+	}
+	list := r.List()
+	if !list[0].Builtin() || list[len(list)-1].Builtin() {
+		t.Error("List() should put built-ins before user profiles")
+	}
+
+	write("broken.yaml", "trial_duration: [\n")
+	if _, err := LoadPresets(dir); err == nil || !strings.Contains(err.Error(), "broken.yaml") {
+		t.Errorf("broken profile error = %v", err)
+	}
+
+	if _, err := LoadPresets(filepath.Join(dir, "missing")); err != nil {
+		t.Errorf("missing profile directory: %v", err)
+	}
+}
+
+func TestLoadLayers(t *testing.T) {
+	r, _ := LoadPresets("")
+	quick, _ := r.Get("quick")
+
+	// Profile alone: no interface yet, so not validated
+	cfg, err := LoadLayers(Layers{Preset: quick})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.TrialDuration != 10*time.Second || cfg.Throughput.ResolutionPct != 1.0 {
+		t.Errorf("quick not applied: trial %v, resolution %v", cfg.TrialDuration, cfg.Throughput.ResolutionPct)
+	}
+
+	// The file overrides the profile, which still supplies the rest
+	path := filepath.Join(t.TempDir(), "run.yaml")
+	os.WriteFile(path, []byte("interface: eth0\ntrial_duration: 20s\n"), 0644)
+	cfg, err = LoadLayers(Layers{Preset: quick, File: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.TrialDuration != 20*time.Second {
+		t.Errorf("file should override the profile: trial %v", cfg.TrialDuration)
+	}
+	if cfg.Throughput.MaxIterations != 10 || len(cfg.FrameSizes) != 3 {
+		t.Errorf("profile settings lost: iterations %d, sizes %v", cfg.Throughput.MaxIterations, cfg.FrameSizes)
+	}
+
+	// The environment overrides the file
+	cfg, err = LoadLayers(Layers{Preset: quick, File: path, Environ: []string{"RFC2544_TRIAL_DURATION=30s"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.TrialDuration != 30*time.Second {
+		t.Errorf("environment should override the file: trial %v", cfg.TrialDuration)
+	}
+
+	// A file is still validated, with the environment applied
+	os.WriteFile(path, []byte("trial_duration: 20s\n"), 0644)
+	if _, err := LoadLayers(Layers{Preset: quick, File: path}); err == nil {
+		t.Error("expected a validation error for a file without an interface")
+	}
+	if _, err := LoadLayers(Layers{File: path, Environ: []string{"RFC2544_INTERFACE=eth0"}}); err != nil {
+		t.Errorf("interface from the environment: %v", err)
+	}
+}
@@ -0,0 +1,168 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// restartKeys are read once at startup, so a change needs a restart
+var restartKeys = map[string]bool{
+	"web_ui":  true,
+	"profile": true,
+}
+
+// dataplaneKeys set up the dataplane when a test starts. They apply from
+// the next test, so they may not change while one is running.
+var dataplaneKeys = map[string]bool{
+	"interface":       true,
+	"line_rate_mbps":  true,
+	"auto_detect_nic": true,
+	"ports":           true,
+	"hw_timestamp":    true,
+	"use_dpdk":        true,
+	"dpdk_args":       true,
+	"use_pacing":      true,
+	"batch_size":      true,
+	"speed_profile":   true,
+	"addressing":      true,
+	"headers":         true,
+	"framing":         true,
+	"latency_probe":   true,
+	"ip_version":      true,
+	"ipv6":            true,
+	"dscp":            true,
+	"payload_pattern": true,
+	"capture":         true,
+	"packet":          true,
+	"trex":            true,
+	"socket":          true,
+}
+
+// Changes returns the top-level YAML keys whose settings differ in next,
+// in file order
+func (c *Config) Changes(next *Config) []string {
+	var keys []string
+	a, b := reflect.ValueOf(c).Elem(), reflect.ValueOf(next).Elem()
+	t := a.Type()
+	for i := 0; i < t.NumField(); i++ {
+		key, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		if key == "" || key == "-" {
+			continue
+		}
+		if !reflect.DeepEqual(a.Field(i).Interface(), b.Field(i).Interface()) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// CheckReload reports the keys a reload from cur to next changes. It
+// refuses keys that need a restart and, while a test is running, keys
+// that set up the dataplane. Thresholds, output settings, test parameters
+// and Y.1564 services may change at any time; a running soak takes a new
+// soak rate_pct or frame_size from its next sample.
+func CheckReload(cur, next *Config, running bool) ([]string, error) {
+	changed := cur.Changes(next)
+	var restart, busy []string
+	for _, key := range changed {
+		switch {
+		case restartKeys[key]:
+			restart = append(restart, key)
+		case dataplaneKeys[key] && running:
+			busy = append(busy, key)
+		}
+	}
+	if len(restart) > 0 {
+		return changed, fmt.Errorf("%s needs a restart", strings.Join(restart, ", "))
+	}
+	if len(busy) > 0 {
+		return changed, fmt.Errorf("%s cannot change while a test is running", strings.Join(busy, ", "))
+	}
+	return changed, nil
+}
+
+// Watch calls fn each time the config file at path or a file it includes
+// changes, until ctx is done. It watches the files' directories, so a save
+// that renames a new file over the old one is seen as well, and follows
+// includes an edit adds. Changes settle for settle before fn is called, so
+// a save that writes in several steps is one call. A file that goes
+// missing is not a change; its return is.
+func Watch(ctx context.Context, path string, settle time.Duration, fn func()) error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	// Keep the files of the last config that loaded while one is broken
+	files := map[string]bool{}
+	watched := map[string]bool{}
+	track := func() error {
+		list, err := Files(path)
+		if err == nil {
+			files = map[string]bool{}
+		}
+		if len(files) == 0 && len(list) == 0 {
+			abs, err := filepath.Abs(path)
+			if err != nil {
+				return err
+			}
+			list = []string{abs}
+		}
+		for _, f := range list {
+			files[f] = true
+			dir := filepath.Dir(f)
+			if watched[dir] {
+				continue
+			}
+			if err := w.Add(dir); err != nil {
+				return err
+			}
+			watched[dir] = true
+		}
+		return nil
+	}
+	if err := track(); err != nil {
+		return err
+	}
+
+	var settled <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case ev, ok := <-w.Events:
+			if !ok {
+				return nil
+			}
+			if files[filepath.Clean(ev.Name)] && ev.Op&(fsnotify.Write|fsnotify.Create) != 0 {
+				settled = time.After(settle)
+			}
+		case err, ok := <-w.Errors:
+			if !ok {
+				return nil
+			}
+			// Events may have been dropped; check the files anyway
+			if errors.Is(err, fsnotify.ErrEventOverflow) {
+				settled = time.After(settle)
+			}
+		case <-settled:
+			settled = nil
+			if _, err := os.Stat(path); err != nil {
+				continue
+			}
+			if err := track(); err != nil {
+				return err
+			}
+			fn()
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckReload(t *testing.T) {
+	cur := DefaultConfig()
+	cur.Interface = "eth0"
+
+	next := DefaultConfig()
+	next.Interface = "eth0"
+	if changed, err := CheckReload(cur, next, true); err != nil || changed != nil {
+		t.Errorf("no change: changed %q, err %v", changed, err)
+	}
+
+	next.Thresholds.MinThroughputPct = 95
+	next.OutputFormat = FormatJSON
+	next.Y1564.Services = append(next.Y1564.Services, Y1564Service{ServiceID: 2, Enabled: true})
+	changed, err := CheckReload(cur, next, true)
+	if err != nil {
+		t.Fatalf("non-destructive changes refused: %v", err)
+	}
+	if want := []string{"thresholds", "output_format", "y1564"}; !reflect.DeepEqual(changed, want) {
+		t.Errorf("changed = %q, want %q", changed, want)
+	}
+
+	// A running soak takes a new rate and frame size from its next sample
+	soak := DefaultConfig()
+	soak.Interface = "eth0"
+	soak.Soak.RatePct, soak.FrameSize = 40, 512
+	if changed, err := CheckReload(cur, soak, true); err != nil || !reflect.DeepEqual(changed, []string{"frame_size", "soak"}) {
+		t.Errorf("soak retune while running: changed %q, err %v", changed, err)
+	}
+
+	next.Interface = "eth1"
+	if _, err := CheckReload(cur, next, true); err == nil || !strings.Contains(err.Error(), "interface") {
+		t.Errorf("interface change while running: %v", err)
+	}
+	if _, err := CheckReload(cur, next, false); err != nil {
+		t.Errorf("interface change while idle: %v", err)
+	}
+
+	next.WebUI.Address = ":9090"
+	if _, err := CheckReload(cur, next, false); err == nil || !strings.Contains(err.Error(), "restart") {
+		t.Errorf("web_ui change: %v", err)
+	}
+}
+
+func TestLoadLayersData(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.yaml")
+	os.WriteFile(path, []byte("interface: eth0\ntrial_duration: 20s\n"), 0644)
+
+	cfg, err := LoadLayers(Layers{File: path, Data: []byte("interface: eth1\n")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Interface != "eth1" || cfg.TrialDuration != 60*time.Second {
+		t.Errorf("data should replace the file: interface %s, trial %v", cfg.Interface, cfg.TrialDuration)
+	}
+	if _, err := LoadLayers(Layers{Data: []byte("trial_duration: 5s\n")}); err == nil {
+		t.Error("expected a validation error for data without an interface")
+	}
+}
+
+// watch runs Watch on path until the test ends, reporting each change
+func watch(t *testing.T, path string) <-chan struct{} {
+	changes := make(chan struct{}, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- Watch(ctx, path, 10*time.Millisecond, func() { changes <- struct{}{} })
+	}()
+	t.Cleanup(func() {
+		cancel()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Errorf("Watch() = %v", err)
+			}
+		case <-time.After(2 * time.Second):
+			t.Error("Watch did not stop with its context")
+		}
+	})
+	// Let the watcher start before the test edits files
+	time.Sleep(50 * time.Millisecond)
+	return changes
+}
+
+// expectChange fails the test unless a change is reported (want) or none
+// is (!want)
+func expectChange(t *testing.T, changes <-chan struct{}, want bool, what string) {
+	t.Helper()
+	wait := 2 * time.Second
+	if !want {
+		wait = 100 * time.Millisecond
+	}
+	select {
+	case <-changes:
+		if !want {
+			t.Fatalf("change reported for %s", what)
+		}
+	case <-time.After(wait):
+		if want {
+			t.Fatalf("%s not reported", what)
+		}
+	}
+}
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "run.yaml")
+	os.WriteFile(path, []byte("interface: eth0\n"), 0644)
+	changes := watch(t, path)
+	expectChange(t, changes, false, "an untouched file")
+
+	os.WriteFile(path, []byte("interface: eth1\ntrial_duration: 5s\n"), 0644)
+	expectChange(t, changes, true, "a write")
+
+	// Editors that save by renaming a new file over the old one
+	tmp := filepath.Join(dir, ".run.yaml.swp")
+	os.WriteFile(tmp, []byte("interface: eth2\n"), 0644)
+	os.Rename(tmp, path)
+	expectChange(t, changes, true, "a rename over the file")
+
+	// Other files in the directory are not the config
+	os.WriteFile(filepath.Join(dir, "other.yaml"), []byte("x: 1\n"), 0644)
+	expectChange(t, changes, false, "another file")
+
+	os.Remove(path)
+	expectChange(t, changes, false, "a removed file")
+	os.WriteFile(path, []byte("interface: eth0\n"), 0644)
+	expectChange(t, changes, true, "a file's return")
+}
+
+func TestWatchIncludes(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "base", "lab.yaml")
+	site := filepath.Join(dir, "site.yaml")
+	later := filepath.Join(dir, "later.yaml")
+	os.Mkdir(filepath.Dir(base), 0755)
+	os.WriteFile(base, []byte("trial_duration: 5s\n"), 0644)
+	os.WriteFile(later, []byte("warmup_period: 1s\n"), 0644)
+	os.WriteFile(site, []byte("include: [base/lab.yaml]\ninterface: eth0\n"), 0644)
+	changes := watch(t, site)
+
+	os.WriteFile(base, []byte("trial_duration: 10s\n"), 0644)
+	expectChange(t, changes, true, "an edit of an include")
+
+	// A file not yet included is watched once an edit includes it
+	os.WriteFile(later, []byte("warmup_period: 2s\n"), 0644)
+	expectChange(t, changes, false, "a file not included")
+	os.WriteFile(site, []byte("include: [base/lab.yaml, later.yaml]\ninterface: eth0\n"), 0644)
+	expectChange(t, changes, true, "an edit of the config file")
+	os.WriteFile(later, []byte("warmup_period: 3s\n"), 0644)
+	expectChange(t, changes, true, "an edit of a new include")
+}
+
+func TestFiles(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a.yaml"), []byte("trial_duration: 5s\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "b.yaml"), []byte("include: [a.yaml]\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "run.yaml"), []byte("include: [b.yaml, a.yaml]\ninterface: eth0\n"), 0644)
+
+	files, err := Files(filepath.Join(dir, "run.yaml"))
+	want := []string{filepath.Join(dir, "a.yaml"), filepath.Join(dir, "b.yaml"), filepath.Join(dir, "run.yaml")}
+	if err != nil || !reflect.DeepEqual(files, want) {
+		t.Errorf("Files() = %v, %v; want %v", files, err, want)
+	}
+
+	// A missing include ends the list after the files before it
+	os.WriteFile(filepath.Join(dir, "run.yaml"), []byte("include: [a.yaml, gone.yaml]\n"), 0644)
+	files, err = Files(filepath.Join(dir, "run.yaml"))
+	if err == nil || !reflect.DeepEqual(files, want[:1]) {
+		t.Errorf("Files() = %v, %v; want %v and an error", files, err, want[:1])
+	}
+}
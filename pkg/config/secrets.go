@@ -0,0 +1,337 @@
+package config
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Credentials need not sit in a config file in plain text: any string
+// value may reference a secret as ${SCHEME:NAME}, resolved when the
+// configuration is loaded, e.g. ipmi_password: ${env:IPMI_PASSWORD}.
+//
+//	${env:NAME}     the environment variable NAME
+//	${file:PATH}    the contents of a file, without the final newline;
+//	                relative to the config file
+//	${secret:NAME}  NAME in the encrypted secrets file (see SecretsConfig)
+//
+// $${ is a literal ${.
+
+// SecretsKeyEnv holds the secrets file key when no key file is set
+const SecretsKeyEnv = "RFC2544_SECRETS_KEY"
+
+// SecretsConfig locates the encrypted file ${secret:NAME} references read,
+// written with 'rfc2544 config secrets set'
+type SecretsConfig struct {
+	File    string `yaml:"file"`
+	KeyFile string `yaml:"key_file"` // Default: the key in $RFC2544_SECRETS_KEY
+}
+
+// SecretProvider looks up the secrets of one reference scheme
+type SecretProvider interface {
+	Secret(name string) (string, error)
+}
+
+// SecretFunc is a SecretProvider function
+type SecretFunc func(name string) (string, error)
+
+// Secret returns f(name)
+func (f SecretFunc) Secret(name string) (string, error) {
+	return f(name)
+}
+
+// Secrets are the providers by scheme, e.g. env for ${env:NAME}
+type Secrets map[string]SecretProvider
+
+// defaultSecrets are the env, file and secret providers; dir is the
+// directory of the config file, for relative paths
+func (c *Config) defaultSecrets(dir string) Secrets {
+	var stored map[string]string
+	return Secrets{
+		"env": SecretFunc(func(name string) (string, error) {
+			v, ok := os.LookupEnv(name)
+			if !ok {
+				return "", fmt.Errorf("environment variable %s is not set", name)
+			}
+			return v, nil
+		}),
+		"file": SecretFunc(func(name string) (string, error) {
+			if !filepath.IsAbs(name) {
+				name = filepath.Join(dir, name)
+			}
+			data, err := os.ReadFile(name)
+			if err != nil {
+				return "", err
+			}
+			return strings.TrimSuffix(strings.TrimSuffix(string(data), "\n"), "\r"), nil
+		}),
+		"secret": SecretFunc(func(name string) (string, error) {
+			if stored == nil {
+				if c.Secrets.File == "" {
+					return "", fmt.Errorf("secrets file is not set")
+				}
+				key, err := c.Secrets.Key(dir)
+				if err != nil {
+					return "", err
+				}
+				path := c.Secrets.File
+				if !filepath.IsAbs(path) {
+					path = filepath.Join(dir, path)
+				}
+				if stored, err = ReadSecretsFile(path, key); err != nil {
+					return "", err
+				}
+			}
+			v, ok := stored[name]
+			if !ok {
+				return "", fmt.Errorf("no secret %s in %s", name, c.Secrets.File)
+			}
+			return v, nil
+		}),
+	}
+}
+
+// refusedSecrets replaces the built-in providers for config that does
+// not come from a file
+var refusedSecrets = func() Secrets {
+	refuse := SecretFunc(func(string) (string, error) {
+		return "", fmt.Errorf("secret references are resolved only in config files")
+	})
+	return Secrets{"env": refuse, "file": refuse, "secret": refuse}
+}()
+
+var secretRef = regexp.MustCompile(`\$\$\{|\$\{([a-z]+):([^}]*)\}`)
+
+// resolveSecrets replaces the secret references in every string of the
+// configuration. The secrets section is resolved first, so the secrets
+// file may be given as ${env:...}.
+func (c *Config) resolveSecrets(dir string, extra Secrets) error {
+	secrets := c.defaultSecrets(dir)
+	for scheme, p := range extra {
+		secrets[scheme] = p
+	}
+	noStore := make(Secrets, len(secrets))
+	for scheme, p := range secrets {
+		if scheme != "secret" {
+			noStore[scheme] = p
+		}
+	}
+	if err := noStore.resolve(reflect.ValueOf(&c.Secrets).Elem(), "secrets", make(map[uintptr]bool)); err != nil {
+		return err
+	}
+	return secrets.resolve(reflect.ValueOf(c).Elem(), "", make(map[uintptr]bool))
+}
+
+// resolve walks v, named by its key path, replacing references in strings
+func (s Secrets) resolve(v reflect.Value, path string, seen map[uintptr]bool) error {
+	join := func(key string) string {
+		if path == "" {
+			return key
+		}
+		return path + "." + key
+	}
+	switch v.Kind() {
+	case reflect.String:
+		if !strings.Contains(v.String(), "${") || !v.CanSet() {
+			return nil
+		}
+		out, err := s.expand(v.String())
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		v.SetString(out)
+	case reflect.Ptr:
+		if v.IsNil() || seen[v.Pointer()] {
+			return nil
+		}
+		seen[v.Pointer()] = true
+		return s.resolve(v.Elem(), path, seen)
+	case reflect.Struct:
+		// A yaml.Node holds a plan step's settings, decoded later
+		if v.Type() == reflect.TypeOf(yaml.Node{}) {
+			if err := s.resolve(v.FieldByName("Value"), path, seen); err != nil {
+				return err
+			}
+			return s.resolve(v.FieldByName("Content"), path, seen)
+		}
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			key, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+			if key == "-" || !f.IsExported() {
+				continue
+			}
+			sub := path
+			if key != "" {
+				sub = join(key)
+			}
+			if err := s.resolve(v.Field(i), sub, seen); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := s.resolve(v.Index(i), fmt.Sprintf("%s[%d]", path, i), seen); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		for _, k := range v.MapKeys() {
+			e := reflect.New(v.Type().Elem()).Elem()
+			e.Set(v.MapIndex(k))
+			if err := s.resolve(e, join(fmt.Sprint(k)), seen); err != nil {
+				return err
+			}
+			v.SetMapIndex(k, e)
+		}
+	}
+	return nil
+}
+
+// expand replaces the references in one value
+func (s Secrets) expand(value string) (string, error) {
+	var firstErr error
+	out := secretRef.ReplaceAllStringFunc(value, func(ref string) string {
+		if ref == "$${" {
+			return "${"
+		}
+		m := secretRef.FindStringSubmatch(ref)
+		p, ok := s[m[1]]
+		if !ok {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("unknown secret reference %s", ref)
+			}
+			return ref
+		}
+		v, err := p.Secret(m[2])
+		if err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("%s: %w", ref, err)
+		}
+		return v
+	})
+	return out, firstErr
+}
+
+// Key reads the secrets file key from KeyFile, relative to dir, or
+// $RFC2544_SECRETS_KEY
+func (s SecretsConfig) Key(dir string) ([]byte, error) {
+	text := os.Getenv(SecretsKeyEnv)
+	if s.KeyFile != "" {
+		path := s.KeyFile
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("secrets key: %w", err)
+		}
+		text = string(data)
+	} else if text == "" {
+		return nil, fmt.Errorf("secrets key: set key_file or %s", SecretsKeyEnv)
+	}
+	return ParseSecretsKey(text)
+}
+
+// GenerateSecretsKey returns a new random key, base64-encoded
+func GenerateSecretsKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// ParseSecretsKey decodes a base64 AES-256 key
+func ParseSecretsKey(text string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(text))
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("secrets key must be 32 bytes, base64-encoded (see 'rfc2544 config secrets keygen')")
+	}
+	return key, nil
+}
+
+// secretsHeader starts a secrets file; the rest is the base64 nonce and
+// AES-256-GCM sealed YAML map of names to values
+const secretsHeader = "# rfc2544 secrets v1\n"
+
+// ReadSecretsFile decrypts a secrets file
+func ReadSecretsFile(path string, key []byte) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("secrets file: %w", err)
+	}
+	if !bytes.HasPrefix(data, []byte(secretsHeader)) {
+		return nil, fmt.Errorf("%s is not a secrets file", path)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data[len(secretsHeader):])))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	gcm, err := secretsCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, fmt.Errorf("%s is truncated", path)
+	}
+	plain, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], []byte(secretsHeader))
+	if err != nil {
+		return nil, fmt.Errorf("%s: wrong key or corrupt file", path)
+	}
+	values := make(map[string]string)
+	if err := yaml.Unmarshal(plain, &values); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return values, nil
+}
+
+// WriteSecretsFile encrypts values to a secrets file readable only by its
+// owner
+func WriteSecretsFile(path string, key []byte, values map[string]string) error {
+	plain, err := yaml.Marshal(values)
+	if err != nil {
+		return err
+	}
+	gcm, err := secretsCipher(key)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	sealed := gcm.Seal(nonce, nonce, plain, []byte(secretsHeader))
+	data := secretsHeader + base64.StdEncoding.EncodeToString(sealed) + "\n"
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		return fmt.Errorf("write secrets file: %w", err)
+	}
+	return nil
+}
+
+func secretsCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// SecretNames lists the names in a secrets file, sorted
+func SecretNames(values map[string]string) []string {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,71 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolveSecrets(t *testing.T) {
+	key, err := GenerateSecretsKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := writeConfigs(t, map[string]string{
+		"secrets.key":   key + "\n",
+		"community.txt": "s3cret\n",
+		"lab.yaml": `interface: eth0
+secrets:
+  file: ${env:TEST_SECRETS_FILE}
+  key_file: secrets.key
+power:
+  ipmi_user: admin
+  ipmi_password: ${secret:ipmi}
+modifiers:
+  community: ${file:community.txt}
+dut:
+  name: "${vault:dut}-$${literal}"
+`,
+	})
+	parsed, _ := ParseSecretsKey(key)
+	if err := WriteSecretsFile(filepath.Join(dir, "secrets.enc"), parsed, map[string]string{"ipmi": "hunter2"}); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TEST_SECRETS_FILE", "secrets.enc")
+
+	vault := Secrets{"vault": SecretFunc(func(name string) (string, error) { return "edge-" + name, nil })}
+	cfg, err := LoadLayers(Layers{File: filepath.Join(dir, "lab.yaml"), Strict: true, Secrets: vault})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Power.IPMIPassword != "hunter2" || cfg.Modifiers.Community != "s3cret" {
+		t.Errorf("ipmi_password %q, community %q", cfg.Power.IPMIPassword, cfg.Modifiers.Community)
+	}
+	if cfg.DUT.Name != "edge-dut-${literal}" {
+		t.Errorf("dut name = %q", cfg.DUT.Name)
+	}
+	if info, _ := os.Stat(filepath.Join(dir, "secrets.enc")); info.Mode().Perm() != 0600 {
+		t.Errorf("secrets file mode %v", info.Mode().Perm())
+	}
+
+	// Without the vault provider the reference is unknown
+	_, err = LoadLayers(Layers{File: filepath.Join(dir, "lab.yaml")})
+	if err == nil || !strings.Contains(err.Error(), "dut.name: unknown secret reference ${vault:dut}") {
+		t.Errorf("unknown scheme: %v", err)
+	}
+
+	// A different key does not open the file
+	other, _ := GenerateSecretsKey()
+	os.WriteFile(filepath.Join(dir, "secrets.key"), []byte(other), 0600)
+	_, err = LoadLayers(Layers{File: filepath.Join(dir, "lab.yaml"), Secrets: vault})
+	if err == nil || !strings.Contains(err.Error(), "power.ipmi_password: ${secret:ipmi}: ") || !strings.Contains(err.Error(), "wrong key") {
+		t.Errorf("wrong key: %v", err)
+	}
+
+	// Nor do references in config sent over the API
+	_, err = LoadLayers(Layers{Data: []byte("interface: eth0\ndut:\n  name: ${file:/etc/passwd}\n")})
+	if err == nil || !strings.Contains(err.Error(), "resolved only in config files") {
+		t.Errorf("API data: %v", err)
+	}
+}
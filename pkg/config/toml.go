@@ -0,0 +1,659 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TOML config files are read into the YAML node tree the rest of loading
+// works on, and written from it. This covers TOML 1.0 except dates and
+// times, which no config setting takes.
+
+// tomlParser reads a TOML document into a YAML mapping
+type tomlParser struct {
+	src     []byte
+	pos     int
+	line    int
+	root    *yaml.Node
+	defined map[*yaml.Node]bool // Tables given their own [header] or set inline
+	dotted  map[*yaml.Node]bool // Tables made by a dotted key, which no [header] may open
+	arrays  map[*yaml.Node]bool // Arrays of [[tables]]
+}
+
+// parseTOML parses a TOML document
+func parseTOML(data []byte) (*yaml.Node, error) {
+	p := &tomlParser{src: data, line: 1, defined: make(map[*yaml.Node]bool), dotted: make(map[*yaml.Node]bool), arrays: make(map[*yaml.Node]bool)}
+	p.root = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Line: 1}
+	if err := p.document(); err != nil {
+		return nil, fmt.Errorf("toml: line %d: %w", p.line, err)
+	}
+	return p.root, nil
+}
+
+func (p *tomlParser) eof() bool { return p.pos >= len(p.src) }
+
+func (p *tomlParser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *tomlParser) next() byte {
+	c := p.peek()
+	p.pos++
+	if c == '\n' {
+		p.line++
+	}
+	return c
+}
+
+func (p *tomlParser) hasPrefix(s string) bool {
+	return bytes.HasPrefix(p.src[p.pos:], []byte(s))
+}
+
+// skipSpace skips spaces and tabs, and with newlines also line breaks and
+// comments
+func (p *tomlParser) skipSpace(newlines bool) {
+	for !p.eof() {
+		switch c := p.peek(); {
+		case c == ' ' || c == '\t':
+			p.next()
+		case newlines && (c == '\n' || c == '\r'):
+			p.next()
+		case newlines && c == '#':
+			p.comment()
+		default:
+			return
+		}
+	}
+}
+
+func (p *tomlParser) comment() {
+	for !p.eof() && p.peek() != '\n' {
+		p.next()
+	}
+}
+
+// endLine expects nothing but a comment up to the end of the line
+func (p *tomlParser) endLine() error {
+	p.skipSpace(false)
+	if p.peek() == '#' {
+		p.comment()
+	}
+	if p.hasPrefix("\r\n") {
+		p.next()
+	}
+	if !p.eof() && p.next() != '\n' {
+		return fmt.Errorf("expected the end of the line")
+	}
+	return nil
+}
+
+func (p *tomlParser) expect(s string) error {
+	if !p.hasPrefix(s) {
+		return fmt.Errorf("expected %q", s)
+	}
+	for range s {
+		p.next()
+	}
+	return nil
+}
+
+func (p *tomlParser) document() error {
+	table := p.root
+	for {
+		p.skipSpace(true)
+		if p.eof() {
+			return nil
+		}
+		var err error
+		switch {
+		case p.hasPrefix("[["):
+			table, err = p.arrayTable()
+		case p.peek() == '[':
+			table, err = p.tableHeader()
+		default:
+			err = p.keyValue(table)
+		}
+		if err != nil {
+			return err
+		}
+		if err := p.endLine(); err != nil {
+			return err
+		}
+	}
+}
+
+// tableHeader reads [a.b] and returns the table it opens
+func (p *tomlParser) tableHeader() (*yaml.Node, error) {
+	p.next()
+	p.skipSpace(false)
+	key, err := p.key()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace(false)
+	if err := p.expect("]"); err != nil {
+		return nil, err
+	}
+	t, err := p.descend(p.root, key, true)
+	if err != nil {
+		return nil, err
+	}
+	if p.defined[t] || p.dotted[t] {
+		return nil, fmt.Errorf("table [%s] defined twice", strings.Join(key, "."))
+	}
+	p.defined[t] = true
+	return t, nil
+}
+
+// arrayTable reads [[a.b]] and returns the table it appends to the array
+func (p *tomlParser) arrayTable() (*yaml.Node, error) {
+	p.next()
+	p.next()
+	p.skipSpace(false)
+	key, err := p.key()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace(false)
+	if err := p.expect("]]"); err != nil {
+		return nil, err
+	}
+	parent, err := p.descend(p.root, key[:len(key)-1], true)
+	if err != nil {
+		return nil, err
+	}
+	name := key[len(key)-1]
+	arr := lookup(parent, name)
+	if arr == nil {
+		arr = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Line: p.line}
+		set(parent, name, arr, p.line)
+		p.arrays[arr] = true
+	} else if !p.arrays[arr] {
+		return nil, fmt.Errorf("%s is not an array of tables", strings.Join(key, "."))
+	}
+	t := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Line: p.line}
+	arr.Content = append(arr.Content, t)
+	p.defined[t] = true
+	return t, nil
+}
+
+// descend walks from a table along a dotted key, creating the tables it
+// lacks. headers lets an array of tables stand for its last table, as in
+// [services.sla] after [[services]].
+func (p *tomlParser) descend(t *yaml.Node, key []string, headers bool) (*yaml.Node, error) {
+	for i, k := range key {
+		v := lookup(t, k)
+		switch {
+		case v == nil:
+			v = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Line: p.line}
+			set(t, k, v, p.line)
+		case headers && p.arrays[v]:
+			v = v.Content[len(v.Content)-1]
+		}
+		if v.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("%s is not a table", strings.Join(key[:i+1], "."))
+		}
+		if p.defined[v] && v.Style == yaml.FlowStyle {
+			return nil, fmt.Errorf("inline table %s cannot be extended", strings.Join(key[:i+1], "."))
+		}
+		t = v
+	}
+	return t, nil
+}
+
+// keyValue reads key = value into a table
+func (p *tomlParser) keyValue(t *yaml.Node) error {
+	line := p.line
+	key, err := p.key()
+	if err != nil {
+		return err
+	}
+	p.skipSpace(false)
+	if err := p.expect("="); err != nil {
+		return err
+	}
+	p.skipSpace(false)
+	v, err := p.value()
+	if err != nil {
+		return err
+	}
+	parent, err := p.descend(t, key[:len(key)-1], false)
+	if err != nil {
+		return err
+	}
+	for n, i := t, 0; i < len(key)-1; i++ {
+		n = lookup(n, key[i])
+		p.dotted[n] = true
+	}
+	name := key[len(key)-1]
+	if lookup(parent, name) != nil {
+		return fmt.Errorf("%s is set twice", strings.Join(key, "."))
+	}
+	set(parent, name, v, line)
+	return nil
+}
+
+var (
+	bareKey   = regexp.MustCompile(`^[A-Za-z0-9_-]+`)
+	bareValue = regexp.MustCompile(`^[0-9A-Za-z_+.:-]+`)
+	dateValue = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}`)
+)
+
+// key reads a possibly dotted key
+func (p *tomlParser) key() ([]string, error) {
+	var key []string
+	for {
+		var k string
+		switch p.peek() {
+		case '"':
+			s, err := p.basicString()
+			if err != nil {
+				return nil, err
+			}
+			k = s
+		case '\'':
+			s, err := p.literalString()
+			if err != nil {
+				return nil, err
+			}
+			k = s
+		default:
+			k = string(bareKey.Find(p.src[p.pos:]))
+			if k == "" {
+				return nil, fmt.Errorf("expected a key")
+			}
+			p.pos += len(k)
+		}
+		key = append(key, k)
+		p.skipSpace(false)
+		if p.peek() != '.' {
+			return key, nil
+		}
+		p.next()
+		p.skipSpace(false)
+	}
+}
+
+// value reads a value and returns it as a YAML node
+func (p *tomlParser) value() (*yaml.Node, error) {
+	line := p.line
+	scalar := func(tag, v string) *yaml.Node {
+		n := &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: v, Line: line}
+		if tag == "!!str" {
+			n.Style = yaml.DoubleQuotedStyle
+		}
+		return n
+	}
+	switch {
+	case p.hasPrefix(`"""`):
+		s, err := p.multilineString(`"""`)
+		return scalar("!!str", s), err
+	case p.hasPrefix(`'''`):
+		s, err := p.multilineString(`'''`)
+		return scalar("!!str", s), err
+	case p.peek() == '"':
+		s, err := p.basicString()
+		return scalar("!!str", s), err
+	case p.peek() == '\'':
+		s, err := p.literalString()
+		return scalar("!!str", s), err
+	case p.peek() == '[':
+		return p.array()
+	case p.peek() == '{':
+		return p.inlineTable()
+	}
+
+	tok := string(bareValue.Find(p.src[p.pos:]))
+	if tok == "" {
+		return nil, fmt.Errorf("expected a value")
+	}
+	p.pos += len(tok)
+	switch tok {
+	case "true", "false":
+		return scalar("!!bool", tok), nil
+	}
+	if strings.Contains(tok, ":") || dateValue.MatchString(tok) {
+		return nil, fmt.Errorf("dates and times are not supported: %s", tok)
+	}
+	switch strings.TrimLeft(tok, "+-") {
+	case "inf":
+		return scalar("!!float", strings.TrimPrefix(strings.Replace(tok, "inf", ".inf", 1), "+")), nil
+	case "nan":
+		return scalar("!!float", ".nan"), nil
+	}
+	digits := strings.TrimLeft(tok, "+-")
+	if digits == "" || digits[0] < '0' || digits[0] > '9' {
+		return nil, fmt.Errorf("invalid value %s", tok)
+	}
+	isHex := strings.HasPrefix(digits, "0x")
+	if !isHex && strings.ContainsAny(digits, ".eE") {
+		f, err := strconv.ParseFloat(strings.ReplaceAll(tok, "_", ""), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid float %s", tok)
+		}
+		v := strconv.FormatFloat(f, 'g', -1, 64)
+		if !strings.ContainsAny(v, ".e") {
+			v += ".0"
+		}
+		return scalar("!!float", v), nil
+	}
+	if len(digits) > 1 && digits[0] == '0' && !strings.ContainsAny(digits[1:2], "xob") {
+		return nil, fmt.Errorf("invalid integer %s: leading zero", tok)
+	}
+	i, err := strconv.ParseInt(tok, 0, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid integer %s", tok)
+	}
+	return scalar("!!int", strconv.FormatInt(i, 10)), nil
+}
+
+func (p *tomlParser) array() (*yaml.Node, error) {
+	arr := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Style: yaml.FlowStyle, Line: p.line}
+	p.next()
+	for {
+		p.skipSpace(true)
+		if p.peek() == ']' {
+			p.next()
+			return arr, nil
+		}
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		arr.Content = append(arr.Content, v)
+		p.skipSpace(true)
+		switch p.next() {
+		case ',':
+		case ']':
+			return arr, nil
+		default:
+			return nil, fmt.Errorf("expected , or ] in an array")
+		}
+	}
+}
+
+func (p *tomlParser) inlineTable() (*yaml.Node, error) {
+	t := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Style: yaml.FlowStyle, Line: p.line}
+	p.next()
+	p.skipSpace(false)
+	if p.peek() == '}' {
+		p.next()
+		p.defined[t] = true
+		return t, nil
+	}
+	for {
+		p.skipSpace(false)
+		if err := p.keyValue(t); err != nil {
+			return nil, err
+		}
+		p.skipSpace(false)
+		if c := p.peek(); c != ',' && c != '}' {
+			return nil, fmt.Errorf("expected , or } in an inline table")
+		}
+		if p.next() == '}' {
+			p.defined[t] = true
+			return t, nil
+		}
+	}
+}
+
+var tomlEscapes = map[byte]string{'b': "\b", 't': "\t", 'n': "\n", 'f': "\f", 'r': "\r", '"': "\"", '\\': "\\"}
+
+// escape reads the escape sequence after a backslash
+func (p *tomlParser) escape(sb *strings.Builder) error {
+	c := p.next()
+	if s, ok := tomlEscapes[c]; ok {
+		sb.WriteString(s)
+		return nil
+	}
+	n := map[byte]int{'u': 4, 'U': 8}[c]
+	if n == 0 || p.pos+n > len(p.src) {
+		return fmt.Errorf("invalid escape \\%c", c)
+	}
+	r, err := strconv.ParseUint(string(p.src[p.pos:p.pos+n]), 16, 32)
+	if err != nil || !utf8.ValidRune(rune(r)) {
+		return fmt.Errorf("invalid escape \\%c%s", c, p.src[p.pos:p.pos+n])
+	}
+	p.pos += n
+	sb.WriteRune(rune(r))
+	return nil
+}
+
+func (p *tomlParser) basicString() (string, error) {
+	var sb strings.Builder
+	p.next()
+	for {
+		switch c := p.next(); c {
+		case '"':
+			return sb.String(), nil
+		case '\\':
+			if err := p.escape(&sb); err != nil {
+				return "", err
+			}
+		case '\n', 0:
+			return "", fmt.Errorf("unterminated string")
+		default:
+			sb.WriteByte(c)
+		}
+	}
+}
+
+func (p *tomlParser) literalString() (string, error) {
+	p.next()
+	end := bytes.IndexAny(p.src[p.pos:], "'\n")
+	if end < 0 || p.src[p.pos+end] != '\'' {
+		return "", fmt.Errorf("unterminated string")
+	}
+	s := string(p.src[p.pos : p.pos+end])
+	p.pos += end + 1
+	return s, nil
+}
+
+// multilineString reads a multi-line basic or literal string, quote being
+// its three quotes. A newline right after the opening quotes is not part
+// of it.
+func (p *tomlParser) multilineString(quote string) (string, error) {
+	p.pos += len(quote)
+	if p.hasPrefix("\r\n") {
+		p.next()
+	}
+	if p.peek() == '\n' {
+		p.next()
+	}
+	var sb strings.Builder
+	for {
+		if p.eof() {
+			return "", fmt.Errorf("unterminated string")
+		}
+		if p.hasPrefix(quote) {
+			// Up to two quotes may end the content, as in """say "hi"""""
+			for i := 0; i < 2 && p.hasPrefix(quote+quote[:1]); i++ {
+				sb.WriteByte(p.next())
+			}
+			p.pos += len(quote)
+			return sb.String(), nil
+		}
+		c := p.next()
+		if c != '\\' || quote == "'''" {
+			sb.WriteByte(c)
+			continue
+		}
+		// A backslash at the end of a line joins it to the next
+		rest := p.pos
+		for rest < len(p.src) && (p.src[rest] == ' ' || p.src[rest] == '\t' || p.src[rest] == '\r') {
+			rest++
+		}
+		if rest < len(p.src) && p.src[rest] == '\n' {
+			p.pos = rest
+			p.skipSpace(true)
+			continue
+		}
+		if err := p.escape(&sb); err != nil {
+			return "", err
+		}
+	}
+}
+
+// lookup returns a table's value for a key, or nil
+func lookup(t *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(t.Content); i += 2 {
+		if t.Content[i].Value == key {
+			return t.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func set(t *yaml.Node, key string, v *yaml.Node, line int) {
+	k := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key, Line: line}
+	t.Content = append(t.Content, k, v)
+}
+
+// writeTOML writes a YAML mapping as a TOML table at path: its plain keys,
+// then its tables and arrays of tables. TOML has no null, so null values
+// are left out.
+func writeTOML(buf *bytes.Buffer, path []string, t *yaml.Node) error {
+	isTables := func(v *yaml.Node) bool {
+		if v.Kind != yaml.SequenceNode || len(v.Content) == 0 {
+			return false
+		}
+		for _, e := range v.Content {
+			if e.Kind != yaml.MappingNode {
+				return false
+			}
+		}
+		return true
+	}
+
+	for i := 0; i+1 < len(t.Content); i += 2 {
+		k, v := t.Content[i], t.Content[i+1]
+		if v.Kind == yaml.MappingNode || isTables(v) || v.Tag == "!!null" {
+			continue
+		}
+		s, err := tomlValue(v)
+		if err != nil {
+			return fmt.Errorf("%s: %w", strings.Join(append(path, k.Value), "."), err)
+		}
+		fmt.Fprintf(buf, "%s = %s\n", tomlKey(k.Value), s)
+	}
+	for i := 0; i+1 < len(t.Content); i += 2 {
+		k, v := t.Content[i], t.Content[i+1]
+		sub := append(append([]string(nil), path...), k.Value)
+		header := make([]string, len(sub))
+		for j, s := range sub {
+			header[j] = tomlKey(s)
+		}
+		switch {
+		case v.Kind == yaml.MappingNode:
+			fmt.Fprintf(buf, "\n[%s]\n", strings.Join(header, "."))
+			if err := writeTOML(buf, sub, v); err != nil {
+				return err
+			}
+		case isTables(v):
+			for _, e := range v.Content {
+				fmt.Fprintf(buf, "\n[[%s]]\n", strings.Join(header, "."))
+				if err := writeTOML(buf, sub, e); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// tomlValue formats a value inline
+func tomlValue(v *yaml.Node) (string, error) {
+	switch v.Kind {
+	case yaml.SequenceNode:
+		parts := make([]string, len(v.Content))
+		for i, e := range v.Content {
+			s, err := tomlValue(e)
+			if err != nil {
+				return "", err
+			}
+			parts[i] = s
+		}
+		return "[" + strings.Join(parts, ", ") + "]", nil
+	case yaml.MappingNode:
+		parts := make([]string, 0, len(v.Content)/2)
+		for i := 0; i+1 < len(v.Content); i += 2 {
+			if v.Content[i+1].Tag == "!!null" {
+				continue
+			}
+			s, err := tomlValue(v.Content[i+1])
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, tomlKey(v.Content[i].Value)+" = "+s)
+		}
+		return "{" + strings.Join(parts, ", ") + "}", nil
+	case yaml.ScalarNode:
+		switch v.Tag {
+		case "!!bool", "!!int":
+			return v.Value, nil
+		case "!!float":
+			f, err := strconv.ParseFloat(v.Value, 64)
+			if err != nil {
+				switch strings.ToLower(v.Value) {
+				case ".inf", "+.inf":
+					return "inf", nil
+				case "-.inf":
+					return "-inf", nil
+				case ".nan":
+					return "nan", nil
+				}
+				return "", fmt.Errorf("invalid float %s", v.Value)
+			}
+			// A whole float keeps a fraction so it reads back as a float
+			s := strconv.FormatFloat(f, 'g', -1, 64)
+			if !strings.ContainsAny(s, ".e") {
+				s += ".0"
+			}
+			return s, nil
+		case "!!null":
+			return "", fmt.Errorf("TOML has no null")
+		}
+		return tomlQuote(v.Value), nil
+	}
+	return "", fmt.Errorf("cannot write YAML node kind %d as TOML", v.Kind)
+}
+
+// tomlQuote writes a basic string
+func tomlQuote(s string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			sb.WriteRune('\\')
+			sb.WriteRune(r)
+		case r == '\n':
+			sb.WriteString(`\n`)
+		case r == '\t':
+			sb.WriteString(`\t`)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&sb, `\u%04X`, r)
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
+
+// tomlKey quotes a key unless it is bare
+func tomlKey(k string) string {
+	if k != "" && len(bareKey.FindString(k)) == len(k) {
+		return k
+	}
+	return tomlQuote(k)
+}
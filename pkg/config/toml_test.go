@@ -0,0 +1,272 @@
+package config
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// tomlDecode parses a TOML document into plain maps, lists and scalars
+func tomlDecode(src string) (interface{}, error) {
+	root, err := parseTOML([]byte(src))
+	if err != nil {
+		return nil, err
+	}
+	data, err := yaml.Marshal(root)
+	if err != nil {
+		return nil, err
+	}
+	var v interface{}
+	err = yaml.Unmarshal(data, &v)
+	return v, err
+}
+
+type tomlMap = map[string]interface{}
+
+type tomlList = []interface{}
+
+func TestParseTOML(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want tomlMap
+	}{
+		{"empty", "", tomlMap{}},
+		{"comments and blank lines", "# head\n\n  # indented\na = 1 # trailing\n", tomlMap{"a": 1}},
+		{"crlf", "a = 1\r\nb = 'x'\r\n", tomlMap{"a": 1, "b": "x"}},
+		{"no final newline", "a = true", tomlMap{"a": true}},
+
+		// Keys
+		{"bare keys", "a-b_1 = 1\n1234 = 2\n", tomlMap{"a-b_1": 1, "1234": 2}},
+		{"quoted keys", "\"a b\" = 1\n'c.d' = 2\n\"\" = 3\n", tomlMap{"a b": 1, "c.d": 2, "": 3}},
+		{"dotted keys", "a.b.c = 1\na.b.d = 2\na . e = 3\n", tomlMap{"a": tomlMap{"b": tomlMap{"c": 1, "d": 2}, "e": 3}}},
+		{"quoted dotted keys", "site.\"rack 1\".'port.a' = 'eth0'\n", tomlMap{"site": tomlMap{"rack 1": tomlMap{"port.a": "eth0"}}}},
+		{"dotted key in a table", "[a]\nb.c = 1\n", tomlMap{"a": tomlMap{"b": tomlMap{"c": 1}}}},
+
+		// Integers and floats
+		{"integers", "a = 42\nb = +7\nc = -17\nd = 1_000\ne = 0\n", tomlMap{"a": 42, "b": 7, "c": -17, "d": 1000, "e": 0}},
+		{"integer bases", "a = 0xff\nb = 0o17\nc = 0b101\nd = 0xdead_beef\n", tomlMap{"a": 255, "b": 15, "c": 5, "d": 0xdeadbeef}},
+		{"floats", "a = 0.5\nb = -3.25\nc = 1e3\nd = 6.626e-34\ne = 1_000.5\nf = 5E+2\n",
+			tomlMap{"a": 0.5, "b": -3.25, "c": 1000.0, "d": 6.626e-34, "e": 1000.5, "f": 500.0}},
+		{"special floats", "a = inf\nb = +inf\nc = -inf\n", tomlMap{"a": math.Inf(1), "b": math.Inf(1), "c": math.Inf(-1)}},
+		{"booleans", "a = true\nb = false\n", tomlMap{"a": true, "b": false}},
+
+		// Strings and escapes
+		{"basic string", `a = "eth0"`, tomlMap{"a": "eth0"}},
+		{"escapes", `a = "q\" b\\ t\t n\n r\r f\f b\b"`, tomlMap{"a": "q\" b\\ t\t n\n r\r f\f b\b"}},
+		{"unicode escapes", `a = "\u00e9\U0001F600"`, tomlMap{"a": "é😀"}},
+		{"literal string", `a = 'C:\Users\lab'`, tomlMap{"a": `C:\Users\lab`}},
+		{"unicode", "a = \"naïve ✓\"\n", tomlMap{"a": "naïve ✓"}},
+		{"string that looks like a number", "a = \"0x10\"\n", tomlMap{"a": "0x10"}},
+
+		// Multi-line strings
+		{"multiline basic", "a = \"\"\"\nline 1\nline 2\"\"\"\n", tomlMap{"a": "line 1\nline 2"}},
+		{"multiline basic keeps later newlines", "a = \"\"\"\n\nx\n\"\"\"\n", tomlMap{"a": "\nx\n"}},
+		{"multiline crlf after quotes", "a = \"\"\"\r\nx\"\"\"\n", tomlMap{"a": "x"}},
+		{"multiline line continuation", "a = \"\"\"\none \\\n    two \\   \n\n  three\"\"\"\n", tomlMap{"a": "one two three"}},
+		{"multiline escapes", "a = \"\"\"tab\\there \\\"q\\\"\"\"\"\n", tomlMap{"a": "tab\there \"q\""}},
+		{"multiline quotes before the end", "a = \"\"\"say \"hi\"\"\"\"\"\n", tomlMap{"a": "say \"hi\"\""}},
+		{"multiline literal", "a = '''\nC:\\path\\n\n'raw' '''\n", tomlMap{"a": "C:\\path\\n\n'raw' "}},
+		{"multiline literal keeps backslashes at line ends", "a = '''x \\\ny'''\n", tomlMap{"a": "x \\\ny"}},
+
+		// Arrays
+		{"array", "a = [1, 2, 3]\n", tomlMap{"a": tomlList{1, 2, 3}}},
+		{"empty array", "a = []\n", tomlMap{"a": tomlList{}}},
+		{"multiline array", "a = [\n  'x', # first\n\n  'y',\n]\n", tomlMap{"a": tomlList{"x", "y"}}},
+		{"nested arrays", "a = [[1, 2], ['x']]\n", tomlMap{"a": tomlList{tomlList{1, 2}, tomlList{"x"}}}},
+		{"array of inline tables", "a = [{x = 1}, {x = 2}]\n", tomlMap{"a": tomlList{tomlMap{"x": 1}, tomlMap{"x": 2}}}},
+
+		// Inline tables
+		{"inline table", "a = {x = 1, y = 'z'}\n", tomlMap{"a": tomlMap{"x": 1, "y": "z"}}},
+		{"empty inline table", "a = {}\n", tomlMap{"a": tomlMap{}}},
+		{"nested inline tables", "a = {b = {c = true}}\n", tomlMap{"a": tomlMap{"b": tomlMap{"c": true}}}},
+		{"dotted keys in an inline table", "a = {b.c = 1, b.d = 2}\n", tomlMap{"a": tomlMap{"b": tomlMap{"c": 1, "d": 2}}}},
+		{"inline table holding an array", "a = {list = [1, 2]}\n", tomlMap{"a": tomlMap{"list": tomlList{1, 2}}}},
+
+		// Tables
+		{"tables", "[a]\nx = 1\n[b]\ny = 2\n", tomlMap{"a": tomlMap{"x": 1}, "b": tomlMap{"y": 2}}},
+		{"dotted table header", "[a.b.c]\nx = 1\n", tomlMap{"a": tomlMap{"b": tomlMap{"c": tomlMap{"x": 1}}}}},
+		{"header spacing", "[ a . 'b c' ]\nx = 1\n", tomlMap{"a": tomlMap{"b c": tomlMap{"x": 1}}}},
+		{"super-table after sub-table", "[a.b]\nx = 1\n[a]\ny = 2\n", tomlMap{"a": tomlMap{"b": tomlMap{"x": 1}, "y": 2}}},
+		{"sub-table of a dotted key table", "[a]\nb.x = 1\n[a.b.c]\ny = 2\n", tomlMap{"a": tomlMap{"b": tomlMap{"x": 1, "c": tomlMap{"y": 2}}}}},
+		{"empty table", "[a]\n", tomlMap{"a": tomlMap{}}},
+
+		// Arrays of tables
+		{"array of tables", "[[s]]\nid = 1\n[[s]]\nid = 2\n", tomlMap{"s": tomlList{tomlMap{"id": 1}, tomlMap{"id": 2}}}},
+		{"empty tables in an array", "[[s]]\n[[s]]\n", tomlMap{"s": tomlList{tomlMap{}, tomlMap{}}}},
+		{"nested array of tables", "[[s]]\nid = 1\n[[s.flows]]\nport = 1\n[[s.flows]]\nport = 2\n[[s]]\nid = 2\n",
+			tomlMap{"s": tomlList{
+				tomlMap{"id": 1, "flows": tomlList{tomlMap{"port": 1}, tomlMap{"port": 2}}},
+				tomlMap{"id": 2},
+			}}},
+		{"sub-table of the last array table", "[[s]]\nid = 1\n[s.sla]\nfd = 5\n[[s]]\nid = 2\n[s.sla]\nfd = 6\n",
+			tomlMap{"s": tomlList{
+				tomlMap{"id": 1, "sla": tomlMap{"fd": 5}},
+				tomlMap{"id": 2, "sla": tomlMap{"fd": 6}},
+			}}},
+		{"array of tables under a table", "[a]\nx = 1\n[[a.b]]\ny = 2\n", tomlMap{"a": tomlMap{"x": 1, "b": tomlList{tomlMap{"y": 2}}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tomlDecode(tt.src)
+			if err != nil {
+				t.Fatalf("parseTOML(%q) error = %v", tt.src, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseTOML(%q) = %#v, want %#v", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTOMLNaN(t *testing.T) {
+	got, err := tomlDecode("a = nan\nb = -nan\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := got.(tomlMap)
+	for _, k := range []string{"a", "b"} {
+		if f, ok := m[k].(float64); !ok || !math.IsNaN(f) {
+			t.Errorf("%s = %#v, want NaN", k, m[k])
+		}
+	}
+}
+
+func TestParseTOMLErrors(t *testing.T) {
+	tests := []struct {
+		name, src, want string
+	}{
+		// Keys and lines
+		{"missing key", "= 1\n", "line 1: expected a key"},
+		{"missing equals", "a 1\n", `line 1: expected "="`},
+		{"missing value", "a =\n", "line 1: expected a value"},
+		{"two values on a line", "a = 1 b = 2\n", "line 1: expected the end of the line"},
+		{"key set twice", "a = 1\n\na = 2\n", "line 3: a is set twice"},
+		{"dotted key set twice", "a.b = 1\na.b = 2\n", "line 2: a.b is set twice"},
+		{"dotted key through a value", "a = 1\na.b = 2\n", "line 2: a is not a table"},
+		{"table set as a value", "[a]\nx = 1\n[b]\n[a.x]\n", "line 4: a.x is not a table"},
+
+		// Tables
+		{"table defined twice", "[a]\n[a]\n", "line 2: table [a] defined twice"},
+		{"sub-table defined twice", "[a.b]\n[a]\n[a.b]\n", "line 3: table [a.b] defined twice"},
+		{"table over a dotted key table", "[a]\nb.x = 1\n[a.b]\n", "line 3: table [a.b] defined twice"},
+		{"unclosed table header", "[a\nx = 1\n", `line 1: expected "]"`},
+		{"array of tables over a table", "[a]\n[[a]]\n", "line 2: a is not an array of tables"},
+		{"array of tables over an array", "a = [1]\n[[a]]\n", "line 2: a is not an array of tables"},
+		{"unclosed array of tables", "[[a]\n", `line 1: expected "]]"`},
+
+		// Inline tables
+		{"inline table extended by a header", "a = {x = 1}\n[a.b]\n", "line 2: inline table a cannot be extended"},
+		{"inline table extended by a dotted key", "a = {x = 1}\na.y = 2\n", "line 2: inline table a cannot be extended"},
+		{"inline table over several lines", "a = {x = 1,\ny = 2}\n", "line 1: expected a key"},
+		{"inline table key set twice", "a = {x = 1, x = 2}\n", "line 1: x is set twice"},
+		{"unclosed inline table", "a = {x = 1\n", "line 1: expected , or } in an inline table"},
+
+		// Arrays
+		{"unclosed array", "a = [1, 2\n", "line 2: expected , or ] in an array"},
+		{"array without commas", "a = [1 2]\n", "line 1: expected , or ] in an array"},
+
+		// Numbers
+		{"leading zero", "a = 012\n", "line 1: invalid integer 012: leading zero"},
+		{"bad integer", "a = 12ab\n", "line 1: invalid integer 12ab"},
+		{"bad float", "a = 1.2.3\n", "line 1: invalid float 1.2.3"},
+		{"integer overflow", "a = 9223372036854775808\n", "line 1: invalid integer"},
+		{"bare word", "a = yes\n", "line 1: invalid value yes"},
+		{"bare sign", "a = -\n", "line 1: invalid value -"},
+
+		// Dates and times, which no setting takes
+		{"date", "a = 1979-05-27\n", "line 1: dates and times are not supported: 1979-05-27"},
+		{"time", "a = 07:32:00\n", "line 1: dates and times are not supported: 07:32:00"},
+		{"date-time", "a = 1979-05-27T07:32:00Z\n", "line 1: dates and times are not supported"},
+
+		// Strings and escapes
+		{"unterminated basic string", "a = \"eth0\n", "line 2: unterminated string"},
+		{"unterminated at the end", "a = \"eth0", "line 1: unterminated string"},
+		{"unterminated literal string", "a = 'eth0\n", "line 1: unterminated string"},
+		{"unterminated multiline basic", "a = \"\"\"\nx\n", "line 3: unterminated string"},
+		{"unterminated multiline literal", "a = '''x''\n", "line 2: unterminated string"},
+		{"unknown escape", `a = "\q"`, `line 1: invalid escape \q`},
+		{"short unicode escape", `a = "\u12"`, `line 1: invalid escape \u`},
+		{"bad unicode escape", `a = "\uzzzz"`, `line 1: invalid escape \uzzzz`},
+		{"surrogate escape", `a = "\uD800"`, `line 1: invalid escape \uD800`},
+		{"escape past the end of unicode", `a = "\U00110000"`, `line 1: invalid escape \U00110000`},
+		{"unknown escape in multiline", "a = \"\"\"\\q\"\"\"\n", `line 1: invalid escape \q`},
+		{"key string unterminated", "\"a = 1\n", "line 2: unterminated string"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseTOML([]byte(tt.src))
+			if err == nil || !strings.HasPrefix(err.Error(), "toml: ") || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("parseTOML(%q) error = %v, want %q", tt.src, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteTOML(t *testing.T) {
+	tests := []struct {
+		name, yaml, want string
+	}{
+		{"scalars", "a: 1\nb: 0.5\nc: true\nd: eth0\n", "a = 1\nb = 0.5\nc = true\nd = \"eth0\"\n"},
+		{"whole float", "a: 2.0\nb: 1e3\nc: !!float 3\n", "a = 2.0\nb = 1000.0\nc = 3.0\n"},
+		{"special floats", "a: .inf\nb: -.inf\nc: .nan\n", "a = inf\nb = -inf\nc = nan\n"},
+		{"nulls left out", "a: null\nb: {x: null, y: 1}\nc: [{x: null}]\n", "\n[b]\ny = 1\n\n[[c]]\n"},
+		{"quoted keys", "\"a b\": 1\n\"c.d\": 2\n", "\"a b\" = 1\n\"c.d\" = 2\n"},
+		{"escapes", "a: \"q\\\" \\\\ \\t \\n \\x01\"\n", "a = \"q\\\" \\\\ \\t \\n \\u0001\"\n"},
+		{"tables after keys", "t: {x: 1}\na: 1\n", "a = 1\n\n[t]\nx = 1\n"},
+		{"nested tables", "a: {b: {c: 1}}\n", "\n[a]\n\n[a.b]\nc = 1\n"},
+		{"arrays", "a: [1, 2]\nb: []\nc: [[x]]\n", "a = [1, 2]\nb = []\nc = [[\"x\"]]\n"},
+		{"arrays of tables", "s:\n  - id: 1\n  - id: 2\n    sla: {fd: 5}\n",
+			"\n[[s]]\nid = 1\n\n[[s]]\nid = 2\n\n[s.sla]\nfd = 5\n"},
+		{"mixed list inline", "a: [{x: 1}, 2]\n", "a = [{x = 1}, 2]\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var doc yaml.Node
+			if err := yaml.Unmarshal([]byte(tt.yaml), &doc); err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			if err := writeTOML(&buf, nil, doc.Content[0]); err != nil {
+				t.Fatalf("writeTOML() error = %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("writeTOML() = %q, want %q", buf.String(), tt.want)
+			}
+
+			// What is written parses back to the same values, less nulls
+			back, err := tomlDecode(buf.String())
+			if err != nil {
+				t.Fatalf("parse back: %v", err)
+			}
+			var want interface{}
+			yaml.Unmarshal([]byte(tt.yaml), &want)
+			dropNulls(want)
+			if tt.name != "special floats" && !reflect.DeepEqual(back, want) {
+				t.Errorf("parsed back %#v, want %#v", back, want)
+			}
+		})
+	}
+}
+
+// dropNulls removes null values from maps, as TOML has none
+func dropNulls(v interface{}) {
+	switch v := v.(type) {
+	case tomlMap:
+		for k, e := range v {
+			if e == nil {
+				delete(v, k)
+				continue
+			}
+			dropNulls(e)
+		}
+	case tomlList:
+		for _, e := range v {
+			dropNulls(e)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+//go:build !cgo || purego
+
+package dataplane
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// packetQdiscBypass is PACKET_QDISC_BYPASS, which package syscall lacks
+const packetQdiscBypass = 20
+
+// afPacket is a raw AF_PACKET socket bound to one interface
+type afPacket struct {
+	iface     string
+	fd        int
+	txPackets atomic.Uint64
+	rxPackets atomic.Uint64
+	txErrors  atomic.Uint64
+	rxErrors  atomic.Uint64
+}
+
+func htons(v uint16) uint16 {
+	return v<<8 | v>>8
+}
+
+// openPort opens an AF_PACKET socket on iface, which needs CAP_NET_RAW
+func openPort(iface string) (port, error) {
+	ifi, err := net.InterfaceByName(iface)
+	if err != nil {
+		return nil, fmt.Errorf("interface %s: %w", iface, ErrNoSuchInterface)
+	}
+	fd, err := syscall.Socket(syscall.AF_PACKET, syscall.SOCK_RAW, int(htons(syscall.ETH_P_ALL)))
+	if err != nil {
+		return nil, fmt.Errorf("AF_PACKET socket on %s: %w", iface, errnoErr(err))
+	}
+	sa := &syscall.SockaddrLinklayer{Protocol: htons(syscall.ETH_P_ALL), Ifindex: ifi.Index}
+	if err := syscall.Bind(fd, sa); err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("bind AF_PACKET socket to %s: %w", iface, errnoErr(err))
+	}
+
+	// Best effort: skip the qdisc and deepen the buffers
+	syscall.SetsockoptInt(fd, syscall.SOL_PACKET, packetQdiscBypass, 1)
+	syscall.SetsockoptInt(fd, syscall.SOL_SOCKET, syscall.SO_SNDBUF, 4<<20)
+	syscall.SetsockoptInt(fd, syscall.SOL_SOCKET, syscall.SO_RCVBUF, 4<<20)
+
+	// recv returns periodically so a trial's receiver can stop
+	tv := syscall.NsecToTimeval(int64(10 * time.Millisecond))
+	if err := syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &tv); err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("AF_PACKET socket on %s: %w", iface, err)
+	}
+	return &afPacket{iface: iface, fd: fd}, nil
+}
+
+func (p *afPacket) send(frame []byte) (bool, error) {
+	for {
+		_, err := syscall.Write(p.fd, frame)
+		switch {
+		case err == nil:
+			p.txPackets.Add(1)
+			return true, nil
+		case errors.Is(err, syscall.EINTR):
+			continue
+		case errors.Is(err, syscall.ENOBUFS), errors.Is(err, syscall.EAGAIN):
+			p.txErrors.Add(1)
+			return false, nil
+		}
+		p.txErrors.Add(1)
+		return false, fmt.Errorf("send on %s: %w", p.iface, err)
+	}
+}
+
+func (p *afPacket) recv(buf []byte) (int, error) {
+	n, from, err := syscall.Recvfrom(p.fd, buf, 0)
+	switch {
+	case err == nil:
+	case errors.Is(err, syscall.EAGAIN), errors.Is(err, syscall.EINTR):
+		return 0, nil
+	default:
+		p.rxErrors.Add(1)
+		return 0, fmt.Errorf("receive on %s: %w", p.iface, err)
+	}
+	// The socket also sees the frames it sends
+	if ll, ok := from.(*syscall.SockaddrLinklayer); ok && ll.Pkttype == syscall.PACKET_OUTGOING {
+		return 0, nil
+	}
+	p.rxPackets.Add(1)
+	return n, nil
+}
+
+func (p *afPacket) stats() PortStats {
+	return PortStats{
+		Interface: p.iface,
+		TxPackets: p.txPackets.Load(),
+		RxPackets: p.rxPackets.Load(),
+		TxErrors:  p.txErrors.Load(),
+		RxErrors:  p.rxErrors.Load(),
+	}
+}
+
+func (p *afPacket) close() {
+	syscall.Close(p.fd)
+}
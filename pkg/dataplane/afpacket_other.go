@@ -0,0 +1,10 @@
+//go:build (!cgo || purego) && !linux
+
+package dataplane
+
+import "fmt"
+
+// openPort fails: AF_PACKET sockets are Linux-only
+func openPort(iface string) (port, error) {
+	return nil, fmt.Errorf("the pure-Go dataplane needs Linux AF_PACKET sockets")
+}
@@ -0,0 +1,111 @@
+package dataplane
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Bits of rfc2544_capabilities_t.flags, RFC2544_CAP_* in rfc2544.h
+const (
+	capHWTimestamp = 1 << iota
+	capDPDK
+	capAFXDP
+	capY1564
+	capRFC2889
+	capOAM
+	capRecovery
+)
+
+// Capabilities are what the dataplane can do on an interface, for front
+// ends to offer only the tests and settings that will run, and for
+// Configure to refuse the rest before a test starts
+type Capabilities struct {
+	HWTimestamp  bool   `json:"hw_timestamp"`   // The interface stamps frames in hardware
+	DPDK         bool   `json:"dpdk"`           // DPDK built in
+	AFXDP        bool   `json:"af_xdp"`         // AF_XDP built in
+	Y1564        bool   `json:"y1564"`          // Y.1564 service activation
+	RFC2889      bool   `json:"rfc2889"`        // RFC 2889 LAN switch tests
+	OAM          bool   `json:"oam"`            // Ethernet OAM discovery and loopback
+	Recovery     bool   `json:"recovery"`       // System recovery and reset tests
+	MaxStreams   uint32 `json:"max_streams"`    // Flows a trial can generate
+	MaxFrameSize uint32 `json:"max_frame_size"` // Largest frame, FCS included, the platform and port carry
+}
+
+// Unsupported says why the dataplane cannot run a test type, or returns
+// "" if it can
+func (caps Capabilities) Unsupported(t TestType) string {
+	switch t {
+	case TestSystemRecovery, TestReset:
+		if !caps.Recovery {
+			return "the system recovery and reset tests are not available in this dataplane"
+		}
+	case TestY1564Config, TestY1564Perf, TestY1564Full:
+		if !caps.Y1564 {
+			return "Y.1564 is not available in this dataplane"
+		}
+	}
+	return ""
+}
+
+// capabilitiesFromFlags decodes rfc2544_capabilities_t
+func capabilitiesFromFlags(flags, maxStreams, maxFrameSize uint32) Capabilities {
+	return Capabilities{
+		HWTimestamp:  flags&capHWTimestamp != 0,
+		DPDK:         flags&capDPDK != 0,
+		AFXDP:        flags&capAFXDP != 0,
+		Y1564:        flags&capY1564 != 0,
+		RFC2889:      flags&capRFC2889 != 0,
+		OAM:          flags&capOAM != 0,
+		Recovery:     flags&capRecovery != 0,
+		MaxStreams:   maxStreams,
+		MaxFrameSize: maxFrameSize,
+	}
+}
+
+// Check returns an error matching ErrUnsupported for the first setting of
+// cfg the dataplane cannot honour. Hardware timestamps are not checked:
+// without them latency falls back to software time.
+func (caps Capabilities) Check(cfg *Config) error {
+	if cfg.UseDPDK && !caps.DPDK {
+		return fmt.Errorf("DPDK: %w", ErrUnsupported)
+	}
+	if cfg.Streams > caps.MaxStreams {
+		return fmt.Errorf("%d streams: %w (at most %d)", cfg.Streams, ErrUnsupported, caps.MaxStreams)
+	}
+	size := cfg.FrameSize
+	if cfg.IncludeJumbo {
+		size = max(size, jumboFrameSize)
+		if len(cfg.JumboSizes) > 0 {
+			size = max(cfg.FrameSize, slices.Max(cfg.JumboSizes))
+		}
+	}
+	if caps.MaxFrameSize != 0 && size > caps.MaxFrameSize {
+		return fmt.Errorf("%d-byte frames on %s: %w (at most %d bytes)", size, cfg.Interface, ErrUnsupported, caps.MaxFrameSize)
+	}
+	return nil
+}
+
+// checkCapabilities returns what the ports of cfg carry, refusing a
+// configuration they cannot run
+func checkCapabilities(cfg *Config) (Capabilities, error) {
+	caps, err := QueryCapabilities(cfg.Interface, cfg.UseDPDK)
+	if err == nil && cfg.RXInterface != "" && cfg.RXInterface != cfg.Interface {
+		var rx Capabilities
+		rx, err = QueryCapabilities(cfg.RXInterface, cfg.UseDPDK)
+		caps = caps.merge(rx)
+	}
+	if err == nil {
+		err = caps.Check(cfg)
+	}
+	if err != nil {
+		return caps, &Error{Op: "configure", Err: err}
+	}
+	return caps, nil
+}
+
+// merge narrows caps to what a second port also carries
+func (caps Capabilities) merge(other Capabilities) Capabilities {
+	caps.HWTimestamp = caps.HWTimestamp && other.HWTimestamp
+	caps.MaxFrameSize = min(caps.MaxFrameSize, other.MaxFrameSize)
+	return caps
+}
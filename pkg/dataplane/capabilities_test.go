@@ -0,0 +1,53 @@
+package dataplane
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCapabilities(t *testing.T) {
+	caps := capabilitiesFromFlags(capHWTimestamp|capY1564|capRecovery, MaxStreams, 1518)
+	if !caps.HWTimestamp || !caps.Y1564 || !caps.Recovery || caps.DPDK || caps.RFC2889 || caps.MaxFrameSize != 1518 {
+		t.Errorf("decoded %+v", caps)
+	}
+
+	cases := []struct {
+		cfg Config
+		ok  bool
+	}{
+		{Config{FrameSize: 1518}, true},
+		{Config{FrameSize: 1519}, false},
+		{Config{IncludeJumbo: true}, false},
+		{Config{Streams: MaxStreams + 1}, false},
+		{Config{UseDPDK: true}, false},
+		{Config{HWTimestamp: true}, true}, // Falls back to software time
+	}
+	for _, c := range cases {
+		err := caps.Check(&c.cfg)
+		if (err == nil) != c.ok || err != nil && !errors.Is(err, ErrUnsupported) {
+			t.Errorf("%+v: %v", c.cfg, err)
+		}
+	}
+
+	// A 9000-byte MTU carries 9000-byte jumbo frames, not 9216
+	mtu9000 := capabilitiesFromFlags(0, MaxStreams, 9018)
+	if err := mtu9000.Check(&Config{IncludeJumbo: true}); err != nil {
+		t.Errorf("9000-byte jumbo frames: %v", err)
+	}
+	if err := mtu9000.Check(&Config{IncludeJumbo: true, JumboSizes: []uint32{9000, 9216}}); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("9216-byte jumbo frames: %v", err)
+	}
+
+	if caps.Unsupported(TestY1564Full) != "" || caps.Unsupported(TestThroughput) != "" {
+		t.Error("supported test refused")
+	}
+	if (Capabilities{}).Unsupported(TestReset) == "" {
+		t.Error("reset test without Recovery")
+	}
+
+	// A port pair carries what both ports do
+	pair := caps.merge(Capabilities{MaxFrameSize: 9018})
+	if pair.HWTimestamp || pair.MaxFrameSize != 1518 || !pair.Y1564 {
+		t.Errorf("merged %+v", pair)
+	}
+}
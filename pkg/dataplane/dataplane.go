@@ -1,3 +1,5 @@
+//go:build cgo && !purego
+
 // Package dataplane provides CGO bindings to the C dataplane library
 package dataplane
 
@@ -6,6 +8,7 @@ package dataplane
 #cgo LDFLAGS: -L${SRCDIR}/../.. -lrfc2544 -lpthread -lm
 #cgo linux LDFLAGS: -lxdp -lbpf
 
+#include <errno.h>
 #include <stdlib.h>
 #include <stdint.h>
 #include <stdbool.h>
@@ -55,6 +58,14 @@ typedef struct {
     double p99_ns;
 } latency_stats_t;
 
+// Frame order and corrupted frames of a trial
+typedef struct {
+    uint64_t reordered;
+    uint64_t duplicates;
+    uint32_t max_reorder_depth;
+    uint64_t corrupted;
+} frame_order_t;
+
 // Throughput result
 typedef struct {
     uint32_t frame_size;
@@ -66,6 +77,28 @@ typedef struct {
     latency_stats_t latency;
 } throughput_result_t;
 
+// One throughput binary search iteration
+typedef struct {
+    double rate_pct;
+    uint64_t frames_tx;
+    uint64_t frames_rx;
+    double loss_pct;
+    bool passed;
+    frame_order_t order;
+    latency_stats_t latency;
+} throughput_trial_t;
+
+// Throughput binary search state
+typedef struct {
+    double low_pct;
+    double high_pct;
+    double best_rate_pct;
+    uint32_t iterations;
+    uint64_t frames_tested;
+    latency_stats_t latency;
+    throughput_trial_t last;
+} throughput_search_t;
+
 // Frame loss point
 typedef struct {
     double offered_rate_pct;
@@ -73,6 +106,7 @@ typedef struct {
     uint64_t frames_sent;
     uint64_t frames_recv;
     double loss_pct;
+    frame_order_t order;
 } frame_loss_point_t;
 
 // Latency result
@@ -110,6 +144,62 @@ typedef struct {
     bool manual_reset;
 } reset_result_t;
 
+// Fixed-rate trial result
+typedef struct {
+    uint32_t frame_size;
+    double offered_rate_pct;
+    uint64_t frames_sent;
+    uint64_t frames_recv;
+    double loss_pct;
+    double delivered_mbps;
+    double elapsed_sec;
+    latency_stats_t latency;
+    frame_order_t order;
+} fixed_rate_result_t;
+
+// Burst train (microburst test)
+#define RFC2544_MAX_TRAIN_BURSTS 1024
+
+typedef struct {
+    uint32_t burst_frames;
+    uint32_t bursts;
+    uint64_t gap_ns;
+    double rate_pct;
+} burst_train_t;
+
+typedef struct {
+    uint64_t frames_sent;
+    uint64_t frames_recv;
+    uint64_t max_latency_ns;
+    uint64_t first_rx_ns;
+    uint64_t last_rx_ns;
+} burst_count_t;
+
+typedef struct {
+    uint32_t frame_size;
+    uint64_t frames_sent;
+    uint64_t frames_recv;
+    double loss_pct;
+    double elapsed_sec;
+    latency_stats_t latency;
+    uint32_t burst_count;
+    burst_count_t bursts[RFC2544_MAX_TRAIN_BURSTS];
+} burst_train_result_t;
+
+// Y.1564 configuration test step phases
+typedef enum {
+    Y1564_STEP_CIR = 0,
+    Y1564_STEP_EIR = 1,
+    Y1564_STEP_POLICING = 2
+} y1564_step_phase_t;
+
+// Y.1564 step acceptance criteria (negative = not checked)
+typedef struct {
+    double fd_threshold_ms;
+    double fdv_threshold_ms;
+    double flr_threshold_pct;
+} y1564_sac_t;
+
 // Y.1564 SLA parameters
 typedef struct {
     double cir_mbps;
@@ -119,6 +209,8 @@ typedef struct {
     double fd_threshold_ms;
     double fdv_threshold_ms;
     double flr_threshold_pct;
+    y1564_sac_t eir_sac;
+    y1564_sac_t policing_sac;
 } y1564_sla_t;
 
 // Y.1564 Service configuration
@@ -129,6 +221,13 @@ typedef struct {
     uint32_t frame_size;
     uint8_t cos;
     bool enabled;
+    bool policing_step;
+    uint16_t vlan_id;
+    uint8_t pcp;
+    uint16_t s_vlan_id;
+    uint8_t s_pcp;
+    uint8_t ip_version;
+    double extra_mbps;
 } y1564_service_t;
 
 // Y.1564 Step result
@@ -147,12 +246,15 @@ typedef struct {
     bool fd_pass;
     bool fdv_pass;
     bool step_pass;
+    y1564_step_phase_t phase;
 } y1564_step_result_t;
 
 // Y.1564 Configuration test result
 typedef struct {
     uint32_t service_id;
-    y1564_step_result_t steps[4];
+    char service_name[32];
+    y1564_step_result_t steps[6];
+    uint32_t step_count;
     bool service_pass;
 } y1564_config_result_t;
 
@@ -176,6 +278,7 @@ typedef struct {
 // Config structure
 typedef struct {
     char interface[64];
+    char rx_interface[64];
     uint64_t line_rate;
     bool auto_detect_nic;
 
@@ -214,16 +317,373 @@ typedef struct {
     char *dpdk_args;
 } rfc2544_config_t;
 
+// RFC 2889 test types
+typedef enum {
+    RFC2889_FORWARDING_RATE = 0,
+    RFC2889_ADDRESS_CACHING = 1,
+    RFC2889_ADDRESS_LEARNING = 2,
+    RFC2889_BROADCAST_FORWARDING = 3,
+    RFC2889_BROADCAST_LATENCY = 4,
+    RFC2889_CONGESTION_CONTROL = 5,
+    RFC2889_FORWARD_PRESSURE = 6,
+    RFC2889_ERROR_FILTERING = 7,
+    RFC2889_TEST_COUNT = 8
+} rfc2889_test_type_t;
+
+// RFC 2889 traffic patterns
+typedef enum {
+    TRAFFIC_FULLY_MESHED = 0,
+    TRAFFIC_PARTIALLY_MESHED = 1,
+    TRAFFIC_PAIR_WISE = 2,
+    TRAFFIC_ONE_TO_MANY = 3,
+    TRAFFIC_MANY_TO_ONE = 4
+} traffic_pattern_t;
+
+// RFC 2889 port configuration
+typedef struct {
+    char interface[64];
+    uint8_t mac_base[6];
+    uint32_t mac_count;
+    bool is_ingress;
+    bool is_egress;
+} rfc2889_port_t;
+
+// RFC 2889 test configuration
+typedef struct {
+    rfc2889_test_type_t test_type;
+    traffic_pattern_t pattern;
+    uint32_t port_count;
+    rfc2889_port_t ports[64];
+    uint32_t frame_size;
+    uint32_t trial_duration_sec;
+    uint32_t warmup_sec;
+    uint32_t address_count;
+    double acceptable_loss_pct;
+} rfc2889_config_t;
+
+// RFC 2889 forwarding rate result
+typedef struct {
+    uint32_t frame_size;
+    uint32_t port_count;
+    traffic_pattern_t pattern;
+    double max_rate_pct;
+    double max_rate_fps;
+    double aggregate_rate_mbps;
+    uint64_t frames_tx;
+    uint64_t frames_rx;
+    double loss_pct;
+    uint32_t flow_count;
+    uint32_t ingress_ports;
+    uint32_t egress_ports;
+} rfc2889_fwd_result_t;
+
+// Section 11 test modifiers
+typedef struct {
+    double broadcast_pct;
+} modifier_config_t;
+
+// Section 12 address pair rotation
+typedef struct {
+    uint8_t src_mac[6];
+    uint8_t dst_mac[6];
+    uint32_t src_ip;
+    uint32_t dst_ip;
+} address_pair_t;
+
+typedef struct {
+    uint32_t pair_count;
+    const address_pair_t *pairs;
+} address_config_t;
+
+typedef struct {
+    uint32_t addresses;
+    uint32_t rate;
+    double duration_sec;
+} address_learn_stats_t;
+
+// Multi-stream traffic
+typedef struct {
+    uint64_t frames_sent;
+    uint64_t frames_recv;
+    double loss_pct;
+    latency_stats_t latency;
+} stream_stats_t;
+
+// Frame encapsulation
+typedef enum {
+    FRAMING_ETHERNET_II = 0,
+    FRAMING_LLC_SNAP = 1
+} framing_mode_t;
+
+typedef struct {
+    framing_mode_t mode;
+    uint16_t ethertype;
+} framing_config_t;
+
+typedef struct {
+    uint16_t s_tpid;
+    uint16_t s_vlan_id;
+    uint8_t s_pcp;
+    uint16_t c_tpid;
+} qinq_config_t;
+
+#define MAX_MPLS_LABELS 8
+
+typedef struct {
+    uint32_t label;
+    uint8_t exp;
+    uint8_t ttl;
+} mpls_label_t;
+
+typedef struct {
+    mpls_label_t labels[MAX_MPLS_LABELS];
+    uint32_t count;
+    bool exp_from_cos;
+} mpls_config_t;
+
+// User-defined frame headers
+#define MAX_TEMPLATE_HEADER 128
+#define TEMPLATE_FILL_SRC_MAC 0x01
+#define TEMPLATE_FILL_DST_MAC 0x02
+
+typedef struct {
+    uint8_t header[MAX_TEMPLATE_HEADER];
+    uint16_t header_len;
+    int16_t ipv4_offset;
+    int16_t ipv6_offset;
+    int16_t udp_offset;
+    uint8_t flags;
+} header_template_t;
+
+// Control-plane policing stress
+typedef struct {
+    uint32_t dut_ip;
+    uint8_t dut_mac[6];
+    uint32_t icmp_pps;
+    uint32_t arp_pps;
+    uint32_t bgp_pps;
+} cpp_config_t;
+
+typedef struct {
+    uint64_t icmp_sent;
+    uint64_t icmp_replies;
+    uint64_t arp_sent;
+    uint64_t arp_replies;
+    uint64_t bgp_sent;
+    uint64_t bgp_replies;
+} cpp_stats_t;
+
+// Latency probe alongside the load
+typedef struct {
+    uint32_t pps;
+    uint8_t pcp;
+    uint8_t dscp;
+} latency_probe_config_t;
+
+typedef struct {
+    uint64_t frames_sent;
+    uint64_t frames_recv;
+    double loss_pct;
+    latency_stats_t latency;
+} latency_probe_stats_t;
+
+// Addresses, UDP ports and marking of the built-in frames
+typedef struct {
+    uint8_t src_mac[6];
+    uint8_t dst_mac[6];
+    uint32_t src_ip;
+    uint32_t dst_ip;
+    uint32_t src_ip_count;
+    uint32_t dst_ip_count;
+    uint16_t src_port;
+    uint16_t dst_port;
+    uint16_t src_port_count;
+    uint16_t dst_port_count;
+    uint8_t dscp;
+} header_fields_t;
+
+// Padding content of generated frames
+typedef enum {
+    PAYLOAD_INCREMENTING = 0,
+    PAYLOAD_ZEROS = 1,
+    PAYLOAD_ONES = 2,
+    PAYLOAD_RANDOM = 3,
+    PAYLOAD_PRBS31 = 4,
+    PAYLOAD_CUSTOM = 5
+} payload_pattern_kind_t;
+
+#define MAX_PAYLOAD_PATTERN 256
+
+typedef struct {
+    payload_pattern_kind_t kind;
+    uint64_t seed;
+    uint32_t len;
+    uint8_t bytes[MAX_PAYLOAD_PATTERN];
+} payload_pattern_t;
+
+typedef struct {
+    char tx_path[256];
+    char rx_path[256];
+    uint32_t limit;
+    uint32_t snaplen;
+} capture_config_t;
+
+typedef struct {
+    uint64_t tx_frames;
+    uint64_t rx_frames;
+} capture_stats_t;
+
+// IP version of the built-in frames
+typedef enum {
+    IP_MODE_V4 = 0,
+    IP_MODE_V6 = 1,
+    IP_MODE_DUAL = 2
+} ip_mode_t;
+
+typedef struct {
+    uint8_t src_addr[16];
+    uint8_t dst_addr[16];
+    uint8_t traffic_class;
+    uint32_t flow_label;
+    uint8_t hop_limit;
+} ipv6_config_t;
+
+typedef struct {
+    ip_mode_t mode;
+    ipv6_config_t ipv6;
+    uint8_t v6_share_pct;
+} ip_stack_config_t;
+
+typedef struct {
+    uint64_t frames_sent;
+    uint64_t frames_recv;
+    double loss_pct;
+    latency_stats_t latency;
+} ip_family_stats_t;
+
+// Live counters
+typedef struct {
+    uint32_t frame_size;
+    double offered_rate_pct;
+    uint64_t tx_packets;
+    uint64_t tx_bytes;
+    uint64_t rx_packets;
+    uint64_t rx_bytes;
+    uint64_t trials;
+    double last_loss_pct;
+    latency_stats_t last_latency;
+    frame_order_t last_order;
+    bool in_trial;
+    uint32_t trial_warmup_sec;
+    uint32_t trial_duration_sec;
+    uint64_t trial_elapsed_ns;
+} live_stats_t;
+
+// Per-port counters
+typedef struct {
+    char interface[64];
+    uint64_t tx_packets;
+    uint64_t rx_packets;
+    uint64_t tx_errors;
+    uint64_t rx_errors;
+} port_stats_t;
+
+// Ethernet OAM remote loopback peers
+#define OAM_MAX_PEERS 16
+#define OAM_CAP_8023AH    0x01
+#define OAM_CAP_REMOTE_LB 0x02
+#define OAM_CAP_Y1731_LB  0x04
+
+typedef struct {
+    uint8_t mac[6];
+    uint32_t capabilities;
+    bool in_loopback;
+    uint8_t meg_level;
+} oam_peer_t;
+
+// NIC capabilities
+typedef struct {
+    char name[64];
+    uint64_t link_speed;
+    bool supports_hw_ts;
+    bool supports_xdp;
+    bool is_up;
+    uint32_t mtu;
+    uint8_t mac[6];
+    char operstate[16];
+    char driver[32];
+    char bus_info[32];
+    char fw_version[32];
+    bool supports_dpdk;
+    char dpdk_driver[16];
+} nic_info_t;
+
+// Library version
+typedef struct {
+    uint32_t major;
+    uint32_t minor;
+    uint32_t patch;
+    uint32_t abi;
+    uint32_t abi_compat;
+    uint32_t features;
+} rfc2544_version_t;
+
+// Dataplane capabilities
+typedef struct {
+    uint32_t flags;
+    uint32_t max_streams;
+    uint32_t max_frame_size;
+} rfc2544_capabilities_t;
+
 // External C functions
+extern void rfc2544_get_version(rfc2544_version_t *version);
+extern int rfc2544_get_capabilities(const char *interface, bool use_dpdk, rfc2544_capabilities_t *caps);
 extern int rfc2544_init(rfc2544_ctx_t **ctx, const char *interface);
 extern int rfc2544_configure(rfc2544_ctx_t *ctx, const rfc2544_config_t *config);
 extern int rfc2544_run(rfc2544_ctx_t *ctx);
 extern void rfc2544_cancel(rfc2544_ctx_t *ctx);
+extern void rfc2544_clear_cancel(rfc2544_ctx_t *ctx);
+extern int rfc2544_set_acceptable_loss(rfc2544_ctx_t *ctx, double loss_pct);
 extern test_state_t rfc2544_get_state(const rfc2544_ctx_t *ctx);
 extern void rfc2544_cleanup(rfc2544_ctx_t *ctx);
+extern int rfc2544_modifiers_configure(rfc2544_ctx_t *ctx, const modifier_config_t *config);
+extern int rfc2544_addresses_configure(rfc2544_ctx_t *ctx, const address_config_t *config);
+extern int rfc2544_addresses_set_learn_rate(rfc2544_ctx_t *ctx, uint32_t rate);
+extern int rfc2544_addresses_get_learn_stats(const rfc2544_ctx_t *ctx, address_learn_stats_t *stats);
+extern int rfc2544_framing_configure(rfc2544_ctx_t *ctx, const framing_config_t *config);
+extern int rfc2544_verify_payload_configure(rfc2544_ctx_t *ctx, bool enabled);
+extern int rfc2544_streams_configure(rfc2544_ctx_t *ctx, uint32_t stream_count);
+extern int rfc2544_vlan_configure(rfc2544_ctx_t *ctx, uint16_t vlan_id, uint8_t pcp);
+extern int rfc2544_qinq_configure(rfc2544_ctx_t *ctx, const qinq_config_t *config);
+extern int rfc2544_mpls_configure(rfc2544_ctx_t *ctx, const mpls_config_t *config);
+extern int rfc2544_speed_profile_configure(rfc2544_ctx_t *ctx, const char *name);
+extern uint64_t rfc2544_get_line_rate_ctx(const rfc2544_ctx_t *ctx);
+extern int rfc2544_get_stream_stats(rfc2544_ctx_t *ctx, stream_stats_t *stats, uint32_t max_count);
+extern int rfc2544_template_configure(rfc2544_ctx_t *ctx, const header_template_t *tpl);
+extern int rfc2544_cpp_configure(rfc2544_ctx_t *ctx, const cpp_config_t *config);
+extern int rfc2544_cpp_get_stats(const rfc2544_ctx_t *ctx, cpp_stats_t *stats);
+extern int rfc2544_latency_probe_configure(rfc2544_ctx_t *ctx, const latency_probe_config_t *config);
+extern int rfc2544_latency_probe_get_stats(const rfc2544_ctx_t *ctx, latency_probe_stats_t *stats);
+extern int rfc2544_ip_configure(rfc2544_ctx_t *ctx, const ip_stack_config_t *config);
+extern int rfc2544_headers_configure(rfc2544_ctx_t *ctx, const header_fields_t *config);
+extern int rfc2544_payload_pattern_configure(rfc2544_ctx_t *ctx, const payload_pattern_t *pattern);
+extern int rfc2544_ip_family_get_stats(const rfc2544_ctx_t *ctx, ip_family_stats_t *v4, ip_family_stats_t *v6);
+extern int rfc2544_get_live_stats(rfc2544_ctx_t *ctx, live_stats_t *stats);
+extern uint64_t rfc2544_get_heartbeat(const rfc2544_ctx_t *ctx);
+extern int rfc2544_get_port_stats(rfc2544_ctx_t *ctx, port_stats_t *stats, uint32_t max_count);
+extern int rfc2544_get_latency_samples(rfc2544_ctx_t *ctx, uint64_t *samples, uint32_t max_count);
+extern int rfc2544_latency_samples_configure(rfc2544_ctx_t *ctx, uint32_t capacity);
+extern int rfc2544_capture_configure(rfc2544_ctx_t *ctx, const capture_config_t *config);
+extern int rfc2544_capture_get_stats(const rfc2544_ctx_t *ctx, capture_stats_t *stats);
 
 extern int rfc2544_throughput_test(rfc2544_ctx_t *ctx, uint32_t frame_size,
                                    throughput_result_t *result, uint32_t *result_count);
+extern void rfc2544_throughput_search_init(rfc2544_ctx_t *ctx, throughput_search_t *search);
+extern int rfc2544_throughput_search_step(rfc2544_ctx_t *ctx, uint32_t frame_size,
+                                          throughput_search_t *search);
+extern void rfc2544_throughput_search_result(rfc2544_ctx_t *ctx, uint32_t frame_size,
+                                             const throughput_search_t *search,
+                                             throughput_result_t *result);
 extern int rfc2544_latency_test(rfc2544_ctx_t *ctx, uint32_t frame_size,
                                 double load_pct, latency_result_t *result);
 extern int rfc2544_frame_loss_test(rfc2544_ctx_t *ctx, uint32_t frame_size,
@@ -235,11 +695,31 @@ extern int rfc2544_system_recovery_test(rfc2544_ctx_t *ctx, uint32_t frame_size,
                                         recovery_result_t *result);
 extern int rfc2544_reset_test(rfc2544_ctx_t *ctx, uint32_t frame_size,
                               reset_result_t *result);
+extern int rfc2544_fixed_rate_trial(rfc2544_ctx_t *ctx, uint32_t frame_size, double rate_pct,
+                                    uint32_t duration_sec, fixed_rate_result_t *result);
+extern int rfc2544_burst_train(rfc2544_ctx_t *ctx, uint32_t frame_size, const burst_train_t *train,
+                               burst_train_result_t *result);
 
 extern uint64_t rfc2544_get_line_rate(const char *interface);
 extern uint64_t rfc2544_calc_pps(uint64_t line_rate, uint32_t frame_size);
 extern void rfc2544_default_config(rfc2544_config_t *config);
 
+// Interface detection functions
+extern int rfc2544_detect_nic(const char *interface, nic_info_t *info);
+extern int rfc2544_list_interfaces(nic_info_t *interfaces, uint32_t max_count);
+extern int rfc2544_recommend_interface(nic_info_t *info);
+
+// RFC 2889 functions
+extern void rfc2889_default_config(rfc2889_config_t *config);
+extern int rfc2889_forwarding_test(rfc2544_ctx_t *ctx, const rfc2889_config_t *config,
+                                   rfc2889_fwd_result_t *result);
+
+// Ethernet OAM functions
+extern int oam_discover(rfc2544_ctx_t *ctx, uint8_t meg_level, uint32_t timeout_ms,
+                        oam_peer_t *peers, uint32_t max_peers, uint32_t *count);
+extern int oam_remote_loopback(rfc2544_ctx_t *ctx, const uint8_t *peer_mac, bool enable,
+                               uint32_t timeout_ms);
+
 // Y.1564 functions
 extern int y1564_config_test(rfc2544_ctx_t *ctx, const y1564_service_t *service,
                              y1564_config_result_t *result);
@@ -251,417 +731,788 @@ extern int y1564_multi_service_test(rfc2544_ctx_t *ctx, const y1564_service_t *s
 */
 import "C"
 import (
+	"context"
+	"encoding/binary"
 	"fmt"
+	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 	"unsafe"
 )
 
-// TestType mirrors C test_type_t
-type TestType int
-
-const (
-	TestThroughput TestType = iota
-	TestLatency
-	TestFrameLoss
-	TestBackToBack
-	TestSystemRecovery
-	TestReset
-	TestY1564Config
-	TestY1564Perf
-	TestY1564Full
-)
-
-// TestState mirrors C test_state_t
-type TestState int
-
-const (
-	StateIdle TestState = iota
-	StateRunning
-	StateCompleted
-	StateFailed
-	StateCancelled
-)
+// Backend names the dataplane the binary was built with
+const Backend = "librfc2544"
+
+// PureGo reports whether the pure-Go dataplane is in use
+const PureGo = false
+
+// Context wraps the C rfc2544_ctx_t.
+//
+// The C library does not lock most of a context's state, so a Context
+// runs its calls one at a time on a goroutine of its own that owns the C
+// context from NewContext to Close. Any goroutine may use a Context, and
+// its methods fall in two groups:
+//
+//   - Probes: State, LiveStats, Heartbeat, GetStats, GetState, Subscribe
+//     and Cancel read or signal what the C library keeps under its own
+//     locks. They run at once, beside a test, for web handlers and the
+//     TUI to poll; after Close they return zero values.
+//   - Calls: every other method. A call waits for the one before it to
+//     return, so a web request made while a test runs queues behind it.
+//     Calling one from a callback of another, e.g. the step function of
+//     RunThroughputSearch, panics, as it could only wait for itself.
+//     After Close, calls fail with ErrClosed.
+//
+// The package's functions without a Context, e.g. DetectNIC, are safe
+// from any goroutine.
+type Context struct {
+	ctx       *C.rfc2544_ctx_t
+	calls     *runner
+	probes    sync.RWMutex // Held by probes; Close clears ctx under it
+	mu        sync.Mutex   // Held by calls, for Go-side state read outside them
+	subs      subscribers
+	config    Config
+	frameSize uint32
+	profile   SpeedProfile
+	caps      Capabilities
+	abandoned atomic.Bool // Given up on by a Watchdog while a call is stuck in it
 
-// LatencyStats contains latency measurements
-type LatencyStats struct {
-	Count    uint64
-	MinNs    float64
-	MaxNs    float64
-	AvgNs    float64
-	JitterNs float64
-	P50Ns    float64
-	P95Ns    float64
-	P99Ns    float64
+	// Result buffers the C library fills, reused by every trial
+	samples []uint64
+	stats   []C.stream_stats_t
 }
 
-// ThroughputResult from binary search test
-type ThroughputResult struct {
-	FrameSize    uint32
-	MaxRatePct   float64
-	MaxRateMbps  float64
-	MaxRatePps   float64
-	FramesTested uint64
-	Iterations   uint32
-	Latency      LatencyStats
+// library is the version of the linked librfc2544, read once
+var library = sync.OnceValue(func() *LibraryVersion {
+	var v C.rfc2544_version_t
+	C.rfc2544_get_version(&v)
+	return newLibraryVersion(uint32(v.major), uint32(v.minor), uint32(v.patch),
+		uint32(v.abi), uint32(v.abi_compat), uint32(v.features))
+})
+
+// Library returns the version of the C dataplane library in use
+func Library() *LibraryVersion {
+	return library()
 }
 
-// FrameLossPoint for a single load level
-type FrameLossPoint struct {
-	OfferedRatePct float64
-	ActualRateMbps float64
-	FramesSent     uint64
-	FramesRecv     uint64
-	LossPct        float64
+// NewContext creates a new RFC2544 test context. It refuses a library
+// whose ABI does not match the one this package mirrors.
+func NewContext(iface string) (*Context, error) {
+	if err := Library().Check(); err != nil {
+		return nil, &Error{Op: "init", Err: err}
+	}
+	c := &Context{}
+	c.calls = newRunner(&c.mu)
+	err := c.calls.exec("NewContext", func() error {
+		cIface := C.CString(iface)
+		defer C.free(unsafe.Pointer(cIface))
+
+		var cctx *C.rfc2544_ctx_t
+		ret := C.rfc2544_init(&cctx, cIface)
+		if ret < 0 {
+			c.calls.close()
+			return codeError("init", int(ret))
+		}
+		c.ctx = cctx
+		c.profile = ProfileFor(uint64(C.rfc2544_get_line_rate_ctx(cctx)))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
 }
 
-// LatencyResult from latency test
-type LatencyResult struct {
-	FrameSize      uint32
-	OfferedRatePct float64
-	Latency        LatencyStats
-}
+// Configure applies test configuration
+func (c *Context) Configure(cfg *Config) error {
+	caps, err := checkCapabilities(cfg)
+	if err != nil {
+		return err
+	}
+	return c.calls.exec("Configure", func() error {
+		c.caps = caps
+
+		var ccfg C.rfc2544_config_t
+		C.rfc2544_default_config(&ccfg)
+
+		// Copy interface name
+		cIface := C.CString(cfg.Interface)
+		defer C.free(unsafe.Pointer(cIface))
+		C.strncpy(&ccfg._interface[0], cIface, 63)
+		if cfg.RXInterface != "" {
+			cRX := C.CString(cfg.RXInterface)
+			defer C.free(unsafe.Pointer(cRX))
+			C.strncpy(&ccfg.rx_interface[0], cRX, 63)
+		}
 
-// BurstResult from back-to-back test
-type BurstResult struct {
-	FrameSize     uint32
-	MaxBurst      uint64
-	BurstDuration float64
-	Trials        uint32
-}
+		ccfg.line_rate = C.uint64_t(cfg.LineRate)
+		ccfg.auto_detect_nic = C.bool(cfg.AutoDetect)
+		ccfg.test_type = C.test_type_t(cfg.TestType)
+		ccfg.frame_size = C.uint32_t(cfg.FrameSize)
+		ccfg.include_jumbo = C.bool(cfg.IncludeJumbo)
+		ccfg.trial_duration_sec = C.uint32_t(cfg.TrialDuration.Seconds())
+		ccfg.warmup_sec = C.uint32_t(cfg.WarmupPeriod.Seconds())
+		ccfg.initial_rate_pct = C.double(cfg.InitialRatePct)
+		ccfg.resolution_pct = C.double(cfg.ResolutionPct)
+		ccfg.max_iterations = C.uint32_t(cfg.MaxIterations)
+		ccfg.acceptable_loss = C.double(cfg.AcceptableLoss)
+		ccfg.hw_timestamp = C.bool(cfg.HWTimestamp)
+		ccfg.measure_latency = C.bool(cfg.MeasureLatency)
+		ccfg.use_pacing = C.bool(cfg.UsePacing)
+		ccfg.batch_size = C.uint32_t(cfg.BatchSize)
+		ccfg.use_dpdk = C.bool(cfg.UseDPDK)
+
+		var dpdkArgsPtr *C.char
+		if cfg.DPDKArgs != "" {
+			dpdkArgsPtr = C.CString(cfg.DPDKArgs)
+			ccfg.dpdk_args = dpdkArgsPtr
+		}
 
-// RecoveryResult from RFC 2544 Section 26.5 System Recovery test
-type RecoveryResult struct {
-	FrameSize       uint32
-	OverloadRatePct float64
-	RecoveryRatePct float64
-	OverloadSec     uint32
-	RecoveryTimeMs  float64
-	FramesLost      uint64
-	Trials          uint32
-}
+		ret := C.rfc2544_configure(c.ctx, &ccfg)
 
-// ResetResult from RFC 2544 Section 26.6 Reset test
-type ResetResult struct {
-	FrameSize    uint32
-	ResetTimeMs  float64
-	FramesLost   uint64
-	Trials       uint32
-	ManualReset  bool
-}
+		// Free DPDK args string after configure copies it
+		if dpdkArgsPtr != nil {
+			C.free(unsafe.Pointer(dpdkArgsPtr))
+		}
+
+		if ret < 0 {
+			return codeError("configure", int(ret))
+		}
+
+		ret = C.rfc2544_verify_payload_configure(c.ctx, C.bool(cfg.VerifyPayload))
+		if ret < 0 {
+			return codeError("payload verification configure", int(ret))
+		}
+
+		ret = C.rfc2544_streams_configure(c.ctx, C.uint32_t(cfg.Streams))
+		if ret < 0 {
+			return codeError("streams configure", int(ret))
+		}
+
+		ret = C.rfc2544_vlan_configure(c.ctx, C.uint16_t(cfg.VLANID), C.uint8_t(cfg.PCP))
+		if ret < 0 {
+			return codeError("VLAN configure", int(ret))
+		}
+
+		cqinq := C.qinq_config_t{
+			s_tpid:    C.uint16_t(cfg.OuterTPID),
+			s_vlan_id: C.uint16_t(cfg.OuterVLANID),
+			s_pcp:     C.uint8_t(cfg.OuterPCP),
+			c_tpid:    C.uint16_t(cfg.TPID),
+		}
+		ret = C.rfc2544_qinq_configure(c.ctx, &cqinq)
+		if ret < 0 {
+			return codeError("QinQ configure", int(ret))
+		}
+
+		if err := ValidateMPLS(cfg.MPLSLabels); err != nil {
+			return fmt.Errorf("configure failed: %w", err)
+		}
+		cmpls := C.mpls_config_t{
+			count:        C.uint32_t(len(cfg.MPLSLabels)),
+			exp_from_cos: C.bool(cfg.MPLSEXPFromCoS),
+		}
+		for i, l := range cfg.MPLSLabels {
+			cmpls.labels[i] = C.mpls_label_t{label: C.uint32_t(l.Label), exp: C.uint8_t(l.EXP), ttl: C.uint8_t(l.TTL)}
+		}
+		ret = C.rfc2544_mpls_configure(c.ctx, &cmpls)
+		if ret < 0 {
+			return codeError("MPLS configure", int(ret))
+		}
 
-// Y1564SLA contains SLA parameters for Y.1564 testing
-type Y1564SLA struct {
-	CIRMbps         float64
-	EIRMbps         float64
-	CBSBytes        uint32
-	EBSBytes        uint32
-	FDThresholdMs   float64
-	FDVThresholdMs  float64
-	FLRThresholdPct float64
+		cprobe := C.latency_probe_config_t{
+			pps:  C.uint32_t(cfg.LatencyProbe.PPS),
+			pcp:  C.uint8_t(cfg.LatencyProbe.PCP),
+			dscp: C.uint8_t(cfg.LatencyProbe.DSCP),
+		}
+		ret = C.rfc2544_latency_probe_configure(c.ctx, &cprobe)
+		if ret < 0 {
+			return codeError("latency probe configure", int(ret))
+		}
+
+		if err := ValidateIP(cfg.IP); err != nil {
+			return fmt.Errorf("configure failed: %w", err)
+		}
+		cip := C.ip_stack_config_t{
+			mode:         C.ip_mode_t(cfg.IP.Mode),
+			v6_share_pct: C.uint8_t(cfg.IP.V6SharePct),
+		}
+		copy(unsafe.Slice((*byte)(unsafe.Pointer(&cip.ipv6.src_addr[0])), 16), cfg.IP.IPv6.Src.To16())
+		copy(unsafe.Slice((*byte)(unsafe.Pointer(&cip.ipv6.dst_addr[0])), 16), cfg.IP.IPv6.Dst.To16())
+		cip.ipv6.traffic_class = C.uint8_t(cfg.IP.IPv6.TrafficClass)
+		cip.ipv6.flow_label = C.uint32_t(cfg.IP.IPv6.FlowLabel)
+		cip.ipv6.hop_limit = C.uint8_t(cfg.IP.IPv6.HopLimit)
+		ret = C.rfc2544_ip_configure(c.ctx, &cip)
+		if ret < 0 {
+			return codeError("IP configure", int(ret))
+		}
+
+		if err := ValidateHeaders(cfg.Headers); err != nil {
+			return fmt.Errorf("configure failed: %w", err)
+		}
+		h := cfg.Headers
+		chdr := C.header_fields_t{
+			src_ip_count:   C.uint32_t(h.SrcIP.Count),
+			dst_ip_count:   C.uint32_t(h.DstIP.Count),
+			src_port:       C.uint16_t(h.SrcPort.First),
+			dst_port:       C.uint16_t(h.DstPort.First),
+			src_port_count: C.uint16_t(h.SrcPort.Count),
+			dst_port_count: C.uint16_t(h.DstPort.Count),
+			dscp:           C.uint8_t(h.DSCP),
+		}
+		for j := range h.SrcMAC {
+			chdr.src_mac[j] = C.uint8_t(h.SrcMAC[j])
+		}
+		for j := range h.DstMAC {
+			chdr.dst_mac[j] = C.uint8_t(h.DstMAC[j])
+		}
+		if ip := h.SrcIP.First.To4(); ip != nil {
+			chdr.src_ip = C.uint32_t(binary.NativeEndian.Uint32(ip))
+		}
+		if ip := h.DstIP.First.To4(); ip != nil {
+			chdr.dst_ip = C.uint32_t(binary.NativeEndian.Uint32(ip))
+		}
+		ret = C.rfc2544_headers_configure(c.ctx, &chdr)
+		if ret < 0 {
+			return codeError("header fields configure", int(ret))
+		}
+
+		if err := ValidatePattern(cfg.Payload); err != nil {
+			return fmt.Errorf("configure failed: %w", err)
+		}
+		cpat := C.payload_pattern_t{
+			kind: C.payload_pattern_kind_t(cfg.Payload.Kind),
+			seed: C.uint64_t(cfg.Payload.Seed),
+			len:  C.uint32_t(len(cfg.Payload.Bytes)),
+		}
+		for i, b := range cfg.Payload.Bytes {
+			cpat.bytes[i] = C.uint8_t(b)
+		}
+		ret = C.rfc2544_payload_pattern_configure(c.ctx, &cpat)
+		if ret < 0 {
+			return codeError("payload pattern configure", int(ret))
+		}
+
+		if err := ValidateLatencySamples(cfg.LatencySamples); err != nil {
+			return fmt.Errorf("configure failed: %w", err)
+		}
+		ret = C.rfc2544_latency_samples_configure(c.ctx, C.uint32_t(cfg.LatencySamples))
+		if ret < 0 {
+			return codeError("latency samples configure", int(ret))
+		}
+
+		if err := ValidateCapture(cfg.Capture); err != nil {
+			return fmt.Errorf("configure failed: %w", err)
+		}
+		ccap := C.capture_config_t{
+			limit:   C.uint32_t(cfg.Capture.Limit),
+			snaplen: C.uint32_t(cfg.Capture.Snaplen),
+		}
+		for i, b := range []byte(cfg.Capture.TX) {
+			ccap.tx_path[i] = C.char(b)
+		}
+		for i, b := range []byte(cfg.Capture.RX) {
+			ccap.rx_path[i] = C.char(b)
+		}
+		ret = C.rfc2544_capture_configure(c.ctx, &ccap)
+		if ret < 0 {
+			return codeError("capture configure", int(ret))
+		}
+
+		// The C dataplane paces from the line rate it detected
+		profile, err := selectProfile(cfg.SpeedProfile, uint64(C.rfc2544_get_line_rate_ctx(c.ctx)))
+		if err != nil {
+			return err
+		}
+		cProfile := C.CString(profile.Name)
+		defer C.free(unsafe.Pointer(cProfile))
+		ret = C.rfc2544_speed_profile_configure(c.ctx, cProfile)
+		if ret < 0 {
+			return codeError("speed profile configure", int(ret))
+		}
+		c.profile = profile
+
+		return nil
+	})
 }
 
-// Y1564Service represents a service configuration for Y.1564 testing
-type Y1564Service struct {
-	ServiceID   uint32
-	ServiceName string
-	SLA         Y1564SLA
-	FrameSize   uint32
-	CoS         uint8
-	Enabled     bool
+// SetModifiers applies RFC 2544 Section 11 modifiers to subsequent trials.
+// A zero value disables all modifiers.
+func (c *Context) SetModifiers(m Modifiers) error {
+	return c.calls.exec("SetModifiers", func() error {
+		cmod := C.modifier_config_t{
+			broadcast_pct: C.double(m.BroadcastPct),
+		}
+
+		ret := C.rfc2544_modifiers_configure(c.ctx, &cmod)
+		if ret < 0 {
+			return codeError("modifiers configure", int(ret))
+		}
+
+		return nil
+	})
 }
 
-// Y1564StepResult from a Y.1564 configuration test step
-type Y1564StepResult struct {
-	Step            uint32
-	OfferedRatePct  float64
-	AchievedRateMbps float64
-	FramesTx        uint64
-	FramesRx        uint64
-	FLRPct          float64
-	FDAvgMs         float64
-	FDMinMs         float64
-	FDMaxMs         float64
-	FDVMs           float64
-	FLRPass         bool
-	FDPass          bool
-	FDVPass         bool
-	StepPass        bool
+// SetAddressPairs sets how many distinct source/destination address pairs
+// subsequent trials rotate through (RFC 2544 Section 12). 0 or 1 uses a
+// single pair.
+func (c *Context) SetAddressPairs(pairs uint32) error {
+	return c.calls.exec("SetAddressPairs", func() error {
+		caddr := C.address_config_t{
+			pair_count: C.uint32_t(pairs),
+		}
+
+		ret := C.rfc2544_addresses_configure(c.ctx, &caddr)
+		if ret < 0 {
+			return codeError("addresses configure", int(ret))
+		}
+
+		return nil
+	})
 }
 
-// Y1564ConfigResult from Y.1564 service configuration test
-type Y1564ConfigResult struct {
-	ServiceID   uint32
-	Steps       [4]Y1564StepResult
-	ServicePass bool
+// SetAddressTable sets the address pairs subsequent trials rotate through
+// round-robin, e.g. drawn from address pools, in place of the pairs
+// derived from the base addresses
+func (c *Context) SetAddressTable(pairs []AddressPair) error {
+	return c.calls.exec("SetAddressTable", func() error {
+		if len(pairs) == 0 {
+			return fmt.Errorf("no address pairs")
+		}
+		// The C library copies the table, which must not be in Go memory
+		table := (*C.address_pair_t)(C.calloc(C.size_t(len(pairs)), C.size_t(unsafe.Sizeof(C.address_pair_t{}))))
+		if table == nil {
+			return fmt.Errorf("out of memory for %d address pairs", len(pairs))
+		}
+		defer C.free(unsafe.Pointer(table))
+		entries := unsafe.Slice(table, len(pairs))
+		for i, p := range pairs {
+			e := &entries[i]
+			if len(p.SrcMAC) == 6 {
+				for j := range e.src_mac {
+					e.src_mac[j] = C.uint8_t(p.SrcMAC[j])
+				}
+			}
+			if len(p.DstMAC) == 6 {
+				for j := range e.dst_mac {
+					e.dst_mac[j] = C.uint8_t(p.DstMAC[j])
+				}
+			}
+			if ip := p.SrcIP.To4(); ip != nil {
+				e.src_ip = C.uint32_t(binary.NativeEndian.Uint32(ip))
+			}
+			if ip := p.DstIP.To4(); ip != nil {
+				e.dst_ip = C.uint32_t(binary.NativeEndian.Uint32(ip))
+			}
+		}
+
+		caddr := C.address_config_t{
+			pair_count: C.uint32_t(len(pairs)),
+			pairs:      table,
+		}
+		ret := C.rfc2544_addresses_configure(c.ctx, &caddr)
+		if ret < 0 {
+			return codeError("addresses configure", int(ret))
+		}
+
+		return nil
+	})
 }
 
-// Y1564PerfResult from Y.1564 service performance test
-type Y1564PerfResult struct {
-	ServiceID   uint32
-	DurationSec uint32
-	FramesTx    uint64
-	FramesRx    uint64
-	FLRPct      float64
-	FDAvgMs     float64
-	FDMinMs     float64
-	FDMaxMs     float64
-	FDVMs       float64
-	FLRPass     bool
-	FDPass      bool
-	FDVPass     bool
-	ServicePass bool
+// SetAddressLearnRate limits how many address pairs per second are
+// introduced before the first trial after the addresses are set, so the
+// DUT's MAC-learning protection does not discard new source MACs. 0
+// introduces them all at once with the test traffic.
+func (c *Context) SetAddressLearnRate(perSec uint32) error {
+	return c.calls.exec("SetAddressLearnRate", func() error {
+		ret := C.rfc2544_addresses_set_learn_rate(c.ctx, C.uint32_t(perSec))
+		if ret < 0 {
+			return codeError("address learn rate", int(ret))
+		}
+		return nil
+	})
 }
 
-// Config for RFC2544 tests
-type Config struct {
-	Interface      string
-	LineRate       uint64
-	AutoDetect     bool
-	TestType       TestType
-	FrameSize      uint32
-	IncludeJumbo   bool
-	TrialDuration  time.Duration
-	WarmupPeriod   time.Duration
-	InitialRatePct float64
-	ResolutionPct  float64
-	MaxIterations  uint32
-	AcceptableLoss float64
-	HWTimestamp    bool
-	MeasureLatency bool
-	UsePacing      bool
-	BatchSize      uint32
-	UseDPDK        bool
-	DPDKArgs       string
+// AddressLearning returns the learning ramp run since the addresses were
+// set, or nil if none has run
+func (c *Context) AddressLearning() *AddressLearning {
+	return value(c.calls, "AddressLearning", func() *AddressLearning {
+		var cs C.address_learn_stats_t
+		C.rfc2544_addresses_get_learn_stats(c.ctx, &cs)
+		if cs.rate == 0 {
+			return nil
+		}
+		return &AddressLearning{
+			Addresses:   uint32(cs.addresses),
+			RatePerSec:  uint32(cs.rate),
+			DurationSec: float64(cs.duration_sec),
+		}
+	})
 }
 
-// Context wraps the C rfc2544_ctx_t
-type Context struct {
-	ctx       *C.rfc2544_ctx_t
-	mu        sync.Mutex
-	stats     Stats
-	config    Config
-	frameSize uint32
+// SetFraming sets the EtherType and encapsulation of frames generated by
+// subsequent trials
+func (c *Context) SetFraming(f Framing) error {
+	return c.calls.exec("SetFraming", func() error {
+		cframing := C.framing_config_t{
+			mode:      C.FRAMING_ETHERNET_II,
+			ethertype: C.uint16_t(f.EtherType),
+		}
+		if f.LLCSNAP {
+			cframing.mode = C.FRAMING_LLC_SNAP
+		}
+
+		ret := C.rfc2544_framing_configure(c.ctx, &cframing)
+		if ret < 0 {
+			return codeError("framing configure", int(ret))
+		}
+		return nil
+	})
 }
 
-// Stats for real-time monitoring
-type Stats struct {
-	TxPackets   uint64
-	TxBytes     uint64
-	RxPackets   uint64
-	RxBytes     uint64
-	CurrentRate float64
-	Progress    float64
-	Timestamp   time.Time
+// SetPacketTemplate sets the headers of frames generated by subsequent
+// trials, overriding the framing
+func (c *Context) SetPacketTemplate(t PacketTemplate) error {
+	return c.calls.exec("SetPacketTemplate", func() error {
+		if len(t.Header) > C.MAX_TEMPLATE_HEADER {
+			return fmt.Errorf("packet template header too long: %d bytes", len(t.Header))
+		}
+		var ctpl C.header_template_t
+		for i, b := range t.Header {
+			ctpl.header[i] = C.uint8_t(b)
+		}
+		ctpl.header_len = C.uint16_t(len(t.Header))
+		ctpl.ipv4_offset = C.int16_t(t.IPv4Offset)
+		ctpl.ipv6_offset = C.int16_t(t.IPv6Offset)
+		ctpl.udp_offset = C.int16_t(t.UDPOffset)
+		if t.FillSrcMAC {
+			ctpl.flags |= C.TEMPLATE_FILL_SRC_MAC
+		}
+		if t.FillDstMAC {
+			ctpl.flags |= C.TEMPLATE_FILL_DST_MAC
+		}
+
+		ret := C.rfc2544_template_configure(c.ctx, &ctpl)
+		if ret < 0 {
+			return codeError("packet template configure", int(ret))
+		}
+		return nil
+	})
 }
 
-// NewContext creates a new RFC2544 test context
-func NewContext(iface string) (*Context, error) {
-	cIface := C.CString(iface)
-	defer C.free(unsafe.Pointer(cIface))
+// SetControlPlaneStress configures control-plane traffic sent alongside
+// subsequent trials and resets its statistics
+func (c *Context) SetControlPlaneStress(cp ControlPlaneStress) error {
+	return c.calls.exec("SetControlPlaneStress", func() error {
+		ccpp := C.cpp_config_t{
+			icmp_pps: C.uint32_t(cp.ICMPPerSec),
+			arp_pps:  C.uint32_t(cp.ARPPerSec),
+			bgp_pps:  C.uint32_t(cp.BGPPerSec),
+		}
+		if ip4 := cp.DUTIP.To4(); ip4 != nil {
+			// Network order: the address bytes as laid out in memory
+			ccpp.dut_ip = C.uint32_t(binary.NativeEndian.Uint32(ip4))
+		}
+		if len(cp.DUTMAC) == 6 {
+			for i := range cp.DUTMAC {
+				ccpp.dut_mac[i] = C.uint8_t(cp.DUTMAC[i])
+			}
+		}
 
-	var cctx *C.rfc2544_ctx_t
-	ret := C.rfc2544_init(&cctx, cIface)
-	if ret < 0 {
-		return nil, fmt.Errorf("init failed: %d", ret)
-	}
+		ret := C.rfc2544_cpp_configure(c.ctx, &ccpp)
+		if ret < 0 {
+			return codeError("control-plane configure", int(ret))
+		}
+		return nil
+	})
+}
 
-	return &Context{ctx: cctx}, nil
+// ControlPlaneStats returns control-plane frames sent and answered since
+// the last SetControlPlaneStress
+func (c *Context) ControlPlaneStats() ControlPlaneStats {
+	return value(c.calls, "ControlPlaneStats", func() ControlPlaneStats {
+		var cs C.cpp_stats_t
+		C.rfc2544_cpp_get_stats(c.ctx, &cs)
+		return ControlPlaneStats{
+			ICMPSent:    uint64(cs.icmp_sent),
+			ICMPReplies: uint64(cs.icmp_replies),
+			ARPSent:     uint64(cs.arp_sent),
+			ARPReplies:  uint64(cs.arp_replies),
+			BGPSent:     uint64(cs.bgp_sent),
+			BGPReplies:  uint64(cs.bgp_replies),
+		}
+	})
 }
 
-// Configure applies test configuration
-func (c *Context) Configure(cfg *Config) error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+// CaptureStats returns the frames written to the capture files since
+// Configure
+func (c *Context) CaptureStats() CaptureStats {
+	return value(c.calls, "CaptureStats", func() CaptureStats {
+		var cs C.capture_stats_t
+		C.rfc2544_capture_get_stats(c.ctx, &cs)
+		return CaptureStats{TxFrames: uint64(cs.tx_frames), RxFrames: uint64(cs.rx_frames)}
+	})
+}
 
-	var ccfg C.rfc2544_config_t
-	C.rfc2544_default_config(&ccfg)
+// Run runs the configured test until it completes or ctx ends
+func (c *Context) Run(ctx context.Context) error {
+	return c.calls.exec("Run", func() error {
+		defer c.watch(ctx)()
+		defer c.subs.poll(c.LiveStats)()
+		ret := C.rfc2544_run(c.ctx)
+		if ctx.Err() != nil {
+			return cancelled(ctx)
+		}
+		if ret < 0 {
+			return codeError("run", int(ret))
+		}
+		return nil
+	})
+}
 
-	// Copy interface name
-	cIface := C.CString(cfg.Interface)
-	defer C.free(unsafe.Pointer(cIface))
-	C.strncpy(&ccfg._interface[0], cIface, 63)
-
-	ccfg.line_rate = C.uint64_t(cfg.LineRate)
-	ccfg.auto_detect_nic = C.bool(cfg.AutoDetect)
-	ccfg.test_type = C.test_type_t(cfg.TestType)
-	ccfg.frame_size = C.uint32_t(cfg.FrameSize)
-	ccfg.include_jumbo = C.bool(cfg.IncludeJumbo)
-	ccfg.trial_duration_sec = C.uint32_t(cfg.TrialDuration.Seconds())
-	ccfg.warmup_sec = C.uint32_t(cfg.WarmupPeriod.Seconds())
-	ccfg.initial_rate_pct = C.double(cfg.InitialRatePct)
-	ccfg.resolution_pct = C.double(cfg.ResolutionPct)
-	ccfg.max_iterations = C.uint32_t(cfg.MaxIterations)
-	ccfg.acceptable_loss = C.double(cfg.AcceptableLoss)
-	ccfg.hw_timestamp = C.bool(cfg.HWTimestamp)
-	ccfg.measure_latency = C.bool(cfg.MeasureLatency)
-	ccfg.use_pacing = C.bool(cfg.UsePacing)
-	ccfg.batch_size = C.uint32_t(cfg.BatchSize)
-	ccfg.use_dpdk = C.bool(cfg.UseDPDK)
-
-	var dpdkArgsPtr *C.char
-	if cfg.DPDKArgs != "" {
-		dpdkArgsPtr = C.CString(cfg.DPDKArgs)
-		ccfg.dpdk_args = dpdkArgsPtr
+// Cancel stops a running test, which returns what it has measured. A
+// test ended by its context instead returns the context's error.
+func (c *Context) Cancel() {
+	c.probes.RLock()
+	defer c.probes.RUnlock()
+	if c.ctx != nil {
+		C.rfc2544_cancel(c.ctx)
 	}
+}
 
-	ret := C.rfc2544_configure(c.ctx, &ccfg)
-
-	// Free DPDK args string after configure copies it
-	if dpdkArgsPtr != nil {
-		C.free(unsafe.Pointer(dpdkArgsPtr))
+// watch ties a test to ctx: it clears an earlier cancellation, then asks
+// the C dataplane to stop when ctx ends, which its trial loops poll for.
+// The returned function stops watching and must be called before the
+// next test starts, so a late cancel cannot reach it.
+func (c *Context) watch(ctx context.Context) func() {
+	C.rfc2544_clear_cancel(c.ctx)
+	if ctx.Err() != nil {
+		C.rfc2544_cancel(c.ctx)
+		return func() {}
 	}
-
-	if ret < 0 {
-		return fmt.Errorf("configure failed: %d", ret)
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		select {
+		case <-ctx.Done():
+			c.Cancel()
+		case <-done:
+		}
+	}()
+	return func() {
+		close(done)
+		<-exited
 	}
+}
 
-	return nil
+// State returns the current test state
+func (c *Context) State() TestState {
+	c.probes.RLock()
+	defer c.probes.RUnlock()
+	if c.ctx == nil {
+		return StateIdle
+	}
+	return TestState(C.rfc2544_get_state(c.ctx))
 }
 
-// Run starts the configured test
-func (c *Context) Run() error {
-	ret := C.rfc2544_run(c.ctx)
-	if ret < 0 {
-		return fmt.Errorf("run failed: %d", ret)
+// LiveStats returns the live counters. Like State and Cancel it is a
+// probe, so it can be polled while a test method runs.
+func (c *Context) LiveStats() LiveStats {
+	c.probes.RLock()
+	defer c.probes.RUnlock()
+	var ls C.live_stats_t
+	if c.ctx == nil || C.rfc2544_get_live_stats(c.ctx, &ls) < 0 {
+		return LiveStats{}
+	}
+	return LiveStats{
+		FrameSize:      uint32(ls.frame_size),
+		OfferedRatePct: float64(ls.offered_rate_pct),
+		TxPackets:      uint64(ls.tx_packets),
+		TxBytes:        uint64(ls.tx_bytes),
+		RxPackets:      uint64(ls.rx_packets),
+		RxBytes:        uint64(ls.rx_bytes),
+		Trials:         uint64(ls.trials),
+		LastLossPct:    float64(ls.last_loss_pct),
+		LastLatency: LatencyStats{
+			Count:    uint64(ls.last_latency.count),
+			MinNs:    float64(ls.last_latency.min_ns),
+			MaxNs:    float64(ls.last_latency.max_ns),
+			AvgNs:    float64(ls.last_latency.avg_ns),
+			JitterNs: float64(ls.last_latency.jitter_ns),
+			P50Ns:    float64(ls.last_latency.p50_ns),
+			P95Ns:    float64(ls.last_latency.p95_ns),
+			P99Ns:    float64(ls.last_latency.p99_ns),
+		},
+		LastOrder:     frameOrderFromC(&ls.last_order),
+		InTrial:       bool(ls.in_trial),
+		TrialWarmup:   time.Duration(ls.trial_warmup_sec) * time.Second,
+		TrialDuration: time.Duration(ls.trial_duration_sec) * time.Second,
+		TrialElapsed:  time.Duration(ls.trial_elapsed_ns),
 	}
-	return nil
 }
 
-// Cancel stops a running test
-func (c *Context) Cancel() {
-	C.rfc2544_cancel(c.ctx)
+// Heartbeat returns a counter that advances while a trial runs. It is a
+// probe too.
+func (c *Context) Heartbeat() uint64 {
+	c.probes.RLock()
+	defer c.probes.RUnlock()
+	if c.ctx == nil {
+		return 0
+	}
+	return uint64(C.rfc2544_get_heartbeat(c.ctx))
 }
 
-// State returns the current test state
-func (c *Context) State() TestState {
-	return TestState(C.rfc2544_get_state(c.ctx))
+// PortStats returns the counters of each opened port: the TX port, then
+// the RX port when RXInterface is set. Ports open with the first trial.
+func (c *Context) PortStats() []PortStats {
+	return value(c.calls, "PortStats", func() []PortStats {
+		var ps [2]C.port_stats_t
+		n := C.rfc2544_get_port_stats(c.ctx, &ps[0], C.uint32_t(len(ps)))
+		if n < 0 {
+			return nil
+		}
+		stats := make([]PortStats, n)
+		for i := range stats {
+			stats[i] = PortStats{
+				Interface: C.GoString(&ps[i]._interface[0]),
+				TxPackets: uint64(ps[i].tx_packets),
+				RxPackets: uint64(ps[i].rx_packets),
+				TxErrors:  uint64(ps[i].tx_errors),
+				RxErrors:  uint64(ps[i].rx_errors),
+			}
+		}
+		return stats
+	})
 }
 
-// Close cleans up resources
+// Close cleans up resources once the running call returns. A context
+// abandoned by a Watchdog is left to the call stuck in it.
 func (c *Context) Close() {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	if c.ctx != nil {
+	if c.abandoned.Load() {
+		return
+	}
+	c.calls.do("Close", func() {
+		c.probes.Lock()
+		defer c.probes.Unlock()
 		C.rfc2544_cleanup(c.ctx)
 		c.ctx = nil
-	}
+		c.calls.close()
+	})
+	c.subs.closeAll()
 }
 
 // runThroughputTestOld executes RFC 2544 Section 26.1 throughput test (deprecated, use RunThroughputTest)
 func (c *Context) runThroughputTestOld(frameSize uint32) ([]ThroughputResult, error) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	maxResults := 8 // 7 standard + 1 jumbo
-	results := make([]C.throughput_result_t, maxResults)
-	var count C.uint32_t
-
-	ret := C.rfc2544_throughput_test(c.ctx, C.uint32_t(frameSize),
-		&results[0], &count)
-	if ret < 0 {
-		return nil, fmt.Errorf("throughput test failed: %d", ret)
-	}
+	return call(c.calls, "runThroughputTestOld", func() ([]ThroughputResult, error) {
+		maxResults := 8 // 7 standard + 1 jumbo
+		results := make([]C.throughput_result_t, maxResults)
+		var count C.uint32_t
+
+		ret := C.rfc2544_throughput_test(c.ctx, C.uint32_t(frameSize),
+			&results[0], &count)
+		if ret < 0 {
+			return nil, codeError("throughput test", int(ret))
+		}
 
-	goResults := make([]ThroughputResult, count)
-	for i := 0; i < int(count); i++ {
-		goResults[i] = ThroughputResult{
-			FrameSize:    uint32(results[i].frame_size),
-			MaxRatePct:   float64(results[i].max_rate_pct),
-			MaxRateMbps:  float64(results[i].max_rate_mbps),
-			MaxRatePps:   float64(results[i].max_rate_pps),
-			FramesTested: uint64(results[i].frames_tested),
-			Iterations:   uint32(results[i].iterations),
-			Latency: LatencyStats{
-				Count:    uint64(results[i].latency.count),
-				MinNs:    float64(results[i].latency.min_ns),
-				MaxNs:    float64(results[i].latency.max_ns),
-				AvgNs:    float64(results[i].latency.avg_ns),
-				JitterNs: float64(results[i].latency.jitter_ns),
-				P50Ns:    float64(results[i].latency.p50_ns),
-				P95Ns:    float64(results[i].latency.p95_ns),
-				P99Ns:    float64(results[i].latency.p99_ns),
-			},
+		goResults := make([]ThroughputResult, count)
+		for i := 0; i < int(count); i++ {
+			goResults[i] = ThroughputResult{
+				FrameSize:    uint32(results[i].frame_size),
+				MaxRatePct:   float64(results[i].max_rate_pct),
+				MaxRateMbps:  float64(results[i].max_rate_mbps),
+				MaxRatePps:   float64(results[i].max_rate_pps),
+				FramesTested: uint64(results[i].frames_tested),
+				Iterations:   uint32(results[i].iterations),
+				Latency: LatencyStats{
+					Count:    uint64(results[i].latency.count),
+					MinNs:    float64(results[i].latency.min_ns),
+					MaxNs:    float64(results[i].latency.max_ns),
+					AvgNs:    float64(results[i].latency.avg_ns),
+					JitterNs: float64(results[i].latency.jitter_ns),
+					P50Ns:    float64(results[i].latency.p50_ns),
+					P95Ns:    float64(results[i].latency.p95_ns),
+					P99Ns:    float64(results[i].latency.p99_ns),
+				},
+			}
 		}
-	}
 
-	return goResults, nil
+		return goResults, nil
+	})
 }
 
 // runLatencyTestOld executes RFC 2544 Section 26.2 latency test (deprecated)
 func (c *Context) runLatencyTestOld(frameSize uint32, loadPct float64) (*LatencyResult, error) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	var result C.latency_result_t
-	ret := C.rfc2544_latency_test(c.ctx, C.uint32_t(frameSize),
-		C.double(loadPct), &result)
-	if ret < 0 {
-		return nil, fmt.Errorf("latency test failed: %d", ret)
-	}
+	return call(c.calls, "runLatencyTestOld", func() (*LatencyResult, error) {
+		var result C.latency_result_t
+		ret := C.rfc2544_latency_test(c.ctx, C.uint32_t(frameSize),
+			C.double(loadPct), &result)
+		if ret < 0 {
+			return nil, codeError("latency test", int(ret))
+		}
 
-	return &LatencyResult{
-		FrameSize:      uint32(result.frame_size),
-		OfferedRatePct: float64(result.offered_rate_pct),
-		Latency: LatencyStats{
-			Count:    uint64(result.latency.count),
-			MinNs:    float64(result.latency.min_ns),
-			MaxNs:    float64(result.latency.max_ns),
-			AvgNs:    float64(result.latency.avg_ns),
-			JitterNs: float64(result.latency.jitter_ns),
-			P50Ns:    float64(result.latency.p50_ns),
-			P95Ns:    float64(result.latency.p95_ns),
-			P99Ns:    float64(result.latency.p99_ns),
-		},
-	}, nil
+		return &LatencyResult{
+			FrameSize:      uint32(result.frame_size),
+			OfferedRatePct: float64(result.offered_rate_pct),
+			Latency: LatencyStats{
+				Count:    uint64(result.latency.count),
+				MinNs:    float64(result.latency.min_ns),
+				MaxNs:    float64(result.latency.max_ns),
+				AvgNs:    float64(result.latency.avg_ns),
+				JitterNs: float64(result.latency.jitter_ns),
+				P50Ns:    float64(result.latency.p50_ns),
+				P95Ns:    float64(result.latency.p95_ns),
+				P99Ns:    float64(result.latency.p99_ns),
+			},
+		}, nil
+	})
 }
 
 // runFrameLossTestOld executes RFC 2544 Section 26.3 frame loss test (deprecated)
 func (c *Context) runFrameLossTestOld(frameSize uint32) ([]FrameLossPoint, error) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	maxResults := 20 // Up to 20 load levels
-	results := make([]C.frame_loss_point_t, maxResults)
-	var count C.uint32_t
-
-	ret := C.rfc2544_frame_loss_test(c.ctx, C.uint32_t(frameSize),
-		&results[0], &count)
-	if ret < 0 {
-		return nil, fmt.Errorf("frame loss test failed: %d", ret)
-	}
+	return call(c.calls, "runFrameLossTestOld", func() ([]FrameLossPoint, error) {
+		maxResults := 20 // Up to 20 load levels
+		results := make([]C.frame_loss_point_t, maxResults)
+		var count C.uint32_t
+
+		ret := C.rfc2544_frame_loss_test(c.ctx, C.uint32_t(frameSize),
+			&results[0], &count)
+		if ret < 0 {
+			return nil, codeError("frame loss test", int(ret))
+		}
 
-	goResults := make([]FrameLossPoint, count)
-	for i := 0; i < int(count); i++ {
-		goResults[i] = FrameLossPoint{
-			OfferedRatePct: float64(results[i].offered_rate_pct),
-			ActualRateMbps: float64(results[i].actual_rate_mbps),
-			FramesSent:     uint64(results[i].frames_sent),
-			FramesRecv:     uint64(results[i].frames_recv),
-			LossPct:        float64(results[i].loss_pct),
+		goResults := make([]FrameLossPoint, count)
+		for i := 0; i < int(count); i++ {
+			goResults[i] = FrameLossPoint{
+				OfferedRatePct: float64(results[i].offered_rate_pct),
+				ActualRateMbps: float64(results[i].actual_rate_mbps),
+				FramesSent:     uint64(results[i].frames_sent),
+				FramesRecv:     uint64(results[i].frames_recv),
+				LossPct:        float64(results[i].loss_pct),
+			}
 		}
-	}
 
-	return goResults, nil
+		return goResults, nil
+	})
 }
 
 // runBackToBackTestOld executes RFC 2544 Section 26.4 burst test (deprecated)
 func (c *Context) runBackToBackTestOld(frameSize uint32) (*BurstResult, error) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	var result C.burst_result_t
-	ret := C.rfc2544_back_to_back_test(c.ctx, C.uint32_t(frameSize), &result)
-	if ret < 0 {
-		return nil, fmt.Errorf("back-to-back test failed: %d", ret)
-	}
+	return call(c.calls, "runBackToBackTestOld", func() (*BurstResult, error) {
+		var result C.burst_result_t
+		ret := C.rfc2544_back_to_back_test(c.ctx, C.uint32_t(frameSize), &result)
+		if ret < 0 {
+			return nil, codeError("back-to-back test", int(ret))
+		}
 
-	return &BurstResult{
-		FrameSize:     uint32(result.frame_size),
-		MaxBurst:      uint64(result.max_burst),
-		BurstDuration: float64(result.burst_duration),
-		Trials:        uint32(result.trials),
-	}, nil
+		return &BurstResult{
+			FrameSize:     uint32(result.frame_size),
+			MaxBurst:      uint64(result.max_burst),
+			BurstDuration: float64(result.burst_duration),
+			Trials:        uint32(result.trials),
+		}, nil
+	})
 }
 
 // GetLineRate returns the interface line rate in bits/sec
@@ -676,170 +1527,343 @@ func CalcPPS(lineRate uint64, frameSize uint32) uint64 {
 	return uint64(C.rfc2544_calc_pps(C.uint64_t(lineRate), C.uint32_t(frameSize)))
 }
 
-// RunY1564ConfigTest executes ITU-T Y.1564 Service Configuration Test
-func (c *Context) RunY1564ConfigTest(service *Y1564Service) (*Y1564ConfigResult, error) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	// Convert Go service to C service
-	var cService C.y1564_service_t
-	cService.service_id = C.uint32_t(service.ServiceID)
-	cService.sla.cir_mbps = C.double(service.SLA.CIRMbps)
-	cService.sla.eir_mbps = C.double(service.SLA.EIRMbps)
-	cService.sla.cbs_bytes = C.uint32_t(service.SLA.CBSBytes)
-	cService.sla.ebs_bytes = C.uint32_t(service.SLA.EBSBytes)
-	cService.sla.fd_threshold_ms = C.double(service.SLA.FDThresholdMs)
-	cService.sla.fdv_threshold_ms = C.double(service.SLA.FDVThresholdMs)
-	cService.sla.flr_threshold_pct = C.double(service.SLA.FLRThresholdPct)
-	cService.frame_size = C.uint32_t(service.FrameSize)
-	cService.cos = C.uint8_t(service.CoS)
-	cService.enabled = C.bool(service.Enabled)
-
-	// Copy service name (ensure null-termination)
-	nameBytes := []byte(service.ServiceName)
-	for i := 0; i < len(nameBytes) && i < 31; i++ {
-		cService.service_name[i] = C.char(nameBytes[i])
+func nicInfoFromC(ni *C.nic_info_t) NICInfo {
+	mac := make(net.HardwareAddr, 6)
+	for i := range mac {
+		mac[i] = byte(ni.mac[i])
 	}
-	cService.service_name[31] = 0 // Ensure null-termination
+	return NICInfo{
+		Name:        C.GoString(&ni.name[0]),
+		LinkSpeed:   uint64(ni.link_speed),
+		Up:          bool(ni.is_up),
+		OperState:   C.GoString(&ni.operstate[0]),
+		MTU:         uint32(ni.mtu),
+		MAC:         mac.String(),
+		Driver:      C.GoString(&ni.driver[0]),
+		BusInfo:     C.GoString(&ni.bus_info[0]),
+		Firmware:    C.GoString(&ni.fw_version[0]),
+		HWTimestamp: bool(ni.supports_hw_ts),
+		XDP:         bool(ni.supports_xdp),
+		DPDK:        bool(ni.supports_dpdk),
+		DPDKDriver:  C.GoString(&ni.dpdk_driver[0]),
+	}
+}
 
-	var cResult C.y1564_config_result_t
-	ret := C.y1564_config_test(c.ctx, &cService, &cResult)
+// DetectNIC probes an interface's link state, driver and capabilities
+func DetectNIC(iface string) (*NICInfo, error) {
+	cIface := C.CString(iface)
+	defer C.free(unsafe.Pointer(cIface))
+
+	var ni C.nic_info_t
+	ret := C.rfc2544_detect_nic(cIface, &ni)
+	if ret == -C.ENOENT {
+		return nil, fmt.Errorf("interface %s: %w", iface, ErrNoSuchInterface)
+	}
 	if ret < 0 {
-		return nil, fmt.Errorf("Y.1564 config test failed: %d", ret)
+		return nil, codeError("NIC detection", int(ret))
 	}
+	info := nicInfoFromC(&ni)
+	return &info, nil
+}
 
-	result := &Y1564ConfigResult{
-		ServiceID:   uint32(cResult.service_id),
-		ServicePass: bool(cResult.service_pass),
+// QueryCapabilities reports what the dataplane can do on iface, or with
+// iface "" what the library can do on any port. dpdk asks about the DPDK
+// platform rather than the default one.
+func QueryCapabilities(iface string, dpdk bool) (Capabilities, error) {
+	var cIface *C.char
+	if iface != "" {
+		cIface = C.CString(iface)
+		defer C.free(unsafe.Pointer(cIface))
 	}
 
-	for i := 0; i < 4; i++ {
-		result.Steps[i] = Y1564StepResult{
-			Step:            uint32(cResult.steps[i].step),
-			OfferedRatePct:  float64(cResult.steps[i].offered_rate_pct),
-			AchievedRateMbps: float64(cResult.steps[i].achieved_rate_mbps),
-			FramesTx:        uint64(cResult.steps[i].frames_tx),
-			FramesRx:        uint64(cResult.steps[i].frames_rx),
-			FLRPct:          float64(cResult.steps[i].flr_pct),
-			FDAvgMs:         float64(cResult.steps[i].fd_avg_ms),
-			FDMinMs:         float64(cResult.steps[i].fd_min_ms),
-			FDMaxMs:         float64(cResult.steps[i].fd_max_ms),
-			FDVMs:           float64(cResult.steps[i].fdv_ms),
-			FLRPass:         bool(cResult.steps[i].flr_pass),
-			FDPass:          bool(cResult.steps[i].fd_pass),
-			FDVPass:         bool(cResult.steps[i].fdv_pass),
-			StepPass:        bool(cResult.steps[i].step_pass),
-		}
+	var cc C.rfc2544_capabilities_t
+	ret := C.rfc2544_get_capabilities(cIface, C.bool(dpdk), &cc)
+	if ret == -C.ENOENT {
+		return Capabilities{}, fmt.Errorf("interface %s: %w", iface, ErrNoSuchInterface)
 	}
-
-	return result, nil
+	if ret < 0 {
+		return Capabilities{}, codeError("capability query", int(ret))
+	}
+	return capabilitiesFromFlags(uint32(cc.flags), uint32(cc.max_streams), uint32(cc.max_frame_size)), nil
 }
 
-// RunY1564PerfTest executes ITU-T Y.1564 Service Performance Test
-func (c *Context) RunY1564PerfTest(service *Y1564Service, durationSec uint32) (*Y1564PerfResult, error) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	// Convert Go service to C service
-	var cService C.y1564_service_t
-	cService.service_id = C.uint32_t(service.ServiceID)
-	cService.sla.cir_mbps = C.double(service.SLA.CIRMbps)
-	cService.sla.eir_mbps = C.double(service.SLA.EIRMbps)
-	cService.sla.cbs_bytes = C.uint32_t(service.SLA.CBSBytes)
-	cService.sla.ebs_bytes = C.uint32_t(service.SLA.EBSBytes)
-	cService.sla.fd_threshold_ms = C.double(service.SLA.FDThresholdMs)
-	cService.sla.fdv_threshold_ms = C.double(service.SLA.FDVThresholdMs)
-	cService.sla.flr_threshold_pct = C.double(service.SLA.FLRThresholdPct)
-	cService.frame_size = C.uint32_t(service.FrameSize)
-	cService.cos = C.uint8_t(service.CoS)
-	cService.enabled = C.bool(service.Enabled)
-
-	// Copy service name (ensure null-termination)
-	nameBytes := []byte(service.ServiceName)
-	for i := 0; i < len(nameBytes) && i < 31; i++ {
-		cService.service_name[i] = C.char(nameBytes[i])
+// ListInterfaces probes every interface except loopback
+func ListInterfaces() ([]NICInfo, error) {
+	nics := make([]C.nic_info_t, maxInterfaces)
+	ret := C.rfc2544_list_interfaces(&nics[0], C.uint32_t(len(nics)))
+	if ret < 0 {
+		return nil, codeError("list interfaces", int(ret))
 	}
-	cService.service_name[31] = 0 // Ensure null-termination
 
-	var cResult C.y1564_perf_result_t
-	ret := C.y1564_perf_test(c.ctx, &cService, C.uint32_t(durationSec), &cResult)
-	if ret < 0 {
-		return nil, fmt.Errorf("Y.1564 perf test failed: %d", ret)
+	infos := make([]NICInfo, ret)
+	for i := range infos {
+		infos[i] = nicInfoFromC(&nics[i])
 	}
+	return infos, nil
+}
 
-	return &Y1564PerfResult{
-		ServiceID:   uint32(cResult.service_id),
-		DurationSec: uint32(cResult.duration_sec),
-		FramesTx:    uint64(cResult.frames_tx),
-		FramesRx:    uint64(cResult.frames_rx),
-		FLRPct:      float64(cResult.flr_pct),
-		FDAvgMs:     float64(cResult.fd_avg_ms),
-		FDMinMs:     float64(cResult.fd_min_ms),
-		FDMaxMs:     float64(cResult.fd_max_ms),
-		FDVMs:       float64(cResult.fdv_ms),
-		FLRPass:     bool(cResult.flr_pass),
-		FDPass:      bool(cResult.fd_pass),
-		FDVPass:     bool(cResult.fdv_pass),
-		ServicePass: bool(cResult.service_pass),
-	}, nil
+// RecommendInterface returns the up interface best suited for testing,
+// preferring speed, AF_XDP, hardware timestamps and jumbo MTU
+func RecommendInterface() (*NICInfo, error) {
+	var ni C.nic_info_t
+	ret := C.rfc2544_recommend_interface(&ni)
+	if ret == -C.ENOENT {
+		return nil, fmt.Errorf("no suitable interface: %w", ErrNoSuchInterface)
+	}
+	if ret < 0 {
+		return nil, codeError("interface recommendation", int(ret))
+	}
+	info := nicInfoFromC(&ni)
+	return &info, nil
 }
 
-// =============================================================================
-// Wrapper types and functions for CLI integration
-// =============================================================================
-
-// ThroughputResult wraps the throughput test result for CLI
-type ThroughputResultCLI struct {
-	FrameSize   uint32
-	MaxRatePct  float64
-	MaxRateMbps float64
-	MaxRatePPS  float64
-	Iterations  uint32
-	Latency     LatencyStats
+// RunY1564ConfigTest executes ITU-T Y.1564 Service Configuration Test
+func (c *Context) RunY1564ConfigTest(ctx context.Context, service *Y1564Service) (*Y1564ConfigResult, error) {
+	return call(c.calls, "RunY1564ConfigTest", func() (*Y1564ConfigResult, error) {
+		defer c.watch(ctx)()
+		defer c.subs.poll(c.LiveStats)()
+
+		// Convert Go service to C service
+		var cService C.y1564_service_t
+		cService.service_id = C.uint32_t(service.ServiceID)
+		cService.sla.cir_mbps = C.double(service.SLA.CIRMbps)
+		cService.sla.eir_mbps = C.double(service.SLA.EIRMbps)
+		cService.sla.cbs_bytes = C.uint32_t(service.SLA.CBSBytes)
+		cService.sla.ebs_bytes = C.uint32_t(service.SLA.EBSBytes)
+		cService.sla.fd_threshold_ms = C.double(service.SLA.FDThresholdMs)
+		cService.sla.fdv_threshold_ms = C.double(service.SLA.FDVThresholdMs)
+		cService.sla.flr_threshold_pct = C.double(service.SLA.FLRThresholdPct)
+		cService.sla.eir_sac = sacToC(service.SLA.EIRSAC)
+		cService.sla.policing_sac = sacToC(service.SLA.PolicingSAC)
+		cService.frame_size = C.uint32_t(service.FrameSize)
+		cService.cos = C.uint8_t(service.CoS)
+		cService.enabled = C.bool(service.Enabled)
+		cService.policing_step = C.bool(service.PolicingStep)
+		cService.vlan_id = C.uint16_t(service.VLANID)
+		cService.pcp = C.uint8_t(service.PCP)
+		cService.s_vlan_id = C.uint16_t(service.OuterVLANID)
+		cService.s_pcp = C.uint8_t(service.OuterPCP)
+		cService.ip_version = C.uint8_t(service.IPVersion)
+		cService.extra_mbps = C.double(service.ExtraMbps)
+
+		// Copy service name (ensure null-termination)
+		nameBytes := []byte(service.ServiceName)
+		for i := 0; i < len(nameBytes) && i < 31; i++ {
+			cService.service_name[i] = C.char(nameBytes[i])
+		}
+		cService.service_name[31] = 0 // Ensure null-termination
+
+		var cResult C.y1564_config_result_t
+		ret := C.y1564_config_test(c.ctx, &cService, &cResult)
+		if ctx.Err() != nil {
+			return nil, cancelled(ctx)
+		}
+		if ret < 0 {
+			return nil, codeError("Y.1564 config test", int(ret))
+		}
+
+		result := &Y1564ConfigResult{
+			ServiceID:   uint32(cResult.service_id),
+			ServicePass: bool(cResult.service_pass),
+		}
+
+		phases := map[C.y1564_step_phase_t]Y1564StepPhase{
+			C.Y1564_STEP_CIR:      Y1564StepCIR,
+			C.Y1564_STEP_EIR:      Y1564StepEIR,
+			C.Y1564_STEP_POLICING: Y1564StepPolicing,
+		}
+		result.Steps = make([]Y1564StepResult, int(cResult.step_count))
+		for i := range result.Steps {
+			result.Steps[i] = Y1564StepResult{
+				Step:            uint32(cResult.steps[i].step),
+				OfferedRatePct:  float64(cResult.steps[i].offered_rate_pct),
+				AchievedRateMbps: float64(cResult.steps[i].achieved_rate_mbps),
+				FramesTx:        uint64(cResult.steps[i].frames_tx),
+				FramesRx:        uint64(cResult.steps[i].frames_rx),
+				FLRPct:          float64(cResult.steps[i].flr_pct),
+				FDAvgMs:         float64(cResult.steps[i].fd_avg_ms),
+				FDMinMs:         float64(cResult.steps[i].fd_min_ms),
+				FDMaxMs:         float64(cResult.steps[i].fd_max_ms),
+				FDVMs:           float64(cResult.steps[i].fdv_ms),
+				FLRPass:         bool(cResult.steps[i].flr_pass),
+				FDPass:          bool(cResult.steps[i].fd_pass),
+				FDVPass:         bool(cResult.steps[i].fdv_pass),
+				StepPass:        bool(cResult.steps[i].step_pass),
+				Phase:           phases[cResult.steps[i].phase],
+			}
+		}
+
+		return result, nil
+	})
 }
 
-// LatencyResultCLI wraps the latency test result for CLI
-type LatencyResultCLI struct {
-	FrameSize uint32
-	LoadPct   float64
-	Latency   LatencyStats
+// sacToC converts step acceptance criteria for the C library
+func sacToC(sac Y1564SAC) C.y1564_sac_t {
+	return C.y1564_sac_t{
+		fd_threshold_ms:   C.double(sac.FDThresholdMs),
+		fdv_threshold_ms:  C.double(sac.FDVThresholdMs),
+		flr_threshold_pct: C.double(sac.FLRThresholdPct),
+	}
 }
 
-// FrameLossResultCLI wraps the frame loss test result for CLI
-type FrameLossResultCLI struct {
-	FrameSize  uint32
-	OfferedPct float64
-	FramesTx   uint64
-	FramesRx   uint64
-	LossPct    float64
+// RunY1564PerfTest executes ITU-T Y.1564 Service Performance Test
+func (c *Context) RunY1564PerfTest(ctx context.Context, service *Y1564Service, durationSec uint32) (*Y1564PerfResult, error) {
+	return call(c.calls, "RunY1564PerfTest", func() (*Y1564PerfResult, error) {
+		defer c.watch(ctx)()
+		defer c.subs.poll(c.LiveStats)()
+
+		// Convert Go service to C service
+		var cService C.y1564_service_t
+		cService.service_id = C.uint32_t(service.ServiceID)
+		cService.sla.cir_mbps = C.double(service.SLA.CIRMbps)
+		cService.sla.eir_mbps = C.double(service.SLA.EIRMbps)
+		cService.sla.cbs_bytes = C.uint32_t(service.SLA.CBSBytes)
+		cService.sla.ebs_bytes = C.uint32_t(service.SLA.EBSBytes)
+		cService.sla.fd_threshold_ms = C.double(service.SLA.FDThresholdMs)
+		cService.sla.fdv_threshold_ms = C.double(service.SLA.FDVThresholdMs)
+		cService.sla.flr_threshold_pct = C.double(service.SLA.FLRThresholdPct)
+		cService.frame_size = C.uint32_t(service.FrameSize)
+		cService.cos = C.uint8_t(service.CoS)
+		cService.enabled = C.bool(service.Enabled)
+		cService.vlan_id = C.uint16_t(service.VLANID)
+		cService.pcp = C.uint8_t(service.PCP)
+		cService.s_vlan_id = C.uint16_t(service.OuterVLANID)
+		cService.s_pcp = C.uint8_t(service.OuterPCP)
+		cService.ip_version = C.uint8_t(service.IPVersion)
+		cService.extra_mbps = C.double(service.ExtraMbps)
+
+		// Copy service name (ensure null-termination)
+		nameBytes := []byte(service.ServiceName)
+		for i := 0; i < len(nameBytes) && i < 31; i++ {
+			cService.service_name[i] = C.char(nameBytes[i])
+		}
+		cService.service_name[31] = 0 // Ensure null-termination
+
+		var cResult C.y1564_perf_result_t
+		ret := C.y1564_perf_test(c.ctx, &cService, C.uint32_t(durationSec), &cResult)
+		if ctx.Err() != nil {
+			return nil, cancelled(ctx)
+		}
+		if ret < 0 {
+			return nil, codeError("Y.1564 perf test", int(ret))
+		}
+
+		return &Y1564PerfResult{
+			ServiceID:   uint32(cResult.service_id),
+			DurationSec: uint32(cResult.duration_sec),
+			FramesTx:    uint64(cResult.frames_tx),
+			FramesRx:    uint64(cResult.frames_rx),
+			FLRPct:      float64(cResult.flr_pct),
+			FDAvgMs:     float64(cResult.fd_avg_ms),
+			FDMinMs:     float64(cResult.fd_min_ms),
+			FDMaxMs:     float64(cResult.fd_max_ms),
+			FDVMs:       float64(cResult.fdv_ms),
+			FLRPass:     bool(cResult.flr_pass),
+			FDPass:      bool(cResult.fd_pass),
+			FDVPass:     bool(cResult.fdv_pass),
+			ServicePass: bool(cResult.service_pass),
+		}, nil
+	})
 }
 
-// BackToBackResultCLI wraps the back-to-back test result for CLI
-type BackToBackResultCLI struct {
-	FrameSize       uint32
-	MaxBurstFrames  uint64
-	BurstDurationUs uint64
-	Trials          uint32
+// RunRFC2889ForwardingTest runs RFC 2889 Section 5.1 forwarding rate test
+// with the given traffic pattern
+func (c *Context) RunRFC2889ForwardingTest(ctx context.Context, cfg RFC2889Config) (*RFC2889ForwardingResult, error) {
+	return call(c.calls, "RunRFC2889ForwardingTest", func() (*RFC2889ForwardingResult, error) {
+		defer c.watch(ctx)()
+		defer c.subs.poll(c.LiveStats)()
+
+		var ccfg C.rfc2889_config_t
+		C.rfc2889_default_config(&ccfg)
+		ccfg.test_type = C.RFC2889_FORWARDING_RATE
+		ccfg.pattern = C.traffic_pattern_t(cfg.Pattern)
+		ccfg.port_count = C.uint32_t(cfg.PortCount)
+		ccfg.frame_size = C.uint32_t(cfg.FrameSize)
+		ccfg.trial_duration_sec = C.uint32_t(cfg.TrialDuration.Seconds())
+		ccfg.warmup_sec = C.uint32_t(cfg.WarmupPeriod.Seconds())
+		ccfg.address_count = C.uint32_t(cfg.AddressCount)
+		ccfg.acceptable_loss_pct = C.double(cfg.AcceptableLossPct)
+
+		var cResult C.rfc2889_fwd_result_t
+		ret := C.rfc2889_forwarding_test(c.ctx, &ccfg, &cResult)
+		if ctx.Err() != nil {
+			return nil, cancelled(ctx)
+		}
+		if ret < 0 {
+			return nil, codeError("RFC 2889 forwarding test", int(ret))
+		}
+
+		return &RFC2889ForwardingResult{
+			FrameSize:    uint32(cResult.frame_size),
+			PortCount:    uint32(cResult.port_count),
+			Pattern:      TrafficPattern(cResult.pattern),
+			MaxRatePct:   float64(cResult.max_rate_pct),
+			MaxRateFPS:   float64(cResult.max_rate_fps),
+			RateMbps:     float64(cResult.aggregate_rate_mbps),
+			FramesTx:     uint64(cResult.frames_tx),
+			FramesRx:     uint64(cResult.frames_rx),
+			LossPct:      float64(cResult.loss_pct),
+			Flows:        uint32(cResult.flow_count),
+			IngressPorts: uint32(cResult.ingress_ports),
+			EgressPorts:  uint32(cResult.egress_ports),
+		}, nil
+	})
 }
 
-// RecoveryResultCLI wraps the system recovery test result for CLI
-type RecoveryResultCLI struct {
-	FrameSize       uint32
-	OverloadRatePct float64
-	RecoveryRatePct float64
-	OverloadSec     uint32
-	RecoveryTimeMs  float64
-	FramesLost      uint64
-	Trials          uint32
+// DiscoverOAMPeers sends 802.3ah Information OAMPDUs and a Y.1731 multicast
+// LBM at megLevel and collects the far-end devices that answer
+func (c *Context) DiscoverOAMPeers(megLevel uint8, timeout time.Duration) ([]OAMPeer, error) {
+	return call(c.calls, "DiscoverOAMPeers", func() ([]OAMPeer, error) {
+		var cpeers [C.OAM_MAX_PEERS]C.oam_peer_t
+		var count C.uint32_t
+
+		ret := C.oam_discover(c.ctx, C.uint8_t(megLevel), C.uint32_t(timeout.Milliseconds()),
+			&cpeers[0], C.OAM_MAX_PEERS, &count)
+		if ret < 0 {
+			return nil, codeError("OAM discovery", int(ret))
+		}
+
+		peers := make([]OAMPeer, int(count))
+		for i := range peers {
+			cp := cpeers[i]
+			mac := make(net.HardwareAddr, 6)
+			for j := range mac {
+				mac[j] = byte(cp.mac[j])
+			}
+			peers[i] = OAMPeer{
+				MAC:            mac.String(),
+				Dot3ah:         cp.capabilities&C.OAM_CAP_8023AH != 0,
+				RemoteLoopback: cp.capabilities&C.OAM_CAP_REMOTE_LB != 0,
+				Y1731Loopback:  cp.capabilities&C.OAM_CAP_Y1731_LB != 0,
+				InLoopback:     bool(cp.in_loopback),
+				MEGLevel:       uint8(cp.meg_level),
+			}
+		}
+		return peers, nil
+	})
 }
 
-// ResetResultCLI wraps the reset test result for CLI
-type ResetResultCLI struct {
-	FrameSize   uint32
-	ResetTimeMs float64
-	FramesLost  uint64
-	Trials      uint32
-	ManualReset bool
+// SetRemoteLoopback places the far-end port at mac into 802.3ah remote
+// loopback (enable=true) or releases it, waiting up to timeout for the peer
+// to confirm the new state
+func (c *Context) SetRemoteLoopback(mac string, enable bool, timeout time.Duration) error {
+	hw, err := net.ParseMAC(mac)
+	if err != nil || len(hw) != 6 {
+		return fmt.Errorf("invalid peer MAC %q", mac)
+	}
+
+	return c.calls.exec("SetRemoteLoopback", func() error {
+		var cmac [6]C.uint8_t
+		for i := range cmac {
+			cmac[i] = C.uint8_t(hw[i])
+		}
+
+		ret := C.oam_remote_loopback(c.ctx, &cmac[0], C.bool(enable), C.uint32_t(timeout.Milliseconds()))
+		if ret < 0 {
+			return codeError("remote loopback", int(ret))
+		}
+		return nil
+	})
 }
 
 // New creates a new RFC2544 context with configuration
@@ -862,38 +1886,261 @@ func New(cfg Config) (*Context, error) {
 
 // SetFrameSize sets the frame size for subsequent tests
 func (c *Context) SetFrameSize(frameSize uint32) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	c.frameSize = frameSize
+	c.calls.do("SetFrameSize", func() {
+		c.frameSize = frameSize
+	})
+}
+
+// Capabilities returns what the configured ports can do
+func (c *Context) Capabilities() Capabilities {
+	return value(c.calls, "Capabilities", func() Capabilities { return c.caps })
+}
+
+// SetAcceptableLoss sets the loss a throughput trial may show and still
+// pass, overriding the configured value for subsequent searches
+func (c *Context) SetAcceptableLoss(lossPct float64) error {
+	return c.calls.exec("SetAcceptableLoss", func() error {
+		ret := C.rfc2544_set_acceptable_loss(c.ctx, C.double(lossPct))
+		if ret < 0 {
+			return codeError("set acceptable loss", int(ret))
+		}
+		c.config.AcceptableLoss = lossPct
+
+		return nil
+	})
 }
 
 // RunThroughputTestCLI runs throughput test and returns CLI-friendly result
-func (c *Context) RunThroughputTest() (*ThroughputResultCLI, error) {
-	results, err := c.runThroughputTestInternal(c.frameSize)
-	if err != nil {
-		return nil, err
+func (c *Context) RunThroughputTest(ctx context.Context) (*ThroughputResultCLI, error) {
+	// Stepping the search here keeps the passing trials' latency samples
+	return c.RunThroughputSearch(ctx, nil, nil)
+}
+
+// RunThroughputSearch runs the throughput binary search from search, or
+// from the start if search is nil, calling step after every iteration
+func (c *Context) RunThroughputSearch(ctx context.Context, search *ThroughputSearch, step func(*ThroughputSearch)) (*ThroughputResultCLI, error) {
+	return call(c.calls, "RunThroughputSearch", func() (*ThroughputResultCLI, error) {
+		defer c.watch(ctx)()
+		defer c.subs.poll(c.LiveStats)()
+
+		var s C.throughput_search_t
+		if search == nil {
+			C.rfc2544_throughput_search_init(c.ctx, &s)
+		} else {
+			s.low_pct = C.double(search.LowPct)
+			s.high_pct = C.double(search.HighPct)
+			s.best_rate_pct = C.double(search.BestRatePct)
+			s.iterations = C.uint32_t(search.Iterations)
+			s.frames_tested = C.uint64_t(search.FramesTested)
+			s.latency = latencyStatsToC(search.Latency)
+		}
+
+		// The latency reported is of the best passing trial
+		var best *LatencyHistogram
+		var bestStreams []StreamStats
+		var bestFamilies []FamilyStats
+		var trials []ThroughputTrial
+		if search != nil {
+			best = search.Latency.Histogram
+			trials = append(trials, search.Trials...)
+		}
+		latency := func() LatencyStats {
+			l := latencyStatsFromC(&s.latency)
+			l.Histogram = best
+			return l
+		}
+
+		for {
+			low := s.low_pct
+			ret := C.rfc2544_throughput_search_step(c.ctx, C.uint32_t(c.frameSize), &s)
+			if ctx.Err() != nil {
+				return nil, cancelled(ctx)
+			}
+			if ret < 0 {
+				return nil, codeError("throughput test", int(ret))
+			}
+			if ret > 0 {
+				break
+			}
+			if s.low_pct > low {
+				best = c.trialHistogram()
+				bestStreams = c.streamStats()
+				bestFamilies = c.familyStats()
+			}
+			trials = append(trials, ThroughputTrial{
+				RatePct:  float64(s.last.rate_pct),
+				FramesTx: uint64(s.last.frames_tx),
+				FramesRx: uint64(s.last.frames_rx),
+				LossPct:  float64(s.last.loss_pct),
+				Passed:   bool(s.last.passed),
+				Latency:  latencyStatsFromC(&s.last.latency),
+
+				FrameOrder: frameOrderFromC(&s.last.order),
+			})
+			if step != nil {
+				step(&ThroughputSearch{
+					LowPct:       float64(s.low_pct),
+					HighPct:      float64(s.high_pct),
+					BestRatePct:  float64(s.best_rate_pct),
+					Iterations:   uint32(s.iterations),
+					FramesTested: uint64(s.frames_tested),
+					Latency:      latency(),
+					Trials:       append([]ThroughputTrial(nil), trials...),
+				})
+			}
+		}
+
+		var r C.throughput_result_t
+		C.rfc2544_throughput_search_result(c.ctx, C.uint32_t(c.frameSize), &s, &r)
+		return &ThroughputResultCLI{
+			FrameSize:   uint32(r.frame_size),
+			MaxRatePct:  float64(r.max_rate_pct),
+			MaxRateMbps: float64(r.max_rate_mbps),
+			MaxRatePPS:  float64(r.max_rate_pps),
+			Iterations:  uint32(r.iterations),
+			Latency:     latency(),
+
+			AcceptableLossPct: c.config.AcceptableLoss,
+			FrameOrder:        bestOrder(trials),
+			Streams:           bestStreams,
+			Families:          bestFamilies,
+			Trials:            trials,
+		}, nil
+	})
+}
+
+// trialHistogram returns the latency distribution of the last trial, or
+// nil unless Config.LatencyHistogram is set. It runs inside a call.
+func (c *Context) trialHistogram() *LatencyHistogram {
+	if !c.config.LatencyHistogram {
+		return nil
 	}
-	if len(results) == 0 {
-		return nil, fmt.Errorf("no results")
+	if n := c.config.latencySamples(); cap(c.samples) < n {
+		c.samples = make([]uint64, n)
 	}
+	samples := c.samples[:c.config.latencySamples()]
+	n := C.rfc2544_get_latency_samples(c.ctx, (*C.uint64_t)(unsafe.Pointer(&samples[0])), C.uint32_t(len(samples)))
+	if n <= 0 {
+		return nil
+	}
+	return NewLatencyHistogram(samples[:n])
+}
 
-	r := results[0]
-	return &ThroughputResultCLI{
-		FrameSize:   r.FrameSize,
-		MaxRatePct:  r.MaxRatePct,
-		MaxRateMbps: r.MaxRateMbps,
-		MaxRatePPS:  r.MaxRatePps,
-		Iterations:  r.Iterations,
-		Latency:     r.Latency,
-	}, nil
+// streamStats returns the per-stream counters of the last trial, or nil
+// unless Config.Streams is set. It runs inside a call.
+func (c *Context) streamStats() []StreamStats {
+	if c.config.Streams <= 1 {
+		return nil
+	}
+	if cap(c.stats) < int(c.config.Streams) {
+		c.stats = make([]C.stream_stats_t, c.config.Streams)
+	}
+	stats := c.stats[:c.config.Streams]
+	n := C.rfc2544_get_stream_stats(c.ctx, &stats[0], C.uint32_t(len(stats)))
+	if n <= 0 {
+		return nil
+	}
+	streams := make([]StreamStats, n)
+	for i := range streams {
+		streams[i] = StreamStats{
+			Stream:   uint32(i),
+			FramesTx: uint64(stats[i].frames_sent),
+			FramesRx: uint64(stats[i].frames_recv),
+			LossPct:  float64(stats[i].loss_pct),
+			Latency:  latencyStatsFromC(&stats[i].latency),
+		}
+	}
+	return streams
+}
+
+// familyStats returns each address family's counters over the last trial,
+// or nil unless Config.IP is dual-stack. It runs inside a call.
+func (c *Context) familyStats() []FamilyStats {
+	if c.config.IP.Mode != IPModeDual {
+		return nil
+	}
+	var v4, v6 C.ip_family_stats_t
+	if C.rfc2544_ip_family_get_stats(c.ctx, &v4, &v6) < 0 {
+		return nil
+	}
+	families := make([]FamilyStats, 2)
+	for i, fs := range []*C.ip_family_stats_t{&v4, &v6} {
+		families[i] = FamilyStats{
+			Family:   []string{"ipv4", "ipv6"}[i],
+			FramesTx: uint64(fs.frames_sent),
+			FramesRx: uint64(fs.frames_recv),
+			LossPct:  float64(fs.loss_pct),
+			Latency:  latencyStatsFromC(&fs.latency),
+		}
+	}
+	return families
+}
+
+// probeStats returns the latency probe's counters over the last trial, nil
+// without a probe
+func (c *Context) probeStats() *ProbeStats {
+	if c.config.LatencyProbe.PPS == 0 {
+		return nil
+	}
+	var ps C.latency_probe_stats_t
+	if C.rfc2544_latency_probe_get_stats(c.ctx, &ps) < 0 {
+		return nil
+	}
+	return &ProbeStats{
+		PCP:      c.config.LatencyProbe.PCP,
+		DSCP:     c.config.LatencyProbe.DSCP,
+		FramesTx: uint64(ps.frames_sent),
+		FramesRx: uint64(ps.frames_recv),
+		LossPct:  float64(ps.loss_pct),
+		Latency:  latencyStatsFromC(&ps.latency),
+	}
+}
+
+func frameOrderFromC(o *C.frame_order_t) FrameOrder {
+	return FrameOrder{
+		ReorderedFrames: uint64(o.reordered),
+		DuplicateFrames: uint64(o.duplicates),
+		MaxReorderDepth: uint32(o.max_reorder_depth),
+		CorruptedFrames: uint64(o.corrupted),
+	}
+}
+
+func latencyStatsFromC(l *C.latency_stats_t) LatencyStats {
+	return LatencyStats{
+		Count:    uint64(l.count),
+		MinNs:    float64(l.min_ns),
+		MaxNs:    float64(l.max_ns),
+		AvgNs:    float64(l.avg_ns),
+		JitterNs: float64(l.jitter_ns),
+		P50Ns:    float64(l.p50_ns),
+		P95Ns:    float64(l.p95_ns),
+		P99Ns:    float64(l.p99_ns),
+	}
+}
+
+func latencyStatsToC(l LatencyStats) C.latency_stats_t {
+	return C.latency_stats_t{
+		count:     C.uint64_t(l.Count),
+		min_ns:    C.double(l.MinNs),
+		max_ns:    C.double(l.MaxNs),
+		avg_ns:    C.double(l.AvgNs),
+		jitter_ns: C.double(l.JitterNs),
+		p50_ns:    C.double(l.P50Ns),
+		p95_ns:    C.double(l.P95Ns),
+		p99_ns:    C.double(l.P99Ns),
+	}
 }
 
 // RunLatencyTestCLI runs latency test at multiple load levels
-func (c *Context) RunLatencyTest(loadLevels []float64) ([]LatencyResultCLI, error) {
+func (c *Context) RunLatencyTest(ctx context.Context, loadLevels []float64) ([]LatencyResultCLI, error) {
+	defer c.subs.poll(c.LiveStats)()
 	var results []LatencyResultCLI
 
 	for _, load := range loadLevels {
-		result, err := c.runLatencyTestInternal(c.frameSize, load)
+		result, err := c.runLatencyTestInternal(ctx, c.frameSize, load)
+		if ctx.Err() != nil {
+			return nil, cancelled(ctx)
+		}
 		if err != nil {
 			continue
 		}
@@ -901,6 +2148,9 @@ func (c *Context) RunLatencyTest(loadLevels []float64) ([]LatencyResultCLI, erro
 			FrameSize: c.frameSize,
 			LoadPct:   load,
 			Latency:   result.Latency,
+			Streams:   result.Streams,
+			Probe:     result.Probe,
+			Families:  result.Families,
 		})
 	}
 
@@ -912,8 +2162,9 @@ func (c *Context) RunLatencyTest(loadLevels []float64) ([]LatencyResultCLI, erro
 }
 
 // RunFrameLossTestCLI runs frame loss test with stepped load
-func (c *Context) RunFrameLossTest(startPct, endPct, stepPct float64) ([]FrameLossResultCLI, error) {
-	results, err := c.runFrameLossTestInternal(c.frameSize)
+func (c *Context) RunFrameLossTest(ctx context.Context, startPct, endPct, stepPct float64) ([]FrameLossResultCLI, error) {
+	defer c.subs.poll(c.LiveStats)()
+	results, err := c.runFrameLossTestInternal(ctx, c.frameSize)
 	if err != nil {
 		return nil, err
 	}
@@ -926,6 +2177,7 @@ func (c *Context) RunFrameLossTest(startPct, endPct, stepPct float64) ([]FrameLo
 			FramesTx:   r.FramesSent,
 			FramesRx:   r.FramesRecv,
 			LossPct:    r.LossPct,
+			FrameOrder: r.FrameOrder,
 		})
 	}
 
@@ -933,8 +2185,9 @@ func (c *Context) RunFrameLossTest(startPct, endPct, stepPct float64) ([]FrameLo
 }
 
 // RunBackToBackTestCLI runs back-to-back burst test
-func (c *Context) RunBackToBackTest(initialBurst uint64, trials uint32) (*BackToBackResultCLI, error) {
-	result, err := c.runBackToBackTestInternal(c.frameSize)
+func (c *Context) RunBackToBackTest(ctx context.Context, initialBurst uint64, trials uint32) (*BackToBackResultCLI, error) {
+	defer c.subs.poll(c.LiveStats)()
+	result, err := c.runBackToBackTestInternal(ctx, c.frameSize)
 	if err != nil {
 		return nil, err
 	}
@@ -948,156 +2201,244 @@ func (c *Context) RunBackToBackTest(initialBurst uint64, trials uint32) (*BackTo
 }
 
 // RunSystemRecoveryTest runs RFC 2544 Section 26.5 System Recovery test
-func (c *Context) RunSystemRecoveryTest(throughputPct float64, overloadSec uint32) (*RecoveryResultCLI, error) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+func (c *Context) RunSystemRecoveryTest(ctx context.Context, throughputPct float64, overloadSec uint32) (*RecoveryResultCLI, error) {
+	return call(c.calls, "RunSystemRecoveryTest", func() (*RecoveryResultCLI, error) {
+		defer c.watch(ctx)()
+		defer c.subs.poll(c.LiveStats)()
 
-	var result C.recovery_result_t
+		var result C.recovery_result_t
 
-	ret := C.rfc2544_system_recovery_test(c.ctx, C.uint32_t(c.frameSize),
-		C.double(throughputPct), C.uint32_t(overloadSec), &result)
-	if ret < 0 {
-		return nil, fmt.Errorf("system recovery test failed: %d", ret)
-	}
+		ret := C.rfc2544_system_recovery_test(c.ctx, C.uint32_t(c.frameSize),
+			C.double(throughputPct), C.uint32_t(overloadSec), &result)
+		if ctx.Err() != nil {
+			return nil, cancelled(ctx)
+		}
+		if ret < 0 {
+			return nil, codeError("system recovery test", int(ret))
+		}
 
-	return &RecoveryResultCLI{
-		FrameSize:       uint32(result.frame_size),
-		OverloadRatePct: float64(result.overload_rate_pct),
-		RecoveryRatePct: float64(result.recovery_rate_pct),
-		OverloadSec:     uint32(result.overload_sec),
-		RecoveryTimeMs:  float64(result.recovery_time_ms),
-		FramesLost:      uint64(result.frames_lost),
-		Trials:          uint32(result.trials),
-	}, nil
+		return &RecoveryResultCLI{
+			FrameSize:       uint32(result.frame_size),
+			OverloadRatePct: float64(result.overload_rate_pct),
+			RecoveryRatePct: float64(result.recovery_rate_pct),
+			OverloadSec:     uint32(result.overload_sec),
+			RecoveryTimeMs:  float64(result.recovery_time_ms),
+			FramesLost:      uint64(result.frames_lost),
+			Trials:          uint32(result.trials),
+		}, nil
+	})
 }
 
 // RunResetTest runs RFC 2544 Section 26.6 Reset test
-func (c *Context) RunResetTest() (*ResetResultCLI, error) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+func (c *Context) RunResetTest(ctx context.Context) (*ResetResultCLI, error) {
+	return call(c.calls, "RunResetTest", func() (*ResetResultCLI, error) {
+		defer c.watch(ctx)()
+		defer c.subs.poll(c.LiveStats)()
 
-	var result C.reset_result_t
+		var result C.reset_result_t
 
-	ret := C.rfc2544_reset_test(c.ctx, C.uint32_t(c.frameSize), &result)
-	if ret < 0 {
-		return nil, fmt.Errorf("reset test failed: %d", ret)
-	}
+		ret := C.rfc2544_reset_test(c.ctx, C.uint32_t(c.frameSize), &result)
+		if ctx.Err() != nil {
+			return nil, cancelled(ctx)
+		}
+		if ret < 0 {
+			return nil, codeError("reset test", int(ret))
+		}
 
-	return &ResetResultCLI{
-		FrameSize:   uint32(result.frame_size),
-		ResetTimeMs: float64(result.reset_time_ms),
-		FramesLost:  uint64(result.frames_lost),
-		Trials:      uint32(result.trials),
-		ManualReset: bool(result.manual_reset),
-	}, nil
+		return &ResetResultCLI{
+			FrameSize:   uint32(result.frame_size),
+			ResetTimeMs: float64(result.reset_time_ms),
+			FramesLost:  uint64(result.frames_lost),
+			Trials:      uint32(result.trials),
+			ManualReset: bool(result.manual_reset),
+		}, nil
+	})
 }
 
-// Internal wrappers for the existing methods
-func (c *Context) runThroughputTestInternal(frameSize uint32) ([]ThroughputResult, error) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+// RunFixedRateTrial runs one trial at ratePct of line rate for duration,
+// measuring loss, delivered throughput and latency
+func (c *Context) RunFixedRateTrial(ctx context.Context, ratePct float64, duration time.Duration) (*FixedRateResult, error) {
+	return call(c.calls, "RunFixedRateTrial", func() (*FixedRateResult, error) {
+		defer c.watch(ctx)()
+		defer c.subs.poll(c.LiveStats)()
 
-	maxResults := 8
-	results := make([]C.throughput_result_t, maxResults)
-	var count C.uint32_t
+		var result C.fixed_rate_result_t
 
-	ret := C.rfc2544_throughput_test(c.ctx, C.uint32_t(frameSize), &results[0], &count)
-	if ret < 0 {
-		return nil, fmt.Errorf("throughput test failed: %d", ret)
-	}
+		ret := C.rfc2544_fixed_rate_trial(c.ctx, C.uint32_t(c.frameSize), C.double(ratePct),
+			C.uint32_t(duration.Seconds()), &result)
+		if ctx.Err() != nil {
+			return nil, cancelled(ctx)
+		}
+		if ret < 0 {
+			return nil, codeError("fixed-rate trial", int(ret))
+		}
 
-	goResults := make([]ThroughputResult, count)
-	for i := 0; i < int(count); i++ {
-		goResults[i] = ThroughputResult{
-			FrameSize:    uint32(results[i].frame_size),
-			MaxRatePct:   float64(results[i].max_rate_pct),
-			MaxRateMbps:  float64(results[i].max_rate_mbps),
-			MaxRatePps:   float64(results[i].max_rate_pps),
-			FramesTested: uint64(results[i].frames_tested),
-			Iterations:   uint32(results[i].iterations),
+		return &FixedRateResult{
+			FrameSize:     uint32(result.frame_size),
+			OfferedPct:    float64(result.offered_rate_pct),
+			FramesTx:      uint64(result.frames_sent),
+			FramesRx:      uint64(result.frames_recv),
+			LossPct:       float64(result.loss_pct),
+			DeliveredMbps: float64(result.delivered_mbps),
+			ElapsedSec:    float64(result.elapsed_sec),
 			Latency: LatencyStats{
-				Count:    uint64(results[i].latency.count),
-				MinNs:    float64(results[i].latency.min_ns),
-				MaxNs:    float64(results[i].latency.max_ns),
-				AvgNs:    float64(results[i].latency.avg_ns),
-				JitterNs: float64(results[i].latency.jitter_ns),
-				P50Ns:    float64(results[i].latency.p50_ns),
-				P95Ns:    float64(results[i].latency.p95_ns),
-				P99Ns:    float64(results[i].latency.p99_ns),
+				Count:    uint64(result.latency.count),
+				MinNs:    float64(result.latency.min_ns),
+				MaxNs:    float64(result.latency.max_ns),
+				AvgNs:    float64(result.latency.avg_ns),
+				JitterNs: float64(result.latency.jitter_ns),
+				P50Ns:    float64(result.latency.p50_ns),
+				P95Ns:    float64(result.latency.p95_ns),
+				P99Ns:    float64(result.latency.p99_ns),
+
+				Histogram: c.trialHistogram(),
 			},
-		}
+			FrameOrder: frameOrderFromC(&result.order),
+			Streams:    c.streamStats(),
+			Families:   c.familyStats(),
+		}, nil
+	})
+}
+
+// RunBurstTrain sends a train of bursts at the current frame size and
+// counts each burst's frames apart
+func (c *Context) RunBurstTrain(ctx context.Context, train BurstTrain) (*BurstTrainResult, error) {
+	if train.Bursts > MaxTrainBursts {
+		return nil, &Error{Op: "burst train", Err: fmt.Errorf("%d bursts, at most %d", train.Bursts, MaxTrainBursts)}
 	}
+	return call(c.calls, "RunBurstTrain", func() (*BurstTrainResult, error) {
+		defer c.watch(ctx)()
+		defer c.subs.poll(c.LiveStats)()
+
+		ctrain := C.burst_train_t{
+			burst_frames: C.uint32_t(train.Frames),
+			bursts:       C.uint32_t(train.Bursts),
+			gap_ns:       C.uint64_t(train.Gap.Nanoseconds()),
+			rate_pct:     C.double(train.RatePct),
+		}
+		result := new(C.burst_train_result_t)
+		ret := C.rfc2544_burst_train(c.ctx, C.uint32_t(c.frameSize), &ctrain, result)
+		if ctx.Err() != nil {
+			return nil, cancelled(ctx)
+		}
+		if ret < 0 {
+			return nil, codeError("burst train", int(ret))
+		}
 
-	return goResults, nil
+		r := &BurstTrainResult{
+			FrameSize:  uint32(result.frame_size),
+			FramesTx:   uint64(result.frames_sent),
+			FramesRx:   uint64(result.frames_recv),
+			LossPct:    float64(result.loss_pct),
+			ElapsedSec: float64(result.elapsed_sec),
+			Latency: LatencyStats{
+				Count:    uint64(result.latency.count),
+				MinNs:    float64(result.latency.min_ns),
+				MaxNs:    float64(result.latency.max_ns),
+				AvgNs:    float64(result.latency.avg_ns),
+				JitterNs: float64(result.latency.jitter_ns),
+				P50Ns:    float64(result.latency.p50_ns),
+				P95Ns:    float64(result.latency.p95_ns),
+				P99Ns:    float64(result.latency.p99_ns),
+			},
+		}
+		for _, b := range result.bursts[:result.burst_count] {
+			bc := BurstCount{FramesTx: uint64(b.frames_sent), FramesRx: uint64(b.frames_recv), MaxLatencyNs: float64(b.max_latency_ns)}
+			if b.last_rx_ns > b.first_rx_ns {
+				bc.RxSpanNs = uint64(b.last_rx_ns - b.first_rx_ns)
+			}
+			r.Bursts = append(r.Bursts, bc)
+		}
+		return r, nil
+	})
 }
 
-func (c *Context) runLatencyTestInternal(frameSize uint32, loadPct float64) (*LatencyResult, error) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	var result C.latency_result_t
-	ret := C.rfc2544_latency_test(c.ctx, C.uint32_t(frameSize), C.double(loadPct), &result)
-	if ret < 0 {
-		return nil, fmt.Errorf("latency test failed: %d", ret)
-	}
+// Internal wrappers for the existing methods
+func (c *Context) runLatencyTestInternal(ctx context.Context, frameSize uint32, loadPct float64) (*LatencyResult, error) {
+	return call(c.calls, "runLatencyTestInternal", func() (*LatencyResult, error) {
+		defer c.watch(ctx)()
+
+		var result C.latency_result_t
+		ret := C.rfc2544_latency_test(c.ctx, C.uint32_t(frameSize), C.double(loadPct), &result)
+		if ctx.Err() != nil {
+			return nil, cancelled(ctx)
+		}
+		if ret < 0 {
+			return nil, codeError("latency test", int(ret))
+		}
 
-	return &LatencyResult{
-		FrameSize:      uint32(result.frame_size),
-		OfferedRatePct: float64(result.offered_rate_pct),
-		Latency: LatencyStats{
-			Count:    uint64(result.latency.count),
-			MinNs:    float64(result.latency.min_ns),
-			MaxNs:    float64(result.latency.max_ns),
-			AvgNs:    float64(result.latency.avg_ns),
-			JitterNs: float64(result.latency.jitter_ns),
-			P50Ns:    float64(result.latency.p50_ns),
-			P95Ns:    float64(result.latency.p95_ns),
-			P99Ns:    float64(result.latency.p99_ns),
-		},
-	}, nil
+		return &LatencyResult{
+			FrameSize:      uint32(result.frame_size),
+			OfferedRatePct: float64(result.offered_rate_pct),
+			Latency: LatencyStats{
+				Count:    uint64(result.latency.count),
+				MinNs:    float64(result.latency.min_ns),
+				MaxNs:    float64(result.latency.max_ns),
+				AvgNs:    float64(result.latency.avg_ns),
+				JitterNs: float64(result.latency.jitter_ns),
+				P50Ns:    float64(result.latency.p50_ns),
+				P95Ns:    float64(result.latency.p95_ns),
+				P99Ns:    float64(result.latency.p99_ns),
+
+				Histogram: c.trialHistogram(),
+			},
+			Streams:  c.streamStats(),
+			Probe:    c.probeStats(),
+			Families: c.familyStats(),
+		}, nil
+	})
 }
 
-func (c *Context) runFrameLossTestInternal(frameSize uint32) ([]FrameLossPoint, error) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+func (c *Context) runFrameLossTestInternal(ctx context.Context, frameSize uint32) ([]FrameLossPoint, error) {
+	return call(c.calls, "runFrameLossTestInternal", func() ([]FrameLossPoint, error) {
+		defer c.watch(ctx)()
 
-	maxResults := 20
-	results := make([]C.frame_loss_point_t, maxResults)
-	var count C.uint32_t
+		maxResults := 20
+		results := make([]C.frame_loss_point_t, maxResults)
+		var count C.uint32_t
 
-	ret := C.rfc2544_frame_loss_test(c.ctx, C.uint32_t(frameSize), &results[0], &count)
-	if ret < 0 {
-		return nil, fmt.Errorf("frame loss test failed: %d", ret)
-	}
+		ret := C.rfc2544_frame_loss_test(c.ctx, C.uint32_t(frameSize), &results[0], &count)
+		if ctx.Err() != nil {
+			return nil, cancelled(ctx)
+		}
+		if ret < 0 {
+			return nil, codeError("frame loss test", int(ret))
+		}
 
-	goResults := make([]FrameLossPoint, count)
-	for i := 0; i < int(count); i++ {
-		goResults[i] = FrameLossPoint{
-			OfferedRatePct: float64(results[i].offered_rate_pct),
-			ActualRateMbps: float64(results[i].actual_rate_mbps),
-			FramesSent:     uint64(results[i].frames_sent),
-			FramesRecv:     uint64(results[i].frames_recv),
-			LossPct:        float64(results[i].loss_pct),
+		goResults := make([]FrameLossPoint, count)
+		for i := 0; i < int(count); i++ {
+			goResults[i] = FrameLossPoint{
+				OfferedRatePct: float64(results[i].offered_rate_pct),
+				ActualRateMbps: float64(results[i].actual_rate_mbps),
+				FramesSent:     uint64(results[i].frames_sent),
+				FramesRecv:     uint64(results[i].frames_recv),
+				LossPct:        float64(results[i].loss_pct),
+				FrameOrder:     frameOrderFromC(&results[i].order),
+			}
 		}
-	}
 
-	return goResults, nil
+		return goResults, nil
+	})
 }
 
-func (c *Context) runBackToBackTestInternal(frameSize uint32) (*BurstResult, error) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+func (c *Context) runBackToBackTestInternal(ctx context.Context, frameSize uint32) (*BurstResult, error) {
+	return call(c.calls, "runBackToBackTestInternal", func() (*BurstResult, error) {
+		defer c.watch(ctx)()
 
-	var result C.burst_result_t
-	ret := C.rfc2544_back_to_back_test(c.ctx, C.uint32_t(frameSize), &result)
-	if ret < 0 {
-		return nil, fmt.Errorf("back-to-back test failed: %d", ret)
-	}
+		var result C.burst_result_t
+		ret := C.rfc2544_back_to_back_test(c.ctx, C.uint32_t(frameSize), &result)
+		if ctx.Err() != nil {
+			return nil, cancelled(ctx)
+		}
+		if ret < 0 {
+			return nil, codeError("back-to-back test", int(ret))
+		}
 
-	return &BurstResult{
-		FrameSize:     uint32(result.frame_size),
-		MaxBurst:      uint64(result.max_burst),
-		BurstDuration: float64(result.burst_duration),
-		Trials:        uint32(result.trials),
-	}, nil
+		return &BurstResult{
+			FrameSize:     uint32(result.frame_size),
+			MaxBurst:      uint64(result.max_burst),
+			BurstDuration: float64(result.burst_duration),
+			Trials:        uint32(result.trials),
+		}, nil
+	})
 }
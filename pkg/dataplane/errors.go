@@ -0,0 +1,83 @@
+package dataplane
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"syscall"
+)
+
+// Errors the dataplane's failures match with errors.Is, whichever backend
+// reported them
+var (
+	ErrPermission           = errors.New("permission denied (run as root or with CAP_NET_RAW)")
+	ErrNoSuchInterface      = errors.New("no such interface")
+	ErrDPDKInitFailed       = errors.New("DPDK initialization failed (check the EAL arguments, hugepages and that the NIC is bound to DPDK)")
+	ErrTimestampUnsupported = errors.New("hardware timestamping not supported")
+	ErrCancelled            = errors.New("test cancelled")
+	ErrStalled              = errors.New("dataplane stalled (no heartbeat)")
+	ErrFault                = errors.New("dataplane fault")
+	ErrClosed               = errors.New("dataplane context closed")
+	ErrLibraryVersion       = errors.New("incompatible librfc2544 version")
+	ErrUnsupported          = errors.New("not supported by the dataplane")
+)
+
+// Return codes of the C dataplane beyond the errno range, mirroring the
+// RFC2544_E* codes in rfc2544.h
+const (
+	codeDPDKInit      = 4097
+	codeNoHWTimestamp = 4098
+)
+
+// Error is a failed dataplane call. Err is one of the Err* values above,
+// or the syscall.Errno the C dataplane returned.
+type Error struct {
+	Op   string // What failed, e.g. "throughput test"
+	Code int    // The C return code, a negative errno; 0 for pure-Go failures
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Op + " failed: " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// codeError converts a negative C return code to an *Error
+func codeError(op string, ret int) error {
+	return &Error{Op: op, Code: ret, Err: codeErr(-ret)}
+}
+
+// codeErr maps a positive errno or RFC2544_E* code to its error
+func codeErr(code int) error {
+	switch code {
+	case int(syscall.EPERM), int(syscall.EACCES):
+		return ErrPermission
+	case int(syscall.ENODEV), int(syscall.ENXIO):
+		return ErrNoSuchInterface
+	case int(syscall.ECANCELED):
+		return ErrCancelled
+	case codeDPDKInit:
+		return ErrDPDKInitFailed
+	case codeNoHWTimestamp:
+		return ErrTimestampUnsupported
+	}
+	return syscall.Errno(code)
+}
+
+// errnoErr maps the errno of a failed system call to its Err* value
+func errnoErr(err error) error {
+	var errno syscall.Errno
+	if errors.As(err, &errno) {
+		return codeErr(int(errno))
+	}
+	return err
+}
+
+// cancelled is the error of a test ended by ctx. It matches both
+// ErrCancelled and the context's error.
+func cancelled(ctx context.Context) error {
+	return fmt.Errorf("%w: %w", ErrCancelled, ctx.Err())
+}
@@ -0,0 +1,61 @@
+package dataplane
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"syscall"
+	"testing"
+)
+
+func TestCodeError(t *testing.T) {
+	cases := []struct {
+		ret  int
+		want error
+	}{
+		{-int(syscall.EPERM), ErrPermission},
+		{-int(syscall.EACCES), ErrPermission},
+		{-int(syscall.ENODEV), ErrNoSuchInterface},
+		{-int(syscall.ECANCELED), ErrCancelled},
+		{-codeDPDKInit, ErrDPDKInitFailed},
+		{-codeNoHWTimestamp, ErrTimestampUnsupported},
+		{-int(syscall.EINVAL), syscall.EINVAL},
+	}
+	for _, c := range cases {
+		err := fmt.Errorf("frame size 512: %w", codeError("throughput test", c.ret))
+		if !errors.Is(err, c.want) {
+			t.Errorf("%d: %v is not %v", c.ret, err, c.want)
+		}
+		var de *Error
+		if !errors.As(err, &de) || de.Code != c.ret || de.Op != "throughput test" {
+			t.Errorf("%d: error %+v", c.ret, de)
+		}
+	}
+
+	err := codeError("configure", -int(syscall.EINVAL))
+	if got := err.Error(); got != "configure failed: invalid argument" {
+		t.Errorf("message %q", got)
+	}
+	if errors.Is(err, ErrPermission) {
+		t.Error("EINVAL matched ErrPermission")
+	}
+}
+
+func TestErrnoErr(t *testing.T) {
+	err := fmt.Errorf("AF_PACKET socket on eth0: %w", errnoErr(syscall.EPERM))
+	if !errors.Is(err, ErrPermission) {
+		t.Errorf("%v is not ErrPermission", err)
+	}
+	if err := errnoErr(syscall.ENOBUFS); !errors.Is(err, syscall.ENOBUFS) {
+		t.Errorf("%v lost its errno", err)
+	}
+}
+
+func TestCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := cancelled(ctx)
+	if !errors.Is(err, ErrCancelled) || !errors.Is(err, context.Canceled) {
+		t.Errorf("%v matches neither ErrCancelled nor context.Canceled", err)
+	}
+}
@@ -0,0 +1,74 @@
+package dataplane
+
+import (
+	"math"
+	"sort"
+)
+
+// Latency samples a trial keeps for its statistics and histogram
+const (
+	DefaultLatencySamples = 10000
+	MaxLatencySamples     = 10000000 // 80 MB of samples
+)
+
+// LatencyBucket counts the samples above the previous bucket's bound and
+// at or below UpperNs
+type LatencyBucket struct {
+	UpperNs uint64
+	Count   uint64
+}
+
+// LatencyHistogram is the latency distribution of one trial, in buckets
+// two significant digits wide (exact below 100 ns, at most 10% above), for
+// tail-latency analysis and CDF plots
+type LatencyHistogram struct {
+	Samples uint64          // Samples bucketed; at most Config.LatencySamples per trial
+	Buckets []LatencyBucket // Non-empty buckets in ascending order
+}
+
+// NewLatencyHistogram buckets round-trip samples in nanoseconds. It
+// returns nil for no samples.
+func NewLatencyHistogram(samples []uint64) *LatencyHistogram {
+	if len(samples) == 0 {
+		return nil
+	}
+	counts := make(map[uint64]uint64)
+	for _, s := range samples {
+		counts[bucketUpper(s)]++
+	}
+	h := &LatencyHistogram{Samples: uint64(len(samples))}
+	for upper, n := range counts {
+		h.Buckets = append(h.Buckets, LatencyBucket{UpperNs: upper, Count: n})
+	}
+	sort.Slice(h.Buckets, func(i, j int) bool { return h.Buckets[i].UpperNs < h.Buckets[j].UpperNs })
+	return h
+}
+
+// bucketUpper rounds ns up to two significant digits
+func bucketUpper(ns uint64) uint64 {
+	scale := uint64(1)
+	for ns/scale >= 100 {
+		scale *= 10
+	}
+	return (ns + scale - 1) / scale * scale
+}
+
+// Percentile returns the upper bound of the bucket holding the p-th
+// percentile (0-100) of the samples, e.g. 99.9 for the tail
+func (h *LatencyHistogram) Percentile(p float64) float64 {
+	if h == nil || h.Samples == 0 {
+		return 0
+	}
+	rank := uint64(math.Ceil(p / 100 * float64(h.Samples)))
+	if rank < 1 {
+		rank = 1
+	}
+	var seen uint64
+	for _, b := range h.Buckets {
+		seen += b.Count
+		if seen >= rank {
+			return float64(b.UpperNs)
+		}
+	}
+	return float64(h.Buckets[len(h.Buckets)-1].UpperNs)
+}
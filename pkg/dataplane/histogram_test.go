@@ -0,0 +1,53 @@
+package dataplane
+
+import "testing"
+
+func TestBucketUpper(t *testing.T) {
+	tests := []struct{ ns, want uint64 }{
+		{0, 0}, {7, 7}, {99, 99}, {100, 100}, {101, 110}, {999, 1000},
+		{1000, 1000}, {1001, 1100}, {12345, 13000}, {99000, 99000},
+	}
+	for _, tt := range tests {
+		if got := bucketUpper(tt.ns); got != tt.want {
+			t.Errorf("bucketUpper(%d) = %d, want %d", tt.ns, got, tt.want)
+		}
+	}
+}
+
+func TestLatencyHistogram(t *testing.T) {
+	if h := NewLatencyHistogram(nil); h != nil {
+		t.Errorf("histogram of no samples: %+v", h)
+	}
+
+	// 990 samples near 5 us and a 10 sample tail at 80 us
+	var samples []uint64
+	for i := 0; i < 990; i++ {
+		samples = append(samples, 5000+uint64(i%50))
+	}
+	for i := 0; i < 10; i++ {
+		samples = append(samples, 80000)
+	}
+	h := NewLatencyHistogram(samples)
+	if h.Samples != 1000 {
+		t.Errorf("%d samples", h.Samples)
+	}
+	var total uint64
+	for i, b := range h.Buckets {
+		total += b.Count
+		if i > 0 && b.UpperNs <= h.Buckets[i-1].UpperNs {
+			t.Errorf("buckets out of order: %+v", h.Buckets)
+		}
+	}
+	if total != h.Samples {
+		t.Errorf("buckets hold %d of %d samples", total, h.Samples)
+	}
+	if len(h.Buckets) != 3 || h.Buckets[0] != (LatencyBucket{UpperNs: 5000, Count: 20}) {
+		t.Errorf("buckets %+v", h.Buckets)
+	}
+
+	for _, tt := range []struct{ p, want float64 }{{0, 5000}, {50, 5100}, {99, 5100}, {99.5, 80000}, {100, 80000}} {
+		if got := h.Percentile(tt.p); got != tt.want {
+			t.Errorf("Percentile(%g) = %g, want %g", tt.p, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+//go:build !cgo || purego
+
+package dataplane
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Classic pcap with nanosecond timestamps, as src/dataplane/common/capture.c
+const (
+	pcapMagicNS        = 0xa1b23c4d
+	pcapLinkTypeEther  = 1
+	pcapFileHeaderLen  = 24
+	pcapRecordLen      = 16
+	pcapDefaultSnaplen = MaxCaptureSnaplen
+)
+
+// pcapWriter writes the first limit frames it is given to a pcap file
+type pcapWriter struct {
+	mu      sync.Mutex
+	f       *os.File
+	w       *bufio.Writer
+	frames  uint64
+	limit   uint64
+	snaplen uint32
+	rec     [pcapRecordLen]byte
+}
+
+// createPcap creates a pcap file keeping the first snaplen bytes (0 =
+// all) of each of the first limit frames
+func createPcap(path string, limit, snaplen uint32) (*pcapWriter, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	w := &pcapWriter{f: f, w: bufio.NewWriterSize(f, 1<<16), limit: uint64(limit), snaplen: snaplen}
+	var hdr [pcapFileHeaderLen]byte
+	binary.NativeEndian.PutUint32(hdr[0:], pcapMagicNS)
+	binary.NativeEndian.PutUint16(hdr[4:], 2)
+	binary.NativeEndian.PutUint16(hdr[6:], 4)
+	snap := snaplen
+	if snap == 0 {
+		snap = pcapDefaultSnaplen
+	}
+	binary.NativeEndian.PutUint32(hdr[16:], snap)
+	binary.NativeEndian.PutUint32(hdr[20:], pcapLinkTypeEther)
+	if _, err := w.w.Write(hdr[:]); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// write records a frame seen at ts, until the limit or a write fails
+func (w *pcapWriter) write(frame []byte, ts time.Time) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.f == nil || w.frames >= w.limit {
+		return
+	}
+	incl := len(frame)
+	if w.snaplen != 0 && incl > int(w.snaplen) {
+		incl = int(w.snaplen)
+	}
+	binary.NativeEndian.PutUint32(w.rec[0:], uint32(ts.Unix()))
+	binary.NativeEndian.PutUint32(w.rec[4:], uint32(ts.Nanosecond()))
+	binary.NativeEndian.PutUint32(w.rec[8:], uint32(incl))
+	binary.NativeEndian.PutUint32(w.rec[12:], uint32(len(frame)))
+	w.w.Write(w.rec[:])
+	if _, err := w.w.Write(frame[:incl]); err != nil {
+		// Stop capturing rather than fail the trial
+		w.f.Close()
+		w.f = nil
+		return
+	}
+	w.frames++
+}
+
+// count returns the frames written
+func (w *pcapWriter) count() uint64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.frames
+}
+
+// flush writes buffered frames to the file
+func (w *pcapWriter) flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.f != nil {
+		w.w.Flush()
+	}
+}
+
+// close flushes and closes the file
+func (w *pcapWriter) close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.f == nil {
+		return nil
+	}
+	err := w.w.Flush()
+	if cerr := w.f.Close(); err == nil {
+		err = cerr
+	}
+	w.f = nil
+	return err
+}
+
+// captureFiles are the open capture files of a Context
+type captureFiles struct {
+	tx, rx *pcapWriter // nil = direction not captured
+}
+
+// openCapture creates the files of a Capture, or returns nil if it
+// captures nothing
+func openCapture(cfg Capture) (*captureFiles, error) {
+	files := &captureFiles{}
+	var err error
+	if cfg.TX != "" {
+		if files.tx, err = createPcap(cfg.TX, cfg.limit(), cfg.Snaplen); err != nil {
+			return nil, fmt.Errorf("capture: %w", err)
+		}
+	}
+	if cfg.RX != "" {
+		if files.rx, err = createPcap(cfg.RX, cfg.limit(), cfg.Snaplen); err != nil {
+			files.close()
+			return nil, fmt.Errorf("capture: %w", err)
+		}
+	}
+	if files.tx == nil && files.rx == nil {
+		return nil, nil
+	}
+	return files, nil
+}
+
+func (f *captureFiles) stats() CaptureStats {
+	var s CaptureStats
+	if f.tx != nil {
+		s.TxFrames = f.tx.count()
+	}
+	if f.rx != nil {
+		s.RxFrames = f.rx.count()
+	}
+	return s
+}
+
+func (f *captureFiles) flush() {
+	for _, w := range []*pcapWriter{f.tx, f.rx} {
+		if w != nil {
+			w.flush()
+		}
+	}
+}
+
+func (f *captureFiles) close() {
+	for _, w := range []*pcapWriter{f.tx, f.rx} {
+		if w != nil {
+			w.close()
+		}
+	}
+}
+
+// capturePort records the frames a port sends to tx and those it
+// receives to rx, either of which may be nil
+type capturePort struct {
+	port
+	tx, rx *pcapWriter
+}
+
+func (p *capturePort) send(frame []byte) (bool, error) {
+	ok, err := p.port.send(frame)
+	if ok && err == nil && p.tx != nil {
+		p.tx.write(frame, time.Now())
+	}
+	return ok, err
+}
+
+func (p *capturePort) recv(buf []byte) (int, error) {
+	n, err := p.port.recv(buf)
+	if n > 0 && p.rx != nil {
+		p.rx.write(buf[:n], time.Now())
+	}
+	return n, err
+}
@@ -0,0 +1,2202 @@
+//go:build !cgo || purego
+
+package dataplane
+
+// The pure-Go dataplane runs the RFC 2544 tests over AF_PACKET sockets
+// without librfc2544, libxdp or a C toolchain. It builds with CGO_ENABLED=0
+// or -tags purego. Frames are sent one system call at a time, so it
+// saturates at a few hundred thousand frames per second: enough for
+// functional checks and slow links, not for line rate on fast ports.
+// Frames carry the same payload as the C dataplane's, so they are
+// returned by the same reflector.
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Backend names the dataplane the binary was built with
+const Backend = "pure-Go AF_PACKET (reduced performance)"
+
+// PureGo reports whether the pure-Go dataplane is in use
+const PureGo = true
+
+// Library returns nil: the pure-Go dataplane uses no C library
+func Library() *LibraryVersion {
+	return nil
+}
+
+// unsupported is the error for features that need the C dataplane
+func unsupported(feature string) error {
+	return fmt.Errorf("%s is not available in the pure-Go dataplane; build with CGO and librfc2544", feature)
+}
+
+// port sends and receives raw Ethernet frames on one interface
+type port interface {
+	// send transmits a frame, returning false if the kernel dropped it
+	send(frame []byte) (bool, error)
+	// recv reads a frame returned to the interface, or returns 0 after a
+	// short timeout
+	recv(buf []byte) (int, error)
+	stats() PortStats
+	close()
+}
+
+// RFC 2544 payload layout, as in include/rfc2544.h
+const (
+	payloadLen        = 24
+	signature         = "RFC2544"
+	seqOffset         = 7
+	timestampOffset   = 11
+	streamIDOffset    = 19
+	probeStream       = 0xffffffff // Stream ID of latency probe frames, as RFC2544_PROBE_STREAM
+	flagsOffset       = 23
+	flagReqTS         = 0x01
+	flagCRC32         = 0x04
+	crcLen            = 4 // CRC-32 trailer with VerifyPayload
+	minFrameSize      = 14 + 20 + 8 + payloadLen
+	llcSNAPLen        = 8
+	max8023Length     = 1500
+	vlanTagLen        = 4
+	mplsLabelLen      = 4
+	etherTypeMPLS     = 0x8847
+	etherTypeIPv6     = 0x86dd
+	ipv6HeaderLen     = 40
+	tpid8021Q         = 0x8100
+	tpid8021AD        = 0x88a8
+	maxTemplateHeader = 128
+	straggleWait      = 100 * time.Millisecond
+)
+
+// Context runs tests on AF_PACKET sockets. Its methods fall in the same
+// two groups as those of the C dataplane's Context: probes run at once,
+// beside a test, and calls take the context lock one at a time.
+type Context struct {
+	mu        sync.Mutex
+	config    Config
+	frameSize uint32
+	lineRate  uint64
+	profile   SpeedProfile
+	caps      Capabilities
+	srcMAC    net.HardwareAddr
+	dstMAC    net.HardwareAddr
+	framing   Framing
+	tpl       PacketTemplate
+	tx, rx    port // Open with the first trial; rx is tx without RXInterface
+	epoch     time.Time
+	capture   *captureFiles // Open from Configure to Close; nil = not capturing
+	captured  CaptureStats  // Of the files last closed
+
+	state     atomic.Int32
+	cancel    atomic.Bool
+	heartbeat atomic.Uint64 // Bumped by the trial loops
+	abandoned atomic.Bool   // Given up on by a Watchdog while a call is stuck in it
+	subs      subscribers
+	spare     trialBuffers // The last trial's buffers, for the next to reuse
+
+	liveMu      sync.Mutex
+	live        LiveStats
+	doneTx      uint64 // Live counters of completed trials
+	doneRx      uint64
+	doneTxBytes uint64
+	doneRxBytes uint64
+	trialStart  time.Time // Of the running trial, while live.InTrial
+}
+
+// NewContext creates a new RFC2544 test context
+func NewContext(iface string) (*Context, error) {
+	ifi, err := net.InterfaceByName(iface)
+	if err != nil {
+		return nil, &Error{Op: "init", Err: fmt.Errorf("interface %s: %w", iface, ErrNoSuchInterface)}
+	}
+	c := &Context{
+		srcMAC:   net.HardwareAddr{0x02, 0, 0, 0, 0, 0x01},
+		dstMAC:   net.HardwareAddr{0x02, 0, 0, 0, 0, 0x02},
+		lineRate: GetLineRate(iface),
+		epoch:    time.Now(),
+	}
+	if len(ifi.HardwareAddr) == 6 {
+		c.srcMAC = ifi.HardwareAddr
+	}
+	c.profile = ProfileFor(c.lineRate)
+	c.config.Interface = iface
+	return c, nil
+}
+
+// Configure applies test configuration
+func (c *Context) Configure(cfg *Config) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if cfg.UseDPDK {
+		return unsupported("DPDK")
+	}
+	if cfg.Streams > MaxStreams {
+		return fmt.Errorf("configure failed: %d streams exceeds %d", cfg.Streams, MaxStreams)
+	}
+	if cfg.VLANID > MaxVLANID || cfg.PCP > 7 {
+		return fmt.Errorf("configure failed: VLAN %d PCP %d out of range", cfg.VLANID, cfg.PCP)
+	}
+	if cfg.OuterVLANID > MaxVLANID || cfg.OuterPCP > 7 {
+		return fmt.Errorf("configure failed: S-tag VLAN %d PCP %d out of range", cfg.OuterVLANID, cfg.OuterPCP)
+	}
+	for _, tpid := range []uint16{cfg.TPID, cfg.OuterTPID} {
+		if tpid != 0 && !ValidTPID(tpid) {
+			return fmt.Errorf("configure failed: TPID 0x%04x not supported", tpid)
+		}
+	}
+	if err := ValidateMPLS(cfg.MPLSLabels); err != nil {
+		return fmt.Errorf("configure failed: %w", err)
+	}
+	if p := cfg.LatencyProbe; p.PCP > 7 || p.DSCP > 63 {
+		return fmt.Errorf("configure failed: latency probe PCP %d DSCP %d out of range", p.PCP, p.DSCP)
+	}
+	if err := ValidateIP(cfg.IP); err != nil {
+		return fmt.Errorf("configure failed: %w", err)
+	}
+	if err := ValidateHeaders(cfg.Headers); err != nil {
+		return fmt.Errorf("configure failed: %w", err)
+	}
+	if err := ValidatePattern(cfg.Payload); err != nil {
+		return fmt.Errorf("configure failed: %w", err)
+	}
+	if err := ValidateLatencySamples(cfg.LatencySamples); err != nil {
+		return fmt.Errorf("configure failed: %w", err)
+	}
+	if err := ValidateCapture(cfg.Capture); err != nil {
+		return fmt.Errorf("configure failed: %w", err)
+	}
+	caps, err := checkCapabilities(cfg)
+	if err != nil {
+		return err
+	}
+	lineRate := cfg.LineRate
+	if lineRate == 0 {
+		lineRate = GetLineRate(cfg.Interface)
+	}
+	profile, err := selectProfile(cfg.SpeedProfile, lineRate)
+	if err != nil {
+		return err
+	}
+	c.closeCapture()
+	capture, err := openCapture(cfg.Capture)
+	if err != nil {
+		return &Error{Op: "configure", Err: err}
+	}
+	c.capture = capture
+	c.captured = CaptureStats{}
+	c.config = *cfg
+	c.lineRate = lineRate
+	c.profile = profile
+	c.caps = caps
+	return nil
+}
+
+// closeCapture closes the capture files, keeping their counts. Open
+// ports are closed too, to reopen without them.
+func (c *Context) closeCapture() {
+	if c.capture == nil {
+		return
+	}
+	c.captured = c.capture.stats()
+	c.capture.close()
+	c.capture = nil
+	c.closePorts()
+}
+
+// CaptureStats returns the frames written to the capture files since
+// Configure
+func (c *Context) CaptureStats() CaptureStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.capture != nil {
+		return c.capture.stats()
+	}
+	return c.captured
+}
+
+// SetModifiers applies RFC 2544 Section 11 modifiers to subsequent trials.
+// Only the zero value is supported.
+func (c *Context) SetModifiers(m Modifiers) error {
+	if m.BroadcastPct > 0 {
+		return unsupported("broadcast frames")
+	}
+	return nil
+}
+
+// SetAddressPairs sets how many distinct source/destination address pairs
+// subsequent trials rotate through. Only a single pair is supported.
+func (c *Context) SetAddressPairs(pairs uint32) error {
+	if pairs > 1 {
+		return unsupported("multiple address pairs")
+	}
+	return nil
+}
+
+// SetAddressTable sets the address pairs subsequent trials rotate through
+func (c *Context) SetAddressTable(pairs []AddressPair) error {
+	return unsupported("address pools")
+}
+
+// SetAddressLearnRate limits how fast address pairs are introduced. With
+// a single pair there is nothing to ramp, so it has no effect.
+func (c *Context) SetAddressLearnRate(perSec uint32) error {
+	return nil
+}
+
+// AddressLearning returns the learning ramp run since the addresses were
+// set; the pure-Go dataplane never runs one
+func (c *Context) AddressLearning() *AddressLearning {
+	return nil
+}
+
+// SetFraming sets the EtherType and encapsulation of frames generated by
+// subsequent trials
+func (c *Context) SetFraming(f Framing) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.framing = f
+	return nil
+}
+
+// SetPacketTemplate sets the headers of frames generated by subsequent
+// trials, overriding the framing
+func (c *Context) SetPacketTemplate(t PacketTemplate) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(t.Header) > maxTemplateHeader {
+		return fmt.Errorf("packet template header too long: %d bytes", len(t.Header))
+	}
+	c.tpl = t
+	return nil
+}
+
+// SetControlPlaneStress configures control-plane traffic sent alongside
+// subsequent trials. Only the zero value, no stress, is supported.
+func (c *Context) SetControlPlaneStress(cp ControlPlaneStress) error {
+	if cp.ICMPPerSec > 0 || cp.ARPPerSec > 0 || cp.BGPPerSec > 0 {
+		return unsupported("control-plane stress")
+	}
+	return nil
+}
+
+// ControlPlaneStats returns control-plane frames sent and answered, always
+// zero in the pure-Go dataplane
+func (c *Context) ControlPlaneStats() ControlPlaneStats {
+	return ControlPlaneStats{}
+}
+
+// Run runs the configured test until it completes or ctx ends
+func (c *Context) Run(ctx context.Context) error {
+	c.mu.Lock()
+	if c.frameSize == 0 {
+		c.frameSize = c.config.FrameSize
+	}
+	test := c.config.TestType
+	c.mu.Unlock()
+
+	var err error
+	switch test {
+	case TestThroughput:
+		_, err = c.RunThroughputTest(ctx)
+	case TestLatency:
+		_, err = c.RunLatencyTest(ctx, []float64{10, 20, 30, 40, 50, 60, 70, 80, 90, 100})
+	case TestFrameLoss:
+		_, err = c.RunFrameLossTest(ctx, 100, 10, 10)
+	case TestBackToBack:
+		_, err = c.RunBackToBackTest(ctx, 2, 50)
+	default:
+		err = unsupported(fmt.Sprintf("test type %d", test))
+	}
+	if ctx.Err() != nil {
+		return cancelled(ctx)
+	}
+	if err != nil {
+		return fmt.Errorf("run failed: %w", err)
+	}
+	return nil
+}
+
+// Cancel stops a running test, which returns what it has measured. A
+// test ended by its context instead returns the context's error.
+func (c *Context) Cancel() {
+	c.cancel.Store(true)
+}
+
+// State returns the current test state
+func (c *Context) State() TestState {
+	return TestState(c.state.Load())
+}
+
+// LiveStats returns the live counters. Like State and Cancel it does not
+// take the context lock, so it can be polled while a test method runs.
+func (c *Context) LiveStats() LiveStats {
+	c.liveMu.Lock()
+	defer c.liveMu.Unlock()
+	ls := c.live
+	if ls.InTrial {
+		ls.TrialElapsed = time.Since(c.trialStart)
+	}
+	return ls
+}
+
+// Heartbeat returns a counter that advances while a trial runs. It does
+// not take the context lock either.
+func (c *Context) Heartbeat() uint64 {
+	return c.heartbeat.Load()
+}
+
+// PortStats returns the counters of each opened port: the TX port, then
+// the RX port when RXInterface is set. Ports open with the first trial.
+func (c *Context) PortStats() []PortStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.tx == nil {
+		return nil
+	}
+	stats := []PortStats{c.tx.stats()}
+	if c.rx != c.tx {
+		stats = append(stats, c.rx.stats())
+	}
+	return stats
+}
+
+// Close cleans up resources. A context abandoned by a Watchdog is left
+// to the call stuck in it.
+func (c *Context) Close() {
+	if c.abandoned.Load() {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closeCapture()
+	c.closePorts()
+	c.subs.closeAll()
+}
+
+func (c *Context) closePorts() {
+	if c.tx != nil {
+		c.tx.close()
+		if c.rx != c.tx {
+			c.rx.close()
+		}
+		c.tx, c.rx = nil, nil
+	}
+}
+
+// New creates a new RFC2544 context with configuration
+func New(cfg Config) (*Context, error) {
+	ctx, err := NewContext(cfg.Interface)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ctx.Configure(&cfg); err != nil {
+		ctx.Close()
+		return nil, err
+	}
+
+	return ctx, nil
+}
+
+// SetFrameSize sets the frame size for subsequent tests
+func (c *Context) SetFrameSize(frameSize uint32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.frameSize = frameSize
+}
+
+// Capabilities returns what the configured ports can do
+func (c *Context) Capabilities() Capabilities {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.caps
+}
+
+// SetAcceptableLoss sets the loss a throughput trial may show and still
+// pass, overriding the configured value for subsequent searches
+func (c *Context) SetAcceptableLoss(lossPct float64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if lossPct < 0 || lossPct > 100 {
+		return fmt.Errorf("set acceptable loss failed: %g%% out of range", lossPct)
+	}
+	c.config.AcceptableLoss = lossPct
+	return nil
+}
+
+// RunThroughputTest runs the throughput binary search for the frame size
+func (c *Context) RunThroughputTest(ctx context.Context) (*ThroughputResultCLI, error) {
+	return c.RunThroughputSearch(ctx, nil, nil)
+}
+
+// RunThroughputSearch runs the throughput binary search from search, or
+// from the start if search is nil, calling step after every iteration
+func (c *Context) RunThroughputSearch(ctx context.Context, search *ThroughputSearch, step func(*ThroughputSearch)) (_ *ThroughputResultCLI, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	defer c.begin(ctx)(&err)
+
+	s := ThroughputSearch{HighPct: c.config.InitialRatePct}
+	if search != nil {
+		s = *search
+		s.Trials = append([]ThroughputTrial(nil), search.Trials...)
+	}
+
+	var streams []StreamStats // Of the best passing trial
+	var families []FamilyStats
+	for s.HighPct-s.LowPct > c.config.ResolutionPct && s.Iterations < c.config.MaxIterations && !c.cancel.Load() {
+		rate := (s.LowPct + s.HighPct) / 2
+		trial, err := c.runTrial(c.frameSize, rate, c.config.TrialDuration, c.config.WarmupPeriod, c.config.MeasureLatency)
+		if err != nil {
+			return nil, fmt.Errorf("throughput test failed: %w", err)
+		}
+		s.FramesTested += trial.sent
+		passed := trial.lossPct <= c.config.AcceptableLoss
+		if passed {
+			s.BestRatePct = rate
+			s.LowPct = rate
+			s.Latency = trial.latency
+			streams = trial.streams
+			families = trial.families
+		} else {
+			s.HighPct = rate
+		}
+		s.Iterations++
+		latency := trial.latency
+		latency.Histogram = nil // Only the result keeps the best trial's
+		s.Trials = append(s.Trials, ThroughputTrial{
+			RatePct:  rate,
+			FramesTx: trial.sent,
+			FramesRx: trial.recv,
+			LossPct:  trial.lossPct,
+			Passed:   passed,
+			Latency:  latency,
+
+			FrameOrder: trial.order,
+		})
+		if step != nil {
+			next := s
+			next.Trials = append([]ThroughputTrial(nil), s.Trials...)
+			step(&next)
+		}
+	}
+	if ctx.Err() != nil {
+		return nil, cancelled(ctx)
+	}
+
+	return &ThroughputResultCLI{
+		FrameSize:   c.frameSize,
+		MaxRatePct:  s.BestRatePct,
+		MaxRateMbps: float64(c.lineRate) * s.BestRatePct / 100 / 1e6,
+		MaxRatePPS:  math.Floor(float64(CalcPPS(c.lineRate, c.frameSize)) * s.BestRatePct / 100),
+		Iterations:  s.Iterations,
+		Latency:     s.Latency,
+
+		AcceptableLossPct: c.config.AcceptableLoss,
+		FrameOrder:        bestOrder(s.Trials),
+		Streams:           streams,
+		Families:          families,
+		Trials:            s.Trials,
+	}, nil
+}
+
+// RunLatencyTest runs latency test at multiple load levels
+func (c *Context) RunLatencyTest(ctx context.Context, loadLevels []float64) (_ []LatencyResultCLI, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	defer c.begin(ctx)(&err)
+
+	var results []LatencyResultCLI
+	for _, load := range loadLevels {
+		if c.cancel.Load() {
+			break
+		}
+		trial, err := c.runTrial(c.frameSize, load, c.config.TrialDuration, c.config.WarmupPeriod, true)
+		if err != nil {
+			continue
+		}
+		results = append(results, LatencyResultCLI{
+			FrameSize: c.frameSize,
+			LoadPct:   load,
+			Latency:   trial.latency,
+			Streams:   trial.streams,
+			Probe:     trial.probe,
+			Families:  trial.families,
+		})
+	}
+	if ctx.Err() != nil {
+		return nil, cancelled(ctx)
+	}
+
+	if len(results) == 0 {
+		return nil, fmt.Errorf("no latency results")
+	}
+
+	return results, nil
+}
+
+// RunFrameLossTest runs frame loss trials from startPct down to endPct in
+// steps of stepPct
+func (c *Context) RunFrameLossTest(ctx context.Context, startPct, endPct, stepPct float64) (_ []FrameLossResultCLI, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	defer c.begin(ctx)(&err)
+
+	if stepPct <= 0 {
+		return nil, fmt.Errorf("frame loss test failed: step %g%% must be positive", stepPct)
+	}
+
+	var results []FrameLossResultCLI
+	for rate := startPct; rate >= endPct && !c.cancel.Load(); rate -= stepPct {
+		trial, err := c.runTrial(c.frameSize, rate, c.config.TrialDuration, c.config.WarmupPeriod, c.config.MeasureLatency)
+		if err != nil {
+			return nil, fmt.Errorf("frame loss test failed: %w", err)
+		}
+		results = append(results, FrameLossResultCLI{
+			FrameSize:  c.frameSize,
+			OfferedPct: rate,
+			FramesTx:   trial.sent,
+			FramesRx:   trial.recv,
+			LossPct:    trial.lossPct,
+			FrameOrder: trial.order,
+			Streams:    trial.streams,
+		})
+	}
+	if ctx.Err() != nil {
+		return nil, cancelled(ctx)
+	}
+
+	return results, nil
+}
+
+// RunBackToBackTest sends bursts of frames as fast as the socket accepts
+// them, doubling from initialBurst while trials bursts in a row return
+// without loss
+func (c *Context) RunBackToBackTest(ctx context.Context, initialBurst uint64, trials uint32) (_ *BackToBackResultCLI, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	defer c.begin(ctx)(&err)
+
+	if initialBurst == 0 {
+		initialBurst = 2
+	}
+	var maxBurst uint64
+	var passed uint32
+	const maxPossible = 1000000 // Cap at 1M frames, as the C dataplane does
+	for burst := initialBurst; burst <= maxPossible && !c.cancel.Load(); burst *= 2 {
+		ok := true
+		for t := uint32(0); t < trials && ok && !c.cancel.Load(); t++ {
+			trial, err := c.runBurst(c.frameSize, burst)
+			if err != nil {
+				return nil, fmt.Errorf("back-to-back test failed: %w", err)
+			}
+			ok = trial.lossPct == 0
+		}
+		if !ok {
+			break
+		}
+		maxBurst = burst
+		passed++
+	}
+	if ctx.Err() != nil {
+		return nil, cancelled(ctx)
+	}
+
+	var durationUs uint64
+	if pps := CalcPPS(c.lineRate, c.frameSize); pps > 0 {
+		durationUs = uint64(float64(maxBurst) * 1e6 / float64(pps))
+	}
+	return &BackToBackResultCLI{
+		FrameSize:       c.frameSize,
+		MaxBurstFrames:  maxBurst,
+		BurstDurationUs: durationUs,
+		Trials:          passed,
+	}, nil
+}
+
+// RunBurstTrain sends a train of bursts at the current frame size, each
+// paced at the train's rate and followed by its idle gap, and counts each
+// burst's frames apart
+func (c *Context) RunBurstTrain(ctx context.Context, train BurstTrain) (_ *BurstTrainResult, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	defer c.begin(ctx)(&err)
+
+	if train.Frames == 0 || train.Bursts == 0 || train.Bursts > MaxTrainBursts || train.RatePct < 0 || train.RatePct > 100 {
+		return nil, fmt.Errorf("burst train failed: invalid train of %d bursts of %d frames at %g%%", train.Bursts, train.Frames, train.RatePct)
+	}
+	result, err := c.runBurstTrain(c.frameSize, train)
+	if err != nil {
+		return nil, fmt.Errorf("burst train failed: %w", err)
+	}
+	if ctx.Err() != nil {
+		return nil, cancelled(ctx)
+	}
+	return result, nil
+}
+
+// RunSystemRecoveryTest runs RFC 2544 Section 26.5 System Recovery test
+func (c *Context) RunSystemRecoveryTest(ctx context.Context, throughputPct float64, overloadSec uint32) (*RecoveryResultCLI, error) {
+	return nil, unsupported("the system recovery test")
+}
+
+// RunResetTest runs RFC 2544 Section 26.6 Reset test
+func (c *Context) RunResetTest(ctx context.Context) (*ResetResultCLI, error) {
+	return nil, unsupported("the reset test")
+}
+
+// RunFixedRateTrial runs one trial at ratePct of line rate for duration,
+// measuring loss, delivered throughput and latency
+func (c *Context) RunFixedRateTrial(ctx context.Context, ratePct float64, duration time.Duration) (_ *FixedRateResult, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	defer c.begin(ctx)(&err)
+
+	if ratePct <= 0 || duration < time.Second {
+		return nil, fmt.Errorf("fixed-rate trial failed: invalid rate %g%% or duration %v", ratePct, duration)
+	}
+	trial, err := c.runTrial(c.frameSize, ratePct, duration.Truncate(time.Second), 0, true)
+	if err != nil {
+		return nil, fmt.Errorf("fixed-rate trial failed: %w", err)
+	}
+	if ctx.Err() != nil {
+		return nil, cancelled(ctx)
+	}
+
+	result := &FixedRateResult{
+		FrameSize:  c.frameSize,
+		OfferedPct: ratePct,
+		FramesTx:   trial.sent,
+		FramesRx:   trial.recv,
+		LossPct:    trial.lossPct,
+		ElapsedSec: trial.elapsed,
+		Latency:    trial.latency,
+		FrameOrder: trial.order,
+		Streams:    trial.streams,
+		Families:   trial.families,
+	}
+	if trial.elapsed > 0 {
+		result.DeliveredMbps = float64(trial.recv) * float64(c.frameSize) * 8 / trial.elapsed / 1e6
+	}
+	return result, nil
+}
+
+// RunY1564ConfigTest executes ITU-T Y.1564 Service Configuration Test
+func (c *Context) RunY1564ConfigTest(ctx context.Context, service *Y1564Service) (*Y1564ConfigResult, error) {
+	return nil, unsupported("Y.1564")
+}
+
+// RunY1564PerfTest executes ITU-T Y.1564 Service Performance Test
+func (c *Context) RunY1564PerfTest(ctx context.Context, service *Y1564Service, durationSec uint32) (*Y1564PerfResult, error) {
+	return nil, unsupported("Y.1564")
+}
+
+// RunRFC2889ForwardingTest runs RFC 2889 Section 5.1 forwarding rate test
+func (c *Context) RunRFC2889ForwardingTest(ctx context.Context, cfg RFC2889Config) (*RFC2889ForwardingResult, error) {
+	return nil, unsupported("RFC 2889")
+}
+
+// DiscoverOAMPeers finds far-end Ethernet OAM devices
+func (c *Context) DiscoverOAMPeers(megLevel uint8, timeout time.Duration) ([]OAMPeer, error) {
+	return nil, unsupported("Ethernet OAM")
+}
+
+// SetRemoteLoopback places a far-end port into 802.3ah remote loopback
+func (c *Context) SetRemoteLoopback(mac string, enable bool, timeout time.Duration) error {
+	return unsupported("Ethernet OAM")
+}
+
+// begin marks a test running until the returned function is called with
+// the test's error. Meanwhile ctx ending cancels the test, as Cancel does.
+func (c *Context) begin(ctx context.Context) func(*error) {
+	c.cancel.Store(ctx.Err() != nil)
+	c.state.Store(int32(StateRunning))
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		select {
+		case <-ctx.Done():
+			c.cancel.Store(true)
+		case <-done:
+		}
+	}()
+	stopStats := c.subs.poll(c.LiveStats)
+	return func(err *error) {
+		close(done)
+		<-exited
+		stopStats()
+		switch {
+		case ctx.Err() != nil:
+			c.state.Store(int32(StateCancelled))
+		case *err != nil:
+			c.state.Store(int32(StateFailed))
+		case c.cancel.Load():
+			c.state.Store(int32(StateCancelled))
+		default:
+			c.state.Store(int32(StateCompleted))
+		}
+	}
+}
+
+// trialResult is the outcome of one trial
+type trialResult struct {
+	sent, recv uint64
+	lossPct    float64
+	elapsed    float64 // Seconds of measurement
+	latency    LatencyStats
+	order      FrameOrder
+	streams    []StreamStats
+	probe      *ProbeStats
+	families   []FamilyStats
+}
+
+// openPorts opens the TX and RX sockets on first use
+func (c *Context) openPorts() error {
+	if c.tx != nil {
+		return nil
+	}
+	tx, err := openPort(c.config.Interface)
+	if err != nil {
+		return err
+	}
+	rx := tx
+	if c.config.RXInterface != "" && c.config.RXInterface != c.config.Interface {
+		if rx, err = openPort(c.config.RXInterface); err != nil {
+			tx.close()
+			return err
+		}
+	}
+	if f := c.capture; f != nil {
+		if rx == tx {
+			tx = &capturePort{port: tx, tx: f.tx, rx: f.rx}
+			rx = tx
+		} else {
+			tx = &capturePort{port: tx, tx: f.tx}
+			rx = &capturePort{port: rx, rx: f.rx}
+		}
+	}
+	c.tx, c.rx = tx, rx
+	return nil
+}
+
+// counter receives a trial's returned frames, counting those numbered
+// from first on, and sampling their latency
+type counter struct {
+	c       *Context
+	offset  int
+	tags    int           // VLAN tags on the frames; the kernel may strip the outer one on receive
+	first   atomic.Uint32 // Sequence number measurement starts at
+	recv    atomic.Uint64
+	live    atomic.Uint64
+	latency bool
+	limit   int // Latency samples kept per array
+	buf     []byte
+	samples []uint64
+	order   orderTracker
+	stop    chan struct{}
+	done    chan error
+
+	// With multiple streams, each stream's frames and the stream of each
+	// latency sample. The sender counts tx, the counter rx.
+	streams       []streamCount
+	sampleStreams []uint16
+
+	// With a latency probe, its frames apart from the load's, numbered
+	// from probeFirst on
+	probe        bool
+	probeFirst   atomic.Uint32
+	probeRecv    uint64
+	probeSamples []uint64
+
+	// With IPv6 frames, the payload offset is the frame's own. Dual-stack
+	// counts each family's frames and samples apart.
+	ipv6          bool
+	families      *[2]streamCount
+	familySamples [2][]uint64
+
+	// With a burst train, each burst's frames, burst i numbered from
+	// i*burst on
+	burst  uint32
+	bursts []burstCount
+}
+
+// streamCount is one stream's frames over a trial's measurement
+type streamCount struct {
+	tx, rx uint64
+}
+
+// burstCount is one burst's frames received, its slowest and the receive
+// times of the first and last of them
+type burstCount struct {
+	rx, maxLatency, firstRx, lastRx uint64
+}
+
+// trialBuffers are a counter's receive buffer, sample arrays and order
+// bitmap. A campaign of hundreds of trials allocates them once: each
+// trial's counter takes them emptied and hands them back when its
+// results have been copied out.
+type trialBuffers struct {
+	buf           []byte
+	samples       []uint64
+	sampleStreams []uint16
+	probeSamples  []uint64
+	familySamples [2][]uint64
+	seen          []uint64
+}
+
+func (c *Context) newCounter(offset int, latency bool, streams int) *counter {
+	r := c.makeCounter(offset, latency, streams)
+	go r.run()
+	return r
+}
+
+// makeCounter returns a counter for newCounter without starting it, for a
+// caller to set it up first
+func (c *Context) makeCounter(offset int, latency bool, streams int) *counter {
+	r := &counter{c: c, offset: offset, latency: latency, stop: make(chan struct{}), done: make(chan error, 1)}
+	c.reuse(r)
+	if len(c.tpl.Header) == 0 {
+		r.tags = len(c.vlanTags())
+		r.ipv6 = c.config.IP.Mode != IPModeV4
+		if c.config.IP.Mode == IPModeDual {
+			r.families = new([2]streamCount)
+		}
+	}
+	if streams > 1 {
+		r.streams = make([]streamCount, streams)
+	}
+	r.first.Store(math.MaxUint32)
+	return r
+}
+
+// reuse hands a new counter the last trial's buffers, emptied
+func (c *Context) reuse(r *counter) {
+	b := c.spare
+	c.spare = trialBuffers{}
+	if b.buf == nil {
+		b.buf = make([]byte, 16384)
+	}
+	r.limit = c.config.latencySamples()
+	r.buf = b.buf
+	r.samples = b.samples[:0]
+	r.sampleStreams = b.sampleStreams[:0]
+	r.probeSamples = b.probeSamples[:0]
+	r.familySamples = [2][]uint64{b.familySamples[0][:0], b.familySamples[1][:0]}
+	r.order.seen = b.seen[:0]
+}
+
+// recycle keeps a finished counter's buffers for the next trial
+func (c *Context) recycle(r *counter) {
+	c.spare = trialBuffers{
+		buf:           r.buf,
+		samples:       r.samples,
+		sampleStreams: r.sampleStreams,
+		probeSamples:  r.probeSamples,
+		familySamples: r.familySamples,
+		seen:          r.order.seen,
+	}
+}
+
+func (r *counter) run() {
+	buf := r.buf
+	for {
+		select {
+		case <-r.stop:
+			r.done <- nil
+			return
+		default:
+		}
+		n, err := r.c.rx.recv(buf)
+		if err != nil {
+			r.done <- err
+			return
+		}
+		offset := r.offset
+		if r.ipv6 {
+			offset = payloadOffset(buf[:n])
+		} else if r.tags > 0 {
+			// VLAN offload moves the outer tag out of the frame
+			offset -= (r.tags - countTags(buf[:n])) * vlanTagLen
+		}
+		seq, ts, ok := parseFrame(buf[:n], offset)
+		if !ok {
+			continue
+		}
+		now := r.c.now()
+		if r.probe && binary.BigEndian.Uint32(buf[offset+streamIDOffset:]) == probeStream {
+			r.recordProbe(buf[:n], offset, seq, ts, now)
+			continue
+		}
+		r.live.Add(1)
+		first := r.first.Load()
+		if seq < first {
+			continue
+		}
+		r.recv.Add(1)
+		family := 0
+		if r.families != nil {
+			if ipHeaderLen(buf[:n]) == ipv6HeaderLen {
+				family = 1
+			}
+			r.families[family].rx++
+		}
+		if r.c.config.VerifyPayload && !payloadIntact(buf[:n], offset) {
+			// Delivered, not lost, but its sequence number and timestamp
+			// cannot be trusted
+			r.order.order.CorruptedFrames++
+			continue
+		}
+		r.order.record(seq - first)
+		if r.bursts != nil {
+			if b := (seq - first) / r.burst; b < uint32(len(r.bursts)) {
+				r.bursts[b].record(now, ts)
+			}
+		}
+		var stream uint32
+		if r.streams != nil {
+			stream = binary.BigEndian.Uint32(buf[offset+streamIDOffset:])
+			if stream >= uint32(len(r.streams)) {
+				stream = 0
+			} else {
+				r.streams[stream].rx++
+			}
+		}
+		if r.latency && len(r.samples) < r.limit && now > ts {
+			r.samples = append(r.samples, now-ts)
+			if r.streams != nil {
+				r.sampleStreams = append(r.sampleStreams, uint16(stream))
+			}
+			if r.families != nil {
+				r.familySamples[family] = append(r.familySamples[family], now-ts)
+			}
+		}
+	}
+}
+
+// record counts a frame of the burst sent at ts and received at now
+func (b *burstCount) record(now, ts uint64) {
+	b.rx++
+	if now > ts && now-ts > b.maxLatency {
+		b.maxLatency = now - ts
+	}
+	if b.firstRx == 0 || now < b.firstRx {
+		b.firstRx = now
+	}
+	b.lastRx = max(b.lastRx, now)
+}
+
+// recordProbe counts a returned latency probe frame
+func (r *counter) recordProbe(f []byte, offset int, seq uint32, ts, now uint64) {
+	if seq < r.probeFirst.Load() {
+		return
+	}
+	r.probeRecv++
+	if r.c.config.VerifyPayload && !payloadIntact(f, offset) {
+		return
+	}
+	if len(r.probeSamples) < r.limit && now > ts {
+		r.probeSamples = append(r.probeSamples, now-ts)
+	}
+}
+
+// probeStats returns the probe's counters once the counter has finished,
+// nil without a probe
+func (r *counter) probeStats(sent uint64) *ProbeStats {
+	if !r.probe {
+		return nil
+	}
+	p := r.c.config.LatencyProbe
+	ps := &ProbeStats{PCP: p.PCP, DSCP: p.DSCP, FramesTx: sent, FramesRx: r.probeRecv, Latency: latencyStats(r.probeSamples)}
+	if sent > 0 && r.probeRecv < sent {
+		ps.LossPct = 100 * float64(sent-r.probeRecv) / float64(sent)
+	}
+	return ps
+}
+
+// streamStats returns each stream's counters, its latency over its share
+// of the samples, once the counter has finished
+func (r *counter) streamStats() []StreamStats {
+	if r.streams == nil {
+		return nil
+	}
+	samples := make([][]uint64, len(r.streams))
+	for i, s := range r.sampleStreams {
+		samples[s] = append(samples[s], r.samples[i])
+	}
+	stats := make([]StreamStats, len(r.streams))
+	for i, s := range r.streams {
+		stats[i] = StreamStats{Stream: uint32(i), FramesTx: s.tx, FramesRx: s.rx, Latency: latencyStats(samples[i])}
+		if s.tx > 0 && s.rx < s.tx {
+			stats[i].LossPct = 100 * float64(s.tx-s.rx) / float64(s.tx)
+		}
+	}
+	return stats
+}
+
+// familyStats returns each address family's counters once the counter has
+// finished, nil unless dual-stack
+func (r *counter) familyStats() []FamilyStats {
+	if r.families == nil {
+		return nil
+	}
+	stats := make([]FamilyStats, 2)
+	for i, f := range r.families {
+		stats[i] = FamilyStats{Family: []string{"ipv4", "ipv6"}[i], FramesTx: f.tx, FramesRx: f.rx, Latency: latencyStats(r.familySamples[i])}
+		if f.tx > 0 && f.rx < f.tx {
+			stats[i].LossPct = 100 * float64(f.tx-f.rx) / float64(f.tx)
+		}
+	}
+	return stats
+}
+
+// dualStack interleaves the IPv6 frame of a dual-stack trial with the
+// IPv4 frames, spreading its share evenly over the frame slots
+type dualStack struct {
+	frame  []byte
+	offset int
+	seal   func()
+	share  uint32
+	acc    uint32
+}
+
+// dualFrame returns the IPv6 frame of a dual-stack trial, nil otherwise
+func (c *Context) dualFrame(frameSize uint32) (*dualStack, error) {
+	if c.config.IP.Mode != IPModeDual || len(c.tpl.Header) > 0 {
+		return nil, nil
+	}
+	f, offset, err := c.ipFrame(frameSize, true)
+	if err != nil {
+		return nil, err
+	}
+	seal, err := c.sealer(f, offset)
+	if err != nil {
+		return nil, err
+	}
+	d := &dualStack{frame: f, offset: offset, seal: seal, share: uint32(c.config.IP.V6SharePct)}
+	if d.share == 0 {
+		d.share = 50
+	}
+	return d, nil
+}
+
+// next reports whether the next frame slot is the IPv6 frame's
+func (d *dualStack) next() bool {
+	if d == nil {
+		return false
+	}
+	d.acc += d.share
+	if d.acc < 100 {
+		return false
+	}
+	d.acc -= 100
+	return true
+}
+
+// maxOrderOffset bounds the sequence numbers tracked for frame order; 64M
+// frames is an 8 MB bitmap
+const maxOrderOffset = 1 << 26
+
+// orderTracker counts reordered and duplicate frames from their sequence
+// numbers, as offsets from the first frame measured
+type orderTracker struct {
+	seen    []uint64 // Bitmap of the offsets received
+	highest uint32
+	any     bool
+	order   FrameOrder
+}
+
+func (t *orderTracker) record(off uint32) {
+	if off >= maxOrderOffset {
+		return
+	}
+	word, mask := off/64, uint64(1)<<(off%64)
+	for uint32(len(t.seen)) <= word {
+		t.seen = append(t.seen, 0)
+	}
+	if t.seen[word]&mask != 0 {
+		t.order.DuplicateFrames++
+		return
+	}
+	t.seen[word] |= mask
+
+	// A frame sent before one already received arrived late
+	if t.any && off < t.highest {
+		t.order.ReorderedFrames++
+		if depth := t.highest - off; depth > t.order.MaxReorderDepth {
+			t.order.MaxReorderDepth = depth
+		}
+	} else {
+		t.highest, t.any = off, true
+	}
+}
+
+// finish waits for stragglers, then stops receiving
+func (r *counter) finish() error {
+	time.Sleep(straggleWait)
+	close(r.stop)
+	return <-r.done
+}
+
+// now is the timestamp written into frames: nanoseconds since the context
+// was created, on the monotonic clock
+func (c *Context) now() uint64 {
+	return uint64(time.Since(c.epoch))
+}
+
+// runTrial offers frames at ratePct of line rate for warmup, then for
+// duration, counting the frames sent during duration that return
+func (c *Context) runTrial(frameSize uint32, ratePct float64, duration, warmup time.Duration, measureLatency bool) (*trialResult, error) {
+	if err := c.openPorts(); err != nil {
+		return nil, err
+	}
+	frame, offset, err := c.buildFrame(frameSize)
+	if err != nil {
+		return nil, err
+	}
+	pps := framePPS(c.lineRate, frameSize) * ratePct / 100
+	if pps <= 0 {
+		return nil, fmt.Errorf("rate %g%% of %d bps is no frames", ratePct, c.lineRate)
+	}
+	batch := int64(c.profile.Batch(frameSize))
+	frames, seals, err := c.streamFrames(frame, offset)
+	if err != nil {
+		return nil, err
+	}
+	dual, err := c.dualFrame(frameSize)
+	if err != nil {
+		return nil, err
+	}
+	ranges := c.headerRange()
+
+	rx := c.newCounter(offset, measureLatency, len(frames))
+
+	// The latency probe goes out on its own schedule beside the paced load
+	var probe []byte
+	var probeSeal func()
+	var probeSeq uint32
+	var probeSent uint64
+	var probeInterval time.Duration
+	var probeNext time.Time
+	if pps := c.config.LatencyProbe.PPS; measureLatency && pps > 0 && len(c.tpl.Header) == 0 {
+		probe = append([]byte(nil), frame...)
+		binary.BigEndian.PutUint32(probe[offset+streamIDOffset:], probeStream)
+		markPriority(probe, offset, c.config.LatencyProbe.PCP, c.config.LatencyProbe.DSCP)
+		if probeSeal, err = c.sealer(probe, offset); err != nil {
+			rx.finish()
+			return nil, err
+		}
+		probeInterval = time.Second / time.Duration(pps)
+		rx.probe = true
+	}
+
+	var seq uint32
+	var sent, liveTx uint64
+	start := time.Now()
+	c.startTrial(frameSize, ratePct, warmup, duration, start)
+	defer c.endTrial()
+	measureAt := start.Add(warmup)
+	end := measureAt.Add(duration)
+	measuring := warmup == 0
+	if measuring {
+		rx.first.Store(0)
+	}
+	for i := int64(0); !c.cancel.Load(); i++ {
+		c.heartbeat.Add(1)
+		now := time.Now()
+		if !now.Before(end) {
+			break
+		}
+		if !measuring && !now.Before(measureAt) {
+			measuring = true
+			rx.first.Store(seq)
+			rx.probeFirst.Store(probeSeq)
+			probeSent = 0
+		}
+		if probe != nil && !now.Before(probeNext) {
+			stampFrame(probe, offset, probeSeq, c.now())
+			probeSeal()
+			ok, err := c.tx.send(probe)
+			if err != nil {
+				rx.finish()
+				return nil, err
+			}
+			if ok {
+				probeSeq++
+				if measuring {
+					probeSent++
+				}
+			}
+			probeNext = probeNext.Add(probeInterval)
+			if probeNext.Before(now) {
+				probeNext = now.Add(probeInterval)
+			}
+		}
+		stream := int(seq) % len(frames)
+		frame, at, seal, family := frames[stream], offset, seals[stream], 0
+		if dual.next() {
+			frame, at, seal, family = dual.frame, dual.offset, dual.seal, 1
+		}
+		ranges.apply(frame, at)
+		stampFrame(frame, at, seq, c.now())
+		seal()
+		ok, err := c.tx.send(frame)
+		if err != nil {
+			rx.finish()
+			return nil, err
+		}
+		if ok {
+			liveTx++
+			seq++
+			if measuring {
+				sent++
+				if rx.streams != nil {
+					rx.streams[stream].tx++
+				}
+				if rx.families != nil {
+					rx.families[family].tx++
+				}
+			}
+		}
+		if i&0x3ff == 0 {
+			c.publish(frameSize, ratePct, liveTx, rx.live.Load(), nil)
+		}
+		// Pace to the offered rate, a batch per wait at high line rates. The
+		// due time is worked out from the start rather than summed from a
+		// whole-nanosecond interval, which would drift at high rates.
+		if (i+1)%batch != 0 {
+			continue
+		}
+		due := start.Add(time.Duration(float64(i+1) * float64(time.Second) / pps))
+		if c.profile.BusyWait {
+			for time.Now().Before(due) {
+			}
+		} else if wait := time.Until(due); wait > 0 {
+			time.Sleep(wait)
+		}
+	}
+	elapsed := time.Since(measureAt).Seconds()
+	if err := rx.finish(); err != nil {
+		return nil, err
+	}
+	r := c.finishTrial(frameSize, ratePct, sent, liveTx, rx, elapsed)
+	r.probe = rx.probeStats(probeSent)
+	c.recycle(rx)
+	return r, nil
+}
+
+// runBurst sends burst frames back to back and counts those that return
+func (c *Context) runBurst(frameSize uint32, burst uint64) (*trialResult, error) {
+	if err := c.openPorts(); err != nil {
+		return nil, err
+	}
+	frame, offset, err := c.buildFrame(frameSize)
+	if err != nil {
+		return nil, err
+	}
+	frames, seals, err := c.streamFrames(frame, offset)
+	if err != nil {
+		return nil, err
+	}
+	dual, err := c.dualFrame(frameSize)
+	if err != nil {
+		return nil, err
+	}
+	ranges := c.headerRange()
+	rx := c.newCounter(offset, false, 0)
+	rx.first.Store(0)
+	start := time.Now()
+	var sent uint64
+	for sent < burst && !c.cancel.Load() {
+		c.heartbeat.Add(1)
+		stream := int(sent % uint64(len(frames)))
+		frame, at, seal := frames[stream], offset, seals[stream]
+		if dual.next() {
+			frame, at, seal = dual.frame, dual.offset, dual.seal
+		}
+		ranges.apply(frame, at)
+		stampFrame(frame, at, uint32(sent), c.now())
+		seal()
+		ok, err := c.tx.send(frame)
+		if err != nil {
+			rx.finish()
+			return nil, err
+		}
+		if !ok {
+			// The socket queue is full: the burst is no longer back to back
+			break
+		}
+		sent++
+	}
+	elapsed := time.Since(start).Seconds()
+	if err := rx.finish(); err != nil {
+		return nil, err
+	}
+	// Frames the socket would not take count as lost
+	r := c.finishTrial(frameSize, 100, burst, sent, rx, elapsed)
+	c.recycle(rx)
+	return r, nil
+}
+
+// gapSpin is how much of an idle gap is busy-waited rather than slept,
+// which would overshoot it
+const gapSpin = 500 * time.Microsecond
+
+// runBurstTrain sends the bursts of a train with the trial frame alone:
+// streams, address rotation and probes are left out, so the bursts alone
+// decide what the DUT must buffer
+func (c *Context) runBurstTrain(frameSize uint32, train BurstTrain) (*BurstTrainResult, error) {
+	if err := c.openPorts(); err != nil {
+		return nil, err
+	}
+	frame, offset, err := c.buildFrame(frameSize)
+	if err != nil {
+		return nil, err
+	}
+	seal, err := c.sealer(frame, offset)
+	if err != nil {
+		return nil, err
+	}
+	ratePct := train.RatePct
+	if ratePct == 0 {
+		ratePct = 100
+	}
+	pps := framePPS(c.lineRate, frameSize) * ratePct / 100
+	if pps <= 0 {
+		return nil, fmt.Errorf("rate %g%% of %d bps is no frames", ratePct, c.lineRate)
+	}
+
+	rx := c.makeCounter(offset, true, 0)
+	rx.burst, rx.bursts = train.Frames, make([]burstCount, train.Bursts)
+	rx.first.Store(0)
+	go rx.run()
+
+	counts := make([]BurstCount, train.Bursts)
+	var seq uint32
+	var liveTx uint64
+	start := time.Now()
+	c.startTrial(frameSize, ratePct, 0, 0, start)
+	defer c.endTrial()
+	for b := range counts {
+		if c.cancel.Load() {
+			break
+		}
+		c.heartbeat.Add(1)
+		burstStart := time.Now()
+		for f := uint32(0); f < train.Frames && !c.cancel.Load(); f++ {
+			stampFrame(frame, offset, seq, c.now())
+			seal()
+			ok, err := c.tx.send(frame)
+			if err != nil {
+				rx.finish()
+				return nil, err
+			}
+			// A frame the socket refuses leaves a hole in the burst and
+			// counts as lost
+			if ok {
+				liveTx++
+			}
+			seq++
+			counts[b].FramesTx++
+			if ratePct < 100 {
+				due := burstStart.Add(time.Duration(float64(f+1) * float64(time.Second) / pps))
+				for time.Now().Before(due) {
+				}
+			}
+		}
+		c.publish(frameSize, ratePct, liveTx, rx.live.Load(), nil)
+		idle := time.Now().Add(train.Gap)
+		if train.Gap > gapSpin {
+			time.Sleep(train.Gap - gapSpin)
+		}
+		for time.Now().Before(idle) && !c.cancel.Load() {
+		}
+	}
+	elapsed := time.Since(start).Seconds()
+	if err := rx.finish(); err != nil {
+		return nil, err
+	}
+	trial := c.finishTrial(frameSize, ratePct, uint64(seq), liveTx, rx, elapsed)
+
+	result := &BurstTrainResult{
+		FrameSize:  frameSize,
+		FramesTx:   trial.sent,
+		FramesRx:   trial.recv,
+		LossPct:    trial.lossPct,
+		ElapsedSec: elapsed,
+		Latency:    trial.latency,
+	}
+	for i, b := range rx.bursts {
+		counts[i].FramesRx = b.rx
+		counts[i].MaxLatencyNs = float64(b.maxLatency)
+		if b.lastRx > b.firstRx {
+			counts[i].RxSpanNs = b.lastRx - b.firstRx
+		}
+	}
+	result.Bursts = counts
+	c.recycle(rx)
+	return result, nil
+}
+
+// finishTrial computes a trial's loss and latency and publishes them
+func (c *Context) finishTrial(frameSize uint32, ratePct float64, sent, liveTx uint64, rx *counter, elapsed float64) *trialResult {
+	r := &trialResult{sent: sent, recv: rx.recv.Load(), elapsed: elapsed}
+	if r.sent > 0 && r.recv < r.sent {
+		r.lossPct = 100 * float64(r.sent-r.recv) / float64(r.sent)
+	}
+	r.latency = latencyStats(rx.samples)
+	r.order = rx.order.order
+	r.streams = rx.streamStats()
+	r.families = rx.familyStats()
+	if c.config.LatencyHistogram {
+		r.latency.Histogram = NewLatencyHistogram(rx.samples)
+	}
+	c.publish(frameSize, ratePct, liveTx, rx.live.Load(), r)
+	if c.capture != nil {
+		c.capture.flush()
+	}
+	return r
+}
+
+// publish updates the live counters with the running trial's tx and rx,
+// adding them to the totals of earlier trials once trial is complete
+func (c *Context) publish(frameSize uint32, ratePct float64, tx, rx uint64, trial *trialResult) {
+	c.liveMu.Lock()
+	defer c.liveMu.Unlock()
+	c.live.FrameSize = frameSize
+	c.live.OfferedRatePct = ratePct
+	c.live.TxPackets = c.doneTx + tx
+	c.live.RxPackets = c.doneRx + rx
+	c.live.TxBytes = c.doneTxBytes + tx*uint64(frameSize)
+	c.live.RxBytes = c.doneRxBytes + rx*uint64(frameSize)
+	if trial != nil {
+		c.doneTx, c.doneRx = c.live.TxPackets, c.live.RxPackets
+		c.doneTxBytes, c.doneRxBytes = c.live.TxBytes, c.live.RxBytes
+		c.live.Trials++
+		c.live.LastLossPct = trial.lossPct
+		c.live.LastLatency = trial.latency
+		c.live.LastOrder = trial.order
+	}
+}
+
+// startTrial marks a timed trial as running in the live counters
+func (c *Context) startTrial(frameSize uint32, ratePct float64, warmup, duration time.Duration, start time.Time) {
+	c.liveMu.Lock()
+	defer c.liveMu.Unlock()
+	c.live.FrameSize = frameSize
+	c.live.OfferedRatePct = ratePct
+	c.live.InTrial = true
+	c.live.TrialWarmup = warmup
+	c.live.TrialDuration = duration
+	c.live.TrialElapsed = 0
+	c.trialStart = start
+}
+
+// endTrial marks the trial from startTrial as over, however it ended
+func (c *Context) endTrial() {
+	c.liveMu.Lock()
+	defer c.liveMu.Unlock()
+	c.live.InTrial = false
+	c.live.TrialElapsed = time.Since(c.trialStart)
+}
+
+// buildFrame returns a test frame of frameSize bytes and the offset of its
+// RFC 2544 payload, laid out as the C dataplane lays out its frames. A
+// dual-stack trial's IPv6 frame comes from dualFrame.
+func (c *Context) buildFrame(frameSize uint32) ([]byte, int, error) {
+	if len(c.tpl.Header) > 0 {
+		return c.templatedFrame(frameSize)
+	}
+	return c.ipFrame(frameSize, c.config.IP.Mode == IPModeV6)
+}
+
+// ipFrame builds a built-in frame, IPv6 with v6, with its labels and tags
+func (c *Context) ipFrame(frameSize uint32, v6 bool) ([]byte, int, error) {
+	f, offset, err := c.builtinFrame(frameSize, v6)
+	if err != nil {
+		return nil, 0, err
+	}
+	if labels := c.config.MPLSLabels; len(labels) > 0 {
+		if et := c.framing.EtherType; c.framing.LLCSNAP || (et != 0 && et != 0x0800 && et != etherTypeIPv6) {
+			return nil, 0, fmt.Errorf("MPLS labels need Ethernet II IP framing")
+		}
+		if offset+len(labels)*mplsLabelLen+payloadLen > len(f) {
+			return nil, 0, fmt.Errorf("frame size %d too small for the MPLS label stack", frameSize)
+		}
+		insertMPLS(f, offset, labels)
+		offset += len(labels) * mplsLabelLen
+	}
+	tags := c.vlanTags()
+	if len(tags) == 0 {
+		return f, offset, nil
+	}
+	if offset+len(tags)*vlanTagLen+payloadLen > len(f) {
+		return nil, 0, fmt.Errorf("frame size %d too small for the VLAN tags", frameSize)
+	}
+	for _, t := range tags {
+		insertVLANTag(f, offset, t.tpid, t.vid, t.pcp)
+		offset += vlanTagLen
+	}
+	return f, offset, nil
+}
+
+// vlanTag is one VLAN tag of the built-in frames
+type vlanTag struct {
+	tpid, vid uint16
+	pcp       uint8
+}
+
+// vlanTags returns the configured tags, inner first
+func (c *Context) vlanTags() []vlanTag {
+	var tags []vlanTag
+	if cfg := &c.config; cfg.VLANID != 0 || cfg.PCP != 0 {
+		tags = append(tags, vlanTag{orTPID(cfg.TPID, tpid8021Q), cfg.VLANID, cfg.PCP})
+	}
+	if cfg := &c.config; cfg.OuterVLANID != 0 || cfg.OuterPCP != 0 {
+		tags = append(tags, vlanTag{orTPID(cfg.OuterTPID, tpid8021AD), cfg.OuterVLANID, cfg.OuterPCP})
+	}
+	return tags
+}
+
+func orTPID(tpid, def uint16) uint16 {
+	if tpid == 0 {
+		return def
+	}
+	return tpid
+}
+
+// countTags counts the VLAN tags after the MAC addresses of f, up to two
+func countTags(f []byte) int {
+	n := 0
+	for at := 12; n < 2 && at+vlanTagLen <= len(f) && ValidTPID(binary.BigEndian.Uint16(f[at:])); at += vlanTagLen {
+		n++
+	}
+	return n
+}
+
+// builtinFrame lays out an untagged frame with the configured framing,
+// IPv6 with v6
+func (c *Context) builtinFrame(frameSize uint32, v6 bool) ([]byte, int, error) {
+	if frameSize < minFrameSize {
+		return nil, 0, fmt.Errorf("frame size %d too small (minimum: %d bytes)", frameSize, minFrameSize)
+	}
+	f := make([]byte, frameSize)
+	src, dst := c.macs()
+	copy(f[0:6], dst)
+	copy(f[6:12], src)
+	binary.BigEndian.PutUint16(f[12:], 0x0800)
+
+	ip := f[14:34]
+	ip[0] = 0x45
+	binary.BigEndian.PutUint16(ip[2:], uint16(frameSize-14))
+	binary.BigEndian.PutUint16(ip[4:], 0x1234)
+	binary.BigEndian.PutUint16(ip[6:], 0x4000) // Don't fragment
+	ip[8] = 64
+	ip[9] = 17 // UDP
+	h := c.headers()
+	ip[1] = h.DSCP << 2
+	copy(ip[12:16], h.SrcIP.First)
+	copy(ip[16:20], h.DstIP.First)
+
+	udp := f[34:42]
+	binary.BigEndian.PutUint16(udp[0:], h.SrcPort.First)
+	binary.BigEndian.PutUint16(udp[2:], h.DstPort.First)
+	binary.BigEndian.PutUint16(udp[4:], uint16(frameSize-34))
+
+	offset := 42
+	c.writePayload(f, offset)
+
+	etherType := c.framing.EtherType
+	if etherType == 0 {
+		etherType = 0x0800
+	}
+	if v6 {
+		if frameSize < minFrameSize+ipv6HeaderLen-20 {
+			return nil, 0, fmt.Errorf("frame size %d too small for IPv6 (minimum: %d bytes)", frameSize, minFrameSize+ipv6HeaderLen-20)
+		}
+		if c.framing.LLCSNAP {
+			return nil, 0, fmt.Errorf("LLC/SNAP framing carries IPv4 only")
+		}
+		toIPv6(f, c.config.IP.IPv6)
+		if c.framing.EtherType != 0 {
+			binary.BigEndian.PutUint16(f[12:], etherType)
+		}
+		return f, offset + ipv6HeaderLen - 20, nil
+	}
+	if !c.framing.LLCSNAP {
+		binary.BigEndian.PutUint16(f[12:], etherType)
+		setIPv4Checksum(ip)
+		return f, offset, nil
+	}
+
+	// 802.3 length, then the LLC/SNAP header carrying the EtherType
+	if frameSize < minFrameSize+llcSNAPLen || frameSize-14-4 > max8023Length {
+		return nil, 0, fmt.Errorf("frame size %d not valid for configured framing", frameSize)
+	}
+	copy(f[14+llcSNAPLen:], f[14:frameSize-llcSNAPLen])
+	binary.BigEndian.PutUint16(f[12:], uint16(frameSize-14-4))
+	copy(f[14:20], []byte{0xAA, 0xAA, 0x03, 0, 0, 0})
+	binary.BigEndian.PutUint16(f[20:], etherType)
+	ip = f[14+llcSNAPLen : 34+llcSNAPLen]
+	binary.BigEndian.PutUint16(ip[2:], uint16(frameSize-14-llcSNAPLen))
+	binary.BigEndian.PutUint16(f[34+llcSNAPLen+4:], uint16(frameSize-34-llcSNAPLen))
+	setIPv4Checksum(ip)
+	return f, offset + llcSNAPLen, nil
+}
+
+// macs returns the source and destination MACs of test frames: the
+// configured ones, else the interface's and the built-in destination
+func (c *Context) macs() (src, dst net.HardwareAddr) {
+	src, dst = c.srcMAC, c.dstMAC
+	if m := c.config.Headers.SrcMAC; m != nil {
+		src = m
+	}
+	if m := c.config.Headers.DstMAC; m != nil {
+		dst = m
+	}
+	return src, dst
+}
+
+// headers returns the configured header fields with the built-in IPv4
+// addresses and UDP ports in place of those left zero
+func (c *Context) headers() HeaderFields {
+	h := c.config.Headers
+	if h.SrcIP.First = h.SrcIP.First.To4(); h.SrcIP.First == nil {
+		h.SrcIP.First = defaultSrcIP
+	}
+	if h.DstIP.First = h.DstIP.First.To4(); h.DstIP.First == nil {
+		h.DstIP.First = defaultDstIP
+	}
+	if h.SrcPort.First == 0 {
+		h.SrcPort.First = defaultSrcPort
+	}
+	if h.DstPort.First == 0 {
+		h.DstPort.First = defaultDstPort
+	}
+	return h
+}
+
+// headerRange cycles the ranged header fields of built-in frames frame
+// by frame, as rfc2544_set_header_range does
+type headerRange struct {
+	h        HeaderFields
+	src, dst uint32 // First IPv4 addresses
+	n        uint64
+}
+
+// headerRange returns the header ranges of a trial's frames, nil without any
+func (c *Context) headerRange() *headerRange {
+	if !c.config.Headers.Ranged() || len(c.tpl.Header) > 0 {
+		return nil
+	}
+	h := c.headers()
+	return &headerRange{h: h, src: binary.BigEndian.Uint32(h.SrcIP.First), dst: binary.BigEndian.Uint32(h.DstIP.First)}
+}
+
+// apply addresses f, with its payload at offset, as the next frame of
+// the ranges. IPv6 frames keep their addresses.
+func (r *headerRange) apply(f []byte, offset int) {
+	if r == nil {
+		return
+	}
+	n := r.n
+	r.n++
+	if ipHeaderLen(f) == 20 && (r.h.SrcIP.Count > 1 || r.h.DstIP.Count > 1) {
+		ip := f[offset-28 : offset-8]
+		if c := r.h.SrcIP.Count; c > 1 {
+			binary.BigEndian.PutUint32(ip[12:], r.src+uint32(n%uint64(c)))
+		}
+		if c := r.h.DstIP.Count; c > 1 {
+			binary.BigEndian.PutUint32(ip[16:], r.dst+uint32(n%uint64(c)))
+		}
+		setIPv4Checksum(ip)
+	}
+	udp := f[offset-8:]
+	if c := r.h.SrcPort.Count; c > 1 {
+		binary.BigEndian.PutUint16(udp[0:], r.h.SrcPort.First+uint16(n%uint64(c)))
+	}
+	if c := r.h.DstPort.Count; c > 1 {
+		binary.BigEndian.PutUint16(udp[2:], r.h.DstPort.First+uint16(n%uint64(c)))
+	}
+}
+
+// toIPv6 rewrites the IPv4 header of an untagged built-in frame as an
+// IPv6 header, as rfc2544_convert_ipv6 does. The frame keeps its size: UDP
+// and the payload move back over the padding.
+func toIPv6(f []byte, v6 IPv6) {
+	copy(f[14+ipv6HeaderLen:], f[34:len(f)-(ipv6HeaderLen-20)])
+	binary.BigEndian.PutUint16(f[12:], etherTypeIPv6)
+	ip6 := f[14 : 14+ipv6HeaderLen]
+	binary.BigEndian.PutUint32(ip6, 6<<28|uint32(v6.TrafficClass)<<20|v6.FlowLabel&MaxFlowLabel)
+	n := uint16(len(f) - 14 - ipv6HeaderLen)
+	binary.BigEndian.PutUint16(ip6[4:], n)
+	ip6[6] = 17 // UDP
+	ip6[7] = v6.HopLimit
+	if ip6[7] == 0 {
+		ip6[7] = 64
+	}
+	copy(ip6[8:24], v6.Src.To16())
+	copy(ip6[24:40], v6.Dst.To16())
+	binary.BigEndian.PutUint16(f[14+ipv6HeaderLen+4:], n)
+	setUDP6Checksum(f, 14+ipv6HeaderLen+8)
+}
+
+// l2Len returns the length of the L2 header of a built-in frame: the MAC
+// addresses, any VLAN tags, the EtherType and any MPLS label stack or
+// LLC/SNAP header
+func l2Len(f []byte) int {
+	at := 12 + countTags(f)*vlanTagLen
+	if at+2 > len(f) {
+		return len(f)
+	}
+	etherType := binary.BigEndian.Uint16(f[at:])
+	at += 2
+	switch {
+	case etherType == etherTypeMPLS:
+		for at+mplsLabelLen <= len(f) {
+			bottom := f[at+2]&1 != 0
+			at += mplsLabelLen
+			if bottom {
+				break
+			}
+		}
+	case etherType <= max8023Length:
+		at += llcSNAPLen
+	}
+	return at
+}
+
+// ipHeaderLen returns the length of the IP header of a built-in frame
+func ipHeaderLen(f []byte) int {
+	if at := l2Len(f); at < len(f) && f[at]>>4 == 6 {
+		return ipv6HeaderLen
+	}
+	return 20
+}
+
+// payloadOffset returns the offset of the RFC 2544 payload of a returned
+// built-in frame, behind its own L2, IP and UDP headers
+func payloadOffset(f []byte) int {
+	return l2Len(f) + ipHeaderLen(f) + 8
+}
+
+// shrinkIP takes n bytes off the IP and UDP lengths of a built-in frame
+// with its payload at offset, refreshing its checksum
+func shrinkIP(f []byte, offset, n int) {
+	udp := f[offset-8:]
+	binary.BigEndian.PutUint16(udp[4:], binary.BigEndian.Uint16(udp[4:])-uint16(n))
+	if ipHeaderLen(f) == ipv6HeaderLen {
+		ip6 := f[offset-8-ipv6HeaderLen:]
+		binary.BigEndian.PutUint16(ip6[4:], binary.BigEndian.Uint16(ip6[4:])-uint16(n))
+		setUDP6Checksum(f, offset)
+		return
+	}
+	ip := f[offset-28 : offset-8]
+	binary.BigEndian.PutUint16(ip[2:], binary.BigEndian.Uint16(ip[2:])-uint16(n))
+	setIPv4Checksum(ip)
+}
+
+// setUDP6Checksum fills in the mandatory UDP checksum of a frame with its
+// IPv6 and UDP headers in front of offset
+func setUDP6Checksum(f []byte, offset int) {
+	ip6 := f[offset-8-ipv6HeaderLen:]
+	udp := f[offset-8:]
+	binary.BigEndian.PutUint16(udp[6:], 0)
+	sum := udp6Checksum(ip6[8:24], ip6[24:40], udp[:binary.BigEndian.Uint16(udp[4:])])
+	if sum == 0 {
+		sum = 0xFFFF // Zero means no checksum
+	}
+	binary.BigEndian.PutUint16(udp[6:], sum)
+}
+
+// insertVLANTag inserts a VLAN tag after the MAC addresses of f, whose
+// payload is at offset, outside any tag already there. The frame keeps its
+// size: the headers and payload move back and the IP, UDP and any 802.3
+// lengths shrink by the tag.
+func insertVLANTag(f []byte, offset int, tpid, vid uint16, pcp uint8) {
+	copy(f[12+vlanTagLen:], f[12:len(f)-vlanTagLen])
+	binary.BigEndian.PutUint16(f[12:], tpid)
+	binary.BigEndian.PutUint16(f[14:], uint16(pcp)<<13|vid)
+	at := 12 + countTags(f)*vlanTagLen
+	if l := binary.BigEndian.Uint16(f[at:]); l <= max8023Length {
+		binary.BigEndian.PutUint16(f[at:], l-vlanTagLen)
+	}
+	shrinkIP(f, offset+vlanTagLen, vlanTagLen)
+}
+
+// markPriority writes pcp into each VLAN tag of f and dscp into its IPv4
+// header or IPv6 traffic class, keeping the ECN bits
+func markPriority(f []byte, offset int, pcp, dscp uint8) {
+	for i, at := 0, 12; i < countTags(f); i, at = i+1, at+vlanTagLen {
+		f[at+2] = f[at+2]&0x1f | pcp<<5
+	}
+	if ipHeaderLen(f) == ipv6HeaderLen {
+		ip6 := f[offset-8-ipv6HeaderLen:]
+		tc := dscp<<2 | ip6[1]>>4&0x03
+		ip6[0] = 0x60 | tc>>4
+		ip6[1] = tc<<4 | ip6[1]&0x0f
+		return
+	}
+	ip := f[offset-28 : offset-8]
+	ip[1] = dscp<<2 | ip[1]&0x03
+	setIPv4Checksum(ip)
+}
+
+// insertMPLS pushes a label stack between the EtherType and the IPv4
+// header of an untagged Ethernet II frame whose payload is at offset,
+// marking the last label bottom of stack. Like a VLAN tag, the stack
+// takes its bytes from the padding.
+func insertMPLS(f []byte, offset int, labels []MPLSLabel) {
+	n := len(labels) * mplsLabelLen
+	copy(f[14+n:], f[14:len(f)-n])
+	binary.BigEndian.PutUint16(f[12:], etherTypeMPLS)
+	for i, l := range labels {
+		ttl := uint32(l.TTL)
+		if ttl == 0 {
+			ttl = 64
+		}
+		entry := l.Label<<12 | uint32(l.EXP)<<9 | ttl
+		if i == len(labels)-1 {
+			entry |= 1 << 8
+		}
+		binary.BigEndian.PutUint32(f[14+i*mplsLabelLen:], entry)
+	}
+	shrinkIP(f, offset+n, n)
+}
+
+// templatedFrame builds a frame from the packet template, filling in its
+// lengths and checksums
+func (c *Context) templatedFrame(frameSize uint32) ([]byte, int, error) {
+	t := c.tpl
+	offset := len(t.Header)
+	if int(frameSize) < offset+payloadLen {
+		return nil, 0, fmt.Errorf("frame size %d too small for packet template (minimum: %d bytes)", frameSize, offset+payloadLen)
+	}
+	f := make([]byte, frameSize)
+	copy(f, t.Header)
+	src, dst := c.macs()
+	if t.FillDstMAC {
+		copy(f[0:6], dst)
+	}
+	if t.FillSrcMAC {
+		copy(f[6:12], src)
+	}
+	c.writePayload(f, offset)
+
+	udp := f[t.UDPOffset:]
+	udpLen := uint16(int(frameSize) - t.UDPOffset)
+	binary.BigEndian.PutUint16(udp[4:], udpLen)
+	binary.BigEndian.PutUint16(udp[6:], 0)
+	if t.IPv4Offset >= 0 {
+		ip := f[t.IPv4Offset : t.IPv4Offset+20]
+		binary.BigEndian.PutUint16(ip[2:], uint16(int(frameSize)-t.IPv4Offset))
+		setIPv4Checksum(ip)
+		return f, offset, nil
+	}
+	ip6 := f[t.IPv6Offset:]
+	binary.BigEndian.PutUint16(ip6[4:], uint16(int(frameSize)-t.IPv6Offset-40))
+	sum := udp6Checksum(ip6[8:24], ip6[24:40], udp[:udpLen])
+	if sum == 0 {
+		sum = 0xFFFF // Zero means no checksum
+	}
+	binary.BigEndian.PutUint16(udp[6:], sum)
+	return f, offset, nil
+}
+
+// writePayload writes the signature and flags of the RFC 2544 payload at
+// offset and the configured padding pattern after it
+func (c *Context) writePayload(f []byte, offset int) {
+	copy(f[offset:], signature)
+	f[offset+flagsOffset] = flagReqTS
+	c.config.Payload.Fill(f[offset+payloadLen:])
+}
+
+// stampFrame writes a frame's sequence number and TX timestamp
+func stampFrame(f []byte, offset int, seq uint32, ts uint64) {
+	binary.BigEndian.PutUint32(f[offset+seqOffset:], seq)
+	binary.BigEndian.PutUint64(f[offset+timestampOffset:], ts)
+}
+
+// streamFrames returns a copy of frame for each of Config.Streams flows,
+// stream i adding i to the source MAC, both IPs and the UDP source port and
+// carrying i as its stream ID, or frame alone for a single flow. Each comes
+// with its sealer.
+func (c *Context) streamFrames(frame []byte, offset int) ([][]byte, []func(), error) {
+	frames := [][]byte{frame}
+	if n := c.config.Streams; n > 1 {
+		if len(c.tpl.Header) > 0 {
+			return nil, nil, unsupported("streams with a packet template")
+		}
+		if c.config.IP.Mode != IPModeV4 {
+			return nil, nil, unsupported("streams with IPv6 frames")
+		}
+		frames = make([][]byte, n)
+		for i := range frames {
+			f := append([]byte(nil), frame...)
+			id := uint32(i)
+			binary.BigEndian.PutUint16(f[10:], binary.BigEndian.Uint16(f[10:])+uint16(id))
+			ip := f[offset-28 : offset-8]
+			binary.BigEndian.PutUint32(ip[12:], binary.BigEndian.Uint32(ip[12:])+id)
+			binary.BigEndian.PutUint32(ip[16:], binary.BigEndian.Uint32(ip[16:])+id)
+			setIPv4Checksum(ip)
+			udp := f[offset-8:]
+			binary.BigEndian.PutUint16(udp, binary.BigEndian.Uint16(udp)+uint16(id))
+			binary.BigEndian.PutUint32(f[offset+streamIDOffset:], id)
+			frames[i] = f
+		}
+	}
+	seals := make([]func(), len(frames))
+	for i, f := range frames {
+		seal, err := c.sealer(f, offset)
+		if err != nil {
+			return nil, nil, err
+		}
+		seals[i] = seal
+	}
+	return frames, seals, nil
+}
+
+// sealer prepares frame to carry a CRC-32 trailer with VerifyPayload,
+// returning the function that seals it after each stamp. The CRC covers
+// the padding, then the payload up to its flags, as in the C dataplane.
+func (c *Context) sealer(f []byte, offset int) (func(), error) {
+	if !c.config.VerifyPayload {
+		return func() {}, nil
+	}
+	p := f[offset:]
+	n := len(p)
+	if n < payloadLen+crcLen {
+		return nil, fmt.Errorf("frame size %d too small for payload verification", len(f))
+	}
+	p[flagsOffset] |= flagCRC32
+	pad := crc32.ChecksumIEEE(p[payloadLen : n-crcLen])
+	return func() {
+		binary.BigEndian.PutUint32(p[n-crcLen:], crc32.Update(pad, crc32.IEEETable, p[:flagsOffset]))
+	}, nil
+}
+
+// payloadIntact checks the CRC-32 trailer of a returned test frame. The
+// payload runs to the end of the UDP datagram in front of it.
+func payloadIntact(f []byte, offset int) bool {
+	if offset < 8 || len(f) < offset {
+		return false
+	}
+	n := int(binary.BigEndian.Uint16(f[offset-4:])) - 8
+	if n < payloadLen+crcLen || offset+n > len(f) {
+		return false
+	}
+	p := f[offset : offset+n]
+	crc := crc32.Update(crc32.ChecksumIEEE(p[payloadLen:n-crcLen]), crc32.IEEETable, p[:flagsOffset])
+	return crc == binary.BigEndian.Uint32(p[n-crcLen:])
+}
+
+// parseFrame returns the sequence number and TX timestamp of a returned
+// test frame with its payload at offset
+func parseFrame(f []byte, offset int) (uint32, uint64, bool) {
+	if len(f) < offset+payloadLen || string(f[offset:offset+len(signature)]) != signature {
+		return 0, 0, false
+	}
+	return binary.BigEndian.Uint32(f[offset+seqOffset:]), binary.BigEndian.Uint64(f[offset+timestampOffset:]), true
+}
+
+func setIPv4Checksum(ip []byte) {
+	ip[10], ip[11] = 0, 0
+	binary.BigEndian.PutUint16(ip[10:], ^onesSum(0, ip[:20]))
+}
+
+func udp6Checksum(src, dst, udp []byte) uint16 {
+	pseudo := make([]byte, 40)
+	copy(pseudo[0:16], src)
+	copy(pseudo[16:32], dst)
+	binary.BigEndian.PutUint32(pseudo[32:], uint32(len(udp)))
+	pseudo[39] = 17
+	return ^onesSum(onesSum(0, pseudo), udp)
+}
+
+// onesSum adds b to the ones' complement sum
+func onesSum(sum uint16, b []byte) uint16 {
+	s := uint32(sum)
+	for i := 0; i+1 < len(b); i += 2 {
+		s += uint32(binary.BigEndian.Uint16(b[i:]))
+	}
+	if len(b)%2 == 1 {
+		s += uint32(b[len(b)-1]) << 8
+	}
+	for s > 0xffff {
+		s = s&0xffff + s>>16
+	}
+	return uint16(s)
+}
+
+// latencyStats summarizes round-trip samples in nanoseconds
+func latencyStats(samples []uint64) LatencyStats {
+	if len(samples) == 0 {
+		return LatencyStats{}
+	}
+	sorted := append([]uint64(nil), samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	var sum float64
+	for _, s := range sorted {
+		sum += float64(s)
+	}
+	avg := sum / float64(len(sorted))
+	var dev float64
+	for _, s := range sorted {
+		dev += math.Abs(float64(s) - avg)
+	}
+	pct := func(p float64) float64 {
+		return float64(sorted[int(math.Ceil(p/100*float64(len(sorted))))-1])
+	}
+	return LatencyStats{
+		Count:    uint64(len(sorted)),
+		MinNs:    float64(sorted[0]),
+		MaxNs:    float64(sorted[len(sorted)-1]),
+		AvgNs:    avg,
+		JitterNs: dev / float64(len(sorted)), // Mean absolute deviation, as in the C dataplane
+		P50Ns:    pct(50),
+		P95Ns:    pct(95),
+		P99Ns:    pct(99),
+	}
+}
+
+// GetLineRate returns the interface line rate in bits/sec, assuming
+// 10 Gbps when the speed cannot be read
+func GetLineRate(iface string) uint64 {
+	if mbps, err := strconv.ParseInt(sysfs(iface, "speed"), 10, 64); err == nil && mbps > 0 {
+		return uint64(mbps) * 1000000
+	}
+	return 10000000000
+}
+
+// CalcPPS calculates packets per second for given rate and frame size
+func CalcPPS(lineRate uint64, frameSize uint32) uint64 {
+	// Ethernet overhead: preamble (8) + IFG (12) = 20 bytes
+	return lineRate / (uint64(frameSize+20) * 8)
+}
+
+// framePPS is CalcPPS unrounded, for pacing: at low rates the whole frames
+// a second CalcPPS rounds down to are a large share of a jumbo frame rate
+func framePPS(lineRate uint64, frameSize uint32) float64 {
+	return float64(lineRate) / (float64(frameSize+20) * 8)
+}
+
+// sysfs reads an attribute of an interface from /sys/class/net, or ""
+func sysfs(iface, attr string) string {
+	data, err := os.ReadFile(filepath.Join("/sys/class/net", iface, attr))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// sysfsLink returns the base name of a link under /sys/class/net, or ""
+func sysfsLink(iface, attr string) string {
+	target, err := os.Readlink(filepath.Join("/sys/class/net", iface, attr))
+	if err != nil {
+		return ""
+	}
+	return filepath.Base(target)
+}
+
+func nicInfo(ifi net.Interface) NICInfo {
+	info := NICInfo{
+		Name:      ifi.Name,
+		Up:        ifi.Flags&net.FlagUp != 0,
+		OperState: sysfs(ifi.Name, "operstate"),
+		MTU:       uint32(ifi.MTU),
+		MAC:       ifi.HardwareAddr.String(),
+		Driver:    sysfsLink(ifi.Name, "device/driver"),
+		BusInfo:   sysfsLink(ifi.Name, "device"),
+	}
+	if mbps, err := strconv.ParseInt(sysfs(ifi.Name, "speed"), 10, 64); err == nil && mbps > 0 {
+		info.LinkSpeed = uint64(mbps) * 1000000
+	}
+	if info.OperState == "" {
+		info.OperState = "unknown"
+	}
+	return info
+}
+
+// DetectNIC probes an interface's link state and driver. Capabilities of
+// the C dataplane (AF_XDP, DPDK, hardware timestamps) are never reported.
+func DetectNIC(iface string) (*NICInfo, error) {
+	ifi, err := net.InterfaceByName(iface)
+	if err != nil {
+		return nil, fmt.Errorf("interface %s: %w", iface, ErrNoSuchInterface)
+	}
+	info := nicInfo(*ifi)
+	return &info, nil
+}
+
+// QueryCapabilities reports what the pure-Go dataplane can do on iface:
+// RFC 2544 trials over AF_PACKET, timed in software. dpdk changes nothing.
+func QueryCapabilities(iface string, dpdk bool) (Capabilities, error) {
+	caps := Capabilities{MaxStreams: MaxStreams, MaxFrameSize: MaxFrameSize}
+	if iface == "" {
+		return caps, nil
+	}
+	ifi, err := net.InterfaceByName(iface)
+	if err != nil {
+		return caps, fmt.Errorf("interface %s: %w", iface, ErrNoSuchInterface)
+	}
+	// The port carries its MTU plus the MAC header and FCS
+	if ifi.MTU > 0 {
+		caps.MaxFrameSize = min(caps.MaxFrameSize, uint32(ifi.MTU)+18)
+	}
+	return caps, nil
+}
+
+// ListInterfaces probes every interface except loopback
+func ListInterfaces() ([]NICInfo, error) {
+	ifis, err := net.Interfaces()
+	if err != nil {
+		return nil, fmt.Errorf("list interfaces failed: %w", err)
+	}
+	var infos []NICInfo
+	for _, ifi := range ifis {
+		if ifi.Flags&net.FlagLoopback != 0 || len(infos) == maxInterfaces {
+			continue
+		}
+		infos = append(infos, nicInfo(ifi))
+	}
+	return infos, nil
+}
+
+// RecommendInterface returns the up interface best suited for testing,
+// preferring speed, then jumbo MTU
+func RecommendInterface() (*NICInfo, error) {
+	infos, err := ListInterfaces()
+	if err != nil {
+		return nil, err
+	}
+	var best *NICInfo
+	for i := range infos {
+		ni := &infos[i]
+		if !ni.Up {
+			continue
+		}
+		if best == nil || ni.LinkSpeed > best.LinkSpeed || ni.LinkSpeed == best.LinkSpeed && ni.MTU > best.MTU {
+			best = ni
+		}
+	}
+	if best == nil {
+		return nil, fmt.Errorf("no suitable interface: no interface is up")
+	}
+	return best, nil
+}
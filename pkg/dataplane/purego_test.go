@@ -0,0 +1,668 @@
+//go:build !cgo || purego
+
+package dataplane
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func testContext() *Context {
+	return &Context{
+		srcMAC: net.HardwareAddr{0x02, 0, 0, 0, 0, 0x01},
+		dstMAC: net.HardwareAddr{0x02, 0, 0, 0, 0, 0x02},
+	}
+}
+
+func TestBuildFrame(t *testing.T) {
+	c := testContext()
+	f, offset, err := c.buildFrame(128)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f) != 128 || offset != 42 {
+		t.Fatalf("len %d, payload offset %d", len(f), offset)
+	}
+	if f[12] != 0x08 || f[13] != 0x00 || onesSum(0, f[14:34]) != 0xffff {
+		t.Errorf("IPv4 header % x", f[12:34])
+	}
+	if got := binary.BigEndian.Uint16(f[38:]); got != 128-34 {
+		t.Errorf("UDP length %d", got)
+	}
+	stampFrame(f, offset, 7, 123456789)
+	seq, ts, ok := parseFrame(f, offset)
+	if !ok || seq != 7 || ts != 123456789 {
+		t.Errorf("parseFrame = %d, %d, %v", seq, ts, ok)
+	}
+	if _, _, ok := parseFrame(f[:offset+10], offset); ok {
+		t.Error("truncated frame parsed")
+	}
+
+	if _, _, err := c.buildFrame(64); err == nil {
+		t.Error("64-byte frame has no room for the payload")
+	}
+}
+
+func TestPayloadSeal(t *testing.T) {
+	c := testContext()
+	c.config.VerifyPayload = true
+	f, offset, err := c.buildFrame(128)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seal, err := c.sealer(f, offset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stampFrame(f, offset, 7, 1000)
+	seal()
+	if f[offset+flagsOffset] != flagReqTS|flagCRC32 || !payloadIntact(f, offset) {
+		t.Fatalf("sealed payload % x", f[offset:])
+	}
+
+	// A reflector rewriting the flags does not break the seal
+	f[offset+flagsOffset] |= 0x02
+	if !payloadIntact(f, offset) {
+		t.Error("flags are covered")
+	}
+	f[100] ^= 0x10
+	if payloadIntact(f, offset) {
+		t.Error("corrupted padding passed")
+	}
+	f[100] ^= 0x10
+	stampFrame(f, offset, 8, 1000)
+	if payloadIntact(f, offset) {
+		t.Error("restamped frame passed without resealing")
+	}
+	seal()
+	if !payloadIntact(f, offset) || payloadIntact(f[:120], offset) {
+		t.Error("resealed frame failed, or truncated frame passed")
+	}
+
+	if f, offset, err = c.buildFrame(68); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.sealer(f, offset); err == nil {
+		t.Error("68-byte frame has no room for the CRC")
+	}
+}
+
+func TestStreamFrames(t *testing.T) {
+	c := testContext()
+	c.config.Streams = 4
+	c.config.VerifyPayload = true
+	f, offset, err := c.buildFrame(128)
+	if err != nil {
+		t.Fatal(err)
+	}
+	frames, seals, err := c.streamFrames(f, offset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(frames) != 4 || len(seals) != 4 {
+		t.Fatalf("got %d frames, %d sealers", len(frames), len(seals))
+	}
+	s := frames[3]
+	if s[11] != 0x04 || s[29] != 4 || s[33] != 5 || binary.BigEndian.Uint16(s[34:]) != 12348 {
+		t.Errorf("stream 3 headers % x", s[:42])
+	}
+	if binary.BigEndian.Uint32(s[offset+streamIDOffset:]) != 3 {
+		t.Error("stream 3 does not carry its ID")
+	}
+	if onesSum(0, s[14:34]) != 0xffff {
+		t.Error("stream 3 IPv4 checksum not updated")
+	}
+	seals[3]()
+	if !payloadIntact(s, offset) {
+		t.Error("stream 3 failed its seal")
+	}
+
+	c.tpl = PacketTemplate{Header: make([]byte, 42)}
+	if _, _, err := c.streamFrames(f, offset); err == nil {
+		t.Error("streams accepted with a packet template")
+	}
+}
+
+func TestBuildFrameLLCSNAP(t *testing.T) {
+	c := testContext()
+	c.framing = Framing{LLCSNAP: true, EtherType: 0x88b5}
+	f, offset, err := c.buildFrame(256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offset != 50 || binary.BigEndian.Uint16(f[12:]) != 256-18 {
+		t.Fatalf("payload offset %d, length field % x", offset, f[12:14])
+	}
+	if !bytes.Equal(f[14:22], []byte{0xaa, 0xaa, 0x03, 0, 0, 0, 0x88, 0xb5}) {
+		t.Errorf("LLC/SNAP header % x", f[14:22])
+	}
+	if onesSum(0, f[22:42]) != 0xffff {
+		t.Error("bad IPv4 checksum")
+	}
+	if _, _, ok := parseFrame(f, offset); !ok {
+		t.Error("payload not found after the LLC/SNAP header")
+	}
+}
+
+func TestBuildFrameVLAN(t *testing.T) {
+	c := testContext()
+	c.config.VLANID, c.config.PCP = 100, 5
+	f, offset, err := c.buildFrame(128)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f) != 128 || offset != 46 {
+		t.Fatalf("len %d, payload offset %d", len(f), offset)
+	}
+	if !bytes.Equal(f[12:18], []byte{0x81, 0x00, 0xa0, 100, 0x08, 0x00}) {
+		t.Errorf("802.1Q tag % x", f[12:18])
+	}
+	if binary.BigEndian.Uint16(f[20:]) != 128-18 || onesSum(0, f[18:38]) != 0xffff {
+		t.Errorf("IPv4 header % x", f[18:38])
+	}
+	if got := binary.BigEndian.Uint16(f[42:]); got != 128-38 {
+		t.Errorf("UDP length %d", got)
+	}
+	if _, _, ok := parseFrame(f, offset); !ok {
+		t.Error("payload not found after the tag")
+	}
+
+	if _, _, err := c.buildFrame(66); err == nil {
+		t.Error("66-byte frame has no room for the tag")
+	}
+}
+
+func TestBuildFrameQinQ(t *testing.T) {
+	c := testContext()
+	c.config.VLANID, c.config.PCP = 100, 5
+	c.config.OuterVLANID, c.config.OuterPCP = 200, 3
+	f, offset, err := c.buildFrame(128)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f) != 128 || offset != 50 {
+		t.Fatalf("len %d, payload offset %d", len(f), offset)
+	}
+	if !bytes.Equal(f[12:22], []byte{0x88, 0xa8, 0x60, 200, 0x81, 0x00, 0xa0, 100, 0x08, 0x00}) {
+		t.Errorf("QinQ tags % x", f[12:22])
+	}
+	if binary.BigEndian.Uint16(f[24:]) != 128-22 || onesSum(0, f[22:42]) != 0xffff {
+		t.Errorf("IPv4 header % x", f[22:42])
+	}
+	if _, _, ok := parseFrame(f, offset); !ok {
+		t.Error("payload not found after the tags")
+	}
+	if countTags(f) != 2 || countTags(append(f[:12:12], f[16:]...)) != 1 {
+		t.Error("tags miscounted")
+	}
+
+	// Legacy TPIDs, with LLC/SNAP: the 802.3 length follows both tags
+	c.config.TPID, c.config.OuterTPID = 0x88a8, 0x9100
+	c.framing.LLCSNAP = true
+	f, _, err = c.buildFrame(128)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f[12] != 0x91 || f[16] != 0x88 || binary.BigEndian.Uint16(f[20:]) != 128-18-8 {
+		t.Errorf("tags and length % x", f[12:22])
+	}
+
+	if _, _, err := c.buildFrame(74); err == nil {
+		t.Error("74-byte LLC/SNAP frame has no room for two tags")
+	}
+}
+
+func TestBuildFrameMPLS(t *testing.T) {
+	c := testContext()
+	c.config.VLANID = 100
+	c.config.MPLSLabels = []MPLSLabel{{Label: 16001, EXP: 5}, {Label: 100, EXP: 5, TTL: 32}}
+	f, offset, err := c.buildFrame(128)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offset != 42+4+8 {
+		t.Fatalf("payload offset %d", offset)
+	}
+	// The tag, then the MPLS EtherType and the stack, bottom of stack last
+	if !bytes.Equal(f[12:26], []byte{0x81, 0x00, 0, 100, 0x88, 0x47, 0x03, 0xe8, 0x1a, 64, 0x00, 0x06, 0x4b, 32}) {
+		t.Errorf("tag and labels % x", f[12:26])
+	}
+	if binary.BigEndian.Uint16(f[28:]) != 128-26 || onesSum(0, f[26:46]) != 0xffff {
+		t.Errorf("IPv4 header % x", f[26:46])
+	}
+	if _, _, ok := parseFrame(f, offset); !ok {
+		t.Error("payload not found after the labels")
+	}
+
+	c.framing.LLCSNAP = true
+	if _, _, err := c.buildFrame(128); err == nil {
+		t.Error("labeled an LLC/SNAP frame")
+	}
+	c.framing.LLCSNAP = false
+	c.config.MPLSLabels = make([]MPLSLabel, MaxMPLSLabels)
+	if _, _, err := c.buildFrame(84); err == nil {
+		t.Error("84-byte frame has no room for eight labels")
+	}
+}
+
+func TestMarkPriority(t *testing.T) {
+	c := testContext()
+	c.config.VLANID, c.config.PCP = 100, 1
+	c.config.OuterVLANID = 200
+	f, offset, err := c.buildFrame(128)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f[offset-28+1] = 0x01 // ECN ECT(1)
+	markPriority(f, offset, 5, 46)
+	if f[14] != 0xa0 || f[15] != 200 || f[18] != 0xa0 || f[19] != 100 {
+		t.Errorf("tags % x", f[12:20])
+	}
+	ip := f[offset-28 : offset-8]
+	if ip[1] != 46<<2|0x01 || onesSum(0, ip) != 0xffff {
+		t.Errorf("IPv4 header % x", ip)
+	}
+}
+
+func TestBuildFrameIPv6(t *testing.T) {
+	c := testContext()
+	c.config.IP = IPStack{Mode: IPModeV6, IPv6: IPv6{Src: net.ParseIP("2001:2::1"), Dst: net.ParseIP("2001:2::2"), TrafficClass: 0x01, FlowLabel: 0x12345}}
+	f, offset, err := c.buildFrame(128)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offset != 62 || binary.BigEndian.Uint16(f[12:]) != 0x86dd {
+		t.Fatalf("payload offset %d, EtherType % x", offset, f[12:14])
+	}
+	if got := binary.BigEndian.Uint32(f[14:]); got != 6<<28|0x01<<20|0x12345 {
+		t.Errorf("version, traffic class and flow label %#x", got)
+	}
+	if f[21] != 64 || !net.IP(f[38:54]).Equal(c.config.IP.IPv6.Dst) {
+		t.Errorf("hop limit %d, destination %v", f[21], net.IP(f[38:54]))
+	}
+	if got := binary.BigEndian.Uint16(f[18:]); got != 128-54 {
+		t.Errorf("IPv6 payload length %d", got)
+	}
+	if sum := udp6Checksum(f[22:38], f[38:54], f[54:]); sum != 0 {
+		t.Errorf("UDP checksum does not verify: %#x", sum)
+	}
+	if _, _, ok := parseFrame(f, payloadOffset(f)); !ok {
+		t.Error("payload not found behind the IPv6 header")
+	}
+
+	// Tags and labels come out of the IPv6 and UDP lengths
+	c.config.VLANID = 100
+	c.config.MPLSLabels = []MPLSLabel{{Label: 16001}}
+	if f, offset, err = c.buildFrame(128); err != nil {
+		t.Fatal(err)
+	}
+	if offset != 62+4+4 || payloadOffset(f) != offset {
+		t.Fatalf("payload offset %d, found at %d", offset, payloadOffset(f))
+	}
+	ip6 := f[offset-48:]
+	if got := binary.BigEndian.Uint16(ip6[4:]); got != 128-62 {
+		t.Errorf("IPv6 payload length %d", got)
+	}
+	if sum := udp6Checksum(ip6[8:24], ip6[24:40], f[offset-8:]); sum != 0 {
+		t.Errorf("UDP checksum does not verify after the tag: %#x", sum)
+	}
+
+	// DSCP into the traffic class, ECN and flow label kept
+	markPriority(f, offset, 5, 46)
+	if got := binary.BigEndian.Uint32(ip6); got != 6<<28|(46<<2|0x01)<<20|0x12345 {
+		t.Errorf("marked version, traffic class and flow label %#x", got)
+	}
+
+	c.config.VLANID, c.config.MPLSLabels = 0, nil
+	c.framing.LLCSNAP = true
+	if _, _, err := c.buildFrame(128); err == nil {
+		t.Error("IPv6 in an LLC/SNAP frame")
+	}
+	c.framing.LLCSNAP = false
+	if _, _, err := c.buildFrame(84); err == nil {
+		t.Error("84-byte frame has no room for the IPv6 header")
+	}
+}
+
+func TestDualStack(t *testing.T) {
+	c := testContext()
+	if d, err := c.dualFrame(128); d != nil || err != nil {
+		t.Fatalf("IPv4-only dual frame %v, %v", d, err)
+	}
+	c.config.IP = IPStack{Mode: IPModeDual, V6SharePct: 30}
+	f, _, err := c.buildFrame(128)
+	if err != nil || binary.BigEndian.Uint16(f[12:]) != 0x0800 {
+		t.Fatalf("dual-stack frame % x, %v", f[12:14], err)
+	}
+	d, err := c.dualFrame(128)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if binary.BigEndian.Uint16(d.frame[12:]) != 0x86dd || d.offset != 62 {
+		t.Fatalf("IPv6 frame % x, payload offset %d", d.frame[12:14], d.offset)
+	}
+
+	// The share is exact over every 100 slots, and spread out
+	v6, run := 0, 0
+	for i := 0; i < 100; i++ {
+		if d.next() {
+			v6++
+			if run++; run > 1 {
+				t.Fatalf("IPv6 frames back to back at slot %d", i)
+			}
+		} else {
+			run = 0
+		}
+	}
+	if v6 != 30 {
+		t.Errorf("%d of 100 frames IPv6", v6)
+	}
+
+	c.config.Streams = 4
+	if _, _, err := c.streamFrames(f, 42); err == nil {
+		t.Errorf("streams with IPv6 frames: %v", err)
+	}
+	if err := ValidateIP(IPStack{Mode: IPModeDual, V6SharePct: 100}); err == nil {
+		t.Error("IPv6 share of 100%")
+	}
+	if err := ValidateIP(IPStack{IPv6: IPv6{Src: net.ParseIP("10.0.0.1")}}); err == nil {
+		t.Error("IPv4 address as the IPv6 source")
+	}
+}
+
+func TestTemplatedFrameIPv6(t *testing.T) {
+	// eth/ipv6/udp with the addresses zeroed
+	header := make([]byte, 14+40+8)
+	binary.BigEndian.PutUint16(header[12:], 0x86dd)
+	header[14] = 0x60
+	header[14+6] = 17
+	header[14+7] = 64
+	c := testContext()
+	c.tpl = PacketTemplate{Header: header, IPv4Offset: -1, IPv6Offset: 14, UDPOffset: 54, FillSrcMAC: true, FillDstMAC: true}
+
+	f, offset, err := c.buildFrame(200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offset != 62 || !bytes.Equal(f[6:12], c.srcMAC) {
+		t.Fatalf("payload offset %d, source MAC % x", offset, f[6:12])
+	}
+	if got := binary.BigEndian.Uint16(f[18:]); got != 200-54 {
+		t.Errorf("IPv6 payload length %d", got)
+	}
+	// The checksum over the pseudo-header and datagram verifies to zero
+	udp := f[54:]
+	if sum := udp6Checksum(f[22:38], f[38:54], udp); sum != 0 {
+		t.Errorf("UDP checksum does not verify: %#x", sum)
+	}
+}
+
+func TestLatencyStats(t *testing.T) {
+	var samples []uint64
+	for i := uint64(1); i <= 100; i++ {
+		samples = append(samples, i*1000)
+	}
+	ls := latencyStats(samples)
+	if ls.Count != 100 || ls.MinNs != 1000 || ls.MaxNs != 100000 || ls.AvgNs != 50500 {
+		t.Errorf("stats %+v", ls)
+	}
+	if ls.P50Ns != 50000 || ls.P95Ns != 95000 || ls.P99Ns != 99000 {
+		t.Errorf("percentiles %v %v %v", ls.P50Ns, ls.P95Ns, ls.P99Ns)
+	}
+	if latencyStats(nil) != (LatencyStats{}) {
+		t.Error("no samples should be zero stats")
+	}
+}
+
+func TestOrderTracker(t *testing.T) {
+	var tr orderTracker
+	// 2, 3 and 5 arrive late; 4 and 1 twice; 70 starts a second word
+	for _, off := range []uint32{0, 1, 4, 2, 3, 4, 1, 7, 5, 70} {
+		tr.record(off)
+	}
+	want := FrameOrder{ReorderedFrames: 3, DuplicateFrames: 2, MaxReorderDepth: 2}
+	if tr.order != want {
+		t.Errorf("order %+v, want %+v", tr.order, want)
+	}
+	tr.record(maxOrderOffset)
+	if tr.order != want {
+		t.Errorf("untracked offset counted: %+v", tr.order)
+	}
+}
+
+func TestBurstCount(t *testing.T) {
+	var b burstCount
+	// Out of order: the span runs from the earliest to the latest arrival
+	b.record(2000, 1000)
+	b.record(1500, 1200)
+	b.record(4000, 1400)
+	if b.rx != 3 || b.maxLatency != 2600 || b.firstRx != 1500 || b.lastRx != 4000 {
+		t.Errorf("burst %+v", b)
+	}
+
+	bc := BurstCount{FramesTx: 200, FramesRx: 150}
+	if bc.LossPct() != 25 {
+		t.Errorf("loss %.1f%%, want 25%%", bc.LossPct())
+	}
+	if (BurstCount{FramesTx: 10, FramesRx: 12}).LossPct() != 0 {
+		t.Error("duplicates counted as negative loss")
+	}
+}
+
+func TestTrialBuffersReused(t *testing.T) {
+	c := testContext()
+	c.config.LatencySamples = 3
+	first := &counter{c: c}
+	c.reuse(first)
+	first.samples = append(first.samples, 10, 20, 30)
+	for _, off := range []uint32{0, 1, 2, 100} {
+		first.order.record(off)
+	}
+	c.recycle(first)
+
+	next := &counter{c: c}
+	c.reuse(next)
+	if len(next.samples) != 0 || cap(next.samples) < 3 || &next.samples[:1][0] != &first.samples[0] {
+		t.Errorf("samples not reused: len %d cap %d", len(next.samples), cap(next.samples))
+	}
+	if next.limit != 3 || &next.buf[0] != &first.buf[0] {
+		t.Errorf("limit %d, receive buffer reused %v", next.limit, &next.buf[0] == &first.buf[0])
+	}
+	// The last trial's offsets are not this one's duplicates
+	for _, off := range []uint32{1, 100} {
+		next.order.record(off)
+	}
+	if next.order.order != (FrameOrder{}) {
+		t.Errorf("stale order bitmap: %+v", next.order.order)
+	}
+	c.config.LatencySamples = MaxLatencySamples + 1
+	if err := c.Configure(&c.config); err == nil {
+		t.Error("expected an error for too many latency samples")
+	}
+}
+
+// loopPort returns the frames it sends
+type loopPort struct{ frames [][]byte }
+
+func (p *loopPort) send(frame []byte) (bool, error) {
+	p.frames = append(p.frames, append([]byte(nil), frame...))
+	return true, nil
+}
+
+func (p *loopPort) recv(buf []byte) (int, error) {
+	if len(p.frames) == 0 {
+		return 0, nil
+	}
+	n := copy(buf, p.frames[0])
+	p.frames = p.frames[1:]
+	return n, nil
+}
+
+func (p *loopPort) stats() PortStats { return PortStats{} }
+func (p *loopPort) close()           {}
+
+func TestCapture(t *testing.T) {
+	dir := t.TempDir()
+	cfg := Capture{TX: filepath.Join(dir, "tx.pcap"), RX: filepath.Join(dir, "rx.pcap"), Limit: 2, Snaplen: 20}
+	files, err := openCapture(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &capturePort{port: &loopPort{}, tx: files.tx, rx: files.rx}
+	frame := make([]byte, 64)
+	buf := make([]byte, 1500)
+	for i := 0; i < 3; i++ {
+		frame[0] = byte(i + 1)
+		p.send(frame)
+		p.recv(buf)
+	}
+	if s := files.stats(); s != (CaptureStats{TxFrames: 2, RxFrames: 2}) {
+		t.Errorf("stats %+v", s)
+	}
+	files.close()
+
+	data, err := os.ReadFile(cfg.RX)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != pcapFileHeaderLen+2*(pcapRecordLen+20) {
+		t.Fatalf("%d bytes", len(data))
+	}
+	rec := data[pcapFileHeaderLen:]
+	if magic := binary.NativeEndian.Uint32(data); magic != pcapMagicNS {
+		t.Errorf("magic %#x", magic)
+	}
+	if incl, orig := binary.NativeEndian.Uint32(rec[8:]), binary.NativeEndian.Uint32(rec[12:]); incl != 20 || orig != 64 {
+		t.Errorf("record of %d bytes of %d", incl, orig)
+	}
+	if second := rec[pcapRecordLen+20+pcapRecordLen]; second != 2 {
+		t.Errorf("second frame starts %d", second)
+	}
+
+	for _, bad := range []Capture{{TX: "a", RX: "a"}, {Snaplen: MaxCaptureSnaplen + 1}, {TX: string(make([]byte, MaxCapturePath+1))}} {
+		if ValidateCapture(bad) == nil {
+			t.Errorf("%+v accepted", bad)
+		}
+	}
+	if _, err := openCapture(Capture{TX: filepath.Join(dir, "missing", "tx.pcap")}); err == nil {
+		t.Error("expected an error for a capture in a missing directory")
+	}
+}
+
+func TestRunCancelledContext(t *testing.T) {
+	c := testContext()
+	c.config = Config{InitialRatePct: 100, ResolutionPct: 0.1, MaxIterations: 20}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.RunThroughputTest(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if c.State() != StateCancelled {
+		t.Errorf("state %v, want cancelled", c.State())
+	}
+}
+
+func TestHeaderFields(t *testing.T) {
+	c := testContext()
+	nextHop := net.HardwareAddr{0x00, 0x1b, 0x21, 0x0a, 0x0b, 0x0c}
+	c.config.Headers = HeaderFields{
+		DstMAC:  nextHop,
+		SrcIP:   IPRange{First: net.ParseIP("192.0.2.10"), Count: 4},
+		DstIP:   IPRange{First: net.ParseIP("198.51.100.1")},
+		DstPort: PortRange{First: 5000, Count: 10},
+		DSCP:    46,
+	}
+	f, offset, err := c.buildFrame(128)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(f[0:6], nextHop) || !bytes.Equal(f[6:12], c.srcMAC) {
+		t.Errorf("MACs % x", f[0:12])
+	}
+	if !net.IP(f[26:30]).Equal(net.ParseIP("192.0.2.10")) || !net.IP(f[30:34]).Equal(net.ParseIP("198.51.100.1")) {
+		t.Errorf("IPs %v -> %v", net.IP(f[26:30]), net.IP(f[30:34]))
+	}
+	if src, dst := binary.BigEndian.Uint16(f[34:]), binary.BigEndian.Uint16(f[36:]); src != 12345 || dst != 5000 {
+		t.Errorf("UDP ports %d -> %d", src, dst)
+	}
+	if tos := f[15]; tos != 46<<2 {
+		t.Errorf("TOS %#x, want DSCP 46", tos)
+	}
+
+	r := c.headerRange()
+	for i := 0; i < 7; i++ {
+		r.apply(f, offset)
+	}
+	if !net.IP(f[26:30]).Equal(net.ParseIP("192.0.2.12")) || !net.IP(f[30:34]).Equal(net.ParseIP("198.51.100.1")) {
+		t.Errorf("7th frame IPs %v -> %v", net.IP(f[26:30]), net.IP(f[30:34]))
+	}
+	if dst := binary.BigEndian.Uint16(f[36:]); dst != 5006 {
+		t.Errorf("7th frame destination port %d", dst)
+	}
+	if sum := onesSum(0, f[14:34]); sum != 0xffff {
+		t.Errorf("IPv4 checksum does not verify: %#x", sum)
+	}
+
+	if err := ValidateHeaders(HeaderFields{SrcPort: PortRange{First: 65000, Count: 1000}}); err == nil {
+		t.Error("port range past 65535 accepted")
+	}
+	if err := ValidateHeaders(HeaderFields{DstIP: IPRange{First: net.ParseIP("2001:db8::1")}}); err == nil {
+		t.Error("IPv6 destination accepted")
+	}
+	if err := ValidateHeaders(HeaderFields{DSCP: 64}); err == nil {
+		t.Error("DSCP 64 accepted")
+	}
+}
+
+func TestPayloadPattern(t *testing.T) {
+	c := testContext()
+	f, offset, err := c.buildFrame(128)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pad := f[offset+payloadLen:]; pad[0] != 0 || pad[5] != 5 {
+		t.Errorf("default padding % x, want incrementing", pad[:8])
+	}
+
+	// Same bytes as the C dataplane's fill
+	for _, tc := range []struct {
+		p    PayloadPattern
+		want []byte
+	}{
+		{PayloadPattern{Kind: PatternZeros}, []byte{0, 0, 0, 0}},
+		{PayloadPattern{Kind: PatternOnes}, []byte{0xff, 0xff, 0xff, 0xff}},
+		{PayloadPattern{Kind: PatternRandom}, []byte{0xe2, 0x20, 0xa8, 0x39, 0x7b, 0x1d, 0xcd, 0xaf, 0x6e}},
+		{PayloadPattern{Kind: PatternPRBS31}, []byte{0x00, 0x00, 0x00, 0x0e, 0x00, 0x00, 0x00, 0xfc}},
+		{PayloadPattern{Kind: PatternCustom, Bytes: []byte{0xde, 0xad, 0xbe}}, []byte{0xde, 0xad, 0xbe, 0xde, 0xad}},
+	} {
+		c.config.Payload = tc.p
+		f, offset, err := c.buildFrame(128)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := f[offset+payloadLen : offset+payloadLen+len(tc.want)]; !bytes.Equal(got, tc.want) {
+			t.Errorf("pattern %d: % x, want % x", tc.p.Kind, got, tc.want)
+		}
+	}
+
+	// A random seed changes the padding
+	a := make([]byte, 16)
+	b := make([]byte, 16)
+	PayloadPattern{Kind: PatternRandom, Seed: 1}.Fill(a)
+	PayloadPattern{Kind: PatternRandom, Seed: 2}.Fill(b)
+	if bytes.Equal(a, b) {
+		t.Error("seeds 1 and 2 fill the same bytes")
+	}
+
+	for _, bad := range []PayloadPattern{{Kind: PatternCustom}, {Kind: PatternCustom, Bytes: make([]byte, MaxPatternLen+1)}, {Kind: 9}} {
+		if err := ValidatePattern(bad); err == nil {
+			t.Errorf("pattern %+v accepted", bad)
+		}
+	}
+}
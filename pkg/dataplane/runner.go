@@ -0,0 +1,112 @@
+package dataplane
+
+import (
+	"runtime"
+	"sync"
+)
+
+// runner runs a context's calls one at a time on a goroutine of its own,
+// locked to an OS thread for the life of the context. Calls reach it over
+// a command channel, so however many goroutines use the context (a web
+// handler, the TUI, a watchdog) they can never reach the C library at the
+// same time.
+type runner struct {
+	cmds   chan func()
+	stop   chan struct{} // Closed by the last command, after which calls fail
+	mu     *sync.Mutex   // Held while a command runs, for Go-side state read outside calls
+	thread int           // OS thread of the goroutine; 0 where it cannot be told
+	once   sync.Once
+}
+
+// newRunner starts a runner whose commands hold mu
+func newRunner(mu *sync.Mutex) *runner {
+	r := &runner{cmds: make(chan func()), stop: make(chan struct{}), mu: mu}
+	started := make(chan struct{})
+	go r.loop(started)
+	<-started
+	return r
+}
+
+func (r *runner) loop(started chan<- struct{}) {
+	// Never unlocked: the thread ends with the goroutine, taking any
+	// thread state the C library left behind with it
+	runtime.LockOSThread()
+	r.thread = threadID()
+	close(started)
+	for {
+		// A command that stopped the runner is its last, even with
+		// others waiting
+		select {
+		case <-r.stop:
+			return
+		default:
+		}
+		select {
+		case cmd := <-r.cmds:
+			r.mu.Lock()
+			cmd()
+			r.mu.Unlock()
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// do runs fn on the runner and waits for it to return. op names the call
+// in errors. A panic in fn is raised again in the caller.
+//
+// A call from inside another on the same context, e.g. from the step
+// callback of RunThroughputSearch, would wait for itself forever, so it
+// panics instead.
+func (r *runner) do(op string, fn func()) error {
+	if r.thread != 0 && threadID() == r.thread {
+		panic("dataplane: " + op + " called from inside another call on the same context")
+	}
+	done := make(chan any, 1)
+	cmd := func() {
+		defer func() { done <- recover() }()
+		fn()
+	}
+	select {
+	case r.cmds <- cmd:
+	case <-r.stop:
+		return &Error{Op: op, Err: ErrClosed}
+	}
+	if p := <-done; p != nil {
+		panic(p)
+	}
+	return nil
+}
+
+// exec runs a call returning an error on the runner
+func (r *runner) exec(op string, fn func() error) error {
+	var err error
+	if rerr := r.do(op, func() { err = fn() }); rerr != nil {
+		return rerr
+	}
+	return err
+}
+
+// close stops the runner once the running command returns. Only a
+// command may call it.
+func (r *runner) close() {
+	r.once.Do(func() { close(r.stop) })
+}
+
+// call runs a call returning a result and an error on r
+func call[T any](r *runner, op string, fn func() (T, error)) (T, error) {
+	var v T
+	var err error
+	if rerr := r.do(op, func() { v, err = fn() }); rerr != nil {
+		return v, rerr
+	}
+	return v, err
+}
+
+// value runs a call returning a result on r, the zero value once r has
+// stopped
+func value[T any](r *runner, op string, fn func() T) T {
+	var v T
+	r.do(op, func() { v = fn() })
+	return v
+}
@@ -0,0 +1,8 @@
+package dataplane
+
+import "syscall"
+
+// threadID returns the OS thread the calling goroutine runs on
+func threadID() int {
+	return syscall.Gettid()
+}
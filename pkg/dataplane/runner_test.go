@@ -0,0 +1,68 @@
+package dataplane
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRunner(t *testing.T) {
+	var mu sync.Mutex
+	r := newRunner(&mu)
+
+	// Calls from many goroutines run one at a time on the runner's thread
+	var wg sync.WaitGroup
+	var running, most, total int
+	threads := make(map[int]bool)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.do("count", func() {
+				running++
+				most = max(most, running)
+				total++
+				threads[threadID()] = true
+				running--
+			})
+		}()
+	}
+	wg.Wait()
+	if total != 50 || most != 1 || len(threads) != 1 {
+		t.Errorf("%d calls, %d at once, on %d threads", total, most, len(threads))
+	}
+
+	// A panic reaches the caller, and the runner carries on
+	func() {
+		defer func() {
+			if p := recover(); p != "boom" {
+				t.Errorf("recovered %v", p)
+			}
+		}()
+		r.do("panic", func() { panic("boom") })
+	}()
+	if v, err := call(r, "value", func() (int, error) { return 7, nil }); v != 7 || err != nil {
+		t.Errorf("call = %d, %v", v, err)
+	}
+
+	// A call from inside a call would deadlock
+	if threadID() != 0 {
+		var p any
+		r.do("outer", func() {
+			defer func() { p = recover() }()
+			r.do("inner", func() {})
+		})
+		if s, _ := p.(string); !strings.Contains(s, "inner called from inside") {
+			t.Errorf("nested call: %v", p)
+		}
+	}
+
+	r.do("close", r.close)
+	if err := r.exec("after", func() error { return nil }); !errors.Is(err, ErrClosed) {
+		t.Errorf("call after close: %v", err)
+	}
+	if v := value(r, "after", func() int { return 1 }); v != 0 {
+		t.Errorf("value after close = %d", v)
+	}
+}
@@ -0,0 +1,114 @@
+package dataplane
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// SpeedProfile is the TX tuning for a class of line rate, mirroring the C
+// dataplane's speed_profile_t. A profile applies from its line rate up to
+// the next one's.
+type SpeedProfile struct {
+	Name       string `json:"name"`
+	LineRate   uint64 `json:"line_rate_bps"` // Where the class starts
+	BatchSize  uint32 `json:"batch_size"`    // Frames sent back to back per pacing wait
+	BusyWait   bool   `json:"busy_wait"`     // Spin instead of sleeping between batches
+	MaxBacklog uint32 `json:"max_backlog"`   // Frames the pacer may fall behind before resetting
+}
+
+// SpeedProfiles are the tuning profiles, slowest first. Up to 10G a frame
+// per wait keeps up; above, the gap between frames is shorter than reading
+// the clock, so frames go out in batches.
+var SpeedProfiles = []SpeedProfile{
+	{Name: "1g", LineRate: 1e9, BatchSize: 1, MaxBacklog: 10},
+	{Name: "2.5g", LineRate: 2.5e9, BatchSize: 1, MaxBacklog: 16},
+	{Name: "5g", LineRate: 5e9, BatchSize: 1, MaxBacklog: 32},
+	{Name: "10g", LineRate: 10e9, BatchSize: 1, MaxBacklog: 64},
+	{Name: "25g", LineRate: 25e9, BatchSize: 4, BusyWait: true, MaxBacklog: 128},
+	{Name: "40g", LineRate: 40e9, BatchSize: 8, BusyWait: true, MaxBacklog: 256},
+	{Name: "50g", LineRate: 50e9, BatchSize: 8, BusyWait: true, MaxBacklog: 256},
+	{Name: "100g", LineRate: 100e9, BatchSize: 16, BusyWait: true, MaxBacklog: 512},
+	{Name: "200g", LineRate: 200e9, BatchSize: 32, BusyWait: true, MaxBacklog: 1024},
+	{Name: "400g", LineRate: 400e9, BatchSize: 64, BusyWait: true, MaxBacklog: 2048},
+}
+
+// minWireSize is a 64-byte frame with its preamble and inter-frame gap,
+// the frame BatchSize is set for
+const minWireSize = 64 + 20
+
+// Batch returns the frames sent back to back per pacing wait at a frame
+// size. A larger frame takes longer on the wire, so fewer outlast reading
+// the clock, and a batch of jumbo frames would burst far beyond the rate.
+func (sp SpeedProfile) Batch(frameSize uint32) uint32 {
+	return max(1, sp.BatchSize*minWireSize/(frameSize+20))
+}
+
+// ProfileFor returns the profile of the fastest class not above lineRate
+func ProfileFor(lineRate uint64) SpeedProfile {
+	p := SpeedProfiles[0]
+	for _, sp := range SpeedProfiles[1:] {
+		if sp.LineRate > lineRate {
+			break
+		}
+		p = sp
+	}
+	return p
+}
+
+// ProfileByName returns the profile of that name
+func ProfileByName(name string) (SpeedProfile, bool) {
+	for _, sp := range SpeedProfiles {
+		if sp.Name == name {
+			return sp, true
+		}
+	}
+	return SpeedProfile{}, false
+}
+
+// selectProfile returns the named profile, or the one for lineRate when
+// name is ""
+func selectProfile(name string, lineRate uint64) (SpeedProfile, error) {
+	if name == "" {
+		return ProfileFor(lineRate), nil
+	}
+	sp, ok := ProfileByName(name)
+	if !ok {
+		return SpeedProfile{}, fmt.Errorf("configure failed: unknown speed profile %q", name)
+	}
+	return sp, nil
+}
+
+// SpeedProfile returns the TX tuning profile trials run with
+func (c *Context) SpeedProfile() SpeedProfile {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.profile
+}
+
+// LinkFEC returns the forward error correction the interface's link
+// runs with, as ethtool reports it (e.g. "RS", "BaseR", "None"), or ""
+// when ethtool cannot tell
+func LinkFEC(iface string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "ethtool", "--show-fec", iface).Output()
+	if err != nil {
+		return ""
+	}
+	return parseFEC(string(out))
+}
+
+// parseFEC extracts the active encoding from ethtool --show-fec output
+func parseFEC(out string) string {
+	for _, line := range strings.Split(out, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok || !strings.HasPrefix(key, "Active FEC encoding") {
+			continue
+		}
+		return strings.TrimSpace(value)
+	}
+	return ""
+}
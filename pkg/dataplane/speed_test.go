@@ -0,0 +1,95 @@
+package dataplane
+
+import "testing"
+
+func TestCalcPPSMultiGig(t *testing.T) {
+	cases := []struct {
+		lineRate uint64
+		size     uint32
+		want     uint64
+	}{
+		{2.5e9, 64, 3720238},
+		{5e9, 64, 7440476},
+		{25e9, 64, 37202380},
+		{40e9, 1518, 3250975},
+		{50e9, 64, 74404761},
+		{100e9, 64, 148809523},
+		{400e9, 64, 595238095},
+		{400e9, 9000, 5543237},
+		{10e9, 9216, 135339},
+		{100e9, 16000, 780274},
+	}
+	for _, c := range cases {
+		if got := CalcPPS(c.lineRate, c.size); got != c.want {
+			t.Errorf("%d bps, %d bytes: %d pps, want %d", c.lineRate, c.size, got, c.want)
+		}
+	}
+}
+
+func TestProfileFor(t *testing.T) {
+	cases := []struct {
+		lineRate uint64
+		want     string
+	}{
+		{100e6, "1g"},
+		{1e9, "1g"},
+		{2.5e9, "2.5g"},
+		{5e9, "5g"},
+		{20e9, "10g"}, // Two bonded 10G links
+		{25e9, "25g"},
+		{40e9, "40g"},
+		{50e9, "50g"},
+		{100e9, "100g"},
+		{400e9, "400g"},
+		{800e9, "400g"},
+	}
+	for _, c := range cases {
+		if got := ProfileFor(c.lineRate).Name; got != c.want {
+			t.Errorf("%d bps: profile %s, want %s", c.lineRate, got, c.want)
+		}
+	}
+
+	if _, err := selectProfile("3g", 10e9); err == nil {
+		t.Error("unknown profile selected")
+	}
+	if p, err := selectProfile("100g", 10e9); err != nil || p.BatchSize <= 1 || !p.BusyWait {
+		t.Errorf("100g profile %+v, %v", p, err)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	p, _ := ProfileByName("100g")
+	cases := []struct {
+		size uint32
+		want uint32
+	}{
+		{64, 16},
+		{128, 9},
+		{1518, 1},
+		{9216, 1},
+	}
+	for _, c := range cases {
+		if got := p.Batch(c.size); got != c.want {
+			t.Errorf("%d bytes: batch %d, want %d", c.size, got, c.want)
+		}
+	}
+	if (SpeedProfile{}).Batch(64) != 1 {
+		t.Error("a zero batch size should send a frame per wait")
+	}
+}
+
+func TestParseFEC(t *testing.T) {
+	out := `FEC parameters for eth0:
+Supported/Configured FEC encodings: Auto RS BaseR
+Active FEC encoding: RS
+`
+	if got := parseFEC(out); got != "RS" {
+		t.Errorf("FEC %q", got)
+	}
+	if got := parseFEC("FEC parameters for eth0:\nConfigured FEC encodings: Auto\nActive FEC encoding: None\n"); got != "None" {
+		t.Errorf("FEC off %q", got)
+	}
+	if got := parseFEC(""); got != "" {
+		t.Errorf("no output %q", got)
+	}
+}
@@ -0,0 +1,200 @@
+package dataplane
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// statsInterval is how often subscribers get a snapshot while a test runs
+const statsInterval = 100 * time.Millisecond
+
+// subscribers fans Stats snapshots out to the channels from Subscribe. It
+// also keeps the start of the running test method and the snapshot of the
+// previous GetStats, for callers that poll.
+type subscribers struct {
+	mu    sync.Mutex
+	chans map[chan Stats]struct{}
+
+	start  time.Time // Of the running or last test method
+	first  LiveStats // Live counters at start
+	prev   LiveStats // At the previous GetStats
+	prevAt time.Time
+}
+
+func (s *subscribers) add(ctx context.Context) <-chan Stats {
+	ch := make(chan Stats, 1)
+	s.mu.Lock()
+	if s.chans == nil {
+		s.chans = make(map[chan Stats]struct{})
+	}
+	s.chans[ch] = struct{}{}
+	s.mu.Unlock()
+	context.AfterFunc(ctx, func() { s.remove(ch) })
+	return ch
+}
+
+func (s *subscribers) remove(ch chan Stats) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.chans[ch]; ok {
+		delete(s.chans, ch)
+		close(ch)
+	}
+}
+
+func (s *subscribers) closeAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for ch := range s.chans {
+		delete(s.chans, ch)
+		close(ch)
+	}
+}
+
+func (s *subscribers) empty() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.chans) == 0
+}
+
+// publish sends a snapshot to every subscriber. One a slow reader has not
+// taken yet is replaced, so readers always see the latest.
+func (s *subscribers) publish(st Stats) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for ch := range s.chans {
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- st
+	}
+}
+
+// snapshot is the Stats of live counters ls, relative to those of the test
+// method started at start, with rates since the counters prev at prevAt
+func snapshot(ls, first LiveStats, start time.Time, prev LiveStats, prevAt, now time.Time) Stats {
+	st := Stats{
+		FrameSize:     ls.FrameSize,
+		TxPackets:     ls.TxPackets - first.TxPackets,
+		TxBytes:       ls.TxBytes - first.TxBytes,
+		RxPackets:     ls.RxPackets - first.RxPackets,
+		RxBytes:       ls.RxBytes - first.RxBytes,
+		CurrentRate:   ls.OfferedRatePct,
+		Iteration:     ls.Trials - first.Trials,
+		LastLossPct:   ls.LastLossPct,
+		LastLatency:   ls.LastLatency,
+		LastOrder:     ls.LastOrder,
+		InTrial:       ls.InTrial,
+		Warmup:        ls.InTrial && ls.TrialElapsed < ls.TrialWarmup,
+		TrialElapsed:  ls.TrialElapsed,
+		TrialWarmup:   ls.TrialWarmup,
+		TrialDuration: ls.TrialDuration,
+		Timestamp:     now,
+	}
+	if !start.IsZero() {
+		st.Elapsed = now.Sub(start)
+	}
+	if total := ls.TrialWarmup + ls.TrialDuration; ls.InTrial && total > 0 {
+		st.TrialProgress = min(float64(ls.TrialElapsed)/float64(total), 1)
+	}
+	if dt := now.Sub(prevAt).Seconds(); dt > 0 && !prevAt.IsZero() {
+		st.TxPPS = float64(ls.TxPackets-prev.TxPackets) / dt
+		st.RxPPS = float64(ls.RxPackets-prev.RxPackets) / dt
+		st.TxMbps = float64(ls.TxBytes-prev.TxBytes) * 8 / dt / 1e6
+		st.RxMbps = float64(ls.RxBytes-prev.RxBytes) * 8 / dt / 1e6
+	}
+	return st
+}
+
+// poll publishes snapshots of the live counters every statsInterval until
+// stop is called, then a last one. Counters are relative to the call.
+func (s *subscribers) poll(live func() LiveStats) (stop func()) {
+	start := time.Now()
+	first := live()
+	s.mu.Lock()
+	s.start, s.first = start, first
+	s.prev, s.prevAt = first, start
+	s.mu.Unlock()
+	prev, prevAt := first, start
+	publish := func(now time.Time) {
+		if s.empty() {
+			return
+		}
+		ls := live()
+		s.publish(snapshot(ls, first, start, prev, prevAt, now))
+		prev, prevAt = ls, now
+	}
+
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		ticker := time.NewTicker(statsInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case now := <-ticker.C:
+				publish(now)
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-exited
+		publish(time.Now())
+	}
+}
+
+// Subscribe returns a channel of Stats snapshots, sent about ten times a
+// second while a test method runs and once more as it returns. A reader
+// that falls behind gets the latest snapshot, never a backlog, and never
+// slows the test. The channel is closed when ctx ends or on Close.
+func (c *Context) Subscribe(ctx context.Context) <-chan Stats {
+	return c.subs.add(ctx)
+}
+
+// get returns the Stats of live counters ls with rates since the previous
+// call, or since the test method started
+func (s *subscribers) get(ls LiveStats, now time.Time) Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := snapshot(ls, s.first, s.start, s.prev, s.prevAt, now)
+	s.prev, s.prevAt = ls, now
+	return st
+}
+
+// GetStats returns a snapshot of the running test like those sent to
+// Subscribe, for callers that poll instead. Rates are over the interval
+// since the previous GetStats. Like State it is a probe, so it can be
+// called while a test method runs.
+func (c *Context) GetStats() Stats {
+	return c.subs.get(c.LiveStats(), time.Now())
+}
+
+// GetState returns the test state with the trial it is on: the frame
+// size, offered load and iteration of the test method, and how far the
+// trial has got
+func (c *Context) GetState() Status {
+	ls := c.LiveStats()
+	c.subs.mu.Lock()
+	st := Status{
+		State:     c.State(),
+		FrameSize: ls.FrameSize,
+		RatePct:   ls.OfferedRatePct,
+		Iteration: ls.Trials - c.subs.first.Trials,
+		InTrial:   ls.InTrial,
+		Warmup:    ls.InTrial && ls.TrialElapsed < ls.TrialWarmup,
+	}
+	c.subs.mu.Unlock()
+	if ls.InTrial {
+		st.Iteration++
+		if total := ls.TrialWarmup + ls.TrialDuration; total > 0 {
+			st.TrialProgress = min(float64(ls.TrialElapsed)/float64(total), 1)
+		}
+	}
+	return st
+}
@@ -0,0 +1,76 @@
+package dataplane
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSubscribers(t *testing.T) {
+	var s subscribers
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := s.add(ctx)
+	other := s.add(context.Background())
+
+	var calls atomic.Uint64
+	live := func() LiveStats {
+		n := calls.Add(1)
+		return LiveStats{FrameSize: 128, TxPackets: 1000 + n*10, TxBytes: n * 1280, Trials: 5 + n}
+	}
+	stop := s.poll(live)
+	stop()
+
+	// The last snapshot replaces any not yet read
+	st := <-ch
+	n := calls.Load()
+	if st.FrameSize != 128 || st.TxPackets != (n-1)*10 || st.Iteration != n-1 {
+		t.Errorf("snapshot %+v after %d polls", st, n)
+	}
+	if st.Elapsed <= 0 || st.Timestamp.IsZero() {
+		t.Errorf("elapsed %v at %v", st.Elapsed, st.Timestamp)
+	}
+	select {
+	case extra := <-ch:
+		t.Errorf("backlog: %+v", extra)
+	default:
+	}
+
+	cancel()
+	if _, ok := <-ch; ok {
+		t.Error("channel open after its context ended")
+	}
+	s.closeAll()
+	<-other
+	if _, ok := <-other; ok {
+		t.Error("channel open after closeAll")
+	}
+}
+
+func TestSubscribersGet(t *testing.T) {
+	var s subscribers
+	at := time.Now()
+	if st := s.get(LiveStats{TxPackets: 50}, at); st.TxPackets != 50 || st.Elapsed != 0 || st.TxPPS != 0 {
+		t.Errorf("before any test method: %+v", st)
+	}
+
+	s.poll(func() LiveStats { return LiveStats{TxPackets: 100, Trials: 2} })()
+	ls := LiveStats{
+		FrameSize: 256, TxPackets: 1100, TxBytes: 256000, Trials: 3,
+		InTrial: true, TrialWarmup: 2 * time.Second, TrialDuration: 8 * time.Second, TrialElapsed: time.Second,
+	}
+	st := s.get(ls, s.start.Add(time.Second))
+	if st.TxPackets != 1000 || st.Iteration != 1 || st.TxPPS != 1000 {
+		t.Errorf("counters %+v", st)
+	}
+	if !st.Warmup || st.TrialProgress != 0.1 {
+		t.Errorf("warmup %v progress %v", st.Warmup, st.TrialProgress)
+	}
+
+	// Rates are since the previous call
+	ls.TxPackets, ls.TrialElapsed = 1600, 6*time.Second
+	st = s.get(ls, s.start.Add(1500*time.Millisecond))
+	if st.TxPPS != 1000 || st.Warmup || st.TrialProgress != 0.6 {
+		t.Errorf("second poll %+v", st)
+	}
+}
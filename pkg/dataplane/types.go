@@ -0,0 +1,1084 @@
+package dataplane
+
+// Types shared by the C dataplane (dataplane.go) and the pure-Go fallback
+// (purego.go)
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"time"
+)
+
+// TestType mirrors C test_type_t
+type TestType int
+
+const (
+	TestThroughput TestType = iota
+	TestLatency
+	TestFrameLoss
+	TestBackToBack
+	TestSystemRecovery
+	TestReset
+	TestY1564Config
+	TestY1564Perf
+	TestY1564Full
+)
+
+// TestState mirrors C test_state_t
+type TestState int
+
+const (
+	StateIdle TestState = iota
+	StateRunning
+	StateCompleted
+	StateFailed
+	StateCancelled
+)
+
+// LatencyStats contains latency measurements
+type LatencyStats struct {
+	Count    uint64
+	MinNs    float64
+	MaxNs    float64
+	AvgNs    float64
+	JitterNs float64
+	P50Ns    float64
+	P95Ns    float64
+	P99Ns    float64
+
+	// Histogram is the full distribution, set with Config.LatencyHistogram
+	Histogram *LatencyHistogram `json:",omitempty"`
+}
+
+// FrameOrder counts test frames the DUT delivered out of sequence or more
+// than once, from their sequence numbers, and with Config.VerifyPayload
+// those it delivered mangled. ECMP and buffering bugs show up here while
+// the loss stays at 0%.
+type FrameOrder struct {
+	ReorderedFrames uint64 `json:",omitempty"` // Received after a later-sent frame
+	DuplicateFrames uint64 `json:",omitempty"` // Extra copies of frames already received
+	MaxReorderDepth uint32 `json:",omitempty"` // Most frames a reordered frame arrived behind
+	CorruptedFrames uint64 `json:",omitempty"` // Received with a payload failing its CRC-32; not lost
+}
+
+// ThroughputResult from binary search test
+type ThroughputResult struct {
+	FrameSize    uint32
+	MaxRatePct   float64
+	MaxRateMbps  float64
+	MaxRatePps   float64
+	FramesTested uint64
+	Iterations   uint32
+	Latency      LatencyStats
+}
+
+// FrameLossPoint for a single load level
+type FrameLossPoint struct {
+	OfferedRatePct float64
+	ActualRateMbps float64
+	FramesSent     uint64
+	FramesRecv     uint64
+	LossPct        float64
+	FrameOrder
+}
+
+// LatencyResult from latency test
+type LatencyResult struct {
+	FrameSize      uint32
+	OfferedRatePct float64
+	Latency        LatencyStats
+	Streams        []StreamStats `json:",omitempty"` // With Config.Streams
+	Probe          *ProbeStats   `json:",omitempty"` // With Config.LatencyProbe
+	Families       []FamilyStats `json:",omitempty"` // With a dual-stack Config.IP
+}
+
+// BurstResult from back-to-back test
+type BurstResult struct {
+	FrameSize     uint32
+	MaxBurst      uint64
+	BurstDuration float64
+	Trials        uint32
+}
+
+// RecoveryResult from RFC 2544 Section 26.5 System Recovery test
+type RecoveryResult struct {
+	FrameSize       uint32
+	OverloadRatePct float64
+	RecoveryRatePct float64
+	OverloadSec     uint32
+	RecoveryTimeMs  float64
+	FramesLost      uint64
+	Trials          uint32
+}
+
+// ResetResult from RFC 2544 Section 26.6 Reset test
+type ResetResult struct {
+	FrameSize   uint32
+	ResetTimeMs float64
+	FramesLost  uint64
+	Trials      uint32
+	ManualReset bool
+}
+
+// Y1564SLA contains SLA parameters for Y.1564 testing
+type Y1564SLA struct {
+	CIRMbps         float64
+	EIRMbps         float64
+	CBSBytes        uint32
+	EBSBytes        uint32
+	FDThresholdMs   float64
+	FDVThresholdMs  float64
+	FLRThresholdPct float64
+	EIRSAC          Y1564SAC // CIR + EIR step
+	PolicingSAC     Y1564SAC // Policing step
+}
+
+// Y1564SAC is the acceptance criteria of a configuration test step above
+// CIR; a negative threshold is not checked
+type Y1564SAC struct {
+	FDThresholdMs   float64
+	FDVThresholdMs  float64
+	FLRThresholdPct float64
+}
+
+// Y1564Service represents a service configuration for Y.1564 testing
+type Y1564Service struct {
+	ServiceID    uint32
+	ServiceName  string
+	SLA          Y1564SLA
+	FrameSize    uint32
+	CoS          uint8
+	Enabled      bool
+	PolicingStep bool   // Add the step at 125% of CIR + EIR
+	VLANID       uint16 // 802.1Q tag of the service's frames; VLANID and PCP 0 = the Config's tag
+	PCP          uint8
+	OuterVLANID  uint16 // QinQ S-tag over it; OuterVLANID and OuterPCP 0 = the Config's S-tag
+	OuterPCP     uint8
+	IPVersion    uint8   // 4 or 6; 0 = IPv6 with an IPv6-only Config.IP, else IPv4
+	ExtraMbps    float64 // Load taken over from failed services, offered on top of every step; the SLA stays at CIR
+}
+
+// Y1564StepPhase is the part of the configuration test a step belongs to
+type Y1564StepPhase string
+
+const (
+	Y1564StepCIR      Y1564StepPhase = "cir"      // At or below CIR
+	Y1564StepEIR      Y1564StepPhase = "eir"      // CIR + EIR
+	Y1564StepPolicing Y1564StepPhase = "policing" // 125% of CIR + EIR
+)
+
+// Y1564StepResult from a Y.1564 configuration test step
+type Y1564StepResult struct {
+	Step             uint32
+	OfferedRatePct   float64
+	AchievedRateMbps float64
+	FramesTx         uint64
+	FramesRx         uint64
+	FLRPct           float64
+	FDAvgMs          float64
+	FDMinMs          float64
+	FDMaxMs          float64
+	FDVMs            float64
+	FLRPass          bool
+	FDPass           bool
+	FDVPass          bool
+	StepPass         bool
+	Phase            Y1564StepPhase
+}
+
+// Y1564ConfigResult from Y.1564 service configuration test
+type Y1564ConfigResult struct {
+	ServiceID   uint32
+	Steps       []Y1564StepResult
+	ServicePass bool
+}
+
+// Y1564PerfResult from Y.1564 service performance test
+type Y1564PerfResult struct {
+	ServiceID   uint32
+	DurationSec uint32
+	FramesTx    uint64
+	FramesRx    uint64
+	FLRPct      float64
+	FDAvgMs     float64
+	FDMinMs     float64
+	FDMaxMs     float64
+	FDVMs       float64
+	FLRPass     bool
+	FDPass      bool
+	FDVPass     bool
+	ServicePass bool
+}
+
+// TrafficPattern mirrors C traffic_pattern_t
+type TrafficPattern int
+
+const (
+	PatternFullMesh TrafficPattern = iota
+	PatternPartialMesh
+	PatternPairWise
+	PatternOneToMany
+	PatternManyToOne
+)
+
+var patternNames = map[TrafficPattern]string{
+	PatternFullMesh:    "mesh",
+	PatternPartialMesh: "partial_mesh",
+	PatternPairWise:    "pairs",
+	PatternOneToMany:   "one_to_many",
+	PatternManyToOne:   "many_to_one",
+}
+
+// String returns the orientation name used in configuration
+func (p TrafficPattern) String() string {
+	if name, ok := patternNames[p]; ok {
+		return name
+	}
+	return fmt.Sprintf("pattern(%d)", int(p))
+}
+
+// MarshalText encodes the pattern by name in JSON results
+func (p TrafficPattern) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// RFC2889Config contains parameters for RFC 2889 LAN switch tests
+type RFC2889Config struct {
+	Pattern           TrafficPattern
+	PortCount         uint32
+	FrameSize         uint32
+	TrialDuration     time.Duration
+	WarmupPeriod      time.Duration
+	AddressCount      uint32
+	AcceptableLossPct float64
+}
+
+// RFC2889ForwardingResult from RFC 2889 Section 5.1 forwarding rate test
+type RFC2889ForwardingResult struct {
+	FrameSize    uint32
+	PortCount    uint32
+	Pattern      TrafficPattern
+	MaxRatePct   float64 // Of the one measured flow
+	MaxRateFPS   float64
+	RateMbps     float64
+	FramesTx     uint64
+	FramesRx     uint64
+	LossPct      float64
+	Flows        uint32
+	IngressPorts uint32
+	EgressPorts  uint32
+}
+
+// Config for RFC2544 tests
+type Config struct {
+	Interface      string
+	RXInterface    string // Receive port through the DUT (empty = Interface)
+	LineRate       uint64
+	AutoDetect     bool
+	TestType       TestType
+	FrameSize      uint32
+	IncludeJumbo   bool
+	JumboSizes     []uint32 // Sizes IncludeJumbo adds (nil = 9000)
+	TrialDuration  time.Duration
+	WarmupPeriod   time.Duration
+	InitialRatePct float64
+	ResolutionPct  float64
+	MaxIterations  uint32
+	AcceptableLoss float64
+	HWTimestamp    bool
+	MeasureLatency bool
+	UsePacing      bool
+	BatchSize      uint32
+	SpeedProfile   string // TX tuning profile by name; "" picks it from the line rate
+	UseDPDK        bool
+	DPDKArgs       string
+
+	// LatencyHistogram attaches each result's latency distribution
+	// (LatencyStats.Histogram)
+	LatencyHistogram bool
+
+	// LatencySamples is the most latency samples a trial keeps, up to
+	// MaxLatencySamples (0 = DefaultLatencySamples). The sample arrays
+	// are allocated once and reused by every trial.
+	LatencySamples uint32
+
+	// VerifyPayload seals a CRC-32 into the last 4 bytes of each frame's
+	// payload and counts received frames failing it
+	// (FrameOrder.CorruptedFrames). Frames need 4 bytes of padding.
+	VerifyPayload bool
+
+	// Streams spreads each trial round-robin across this many flows with
+	// distinct MAC/IP/UDP tuples, to exercise the DUT's hashing and load
+	// balancing, reporting each one's counters (StreamStats). Stream i
+	// adds i to the source MAC, both IPs and the UDP source port. 0 or 1
+	// is a single flow; built-in headers only.
+	Streams uint32
+
+	// VLANID and PCP tag built-in frames with an 802.1Q header. The tag
+	// counts toward FrameSize, taking 4 bytes of padding. VLANID 0 with a
+	// PCP is priority-tagged; both 0 is untagged.
+	VLANID uint16
+	PCP    uint8
+
+	// OuterVLANID and OuterPCP stack an 802.1ad S-tag over that tag
+	// (QinQ), taking 4 more bytes of padding. OuterTPID is the S-tag's
+	// TPID (0 = 0x88a8) and TPID the inner tag's (0 = 0x8100).
+	OuterVLANID uint16
+	OuterPCP    uint8
+	OuterTPID   uint16
+	TPID        uint16
+
+	// MPLSLabels pushes a label stack, outermost first, between the tags
+	// and the IPv4 header of built-in Ethernet II frames, taking 4 bytes
+	// of padding a label. With MPLSEXPFromCoS, Y.1564 services mark the
+	// EXP bits of every label from their CoS (DSCP >> 3).
+	MPLSLabels     []MPLSLabel
+	MPLSEXPFromCoS bool
+
+	// LatencyProbe sends a low-rate stream at a priority of its own
+	// alongside the load of trials that measure latency
+	LatencyProbe LatencyProbe
+
+	// IP selects IPv4, IPv6 or dual-stack built-in frames. Dual-stack
+	// splits each trial's frames between both families, reporting each
+	// one's counters (FamilyStats).
+	IP IPStack
+
+	// Headers replaces the built-in addresses and UDP ports of the
+	// frames, e.g. the next hop's MAC through a routed DUT or the 5-tuple
+	// a firewall admits
+	Headers HeaderFields
+
+	// Payload fills the padding of generated frames, for DUTs whose
+	// compression or deduplication depends on its content. The zero value
+	// is the built-in incrementing fill.
+	Payload PayloadPattern
+
+	// Capture saves the frames trials send and receive to pcap files,
+	// for post-mortem of frame loss. The zero value captures nothing.
+	Capture Capture
+}
+
+// DefaultCaptureLimit is the most frames a capture file takes when
+// Capture.Limit is 0
+const DefaultCaptureLimit = 100000
+
+// MaxCaptureSnaplen is the longest part of a frame a capture keeps
+const MaxCaptureSnaplen = 262144
+
+// MaxCapturePath is the longest capture file path
+const MaxCapturePath = 255
+
+// Capture selects pcap files for the frames of trials. A file takes the
+// first Limit frames after Configure, across trials, and is closed with
+// the Context.
+type Capture struct {
+	TX      string // Frames sent; "" = not captured
+	RX      string // Frames received, test frames or not; "" = not captured
+	Limit   uint32 // Frames a file; 0 = DefaultCaptureLimit
+	Snaplen uint32 // Bytes kept of each frame, e.g. 64 for headers only; 0 = whole frames
+}
+
+// CaptureStats counts the frames written to the capture files
+type CaptureStats struct {
+	TxFrames uint64
+	RxFrames uint64
+}
+
+// ValidateCapture checks a capture fits the dataplane
+func ValidateCapture(c Capture) error {
+	switch {
+	case len(c.TX) > MaxCapturePath || len(c.RX) > MaxCapturePath:
+		return fmt.Errorf("capture path longer than %d bytes", MaxCapturePath)
+	case c.Snaplen > MaxCaptureSnaplen:
+		return fmt.Errorf("capture snaplen %d (at most %d)", c.Snaplen, MaxCaptureSnaplen)
+	case c.TX != "" && c.TX == c.RX:
+		return fmt.Errorf("capture of both directions to the same file %s", c.TX)
+	}
+	return nil
+}
+
+// limit is the frames a capture file takes
+func (c Capture) limit() uint32 {
+	if c.Limit == 0 {
+		return DefaultCaptureLimit
+	}
+	return c.Limit
+}
+
+// PatternKind mirrors C payload_pattern_kind_t
+type PatternKind int
+
+const (
+	PatternIncrementing PatternKind = iota // 00 01 02 .. ff 00 ..
+	PatternZeros
+	PatternOnes   // All ff
+	PatternRandom // SplitMix64 outputs from Seed, most significant byte first
+	PatternPRBS31 // x^31 + x^28 + 1 from all ones, MSB first
+	PatternCustom // Bytes repeated
+)
+
+// MaxPatternLen is the longest custom pattern
+const MaxPatternLen = 256
+
+// PayloadPattern is the content of the padding after the RFC 2544
+// payload. Every frame of a trial carries the same padding, the pattern
+// starting at its first byte.
+type PayloadPattern struct {
+	Kind  PatternKind
+	Seed  uint64 // PatternRandom
+	Bytes []byte // PatternCustom, 1-MaxPatternLen bytes
+}
+
+// ValidateLatencySamples checks a sample capacity fits the dataplane
+func ValidateLatencySamples(n uint32) error {
+	if n > MaxLatencySamples {
+		return fmt.Errorf("%d latency samples (at most %d)", n, MaxLatencySamples)
+	}
+	return nil
+}
+
+// latencySamples is the sample capacity cfg configures
+func (cfg *Config) latencySamples() int {
+	if cfg.LatencySamples == 0 {
+		return DefaultLatencySamples
+	}
+	return int(cfg.LatencySamples)
+}
+
+// ValidatePattern checks a payload pattern fits the dataplane
+func ValidatePattern(p PayloadPattern) error {
+	switch {
+	case p.Kind < PatternIncrementing || p.Kind > PatternCustom:
+		return fmt.Errorf("unknown payload pattern %d", p.Kind)
+	case p.Kind == PatternCustom && (len(p.Bytes) == 0 || len(p.Bytes) > MaxPatternLen):
+		return fmt.Errorf("custom payload pattern of %d bytes (1-%d)", len(p.Bytes), MaxPatternLen)
+	}
+	return nil
+}
+
+// Fill writes the pattern over b, as the C dataplane fills its padding
+func (p PayloadPattern) Fill(b []byte) {
+	switch p.Kind {
+	case PatternZeros:
+		clear(b)
+	case PatternOnes:
+		for i := range b {
+			b[i] = 0xFF
+		}
+	case PatternRandom:
+		state := p.Seed
+		var v [8]byte
+		for i := 0; i < len(b); i += 8 {
+			state += 0x9E3779B97F4A7C15
+			z := state
+			z = (z ^ z>>30) * 0xBF58476D1CE4E5B9
+			z = (z ^ z>>27) * 0x94D049BB133111EB
+			binary.BigEndian.PutUint64(v[:], z^z>>31)
+			copy(b[i:], v[:])
+		}
+	case PatternPRBS31:
+		state := uint32(0x7FFFFFFF)
+		for i := range b {
+			var out byte
+			for bit := 0; bit < 8; bit++ {
+				next := (state>>30 ^ state>>27) & 1
+				state = (state<<1 | next) & 0x7FFFFFFF
+				out = out<<1 | byte(next)
+			}
+			b[i] = out
+		}
+	case PatternCustom:
+		if len(p.Bytes) == 0 {
+			clear(b)
+			return
+		}
+		for i := range b {
+			b[i] = p.Bytes[i%len(p.Bytes)]
+		}
+	default:
+		for i := range b {
+			b[i] = byte(i)
+		}
+	}
+}
+
+// HeaderFields are the addresses, UDP ports and marking of built-in
+// frames. A field left zero keeps the built-in one (02:00:00:00:00:01 or
+// the interface's MAC -> 02:00:00:00:00:02, 10.0.0.1 -> 10.0.0.2, UDP
+// 12345 -> 3842, best effort). Source and destination MACs also address
+// templated frames.
+type HeaderFields struct {
+	SrcMAC  net.HardwareAddr
+	DstMAC  net.HardwareAddr
+	SrcIP   IPRange
+	DstIP   IPRange
+	SrcPort PortRange
+	DstPort PortRange
+	DSCP    uint8 // Of the IPv4 header (0-63); IPv6 frames take IPv6.TrafficClass
+}
+
+// IPRange is an IPv4 address, or with Count over 1 the range of Count
+// consecutive addresses from it that frame n cycles to First + n % Count.
+// IPv6 frames take their addresses from IPStack.
+type IPRange struct {
+	First net.IP
+	Count uint32
+}
+
+// PortRange is a UDP port, or with Count over 1 the range of Count
+// consecutive ports from it that frame n cycles to First + n % Count
+type PortRange struct {
+	First uint16
+	Count uint16
+}
+
+// Ranged reports whether any field is a range
+func (h HeaderFields) Ranged() bool {
+	return h.SrcIP.Count > 1 || h.DstIP.Count > 1 || h.SrcPort.Count > 1 || h.DstPort.Count > 1
+}
+
+// Built-in header fields of test frames
+var (
+	defaultSrcIP = net.IPv4(10, 0, 0, 1).To4()
+	defaultDstIP = net.IPv4(10, 0, 0, 2).To4()
+)
+
+const (
+	defaultSrcPort = 12345
+	defaultDstPort = 3842
+)
+
+// ValidateHeaders checks header fields fit the dataplane
+func ValidateHeaders(h HeaderFields) error {
+	for _, m := range []net.HardwareAddr{h.SrcMAC, h.DstMAC} {
+		if m != nil && len(m) != 6 {
+			return fmt.Errorf("%v is not an Ethernet MAC address", m)
+		}
+	}
+	for _, r := range []struct {
+		name  string
+		r     IPRange
+		first net.IP
+	}{{"source", h.SrcIP, defaultSrcIP}, {"destination", h.DstIP, defaultDstIP}} {
+		first := r.first
+		if r.r.First != nil {
+			if first = r.r.First.To4(); first == nil {
+				return fmt.Errorf("%s %v is not an IPv4 address", r.name, r.r.First)
+			}
+		}
+		if r.r.Count > 1 && uint64(binary.BigEndian.Uint32(first))+uint64(r.r.Count)-1 > math.MaxUint32 {
+			return fmt.Errorf("%s range of %d addresses from %v runs past 255.255.255.255", r.name, r.r.Count, first)
+		}
+	}
+	for _, r := range []struct {
+		name  string
+		r     PortRange
+		first uint16
+	}{{"source", h.SrcPort, defaultSrcPort}, {"destination", h.DstPort, defaultDstPort}} {
+		first := r.first
+		if r.r.First != 0 {
+			first = r.r.First
+		}
+		if r.r.Count > 1 && uint32(first)+uint32(r.r.Count)-1 > math.MaxUint16 {
+			return fmt.Errorf("%s range of %d ports from %d runs past 65535", r.name, r.r.Count, first)
+		}
+	}
+	if h.DSCP > 63 {
+		return fmt.Errorf("DSCP %d out of range (0-63)", h.DSCP)
+	}
+	return nil
+}
+
+// IPMode mirrors C ip_mode_t
+type IPMode int
+
+const (
+	IPModeV4   IPMode = iota // IPv4 only
+	IPModeV6                 // IPv6 only
+	IPModeDual               // IPv4 and IPv6 frames interleaved
+)
+
+func (m IPMode) String() string {
+	switch m {
+	case IPModeV4:
+		return "ipv4"
+	case IPModeV6:
+		return "ipv6"
+	case IPModeDual:
+		return "dual"
+	}
+	return fmt.Sprintf("IPMode(%d)", int(m))
+}
+
+// IPStack is the IP version of built-in frames. IPv6 frames take 20
+// bytes of padding for their longer header; address pairs and streams
+// rotate IPv4 addresses, so they need IPModeV4.
+type IPStack struct {
+	Mode       IPMode
+	IPv6       IPv6
+	V6SharePct uint8 // Dual-stack: share of the frames that are IPv6 (0 = 50)
+}
+
+// IPv6 are the header fields of IPv6 test frames
+type IPv6 struct {
+	Src          net.IP
+	Dst          net.IP
+	TrafficClass uint8  // DSCP << 2 | ECN
+	FlowLabel    uint32 // 0-MaxFlowLabel
+	HopLimit     uint8  // 0 = 64
+}
+
+// MaxFlowLabel bounds IPv6.FlowLabel, as IPV6_MAX_FLOW_LABEL
+const MaxFlowLabel = 1<<20 - 1
+
+// ValidateIP checks an IP stack configuration fits the dataplane
+func ValidateIP(ip IPStack) error {
+	if ip.Mode < IPModeV4 || ip.Mode > IPModeDual {
+		return fmt.Errorf("IP mode %d out of range", int(ip.Mode))
+	}
+	if ip.V6SharePct >= 100 {
+		return fmt.Errorf("IPv6 share %d%% out of range (0-99)", ip.V6SharePct)
+	}
+	if ip.IPv6.FlowLabel > MaxFlowLabel {
+		return fmt.Errorf("flow label %d exceeds %d", ip.IPv6.FlowLabel, MaxFlowLabel)
+	}
+	for _, a := range []net.IP{ip.IPv6.Src, ip.IPv6.Dst} {
+		if a != nil && (a.To16() == nil || a.To4() != nil) {
+			return fmt.Errorf("%v is not an IPv6 address", a)
+		}
+	}
+	return nil
+}
+
+// FamilyStats are one address family's frames over a trial's measurement,
+// with a dual-stack Config.IP
+type FamilyStats struct {
+	Family   string // "ipv4" or "ipv6"
+	FramesTx uint64
+	FramesRx uint64
+	LossPct  float64
+	Latency  LatencyStats // Over the family's share of the latency samples
+}
+
+// LatencyProbe is a low-rate stream of the trial's frames marked with a
+// higher priority, as voice rides a congested link. Its frames count
+// toward neither the trial's loss nor its latency; they are reported as
+// ProbeStats.
+type LatencyProbe struct {
+	PPS  uint32 // Probe frames per second (0 = off)
+	PCP  uint8  // 802.1p priority written into the frames' VLAN tags
+	DSCP uint8  // DSCP of the frames' IPv4 header (0-63)
+}
+
+// ProbeStats are the latency probe's frames over a trial's measurement
+type ProbeStats struct {
+	PCP      uint8
+	DSCP     uint8
+	FramesTx uint64
+	FramesRx uint64
+	LossPct  float64
+	Latency  LatencyStats
+}
+
+// MaxVLANID bounds Config.VLANID and Y1564Service.VLANID
+const MaxVLANID = 4094
+
+// MPLSLabel is one entry of the MPLS label stack of test frames
+type MPLSLabel struct {
+	Label uint32 `json:"label"` // 20-bit label
+	EXP   uint8  `json:"exp"`   // Traffic class bits, 0-7
+	TTL   uint8  `json:"ttl"`   // 0 = 64
+}
+
+// MPLS label stack bounds, as in include/rfc2544.h
+const (
+	MaxMPLSLabels = 8
+	MaxMPLSLabel  = 1<<20 - 1
+)
+
+// ValidateMPLS checks a label stack fits the dataplane
+func ValidateMPLS(labels []MPLSLabel) error {
+	if len(labels) > MaxMPLSLabels {
+		return fmt.Errorf("%d MPLS labels exceeds %d", len(labels), MaxMPLSLabels)
+	}
+	for _, l := range labels {
+		if l.Label > MaxMPLSLabel || l.EXP > 7 {
+			return fmt.Errorf("MPLS label %d EXP %d out of range", l.Label, l.EXP)
+		}
+	}
+	return nil
+}
+
+// ValidTPID reports whether tpid is one frames may be tagged with:
+// 0x8100, 0x88a8 or the pre-802.1ad 0x9100, 0x9200 and 0x9300
+func ValidTPID(tpid uint16) bool {
+	switch tpid {
+	case 0x8100, 0x88a8, 0x9100, 0x9200, 0x9300:
+		return true
+	}
+	return false
+}
+
+// MaxStreams bounds Config.Streams, as in include/rfc2544.h
+const MaxStreams = 1024
+
+// MaxFrameSize is the largest frame AF_PACKET sends, RFC2544_MAX_FRAME_SIZE
+const MaxFrameSize = 16000
+
+// jumboFrameSize is the frame Config.IncludeJumbo adds
+const jumboFrameSize = 9000
+
+// StreamStats are one stream's counters over a trial's measurement.
+// Corrupted frames (FrameOrder.CorruptedFrames) count for no stream.
+type StreamStats struct {
+	Stream   uint32
+	FramesTx uint64
+	FramesRx uint64
+	LossPct  float64
+	Latency  LatencyStats // Over the stream's share of the latency samples
+}
+
+// Modifiers are the RFC 2544 Section 11 conditions applied in the data plane
+type Modifiers struct {
+	BroadcastPct float64 // Share of frames sent to the broadcast address (0-50)
+}
+
+// Framing selects the encapsulation of generated test frames
+type Framing struct {
+	LLCSNAP   bool   // 802.3 length + LLC/SNAP header instead of Ethernet II
+	EtherType uint16 // EtherType or SNAP protocol ID (0 = IPv4)
+}
+
+// PacketTemplate replaces the built-in Ethernet/IPv4/UDP headers of test
+// frames. Lengths and checksums are filled in for each frame size.
+type PacketTemplate struct {
+	Header     []byte // Headers preceding the RFC 2544 payload (empty = built-in)
+	IPv4Offset int    // -1 if none
+	IPv6Offset int    // -1 if none
+	UDPOffset  int
+	FillSrcMAC bool // Write the interface MAC as source
+	FillDstMAC bool // Write the DUT MAC as destination
+}
+
+// ControlPlaneStress directs ICMP, ARP and BGP-port traffic at the DUT's own
+// address while trials run. All rates zero disables it.
+type ControlPlaneStress struct {
+	DUTIP      net.IP
+	DUTMAC     net.HardwareAddr // nil = test destination MAC
+	ICMPPerSec uint32
+	ARPPerSec  uint32
+	BGPPerSec  uint32
+}
+
+// ControlPlaneStats counts control-plane frames sent and answered by the DUT
+type ControlPlaneStats struct {
+	ICMPSent    uint64 `json:"icmp_sent"`
+	ICMPReplies uint64 `json:"icmp_replies"`
+	ARPSent     uint64 `json:"arp_sent"`
+	ARPReplies  uint64 `json:"arp_replies"`
+	BGPSent     uint64 `json:"bgp_sent"`
+	BGPReplies  uint64 `json:"bgp_replies"` // SYN-ACK or RST from port 179
+}
+
+// AddressLearning records the ramp that introduced the address pairs
+// before the first trial
+type AddressLearning struct {
+	Addresses   uint32  `json:"addresses"` // Pairs introduced, one frame each
+	RatePerSec  uint32  `json:"rate_per_sec"`
+	DurationSec float64 `json:"duration_sec"`
+}
+
+// PortStats counts all frames on one opened port, test frames or not
+type PortStats struct {
+	Interface string `json:"interface"`
+	TxPackets uint64 `json:"tx_packets"`
+	RxPackets uint64 `json:"rx_packets"`
+	TxErrors  uint64 `json:"tx_errors"`
+	RxErrors  uint64 `json:"rx_errors"`
+}
+
+// LiveStats are counters updated while a test runs
+type LiveStats struct {
+	FrameSize      uint32
+	OfferedRatePct float64
+	TxPackets      uint64 // Test frames sent, including warmup
+	TxBytes        uint64
+	RxPackets      uint64
+	RxBytes        uint64
+	Trials         uint64 // Completed trials
+	LastLossPct    float64
+	LastLatency    LatencyStats
+	LastOrder      FrameOrder
+	InTrial        bool          // A trial is running
+	TrialWarmup    time.Duration // Warmup of the current or last trial
+	TrialDuration  time.Duration // Measurement of the current or last trial, after the warmup
+	TrialElapsed   time.Duration // Time into the current trial, warmup included
+}
+
+// Stats is a snapshot of a running test, sent to the channels returned by
+// Subscribe. Counters and Iteration count from the start of the test
+// method; rates are over the interval since the previous snapshot.
+type Stats struct {
+	FrameSize   uint32
+	TxPackets   uint64
+	TxBytes     uint64
+	RxPackets   uint64
+	RxBytes     uint64
+	TxMbps      float64
+	RxMbps      float64
+	TxPPS       float64
+	RxPPS       float64
+	CurrentRate float64 // Offered load of the current trial, % of line rate
+	Iteration   uint64  // Trials completed
+	LastLossPct float64
+	LastLatency LatencyStats
+	LastOrder   FrameOrder
+
+	InTrial       bool          // A trial is running
+	Warmup        bool          // The running trial is in its warmup
+	TrialElapsed  time.Duration // Time into the current trial, warmup included
+	TrialWarmup   time.Duration
+	TrialDuration time.Duration // Measurement after the warmup
+	TrialProgress float64       // Fraction of warmup and measurement done, 0-1
+
+	Elapsed   time.Duration // Since the test method started
+	Timestamp time.Time
+}
+
+// Status is where a running test is: its state, the trial it is on and
+// how far that trial has got
+type Status struct {
+	State         TestState
+	FrameSize     uint32  // Of the current or last trial
+	RatePct       float64 // Offered load of the current or last trial
+	Iteration     uint64  // Trial of the test method, from 1; between trials, the last one
+	InTrial       bool
+	Warmup        bool
+	TrialProgress float64 // Fraction of warmup and measurement done, 0-1
+}
+
+// AddressPair is one flow's addresses; a nil address keeps the built-in one
+type AddressPair struct {
+	SrcMAC, DstMAC net.HardwareAddr
+	SrcIP, DstIP   net.IP
+}
+
+// NICInfo describes a network interface and what it supports for testing
+type NICInfo struct {
+	Name        string `json:"name"`
+	LinkSpeed   uint64 `json:"link_speed_bps"` // 0 if unknown
+	Up          bool   `json:"up"`
+	OperState   string `json:"operstate"`
+	MTU         uint32 `json:"mtu"`
+	MAC         string `json:"mac"`
+	Driver      string `json:"driver,omitempty"`
+	BusInfo     string `json:"bus_info,omitempty"`
+	Firmware    string `json:"firmware,omitempty"`
+	HWTimestamp bool   `json:"hw_timestamp"`
+	XDP         bool   `json:"af_xdp"`
+	DPDK        bool   `json:"dpdk"`                  // DPDK binding available
+	DPDKDriver  string `json:"dpdk_driver,omitempty"` // Kernel module to bind for DPDK
+}
+
+// FormatLinkSpeed formats a link speed in bits per second, "-" if unknown
+func FormatLinkSpeed(bps uint64) string {
+	switch {
+	case bps == 0:
+		return "-"
+	case bps >= 1000000000:
+		return strconv.FormatFloat(float64(bps)/1e9, 'f', -1, 64) + " Gbps"
+	}
+	return strconv.FormatUint(bps/1000000, 10) + " Mbps"
+}
+
+// maxInterfaces bounds ListInterfaces
+const maxInterfaces = 64
+
+// =============================================================================
+// Wrapper types for CLI integration
+// =============================================================================
+
+// ThroughputResult wraps the throughput test result for CLI
+type ThroughputResultCLI struct {
+	FrameSize         uint32
+	MaxRatePct        float64
+	MaxRateMbps       float64
+	MaxRatePPS        float64
+	Iterations        uint32
+	Latency           LatencyStats
+	AcceptableLossPct float64 // Loss a passing trial was allowed
+	FrameOrder                // Of the trial at MaxRatePct
+
+	// Streams are the per-stream counters of the trial at MaxRatePct,
+	// with Config.Streams
+	Streams []StreamStats `json:",omitempty"`
+
+	// Families are its per-family counters, with a dual-stack Config.IP
+	Families []FamilyStats `json:",omitempty"`
+
+	// Trials are the search's iterations in order, to audit where it
+	// converged. A linear search tries every step, making them the DUT's
+	// whole rate/loss curve.
+	Trials []ThroughputTrial `json:",omitempty"`
+
+	// Search is the algorithm that found MaxRatePct, "linear" or
+	// "hybrid"; empty for the binary search
+	Search string `json:",omitempty"`
+}
+
+// bestOrder returns the frame order of the last passing trial, the one
+// the search reports
+func bestOrder(trials []ThroughputTrial) FrameOrder {
+	for i := len(trials) - 1; i >= 0; i-- {
+		if trials[i].Passed {
+			return trials[i].FrameOrder
+		}
+	}
+	return FrameOrder{}
+}
+
+// ThroughputTrial is one iteration of the throughput binary search
+type ThroughputTrial struct {
+	RatePct  float64
+	FramesTx uint64
+	FramesRx uint64
+	LossPct  float64
+	Passed   bool         // Loss within the acceptable loss; the search went up
+	Latency  LatencyStats // Of the trial, passed or not; Count 0 unmeasured
+	FrameOrder
+}
+
+// ThroughputSearch is the state of a throughput binary search between
+// iterations, saved to resume an interrupted run
+type ThroughputSearch struct {
+	LowPct       float64
+	HighPct      float64
+	BestRatePct  float64
+	Iterations   uint32
+	FramesTested uint64
+	Latency      LatencyStats
+
+	Trials []ThroughputTrial `json:",omitempty"` // Iterations so far
+}
+
+// LatencyResultCLI wraps the latency test result for CLI
+type LatencyResultCLI struct {
+	FrameSize uint32
+	LoadPct   float64
+	Latency   LatencyStats  // Of the load, without the probe's frames
+	Streams   []StreamStats `json:",omitempty"` // With Config.Streams
+	Probe     *ProbeStats   `json:",omitempty"` // With Config.LatencyProbe
+	Families  []FamilyStats `json:",omitempty"` // With a dual-stack Config.IP
+}
+
+// FrameLossResultCLI wraps the frame loss test result for CLI
+type FrameLossResultCLI struct {
+	FrameSize  uint32
+	OfferedPct float64
+	FramesTx   uint64
+	FramesRx   uint64
+	LossPct    float64
+	FrameOrder
+	Streams []StreamStats `json:",omitempty"` // With Config.Streams
+
+	// PartialDropRate marks the highest load a partial drop rate search
+	// found with loss at or below its threshold
+	PartialDropRate bool `json:",omitempty"`
+}
+
+// BackToBackResultCLI wraps the back-to-back test result for CLI
+type BackToBackResultCLI struct {
+	FrameSize       uint32
+	MaxBurstFrames  uint64
+	BurstDurationUs uint64
+	Trials          uint32
+}
+
+// RecoveryResultCLI wraps the system recovery test result for CLI
+type RecoveryResultCLI struct {
+	FrameSize       uint32
+	OverloadRatePct float64
+	RecoveryRatePct float64
+	OverloadSec     uint32
+	RecoveryTimeMs  float64
+	FramesLost      uint64
+	Trials          uint32
+}
+
+// ResetResultCLI wraps the reset test result for CLI
+type ResetResultCLI struct {
+	FrameSize   uint32
+	ResetTimeMs float64
+	FramesLost  uint64
+	Trials      uint32
+	ManualReset bool
+}
+
+// OAMPeer is a far-end device found by Ethernet OAM discovery
+type OAMPeer struct {
+	MAC            string `json:"mac"`
+	Dot3ah         bool   `json:"ieee_802_3ah"`    // Answers 802.3ah Information OAMPDUs
+	RemoteLoopback bool   `json:"remote_loopback"` // Can be placed into 802.3ah remote loopback
+	Y1731Loopback  bool   `json:"y1731_loopback"`  // Answers Y.1731 LBM with LBR
+	InLoopback     bool   `json:"in_loopback"`
+	MEGLevel       uint8  `json:"meg_level,omitempty"`
+}
+
+// FixedRateResult is one trial at a fixed offered load
+type FixedRateResult struct {
+	FrameSize     uint32
+	OfferedPct    float64
+	FramesTx      uint64
+	FramesRx      uint64
+	LossPct       float64
+	DeliveredMbps float64
+	ElapsedSec    float64
+	Latency       LatencyStats
+	FrameOrder
+	Streams  []StreamStats `json:",omitempty"` // With Config.Streams
+	Families []FamilyStats `json:",omitempty"` // With a dual-stack Config.IP
+}
+
+// MaxTrainBursts is the most bursts a burst train reports one by one
+const MaxTrainBursts = 1024
+
+// BurstTrain is a train of equal bursts separated by idle gaps, which a
+// DUT must buffer whenever its egress drains slower than a burst arrives
+type BurstTrain struct {
+	Frames  uint32        // Frames per burst
+	Bursts  uint32        // Bursts in the train, at most MaxTrainBursts
+	Gap     time.Duration // Idle time from a burst's last frame to the next's first
+	RatePct float64       // Rate within a burst (% of line rate; 0 = 100)
+}
+
+// BurstTrainResult is a burst train's loss, overall and burst by burst
+type BurstTrainResult struct {
+	FrameSize  uint32
+	FramesTx   uint64
+	FramesRx   uint64
+	LossPct    float64
+	ElapsedSec float64
+	Latency    LatencyStats
+	Bursts     []BurstCount
+}
+
+// BurstCount is one burst of a train. Its frames count as lost when the
+// port refused them, which leaves a hole in the burst.
+type BurstCount struct {
+	FramesTx     uint64
+	FramesRx     uint64
+	MaxLatencyNs float64 // Latency of its slowest frame
+	RxSpanNs     uint64  // First to last of its frames received
+}
+
+// LossPct is the share of the burst's frames lost
+func (b BurstCount) LossPct() float64 {
+	if b.FramesTx == 0 || b.FramesRx >= b.FramesTx {
+		return 0
+	}
+	return 100 * float64(b.FramesTx-b.FramesRx) / float64(b.FramesTx)
+}
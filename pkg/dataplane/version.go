@@ -0,0 +1,86 @@
+package dataplane
+
+import (
+	"fmt"
+	"strings"
+)
+
+// abiVersion is the ABI of librfc2544 the cgo preamble mirrors, its
+// RFC2544_ABI_VERSION. Bump it with the C macro whenever a mirrored struct
+// or call changes.
+const abiVersion = 4
+
+// Library features, by bit of rfc2544_version_t.features
+const (
+	FeatureAFXDP = "af_xdp"
+	FeatureDPDK  = "dpdk"
+)
+
+var libraryFeatures = []string{FeatureAFXDP, FeatureDPDK}
+
+// LibraryVersion is the version of the C dataplane library a binary runs
+// with, which for a shared library may differ from the one it was built
+// against
+type LibraryVersion struct {
+	Version     string   `json:"version"`            // e.g. "1.0.0"
+	ABI         uint32   `json:"abi"`                // ABI the library implements
+	ABICompat   uint32   `json:"abi_compat"`         // Oldest ABI whose callers still work with it
+	ExpectedABI uint32   `json:"expected_abi"`       // ABI this binary was built for
+	Features    []string `json:"features,omitempty"` // Optional parts built in, e.g. dpdk
+}
+
+// newLibraryVersion decodes the fields of rfc2544_version_t
+func newLibraryVersion(major, minor, patch, abi, abiCompat, features uint32) *LibraryVersion {
+	v := &LibraryVersion{
+		Version:     fmt.Sprintf("%d.%d.%d", major, minor, patch),
+		ABI:         abi,
+		ABICompat:   abiCompat,
+		ExpectedABI: abiVersion,
+	}
+	for bit, name := range libraryFeatures {
+		if features&(1<<bit) != 0 {
+			v.Features = append(v.Features, name)
+		}
+	}
+	return v
+}
+
+// Check returns an error matching ErrLibraryVersion if the library cannot
+// serve this binary: it is older than the ABI the binary was built for,
+// or has dropped it. A newer library that still supports the ABI is fine.
+func (v *LibraryVersion) Check() error {
+	if v == nil {
+		return nil
+	}
+	switch {
+	case v.ABI < v.ExpectedABI:
+		return fmt.Errorf("%w: librfc2544 %s has ABI %d, older than the %d this binary needs; upgrade the library",
+			ErrLibraryVersion, v.Version, v.ABI, v.ExpectedABI)
+	case v.ABICompat > v.ExpectedABI:
+		return fmt.Errorf("%w: librfc2544 %s no longer supports ABI %d (oldest %d); rebuild against it",
+			ErrLibraryVersion, v.Version, v.ExpectedABI, v.ABICompat)
+	}
+	return nil
+}
+
+// Has reports whether the library was built with a feature. A nil
+// version, the pure-Go dataplane, has none.
+func (v *LibraryVersion) Has(feature string) bool {
+	if v == nil {
+		return false
+	}
+	for _, f := range v.Features {
+		if f == feature {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *LibraryVersion) String() string {
+	s := fmt.Sprintf("librfc2544 %s (ABI %d)", v.Version, v.ABI)
+	if len(v.Features) > 0 {
+		s += " with " + strings.Join(v.Features, ", ")
+	}
+	return s
+}
@@ -0,0 +1,39 @@
+package dataplane
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLibraryVersion(t *testing.T) {
+	v := newLibraryVersion(1, 2, 3, 4, 1, 1<<1)
+	if v.Version != "1.2.3" || v.ExpectedABI != abiVersion || !v.Has(FeatureDPDK) || v.Has(FeatureAFXDP) {
+		t.Errorf("decoded %+v", v)
+	}
+	if got := v.String(); got != "librfc2544 1.2.3 (ABI 4) with dpdk" {
+		t.Errorf("String() = %q", got)
+	}
+
+	cases := []struct {
+		abi, compat, expected uint32
+		ok                    bool
+	}{
+		{1, 1, 1, true},
+		{3, 1, 2, true},  // Newer, still serves the ABI
+		{1, 1, 2, false}, // Older than the binary
+		{3, 3, 2, false}, // Newer, dropped the ABI
+	}
+	for _, c := range cases {
+		v := &LibraryVersion{Version: "x", ABI: c.abi, ABICompat: c.compat, ExpectedABI: c.expected}
+		err := v.Check()
+		if (err == nil) != c.ok || err != nil && !errors.Is(err, ErrLibraryVersion) {
+			t.Errorf("ABI %d compat %d, expected %d: %v", c.abi, c.compat, c.expected, err)
+		}
+	}
+
+	// The pure-Go dataplane has no library to check
+	var none *LibraryVersion
+	if none.Check() != nil || none.Has(FeatureDPDK) {
+		t.Error("nil version")
+	}
+}
@@ -0,0 +1,144 @@
+package dataplane
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Watchdog runs dataplane calls while watching the context's heartbeat,
+// so that a wedged dataplane costs one trial instead of the run. A call
+// whose heartbeat stops for Timeout is cancelled; if it has not returned
+// after Grace, the context is abandoned to it and a fresh one opened. A
+// call that panics also gets a fresh context. Either way the call fails
+// with ErrStalled or ErrFault and the next one runs on the new context. A
+// call that finds none, open having failed, fails with ErrFault too.
+//
+// A fault in the C dataplane itself, such as a segmentation fault, still
+// ends the process; checkpointed tests resume from where it stopped.
+type Watchdog struct {
+	Timeout time.Duration // Heartbeat silence that counts as wedged; 0 disables the check
+	Grace   time.Duration // How long a cancelled call has to return
+
+	open     func() (*Context, error)
+	mu       sync.Mutex
+	ctx      *Context // nil after a failed restart, reopened by the next call
+	restarts int
+}
+
+// NewWatchdog watches calls on c. It replaces c with a context from open,
+// which must apply all of c's settings.
+func NewWatchdog(c *Context, open func() (*Context, error), timeout time.Duration) *Watchdog {
+	return &Watchdog{Timeout: timeout, Grace: 5 * time.Second, open: open, ctx: c}
+}
+
+// Context returns the current context, which a restart replaces, or nil
+// while it cannot be reopened
+func (w *Watchdog) Context() *Context {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.ctx
+}
+
+// Restarts returns how many times the context was replaced
+func (w *Watchdog) Restarts() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.restarts
+}
+
+// Close closes the current context
+func (w *Watchdog) Close() {
+	if c := w.Context(); c != nil {
+		c.Close()
+	}
+}
+
+// Do calls fn on the current context. op names the call in errors, e.g.
+// "throughput test".
+func (w *Watchdog) Do(ctx context.Context, op string, fn func(*Context) error) error {
+	c, err := w.current()
+	if err != nil {
+		return &Error{Op: op, Err: err}
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("%w: %v", ErrFault, r)
+			}
+		}()
+		done <- fn(c)
+	}()
+
+	var tick <-chan time.Time
+	if w.Timeout > 0 {
+		ticker := time.NewTicker(w.Timeout / 4)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	beat, beatAt := c.Heartbeat(), time.Now()
+	for {
+		select {
+		case err := <-done:
+			if errors.Is(err, ErrFault) {
+				c.Close()
+				w.restart(c)
+				return &Error{Op: op, Err: err}
+			}
+			return err
+		case now := <-tick:
+			if b := c.Heartbeat(); b != beat {
+				beat, beatAt = b, now
+				continue
+			}
+			if now.Sub(beatAt) < w.Timeout || ctx.Err() != nil {
+				continue
+			}
+		}
+
+		// Wedged: ask the call to stop, then give the context up to it
+		c.Cancel()
+		select {
+		case <-done:
+			c.Close()
+		case <-time.After(w.Grace):
+			c.abandoned.Store(true)
+		}
+		w.restart(c)
+		return &Error{Op: op, Err: ErrStalled}
+	}
+}
+
+// current returns the context to run on, reopening it after a failed
+// restart
+func (w *Watchdog) current() (*Context, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.ctx != nil {
+		return w.ctx, nil
+	}
+	c, err := w.open()
+	if err != nil {
+		return nil, fmt.Errorf("%w: reopen after restart: %v", ErrFault, err)
+	}
+	w.ctx = c
+	return c, nil
+}
+
+// restart replaces old with a freshly opened context
+func (w *Watchdog) restart(old *Context) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.ctx != old {
+		return
+	}
+	w.restarts++
+	w.ctx = nil
+	if c, err := w.open(); err == nil {
+		w.ctx = c
+	}
+}
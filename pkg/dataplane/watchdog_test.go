@@ -0,0 +1,70 @@
+//go:build !cgo || purego
+
+package dataplane
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWatchdog(t *testing.T) {
+	var opened []*Context
+	w := NewWatchdog(testContext(), func() (*Context, error) {
+		c := testContext()
+		opened = append(opened, c)
+		return c, nil
+	}, 40*time.Millisecond)
+	w.Grace = 20 * time.Millisecond
+	ctx := context.Background()
+
+	// A call that keeps its heartbeat going outlives the timeout
+	err := w.Do(ctx, "trial", func(c *Context) error {
+		for end := time.Now().Add(100 * time.Millisecond); time.Now().Before(end); {
+			c.heartbeat.Add(1)
+			time.Sleep(5 * time.Millisecond)
+		}
+		return nil
+	})
+	if err != nil || w.Restarts() != 0 {
+		t.Fatalf("healthy call: %v, %d restarts", err, w.Restarts())
+	}
+
+	// Wedged, even for cancellation: abandoned, and a fresh context opened
+	stuck := make(chan struct{})
+	defer close(stuck)
+	first := w.Context()
+	err = w.Do(ctx, "trial", func(c *Context) error {
+		<-stuck
+		return nil
+	})
+	if !errors.Is(err, ErrStalled) {
+		t.Fatalf("wedged call: %v", err)
+	}
+	if w.Restarts() != 1 || w.Context() == first || !first.abandoned.Load() || !first.cancel.Load() {
+		t.Fatalf("%d restarts, abandoned %v", w.Restarts(), first.abandoned.Load())
+	}
+
+	// Wedged until cancelled: the context is closed and replaced
+	second := w.Context()
+	err = w.Do(ctx, "trial", func(c *Context) error {
+		for !c.cancel.Load() {
+			time.Sleep(time.Millisecond)
+		}
+		return nil
+	})
+	if !errors.Is(err, ErrStalled) || second.abandoned.Load() || w.Context() == second {
+		t.Fatalf("cancellable call: %v", err)
+	}
+
+	// A panic fails the call instead of the process
+	err = w.Do(ctx, "trial", func(c *Context) error {
+		var results []ThroughputResult
+		_ = results[3]
+		return nil
+	})
+	if !errors.Is(err, ErrFault) || w.Restarts() != 3 || len(opened) != 3 {
+		t.Fatalf("panicking call: %v, %d restarts", err, w.Restarts())
+	}
+}
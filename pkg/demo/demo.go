@@ -0,0 +1,103 @@
+// Package demo bundles a sample result set for training and evaluation
+// without a tester: two RFC 2544 suite runs against 1 Gbit/s switches, one
+// passing its acceptance thresholds and one failing them, as -o json
+// writes them, and the configuration they were run with. The demo command
+// loads them into the terminal UI, the Web UI and the report generator.
+package demo
+
+import (
+	"embed"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/krisarmstrong/rfc2544-master/pkg/config"
+)
+
+//go:embed data
+var data embed.FS
+
+// configFile is the configuration of the sample runs
+const configFile = "data/demo.yaml"
+
+// Run is one sample results file
+type Run struct {
+	Name string // File name, e.g. "switch-a.json"
+	Data []byte // JSON results, as -o json writes them
+}
+
+// Runs returns the sample results files, the passing run first
+func Runs() []Run {
+	var runs []Run
+	for _, name := range []string{"switch-a.json", "switch-b.json"} {
+		runs = append(runs, Run{Name: name, Data: read(path.Join("data", name))})
+	}
+	return runs
+}
+
+// Sample is a sample run with its results file decoded as R
+type Sample[R any] struct {
+	Name   string // Run name, e.g. "switch-a"
+	Data   []byte // JSON results, as -o json wrote them
+	Report R
+}
+
+// Names names the sample runs, the passing run first
+func Names() []string {
+	var names []string
+	for _, r := range Runs() {
+		names = append(names, runName(r))
+	}
+	return names
+}
+
+// Load decodes each sample run with decode, which types its results as
+// the run kept them
+func Load[R any](decode func(data []byte) (R, error)) ([]Sample[R], error) {
+	var samples []Sample[R]
+	for _, r := range Runs() {
+		report, err := decode(r.Data)
+		if err != nil {
+			return nil, fmt.Errorf("demo run %s: %w", r.Name, err)
+		}
+		samples = append(samples, Sample[R]{Name: runName(r), Data: r.Data, Report: report})
+	}
+	return samples, nil
+}
+
+// Find returns the sample named name, or nil
+func Find[R any](samples []Sample[R], name string) *Sample[R] {
+	for i := range samples {
+		if samples[i].Name == name {
+			return &samples[i]
+		}
+	}
+	return nil
+}
+
+func runName(r Run) string {
+	return strings.TrimSuffix(r.Name, ".json")
+}
+
+// Config returns the configuration the sample runs were made with
+func Config() (*config.Config, error) {
+	cfg, err := config.LoadLayers(config.Layers{Data: ConfigYAML(), Strict: true})
+	if err != nil {
+		return nil, fmt.Errorf("demo config: %w", err)
+	}
+	return cfg, nil
+}
+
+// ConfigYAML returns the configuration as a config file
+func ConfigYAML() []byte {
+	return read(configFile)
+}
+
+// read returns an embedded file, there since the build
+func read(name string) []byte {
+	b, err := data.ReadFile(name)
+	if err != nil {
+		panic(fmt.Sprintf("demo: %v", err))
+	}
+	return b
+}
@@ -0,0 +1,93 @@
+package demo
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/krisarmstrong/rfc2544-master/pkg/config"
+)
+
+func TestConfig(t *testing.T) {
+	cfg, err := Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("validate: %v", err)
+	}
+	if cfg.TestType != config.TestSuite || !cfg.Thresholds.Enabled() {
+		t.Errorf("test %s, thresholds %+v", cfg.TestType, cfg.Thresholds)
+	}
+}
+
+func TestRuns(t *testing.T) {
+	runs := Runs()
+	if len(runs) != 2 {
+		t.Fatalf("%d runs", len(runs))
+	}
+	sizes := len(config.StandardFrameSizes(false))
+	for i, run := range runs {
+		var doc struct {
+			Metadata struct {
+				TestType config.TestType   `json:"test_type"`
+				DUT      *config.DUTConfig `json:"dut"`
+			} `json:"metadata"`
+			Results    []json.RawMessage `json:"results"`
+			Thresholds struct {
+				Passed bool `json:"passed"`
+			} `json:"thresholds"`
+		}
+		if err := json.Unmarshal(run.Data, &doc); err != nil {
+			t.Fatalf("%s: %v", run.Name, err)
+		}
+		if doc.Metadata.TestType != config.TestSuite || doc.Metadata.DUT == nil || len(doc.Results) != sizes {
+			t.Errorf("%s: %s run of %v with %d results", run.Name, doc.Metadata.TestType, doc.Metadata.DUT, len(doc.Results))
+		}
+		// One run to show a pass, one a failure
+		if doc.Thresholds.Passed != (i == 0) {
+			t.Errorf("%s: thresholds passed %v", run.Name, doc.Thresholds.Passed)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	type suite struct {
+		FrameSize  uint32 `json:"frame_size"`
+		Throughput *struct {
+			MaxRatePct float64
+		} `json:"throughput"`
+	}
+	samples, err := Load(func(data []byte) ([]suite, error) {
+		var doc struct {
+			Results []suite `json:"results"`
+		}
+		err := json.Unmarshal(data, &doc)
+		return doc.Results, err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := Names(); !slices.Equal(names, []string{"switch-a", "switch-b"}) || len(samples) != len(names) {
+		t.Fatalf("names %v, %d samples", names, len(samples))
+	}
+	for _, s := range samples {
+		for _, r := range s.Report {
+			if r.Throughput == nil || r.Throughput.MaxRatePct <= 0 {
+				t.Errorf("%s: %d bytes without a throughput", s.Name, r.FrameSize)
+			}
+		}
+	}
+	if s := Find(samples, "switch-b"); s == nil || s.Name != "switch-b" || len(s.Data) == 0 {
+		t.Errorf("Find(switch-b) = %+v", s)
+	}
+	if s := Find(samples, "switch-c"); s != nil {
+		t.Errorf("Find(switch-c) = %+v", s)
+	}
+
+	bad := errors.New("bad results")
+	if _, err := Load(func([]byte) (int, error) { return 0, bad }); !errors.Is(err, bad) {
+		t.Errorf("Load error %v", err)
+	}
+}
@@ -0,0 +1,225 @@
+// Package drift groups soak test samples into fixed time buckets and
+// summarises how throughput and latency move over the run, which exposes
+// thermal throttling and slow resource leaks in the DUT
+package drift
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Sample is one fixed-rate trial taken during a soak
+type Sample struct {
+	Time           time.Time `json:"time"`
+	OfferedPct     float64   `json:"offered_pct"`
+	ThroughputMbps float64   `json:"throughput_mbps"`
+	FramesTx       uint64    `json:"frames_tx"`
+	FramesRx       uint64    `json:"frames_rx"`
+	LossPct        float64   `json:"loss_pct"`
+	P50Us          float64   `json:"p50_us"`
+	P95Us          float64   `json:"p95_us"`
+	P99Us          float64   `json:"p99_us"`
+	MaxUs          float64   `json:"max_us"`
+}
+
+// Bucket averages the samples that fall into one interval
+type Bucket struct {
+	Start          time.Time `json:"start"`
+	Samples        int       `json:"samples"`
+	ThroughputMbps float64   `json:"throughput_mbps"`
+	LossPct        float64   `json:"loss_pct"`
+	P50Us          float64   `json:"p50_us"`
+	P95Us          float64   `json:"p95_us"`
+	P99Us          float64   `json:"p99_us"`
+}
+
+// Thresholds bound acceptable drift between the first and last bucket
+type Thresholds struct {
+	MaxThroughputDriftPct float64
+	MaxLatencyDriftPct    float64
+}
+
+// Summary describes drift across the whole soak
+type Summary struct {
+	Buckets                   int     `json:"buckets"`
+	ThroughputDriftPct        float64 `json:"throughput_drift_pct"`
+	P99DriftPct               float64 `json:"p99_drift_pct"`
+	ThroughputSlopePctPerHour float64 `json:"throughput_slope_pct_per_hour"`
+	Degraded                  bool    `json:"degraded"`
+	Verdict                   string  `json:"verdict"`
+}
+
+// Report is a fixed-rate soak at one frame size with its drift summary.
+// FrameSize and RatePct are what the soak started at; Changes record any
+// retune while it ran.
+type Report struct {
+	FrameSize uint32   `json:"frame_size"`
+	RatePct   float64  `json:"rate_pct"`
+	Samples   int      `json:"samples"`
+	Buckets   []Bucket `json:"buckets"`
+	Summary   Summary  `json:"drift"`
+
+	// SeriesFile holds every sample, written as the soak ran
+	SeriesFile string `json:"series_file,omitempty"`
+
+	// Changes mark where the soak was reconfigured while it ran
+	Changes []Change `json:"changes,omitempty"`
+}
+
+// Change marks a reconfiguration of a running soak. The samples from
+// Sample on were offered at RatePct in FrameSize-byte frames.
+type Change struct {
+	Time      time.Time `json:"time"`
+	Sample    int       `json:"sample"` // Index of the first sample taken with it
+	RatePct   float64   `json:"rate_pct"`
+	FrameSize uint32    `json:"frame_size"`
+}
+
+// Tracker accumulates samples into buckets
+type Tracker struct {
+	interval time.Duration
+	start    time.Time
+	base     int // First bucket since the last Mark
+	buckets  []Bucket
+}
+
+// NewTracker creates a tracker with the given bucket interval
+func NewTracker(interval time.Duration) *Tracker {
+	if interval <= 0 {
+		interval = 15 * time.Minute
+	}
+	return &Tracker{interval: interval}
+}
+
+// Add folds a sample into its bucket
+func (t *Tracker) Add(s Sample) {
+	if t.start.IsZero() {
+		t.start = s.Time
+	}
+	idx := int(s.Time.Sub(t.start) / t.interval)
+	if idx < 0 {
+		idx = 0
+	}
+	idx += t.base
+	for len(t.buckets) <= idx {
+		n := len(t.buckets) - t.base
+		t.buckets = append(t.buckets, Bucket{Start: t.start.Add(time.Duration(n) * t.interval)})
+	}
+
+	b := &t.buckets[idx]
+	b.Samples++
+	n := float64(b.Samples)
+	b.ThroughputMbps += (s.ThroughputMbps - b.ThroughputMbps) / n
+	b.LossPct += (s.LossPct - b.LossPct) / n
+	b.P50Us += (s.P50Us - b.P50Us) / n
+	b.P95Us += (s.P95Us - b.P95Us) / n
+	b.P99Us += (s.P99Us - b.P99Us) / n
+}
+
+// Mark closes the current bucket at a reconfiguration: later samples go
+// into buckets starting at at, and drift is measured from the first of
+// them, since throughput and latency before it were taken at another load
+func (t *Tracker) Mark(at time.Time) {
+	t.start = at
+	t.base = len(t.buckets)
+}
+
+// Buckets returns the non-empty buckets in time order
+func (t *Tracker) Buckets() []Bucket {
+	return nonEmpty(t.buckets)
+}
+
+func nonEmpty(buckets []Bucket) []Bucket {
+	var out []Bucket
+	for _, b := range buckets {
+		if b.Samples > 0 {
+			out = append(out, b)
+		}
+	}
+	return out
+}
+
+// Summarize compares the first and last bucket since the last Mark and
+// fits a throughput trend
+func (t *Tracker) Summarize(th Thresholds) Summary {
+	buckets := nonEmpty(t.buckets[t.base:])
+	s := Summary{Buckets: len(buckets), Verdict: "Insufficient data"}
+	if len(buckets) < 2 {
+		return s
+	}
+
+	first, last := buckets[0], buckets[len(buckets)-1]
+	s.ThroughputDriftPct = pctChange(first.ThroughputMbps, last.ThroughputMbps)
+	s.P99DriftPct = pctChange(first.P99Us, last.P99Us)
+
+	if slope := throughputSlope(buckets); first.ThroughputMbps > 0 {
+		s.ThroughputSlopePctPerHour = slope * float64(time.Hour) / first.ThroughputMbps * 100.0
+	}
+
+	var reasons []string
+	if -s.ThroughputDriftPct > th.MaxThroughputDriftPct {
+		reasons = append(reasons, fmt.Sprintf("throughput fell %.2f%%", -s.ThroughputDriftPct))
+	}
+	if s.P99DriftPct > th.MaxLatencyDriftPct {
+		reasons = append(reasons, fmt.Sprintf("P99 latency rose %.1f%%", s.P99DriftPct))
+	}
+	s.Degraded = len(reasons) > 0
+	if s.Degraded {
+		s.Verdict = "Degraded: " + strings.Join(reasons, ", ")
+	} else {
+		s.Verdict = "Stable"
+	}
+	return s
+}
+
+func pctChange(from, to float64) float64 {
+	if from == 0 {
+		return 0
+	}
+	return (to - from) / from * 100.0
+}
+
+// throughputSlope returns the least-squares slope in Mbps per nanosecond
+func throughputSlope(buckets []Bucket) float64 {
+	origin := buckets[0].Start
+	var sx, sy, sxx, sxy float64
+	for _, b := range buckets {
+		x := float64(b.Start.Sub(origin))
+		sx += x
+		sy += b.ThroughputMbps
+		sxx += x * x
+		sxy += x * b.ThroughputMbps
+	}
+	n := float64(len(buckets))
+	den := n*sxx - sx*sx
+	if den == 0 {
+		return 0
+	}
+	return (n*sxy - sx*sy) / den
+}
+
+var sparks = []rune("▁▂▃▄▅▆▇█")
+
+// Sparkline renders values as a single-line bar chart
+func Sparkline(values []float64) string {
+	if len(values) == 0 {
+		return ""
+	}
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		lo = math.Min(lo, v)
+		hi = math.Max(hi, v)
+	}
+
+	var sb strings.Builder
+	for _, v := range values {
+		idx := 0
+		if hi > lo {
+			idx = int((v - lo) / (hi - lo) * float64(len(sparks)-1))
+		}
+		sb.WriteRune(sparks[idx])
+	}
+	return sb.String()
+}
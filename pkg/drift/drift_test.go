@@ -0,0 +1,151 @@
+package drift
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var t0 = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestBucketing(t *testing.T) {
+	tr := NewTracker(15 * time.Minute)
+	tr.Add(Sample{Time: t0, ThroughputMbps: 100, P99Us: 10})
+	tr.Add(Sample{Time: t0.Add(5 * time.Minute), ThroughputMbps: 200, P99Us: 20})
+	tr.Add(Sample{Time: t0.Add(46 * time.Minute), ThroughputMbps: 90, P99Us: 30})
+
+	b := tr.Buckets()
+	if len(b) != 2 {
+		t.Fatalf("len(Buckets) = %d, want 2 (empty bucket skipped)", len(b))
+	}
+	if b[0].Samples != 2 || b[0].ThroughputMbps != 150 || b[0].P99Us != 15 {
+		t.Errorf("bucket 0 = %+v, want 2 samples averaging 150 Mbps / 15 us", b[0])
+	}
+	if !b[1].Start.Equal(t0.Add(45 * time.Minute)) {
+		t.Errorf("bucket 1 start = %v, want t0+45m", b[1].Start)
+	}
+}
+
+func TestSummarizeStable(t *testing.T) {
+	tr := NewTracker(time.Minute)
+	for i := 0; i < 4; i++ {
+		tr.Add(Sample{Time: t0.Add(time.Duration(i) * time.Minute), ThroughputMbps: 1000, P99Us: 50})
+	}
+	s := tr.Summarize(Thresholds{MaxThroughputDriftPct: 1, MaxLatencyDriftPct: 25})
+	if s.Degraded || s.Verdict != "Stable" {
+		t.Errorf("Summary = %+v, want stable", s)
+	}
+	if s.ThroughputSlopePctPerHour != 0 {
+		t.Errorf("slope = %.3f, want 0", s.ThroughputSlopePctPerHour)
+	}
+}
+
+func TestSummarizeDegraded(t *testing.T) {
+	tr := NewTracker(time.Hour)
+	tr.Add(Sample{Time: t0, ThroughputMbps: 1000, P99Us: 50})
+	tr.Add(Sample{Time: t0.Add(time.Hour), ThroughputMbps: 950, P99Us: 100})
+
+	s := tr.Summarize(Thresholds{MaxThroughputDriftPct: 1, MaxLatencyDriftPct: 25})
+	if !s.Degraded {
+		t.Fatalf("Summary = %+v, want degraded", s)
+	}
+	if math.Abs(s.ThroughputDriftPct+5) > 1e-9 {
+		t.Errorf("ThroughputDriftPct = %.3f, want -5", s.ThroughputDriftPct)
+	}
+	if math.Abs(s.P99DriftPct-100) > 1e-9 {
+		t.Errorf("P99DriftPct = %.3f, want 100", s.P99DriftPct)
+	}
+	if math.Abs(s.ThroughputSlopePctPerHour+5) > 1e-6 {
+		t.Errorf("slope = %.3f, want -5 %%/h", s.ThroughputSlopePctPerHour)
+	}
+}
+
+func TestSummarizeInsufficient(t *testing.T) {
+	tr := NewTracker(0)
+	tr.Add(Sample{Time: t0, ThroughputMbps: 1000})
+	if s := tr.Summarize(Thresholds{}); s.Degraded || s.Buckets != 1 {
+		t.Errorf("Summary = %+v, want one bucket and no verdict", s)
+	}
+}
+
+func TestMark(t *testing.T) {
+	tr := NewTracker(10 * time.Minute)
+	tr.Add(Sample{Time: t0, ThroughputMbps: 1000, P99Us: 50})
+	tr.Add(Sample{Time: t0.Add(10 * time.Minute), ThroughputMbps: 1000, P99Us: 50})
+	// Halving the offered load is not a throughput drop
+	tr.Mark(t0.Add(15 * time.Minute))
+	tr.Add(Sample{Time: t0.Add(16 * time.Minute), ThroughputMbps: 500, P99Us: 20})
+	tr.Add(Sample{Time: t0.Add(26 * time.Minute), ThroughputMbps: 500, P99Us: 20})
+
+	b := tr.Buckets()
+	if len(b) != 4 {
+		t.Fatalf("len(Buckets) = %d, want the 2 buckets before the mark and 2 after", len(b))
+	}
+	if !b[1].Start.Equal(t0.Add(10*time.Minute)) || !b[2].Start.Equal(t0.Add(15*time.Minute)) || !b[3].Start.Equal(t0.Add(25*time.Minute)) {
+		t.Errorf("bucket starts = %v, %v, %v; want t0+10m, t0+15m, t0+25m", b[1].Start, b[2].Start, b[3].Start)
+	}
+	s := tr.Summarize(Thresholds{MaxThroughputDriftPct: 1, MaxLatencyDriftPct: 25})
+	if s.Degraded || s.Buckets != 2 || s.ThroughputDriftPct != 0 {
+		t.Errorf("Summary = %+v, want 2 stable buckets since the mark", s)
+	}
+}
+
+func TestSparkline(t *testing.T) {
+	if got := Sparkline([]float64{0, 7, 14}); got != "▁▄█" {
+		t.Errorf("Sparkline = %q, want ▁▄█", got)
+	}
+	if got := Sparkline([]float64{5, 5}); got != "▁▁" {
+		t.Errorf("Sparkline flat = %q, want ▁▁", got)
+	}
+	if Sparkline(nil) != "" {
+		t.Error("Sparkline(nil) should be empty")
+	}
+}
+
+func TestSeries(t *testing.T) {
+	dir := t.TempDir()
+	samples := []Sample{
+		{Time: t0, OfferedPct: 90, ThroughputMbps: 900, FramesTx: 1000, FramesRx: 999, LossPct: 0.1, P99Us: 12.5},
+		{Time: t0.Add(time.Minute), OfferedPct: 90, ThroughputMbps: 899.5, FramesTx: 1000, FramesRx: 1000},
+	}
+	write := func(name string) string {
+		path := filepath.Join(dir, name)
+		s, err := CreateSeries(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, smp := range samples {
+			if err := s.Write(smp); err != nil {
+				t.Fatal(err)
+			}
+			// Each row is on disk as soon as it is written
+			data, _ := os.ReadFile(path)
+			if rows := strings.Count(string(data), "\n"); rows < i+1 {
+				t.Errorf("%s: %d rows after sample %d", name, rows, i+1)
+			}
+		}
+		if err := s.Close(); err != nil {
+			t.Fatal(err)
+		}
+		data, _ := os.ReadFile(path)
+		return string(data)
+	}
+
+	csv := strings.Split(strings.TrimSpace(write("soak.csv")), "\n")
+	if len(csv) != 3 || !strings.HasPrefix(csv[0], "time,offered_pct,") {
+		t.Fatalf("csv = %q", csv)
+	}
+	if want := "2024-01-01T00:00:00Z,90,900,1000,999,0.1,0,0,12.5,0"; csv[1] != want {
+		t.Errorf("csv row = %q, want %q", csv[1], want)
+	}
+
+	lines := strings.Split(strings.TrimSpace(write("soak.jsonl")), "\n")
+	var got Sample
+	if len(lines) != 2 || json.Unmarshal([]byte(lines[1]), &got) != nil || got != samples[1] {
+		t.Errorf("jsonl = %q", lines)
+	}
+}
@@ -0,0 +1,86 @@
+package drift
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// seriesHeader is the first row of a CSV series
+var seriesHeader = []string{
+	"time", "offered_pct", "throughput_mbps", "frames_tx", "frames_rx",
+	"loss_pct", "p50_us", "p95_us", "p99_us", "max_us",
+}
+
+// Series writes every sample of a soak to a file as it is taken, one row
+// each, so a run of days can be followed while it goes and keeps what it
+// measured if it is killed. A path ending in .jsonl gets JSON lines, any
+// other CSV.
+type Series struct {
+	f   *os.File
+	csv *csv.Writer   // nil for JSON lines
+	enc *json.Encoder // nil for CSV
+}
+
+// SeriesFormat returns "jsonl" or "csv", the format CreateSeries writes to
+// a path
+func SeriesFormat(path string) string {
+	if filepath.Ext(path) == ".jsonl" {
+		return "jsonl"
+	}
+	return "csv"
+}
+
+// CreateSeries creates or truncates a series file
+func CreateSeries(path string) (*Series, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	s := &Series{f: f}
+	if SeriesFormat(path) == "jsonl" {
+		s.enc = json.NewEncoder(f)
+		return s, nil
+	}
+	s.csv = csv.NewWriter(f)
+	if err := s.csv.Write(seriesHeader); err != nil {
+		f.Close()
+		return nil, err
+	}
+	s.csv.Flush()
+	if err := s.csv.Error(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Write appends a sample, reaching the file before it returns
+func (s *Series) Write(smp Sample) error {
+	if s.enc != nil {
+		return s.enc.Encode(smp)
+	}
+	num := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+	s.csv.Write([]string{
+		smp.Time.UTC().Format(time.RFC3339Nano),
+		num(smp.OfferedPct),
+		num(smp.ThroughputMbps),
+		strconv.FormatUint(smp.FramesTx, 10),
+		strconv.FormatUint(smp.FramesRx, 10),
+		num(smp.LossPct),
+		num(smp.P50Us),
+		num(smp.P95Us),
+		num(smp.P99Us),
+		num(smp.MaxUs),
+	})
+	s.csv.Flush()
+	return s.csv.Error()
+}
+
+// Close closes the file
+func (s *Series) Close() error {
+	return s.f.Close()
+}
@@ -0,0 +1,276 @@
+// Package dutconfig snapshots the DUT configuration before and after a run
+//
+// A snapshot runs a list of commands, on the DUT over ssh or locally (for
+// example netconf-console --get-config), and keeps their output. Comparing
+// the snapshots taken before and after the tests shows whether the DUT was
+// reconfigured mid-run, which would invalidate the results.
+package dutconfig
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/krisarmstrong/rfc2544-master/pkg/internal/execrun"
+)
+
+// diffContext is the number of unchanged lines shown around each change
+const diffContext = 3
+
+// Options control how snapshots are taken
+type Options struct {
+	SSH      string           // user@host to run the commands on; empty runs them locally
+	Commands []string         // e.g. "show running-config"
+	Ignore   []*regexp.Regexp // Lines that change on their own, e.g. timestamps
+	Timeout  time.Duration    // Per command
+}
+
+// Output is one command's output in a snapshot
+type Output struct {
+	Command string `json:"command"`
+	Text    string `json:"text"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Snapshot is the output of every command at one point in time
+type Snapshot struct {
+	Taken   time.Time `json:"taken"`
+	Outputs []Output  `json:"outputs"`
+}
+
+// Change is a command whose output differs between the snapshots
+type Change struct {
+	Command string   `json:"command"`
+	Diff    []string `json:"diff"` // Unified diff lines
+}
+
+// Report is the before/after comparison recorded with the results
+type Report struct {
+	Before  Snapshot  `json:"before"`
+	After   *Snapshot `json:"after,omitempty"`
+	Changed bool      `json:"changed"`
+	Changes []Change  `json:"changes,omitempty"`
+}
+
+// Snapshotter takes DUT configuration snapshots
+type Snapshotter struct {
+	Options Options
+	Run     execrun.Runner // Tests replace it to avoid depending on a DUT
+}
+
+// New creates a snapshotter using the system ssh and shell
+func New(opts Options) *Snapshotter {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 30 * time.Second
+	}
+	return &Snapshotter{Options: opts, Run: execrun.Exec}
+}
+
+// Take runs every command once. A failed command is recorded in its
+// output rather than stopping the snapshot.
+func (s *Snapshotter) Take(ctx context.Context) Snapshot {
+	snap := Snapshot{Taken: time.Now()}
+	for _, cmd := range s.Options.Commands {
+		cctx, cancel := context.WithTimeout(ctx, s.Options.Timeout)
+		var out []byte
+		var err error
+		if s.Options.SSH != "" {
+			// BatchMode fails instead of prompting for a password mid-run
+			out, err = s.Run(cctx, "ssh", "-o", "BatchMode=yes", s.Options.SSH, cmd)
+		} else {
+			out, err = s.Run(cctx, "sh", "-c", cmd)
+		}
+		cancel()
+
+		o := Output{Command: cmd, Text: string(out)}
+		if err != nil {
+			o.Error = err.Error()
+		}
+		snap.Outputs = append(snap.Outputs, o)
+	}
+	return snap
+}
+
+// Compare reports the commands whose output differs between before and
+// after, ignoring lines that match the Ignore patterns
+func (s *Snapshotter) Compare(before, after Snapshot) *Report {
+	r := &Report{Before: before, After: &after}
+	for i, b := range before.Outputs {
+		if i >= len(after.Outputs) {
+			break
+		}
+		a := after.Outputs[i]
+		var diff []string
+		if b.Error != a.Error {
+			diff = append(diff, "-error: "+b.Error, "+error: "+a.Error)
+		}
+		diff = append(diff, Diff(s.lines(b.Text), s.lines(a.Text))...)
+		if len(diff) > 0 {
+			r.Changes = append(r.Changes, Change{Command: b.Command, Diff: diff})
+		}
+	}
+	r.Changed = len(r.Changes) > 0
+	return r
+}
+
+// lines splits output into lines, dropping ignored ones
+func (s *Snapshotter) lines(text string) []string {
+	var lines []string
+	for _, l := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		l = strings.TrimRight(l, " \r")
+		ignored := false
+		for _, re := range s.Options.Ignore {
+			if re.MatchString(l) {
+				ignored = true
+				break
+			}
+		}
+		if !ignored {
+			lines = append(lines, l)
+		}
+	}
+	return lines
+}
+
+// maxDiffCells bounds the LCS table. Beyond it the differing middle of the
+// two outputs is reported as replaced wholesale.
+const maxDiffCells = 1 << 22
+
+// edit is one line of an edit script: ' ' kept, '-' removed, '+' added
+type edit struct {
+	op   byte
+	text string
+	a, b int // Line numbers before the edit, 0-based
+}
+
+// Diff returns the unified diff from a to b, or nil if they are equal
+func Diff(a, b []string) []string {
+	// Trim the common prefix and suffix; configs usually differ in a few lines
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		suf++
+	}
+	if pre == len(a) && pre == len(b) {
+		return nil
+	}
+
+	var edits []edit
+	for i := 0; i < pre; i++ {
+		edits = append(edits, edit{' ', a[i], i, i})
+	}
+	edits = append(edits, middle(a[pre:len(a)-suf], b[pre:len(b)-suf], pre)...)
+	for i := 0; i < suf; i++ {
+		ai, bi := len(a)-suf+i, len(b)-suf+i
+		edits = append(edits, edit{' ', a[ai], ai, bi})
+	}
+	return hunks(edits)
+}
+
+// middle diffs the lines between the common prefix and suffix by longest
+// common subsequence. off is the prefix length, for line numbers.
+func middle(a, b []string, off int) []edit {
+	var edits []edit
+	if len(a)*len(b) > maxDiffCells {
+		for i, l := range a {
+			edits = append(edits, edit{'-', l, off + i, off})
+		}
+		for i, l := range b {
+			edits = append(edits, edit{'+', l, off + len(a), off + i})
+		}
+		return edits
+	}
+
+	// lcs[i][j] is the LCS length of a[i:] and b[j:]
+	w := len(b) + 1
+	lcs := make([]int32, (len(a)+1)*w)
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i*w+j] = lcs[(i+1)*w+j+1] + 1
+			} else if lcs[(i+1)*w+j] >= lcs[i*w+j+1] {
+				lcs[i*w+j] = lcs[(i+1)*w+j]
+			} else {
+				lcs[i*w+j] = lcs[i*w+j+1]
+			}
+		}
+	}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			edits = append(edits, edit{' ', a[i], off + i, off + j})
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[(i+1)*w+j] >= lcs[i*w+j+1]):
+			edits = append(edits, edit{'-', a[i], off + i, off + j})
+			i++
+		default:
+			edits = append(edits, edit{'+', b[j], off + i, off + j})
+			j++
+		}
+	}
+	return edits
+}
+
+// hunks formats an edit script as unified diff hunks with context
+func hunks(edits []edit) []string {
+	var out []string
+	for start := 0; start < len(edits); {
+		// Find the next change and extend the hunk while changes are
+		// within two contexts of each other
+		first := start
+		for first < len(edits) && edits[first].op == ' ' {
+			first++
+		}
+		if first == len(edits) {
+			break
+		}
+		last := first
+		for k := first; k < len(edits) && k <= last+2*diffContext; k++ {
+			if edits[k].op != ' ' {
+				last = k
+			}
+		}
+		lo := first - diffContext
+		if lo < start {
+			lo = start
+		}
+		hi := last + diffContext + 1
+		if hi > len(edits) {
+			hi = len(edits)
+		}
+
+		var na, nb int
+		for _, e := range edits[lo:hi] {
+			if e.op != '+' {
+				na++
+			}
+			if e.op != '-' {
+				nb++
+			}
+		}
+		out = append(out, fmt.Sprintf("@@ -%s +%s @@", hunkRange(edits[lo].a, na), hunkRange(edits[lo].b, nb)))
+		for _, e := range edits[lo:hi] {
+			out = append(out, string(e.op)+e.text)
+		}
+		start = hi
+	}
+	return out
+}
+
+// hunkRange formats a hunk's start line and length as diff -u does
+func hunkRange(start, n int) string {
+	if n == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if n == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, n)
+}
@@ -0,0 +1,112 @@
+package dutconfig
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	a := strings.Split("hostname r1|interface eth0| mtu 1500| no shutdown|interface eth1| mtu 1500|!|end", "|")
+	b := strings.Split("hostname r1|interface eth0| mtu 9000| no shutdown|interface eth1| mtu 1500|!|end", "|")
+	want := []string{
+		"@@ -1,6 +1,6 @@",
+		" hostname r1",
+		" interface eth0",
+		"- mtu 1500",
+		"+ mtu 9000",
+		"  no shutdown",
+		" interface eth1",
+		"  mtu 1500",
+	}
+	if got := Diff(a, b); !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	if got := Diff(a, a); got != nil {
+		t.Errorf("Diff(equal) = %q, want nil", got)
+	}
+	if got := Diff(nil, []string{"x"}); !reflect.DeepEqual(got, []string{"@@ -0,0 +1 @@", "+x"}) {
+		t.Errorf("Diff(empty, x) = %q", got)
+	}
+}
+
+func TestDiffHunks(t *testing.T) {
+	var a []string
+	for i := 0; i < 40; i++ {
+		a = append(a, string(rune('a'+i%26))+strings.Repeat("x", i/26))
+	}
+	b := append([]string(nil), a...)
+	b[2] = "changed"
+	b[30] = "changed too"
+
+	got := Diff(a, b)
+	var headers []string
+	for _, l := range got {
+		if strings.HasPrefix(l, "@@") {
+			headers = append(headers, l)
+		}
+	}
+	if want := []string{"@@ -1,6 +1,6 @@", "@@ -28,7 +28,7 @@"}; !reflect.DeepEqual(headers, want) {
+		t.Errorf("hunks = %q, want %q", headers, want)
+	}
+}
+
+func TestTake(t *testing.T) {
+	var calls [][]string
+	s := New(Options{SSH: "admin@r1", Commands: []string{"show running-config", "show version"}})
+	s.Run = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		calls = append(calls, append([]string{name}, args...))
+		if args[len(args)-1] == "show version" {
+			return nil, errors.New("exit status 255")
+		}
+		return []byte("hostname r1\n"), nil
+	}
+
+	snap := s.Take(context.Background())
+	if len(snap.Outputs) != 2 || snap.Outputs[0].Text != "hostname r1\n" || snap.Outputs[1].Error == "" {
+		t.Errorf("snapshot = %+v", snap)
+	}
+	if want := []string{"ssh", "-o", "BatchMode=yes", "admin@r1", "show running-config"}; !reflect.DeepEqual(calls[0], want) {
+		t.Errorf("ran %q, want %q", calls[0], want)
+	}
+
+	s.Options.SSH = ""
+	calls = nil
+	s.Take(context.Background())
+	if calls[0][0] != "sh" || calls[0][2] != "show running-config" {
+		t.Errorf("local command ran as %q", calls[0])
+	}
+}
+
+func TestCompare(t *testing.T) {
+	s := New(Options{Ignore: []*regexp.Regexp{regexp.MustCompile(`^! Last configuration change`)}})
+	before := Snapshot{Outputs: []Output{
+		{Command: "show run", Text: "! Last configuration change at 10:00\nhostname r1\nmtu 1500\n"},
+		{Command: "show version", Text: "v1\n"},
+	}}
+
+	after := Snapshot{Outputs: []Output{
+		{Command: "show run", Text: "! Last configuration change at 11:30\nhostname r1\nmtu 1500\n"},
+		{Command: "show version", Text: "v1\n"},
+	}}
+	if r := s.Compare(before, after); r.Changed {
+		t.Errorf("ignored lines reported as changes: %+v", r.Changes)
+	}
+
+	after.Outputs[0].Text = "! Last configuration change at 11:30\nhostname r1\nmtu 9000\n"
+	after.Outputs[1].Error = "timeout"
+	r := s.Compare(before, after)
+	if !r.Changed || len(r.Changes) != 2 || r.Changes[0].Command != "show run" {
+		t.Fatalf("changes = %+v", r.Changes)
+	}
+	if d := strings.Join(r.Changes[0].Diff, "\n"); !strings.Contains(d, "-mtu 1500") || !strings.Contains(d, "+mtu 9000") {
+		t.Errorf("diff = %s", d)
+	}
+	if r.Changes[1].Diff[1] != "+error: timeout" {
+		t.Errorf("error change = %q", r.Changes[1].Diff)
+	}
+}
@@ -0,0 +1,199 @@
+// Package flows tracks per-flow health in multi-flow tests and applies the
+// configured failure policy when a flow's path persistently drops all traffic
+package flows
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/krisarmstrong/rfc2544-master/pkg/config"
+)
+
+// Timeline actions
+const (
+	ActionFailed     = "failed"
+	ActionRebalanced = "rebalanced"
+	ActionAborted    = "aborted"
+)
+
+// Event is one entry in the flow failure timeline
+type Event struct {
+	Time   time.Time `json:"time"`
+	FlowID uint32    `json:"flow_id"`
+	Action string    `json:"action"`
+	Detail string    `json:"detail"`
+}
+
+// Report captures the policy and what it did during a run
+type Report struct {
+	Policy        config.FlowFailurePolicy `json:"policy"`
+	PersistTrials int                      `json:"persist_trials"`
+	Failed        []uint32                 `json:"failed"`
+	ExtraMbps     map[uint32]float64       `json:"extra_mbps,omitempty"`
+	Events        []Event                  `json:"events"`
+}
+
+// Flow is a single traffic flow and its configured rate
+type Flow struct {
+	ID       uint32
+	RateMbps float64
+}
+
+type flowState struct {
+	Flow
+	extra    float64 // rebalanced from failed flows, on top of RateMbps
+	streak   int
+	failed   bool
+	complete bool
+}
+
+// Tracker observes per-trial loss for each flow
+type Tracker struct {
+	policy  config.FlowFailurePolicy
+	persist int
+	flows   []*flowState
+	byID    map[uint32]*flowState
+	events  []Event
+	aborted bool
+	now     func() time.Time
+}
+
+// NewTracker creates a tracker for the given flows
+func NewTracker(cfg config.FlowFailureConfig, flows []Flow) *Tracker {
+	t := &Tracker{
+		policy:  cfg.Policy,
+		persist: cfg.PersistTrials,
+		byID:    make(map[uint32]*flowState),
+		now:     time.Now,
+	}
+	if t.persist < 1 {
+		t.persist = 1
+	}
+	for _, f := range flows {
+		st := &flowState{Flow: f}
+		t.flows = append(t.flows, st)
+		t.byID[f.ID] = st
+	}
+	return t
+}
+
+// Observe records one trial's loss for a flow. It returns true when this
+// observation caused the flow to be declared failed.
+func (t *Tracker) Observe(id uint32, lossPct float64) bool {
+	st, ok := t.byID[id]
+	if !ok || st.failed {
+		return false
+	}
+
+	if lossPct < 100.0 {
+		st.streak = 0
+		return false
+	}
+
+	st.streak++
+	if st.streak < t.persist {
+		return false
+	}
+
+	st.failed = true
+	t.record(id, ActionFailed, fmt.Sprintf("100%% loss for %d consecutive trials", st.streak))
+
+	switch t.policy {
+	case config.FlowPolicyAbort:
+		t.aborted = true
+		t.record(id, ActionAborted, "Remaining flows skipped")
+	case config.FlowPolicyRebalance:
+		t.rebalance(st)
+	}
+
+	return true
+}
+
+// rebalance splits a failed flow's load across flows still to be tested.
+// The share is kept apart from each flow's configured rate, which stays the
+// rate its SLA is judged at.
+func (t *Tracker) rebalance(failed *flowState) {
+	var remaining []*flowState
+	for _, st := range t.flows {
+		if !st.failed && !st.complete {
+			remaining = append(remaining, st)
+		}
+	}
+	if len(remaining) == 0 {
+		t.record(failed.ID, ActionRebalanced, "No remaining flows to absorb rate")
+		return
+	}
+
+	share := (failed.RateMbps + failed.extra) / float64(len(remaining))
+	for _, st := range remaining {
+		st.extra += share
+		t.record(failed.ID, ActionRebalanced,
+			fmt.Sprintf("+%.2f Mbps to flow %d (now %.2f Mbps over its %.2f Mbps)",
+				share, st.ID, st.extra, st.RateMbps))
+	}
+	failed.extra = 0
+}
+
+// Complete marks a flow as finished so it no longer absorbs rebalanced rate
+func (t *Tracker) Complete(id uint32) {
+	if st, ok := t.byID[id]; ok {
+		st.complete = true
+	}
+}
+
+// Rate returns the flow's configured rate
+func (t *Tracker) Rate(id uint32) float64 {
+	if st, ok := t.byID[id]; ok {
+		return st.RateMbps
+	}
+	return 0
+}
+
+// Extra returns the load rebalanced onto the flow from failed flows
+func (t *Tracker) Extra(id uint32) float64 {
+	if st, ok := t.byID[id]; ok {
+		return st.extra
+	}
+	return 0
+}
+
+// Failed reports whether the flow has been declared failed
+func (t *Tracker) Failed(id uint32) bool {
+	st, ok := t.byID[id]
+	return ok && st.failed
+}
+
+// Aborted reports whether the abort policy stopped the run
+func (t *Tracker) Aborted() bool {
+	return t.aborted
+}
+
+// Report returns the policy, failed flows and timeline
+func (t *Tracker) Report() Report {
+	r := Report{
+		Policy:        t.policy,
+		PersistTrials: t.persist,
+		Events:        t.events,
+	}
+	for _, st := range t.flows {
+		if st.failed {
+			r.Failed = append(r.Failed, st.ID)
+		}
+		if st.extra > 0 {
+			if r.ExtraMbps == nil {
+				r.ExtraMbps = make(map[uint32]float64)
+			}
+			r.ExtraMbps[st.ID] = st.extra
+		}
+	}
+	return r
+}
+
+func (t *Tracker) record(id uint32, action, detail string) {
+	t.events = append(t.events, Event{
+		Time:   t.now(),
+		FlowID: id,
+		Action: action,
+		Detail: detail,
+	})
+}
@@ -0,0 +1,109 @@
+package flows
+
+import (
+	"testing"
+
+	"github.com/krisarmstrong/rfc2544-master/pkg/config"
+)
+
+func newTracker(policy config.FlowFailurePolicy, persist int) *Tracker {
+	return NewTracker(config.FlowFailureConfig{Policy: policy, PersistTrials: persist}, []Flow{
+		{ID: 1, RateMbps: 100},
+		{ID: 2, RateMbps: 100},
+		{ID: 3, RateMbps: 100},
+	})
+}
+
+func TestObservePersistence(t *testing.T) {
+	tr := newTracker(config.FlowPolicyMarkFailed, 2)
+
+	if tr.Observe(1, 100) {
+		t.Error("Single 100% loss trial should not fail the flow")
+	}
+	tr.Observe(1, 5) // Recovery resets the streak
+	if tr.Observe(1, 100) {
+		t.Error("Streak should have been reset by a partial loss trial")
+	}
+	if !tr.Observe(1, 100) {
+		t.Error("Second consecutive 100% loss trial should fail the flow")
+	}
+	if !tr.Failed(1) {
+		t.Error("Flow 1 should be failed")
+	}
+	if tr.Aborted() {
+		t.Error("mark_failed should not abort")
+	}
+
+	r := tr.Report()
+	if len(r.Failed) != 1 || r.Failed[0] != 1 {
+		t.Errorf("Failed = %v, want [1]", r.Failed)
+	}
+	if len(r.Events) != 1 || r.Events[0].Action != ActionFailed {
+		t.Errorf("Events = %+v, want one failed event", r.Events)
+	}
+}
+
+func TestRebalance(t *testing.T) {
+	tr := newTracker(config.FlowPolicyRebalance, 1)
+	tr.Complete(3)
+
+	if !tr.Observe(1, 100) {
+		t.Fatal("Flow 1 should fail")
+	}
+
+	// Flow 3 is complete, so flow 2 absorbs the full rate as extra load
+	if got := tr.Extra(2); got != 100 {
+		t.Errorf("Extra(2) = %.2f, want 100", got)
+	}
+	if got := tr.Extra(3); got != 0 {
+		t.Errorf("Extra(3) = %.2f, want 0", got)
+	}
+	// Configured rates, which the SLA is judged at, are untouched
+	for id := uint32(1); id <= 3; id++ {
+		if got := tr.Rate(id); got != 100 {
+			t.Errorf("Rate(%d) = %.2f, want 100", id, got)
+		}
+	}
+
+	r := tr.Report()
+	if len(r.Events) != 2 || r.Events[1].Action != ActionRebalanced {
+		t.Errorf("Events = %+v, want failed then rebalanced", r.Events)
+	}
+	if len(r.ExtraMbps) != 1 || r.ExtraMbps[2] != 100 {
+		t.Errorf("ExtraMbps = %v, want map[2:100]", r.ExtraMbps)
+	}
+}
+
+func TestRebalanceNoRemaining(t *testing.T) {
+	tr := newTracker(config.FlowPolicyRebalance, 1)
+	tr.Complete(2)
+	tr.Complete(3)
+
+	tr.Observe(1, 100)
+	events := tr.Report().Events
+	if len(events) != 2 || events[1].Action != ActionRebalanced {
+		t.Fatalf("Events = %+v, want a rebalance note", events)
+	}
+}
+
+func TestAbort(t *testing.T) {
+	tr := newTracker(config.FlowPolicyAbort, 1)
+
+	tr.Observe(2, 100)
+	if !tr.Aborted() {
+		t.Error("abort policy should stop the run")
+	}
+	if tr.Observe(2, 100) {
+		t.Error("An already failed flow should not fail again")
+	}
+}
+
+func TestUnknownFlow(t *testing.T) {
+	tr := newTracker(config.FlowPolicyMarkFailed, 1)
+	if tr.Observe(42, 100) {
+		t.Error("Unknown flow should be ignored")
+	}
+	if tr.Rate(42) != 0 || tr.Failed(42) {
+		t.Error("Unknown flow should have no state")
+	}
+}
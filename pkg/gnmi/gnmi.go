@@ -0,0 +1,494 @@
+// Package gnmi exposes live test statistics as a gNMI target
+//
+// Telemetry collectors subscribe to the tester the same way they do to
+// routers: Capabilities, Get and Subscribe (STREAM sample/on-change, ONCE
+// and POLL) are served over gRPC on HTTP/2 with TLS. Messages are encoded
+// directly from the gnmi.proto field numbers, so no gRPC or protobuf
+// runtime is needed.
+//
+// The data model is small and OpenConfig-like:
+//
+//	/interfaces/interface[name=<if>]/state/counters/{out-pkts,out-octets,in-pkts,in-octets}
+//	/tester/state/{test-type,status,frame-size,offered-rate-pct,trials}
+//	/tester/state/rates/{tx-mbps,rx-mbps}
+//	/tester/state/loss-pct
+//	/tester/state/latency/{min-ns,avg-ns,max-ns,p99-ns}
+package gnmi
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Version is the gNMI specification version reported by Capabilities
+const Version = "0.8.0"
+
+// Subscription list modes
+const (
+	ModeStream = 0
+	ModeOnce   = 1
+	ModePoll   = 2
+)
+
+// Per-path subscription modes
+const (
+	SubTargetDefined = 0
+	SubOnChange      = 1
+	SubSample        = 2
+)
+
+// DefaultSampleInterval applies to SAMPLE and TARGET_DEFINED subscriptions
+// that do not request an interval
+const DefaultSampleInterval = time.Second
+
+// Snapshot is the tester state published to subscribers
+type Snapshot struct {
+	Interface      string
+	TestType       string
+	Status         string
+	FrameSize      uint32
+	OfferedRatePct float64
+	Trials         uint64
+	TxPackets      uint64
+	TxOctets       uint64
+	RxPackets      uint64
+	RxOctets       uint64
+	TxMbps         float64
+	RxMbps         float64
+	LossPct        float64
+	LatencyMinNs   float64
+	LatencyAvgNs   float64
+	LatencyMaxNs   float64
+	LatencyP99Ns   float64
+	Timestamp      time.Time
+}
+
+// PathElem is one element of a gNMI path
+type PathElem struct {
+	Name string
+	Key  map[string]string
+}
+
+// Path is a gNMI path
+type Path struct {
+	Origin string
+	Elem   []PathElem
+	Target string
+}
+
+// ParsePath parses "/a/b[k=v]/c" into a Path
+func ParsePath(s string) (Path, error) {
+	var p Path
+	s = strings.Trim(s, "/")
+	if s == "" {
+		return p, nil
+	}
+	for _, part := range strings.Split(s, "/") {
+		e := PathElem{Name: part}
+		if i := strings.IndexByte(part, '['); i >= 0 {
+			e.Name = part[:i]
+			e.Key = make(map[string]string)
+			for _, kv := range strings.Split(strings.TrimSuffix(part[i+1:], "]"), "][") {
+				k, v, ok := strings.Cut(kv, "=")
+				if !ok {
+					return Path{}, fmt.Errorf("invalid path key %q", kv)
+				}
+				e.Key[k] = v
+			}
+		}
+		if e.Name == "" {
+			return Path{}, fmt.Errorf("empty path element in %q", s)
+		}
+		p.Elem = append(p.Elem, e)
+	}
+	return p, nil
+}
+
+// String formats the path as "/a/b[k=v]"
+func (p Path) String() string {
+	var sb strings.Builder
+	for _, e := range p.Elem {
+		sb.WriteByte('/')
+		sb.WriteString(e.Name)
+		keys := make([]string, 0, len(e.Key))
+		for k := range e.Key {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(&sb, "[%s=%s]", k, e.Key[k])
+		}
+	}
+	if sb.Len() == 0 {
+		return "/"
+	}
+	return sb.String()
+}
+
+// join appends q's elements to p
+func (p Path) join(q Path) Path {
+	out := Path{Origin: p.Origin, Target: p.Target}
+	out.Elem = append(append(out.Elem, p.Elem...), q.Elem...)
+	if q.Origin != "" {
+		out.Origin = q.Origin
+	}
+	return out
+}
+
+// matches reports whether leaf lies under the subscription path sub.
+// Names and key values may be "*", "..." matches any remaining elements,
+// and keys absent from sub match any value.
+func matches(sub, leaf Path) bool {
+	for i, se := range sub.Elem {
+		if se.Name == "..." {
+			return true
+		}
+		if i >= len(leaf.Elem) {
+			return false
+		}
+		le := leaf.Elem[i]
+		if se.Name != "*" && se.Name != le.Name {
+			return false
+		}
+		for k, v := range se.Key {
+			if v != "*" && le.Key[k] != v {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// leaf is one value in the data model
+type leaf struct {
+	path Path
+	val  interface{}
+}
+
+func mustPath(s string) Path {
+	p, err := ParsePath(s)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// leaves flattens the snapshot into the data model
+func (s Snapshot) leaves() []leaf {
+	ifPath := fmt.Sprintf("/interfaces/interface[name=%s]/state/counters/", s.Interface)
+	return []leaf{
+		{mustPath(ifPath + "out-pkts"), s.TxPackets},
+		{mustPath(ifPath + "out-octets"), s.TxOctets},
+		{mustPath(ifPath + "in-pkts"), s.RxPackets},
+		{mustPath(ifPath + "in-octets"), s.RxOctets},
+		{mustPath("/tester/state/test-type"), s.TestType},
+		{mustPath("/tester/state/status"), s.Status},
+		{mustPath("/tester/state/frame-size"), uint64(s.FrameSize)},
+		{mustPath("/tester/state/offered-rate-pct"), s.OfferedRatePct},
+		{mustPath("/tester/state/trials"), s.Trials},
+		{mustPath("/tester/state/rates/tx-mbps"), s.TxMbps},
+		{mustPath("/tester/state/rates/rx-mbps"), s.RxMbps},
+		{mustPath("/tester/state/loss-pct"), s.LossPct},
+		{mustPath("/tester/state/latency/min-ns"), s.LatencyMinNs},
+		{mustPath("/tester/state/latency/avg-ns"), s.LatencyAvgNs},
+		{mustPath("/tester/state/latency/max-ns"), s.LatencyMaxNs},
+		{mustPath("/tester/state/latency/p99-ns"), s.LatencyP99Ns},
+	}
+}
+
+// selectLeaves returns the leaves under any of paths
+func selectLeaves(all []leaf, paths []Path) []leaf {
+	var out []leaf
+	for _, l := range all {
+		for _, p := range paths {
+			if matches(p, l.path) {
+				out = append(out, l)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// ============================================================================
+// Message encoding (field numbers from gnmi.proto)
+// ============================================================================
+
+func encodePath(p Path) []byte {
+	var e encoder
+	e.string(2, p.Origin)
+	for _, pe := range p.Elem {
+		var ee encoder
+		ee.string(1, pe.Name)
+		keys := make([]string, 0, len(pe.Key))
+		for k := range pe.Key {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			var kv encoder
+			kv.string(1, k)
+			kv.string(2, pe.Key[k])
+			ee.bytes(2, kv.buf)
+		}
+		e.bytes(3, ee.buf)
+	}
+	e.string(4, p.Target)
+	return e.buf
+}
+
+func decodePath(b []byte) (Path, error) {
+	var p Path
+	fields, err := decode(b)
+	if err != nil {
+		return p, err
+	}
+	for _, f := range fields {
+		switch f.num {
+		case 1: // Deprecated string elements
+			p.Elem = append(p.Elem, PathElem{Name: string(f.data)})
+		case 2:
+			p.Origin = string(f.data)
+		case 3:
+			pe, err := decodePathElem(f.data)
+			if err != nil {
+				return p, err
+			}
+			p.Elem = append(p.Elem, pe)
+		case 4:
+			p.Target = string(f.data)
+		}
+	}
+	return p, nil
+}
+
+func decodePathElem(b []byte) (PathElem, error) {
+	var pe PathElem
+	fields, err := decode(b)
+	if err != nil {
+		return pe, err
+	}
+	for _, f := range fields {
+		switch f.num {
+		case 1:
+			pe.Name = string(f.data)
+		case 2:
+			kv, err := decode(f.data)
+			if err != nil {
+				return pe, err
+			}
+			var k, v string
+			for _, x := range kv {
+				switch x.num {
+				case 1:
+					k = string(x.data)
+				case 2:
+					v = string(x.data)
+				}
+			}
+			if pe.Key == nil {
+				pe.Key = make(map[string]string)
+			}
+			pe.Key[k] = v
+		}
+	}
+	return pe, nil
+}
+
+// encodeTypedValue maps Go values onto TypedValue
+func encodeTypedValue(v interface{}) []byte {
+	var e encoder
+	switch x := v.(type) {
+	case string:
+		e.bytes(1, []byte(x))
+	case int64:
+		e.uvarint(2, uint64(x))
+	case uint64:
+		e.uvarint(3, x)
+	case bool:
+		e.uvarint(4, boolToUint(x))
+	case float64:
+		e.double(14, x)
+	}
+	return e.buf
+}
+
+func boolToUint(b bool) uint64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// decodeTypedValue is the inverse of encodeTypedValue
+func decodeTypedValue(b []byte) (interface{}, error) {
+	fields, err := decode(b)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range fields {
+		switch f.num {
+		case 1:
+			return string(f.data), nil
+		case 2:
+			return int64(f.v), nil
+		case 3:
+			return f.v, nil
+		case 4:
+			return f.v != 0, nil
+		case 14:
+			return math.Float64frombits(f.v), nil
+		}
+	}
+	return nil, nil
+}
+
+// encodeNotification builds a Notification with one Update per leaf.
+// Leaf paths are absolute; the prefix only carries the request target.
+func encodeNotification(ts time.Time, target string, leaves []leaf) []byte {
+	var e encoder
+	e.uvarint(1, uint64(ts.UnixNano()))
+	if target != "" {
+		e.bytes(2, encodePath(Path{Target: target}))
+	}
+	for _, l := range leaves {
+		var u encoder
+		u.bytes(1, encodePath(l.path))
+		u.bytes(3, encodeTypedValue(l.val))
+		e.bytes(4, u.buf)
+	}
+	return e.buf
+}
+
+// subscribeResponse wraps a Notification, or a sync_response when n is nil
+func subscribeResponse(n []byte) []byte {
+	var e encoder
+	if n == nil {
+		e.bool(3, true)
+	} else {
+		e.bytes(1, n)
+	}
+	return e.buf
+}
+
+func encodeCapabilityResponse() []byte {
+	var e encoder
+	var model encoder
+	model.string(1, "rfc2544-tester")
+	model.string(2, "rfc2544-master")
+	model.string(3, "1.0.0")
+	e.bytes(1, model.buf)
+	e.bytes(2, []byte{0}) // Packed encodings: JSON
+	e.string(3, Version)
+	return e.buf
+}
+
+// subscription is one requested path
+type subscription struct {
+	path     Path
+	mode     int
+	interval time.Duration
+}
+
+// subscribeRequest is the subset of SubscribeRequest the target uses
+type subscribeRequest struct {
+	prefix      Path
+	subs        []subscription
+	mode        int
+	updatesOnly bool
+	poll        bool
+}
+
+func decodeSubscribeRequest(b []byte) (*subscribeRequest, error) {
+	fields, err := decode(b)
+	if err != nil {
+		return nil, err
+	}
+	req := &subscribeRequest{}
+	for _, f := range fields {
+		switch f.num {
+		case 1:
+			if err := req.decodeList(f.data); err != nil {
+				return nil, err
+			}
+		case 3:
+			req.poll = true
+		}
+	}
+	return req, nil
+}
+
+func (req *subscribeRequest) decodeList(b []byte) error {
+	fields, err := decode(b)
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		switch f.num {
+		case 1:
+			if req.prefix, err = decodePath(f.data); err != nil {
+				return err
+			}
+		case 2:
+			sub, err := decodeSubscription(f.data)
+			if err != nil {
+				return err
+			}
+			req.subs = append(req.subs, sub)
+		case 5:
+			req.mode = int(f.v)
+		case 9:
+			req.updatesOnly = f.v != 0
+		}
+	}
+	return nil
+}
+
+func decodeSubscription(b []byte) (subscription, error) {
+	var sub subscription
+	fields, err := decode(b)
+	if err != nil {
+		return sub, err
+	}
+	for _, f := range fields {
+		switch f.num {
+		case 1:
+			if sub.path, err = decodePath(f.data); err != nil {
+				return sub, err
+			}
+		case 2:
+			sub.mode = int(f.v)
+		case 3:
+			sub.interval = time.Duration(f.v)
+		}
+	}
+	return sub, nil
+}
+
+// decodeGetRequest returns the prefix and paths of a GetRequest
+func decodeGetRequest(b []byte) (Path, []Path, error) {
+	var prefix Path
+	var paths []Path
+	fields, err := decode(b)
+	if err != nil {
+		return prefix, nil, err
+	}
+	for _, f := range fields {
+		switch f.num {
+		case 1:
+			if prefix, err = decodePath(f.data); err != nil {
+				return prefix, nil, err
+			}
+		case 2:
+			p, err := decodePath(f.data)
+			if err != nil {
+				return prefix, nil, err
+			}
+			paths = append(paths, p)
+		}
+	}
+	return prefix, paths, nil
+}
@@ -0,0 +1,138 @@
+package gnmi
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParsePath(t *testing.T) {
+	p, err := ParsePath("/interfaces/interface[name=eth0]/state/counters")
+	if err != nil {
+		t.Fatalf("ParsePath: %v", err)
+	}
+	if len(p.Elem) != 4 || p.Elem[1].Key["name"] != "eth0" {
+		t.Fatalf("ParsePath = %+v", p)
+	}
+	if got := p.String(); got != "/interfaces/interface[name=eth0]/state/counters" {
+		t.Errorf("String = %q", got)
+	}
+
+	if p, _ := ParsePath("/"); len(p.Elem) != 0 || p.String() != "/" {
+		t.Errorf("Root path = %+v", p)
+	}
+	if _, err := ParsePath("/a[k]/b"); err == nil {
+		t.Error("Key without value should fail")
+	}
+	if _, err := ParsePath("/a//b"); err == nil {
+		t.Error("Empty element should fail")
+	}
+}
+
+func TestMatches(t *testing.T) {
+	leafPath := mustPath("/interfaces/interface[name=eth0]/state/counters/in-pkts")
+	tests := []struct {
+		sub  string
+		want bool
+	}{
+		{"/", true},
+		{"/interfaces", true},
+		{"/interfaces/interface[name=eth0]", true},
+		{"/interfaces/interface[name=*]/state", true},
+		{"/interfaces/interface/state", true},
+		{"/interfaces/interface[name=eth1]", false},
+		{"/*/interface/state/counters/in-pkts", true},
+		{"/interfaces/...", true},
+		{"/tester", false},
+		{"/interfaces/interface[name=eth0]/state/counters/in-pkts/extra", false},
+	}
+	for _, tt := range tests {
+		if got := matches(mustPath(tt.sub), leafPath); got != tt.want {
+			t.Errorf("matches(%s) = %v, want %v", tt.sub, got, tt.want)
+		}
+	}
+}
+
+func TestPathRoundTrip(t *testing.T) {
+	p := Path{
+		Origin: "openconfig",
+		Target: "dut",
+		Elem: []PathElem{
+			{Name: "interfaces"},
+			{Name: "interface", Key: map[string]string{"name": "eth0", "unit": "0"}},
+		},
+	}
+	got, err := decodePath(encodePath(p))
+	if err != nil {
+		t.Fatalf("decodePath: %v", err)
+	}
+	if !reflect.DeepEqual(got, p) {
+		t.Errorf("decodePath = %+v, want %+v", got, p)
+	}
+}
+
+func TestTypedValueRoundTrip(t *testing.T) {
+	for _, v := range []interface{}{"running", int64(-5), uint64(1 << 40), true, 99.25} {
+		got, err := decodeTypedValue(encodeTypedValue(v))
+		if err != nil {
+			t.Fatalf("decodeTypedValue(%v): %v", v, err)
+		}
+		if got != v {
+			t.Errorf("TypedValue round trip = %v (%T), want %v (%T)", got, got, v, v)
+		}
+	}
+}
+
+func TestDecodeTruncated(t *testing.T) {
+	var e encoder
+	e.bytes(1, []byte("hello"))
+	if _, err := decode(e.buf[:len(e.buf)-1]); err == nil {
+		t.Error("Truncated message should fail")
+	}
+}
+
+func TestSubscribeRequestDecode(t *testing.T) {
+	var sub encoder
+	sub.bytes(1, encodePath(mustPath("/tester/state")))
+	sub.uvarint(2, SubSample)
+	sub.uvarint(3, uint64(500*time.Millisecond))
+
+	var list encoder
+	list.bytes(1, encodePath(Path{Target: "tester"}))
+	list.bytes(2, sub.buf)
+	list.uvarint(5, ModeStream)
+	list.bool(9, true)
+
+	var req encoder
+	req.bytes(1, list.buf)
+
+	got, err := decodeSubscribeRequest(req.buf)
+	if err != nil {
+		t.Fatalf("decodeSubscribeRequest: %v", err)
+	}
+	if got.prefix.Target != "tester" || !got.updatesOnly || got.mode != ModeStream || len(got.subs) != 1 {
+		t.Fatalf("request = %+v", got)
+	}
+	s := got.subs[0]
+	if s.path.String() != "/tester/state" || s.mode != SubSample || s.interval != 500*time.Millisecond {
+		t.Errorf("subscription = %+v", s)
+	}
+}
+
+func TestSnapshotLeaves(t *testing.T) {
+	snap := Snapshot{Interface: "eth0", TxPackets: 10, RxOctets: 640, Status: "running", LossPct: 0.5}
+	got := make(map[string]interface{})
+	for _, l := range selectLeaves(snap.leaves(), []Path{mustPath("/interfaces"), mustPath("/tester/state/status")}) {
+		got[l.path.String()] = l.val
+	}
+	want := map[string]interface{}{
+		"/interfaces/interface[name=eth0]/state/counters/out-pkts":   uint64(10),
+		"/interfaces/interface[name=eth0]/state/counters/out-octets": uint64(0),
+		"/interfaces/interface[name=eth0]/state/counters/in-pkts":    uint64(0),
+		"/interfaces/interface[name=eth0]/state/counters/in-octets":  uint64(640),
+		"/tester/state/status": "running",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("leaves = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,108 @@
+package gnmi
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// Protobuf wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+var errTruncated = errors.New("truncated protobuf message")
+
+// encoder appends protobuf fields to a buffer
+type encoder struct {
+	buf []byte
+}
+
+func (e *encoder) tag(field, wire int) {
+	e.buf = binary.AppendUvarint(e.buf, uint64(field)<<3|uint64(wire))
+}
+
+func (e *encoder) uvarint(field int, v uint64) {
+	e.tag(field, wireVarint)
+	e.buf = binary.AppendUvarint(e.buf, v)
+}
+
+func (e *encoder) bool(field int, v bool) {
+	if v {
+		e.uvarint(field, 1)
+	}
+}
+
+func (e *encoder) double(field int, v float64) {
+	e.tag(field, wireFixed64)
+	e.buf = binary.LittleEndian.AppendUint64(e.buf, math.Float64bits(v))
+}
+
+func (e *encoder) bytes(field int, b []byte) {
+	e.tag(field, wireBytes)
+	e.buf = binary.AppendUvarint(e.buf, uint64(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+func (e *encoder) string(field int, s string) {
+	if s != "" {
+		e.bytes(field, []byte(s))
+	}
+}
+
+// field is one decoded protobuf field. Varint and fixed values are in v,
+// length-delimited values in data.
+type field struct {
+	num  int
+	wire int
+	v    uint64
+	data []byte
+}
+
+// decode splits a protobuf message into its fields
+func decode(b []byte) ([]field, error) {
+	var fields []field
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return nil, errTruncated
+		}
+		b = b[n:]
+		f := field{num: int(key >> 3), wire: int(key & 7)}
+
+		switch f.wire {
+		case wireVarint:
+			f.v, n = binary.Uvarint(b)
+			if n <= 0 {
+				return nil, errTruncated
+			}
+			b = b[n:]
+		case wireFixed64:
+			if len(b) < 8 {
+				return nil, errTruncated
+			}
+			f.v = binary.LittleEndian.Uint64(b)
+			b = b[8:]
+		case wireFixed32:
+			if len(b) < 4 {
+				return nil, errTruncated
+			}
+			f.v = uint64(binary.LittleEndian.Uint32(b))
+			b = b[4:]
+		case wireBytes:
+			l, n := binary.Uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < l {
+				return nil, errTruncated
+			}
+			f.data = b[n : n+int(l)]
+			b = b[n+int(l):]
+		default:
+			return nil, errors.New("unsupported protobuf wire type")
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
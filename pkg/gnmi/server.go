@@ -0,0 +1,475 @@
+package gnmi
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// gRPC status codes used by the target
+const (
+	codeOK              = 0
+	codeInvalidArgument = 3
+	codeUnimplemented   = 12
+	codeInternal        = 13
+)
+
+// maxMessageSize bounds request messages read from clients
+const maxMessageSize = 4 << 20
+
+// Server is a gNMI target serving the latest Snapshot
+type Server struct {
+	addr     string
+	certFile string
+	keyFile  string
+	mux      *http.ServeMux
+	server   *http.Server
+
+	mu      sync.Mutex
+	snap    Snapshot
+	changed chan struct{}
+}
+
+// Option configures a Server
+type Option func(*Server)
+
+// WithTLSFiles serves with the given certificate and key instead of a
+// generated self-signed certificate
+func WithTLSFiles(certFile, keyFile string) Option {
+	return func(s *Server) {
+		s.certFile = certFile
+		s.keyFile = keyFile
+	}
+}
+
+// NewServer creates a gNMI target listening on addr
+func NewServer(addr string, opts ...Option) *Server {
+	s := &Server{
+		addr:    addr,
+		mux:     http.NewServeMux(),
+		changed: make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.mux.HandleFunc("/gnmi.gNMI/Capabilities", s.handleCapabilities)
+	s.mux.HandleFunc("/gnmi.gNMI/Get", s.handleGet)
+	s.mux.HandleFunc("/gnmi.gNMI/Set", s.handleSet)
+	s.mux.HandleFunc("/gnmi.gNMI/Subscribe", s.handleSubscribe)
+	return s
+}
+
+// Handler returns the gRPC handler, for embedding in another HTTP/2 server
+func (s *Server) Handler() http.Handler {
+	return s.mux
+}
+
+// Update publishes a new snapshot and wakes ON_CHANGE subscribers
+func (s *Server) Update(snap Snapshot) {
+	if snap.Timestamp.IsZero() {
+		snap.Timestamp = time.Now()
+	}
+	s.mu.Lock()
+	s.snap = snap
+	close(s.changed)
+	s.changed = make(chan struct{})
+	s.mu.Unlock()
+}
+
+// current returns the latest snapshot and a channel closed on the next Update
+func (s *Server) current() (Snapshot, <-chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snap := s.snap
+	if snap.Timestamp.IsZero() {
+		snap.Timestamp = time.Now()
+	}
+	return snap, s.changed
+}
+
+// Start serves gNMI over TLS (blocking)
+func (s *Server) Start() error {
+	s.server = &http.Server{
+		Addr:    s.addr,
+		Handler: s.mux,
+	}
+
+	log.Printf("[gnmi] Starting target on %s", s.addr)
+	if s.certFile != "" {
+		return s.server.ListenAndServeTLS(s.certFile, s.keyFile)
+	}
+	cert, err := selfSignedCert()
+	if err != nil {
+		return fmt.Errorf("failed to generate certificate: %w", err)
+	}
+	s.server.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+	return s.server.ListenAndServeTLS("", "")
+}
+
+// Stop shuts down the server
+func (s *Server) Stop() error {
+	if s.server != nil {
+		return s.server.Close()
+	}
+	return nil
+}
+
+// selfSignedCert generates an in-memory certificate for the target
+func selfSignedCert() (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "rfc2544-gnmi"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(365 * 24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
+
+// ============================================================================
+// gRPC framing
+// ============================================================================
+
+// readMessage reads one length-prefixed gRPC message
+func readMessage(r io.Reader) ([]byte, error) {
+	var hdr [5]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, err
+	}
+	if hdr[0] != 0 {
+		return nil, fmt.Errorf("compressed messages are not supported")
+	}
+	n := binary.BigEndian.Uint32(hdr[1:])
+	if n > maxMessageSize {
+		return nil, fmt.Errorf("message of %d bytes exceeds limit", n)
+	}
+	msg := make([]byte, n)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// frame prefixes msg with the gRPC message header
+func frame(msg []byte) []byte {
+	out := make([]byte, 5, 5+len(msg))
+	binary.BigEndian.PutUint32(out[1:], uint32(len(msg)))
+	return append(out, msg...)
+}
+
+// stream writes gRPC responses on an HTTP/2 response
+type stream struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+func newStream(w http.ResponseWriter) *stream {
+	w.Header().Set("Content-Type", "application/grpc")
+	w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+	f, _ := w.(http.Flusher)
+	return &stream{w: w, flusher: f}
+}
+
+func (st *stream) send(msg []byte) error {
+	if _, err := st.w.Write(frame(msg)); err != nil {
+		return err
+	}
+	if st.flusher != nil {
+		st.flusher.Flush()
+	}
+	return nil
+}
+
+func (st *stream) finish(code int, msg string) {
+	st.w.Header().Set("Grpc-Status", strconv.Itoa(code))
+	if msg != "" {
+		st.w.Header().Set("Grpc-Message", msg)
+	}
+}
+
+// unary reads a single request message, rejecting non-gRPC requests
+func unary(w http.ResponseWriter, r *http.Request) ([]byte, *stream, bool) {
+	if r.Method != http.MethodPost || r.ProtoMajor != 2 {
+		http.Error(w, "gRPC requires HTTP/2 POST", http.StatusBadRequest)
+		return nil, nil, false
+	}
+	st := newStream(w)
+	msg, err := readMessage(r.Body)
+	if err != nil {
+		st.finish(codeInvalidArgument, err.Error())
+		return nil, st, false
+	}
+	return msg, st, true
+}
+
+// ============================================================================
+// RPC handlers
+// ============================================================================
+
+func (s *Server) handleCapabilities(w http.ResponseWriter, r *http.Request) {
+	_, st, ok := unary(w, r)
+	if !ok {
+		return
+	}
+	if err := st.send(encodeCapabilityResponse()); err != nil {
+		return
+	}
+	st.finish(codeOK, "")
+}
+
+func (s *Server) handleGet(w http.ResponseWriter, r *http.Request) {
+	msg, st, ok := unary(w, r)
+	if !ok {
+		return
+	}
+	prefix, paths, err := decodeGetRequest(msg)
+	if err != nil {
+		st.finish(codeInvalidArgument, err.Error())
+		return
+	}
+	if len(paths) == 0 {
+		paths = []Path{{}}
+	}
+	for i := range paths {
+		paths[i] = prefix.join(paths[i])
+	}
+
+	snap, _ := s.current()
+	var e encoder
+	e.bytes(1, encodeNotification(snap.Timestamp, prefix.Target, selectLeaves(snap.leaves(), paths)))
+	if err := st.send(e.buf); err != nil {
+		return
+	}
+	st.finish(codeOK, "")
+}
+
+func (s *Server) handleSet(w http.ResponseWriter, r *http.Request) {
+	_, st, ok := unary(w, r)
+	if !ok {
+		return
+	}
+	st.finish(codeUnimplemented, "the tester is read-only")
+}
+
+func (s *Server) handleSubscribe(w http.ResponseWriter, r *http.Request) {
+	msg, st, ok := unary(w, r)
+	if !ok {
+		return
+	}
+	req, err := decodeSubscribeRequest(msg)
+	if err != nil {
+		st.finish(codeInvalidArgument, err.Error())
+		return
+	}
+	if req.poll || len(req.subs) == 0 {
+		st.finish(codeInvalidArgument, "first request must be a subscription list")
+		return
+	}
+	for i := range req.subs {
+		req.subs[i].path = req.prefix.join(req.subs[i].path)
+	}
+
+	sub := &subscriber{req: req, st: st, last: make(map[string]interface{})}
+	switch req.mode {
+	case ModeOnce:
+		err = sub.once(s)
+	case ModePoll:
+		err = sub.poll(s, r.Body, r.Context().Done())
+	case ModeStream:
+		err = sub.stream(s, r.Context().Done())
+	default:
+		st.finish(codeInvalidArgument, fmt.Sprintf("unknown subscription mode %d", req.mode))
+		return
+	}
+	if err != nil {
+		st.finish(codeInternal, err.Error())
+		return
+	}
+	st.finish(codeOK, "")
+}
+
+// subscriber tracks one Subscribe RPC
+type subscriber struct {
+	req  *subscribeRequest
+	st   *stream
+	last map[string]interface{} // Values last sent, for ON_CHANGE
+}
+
+func (sub *subscriber) paths(filter func(subscription) bool) []Path {
+	var paths []Path
+	for _, s := range sub.req.subs {
+		if filter == nil || filter(s) {
+			paths = append(paths, s.path)
+		}
+	}
+	return paths
+}
+
+// send writes the leaves matching paths. With changedOnly, leaves whose
+// value equals the last one sent are skipped.
+func (sub *subscriber) send(snap Snapshot, paths []Path, changedOnly bool) error {
+	var out []leaf
+	for _, l := range selectLeaves(snap.leaves(), paths) {
+		key := l.path.String()
+		if changedOnly && sub.last[key] == l.val {
+			continue
+		}
+		sub.last[key] = l.val
+		out = append(out, l)
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return sub.st.send(subscribeResponse(encodeNotification(snap.Timestamp, sub.req.prefix.Target, out)))
+}
+
+func (sub *subscriber) sync() error {
+	return sub.st.send(subscribeResponse(nil))
+}
+
+// initial sends the full state followed by sync_response
+func (sub *subscriber) initial(s *Server) (<-chan struct{}, error) {
+	snap, changed := s.current()
+	if !sub.req.updatesOnly {
+		if err := sub.send(snap, sub.paths(nil), false); err != nil {
+			return changed, err
+		}
+	} else {
+		// Seed ON_CHANGE state so the first change is reported as a diff
+		for _, l := range selectLeaves(snap.leaves(), sub.paths(nil)) {
+			sub.last[l.path.String()] = l.val
+		}
+	}
+	return changed, sub.sync()
+}
+
+func (sub *subscriber) once(s *Server) error {
+	_, err := sub.initial(s)
+	return err
+}
+
+func (sub *subscriber) poll(s *Server, body io.Reader, done <-chan struct{}) error {
+	if _, err := sub.initial(s); err != nil {
+		return err
+	}
+	polls := make(chan error)
+	go func() {
+		for {
+			msg, err := readMessage(body)
+			if err == nil {
+				var req *subscribeRequest
+				if req, err = decodeSubscribeRequest(msg); err == nil && !req.poll {
+					err = fmt.Errorf("expected poll request")
+				}
+			}
+			select {
+			case polls <- err:
+			case <-done:
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-done:
+			return nil
+		case err := <-polls:
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			snap, _ := s.current()
+			if err := sub.send(snap, sub.paths(nil), false); err != nil {
+				return err
+			}
+			if err := sub.sync(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (sub *subscriber) stream(s *Server, done <-chan struct{}) error {
+	changed, err := sub.initial(s)
+	if err != nil {
+		return err
+	}
+
+	// One ticker per sampled path; ticks carry the subscription index
+	due := make(chan int)
+	stop := make(chan struct{})
+	defer close(stop)
+	for i, sb := range sub.req.subs {
+		if sb.mode == SubOnChange {
+			continue
+		}
+		interval := sb.interval
+		if interval <= 0 {
+			interval = DefaultSampleInterval
+		}
+		go func(i int, interval time.Duration) {
+			t := time.NewTicker(interval)
+			defer t.Stop()
+			for {
+				select {
+				case <-t.C:
+					select {
+					case due <- i:
+					case <-stop:
+						return
+					}
+				case <-stop:
+					return
+				}
+			}
+		}(i, interval)
+	}
+
+	onChange := sub.paths(func(sb subscription) bool { return sb.mode == SubOnChange })
+	for {
+		select {
+		case <-done:
+			return nil
+		case i := <-due:
+			snap, _ := s.current()
+			if err := sub.send(snap, []Path{sub.req.subs[i].path}, false); err != nil {
+				return err
+			}
+		case <-changed:
+			var snap Snapshot
+			snap, changed = s.current()
+			if len(onChange) > 0 {
+				if err := sub.send(snap, onChange, true); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
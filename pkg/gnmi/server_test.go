@@ -0,0 +1,252 @@
+package gnmi
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newTestServer(t *testing.T) (*Server, *httptest.Server) {
+	t.Helper()
+	s := NewServer("")
+	ts := httptest.NewUnstartedServer(s.Handler())
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	t.Cleanup(ts.Close)
+	s.Update(Snapshot{Interface: "eth0", Status: "running", TxPackets: 100, RxPackets: 99, LossPct: 1})
+	return s, ts
+}
+
+// rpc opens a call and returns the response and a writer for further requests
+func rpc(t *testing.T, ts *httptest.Server, method string, req []byte) (*http.Response, *io.PipeWriter) {
+	t.Helper()
+	pr, pw := io.Pipe()
+	httpReq, err := http.NewRequest(http.MethodPost, ts.URL+"/gnmi.gNMI/"+method, pr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpReq.Header.Set("Content-Type", "application/grpc")
+	go pw.Write(frame(req))
+
+	resp, err := ts.Client().Do(httpReq)
+	if err != nil {
+		t.Fatalf("%s: %v", method, err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	if resp.ProtoMajor != 2 {
+		t.Fatalf("%s used HTTP/%d", method, resp.ProtoMajor)
+	}
+	return resp, pw
+}
+
+// notificationValues decodes the updates of a Notification
+func notificationValues(t *testing.T, b []byte) map[string]interface{} {
+	t.Helper()
+	fields, err := decode(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := make(map[string]interface{})
+	for _, f := range fields {
+		if f.num != 4 {
+			continue
+		}
+		uf, _ := decode(f.data)
+		var path Path
+		var val interface{}
+		for _, x := range uf {
+			switch x.num {
+			case 1:
+				path, _ = decodePath(x.data)
+			case 3:
+				val, _ = decodeTypedValue(x.data)
+			}
+		}
+		out[path.String()] = val
+	}
+	return out
+}
+
+// readSubscribe reads one SubscribeResponse, returning its values or nil
+// for a sync_response
+func readSubscribe(t *testing.T, r io.Reader) map[string]interface{} {
+	t.Helper()
+	msg, err := readMessage(r)
+	if err != nil {
+		t.Fatalf("readMessage: %v", err)
+	}
+	fields, _ := decode(msg)
+	for _, f := range fields {
+		switch f.num {
+		case 1:
+			return notificationValues(t, f.data)
+		case 3:
+			return nil
+		}
+	}
+	t.Fatal("empty SubscribeResponse")
+	return nil
+}
+
+func subscribeList(mode int, updatesOnly bool, subs ...subscription) []byte {
+	var list encoder
+	for _, s := range subs {
+		var se encoder
+		se.bytes(1, encodePath(s.path))
+		se.uvarint(2, uint64(s.mode))
+		if s.interval > 0 {
+			se.uvarint(3, uint64(s.interval))
+		}
+		list.bytes(2, se.buf)
+	}
+	list.uvarint(5, uint64(mode))
+	list.bool(9, updatesOnly)
+	var req encoder
+	req.bytes(1, list.buf)
+	return req.buf
+}
+
+func TestCapabilities(t *testing.T) {
+	_, ts := newTestServer(t)
+	resp, pw := rpc(t, ts, "Capabilities", nil)
+	pw.Close()
+	msg, err := readMessage(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields, _ := decode(msg)
+	var version string
+	for _, f := range fields {
+		if f.num == 3 {
+			version = string(f.data)
+		}
+	}
+	if version != Version {
+		t.Errorf("gNMI version = %q", version)
+	}
+	io.Copy(io.Discard, resp.Body)
+	if resp.Trailer.Get("Grpc-Status") != "0" {
+		t.Errorf("Grpc-Status = %q", resp.Trailer.Get("Grpc-Status"))
+	}
+}
+
+func TestGet(t *testing.T) {
+	_, ts := newTestServer(t)
+	var req encoder
+	req.bytes(2, encodePath(mustPath("/tester/state/loss-pct")))
+	resp, pw := rpc(t, ts, "Get", req.buf)
+	pw.Close()
+
+	msg, err := readMessage(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields, _ := decode(msg)
+	if len(fields) != 1 || fields[0].num != 1 {
+		t.Fatalf("GetResponse fields = %+v", fields)
+	}
+	vals := notificationValues(t, fields[0].data)
+	if len(vals) != 1 || vals["/tester/state/loss-pct"] != 1.0 {
+		t.Errorf("Get values = %v", vals)
+	}
+}
+
+func TestSetUnimplemented(t *testing.T) {
+	_, ts := newTestServer(t)
+	resp, pw := rpc(t, ts, "Set", nil)
+	pw.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.Trailer.Get("Grpc-Status") != "12" {
+		t.Errorf("Grpc-Status = %q, want 12", resp.Trailer.Get("Grpc-Status"))
+	}
+}
+
+func TestSubscribeOnce(t *testing.T) {
+	_, ts := newTestServer(t)
+	resp, pw := rpc(t, ts, "Subscribe", subscribeList(ModeOnce, false,
+		subscription{path: mustPath("/interfaces/interface[name=*]/state/counters")}))
+	defer pw.Close()
+
+	vals := readSubscribe(t, resp.Body)
+	if len(vals) != 4 || vals["/interfaces/interface[name=eth0]/state/counters/out-pkts"] != uint64(100) {
+		t.Fatalf("ONCE values = %v", vals)
+	}
+	if readSubscribe(t, resp.Body) != nil {
+		t.Fatal("Expected sync_response")
+	}
+	if _, err := readMessage(resp.Body); err != io.EOF {
+		t.Errorf("ONCE should end the stream, got %v", err)
+	}
+	if resp.Trailer.Get("Grpc-Status") != "0" {
+		t.Errorf("Grpc-Status = %q", resp.Trailer.Get("Grpc-Status"))
+	}
+}
+
+func TestSubscribePoll(t *testing.T) {
+	s, ts := newTestServer(t)
+	resp, pw := rpc(t, ts, "Subscribe", subscribeList(ModePoll, false,
+		subscription{path: mustPath("/tester/state/status")}))
+
+	if vals := readSubscribe(t, resp.Body); vals["/tester/state/status"] != "running" {
+		t.Fatalf("initial POLL values = %v", vals)
+	}
+	readSubscribe(t, resp.Body)
+
+	s.Update(Snapshot{Interface: "eth0", Status: "complete"})
+	var poll encoder
+	poll.bytes(3, nil)
+	pw.Write(frame(poll.buf))
+	if vals := readSubscribe(t, resp.Body); vals["/tester/state/status"] != "complete" {
+		t.Fatalf("polled values = %v", vals)
+	}
+	if readSubscribe(t, resp.Body) != nil {
+		t.Fatal("Poll response should end with sync_response")
+	}
+	pw.Close()
+}
+
+func TestSubscribeStream(t *testing.T) {
+	s, ts := newTestServer(t)
+	resp, pw := rpc(t, ts, "Subscribe", subscribeList(ModeStream, true,
+		subscription{path: mustPath("/tester/state/status"), mode: SubOnChange},
+		subscription{path: mustPath("/tester/state/rates"), mode: SubSample, interval: 20 * time.Millisecond}))
+	defer pw.Close()
+
+	// updates_only skips the initial state
+	if readSubscribe(t, resp.Body) != nil {
+		t.Fatal("Expected sync_response first with updates_only")
+	}
+
+	// Samples arrive on the interval
+	vals := readSubscribe(t, resp.Body)
+	if _, ok := vals["/tester/state/rates/tx-mbps"]; !ok || len(vals) != 2 {
+		t.Fatalf("sample values = %v", vals)
+	}
+
+	// ON_CHANGE sends only changed leaves
+	s.Update(Snapshot{Interface: "eth0", Status: "complete", TxMbps: 5})
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		vals = readSubscribe(t, resp.Body)
+		if v, ok := vals["/tester/state/status"]; ok {
+			if v != "complete" || len(vals) != 1 {
+				t.Errorf("on-change values = %v", vals)
+			}
+			return
+		}
+	}
+	t.Fatal("No on-change update received")
+}
+
+func TestSubscribeRejectsEmpty(t *testing.T) {
+	_, ts := newTestServer(t)
+	resp, pw := rpc(t, ts, "Subscribe", subscribeList(ModeStream, false))
+	pw.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if len(bytes.TrimSpace(body)) != 0 || resp.Trailer.Get("Grpc-Status") != "3" {
+		t.Errorf("Grpc-Status = %q, want 3", resp.Trailer.Get("Grpc-Status"))
+	}
+}
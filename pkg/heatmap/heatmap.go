@@ -0,0 +1,210 @@
+// Package heatmap renders latency over time as a heatmap: one column per
+// trial across, latency rows up, and the colour of each cell the number of
+// frames that saw that latency in that trial
+//
+// Aggregate percentiles hide periodic spikes (a route flap every ten
+// minutes, a garbage-collecting control plane) that stand out as vertical
+// stripes here. Trials report a latency summary rather than every frame,
+// so each sample's frame count is spread over the rows between its
+// percentile points (min, P50, P95, P99, max); a cell is an estimate, but
+// the shape in time is measured. Maps are written as PNG, SVG or a
+// self-contained interactive HTML page.
+package heatmap
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultRows is the number of latency rows when none is given
+const DefaultRows = 32
+
+// minLatencyUs floors the lowest row, since rows are log-spaced
+const minLatencyUs = 0.1
+
+// Sample is the latency summary of one trial. Zero percentiles are treated
+// as not measured.
+type Sample struct {
+	Time  time.Time `json:"time"`
+	Count uint64    `json:"count"` // Frames the summary covers
+	MinUs float64   `json:"min_us"`
+	P50Us float64   `json:"p50_us,omitempty"` // Average when no median is measured
+	P95Us float64   `json:"p95_us,omitempty"`
+	P99Us float64   `json:"p99_us,omitempty"`
+	MaxUs float64   `json:"max_us"`
+}
+
+// Heatmap is a grid of estimated frame counts
+type Heatmap struct {
+	Title      string      `json:"title"`
+	Start      time.Time   `json:"start"`
+	OffsetsSec []float64   `json:"offsets_sec"` // Start of each column after Start
+	EdgesUs    []float64   `json:"edges_us"`    // Row boundaries, ascending; one more than rows
+	Counts     [][]float64 `json:"counts"`      // Counts[column][row]
+}
+
+// Rows returns the number of latency rows
+func (h *Heatmap) Rows() int {
+	return len(h.EdgesUs) - 1
+}
+
+// Max returns the largest cell count
+func (h *Heatmap) Max() float64 {
+	var m float64
+	for _, col := range h.Counts {
+		for _, c := range col {
+			m = math.Max(m, c)
+		}
+	}
+	return m
+}
+
+// Build gives each sample a column, in time order, and spreads its frame
+// count over log-spaced latency rows. Columns are trials rather than fixed
+// intervals, since trial overhead makes their spacing uneven.
+func Build(title string, samples []Sample, rows int) (*Heatmap, error) {
+	if rows <= 0 {
+		rows = DefaultRows
+	}
+
+	lo, hi := math.Inf(1), 0.0
+	var used []Sample
+	for _, s := range samples {
+		if s.Count == 0 || s.MaxUs <= 0 {
+			continue
+		}
+		lo = math.Min(lo, math.Max(s.MinUs, minLatencyUs))
+		hi = math.Max(hi, s.MaxUs)
+		used = append(used, s)
+	}
+	if len(used) == 0 {
+		return nil, errors.New("no latency samples")
+	}
+	if hi <= lo {
+		hi = lo * 2
+	}
+
+	sort.SliceStable(used, func(i, j int) bool { return used[i].Time.Before(used[j].Time) })
+
+	h := &Heatmap{Title: title, Start: used[0].Time, EdgesUs: make([]float64, rows+1)}
+	ratio := math.Log(hi / lo)
+	for i := range h.EdgesUs {
+		h.EdgesUs[i] = lo * math.Exp(ratio*float64(i)/float64(rows))
+	}
+	h.EdgesUs[rows] = hi // Exact, so the maximum lands in the top row
+
+	for _, s := range used {
+		col := make([]float64, rows)
+		h.spread(col, s)
+		h.Counts = append(h.Counts, col)
+		h.OffsetsSec = append(h.OffsetsSec, s.Time.Sub(h.Start).Seconds())
+	}
+	return h, nil
+}
+
+// spread distributes a sample's frames over the rows. The mass between two
+// percentile points is spread evenly in log latency.
+func (h *Heatmap) spread(col []float64, s Sample) {
+	type point struct{ us, frac float64 }
+	points := []point{{math.Max(s.MinUs, h.EdgesUs[0]), 0}}
+	for _, p := range []point{{s.P50Us, 0.50}, {s.P95Us, 0.95}, {s.P99Us, 0.99}} {
+		if p.us > 0 && p.us >= points[len(points)-1].us && p.us <= s.MaxUs {
+			points = append(points, p)
+		}
+	}
+	points = append(points, point{s.MaxUs, 1})
+
+	n := float64(s.Count)
+	for i := 1; i < len(points); i++ {
+		a, b := points[i-1], points[i]
+		mass := (b.frac - a.frac) * n
+		if mass <= 0 {
+			continue
+		}
+		if b.us <= a.us {
+			col[h.row(a.us)] += mass
+			continue
+		}
+		la, lb := math.Log(a.us), math.Log(b.us)
+		for r := h.row(a.us); r <= h.row(b.us); r++ {
+			from := math.Max(la, math.Log(h.EdgesUs[r]))
+			to := math.Min(lb, math.Log(h.EdgesUs[r+1]))
+			if to > from {
+				col[r] += mass * (to - from) / (lb - la)
+			}
+		}
+	}
+}
+
+// row returns the row holding latency us
+func (h *Heatmap) row(us float64) int {
+	rows := h.Rows()
+	for r := 0; r < rows; r++ {
+		if us < h.EdgesUs[r+1] {
+			return r
+		}
+	}
+	return rows - 1
+}
+
+// Formats lists the file extensions Write accepts
+func Formats() []string {
+	return []string{".png", ".svg", ".html"}
+}
+
+// SupportedFile reports whether Write can produce path
+func SupportedFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, f := range Formats() {
+		if ext == f {
+			return true
+		}
+	}
+	return false
+}
+
+// Write renders the heatmap to path, choosing the format by extension
+func (h *Heatmap) Write(path string) error {
+	if !SupportedFile(path) {
+		return fmt.Errorf("%s: heatmap file must end in %s", path, strings.Join(Formats(), ", "))
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".png":
+		err = h.WritePNG(f)
+	case ".svg":
+		err = h.WriteSVG(f)
+	default:
+		err = h.WriteHTML(f)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// FormatUs formats a latency for axis labels and tooltips
+func FormatUs(us float64) string {
+	switch {
+	case us >= 1e6:
+		return fmt.Sprintf("%.2fs", us/1e6)
+	case us >= 1e3:
+		return fmt.Sprintf("%.3gms", us/1e3)
+	}
+	return fmt.Sprintf("%.3gus", us)
+}
+
+// formatElapsed formats an offset in seconds as h:mm:ss
+func formatElapsed(sec float64) string {
+	d := time.Duration(sec) * time.Second
+	return fmt.Sprintf("%d:%02d:%02d", int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60)
+}
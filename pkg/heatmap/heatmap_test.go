@@ -0,0 +1,117 @@
+package heatmap
+
+import (
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var t0 = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func steady(at time.Duration) Sample {
+	return Sample{Time: t0.Add(at), Count: 1000, MinUs: 10, P50Us: 12, P95Us: 15, P99Us: 18, MaxUs: 20}
+}
+
+func sum(col []float64) float64 {
+	var s float64
+	for _, c := range col {
+		s += c
+	}
+	return s
+}
+
+func TestBuild(t *testing.T) {
+	spike := Sample{Time: t0.Add(2 * time.Minute), Count: 1000, MinUs: 10, P50Us: 12, P95Us: 15, P99Us: 900, MaxUs: 1000}
+	h, err := Build("soak", []Sample{steady(0), steady(time.Minute), steady(4 * time.Minute), spike}, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(h.Counts) != 4 || h.Rows() != 16 {
+		t.Fatalf("grid = %d x %d, want 4 x 16", len(h.Counts), h.Rows())
+	}
+	if h.OffsetsSec[2] != 120 || h.OffsetsSec[3] != 240 {
+		t.Errorf("offsets = %v, want columns in time order", h.OffsetsSec)
+	}
+	if h.EdgesUs[0] != 10 || h.EdgesUs[16] != 1000 {
+		t.Errorf("edges = %.1f..%.1f, want 10..1000", h.EdgesUs[0], h.EdgesUs[16])
+	}
+
+	// Every frame is placed
+	for c := range h.Counts {
+		if got := sum(h.Counts[c]); math.Abs(got-1000) > 1e-6 {
+			t.Errorf("column %d holds %.3f frames, want 1000", c, got)
+		}
+	}
+
+	// Only the spike reaches the top rows
+	top := h.row(500)
+	for c, col := range h.Counts {
+		var high float64
+		for _, v := range col[top:] {
+			high += v
+		}
+		if (c == 2) != (high > 0) {
+			t.Errorf("column %d has %.1f frames above 500us", c, high)
+		}
+	}
+}
+
+func TestBuildWithoutPercentiles(t *testing.T) {
+	// Min, average and max only, as from TRex or Y.1564 trials
+	h, err := Build("perf", []Sample{{Time: t0, Count: 10, MinUs: 5, P50Us: 6, MaxUs: 50}}, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := sum(h.Counts[0]); math.Abs(got-10) > 1e-9 {
+		t.Errorf("placed %.3f frames, want 10", got)
+	}
+}
+
+func TestBuildErrors(t *testing.T) {
+	if _, err := Build("x", nil, 0); err == nil {
+		t.Error("Expected error for no samples")
+	}
+	if _, err := Build("x", []Sample{{Time: t0, MaxUs: 10}}, 0); err == nil {
+		t.Error("Expected error when no sample covers any frames")
+	}
+}
+
+func TestWrite(t *testing.T) {
+	h, err := Build("soak <512B>", []Sample{steady(0), steady(time.Minute)}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+
+	pngPath := filepath.Join(dir, "h.png")
+	if err := h.Write(pngPath); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(pngPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if img, err := png.Decode(f); err != nil || img.Bounds().Dx() != width {
+		t.Errorf("png decode: %v", err)
+	}
+
+	for name, want := range map[string]string{"h.svg": "<title>", "h.html": `id="readout"`} {
+		path := filepath.Join(dir, name)
+		if err := h.Write(path); err != nil {
+			t.Fatal(err)
+		}
+		data, _ := os.ReadFile(path)
+		if !strings.Contains(string(data), want) || !strings.Contains(string(data), "soak &lt;512B&gt;") {
+			t.Errorf("%s is missing %q or the escaped title", name, want)
+		}
+	}
+
+	if err := h.Write(filepath.Join(dir, "h.jpg")); err == nil {
+		t.Error("Expected error for an unsupported extension")
+	}
+}
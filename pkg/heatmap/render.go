@@ -0,0 +1,197 @@
+package heatmap
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"html/template"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+)
+
+// Colour ramp from empty to busiest cell (viridis)
+var ramp = []color.RGBA{
+	{0x44, 0x01, 0x54, 0xff},
+	{0x3b, 0x52, 0x8b, 0xff},
+	{0x21, 0x91, 0x8c, 0xff},
+	{0x5e, 0xc9, 0x62, 0xff},
+	{0xfd, 0xe7, 0x25, 0xff},
+}
+
+var background = color.RGBA{0xff, 0xff, 0xff, 0xff}
+
+// colorFor maps a count to the ramp on a log scale, so a few frames in a
+// spike are still visible next to the bulk of the traffic
+func colorFor(count, max float64) color.RGBA {
+	if count <= 0 || max <= 0 {
+		return background
+	}
+	t := math.Log1p(count) / math.Log1p(max)
+	pos := t * float64(len(ramp)-1)
+	i := int(pos)
+	if i >= len(ramp)-1 {
+		return ramp[len(ramp)-1]
+	}
+	f := pos - float64(i)
+	a, b := ramp[i], ramp[i+1]
+	mix := func(x, y uint8) uint8 { return uint8(float64(x) + (float64(y)-float64(x))*f) }
+	return color.RGBA{mix(a.R, b.R), mix(a.G, b.G), mix(a.B, b.B), 0xff}
+}
+
+func hexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// Image layout, in pixels
+const (
+	width        = 960
+	height       = 480
+	marginLeft   = 80
+	marginRight  = 110
+	marginTop    = 40
+	marginBottom = 56
+	plotWidth    = width - marginLeft - marginRight
+	plotHeight   = height - marginTop - marginBottom
+	maxTicks     = 8
+)
+
+// WritePNG writes the cells and a colour legend without text, for
+// embedding in documents that add their own captions
+func (h *Heatmap) WritePNG(w io.Writer) error {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	fill := func(x0, y0, x1, y1 int, c color.RGBA) {
+		for y := y0; y < y1; y++ {
+			for x := x0; x < x1; x++ {
+				img.SetRGBA(x, y, c)
+			}
+		}
+	}
+	fill(0, 0, width, height, background)
+
+	cols, rows, max := len(h.Counts), h.Rows(), h.Max()
+	for c, col := range h.Counts {
+		x0 := marginLeft + c*plotWidth/cols
+		x1 := marginLeft + (c+1)*plotWidth/cols
+		for r, count := range col {
+			// Row 0 is the lowest latency, drawn at the bottom
+			y0 := marginTop + (rows-r-1)*plotHeight/rows
+			y1 := marginTop + (rows-r)*plotHeight/rows
+			fill(x0, y0, x1, y1, colorFor(count, max))
+		}
+	}
+
+	// Legend: busiest at the top
+	lx := width - marginRight + 30
+	for y := 0; y < plotHeight; y++ {
+		c := colorFor(max*float64(plotHeight-y)/float64(plotHeight), max)
+		fill(lx, marginTop+y, lx+16, marginTop+y+1, c)
+	}
+	return png.Encode(w, img)
+}
+
+// WriteSVG writes a labelled heatmap with a tooltip on every cell
+func (h *Heatmap) WriteSVG(w io.Writer) error {
+	_, err := w.Write(h.svg())
+	return err
+}
+
+func (h *Heatmap) svg() []byte {
+	var b bytes.Buffer
+	cols, rows, max := len(h.Counts), h.Rows(), h.Max()
+	cw := float64(plotWidth) / float64(cols)
+	rh := float64(plotHeight) / float64(rows)
+
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="11">`+"\n",
+		width, height, width, height)
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="#ffffff"/>`+"\n", width, height)
+	fmt.Fprintf(&b, `<text x="%d" y="24" font-size="14" font-weight="bold">%s</text>`+"\n", marginLeft, html.EscapeString(h.Title))
+	fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%d" height="%d" fill="none" stroke="#999999"/>`+"\n",
+		marginLeft, marginTop, plotWidth, plotHeight)
+
+	for c, col := range h.Counts {
+		x := float64(marginLeft) + float64(c)*cw
+		at := formatElapsed(h.OffsetsSec[c])
+		for r, count := range col {
+			if count <= 0 {
+				continue
+			}
+			y := float64(marginTop) + float64(rows-r-1)*rh
+			lat := FormatUs(h.EdgesUs[r]) + "-" + FormatUs(h.EdgesUs[r+1])
+			fmt.Fprintf(&b, `<rect x="%.2f" y="%.2f" width="%.2f" height="%.2f" fill="%s" data-t="%s" data-l="%s" data-c="%.0f"><title>%s, %s: %.0f frames</title></rect>`+"\n",
+				x, y, cw+0.5, rh+0.5, hexColor(colorFor(count, max)), at, lat, count, at, lat, count)
+		}
+	}
+
+	// Time axis
+	step := (cols + maxTicks - 1) / maxTicks
+	for c := 0; c < cols; c += step {
+		x := float64(marginLeft) + float64(c)*cw
+		fmt.Fprintf(&b, `<line x1="%.2f" y1="%d" x2="%.2f" y2="%d" stroke="#999999"/>`+"\n", x, marginTop+plotHeight, x, marginTop+plotHeight+5)
+		fmt.Fprintf(&b, `<text x="%.2f" y="%d" text-anchor="middle">%s</text>`+"\n", x, marginTop+plotHeight+18, formatElapsed(h.OffsetsSec[c]))
+	}
+	fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="middle">Elapsed (h:mm:ss)</text>`+"\n", marginLeft+plotWidth/2, height-12)
+
+	// Latency axis
+	step = (rows + maxTicks - 1) / maxTicks
+	for r := 0; r <= rows; r += step {
+		y := float64(marginTop) + float64(rows-r)*rh
+		fmt.Fprintf(&b, `<line x1="%d" y1="%.2f" x2="%d" y2="%.2f" stroke="#999999"/>`+"\n", marginLeft-5, y, marginLeft, y)
+		fmt.Fprintf(&b, `<text x="%d" y="%.2f" text-anchor="end" dominant-baseline="middle">%s</text>`+"\n", marginLeft-8, y, FormatUs(h.EdgesUs[r]))
+	}
+	fmt.Fprintf(&b, `<text transform="translate(16 %d) rotate(-90)" text-anchor="middle">Latency</text>`+"\n", marginTop+plotHeight/2)
+
+	// Legend
+	lx := width - marginRight + 30
+	const steps = 32
+	for i := 0; i < steps; i++ {
+		y := float64(marginTop) + float64(i)*float64(plotHeight)/steps
+		c := colorFor(max*float64(steps-i)/steps, max)
+		fmt.Fprintf(&b, `<rect x="%d" y="%.2f" width="16" height="%.2f" fill="%s"/>`+"\n", lx, y, float64(plotHeight)/steps+0.5, hexColor(c))
+	}
+	fmt.Fprintf(&b, `<text x="%d" y="%d">%.0f</text>`+"\n", lx+20, marginTop+10, max)
+	fmt.Fprintf(&b, `<text x="%d" y="%d">1</text>`+"\n", lx+20, marginTop+plotHeight)
+	fmt.Fprintf(&b, `<text x="%d" y="%d">frames</text>`+"\n", lx, marginTop-8)
+
+	b.WriteString("</svg>\n")
+	return b.Bytes()
+}
+
+var pageTemplate = template.Must(template.New("heatmap").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 24px; }
+#readout { height: 1.5em; margin-top: 8px; font-family: monospace; }
+svg rect[data-c]:hover { stroke: #000000; stroke-width: 1; }
+</style>
+</head>
+<body>
+{{.SVG}}
+<div id="readout">Hover over a cell for its time, latency range and frame count</div>
+<p>Start {{.Start}}; one column per trial. Counts are estimated from each trial's latency percentiles.</p>
+<script>
+document.querySelectorAll("svg rect[data-c]").forEach(function (cell) {
+  cell.addEventListener("mouseover", function () {
+    document.getElementById("readout").textContent =
+      "+" + cell.dataset.t + "  " + cell.dataset.l + "  " + cell.dataset.c + " frames";
+  });
+});
+</script>
+</body>
+</html>
+`))
+
+// WriteHTML writes a self-contained page with the SVG heatmap and a live
+// readout of the cell under the pointer
+func (h *Heatmap) WriteHTML(w io.Writer) error {
+	return pageTemplate.Execute(w, struct {
+		Title string
+		Start string
+		SVG   template.HTML
+	}{h.Title, h.Start.Format("2006-01-02 15:04:05 MST"), template.HTML(h.svg())})
+}
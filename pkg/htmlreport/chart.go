@@ -0,0 +1,171 @@
+package htmlreport
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"math"
+)
+
+// ChartKind selects how series are drawn
+type ChartKind int
+
+// Chart kinds
+const (
+	ChartBar  ChartKind = iota // Grouped bars, one group per category
+	ChartLine                  // One line per series
+)
+
+// Chart is a small chart over categorical x values, such as frame sizes
+type Chart struct {
+	Title      string
+	Kind       ChartKind
+	XLabel     string
+	YLabel     string
+	Categories []string
+	Series     []Series
+}
+
+// Series is one named set of values, one per category. NaN marks a
+// category the series has no value for.
+type Series struct {
+	Name   string
+	Values []float64
+}
+
+// Series colours, in order
+var palette = []string{"#1565c0", "#ef6c00", "#2e7d32", "#6a1b9a", "#c62828", "#00838f", "#8d6e63", "#546e7a"}
+
+// Chart layout, in pixels
+const (
+	chartWidth   = 760
+	chartHeight  = 300
+	chartLeft    = 70
+	chartRight   = 150
+	chartTop     = 36
+	chartBottom  = 48
+	chartPlotW   = chartWidth - chartLeft - chartRight
+	chartPlotH   = chartHeight - chartTop - chartBottom
+	chartYTicks  = 5
+	chartMaxTick = 12 // Most category labels drawn before thinning
+)
+
+// niceCeil rounds v up to 1, 2, 2.5 or 5 times a power of ten, so axis
+// ticks land on round numbers
+func niceCeil(v float64) float64 {
+	if v <= 0 {
+		return 1
+	}
+	mag := math.Pow(10, math.Floor(math.Log10(v)))
+	for _, m := range []float64{1, 2, 2.5, 5, 10} {
+		if v <= m*mag*(1+1e-9) {
+			return m * mag
+		}
+	}
+	return 10 * mag
+}
+
+// formatTick formats an axis value without trailing zeros
+func formatTick(v float64) string {
+	switch {
+	case v == math.Trunc(v):
+		return fmt.Sprintf("%.0f", v)
+	case math.Abs(v) >= 1:
+		return fmt.Sprintf("%.1f", v)
+	}
+	return fmt.Sprintf("%.3g", v)
+}
+
+// svg draws the chart. Values are read from zero up, since throughput and
+// latency are compared against zero rather than against each other.
+func (c Chart) svg() []byte {
+	var b bytes.Buffer
+	esc := html.EscapeString
+
+	max := 0.0
+	for _, s := range c.Series {
+		for _, v := range s.Values {
+			if !math.IsNaN(v) {
+				max = math.Max(max, v)
+			}
+		}
+	}
+	top := niceCeil(max)
+	cats := len(c.Categories)
+	if cats == 0 {
+		cats = 1
+	}
+	cw := float64(chartPlotW) / float64(cats)
+	y := func(v float64) float64 { return chartTop + chartPlotH - v/top*chartPlotH }
+
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="11" role="img">`+"\n",
+		chartWidth, chartHeight, chartWidth, chartHeight)
+	fmt.Fprintf(&b, `<text x="%d" y="20" font-size="13" font-weight="bold">%s</text>`+"\n", chartLeft, esc(c.Title))
+
+	// Horizontal grid and value axis
+	for i := 0; i <= chartYTicks; i++ {
+		v := top * float64(i) / chartYTicks
+		fmt.Fprintf(&b, `<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" stroke="#e0e0e0"/>`+"\n", chartLeft, y(v), chartLeft+chartPlotW, y(v))
+		fmt.Fprintf(&b, `<text x="%d" y="%.1f" text-anchor="end" dominant-baseline="middle">%s</text>`+"\n", chartLeft-6, y(v), formatTick(v))
+	}
+	fmt.Fprintf(&b, `<text transform="translate(16 %d) rotate(-90)" text-anchor="middle">%s</text>`+"\n", chartTop+chartPlotH/2, esc(c.YLabel))
+
+	// Category axis, thinned when crowded
+	step := (len(c.Categories) + chartMaxTick - 1) / chartMaxTick
+	for i, cat := range c.Categories {
+		if step > 1 && i%step != 0 {
+			continue
+		}
+		x := chartLeft + (float64(i)+0.5)*cw
+		fmt.Fprintf(&b, `<text x="%.1f" y="%d" text-anchor="middle">%s</text>`+"\n", x, chartTop+chartPlotH+16, esc(cat))
+	}
+	fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="middle">%s</text>`+"\n", chartLeft+chartPlotW/2, chartHeight-8, esc(c.XLabel))
+
+	for si, s := range c.Series {
+		color := palette[si%len(palette)]
+		switch c.Kind {
+		case ChartBar:
+			bw := cw * 0.8 / float64(len(c.Series))
+			for i, v := range s.Values {
+				if i >= len(c.Categories) || math.IsNaN(v) {
+					continue
+				}
+				x := chartLeft + float64(i)*cw + cw*0.1 + float64(si)*bw
+				fmt.Fprintf(&b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s"><title>%s %s: %s</title></rect>`+"\n",
+					x, y(v), bw, chartTop+chartPlotH-y(v), color, esc(s.Name), esc(c.Categories[i]), formatValue(v))
+			}
+		case ChartLine:
+			var path bytes.Buffer
+			pen := "M"
+			for i, v := range s.Values {
+				if i >= len(c.Categories) || math.IsNaN(v) {
+					pen = "M" // Gap: lift the pen
+					continue
+				}
+				x := chartLeft + (float64(i)+0.5)*cw
+				fmt.Fprintf(&path, "%s%.1f %.1f ", pen, x, y(v))
+				pen = "L"
+				fmt.Fprintf(&b, `<circle cx="%.1f" cy="%.1f" r="3" fill="%s"><title>%s %s: %s</title></circle>`+"\n",
+					x, y(v), color, esc(s.Name), esc(c.Categories[i]), formatValue(v))
+			}
+			fmt.Fprintf(&b, `<path d="%s" fill="none" stroke="%s" stroke-width="2"/>`+"\n", path.String(), color)
+		}
+
+		// Legend
+		ly := chartTop + 4 + si*18
+		fmt.Fprintf(&b, `<rect x="%d" y="%d" width="12" height="12" fill="%s"/>`+"\n", chartLeft+chartPlotW+16, ly, color)
+		fmt.Fprintf(&b, `<text x="%d" y="%d">%s</text>`+"\n", chartLeft+chartPlotW+34, ly+10, esc(s.Name))
+	}
+
+	fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%d" height="%d" fill="none" stroke="#999999"/>`+"\n", chartLeft, chartTop, chartPlotW, chartPlotH)
+	b.WriteString("</svg>")
+	return b.Bytes()
+}
+
+// formatValue formats a value for tooltips
+func formatValue(v float64) string {
+	if v == math.Trunc(v) && math.Abs(v) < 1e15 {
+		return fmt.Sprintf("%.0f", v)
+	}
+	return fmt.Sprintf("%.4g", v)
+}
@@ -0,0 +1,236 @@
+// Package htmlreport renders a test run as one self-contained HTML page
+// for handing to customers: a pass/fail banner, the test configuration,
+// and a table and charts per test
+//
+// The page carries its own styles and draws charts as inline SVG, so it
+// needs no network access, opens from an email attachment and prints as it
+// looks on screen. The page is built from plain tables and series;
+// Sections and the other layout functions turn the results of each test
+// into them.
+package htmlreport
+
+import (
+	"html/template"
+	"io"
+	"time"
+)
+
+// Verdict is the outcome of a section, table row or the whole report
+type Verdict int
+
+// Verdicts
+const (
+	VerdictNone Verdict = iota // No pass criteria apply
+	VerdictPass
+	VerdictFail
+)
+
+// String returns the banner text for v
+func (v Verdict) String() string {
+	switch v {
+	case VerdictPass:
+		return "PASS"
+	case VerdictFail:
+		return "FAIL"
+	}
+	return ""
+}
+
+// Class returns the CSS class the page styles v with
+func (v Verdict) Class() string {
+	switch v {
+	case VerdictPass:
+		return "pass"
+	case VerdictFail:
+		return "fail"
+	}
+	return "none"
+}
+
+// Of returns VerdictPass or VerdictFail
+func Of(pass bool) Verdict {
+	if pass {
+		return VerdictPass
+	}
+	return VerdictFail
+}
+
+// Report is a whole run
+type Report struct {
+	Title     string
+	Subtitle  string // e.g. the DUT
+	Generated time.Time
+	Config    []Field  // Test configuration, in display order
+	Caveats   []string // Shown under the verdict, e.g. methodology deviations
+	Figures   []Figure
+	Sections  []Section
+}
+
+// Figure is a preformatted text drawing, e.g. the wiring, with numbered
+// notes under it
+type Figure struct {
+	Title string
+	Lines []string
+	Notes []string
+}
+
+// Field is one name/value line of the configuration table
+type Field struct {
+	Name  string
+	Value string
+}
+
+// Section is one test, or one group of checks, with its own verdict
+type Section struct {
+	Title   string
+	Verdict Verdict
+	Detail  string // Shown next to the verdict, e.g. why it failed
+	Tables  []Table
+	Charts  []Chart
+}
+
+// Table is a captioned table of preformatted cells
+type Table struct {
+	Caption  string
+	Header   []string
+	Rows     [][]string
+	Verdicts []Verdict // Per row; rows beyond the slice have none
+}
+
+// verdict returns the verdict of row i
+func (t Table) verdict(i int) Verdict {
+	if i < len(t.Verdicts) {
+		return t.Verdicts[i]
+	}
+	return VerdictNone
+}
+
+// Verdict combines the section verdicts: failed if any section failed,
+// passed if any passed, and none if no section has pass criteria
+func (r *Report) Verdict() Verdict {
+	v := VerdictNone
+	for _, s := range r.Sections {
+		switch s.Verdict {
+		case VerdictFail:
+			return VerdictFail
+		case VerdictPass:
+			v = VerdictPass
+		}
+	}
+	return v
+}
+
+var pageTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"chart":   func(c Chart) template.HTML { return template.HTML(c.svg()) },
+	"verdict": func(t Table, i int) Verdict { return t.verdict(i) },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; color: #222222; margin: 0 auto; max-width: 1000px; padding: 24px; }
+h1 { margin-bottom: 4px; }
+h2 { border-bottom: 1px solid #dddddd; padding-bottom: 4px; margin-top: 36px; }
+.subtitle, .generated { color: #666666; margin: 2px 0; }
+.banner { border-radius: 6px; color: #ffffff; font-size: 20px; font-weight: bold; margin: 20px 0; padding: 14px 20px; }
+.banner.pass { background: #2e7d32; }
+.banner.fail { background: #c62828; }
+.banner.none { background: #546e7a; }
+.badge { border-radius: 4px; color: #ffffff; font-size: 13px; margin-left: 8px; padding: 2px 8px; vertical-align: middle; }
+.badge.pass { background: #2e7d32; }
+.badge.fail { background: #c62828; }
+.detail { color: #555555; }
+.caveats { background: #fff8e1; border: 1px solid #ffb300; border-radius: 6px; margin: 20px 0; padding: 10px 20px; }
+.caveats ul { margin: 6px 0; padding-left: 20px; }
+table { border-collapse: collapse; margin: 12px 0; width: 100%; }
+caption { font-weight: bold; padding: 6px 0; text-align: left; }
+th, td { border: 1px solid #dddddd; padding: 5px 8px; text-align: right; }
+th:first-child, td:first-child { text-align: left; }
+th { background: #f3f3f3; }
+table.config td:last-child { text-align: left; }
+tr.pass td:last-child { color: #2e7d32; font-weight: bold; }
+tr.fail td { background: #fdecea; }
+tr.fail td:last-child { color: #c62828; font-weight: bold; }
+.charts svg { display: block; margin: 16px 0; max-width: 100%; height: auto; }
+pre.figure { background: #f7f7f7; border: 1px solid #dddddd; border-radius: 4px; font-size: 13px; overflow-x: auto; padding: 12px; }
+@media print { .section { page-break-inside: avoid; } }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{- if .Subtitle}}
+<p class="subtitle">{{.Subtitle}}</p>
+{{- end}}
+<p class="generated">Generated {{.Generated.Format "2006-01-02 15:04:05 MST"}}</p>
+<div class="banner {{.Verdict.Class}}">{{with .Verdict.String}}Overall result: {{.}}{{else}}Informational run: no pass criteria apply{{end}}</div>
+{{- if .Caveats}}
+<div class="caveats"><strong>Caveats</strong>
+<ul>
+{{- range .Caveats}}
+<li>{{.}}</li>
+{{- end}}
+</ul>
+</div>
+{{- end}}
+{{- if .Config}}
+<h2>Test configuration</h2>
+<table class="config">
+{{- range .Config}}
+<tr><td>{{.Name}}</td><td>{{.Value}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- range .Figures}}
+<h2>{{.Title}}</h2>
+<pre class="figure">
+{{- range .Lines}}
+{{.}}
+{{- end}}
+</pre>
+{{- if .Notes}}
+<ol>
+{{- range .Notes}}
+<li>{{.}}</li>
+{{- end}}
+</ol>
+{{- end}}
+{{- end}}
+{{- range .Sections}}
+<div class="section">
+<h2>{{.Title}}{{if .Verdict.String}} <span class="badge {{.Verdict.Class}}">{{.Verdict}}</span>{{end}}</h2>
+{{- if .Detail}}
+<p class="detail">{{.Detail}}</p>
+{{- end}}
+{{- range $t := .Tables}}
+<table>
+{{- if $t.Caption}}
+<caption>{{$t.Caption}}</caption>
+{{- end}}
+<tr>{{range $t.Header}}<th>{{.}}</th>{{end}}</tr>
+{{- range $i, $row := $t.Rows}}
+<tr class="{{(verdict $t $i).Class}}">{{range $row}}<td>{{.}}</td>{{end}}</tr>
+{{- end}}
+</table>
+{{- end}}
+{{- if .Charts}}
+<div class="charts">
+{{- range .Charts}}
+{{chart .}}
+{{- end}}
+</div>
+{{- end}}
+</div>
+{{- end}}
+</body>
+</html>
+`))
+
+// Write renders the report as a complete HTML page
+func (r *Report) Write(w io.Writer) error {
+	if r.Generated.IsZero() {
+		r.Generated = time.Now()
+	}
+	return pageTemplate.Execute(w, r)
+}
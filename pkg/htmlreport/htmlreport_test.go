@@ -0,0 +1,149 @@
+package htmlreport
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerdict(t *testing.T) {
+	tests := []struct {
+		name     string
+		sections []Verdict
+		want     Verdict
+	}{
+		{"no sections", nil, VerdictNone},
+		{"informational only", []Verdict{VerdictNone, VerdictNone}, VerdictNone},
+		{"passed", []Verdict{VerdictNone, VerdictPass}, VerdictPass},
+		{"one failure", []Verdict{VerdictPass, VerdictFail, VerdictPass}, VerdictFail},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Report{}
+			for _, v := range tt.sections {
+				r.Sections = append(r.Sections, Section{Verdict: v})
+			}
+			if got := r.Verdict(); got != tt.want {
+				t.Errorf("Verdict() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNiceCeil(t *testing.T) {
+	tests := []struct{ in, want float64 }{
+		{0, 1},
+		{0.3, 0.5},
+		{1, 1},
+		{7.3, 10},
+		{100, 100},
+		{180, 200},
+		{230, 250},
+		{9412.5, 10000},
+	}
+	for _, tt := range tests {
+		if got := niceCeil(tt.in); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("niceCeil(%v) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	r := &Report{
+		Title:     "RFC 2544 Test Report",
+		Subtitle:  "Acme <R1>",
+		Generated: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+		Config:    []Field{{"Interface", "eth0"}, {"Trial duration", "60s"}},
+		Caveats:   []string{"RFC 2544 24: trial duration < 60s"},
+		Figures:   []Figure{{Title: "Wiring", Lines: []string{"| eth0 | <==> | DUT |"}, Notes: []string{"Connect eth0"}}},
+		Sections: []Section{
+			{
+				Title:   "Throughput",
+				Verdict: VerdictNone,
+				Tables: []Table{{
+					Header: []string{"Frame size", "Rate %"},
+					Rows:   [][]string{{"64", "98.50"}, {"1518", "100.00"}},
+				}},
+				Charts: []Chart{{
+					Title:      "Throughput",
+					Kind:       ChartBar,
+					Categories: []string{"64", "1518"},
+					Series:     []Series{{Name: "Rate %", Values: []float64{98.5, 100}}},
+				}},
+			},
+			{
+				Title:   "Y.1564 service 1",
+				Verdict: VerdictFail,
+				Detail:  "FD above threshold",
+				Tables: []Table{{
+					Header:   []string{"Step", "Result"},
+					Rows:     [][]string{{"1", "PASS"}, {"2", "FAIL"}},
+					Verdicts: []Verdict{VerdictPass, VerdictFail},
+				}},
+				Charts: []Chart{{
+					Title:      "Frame delay",
+					Kind:       ChartLine,
+					Categories: []string{"1", "2", "3"},
+					Series:     []Series{{Name: "FD <ms>", Values: []float64{1, math.NaN(), 3}}},
+				}},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := r.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	page := buf.String()
+
+	for _, want := range []string{
+		"<!DOCTYPE html>",
+		`<div class="banner fail">Overall result: FAIL</div>`,
+		"Acme &lt;R1&gt;",
+		"<tr><td>Trial duration</td><td>60s</td></tr>",
+		`<span class="badge fail">FAIL</span>`,
+		`<tr class="fail"><td>2</td><td>FAIL</td></tr>`,
+		`<tr class="none"><td>64</td><td>98.50</td></tr>`,
+		"FD &lt;ms&gt;", // Escaped inside the SVG
+		"2026-03-01 12:00:00 UTC",
+		"<pre class=\"figure\">\n| eth0 | &lt;==&gt; | DUT |\n</pre>",
+		"<li>Connect eth0</li>",
+		"<li>RFC 2544 24: trial duration &lt; 60s</li>",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page missing %q", want)
+		}
+	}
+	if n := strings.Count(page, "<svg"); n != 2 {
+		t.Errorf("page has %d charts, want 2", n)
+	}
+	if strings.Contains(page, "<script") || strings.Contains(page, "<link") {
+		t.Error("page should be self-contained")
+	}
+
+	// The NaN gap splits the line into two pen strokes
+	start := strings.Index(page, `<path d="`)
+	if start < 0 {
+		t.Fatal("line chart has no path")
+	}
+	d := page[start : start+strings.Index(page[start:], `" fill`)]
+	if strings.Count(d, "M") != 2 || strings.Contains(page, "NaN") {
+		t.Errorf("path %q should lift the pen over the missing value", d)
+	}
+}
+
+func TestWriteInformational(t *testing.T) {
+	r := &Report{Title: "Soak", Sections: []Section{{Title: "Soak", Verdict: VerdictNone}}}
+	var buf bytes.Buffer
+	if err := r.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `<div class="banner none">Informational run`) {
+		t.Error("expected an informational banner when no section has pass criteria")
+	}
+	if r.Generated.IsZero() {
+		t.Error("Write should stamp the generation time")
+	}
+}
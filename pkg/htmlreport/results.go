@@ -0,0 +1,773 @@
+package htmlreport
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/krisarmstrong/rfc2544-master/pkg/aqm"
+	"github.com/krisarmstrong/rfc2544-master/pkg/certify"
+	"github.com/krisarmstrong/rfc2544-master/pkg/config"
+	"github.com/krisarmstrong/rfc2544-master/pkg/dataplane"
+	"github.com/krisarmstrong/rfc2544-master/pkg/drift"
+	"github.com/krisarmstrong/rfc2544-master/pkg/dutconfig"
+	"github.com/krisarmstrong/rfc2544-master/pkg/flows"
+	"github.com/krisarmstrong/rfc2544-master/pkg/impair"
+	"github.com/krisarmstrong/rfc2544-master/pkg/microburst"
+	"github.com/krisarmstrong/rfc2544-master/pkg/prequal"
+	"github.com/krisarmstrong/rfc2544-master/pkg/qos"
+	"github.com/krisarmstrong/rfc2544-master/pkg/selfprof"
+	"github.com/krisarmstrong/rfc2544-master/pkg/udpecho"
+)
+
+// Composite is a result holding several tests at one frame size, as a
+// suite or a characterization does
+type Composite interface {
+	// Tests are the tests it holds, in the order the report shows them
+	Tests() []config.TestType
+	// Result is one test's result in the form the standalone test stores
+	// it, or nil if the test has none
+	Result(t config.TestType) interface{}
+	// Failures are the tests that failed to run or were skipped
+	Failures() []Failure
+}
+
+// Failure is a test of a Composite that has no result, and why
+type Failure struct {
+	FrameSize uint32
+	Test      config.TestType
+	Error     string
+}
+
+// FlowFailuresSection reports the flows a flow failure policy acted on
+func FlowFailuresSection(fr flows.Report) Section {
+	t := Table{Header: []string{"Time", "Flow", "Action", "Detail"}}
+	for _, e := range fr.Events {
+		t.Rows = append(t.Rows, []string{e.Time.Format("15:04:05"), fmt.Sprintf("%d", e.FlowID), e.Action, e.Detail})
+	}
+	return Section{
+		Title:   "Flow failures",
+		Verdict: Of(len(fr.Failed) == 0),
+		Detail:  fmt.Sprintf("Policy %s: %d flow(s) failed", fr.Policy, len(fr.Failed)),
+		Tables:  []Table{t},
+	}
+}
+
+// ComplianceSection reports which of the RFC's recommendations the run
+// honored. Methodology deviations qualify the results; they do not fail
+// the DUT.
+func ComplianceSection(checks []config.ComplianceCheck) Section {
+	t := Table{Header: []string{"Section", "Recommendation", "Configured", "Honored"}}
+	for _, c := range checks {
+		t.Rows = append(t.Rows, []string{c.Section, c.Recommendation, c.Detail, yesNo(c.Honored)})
+		t.Verdicts = append(t.Verdicts, Of(c.Honored))
+	}
+	return Section{
+		Title:  "RFC 2544 methodology compliance",
+		Detail: "Deviations from the RFC's recommendations qualify the results above rather than failing the device.",
+		Tables: []Table{t},
+	}
+}
+
+// PreQualSection reports the reachability checks run before testing
+func PreQualSection(p *prequal.Report) Section {
+	t := Table{Header: []string{"Target", "Family", "Ping loss %", "RTT avg (ms)", "Hops", "Path MTU", "Reachable"}}
+	for _, tr := range p.Targets {
+		loss, rtt := "-", "-"
+		if tr.Ping != nil {
+			loss = fmt.Sprintf("%.1f", tr.Ping.LossPct)
+			rtt = fmt.Sprintf("%.2f", tr.Ping.AvgMs)
+		}
+		mtu := "-"
+		if tr.PathMTU > 0 {
+			mtu = fmt.Sprintf("%d", tr.PathMTU)
+		}
+		t.Rows = append(t.Rows, []string{tr.Target, tr.Family, loss, rtt, fmt.Sprintf("%d", len(tr.Route)), mtu, yesNo(tr.Reachable)})
+		t.Verdicts = append(t.Verdicts, Of(tr.Reachable))
+	}
+	return Section{Title: "Pre-qualification", Verdict: Of(p.Passed), Tables: []Table{t}}
+}
+
+// DUTConfigSection shows the DUT config diff. A change fails the section:
+// results taken across a reconfiguration are not comparable.
+func DUTConfigSection(r *dutconfig.Report) Section {
+	s := Section{Title: "DUT configuration"}
+	if !r.Changed {
+		s.Detail = fmt.Sprintf("Unchanged over the run (%d commands compared).", len(r.Before.Outputs))
+		return s
+	}
+	s.Verdict = VerdictFail
+	s.Detail = "The configuration changed while the tests ran; results may not be valid."
+	for _, c := range r.Changes {
+		t := Table{Caption: c.Command, Header: []string{"Diff"}}
+		for _, l := range c.Diff {
+			t.Rows = append(t.Rows, []string{l})
+			if !strings.HasPrefix(l, " ") && !strings.HasPrefix(l, "@@") {
+				t.Verdicts = append(t.Verdicts, VerdictFail)
+			} else {
+				t.Verdicts = append(t.Verdicts, VerdictNone)
+			}
+		}
+		s.Tables = append(s.Tables, t)
+	}
+	return s
+}
+
+// SelfProfileSection shows the tester's own CPU and memory use over the run
+func SelfProfileSection(p *selfprof.Report) Section {
+	const mib = 1 << 20
+	t := Table{
+		Header: []string{"CPUs", "CPU avg %", "CPU peak %", "Peak RSS (MiB)", "Peak Go heap (MiB)", "Max goroutines"},
+		Rows: [][]string{{
+			fmt.Sprintf("%d", p.NumCPU), fmt.Sprintf("%.1f", p.AvgCPUPct), fmt.Sprintf("%.1f", p.PeakCPUPct),
+			fmt.Sprintf("%.1f", float64(p.PeakRSSBytes)/mib), fmt.Sprintf("%.1f", float64(p.PeakHeapBytes)/mib), fmt.Sprintf("%d", p.MaxGoroutines),
+		}},
+	}
+	chart := Chart{Title: "Tester CPU", Kind: ChartLine, XLabel: "Elapsed", YLabel: "CPU % (100 = one core)"}
+	cpu := Series{Name: "CPU %"}
+	for _, s := range p.Samples {
+		chart.Categories = append(chart.Categories, s.Time.Sub(p.Samples[0].Time).Truncate(time.Second).String())
+		cpu.Values = append(cpu.Values, s.CPUPct)
+	}
+	chart.Series = []Series{cpu}
+	return Section{
+		Title:  "Tester resources",
+		Detail: "CPU and memory used by the tester itself, including the dataplane threads.",
+		Tables: []Table{t},
+		Charts: []Chart{chart},
+	}
+}
+
+// CertificationSections lays out a certification report, one section per
+// RFC section with the reporting fields Section 26 asks for
+func CertificationSections(r *certify.Report) []Section {
+	f := func(format string, args ...interface{}) string { return fmt.Sprintf(format, args...) }
+	section := func(id string, s certify.Section, t Table) Section {
+		detail := s.Procedure
+		if len(s.Notes) > 0 {
+			detail += ". Incomplete: " + strings.Join(s.Notes, "; ")
+		}
+		return Section{Title: id + " " + s.Title, Verdict: Of(s.Complete), Detail: detail, Tables: []Table{t}}
+	}
+
+	params := Table{Header: []string{"Section", "Parameter", "Value"}}
+	for _, p := range r.Parameters {
+		params.Rows = append(params.Rows, []string{p.Section, p.Name, p.Value})
+	}
+	sections := []Section{{Title: "Certification parameters", Detail: "The RFC's recommended parameters the run used", Tables: []Table{params}}}
+	if len(r.Overrides) > 0 {
+		t := Table{Header: []string{"Setting", "Configured", "Certification"}}
+		for _, o := range r.Overrides {
+			t.Rows = append(t.Rows, []string{o.Setting, o.From, o.To})
+		}
+		sections[0].Tables = append(sections[0].Tables, t)
+	}
+
+	t := Table{Header: []string{"Frame Size", "Frames/s", "Theoretical Frames/s", "Rate %", "Mbps", "Trials"}}
+	for _, row := range r.Throughput.Rows {
+		t.Rows = append(t.Rows, []string{f("%d", row.FrameSize), f("%.0f", row.FramesPerSec), f("%.0f", row.TheoreticalFPS), f("%.2f", row.RatePct), f("%.2f", row.Mbps), f("%d", row.Trials)})
+	}
+	sections = append(sections, section("26.1", r.Throughput.Section, t))
+
+	t = Table{Caption: r.Latency.Definition, Header: []string{"Frame Size", "Rate %", "Avg (us)", "Std Dev (us)", "Min (us)", "Max (us)", "Trials"}}
+	for _, row := range r.Latency.Rows {
+		t.Rows = append(t.Rows, []string{f("%d", row.FrameSize), f("%.2f", row.RatePct), f("%.2f", row.AvgUs), f("%.2f", row.StdDevUs), f("%.2f", row.MinUs), f("%.2f", row.MaxUs), f("%d", len(row.Trials))})
+	}
+	sections = append(sections, section("26.2", r.Latency.Section, t))
+
+	t = Table{Header: []string{"Frame Size", "Offered %", "Frames TX", "Frames RX", "Loss %"}}
+	for _, row := range r.FrameLoss.Rows {
+		for _, trial := range row.Trials {
+			t.Rows = append(t.Rows, []string{f("%d", row.FrameSize), f("%.1f", trial.OfferedPct), f("%d", trial.FramesTx), f("%d", trial.FramesRx), f("%.4f", trial.LossPct)})
+		}
+	}
+	sections = append(sections, section("26.3", r.FrameLoss.Section, t))
+
+	t = Table{Header: []string{"Frame Size", "Burst Frames", "Burst (us)", "Trials"}}
+	for _, row := range r.BackToBack.Rows {
+		t.Rows = append(t.Rows, []string{f("%d", row.FrameSize), f("%d", row.BurstFrames), f("%.0f", row.BurstUs), f("%d", row.Trials)})
+	}
+	sections = append(sections, section("26.4", r.BackToBack.Section, t))
+
+	t = Table{Header: []string{"Frame Size", "Throughput %", "Overload %", "Overload (s)", "Recovery (ms)", "Frames Lost"}}
+	for _, row := range r.Recovery.Rows {
+		t.Rows = append(t.Rows, []string{f("%d", row.FrameSize), f("%.2f", row.ThroughputPct), f("%.2f", row.OverloadPct), f("%d", row.OverloadSec), f("%.2f", row.RecoveryMs), f("%d", row.FramesLost)})
+	}
+	sections = append(sections, section("26.5", r.Recovery.Section, t))
+
+	t = Table{Header: []string{"Frame Size", "Reset", "Reset Time (ms)", "Frames Lost"}}
+	for _, row := range r.Reset.Rows {
+		t.Rows = append(t.Rows, []string{f("%d", row.FrameSize), row.ResetType, f("%.2f", row.ResetMs), f("%d", row.FramesLost)})
+	}
+	return append(sections, section("26.6", r.Reset.Section, t))
+}
+
+// Sections lays out the results of one test type: a table per test, with
+// charts where the results have a shape worth seeing. It returns nil for
+// results it has no layout for.
+func Sections(results []interface{}, testType config.TestType) []Section {
+	us := func(ns float64) string { return fmt.Sprintf("%.2f", ns/1000) }
+
+	switch testType {
+	case config.TestThroughput:
+		s := Section{Title: "Throughput (Section 26.1)"}
+		t := Table{Header: []string{"Frame size", "Rate %", "Mbit/s", "Frames/s", "Iterations", "Acceptable loss %", "Latency min (us)", "Latency avg (us)", "Latency max (us)"}}
+		var sizes, rates []string
+		var rate, mbps, lmin, lavg, lmax []float64
+		// Every search iteration, by frame size then offered rate
+		trialLoss := map[string]map[string]float64{}
+		trialLatency := map[string]map[string]float64{}
+		measured := false
+		for _, r := range results {
+			if tr, ok := r.(*dataplane.ThroughputResultCLI); ok {
+				size := fmt.Sprintf("%d", tr.FrameSize)
+				for _, trial := range tr.Trials {
+					if trialLoss[size] == nil {
+						trialLoss[size], trialLatency[size] = map[string]float64{}, map[string]float64{}
+					}
+					offered := fmt.Sprintf("%.2f", trial.RatePct)
+					if !containsString(rates, offered) {
+						rates = append(rates, offered)
+					}
+					trialLoss[size][offered] = trial.LossPct
+					if trial.Latency.Count > 0 {
+						trialLatency[size][offered] = trial.Latency.AvgNs / 1000
+						measured = true
+					}
+				}
+				t.Rows = append(t.Rows, []string{
+					fmt.Sprintf("%d", tr.FrameSize),
+					fmt.Sprintf("%.2f", tr.MaxRatePct),
+					fmt.Sprintf("%.2f", tr.MaxRateMbps),
+					fmt.Sprintf("%.0f", tr.MaxRatePPS),
+					fmt.Sprintf("%d", tr.Iterations),
+					fmt.Sprintf("%.4g", tr.AcceptableLossPct),
+					us(tr.Latency.MinNs), us(tr.Latency.AvgNs), us(tr.Latency.MaxNs),
+				})
+				sizes = append(sizes, fmt.Sprintf("%d", tr.FrameSize))
+				rate = append(rate, tr.MaxRatePct)
+				mbps = append(mbps, tr.MaxRateMbps)
+				lmin, lavg, lmax = append(lmin, tr.Latency.MinNs/1000), append(lavg, tr.Latency.AvgNs/1000), append(lmax, tr.Latency.MaxNs/1000)
+			}
+		}
+		if len(t.Rows) == 0 {
+			return nil
+		}
+		s.Tables = append(s.Tables, t)
+		s.Charts = []Chart{
+			{Title: "Throughput by frame size", Kind: ChartBar, XLabel: "Frame size (bytes)", YLabel: "% of line rate",
+				Categories: sizes, Series: []Series{{Name: "Rate %", Values: rate}}},
+			{Title: "Throughput in Mbit/s", Kind: ChartBar, XLabel: "Frame size (bytes)", YLabel: "Mbit/s",
+				Categories: sizes, Series: []Series{{Name: "Mbit/s", Values: mbps}}},
+			{Title: "Latency at throughput", Kind: ChartBar, XLabel: "Frame size (bytes)", YLabel: "Latency (us)",
+				Categories: sizes, Series: []Series{{Name: "Min", Values: lmin}, {Name: "Avg", Values: lavg}, {Name: "Max", Values: lmax}}},
+		}
+		if len(rates) > 0 {
+			sort.Slice(rates, func(i, j int) bool {
+				a, _ := strconv.ParseFloat(rates[i], 64)
+				b, _ := strconv.ParseFloat(rates[j], 64)
+				return a < b
+			})
+			loss := Chart{Title: "Loss by offered rate in the search", Kind: ChartLine,
+				XLabel: "Offered rate (% of line rate)", YLabel: "Loss %", Categories: rates}
+			latency := Chart{Title: "Latency by offered rate in the search", Kind: ChartLine,
+				XLabel: "Offered rate (% of line rate)", YLabel: "Latency avg (us)", Categories: rates}
+			for _, size := range sizes {
+				if trialLoss[size] == nil {
+					continue
+				}
+				name := size + " bytes"
+				loss.Series = append(loss.Series, Series{Name: name, Values: seriesValues(rates, trialLoss[size])})
+				latency.Series = append(latency.Series, Series{Name: name, Values: seriesValues(rates, trialLatency[size])})
+			}
+			s.Charts = append(s.Charts, loss)
+			if measured {
+				s.Charts = append(s.Charts, latency)
+			}
+		}
+		return []Section{s}
+
+	case config.TestLatency:
+		t := Table{Header: []string{"Frame size", "Load %", "Min (us)", "Avg (us)", "Max (us)", "Jitter (us)", "P50 (us)", "P95 (us)", "P99 (us)"}}
+		var sizes []string
+		var loads []float64
+		avg := map[float64]map[string]float64{}
+		for _, r := range results {
+			lrs, ok := r.([]dataplane.LatencyResultCLI)
+			if !ok {
+				continue
+			}
+			for _, lr := range lrs {
+				l := lr.Latency
+				t.Rows = append(t.Rows, []string{
+					fmt.Sprintf("%d", lr.FrameSize), fmt.Sprintf("%.1f", lr.LoadPct),
+					us(l.MinNs), us(l.AvgNs), us(l.MaxNs), us(l.JitterNs), us(l.P50Ns), us(l.P95Ns), us(l.P99Ns),
+				})
+				size := fmt.Sprintf("%d", lr.FrameSize)
+				if len(sizes) == 0 || sizes[len(sizes)-1] != size {
+					sizes = append(sizes, size)
+				}
+				if avg[lr.LoadPct] == nil {
+					avg[lr.LoadPct] = map[string]float64{}
+					loads = append(loads, lr.LoadPct)
+				}
+				avg[lr.LoadPct][size] = l.AvgNs / 1000
+			}
+		}
+		if len(t.Rows) == 0 {
+			return nil
+		}
+		chart := Chart{Title: "Average latency by frame size", Kind: ChartLine,
+			XLabel: "Frame size (bytes)", YLabel: "Latency (us)", Categories: sizes}
+		for _, load := range loads {
+			chart.Series = append(chart.Series, Series{Name: fmt.Sprintf("%.0f%% load", load), Values: seriesValues(sizes, avg[load])})
+		}
+		return []Section{{Title: "Latency (Section 26.2)", Tables: []Table{t}, Charts: []Chart{chart}}}
+
+	case config.TestFrameLoss:
+		t := Table{Header: []string{"Frame size", "Offered %", "Frames TX", "Frames RX", "Loss %"}}
+		var offered, sizes []string
+		loss := map[string]map[string]float64{}
+		for _, r := range results {
+			flrs, ok := r.([]dataplane.FrameLossResultCLI)
+			if !ok {
+				continue
+			}
+			for _, fl := range flrs {
+				t.Rows = append(t.Rows, []string{
+					fmt.Sprintf("%d", fl.FrameSize), fmt.Sprintf("%.1f", fl.OfferedPct),
+					fmt.Sprintf("%d", fl.FramesTx), fmt.Sprintf("%d", fl.FramesRx), fmt.Sprintf("%.4f", fl.LossPct),
+				})
+				size, load := fmt.Sprintf("%d bytes", fl.FrameSize), fmt.Sprintf("%.0f", fl.OfferedPct)
+				if loss[size] == nil {
+					loss[size] = map[string]float64{}
+					sizes = append(sizes, size)
+				}
+				loss[size][load] = fl.LossPct
+				if !containsString(offered, load) {
+					offered = append(offered, load)
+				}
+			}
+		}
+		if len(t.Rows) == 0 {
+			return nil
+		}
+		chart := Chart{Title: "Frame loss by offered load", Kind: ChartLine,
+			XLabel: "Offered load (% of line rate)", YLabel: "Loss %", Categories: offered}
+		for _, size := range sizes {
+			chart.Series = append(chart.Series, Series{Name: size, Values: seriesValues(offered, loss[size])})
+		}
+		return []Section{{Title: "Frame loss rate (Section 26.3)", Tables: []Table{t}, Charts: []Chart{chart}}}
+
+	case config.TestBackToBack:
+		t := Table{Header: []string{"Frame size", "Max burst (frames)", "Burst duration (us)", "Trials"}}
+		var sizes []string
+		var burst []float64
+		for _, r := range results {
+			if br, ok := r.(*dataplane.BackToBackResultCLI); ok {
+				t.Rows = append(t.Rows, []string{
+					fmt.Sprintf("%d", br.FrameSize), fmt.Sprintf("%d", br.MaxBurstFrames),
+					fmt.Sprintf("%d", br.BurstDurationUs), fmt.Sprintf("%d", br.Trials),
+				})
+				sizes = append(sizes, fmt.Sprintf("%d", br.FrameSize))
+				burst = append(burst, float64(br.MaxBurstFrames))
+			}
+		}
+		if len(t.Rows) == 0 {
+			return nil
+		}
+		chart := Chart{Title: "Longest loss-free burst", Kind: ChartBar, XLabel: "Frame size (bytes)", YLabel: "Frames",
+			Categories: sizes, Series: []Series{{Name: "Burst", Values: burst}}}
+		return []Section{{Title: "Back-to-back frames (Section 26.4)", Tables: []Table{t}, Charts: []Chart{chart}}}
+
+	case config.TestSystemRecovery:
+		t := Table{Header: []string{"Frame size", "Overload %", "Recovery %", "Overload (s)", "Recovery time (ms)", "Frames lost", "Trials"}}
+		var sizes []string
+		var ms []float64
+		for _, r := range results {
+			if rr, ok := r.(*dataplane.RecoveryResultCLI); ok {
+				t.Rows = append(t.Rows, []string{
+					fmt.Sprintf("%d", rr.FrameSize), fmt.Sprintf("%.1f", rr.OverloadRatePct), fmt.Sprintf("%.1f", rr.RecoveryRatePct),
+					fmt.Sprintf("%d", rr.OverloadSec), fmt.Sprintf("%.2f", rr.RecoveryTimeMs), fmt.Sprintf("%d", rr.FramesLost), fmt.Sprintf("%d", rr.Trials),
+				})
+				sizes = append(sizes, fmt.Sprintf("%d", rr.FrameSize))
+				ms = append(ms, rr.RecoveryTimeMs)
+			}
+		}
+		if len(t.Rows) == 0 {
+			return nil
+		}
+		chart := Chart{Title: "Recovery time", Kind: ChartBar, XLabel: "Frame size (bytes)", YLabel: "ms",
+			Categories: sizes, Series: []Series{{Name: "Recovery", Values: ms}}}
+		return []Section{{Title: "System recovery (Section 26.5)", Tables: []Table{t}, Charts: []Chart{chart}}}
+
+	case config.TestReset:
+		t := Table{Header: []string{"Frame size", "Reset time (ms)", "Frames lost", "Trials", "Manual reset"}}
+		for _, r := range results {
+			if rr, ok := r.(*dataplane.ResetResultCLI); ok {
+				t.Rows = append(t.Rows, []string{
+					fmt.Sprintf("%d", rr.FrameSize), fmt.Sprintf("%.2f", rr.ResetTimeMs),
+					fmt.Sprintf("%d", rr.FramesLost), fmt.Sprintf("%d", rr.Trials), yesNo(rr.ManualReset),
+				})
+			}
+		}
+		if len(t.Rows) == 0 {
+			return nil
+		}
+		return []Section{{Title: "Reset (Section 26.6)", Tables: []Table{t}}}
+
+	case config.TestCharacterize:
+		return compositeSections(results, "Characterization errors")
+
+	case config.TestSuite:
+		return compositeSections(results, "Suite errors")
+
+	case config.TestSoak:
+		var sections []Section
+		for _, r := range results {
+			sr, ok := r.(*drift.Report)
+			if !ok {
+				continue
+			}
+			t := Table{Header: []string{"Bucket start", "Samples", "Mbit/s", "Loss %", "P50 (us)", "P95 (us)", "P99 (us)"}}
+			var at []string
+			var tput, p50, p99 []float64
+			for _, b := range sr.Buckets {
+				t.Rows = append(t.Rows, []string{
+					b.Start.Format("15:04:05"), fmt.Sprintf("%d", b.Samples), fmt.Sprintf("%.2f", b.ThroughputMbps),
+					fmt.Sprintf("%.4f", b.LossPct), fmt.Sprintf("%.2f", b.P50Us), fmt.Sprintf("%.2f", b.P95Us), fmt.Sprintf("%.2f", b.P99Us),
+				})
+				at = append(at, b.Start.Format("15:04"))
+				tput, p50, p99 = append(tput, b.ThroughputMbps), append(p50, b.P50Us), append(p99, b.P99Us)
+			}
+			s := Section{
+				Title:  fmt.Sprintf("Soak, %d-byte frames at %.1f%%", sr.FrameSize, sr.RatePct),
+				Detail: fmt.Sprintf("%s (throughput drift %+.2f%%, P99 drift %+.1f%%)", sr.Summary.Verdict, sr.Summary.ThroughputDriftPct, sr.Summary.P99DriftPct),
+				Tables: []Table{t},
+				Charts: []Chart{
+					{Title: "Throughput per bucket", Kind: ChartLine, XLabel: "Bucket start", YLabel: "Mbit/s",
+						Categories: at, Series: []Series{{Name: "Mbit/s", Values: tput}}},
+					{Title: "Latency per bucket", Kind: ChartLine, XLabel: "Bucket start", YLabel: "Latency (us)",
+						Categories: at, Series: []Series{{Name: "P50", Values: p50}, {Name: "P99", Values: p99}}},
+				},
+			}
+			if len(sr.Changes) > 0 {
+				c := Table{Caption: "Reconfigured while running; drift is measured since the last change", Header: []string{"Time", "From sample", "Rate %", "Frame size"}}
+				for _, ch := range sr.Changes {
+					c.Rows = append(c.Rows, []string{ch.Time.Format("15:04:05"), fmt.Sprintf("%d", ch.Sample), fmt.Sprintf("%.1f", ch.RatePct), fmt.Sprintf("%d", ch.FrameSize)})
+				}
+				s.Tables = append(s.Tables, c)
+			}
+			// Drift needs two buckets to compare
+			if sr.Summary.Buckets >= 2 {
+				s.Verdict = Of(!sr.Summary.Degraded)
+			}
+			sections = append(sections, s)
+		}
+		return sections
+
+	case config.TestQoS:
+		var sections []Section
+		for _, r := range results {
+			qr, ok := r.(*qos.Report)
+			if !ok {
+				continue
+			}
+			t := Table{Header: []string{"Class", "PCP", "DSCP", "Offered %", "Offered Mbit/s", "Delivered Mbit/s", "Share %", "Loss %", "Avg (us)", "Max (us)", "Jitter (us)"}}
+			var names []string
+			var offered, share, loss []float64
+			for _, c := range qr.Classes {
+				t.Rows = append(t.Rows, []string{
+					c.Name, fmt.Sprintf("%d", c.PCP), fmt.Sprintf("%d", c.DSCP), fmt.Sprintf("%.2f", c.OfferedSharePct),
+					fmt.Sprintf("%.2f", c.OfferedMbps), fmt.Sprintf("%.2f", c.DeliveredMbps), fmt.Sprintf("%.2f", c.DeliveredSharePct),
+					fmt.Sprintf("%.4f", c.LossPct), fmt.Sprintf("%.2f", c.Latency.AvgNs/1000), fmt.Sprintf("%.2f", c.Latency.MaxNs/1000),
+					fmt.Sprintf("%.2f", c.Latency.JitterNs/1000),
+				})
+				names = append(names, c.Name)
+				offered, share, loss = append(offered, c.OfferedSharePct), append(share, c.DeliveredSharePct), append(loss, c.LossPct)
+			}
+			sections = append(sections, Section{
+				Title:  fmt.Sprintf("QoS matrix, %d-byte frames at %.1f%%", qr.FrameSize, qr.LoadPct),
+				Detail: fmt.Sprintf("Classes marked by %s; %.2f Mbit/s delivered, %.4f%% lost overall.", qr.Marking, qr.DeliveredMbps, qr.LossPct),
+				Tables: []Table{t},
+				Charts: []Chart{
+					{Title: "Share per class", Kind: ChartBar, XLabel: "Class", YLabel: "% of frames",
+						Categories: names, Series: []Series{{Name: "Offered", Values: offered}, {Name: "Delivered", Values: share}}},
+					{Title: "Loss per class", Kind: ChartBar, XLabel: "Class", YLabel: "Loss %",
+						Categories: names, Series: []Series{{Name: "Loss %", Values: loss}}},
+				},
+			})
+		}
+		return sections
+
+	case config.TestAQM:
+		var sections []Section
+		for _, r := range results {
+			ar, ok := r.(*aqm.Report)
+			if !ok {
+				continue
+			}
+			t := Table{Header: []string{"Load %", "Loss %", "Avg (us)", "P99 (us)", "Queue (us)"}}
+			var loads []string
+			var loss, queue []float64
+			for _, st := range ar.Steps {
+				t.Rows = append(t.Rows, []string{
+					fmt.Sprintf("%.1f", st.LoadPct), fmt.Sprintf("%.4f", st.LossPct), fmt.Sprintf("%.2f", st.AvgUs),
+					fmt.Sprintf("%.2f", st.P99Us), fmt.Sprintf("%.2f", st.QueueUs),
+				})
+				loads = append(loads, fmt.Sprintf("%.1f", st.LoadPct))
+				loss, queue = append(loss, st.LossPct), append(queue, st.QueueUs)
+			}
+			sections = append(sections, Section{
+				Title:  fmt.Sprintf("AQM, %d-byte frames", ar.FrameSize),
+				Detail: fmt.Sprintf("%s (baseline latency %.2f us).", ar.Summary.Verdict, ar.Summary.BaselineUs),
+				Tables: []Table{t},
+				Charts: []Chart{
+					{Title: "Loss vs load", Kind: ChartLine, XLabel: "Offered load %", YLabel: "Loss %",
+						Categories: loads, Series: []Series{{Name: "Loss %", Values: loss}}},
+					{Title: "Queueing delay vs load", Kind: ChartLine, XLabel: "Offered load %", YLabel: "Delay (us)",
+						Categories: loads, Series: []Series{{Name: "Queue", Values: queue}}},
+				},
+			})
+		}
+		return sections
+
+	case config.TestMicroburst:
+		var sections []Section
+		for _, r := range results {
+			mr, ok := r.(*microburst.Report)
+			if !ok {
+				continue
+			}
+			t := Table{Header: []string{"Gap", "Lossless burst", "First loss", "Duty cycle %", "Egress (Mbps)", "Buffer (frames)", "Buffer (bytes)", "Buffer (us)", "Verdict"}}
+			var charts []Chart
+			for _, g := range mr.Gaps {
+				s := g.Summary
+				t.Rows = append(t.Rows, []string{
+					g.Gap.String(), fmt.Sprintf("%d", s.MaxLosslessFrames), fmt.Sprintf("%d", s.FirstLossFrames),
+					fmt.Sprintf("%.1f", s.DutyCyclePct), fmt.Sprintf("%.1f", s.DrainMbps), fmt.Sprintf("%.0f", s.BufferFrames),
+					fmt.Sprintf("%.0f", s.BufferBytes), fmt.Sprintf("%.1f", s.BufferUs), s.Verdict,
+				})
+				if lt := g.FirstLossTrain(); lt != nil {
+					var names []string
+					var loss []float64
+					for i, b := range lt.Bursts {
+						names = append(names, fmt.Sprintf("%d", i+1))
+						loss = append(loss, b.LossPct)
+					}
+					charts = append(charts, Chart{
+						Title: fmt.Sprintf("Loss per burst, %d-frame bursts, %v gap", lt.Frames, g.Gap), Kind: ChartBar,
+						XLabel: "Burst", YLabel: "Loss %", Categories: names, Series: []Series{{Name: "Loss %", Values: loss}},
+					})
+				}
+			}
+			sections = append(sections, Section{
+				Title:  fmt.Sprintf("Microburst, %d-byte frames", mr.FrameSize),
+				Detail: fmt.Sprintf("Trains of %d bursts at %.1f%% of line rate.", mr.Bursts, mr.RatePct),
+				Tables: []Table{t},
+				Charts: charts,
+			})
+		}
+		return sections
+
+	case config.TestSelfTest:
+		var sections []Section
+		for _, r := range results {
+			sr, ok := r.(*impair.Report)
+			if !ok {
+				continue
+			}
+			t := Table{Header: []string{"Case", "Netem", "Latency (us)", "Expected", "Jitter (us)", "Expected", "Loss %", "Expected", "Reordered", "Result"}}
+			for _, c := range sr.Cases {
+				verdict := "Pass"
+				if !c.Passed {
+					verdict = "Fail: " + strings.Join(c.Failures, "; ")
+				}
+				t.Rows = append(t.Rows, []string{
+					c.Name, c.Netem.String(),
+					fmt.Sprintf("%.1f", c.Measured.AvgUs), fmt.Sprintf("%.1f", c.Expected.AvgUs),
+					fmt.Sprintf("%.1f", c.Measured.JitterUs), fmt.Sprintf("%.1f", c.Expected.JitterUs),
+					fmt.Sprintf("%.3f", c.Measured.LossPct), fmt.Sprintf("%.3f", c.Expected.LossPct),
+					fmt.Sprintf("%d", c.Measured.Reordered), verdict,
+				})
+			}
+			b := sr.Baseline
+			sections = append(sections, Section{
+				Title: fmt.Sprintf("Self-test, %d-byte frames", sr.FrameSize),
+				Detail: fmt.Sprintf("Netem on %s. Baseline latency %.1f us, jitter %.1f us, loss %.3f%%.",
+					sr.Interface, b.AvgUs, b.JitterUs, b.LossPct),
+				Tables: []Table{t},
+			})
+		}
+		return sections
+
+	case config.TestUDPEcho:
+		t := Table{Header: []string{"Frame size", "Rate (pps)", "Sent", "Received", "Loss %", "Reordered", "Duplicates", "Min (us)", "Avg (us)", "Max (us)", "Jitter (us)", "P99 (us)"}}
+		var sizes, flags []string
+		var avg, p99 []float64
+		for _, r := range results {
+			if ur, ok := r.(*udpecho.Result); ok {
+				t.Rows = append(t.Rows, []string{
+					fmt.Sprintf("%d", ur.FrameSize), fmt.Sprintf("%d", ur.RatePPS), fmt.Sprintf("%d", ur.Sent), fmt.Sprintf("%d", ur.Received),
+					fmt.Sprintf("%.4f", ur.LossPct), fmt.Sprintf("%d", ur.Reordered), fmt.Sprintf("%d", ur.Duplicates),
+					fmt.Sprintf("%.2f", ur.MinUs), fmt.Sprintf("%.2f", ur.AvgUs), fmt.Sprintf("%.2f", ur.MaxUs), fmt.Sprintf("%.2f", ur.JitterUs), fmt.Sprintf("%.2f", ur.P99Us),
+				})
+				sizes = append(sizes, fmt.Sprintf("%d", ur.FrameSize))
+				avg, p99 = append(avg, ur.AvgUs), append(p99, ur.P99Us)
+				for _, f := range ur.ReducedAccuracy {
+					if !containsString(flags, f) {
+						flags = append(flags, f)
+					}
+				}
+			}
+		}
+		if len(t.Rows) == 0 {
+			return nil
+		}
+		chart := Chart{Title: "Round-trip time", Kind: ChartBar, XLabel: "Frame size (bytes)", YLabel: "RTT (us)",
+			Categories: sizes, Series: []Series{{Name: "Avg", Values: avg}, {Name: "P99", Values: p99}}}
+		return []Section{{Title: "UDP echo", Detail: "Reduced accuracy: " + strings.Join(flags, ", "),
+			Tables: []Table{t}, Charts: []Chart{chart}}}
+
+	case config.TestY1564Config, config.TestY1564Perf, config.TestY1564Full:
+		// One section per service, in the order the services ran
+		var sections []Section
+		index := map[uint32]int{}
+		section := func(id uint32) *Section {
+			i, ok := index[id]
+			if !ok {
+				i = len(sections)
+				index[id] = i
+				sections = append(sections, Section{
+					Title:   fmt.Sprintf("Y.1564 service %d", id),
+					Verdict: VerdictPass,
+					Tables:  []Table{{Header: []string{"Phase", "Offered %", "FLR %", "FD avg (ms)", "FD max (ms)", "FDV (ms)", "Result"}}},
+				})
+			}
+			return &sections[i]
+		}
+		for _, r := range results {
+			switch yr := r.(type) {
+			case *dataplane.Y1564ConfigResult:
+				s := section(yr.ServiceID)
+				var steps []string
+				var fd []float64
+				for _, step := range yr.Steps {
+					s.Tables[0].Rows = append(s.Tables[0].Rows, []string{
+						fmt.Sprintf("Config step %d (%s)", step.Step, step.Phase), fmt.Sprintf("%.0f", step.OfferedRatePct), fmt.Sprintf("%.4f", step.FLRPct),
+						fmt.Sprintf("%.2f", step.FDAvgMs), fmt.Sprintf("%.2f", step.FDMaxMs), fmt.Sprintf("%.2f", step.FDVMs), passFail(step.StepPass),
+					})
+					s.Tables[0].Verdicts = append(s.Tables[0].Verdicts, Of(step.StepPass))
+					steps = append(steps, fmt.Sprintf("%.0f%%", step.OfferedRatePct))
+					fd = append(fd, step.FDAvgMs)
+				}
+				s.Charts = append(s.Charts, Chart{Title: "Frame delay per configuration step", Kind: ChartBar,
+					XLabel: "Offered load (% of CIR)", YLabel: "FD avg (ms)", Categories: steps, Series: []Series{{Name: "FD", Values: fd}}})
+				if !yr.ServicePass {
+					s.Verdict = VerdictFail
+				}
+			case *dataplane.Y1564PerfResult:
+				s := section(yr.ServiceID)
+				s.Tables[0].Rows = append(s.Tables[0].Rows, []string{
+					fmt.Sprintf("Performance (%ds)", yr.DurationSec), "100", fmt.Sprintf("%.4f", yr.FLRPct),
+					fmt.Sprintf("%.2f", yr.FDAvgMs), fmt.Sprintf("%.2f", yr.FDMaxMs), fmt.Sprintf("%.2f", yr.FDVMs), passFail(yr.ServicePass),
+				})
+				s.Tables[0].Verdicts = append(s.Tables[0].Verdicts, Of(yr.ServicePass))
+				if !yr.ServicePass {
+					s.Verdict = VerdictFail
+				}
+			}
+		}
+		return sections
+
+	case config.TestRFC2889Forwarding:
+		t := Table{Caption: "One flow, TX port to RX port, measured per pattern", Header: []string{"Frame size", "Pattern", "Rate %", "Mbit/s", "Loss %"}}
+		for _, r := range results {
+			if f, ok := r.(*dataplane.RFC2889ForwardingResult); ok {
+				t.Rows = append(t.Rows, []string{
+					fmt.Sprintf("%d", f.FrameSize), f.Pattern.String(),
+					fmt.Sprintf("%.2f", f.MaxRatePct), fmt.Sprintf("%.2f", f.RateMbps), fmt.Sprintf("%.4f", f.LossPct),
+				})
+			}
+		}
+		if len(t.Rows) == 0 {
+			return nil
+		}
+		return []Section{{Title: "RFC 2889 forwarding rate", Tables: []Table{t}}}
+	}
+	return nil
+}
+
+// compositeSections lays out each test of the composites in turn, as the
+// standalone test would, then the tests that failed or were skipped
+func compositeSections(results []interface{}, errorsTitle string) []Section {
+	var composites []Composite
+	for _, r := range results {
+		if c, ok := r.(Composite); ok {
+			composites = append(composites, c)
+		}
+	}
+	if len(composites) == 0 {
+		return nil
+	}
+
+	var sections []Section
+	for _, t := range composites[0].Tests() {
+		var section []interface{}
+		for _, c := range composites {
+			if tr := c.Result(t); tr != nil {
+				section = append(section, tr)
+			}
+		}
+		sections = append(sections, Sections(section, t)...)
+	}
+	errs := Table{Header: []string{"Frame size", "Test", "Error"}}
+	for _, c := range composites {
+		for _, f := range c.Failures() {
+			errs.Rows = append(errs.Rows, []string{fmt.Sprintf("%d", f.FrameSize), string(f.Test), f.Error})
+		}
+	}
+	if len(errs.Rows) > 0 {
+		sections = append(sections, Section{Title: errorsTitle, Detail: "Tests that failed to run or were skipped.", Tables: []Table{errs}})
+	}
+	return sections
+}
+
+// seriesValues orders values by category, with NaN where one is missing
+func seriesValues(categories []string, values map[string]float64) []float64 {
+	out := make([]float64, len(categories))
+	for i, c := range categories {
+		v, ok := values[c]
+		if !ok {
+			v = math.NaN()
+		}
+		out[i] = v
+	}
+	return out
+}
+
+func containsString(list []string, s string) bool {
+	for _, have := range list {
+		if have == s {
+			return true
+		}
+	}
+	return false
+}
+
+func passFail(pass bool) string {
+	if pass {
+		return "PASS"
+	}
+	return "FAIL"
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
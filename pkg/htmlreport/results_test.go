@@ -0,0 +1,265 @@
+package htmlreport
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/krisarmstrong/rfc2544-master/pkg/aqm"
+	"github.com/krisarmstrong/rfc2544-master/pkg/certify"
+	"github.com/krisarmstrong/rfc2544-master/pkg/config"
+	"github.com/krisarmstrong/rfc2544-master/pkg/dataplane"
+	"github.com/krisarmstrong/rfc2544-master/pkg/drift"
+	"github.com/krisarmstrong/rfc2544-master/pkg/dutconfig"
+	"github.com/krisarmstrong/rfc2544-master/pkg/microburst"
+	"github.com/krisarmstrong/rfc2544-master/pkg/prequal"
+	"github.com/krisarmstrong/rfc2544-master/pkg/qos"
+	"github.com/krisarmstrong/rfc2544-master/pkg/udpecho"
+)
+
+// Fixture results, as the CLI stores them for each test type
+var (
+	throughput64 = &dataplane.ThroughputResultCLI{
+		FrameSize: 64, MaxRatePct: 98.5, MaxRateMbps: 985, MaxRatePPS: 1466071, Iterations: 2, AcceptableLossPct: 0,
+		Latency: dataplane.LatencyStats{Count: 10, MinNs: 1000, AvgNs: 2000, MaxNs: 4000},
+		Trials: []dataplane.ThroughputTrial{
+			{RatePct: 100, LossPct: 1.5},
+			{RatePct: 98.5, Passed: true, Latency: dataplane.LatencyStats{Count: 10, AvgNs: 2000}},
+		},
+	}
+	throughput1518 = &dataplane.ThroughputResultCLI{FrameSize: 1518, MaxRatePct: 100, MaxRateMbps: 1000, MaxRatePPS: 81274, Iterations: 1,
+		Trials: []dataplane.ThroughputTrial{{RatePct: 100, Passed: true}}}
+	latency = []dataplane.LatencyResultCLI{
+		{FrameSize: 64, LoadPct: 50, Latency: dataplane.LatencyStats{MinNs: 1000, AvgNs: 1500, MaxNs: 3000}},
+		{FrameSize: 64, LoadPct: 100, Latency: dataplane.LatencyStats{MinNs: 1000, AvgNs: 2500, MaxNs: 6000}},
+	}
+	frameLoss = []dataplane.FrameLossResultCLI{
+		{FrameSize: 64, OfferedPct: 100, FramesTx: 1000, FramesRx: 990, LossPct: 1},
+		{FrameSize: 64, OfferedPct: 90, FramesTx: 900, FramesRx: 900},
+	}
+	soak = &drift.Report{
+		FrameSize: 512, RatePct: 80, Samples: 4,
+		Buckets: []drift.Bucket{
+			{Start: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC), Samples: 2, ThroughputMbps: 800, P99Us: 10},
+			{Start: time.Date(2026, 3, 1, 12, 5, 0, 0, time.UTC), Samples: 2, ThroughputMbps: 700, P99Us: 30},
+		},
+		Summary: drift.Summary{Buckets: 2, ThroughputDriftPct: -12.5, P99DriftPct: 200, Degraded: true, Verdict: "Degraded"},
+	}
+	qosMatrix = &qos.Report{FrameSize: 512, Marking: config.MarkDSCP, LoadPct: 100, Classes: []qos.Class{
+		{Name: "voice", DSCP: 46, OfferedSharePct: 20, DeliveredSharePct: 25},
+		{Name: "best-effort", OfferedSharePct: 80, DeliveredSharePct: 75, LossPct: 6.25},
+	}}
+	aqmRamp = &aqm.Report{FrameSize: 512, Steps: []aqm.Step{{LoadPct: 90}, {LoadPct: 100, LossPct: 2, QueueUs: 50}}, Summary: aqm.Summary{Verdict: "WRED"}}
+	bursts  = &microburst.Report{FrameSize: 64, Bursts: 3, RatePct: 100, Gaps: []microburst.Gap{{
+		Gap: time.Millisecond,
+		Trains: []microburst.Train{
+			microburst.NewTrain(100, []microburst.Burst{{FramesTx: 100, FramesRx: 100}}),
+			microburst.NewTrain(200, []microburst.Burst{{FramesTx: 200, FramesRx: 200}, {FramesTx: 200, FramesRx: 150, LossPct: 25}}),
+		},
+		Summary: microburst.Summary{MaxLosslessFrames: 100, FirstLossFrames: 200},
+	}}}
+	echo = &udpecho.Result{FrameSize: 128, RatePPS: 1000, Sent: 100, Received: 100, AvgUs: 50, P99Us: 90, ReducedAccuracy: []string{"kernel timestamps"}}
+)
+
+// suite is a Composite as the CLI's suite stores one frame size
+type suite struct {
+	size       uint32
+	throughput *dataplane.ThroughputResultCLI
+	errs       map[config.TestType]string
+}
+
+func (s *suite) Tests() []config.TestType {
+	return []config.TestType{config.TestThroughput, config.TestLatency}
+}
+
+func (s *suite) Result(t config.TestType) interface{} {
+	if t == config.TestThroughput && s.throughput != nil {
+		return s.throughput
+	}
+	return nil
+}
+
+func (s *suite) Failures() []Failure {
+	var f []Failure
+	for _, t := range s.Tests() {
+		if msg, ok := s.errs[t]; ok {
+			f = append(f, Failure{FrameSize: s.size, Test: t, Error: msg})
+		}
+	}
+	return f
+}
+
+// laidOut is the shape of a section the tests check
+type laidOut struct {
+	Title    string
+	Verdict  Verdict
+	Rows     int
+	Charts   int
+	FirstRow []string
+}
+
+func shape(sections []Section) []laidOut {
+	var out []laidOut
+	for _, s := range sections {
+		l := laidOut{Title: s.Title, Verdict: s.Verdict, Charts: len(s.Charts)}
+		for _, t := range s.Tables {
+			l.Rows += len(t.Rows)
+		}
+		if len(s.Tables) > 0 && len(s.Tables[0].Rows) > 0 {
+			l.FirstRow = s.Tables[0].Rows[0]
+		}
+		out = append(out, l)
+	}
+	return out
+}
+
+func TestSections(t *testing.T) {
+	tests := []struct {
+		name     string
+		testType config.TestType
+		results  []interface{}
+		want     []laidOut
+	}{
+		{"throughput", config.TestThroughput, []interface{}{throughput64, throughput1518}, []laidOut{{
+			Title: "Throughput (Section 26.1)", Rows: 2, Charts: 5,
+			FirstRow: []string{"64", "98.50", "985.00", "1466071", "2", "0", "1.00", "2.00", "4.00"},
+		}}},
+		{"latency", config.TestLatency, []interface{}{latency}, []laidOut{{
+			Title: "Latency (Section 26.2)", Rows: 2, Charts: 1,
+			FirstRow: []string{"64", "50.0", "1.00", "1.50", "3.00", "0.00", "0.00", "0.00", "0.00"},
+		}}},
+		{"frame loss", config.TestFrameLoss, []interface{}{frameLoss}, []laidOut{{
+			Title: "Frame loss rate (Section 26.3)", Rows: 2, Charts: 1,
+			FirstRow: []string{"64", "100.0", "1000", "990", "1.0000"},
+		}}},
+		{"soak drift fails", config.TestSoak, []interface{}{soak}, []laidOut{{
+			Title: "Soak, 512-byte frames at 80.0%", Verdict: VerdictFail, Rows: 2, Charts: 2,
+			FirstRow: []string{"12:00:00", "2", "800.00", "0.0000", "0.00", "0.00", "10.00"},
+		}}},
+		{"reconfigured soak", config.TestSoak, []interface{}{&drift.Report{
+			FrameSize: 512, RatePct: 80, Buckets: soak.Buckets, Summary: drift.Summary{Buckets: 1, Verdict: "Insufficient data"},
+			Changes: []drift.Change{{Time: time.Date(2026, 3, 1, 12, 4, 0, 0, time.UTC), Sample: 2, RatePct: 40, FrameSize: 512}},
+		}}, []laidOut{{
+			Title: "Soak, 512-byte frames at 80.0%", Rows: 3, Charts: 2,
+			FirstRow: []string{"12:00:00", "2", "800.00", "0.0000", "0.00", "0.00", "10.00"},
+		}}},
+		{"qos", config.TestQoS, []interface{}{qosMatrix}, []laidOut{{
+			Title: "QoS matrix, 512-byte frames at 100.0%", Rows: 2, Charts: 2,
+			FirstRow: []string{"voice", "0", "46", "20.00", "0.00", "0.00", "25.00", "0.0000", "0.00", "0.00", "0.00"},
+		}}},
+		{"aqm", config.TestAQM, []interface{}{aqmRamp}, []laidOut{{
+			Title: "AQM, 512-byte frames", Rows: 2, Charts: 2,
+			FirstRow: []string{"90.0", "0.0000", "0.00", "0.00", "0.00"},
+		}}},
+		{"microburst charts the first lossy train", config.TestMicroburst, []interface{}{bursts}, []laidOut{{
+			Title: "Microburst, 64-byte frames", Rows: 1, Charts: 1,
+			FirstRow: []string{"1ms", "100", "200", "0.0", "0.0", "0", "0", "0.0", ""},
+		}}},
+		{"udp echo", config.TestUDPEcho, []interface{}{echo}, []laidOut{{
+			Title: "UDP echo", Rows: 1, Charts: 1,
+			FirstRow: []string{"128", "1000", "100", "100", "0.0000", "0", "0", "0.00", "50.00", "0.00", "0.00", "90.00"},
+		}}},
+		{"one section per Y.1564 service", config.TestY1564Full, []interface{}{
+			&dataplane.Y1564ConfigResult{ServiceID: 1, ServicePass: true, Steps: []dataplane.Y1564StepResult{{Step: 1, OfferedRatePct: 25, StepPass: true}}},
+			&dataplane.Y1564ConfigResult{ServiceID: 2, ServicePass: true},
+			&dataplane.Y1564PerfResult{ServiceID: 1, DurationSec: 60, FLRPct: 1},
+		}, []laidOut{
+			{Title: "Y.1564 service 1", Verdict: VerdictFail, Rows: 2, Charts: 1,
+				FirstRow: []string{"Config step 1 ()", "25", "0.0000", "0.00", "0.00", "0.00", "PASS"}},
+			{Title: "Y.1564 service 2", Verdict: VerdictPass, Charts: 1},
+		}},
+		{"rfc 2889", config.TestRFC2889Forwarding, []interface{}{&dataplane.RFC2889ForwardingResult{FrameSize: 64, Pattern: dataplane.PatternOneToMany, MaxRatePct: 50, RateMbps: 500}}, []laidOut{{
+			Title: "RFC 2889 forwarding rate", Rows: 1,
+			FirstRow: []string{"64", dataplane.PatternOneToMany.String(), "50.00", "500.00", "0.0000"},
+		}}},
+		{"suite lays out each test, then its errors", config.TestSuite, []interface{}{
+			&suite{size: 64, throughput: throughput64, errs: map[config.TestType]string{config.TestLatency: "skipped"}},
+			&suite{size: 1518, errs: map[config.TestType]string{config.TestThroughput: "timeout", config.TestLatency: "skipped"}},
+		}, []laidOut{
+			{Title: "Throughput (Section 26.1)", Rows: 1, Charts: 5, FirstRow: []string{"64", "98.50", "985.00", "1466071", "2", "0", "1.00", "2.00", "4.00"}},
+			{Title: "Suite errors", Rows: 3, FirstRow: []string{"64", "latency", "skipped"}},
+		}},
+		{"no results", config.TestThroughput, nil, nil},
+		{"results of another test", config.TestBackToBack, []interface{}{throughput64}, nil},
+		{"no layout", config.TestType("unknown"), []interface{}{throughput64}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := shape(Sections(tt.results, tt.testType)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Sections() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSectionsSearchCharts(t *testing.T) {
+	s := Sections([]interface{}{throughput64, throughput1518}, config.TestThroughput)[0]
+	loss := s.Charts[3]
+	if want := []string{"98.50", "100.00"}; !reflect.DeepEqual(loss.Categories, want) {
+		t.Fatalf("search rates = %v, want %v", loss.Categories, want)
+	}
+	// 1518 bytes never tried 98.5%, so its series has a gap there
+	if v := loss.Series[1].Values; !math.IsNaN(v[0]) || v[1] != 0 {
+		t.Errorf("1518-byte loss = %v, want [NaN 0]", v)
+	}
+	if lat := s.Charts[4]; lat.Series[0].Values[0] != 2 {
+		t.Errorf("64-byte latency at 98.5%% = %v us, want 2", lat.Series[0].Values[0])
+	}
+}
+
+func TestCertificationSections(t *testing.T) {
+	r := &certify.Report{
+		Parameters: []certify.Parameter{{Section: "24", Name: "Trial duration", Value: "60s"}},
+		Overrides:  []certify.Override{{Setting: "trial_duration", From: "10s", To: "60s"}},
+	}
+	r.Throughput.Section = certify.Section{Title: "Throughput", Procedure: "Binary search", Complete: true}
+	r.Throughput.Rows = []certify.ThroughputRow{{FrameSize: 64, FramesPerSec: 1488095, TheoreticalFPS: 1488095, RatePct: 100, Mbps: 1000, Trials: 20}}
+	r.Latency.Section = certify.Section{Title: "Latency", Procedure: "20 trials", Notes: []string{"not at 1518 bytes"}}
+
+	got := CertificationSections(r)
+	var titles []string
+	for _, s := range got {
+		titles = append(titles, s.Title)
+	}
+	want := []string{"Certification parameters", "26.1 Throughput", "26.2 Latency", "26.3 ", "26.4 ", "26.5 ", "26.6 "}
+	if !reflect.DeepEqual(titles, want) {
+		t.Fatalf("titles = %q, want %q", titles, want)
+	}
+	if n := len(got[0].Tables); n != 2 {
+		t.Errorf("parameters section has %d tables, want 2 with the overrides", n)
+	}
+	if got[1].Verdict != VerdictPass || got[2].Verdict != VerdictFail {
+		t.Errorf("verdicts = %v, %v; want complete sections to pass", got[1].Verdict, got[2].Verdict)
+	}
+	if want := "20 trials. Incomplete: not at 1518 bytes"; got[2].Detail != want {
+		t.Errorf("detail = %q, want %q", got[2].Detail, want)
+	}
+	if row := got[1].Tables[0].Rows[0]; !reflect.DeepEqual(row, []string{"64", "1488095", "1488095", "100.00", "1000.00", "20"}) {
+		t.Errorf("throughput row = %q", row)
+	}
+}
+
+func TestRunSections(t *testing.T) {
+	tests := []struct {
+		name    string
+		section Section
+		want    laidOut
+	}{
+		{"unreachable target", PreQualSection(&prequal.Report{Targets: []prequal.TargetResult{
+			{Target: "192.0.2.1", Family: "ipv4", Ping: &prequal.Ping{LossPct: 100}},
+		}}), laidOut{Title: "Pre-qualification", Verdict: VerdictFail, Rows: 1, FirstRow: []string{"192.0.2.1", "ipv4", "100.0", "0.00", "0", "-", "no"}}},
+		{"unchanged DUT", DUTConfigSection(&dutconfig.Report{}), laidOut{Title: "DUT configuration"}},
+		{"changed DUT", DUTConfigSection(&dutconfig.Report{Changed: true, Changes: []dutconfig.Change{{Command: "show run", Diff: []string{"@@ -1 +1 @@", "-mtu 1500", "+mtu 9000"}}}}),
+			laidOut{Title: "DUT configuration", Verdict: VerdictFail, Rows: 3, FirstRow: []string{"@@ -1 +1 @@"}}},
+		{"compliance informs only", ComplianceSection([]config.ComplianceCheck{{Section: "24", Recommendation: "60s trials", Detail: "10s"}}),
+			laidOut{Title: "RFC 2544 methodology compliance", Rows: 1, FirstRow: []string{"24", "60s trials", "10s", "no"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := shape([]Section{tt.section}); !reflect.DeepEqual(got, []laidOut{tt.want}) {
+				t.Errorf("section = %+v, want %+v", got[0], tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,223 @@
+// Package impair injects known impairments into a test path with Linux
+// netem and checks that the tester measures what was injected
+//
+// Each Case shapes the egress of one interface, normally the reflector's
+// end of a veth pair, with a tc netem qdisc adding delay, jitter, loss or
+// reordering. A trial measured through the impaired path is compared with
+// a baseline trial through the clean path: the added latency, jitter and
+// loss must match the impairment within a Tolerance, and reordering must
+// be detected when it was injected and not reported when it was not. The
+// suite gives users confidence in the numbers the tester reports and
+// maintainers a regression harness for the measurement code.
+package impair
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/krisarmstrong/rfc2544-master/pkg/internal/execrun"
+)
+
+// Netem is the impairment a netem qdisc applies to every frame
+type Netem struct {
+	Delay      time.Duration `yaml:"delay" json:"delay"`
+	Jitter     time.Duration `yaml:"jitter" json:"jitter,omitempty"`           // Uniform +/- around Delay
+	LossPct    float64       `yaml:"loss_pct" json:"loss_pct,omitempty"`       // Random loss
+	ReorderPct float64       `yaml:"reorder_pct" json:"reorder_pct,omitempty"` // Sent without the delay, overtaking earlier frames
+}
+
+// Validate checks the impairment is one netem can apply
+func (n Netem) Validate() error {
+	if n.Delay < 0 || n.Jitter < 0 {
+		return fmt.Errorf("delay and jitter must be >= 0")
+	}
+	if n.Jitter > n.Delay {
+		return fmt.Errorf("jitter %v exceeds the delay %v", n.Jitter, n.Delay)
+	}
+	if n.LossPct < 0 || n.LossPct > 100 || n.ReorderPct < 0 || n.ReorderPct > 100 {
+		return fmt.Errorf("loss_pct and reorder_pct must be 0-100")
+	}
+	if n.ReorderPct > 0 && n.Delay == 0 {
+		return fmt.Errorf("reordering needs a delay for the other frames")
+	}
+	if n == (Netem{}) {
+		return fmt.Errorf("no impairment")
+	}
+	return nil
+}
+
+// netemLimit is the queue length netem is given, in frames. The default
+// 1000 drops frames of a fast trial held for a long delay, which would be
+// reported as loss that was never injected.
+const netemLimit = 100000
+
+// Args are the netem arguments of tc qdisc
+func (n Netem) Args() []string {
+	args := []string{"netem", "limit", strconv.Itoa(netemLimit)}
+	if n.Delay > 0 {
+		args = append(args, "delay", tcTime(n.Delay))
+		if n.Jitter > 0 {
+			args = append(args, tcTime(n.Jitter))
+		}
+	}
+	if n.LossPct > 0 {
+		args = append(args, "loss", tcPct(n.LossPct))
+	}
+	if n.ReorderPct > 0 {
+		args = append(args, "reorder", tcPct(n.ReorderPct))
+	}
+	return args
+}
+
+// String shows the impairment as netem arguments
+func (n Netem) String() string {
+	return strings.Join(n.Args()[3:], " ")
+}
+
+// tcTime formats d in microseconds, which tc accepts at any size
+func tcTime(d time.Duration) string {
+	return strconv.FormatInt(d.Microseconds(), 10) + "us"
+}
+
+func tcPct(p float64) string {
+	return strconv.FormatFloat(p, 'f', -1, 64) + "%"
+}
+
+// Case is one impairment of the verification suite
+type Case struct {
+	Name  string `yaml:"name" json:"name"`
+	Netem `yaml:",inline"`
+}
+
+// DefaultSuite exercises each impairment on its own and then together
+var DefaultSuite = []Case{
+	{Name: "delay", Netem: Netem{Delay: 10 * time.Millisecond}},
+	{Name: "jitter", Netem: Netem{Delay: 10 * time.Millisecond, Jitter: 2 * time.Millisecond}},
+	{Name: "loss", Netem: Netem{LossPct: 1}},
+	{Name: "reorder", Netem: Netem{Delay: 5 * time.Millisecond, ReorderPct: 10}},
+	{Name: "combined", Netem: Netem{Delay: 20 * time.Millisecond, Jitter: 1 * time.Millisecond, LossPct: 2}},
+}
+
+// Injector impairs the egress of one interface with tc
+type Injector struct {
+	Interface string
+	Run       execrun.Runner // Tests replace it to avoid changing the host's qdiscs
+}
+
+// NewInjector creates an injector for iface using the system tc
+func NewInjector(iface string) *Injector {
+	return &Injector{Interface: iface, Run: execrun.Exec}
+}
+
+// Apply replaces the interface's root qdisc with netem applying n
+func (i *Injector) Apply(ctx context.Context, n Netem) error {
+	args := append([]string{"qdisc", "replace", "dev", i.Interface, "root"}, n.Args()...)
+	if _, err := i.Run(ctx, "tc", args...); err != nil {
+		return fmt.Errorf("tc %s: %w", strings.Join(args, " "), err)
+	}
+	return nil
+}
+
+// Clear restores the interface's default root qdisc. Clearing an
+// interface without netem is not an error.
+func (i *Injector) Clear(ctx context.Context) error {
+	out, err := i.Run(ctx, "tc", "qdisc", "show", "dev", i.Interface, "root")
+	if err != nil {
+		return fmt.Errorf("tc qdisc show dev %s: %w", i.Interface, err)
+	}
+	if !strings.Contains(string(out), "netem") {
+		return nil
+	}
+	if _, err := i.Run(ctx, "tc", "qdisc", "del", "dev", i.Interface, "root"); err != nil {
+		return fmt.Errorf("tc qdisc del dev %s root: %w", i.Interface, err)
+	}
+	return nil
+}
+
+// Measured is what one trial through the path reported
+type Measured struct {
+	Frames    uint64  `json:"frames"` // Received
+	AvgUs     float64 `json:"avg_us"`
+	JitterUs  float64 `json:"jitter_us"` // Mean absolute deviation
+	LossPct   float64 `json:"loss_pct"`
+	Reordered uint64  `json:"reordered"`
+}
+
+// Tolerance is how far a measurement may stray from the injected value
+type Tolerance struct {
+	DelayUs  float64 `yaml:"delay_us" json:"delay_us"`
+	JitterUs float64 `yaml:"jitter_us" json:"jitter_us"`
+	LossPct  float64 `yaml:"loss_pct" json:"loss_pct"`
+}
+
+// CaseResult is the verdict on one case
+type CaseResult struct {
+	Case     `json:"case"`
+	Measured Measured `json:"measured"`
+	Expected Measured `json:"expected"` // Reordered is 1 where reordering must be seen
+	Passed   bool     `json:"passed"`
+	Failures []string `json:"failures,omitempty"`
+}
+
+// Report is the outcome of the suite at one frame size
+type Report struct {
+	FrameSize uint32       `json:"frame_size"`
+	Interface string       `json:"interface"` // Where netem was applied
+	Tolerance Tolerance    `json:"tolerance"`
+	Baseline  Measured     `json:"baseline"`
+	Cases     []CaseResult `json:"cases"`
+	Passed    bool         `json:"passed"`
+}
+
+// Check compares a trial through the impaired path with the baseline.
+// The added latency is the netem delay on every frame reordering does not
+// send straight away. Uniform netem jitter of +/- J is a mean absolute
+// deviation of J/2, added in quadrature to the baseline's. Loss adds to
+// the baseline loss. Frames overtaking the delayed ones must show as
+// reordered; without jitter or reordering, no more frames may be
+// reordered than in the baseline.
+func Check(c Case, baseline, m Measured, tol Tolerance) CaseResult {
+	jitterUs := float64(c.Jitter.Microseconds()) / 2
+	r := CaseResult{
+		Case:     c,
+		Measured: m,
+		Expected: Measured{
+			Frames:   m.Frames,
+			AvgUs:    baseline.AvgUs + float64(c.Delay.Microseconds())*(100-c.ReorderPct)/100,
+			JitterUs: math.Hypot(baseline.JitterUs, jitterUs),
+			LossPct:  baseline.LossPct + c.LossPct*(100-baseline.LossPct)/100,
+		},
+	}
+	if c.ReorderPct > 0 {
+		r.Expected.Reordered = 1
+	}
+	if m.Frames == 0 {
+		r.Failures = append(r.Failures, "no frames received")
+		return r
+	}
+
+	if d := m.AvgUs - r.Expected.AvgUs; math.Abs(d) > tol.DelayUs {
+		r.Failures = append(r.Failures, fmt.Sprintf("latency %.1fus, expected %.1fus +/- %.1fus", m.AvgUs, r.Expected.AvgUs, tol.DelayUs))
+	}
+	// Reordering splits the latencies in two, which is no jitter to check
+	if c.ReorderPct == 0 {
+		if d := m.JitterUs - r.Expected.JitterUs; math.Abs(d) > tol.JitterUs {
+			r.Failures = append(r.Failures, fmt.Sprintf("jitter %.1fus, expected %.1fus +/- %.1fus", m.JitterUs, r.Expected.JitterUs, tol.JitterUs))
+		}
+	}
+	if d := m.LossPct - r.Expected.LossPct; math.Abs(d) > tol.LossPct {
+		r.Failures = append(r.Failures, fmt.Sprintf("loss %.3f%%, expected %.3f%% +/- %.3f%%", m.LossPct, r.Expected.LossPct, tol.LossPct))
+	}
+	switch {
+	case c.ReorderPct > 0 && m.Reordered == 0:
+		r.Failures = append(r.Failures, "injected reordering not detected")
+	case c.ReorderPct == 0 && c.Jitter == 0 && m.Reordered > baseline.Reordered:
+		r.Failures = append(r.Failures, fmt.Sprintf("%d frames reported reordered, none injected", m.Reordered-baseline.Reordered))
+	}
+	r.Passed = len(r.Failures) == 0
+	return r
+}
@@ -0,0 +1,107 @@
+package impair
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestArgs(t *testing.T) {
+	n := Netem{Delay: 10 * time.Millisecond, Jitter: 1500 * time.Microsecond, LossPct: 0.5, ReorderPct: 25}
+	if got := n.String(); got != "delay 10000us 1500us loss 0.5% reorder 25%" {
+		t.Errorf("String = %q", got)
+	}
+	if got := (Netem{LossPct: 2}).String(); got != "loss 2%" {
+		t.Errorf("loss only = %q", got)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, c := range DefaultSuite {
+		if err := c.Validate(); err != nil {
+			t.Errorf("%s: %v", c.Name, err)
+		}
+	}
+	bad := []Netem{
+		{},
+		{Delay: time.Millisecond, Jitter: 2 * time.Millisecond},
+		{ReorderPct: 10},
+		{LossPct: 101},
+	}
+	for _, n := range bad {
+		if err := n.Validate(); err == nil {
+			t.Errorf("%+v: expected error", n)
+		}
+	}
+}
+
+func TestInjector(t *testing.T) {
+	var cmds []string
+	qdisc := "qdisc noqueue 0: root refcnt 2"
+	inj := &Injector{Interface: "veth-rx", Run: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		cmds = append(cmds, name+" "+strings.Join(args, " "))
+		return []byte(qdisc), nil
+	}}
+
+	if err := inj.Clear(context.Background()); err != nil {
+		t.Fatalf("Clear: %v", err)
+	}
+	if len(cmds) != 1 {
+		t.Errorf("cleared without netem: %q", cmds)
+	}
+
+	if err := inj.Apply(context.Background(), Netem{Delay: time.Millisecond}); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if cmds[1] != "tc qdisc replace dev veth-rx root netem limit 100000 delay 1000us" {
+		t.Errorf("apply = %q", cmds[1])
+	}
+
+	qdisc = "qdisc netem 8001: root refcnt 2 limit 1000 delay 1ms"
+	if err := inj.Clear(context.Background()); err != nil {
+		t.Fatalf("Clear: %v", err)
+	}
+	if cmds[len(cmds)-1] != "tc qdisc del dev veth-rx root" {
+		t.Errorf("clear = %q", cmds)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	base := Measured{Frames: 10000, AvgUs: 50, JitterUs: 5}
+	tol := Tolerance{DelayUs: 500, JitterUs: 250, LossPct: 0.5}
+	jitter := Case{Name: "jitter", Netem: Netem{Delay: 10 * time.Millisecond, Jitter: 2 * time.Millisecond}}
+
+	r := Check(jitter, base, Measured{Frames: 10000, AvgUs: 10120, JitterUs: 980, Reordered: 40}, tol)
+	if !r.Passed {
+		t.Errorf("failures %v", r.Failures)
+	}
+	if r.Expected.AvgUs != 10050 {
+		t.Errorf("expected latency %v", r.Expected.AvgUs)
+	}
+
+	r = Check(jitter, base, Measured{Frames: 10000, AvgUs: 10120, JitterUs: 100}, tol)
+	if r.Passed || len(r.Failures) != 1 || !strings.Contains(r.Failures[0], "jitter") {
+		t.Errorf("missed jitter: %v", r.Failures)
+	}
+
+	loss := Case{Name: "loss", Netem: Netem{LossPct: 1}}
+	if r := Check(loss, base, Measured{Frames: 9700, AvgUs: 52, JitterUs: 5, LossPct: 3}, tol); r.Passed {
+		t.Error("3% loss passed for 1% injected")
+	}
+	if r := Check(loss, base, Measured{Frames: 9900, AvgUs: 52, JitterUs: 5, LossPct: 1, Reordered: 3}, tol); r.Passed {
+		t.Error("reordering without any injected passed")
+	}
+
+	reorder := Case{Name: "reorder", Netem: Netem{Delay: 5 * time.Millisecond, ReorderPct: 10}}
+	if r := Check(reorder, base, Measured{Frames: 10000, AvgUs: 4550, JitterUs: 900}, tol); r.Passed {
+		t.Error("undetected reordering passed")
+	}
+	if r := Check(reorder, base, Measured{Frames: 10000, AvgUs: 4550, JitterUs: 900, Reordered: 1000}, tol); !r.Passed {
+		t.Errorf("failures %v", r.Failures)
+	}
+
+	if r := Check(loss, base, Measured{}, tol); r.Passed {
+		t.Error("passed without frames")
+	}
+}
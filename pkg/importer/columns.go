@@ -0,0 +1,356 @@
+package importer
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// field is a measurement recognised in a column or key name
+type field int
+
+const (
+	fieldNone field = iota
+	fieldFrameSize
+	fieldLoad // Offered load, % of line rate or throughput
+	fieldRatePct
+	fieldRateMbps
+	fieldRatePPS
+	fieldLatencyMin // Latency fields are stored in ns
+	fieldLatencyAvg
+	fieldLatencyMax
+	fieldJitter
+	fieldLossPct
+	fieldFramesTx
+	fieldFramesRx
+	fieldBurstFrames
+	fieldBurstUs
+	fieldTrials
+)
+
+// Result kinds, named like the native test types
+const (
+	kindThroughput = "throughput"
+	kindLatency    = "latency"
+	kindFrameLoss  = "frame_loss"
+	kindBackToBack = "back_to_back"
+)
+
+// column is a classified column or key: value * scale is in the field's unit
+type column struct {
+	field field
+	scale float64
+}
+
+// Unit scales to Mbps, frames/s and ns
+var (
+	bitRateUnits = map[string]float64{
+		"bps": 1e-6, "kbps": 1e-3, "mbps": 1, "gbps": 1e3,
+	}
+	frameRateUnits = map[string]float64{
+		"fps": 1, "pps": 1, "kfps": 1e3, "kpps": 1e3, "mfps": 1e6, "mpps": 1e6,
+	}
+	timeUnits = map[string]float64{
+		"ns": 1, "us": 1e3, "ms": 1e6, "s": 1e9,
+	}
+	countUnits = map[string]bool{
+		"": true, "frames": true, "frame": true, "packets": true, "pkts": true,
+	}
+)
+
+// Spellings of units, normalised before lookup
+var unitAliases = strings.NewReplacer(
+	"µ", "u", "μ", "u", " ", "",
+	"/sec", "/s", "bit/s", "bps", "frames/s", "fps", "frame/s", "fps",
+	"packets/s", "pps", "pkts/s", "pps", "pkt/s", "pps",
+	"nsec", "ns", "usec", "us", "msec", "ms", "sec", "s",
+)
+
+func normalizeUnit(u string) string {
+	u = unitAliases.Replace(strings.ToLower(strings.TrimSpace(u)))
+	switch u {
+	case "mbit", "mb/s":
+		return "mbps"
+	case "gbit", "gb/s":
+		return "gbps"
+	case "kbit", "kb/s":
+		return "kbps"
+	case "percent", "pct":
+		return "%"
+	}
+	return u
+}
+
+func isKnownUnit(u string) bool {
+	if u == "%" || (u != "" && countUnits[u]) {
+		return true
+	}
+	_, br := bitRateUnits[u]
+	_, fr := frameRateUnits[u]
+	_, tu := timeUnits[u]
+	return br || fr || tu
+}
+
+// splitUnit separates "Avg Latency (us)", "Drop Rate [%]", "Throughput %"
+// and "tx_bps" into a normalised name and unit
+func splitUnit(header string) (name, unit string) {
+	h := strings.TrimSpace(header)
+	if i := strings.IndexAny(h, "(["); i >= 0 {
+		if j := strings.IndexAny(h[i:], ")]"); j > 0 {
+			unit = h[i+1 : i+j]
+			h = h[:i] + " " + h[i+j+1:]
+		}
+	} else if strings.HasSuffix(h, "%") {
+		unit, h = "%", strings.TrimSuffix(h, "%")
+	}
+	name = normalizeName(h)
+	unit = normalizeUnit(unit)
+
+	// Trailing unit word: "latency us", "tx bps"
+	if unit == "" {
+		if i := strings.LastIndex(name, " "); i >= 0 {
+			if u := normalizeUnit(name[i+1:]); isKnownUnit(u) && u != "" && !countUnits[u] {
+				name, unit = name[:i], u
+			}
+		}
+	}
+	return name, unit
+}
+
+// normalizeName lowercases a name and replaces punctuation with spaces
+func normalizeName(s string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			sb.WriteRune(r)
+		} else {
+			sb.WriteRune(' ')
+		}
+	}
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
+
+// hasWord reports whether name contains any of the words or phrases
+func hasWord(name string, words ...string) bool {
+	padded := " " + name + " "
+	for _, w := range words {
+		if strings.Contains(padded, " "+w+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+// classify recognises a column or key name
+func classify(header string) column {
+	name, unit := splitUnit(header)
+	switch {
+	case name == "":
+		return column{}
+	case hasWord(name, "cpu", "queue", "core", "per core"):
+		return column{}
+	case hasWord(name, "trials", "trial count", "number of trials"):
+		return column{field: fieldTrials, scale: 1}
+	case hasWord(name, "burst", "back to back", "b2b"):
+		if s, ok := timeUnits[unit]; ok {
+			return column{field: fieldBurstUs, scale: s / 1e3}
+		}
+		if hasWord(name, "duration", "time") {
+			return column{field: fieldBurstUs, scale: 1}
+		}
+		if countUnits[unit] {
+			return column{field: fieldBurstFrames, scale: 1}
+		}
+	case hasWord(name, "frame size", "framesize", "frame length", "packet size", "pkt size"),
+		name == "size", name == "length":
+		return column{field: fieldFrameSize, scale: 1}
+	case hasWord(name, "jitter", "delay variation", "pdv", "ipdv", "fdv"):
+		return timeColumn(fieldJitter, unit)
+	case hasWord(name, "latency", "delay"):
+		f := fieldLatencyAvg
+		if hasWord(name, "min", "minimum", "lowest", "best") {
+			f = fieldLatencyMin
+		} else if hasWord(name, "max", "maximum", "highest", "worst") {
+			f = fieldLatencyMax
+		}
+		return timeColumn(f, unit)
+	case hasWord(name, "loss", "drop", "lost", "flr"):
+		if unit == "%" || hasWord(name, "rate", "ratio", "percent", "percentage", "pct") {
+			return column{field: fieldLossPct, scale: 1}
+		}
+	case hasWord(name, "offered load", "load", "offered rate", "offered", "tested load"):
+		if unit == "%" || (unit == "" && !hasWord(name, "rate")) {
+			return column{field: fieldLoad, scale: 1}
+		}
+	case countUnits[unit] && hasWord(name, "tx", "sent", "transmitted", "opackets", "tx frames", "tx packets"):
+		if hasWord(name, "frames", "frame", "packets", "pkts", "count", "opackets") {
+			return column{field: fieldFramesTx, scale: 1}
+		}
+	case countUnits[unit] && hasWord(name, "rx", "received", "ipackets"):
+		if hasWord(name, "frames", "frame", "packets", "pkts", "count", "ipackets") {
+			return column{field: fieldFramesRx, scale: 1}
+		}
+	}
+
+	if s, ok := bitRateUnits[unit]; ok {
+		return column{field: fieldRateMbps, scale: s}
+	}
+	if s, ok := frameRateUnits[unit]; ok {
+		return column{field: fieldRatePPS, scale: s}
+	}
+	if unit == "%" && hasWord(name, "throughput", "rate", "utilization", "utilisation", "line rate", "max rate") {
+		return column{field: fieldRatePct, scale: 1}
+	}
+	return column{}
+}
+
+// timeColumn scales a latency column to ns; testers report µs by default
+func timeColumn(f field, unit string) column {
+	if s, ok := timeUnits[unit]; ok {
+		return column{field: f, scale: s}
+	}
+	if unit == "" {
+		return column{field: f, scale: 1e3}
+	}
+	return column{}
+}
+
+// kindHint recognises a test name in a section title or test column
+func kindHint(s string) string {
+	name := normalizeName(s)
+	switch {
+	case hasWord(name, "back to back", "burst", "b2b", "burstability"):
+		return kindBackToBack
+	case hasWord(name, "frame loss", "loss"):
+		return kindFrameLoss
+	case hasWord(name, "latency", "delay"):
+		return kindLatency
+	case hasWord(name, "throughput", "ndr", "pdr"):
+		return kindThroughput
+	}
+	return ""
+}
+
+// record is one imported result
+type record struct {
+	kind      string
+	frameSize uint32
+	values    map[field]float64
+}
+
+func (r *record) set(c column, v float64) {
+	if r.values == nil {
+		r.values = make(map[field]float64)
+	}
+	// The first column for a field wins (leftmost in CSV, sorted keys in JSON)
+	if _, ok := r.values[c.field]; !ok {
+		r.values[c.field] = v * c.scale
+	}
+}
+
+func (r *record) has(fields ...field) bool {
+	for _, f := range fields {
+		if _, ok := r.values[f]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// measured reports whether the record carries any measurement
+func (r *record) measured() bool {
+	return r.has(fieldRatePct, fieldRateMbps, fieldRatePPS, fieldLatencyMin, fieldLatencyAvg,
+		fieldLatencyMax, fieldJitter, fieldLossPct, fieldFramesTx, fieldBurstFrames, fieldBurstUs)
+}
+
+// resolveKind sets the kind from a hint, or from the fields present
+func (r *record) resolveKind(hint string) {
+	switch {
+	case hint != "":
+		r.kind = hint
+	case r.has(fieldBurstFrames, fieldBurstUs):
+		r.kind = kindBackToBack
+	case r.has(fieldLoad) && r.has(fieldLossPct, fieldFramesRx) && !r.has(fieldRatePct, fieldRateMbps, fieldRatePPS):
+		r.kind = kindFrameLoss
+	case r.has(fieldRatePct, fieldRateMbps, fieldRatePPS):
+		r.kind = kindThroughput
+	case r.has(fieldLatencyMin, fieldLatencyAvg, fieldLatencyMax, fieldJitter):
+		r.kind = kindLatency
+	default:
+		r.kind = kindFrameLoss
+	}
+}
+
+// result lays out a record with the native result field names
+func (r *record) result() map[string]interface{} {
+	m := map[string]interface{}{"FrameSize": r.frameSize}
+	put := func(name string, f field) {
+		if v, ok := r.values[f]; ok {
+			m[name] = v
+		}
+	}
+	latency := map[string]interface{}{}
+	for name, f := range map[string]field{
+		"MinNs": fieldLatencyMin, "AvgNs": fieldLatencyAvg, "MaxNs": fieldLatencyMax, "JitterNs": fieldJitter,
+	} {
+		if v, ok := r.values[f]; ok {
+			latency[name] = v
+		}
+	}
+
+	switch r.kind {
+	case kindThroughput:
+		put("MaxRatePct", fieldRatePct)
+		put("MaxRateMbps", fieldRateMbps)
+		put("MaxRatePPS", fieldRatePPS)
+		if len(latency) > 0 {
+			m["Latency"] = latency
+		}
+	case kindLatency:
+		// RFC 2544 measures latency at the throughput rate unless told otherwise
+		m["LoadPct"] = 100.0
+		put("LoadPct", fieldLoad)
+		m["Latency"] = latency
+	case kindFrameLoss:
+		m["OfferedPct"] = 100.0
+		put("OfferedPct", fieldLoad)
+		put("FramesTx", fieldFramesTx)
+		put("FramesRx", fieldFramesRx)
+		put("LossPct", fieldLossPct)
+		tx, rx := r.values[fieldFramesTx], r.values[fieldFramesRx]
+		if _, ok := m["LossPct"]; !ok && tx > 0 && r.has(fieldFramesRx) {
+			m["LossPct"] = (tx - rx) / tx * 100
+		}
+	case kindBackToBack:
+		put("MaxBurstFrames", fieldBurstFrames)
+		put("BurstDurationUs", fieldBurstUs)
+		put("Trials", fieldTrials)
+	}
+	return m
+}
+
+// parseNumber reads "99.5", "99.5 %", "1,000" or, with decimalComma, "99,5"
+func parseNumber(s string, decimalComma bool) (float64, bool) {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexAny(s, " \t%"); i >= 0 {
+		s = s[:i]
+	}
+	if decimalComma {
+		s = strings.ReplaceAll(s, ".", "")
+		s = strings.ReplaceAll(s, ",", ".")
+	} else {
+		s = strings.ReplaceAll(s, ",", "")
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	return v, err == nil
+}
+
+// sortedKeys returns map keys in order so imports are deterministic
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
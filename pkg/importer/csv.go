@@ -0,0 +1,163 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+)
+
+// parseCSV reads a tester CSV export. Exports hold one or more tables, each
+// a header row followed by data rows, optionally under a title row naming
+// the test ("Throughput", "Frame Loss"). Preamble rows (unit, serial,
+// profile) are skipped. The vendor is detected from the preamble.
+func parseCSV(data []byte, defaultFS uint32) ([]record, string, error) {
+	comma := detectDelimiter(data)
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comma = comma
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	r.TrimLeadingSpace = true
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, "", err
+	}
+	decimalComma := comma == ';'
+
+	var (
+		recs    []record
+		vendor  string
+		columns []column
+		testCol = -1
+		title   string
+	)
+	for _, row := range rows {
+		cells := trimCells(row)
+		if len(cells) == 0 {
+			continue
+		}
+		if vendor == "" {
+			vendor = detectVendor(cells)
+		}
+
+		if cols, tc, ok := headerRow(cells); ok {
+			columns, testCol = cols, tc
+			continue
+		}
+		if len(cells) == 1 || (len(cells) == 2 && hasWord(normalizeName(cells[0]), "test", "test name", "test type")) {
+			if hint := kindHint(cells[len(cells)-1]); hint != "" {
+				title = hint
+				columns = nil
+			}
+			continue
+		}
+		if columns == nil {
+			continue
+		}
+
+		rec := record{frameSize: defaultFS}
+		hasFS := false
+		for i, c := range columns {
+			if c.field == fieldNone || i >= len(cells) {
+				continue
+			}
+			v, ok := parseNumber(cells[i], decimalComma)
+			if !ok {
+				continue
+			}
+			if c.field == fieldFrameSize {
+				rec.frameSize, hasFS = uint32(v), true
+				continue
+			}
+			rec.set(c, v)
+		}
+		if !rec.measured() || (!hasFS && defaultFS == 0 && hasField(columns, fieldFrameSize)) {
+			continue
+		}
+		hint := title
+		if testCol >= 0 && testCol < len(cells) {
+			if h := kindHint(cells[testCol]); h != "" {
+				hint = h
+			}
+		}
+		rec.resolveKind(hint)
+		recs = append(recs, rec)
+	}
+	return recs, vendor, nil
+}
+
+// headerRow recognises a table header: at least two measurement columns
+// and no numeric cells
+func headerRow(cells []string) ([]column, int, bool) {
+	cols := make([]column, len(cells))
+	testCol := -1
+	known := 0
+	for i, cell := range cells {
+		if _, ok := parseNumber(cell, false); ok {
+			return nil, -1, false
+		}
+		cols[i] = classify(cell)
+		if cols[i].field != fieldNone {
+			known++
+		}
+		if n := normalizeName(cell); n == "test" || n == "test name" || n == "test type" {
+			testCol = i
+		}
+	}
+	return cols, testCol, known >= 2
+}
+
+func hasField(cols []column, f field) bool {
+	for _, c := range cols {
+		if c.field == f {
+			return true
+		}
+	}
+	return false
+}
+
+// trimCells trims each cell and drops trailing empty cells; a row with
+// no content returns nil
+func trimCells(row []string) []string {
+	cells := make([]string, len(row))
+	last := -1
+	for i, c := range row {
+		cells[i] = strings.TrimSpace(c)
+		if cells[i] != "" {
+			last = i
+		}
+	}
+	return cells[:last+1]
+}
+
+// detectDelimiter picks ';' (European locale exports), tab or ','
+func detectDelimiter(data []byte) rune {
+	head := data
+	if len(head) > 4096 {
+		head = head[:4096]
+	}
+	counts := map[rune]int{
+		',':  bytes.Count(head, []byte(",")),
+		';':  bytes.Count(head, []byte(";")),
+		'\t': bytes.Count(head, []byte("\t")),
+	}
+	best := ','
+	for _, d := range []rune{';', '\t'} {
+		if counts[d] > counts[best] {
+			best = d
+		}
+	}
+	return best
+}
+
+func detectVendor(cells []string) string {
+	for _, c := range cells {
+		lc := strings.ToLower(c)
+		switch {
+		case strings.Contains(lc, "exfo"):
+			return FormatEXFO
+		case strings.Contains(lc, "viavi"), strings.Contains(lc, "jdsu"):
+			return FormatViavi
+		}
+	}
+	return ""
+}
@@ -0,0 +1,135 @@
+// Package importer converts result exports from third-party testers into
+// the JSON results format written with -o json
+//
+// Imported results use the same field names as native results, so a lab
+// running several tools can put every run through report compare. CSV
+// exports (EXFO, Viavi) and TRex JSON are read by recognising column or key
+// names and their units rather than fixed layouts, since both vary between
+// tool versions and test profiles.
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/krisarmstrong/rfc2544-master/pkg/config"
+)
+
+// Supported formats
+const (
+	FormatCSV   = "csv"   // Generic tester CSV export
+	FormatEXFO  = "exfo"  // EXFO RFC 2544 CSV export
+	FormatViavi = "viavi" // Viavi (JDSU) RFC 2544 CSV export
+	FormatTRex  = "trex"  // TRex NDR benchmark or stats JSON
+)
+
+// Formats lists the accepted Options.Format values
+func Formats() []string {
+	return []string{FormatCSV, FormatEXFO, FormatViavi, FormatTRex}
+}
+
+// Options control an import
+type Options struct {
+	Format    string            // Empty = detect from the file
+	FrameSize uint32            // Frame size for results that do not record one
+	DUT       *config.DUTConfig // DUT the results were measured on
+}
+
+// Metadata mirrors the native results metadata, recording where the
+// results came from
+type Metadata struct {
+	TestType     string            `json:"test_type"` // Empty when the file mixes test types
+	DUT          *config.DUTConfig `json:"dut,omitempty"`
+	ImportedFrom string            `json:"imported_from"`
+	SourceFile   string            `json:"source_file"`
+}
+
+// Report is an imported run in the JSON results format
+type Report struct {
+	Metadata Metadata      `json:"metadata"`
+	Results  []interface{} `json:"results"`
+}
+
+// Import reads a third-party results file
+func Import(path string, opts Options) (*Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Read(path, data, opts)
+}
+
+// Read converts the contents of a results file. The name is recorded in
+// the metadata and used to detect the format.
+func Read(name string, data []byte, opts Options) (*Report, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	format := strings.ToLower(opts.Format)
+	if format == "" {
+		format = DetectFormat(name, data)
+	}
+
+	var recs []record
+	var err error
+	switch format {
+	case FormatCSV, FormatEXFO, FormatViavi:
+		var vendor string
+		recs, vendor, err = parseCSV(data, opts.FrameSize)
+		if format == FormatCSV && vendor != "" {
+			format = vendor
+		}
+	case FormatTRex:
+		recs, err = parseTRex(data, opts.FrameSize)
+	default:
+		return nil, fmt.Errorf("unknown import format %q (supported: %s)", opts.Format, strings.Join(Formats(), ", "))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if len(recs) == 0 {
+		return nil, fmt.Errorf("%s: no results recognised", name)
+	}
+
+	report := &Report{
+		Metadata: Metadata{DUT: opts.DUT, ImportedFrom: format, SourceFile: filepath.Base(name)},
+	}
+	for i, r := range recs {
+		if i == 0 {
+			report.Metadata.TestType = r.kind
+		} else if r.kind != report.Metadata.TestType {
+			report.Metadata.TestType = ""
+		}
+		report.Results = append(report.Results, r.result())
+	}
+	return report, nil
+}
+
+// DetectFormat picks a format from the file extension, falling back to the
+// content for unknown extensions
+func DetectFormat(name string, data []byte) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv", ".txt":
+		return FormatCSV
+	case ".json":
+		return FormatTRex
+	}
+	if json.Valid(data) {
+		return FormatTRex
+	}
+	return FormatCSV
+}
+
+// IsNative reports whether data is already a JSON results file
+func IsNative(data []byte) bool {
+	var doc struct {
+		Metadata json.RawMessage `json:"metadata"`
+		Results  json.RawMessage `json:"results"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return false
+	}
+	return doc.Metadata != nil && doc.Results != nil
+}
@@ -0,0 +1,254 @@
+package importer
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/krisarmstrong/rfc2544-master/pkg/config"
+)
+
+const exfoCSV = `EXFO FTB-1 Pro,RFC 2544 Report
+Unit Serial,123456
+,
+Throughput
+Frame Size (Bytes),Throughput (%),Throughput (Mbit/s),Throughput (frame/s)
+64,95.50,955.0,"1,421,130"
+1518,100.00,1000.0,81274
+,
+Latency
+Frame Size (Bytes),Min Latency (µs),Avg Latency (µs),Max Latency (µs)
+64,2.1,2.5,3.0
+,
+Frame Loss
+Frame Size (Bytes),Offered Load (%),Frame Loss (%)
+64,100,4.5
+64,90,0
+,
+Back-to-Back
+Frame Size (Bytes),Burst (frames),Burst Duration (ms)
+64,12000,0.8
+`
+
+const viaviCSV = `Viavi T-BERD 5800;RFC 2544
+Test;Frame Size;Throughput (%);Throughput (Mbps);Latency (us)
+Throughput;64;99,5;995,0;3,2
+Throughput;128;100;1000;3,4
+`
+
+const trexNDR = `[
+  {"frame_size": 64, "results": {
+    "TX [MPPS]": 14.2, "Total TX L1 [Gbps]": 9.54, "Drop Rate [%]": 0.0,
+    "Avg Latency [us]": 12.5, "Max Latency [us]": 40, "Jitter [us]": 2,
+    "Line Utilization [%]": 95.4, "CPU Utilization [%]": 60,
+    "iter_stats": [{"TX [MPPS]": 1}]
+  }},
+  {"frame_size": 1518, "results": {"Line Utilization [%]": 100, "Drop Rate [%]": 0.0}}
+]`
+
+const trexStats = `{
+  "frame_size": 512,
+  "global": {"tx_bps": 9.2e9, "tx_pps": 2.1e6, "rx_bps": 9.1e9},
+  "total": {"opackets": 1000000, "ipackets": 999000},
+  "latency": {
+    "global": {"bad_hdr": 0},
+    "1": {"latency": {"average": 10.0, "total_min": 5, "total_max": 30, "jitter": 1}},
+    "2": {"latency": {"average": 20.0, "total_min": 4, "total_max": 50, "jitter": 3}}
+  }
+}`
+
+// results round-trips a report through JSON, as compare reads it
+func results(t *testing.T, r *Report) []map[string]interface{} {
+	t.Helper()
+	data, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Results []map[string]interface{} `json:"results"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	return doc.Results
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6*math.Max(1, math.Abs(b))
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		header string
+		field  field
+		scale  float64
+	}{
+		{"Frame Size (Bytes)", fieldFrameSize, 1},
+		{"pkt_size", fieldFrameSize, 1},
+		{"Throughput (%)", fieldRatePct, 1},
+		{"Throughput %", fieldRatePct, 1},
+		{"Throughput (Mbit/s)", fieldRateMbps, 1},
+		{"Total TX L1 [Gbps]", fieldRateMbps, 1e3},
+		{"tx_bps", fieldRateMbps, 1e-6},
+		{"TX [MPPS]", fieldRatePPS, 1e6},
+		{"Avg Latency (µs)", fieldLatencyAvg, 1e3},
+		{"Min Latency (ms)", fieldLatencyMin, 1e6},
+		{"Latency", fieldLatencyAvg, 1e3},
+		{"Jitter [us]", fieldJitter, 1e3},
+		{"Frame Loss (%)", fieldLossPct, 1},
+		{"Drop Rate [%]", fieldLossPct, 1},
+		{"Offered Load (%)", fieldLoad, 1},
+		{"Tx Frames", fieldFramesTx, 1},
+		{"Frames Received", fieldFramesRx, 1},
+		{"Burst Size (frames)", fieldBurstFrames, 1},
+		{"Burst Duration (ms)", fieldBurstUs, 1e3},
+		{"CPU Utilization [%]", fieldNone, 0},
+		{"Lost Frames", fieldNone, 0},
+		{"Status", fieldNone, 0},
+	}
+	for _, tt := range tests {
+		c := classify(tt.header)
+		if c.field != tt.field || (tt.field != fieldNone && !near(c.scale, tt.scale)) {
+			t.Errorf("classify(%q) = %+v, want field %d scale %g", tt.header, c, tt.field, tt.scale)
+		}
+	}
+}
+
+func TestEXFOCSV(t *testing.T) {
+	r, err := Read("exfo.csv", []byte(exfoCSV), Options{DUT: &config.DUTConfig{Name: "dut-1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Metadata.ImportedFrom != FormatEXFO || r.Metadata.TestType != "" || r.Metadata.DUT.Name != "dut-1" {
+		t.Errorf("metadata = %+v", r.Metadata)
+	}
+	res := results(t, r)
+	if len(res) != 6 {
+		t.Fatalf("results = %d, want 6: %v", len(res), res)
+	}
+
+	tp := res[0]
+	if tp["FrameSize"] != 64.0 || tp["MaxRatePct"] != 95.5 || tp["MaxRatePPS"] != 1421130.0 {
+		t.Errorf("throughput = %v", tp)
+	}
+	lat := res[2]
+	if l, ok := lat["Latency"].(map[string]interface{}); !ok || !near(l["AvgNs"].(float64), 2500) || lat["LoadPct"] != 100.0 {
+		t.Errorf("latency = %v", lat)
+	}
+	if fl := res[4]; fl["OfferedPct"] != 90.0 || fl["LossPct"] != 0.0 {
+		t.Errorf("frame loss = %v", fl)
+	}
+	if b2b := res[5]; b2b["MaxBurstFrames"] != 12000.0 || !near(b2b["BurstDurationUs"].(float64), 800) {
+		t.Errorf("back-to-back = %v", b2b)
+	}
+}
+
+func TestViaviCSV(t *testing.T) {
+	r, err := Read("viavi.csv", []byte(viaviCSV), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Metadata.ImportedFrom != FormatViavi || r.Metadata.TestType != "throughput" {
+		t.Errorf("metadata = %+v", r.Metadata)
+	}
+	res := results(t, r)
+	if len(res) != 2 || res[0]["MaxRatePct"] != 99.5 || res[0]["MaxRateMbps"] != 995.0 {
+		t.Fatalf("results = %v", res)
+	}
+	if l := res[0]["Latency"].(map[string]interface{}); !near(l["AvgNs"].(float64), 3200) {
+		t.Errorf("latency = %v", l)
+	}
+}
+
+func TestTRexNDR(t *testing.T) {
+	r, err := Read("ndr.json", []byte(trexNDR), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := results(t, r)
+	if len(res) != 2 {
+		t.Fatalf("results = %d, want 2 (iteration stats skipped): %v", len(res), res)
+	}
+	tp := res[0]
+	if tp["FrameSize"] != 64.0 || tp["MaxRatePct"] != 95.4 || !near(tp["MaxRateMbps"].(float64), 9540) || !near(tp["MaxRatePPS"].(float64), 14.2e6) {
+		t.Errorf("NDR = %v", tp)
+	}
+	if l := tp["Latency"].(map[string]interface{}); !near(l["MaxNs"].(float64), 40000) {
+		t.Errorf("latency = %v", l)
+	}
+	if res[1]["FrameSize"] != 1518.0 {
+		t.Errorf("second frame size = %v", res[1]["FrameSize"])
+	}
+}
+
+func TestTRexStats(t *testing.T) {
+	r, err := Read("stats.json", []byte(trexStats), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := results(t, r)
+	if len(res) != 1 {
+		t.Fatalf("results = %v", res)
+	}
+	tp := res[0]
+	if tp["FrameSize"] != 512.0 || !near(tp["MaxRateMbps"].(float64), 9200) {
+		t.Errorf("stats = %v", tp)
+	}
+	l := tp["Latency"].(map[string]interface{})
+	if !near(l["AvgNs"].(float64), 15000) || !near(l["MinNs"].(float64), 4000) || !near(l["MaxNs"].(float64), 50000) || !near(l["JitterNs"].(float64), 3000) {
+		t.Errorf("latency = %v", l)
+	}
+}
+
+func TestFrameSizeOption(t *testing.T) {
+	r, err := Read("ndr.json", []byte(`{"Line Utilization [%]": 98}`), Options{FrameSize: 256})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res := results(t, r); res[0]["FrameSize"] != 256.0 {
+		t.Errorf("frame size = %v", res[0]["FrameSize"])
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	if _, err := Read("a.csv", []byte(exfoCSV), Options{Format: "ixia"}); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+	if _, err := Read("a.csv", []byte("Name,Value\nfoo,bar\n"), Options{}); err == nil {
+		t.Error("Expected error when nothing is recognised")
+	}
+	if _, err := Read("a.json", []byte("{"), Options{}); err == nil {
+		t.Error("Expected error for invalid JSON")
+	}
+	if _, err := Import(filepath.Join(t.TempDir(), "missing.csv"), Options{}); err == nil {
+		t.Error("Expected error for missing file")
+	}
+}
+
+func TestImportFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.csv")
+	if err := os.WriteFile(path, []byte(viaviCSV), 0644); err != nil {
+		t.Fatal(err)
+	}
+	r, err := Import(path, Options{Format: FormatCSV})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Metadata.SourceFile != "run.csv" || len(r.Results) != 2 {
+		t.Errorf("report = %+v", r)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	if f := DetectFormat("x.CSV", nil); f != FormatCSV {
+		t.Errorf("DetectFormat(csv) = %s", f)
+	}
+	if f := DetectFormat("export", []byte(`{"a": 1}`)); f != FormatTRex {
+		t.Errorf("DetectFormat(json content) = %s", f)
+	}
+	if !IsNative([]byte(`{"metadata": {}, "results": []}`)) || IsNative([]byte(trexStats)) || IsNative([]byte(exfoCSV)) {
+		t.Error("IsNative misidentified a file")
+	}
+}
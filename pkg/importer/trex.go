@@ -0,0 +1,143 @@
+package importer
+
+import (
+	"encoding/json"
+	"math"
+)
+
+// parseTRex reads TRex JSON. Two shapes are recognised anywhere in the
+// document, so per-frame-size wrappers written by lab scripts also work:
+//
+//   - NDR benchmark results, keyed like "TX [MPPS]", "Total TX L1 [Gbps]",
+//     "Drop Rate [%]" and "Avg Latency [us]"
+//   - Stats snapshots from get_stats(), with "global", "total" and
+//     "latency" sections, taken as the throughput at the rate they ran
+//
+// A "frame_size" (or "pkt_size", "size") key applies to the object it is
+// in and everything below it.
+func parseTRex(data []byte, defaultFS uint32) ([]record, error) {
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	var recs []record
+	walkTRex(doc, defaultFS, &recs)
+	return recs, nil
+}
+
+func walkTRex(v interface{}, fs uint32, out *[]record) {
+	switch t := v.(type) {
+	case []interface{}:
+		for _, e := range t {
+			walkTRex(e, fs, out)
+		}
+	case map[string]interface{}:
+		for k, v := range t {
+			if n, ok := v.(float64); ok && classify(k).field == fieldFrameSize {
+				fs = uint32(n)
+			}
+		}
+		if rec, ok := statsRecord(t, fs); ok {
+			*out = append(*out, rec)
+			return
+		}
+		if rec, ok := keyedRecord(t, fs); ok {
+			*out = append(*out, rec)
+			return
+		}
+		for _, k := range sortedKeys(t) {
+			walkTRex(t[k], fs, out)
+		}
+	}
+}
+
+// keyedRecord reads an object whose numeric keys name measurements.
+// Nested objects such as per-iteration stats are not descended into.
+func keyedRecord(obj map[string]interface{}, fs uint32) (record, bool) {
+	rec := record{frameSize: fs}
+	for _, k := range sortedKeys(obj) {
+		n, ok := obj[k].(float64)
+		if !ok {
+			continue
+		}
+		if c := classify(k); c.field != fieldNone && c.field != fieldFrameSize {
+			rec.set(c, n)
+		}
+	}
+	if !rec.measured() {
+		return record{}, false
+	}
+	rec.resolveKind("")
+	return rec, true
+}
+
+// statsRecord reads a get_stats() snapshot. Rates and latency come from
+// the global and per-stream sections, loss from the total port counters.
+func statsRecord(obj map[string]interface{}, fs uint32) (record, bool) {
+	global, ok := obj["global"].(map[string]interface{})
+	if !ok {
+		return record{}, false
+	}
+	total, hasTotal := obj["total"].(map[string]interface{})
+	latency, hasLatency := obj["latency"].(map[string]interface{})
+	if !hasTotal && !hasLatency {
+		return record{}, false
+	}
+
+	rec := record{frameSize: fs}
+	if v, ok := global["tx_bps"].(float64); ok {
+		rec.set(column{field: fieldRateMbps, scale: 1e-6}, v)
+	}
+	if v, ok := global["tx_pps"].(float64); ok {
+		rec.set(column{field: fieldRatePPS, scale: 1}, v)
+	}
+	if hasTotal {
+		tx, txOK := total["opackets"].(float64)
+		rx, rxOK := total["ipackets"].(float64)
+		if txOK && rxOK && tx > 0 {
+			rec.set(column{field: fieldLossPct, scale: 1}, math.Max(0, (tx-rx)/tx*100))
+		}
+	}
+
+	// Aggregate across streams: the worst min/max/jitter and the mean average
+	minNs, maxNs, jitterNs, sumAvg, streams := math.Inf(1), 0.0, 0.0, 0.0, 0
+	for id, s := range latency {
+		stream, ok := s.(map[string]interface{})
+		if !ok || id == "global" {
+			continue
+		}
+		lat, ok := stream["latency"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		avg, ok := lat["average"].(float64)
+		if !ok {
+			continue
+		}
+		streams++
+		sumAvg += avg * 1e3
+		if v, ok := lat["total_min"].(float64); ok {
+			minNs = math.Min(minNs, v*1e3)
+		}
+		if v, ok := lat["total_max"].(float64); ok {
+			maxNs = math.Max(maxNs, v*1e3)
+		}
+		if v, ok := lat["jitter"].(float64); ok {
+			jitterNs = math.Max(jitterNs, v*1e3)
+		}
+	}
+	if streams > 0 {
+		rec.set(column{field: fieldLatencyAvg, scale: 1}, sumAvg/float64(streams))
+		if !math.IsInf(minNs, 1) {
+			rec.set(column{field: fieldLatencyMin, scale: 1}, minNs)
+		}
+		rec.set(column{field: fieldLatencyMax, scale: 1}, maxNs)
+		rec.set(column{field: fieldJitter, scale: 1}, jitterNs)
+	}
+
+	if !rec.measured() {
+		return record{}, false
+	}
+	rec.kind = kindThroughput
+	return rec, true
+}
@@ -0,0 +1,23 @@
+// Package execrun runs the host tools other packages drive, such as ping,
+// ssh and tc, behind a function type their tests replace
+package execrun
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Runner executes a command and returns its standard output
+type Runner func(ctx context.Context, name string, args ...string) ([]byte, error)
+
+// Exec runs commands with os/exec. The error of a command that fails
+// carries its standard error, which is where ssh and tc say why.
+func Exec(ctx context.Context, name string, args ...string) ([]byte, error) {
+	out, err := exec.CommandContext(ctx, name, args...).Output()
+	if ee, ok := err.(*exec.ExitError); ok && len(ee.Stderr) > 0 {
+		return out, fmt.Errorf("%s: %s", err, strings.TrimSpace(string(ee.Stderr)))
+	}
+	return out, err
+}
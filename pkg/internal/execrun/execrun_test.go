@@ -0,0 +1,23 @@
+package execrun
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestExec(t *testing.T) {
+	out, err := Exec(context.Background(), "sh", "-c", "echo out")
+	if err != nil || string(out) != "out\n" {
+		t.Fatalf("Exec = %q, %v; want %q", out, err, "out\n")
+	}
+
+	// A failure keeps its output and reports what the command said
+	out, err = Exec(context.Background(), "sh", "-c", "echo partial; echo 'no such device' >&2; exit 2")
+	if err == nil || !strings.Contains(err.Error(), "exit status 2: no such device") {
+		t.Errorf("Exec error = %v, want the exit status and standard error", err)
+	}
+	if string(out) != "partial\n" {
+		t.Errorf("Exec output = %q, want %q", out, "partial\n")
+	}
+}
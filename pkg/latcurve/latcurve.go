@@ -0,0 +1,194 @@
+// Package latcurve assembles latency against frame size at the throughput
+// rate across a run. A store-and-forward DUT receives each frame whole
+// before sending it on, so its latency grows by one serialization delay
+// per byte of frame; a cut-through DUT's stays flat. The slope of the
+// curve against the serialization delay shows which one the DUT is; a
+// slope in between, or one that changes part way along the curve, is a
+// hybrid.
+package latcurve
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Forwarding modes inferred from the curve's slope
+const (
+	StoreAndForward = "store-and-forward"
+	CutThrough      = "cut-through"
+	Hybrid          = "hybrid"
+)
+
+// Slope ratios, of the latency slope to the serialization slope, below
+// which a DUT cuts through and at or above which it stores and forwards
+const (
+	cutThroughRatio      = 0.25
+	storeAndForwardRatio = 0.75
+)
+
+// Point is the latency of one frame size at the throughput rate
+type Point struct {
+	FrameSize       uint32  `json:"frame_size"`
+	LoadPct         float64 `json:"load_pct"` // Offered load the latency was measured at
+	AvgUs           float64 `json:"avg_us"`
+	MinUs           float64 `json:"min_us"`
+	MaxUs           float64 `json:"max_us"`
+	SerializationUs float64 `json:"serialization_us"`     // One frame's serialization delay at line rate
+	NetAvgUs        float64 `json:"net_avg_us,omitempty"` // AvgUs less SerializationUs, when subtracted
+}
+
+// Curve is latency against frame size, smallest frame first
+type Curve struct {
+	LineRateMbps            float64 `json:"line_rate_mbps,omitempty"`
+	SerializationSubtracted bool    `json:"serialization_subtracted"`
+	Points                  []Point `json:"points"`
+	SlopeNsPerByte          float64 `json:"slope_ns_per_byte"`                   // Least-squares slope of AvgUs
+	SerializationNsPerByte  float64 `json:"serialization_ns_per_byte,omitempty"` // Slope of a store-and-forward DUT
+	Forwarding              string  `json:"forwarding,omitempty"`                // Inferred from the slope, when the line rate is known
+
+	SlopeRatio float64   `json:"slope_ratio,omitempty"` // SlopeNsPerByte over SerializationNsPerByte
+	Segments   []Segment `json:"segments,omitempty"`    // Between adjacent frame sizes, when there are three or more
+	Evidence   []string  `json:"evidence,omitempty"`    // Why Forwarding was chosen, for the report
+}
+
+// Segment is the slope of the curve between two adjacent frame sizes
+type Segment struct {
+	FromSize       uint32  `json:"from_size"`
+	ToSize         uint32  `json:"to_size"`
+	SlopeNsPerByte float64 `json:"slope_ns_per_byte"`
+	SlopeRatio     float64 `json:"slope_ratio,omitempty"`
+	Forwarding     string  `json:"forwarding,omitempty"` // Cut-through or store-and-forward, whichever the ratio is nearer
+}
+
+// Build sorts points by frame size, fills in their serialization delay at
+// lineRateMbps (0 if unknown), and fits the slope. subtract also reports
+// each point's latency less its serialization delay. It returns nil for
+// fewer than two frame sizes.
+func Build(points []Point, lineRateMbps float64, subtract bool) *Curve {
+	if len(points) < 2 {
+		return nil
+	}
+	c := &Curve{
+		LineRateMbps:            lineRateMbps,
+		SerializationSubtracted: subtract && lineRateMbps > 0,
+		Points:                  append([]Point(nil), points...),
+	}
+	sort.SliceStable(c.Points, func(i, j int) bool { return c.Points[i].FrameSize < c.Points[j].FrameSize })
+
+	if lineRateMbps > 0 {
+		// Mbps is bits per microsecond
+		c.SerializationNsPerByte = 8 * 1000 / lineRateMbps
+		for i := range c.Points {
+			p := &c.Points[i]
+			p.SerializationUs = float64(p.FrameSize) * 8 / lineRateMbps
+			if c.SerializationSubtracted {
+				p.NetAvgUs = p.AvgUs - p.SerializationUs
+			}
+		}
+	}
+
+	slope, ok := slopeUsPerByte(c.Points)
+	if !ok {
+		return c
+	}
+	c.SlopeNsPerByte = slope * 1000
+	if c.SerializationNsPerByte == 0 {
+		c.Evidence = append(c.Evidence, "Line rate unknown; the slope cannot be compared with the serialization delay")
+		return c
+	}
+	c.classify()
+	return c
+}
+
+// classify sets Forwarding from the slope ratio of the whole curve and of
+// each segment, with the evidence for it
+func (c *Curve) classify() {
+	c.SlopeRatio = c.SlopeNsPerByte / c.SerializationNsPerByte
+	c.Evidence = append(c.Evidence, fmt.Sprintf(
+		"Latency rises %.3f ns/byte from %d to %d bytes; serialization at %.0f Mbps is %.3f ns/byte (ratio %.2f)",
+		c.SlopeNsPerByte, c.Points[0].FrameSize, c.Points[len(c.Points)-1].FrameSize,
+		c.LineRateMbps, c.SerializationNsPerByte, c.SlopeRatio))
+
+	switch {
+	case c.SlopeRatio < cutThroughRatio:
+		c.Forwarding = CutThrough
+		c.Evidence = append(c.Evidence, fmt.Sprintf(
+			"Ratio below %.2f: latency does not grow with the frame, so the DUT forwards before the frame is received whole", cutThroughRatio))
+	case c.SlopeRatio >= storeAndForwardRatio:
+		c.Forwarding = StoreAndForward
+		c.Evidence = append(c.Evidence, fmt.Sprintf(
+			"Ratio at or above %.2f: latency grows by about one serialization delay per byte, so the DUT receives each frame whole", storeAndForwardRatio))
+	default:
+		c.Forwarding = Hybrid
+		c.Evidence = append(c.Evidence, fmt.Sprintf(
+			"Ratio between %.2f and %.2f: latency grows with the frame, but by less than its serialization delay", cutThroughRatio, storeAndForwardRatio))
+	}
+
+	if len(c.Points) < 3 {
+		return
+	}
+	c.segments()
+	// One switch from cutting through small frames to storing large ones
+	// is a DUT that changes mode with the frame size; any other pattern
+	// is noise on the short segments
+	switchAt := -1
+	for i := 1; i < len(c.Segments); i++ {
+		if c.Segments[i].Forwarding == c.Segments[i-1].Forwarding {
+			continue
+		}
+		if switchAt >= 0 {
+			return
+		}
+		switchAt = i
+	}
+	if switchAt < 0 || c.Segments[0].Forwarding != CutThrough {
+		return
+	}
+	at := c.Segments[switchAt]
+	c.Forwarding = Hybrid
+	c.Evidence = append(c.Evidence, fmt.Sprintf(
+		"Segments up to %d bytes are cut-through and from %d bytes store-and-forward: the DUT switches mode with the frame size",
+		at.FromSize, at.FromSize))
+}
+
+// segments fits the slope between each pair of adjacent frame sizes
+func (c *Curve) segments() {
+	for i := 1; i < len(c.Points); i++ {
+		a, b := c.Points[i-1], c.Points[i]
+		if a.FrameSize == b.FrameSize {
+			continue
+		}
+		seg := Segment{
+			FromSize:       a.FrameSize,
+			ToSize:         b.FrameSize,
+			SlopeNsPerByte: (b.AvgUs - a.AvgUs) * 1000 / float64(b.FrameSize-a.FrameSize),
+		}
+		seg.SlopeRatio = seg.SlopeNsPerByte / c.SerializationNsPerByte
+		// Halfway between a flat curve and one serialization per byte
+		if seg.SlopeRatio >= 0.5 {
+			seg.Forwarding = StoreAndForward
+		} else {
+			seg.Forwarding = CutThrough
+		}
+		c.Segments = append(c.Segments, seg)
+	}
+}
+
+// slopeUsPerByte returns the least-squares slope of AvgUs against frame
+// size, or false if every point has the same frame size
+func slopeUsPerByte(points []Point) (float64, bool) {
+	var sx, sy, sxx, sxy float64
+	for _, p := range points {
+		x := float64(p.FrameSize)
+		sx += x
+		sy += p.AvgUs
+		sxx += x * x
+		sxy += x * p.AvgUs
+	}
+	n := float64(len(points))
+	den := n*sxx - sx*sx
+	if den == 0 {
+		return 0, false
+	}
+	return (n*sxy - sx*sy) / den, true
+}
@@ -0,0 +1,87 @@
+package latcurve
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestBuildStoreAndForward(t *testing.T) {
+	// 1 Gbps: 8 ns per byte; 2 us fixed plus one serialization delay
+	var points []Point
+	for _, fs := range []uint32{1518, 64, 512} {
+		points = append(points, Point{FrameSize: fs, LoadPct: 100, AvgUs: 2 + float64(fs)*0.008})
+	}
+	c := Build(points, 1000, true)
+	if c == nil {
+		t.Fatal("no curve")
+	}
+	if c.Points[0].FrameSize != 64 || c.Points[2].FrameSize != 1518 {
+		t.Errorf("points not sorted: %+v", c.Points)
+	}
+	if math.Abs(c.SlopeNsPerByte-8) > 1e-6 || c.SerializationNsPerByte != 8 {
+		t.Errorf("slope %g ns/byte, serialization %g ns/byte", c.SlopeNsPerByte, c.SerializationNsPerByte)
+	}
+	if c.Forwarding != StoreAndForward {
+		t.Errorf("forwarding %q", c.Forwarding)
+	}
+	for _, p := range c.Points {
+		if math.Abs(p.NetAvgUs-2) > 1e-9 {
+			t.Errorf("%d bytes: net %g us, want 2", p.FrameSize, p.NetAvgUs)
+		}
+	}
+}
+
+func TestBuildCutThrough(t *testing.T) {
+	points := []Point{{FrameSize: 64, AvgUs: 1.0}, {FrameSize: 1518, AvgUs: 1.2}}
+	c := Build(points, 10000, false)
+	if c.Forwarding != CutThrough {
+		t.Errorf("forwarding %q (slope %g ns/byte)", c.Forwarding, c.SlopeNsPerByte)
+	}
+	if c.SerializationSubtracted || c.Points[0].NetAvgUs != 0 {
+		t.Errorf("serialization subtracted without asking: %+v", c)
+	}
+}
+
+func TestBuildUnknownLineRate(t *testing.T) {
+	if c := Build([]Point{{FrameSize: 64}}, 1000, true); c != nil {
+		t.Errorf("curve from one frame size: %+v", c)
+	}
+	c := Build([]Point{{FrameSize: 64, AvgUs: 1}, {FrameSize: 128, AvgUs: 2}}, 0, true)
+	if c.Forwarding != "" || c.SerializationSubtracted || c.Points[0].SerializationUs != 0 {
+		t.Errorf("serialization used without a line rate: %+v", c)
+	}
+}
+
+func TestBuildHybrid(t *testing.T) {
+	// Half a serialization delay per byte across the whole curve
+	points := []Point{{FrameSize: 64, AvgUs: 1}, {FrameSize: 1518, AvgUs: 1 + 1454*0.004}}
+	c := Build(points, 1000, false)
+	if c.Forwarding != Hybrid || math.Abs(c.SlopeRatio-0.5) > 1e-9 || len(c.Evidence) != 2 {
+		t.Errorf("forwarding %q, ratio %g, evidence %q", c.Forwarding, c.SlopeRatio, c.Evidence)
+	}
+
+	// Flat up to 512 bytes, one serialization delay per byte beyond
+	points = []Point{{FrameSize: 64, AvgUs: 2}, {FrameSize: 256, AvgUs: 2}, {FrameSize: 512, AvgUs: 2},
+		{FrameSize: 1024, AvgUs: 2 + 512*0.008}, {FrameSize: 1518, AvgUs: 2 + 1006*0.008}}
+	c = Build(points, 1000, false)
+	if c.Forwarding != Hybrid || len(c.Segments) != 4 {
+		t.Fatalf("forwarding %q, segments %+v", c.Forwarding, c.Segments)
+	}
+	if c.Segments[1].Forwarding != CutThrough || c.Segments[2].Forwarding != StoreAndForward {
+		t.Errorf("segments %+v", c.Segments)
+	}
+	if last := c.Evidence[len(c.Evidence)-1]; !strings.Contains(last, "512 bytes") {
+		t.Errorf("evidence %q", c.Evidence)
+	}
+}
+
+func TestBuildNoisySegments(t *testing.T) {
+	// Segments alternate around a flat curve: noise, not a mode switch
+	points := []Point{{FrameSize: 64, AvgUs: 2}, {FrameSize: 128, AvgUs: 2.5},
+		{FrameSize: 256, AvgUs: 2}, {FrameSize: 512, AvgUs: 2.5}, {FrameSize: 1024, AvgUs: 2}}
+	c := Build(points, 1000, false)
+	if c.Forwarding != CutThrough {
+		t.Errorf("forwarding %q, segments %+v", c.Forwarding, c.Segments)
+	}
+}
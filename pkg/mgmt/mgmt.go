@@ -0,0 +1,283 @@
+// Package mgmt generates RFC 2544 Section 11.2 management traffic toward the DUT
+//
+// The RFC suggests sending one management query per second (e.g. an SNMP GET)
+// to the device under test while a trial runs, so that the effect of the DUT
+// servicing its control plane can be compared with the baseline results.
+package mgmt
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// DefaultPort is the SNMP agent port used when the target has none
+const DefaultPort = "161"
+
+// MaxPerSec is the highest query rate; a faster poll stresses the DUT's
+// SNMP agent rather than adding management load alongside the trial
+const MaxPerSec = 1000
+
+// sysUpTime.0 (1.3.6.1.2.1.1.3.0), answered by every SNMP agent
+var sysUpTimeOID = []byte{0x2b, 0x06, 0x01, 0x02, 0x01, 0x01, 0x03, 0x00}
+
+// Stats summarizes the management queries sent during a run
+type Stats struct {
+	Target   string  `json:"target"`
+	Sent     uint64  `json:"sent"`
+	Answered uint64  `json:"answered"`
+	AvgRTTMs float64 `json:"avg_rtt_ms"`
+	MaxRTTMs float64 `json:"max_rtt_ms"`
+}
+
+// AnsweredPct returns the percentage of queries the DUT answered
+func (s Stats) AnsweredPct() float64 {
+	if s.Sent == 0 {
+		return 0
+	}
+	return 100.0 * float64(s.Answered) / float64(s.Sent)
+}
+
+// Poller sends SNMPv2c GET requests to the DUT at a fixed rate
+type Poller struct {
+	target    string
+	community string
+	interval  time.Duration
+
+	conn    net.Conn
+	mu      sync.Mutex
+	pending map[int32]time.Time
+	stats   Stats
+	rttSum  time.Duration
+	nextID  int32
+	stop    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewPoller creates a poller for target (host or host:port) sending
+// perSec queries per second, clamped to 1-MaxPerSec
+func NewPoller(target, community string, perSec uint32) *Poller {
+	if _, _, err := net.SplitHostPort(target); err != nil {
+		target = net.JoinHostPort(target, DefaultPort)
+	}
+	if perSec == 0 {
+		perSec = 1
+	} else if perSec > MaxPerSec {
+		perSec = MaxPerSec
+	}
+	return &Poller{
+		target:    target,
+		community: community,
+		interval:  time.Second / time.Duration(perSec),
+		pending:   make(map[int32]time.Time),
+		stats:     Stats{Target: target},
+	}
+}
+
+// Start opens the socket and begins sending queries
+func (p *Poller) Start() error {
+	conn, err := net.Dial("udp", p.target)
+	if err != nil {
+		return fmt.Errorf("dial %s: %w", p.target, err)
+	}
+	p.conn = conn
+	p.stop = make(chan struct{})
+
+	p.wg.Add(2)
+	go p.sendLoop()
+	go p.recvLoop()
+	return nil
+}
+
+// Stop halts the poller and returns the collected statistics
+func (p *Poller) Stop() Stats {
+	if p.conn == nil {
+		return p.stats
+	}
+	close(p.stop)
+	p.conn.Close()
+	p.wg.Wait()
+	p.conn = nil
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stats.Answered > 0 {
+		p.stats.AvgRTTMs = float64(p.rttSum) / float64(p.stats.Answered) / float64(time.Millisecond)
+	}
+	return p.stats
+}
+
+func (p *Poller) sendLoop() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		p.send()
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *Poller) send() {
+	p.mu.Lock()
+	p.nextID++
+	if p.nextID <= 0 {
+		p.nextID = 1
+	}
+	id := p.nextID
+	p.mu.Unlock()
+
+	if _, err := p.conn.Write(encodeGetRequest(p.community, id)); err != nil {
+		return
+	}
+
+	p.mu.Lock()
+	p.pending[id] = time.Now()
+	p.stats.Sent++
+	p.mu.Unlock()
+}
+
+func (p *Poller) recvLoop() {
+	defer p.wg.Done()
+
+	buf := make([]byte, 1500)
+	for {
+		n, err := p.conn.Read(buf)
+		if err != nil {
+			select {
+			case <-p.stop:
+				return
+			default:
+			}
+			// ICMP port unreachable surfaces as a read error; keep listening
+			time.Sleep(10 * time.Millisecond)
+			continue
+		}
+
+		id, err := parseResponseID(buf[:n])
+		if err != nil {
+			continue
+		}
+
+		p.mu.Lock()
+		if sentAt, ok := p.pending[id]; ok {
+			delete(p.pending, id)
+			rtt := time.Since(sentAt)
+			p.stats.Answered++
+			p.rttSum += rtt
+			if ms := float64(rtt) / float64(time.Millisecond); ms > p.stats.MaxRTTMs {
+				p.stats.MaxRTTMs = ms
+			}
+		}
+		p.mu.Unlock()
+	}
+}
+
+// encodeGetRequest builds an SNMPv2c GetRequest for sysUpTime.0
+func encodeGetRequest(community string, id int32) []byte {
+	varbind := tlv(0x30, append(tlv(0x06, sysUpTimeOID), 0x05, 0x00))
+	pdu := tlv(0xa0, concat(
+		tlv(0x02, encodeInt(id)),
+		tlv(0x02, []byte{0x00}), // error-status
+		tlv(0x02, []byte{0x00}), // error-index
+		tlv(0x30, varbind),
+	))
+	return tlv(0x30, concat(
+		tlv(0x02, []byte{0x01}), // version: SNMPv2c
+		tlv(0x04, []byte(community)),
+		pdu,
+	))
+}
+
+// parseResponseID extracts the request-id from an SNMP GetResponse
+func parseResponseID(msg []byte) (int32, error) {
+	tag, body, _, err := readTLV(msg)
+	if err != nil || tag != 0x30 {
+		return 0, errors.New("not an SNMP message")
+	}
+	// Skip version and community
+	for i := 0; i < 2; i++ {
+		if _, _, body, err = readTLV(body); err != nil {
+			return 0, err
+		}
+	}
+	tag, pdu, _, err := readTLV(body)
+	if err != nil || tag != 0xa2 {
+		return 0, errors.New("not a GetResponse")
+	}
+	tag, idBytes, _, err := readTLV(pdu)
+	if err != nil || tag != 0x02 || len(idBytes) == 0 || len(idBytes) > 4 {
+		return 0, errors.New("invalid request-id")
+	}
+
+	var id int32
+	if idBytes[0]&0x80 != 0 {
+		id = -1
+	}
+	for _, b := range idBytes {
+		id = id<<8 | int32(b)
+	}
+	return id, nil
+}
+
+// readTLV splits one BER element off the front of data
+func readTLV(data []byte) (tag byte, value, rest []byte, err error) {
+	if len(data) < 2 {
+		return 0, nil, nil, errors.New("short element")
+	}
+	tag = data[0]
+	length := int(data[1])
+	offset := 2
+	if length&0x80 != 0 {
+		n := length & 0x7f
+		if n == 0 || n > 2 || len(data) < 2+n {
+			return 0, nil, nil, errors.New("bad length")
+		}
+		length = 0
+		for _, b := range data[2 : 2+n] {
+			length = length<<8 | int(b)
+		}
+		offset += n
+	}
+	if len(data) < offset+length {
+		return 0, nil, nil, errors.New("truncated element")
+	}
+	return tag, data[offset : offset+length], data[offset+length:], nil
+}
+
+func tlv(tag byte, value []byte) []byte {
+	out := []byte{tag}
+	switch {
+	case len(value) < 0x80:
+		out = append(out, byte(len(value)))
+	case len(value) <= 0xff:
+		out = append(out, 0x81, byte(len(value)))
+	default:
+		out = append(out, 0x82, byte(len(value)>>8), byte(len(value)))
+	}
+	return append(out, value...)
+}
+
+// encodeInt returns the minimal two's complement encoding of v
+func encodeInt(v int32) []byte {
+	b := []byte{byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)}
+	for len(b) > 1 && ((b[0] == 0x00 && b[1]&0x80 == 0) || (b[0] == 0xff && b[1]&0x80 != 0)) {
+		b = b[1:]
+	}
+	return b
+}
+
+func concat(parts ...[]byte) []byte {
+	var out []byte
+	for _, p := range parts {
+		out = append(out, p...)
+	}
+	return out
+}
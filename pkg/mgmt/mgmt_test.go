@@ -0,0 +1,117 @@
+package mgmt
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestEncodeInt(t *testing.T) {
+	tests := []struct {
+		v    int32
+		want []byte
+	}{
+		{0, []byte{0x00}},
+		{1, []byte{0x01}},
+		{127, []byte{0x7f}},
+		{128, []byte{0x00, 0x80}},
+		{256, []byte{0x01, 0x00}},
+		{-1, []byte{0xff}},
+	}
+	for _, tt := range tests {
+		got := encodeInt(tt.v)
+		if string(got) != string(tt.want) {
+			t.Errorf("encodeInt(%d) = %x, want %x", tt.v, got, tt.want)
+		}
+	}
+}
+
+// fakeResponse turns a GetRequest into a GetResponse with the same request-id
+func fakeResponse(req []byte, community string) []byte {
+	resp := append([]byte(nil), req...)
+	resp[2+3+2+len(community)] = 0xa2
+	return resp
+}
+
+func TestParseResponseID(t *testing.T) {
+	for _, id := range []int32{1, 200, 70000, 1 << 30} {
+		req := encodeGetRequest("public", id)
+		if _, err := parseResponseID(req); err == nil {
+			t.Errorf("GetRequest should not parse as a response")
+		}
+
+		got, err := parseResponseID(fakeResponse(req, "public"))
+		if err != nil {
+			t.Fatalf("parseResponseID: %v", err)
+		}
+		if got != id {
+			t.Errorf("request-id = %d, want %d", got, id)
+		}
+	}
+}
+
+func TestParseResponseTruncated(t *testing.T) {
+	resp := fakeResponse(encodeGetRequest("public", 5), "public")
+	if _, err := parseResponseID(resp[:len(resp)-4]); err == nil {
+		t.Error("Expected error for truncated message")
+	}
+}
+
+func TestNewPollerDefaultPort(t *testing.T) {
+	p := NewPoller("192.0.2.1", "public", 1)
+	if p.target != "192.0.2.1:161" {
+		t.Errorf("target = %s, want 192.0.2.1:161", p.target)
+	}
+
+	p = NewPoller("192.0.2.1:1161", "public", 0)
+	if p.target != "192.0.2.1:1161" {
+		t.Errorf("target = %s, want 192.0.2.1:1161", p.target)
+	}
+	if p.interval != time.Second {
+		t.Errorf("interval = %v, want 1s", p.interval)
+	}
+}
+
+func TestNewPollerClampsRate(t *testing.T) {
+	// Above 1e9/s the interval would round to 0 and panic the ticker
+	p := NewPoller("192.0.2.1", "public", 2000000000)
+	if want := time.Second / MaxPerSec; p.interval != want {
+		t.Errorf("interval = %v, want %v", p.interval, want)
+	}
+}
+
+func TestPollerAgainstAgent(t *testing.T) {
+	agent, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer agent.Close()
+
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, addr, err := agent.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			agent.WriteTo(fakeResponse(buf[:n], "public"), addr)
+		}
+	}()
+
+	p := NewPoller(agent.LocalAddr().String(), "public", 50)
+	if err := p.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	stats := p.Stop()
+
+	if stats.Sent == 0 {
+		t.Fatal("Expected queries to be sent")
+	}
+	if stats.Answered == 0 {
+		t.Error("Expected queries to be answered")
+	}
+	if stats.AnsweredPct() > 100 {
+		t.Errorf("AnsweredPct = %.1f, want <= 100", stats.AnsweredPct())
+	}
+}
@@ -0,0 +1,244 @@
+// Package microburst maps a DUT's egress buffer from trains of line-rate
+// bursts: the burst size the DUT starts dropping at, with the egress drain
+// rate, gives how many frames it can queue. Repeating the search at
+// several inter-burst gaps shows whether the queue empties between bursts
+// or builds up over the train.
+package microburst
+
+import (
+	"fmt"
+	"time"
+)
+
+// wireOverhead is the preamble, SFD and inter-frame gap of each frame
+const wireOverhead = 20
+
+// Burst is one burst of a train
+type Burst struct {
+	FramesTx  uint64  `json:"frames_tx"`
+	FramesRx  uint64  `json:"frames_rx"`
+	LossPct   float64 `json:"loss_pct"`
+	MaxUs     float64 `json:"max_us"`     // Latency of its slowest frame
+	DrainMbps float64 `json:"drain_mbps"` // Rate its frames left the DUT at
+}
+
+// NewBurst makes a burst from its counts; rxSpanNs is the time from its
+// first frame received to its last
+func NewBurst(frameSize uint32, tx, rx uint64, maxLatencyNs float64, rxSpanNs uint64) Burst {
+	b := Burst{FramesTx: tx, FramesRx: rx, MaxUs: maxLatencyNs / 1000}
+	if tx > 0 && rx < tx {
+		b.LossPct = 100 * float64(tx-rx) / float64(tx)
+	}
+	if rx > 1 && rxSpanNs > 0 {
+		// The span covers the gaps between rx frames, one fewer than them
+		b.DrainMbps = float64(rx-1) * float64(frameSize+wireOverhead) * 8 * 1000 / float64(rxSpanNs)
+	}
+	return b
+}
+
+// Train is one trial: a train of equal bursts
+type Train struct {
+	Frames         uint32  `json:"frames"` // Frames per burst
+	FramesTx       uint64  `json:"frames_tx"`
+	FramesRx       uint64  `json:"frames_rx"`
+	LossPct        float64 `json:"loss_pct"`
+	LossyBursts    int     `json:"lossy_bursts"`
+	FirstLossBurst int     `json:"first_loss_burst,omitempty"` // 1-based; 0 = no burst lost frames
+	Bursts         []Burst `json:"bursts"`
+}
+
+// NewTrain totals a train's bursts
+func NewTrain(frames uint32, bursts []Burst) Train {
+	t := Train{Frames: frames, Bursts: bursts}
+	for i, b := range bursts {
+		t.FramesTx += b.FramesTx
+		t.FramesRx += b.FramesRx
+		if b.FramesRx < b.FramesTx {
+			t.LossyBursts++
+			if t.FirstLossBurst == 0 {
+				t.FirstLossBurst = i + 1
+			}
+		}
+	}
+	if t.FramesTx > 0 && t.FramesRx < t.FramesTx {
+		t.LossPct = 100 * float64(t.FramesTx-t.FramesRx) / float64(t.FramesTx)
+	}
+	return t
+}
+
+// Lossless reports whether every frame of the train arrived
+func (t Train) Lossless() bool {
+	return t.LossyBursts == 0
+}
+
+// drainMbps is the average drain rate of the train's bursts
+func (t Train) drainMbps() float64 {
+	var sum float64
+	var n int
+	for _, b := range t.Bursts {
+		if b.DrainMbps > 0 {
+			sum += b.DrainMbps
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return sum / float64(n)
+}
+
+// Search finds the largest lossless burst: the burst size grows from the
+// start, doubling or by a fixed step, until a train loses frames or the
+// maximum is reached, and is then bisected between the largest lossless
+// and the smallest lossy size down to the resolution
+type Search struct {
+	start, max, step, resolution uint32
+
+	lo, hi uint32 // Largest lossless, smallest lossy size (0 = none yet)
+	tried  bool
+}
+
+// NewSearch starts a search at start frames per burst; step 0 doubles
+func NewSearch(start, maxFrames, step, resolution uint32) *Search {
+	return &Search{start: max(start, 1), max: maxFrames, step: step, resolution: max(resolution, 1)}
+}
+
+// Next returns the burst size to try next, or false once the search is done
+func (s *Search) Next() (uint32, bool) {
+	if s.hi == 0 {
+		switch {
+		case !s.tried:
+			return min(s.start, s.max), true
+		case s.lo == 0 || s.lo >= s.max:
+			return 0, false
+		}
+		n := s.lo * 2
+		if s.step > 0 {
+			n = s.lo + s.step
+		}
+		return min(n, s.max), true
+	}
+	if s.hi-s.lo <= s.resolution {
+		return 0, false
+	}
+	return s.lo + (s.hi-s.lo)/2, true
+}
+
+// Record records whether a train of bursts of a size was lossless
+func (s *Search) Record(frames uint32, lossless bool) {
+	s.tried = true
+	switch {
+	case lossless && frames > s.lo:
+		s.lo = frames
+	case !lossless && (s.hi == 0 || frames < s.hi):
+		s.hi = frames
+	}
+}
+
+// Largest returns the largest lossless burst size found (0 = none)
+func (s *Search) Largest() uint32 {
+	return s.lo
+}
+
+// FirstLoss returns the smallest burst size that lost frames (0 = none)
+func (s *Search) FirstLoss() uint32 {
+	return s.hi
+}
+
+// Summary is the buffering one inter-burst gap shows
+type Summary struct {
+	GapUs             float64 `json:"gap_us"`
+	BurstUs           float64 `json:"burst_us"`       // Length of the largest lossless burst
+	DutyCyclePct      float64 `json:"duty_cycle_pct"` // Its share of a burst plus gap
+	MaxLosslessFrames uint32  `json:"max_lossless_frames"`
+	FirstLossFrames   uint32  `json:"first_loss_frames,omitempty"` // 0 = no loss up to the maximum
+	DrainMbps         float64 `json:"drain_mbps"`                  // Egress rate, configured or measured
+	DrainMeasured     bool    `json:"drain_measured"`
+	BufferFrames      float64 `json:"buffer_frames"` // Frames queued at the end of the largest lossless burst
+	BufferBytes       float64 `json:"buffer_bytes"`
+	BufferUs          float64 `json:"buffer_us"` // Time to drain them
+	Verdict           string  `json:"verdict"`
+}
+
+// Report is the buffer-depth search at one frame size, one search per
+// inter-burst gap
+type Report struct {
+	FrameSize uint32  `json:"frame_size"`
+	Bursts    uint32  `json:"bursts"` // Bursts per train
+	RatePct   float64 `json:"burst_rate_pct"`
+	BurstMbps float64 `json:"burst_mbps,omitempty"` // 0 = line rate unknown
+	Gaps      []Gap   `json:"gaps"`
+}
+
+// Gap is the search at one gap: every train sent, in order
+type Gap struct {
+	Gap     time.Duration `json:"gap"`
+	Trains  []Train       `json:"trains"`
+	Summary Summary       `json:"summary"`
+}
+
+// FirstLossTrain returns the train of the smallest lossy burst size, or nil
+func (g *Gap) FirstLossTrain() *Train {
+	for i := range g.Trains {
+		if g.Trains[i].Frames == g.Summary.FirstLossFrames && !g.Trains[i].Lossless() {
+			return &g.Trains[i]
+		}
+	}
+	return nil
+}
+
+// Analyze summarises a gap's search. burstMbps is the rate within a
+// burst; egressMbps is the rate the DUT drains at, 0 to measure it from
+// the largest lossless train.
+func Analyze(trains []Train, s *Search, frameSize uint32, burstMbps, egressMbps float64, gap time.Duration) Summary {
+	sum := Summary{
+		GapUs:             float64(gap) / float64(time.Microsecond),
+		MaxLosslessFrames: s.Largest(),
+		FirstLossFrames:   s.FirstLoss(),
+		DrainMbps:         egressMbps,
+	}
+	if sum.MaxLosslessFrames == 0 {
+		sum.Verdict = fmt.Sprintf("Loss in bursts of %d frames, the smallest tried", sum.FirstLossFrames)
+		return sum
+	}
+	wireBits := float64(frameSize+wireOverhead) * 8
+	if burstMbps > 0 {
+		sum.BurstUs = float64(sum.MaxLosslessFrames) * wireBits / burstMbps
+	}
+	if sum.BurstUs+sum.GapUs > 0 {
+		sum.DutyCyclePct = 100 * sum.BurstUs / (sum.BurstUs + sum.GapUs)
+	}
+	if sum.DrainMbps <= 0 {
+		for _, t := range trains {
+			if t.Frames == sum.MaxLosslessFrames && t.Lossless() {
+				sum.DrainMbps, sum.DrainMeasured = t.drainMbps(), true
+				break
+			}
+		}
+	}
+
+	queued := float64(sum.MaxLosslessFrames)
+	if sum.DrainMbps > 0 && burstMbps > 0 {
+		queued *= max(0, 1-sum.DrainMbps/burstMbps)
+	}
+	sum.BufferFrames = queued
+	sum.BufferBytes = queued * float64(frameSize)
+	if sum.DrainMbps > 0 {
+		sum.BufferUs = queued * wireBits / sum.DrainMbps
+	}
+
+	switch {
+	case sum.FirstLossFrames == 0:
+		sum.Verdict = fmt.Sprintf("No loss up to bursts of %d frames", sum.MaxLosslessFrames)
+	case queued < 1:
+		sum.Verdict = fmt.Sprintf("Loss from %d-frame bursts, but egress drains at the burst rate: not buffer overflow",
+			sum.FirstLossFrames)
+	default:
+		sum.Verdict = fmt.Sprintf("Buffer of about %.0f frames (%.0f bytes, %.1f us) before loss at %d-frame bursts",
+			sum.BufferFrames, sum.BufferBytes, sum.BufferUs, sum.FirstLossFrames)
+		if sum.BufferUs > sum.GapUs {
+			sum.Verdict += "; the gap is shorter than the queue takes to drain, so bursts build on each other"
+		}
+	}
+	return sum
+}
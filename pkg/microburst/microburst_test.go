@@ -0,0 +1,117 @@
+package microburst
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// search runs a search against a DUT that drops bursts above limit
+func search(s *Search, limit uint32) []uint32 {
+	var tried []uint32
+	for {
+		n, ok := s.Next()
+		if !ok {
+			return tried
+		}
+		tried = append(tried, n)
+		s.Record(n, n <= limit)
+	}
+}
+
+func TestSearchDoubling(t *testing.T) {
+	s := NewSearch(16, 100000, 0, 8)
+	tried := search(s, 300)
+	if s.Largest() < 292 || s.Largest() > 300 || s.FirstLoss() <= 300 || s.FirstLoss()-s.Largest() > 8 {
+		t.Errorf("largest %d, first loss %d after %v", s.Largest(), s.FirstLoss(), tried)
+	}
+	if tried[0] != 16 || tried[1] != 32 || tried[5] != 512 {
+		t.Errorf("tried %v, want doubling from 16", tried)
+	}
+
+	s = NewSearch(16, 1000, 0, 8)
+	search(s, 5000)
+	if s.Largest() != 1000 || s.FirstLoss() != 0 {
+		t.Errorf("no loss: largest %d, first loss %d", s.Largest(), s.FirstLoss())
+	}
+
+	s = NewSearch(16, 1000, 0, 1)
+	search(s, 5)
+	if s.Largest() != 5 || s.FirstLoss() != 6 {
+		t.Errorf("loss below start: largest %d, first loss %d", s.Largest(), s.FirstLoss())
+	}
+	s = NewSearch(16, 1000, 0, 1)
+	search(s, 0)
+	if s.Largest() != 0 || s.FirstLoss() != 1 {
+		t.Errorf("every burst lossy: largest %d, first loss %d", s.Largest(), s.FirstLoss())
+	}
+}
+
+func TestSearchStep(t *testing.T) {
+	s := NewSearch(100, 1000, 100, 1)
+	tried := search(s, 450)
+	if tried[1] != 200 || tried[4] != 500 {
+		t.Errorf("tried %v, want steps of 100", tried)
+	}
+	if s.Largest() != 450 || s.FirstLoss() != 451 {
+		t.Errorf("largest %d, first loss %d", s.Largest(), s.FirstLoss())
+	}
+}
+
+func TestNewTrain(t *testing.T) {
+	tr := NewTrain(100, []Burst{
+		NewBurst(64, 100, 100, 5000, 6720),
+		NewBurst(64, 100, 90, 9000, 6000),
+		NewBurst(64, 100, 95, 9000, 6000),
+	})
+	if tr.FramesTx != 300 || tr.FramesRx != 285 || tr.LossPct != 5 {
+		t.Errorf("totals %+v", tr)
+	}
+	if tr.LossyBursts != 2 || tr.FirstLossBurst != 2 || tr.Lossless() {
+		t.Errorf("lossy %d, first %d", tr.LossyBursts, tr.FirstLossBurst)
+	}
+	if b := tr.Bursts[1]; b.LossPct != 10 || b.MaxUs != 9 {
+		t.Errorf("burst 2 = %+v", b)
+	}
+	// 99 gaps of 84 bytes in 6.72 us is 9.9 Gbit/s
+	if d := tr.Bursts[0].DrainMbps; math.Abs(d-9900) > 1e-6 {
+		t.Errorf("drain %.1f Mbps", d)
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	// 10G in, 1G out: each lossless 1000-frame burst leaves 900 queued
+	s := NewSearch(1000, 1000000, 0, 1)
+	s.Record(1000, true)
+	s.Record(1001, false)
+	trains := []Train{NewTrain(1000, []Burst{NewBurst(64, 1000, 1000, 0, 671328)})}
+
+	sum := Analyze(trains, s, 64, 10000, 1000, 100*time.Microsecond)
+	if math.Abs(sum.BufferFrames-900) > 1e-9 || math.Abs(sum.BufferBytes-57600) > 1e-6 {
+		t.Errorf("buffer %.1f frames %.0f bytes", sum.BufferFrames, sum.BufferBytes)
+	}
+	if math.Abs(sum.BufferUs-604.8) > 1e-6 || math.Abs(sum.BurstUs-67.2) > 1e-9 {
+		t.Errorf("buffer %.1f us, burst %.1f us", sum.BufferUs, sum.BurstUs)
+	}
+	if sum.DrainMeasured || sum.FirstLossFrames != 1001 {
+		t.Errorf("summary %+v", sum)
+	}
+
+	// Measured from the lossless train: 999 gaps of 672 ns is 1 Gbit/s
+	sum = Analyze(trains, s, 64, 10000, 0, time.Millisecond)
+	if !sum.DrainMeasured || math.Abs(sum.DrainMbps-1000) > 1e-6 || math.Abs(sum.BufferFrames-900) > 1e-9 {
+		t.Errorf("measured summary %+v", sum)
+	}
+
+	// Egress at the burst rate queues nothing
+	sum = Analyze(trains, s, 64, 1000, 1000, time.Millisecond)
+	if sum.BufferFrames != 0 {
+		t.Errorf("no queueing: %+v", sum)
+	}
+
+	s = NewSearch(16, 16, 0, 1)
+	s.Record(16, true)
+	if sum := Analyze(nil, s, 64, 10000, 0, time.Millisecond); sum.FirstLossFrames != 0 || sum.MaxLosslessFrames != 16 {
+		t.Errorf("no loss: %+v", sum)
+	}
+}
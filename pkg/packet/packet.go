@@ -0,0 +1,368 @@
+// Package packet compiles packet template expressions into the headers
+// the dataplane places in front of the RFC 2544 payload
+//
+// An expression is a Scapy-style stack of layers separated by "/", each
+// with optional field overrides:
+//
+//	eth/dot1q(vlan=100,pcp=5)/ipv6(src=2001:db8::1,dst=2001:db8::2)/udp(dport=3842)
+//
+// The stack starts with eth, may carry up to two VLAN tags (dot1q, dot1ad),
+// and ends with ipv4 or ipv6 followed by udp. Fields not given take the
+// dataplane's defaults; EtherTypes and protocol numbers follow the stack.
+// Lengths and checksums depend on the frame size and are filled in by the
+// dataplane for each trial.
+package packet
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// PayloadLen is the size of the RFC 2544 payload following the headers
+const PayloadLen = 24
+
+// MaxHeaderLen is the largest header the dataplane accepts
+const MaxHeaderLen = 128
+
+// Layer is one parsed layer of an expression
+type Layer struct {
+	Name   string
+	Fields map[string]string
+}
+
+// Header is a compiled template
+type Header struct {
+	Bytes      []byte // Headers up to the payload, lengths and checksums zeroed
+	IPv4Offset int    // -1 if the stack is IPv6
+	IPv6Offset int    // -1 if the stack is IPv4
+	UDPOffset  int
+	FillSrcMAC bool // eth src not given: the interface MAC is used
+	FillDstMAC bool // eth dst not given: the DUT MAC is used
+}
+
+// MinFrameSize is the smallest frame that fits the headers and payload
+func (h *Header) MinFrameSize() uint32 {
+	return uint32(len(h.Bytes)) + PayloadLen
+}
+
+// Layer names and their aliases
+var layerNames = map[string]string{
+	"eth": "eth", "ether": "eth",
+	"dot1q": "dot1q", "vlan": "dot1q",
+	"dot1ad": "dot1ad", "qinq": "dot1ad",
+	"ipv4": "ipv4", "ip": "ipv4",
+	"ipv6": "ipv6",
+	"udp":  "udp",
+}
+
+// Fields each layer accepts
+var layerFields = map[string][]string{
+	"eth":    {"src", "dst", "type"},
+	"dot1q":  {"vlan", "pcp", "dei"},
+	"dot1ad": {"vlan", "pcp", "dei"},
+	"ipv4":   {"src", "dst", "tos", "ttl", "id", "df", "proto"},
+	"ipv6":   {"src", "dst", "tc", "fl", "hlim", "nh"},
+	"udp":    {"sport", "dport"},
+}
+
+// Parse splits an expression into layers
+func Parse(expr string) ([]Layer, error) {
+	var layers []Layer
+	for _, part := range strings.Split(expr, "/") {
+		part = strings.TrimSpace(part)
+		name, args := part, ""
+		if i := strings.IndexByte(part, '('); i >= 0 {
+			if !strings.HasSuffix(part, ")") {
+				return nil, fmt.Errorf("layer %q: missing )", part)
+			}
+			name, args = strings.TrimSpace(part[:i]), part[i+1:len(part)-1]
+		}
+		canonical, ok := layerNames[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown layer %q", name)
+		}
+
+		layer := Layer{Name: canonical, Fields: make(map[string]string)}
+		if strings.TrimSpace(args) != "" {
+			for _, kv := range strings.Split(args, ",") {
+				k, v, ok := strings.Cut(kv, "=")
+				k, v = strings.ToLower(strings.TrimSpace(k)), strings.TrimSpace(v)
+				if !ok || k == "" || v == "" {
+					return nil, fmt.Errorf("%s: field %q must be name=value", canonical, strings.TrimSpace(kv))
+				}
+				if !knownField(canonical, k) {
+					return nil, fmt.Errorf("%s: unknown field %q", canonical, k)
+				}
+				if _, dup := layer.Fields[k]; dup {
+					return nil, fmt.Errorf("%s: field %q given twice", canonical, k)
+				}
+				layer.Fields[k] = v
+			}
+		}
+		layers = append(layers, layer)
+	}
+	return layers, nil
+}
+
+func knownField(layer, field string) bool {
+	for _, f := range layerFields[layer] {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// Compile parses and encodes an expression
+func Compile(expr string) (*Header, error) {
+	layers, err := Parse(expr)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkStack(layers); err != nil {
+		return nil, err
+	}
+
+	h := &Header{IPv4Offset: -1, IPv6Offset: -1}
+	for i, l := range layers {
+		var next string
+		if i+1 < len(layers) {
+			next = layers[i+1].Name
+		}
+		offset := len(h.Bytes)
+		var b []byte
+		switch l.Name {
+		case "eth":
+			b, err = encodeEth(l, next, h)
+		case "dot1q", "dot1ad":
+			b, err = encodeVLAN(l, next)
+		case "ipv4":
+			h.IPv4Offset = offset
+			b, err = encodeIPv4(l)
+		case "ipv6":
+			h.IPv6Offset = offset
+			b, err = encodeIPv6(l)
+		case "udp":
+			h.UDPOffset = offset
+			b, err = encodeUDP(l)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", l.Name, err)
+		}
+		h.Bytes = append(h.Bytes, b...)
+	}
+	if len(h.Bytes) > MaxHeaderLen {
+		return nil, fmt.Errorf("headers are %d bytes, at most %d supported", len(h.Bytes), MaxHeaderLen)
+	}
+	return h, nil
+}
+
+// checkStack requires eth, up to two VLAN tags, an IP layer and udp
+func checkStack(layers []Layer) error {
+	n := len(layers)
+	if n < 3 || layers[0].Name != "eth" || layers[n-1].Name != "udp" {
+		return fmt.Errorf("template must start with eth and end with ipv4/udp or ipv6/udp")
+	}
+	if ip := layers[n-2].Name; ip != "ipv4" && ip != "ipv6" {
+		return fmt.Errorf("udp must follow ipv4 or ipv6, not %s", ip)
+	}
+	tags := layers[1 : n-2]
+	if len(tags) > 2 {
+		return fmt.Errorf("at most two VLAN tags are supported")
+	}
+	for _, t := range tags {
+		if t.Name != "dot1q" && t.Name != "dot1ad" {
+			return fmt.Errorf("%s must follow the VLAN tags", t.Name)
+		}
+	}
+	return nil
+}
+
+// etherTypeOf is the EtherType announcing a layer
+func etherTypeOf(layer string) uint16 {
+	switch layer {
+	case "dot1q":
+		return 0x8100
+	case "dot1ad":
+		return 0x88A8
+	case "ipv6":
+		return 0x86DD
+	default:
+		return 0x0800
+	}
+}
+
+func encodeEth(l Layer, next string, h *Header) ([]byte, error) {
+	b := make([]byte, 14)
+	if v, ok := l.Fields["dst"]; ok {
+		mac, err := net.ParseMAC(v)
+		if err != nil || len(mac) != 6 {
+			return nil, fmt.Errorf("invalid dst %q", v)
+		}
+		copy(b[0:], mac)
+	} else {
+		h.FillDstMAC = true
+	}
+	if v, ok := l.Fields["src"]; ok {
+		mac, err := net.ParseMAC(v)
+		if err != nil || len(mac) != 6 {
+			return nil, fmt.Errorf("invalid src %q", v)
+		}
+		copy(b[6:], mac)
+	} else {
+		h.FillSrcMAC = true
+	}
+	etherType, err := uintField(l, "type", 16, uint64(etherTypeOf(next)))
+	if err != nil {
+		return nil, err
+	}
+	binary.BigEndian.PutUint16(b[12:], uint16(etherType))
+	return b, nil
+}
+
+func encodeVLAN(l Layer, next string) ([]byte, error) {
+	vid, err := uintField(l, "vlan", 12, 0)
+	if err != nil {
+		return nil, err
+	}
+	pcp, err := uintField(l, "pcp", 3, 0)
+	if err != nil {
+		return nil, err
+	}
+	dei, err := uintField(l, "dei", 1, 0)
+	if err != nil {
+		return nil, err
+	}
+	// The TPID is written by the preceding layer's EtherType
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint16(b[0:], uint16(pcp<<13|dei<<12|vid))
+	binary.BigEndian.PutUint16(b[2:], etherTypeOf(next))
+	return b, nil
+}
+
+func encodeIPv4(l Layer) ([]byte, error) {
+	b := make([]byte, 20)
+	tos, err := uintField(l, "tos", 8, 0)
+	if err != nil {
+		return nil, err
+	}
+	id, err := uintField(l, "id", 16, 0x1234)
+	if err != nil {
+		return nil, err
+	}
+	df, err := uintField(l, "df", 1, 1)
+	if err != nil {
+		return nil, err
+	}
+	ttl, err := uintField(l, "ttl", 8, 64)
+	if err != nil {
+		return nil, err
+	}
+	proto, err := uintField(l, "proto", 8, 17)
+	if err != nil {
+		return nil, err
+	}
+	b[0] = 0x45 // IPv4, 20 byte header
+	b[1] = byte(tos)
+	binary.BigEndian.PutUint16(b[4:], uint16(id))
+	b[6] = byte(df << 6) // Don't fragment
+	b[8] = byte(ttl)
+	b[9] = byte(proto)
+	for _, a := range []struct {
+		name string
+		def  string
+		off  int
+	}{{"src", "10.0.0.1", 12}, {"dst", "10.0.0.2", 16}} {
+		ip, err := ipField(l, a.name, a.def)
+		if err != nil {
+			return nil, err
+		}
+		v4 := ip.To4()
+		if v4 == nil {
+			return nil, fmt.Errorf("%s %s is not an IPv4 address", a.name, ip)
+		}
+		copy(b[a.off:], v4)
+	}
+	return b, nil
+}
+
+func encodeIPv6(l Layer) ([]byte, error) {
+	b := make([]byte, 40)
+	tc, err := uintField(l, "tc", 8, 0)
+	if err != nil {
+		return nil, err
+	}
+	fl, err := uintField(l, "fl", 20, 0)
+	if err != nil {
+		return nil, err
+	}
+	nh, err := uintField(l, "nh", 8, 17)
+	if err != nil {
+		return nil, err
+	}
+	hlim, err := uintField(l, "hlim", 8, 64)
+	if err != nil {
+		return nil, err
+	}
+	binary.BigEndian.PutUint32(b[0:], uint32(6<<28|tc<<20|fl))
+	b[6] = byte(nh)
+	b[7] = byte(hlim)
+	for _, a := range []struct {
+		name string
+		def  string
+		off  int
+	}{{"src", "fe80::1", 8}, {"dst", "fe80::2", 24}} {
+		ip, err := ipField(l, a.name, a.def)
+		if err != nil {
+			return nil, err
+		}
+		if ip.To4() != nil {
+			return nil, fmt.Errorf("%s %s is not an IPv6 address", a.name, ip)
+		}
+		copy(b[a.off:], ip.To16())
+	}
+	return b, nil
+}
+
+func encodeUDP(l Layer) ([]byte, error) {
+	b := make([]byte, 8)
+	sport, err := uintField(l, "sport", 16, 12345)
+	if err != nil {
+		return nil, err
+	}
+	dport, err := uintField(l, "dport", 16, 3842)
+	if err != nil {
+		return nil, err
+	}
+	binary.BigEndian.PutUint16(b[0:], uint16(sport))
+	binary.BigEndian.PutUint16(b[2:], uint16(dport))
+	return b, nil
+}
+
+// uintField parses a decimal or 0x-prefixed field of the given width
+func uintField(l Layer, name string, bits int, def uint64) (uint64, error) {
+	s, ok := l.Fields[name]
+	if !ok {
+		return def, nil
+	}
+	v, err := strconv.ParseUint(s, 0, 64)
+	if err != nil || v >= 1<<uint(bits) {
+		return 0, fmt.Errorf("invalid %s %q (%d-bit value)", name, s, bits)
+	}
+	return v, nil
+}
+
+func ipField(l Layer, name, def string) (net.IP, error) {
+	s, ok := l.Fields[name]
+	if !ok {
+		s = def
+	}
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid %s %q", name, s)
+	}
+	return ip, nil
+}
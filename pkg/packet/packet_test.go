@@ -0,0 +1,108 @@
+package packet
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCompileDefault(t *testing.T) {
+	h, err := Compile("eth/ipv4/udp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(h.Bytes) != 42 || h.IPv4Offset != 14 || h.IPv6Offset != -1 || h.UDPOffset != 34 {
+		t.Fatalf("header = %+v", h)
+	}
+	if !h.FillSrcMAC || !h.FillDstMAC {
+		t.Error("MACs not given should be filled by the dataplane")
+	}
+	if h.MinFrameSize() != 66 {
+		t.Errorf("MinFrameSize = %d, want 66", h.MinFrameSize())
+	}
+
+	// Same addresses and ports as the built-in frame
+	b := h.Bytes
+	if b[12] != 0x08 || b[13] != 0x00 || b[14] != 0x45 || b[22] != 64 || b[23] != 17 {
+		t.Errorf("eth/ipv4 = % x", b[12:24])
+	}
+	if !bytes.Equal(b[26:34], []byte{10, 0, 0, 1, 10, 0, 0, 2}) {
+		t.Errorf("addresses = % x", b[26:34])
+	}
+	if !bytes.Equal(b[34:38], []byte{0x30, 0x39, 0x0f, 0x02}) {
+		t.Errorf("ports = % x", b[34:38])
+	}
+}
+
+func TestCompileVLANIPv6(t *testing.T) {
+	h, err := Compile("eth(dst=02:00:00:00:00:fe)/dot1ad(vlan=10)/dot1q(vlan=100,pcp=5)/ipv6(src=2001:db8::1,dst=2001:db8::2,hlim=32,tc=0xb8)/udp(dport=7)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(h.Bytes) != 14+4+4+40+8 || h.IPv6Offset != 22 || h.UDPOffset != 62 || h.IPv4Offset != -1 {
+		t.Fatalf("header = %+v", h)
+	}
+	if h.FillDstMAC || !h.FillSrcMAC {
+		t.Errorf("fill = src %v dst %v", h.FillSrcMAC, h.FillDstMAC)
+	}
+
+	b := h.Bytes
+	want := []byte{
+		0x88, 0xa8, 0x00, 0x0a, // S-tag, VID 10
+		0x81, 0x00, 0xa0, 0x64, // C-tag, PCP 5, VID 100
+		0x86, 0xdd,
+	}
+	if !bytes.Equal(b[12:22], want) {
+		t.Errorf("tags = % x, want % x", b[12:22], want)
+	}
+	if b[22] != 0x6b || b[23] != 0x80 || b[28] != 17 || b[29] != 32 {
+		t.Errorf("ipv6 = % x", b[22:30])
+	}
+	if b[30] != 0x20 || b[31] != 0x01 || b[45] != 1 || b[61] != 2 {
+		t.Errorf("ipv6 addresses = % x", b[30:62])
+	}
+	if b[64] != 0 || b[65] != 7 {
+		t.Errorf("dport = % x", b[64:66])
+	}
+}
+
+func TestCompileOverrides(t *testing.T) {
+	h, err := Compile(" Ether(type=0x8847) / IP(ttl=1,df=0,tos=0x10,proto=6) / UDP ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := h.Bytes
+	if b[12] != 0x88 || b[13] != 0x47 {
+		t.Errorf("ethertype = % x", b[12:14])
+	}
+	if b[15] != 0x10 || b[20] != 0 || b[22] != 1 || b[23] != 6 {
+		t.Errorf("ipv4 = % x", b[14:24])
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := map[string]string{
+		"ipv4/udp":                                    "start with eth",
+		"eth/ipv4":                                    "end with",
+		"eth/udp/udp":                                 "must follow ipv4 or ipv6",
+		"eth/dot1q/dot1q/dot1q/ipv4/udp":              "two VLAN tags",
+		"eth/ipv4/ipv4/udp":                           "must follow the VLAN tags",
+		"eth/mpls/ipv4/udp":                           "unknown layer",
+		"eth/dot1q(vlan=4096)/ipv4/udp":               "invalid vlan",
+		"eth/dot1q(prio=1)/ipv4/udp":                  "unknown field",
+		"eth/ipv4(ttl)/udp":                           "name=value",
+		"eth/ipv4(ttl=1,ttl=2)/udp":                   "given twice",
+		"eth/ipv4(src=2001:db8::1)/udp":               "not an IPv4 address",
+		"eth/ipv6(dst=10.0.0.1)/udp":                  "not an IPv6 address",
+		"eth(src=02:00:00:00:00)/ipv4/udp":            "invalid src",
+		"eth/ipv4(dst=10.0.0.300)/udp":                "invalid dst",
+		"eth/ipv4/udp(sport=70000":                    "missing )",
+		"eth/ipv6(fl=0x100000)/udp(dport=7,sport=-1)": "invalid fl",
+	}
+	for expr, want := range tests {
+		_, err := Compile(expr)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Compile(%q) = %v, want error containing %q", expr, err, want)
+		}
+	}
+}
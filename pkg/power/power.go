@@ -0,0 +1,193 @@
+// Package power samples DUT power draw from a PDU or BMC while a test runs
+//
+// Readings come from a Source, either ipmitool's DCMI power reading or any
+// command that prints the current draw in watts (for example an snmpget of
+// a PDU outlet). The Sampler polls the source at a fixed interval and the
+// collected statistics are used to report throughput per watt.
+package power
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Source returns the instantaneous power draw in watts
+type Source interface {
+	Name() string
+	Read(ctx context.Context) (float64, error)
+}
+
+// CommandSource runs a shell command and parses watts from its output
+type CommandSource struct {
+	Command string
+	Scale   float64 // Multiplier applied to the parsed value (0 = 1)
+}
+
+// Name returns the command being run
+func (s *CommandSource) Name() string {
+	return s.Command
+}
+
+// Read runs the command once
+func (s *CommandSource) Read(ctx context.Context) (float64, error) {
+	out, err := exec.CommandContext(ctx, "sh", "-c", s.Command).Output()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", s.Command, err)
+	}
+	w, err := ParseWatts(string(out))
+	if err != nil {
+		return 0, err
+	}
+	if s.Scale != 0 {
+		w *= s.Scale
+	}
+	return w, nil
+}
+
+// NewIPMISource reads DCMI power via ipmitool. An empty host uses the local
+// BMC in-band; otherwise the lanplus interface is used with user/password.
+func NewIPMISource(host, user, password string) *CommandSource {
+	args := []string{"ipmitool"}
+	if host != "" {
+		args = append(args, "-I", "lanplus", "-H", shellQuote(host),
+			"-U", shellQuote(user), "-P", shellQuote(password))
+	}
+	args = append(args, "dcmi", "power", "reading")
+	return &CommandSource{Command: strings.Join(args, " ")}
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+var (
+	wattsRe  = regexp.MustCompile(`(?i)([0-9]+(?:\.[0-9]+)?)\s*(?:watts?|w)\b`)
+	numberRe = regexp.MustCompile(`[0-9]+(?:\.[0-9]+)?`)
+)
+
+// ParseWatts extracts a power reading from command output. A value labelled
+// in watts is preferred (ipmitool prints "Instantaneous power reading:
+// 220 Watts"); otherwise the first number is used.
+func ParseWatts(out string) (float64, error) {
+	if m := wattsRe.FindStringSubmatch(out); m != nil {
+		return strconv.ParseFloat(m[1], 64)
+	}
+	if m := numberRe.FindString(out); m != "" {
+		return strconv.ParseFloat(m, 64)
+	}
+	return 0, errors.New("no power reading in output")
+}
+
+// Stats summarizes the readings taken during a run
+type Stats struct {
+	Source   string  `json:"source"`
+	Samples  uint64  `json:"samples"`
+	Errors   uint64  `json:"errors"`
+	AvgWatts float64 `json:"avg_watts"`
+	MinWatts float64 `json:"min_watts"`
+	MaxWatts float64 `json:"max_watts"`
+}
+
+// MbpsPerWatt returns throughput efficiency, or 0 with no readings
+func (s Stats) MbpsPerWatt(mbps float64) float64 {
+	if s.Samples == 0 || s.AvgWatts <= 0 {
+		return 0
+	}
+	return mbps / s.AvgWatts
+}
+
+// Sampler polls a Source at a fixed interval
+type Sampler struct {
+	source   Source
+	interval time.Duration
+
+	mu     sync.Mutex
+	stats  Stats
+	sum    float64
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewSampler creates a sampler reading source every interval
+func NewSampler(source Source, interval time.Duration) *Sampler {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	return &Sampler{
+		source:   source,
+		interval: interval,
+		stats:    Stats{Source: source.Name()},
+	}
+}
+
+// Start begins sampling in the background
+func (s *Sampler) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	s.wg.Add(1)
+	go s.loop(ctx)
+}
+
+// Stop halts sampling and returns the collected statistics
+func (s *Sampler) Stop() Stats {
+	if s.cancel != nil {
+		s.cancel()
+		s.wg.Wait()
+		s.cancel = nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stats.Samples > 0 {
+		s.stats.AvgWatts = s.sum / float64(s.stats.Samples)
+	}
+	return s.stats
+}
+
+func (s *Sampler) loop(ctx context.Context) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.sample(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Sampler) sample(ctx context.Context) {
+	rctx, cancel := context.WithTimeout(ctx, s.interval)
+	defer cancel()
+	w, err := s.source.Read(rctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		// Readings cut short by Stop are not source errors
+		if ctx.Err() == nil {
+			s.stats.Errors++
+		}
+		return
+	}
+	if s.stats.Samples == 0 || w < s.stats.MinWatts {
+		s.stats.MinWatts = w
+	}
+	if w > s.stats.MaxWatts {
+		s.stats.MaxWatts = w
+	}
+	s.stats.Samples++
+	s.sum += w
+}
@@ -0,0 +1,102 @@
+package power
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseWatts(t *testing.T) {
+	tests := []struct {
+		out  string
+		want float64
+	}{
+		{"    Instantaneous power reading:                   220 Watts\n    Minimum during sampling period:  180 Watts\n", 220},
+		{"Outlet 3: 1.8 A, 412.5 W\n", 412.5},
+		{"3171\n", 3171},
+	}
+	for _, tt := range tests {
+		got, err := ParseWatts(tt.out)
+		if err != nil {
+			t.Errorf("ParseWatts(%q): %v", tt.out, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseWatts(%q) = %.1f, want %.1f", tt.out, got, tt.want)
+		}
+	}
+
+	if _, err := ParseWatts("No reading available"); err == nil {
+		t.Error("Expected error for output without a number")
+	}
+}
+
+func TestCommandSource(t *testing.T) {
+	src := &CommandSource{Command: "echo 3171", Scale: 0.1}
+	w, err := src.Read(context.Background())
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if w < 317.09 || w > 317.11 {
+		t.Errorf("Read = %.2f, want 317.1", w)
+	}
+
+	if _, err := (&CommandSource{Command: "exit 1"}).Read(context.Background()); err == nil {
+		t.Error("Expected error from failing command")
+	}
+}
+
+func TestNewIPMISource(t *testing.T) {
+	if got := NewIPMISource("", "", "").Command; got != "ipmitool dcmi power reading" {
+		t.Errorf("in-band command = %q", got)
+	}
+	want := "ipmitool -I lanplus -H 'bmc1' -U 'admin' -P 'p'\\''w' dcmi power reading"
+	if got := NewIPMISource("bmc1", "admin", "p'w").Command; got != want {
+		t.Errorf("lanplus command = %q, want %q", got, want)
+	}
+}
+
+type fakeSource struct {
+	mu       sync.Mutex
+	readings []float64
+	n        int
+}
+
+func (f *fakeSource) Name() string { return "fake" }
+
+func (f *fakeSource) Read(ctx context.Context) (float64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.n >= len(f.readings) {
+		return 0, errors.New("no more readings")
+	}
+	w := f.readings[f.n]
+	f.n++
+	return w, nil
+}
+
+func TestSampler(t *testing.T) {
+	src := &fakeSource{readings: []float64{100, 300, 200}}
+	s := NewSampler(src, 10*time.Millisecond)
+	s.Start()
+	time.Sleep(100 * time.Millisecond)
+	stats := s.Stop()
+
+	if stats.Samples != 3 {
+		t.Fatalf("Samples = %d, want 3", stats.Samples)
+	}
+	if stats.AvgWatts != 200 || stats.MinWatts != 100 || stats.MaxWatts != 300 {
+		t.Errorf("Stats = %+v, want avg 200 min 100 max 300", stats)
+	}
+	if stats.Errors == 0 {
+		t.Error("Expected errors once readings ran out")
+	}
+	if got := stats.MbpsPerWatt(1000); got != 5 {
+		t.Errorf("MbpsPerWatt = %.2f, want 5", got)
+	}
+	if (Stats{}).MbpsPerWatt(1000) != 0 {
+		t.Error("MbpsPerWatt without samples should be 0")
+	}
+}
@@ -0,0 +1,171 @@
+package preflight
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/krisarmstrong/rfc2544-master/pkg/config"
+	"github.com/krisarmstrong/rfc2544-master/pkg/dataplane"
+)
+
+// Status is the outcome of a dry-run check
+type Status string
+
+const (
+	OK   Status = "ok"
+	Warn Status = "warn"
+	Fail Status = "FAIL"
+)
+
+// Result is one dry-run check
+type Result struct {
+	Status  Status
+	Message string
+}
+
+// Checks records the dry-run checks of a run, in the order they ran
+type Checks struct {
+	Results []Result
+	Failed  int
+}
+
+func (c *Checks) ok(format string, a ...interface{}) {
+	c.Results = append(c.Results, Result{OK, fmt.Sprintf(format, a...)})
+}
+
+func (c *Checks) warn(format string, a ...interface{}) {
+	c.Results = append(c.Results, Result{Warn, fmt.Sprintf(format, a...)})
+}
+
+func (c *Checks) fail(format string, a ...interface{}) {
+	c.Failed++
+	c.Results = append(c.Results, Result{Fail, fmt.Sprintf(format, a...)})
+}
+
+// Check checks the config, the interfaces the run opens and the
+// privileges the dataplane needs, without opening the dataplane.
+// frameSizes are the sizes the interfaces' MTU must carry.
+func Check(cfg *config.Config, frameSizes []uint32) *Checks {
+	c := &Checks{}
+	if err := cfg.Validate(); err != nil {
+		c.fail("Configuration: %v", err)
+	} else {
+		c.ok("Configuration is valid")
+	}
+
+	if cfg.TRex.Enabled() {
+		// The server is only contacted when the run starts
+		c.ok("TRex server %s (port %d -> %d), not contacted", cfg.TRex.Server, cfg.TRex.TxPort, cfg.TRex.RxPort)
+	} else if cfg.Socket.Enabled() {
+		c.ok("Socket mode reflector %s, not contacted", cfg.Socket.Target)
+		c.warn("Socket mode: rates capped at %d pps, round-trip latency with software timestamps", cfg.Socket.MaxPPS)
+	} else if cfg.TestType == config.TestUDPEcho {
+		// Unprivileged UDP sockets; no interface is opened
+		c.ok("UDP reflector %s, not contacted", cfg.UDPEcho.Target)
+	} else {
+		for _, name := range PortNames(cfg) {
+			c.checkInterface(cfg, name, frameSizes)
+		}
+		if cfg.Ports.Enabled() && cfg.HWTimestamp {
+			c.warn("Ports %s -> %s: latency uses software timestamps, the NICs do not share a clock", cfg.Interface, cfg.Ports.RX)
+		}
+		c.checkPrivileges()
+		if dataplane.PureGo {
+			c.warn("Dataplane: %s, rates are limited by the host's socket performance", dataplane.Backend)
+		}
+	}
+	if ports := cfg.InServicePorts(); len(ports) > 0 {
+		c.warn("%s in service: the run needs --ack-impact", strings.Join(ports, ", "))
+	}
+	return c
+}
+
+// PortNames returns the interfaces the dataplane opens: the transmit
+// interface, then the receive port of a port pair
+func PortNames(cfg *config.Config) []string {
+	if cfg.Ports.Enabled() {
+		return []string{cfg.Interface, cfg.Ports.RX}
+	}
+	return []string{cfg.Interface}
+}
+
+// checkInterface checks the interface exists and its MTU carries the
+// largest frame size
+func (c *Checks) checkInterface(cfg *config.Config, name string, frameSizes []uint32) {
+	nic, err := dataplane.DetectNIC(name)
+	if err != nil {
+		c.fail("%v (see 'rfc2544 list-interfaces')", err)
+		return
+	}
+	if nic.Up && nic.LinkSpeed > 0 {
+		c.ok("Interface %s is up at %s", nic.Name, dataplane.FormatLinkSpeed(nic.LinkSpeed))
+	} else if nic.Up {
+		c.ok("Interface %s is up", nic.Name)
+	} else {
+		c.warn("Interface %s link is %s", nic.Name, nic.OperState)
+	}
+	if cfg.HWTimestamp && !nic.HWTimestamp && !cfg.Ports.Enabled() {
+		c.warn("%s does not support hardware timestamping, latency uses software timestamps", nic.Name)
+	}
+	if cfg.LineRateMbps == 0 && nic.LinkSpeed == 0 {
+		c.warn("Link speed of %s is unknown, set line_rate_mbps in the config", nic.Name)
+	}
+
+	// Frame sizes include the Ethernet header and FCS, the MTU does not
+	var largest uint32
+	for _, fs := range frameSizes {
+		if fs > largest {
+			largest = fs
+		}
+	}
+	if largest == 0 {
+		return
+	}
+	if need := largest - 18; nic.MTU < need {
+		c.fail("MTU %d of %s is too small for %d byte frames (needs %d)", nic.MTU, nic.Name, largest, need)
+	} else {
+		c.ok("MTU %d carries %d byte frames", nic.MTU, largest)
+	}
+}
+
+// Capabilities the dataplane needs to open raw and AF_XDP sockets
+var requiredCaps = []struct {
+	name string
+	bit  uint
+}{
+	{"CAP_NET_ADMIN", 12},
+	{"CAP_NET_RAW", 13},
+}
+
+// checkPrivileges checks the effective capabilities of the process
+func (c *Checks) checkPrivileges() {
+	data, err := os.ReadFile("/proc/self/status")
+	if err != nil {
+		if os.Geteuid() == 0 {
+			c.ok("Running as root")
+		} else {
+			c.fail("Not running as root (run with sudo)")
+		}
+		return
+	}
+
+	var capEff uint64
+	for _, line := range strings.Split(string(data), "\n") {
+		if v, ok := strings.CutPrefix(line, "CapEff:"); ok {
+			capEff, _ = strconv.ParseUint(strings.TrimSpace(v), 16, 64)
+		}
+	}
+	var missing []string
+	for _, cp := range requiredCaps {
+		if capEff&(1<<cp.bit) == 0 {
+			missing = append(missing, cp.name)
+		}
+	}
+	if len(missing) > 0 {
+		c.fail("Missing %s (run with sudo or setcap cap_net_admin,cap_net_raw+ep)", strings.Join(missing, ", "))
+		return
+	}
+	c.ok("Privileges: CAP_NET_ADMIN, CAP_NET_RAW")
+}
@@ -0,0 +1,37 @@
+package preflight
+
+import (
+	"testing"
+
+	"github.com/krisarmstrong/rfc2544-master/pkg/config"
+)
+
+func TestCheckSocketMode(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.TestType = config.TestThroughput
+	cfg.Socket.Target = "192.0.2.1:7"
+	cfg.DSCP = 70
+
+	// No interface is probed: the reflector is only contacted by the run
+	c := Check(cfg, cfg.TestFrameSizes())
+	if c.Failed != 1 || len(c.Results) != 3 {
+		t.Fatalf("Check() = %+v", c)
+	}
+	for i, want := range []Status{Fail, OK, Warn} {
+		if c.Results[i].Status != want {
+			t.Errorf("check %d: %s %q, want %s", i, c.Results[i].Status, c.Results[i].Message, want)
+		}
+	}
+}
+
+func TestPortNames(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Interface = "eth0"
+	if names := PortNames(cfg); len(names) != 1 || names[0] != "eth0" {
+		t.Errorf("PortNames() = %v", names)
+	}
+	cfg.Ports = config.PortsConfig{TX: "eth0", RX: "eth1"}
+	if names := PortNames(cfg); len(names) != 2 || names[1] != "eth1" {
+		t.Errorf("PortNames() with a port pair = %v", names)
+	}
+}
@@ -0,0 +1,123 @@
+package preflight
+
+import (
+	"time"
+
+	"github.com/krisarmstrong/rfc2544-master/pkg/config"
+	"github.com/krisarmstrong/rfc2544-master/pkg/dataplane"
+)
+
+// Phase is the traffic one test of a run offers over its frame sizes, at
+// most
+type Phase struct {
+	Test     config.TestType
+	Duration time.Duration // 0 = not estimated
+	PeakMbps float64       // Highest offered load on the wire
+	Bytes    float64       // Frame bytes sent
+}
+
+// Impact returns the traffic each test of the run offers, for an operator
+// to weigh before loading a production link. It is an upper bound: every
+// search trial is taken at its highest rate. lineMbps is the 100% rate, 0
+// if unknown, in which case only durations are given.
+func Impact(cfg *config.Config, frameSizes []uint32, lineMbps float64) []Phase {
+	tests := []config.TestType{cfg.TestType}
+	switch cfg.TestType {
+	case config.TestSuite:
+		tests = suiteTests
+	case config.TestCharacterize:
+		tests = []config.TestType{config.TestThroughput, config.TestLatency}
+	}
+
+	var phases []Phase
+	for _, t := range tests {
+		peak, mean := load(cfg, t)
+		phase := Phase{Test: t}
+		test := *cfg
+		test.TestType = t
+		for _, fs := range frameSizes {
+			d, _ := Duration(&test, []uint32{fs})
+			phase.Duration += d
+			bps := lineMbps * 1e6
+			if cfg.Socket.Enabled() {
+				// 100% is the socket rate cap
+				bps = float64(cfg.Socket.MaxPPS) * float64(fs+20) * 8
+			}
+			if t == config.TestUDPEcho {
+				bps, peak, mean = float64(cfg.UDPEcho.RatePPS)*float64(fs+20)*8, 100, 100
+			}
+			phase.PeakMbps = max(phase.PeakMbps, bps*peak/100/1e6)
+			// Preamble and inter-frame gap take 20 bytes of each frame's slot
+			phase.Bytes += bps * mean / 100 / 8 * d.Seconds() * float64(fs) / float64(fs+20)
+		}
+		phases = append(phases, phase)
+	}
+	return phases
+}
+
+// load returns the highest and the average load a test offers, in % of
+// line rate, taking a search's trials at its starting rate
+func load(cfg *config.Config, t config.TestType) (peak, mean float64) {
+	avg := func(v []float64) float64 {
+		var sum float64
+		for _, x := range v {
+			sum += x
+		}
+		return sum / float64(max(len(v), 1))
+	}
+	switch t {
+	case config.TestThroughput:
+		if tp := cfg.Throughput; tp.SearchAlgorithm == config.SearchLinear {
+			return tp.InitialRatePct, avg(SearchSteps(tp))
+		}
+		return cfg.Throughput.InitialRatePct, cfg.Throughput.InitialRatePct
+	case config.TestLatency:
+		// Load levels are shares of a throughput of at most line rate
+		for _, l := range cfg.Latency.LoadLevels {
+			peak = max(peak, l)
+		}
+		return peak, avg(cfg.Latency.LoadLevels)
+	case config.TestFrameLoss:
+		if fl := cfg.FrameLoss; !fl.Search() {
+			return fl.StartPct, (fl.StartPct + fl.EndPct) / 2
+		}
+		return cfg.FrameLoss.StartPct, cfg.FrameLoss.StartPct
+	case config.TestSoak:
+		return cfg.Soak.RatePct, cfg.Soak.RatePct
+	case config.TestQoS:
+		return cfg.QoS.LoadPct, cfg.QoS.LoadPct
+	case config.TestAQM:
+		return cfg.AQM.EndPct, (cfg.AQM.StartPct + cfg.AQM.EndPct) / 2
+	case config.TestMicroburst:
+		return cfg.Microburst.BurstRatePct, cfg.Microburst.BurstRatePct
+	}
+	// Back-to-back bursts, recovery overload and the rest run up to line rate
+	return 100, 100
+}
+
+// LineMbps returns the line rate the impact of a run is estimated at:
+// line_rate_mbps, else the link speed of its interface; 0 if unknown
+func LineMbps(cfg *config.Config) float64 {
+	if cfg.LineRateMbps > 0 || cfg.Socket.Enabled() {
+		return float64(cfg.LineRateMbps)
+	}
+	if cfg.TRex.Enabled() || cfg.TestType == config.TestUDPEcho || cfg.Interface == "" {
+		return 0
+	}
+	nic, err := dataplane.DetectNIC(cfg.Interface)
+	if err != nil {
+		return 0
+	}
+	return float64(nic.LinkSpeed) / 1e6
+}
+
+// Total sums the phases of a run
+func Total(phases []Phase) Phase {
+	var total Phase
+	for _, p := range phases {
+		total.Duration += p.Duration
+		total.PeakMbps = max(total.PeakMbps, p.PeakMbps)
+		total.Bytes += p.Bytes
+	}
+	return total
+}
@@ -0,0 +1,49 @@
+package preflight
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/krisarmstrong/rfc2544-master/pkg/config"
+)
+
+func TestImpact(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.TestType = config.TestThroughput
+	phases := Impact(cfg, []uint32{64}, 1000)
+	if len(phases) != 1 {
+		t.Fatalf("%d phases, want 1", len(phases))
+	}
+	p := phases[0]
+	// Every trial at 100%, less the preamble and gap of each slot
+	want := 1e9 / 8 * 620 * 64 / 84
+	if p.Duration != 620*time.Second || p.PeakMbps != 1000 || math.Abs(p.Bytes-want) > 1 {
+		t.Errorf("Impact() = %+v, want %.0f bytes", p, want)
+	}
+
+	// Without a line rate only the duration is known
+	if p := Impact(cfg, []uint32{64}, 0)[0]; p.Duration != 620*time.Second || p.Bytes != 0 {
+		t.Errorf("Impact() at unknown line rate = %+v", p)
+	}
+}
+
+func TestImpactSuite(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.TestType = config.TestSuite
+	phases := Impact(cfg, []uint32{64, 1518}, 1000)
+	if len(phases) != len(suiteTests) {
+		t.Fatalf("%d phases, want one per suite test", len(phases))
+	}
+
+	total := Total(phases)
+	var d time.Duration
+	var bytes float64
+	for _, p := range phases {
+		d += p.Duration
+		bytes += p.Bytes
+	}
+	if total.Duration != d || total.Bytes != bytes || total.PeakMbps != 1000 {
+		t.Errorf("Total() = %+v", total)
+	}
+}
@@ -0,0 +1,159 @@
+// Package preflight previews a run before any traffic is sent
+//
+// It estimates how long the test plan takes and how much traffic each of
+// its tests offers, and runs the checks of a dry run: the config, the
+// interfaces and the privileges the dataplane needs.
+package preflight
+
+import (
+	"time"
+
+	"github.com/krisarmstrong/rfc2544-master/pkg/config"
+)
+
+// suiteTests are the tests a suite runs, in order
+var suiteTests = []config.TestType{
+	config.TestThroughput, config.TestLatency, config.TestFrameLoss,
+	config.TestBackToBack, config.TestSystemRecovery, config.TestReset,
+}
+
+// Duration returns the expected run time of the test plan and whether it
+// covers the whole run. Throughput and the frame loss search use the
+// number of binary search steps to the resolution; recovery and reset use
+// their maximum wait.
+func Duration(cfg *config.Config, frameSizes []uint32) (time.Duration, bool) {
+	trial := cfg.TrialDuration + cfg.WarmupPeriod
+	complete := true
+
+	var perFrame func(t config.TestType) time.Duration
+	perFrame = func(t config.TestType) time.Duration {
+		switch t {
+		case config.TestThroughput:
+			return time.Duration(SearchIterations(cfg.Throughput)) * trial
+		case config.TestLatency:
+			return time.Duration(len(cfg.Latency.LoadLevels)) * trial
+		case config.TestFrameLoss:
+			steps := 0
+			if fl := cfg.FrameLoss; fl.Search() {
+				// At most the start_pct trial, one per halving of the
+				// range, and end_pct when nothing passes
+				steps = 1
+				for width := fl.StartPct - fl.EndPct; width > fl.SearchResolutionPct; width /= 2 {
+					steps++
+				}
+				if fl.EndPct < fl.StartPct {
+					steps++
+				}
+			} else if fl.StepPct > 0 {
+				for rate := fl.StartPct; rate >= fl.EndPct; rate -= fl.StepPct {
+					steps++
+				}
+			}
+			return time.Duration(steps) * trial
+		case config.TestBackToBack:
+			// One second trials per burst size; at least one burst size
+			complete = false
+			return time.Duration(cfg.BackToBack.Trials) * time.Second
+		case config.TestSystemRecovery:
+			return time.Duration(cfg.Recovery.OverloadSec)*time.Second + 60*time.Second
+		case config.TestReset:
+			return 300 * time.Second
+		case config.TestSuite:
+			var d time.Duration
+			for _, s := range suiteTests {
+				d += perFrame(s)
+			}
+			return d
+		case config.TestCharacterize:
+			return perFrame(config.TestThroughput) + perFrame(config.TestLatency)
+		case config.TestSoak:
+			return cfg.Soak.Duration
+		case config.TestQoS:
+			return cfg.WarmupPeriod + cfg.TrialDuration
+		case config.TestAQM:
+			steps := int((cfg.AQM.EndPct-cfg.AQM.StartPct)/cfg.AQM.StepPct) + 1
+			return time.Duration(steps) * (cfg.WarmupPeriod + cfg.AQM.StepDuration)
+		case config.TestMicroburst:
+			var d time.Duration
+			for _, gap := range cfg.Microburst.Gaps {
+				// Each train waits for its stragglers after the last burst
+				d += time.Duration(microburstTrains(cfg.Microburst)) * (time.Duration(cfg.Microburst.Bursts)*gap + 100*time.Millisecond)
+			}
+			return d
+		case config.TestSelfTest:
+			return time.Duration(len(cfg.SelfTest.Suite())+1) * (cfg.WarmupPeriod + cfg.SelfTest.Duration)
+		case config.TestUDPEcho:
+			return cfg.UDPEcho.Duration + cfg.UDPEcho.Timeout
+		}
+		return 0
+	}
+
+	d := perFrame(cfg.TestType)
+	if !config.IsRFC2544Test(cfg.TestType) {
+		return d * time.Duration(len(frameSizes)), complete
+	}
+
+	// Modifier and control-plane runs repeat the test
+	runs := 1
+	if cfg.Modifiers.Enabled() {
+		runs++
+	}
+	if cfg.ControlPlane.Enabled() {
+		runs++
+	}
+	return d * time.Duration(runs*len(frameSizes)), complete
+}
+
+// SearchIterations is the number of throughput trials the search runs at
+// most: the binary search halves the range until it is within the
+// resolution, a linear search tries every step, and a hybrid one the
+// steps and the binary search of one
+func SearchIterations(t config.ThroughputConfig) uint32 {
+	binary := func(width float64) uint32 {
+		var n uint32
+		for n < t.MaxIterations && width > t.ResolutionPct {
+			width /= 2
+			n++
+		}
+		return n
+	}
+	switch t.SearchAlgorithm {
+	case config.SearchLinear:
+		return uint32(len(SearchSteps(t)))
+	case config.SearchHybrid:
+		return uint32(len(SearchSteps(t))) + binary(t.StepPct)
+	}
+	return binary(t.InitialRatePct)
+}
+
+// SearchSteps returns the rates a linear search tries, from the initial
+// rate down by the step while above the resolution
+func SearchSteps(tp config.ThroughputConfig) []float64 {
+	var rates []float64
+	for i := 0; tp.StepPct > 0; i++ {
+		rate := tp.InitialRatePct - float64(i)*tp.StepPct
+		if rate < tp.ResolutionPct {
+			break
+		}
+		rates = append(rates, rate)
+	}
+	return rates
+}
+
+// microburstTrains is the most trains one gap's search sends: growing
+// from the start to the maximum, then bisecting the last step
+func microburstTrains(m config.MicroburstConfig) int {
+	n, last := 1, m.StartFrames
+	for f := m.StartFrames; f > 0 && f < m.MaxFrames; n++ {
+		last = f
+		if m.StepFrames > 0 {
+			f += m.StepFrames
+		} else {
+			f *= 2
+		}
+	}
+	for span := m.MaxFrames - last; span > max(m.ResolutionFrames, 1); span /= 2 {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,47 @@
+package preflight
+
+import (
+	"testing"
+	"time"
+
+	"github.com/krisarmstrong/rfc2544-master/pkg/config"
+)
+
+func TestSearchIterations(t *testing.T) {
+	tp := config.DefaultConfig().Throughput
+	// 100% halved ten times is within 0.1%
+	if n := SearchIterations(tp); n != 10 {
+		t.Errorf("binary: %d iterations, want 10", n)
+	}
+
+	tp.SearchAlgorithm, tp.StepPct = config.SearchLinear, 10
+	if n := SearchIterations(tp); n != 10 {
+		t.Errorf("linear: %d iterations, want 10", n)
+	}
+	// The steps, then 10% halved seven times
+	tp.SearchAlgorithm = config.SearchHybrid
+	if n := SearchIterations(tp); n != 17 {
+		t.Errorf("hybrid: %d iterations, want 17", n)
+	}
+}
+
+func TestDuration(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.TestType = config.TestThroughput
+	d, complete := Duration(cfg, []uint32{64, 128})
+	if want := 2 * 10 * 62 * time.Second; d != want || !complete {
+		t.Errorf("throughput: %v, %t; want %v, true", d, complete, want)
+	}
+
+	cfg.TestType = config.TestSystemRecovery
+	cfg.Recovery.OverloadSec = 120
+	if d, _ := Duration(cfg, []uint32{64}); d != 180*time.Second {
+		t.Errorf("recovery: %v, want 3m0s", d)
+	}
+
+	// Back-to-back bursts grow until the DUT drops frames
+	cfg.TestType = config.TestBackToBack
+	if _, complete := Duration(cfg, []uint32{64}); complete {
+		t.Error("back-to-back estimate should be incomplete")
+	}
+}
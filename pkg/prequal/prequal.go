@@ -0,0 +1,300 @@
+// Package prequal checks basic reachability before performance tests run
+//
+// Each target gets a ping sweep, a traceroute and a path MTU probe using
+// the system ping and traceroute tools, so a failed test can be quickly
+// attributed to a reachability problem rather than forwarding performance.
+// IPv4 and IPv6 targets are both supported.
+package prequal
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/krisarmstrong/rfc2544-master/pkg/internal/execrun"
+)
+
+// Header overhead between the ping payload and the IP MTU
+const (
+	ipv4Overhead = 20 + 8 // IPv4 + ICMP
+	ipv6Overhead = 40 + 8 // IPv6 + ICMPv6
+)
+
+// Minimum MTUs every path must carry
+const (
+	minMTUIPv4 = 576
+	minMTUIPv6 = 1280
+)
+
+// Options control which checks run
+type Options struct {
+	Count      int           // Echo requests per ping sweep
+	Timeout    time.Duration // Per-probe reply timeout
+	Traceroute bool
+	MaxHops    int
+	PathMTU    bool
+	MaxMTU     int // Upper bound for the path MTU search
+}
+
+// Ping summarises a ping sweep
+type Ping struct {
+	Sent     int     `json:"sent"`
+	Received int     `json:"received"`
+	LossPct  float64 `json:"loss_pct"`
+	MinMs    float64 `json:"min_ms"`
+	AvgMs    float64 `json:"avg_ms"`
+	MaxMs    float64 `json:"max_ms"`
+}
+
+// Hop is one traceroute hop. An empty Address means no reply.
+type Hop struct {
+	TTL     int     `json:"ttl"`
+	Address string  `json:"address,omitempty"`
+	RTTMs   float64 `json:"rtt_ms,omitempty"`
+}
+
+// TargetResult holds the checks for one target
+type TargetResult struct {
+	Target    string   `json:"target"`
+	Family    string   `json:"family"` // "ipv4" or "ipv6"
+	Reachable bool     `json:"reachable"`
+	Ping      *Ping    `json:"ping,omitempty"`
+	Route     []Hop    `json:"route,omitempty"`
+	PathMTU   int      `json:"path_mtu,omitempty"`
+	Errors    []string `json:"errors,omitempty"`
+}
+
+// Report is the pre-qualification outcome recorded with the results
+type Report struct {
+	Targets []TargetResult `json:"targets"`
+	Passed  bool           `json:"passed"`
+}
+
+// Checker runs pre-qualification checks
+type Checker struct {
+	Options Options
+	Run     execrun.Runner // Tests replace it to avoid depending on the network
+}
+
+// NewChecker creates a checker using the system tools
+func NewChecker(opts Options) *Checker {
+	if opts.Count <= 0 {
+		opts.Count = 5
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = time.Second
+	}
+	if opts.MaxHops <= 0 {
+		opts.MaxHops = 30
+	}
+	if opts.MaxMTU <= 0 {
+		opts.MaxMTU = 1500
+	}
+	return &Checker{Options: opts, Run: execrun.Exec}
+}
+
+// Check runs every enabled check against each target. The report passes
+// when every target answers pings.
+func (c *Checker) Check(ctx context.Context, targets []string) *Report {
+	report := &Report{Passed: true}
+	for _, t := range targets {
+		r := c.CheckTarget(ctx, t)
+		if !r.Reachable {
+			report.Passed = false
+		}
+		report.Targets = append(report.Targets, r)
+	}
+	return report
+}
+
+// CheckTarget runs the checks for one target
+func (c *Checker) CheckTarget(ctx context.Context, target string) TargetResult {
+	r := TargetResult{Target: target, Family: "ipv4"}
+	ip := net.ParseIP(target)
+	if ip == nil {
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, target)
+		if err != nil || len(addrs) == 0 {
+			r.Errors = append(r.Errors, fmt.Sprintf("resolve: %v", err))
+			return r
+		}
+		ip = addrs[0].IP
+	}
+	v6 := ip.To4() == nil
+	if v6 {
+		r.Family = "ipv6"
+	}
+	addr := ip.String()
+
+	ping, err := c.ping(ctx, addr, v6)
+	if err != nil {
+		r.Errors = append(r.Errors, fmt.Sprintf("ping: %v", err))
+	}
+	if ping != nil {
+		r.Ping = ping
+		r.Reachable = ping.Received > 0
+	}
+
+	if c.Options.Traceroute {
+		route, err := c.traceroute(ctx, addr, v6)
+		if err != nil {
+			r.Errors = append(r.Errors, fmt.Sprintf("traceroute: %v", err))
+		}
+		r.Route = route
+	}
+
+	if c.Options.PathMTU && r.Reachable {
+		r.PathMTU = c.pathMTU(ctx, addr, v6)
+	}
+	return r
+}
+
+func (c *Checker) ping(ctx context.Context, addr string, v6 bool) (*Ping, error) {
+	args := pingArgs(v6, c.Options.Timeout, "-c", strconv.Itoa(c.Options.Count), "-i", "0.2", addr)
+	out, err := c.Run(ctx, "ping", args...)
+	// ping exits non-zero on loss but still prints statistics
+	p, perr := ParsePing(string(out))
+	if perr != nil {
+		if err != nil {
+			return nil, err
+		}
+		return nil, perr
+	}
+	return p, nil
+}
+
+func (c *Checker) traceroute(ctx context.Context, addr string, v6 bool) ([]Hop, error) {
+	args := []string{"-n", "-q", "1",
+		"-w", strconv.Itoa(timeoutSeconds(c.Options.Timeout)),
+		"-m", strconv.Itoa(c.Options.MaxHops)}
+	if v6 {
+		args = append([]string{"-6"}, args...)
+	}
+	out, err := c.Run(ctx, "traceroute", append(args, addr)...)
+	hops := ParseTraceroute(string(out))
+	if len(hops) == 0 && err != nil {
+		return nil, err
+	}
+	return hops, nil
+}
+
+// pathMTU binary searches for the largest packet that passes unfragmented
+func (c *Checker) pathMTU(ctx context.Context, addr string, v6 bool) int {
+	lo, overhead := minMTUIPv4, ipv4Overhead
+	if v6 {
+		lo, overhead = minMTUIPv6, ipv6Overhead
+	}
+	probe := func(mtu int) bool {
+		args := pingArgs(v6, c.Options.Timeout, "-c", "1", "-M", "do",
+			"-s", strconv.Itoa(mtu-overhead), addr)
+		out, _ := c.Run(ctx, "ping", args...)
+		p, err := ParsePing(string(out))
+		return err == nil && p.Received > 0
+	}
+	return SearchMTU(lo, c.Options.MaxMTU, probe)
+}
+
+// SearchMTU returns the largest MTU in [lo, hi] for which probe succeeds,
+// assuming sizes up to the path MTU pass. It returns 0 if lo fails.
+func SearchMTU(lo, hi int, probe func(mtu int) bool) int {
+	if hi < lo || !probe(lo) {
+		return 0
+	}
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if probe(mid) {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	return lo
+}
+
+func pingArgs(v6 bool, timeout time.Duration, args ...string) []string {
+	base := []string{"-n", "-W", strconv.Itoa(timeoutSeconds(timeout))}
+	if v6 {
+		base = append([]string{"-6"}, base...)
+	}
+	return append(base, args...)
+}
+
+func timeoutSeconds(d time.Duration) int {
+	s := int((d + time.Second - 1) / time.Second)
+	if s < 1 {
+		s = 1
+	}
+	return s
+}
+
+var (
+	pingCountRe = regexp.MustCompile(`(\d+) packets transmitted, (\d+) (?:packets )?received`)
+	pingRTTRe   = regexp.MustCompile(`= ([\d.]+)/([\d.]+)/([\d.]+)`)
+	hopRe       = regexp.MustCompile(`^\s*(\d+)\s+(.*)$`)
+	hopRTTRe    = regexp.MustCompile(`([\d.]+) ms`)
+)
+
+// ParsePing reads the statistics printed by iputils or BusyBox ping
+func ParsePing(out string) (*Ping, error) {
+	m := pingCountRe.FindStringSubmatch(out)
+	if m == nil {
+		return nil, fmt.Errorf("no ping statistics in output")
+	}
+	p := &Ping{}
+	p.Sent, _ = strconv.Atoi(m[1])
+	p.Received, _ = strconv.Atoi(m[2])
+	if p.Sent > 0 {
+		p.LossPct = float64(p.Sent-p.Received) * 100 / float64(p.Sent)
+	}
+	if m := pingRTTRe.FindStringSubmatch(out); m != nil {
+		p.MinMs, _ = strconv.ParseFloat(m[1], 64)
+		p.AvgMs, _ = strconv.ParseFloat(m[2], 64)
+		p.MaxMs, _ = strconv.ParseFloat(m[3], 64)
+	}
+	return p, nil
+}
+
+// ParseTraceroute reads numeric (-n) traceroute output with one query per hop
+func ParseTraceroute(out string) []Hop {
+	var hops []Hop
+	for _, line := range strings.Split(out, "\n") {
+		m := hopRe.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		hop := Hop{}
+		hop.TTL, _ = strconv.Atoi(m[1])
+		rest := strings.Fields(m[2])
+		if len(rest) > 0 && rest[0] != "*" && net.ParseIP(rest[0]) != nil {
+			hop.Address = rest[0]
+			if rm := hopRTTRe.FindStringSubmatch(m[2]); rm != nil {
+				hop.RTTMs, _ = strconv.ParseFloat(rm[1], 64)
+			}
+		}
+		hops = append(hops, hop)
+	}
+	return hops
+}
+
+// Summary returns a one-line description of a target result
+func (r TargetResult) Summary() string {
+	var parts []string
+	if r.Ping != nil {
+		parts = append(parts, fmt.Sprintf("ping %d/%d (%.0f%% loss, avg %.2f ms)",
+			r.Ping.Received, r.Ping.Sent, r.Ping.LossPct, r.Ping.AvgMs))
+	}
+	if len(r.Route) > 0 {
+		parts = append(parts, fmt.Sprintf("%d hops", len(r.Route)))
+	}
+	if r.PathMTU > 0 {
+		parts = append(parts, fmt.Sprintf("path MTU %d", r.PathMTU))
+	}
+	parts = append(parts, r.Errors...)
+	if len(parts) == 0 {
+		return "no result"
+	}
+	return strings.Join(parts, ", ")
+}
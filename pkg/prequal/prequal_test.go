@@ -0,0 +1,143 @@
+package prequal
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/krisarmstrong/rfc2544-master/pkg/internal/execrun"
+)
+
+const iputilsPing = `PING 192.0.2.1 (192.0.2.1) 56(84) bytes of data.
+64 bytes from 192.0.2.1: icmp_seq=1 ttl=64 time=0.412 ms
+
+--- 192.0.2.1 ping statistics ---
+5 packets transmitted, 4 received, 20% packet loss, time 804ms
+rtt min/avg/max/mdev = 0.301/0.412/0.530/0.080 ms
+`
+
+const busyboxPing = `--- 2001:db8::1 ping statistics ---
+3 packets transmitted, 3 packets received, 0% packet loss
+round-trip min/avg/max = 1.100/1.200/1.300 ms
+`
+
+const traceroute = `traceroute to 198.51.100.7 (198.51.100.7), 30 hops max, 60 byte packets
+ 1  192.0.2.254  0.512 ms
+ 2  *
+ 3  198.51.100.7  3.250 ms
+`
+
+func TestParsePing(t *testing.T) {
+	p, err := ParsePing(iputilsPing)
+	if err != nil {
+		t.Fatalf("ParsePing: %v", err)
+	}
+	if p.Sent != 5 || p.Received != 4 || p.LossPct != 20 || p.AvgMs != 0.412 || p.MaxMs != 0.530 {
+		t.Errorf("iputils = %+v", p)
+	}
+
+	p, err = ParsePing(busyboxPing)
+	if err != nil {
+		t.Fatalf("ParsePing: %v", err)
+	}
+	if p.Sent != 3 || p.Received != 3 || p.MinMs != 1.1 {
+		t.Errorf("busybox = %+v", p)
+	}
+
+	if _, err := ParsePing("ping: unknown host"); err == nil {
+		t.Error("Expected error without statistics")
+	}
+}
+
+func TestParseTraceroute(t *testing.T) {
+	hops := ParseTraceroute(traceroute)
+	if len(hops) != 3 {
+		t.Fatalf("hops = %+v", hops)
+	}
+	if hops[0].Address != "192.0.2.254" || hops[0].RTTMs != 0.512 {
+		t.Errorf("hop 1 = %+v", hops[0])
+	}
+	if hops[1].TTL != 2 || hops[1].Address != "" {
+		t.Errorf("hop 2 = %+v, want no reply", hops[1])
+	}
+	if hops[2].Address != "198.51.100.7" {
+		t.Errorf("hop 3 = %+v", hops[2])
+	}
+}
+
+func TestSearchMTU(t *testing.T) {
+	probes := 0
+	got := SearchMTU(576, 9000, func(mtu int) bool {
+		probes++
+		return mtu <= 1492
+	})
+	if got != 1492 {
+		t.Errorf("SearchMTU = %d, want 1492", got)
+	}
+	if probes > 16 {
+		t.Errorf("SearchMTU used %d probes", probes)
+	}
+	if got := SearchMTU(1280, 1500, func(int) bool { return false }); got != 0 {
+		t.Errorf("SearchMTU with no replies = %d, want 0", got)
+	}
+}
+
+// fakeRunner answers pings up to an MTU of 1400 bytes
+func fakeRunner(t *testing.T, reachable bool) execrun.Runner {
+	return func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		joined := strings.Join(args, " ")
+		switch name {
+		case "ping":
+			if !reachable {
+				return []byte("2 packets transmitted, 0 received, 100% packet loss\n"), errors.New("exit status 1")
+			}
+			for i, a := range args {
+				if a == "-s" {
+					size, _ := strconv.Atoi(args[i+1])
+					if size+ipv4Overhead > 1400 {
+						return []byte("1 packets transmitted, 0 received, 100% packet loss\n"), errors.New("exit status 1")
+					}
+				}
+			}
+			return []byte(iputilsPing), nil
+		case "traceroute":
+			if strings.Contains(joined, "-6") {
+				t.Error("IPv4 target traced with -6")
+			}
+			return []byte(traceroute), nil
+		}
+		t.Fatalf("unexpected command %s %s", name, joined)
+		return nil, nil
+	}
+}
+
+func TestCheck(t *testing.T) {
+	c := NewChecker(Options{Traceroute: true, PathMTU: true})
+	c.Run = fakeRunner(t, true)
+	report := c.Check(context.Background(), []string{"198.51.100.7"})
+	if !report.Passed || len(report.Targets) != 1 {
+		t.Fatalf("report = %+v", report)
+	}
+	r := report.Targets[0]
+	if r.Family != "ipv4" || !r.Reachable || len(r.Route) != 3 || r.PathMTU != 1400 {
+		t.Errorf("result = %+v", r)
+	}
+	if s := r.Summary(); !strings.Contains(s, "path MTU 1400") {
+		t.Errorf("Summary = %q", s)
+	}
+
+	c.Run = fakeRunner(t, false)
+	report = c.Check(context.Background(), []string{"198.51.100.7"})
+	if report.Passed || report.Targets[0].PathMTU != 0 {
+		t.Errorf("unreachable report = %+v", report.Targets[0])
+	}
+}
+
+func TestPingArgsIPv6(t *testing.T) {
+	args := pingArgs(true, 0, "-c", "1", "2001:db8::1")
+	if args[0] != "-6" || args[len(args)-1] != "2001:db8::1" {
+		t.Errorf("pingArgs = %v", args)
+	}
+}
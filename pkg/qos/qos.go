@@ -0,0 +1,35 @@
+// Package qos holds the QoS scheduling matrix: every marked class offered
+// at once, and how the DUT shares its egress between them
+package qos
+
+import (
+	"github.com/krisarmstrong/rfc2544-master/pkg/config"
+	"github.com/krisarmstrong/rfc2544-master/pkg/dataplane"
+)
+
+// Report is the QoS scheduling matrix at one frame size
+type Report struct {
+	FrameSize     uint32            `json:"frame_size"`
+	Marking       config.QoSMarking `json:"marking"`
+	LoadPct       float64           `json:"load_pct"`
+	FramesTx      uint64            `json:"frames_tx"`
+	FramesRx      uint64            `json:"frames_rx"`
+	LossPct       float64           `json:"loss_pct"`
+	DeliveredMbps float64           `json:"delivered_mbps"`
+	Classes       []Class           `json:"classes"`
+}
+
+// Class is one row of the matrix
+type Class struct {
+	Name              string                 `json:"name"`
+	PCP               uint8                  `json:"pcp"`
+	DSCP              uint8                  `json:"dscp"`
+	OfferedSharePct   float64                `json:"offered_share_pct"`
+	OfferedMbps       float64                `json:"offered_mbps"`
+	FramesTx          uint64                 `json:"frames_tx"`
+	FramesRx          uint64                 `json:"frames_rx"`
+	LossPct           float64                `json:"loss_pct"`
+	DeliveredMbps     float64                `json:"delivered_mbps"`
+	DeliveredSharePct float64                `json:"delivered_share_pct"`
+	Latency           dataplane.LatencyStats `json:"latency"`
+}
@@ -0,0 +1,285 @@
+// Package redact removes identifying details from results before they are
+// shared outside the lab
+//
+// Documents are walked generically as decoded JSON, so native results,
+// imported results and comparison reports are all handled. MAC and IP
+// addresses are found anywhere in string values. Host names and site
+// details cannot be told apart from other text, so they are recognised by
+// key (target, server, interface, DUT name, ...) and replaced whole.
+// Numbers and booleans are never touched, so performance figures survive.
+//
+// Strip mode replaces each value with a fixed placeholder. Hash mode
+// replaces it with a keyed hash, so the same address still matches across
+// a report without being revealed; the key is random unless a salt is
+// given, because unsalted hashes of the IPv4 space are easily reversed.
+package redact
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+)
+
+// Modes
+const (
+	ModeStrip = "strip" // Replace with a placeholder
+	ModeHash  = "hash"  // Replace with a salted hash token
+)
+
+// Modes lists the accepted modes
+func Modes() []string {
+	return []string{ModeStrip, ModeHash}
+}
+
+// Placeholder replaces values in strip mode
+const Placeholder = "[redacted]"
+
+// Kinds of redacted values, used as the hash token prefix
+const (
+	KindMAC  = "mac"
+	KindIP   = "ip"
+	KindHost = "host"
+)
+
+// hostKeys are keys whose string values are host names, addresses,
+// paths or site details. Keys are compared lower-case without
+// underscores, since some results use Go field names.
+var hostKeys = map[string]bool{
+	"interface":        true,
+	"target":           true,
+	"targets":          true,
+	"server":           true,
+	"host":             true,
+	"hostname":         true,
+	"address":          true,
+	"peer":             true,
+	"loopedpeer":       true,
+	"managementtarget": true,
+	"dutip":            true,
+	"source":           true, // Power source command, compare source file
+	"sourcefile":       true,
+	"label":            true, // Compare run label, usually the DUT name
+	"site":             true,
+	"location":         true,
+	"publicurl":        true,
+	"url":              true,
+}
+
+// dutHostKeys are the keys of DUT metadata that identify the device
+// itself; vendor, model and firmware are kept for comparisons
+var dutHostKeys = map[string]bool{"name": true}
+
+var (
+	macRe  = regexp.MustCompile(`\b[0-9A-Fa-f]{2}(?:[:-][0-9A-Fa-f]{2}){5}\b`)
+	ipv4Re = regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`)
+	ipv6Re = regexp.MustCompile(`[0-9A-Fa-f]*:[0-9A-Fa-f:]*:[0-9A-Fa-f.]*`)
+)
+
+// Redactor replaces identifying values
+type Redactor struct {
+	mode string
+	key  []byte
+}
+
+// New returns a Redactor for mode. In hash mode salt keys the hash; an
+// empty salt uses a random one, so tokens only match within one run.
+func New(mode, salt string) (*Redactor, error) {
+	r := &Redactor{mode: mode}
+	switch mode {
+	case ModeStrip:
+	case ModeHash:
+		r.key = []byte(salt)
+		if salt == "" {
+			r.key = make([]byte, 32)
+			if _, err := rand.Read(r.key); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown redaction mode %q (want %s)", mode, strings.Join(Modes(), " or "))
+	}
+	return r, nil
+}
+
+// Mode returns the redaction mode
+func (r *Redactor) Mode() string {
+	return r.mode
+}
+
+// token replaces one value of the given kind
+func (r *Redactor) token(kind, value string) string {
+	if r.mode == ModeStrip {
+		return Placeholder
+	}
+	mac := hmac.New(sha256.New, r.key)
+	mac.Write([]byte(kind + ":" + value))
+	return kind + "-" + hex.EncodeToString(mac.Sum(nil)[:4])
+}
+
+// Host replaces a whole host name, path or site value. Empty values stay
+// empty so optional fields keep meaning "not set".
+func (r *Redactor) Host(s string) string {
+	if s == "" {
+		return s
+	}
+	return r.token(KindHost, strings.ToLower(s))
+}
+
+// String replaces the MAC and IP addresses inside s, leaving other text
+func (r *Redactor) String(s string) string {
+	s = macRe.ReplaceAllStringFunc(s, func(m string) string {
+		return r.token(KindMAC, strings.ToLower(strings.ReplaceAll(m, "-", ":")))
+	})
+	s = ipv4Re.ReplaceAllStringFunc(s, func(m string) string {
+		ip := net.ParseIP(m)
+		if ip == nil {
+			return m // e.g. a version number such as 1.2.3.400
+		}
+		return r.token(KindIP, ip.String())
+	})
+	return ipv6Re.ReplaceAllStringFunc(s, func(m string) string {
+		// Clock times and port suffixes also match; only real IPv6
+		// addresses with at least two colons are replaced
+		ip := net.ParseIP(strings.Trim(m, ":"))
+		if ip == nil || ip.To4() != nil || strings.Count(m, ":") < 2 {
+			return m
+		}
+		return r.token(KindIP, ip.String())
+	})
+}
+
+// Value redacts a decoded JSON value (maps, slices and scalars as
+// produced by encoding/json), returning the redacted copy
+func (r *Redactor) Value(v interface{}) interface{} {
+	return r.value(v, "", false)
+}
+
+func (r *Redactor) value(v interface{}, key string, inDUT bool) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(t))
+		for k, child := range t {
+			out[k] = r.value(child, normalize(k), key == "dut")
+		}
+		return out
+	case *object:
+		out := &object{keys: t.keys, values: make([]interface{}, len(t.values))}
+		for i, child := range t.values {
+			out.values[i] = r.value(child, normalize(t.keys[i]), key == "dut")
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(t))
+		for i, child := range t {
+			out[i] = r.value(child, key, inDUT)
+		}
+		return out
+	case string:
+		if hostKeys[key] || (inDUT && dutHostKeys[key]) {
+			return r.Host(t)
+		}
+		return r.String(t)
+	}
+	return v
+}
+
+func normalize(key string) string {
+	return strings.ToLower(strings.ReplaceAll(key, "_", ""))
+}
+
+// JSON redacts a JSON document, returning it indented. Object keys keep
+// their order and numbers are carried through as written.
+func (r *Redactor) JSON(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	doc, err := decode(dec)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(r.Value(doc), "", "  ")
+}
+
+// object is a JSON object that remembers its key order
+type object struct {
+	keys   []string
+	values []interface{}
+}
+
+// MarshalJSON writes the keys in their original order
+func (o *object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, k := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(k)
+		if err != nil {
+			return nil, err
+		}
+		val, err := json.Marshal(o.values[i])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(val)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// decode reads one JSON value, keeping object key order
+func decode(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case json.Delim('{'):
+		o := &object{}
+		for dec.More() {
+			k, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			v, err := decode(dec)
+			if err != nil {
+				return nil, err
+			}
+			o.keys = append(o.keys, k.(string))
+			o.values = append(o.values, v)
+		}
+		_, err := dec.Token() // '}'
+		return o, err
+	case json.Delim('['):
+		a := []interface{}{}
+		for dec.More() {
+			v, err := decode(dec)
+			if err != nil {
+				return nil, err
+			}
+			a = append(a, v)
+		}
+		_, err := dec.Token() // ']'
+		return a, err
+	}
+	return tok, nil
+}
+
+// Marshal encodes v as JSON and redacts it, for results still held as Go
+// values
+func (r *Redactor) Marshal(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return r.JSON(data)
+}
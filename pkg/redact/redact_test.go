@@ -0,0 +1,116 @@
+package redact
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const sample = `{
+  "metadata": {
+    "interface": "eth0",
+    "test_type": "throughput",
+    "dut": {"name": "core-nyc-01", "vendor": "Acme", "model": "X9"},
+    "trex": {"server": "trex.lab.example.com", "tx_port": 0}
+  },
+  "prequalification": {
+    "targets": [{"target": "192.0.2.1", "route": [{"ttl": 1, "address": "198.51.100.1"}],
+                 "errors": ["no reply from 2001:db8::1 at 12:22:50"]}],
+    "passed": true
+  },
+  "results": [{"FrameSize": 64, "MaxRateMbps": 761.9047619, "FramesTx": 12345678901234567}],
+  "oam": {"peers": [{"mac": "02:00:00:00:00:FE"}]}
+}`
+
+func redactSample(t *testing.T, mode, salt string) (string, map[string]interface{}) {
+	t.Helper()
+	r, err := New(mode, salt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.JSON([]byte(sample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(out, &doc); err != nil {
+		t.Fatal(err)
+	}
+	return string(out), doc
+}
+
+func TestStrip(t *testing.T) {
+	out, doc := redactSample(t, ModeStrip, "")
+	for _, secret := range []string{"eth0", "core-nyc-01", "trex.lab", "192.0.2.1", "198.51.100.1", "2001:db8::1", "02:00:00:00:00"} {
+		if strings.Contains(strings.ToLower(out), strings.ToLower(secret)) {
+			t.Errorf("%q survived redaction:\n%s", secret, out)
+		}
+	}
+
+	meta := doc["metadata"].(map[string]interface{})
+	dut := meta["dut"].(map[string]interface{})
+	if meta["interface"] != Placeholder || dut["name"] != Placeholder {
+		t.Errorf("metadata = %v", meta)
+	}
+	if dut["vendor"] != "Acme" || dut["model"] != "X9" || meta["test_type"] != "throughput" {
+		t.Errorf("non-identifying metadata changed: %v", meta)
+	}
+
+	// Performance numbers are untouched, including large counters
+	for _, want := range []string{`"MaxRateMbps": 761.9047619`, `"FramesTx": 12345678901234567`, `"FrameSize": 64`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %s in:\n%s", want, out)
+		}
+	}
+	if strings.Index(out, `"metadata"`) > strings.Index(out, `"results"`) {
+		t.Errorf("key order not kept:\n%s", out)
+	}
+	if !strings.Contains(out, "at 12:22:50") {
+		t.Errorf("clock time was redacted:\n%s", out)
+	}
+}
+
+func TestHash(t *testing.T) {
+	_, a := redactSample(t, ModeHash, "lab-1")
+	_, b := redactSample(t, ModeHash, "lab-1")
+	_, c := redactSample(t, ModeHash, "lab-2")
+
+	ifaceOf := func(doc map[string]interface{}) string {
+		return doc["metadata"].(map[string]interface{})["interface"].(string)
+	}
+	if ifaceOf(a) != ifaceOf(b) {
+		t.Errorf("same salt gave %s and %s", ifaceOf(a), ifaceOf(b))
+	}
+	if ifaceOf(a) == ifaceOf(c) {
+		t.Error("different salts gave the same token")
+	}
+	if !strings.HasPrefix(ifaceOf(a), KindHost+"-") {
+		t.Errorf("interface token = %s", ifaceOf(a))
+	}
+}
+
+func TestString(t *testing.T) {
+	r, _ := New(ModeHash, "k")
+	tests := map[string]string{
+		"peer 02-00-00-00-00-fe looped": "peer " + r.token(KindMAC, "02:00:00:00:00:fe") + " looped",
+		"dial 10.0.0.1:3842":            "dial " + r.token(KindIP, "10.0.0.1") + ":3842",
+		"[2001:DB8::1]:3842":            "[" + r.token(KindIP, "2001:db8::1") + "]:3842",
+		"version 1.2.3.400":             "version 1.2.3.400",
+		"loss: 0.5%":                    "loss: 0.5%",
+	}
+	for in, want := range tests {
+		if got := r.String(in); got != want {
+			t.Errorf("String(%q) = %q, want %q", in, got, want)
+		}
+	}
+	// The same MAC in either notation gets one token
+	if r.String("02:00:00:00:00:FE") != r.String("02-00-00-00-00-fe") {
+		t.Error("MAC notations hash differently")
+	}
+}
+
+func TestNewUnknownMode(t *testing.T) {
+	if _, err := New("scramble", ""); err == nil {
+		t.Error("Expected error for an unknown mode")
+	}
+}
@@ -0,0 +1,196 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// A4 in points
+const (
+	pageWidth  = 595.28
+	pageHeight = 841.89
+)
+
+// Glyph widths of the standard Helvetica fonts for ASCII 32-126, in
+// thousandths of the font size. The standard fonts need no embedding, so
+// every PDF reader renders them, but text has to be measured here.
+var (
+	helveticaWidths = [95]int{
+		278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+		1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+		333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+		556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+	}
+	helveticaBoldWidths = [95]int{
+		278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+		975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+		333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+		611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+	}
+)
+
+// textWidth returns the width of s in points
+func textWidth(s string, size float64, bold bool) float64 {
+	widths := &helveticaWidths
+	if bold {
+		widths = &helveticaBoldWidths
+	}
+	total := 0
+	for _, b := range encode(s) {
+		if b >= 32 && b <= 126 {
+			total += widths[b-32]
+		} else {
+			total += 556 // Latin-1 letters are close to the digit width
+		}
+	}
+	return float64(total) * size / 1000
+}
+
+// encode converts s to WinAnsi bytes, which match Latin-1 above 0xA0.
+// Characters the standard fonts cannot show become '?'.
+func encode(s string) []byte {
+	out := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch {
+		case r >= 32 && r <= 126, r >= 0xA0 && r <= 0xFF:
+			out = append(out, byte(r))
+		case r == '\t':
+			out = append(out, ' ')
+		case r == '–' || r == '—':
+			out = append(out, '-')
+		default:
+			out = append(out, '?')
+		}
+	}
+	return out
+}
+
+// escape writes b as a PDF literal string body
+func escape(b []byte) string {
+	var sb strings.Builder
+	for _, c := range b {
+		switch c {
+		case '(', ')', '\\':
+			sb.WriteByte('\\')
+			sb.WriteByte(c)
+		default:
+			if c >= 0x80 {
+				fmt.Fprintf(&sb, "\\%03o", c)
+			} else {
+				sb.WriteByte(c)
+			}
+		}
+	}
+	return sb.String()
+}
+
+// rgb is a colour with components from 0 to 1
+type rgb struct{ r, g, b float64 }
+
+var (
+	black     = rgb{0, 0, 0}
+	white     = rgb{1, 1, 1}
+	grey      = rgb{0.4, 0.4, 0.4}
+	rule      = rgb{0.75, 0.75, 0.75}
+	shade     = rgb{0.93, 0.93, 0.93}
+	passGreen = rgb{0.18, 0.49, 0.20}
+	failRed   = rgb{0.78, 0.16, 0.16}
+	slate     = rgb{0.33, 0.43, 0.48}
+	failShade = rgb{0.99, 0.93, 0.92}
+	caution   = rgb{1, 0.97, 0.88}
+)
+
+// page is the content stream of one page. Coordinates passed in are from
+// the top-left corner, as the layout works top-down; PDF's origin is the
+// bottom-left.
+type page struct {
+	buf bytes.Buffer
+}
+
+func (p *page) text(x, top, size float64, bold bool, c rgb, s string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(&p.buf, "BT %.3f %.3f %.3f rg /%s %.2f Tf %.2f %.2f Td (%s) Tj ET\n",
+		c.r, c.g, c.b, font, size, x, pageHeight-top, escape(encode(s)))
+}
+
+func (p *page) rect(x, top, w, h float64, fill rgb) {
+	fmt.Fprintf(&p.buf, "%.3f %.3f %.3f rg %.2f %.2f %.2f %.2f re f\n",
+		fill.r, fill.g, fill.b, x, pageHeight-top-h, w, h)
+}
+
+func (p *page) line(x1, top1, x2, top2, width float64, c rgb) {
+	fmt.Fprintf(&p.buf, "%.3f %.3f %.3f RG %.2f w %.2f %.2f m %.2f %.2f l S\n",
+		c.r, c.g, c.b, width, x1, pageHeight-top1, x2, pageHeight-top2)
+}
+
+// document is a set of pages written as a PDF 1.4 file
+type document struct {
+	title   string
+	created time.Time
+	pages   []*page
+}
+
+func (d *document) newPage() *page {
+	p := &page{}
+	d.pages = append(d.pages, p)
+	return p
+}
+
+// pdfDate formats t as a PDF date string
+func pdfDate(t time.Time) string {
+	_, offset := t.Zone()
+	sign := '+'
+	if offset < 0 {
+		sign, offset = '-', -offset
+	}
+	return fmt.Sprintf("D:%s%c%02d'%02d'", t.Format("20060102150405"), sign, offset/3600, offset%3600/60)
+}
+
+// write emits the file: catalog, page tree, fonts and info, then a page
+// and content stream object per page, and the cross-reference table
+func (d *document) write(w io.Writer) error {
+	var out bytes.Buffer
+	var offsets []int
+	obj := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	const firstPage = 6 // Objects 1-5 precede the pages
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+2*i)
+	}
+	obj("<< /Type /Catalog /Pages 2 0 R >>")
+	obj(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	obj("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	obj("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	obj(fmt.Sprintf("<< /Title (%s) /Producer (rfc2544) /CreationDate (%s) >>", escape(encode(d.title)), pdfDate(d.created)))
+
+	for i, p := range d.pages {
+		obj(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			pageWidth, pageHeight, firstPage+2*i+1))
+		obj(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", p.buf.Len(), p.buf.String()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	_, err := w.Write(out.Bytes())
+	return err
+}
@@ -0,0 +1,467 @@
+// Package report renders a certification report ("birth certificate") as
+// a PDF for carrier acceptance of a circuit or service
+//
+// The document follows the layout acceptance engineers expect from ITU-T
+// Y.1564 and RFC 2544 test sets: identification of the circuit and DUT, the
+// test setup, the SLA or acceptance thresholds, a results section per
+// service or test with step tables, an overall PASS/FAIL verdict and a
+// signature block. A SHA-256 digest of the machine-readable results is
+// printed on every page so the paper copy can be tied back to the JSON.
+//
+// The PDF is written directly using the standard Helvetica fonts, so the
+// package needs nothing outside the standard library. Like htmlreport,
+// callers describe the run with plain tables and the package knows
+// nothing about the result types of individual tests.
+package report
+
+import (
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Verdict is the outcome of a section, table row or the whole certificate
+type Verdict int
+
+// Verdicts
+const (
+	VerdictNone Verdict = iota // No pass criteria apply
+	VerdictPass
+	VerdictFail
+)
+
+// String returns the printed text for v
+func (v Verdict) String() string {
+	switch v {
+	case VerdictPass:
+		return "PASS"
+	case VerdictFail:
+		return "FAIL"
+	}
+	return ""
+}
+
+// Of returns VerdictPass or VerdictFail
+func Of(pass bool) Verdict {
+	if pass {
+		return VerdictPass
+	}
+	return VerdictFail
+}
+
+// Certificate is the content of one report
+type Certificate struct {
+	Title      string
+	Standard   string // Methodology the results follow, e.g. "ITU-T Y.1564"
+	Generated  time.Time
+	Identity   []Field   // Circuit, customer and DUT
+	Setup      []Field   // Test configuration
+	Caveats    []string  // Printed under the verdict, e.g. methodology deviations
+	Criteria   []Table   // SLA or acceptance thresholds
+	Sections   []Section // Results
+	Digest     string    // SHA-256 of the machine-readable results, hex
+	Signatures []string  // Roles to sign, e.g. "Tested by"; none omits the block
+}
+
+// Field is one name/value line
+type Field struct {
+	Name  string
+	Value string
+}
+
+// Section is one service or test, with its own verdict
+type Section struct {
+	Title   string
+	Verdict Verdict
+	Detail  string // Printed under the title, e.g. why it failed
+	Tables  []Table
+}
+
+// Table is a captioned table of preformatted cells
+type Table struct {
+	Caption  string
+	Header   []string
+	Rows     [][]string
+	Verdicts []Verdict // Per row; rows beyond the slice have none
+}
+
+// verdict returns the verdict of row i
+func (t Table) verdict(i int) Verdict {
+	if i < len(t.Verdicts) {
+		return t.Verdicts[i]
+	}
+	return VerdictNone
+}
+
+// Verdict combines the section verdicts: failed if any section failed,
+// passed if any passed, and none if no section has pass criteria
+func (c *Certificate) Verdict() Verdict {
+	v := VerdictNone
+	for _, s := range c.Sections {
+		switch s.Verdict {
+		case VerdictFail:
+			return VerdictFail
+		case VerdictPass:
+			v = VerdictPass
+		}
+	}
+	return v
+}
+
+// Write renders the certificate as a PDF
+func (c *Certificate) Write(w io.Writer) error {
+	if c.Generated.IsZero() {
+		c.Generated = time.Now()
+	}
+	l := newLayout(c)
+	l.render()
+	return l.doc.write(w)
+}
+
+// Page geometry and type sizes, in points
+const (
+	margin      = 50.0
+	headerSpace = 34.0 // Running header above the content on later pages
+	footerSpace = 40.0
+	contentW    = pageWidth - 2*margin
+	bodySize    = 9.0
+	tableSize   = 8.0
+	rowHeight   = 13.0
+	cellPad     = 4.0
+)
+
+// layout places the certificate's content top-down, starting new pages as
+// it fills them. Running headers and footers are drawn once the page count
+// is known.
+type layout struct {
+	cert *Certificate
+	doc  *document
+	page *page
+	y    float64 // Top of the next line
+}
+
+func newLayout(c *Certificate) *layout {
+	l := &layout{cert: c, doc: &document{title: c.Title, created: c.Generated}}
+	l.page = l.doc.newPage()
+	l.y = margin
+	return l
+}
+
+// bottom is the lowest y content may reach
+const bottom = pageHeight - margin - footerSpace
+
+// ensure starts a new page unless h points fit on this one, and reports
+// whether it did
+func (l *layout) ensure(h float64) bool {
+	if l.y+h <= bottom {
+		return false
+	}
+	l.page = l.doc.newPage()
+	l.y = margin + headerSpace
+	return true
+}
+
+func (l *layout) render() {
+	c := l.cert
+	l.title()
+	l.banner(c.Verdict())
+	l.caveats(c.Caveats)
+	l.fields("Identification", c.Identity)
+	l.fields("Test setup", c.Setup)
+	if len(c.Criteria) > 0 {
+		l.heading("Acceptance criteria", VerdictNone)
+		for _, t := range c.Criteria {
+			l.table(t)
+		}
+	}
+	for _, s := range c.Sections {
+		l.section(s)
+	}
+	l.signatures()
+	l.furniture()
+}
+
+func (l *layout) title() {
+	c := l.cert
+	l.page.text(margin, l.y+18, 18, true, black, c.Title)
+	l.y += 26
+	if c.Standard != "" {
+		l.page.text(margin, l.y+10, 10, false, grey, c.Standard)
+		l.y += 14
+	}
+	l.page.text(margin, l.y+10, 10, false, grey, "Generated "+c.Generated.Format("2006-01-02 15:04:05 MST"))
+	l.y += 18
+}
+
+func (l *layout) banner(v Verdict) {
+	const h = 30.0
+	fill, text := slate, "NOT EVALUATED: no acceptance criteria apply"
+	switch v {
+	case VerdictPass:
+		fill, text = passGreen, "OVERALL RESULT: PASS"
+	case VerdictFail:
+		fill, text = failRed, "OVERALL RESULT: FAIL"
+	}
+	l.page.rect(margin, l.y, contentW, h, fill)
+	l.page.text(margin+12, l.y+20, 14, true, white, text)
+	l.y += h + 8
+}
+
+// caveats prints the lines qualifying the verdict in a shaded box
+func (l *layout) caveats(lines []string) {
+	if len(lines) == 0 {
+		return
+	}
+	var wrapped []string
+	for _, s := range lines {
+		for i, line := range wrap(s, bodySize, false, contentW-36) {
+			if i == 0 {
+				line = "- " + line
+			} else {
+				line = "  " + line
+			}
+			wrapped = append(wrapped, line)
+		}
+	}
+	h := 24 + float64(len(wrapped))*(bodySize+4)
+	l.ensure(h)
+	l.page.rect(margin, l.y, contentW, h, caution)
+	l.page.text(margin+12, l.y+16, 10, true, black, "Caveats")
+	y := l.y + 22
+	for _, line := range wrapped {
+		l.page.text(margin+18, y+bodySize, bodySize, false, black, line)
+		y += bodySize + 4
+	}
+	l.y += h + 8
+}
+
+// heading starts a titled block, keeping it with at least a few lines of
+// what follows
+func (l *layout) heading(title string, v Verdict) {
+	l.ensure(22 + 3*rowHeight)
+	l.y += 10
+	l.page.text(margin, l.y+11, 11, true, black, title)
+	if s := v.String(); s != "" {
+		color := passGreen
+		if v == VerdictFail {
+			color = failRed
+		}
+		x := margin + textWidth(title, 11, true) + 10
+		w := textWidth(s, 8, true) + 10
+		l.page.rect(x, l.y+1, w, 12, color)
+		l.page.text(x+5, l.y+10, 8, true, white, s)
+	}
+	l.y += 15
+	l.page.line(margin, l.y, margin+contentW, l.y, 0.5, rule)
+	l.y += 6
+}
+
+// paragraph prints wrapped text
+func (l *layout) paragraph(s string, size float64, c rgb) {
+	for _, line := range wrap(s, size, false, contentW) {
+		l.ensure(size + 4)
+		l.page.text(margin, l.y+size, size, false, c, line)
+		l.y += size + 4
+	}
+}
+
+// fields prints name/value lines in two columns
+func (l *layout) fields(title string, fields []Field) {
+	if len(fields) == 0 {
+		return
+	}
+	l.heading(title, VerdictNone)
+	nameW := 0.0
+	for _, f := range fields {
+		if w := textWidth(f.Name, bodySize, true); w > nameW {
+			nameW = w
+		}
+	}
+	if nameW > contentW/3 {
+		nameW = contentW / 3
+	}
+	valueX := margin + nameW + 12
+	for _, f := range fields {
+		lines := wrap(f.Value, bodySize, false, margin+contentW-valueX)
+		if len(lines) == 0 {
+			lines = []string{""}
+		}
+		l.ensure(float64(len(lines)) * (bodySize + 4))
+		l.page.text(margin, l.y+bodySize, bodySize, true, black, truncate(f.Name, bodySize, true, nameW))
+		for _, line := range lines {
+			l.page.text(valueX, l.y+bodySize, bodySize, false, black, line)
+			l.y += bodySize + 4
+		}
+	}
+}
+
+func (l *layout) section(s Section) {
+	l.heading(s.Title, s.Verdict)
+	if s.Detail != "" {
+		l.paragraph(s.Detail, bodySize, grey)
+	}
+	for _, t := range s.Tables {
+		l.table(t)
+	}
+}
+
+// table prints t with the header repeated on each page it spans. Columns
+// share the content width in proportion to their widest cell; cells that
+// still do not fit are truncated.
+func (l *layout) table(t Table) {
+	widths := columnWidths(t)
+	l.y += 4
+	if t.Caption != "" {
+		l.ensure(bodySize + 4 + 2*rowHeight)
+		l.page.text(margin, l.y+bodySize, bodySize, true, black, t.Caption)
+		l.y += bodySize + 5
+	}
+	l.ensure(2 * rowHeight)
+	l.row(t.Header, widths, true, VerdictNone)
+	for i, cells := range t.Rows {
+		if l.ensure(rowHeight) {
+			l.row(t.Header, widths, true, VerdictNone)
+		}
+		l.row(cells, widths, false, t.verdict(i))
+	}
+	l.y += 6
+}
+
+// row prints one table row. The first column is left-aligned and the rest
+// right-aligned, as numbers are; a verdict colours the last cell and shades
+// failed rows.
+func (l *layout) row(cells []string, widths []float64, header bool, v Verdict) {
+	switch {
+	case header:
+		l.page.rect(margin, l.y, contentW, rowHeight, shade)
+	case v == VerdictFail:
+		l.page.rect(margin, l.y, contentW, rowHeight, failShade)
+	}
+	x := margin
+	for i, w := range widths {
+		cell := ""
+		if i < len(cells) {
+			cell = truncate(cells[i], tableSize, header, w-2*cellPad)
+		}
+		bold, color := header, black
+		if !header && i == len(widths)-1 && v != VerdictNone {
+			bold, color = true, passGreen
+			if v == VerdictFail {
+				color = failRed
+			}
+		}
+		tx := x + cellPad
+		if i > 0 {
+			tx = x + w - cellPad - textWidth(cell, tableSize, bold)
+		}
+		l.page.text(tx, l.y+rowHeight-4, tableSize, bold, color, cell)
+		x += w
+	}
+	l.y += rowHeight
+	l.page.line(margin, l.y, margin+contentW, l.y, 0.3, rule)
+}
+
+func columnWidths(t Table) []float64 {
+	n := len(t.Header)
+	for _, r := range t.Rows {
+		if len(r) > n {
+			n = len(r)
+		}
+	}
+	widths := make([]float64, n)
+	measure := func(cells []string, bold bool) {
+		for i, c := range cells {
+			if w := textWidth(c, tableSize, bold) + 2*cellPad; w > widths[i] {
+				widths[i] = w
+			}
+		}
+	}
+	measure(t.Header, true)
+	for _, r := range t.Rows {
+		measure(r, false)
+	}
+	total := 0.0
+	for _, w := range widths {
+		total += w
+	}
+	if total == 0 {
+		return widths
+	}
+	for i := range widths {
+		widths[i] *= contentW / total
+	}
+	return widths
+}
+
+// signatures prints a signature and date line per role
+func (l *layout) signatures() {
+	if len(l.cert.Signatures) == 0 {
+		return
+	}
+	l.heading("Sign-off", VerdictNone)
+	const lineW = 230.0
+	for _, role := range l.cert.Signatures {
+		l.ensure(44)
+		l.y += 28
+		l.page.line(margin, l.y, margin+lineW, l.y, 0.6, black)
+		l.page.line(margin+lineW+40, l.y, margin+contentW, l.y, 0.6, black)
+		l.page.text(margin, l.y+10, bodySize, false, grey, role+" (name, signature)")
+		l.page.text(margin+lineW+40, l.y+10, bodySize, false, grey, "Date")
+		l.y += 14
+	}
+}
+
+// furniture draws the running header and the footer on every page
+func (l *layout) furniture() {
+	c := l.cert
+	total := len(l.doc.pages)
+	for i, p := range l.doc.pages {
+		if i > 0 {
+			p.text(margin, margin+10, 8, true, grey, c.Title)
+			p.line(margin, margin+16, margin+contentW, margin+16, 0.5, rule)
+		}
+		top := pageHeight - margin - footerSpace + 16
+		p.line(margin, top, margin+contentW, top, 0.5, rule)
+		left := "Generated " + c.Generated.Format("2006-01-02 15:04 MST")
+		if c.Digest != "" {
+			left += "   Results SHA-256 " + c.Digest
+		}
+		p.text(margin, top+12, 7, false, grey, truncate(left, 7, false, contentW-60))
+		num := "Page " + strconv.Itoa(i+1) + " of " + strconv.Itoa(total)
+		p.text(margin+contentW-textWidth(num, 7, false), top+12, 7, false, grey, num)
+	}
+}
+
+// wrap breaks s into lines no wider than width, at spaces where it can
+func wrap(s string, size float64, bold bool, width float64) []string {
+	var lines []string
+	for _, para := range strings.Split(s, "\n") {
+		line := ""
+		for _, word := range strings.Fields(para) {
+			next := word
+			if line != "" {
+				next = line + " " + word
+			}
+			if line != "" && textWidth(next, size, bold) > width {
+				lines = append(lines, truncate(line, size, bold, width))
+				next = word
+			}
+			line = next
+		}
+		lines = append(lines, truncate(line, size, bold, width))
+	}
+	return lines
+}
+
+// truncate shortens s with an ellipsis until it fits in width
+func truncate(s string, size float64, bold bool, width float64) string {
+	if textWidth(s, size, bold) <= width {
+		return s
+	}
+	r := []rune(s)
+	for len(r) > 0 && textWidth(string(r)+"...", size, bold) > width {
+		r = r[:len(r)-1]
+	}
+	return string(r) + "..."
+}
@@ -0,0 +1,190 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerdict(t *testing.T) {
+	tests := []struct {
+		name     string
+		sections []Verdict
+		want     Verdict
+	}{
+		{"no sections", nil, VerdictNone},
+		{"informational only", []Verdict{VerdictNone}, VerdictNone},
+		{"passed", []Verdict{VerdictNone, VerdictPass}, VerdictPass},
+		{"one failure", []Verdict{VerdictPass, VerdictFail, VerdictPass}, VerdictFail},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Certificate{}
+			for _, v := range tt.sections {
+				c.Sections = append(c.Sections, Section{Verdict: v})
+			}
+			if got := c.Verdict(); got != tt.want {
+				t.Errorf("Verdict() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEscape(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"plain", "plain"},
+		{"a (b) c", `a \(b\) c`},
+		{`back\slash`, `back\\slash`},
+		{"µs", `\265s`},
+		{"日本", "??"},
+	}
+	for _, tt := range tests {
+		if got := escape(encode(tt.in)); got != tt.want {
+			t.Errorf("escape(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestWrap(t *testing.T) {
+	lines := wrap("the quick brown fox jumps over the lazy dog", 10, false, 60)
+	if len(lines) < 2 {
+		t.Fatalf("wrap() = %q, want several lines", lines)
+	}
+	for _, l := range lines {
+		if w := textWidth(l, 10, false); w > 60 {
+			t.Errorf("line %q is %.1fpt wide, want at most 60", l, w)
+		}
+	}
+	if got := strings.Join(lines, " "); got != "the quick brown fox jumps over the lazy dog" {
+		t.Errorf("wrap lost words: %q", got)
+	}
+
+	if got := truncate("0123456789", 10, false, 30); !strings.HasSuffix(got, "...") || textWidth(got, 10, false) > 30 {
+		t.Errorf("truncate() = %q, want a shortened string that fits", got)
+	}
+}
+
+// checkStructure verifies the cross-reference table points at each object
+func checkStructure(t *testing.T, pdf []byte) {
+	t.Helper()
+	if !bytes.HasPrefix(pdf, []byte("%PDF-1.4\n")) {
+		t.Fatal("missing PDF header")
+	}
+	if !bytes.HasSuffix(pdf, []byte("%%EOF\n")) {
+		t.Fatal("missing EOF trailer")
+	}
+	m := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(pdf)
+	if m == nil {
+		t.Fatal("missing startxref")
+	}
+	xref, _ := strconv.Atoi(string(m[1]))
+	if !bytes.HasPrefix(pdf[xref:], []byte("xref\n")) {
+		t.Fatalf("startxref %d does not point at the xref table", xref)
+	}
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(pdf[xref:], -1)
+	if len(entries) == 0 {
+		t.Fatal("empty xref table")
+	}
+	for i, e := range entries {
+		off, _ := strconv.Atoi(string(e[1]))
+		want := fmt.Sprintf("%d 0 obj\n", i+1)
+		if !bytes.HasPrefix(pdf[off:], []byte(want)) {
+			t.Errorf("xref entry %d points at %q, want %q", i+1, pdf[off:off+12], want)
+		}
+	}
+	for _, s := range regexp.MustCompile(`(?s)<< /Length (\d+) >>\nstream\n(.*?)endstream`).FindAllSubmatch(pdf, -1) {
+		if n, _ := strconv.Atoi(string(s[1])); n != len(s[2]) {
+			t.Errorf("stream /Length %d, content is %d bytes", n, len(s[2]))
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	c := &Certificate{
+		Title:     "Y.1564 Service Activation Certificate",
+		Standard:  "ITU-T Y.1564",
+		Generated: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+		Identity:  []Field{{"Circuit ID", "CKT-0042 (east)"}, {"DUT", "Acme R1"}},
+		Setup:     []Field{{"Interface", "eth0"}},
+		Caveats:   []string{"RFC 2544 24: trial duration 10s (60s recommended)"},
+		Criteria: []Table{{
+			Header: []string{"Service", "CIR Mbps", "FD ms"},
+			Rows:   [][]string{{"1 Voice", "100", "10"}},
+		}},
+		Sections: []Section{
+			{Title: "Service 1: Voice", Verdict: VerdictPass, Tables: []Table{{
+				Caption:  "Configuration test",
+				Header:   []string{"Step", "Result"},
+				Rows:     [][]string{{"1", "PASS"}},
+				Verdicts: []Verdict{VerdictPass},
+			}}},
+			{Title: "Service 2: Data", Verdict: VerdictFail, Detail: "FLR above threshold"},
+		},
+		Digest:     "abc123",
+		Signatures: []string{"Tested by", "Accepted by"},
+	}
+
+	var buf bytes.Buffer
+	if err := c.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	pdf := buf.Bytes()
+	checkStructure(t, pdf)
+
+	for _, want := range []string{
+		"(OVERALL RESULT: FAIL)",
+		`(CKT-0042 \(east\))`,
+		"(Configuration test)",
+		"(FLR above threshold)",
+		"(- RFC 2544 24: trial duration 10s \\(60s recommended\\))",
+		"(Accepted by \\(name, signature\\))",
+		"Results SHA-256 abc123",
+		"(Page 1 of 1)",
+		"/BaseFont /Helvetica-Bold",
+		"/CreationDate (D:20260301120000+00'00')",
+	} {
+		if !bytes.Contains(pdf, []byte(want)) {
+			t.Errorf("PDF missing %q", want)
+		}
+	}
+}
+
+func TestWritePaginates(t *testing.T) {
+	table := Table{Header: []string{"Trial", "Rate %"}}
+	for i := 0; i < 150; i++ {
+		table.Rows = append(table.Rows, []string{strconv.Itoa(i), "99.0"})
+	}
+	c := &Certificate{Title: "Long", Sections: []Section{{Title: "Soak", Tables: []Table{table}}}}
+
+	var buf bytes.Buffer
+	if err := c.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	pdf := buf.Bytes()
+	checkStructure(t, pdf)
+
+	pages := bytes.Count(pdf, []byte("/Type /Page "))
+	if pages < 2 {
+		t.Fatalf("150 rows fit on %d page, want several", pages)
+	}
+	if !bytes.Contains(pdf, []byte(fmt.Sprintf("/Count %d", pages))) {
+		t.Errorf("page tree count does not match %d pages", pages)
+	}
+	if !bytes.Contains(pdf, []byte(fmt.Sprintf("(Page %d of %d)", pages, pages))) {
+		t.Error("last page is not numbered")
+	}
+	// The header row repeats on every page the table spans
+	if n := bytes.Count(pdf, []byte("(Rate %)")); n != pages {
+		t.Errorf("table header printed %d times over %d pages", n, pages)
+	}
+	if !bytes.Contains(pdf, []byte("(NOT EVALUATED: no acceptance criteria apply)")) {
+		t.Error("expected the not-evaluated banner without pass criteria")
+	}
+	if c.Generated.IsZero() {
+		t.Error("Write should stamp the generation time")
+	}
+}
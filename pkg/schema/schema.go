@@ -0,0 +1,306 @@
+// Package schema generates JSON Schemas (draft 2020-12) from Go types
+//
+// Schemas are derived by reflection using the same field names and
+// omitempty rules as encoding/json or yaml.v3, so they stay in sync with
+// the config file format and the reports the tool writes.
+package schema
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Draft is the JSON Schema dialect the generated documents declare
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// durationPattern matches strings accepted by time.ParseDuration
+const durationPattern = `^-?([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`
+
+// Schema is a JSON Schema node
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	ID                   string             `json:"$id,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+	Defs                 map[string]*Schema `json:"$defs,omitempty"`
+}
+
+// Generator builds schemas for Go types, collecting named structs in $defs
+type Generator struct {
+	// Tag selects the field naming rules: "json" or "yaml"
+	Tag string
+
+	// Required marks fields without omitempty as required. Set it for
+	// documents the tool writes; leave it unset for input files where
+	// missing fields take defaults.
+	Required bool
+
+	// Interfaces lists the concrete types an interface{} value can hold.
+	// With none, interface{} accepts any value.
+	Interfaces []reflect.Type
+
+	// Enums lists the allowed values of named types
+	Enums map[reflect.Type][]interface{}
+
+	// Types gives the schema of types whose encoded form does not follow
+	// their fields, e.g. a yaml.Node holding part of a document
+	Types map[reflect.Type]*Schema
+
+	defs  map[string]*Schema
+	names map[reflect.Type]string
+}
+
+// NewGenerator creates a generator using the given struct tag
+func NewGenerator(tag string) *Generator {
+	return &Generator{
+		Tag:   tag,
+		Enums: make(map[reflect.Type][]interface{}),
+		Types: make(map[reflect.Type]*Schema),
+		defs:  make(map[string]*Schema),
+		names: make(map[reflect.Type]string),
+	}
+}
+
+// Enum registers the allowed values of a named type, taken from values
+func (g *Generator) Enum(values ...interface{}) {
+	if len(values) == 0 {
+		return
+	}
+	t := reflect.TypeOf(values[0])
+	for _, v := range values {
+		g.Enums[t] = append(g.Enums[t], reflect.ValueOf(v).Convert(basicType(t.Kind())).Interface())
+	}
+}
+
+// Document returns a standalone schema for v's type with its $defs
+func (g *Generator) Document(v interface{}, id, title string) *Schema {
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	var doc *Schema
+	if t.Kind() == reflect.Struct {
+		doc = g.structSchema(t)
+	} else {
+		doc = g.Reflect(t)
+	}
+	doc.Schema = Draft
+	doc.ID = id
+	doc.Title = title
+	if len(g.defs) > 0 {
+		doc.Defs = g.defs
+	}
+	return doc
+}
+
+// Defs returns the named structs collected so far, by their $defs name
+func (g *Generator) Defs() map[string]*Schema {
+	return g.defs
+}
+
+// Reflect returns the schema for t, referencing named structs via $defs
+func (g *Generator) Reflect(t reflect.Type) *Schema {
+	if values, ok := g.Enums[t]; ok {
+		s := g.kindSchema(t)
+		s.Enum = values
+		return s
+	}
+	if s, ok := g.Types[t]; ok {
+		return s
+	}
+
+	switch {
+	case t == reflect.TypeOf(time.Duration(0)):
+		if g.Tag == "yaml" {
+			return &Schema{AnyOf: []*Schema{
+				{Type: "string", Pattern: durationPattern},
+				{Type: "integer", Description: "Nanoseconds"},
+			}}
+		}
+		return &Schema{Type: "integer", Description: "Nanoseconds"}
+	case t == reflect.TypeOf(time.Time{}):
+		return &Schema{Type: "string", Format: "date-time"}
+	case g.Tag == "json" && implementsMarshaler(t):
+		return &Schema{}
+	case implementsTextMarshaler(t):
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return g.Reflect(t.Elem())
+	case reflect.Interface:
+		if len(g.Interfaces) == 0 {
+			return &Schema{}
+		}
+		s := &Schema{}
+		for _, it := range g.Interfaces {
+			s.AnyOf = append(s.AnyOf, g.Reflect(it))
+		}
+		return s
+	case reflect.Struct:
+		return &Schema{Ref: "#/$defs/" + g.define(t)}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.Reflect(t.Elem())}
+	case reflect.Array:
+		n := t.Len()
+		return &Schema{Type: "array", Items: g.Reflect(t.Elem()), MinItems: &n, MaxItems: &n}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.Reflect(t.Elem())}
+	}
+	return g.kindSchema(t)
+}
+
+// kindSchema maps scalar kinds to JSON types
+func (g *Generator) kindSchema(t reflect.Type) *Schema {
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Schema{Type: "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		zero := 0.0
+		return &Schema{Type: "integer", Minimum: &zero}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	}
+	return &Schema{}
+}
+
+// define adds t to $defs once and returns its name. Names are the bare
+// type name unless two packages collide, in which case the later one is
+// package qualified.
+func (g *Generator) define(t reflect.Type) string {
+	if name, ok := g.names[t]; ok {
+		return name
+	}
+	name := t.Name()
+	if name == "" {
+		name = "anonymous"
+	}
+	if _, taken := g.defs[name]; taken {
+		name = pkgName(t) + "." + name
+	}
+	g.names[t] = name
+	g.defs[name] = &Schema{} // Placeholder so recursive types terminate
+	*g.defs[name] = *g.structSchema(t)
+	return name
+}
+
+func (g *Generator) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	g.addFields(s, t)
+	return s
+}
+
+// addFields follows encoding/json and yaml.v3 field rules, flattening
+// embedded (json) or inline (yaml) structs into the parent
+func (g *Generator) addFields(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, opts := parseTag(f.Tag.Get(g.Tag))
+		if name == "-" && opts == "" {
+			continue
+		}
+
+		ft := f.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		inline := (g.Tag == "json" && f.Anonymous && name == "") ||
+			(g.Tag == "yaml" && hasOpt(opts, "inline"))
+		if inline && ft.Kind() == reflect.Struct {
+			g.addFields(s, ft)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+			if g.Tag == "yaml" {
+				name = strings.ToLower(name)
+			}
+		}
+		s.Properties[name] = g.Reflect(f.Type)
+		if g.Required && !hasOpt(opts, "omitempty") {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+func parseTag(tag string) (name, opts string) {
+	if i := strings.Index(tag, ","); i >= 0 {
+		return tag[:i], tag[i+1:]
+	}
+	return tag, ""
+}
+
+func hasOpt(opts, want string) bool {
+	for _, o := range strings.Split(opts, ",") {
+		if o == want {
+			return true
+		}
+	}
+	return false
+}
+
+func pkgName(t reflect.Type) string {
+	p := t.PkgPath()
+	if i := strings.LastIndex(p, "/"); i >= 0 {
+		p = p[i+1:]
+	}
+	return p
+}
+
+func basicType(k reflect.Kind) reflect.Type {
+	switch k {
+	case reflect.String:
+		return reflect.TypeOf("")
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return reflect.TypeOf(int64(0))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return reflect.TypeOf(uint64(0))
+	case reflect.Float32, reflect.Float64:
+		return reflect.TypeOf(float64(0))
+	}
+	return reflect.TypeOf(false)
+}
+
+var (
+	marshalerType     = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+func implementsMarshaler(t reflect.Type) bool {
+	return t.Kind() != reflect.Interface &&
+		(t.Implements(marshalerType) || reflect.PtrTo(t).Implements(marshalerType))
+}
+
+func implementsTextMarshaler(t reflect.Type) bool {
+	return t.Kind() != reflect.Interface &&
+		(t.Implements(textMarshalerType) || reflect.PtrTo(t).Implements(textMarshalerType))
+}
@@ -0,0 +1,158 @@
+package schema
+
+import (
+	"encoding/json"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type inner struct {
+	Value float64 `json:"value"`
+}
+
+type sample struct {
+	Name     string            `json:"name"`
+	Count    uint32            `json:"count,omitempty"`
+	Timeout  time.Duration     `json:"timeout"`
+	At       time.Time         `json:"at"`
+	IP       net.IP            `json:"ip"`
+	Inner    inner             `json:"inner"`
+	List     []*inner          `json:"list"`
+	Fixed    [2]int            `json:"fixed"`
+	Labels   map[string]string `json:"labels"`
+	Any      interface{}       `json:"any"`
+	Skipped  string            `json:"-"`
+	internal int
+	embedded
+}
+
+type embedded struct {
+	Extra bool `json:"extra"`
+}
+
+type level string
+
+type yamlDoc struct {
+	Level    level         `yaml:"level"`
+	Period   time.Duration `yaml:"period"`
+	Untagged int
+}
+
+func TestGenerateJSON(t *testing.T) {
+	g := NewGenerator("json")
+	g.Required = true
+	doc := g.Document(sample{}, "sample.schema.json", "Sample")
+
+	if doc.Schema != Draft || doc.ID != "sample.schema.json" || doc.Type != "object" {
+		t.Fatalf("doc header = %q %q %q", doc.Schema, doc.ID, doc.Type)
+	}
+
+	p := doc.Properties
+	for _, name := range []string{"Skipped", "-", "internal"} {
+		if _, ok := p[name]; ok {
+			t.Errorf("Property %q should be skipped", name)
+		}
+	}
+	if p["extra"] == nil || p["extra"].Type != "boolean" {
+		t.Error("Embedded struct fields should be flattened")
+	}
+	if p["count"].Type != "integer" || p["count"].Minimum == nil {
+		t.Errorf("count = %+v, want unsigned integer", p["count"])
+	}
+	if p["timeout"].Type != "integer" {
+		t.Errorf("JSON durations should be integer nanoseconds, got %+v", p["timeout"])
+	}
+	if p["at"].Format != "date-time" || p["ip"].Type != "string" {
+		t.Error("time.Time and net.IP should be strings")
+	}
+	if p["inner"].Ref != "#/$defs/inner" || doc.Defs["inner"] == nil {
+		t.Errorf("inner = %+v, want $ref to $defs", p["inner"])
+	}
+	if p["list"].Items.Ref != "#/$defs/inner" {
+		t.Error("Pointer slice items should reference the element type")
+	}
+	if *p["fixed"].MinItems != 2 || *p["fixed"].MaxItems != 2 {
+		t.Error("Arrays should have a fixed length")
+	}
+	if p["labels"].AdditionalProperties.Type != "string" {
+		t.Error("Maps should describe their values")
+	}
+
+	required := make(map[string]bool)
+	for _, r := range doc.Required {
+		required[r] = true
+	}
+	if !required["name"] || required["count"] {
+		t.Errorf("Required = %v, want name but not omitempty count", doc.Required)
+	}
+
+	if _, err := json.Marshal(doc); err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+}
+
+func TestGenerateYAML(t *testing.T) {
+	g := NewGenerator("yaml")
+	g.Enum(level("low"), level("high"))
+	doc := g.Document(&yamlDoc{}, "", "")
+
+	if got := doc.Properties["level"].Enum; !reflect.DeepEqual(got, []interface{}{"low", "high"}) {
+		t.Errorf("level enum = %v", got)
+	}
+	if len(doc.Properties["period"].AnyOf) != 2 {
+		t.Error("YAML durations should accept strings and integers")
+	}
+	if doc.Properties["untagged"] == nil {
+		t.Error("Untagged YAML fields should use the lowercased name")
+	}
+	if len(doc.Required) != 0 {
+		t.Error("Fields should not be required unless requested")
+	}
+}
+
+func TestInterfaces(t *testing.T) {
+	g := NewGenerator("json")
+	g.Interfaces = []reflect.Type{reflect.TypeOf(&inner{}), reflect.TypeOf([]inner{})}
+	s := g.Reflect(reflect.TypeOf((*interface{})(nil)).Elem())
+	if len(s.AnyOf) != 2 || s.AnyOf[0].Ref != "#/$defs/inner" || s.AnyOf[1].Type != "array" {
+		t.Errorf("interface schema = %+v", s)
+	}
+}
+
+func TestConfigSchema(t *testing.T) {
+	doc := Config()
+	tt := doc.Properties["test_type"]
+	if tt == nil || len(tt.Enum) == 0 {
+		t.Fatal("test_type should enumerate test types")
+	}
+	found := false
+	for _, v := range tt.Enum {
+		if v == "throughput" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("test_type enum %v missing throughput", tt.Enum)
+	}
+	if doc.Properties["trial_duration"] == nil || doc.Properties["y1564"] == nil {
+		t.Error("Config schema missing top-level keys")
+	}
+	if step := doc.Defs["PlanStep"]; step == nil || step.Properties["set"].Ref != "#" {
+		t.Errorf("plan step set should take top-level config keys: %+v", step)
+	}
+	if _, err := json.Marshal(doc); err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+}
+
+func TestWebConfigSchema(t *testing.T) {
+	doc := WebConfig()
+	if doc.Properties["interface"] == nil || doc.Properties["y1564"].Ref == "" {
+		t.Errorf("web config schema = %+v", doc.Properties)
+	}
+	if len(doc.Required) != 0 {
+		t.Error("Web config input fields should not be required")
+	}
+}
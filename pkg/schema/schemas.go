@@ -0,0 +1,47 @@
+package schema
+
+import (
+	"reflect"
+
+	"github.com/krisarmstrong/rfc2544-master/pkg/config"
+	"github.com/krisarmstrong/rfc2544-master/pkg/web"
+	"gopkg.in/yaml.v3"
+)
+
+// Schema names, also used as the file stem written by "rfc2544 schema"
+const (
+	NameConfig    = "config"
+	NameWebConfig = "web-config"
+	NameResults   = "results"
+)
+
+// FileName returns the conventional file name for a schema
+func FileName(name string) string {
+	return name + ".schema.json"
+}
+
+// Config returns the schema for the YAML configuration file
+func Config() *Schema {
+	g := NewGenerator("yaml")
+
+	var types []interface{}
+	for _, t := range config.TestTypes() {
+		types = append(types, t)
+	}
+	g.Enum(types...)
+	g.Enum(config.FormatText, config.FormatJSON, config.FormatCSV)
+	g.Enum(config.EncapEthernetII, config.EncapLLCSNAP)
+	g.Enum(config.FlowPolicyMarkFailed, config.FlowPolicyRebalance, config.FlowPolicyAbort)
+	g.Enum(config.OrientationOneToMany, config.OrientationManyToOne)
+	// Plan steps set config keys as at the top level
+	g.Types[reflect.TypeOf(yaml.Node{})] = &Schema{Ref: "#"}
+
+	return g.Document(config.Config{}, FileName(NameConfig), "RFC2544 Test Master configuration")
+}
+
+// WebConfig returns the schema for the web API test configuration
+// accepted by POST /api/start and returned by GET /api/config
+func WebConfig() *Schema {
+	g := NewGenerator("json")
+	return g.Document(web.Config{}, FileName(NameWebConfig), "RFC2544 Test Master web test configuration")
+}
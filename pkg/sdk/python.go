@@ -0,0 +1,272 @@
+package sdk
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/krisarmstrong/rfc2544-master/pkg/schema"
+)
+
+// pythonKeywords cannot name a field of a class-syntax TypedDict
+var pythonKeywords = map[string]bool{
+	"False": true, "None": true, "True": true, "and": true, "as": true, "assert": true, "async": true,
+	"await": true, "break": true, "class": true, "continue": true, "def": true, "del": true, "elif": true,
+	"else": true, "except": true, "finally": true, "for": true, "from": true, "global": true, "if": true,
+	"import": true, "in": true, "is": true, "lambda": true, "nonlocal": true, "not": true, "or": true,
+	"pass": true, "raise": true, "return": true, "try": true, "while": true, "with": true, "yield": true,
+}
+
+// pythonTypes renders schemas as type hints, noting the typing names used
+type pythonTypes map[string]bool
+
+func (p pythonTypes) expr(s *schema.Schema) string {
+	if name := refName(s); name != "" {
+		return name
+	}
+	if len(s.Enum) > 0 {
+		p["Literal"] = true
+		values := make([]string, len(s.Enum))
+		for i, v := range s.Enum {
+			data, _ := json.Marshal(v)
+			values[i] = string(data)
+		}
+		return "Literal[" + strings.Join(values, ", ") + "]"
+	}
+	if len(s.AnyOf) > 0 {
+		p["Union"] = true
+		parts := make([]string, len(s.AnyOf))
+		for i, sub := range s.AnyOf {
+			parts[i] = p.expr(sub)
+		}
+		return "Union[" + strings.Join(parts, ", ") + "]"
+	}
+	switch s.Type {
+	case "string":
+		return "str"
+	case "integer":
+		return "int"
+	case "number":
+		return "float"
+	case "boolean":
+		return "bool"
+	case "array":
+		p["List"] = true
+		return "List[" + p.expr(s.Items) + "]"
+	case "object":
+		p["Dict"] = true
+		if s.AdditionalProperties != nil {
+			return "Dict[str, " + p.expr(s.AdditionalProperties) + "]"
+		}
+		p["Any"] = true
+		return "Dict[str, Any]"
+	}
+	p["Any"] = true
+	return "Any"
+}
+
+func pythonClient(a *api) []byte {
+	types := pythonTypes{"TypedDict": true, "Iterator": true, "Optional": true}
+	var body strings.Builder
+
+	body.WriteString("\n# Messages\n")
+	for _, name := range a.names {
+		def := a.defs[name]
+		props := sortedProperties(def)
+		cls := typeName(name)
+		functional := false
+		for _, prop := range props {
+			functional = functional || pythonKeywords[prop] || !isIdentifier(prop)
+		}
+
+		body.WriteString("\n\n")
+		if functional {
+			fmt.Fprintf(&body, "%s = TypedDict(\"%s\", {\n", cls, cls)
+			for _, prop := range props {
+				fmt.Fprintf(&body, "    %q: %q,\n", prop, types.expr(def.Properties[prop]))
+			}
+			body.WriteString("}, total=False)\n")
+			continue
+		}
+		fmt.Fprintf(&body, "class %s(TypedDict, total=False):\n", cls)
+		if len(props) == 0 {
+			body.WriteString("    pass\n")
+		}
+		for _, prop := range props {
+			s := def.Properties[prop]
+			fmt.Fprintf(&body, "    %s: %s", prop, types.expr(s))
+			if note := fieldNote(s); note != "" {
+				fmt.Fprintf(&body, "  # %s", note)
+			}
+			body.WriteString("\n")
+		}
+	}
+
+	body.WriteString(pythonRuntime)
+	for _, e := range a.endpoints {
+		args := []string{"self"}
+		for _, p := range e.params {
+			args = append(args, p+": str")
+		}
+		switch {
+		case e.text:
+			args = append(args, "body: str")
+		case e.request != nil:
+			args = append(args, "body: "+types.expr(e.request))
+		}
+		for _, q := range e.Query {
+			args = append(args, q+": Optional[str] = None")
+		}
+
+		ret := "bytes"
+		switch {
+		case e.Stream:
+			ret = "Iterator[" + types.expr(e.response) + "]"
+		case e.response != nil:
+			ret = types.expr(e.response)
+		}
+
+		path := fmt.Sprintf("%q", e.Path)
+		if len(e.params) > 0 {
+			path = "f" + path
+			for _, p := range e.params {
+				path = strings.Replace(path, "{"+p+"}", "{_quote("+p+")}", 1)
+			}
+		}
+		call := []string{fmt.Sprintf("%q", e.Method), path}
+		if len(e.Query) > 0 {
+			var q []string
+			for _, name := range e.Query {
+				q = append(q, fmt.Sprintf("%q: %s", name, name))
+			}
+			call = append(call, "query={"+strings.Join(q, ", ")+"}")
+		}
+		if e.text || e.request != nil {
+			call = append(call, "body=body")
+		}
+		if e.text {
+			call = append(call, `content_type="application/yaml"`)
+		}
+		if e.response == nil {
+			call = append(call, "raw=True")
+		}
+
+		fmt.Fprintf(&body, "\n    def %s(%s) -> %s:\n", e.Name, strings.Join(args, ", "), ret)
+		fmt.Fprintf(&body, "        \"\"\"%s\"\"\"\n", e.Doc)
+		if e.Stream {
+			fmt.Fprintf(&body, "        return self._stream(%s)\n", path)
+			continue
+		}
+		fmt.Fprintf(&body, "        return self._request(%s)\n", strings.Join(call, ", "))
+	}
+
+	names := make([]string, 0, len(types))
+	for name := range types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n", generatedHeader)
+	b.WriteString(pythonDoc)
+	b.WriteString("\nfrom __future__ import annotations\n\n")
+	b.WriteString("import json\nimport urllib.error\nimport urllib.parse\nimport urllib.request\n")
+	fmt.Fprintf(&b, "from typing import %s\n", strings.Join(names, ", "))
+	b.WriteString(body.String())
+	return []byte(b.String())
+}
+
+// isIdentifier reports whether s is an ASCII Python or JavaScript name
+func isIdentifier(s string) bool {
+	for i, r := range s {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || i > 0 && r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return s != ""
+}
+
+const pythonDoc = `"""Client of the web API of rfc2544 --web, for lab automation.
+
+Messages are TypedDicts: plain dicts with the field names of the JSON API.
+
+    from rfc2544_client import Client
+
+    client = Client("http://tester:8080")
+    client.start({"interface": "eth0", "frame_size": 512})
+    for event in client.events():
+        print(event["type"], event.get("status") or event.get("progress"))
+"""
+`
+
+const pythonRuntime = `
+
+
+class APIError(Exception):
+    """A request the instance refused, with its HTTP status and message"""
+
+    def __init__(self, status: int, message: str):
+        super().__init__(f"{status}: {message}" if message else str(status))
+        self.status = status
+        self.message = message
+
+
+def _quote(value: str) -> str:
+    return urllib.parse.quote(str(value), safe="")
+
+
+class Client:
+    """Client of a running instance, e.g. Client("http://tester:8080").
+    A missing scheme means http."""
+
+    def __init__(self, base_url: str, timeout: float = 30.0):
+        if "://" not in base_url:
+            base_url = "http://" + base_url
+        self.base_url = base_url.rstrip("/")
+        self.timeout = timeout
+
+    def _request(self, method: str, path: str, query: Optional[Dict[str, Optional[str]]] = None,
+                 body: Any = None, content_type: str = "application/json", raw: bool = False) -> Any:
+        url = self.base_url + path
+        params = {k: v for k, v in (query or {}).items() if v is not None}
+        if params:
+            url += "?" + urllib.parse.urlencode(params)
+        data = None
+        headers = {}
+        if body is not None:
+            data = body.encode() if isinstance(body, str) else json.dumps(body).encode()
+            headers["Content-Type"] = content_type
+        req = urllib.request.Request(url, data=data, method=method, headers=headers)
+        try:
+            with urllib.request.urlopen(req, timeout=self.timeout) as resp:
+                payload = resp.read()
+        except urllib.error.HTTPError as err:
+            raise APIError(err.code, err.read(512).decode(errors="replace").strip()) from None
+        if raw:
+            return payload
+        return json.loads(payload) if payload else None
+
+    def _stream(self, path: str) -> Iterator[Any]:
+        req = urllib.request.Request(self.base_url + path, headers={"Accept": "text/event-stream"})
+        try:
+            resp = urllib.request.urlopen(req)
+        except urllib.error.HTTPError as err:
+            raise APIError(err.code, err.read(512).decode(errors="replace").strip()) from None
+
+        def events() -> Iterator[Any]:
+            with resp:
+                data = []
+                for raw_line in resp:
+                    line = raw_line.decode().rstrip("\r\n")
+                    if line.startswith("data:"):
+                        data.append(line[6:] if line.startswith("data: ") else line[5:])
+                    elif not line and data:
+                        try:
+                            yield json.loads("\n".join(data))
+                        except ValueError:
+                            pass
+                        data = []
+
+        return events()
+`
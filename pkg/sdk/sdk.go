@@ -0,0 +1,168 @@
+// Package sdk generates Python and TypeScript clients of the web API, for
+// lab automation that drives the tester from outside Go.
+//
+// The clients are generated from web.Endpoints and the JSON Schemas of
+// the messages, so they follow the server as it changes. Each is a single
+// file without dependencies: the Python client uses the standard library,
+// the TypeScript client the fetch API.
+package sdk
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/krisarmstrong/rfc2544-master/pkg/schema"
+	"github.com/krisarmstrong/rfc2544-master/pkg/web"
+)
+
+// Client languages
+const (
+	Python     = "python"
+	TypeScript = "typescript"
+)
+
+// Languages lists the languages clients are generated in
+func Languages() []string {
+	return []string{Python, TypeScript}
+}
+
+// FileName returns the path of a language's client in the SDK directory
+func FileName(lang string) string {
+	switch lang {
+	case Python:
+		return "python/rfc2544_client.py"
+	case TypeScript:
+		return "typescript/rfc2544Client.ts"
+	}
+	return ""
+}
+
+// Generate returns the client source for lang
+func Generate(lang string) ([]byte, error) {
+	a := newAPI()
+	switch lang {
+	case Python:
+		return pythonClient(a), nil
+	case TypeScript:
+		return typeScriptClient(a), nil
+	}
+	return nil, fmt.Errorf("unknown SDK language %q (%s)", lang, strings.Join(Languages(), ", "))
+}
+
+// generatedHeader marks the files for tools and reviewers
+const generatedHeader = "Code generated by rfc2544 sdk; DO NOT EDIT."
+
+// api is the web API as the generators see it
+type api struct {
+	endpoints []endpoint
+	defs      map[string]*schema.Schema
+	names     []string // Sorted $defs names
+}
+
+// endpoint is a web.Endpoint with its messages as schemas
+type endpoint struct {
+	web.Endpoint
+	params   []string       // Path parameters, in order
+	request  *schema.Schema // nil without a JSON body
+	text     bool           // The body is YAML text
+	response *schema.Schema // nil for a binary response
+}
+
+func newAPI() *api {
+	g := schema.NewGenerator("json")
+	a := &api{}
+	for _, e := range web.Endpoints() {
+		ep := endpoint{Endpoint: e, params: pathParams(e.Path)}
+		switch {
+		case e.Request == nil:
+		case reflect.TypeOf(e.Request).Kind() == reflect.String:
+			ep.text = true
+		default:
+			ep.request = g.Reflect(reflect.TypeOf(e.Request))
+		}
+		if e.Response != nil {
+			ep.response = g.Reflect(reflect.TypeOf(e.Response))
+		}
+		a.endpoints = append(a.endpoints, ep)
+	}
+	a.defs = g.Defs()
+	for name := range a.defs {
+		a.names = append(a.names, name)
+	}
+	sort.Strings(a.names)
+	return a
+}
+
+// pathParams returns the {name} parameters of path
+func pathParams(path string) []string {
+	var params []string
+	for {
+		open := strings.IndexByte(path, '{')
+		if open < 0 {
+			return params
+		}
+		end := strings.IndexByte(path[open:], '}')
+		if end < 0 {
+			return params
+		}
+		params = append(params, path[open+1:open+end])
+		path = path[open+end+1:]
+	}
+}
+
+// typeName turns a $defs name into an identifier: package-qualified
+// names on a collision, e.g. "config.Service", become ConfigService
+func typeName(def string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(def, ".") {
+		if part != "" {
+			b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+		}
+	}
+	return b.String()
+}
+
+// refName returns the type a $ref names, or ""
+func refName(s *schema.Schema) string {
+	if name, ok := strings.CutPrefix(s.Ref, "#/$defs/"); ok {
+		return typeName(name)
+	}
+	return ""
+}
+
+// fieldNote is the comment on a field whose type alone does not tell
+// its encoding, e.g. "Nanoseconds"
+func fieldNote(s *schema.Schema) string {
+	switch {
+	case s.Description != "":
+		return s.Description
+	case s.Format == "date-time":
+		return "RFC 3339 time"
+	case s.Format == "byte":
+		return "Base64"
+	}
+	return ""
+}
+
+// sortedProperties returns the names of an object's properties in order
+func sortedProperties(s *schema.Schema) []string {
+	names := make([]string, 0, len(s.Properties))
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// camelCase turns a snake_case name into lowerCamelCase
+func camelCase(name string) string {
+	parts := strings.Split(name, "_")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
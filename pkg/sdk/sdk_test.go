@@ -0,0 +1,70 @@
+package sdk
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestCheckedIn keeps the clients in sdk/ in step with the web API
+func TestCheckedIn(t *testing.T) {
+	for _, lang := range Languages() {
+		src, err := Generate(lang)
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join("..", "..", "sdk", FileName(lang))
+		have, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(have, src) {
+			t.Errorf("%s is stale; run: rfc2544 sdk --dir sdk", path)
+		}
+	}
+	if _, err := Generate("cobol"); err == nil {
+		t.Error("expected an error for an unknown language")
+	}
+}
+
+func TestClients(t *testing.T) {
+	py, _ := Generate(Python)
+	ts, _ := Generate(TypeScript)
+	for _, want := range []string{
+		"class Stats(TypedDict, total=False):",
+		"    def campaign_results(self, id: str) -> CampaignResults:",
+		`return self._request("GET", f"/api/campaigns/{_quote(id)}/results")`,
+		`return self._request("POST", "/api/config", body=body, content_type="application/yaml")`,
+		"    def events(self) -> Iterator[Event]:",
+		`query={"run_id": run_id}, raw=True)`,
+	} {
+		if !strings.Contains(string(py), want) {
+			t.Errorf("Python client lacks %q", want)
+		}
+	}
+	for _, want := range []string{
+		"export interface Stats {",
+		"  campaignResults(id: string): Promise<CampaignResults> {",
+		"${encodeURIComponent(id)}/results`",
+		"  events(signal?: AbortSignal): AsyncGenerator<Event> {",
+		`  report(query: { run_id?: string } = {}): Promise<ArrayBuffer> {`,
+	} {
+		if !strings.Contains(string(ts), want) {
+			t.Errorf("TypeScript client lacks %q", want)
+		}
+	}
+}
+
+func TestNames(t *testing.T) {
+	if got := pathParams("/api/campaigns/{id}/runs/{run}"); len(got) != 2 || got[0] != "id" || got[1] != "run" {
+		t.Errorf("pathParams: %v", got)
+	}
+	if got := typeName("config.Service"); got != "ConfigService" {
+		t.Errorf("typeName: %s", got)
+	}
+	if got := camelCase("continue_campaign"); got != "continueCampaign" {
+		t.Errorf("camelCase: %s", got)
+	}
+}
@@ -0,0 +1,247 @@
+package sdk
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/krisarmstrong/rfc2544-master/pkg/schema"
+)
+
+// typeScriptType renders a schema as a TypeScript type
+func typeScriptType(s *schema.Schema) string {
+	if name := refName(s); name != "" {
+		return name
+	}
+	if len(s.Enum) > 0 {
+		values := make([]string, len(s.Enum))
+		for i, v := range s.Enum {
+			data, _ := json.Marshal(v)
+			values[i] = string(data)
+		}
+		return strings.Join(values, " | ")
+	}
+	if len(s.AnyOf) > 0 {
+		parts := make([]string, len(s.AnyOf))
+		for i, sub := range s.AnyOf {
+			parts[i] = typeScriptType(sub)
+		}
+		return strings.Join(parts, " | ")
+	}
+	switch s.Type {
+	case "string":
+		return "string"
+	case "integer", "number":
+		return "number"
+	case "boolean":
+		return "boolean"
+	case "array":
+		item := typeScriptType(s.Items)
+		if strings.Contains(item, " | ") {
+			return "Array<" + item + ">"
+		}
+		return item + "[]"
+	case "object":
+		if s.AdditionalProperties != nil {
+			return "Record<string, " + typeScriptType(s.AdditionalProperties) + ">"
+		}
+		return "Record<string, unknown>"
+	}
+	return "unknown"
+}
+
+func typeScriptClient(a *api) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "// %s\n", generatedHeader)
+	b.WriteString(typeScriptDoc)
+
+	b.WriteString("\n// Messages\n")
+	for _, name := range a.names {
+		def := a.defs[name]
+		fmt.Fprintf(&b, "\nexport interface %s {\n", typeName(name))
+		for _, prop := range sortedProperties(def) {
+			s := def.Properties[prop]
+			if note := fieldNote(s); note != "" {
+				fmt.Fprintf(&b, "  /** %s */\n", note)
+			}
+			key := prop
+			if !isIdentifier(prop) {
+				key = fmt.Sprintf("%q", prop)
+			}
+			fmt.Fprintf(&b, "  %s?: %s;\n", key, typeScriptType(s))
+		}
+		b.WriteString("}\n")
+	}
+
+	b.WriteString(typeScriptRuntime)
+	for _, e := range a.endpoints {
+		var args []string
+		for _, p := range e.params {
+			args = append(args, p+": string")
+		}
+		switch {
+		case e.text:
+			args = append(args, "body: string")
+		case e.request != nil:
+			args = append(args, "body: "+typeScriptType(e.request))
+		}
+		if len(e.Query) > 0 {
+			var q []string
+			for _, name := range e.Query {
+				q = append(q, name+"?: string")
+			}
+			args = append(args, "query: { "+strings.Join(q, "; ")+" } = {}")
+		}
+
+		path := fmt.Sprintf("%q", e.Path)
+		if len(e.params) > 0 {
+			path = "`" + e.Path + "`"
+			for _, p := range e.params {
+				path = strings.Replace(path, "{"+p+"}", "${encodeURIComponent("+p+")}", 1)
+			}
+		}
+
+		fmt.Fprintf(&b, "\n  /** %s */\n", e.Doc)
+		name := camelCase(e.Name)
+		if e.Stream {
+			args = append(args, "signal?: AbortSignal")
+			fmt.Fprintf(&b, "  %s(%s): AsyncGenerator<%s> {\n", name, strings.Join(args, ", "), typeScriptType(e.response))
+			fmt.Fprintf(&b, "    return this.stream(%s, signal);\n  }\n", path)
+			continue
+		}
+
+		ret := "ArrayBuffer"
+		if e.response != nil {
+			ret = typeScriptType(e.response)
+		}
+		call := []string{fmt.Sprintf("%q", e.Method), path}
+		opts := []string{}
+		if len(e.Query) > 0 {
+			opts = append(opts, "query")
+		}
+		switch {
+		case e.text:
+			opts = append(opts, "text: body")
+		case e.request != nil:
+			opts = append(opts, "json: body")
+		}
+		if e.response == nil {
+			opts = append(opts, "raw: true")
+		}
+		if len(opts) > 0 {
+			call = append(call, "{ "+strings.Join(opts, ", ")+" }")
+		}
+		fmt.Fprintf(&b, "  %s(%s): Promise<%s> {\n", name, strings.Join(args, ", "), ret)
+		fmt.Fprintf(&b, "    return this.request(%s);\n  }\n", strings.Join(call, ", "))
+	}
+	b.WriteString("}\n")
+	return []byte(b.String())
+}
+
+const typeScriptDoc = `/**
+ * Client of the web API of rfc2544 --web, for lab automation.
+ *
+ * Messages are plain objects with the field names of the JSON API. The
+ * client needs only fetch (browsers, Node.js 18+, Deno, Bun).
+ *
+ *   import { Client } from "./rfc2544Client";
+ *
+ *   const client = new Client("http://tester:8080");
+ *   await client.start({ interface: "eth0", frame_size: 512 });
+ *   for await (const event of client.events()) {
+ *     console.log(event.type, event.status ?? event.progress);
+ *   }
+ */
+`
+
+const typeScriptRuntime = `
+/** A request the instance refused, with its HTTP status and message */
+export class APIError extends Error {
+  constructor(
+    readonly status: number,
+    message: string,
+  ) {
+    super(message ? ` + "`${status}: ${message}`" + ` : String(status));
+    this.name = "APIError";
+  }
+}
+
+type Fetch = (input: string, init?: RequestInit) => Promise<Response>;
+
+interface RequestOptions {
+  query?: Record<string, string | undefined>;
+  json?: unknown;
+  text?: string;
+  raw?: boolean;
+}
+
+/** Client of a running instance; a base URL without a scheme means http */
+export class Client {
+  readonly baseURL: string;
+
+  constructor(
+    baseURL: string,
+    private readonly fetchImpl: Fetch = (input, init) => fetch(input, init),
+  ) {
+    if (!baseURL.includes("://")) baseURL = "http://" + baseURL;
+    this.baseURL = baseURL.replace(/\/+$/, "");
+  }
+
+  private async request<T>(method: string, path: string, opts: RequestOptions = {}): Promise<T> {
+    let url = this.baseURL + path;
+    const params = new URLSearchParams();
+    for (const [k, v] of Object.entries(opts.query ?? {})) {
+      if (v !== undefined) params.set(k, v);
+    }
+    if (params.toString()) url += "?" + params.toString();
+    const init: RequestInit = { method, headers: {} };
+    if (opts.json !== undefined) {
+      init.body = JSON.stringify(opts.json);
+      init.headers = { "Content-Type": "application/json" };
+    } else if (opts.text !== undefined) {
+      init.body = opts.text;
+      init.headers = { "Content-Type": "application/yaml" };
+    }
+    const resp = await this.fetchImpl(url, init);
+    if (!resp.ok) throw new APIError(resp.status, (await resp.text()).trim());
+    if (opts.raw) return (await resp.arrayBuffer()) as T;
+    const body = await resp.text();
+    return (body ? JSON.parse(body) : undefined) as T;
+  }
+
+  private async *stream<T>(path: string, signal?: AbortSignal): AsyncGenerator<T> {
+    const resp = await this.fetchImpl(this.baseURL + path, {
+      headers: { Accept: "text/event-stream" },
+      signal,
+    });
+    if (!resp.ok || !resp.body) throw new APIError(resp.status, (await resp.text()).trim());
+    const reader = resp.body.getReader();
+    const decoder = new TextDecoder();
+    let buffered = "";
+    let data: string[] = [];
+    try {
+      for (;;) {
+        const { value, done } = await reader.read();
+        if (done) return;
+        buffered += decoder.decode(value, { stream: true });
+        let nl: number;
+        while ((nl = buffered.indexOf("\n")) >= 0) {
+          const line = buffered.slice(0, nl).replace(/\r$/, "");
+          buffered = buffered.slice(nl + 1);
+          if (line.startsWith("data:")) {
+            data.push(line.slice(line.startsWith("data: ") ? 6 : 5));
+          } else if (line === "" && data.length > 0) {
+            try {
+              yield JSON.parse(data.join("\n")) as T;
+            } catch {
+              // Not a JSON event
+            }
+            data = [];
+          }
+        }
+      }
+    } finally {
+      reader.releaseLock();
+    }
+  }
+`
@@ -0,0 +1,156 @@
+// Package search finds a DUT's highest passing rate with fixed-rate
+// trials: the throughput searches other than the dataplane's own binary
+// one, and the frame loss test's partial drop rate search
+package search
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/krisarmstrong/rfc2544-master/pkg/config"
+	"github.com/krisarmstrong/rfc2544-master/pkg/dataplane"
+	"github.com/krisarmstrong/rfc2544-master/pkg/preflight"
+)
+
+// Runner runs the trials of a search; every backend does
+type Runner interface {
+	RunFixedRateTrial(ctx context.Context, ratePct float64, duration time.Duration) (*dataplane.FixedRateResult, error)
+	RunThroughputSearch(ctx context.Context, search *dataplane.ThroughputSearch, step func(*dataplane.ThroughputSearch)) (*dataplane.ThroughputResultCLI, error)
+}
+
+// Throughput runs the linear or hybrid search of tp at fs-byte frames,
+// each trial lasting duration and passing at up to lossPct loss. Both step
+// down from the initial rate, for a DUT that passes at a rate above one it
+// fails at: linear tries every step, hybrid stops at the first pass and
+// binary-searches the step above it with the runner's own search.
+func Throughput(ctx context.Context, r Runner, tp config.ThroughputConfig, lossPct float64, duration time.Duration, fs uint32) (*dataplane.ThroughputResultCLI, error) {
+	if tp.StepPct <= 0 {
+		return nil, fmt.Errorf("throughput %s search needs a step_pct above 0", tp.SearchAlgorithm)
+	}
+
+	var trials []dataplane.ThroughputTrial
+	var best *dataplane.FixedRateResult // Highest passing rate
+	for _, rate := range preflight.SearchSteps(tp) {
+		t, err := r.RunFixedRateTrial(ctx, rate, duration)
+		if err != nil {
+			return nil, err
+		}
+		passed := t.LossPct <= lossPct
+		latency := t.Latency
+		latency.Histogram = nil // Only the result keeps the best trial's
+		trials = append(trials, dataplane.ThroughputTrial{
+			RatePct:    rate,
+			FramesTx:   t.FramesTx,
+			FramesRx:   t.FramesRx,
+			LossPct:    t.LossPct,
+			Passed:     passed,
+			Latency:    latency,
+			FrameOrder: t.FrameOrder,
+		})
+		if passed && best == nil {
+			best = t
+			if tp.SearchAlgorithm == config.SearchHybrid {
+				break
+			}
+		}
+	}
+
+	result := &dataplane.ThroughputResultCLI{FrameSize: fs, AcceptableLossPct: lossPct}
+	if best != nil {
+		// The step above the pass failed; the binary search narrows it
+		// down to the resolution
+		if tp.SearchAlgorithm == config.SearchHybrid && best.OfferedPct < tp.InitialRatePct {
+			narrowed, err := r.RunThroughputSearch(ctx, &dataplane.ThroughputSearch{
+				LowPct:      best.OfferedPct,
+				HighPct:     min(best.OfferedPct+tp.StepPct, tp.InitialRatePct),
+				BestRatePct: best.OfferedPct,
+				Latency:     best.Latency,
+			}, nil)
+			if err != nil {
+				return nil, err
+			}
+			result = narrowed
+			if result.MaxRatePct <= best.OfferedPct {
+				result.FrameOrder, result.Streams, result.Families = best.FrameOrder, best.Streams, best.Families
+			}
+		} else {
+			// As sent, the backends not reporting the line rate
+			pps := 0.0
+			if best.ElapsedSec > 0 {
+				pps = math.Floor(float64(best.FramesTx) / best.ElapsedSec)
+			}
+			result.MaxRatePct = best.OfferedPct
+			result.MaxRatePPS = pps
+			result.MaxRateMbps = pps * float64(fs+20) * 8 / 1e6
+			result.Latency = best.Latency
+			result.FrameOrder, result.Streams, result.Families = best.FrameOrder, best.Streams, best.Families
+		}
+	}
+	result.Iterations += uint32(len(trials))
+	result.Trials = append(trials, result.Trials...)
+	result.Search = string(tp.SearchAlgorithm)
+	return result, nil
+}
+
+// PartialDropRate binary-searches start_pct down to end_pct for the
+// highest load with loss at or below the threshold. Every trial is
+// returned, highest load first, with the partial drop rate marked; none is
+// marked when even end_pct loses too much.
+func PartialDropRate(ctx context.Context, r Runner, fl config.FrameLossConfig, duration time.Duration) ([]dataplane.FrameLossResultCLI, error) {
+	var results []dataplane.FrameLossResultCLI
+	best := -1
+	trial := func(pct float64) (bool, error) {
+		t, err := r.RunFixedRateTrial(ctx, pct, duration)
+		if err != nil {
+			return false, err
+		}
+		results = append(results, dataplane.FrameLossResultCLI{
+			FrameSize:  t.FrameSize,
+			OfferedPct: pct,
+			FramesTx:   t.FramesTx,
+			FramesRx:   t.FramesRx,
+			LossPct:    t.LossPct,
+			FrameOrder: t.FrameOrder,
+			Streams:    t.Streams,
+		})
+		pass := t.LossPct <= fl.SearchThresholdPct
+		if pass && (best < 0 || pct > results[best].OfferedPct) {
+			best = len(results) - 1
+		}
+		return pass, nil
+	}
+
+	pass, err := trial(fl.StartPct)
+	if err != nil {
+		return nil, err
+	}
+	// high always loses too much; low is the best passing load once one
+	// passes
+	low, high := fl.EndPct, fl.StartPct
+	for !pass && high-low > fl.SearchResolutionPct {
+		mid := (low + high) / 2
+		ok, err := trial(mid)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	if best < 0 && fl.EndPct < fl.StartPct {
+		if _, err := trial(fl.EndPct); err != nil {
+			return nil, err
+		}
+	}
+
+	if best >= 0 {
+		results[best].PartialDropRate = true
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].OfferedPct > results[j].OfferedPct })
+	return results, nil
+}
@@ -0,0 +1,204 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/krisarmstrong/rfc2544-master/pkg/config"
+	"github.com/krisarmstrong/rfc2544-master/pkg/dataplane"
+)
+
+// dut passes every trial up to passPct and loses lossPct of frames above
+// it, 1% unless set. Its binary search halves the range it is given down
+// to resolution.
+type dut struct {
+	passPct    float64
+	lossPct    float64
+	resolution float64
+	rates      []float64
+	searched   *dataplane.ThroughputSearch
+	err        error
+}
+
+func (d *dut) RunFixedRateTrial(ctx context.Context, ratePct float64, duration time.Duration) (*dataplane.FixedRateResult, error) {
+	if d.err != nil {
+		return nil, d.err
+	}
+	d.rates = append(d.rates, ratePct)
+	r := &dataplane.FixedRateResult{OfferedPct: ratePct, FramesTx: 1000, FramesRx: 1000, ElapsedSec: 1}
+	if ratePct > d.passPct {
+		r.LossPct = d.lossPct
+		if r.LossPct == 0 {
+			r.LossPct = 1
+		}
+		r.FramesRx = uint64(1000 * (100 - r.LossPct) / 100)
+	}
+	return r, nil
+}
+
+func (d *dut) RunThroughputSearch(ctx context.Context, search *dataplane.ThroughputSearch, step func(*dataplane.ThroughputSearch)) (*dataplane.ThroughputResultCLI, error) {
+	d.searched = &dataplane.ThroughputSearch{LowPct: search.LowPct, HighPct: search.HighPct, BestRatePct: search.BestRatePct}
+	result := &dataplane.ThroughputResultCLI{MaxRatePct: search.BestRatePct}
+	for low, high := search.LowPct, search.HighPct; high-low > d.resolution; {
+		mid := (low + high) / 2
+		passed := mid <= d.passPct
+		if passed {
+			low, result.MaxRatePct = mid, mid
+		} else {
+			high = mid
+		}
+		result.Iterations++
+		result.Trials = append(result.Trials, dataplane.ThroughputTrial{RatePct: mid, Passed: passed})
+	}
+	return result, nil
+}
+
+func TestThroughput(t *testing.T) {
+	tests := []struct {
+		name       string
+		algorithm  config.SearchAlgorithm
+		resolution float64
+		passPct    float64
+		wantRates  []float64 // Fixed-rate trials, in order
+		wantSearch []float64 // Range handed to the binary search, if any
+		wantMax    float64
+		wantIter   uint32
+	}{
+		{"linear steps down through every rate", config.SearchLinear, 1, 75,
+			[]float64{100, 80, 60, 40, 20}, nil, 60, 5},
+		{"linear passing at the initial rate", config.SearchLinear, 1, 100,
+			[]float64{100, 80, 60, 40, 20}, nil, 100, 5},
+		{"hybrid narrows the step above the first pass", config.SearchHybrid, 1, 75,
+			[]float64{100, 80, 60}, []float64{60, 80}, 75, 3 + 5},
+		{"hybrid passing at the initial rate", config.SearchHybrid, 1, 100,
+			[]float64{100}, nil, 100, 1},
+		{"no rate passes", config.SearchHybrid, 1, 5,
+			[]float64{100, 80, 60, 40, 20}, nil, 0, 5},
+		{"steps stop below the resolution", config.SearchLinear, 30, 5,
+			[]float64{100, 80, 60, 40}, nil, 0, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tp := config.DefaultConfig().Throughput
+			tp.SearchAlgorithm, tp.InitialRatePct, tp.StepPct, tp.ResolutionPct = tt.algorithm, 100, 20, tt.resolution
+			d := &dut{passPct: tt.passPct, resolution: tt.resolution}
+
+			r, err := Throughput(context.Background(), d, tp, 0, time.Second, 64)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(d.rates, tt.wantRates) {
+				t.Errorf("trials at %v, want %v", d.rates, tt.wantRates)
+			}
+			switch {
+			case tt.wantSearch == nil && d.searched != nil:
+				t.Errorf("binary search of %.1f-%.1f%%, want none", d.searched.LowPct, d.searched.HighPct)
+			case tt.wantSearch != nil && d.searched == nil:
+				t.Errorf("no binary search, want %v", tt.wantSearch)
+			case tt.wantSearch != nil && (d.searched.LowPct != tt.wantSearch[0] || d.searched.HighPct != tt.wantSearch[1]):
+				t.Errorf("binary search of %.1f-%.1f%%, want %v", d.searched.LowPct, d.searched.HighPct, tt.wantSearch)
+			}
+			if r.MaxRatePct != tt.wantMax || r.Iterations != tt.wantIter || len(r.Trials) != int(tt.wantIter) {
+				t.Errorf("max %.2f%% in %d iterations (%d trials), want %.2f%% in %d",
+					r.MaxRatePct, r.Iterations, len(r.Trials), tt.wantMax, tt.wantIter)
+			}
+			if r.Search != string(tt.algorithm) {
+				t.Errorf("search %q, want %q", r.Search, tt.algorithm)
+			}
+		})
+	}
+}
+
+func TestThroughputLinearResult(t *testing.T) {
+	tp := config.DefaultConfig().Throughput
+	tp.SearchAlgorithm, tp.InitialRatePct, tp.StepPct = config.SearchLinear, 100, 10
+	r, err := Throughput(context.Background(), &dut{passPct: 50}, tp, 0, time.Second, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 1000 frames in a second, each with 20 bytes of preamble and gap
+	if r.MaxRatePct != 50 || r.MaxRatePPS != 1000 || r.MaxRateMbps != 1000*84*8/1e6 {
+		t.Errorf("result %.1f%%, %.0f pps, %.3f Mbps", r.MaxRatePct, r.MaxRatePPS, r.MaxRateMbps)
+	}
+	if r.FrameSize != 64 || !r.Trials[len(r.Trials)-1].Passed || r.Trials[0].Passed {
+		t.Errorf("result %+v", r)
+	}
+}
+
+func TestThroughputErrors(t *testing.T) {
+	tp := config.DefaultConfig().Throughput
+	tp.SearchAlgorithm, tp.StepPct = config.SearchLinear, 0
+	if _, err := Throughput(context.Background(), &dut{}, tp, 0, time.Second, 64); err == nil {
+		t.Error("expected an error for a step_pct of 0")
+	}
+
+	failed := errors.New("link down")
+	tp.StepPct = 10
+	if _, err := Throughput(context.Background(), &dut{err: failed}, tp, 0, time.Second, 64); !errors.Is(err, failed) {
+		t.Errorf("trial error: %v", err)
+	}
+}
+
+func TestPartialDropRate(t *testing.T) {
+	tests := []struct {
+		name      string
+		passPct   float64
+		lossPct   float64
+		threshold float64
+		wantRate  float64 // Marked partial drop rate, 0 for none
+		wantTrial int
+	}{
+		{"start passes", 100, 0, 0.5, 100, 1},
+		// 100, 55, 77.5, 66.25, 60.625, 63.4375, 62.03125, 62.734375, 63.0859375
+		{"converges to the resolution", 63.3, 0, 0.5, 63.0859375, 9},
+		{"loss at the threshold passes", 40, 0.5, 0.5, 100, 1},
+		{"loss above the threshold fails", 40, 0.5, 0.4, 39.8828125, 9},
+		// The search gives up half a percent above end_pct, then tries it
+		{"even the minimum fails", 5, 0, 0.5, 0, 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fl := config.DefaultConfig().FrameLoss
+			fl.StartPct, fl.EndPct, fl.SearchThresholdPct, fl.SearchResolutionPct = 100, 10, tt.threshold, 0.5
+			d := &dut{passPct: tt.passPct, lossPct: tt.lossPct}
+
+			results, err := PartialDropRate(context.Background(), d, fl, time.Second)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(results) != tt.wantTrial || len(d.rates) != tt.wantTrial {
+				t.Errorf("%d results of %d trials at %v, want %d", len(results), len(d.rates), d.rates, tt.wantTrial)
+			}
+			marked := 0.0
+			for i, r := range results {
+				if i > 0 && r.OfferedPct > results[i-1].OfferedPct {
+					t.Errorf("results not highest load first: %.2f%% after %.2f%%", r.OfferedPct, results[i-1].OfferedPct)
+				}
+				if r.PartialDropRate {
+					if marked != 0 {
+						t.Errorf("%.2f%% and %.2f%% both marked", marked, r.OfferedPct)
+					}
+					marked = r.OfferedPct
+				}
+			}
+			if marked != tt.wantRate {
+				t.Errorf("partial drop rate %v%%, want %v%%", marked, tt.wantRate)
+			}
+			if tt.wantRate == 0 && d.rates[len(d.rates)-1] != fl.EndPct {
+				t.Errorf("last trial at %.2f%%, want end_pct %.2f%%", d.rates[len(d.rates)-1], fl.EndPct)
+			}
+		})
+	}
+}
+
+func TestPartialDropRateError(t *testing.T) {
+	fl := config.DefaultConfig().FrameLoss
+	fl.SearchThresholdPct = 0.5
+	failed := errors.New("link down")
+	if _, err := PartialDropRate(context.Background(), &dut{err: failed}, fl, time.Second); !errors.Is(err, failed) {
+		t.Errorf("trial error: %v", err)
+	}
+}
@@ -0,0 +1,202 @@
+// Package selfprof profiles the tester itself while a test runs
+//
+// Results that look like a DUT limit are sometimes the tester running out
+// of CPU. The Sampler records the process's own CPU and memory use at a
+// fixed interval so a run can show the generator had headroom, and Handler
+// serves the Go pprof endpoints for digging into performance reports.
+// CPU time covers the whole process, so the C dataplane threads are
+// included alongside the Go control plane.
+package selfprof
+
+import (
+	"context"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Sample is one reading of the process's resource use
+type Sample struct {
+	Time       time.Time `json:"time"`
+	CPUPct     float64   `json:"cpu_pct"` // Since the previous sample; 100 = one core busy
+	RSSBytes   uint64    `json:"rss_bytes"`
+	HeapBytes  uint64    `json:"heap_bytes"` // Go heap in use
+	Goroutines int       `json:"goroutines"`
+	NumGC      uint32    `json:"num_gc"`
+}
+
+// Report is the resource use recorded with a run
+type Report struct {
+	IntervalSec   float64  `json:"interval_sec"`
+	NumCPU        int      `json:"num_cpu"`
+	AvgCPUPct     float64  `json:"avg_cpu_pct"`
+	PeakCPUPct    float64  `json:"peak_cpu_pct"`
+	PeakRSSBytes  uint64   `json:"peak_rss_bytes"`
+	PeakHeapBytes uint64   `json:"peak_heap_bytes"`
+	MaxGoroutines int      `json:"max_goroutines"`
+	Samples       []Sample `json:"samples"`
+}
+
+// summarize fills the report's aggregates from its samples
+func (r *Report) summarize() {
+	if len(r.Samples) == 0 {
+		return
+	}
+	var sum float64
+	for _, s := range r.Samples {
+		sum += s.CPUPct
+		if s.CPUPct > r.PeakCPUPct {
+			r.PeakCPUPct = s.CPUPct
+		}
+		if s.RSSBytes > r.PeakRSSBytes {
+			r.PeakRSSBytes = s.RSSBytes
+		}
+		if s.HeapBytes > r.PeakHeapBytes {
+			r.PeakHeapBytes = s.HeapBytes
+		}
+		if s.Goroutines > r.MaxGoroutines {
+			r.MaxGoroutines = s.Goroutines
+		}
+	}
+	r.AvgCPUPct = sum / float64(len(r.Samples))
+}
+
+// Sampler reads the process's resource use at a fixed interval
+type Sampler struct {
+	interval time.Duration
+
+	mu      sync.Mutex
+	samples []Sample
+	lastCPU time.Duration
+	lastAt  time.Time
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// NewSampler creates a sampler reading every interval
+func NewSampler(interval time.Duration) *Sampler {
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	return &Sampler{interval: interval}
+}
+
+// Start begins sampling in the background. CPU use is measured from here.
+func (s *Sampler) Start() {
+	s.lastCPU, s.lastAt = cpuTime(), time.Now()
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	s.wg.Add(1)
+	go s.loop(ctx)
+}
+
+// Stop halts sampling, takes a final reading and returns the report
+func (s *Sampler) Stop() *Report {
+	if s.cancel != nil {
+		s.cancel()
+		s.wg.Wait()
+		s.cancel = nil
+
+		// A final reading covers the tail of the run, unless it is too
+		// short for a meaningful CPU figure
+		s.mu.Lock()
+		short := len(s.samples) > 0 && time.Since(s.lastAt) < s.interval/4
+		s.mu.Unlock()
+		if !short {
+			s.sample(time.Now())
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := &Report{
+		IntervalSec: s.interval.Seconds(),
+		NumCPU:      runtime.NumCPU(),
+		Samples:     append([]Sample(nil), s.samples...),
+	}
+	r.summarize()
+	return r
+}
+
+func (s *Sampler) loop(ctx context.Context) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.sample(now)
+		}
+	}
+}
+
+func (s *Sampler) sample(now time.Time) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	cpu := cpuTime()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	wall := now.Sub(s.lastAt)
+	if wall <= 0 {
+		return
+	}
+	s.samples = append(s.samples, Sample{
+		Time:       now,
+		CPUPct:     float64(cpu-s.lastCPU) / float64(wall) * 100,
+		RSSBytes:   rss(),
+		HeapBytes:  mem.HeapInuse,
+		Goroutines: runtime.NumGoroutine(),
+		NumGC:      mem.NumGC,
+	})
+	s.lastCPU, s.lastAt = cpu, now
+}
+
+// cpuTime returns the user and system CPU time used by the process
+func cpuTime() time.Duration {
+	var ru syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &ru); err != nil {
+		return 0
+	}
+	return time.Duration(ru.Utime.Nano() + ru.Stime.Nano())
+}
+
+// rss returns the resident set size, or 0 where /proc is not available
+func rss() uint64 {
+	data, err := os.ReadFile("/proc/self/statm")
+	if err != nil {
+		return 0
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) < 2 {
+		return 0
+	}
+	pages, err := strconv.ParseUint(fields[1], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return pages * uint64(os.Getpagesize())
+}
+
+// Handler serves the pprof endpoints under /debug/pprof/ on its own mux,
+// so they are never exposed on the Web UI's address by accident
+func Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return mux
+}
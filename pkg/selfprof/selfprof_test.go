@@ -0,0 +1,88 @@
+package selfprof
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestSampler(t *testing.T) {
+	s := NewSampler(20 * time.Millisecond)
+	s.Start()
+
+	// Keep one core busy for a few intervals
+	deadline := time.Now().Add(120 * time.Millisecond)
+	x := 0
+	for time.Now().Before(deadline) {
+		x++
+	}
+	r := s.Stop()
+
+	if len(r.Samples) < 3 {
+		t.Fatalf("Samples = %d, want at least 3", len(r.Samples))
+	}
+	if r.NumCPU != runtime.NumCPU() || r.IntervalSec != 0.02 {
+		t.Errorf("Report = %+v, want %d CPUs at 0.02s", r, runtime.NumCPU())
+	}
+	if r.PeakCPUPct < 20 {
+		t.Errorf("PeakCPUPct = %.1f, want a busy core to show", r.PeakCPUPct)
+	}
+	if r.PeakHeapBytes == 0 || r.MaxGoroutines == 0 {
+		t.Errorf("Report = %+v, want heap and goroutines recorded", r)
+	}
+	for i := 1; i < len(r.Samples); i++ {
+		if !r.Samples[i].Time.After(r.Samples[i-1].Time) {
+			t.Fatalf("samples out of order at %d", i)
+		}
+	}
+
+	// Stopping again returns the same samples without a new reading
+	if again := s.Stop(); len(again.Samples) != len(r.Samples) {
+		t.Errorf("second Stop has %d samples, want %d", len(again.Samples), len(r.Samples))
+	}
+	_ = x
+}
+
+func TestSummarize(t *testing.T) {
+	r := &Report{Samples: []Sample{
+		{CPUPct: 10, RSSBytes: 100, HeapBytes: 50, Goroutines: 4},
+		{CPUPct: 30, RSSBytes: 300, HeapBytes: 20, Goroutines: 9},
+		{CPUPct: 20, RSSBytes: 200, HeapBytes: 70, Goroutines: 6},
+	}}
+	r.summarize()
+	if r.AvgCPUPct != 20 || r.PeakCPUPct != 30 {
+		t.Errorf("CPU avg %.1f peak %.1f, want 20 and 30", r.AvgCPUPct, r.PeakCPUPct)
+	}
+	if r.PeakRSSBytes != 300 || r.PeakHeapBytes != 70 || r.MaxGoroutines != 9 {
+		t.Errorf("Report = %+v, want peaks 300, 70 and 9", r)
+	}
+
+	empty := &Report{}
+	empty.summarize()
+	if empty.AvgCPUPct != 0 {
+		t.Error("empty report should summarize to zero")
+	}
+}
+
+func TestHandler(t *testing.T) {
+	srv := httptest.NewServer(Handler())
+	defer srv.Close()
+
+	for path, want := range map[string]int{
+		"/debug/pprof/":          http.StatusOK,
+		"/debug/pprof/goroutine": http.StatusOK,
+		"/debug/pprof/cmdline":   http.StatusOK,
+		"/":                      http.StatusNotFound,
+	} {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("GET %s = %d, want %d", path, resp.StatusCode, want)
+		}
+	}
+}
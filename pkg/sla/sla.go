@@ -0,0 +1,468 @@
+// Package sla builds MEF 10.3 performance attribute reports from Y.1564
+// performance runs repeated over a reporting period
+//
+// A service monitored by running the performance test on a schedule (e.g.
+// every 15 minutes from cron, each run saved with -o json) leaves a series
+// of measurement intervals per service. Over a period, the intervals of
+// each class of service give its MEF 10.3 attributes: Frame Delay (FD),
+// Mean Frame Delay (MFD), Inter-Frame Delay Variation (IFDV), Frame Loss
+// Ratio (FLR) and Availability, for monthly SLA reporting to customers.
+//
+// Each interval is one run's performance result, taken as a MEF 10.3 ΔT
+// interval: a sliding window of n intervals whose loss exceeds C makes the
+// time unavailable, and n below C make it available again. FD, MFD, IFDV
+// and FLR are measured over available time only. Runs report a delay
+// summary rather than every frame, so FD and IFDV are the Pd-percentile
+// of the intervals' mean delay and delay variation, weighted by frames
+// received.
+package sla
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/krisarmstrong/rfc2544-master/pkg/config"
+)
+
+// Service is a Y.1564 service's class and objectives, recorded in the
+// metadata of the runs that tested it
+type Service struct {
+	ServiceID       uint32  `json:"service_id"`
+	Name            string  `json:"name,omitempty"`
+	CoS             uint8   `json:"cos"`
+	FDThresholdMs   float64 `json:"fd_threshold_ms"`
+	FDVThresholdMs  float64 `json:"fdv_threshold_ms"`
+	FLRThresholdPct float64 `json:"flr_threshold_pct"`
+}
+
+// Interval is one measurement of a service
+type Interval struct {
+	Start    time.Time
+	Duration time.Duration
+	FramesTx uint64
+	FramesRx uint64
+	FDAvgMs  float64
+	FDVMs    float64
+}
+
+// LossPct returns the interval's frame loss ratio
+func (iv Interval) LossPct() float64 {
+	if iv.FramesTx == 0 || iv.FramesRx >= iv.FramesTx {
+		return 0
+	}
+	return float64(iv.FramesTx-iv.FramesRx) * 100 / float64(iv.FramesTx)
+}
+
+// Params are the MEF 10.3 parameters of the attributes
+type Params struct {
+	Percentile      float64 // Pd of FD and IFDV, e.g. 99
+	Window          int     // n, consecutive intervals that change availability
+	UnavailLossPct  float64 // C, loss above which an interval counts towards unavailability
+	AvailabilityPct float64 // Availability objective; 0 is not checked
+}
+
+// DefaultParams returns the parameters used when none are given
+func DefaultParams() Params {
+	return Params{Percentile: 99, Window: 1, UnavailLossPct: 50}
+}
+
+// Validate checks the parameters
+func (p Params) Validate() error {
+	if p.Percentile <= 0 || p.Percentile > 100 {
+		return fmt.Errorf("percentile must be in (0, 100]: %v", p.Percentile)
+	}
+	if p.Window < 1 {
+		return fmt.Errorf("window must be at least 1 interval: %d", p.Window)
+	}
+	if p.UnavailLossPct < 0 || p.UnavailLossPct >= 100 {
+		return fmt.Errorf("unavailability loss threshold must be in [0, 100): %v", p.UnavailLossPct)
+	}
+	if p.AvailabilityPct < 0 || p.AvailabilityPct > 100 {
+		return fmt.Errorf("availability objective must be 0-100%%: %v", p.AvailabilityPct)
+	}
+	return nil
+}
+
+// Objectives are a class's performance objectives; 0 is not checked
+type Objectives struct {
+	FDMs            float64 `json:"fd_ms,omitempty"`
+	IFDVMs          float64 `json:"ifdv_ms,omitempty"`
+	FLRPct          float64 `json:"flr_pct,omitempty"`
+	AvailabilityPct float64 `json:"availability_pct,omitempty"`
+}
+
+// Class is the performance of one class of service over the period
+type Class struct {
+	CoS                uint8      `json:"cos"`
+	Services           []string   `json:"services"`
+	Intervals          int        `json:"intervals"`
+	AvailableIntervals int        `json:"available_intervals"`
+	MeasuredSec        float64    `json:"measured_sec"`
+	FramesTx           uint64     `json:"frames_tx"` // Over available time
+	FramesRx           uint64     `json:"frames_rx"`
+	FDMs               float64    `json:"fd_ms"`
+	MFDMs              float64    `json:"mfd_ms"`
+	IFDVMs             float64    `json:"ifdv_ms"`
+	FLRPct             float64    `json:"flr_pct"`
+	AvailabilityPct    float64    `json:"availability_pct"`
+	Objectives         Objectives `json:"objectives"`
+	Failures           []string   `json:"failures,omitempty"`
+	Met                bool       `json:"met"`
+}
+
+// Report is the performance attribute report of a period
+type Report struct {
+	From       time.Time `json:"from"`
+	To         time.Time `json:"to"`
+	Runs       int       `json:"runs"`
+	DUT        string    `json:"dut,omitempty"`
+	Percentile float64   `json:"percentile"`
+	Window     int       `json:"window"`
+	UnavailPct float64   `json:"unavail_loss_pct"`
+	Classes    []Class   `json:"classes"`
+}
+
+// Run is one loaded results file
+type Run struct {
+	Source   string
+	DUT      string
+	Started  time.Time
+	Services []Service
+	Results  map[uint32]Interval // By service ID
+}
+
+// Load reads the Y.1564 performance results of a JSON results file
+// written with -o json
+func Load(path string) (*Run, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc struct {
+		Metadata struct {
+			Started  time.Time         `json:"started"`
+			Services []Service         `json:"services"`
+			DUT      *config.DUTConfig `json:"dut"`
+		} `json:"metadata"`
+		Results []json.RawMessage `json:"results"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if doc.Metadata.Started.IsZero() || len(doc.Metadata.Services) == 0 {
+		return nil, fmt.Errorf("%s: no Y.1564 run start and services recorded", path)
+	}
+
+	run := &Run{
+		Source:   path,
+		Started:  doc.Metadata.Started,
+		Services: doc.Metadata.Services,
+		Results:  make(map[uint32]Interval),
+	}
+	if d := doc.Metadata.DUT; d != nil {
+		run.DUT = d.Label()
+	}
+
+	// Performance results are told from configuration results by their
+	// duration; services follow each other in the run
+	start := run.Started
+	for _, raw := range doc.Results {
+		var r struct {
+			ServiceID   *uint32
+			DurationSec *uint32
+			FramesTx    uint64
+			FramesRx    uint64
+			FDAvgMs     float64
+			FDVMs       float64
+		}
+		if json.Unmarshal(raw, &r) != nil || r.ServiceID == nil || r.DurationSec == nil {
+			continue
+		}
+		d := time.Duration(*r.DurationSec) * time.Second
+		run.Results[*r.ServiceID] = Interval{
+			Start:    start,
+			Duration: d,
+			FramesTx: r.FramesTx,
+			FramesRx: r.FramesRx,
+			FDAvgMs:  r.FDAvgMs,
+			FDVMs:    r.FDVMs,
+		}
+		start = start.Add(d)
+	}
+	if len(run.Results) == 0 {
+		return nil, fmt.Errorf("%s: no Y.1564 performance results", path)
+	}
+	return run, nil
+}
+
+// Build reports the runs that started within [from, to). A zero from or
+// to leaves that end of the period open; the report's period is then the
+// span of the runs.
+func Build(runs []*Run, from, to time.Time, p Params) (*Report, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	rep := &Report{From: from, To: to, Percentile: p.Percentile, Window: p.Window, UnavailPct: p.UnavailLossPct}
+
+	type class struct {
+		services  map[uint32]Service
+		intervals []Interval
+	}
+	classes := make(map[uint8]*class)
+	var first, last time.Time
+	for _, run := range runs {
+		if (!from.IsZero() && run.Started.Before(from)) || (!to.IsZero() && !run.Started.Before(to)) {
+			continue
+		}
+		rep.Runs++
+		if rep.DUT == "" {
+			rep.DUT = run.DUT
+		}
+		for _, svc := range run.Services {
+			iv, ok := run.Results[svc.ServiceID]
+			if !ok || iv.FramesTx == 0 {
+				continue
+			}
+			c := classes[svc.CoS]
+			if c == nil {
+				c = &class{services: make(map[uint32]Service)}
+				classes[svc.CoS] = c
+			}
+			c.services[svc.ServiceID] = svc
+			c.intervals = append(c.intervals, iv)
+			if first.IsZero() || iv.Start.Before(first) {
+				first = iv.Start
+			}
+			if end := iv.Start.Add(iv.Duration); end.After(last) {
+				last = end
+			}
+		}
+	}
+	if len(classes) == 0 {
+		return nil, fmt.Errorf("no Y.1564 performance results in the period")
+	}
+	if rep.From.IsZero() {
+		rep.From = first
+	}
+	if rep.To.IsZero() {
+		rep.To = last
+	}
+
+	for cos, c := range classes {
+		var services []Service
+		for _, svc := range c.services {
+			services = append(services, svc)
+		}
+		sort.Slice(services, func(i, j int) bool { return services[i].ServiceID < services[j].ServiceID })
+		sort.SliceStable(c.intervals, func(i, j int) bool { return c.intervals[i].Start.Before(c.intervals[j].Start) })
+		rep.Classes = append(rep.Classes, buildClass(cos, services, c.intervals, p))
+	}
+	sort.Slice(rep.Classes, func(i, j int) bool { return rep.Classes[i].CoS < rep.Classes[j].CoS })
+	return rep, nil
+}
+
+// buildClass computes a class's attributes from its intervals in time order
+func buildClass(cos uint8, services []Service, intervals []Interval, p Params) Class {
+	c := Class{CoS: cos, Intervals: len(intervals)}
+
+	// The strictest objective of the class's services applies
+	for _, svc := range services {
+		name := strconv.FormatUint(uint64(svc.ServiceID), 10)
+		if svc.Name != "" {
+			name += " (" + svc.Name + ")"
+		}
+		c.Services = append(c.Services, name)
+		c.Objectives.FDMs = strictest(c.Objectives.FDMs, svc.FDThresholdMs)
+		c.Objectives.IFDVMs = strictest(c.Objectives.IFDVMs, svc.FDVThresholdMs)
+		c.Objectives.FLRPct = strictest(c.Objectives.FLRPct, svc.FLRThresholdPct)
+	}
+	c.Objectives.AvailabilityPct = p.AvailabilityPct
+
+	avail := Availability(intervals, p.Window, p.UnavailLossPct)
+	var measured, available time.Duration
+	var fd, ifdv []weighted
+	var fdSum float64
+	for i, iv := range intervals {
+		measured += iv.Duration
+		if !avail[i] {
+			continue
+		}
+		c.AvailableIntervals++
+		available += iv.Duration
+		c.FramesTx += iv.FramesTx
+		c.FramesRx += min(iv.FramesRx, iv.FramesTx)
+		if iv.FramesRx > 0 {
+			w := float64(iv.FramesRx)
+			fd = append(fd, weighted{iv.FDAvgMs, w})
+			ifdv = append(ifdv, weighted{iv.FDVMs, w})
+			fdSum += iv.FDAvgMs * w
+		}
+	}
+	c.MeasuredSec = measured.Seconds()
+	if measured > 0 {
+		c.AvailabilityPct = float64(available) * 100 / float64(measured)
+	}
+	if c.FramesTx > 0 {
+		c.FLRPct = float64(c.FramesTx-c.FramesRx) * 100 / float64(c.FramesTx)
+	}
+	if rx := c.FramesRx; rx > 0 {
+		c.MFDMs = fdSum / float64(rx)
+	}
+	c.FDMs = percentile(fd, p.Percentile)
+	c.IFDVMs = percentile(ifdv, p.Percentile)
+
+	check := func(name string, value, objective float64, higher bool) {
+		if objective <= 0 {
+			return
+		}
+		if (higher && value < objective) || (!higher && value > objective) {
+			c.Failures = append(c.Failures, fmt.Sprintf("%s %.4g, objective %.4g", name, value, objective))
+		}
+	}
+	check("FD", c.FDMs, c.Objectives.FDMs, false)
+	check("IFDV", c.IFDVMs, c.Objectives.IFDVMs, false)
+	check("FLR", c.FLRPct, c.Objectives.FLRPct, false)
+	check("availability", c.AvailabilityPct, c.Objectives.AvailabilityPct, true)
+	if c.AvailableIntervals == 0 {
+		c.Failures = append(c.Failures, "no available time")
+	}
+	c.Met = len(c.Failures) == 0
+	return c
+}
+
+// Availability returns whether each interval, in time order, was
+// available. The state changes at the first of window consecutive
+// intervals that all have loss above lossPct, or all at or below it.
+func Availability(intervals []Interval, window int, lossPct float64) []bool {
+	avail := make([]bool, len(intervals))
+	state := true
+	for i := range intervals {
+		if i+window <= len(intervals) {
+			high := 0
+			for _, iv := range intervals[i : i+window] {
+				if iv.LossPct() > lossPct {
+					high++
+				}
+			}
+			switch {
+			case state && high == window:
+				state = false
+			case !state && high == 0:
+				state = true
+			}
+		}
+		avail[i] = state
+	}
+	return avail
+}
+
+// strictest returns the lower of two objectives, ignoring unset ones
+func strictest(a, b float64) float64 {
+	if a <= 0 || (b > 0 && b < a) {
+		return b
+	}
+	return a
+}
+
+type weighted struct {
+	value, weight float64
+}
+
+// percentile returns the weighted pct-percentile of the values
+func percentile(values []weighted, pct float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i].value < values[j].value })
+	var total float64
+	for _, v := range values {
+		total += v.weight
+	}
+	target := total * pct / 100
+	var sum float64
+	for _, v := range values {
+		sum += v.weight
+		if sum >= target {
+			return v.value
+		}
+	}
+	return values[len(values)-1].value
+}
+
+// WriteText writes the report as a table
+func (r *Report) WriteText(w io.Writer) error {
+	fmt.Fprintf(w, "MEF 10.3 Performance Attribute Report\n")
+	fmt.Fprintf(w, "Period: %s to %s (%d runs)\n", r.From.Format(time.RFC3339), r.To.Format(time.RFC3339), r.Runs)
+	if r.DUT != "" {
+		fmt.Fprintf(w, "DUT: %s\n", r.DUT)
+	}
+	fmt.Fprintf(w, "FD and IFDV at P%g; unavailable after %d interval(s) with FLR > %g%%\n\n", r.Percentile, r.Window, r.UnavailPct)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "CoS\tFD(ms)\tMFD(ms)\tIFDV(ms)\tFLR%\tAvail%\tIntervals\tSLA\t")
+	for _, c := range r.Classes {
+		verdict := "MET"
+		if !c.Met {
+			verdict = "MISSED"
+		}
+		fmt.Fprintf(tw, "%d\t%.3f\t%.3f\t%.3f\t%.4f\t%.3f\t%d/%d\t%s\t\n",
+			c.CoS, c.FDMs, c.MFDMs, c.IFDVMs, c.FLRPct, c.AvailabilityPct, c.AvailableIntervals, c.Intervals, verdict)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	for _, c := range r.Classes {
+		fmt.Fprintf(w, "\nCoS %d: services %s\n", c.CoS, strings.Join(c.Services, ", "))
+		for _, f := range c.Failures {
+			fmt.Fprintf(w, "  Missed: %s\n", f)
+		}
+	}
+	return nil
+}
+
+// WriteCSV writes one row per class of service
+func (r *Report) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	header := []string{"from", "to", "cos", "services", "fd_ms", "mfd_ms", "ifdv_ms", "flr_pct", "availability_pct",
+		"intervals", "available_intervals", "fd_objective_ms", "ifdv_objective_ms", "flr_objective_pct", "availability_objective_pct", "met"}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	f := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+	for _, c := range r.Classes {
+		rec := []string{
+			r.From.Format(time.RFC3339), r.To.Format(time.RFC3339),
+			strconv.Itoa(int(c.CoS)), strings.Join(c.Services, ";"),
+			f(round(c.FDMs)), f(round(c.MFDMs)), f(round(c.IFDVMs)), f(round(c.FLRPct)), f(round(c.AvailabilityPct)),
+			strconv.Itoa(c.Intervals), strconv.Itoa(c.AvailableIntervals),
+			f(c.Objectives.FDMs), f(c.Objectives.IFDVMs), f(c.Objectives.FLRPct), f(c.Objectives.AvailabilityPct),
+			strconv.FormatBool(c.Met),
+		}
+		if err := cw.Write(rec); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteJSON writes the report as indented JSON
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// round keeps six decimals in exports
+func round(v float64) float64 {
+	return math.Round(v*1e6) / 1e6
+}
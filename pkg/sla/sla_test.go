@@ -0,0 +1,152 @@
+package sla
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAvailability(t *testing.T) {
+	lossy := Interval{FramesTx: 100, FramesRx: 10}
+	clean := Interval{FramesTx: 100, FramesRx: 100}
+	ivs := []Interval{clean, lossy, clean, lossy, lossy, lossy, clean, clean, lossy}
+
+	avail := Availability(ivs, 1, 50)
+	want := []bool{true, false, true, false, false, false, true, true, false}
+	for i := range want {
+		if avail[i] != want[i] {
+			t.Errorf("n=1: interval %d available %v", i, avail[i])
+		}
+	}
+
+	// With n=3 the lone lossy interval stays available, and the run of
+	// three turns unavailable from its first interval
+	avail = Availability(ivs, 3, 50)
+	want = []bool{true, true, true, false, false, false, false, false, false}
+	for i := range want {
+		if avail[i] != want[i] {
+			t.Errorf("n=3: interval %d available %v", i, avail[i])
+		}
+	}
+}
+
+func TestBuild(t *testing.T) {
+	start := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	services := []Service{
+		{ServiceID: 1, Name: "voice", CoS: 46, FDThresholdMs: 5, FDVThresholdMs: 1, FLRThresholdPct: 0.1},
+		{ServiceID: 2, Name: "data", CoS: 0, FDThresholdMs: 20, FLRThresholdPct: 1},
+	}
+	var runs []*Run
+	for i := 0; i < 10; i++ {
+		at := start.Add(time.Duration(i) * time.Hour)
+		voice := Interval{Start: at, Duration: 15 * time.Minute, FramesTx: 1000, FramesRx: 1000, FDAvgMs: 2, FDVMs: 0.5}
+		data := Interval{Start: at, Duration: 15 * time.Minute, FramesTx: 1000, FramesRx: 998, FDAvgMs: 10, FDVMs: 2}
+		if i == 4 {
+			voice.FramesRx = 0 // Outage
+		}
+		if i == 7 {
+			voice.FDAvgMs = 8
+		}
+		runs = append(runs, &Run{Started: at, Services: services, Results: map[uint32]Interval{1: voice, 2: data}})
+	}
+	// Outside the period
+	runs = append(runs, &Run{Started: start.AddDate(0, -1, 0), Services: services, Results: map[uint32]Interval{1: {FramesTx: 1}}})
+
+	p := DefaultParams()
+	p.AvailabilityPct = 99.9
+	rep, err := Build(runs, start, start.AddDate(0, 1, 0), p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Runs != 10 || len(rep.Classes) != 2 {
+		t.Fatalf("%d runs, %d classes", rep.Runs, len(rep.Classes))
+	}
+
+	data, voice := rep.Classes[0], rep.Classes[1]
+	if voice.CoS != 46 || voice.Intervals != 10 || voice.AvailableIntervals != 9 {
+		t.Errorf("voice %+v", voice)
+	}
+	if voice.AvailabilityPct != 90 || voice.FLRPct != 0 {
+		t.Errorf("voice availability %v FLR %v", voice.AvailabilityPct, voice.FLRPct)
+	}
+	// P99 of nine equally weighted intervals is the worst one
+	if voice.FDMs != 8 || voice.IFDVMs != 0.5 {
+		t.Errorf("voice FD %v IFDV %v", voice.FDMs, voice.IFDVMs)
+	}
+	if want := (8*2 + 8.0) / 9; voice.MFDMs < want-1e-9 || voice.MFDMs > want+1e-9 {
+		t.Errorf("voice MFD %v, want %v", voice.MFDMs, want)
+	}
+	if voice.Met || len(voice.Failures) != 2 {
+		t.Errorf("voice failures %v", voice.Failures)
+	}
+
+	if data.AvailabilityPct != 100 || data.FLRPct < 0.19 || data.FLRPct > 0.21 || !data.Met {
+		t.Errorf("data %+v", data)
+	}
+
+	var buf bytes.Buffer
+	if err := rep.WriteText(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "MISSED") || !strings.Contains(buf.String(), "1 (voice)") {
+		t.Errorf("text report:\n%s", buf.String())
+	}
+	buf.Reset()
+	if err := rep.WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(buf.String(), "\n"); lines != 3 {
+		t.Errorf("%d CSV lines", lines)
+	}
+
+	if _, err := Build(runs, start.AddDate(1, 0, 0), time.Time{}, p); err == nil {
+		t.Error("expected an error for a period without runs")
+	}
+	p.Window = 0
+	if _, err := Build(runs, time.Time{}, time.Time{}, p); err == nil {
+		t.Error("expected an error for window 0")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	doc := `{
+  "metadata": {
+    "started": "2026-09-01T00:00:00Z",
+    "test_type": "y1564_full",
+    "dut": {"vendor": "Acme", "model": "X1"},
+    "services": [{"service_id": 1, "cos": 46, "fd_threshold_ms": 5}, {"service_id": 2, "cos": 0}]
+  },
+  "results": [
+    {"ServiceID": 1, "Steps": [], "ServicePass": true},
+    {"ServiceID": 1, "DurationSec": 900, "FramesTx": 100, "FramesRx": 99, "FDAvgMs": 1.5, "FDVMs": 0.2},
+    {"ServiceID": 2, "DurationSec": 900, "FramesTx": 100, "FramesRx": 100, "FDAvgMs": 3}
+  ]
+}`
+	path := filepath.Join(t.TempDir(), "run.json")
+	if err := os.WriteFile(path, []byte(doc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	run, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.DUT != "Acme X1" || len(run.Results) != 2 {
+		t.Errorf("run %+v", run)
+	}
+	if iv := run.Results[1]; iv.FramesRx != 99 || iv.Duration != 15*time.Minute || !iv.Start.Equal(run.Started) {
+		t.Errorf("service 1 %+v", iv)
+	}
+	if iv := run.Results[2]; !iv.Start.Equal(run.Started.Add(15 * time.Minute)) {
+		t.Errorf("service 2 starts %v", iv.Start)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"metadata": {"test_type": "throughput"}, "results": []}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("expected an error for a run without Y.1564 services")
+	}
+}
@@ -0,0 +1,179 @@
+// Package threshold gates a run on its configured thresholds
+//
+// Only thresholds the test type measures are checked, so one config can
+// gate several tests; but one the test measures and the run has no result
+// for is a violation, since a gate must not pass on a figure that was
+// never taken.
+package threshold
+
+import (
+	"fmt"
+
+	"github.com/krisarmstrong/rfc2544-master/pkg/config"
+	"github.com/krisarmstrong/rfc2544-master/pkg/dataplane"
+	"github.com/krisarmstrong/rfc2544-master/pkg/udpecho"
+)
+
+// Check is one result compared against a threshold
+type Check struct {
+	FrameSize uint32 `json:"frame_size,omitempty"`
+	Criterion string `json:"criterion"`
+	Measured  string `json:"measured"`
+	Limit     string `json:"limit"`
+	Pass      bool   `json:"pass"`
+}
+
+// Report records a run's threshold checks
+type Report struct {
+	Passed      bool     `json:"passed"`
+	Checks      []Check  `json:"checks"`
+	NotMeasured []string `json:"not_measured,omitempty"` // Thresholds the run produced no result for
+}
+
+// MultiResult holds several tests' results at one frame size, as a suite
+// or characterization run does
+type MultiResult interface {
+	Tests() []config.TestType
+	Result(t config.TestType) interface{}
+}
+
+// passFail labels a check's outcome
+func passFail(pass bool) string {
+	if pass {
+		return "PASS"
+	}
+	return "FAIL"
+}
+
+// Evaluate compares the results against the thresholds of cfg, or returns
+// nil when none are set. frameSizes are the sizes the run tested, each of
+// which needs a throughput result when a throughput threshold is set.
+func Evaluate(results []interface{}, cfg *config.Config, frameSizes []uint32) *Report {
+	t := cfg.Thresholds
+	if !t.Enabled() {
+		return nil
+	}
+	tt := cfg.TestType
+	y1564 := tt == config.TestY1564Config || tt == config.TestY1564Perf || tt == config.TestY1564Full
+	measuresThroughput := tt == config.TestThroughput || tt == config.TestSuite || tt == config.TestCharacterize
+	measuresLatency := tt == config.TestLatency || tt == config.TestSuite || tt == config.TestCharacterize ||
+		tt == config.TestUDPEcho || (tt == config.TestThroughput && cfg.MeasureLatency)
+	measuresLoss := tt == config.TestFrameLoss || tt == config.TestSuite || tt == config.TestUDPEcho
+
+	r := &Report{Passed: true}
+	check := func(size uint32, criterion, measured, limit string, pass bool) {
+		r.Checks = append(r.Checks, Check{FrameSize: size, Criterion: criterion, Measured: measured, Limit: limit, Pass: pass})
+		if !pass {
+			r.Passed = false
+		}
+	}
+	latency := func(size uint32, what string, avgUs, p99Us float64) {
+		if t.MaxLatencyAvgUs > 0 {
+			check(size, "Average latency"+what, fmt.Sprintf("%.2f us", avgUs), fmt.Sprintf("<= %.2f us", t.MaxLatencyAvgUs), avgUs <= t.MaxLatencyAvgUs)
+		}
+		if t.MaxLatencyP99Us > 0 {
+			check(size, "P99 latency"+what, fmt.Sprintf("%.2f us", p99Us), fmt.Sprintf("<= %.2f us", t.MaxLatencyP99Us), p99Us <= t.MaxLatencyP99Us)
+		}
+	}
+	loss := func(size uint32, what string, pct float64) {
+		check(size, "Frame loss"+what, fmt.Sprintf("%.4f%%", pct), fmt.Sprintf("<= %.4g%%", t.MaxFrameLossPct), pct <= t.MaxFrameLossPct)
+	}
+
+	// Suite and characterization runs hold each test's result per frame size
+	var flat []interface{}
+	for _, res := range results {
+		if mr, ok := res.(MultiResult); ok {
+			for _, st := range mr.Tests() {
+				if tr := mr.Result(st); tr != nil {
+					flat = append(flat, tr)
+				}
+			}
+			continue
+		}
+		flat = append(flat, res)
+	}
+
+	measured := map[string]bool{}
+	throughputSizes := map[uint32]bool{}
+	for _, res := range flat {
+		switch v := res.(type) {
+		case *dataplane.ThroughputResultCLI:
+			throughputSizes[v.FrameSize] = true
+			if min, ok := t.MinThroughputMbps[v.FrameSize]; ok {
+				check(v.FrameSize, "Throughput", fmt.Sprintf("%.2f Mbps", v.MaxRateMbps), fmt.Sprintf(">= %.2f Mbps", min), v.MaxRateMbps >= min)
+			}
+			if t.MinThroughputPct > 0 {
+				check(v.FrameSize, "Throughput", fmt.Sprintf("%.2f%%", v.MaxRatePct), fmt.Sprintf(">= %.2f%%", t.MinThroughputPct), v.MaxRatePct >= t.MinThroughputPct)
+			}
+			if v.Latency.AvgNs > 0 && tt == config.TestThroughput {
+				measured["latency"] = true
+				latency(v.FrameSize, " at throughput", v.Latency.AvgNs/1000, v.Latency.P99Ns/1000)
+			}
+		case []dataplane.LatencyResultCLI:
+			for _, lr := range v {
+				measured["latency"] = true
+				latency(lr.FrameSize, fmt.Sprintf(" at %.0f%% load", lr.LoadPct), lr.Latency.AvgNs/1000, lr.Latency.P99Ns/1000)
+			}
+		case []dataplane.FrameLossResultCLI:
+			if t.MaxFrameLossPct < 0 {
+				continue
+			}
+			// The highest offered load per frame size
+			top := map[uint32]dataplane.FrameLossResultCLI{}
+			var sizes []uint32
+			for _, fl := range v {
+				have, ok := top[fl.FrameSize]
+				if !ok {
+					sizes = append(sizes, fl.FrameSize)
+				}
+				if !ok || fl.OfferedPct > have.OfferedPct {
+					top[fl.FrameSize] = fl
+				}
+			}
+			for _, size := range sizes {
+				measured["loss"] = true
+				loss(size, fmt.Sprintf(" at %.0f%% offered", top[size].OfferedPct), top[size].LossPct)
+			}
+		case *udpecho.Result:
+			measured["latency"] = true
+			latency(v.FrameSize, " (round trip)", v.AvgUs, v.P99Us)
+			if t.MaxFrameLossPct >= 0 {
+				measured["loss"] = true
+				loss(v.FrameSize, "", v.LossPct)
+			}
+		case *dataplane.Y1564ConfigResult:
+			if t.Y1564MustPass {
+				measured["y1564"] = true
+				check(0, fmt.Sprintf("Y.1564 service %d configuration test", v.ServiceID), passFail(v.ServicePass), "PASS", v.ServicePass)
+			}
+		case *dataplane.Y1564PerfResult:
+			if t.Y1564MustPass {
+				measured["y1564"] = true
+				check(0, fmt.Sprintf("Y.1564 service %d performance test", v.ServiceID), passFail(v.ServicePass), "PASS", v.ServicePass)
+			}
+		}
+	}
+
+	missing := func(what string) {
+		r.NotMeasured = append(r.NotMeasured, what)
+		r.Passed = false
+	}
+	if measuresThroughput {
+		for _, fs := range frameSizes {
+			_, perSize := t.MinThroughputMbps[fs]
+			if (perSize || t.MinThroughputPct > 0) && !throughputSizes[fs] {
+				missing(fmt.Sprintf("throughput at %d bytes", fs))
+			}
+		}
+	}
+	if measuresLatency && (t.MaxLatencyAvgUs > 0 || t.MaxLatencyP99Us > 0) && !measured["latency"] {
+		missing("latency")
+	}
+	if measuresLoss && t.MaxFrameLossPct >= 0 && !measured["loss"] {
+		missing("frame loss")
+	}
+	if y1564 && t.Y1564MustPass && !measured["y1564"] {
+		missing("Y.1564 services")
+	}
+	return r
+}
@@ -0,0 +1,81 @@
+package threshold
+
+import (
+	"testing"
+
+	"github.com/krisarmstrong/rfc2544-master/pkg/config"
+	"github.com/krisarmstrong/rfc2544-master/pkg/dataplane"
+)
+
+// suite holds one frame size's results the way a suite run does
+type suite struct {
+	throughput *dataplane.ThroughputResultCLI
+	latency    []dataplane.LatencyResultCLI
+}
+
+func (s *suite) Tests() []config.TestType {
+	return []config.TestType{config.TestThroughput, config.TestLatency}
+}
+
+func (s *suite) Result(t config.TestType) interface{} {
+	switch {
+	case t == config.TestThroughput && s.throughput != nil:
+		return s.throughput
+	case t == config.TestLatency && s.latency != nil:
+		return s.latency
+	}
+	return nil
+}
+
+func TestEvaluateDisabled(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Thresholds = config.ThresholdsConfig{MaxFrameLossPct: -1}
+	if r := Evaluate(nil, cfg, nil); r != nil {
+		t.Errorf("Evaluate() = %+v, want nil without thresholds", r)
+	}
+}
+
+func TestEvaluateThroughput(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.TestType = config.TestThroughput
+	cfg.Thresholds = config.ThresholdsConfig{MinThroughputPct: 90, MaxFrameLossPct: -1}
+
+	results := []interface{}{
+		&dataplane.ThroughputResultCLI{FrameSize: 64, MaxRatePct: 95},
+		&dataplane.ThroughputResultCLI{FrameSize: 128, MaxRatePct: 80},
+	}
+	r := Evaluate(results, cfg, []uint32{64, 128})
+	if r.Passed || len(r.Checks) != 2 || !r.Checks[0].Pass || r.Checks[1].Pass {
+		t.Fatalf("Evaluate() = %+v", r)
+	}
+
+	// A frame size without a result fails the gate
+	r = Evaluate(results[:1], cfg, []uint32{64, 128})
+	if r.Passed || len(r.NotMeasured) != 1 || r.NotMeasured[0] != "throughput at 128 bytes" {
+		t.Fatalf("Evaluate() = %+v", r)
+	}
+}
+
+func TestEvaluateSuite(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.TestType = config.TestSuite
+	cfg.Thresholds = config.ThresholdsConfig{MinThroughputPct: 90, MaxLatencyAvgUs: 10, MaxFrameLossPct: -1}
+
+	results := []interface{}{&suite{
+		throughput: &dataplane.ThroughputResultCLI{FrameSize: 64, MaxRatePct: 100},
+		latency: []dataplane.LatencyResultCLI{
+			{FrameSize: 64, LoadPct: 50, Latency: dataplane.LatencyStats{AvgNs: 5000}},
+		},
+	}}
+	r := Evaluate(results, cfg, []uint32{64})
+	if !r.Passed || len(r.Checks) != 2 || len(r.NotMeasured) != 0 {
+		t.Fatalf("Evaluate() = %+v", r)
+	}
+
+	// A suite that never measured latency cannot pass a latency threshold
+	results[0].(*suite).latency = nil
+	r = Evaluate(results, cfg, []uint32{64})
+	if r.Passed || len(r.NotMeasured) != 1 || r.NotMeasured[0] != "latency" {
+		t.Fatalf("Evaluate() = %+v", r)
+	}
+}
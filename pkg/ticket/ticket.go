@@ -0,0 +1,297 @@
+// Package ticket opens or updates a ticket in a ticketing system when a
+// run fails its SLA validation, attaching the report
+//
+// A Connector files an Issue against one system, ServiceNow or Jira. The
+// issue's Key identifies the circuit: while a ticket filed with the same
+// key is still open, later failures add to it instead of opening another,
+// so a circuit failing every scheduled validation overnight leaves the NOC
+// one ticket with every report attached.
+package ticket
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Issue is a failed validation to file
+type Issue struct {
+	Key         string // Identifies the circuit across runs, e.g. its circuit ID
+	Summary     string
+	Description string
+	Report      []byte // Attached to the ticket, if not empty
+	ReportName  string // File name of the attachment, e.g. CKT-0042.pdf
+	ReportType  string // MIME type of the attachment (empty = application/octet-stream)
+}
+
+// Ticket is the ticket an issue was filed as
+type Ticket struct {
+	ID      string // e.g. INC0012345 or NOC-17
+	URL     string
+	Created bool // Opened for this issue; false if an open ticket was updated
+}
+
+// Connector files issues with one ticketing system
+type Connector interface {
+	File(ctx context.Context, issue Issue) (*Ticket, error)
+}
+
+// client makes the JSON requests of a connector
+type client struct {
+	base     string
+	user     string
+	password string
+	http     *http.Client
+}
+
+func newClient(baseURL, user, password string) client {
+	return client{base: strings.TrimRight(baseURL, "/"), user: user, password: password, http: &http.Client{}}
+}
+
+// auth signs req with basic auth, or as a bearer token without a user
+func (c *client) auth(req *http.Request) {
+	switch {
+	case c.user != "":
+		req.SetBasicAuth(c.user, c.password)
+	case c.password != "":
+		req.Header.Set("Authorization", "Bearer "+c.password)
+	}
+}
+
+// do sends body, as JSON unless it is an io.Reader, with header added,
+// decoding the JSON response into out unless nil
+func (c *client) do(ctx context.Context, method, path string, body, out interface{}, header http.Header) error {
+	var r io.Reader
+	if body != nil {
+		if br, ok := body.(io.Reader); ok {
+			r = br
+		} else {
+			data, err := json.Marshal(body)
+			if err != nil {
+				return err
+			}
+			r = bytes.NewReader(data)
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.base+path, r)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	c.auth(req)
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		if text := strings.TrimSpace(string(msg)); text != "" {
+			return fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, text)
+		}
+		return fmt.Errorf("%s %s: %s", method, path, resp.Status)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// ServiceNow files issues as records of a table, incident by default,
+// finding open ones by their correlation ID
+type ServiceNow struct {
+	Table           string // Default incident
+	AssignmentGroup string // Name or sys_id (empty = the table's default)
+	c               client
+}
+
+// NewServiceNow returns a connector for the instance at baseURL, e.g.
+// https://example.service-now.com, with basic auth
+func NewServiceNow(baseURL, user, password string) *ServiceNow {
+	return &ServiceNow{c: newClient(baseURL, user, password)}
+}
+
+func (s *ServiceNow) table() string {
+	if s.Table == "" {
+		return "incident"
+	}
+	return s.Table
+}
+
+// snowRecord is the part of a Table API record the connector reads
+type snowRecord struct {
+	SysID  string `json:"sys_id"`
+	Number string `json:"number"`
+}
+
+// File adds a work note and the report to the open record for the issue's
+// key, or opens a record
+func (s *ServiceNow) File(ctx context.Context, issue Issue) (*Ticket, error) {
+	table := s.table()
+	path := "/api/now/table/" + url.PathEscape(table)
+
+	q := url.Values{}
+	q.Set("sysparm_query", "active=true^correlation_id="+issue.Key)
+	q.Set("sysparm_fields", "sys_id,number")
+	q.Set("sysparm_limit", "1")
+	var found struct {
+		Result []snowRecord `json:"result"`
+	}
+	if err := s.c.do(ctx, http.MethodGet, path+"?"+q.Encode(), nil, &found, nil); err != nil {
+		return nil, fmt.Errorf("servicenow: %w", err)
+	}
+
+	var rec snowRecord
+	created := len(found.Result) == 0
+	if created {
+		body := map[string]string{
+			"short_description": issue.Summary,
+			"description":       issue.Description,
+			"correlation_id":    issue.Key,
+		}
+		if s.AssignmentGroup != "" {
+			body["assignment_group"] = s.AssignmentGroup
+		}
+		var out struct {
+			Result snowRecord `json:"result"`
+		}
+		if err := s.c.do(ctx, http.MethodPost, path, body, &out, nil); err != nil {
+			return nil, fmt.Errorf("servicenow: %w", err)
+		}
+		rec = out.Result
+	} else {
+		rec = found.Result[0]
+		note := map[string]string{"work_notes": issue.Summary + "\n\n" + issue.Description}
+		if err := s.c.do(ctx, http.MethodPatch, path+"/"+url.PathEscape(rec.SysID), note, nil, nil); err != nil {
+			return nil, fmt.Errorf("servicenow: %w", err)
+		}
+	}
+
+	if len(issue.Report) > 0 {
+		q := url.Values{}
+		q.Set("table_name", table)
+		q.Set("table_sys_id", rec.SysID)
+		q.Set("file_name", issue.ReportName)
+		h := http.Header{"Content-Type": {reportType(issue)}}
+		if err := s.c.do(ctx, http.MethodPost, "/api/now/attachment/file?"+q.Encode(), bytes.NewReader(issue.Report), nil, h); err != nil {
+			return nil, fmt.Errorf("servicenow: attach report to %s: %w", rec.Number, err)
+		}
+	}
+
+	return &Ticket{
+		ID:      rec.Number,
+		URL:     s.c.base + "/nav_to.do?uri=" + url.QueryEscape(table+".do?sys_id="+rec.SysID),
+		Created: created,
+	}, nil
+}
+
+// Jira files issues in a project, finding open ones by a label derived
+// from the key
+type Jira struct {
+	Project   string // Project key, e.g. NOC
+	IssueType string // Default Bug
+	c         client
+}
+
+// NewJira returns a connector for the site at baseURL, e.g.
+// https://example.atlassian.net. Jira Cloud takes the account email as
+// user and an API token as password; Jira Server or Data Center takes a
+// personal access token as password with no user.
+func NewJira(baseURL, user, password, project string) *Jira {
+	return &Jira{Project: project, c: newClient(baseURL, user, password)}
+}
+
+// labelChars are those a Jira label can not contain
+var labelChars = regexp.MustCompile(`[^A-Za-z0-9_.:-]+`)
+
+// Label is the Jira label marking the tickets filed for a key
+func Label(key string) string {
+	return "rfc2544-" + labelChars.ReplaceAllString(key, "_")
+}
+
+// File comments on the open issue labelled for the issue's key with the
+// report attached, or opens an issue
+func (j *Jira) File(ctx context.Context, issue Issue) (*Ticket, error) {
+	label := Label(issue.Key)
+	search := map[string]interface{}{
+		"jql":        fmt.Sprintf("project = %q AND labels = %q AND statusCategory != Done ORDER BY created DESC", j.Project, label),
+		"maxResults": 1,
+		"fields":     []string{"key"},
+	}
+	var found struct {
+		Issues []struct {
+			Key string `json:"key"`
+		} `json:"issues"`
+	}
+	if err := j.c.do(ctx, http.MethodPost, "/rest/api/2/search", search, &found, nil); err != nil {
+		return nil, fmt.Errorf("jira: %w", err)
+	}
+
+	var key string
+	created := len(found.Issues) == 0
+	if created {
+		issueType := j.IssueType
+		if issueType == "" {
+			issueType = "Bug"
+		}
+		body := map[string]interface{}{
+			"fields": map[string]interface{}{
+				"project":     map[string]string{"key": j.Project},
+				"issuetype":   map[string]string{"name": issueType},
+				"summary":     issue.Summary,
+				"description": issue.Description,
+				"labels":      []string{label},
+			},
+		}
+		var out struct {
+			Key string `json:"key"`
+		}
+		if err := j.c.do(ctx, http.MethodPost, "/rest/api/2/issue", body, &out, nil); err != nil {
+			return nil, fmt.Errorf("jira: %w", err)
+		}
+		key = out.Key
+	} else {
+		key = found.Issues[0].Key
+		comment := map[string]string{"body": issue.Summary + "\n\n" + issue.Description}
+		if err := j.c.do(ctx, http.MethodPost, "/rest/api/2/issue/"+url.PathEscape(key)+"/comment", comment, nil, nil); err != nil {
+			return nil, fmt.Errorf("jira: %w", err)
+		}
+	}
+
+	if len(issue.Report) > 0 {
+		var buf bytes.Buffer
+		mw := multipart.NewWriter(&buf)
+		part, err := mw.CreateFormFile("file", issue.ReportName)
+		if err != nil {
+			return nil, err
+		}
+		part.Write(issue.Report)
+		mw.Close()
+		h := http.Header{"Content-Type": {mw.FormDataContentType()}, "X-Atlassian-Token": {"no-check"}}
+		if err := j.c.do(ctx, http.MethodPost, "/rest/api/2/issue/"+url.PathEscape(key)+"/attachments", &buf, nil, h); err != nil {
+			return nil, fmt.Errorf("jira: attach report to %s: %w", key, err)
+		}
+	}
+
+	return &Ticket{ID: key, URL: j.c.base + "/browse/" + key, Created: created}, nil
+}
+
+func reportType(issue Issue) string {
+	if issue.ReportType == "" {
+		return "application/octet-stream"
+	}
+	return issue.ReportType
+}
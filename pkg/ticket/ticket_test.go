@@ -0,0 +1,170 @@
+package ticket
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeSystem records the requests a connector makes, answering each by
+// method and path prefix
+type fakeSystem struct {
+	mu       sync.Mutex
+	requests []string
+	bodies   map[string]string
+	answers  map[string]string
+}
+
+func newFakeSystem(answers map[string]string) (*fakeSystem, *httptest.Server) {
+	f := &fakeSystem{bodies: map[string]string{}, answers: answers}
+	return f, httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		key := r.Method + " " + r.URL.Path
+		f.mu.Lock()
+		f.requests = append(f.requests, key)
+		f.bodies[key] = string(body)
+		answer, found := "", false
+		for prefix, a := range f.answers {
+			if strings.HasPrefix(key, prefix) {
+				answer, found = a, true
+			}
+		}
+		f.mu.Unlock()
+		if user, pass, ok := r.BasicAuth(); !ok || user != "noc" || pass != "s3cret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if !found {
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, answer)
+	}))
+}
+
+func (f *fakeSystem) body(key string) map[string]interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var v map[string]interface{}
+	json.Unmarshal([]byte(f.bodies[key]), &v)
+	return v
+}
+
+var testIssue = Issue{
+	Key:         "CKT-0042",
+	Summary:     "SLA validation failed: CKT-0042",
+	Description: "Throughput 612 Mbps (limit 900 Mbps)",
+	Report:      []byte(`{"results":[]}`),
+	ReportName:  "CKT-0042.json",
+	ReportType:  "application/json",
+}
+
+func TestServiceNowOpens(t *testing.T) {
+	f, ts := newFakeSystem(map[string]string{
+		"GET /api/now/table/incident":  `{"result":[]}`,
+		"POST /api/now/table/incident": `{"result":{"sys_id":"abc123","number":"INC0012345"}}`,
+	})
+	defer ts.Close()
+
+	s := NewServiceNow(ts.URL+"/", "noc", "s3cret")
+	s.AssignmentGroup = "NOC Tier 2"
+	tk, err := s.File(context.Background(), testIssue)
+	if err != nil {
+		t.Fatalf("File: %v", err)
+	}
+	if !tk.Created || tk.ID != "INC0012345" || !strings.Contains(tk.URL, "abc123") {
+		t.Errorf("ticket %+v", tk)
+	}
+	rec := f.body("POST /api/now/table/incident")
+	if rec["correlation_id"] != "CKT-0042" || rec["assignment_group"] != "NOC Tier 2" || rec["short_description"] != testIssue.Summary {
+		t.Errorf("record %v", rec)
+	}
+	if got := f.bodies["POST /api/now/attachment/file"]; got != string(testIssue.Report) {
+		t.Errorf("attachment %q", got)
+	}
+}
+
+func TestServiceNowUpdatesOpenRecord(t *testing.T) {
+	f, ts := newFakeSystem(map[string]string{
+		"GET /api/now/table/incident": `{"result":[{"sys_id":"abc123","number":"INC0012345"}]}`,
+	})
+	defer ts.Close()
+
+	tk, err := NewServiceNow(ts.URL, "noc", "s3cret").File(context.Background(), testIssue)
+	if err != nil {
+		t.Fatalf("File: %v", err)
+	}
+	if tk.Created || tk.ID != "INC0012345" {
+		t.Errorf("ticket %+v", tk)
+	}
+	if note := f.body("PATCH /api/now/table/incident/abc123"); !strings.Contains(note["work_notes"].(string), "612 Mbps") {
+		t.Errorf("work note %v", note)
+	}
+	for _, r := range f.requests {
+		if r == "POST /api/now/table/incident" {
+			t.Error("opened a second record")
+		}
+	}
+}
+
+func TestJiraOpensThenComments(t *testing.T) {
+	f, ts := newFakeSystem(map[string]string{
+		"POST /rest/api/2/search": `{"issues":[]}`,
+		"POST /rest/api/2/issue":  `{"key":"NOC-17"}`,
+	})
+	defer ts.Close()
+
+	j := NewJira(ts.URL, "noc", "s3cret", "NOC")
+	tk, err := j.File(context.Background(), testIssue)
+	if err != nil {
+		t.Fatalf("File: %v", err)
+	}
+	if !tk.Created || tk.ID != "NOC-17" || tk.URL != ts.URL+"/browse/NOC-17" {
+		t.Errorf("ticket %+v", tk)
+	}
+	fields := f.body("POST /rest/api/2/issue")["fields"].(map[string]interface{})
+	if labels := fields["labels"].([]interface{}); len(labels) != 1 || labels[0] != "rfc2544-CKT-0042" {
+		t.Errorf("labels %v", labels)
+	}
+	if fields["issuetype"].(map[string]interface{})["name"] != "Bug" {
+		t.Errorf("issue type %v", fields["issuetype"])
+	}
+	if !strings.Contains(f.bodies["POST /rest/api/2/issue/NOC-17/attachments"], `{"results":[]}`) {
+		t.Error("report not attached")
+	}
+
+	f.mu.Lock()
+	f.answers["POST /rest/api/2/search"] = `{"issues":[{"key":"NOC-17"}]}`
+	f.mu.Unlock()
+	if tk, err = j.File(context.Background(), testIssue); err != nil {
+		t.Fatalf("File: %v", err)
+	}
+	if tk.Created || tk.ID != "NOC-17" {
+		t.Errorf("second ticket %+v", tk)
+	}
+	if c := f.body("POST /rest/api/2/issue/NOC-17/comment"); !strings.Contains(c["body"].(string), "612 Mbps") {
+		t.Errorf("comment %v", c)
+	}
+}
+
+func TestFileError(t *testing.T) {
+	_, ts := newFakeSystem(nil)
+	defer ts.Close()
+
+	_, err := NewJira(ts.URL, "noc", "wrong", "NOC").File(context.Background(), testIssue)
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("expected the refusal, got %v", err)
+	}
+}
+
+func TestLabel(t *testing.T) {
+	if got := Label("CKT 0042/west"); got != "rfc2544-CKT_0042_west" {
+		t.Errorf("Label = %q", got)
+	}
+}
@@ -0,0 +1,286 @@
+package trex
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultPort is the TRex stateless RPC port
+const DefaultPort = 4501
+
+// API version requested from the server (TRex stateless API v5)
+const (
+	apiName  = "STL"
+	apiMajor = 5
+	apiMinor = 1
+)
+
+// Client is a JSON-RPC client for a TRex stateless server
+type Client struct {
+	mu       sync.Mutex
+	conn     *reqConn
+	id       int
+	apiH     string
+	handlers map[uint8]string // Port ownership handlers from acquire
+}
+
+type rpcRequest struct {
+	JSONRPC string                 `json:"jsonrpc"`
+	ID      int                    `json:"id"`
+	Method  string                 `json:"method"`
+	Params  map[string]interface{} `json:"params"`
+}
+
+type rpcResponse struct {
+	ID     int             `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Data    string `json:"specific_err"`
+	} `json:"error"`
+}
+
+// Dial connects to a TRex server at host[:port] and negotiates the API
+func Dial(addr string, timeout time.Duration) (*Client, error) {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, strconv.Itoa(DefaultPort))
+	}
+	conn, err := dialREQ(addr, timeout)
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{conn: conn, handlers: make(map[uint8]string)}
+
+	var api struct {
+		APIH string `json:"api_h"`
+	}
+	err = c.call("api_sync_v2", map[string]interface{}{
+		"name": apiName, "major": apiMajor, "minor": apiMinor,
+	}, &api)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	c.apiH = api.APIH
+	return c, nil
+}
+
+// call sends one request. Port methods get the port's handler when the
+// port has been acquired.
+func (c *Client) call(method string, params map[string]interface{}, result interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if params == nil {
+		params = make(map[string]interface{})
+	}
+	if c.apiH != "" {
+		params["api_h"] = c.apiH
+	}
+	if port, ok := params["port_id"].(uint8); ok {
+		if h, ok := c.handlers[port]; ok {
+			params["handler"] = h
+		}
+	}
+
+	c.id++
+	req, err := json.Marshal(rpcRequest{JSONRPC: "2.0", ID: c.id, Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+	data, err := c.conn.roundTrip(req)
+	if err != nil {
+		return fmt.Errorf("trex %s: %w", method, err)
+	}
+
+	var resp rpcResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return fmt.Errorf("trex %s: invalid response: %w", method, err)
+	}
+	if resp.Error != nil {
+		msg := resp.Error.Message
+		if resp.Error.Data != "" {
+			msg += ": " + resp.Error.Data
+		}
+		return fmt.Errorf("trex %s failed: %s", method, msg)
+	}
+	if result != nil && resp.Result != nil {
+		if err := json.Unmarshal(resp.Result, result); err != nil {
+			return fmt.Errorf("trex %s: invalid result: %w", method, err)
+		}
+	}
+	return nil
+}
+
+// Acquire takes ownership of a port. force takes it from another user.
+func (c *Client) Acquire(port uint8, user string, force bool) error {
+	var handler string
+	err := c.call("acquire", map[string]interface{}{
+		"port_id": port, "user": user, "session_id": 0, "force": force,
+	}, &handler)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.handlers[port] = handler
+	c.mu.Unlock()
+	return nil
+}
+
+// Release gives up ownership of a port
+func (c *Client) Release(port uint8) error {
+	err := c.call("release", map[string]interface{}{"port_id": port}, nil)
+	c.mu.Lock()
+	delete(c.handlers, port)
+	c.mu.Unlock()
+	return err
+}
+
+// PortStatus is the state and attributes of a port
+type PortStatus struct {
+	Owner string `json:"owner"`
+	State string `json:"state"` // IDLE, STREAMS, TX, PAUSE
+	Attr  struct {
+		SpeedMbps uint64 `json:"speed"` // Link speed
+		Link      struct {
+			Up bool `json:"up"`
+		} `json:"link"`
+	} `json:"attr"`
+}
+
+// Transmitting reports whether the port is still sending
+func (s *PortStatus) Transmitting() bool {
+	return s.State == "TX"
+}
+
+// PortStatus reads the state of a port
+func (c *Client) PortStatus(port uint8) (*PortStatus, error) {
+	var s PortStatus
+	if err := c.call("get_port_status", map[string]interface{}{"port_id": port}, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// PortStats are a port's cumulative counters
+type PortStats struct {
+	OPackets uint64 `json:"opackets"`
+	IPackets uint64 `json:"ipackets"`
+	OBytes   uint64 `json:"obytes"`
+	IBytes   uint64 `json:"ibytes"`
+}
+
+// PortStats reads a port's counters
+func (c *Client) PortStats(port uint8) (*PortStats, error) {
+	var s PortStats
+	if err := c.call("get_port_stats", map[string]interface{}{"port_id": port}, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// RemoveAllStreams clears a port's streams
+func (c *Client) RemoveAllStreams(port uint8) error {
+	return c.call("remove_all_streams", map[string]interface{}{"port_id": port}, nil)
+}
+
+// AddStream adds a stream to a port
+func (c *Client) AddStream(port uint8, id int, s *Stream) error {
+	return c.call("add_stream", map[string]interface{}{
+		"port_id": port, "stream_id": id, "stream": s,
+	}, nil)
+}
+
+// StartTraffic starts a port's streams at their own rates. A duration of
+// zero runs until stopped; bursts stop by themselves.
+func (c *Client) StartTraffic(port uint8, duration time.Duration) error {
+	d := -1.0
+	if duration > 0 {
+		d = duration.Seconds()
+	}
+	return c.call("start_traffic", map[string]interface{}{
+		"port_id":  port,
+		"mul":      map[string]interface{}{"type": "raw", "op": "abs", "value": 1.0},
+		"duration": d,
+		"force":    true,
+	}, nil)
+}
+
+// StopTraffic stops a port's streams
+func (c *Client) StopTraffic(port uint8) error {
+	return c.call("stop_traffic", map[string]interface{}{"port_id": port}, nil)
+}
+
+// LatencyStats are the latency counters for one packet group, in µs
+type LatencyStats struct {
+	Average  float64 `json:"average"`
+	Min      float64 `json:"total_min"`
+	Max      float64 `json:"total_max"`
+	Jitter   float64 `json:"jitter"`
+	Received uint64  `json:"-"` // Latency packets received
+}
+
+// Latency reads the latency counters of a packet group, or nil if the
+// group has none
+func (c *Client) Latency(pgid int) (*LatencyStats, error) {
+	stats, err := c.PGStats([]int{pgid})
+	if err != nil {
+		return nil, err
+	}
+	return stats[pgid].Latency, nil
+}
+
+// PGStats are the counters of one packet group
+type PGStats struct {
+	TxPkts  uint64
+	RxPkts  uint64
+	Latency *LatencyStats // nil unless the group measures latency
+}
+
+// PGStats reads the counters of packet groups
+func (c *Client) PGStats(pgids []int) (map[int]*PGStats, error) {
+	var resp struct {
+		Latency map[string]struct {
+			Latency LatencyStats `json:"latency"`
+		} `json:"latency"`
+		FlowStats map[string]struct {
+			TxPkts map[string]uint64 `json:"tx_pkts"`
+			RxPkts map[string]uint64 `json:"rx_pkts"`
+		} `json:"flow_stats"`
+	}
+	if err := c.call("get_pgid_stats", map[string]interface{}{"pgids": pgids}, &resp); err != nil {
+		return nil, err
+	}
+	stats := make(map[int]*PGStats, len(pgids))
+	for _, id := range pgids {
+		key := strconv.Itoa(id)
+		f := resp.FlowStats[key]
+		s := &PGStats{TxPkts: f.TxPkts["total"], RxPkts: f.RxPkts["total"]}
+		if l, ok := resp.Latency[key]; ok {
+			lat := l.Latency
+			lat.Received = s.RxPkts
+			s.Latency = &lat
+		}
+		stats[id] = s
+	}
+	return stats, nil
+}
+
+// Close releases acquired ports and closes the connection
+func (c *Client) Close() error {
+	c.mu.Lock()
+	ports := make([]uint8, 0, len(c.handlers))
+	for p := range c.handlers {
+		ports = append(ports, p)
+	}
+	c.mu.Unlock()
+	for _, p := range ports {
+		c.Release(p)
+	}
+	return c.conn.Close()
+}
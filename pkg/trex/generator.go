@@ -0,0 +1,461 @@
+// Package trex drives a TRex traffic generator over its stateless JSON-RPC
+// API, running the RFC 2544 trials on TRex ports instead of the local
+// dataplane
+//
+// A Generator owns one transmit and one receive port (the same port when
+// the DUT loops traffic back) and runs each trial by loading a fixed
+// Ethernet/IPv4/UDP stream, transmitting for the trial duration and
+// comparing the port counters before and after. Latency comes from a
+// low-rate TRex latency stream sent alongside the test stream. A QoS
+// trial sends one marked stream per class at once and counts each with
+// TRex flow statistics.
+package trex
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// ErrCancelled is returned by trials after Cancel
+var ErrCancelled = errors.New("cancelled")
+
+// Defaults for unset Options
+const (
+	DefaultTimeout = 10 * time.Second
+	DefaultUser    = "rfc2544"
+)
+
+const (
+	pollInterval   = 100 * time.Millisecond
+	latencyPPS     = 1000    // Rate of the latency stream, taken out of the offered load
+	maxBurstFrames = 1000000 // Back-to-back search cap, as in the local dataplane
+	ethOverhead    = 20      // Preamble, SFD and inter-frame gap, in bytes
+)
+
+// Options configure a Generator
+type Options struct {
+	Server        string        // host[:port]
+	TxPort        uint8         // Port sending toward the DUT
+	RxPort        uint8         // Port receiving from the DUT (may equal TxPort)
+	User          string        // Owner name shown by TRex
+	Force         bool          // Take ports owned by another user
+	LineRateMbps  uint64        // 0 = link speed of the TX port
+	Timeout       time.Duration // Per RPC call
+	TrialDuration time.Duration
+	Warmup        time.Duration // Traffic sent before each trial, not counted
+	Drain         time.Duration // Wait for in-flight frames after a trial
+	Latency       bool          // Add a latency stream to throughput and loss trials
+
+	// Throughput binary search (Section 26.1)
+	InitialRatePct float64
+	ResolutionPct  float64
+	MaxIterations  uint32
+	AcceptableLoss float64
+}
+
+// Latency is latency measured by the TRex latency stream
+type Latency struct {
+	Count    uint64
+	MinNs    float64
+	AvgNs    float64
+	MaxNs    float64
+	JitterNs float64
+}
+
+// Trial is the outcome of one fixed-rate trial
+type Trial struct {
+	RatePct       float64
+	FramesTx      uint64
+	FramesRx      uint64
+	LossPct       float64
+	DeliveredMbps float64 // Received rate including Ethernet overhead
+	Elapsed       time.Duration
+	Latency       Latency
+}
+
+// ThroughputResult is the highest rate with loss within the acceptable loss
+type ThroughputResult struct {
+	FrameSize   uint32
+	MaxRatePct  float64
+	MaxRateMbps float64
+	MaxRatePPS  float64
+	Iterations  uint32
+	Latency     Latency // From the best passing trial
+
+	AcceptableLoss float64       // Loss a passing trial was allowed
+	Trials         []SearchTrial // Every iteration, in order
+}
+
+// SearchTrial is one iteration of a throughput search
+type SearchTrial struct {
+	Trial
+	Passed bool // Loss within the acceptable loss; the search went up
+}
+
+// BurstResult is the longest burst forwarded without loss
+type BurstResult struct {
+	FrameSize       uint32
+	MaxBurstFrames  uint64
+	BurstDurationUs float64
+	Trials          uint32 // Burst sizes that passed
+}
+
+// Generator runs RFC 2544 trials on a TRex server
+type Generator struct {
+	client      *Client
+	opts        Options
+	lineRateBps float64
+	frameSize   uint32
+	pgid        int
+	cancelled   atomic.Bool
+}
+
+// Connect opens a session on the TRex server and acquires the ports
+func Connect(opts Options) (*Generator, error) {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.User == "" {
+		opts.User = DefaultUser
+	}
+
+	client, err := Dial(opts.Server, opts.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	g := &Generator{client: client, opts: opts, frameSize: 64}
+
+	for _, port := range g.ports() {
+		if err := client.Acquire(port, opts.User, opts.Force); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("acquire port %d: %w", port, err)
+		}
+	}
+
+	g.lineRateBps = float64(opts.LineRateMbps) * 1e6
+	if g.lineRateBps == 0 {
+		status, err := client.PortStatus(opts.TxPort)
+		if err != nil {
+			client.Close()
+			return nil, err
+		}
+		if status.Attr.SpeedMbps == 0 {
+			client.Close()
+			return nil, fmt.Errorf("port %d reports no link speed; set the line rate", opts.TxPort)
+		}
+		g.lineRateBps = float64(status.Attr.SpeedMbps) * 1e6
+	}
+	return g, nil
+}
+
+func (g *Generator) ports() []uint8 {
+	if g.opts.RxPort == g.opts.TxPort {
+		return []uint8{g.opts.TxPort}
+	}
+	return []uint8{g.opts.TxPort, g.opts.RxPort}
+}
+
+// LineRateMbps returns the line rate trial rates are relative to
+func (g *Generator) LineRateMbps() float64 {
+	return g.lineRateBps / 1e6
+}
+
+// SetFrameSize sets the frame size for subsequent trials
+func (g *Generator) SetFrameSize(size uint32) {
+	g.frameSize = size
+}
+
+// SetAcceptableLoss sets the loss a throughput trial may show and still pass
+func (g *Generator) SetAcceptableLoss(pct float64) {
+	g.opts.AcceptableLoss = pct
+}
+
+// Cancel stops the running trial; later trials return ErrCancelled
+func (g *Generator) Cancel() {
+	g.cancelled.Store(true)
+}
+
+// Close stops traffic and releases the ports
+func (g *Generator) Close() error {
+	g.client.StopTraffic(g.opts.TxPort)
+	return g.client.Close()
+}
+
+// maxPPS is the line rate in frames per second at the current frame size
+func (g *Generator) maxPPS() float64 {
+	return g.lineRateBps / (float64(g.frameSize+ethOverhead) * 8)
+}
+
+// Trial offers ratePct of line rate for duration
+func (g *Generator) Trial(ratePct float64, duration time.Duration) (*Trial, error) {
+	return g.trial(ratePct, duration, g.opts.Latency)
+}
+
+// Latency runs a trial at loadPct of line rate with latency measurement
+// (Section 26.2)
+func (g *Generator) Latency(loadPct float64) (*Trial, error) {
+	t, err := g.trial(loadPct, g.opts.TrialDuration, true)
+	if err != nil {
+		return nil, err
+	}
+	if t.Latency.Count == 0 {
+		return nil, fmt.Errorf("no latency packets received at %.1f%%", loadPct)
+	}
+	return t, nil
+}
+
+func (g *Generator) trial(ratePct float64, duration time.Duration, latency bool) (*Trial, error) {
+	pps := g.maxPPS() * ratePct / 100
+	if pps < 1 {
+		return nil, fmt.Errorf("rate %.4f%% is below 1 frame/s", ratePct)
+	}
+	mode := func(rate float64) Mode {
+		return Mode{Type: "continuous", Rate: Rate{Type: "pps", Value: rate}}
+	}
+	frame := buildFrame(g.frameSize)
+
+	if g.opts.Warmup > 0 {
+		if _, _, _, err := g.send([]*Stream{newStream(frame, mode(pps))}, g.opts.Warmup); err != nil {
+			return nil, err
+		}
+	}
+
+	streams := []*Stream{newStream(frame, mode(pps))}
+	pgid := 0
+	if latency && pps >= 2*latencyPPS {
+		g.pgid++
+		pgid = g.pgid
+		lat := newStream(frame, mode(latencyPPS))
+		lat.FlowStats = FlowStats{Enabled: true, StreamID: pgid, RuleType: "latency"}
+		streams[0].Mode.Rate.Value = pps - latencyPPS
+		streams = append(streams, lat)
+	}
+
+	tx, rx, elapsed, err := g.send(streams, duration)
+	if err != nil {
+		return nil, err
+	}
+	t := &Trial{RatePct: ratePct, FramesTx: tx, FramesRx: rx, LossPct: lossPct(tx, rx), Elapsed: elapsed}
+	if elapsed > 0 {
+		t.DeliveredMbps = float64(rx) * float64(g.frameSize+ethOverhead) * 8 / elapsed.Seconds() / 1e6
+	}
+
+	if pgid != 0 {
+		l, err := g.client.Latency(pgid)
+		if err != nil {
+			return nil, err
+		}
+		if l != nil {
+			t.Latency = latencyOf(l)
+		}
+	}
+	return t, nil
+}
+
+// latencyOf converts TRex latency counters, in µs, to a Latency
+func latencyOf(l *LatencyStats) Latency {
+	lat := Latency{
+		Count:    l.Received,
+		MinNs:    l.Min * 1e3,
+		AvgNs:    l.Average * 1e3,
+		MaxNs:    l.Max * 1e3,
+		JitterNs: l.Jitter * 1e3,
+	}
+	if lat.Count == 0 && l.Max > 0 {
+		lat.Count = 1 // Older servers do not report the latency packet count
+	}
+	return lat
+}
+
+// send loads the streams, transmits for duration (bursts end by
+// themselves when duration is zero) and returns the frames sent and
+// received over the trial
+func (g *Generator) send(streams []*Stream, duration time.Duration) (tx, rx uint64, elapsed time.Duration, err error) {
+	if g.cancelled.Load() {
+		return 0, 0, 0, ErrCancelled
+	}
+	c, txPort, rxPort := g.client, g.opts.TxPort, g.opts.RxPort
+
+	if err := c.RemoveAllStreams(txPort); err != nil {
+		return 0, 0, 0, err
+	}
+	for i, s := range streams {
+		if err := c.AddStream(txPort, i+1, s); err != nil {
+			return 0, 0, 0, err
+		}
+	}
+
+	before, err := g.counters()
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	start := time.Now()
+	if err := c.StartTraffic(txPort, duration); err != nil {
+		return 0, 0, 0, err
+	}
+	if err := g.wait(duration); err != nil {
+		return 0, 0, 0, err
+	}
+	elapsed = time.Since(start)
+	if g.opts.Drain > 0 {
+		time.Sleep(g.opts.Drain)
+	}
+	after, err := g.counters()
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	tx = after[txPort].OPackets - before[txPort].OPackets
+	rx = after[rxPort].IPackets - before[rxPort].IPackets
+	return tx, rx, elapsed, nil
+}
+
+// wait polls until the TX port stops sending, stopping it on cancel or
+// when it runs well past duration
+func (g *Generator) wait(duration time.Duration) error {
+	deadline := time.Now().Add(duration + g.opts.Timeout)
+	for {
+		if g.cancelled.Load() {
+			g.client.StopTraffic(g.opts.TxPort)
+			return ErrCancelled
+		}
+		status, err := g.client.PortStatus(g.opts.TxPort)
+		if err != nil {
+			return err
+		}
+		if !status.Transmitting() {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			g.client.StopTraffic(g.opts.TxPort)
+			return fmt.Errorf("port %d still transmitting after %v", g.opts.TxPort, duration)
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+func (g *Generator) counters() (map[uint8]*PortStats, error) {
+	stats := make(map[uint8]*PortStats)
+	for _, port := range g.ports() {
+		s, err := g.client.PortStats(port)
+		if err != nil {
+			return nil, err
+		}
+		stats[port] = s
+	}
+	return stats, nil
+}
+
+func lossPct(tx, rx uint64) float64 {
+	if tx == 0 || rx >= tx {
+		return 0
+	}
+	return float64(tx-rx) / float64(tx) * 100
+}
+
+// Search is the state of a throughput binary search between iterations
+type Search struct {
+	LowPct     float64
+	HighPct    float64
+	BestPct    float64
+	Iterations uint32
+	Latency    Latency
+	Trials     []SearchTrial
+}
+
+// Throughput binary searches for the highest passing rate (Section 26.1)
+func (g *Generator) Throughput() (*ThroughputResult, error) {
+	return g.ThroughputFrom(nil, nil)
+}
+
+// ThroughputFrom continues a throughput search from search, or starts one
+// if search is nil, calling step after every iteration
+func (g *Generator) ThroughputFrom(search *Search, step func(*Search)) (*ThroughputResult, error) {
+	o := g.opts
+	s := Search{HighPct: o.InitialRatePct}
+	if search != nil {
+		s = *search
+		s.Trials = append([]SearchTrial(nil), search.Trials...)
+	}
+
+	for s.HighPct-s.LowPct > o.ResolutionPct && s.Iterations < o.MaxIterations {
+		rate := (s.LowPct + s.HighPct) / 2
+		t, err := g.Trial(rate, o.TrialDuration)
+		if err != nil {
+			return nil, err
+		}
+		passed := t.LossPct <= o.AcceptableLoss
+		if passed {
+			s.BestPct, s.LowPct = rate, rate
+			s.Latency = t.Latency
+		} else {
+			s.HighPct = rate
+		}
+		s.Iterations++
+		s.Trials = append(s.Trials, SearchTrial{Trial: *t, Passed: passed})
+		if step != nil {
+			next := s
+			next.Trials = append([]SearchTrial(nil), s.Trials...)
+			step(&next)
+		}
+	}
+
+	return &ThroughputResult{
+		FrameSize:   g.frameSize,
+		MaxRatePct:  s.BestPct,
+		MaxRateMbps: g.LineRateMbps() * s.BestPct / 100,
+		MaxRatePPS:  g.maxPPS() * s.BestPct / 100,
+		Iterations:  s.Iterations,
+		Latency:     s.Latency,
+
+		AcceptableLoss: o.AcceptableLoss,
+		Trials:         s.Trials,
+	}, nil
+}
+
+// FrameLoss steps the offered load down from startPct to endPct
+// (Section 26.3)
+func (g *Generator) FrameLoss(startPct, endPct, stepPct float64) ([]Trial, error) {
+	if stepPct <= 0 {
+		return nil, fmt.Errorf("frame loss step must be > 0")
+	}
+	var trials []Trial
+	for rate := startPct; rate >= endPct; rate -= stepPct {
+		t, err := g.Trial(rate, g.opts.TrialDuration)
+		if err != nil {
+			return nil, err
+		}
+		trials = append(trials, *t)
+	}
+	return trials, nil
+}
+
+// BackToBack doubles the burst at line rate until a burst loses frames
+// in any of the trials (Section 26.4)
+func (g *Generator) BackToBack(initialBurst uint64, trials uint32) (*BurstResult, error) {
+	result := &BurstResult{FrameSize: g.frameSize}
+	frame := buildFrame(g.frameSize)
+	pps := g.maxPPS()
+
+	for burst := initialBurst; burst > 0 && burst <= maxBurstFrames; burst *= 2 {
+		passed := true
+		for i := uint32(0); i < trials && passed; i++ {
+			s := newStream(frame, Mode{Type: "single_burst", TotalPkts: burst, Rate: Rate{Type: "pps", Value: pps}})
+			tx, rx, _, err := g.send([]*Stream{s}, 0)
+			if err != nil {
+				return nil, err
+			}
+			passed = tx >= burst && rx >= tx
+		}
+		if !passed {
+			break
+		}
+		result.MaxBurstFrames = burst
+		result.Trials++
+	}
+
+	result.BurstDurationUs = float64(result.MaxBurstFrames) * 1e6 / pps
+	return result, nil
+}
@@ -0,0 +1,493 @@
+package trex
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeServer is a TRex RPC server in front of a simulated DUT that
+// forwards up to capacityPPS and absorbs bursts of up to bufferFrames
+type fakeServer struct {
+	t            *testing.T
+	ln           net.Listener
+	speedMbps    uint64
+	capacityPPS  float64
+	bufferFrames uint64
+	ownedBy      string // Another user holding the ports
+	strict       bool   // Serve tagged streams by PCP, highest first, instead of sharing loss
+
+	mu      sync.Mutex
+	stats   map[uint8]*PortStats
+	streams map[uint8][]Stream
+	flowTx  map[int]uint64 // Packets per packet group
+	flowRx  map[int]uint64
+	avgUs   map[int]float64 // Latency groups only
+	calls   []string
+	rxPort  uint8
+}
+
+func newFakeServer(t *testing.T, rxPort uint8) *fakeServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeServer{
+		t: t, ln: ln, speedMbps: 10000, capacityPPS: 1e6, bufferFrames: 5000, rxPort: rxPort,
+		stats:   map[uint8]*PortStats{0: {}, 1: {}},
+		streams: make(map[uint8][]Stream),
+		flowTx:  make(map[int]uint64),
+		flowRx:  make(map[int]uint64),
+		avgUs:   make(map[int]float64),
+	}
+	t.Cleanup(func() { ln.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *fakeServer) addr() string { return s.ln.Addr().String() }
+
+func (s *fakeServer) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeServer) handle(conn net.Conn) {
+	defer conn.Close()
+	c := &reqConn{conn: conn, r: bufio.NewReader(conn)}
+	peer := make([]byte, 64)
+	if _, err := io.ReadFull(c.r, peer); err != nil {
+		return
+	}
+	conn.Write(greeting())
+	if _, _, err := c.readFrame(); err != nil {
+		return
+	}
+	c.writeFrame(flagCommand, readyCommand("REP"))
+
+	for {
+		var body []byte
+		for {
+			flags, b, err := c.readFrame()
+			if err != nil {
+				return
+			}
+			body = b
+			if flags&flagMore == 0 {
+				break
+			}
+		}
+		var req struct {
+			ID     int                        `json:"id"`
+			Method string                     `json:"method"`
+			Params map[string]json.RawMessage `json:"params"`
+		}
+		if err := json.Unmarshal(body, &req); err != nil {
+			s.t.Errorf("bad request: %v", err)
+			return
+		}
+		result, rpcErr := s.dispatch(req.Method, req.Params)
+		resp := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+		if rpcErr != "" {
+			resp["error"] = map[string]interface{}{"code": -32000, "message": rpcErr}
+		} else {
+			resp["result"] = result
+		}
+		data, _ := json.Marshal(resp)
+		c.writeFrame(flagMore, nil)
+		c.writeFrame(0, data)
+	}
+}
+
+func (s *fakeServer) dispatch(method string, params map[string]json.RawMessage) (interface{}, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = append(s.calls, method)
+
+	var port uint8
+	json.Unmarshal(params["port_id"], &port)
+	if method != "api_sync_v2" {
+		var apiH string
+		json.Unmarshal(params["api_h"], &apiH)
+		if apiH != "h-1" {
+			return nil, "bad api_h"
+		}
+	}
+	switch method {
+	case "get_port_status", "get_port_stats", "get_pgid_stats", "api_sync_v2", "acquire":
+	default:
+		var handler string
+		json.Unmarshal(params["handler"], &handler)
+		if handler != "port-"+strconv.Itoa(int(port)) {
+			return nil, "port not owned"
+		}
+	}
+
+	switch method {
+	case "api_sync_v2":
+		return map[string]string{"api_h": "h-1"}, ""
+	case "acquire":
+		var force bool
+		json.Unmarshal(params["force"], &force)
+		if s.ownedBy != "" && !force {
+			return nil, "port is owned by " + s.ownedBy
+		}
+		return "port-" + strconv.Itoa(int(port)), ""
+	case "release", "stop_traffic":
+		return nil, ""
+	case "get_port_status":
+		return map[string]interface{}{"state": "IDLE", "attr": map[string]interface{}{"speed": s.speedMbps}}, ""
+	case "get_port_stats":
+		return s.stats[port], ""
+	case "remove_all_streams":
+		s.streams[port] = nil
+		return nil, ""
+	case "add_stream":
+		var st Stream
+		if err := json.Unmarshal(params["stream"], &st); err != nil {
+			return nil, err.Error()
+		}
+		s.streams[port] = append(s.streams[port], st)
+		return nil, ""
+	case "start_traffic":
+		var duration float64
+		json.Unmarshal(params["duration"], &duration)
+		s.transmit(port, duration)
+		return nil, ""
+	case "get_pgid_stats":
+		var pgids []int
+		json.Unmarshal(params["pgids"], &pgids)
+		lat := map[string]interface{}{}
+		flow := map[string]interface{}{}
+		for _, id := range pgids {
+			key := strconv.Itoa(id)
+			if avg, ok := s.avgUs[id]; ok {
+				lat[key] = map[string]interface{}{"latency": map[string]float64{
+					"average": avg, "total_min": avg / 2, "total_max": avg * 2, "jitter": 1,
+				}}
+			}
+			flow[key] = map[string]interface{}{
+				"tx_pkts": map[string]uint64{"total": s.flowTx[id]},
+				"rx_pkts": map[string]uint64{"total": s.flowRx[id]},
+			}
+		}
+		return map[string]interface{}{"latency": lat, "flow_stats": flow}, ""
+	}
+	return nil, "unknown method " + method
+}
+
+// transmit runs the port's streams through the simulated DUT
+func (s *fakeServer) transmit(port uint8, duration float64) {
+	total := 0.0
+	for _, st := range s.streams[port] {
+		total += st.Mode.Rate.Value
+	}
+
+	// Strict priority: capacity left after the higher classes
+	left := map[int]float64{}
+	if s.strict {
+		remaining := s.capacityPPS
+		for pcp := 7; pcp >= 0; pcp-- {
+			offered := 0.0
+			for _, st := range s.streams[port] {
+				if streamPCP(st) == pcp {
+					offered += st.Mode.Rate.Value
+				}
+			}
+			left[pcp] = 1
+			if offered > remaining {
+				left[pcp] = remaining / offered
+			}
+			remaining = math.Max(0, remaining-offered)
+		}
+	}
+
+	for _, st := range s.streams[port] {
+		var tx, rx uint64
+		switch st.Mode.Type {
+		case "continuous":
+			tx = uint64(st.Mode.Rate.Value * duration)
+			rx = tx
+			if s.strict {
+				rx = uint64(float64(tx) * left[streamPCP(st)])
+			} else if total > s.capacityPPS {
+				rx = uint64(float64(tx) * s.capacityPPS / total)
+			}
+		case "single_burst":
+			tx = st.Mode.TotalPkts
+			rx = tx
+			if tx > s.bufferFrames {
+				rx = s.bufferFrames
+			}
+		}
+		s.stats[port].OPackets += tx
+		s.stats[s.rxPort].IPackets += rx
+		if id := st.FlowStats.StreamID; st.FlowStats.Enabled {
+			s.flowTx[id] += tx
+			s.flowRx[id] += rx
+			if st.FlowStats.RuleType == "latency" {
+				s.avgUs[id] = 10
+				if rx < tx || (!s.strict && total > s.capacityPPS) {
+					s.avgUs[id] = 500
+				}
+			}
+		}
+	}
+}
+
+// streamPCP returns the PCP of a tagged stream's frames, or 0
+func streamPCP(st Stream) int {
+	b := st.Packet.Binary
+	if len(b) > 14 && b[12] == 0x81 && b[13] == 0x00 {
+		return int(b[14] >> 5)
+	}
+	return 0
+}
+
+func (s *fakeServer) called(method string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, m := range s.calls {
+		if m == method {
+			n++
+		}
+	}
+	return n
+}
+
+func testOptions(addr string) Options {
+	return Options{
+		Server:         addr,
+		TxPort:         0,
+		RxPort:         1,
+		TrialDuration:  time.Second,
+		Timeout:        5 * time.Second,
+		InitialRatePct: 100,
+		ResolutionPct:  0.1,
+		MaxIterations:  20,
+	}
+}
+
+func connect(t *testing.T, opts Options) *Generator {
+	t.Helper()
+	g, err := Connect(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { g.Close() })
+	return g
+}
+
+func TestConnect(t *testing.T) {
+	s := newFakeServer(t, 1)
+	g := connect(t, testOptions(s.addr()))
+
+	if g.LineRateMbps() != 10000 {
+		t.Errorf("LineRateMbps() = %v, want link speed 10000", g.LineRateMbps())
+	}
+	if n := s.called("acquire"); n != 2 {
+		t.Errorf("acquired %d ports, want 2", n)
+	}
+
+	opts := testOptions(s.addr())
+	opts.LineRateMbps = 1000
+	opts.RxPort = 0
+	if g := connect(t, opts); g.LineRateMbps() != 1000 {
+		t.Errorf("LineRateMbps() = %v, want configured 1000", g.LineRateMbps())
+	}
+}
+
+func TestConnectErrors(t *testing.T) {
+	s := newFakeServer(t, 1)
+	s.ownedBy = "lab-user"
+	if _, err := Connect(testOptions(s.addr())); err == nil {
+		t.Error("Expected error for ports owned by another user")
+	}
+	opts := testOptions(s.addr())
+	opts.Force = true
+	connect(t, opts)
+
+	s.speedMbps = 0
+	if _, err := Connect(opts); err == nil {
+		t.Error("Expected error when the line rate is unknown")
+	}
+
+	ln, _ := net.Listen("tcp", "127.0.0.1:0")
+	go func() {
+		if c, err := ln.Accept(); err == nil {
+			c.Write([]byte("HTTP/1.1 400 Bad Request\r\n\r\n" + string(make([]byte, 64))))
+			c.Close()
+		}
+	}()
+	defer ln.Close()
+	if _, err := Connect(testOptions(ln.Addr().String())); err == nil {
+		t.Error("Expected error for a non-ZMTP server")
+	}
+}
+
+func TestThroughput(t *testing.T) {
+	s := newFakeServer(t, 1)
+	g := connect(t, testOptions(s.addr()))
+	g.SetFrameSize(64)
+
+	// 1 Mpps of 14.88 Mpps line rate at 64 bytes
+	want := 1e6 / g.maxPPS() * 100
+	r, err := g.Throughput()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(r.MaxRatePct-want) > 0.1 || r.MaxRatePct > want {
+		t.Errorf("MaxRatePct = %.3f, want just under %.3f", r.MaxRatePct, want)
+	}
+	if r.Iterations == 0 || r.FrameSize != 64 {
+		t.Errorf("result = %+v", r)
+	}
+	if wantPPS := g.maxPPS() * r.MaxRatePct / 100; math.Abs(r.MaxRatePPS-wantPPS) > 1 {
+		t.Errorf("MaxRatePPS = %.0f, want %.0f", r.MaxRatePPS, wantPPS)
+	}
+
+	// Tolerating 10% loss passes up to 1 Mpps / 0.9 offered
+	g.SetAcceptableLoss(10)
+	r, err = g.Throughput()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want /= 0.9; math.Abs(r.MaxRatePct-want) > 0.1 || r.MaxRatePct > want {
+		t.Errorf("with 10%% loss: MaxRatePct = %.3f, want just under %.3f", r.MaxRatePct, want)
+	}
+	if r.AcceptableLoss != 10 {
+		t.Errorf("AcceptableLoss = %v, want 10", r.AcceptableLoss)
+	}
+}
+
+func TestThroughputFrom(t *testing.T) {
+	s := newFakeServer(t, 1)
+	g := connect(t, testOptions(s.addr()))
+	g.SetFrameSize(64)
+
+	var steps []Search
+	full, err := g.ThroughputFrom(nil, func(s *Search) { steps = append(steps, *s) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(steps) != int(full.Iterations) {
+		t.Fatalf("%d steps for %d iterations", len(steps), full.Iterations)
+	}
+
+	// Resuming from a midway step reaches the same result
+	mid := steps[len(steps)/2]
+	r, err := g.ThroughputFrom(&mid, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.MaxRatePct != full.MaxRatePct || r.Iterations != full.Iterations || len(r.Trials) != len(full.Trials) {
+		t.Errorf("resumed = %+v, want %+v", r, full)
+	}
+	for i, trial := range full.Trials {
+		if trial.RatePct == 0 || trial.Passed != (trial.LossPct <= full.AcceptableLoss) {
+			t.Errorf("trial %d = %+v", i, trial)
+		}
+	}
+}
+
+func TestTrialLatency(t *testing.T) {
+	s := newFakeServer(t, 0)
+	opts := testOptions(s.addr())
+	opts.RxPort = 0 // Looped back to the sending port
+	g := connect(t, opts)
+	g.SetFrameSize(512)
+
+	tr, err := g.Latency(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.LossPct != 0 || tr.FramesTx == 0 || tr.FramesRx != tr.FramesTx {
+		t.Errorf("trial = %+v", tr)
+	}
+	if tr.Latency.Count != latencyPPS || tr.Latency.AvgNs != 10000 || tr.Latency.MaxNs != 20000 {
+		t.Errorf("latency = %+v", tr.Latency)
+	}
+
+	tr, err = g.Trial(100, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.LossPct == 0 || tr.Latency.Count != 0 {
+		t.Errorf("overloaded trial without latency = %+v", tr)
+	}
+}
+
+func TestFrameLoss(t *testing.T) {
+	s := newFakeServer(t, 1)
+	g := connect(t, testOptions(s.addr()))
+	g.SetFrameSize(1518)
+
+	// 1518 bytes at 10G is ~812743 fps, below the DUT's 1 Mpps
+	trials, err := g.FrameLoss(100, 10, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trials) != 10 || trials[0].RatePct != 100 || trials[9].RatePct < 9.99 {
+		t.Fatalf("trials = %+v", trials)
+	}
+	for _, tr := range trials {
+		if tr.LossPct != 0 {
+			t.Errorf("loss at %.0f%% = %.4f", tr.RatePct, tr.LossPct)
+		}
+	}
+	if _, err := g.FrameLoss(100, 10, 0); err == nil {
+		t.Error("Expected error for zero step")
+	}
+}
+
+func TestBackToBack(t *testing.T) {
+	s := newFakeServer(t, 1)
+	g := connect(t, testOptions(s.addr()))
+	g.SetFrameSize(64)
+
+	r, err := g.BackToBack(1000, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 1000, 2000 and 4000 fit the 5000 frame buffer; 8000 does not
+	if r.MaxBurstFrames != 4000 || r.Trials != 3 {
+		t.Errorf("result = %+v", r)
+	}
+	if want := 4000 * 1e6 / g.maxPPS(); math.Abs(r.BurstDurationUs-want) > 1e-6 {
+		t.Errorf("BurstDurationUs = %v, want %v", r.BurstDurationUs, want)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	s := newFakeServer(t, 1)
+	g := connect(t, testOptions(s.addr()))
+	g.Cancel()
+	if _, err := g.Throughput(); !errors.Is(err, ErrCancelled) {
+		t.Errorf("Throughput() after Cancel = %v, want ErrCancelled", err)
+	}
+}
+
+func TestBuildFrame(t *testing.T) {
+	for _, fs := range []uint32{64, 1518, 9000} {
+		f := buildFrame(fs)
+		if len(f) != int(fs)-4 {
+			t.Errorf("buildFrame(%d) length = %d", fs, len(f))
+		}
+		if ipChecksum(f[14:34]) != 0 {
+			t.Errorf("buildFrame(%d) has a bad IPv4 checksum", fs)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package trex
+
+import "fmt"
+
+// Class is one marked stream of a QoS trial
+type Class struct {
+	Name     string
+	Marking  Marking
+	SharePct float64 // Share of the offered load
+}
+
+// ClassResult is one class's outcome in a QoS trial
+type ClassResult struct {
+	Class
+	FramesTx      uint64
+	FramesRx      uint64
+	LossPct       float64
+	OfferedMbps   float64
+	DeliveredMbps float64 // Received rate including Ethernet overhead
+	SharePct      float64 // Share of all frames received
+	Latency       Latency
+}
+
+// QoSResult is a QoS trial: every class sent at once, sharing the load
+type QoSResult struct {
+	FrameSize     uint32
+	LoadPct       float64
+	FramesTx      uint64
+	FramesRx      uint64
+	LossPct       float64
+	DeliveredMbps float64
+	Classes       []ClassResult
+}
+
+// QoS offers loadPct of line rate for the trial duration, split across
+// the classes by their shares and sent simultaneously, and counts each
+// class with TRex flow statistics. When the DUT's egress saturates, the
+// per-class share, loss and latency show how it schedules the classes.
+func (g *Generator) QoS(loadPct float64, classes []Class) (*QoSResult, error) {
+	if len(classes) == 0 {
+		return nil, fmt.Errorf("no QoS classes")
+	}
+	pps := g.maxPPS() * loadPct / 100
+	mode := func(rate float64) Mode {
+		return Mode{Type: "continuous", Rate: Rate{Type: "pps", Value: rate}}
+	}
+
+	// Each class is a counted stream plus, when fast enough, a latency
+	// stream with the same marking that takes its rate out of the class
+	type group struct{ stats, latency int }
+	build := func(counted bool) ([]*Stream, []group, error) {
+		var streams []*Stream
+		groups := make([]group, len(classes))
+		for i, c := range classes {
+			rate := pps * c.SharePct / 100
+			if rate < 1 {
+				return nil, nil, fmt.Errorf("class %s: rate %.4f%% is below 1 frame/s", c.Name, loadPct*c.SharePct/100)
+			}
+			frame := buildMarkedFrame(g.frameSize, c.Marking)
+			s := newStream(frame, mode(rate))
+			streams = append(streams, s)
+			if !counted {
+				continue
+			}
+			g.pgid++
+			groups[i].stats = g.pgid
+			s.FlowStats = FlowStats{Enabled: true, StreamID: g.pgid, RuleType: "stats"}
+			if rate >= 2*latencyPPS {
+				g.pgid++
+				groups[i].latency = g.pgid
+				lat := newStream(frame, mode(latencyPPS))
+				lat.FlowStats = FlowStats{Enabled: true, StreamID: g.pgid, RuleType: "latency"}
+				s.Mode.Rate.Value = rate - latencyPPS
+				streams = append(streams, lat)
+			}
+		}
+		return streams, groups, nil
+	}
+
+	if g.opts.Warmup > 0 {
+		streams, _, err := build(false)
+		if err != nil {
+			return nil, err
+		}
+		if _, _, _, err := g.send(streams, g.opts.Warmup); err != nil {
+			return nil, err
+		}
+	}
+
+	streams, groups, err := build(true)
+	if err != nil {
+		return nil, err
+	}
+	tx, rx, elapsed, err := g.send(streams, g.opts.TrialDuration)
+	if err != nil {
+		return nil, err
+	}
+	var pgids []int
+	for _, gr := range groups {
+		pgids = append(pgids, gr.stats)
+		if gr.latency != 0 {
+			pgids = append(pgids, gr.latency)
+		}
+	}
+	stats, err := g.client.PGStats(pgids)
+	if err != nil {
+		return nil, err
+	}
+
+	mbps := func(frames uint64) float64 {
+		if elapsed <= 0 {
+			return 0
+		}
+		return float64(frames) * float64(g.frameSize+ethOverhead) * 8 / elapsed.Seconds() / 1e6
+	}
+	result := &QoSResult{FrameSize: g.frameSize, LoadPct: loadPct, FramesTx: tx, FramesRx: rx, LossPct: lossPct(tx, rx), DeliveredMbps: mbps(rx)}
+	var classRx uint64
+	for i, c := range classes {
+		r := ClassResult{Class: c, OfferedMbps: g.LineRateMbps() * loadPct / 100 * c.SharePct / 100}
+		for _, id := range []int{groups[i].stats, groups[i].latency} {
+			if s := stats[id]; id != 0 && s != nil {
+				r.FramesTx += s.TxPkts
+				r.FramesRx += s.RxPkts
+			}
+		}
+		if l := stats[groups[i].latency]; groups[i].latency != 0 && l != nil && l.Latency != nil {
+			r.Latency = latencyOf(l.Latency)
+		}
+		r.LossPct = lossPct(r.FramesTx, r.FramesRx)
+		r.DeliveredMbps = mbps(r.FramesRx)
+		classRx += r.FramesRx
+		result.Classes = append(result.Classes, r)
+	}
+	if tx > 0 && result.Classes[0].FramesTx == 0 {
+		return nil, fmt.Errorf("no per-class counters from TRex; flow statistics need a NIC that supports them")
+	}
+	for i := range result.Classes {
+		if classRx > 0 {
+			result.Classes[i].SharePct = float64(result.Classes[i].FramesRx) / float64(classRx) * 100
+		}
+	}
+	return result, nil
+}
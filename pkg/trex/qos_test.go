@@ -0,0 +1,84 @@
+package trex
+
+import (
+	"math"
+	"testing"
+)
+
+// pcpClasses returns eight tagged classes with equal shares
+func pcpClasses() []Class {
+	var classes []Class
+	for pcp := uint8(0); pcp < 8; pcp++ {
+		classes = append(classes, Class{Name: string(rune('0' + pcp)), Marking: Marking{Tagged: true, VLAN: 100, PCP: pcp, DSCP: pcp * 8}, SharePct: 12.5})
+	}
+	return classes
+}
+
+func TestBuildMarkedFrame(t *testing.T) {
+	f := buildMarkedFrame(128, Marking{Tagged: true, VLAN: 100, PCP: 5, DSCP: 46})
+	if len(f) != 124 {
+		t.Fatalf("frame length = %d, want 124", len(f))
+	}
+	if f[12] != 0x81 || f[13] != 0x00 || f[14]>>5 != 5 || uint16(f[14]&0x0f)<<8|uint16(f[15]) != 100 {
+		t.Errorf("tag = % x", f[12:16])
+	}
+	if f[16] != 0x08 || f[17] != 0x00 || f[19] != 46<<2 {
+		t.Errorf("EtherType/TOS = % x", f[16:20])
+	}
+	if ipChecksum(f[18:38]) != 0 {
+		t.Error("bad IPv4 checksum")
+	}
+
+	plain := buildFrame(128)
+	if plain[12] != 0x08 || plain[15] != 0 {
+		t.Errorf("untagged frame = % x", plain[12:16])
+	}
+}
+
+func TestQoS(t *testing.T) {
+	s := newFakeServer(t, 1)
+	g := connect(t, testOptions(s.addr()))
+	g.SetFrameSize(512)
+
+	// 512 bytes at 10G is ~2.35 Mpps against the DUT's 1 Mpps; sharing the
+	// loss, every class gets the same share of what gets through
+	r, err := g.QoS(100, pcpClasses())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Classes) != 8 || r.LossPct == 0 {
+		t.Fatalf("result = %+v", r)
+	}
+	for _, c := range r.Classes {
+		if math.Abs(c.SharePct-12.5) > 0.01 || c.LossPct == 0 {
+			t.Errorf("class %s: share %.3f%%, loss %.3f%%", c.Name, c.SharePct, c.LossPct)
+		}
+		if c.Latency.Count == 0 {
+			t.Errorf("class %s: no latency", c.Name)
+		}
+	}
+
+	// Strict priority fills the DUT from PCP 7 down and starves the rest
+	s.strict = true
+	r, err = g.QoS(100, pcpClasses())
+	if err != nil {
+		t.Fatal(err)
+	}
+	top, bottom := r.Classes[7], r.Classes[0]
+	if top.LossPct != 0 || top.Latency.AvgNs != 10000 {
+		t.Errorf("PCP 7: loss %.3f%%, latency %v", top.LossPct, top.Latency.AvgNs)
+	}
+	if bottom.FramesRx != 0 || bottom.LossPct != 100 {
+		t.Errorf("PCP 0: received %d, loss %.3f%%", bottom.FramesRx, bottom.LossPct)
+	}
+	if top.SharePct <= 12.5 {
+		t.Errorf("PCP 7 share = %.2f%%, want more than an equal share", top.SharePct)
+	}
+
+	if _, err := g.QoS(100, nil); err == nil {
+		t.Error("Expected error for no classes")
+	}
+	if _, err := g.QoS(1e-6, pcpClasses()); err == nil {
+		t.Error("Expected error for a class below 1 frame/s")
+	}
+}
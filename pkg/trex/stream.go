@@ -0,0 +1,136 @@
+package trex
+
+import (
+	"encoding/binary"
+	"net"
+)
+
+// Stream is a TRex stateless stream definition
+type Stream struct {
+	Enabled     bool      `json:"enabled"`
+	SelfStart   bool      `json:"self_start"`
+	ISG         float64   `json:"isg"`
+	NextStream  int       `json:"next_stream_id"`
+	Flags       int       `json:"flags"` // 0 = port source and destination MACs replace the packet's
+	ActionCount int       `json:"action_count"`
+	RandomSeed  int       `json:"random_seed"`
+	Packet      Packet    `json:"packet"`
+	Mode        Mode      `json:"mode"`
+	VM          VM        `json:"vm"`
+	FlowStats   FlowStats `json:"flow_stats"`
+}
+
+// Packet is the frame a stream sends, without FCS
+type Packet struct {
+	Binary []byte `json:"binary"` // Encoded as base64, as TRex expects
+	Meta   string `json:"meta"`
+}
+
+// Mode is how a stream transmits
+type Mode struct {
+	Type      string `json:"type"` // continuous or single_burst
+	TotalPkts uint64 `json:"total_pkts,omitempty"`
+	Rate      Rate   `json:"rate"`
+}
+
+// Rate is a stream's transmit rate
+type Rate struct {
+	Type  string  `json:"type"` // pps, bps_L1, bps_L2 or percentage
+	Value float64 `json:"value"`
+}
+
+// VM is the field engine program; streams here send a fixed frame
+type VM struct {
+	Instructions []interface{} `json:"instructions"`
+	SplitByVar   string        `json:"split_by_var"`
+}
+
+// FlowStats enables per-stream counting or latency measurement
+type FlowStats struct {
+	Enabled  bool   `json:"enabled"`
+	StreamID int    `json:"stream_id,omitempty"` // Packet group ID
+	RuleType string `json:"rule_type,omitempty"` // stats or latency
+}
+
+func newStream(pkt []byte, mode Mode) *Stream {
+	return &Stream{
+		Enabled:    true,
+		SelfStart:  true,
+		NextStream: -1,
+		Packet:     Packet{Binary: pkt},
+		Mode:       mode,
+		VM:         VM{Instructions: []interface{}{}},
+	}
+}
+
+// Addresses of generated frames. The MACs are placeholders: TRex writes
+// the port's own and configured destination MACs over them.
+var (
+	srcIP = net.IPv4(16, 0, 0, 1).To4()
+	dstIP = net.IPv4(48, 0, 0, 1).To4()
+)
+
+const (
+	srcPort = 1025
+	dstPort = 12
+)
+
+// Marking is the priority marking of a generated frame
+type Marking struct {
+	Tagged bool   // Add an 802.1Q tag carrying VLAN and PCP
+	VLAN   uint16 // 0 = priority-tagged
+	PCP    uint8
+	DSCP   uint8 // Upper six bits of the IPv4 TOS byte
+}
+
+// buildFrame returns an Ethernet/IPv4/UDP test frame. frameSize includes
+// the 4-byte FCS, which the NIC appends.
+func buildFrame(frameSize uint32) []byte {
+	return buildMarkedFrame(frameSize, Marking{})
+}
+
+// buildMarkedFrame returns a test frame with the given marking. A tag
+// counts toward frameSize.
+func buildMarkedFrame(frameSize uint32, m Marking) []byte {
+	n := int(frameSize) - 4
+	if n < 60 {
+		n = 60
+	}
+	f := make([]byte, n)
+
+	// Ethernet: zero MACs (overwritten by TRex), optional 802.1Q tag, IPv4
+	l3 := 14
+	if m.Tagged {
+		binary.BigEndian.PutUint16(f[12:], 0x8100)
+		binary.BigEndian.PutUint16(f[14:], uint16(m.PCP&7)<<13|m.VLAN&0xfff)
+		l3 += 4
+	}
+	binary.BigEndian.PutUint16(f[l3-2:], 0x0800)
+
+	ip := f[l3 : l3+20]
+	ip[0] = 0x45
+	ip[1] = m.DSCP << 2
+	binary.BigEndian.PutUint16(ip[2:], uint16(n-l3))
+	ip[8] = 64 // TTL
+	ip[9] = 17 // UDP
+	copy(ip[12:], srcIP)
+	copy(ip[16:], dstIP)
+	binary.BigEndian.PutUint16(ip[10:], ipChecksum(ip))
+
+	udp := f[l3+20 : l3+28]
+	binary.BigEndian.PutUint16(udp[0:], srcPort)
+	binary.BigEndian.PutUint16(udp[2:], dstPort)
+	binary.BigEndian.PutUint16(udp[4:], uint16(n-l3-20))
+	return f
+}
+
+func ipChecksum(hdr []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(hdr); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(hdr[i:]))
+	}
+	for sum > 0xffff {
+		sum = (sum >> 16) + (sum & 0xffff)
+	}
+	return ^uint16(sum)
+}
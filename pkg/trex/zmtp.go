@@ -0,0 +1,188 @@
+package trex
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// The TRex RPC server is a ZeroMQ REP socket. reqConn speaks just enough
+// ZMTP 3.0 (NULL security) to act as the matching REQ peer, so no libzmq
+// binding is needed.
+
+// ZMTP frame flags
+const (
+	flagMore    = 0x01
+	flagLong    = 0x02
+	flagCommand = 0x04
+)
+
+// maxFrame bounds replies read from the server; stream lists and stats
+// are well under this
+const maxFrame = 64 << 20
+
+type reqConn struct {
+	conn    net.Conn
+	r       *bufio.Reader
+	timeout time.Duration
+}
+
+// greeting is the 64-byte ZMTP 3.0 greeting for the NULL mechanism
+func greeting() []byte {
+	g := make([]byte, 64)
+	g[0], g[9] = 0xff, 0x7f
+	g[10], g[11] = 3, 0
+	copy(g[12:32], "NULL")
+	return g
+}
+
+// dialREQ connects and completes the ZMTP handshake
+func dialREQ(addr string, timeout time.Duration) (*reqConn, error) {
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return nil, err
+	}
+	c := &reqConn{conn: conn, r: bufio.NewReader(conn), timeout: timeout}
+	if err := c.handshake(); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("zmtp handshake with %s: %w", addr, err)
+	}
+	return c, nil
+}
+
+func (c *reqConn) handshake() error {
+	c.deadline()
+	if _, err := c.conn.Write(greeting()); err != nil {
+		return err
+	}
+	peer := make([]byte, 64)
+	if _, err := io.ReadFull(c.r, peer); err != nil {
+		return err
+	}
+	if peer[0] != 0xff || peer[9] != 0x7f {
+		return fmt.Errorf("not a ZMTP peer")
+	}
+	if peer[10] < 3 {
+		return fmt.Errorf("unsupported ZMTP version %d.%d", peer[10], peer[11])
+	}
+	if mech := string(bytes.TrimRight(peer[12:32], "\x00")); mech != "NULL" {
+		return fmt.Errorf("unsupported security mechanism %q", mech)
+	}
+
+	if err := c.writeFrame(flagCommand, readyCommand("REQ")); err != nil {
+		return err
+	}
+	flags, body, err := c.readFrame()
+	if err != nil {
+		return err
+	}
+	if flags&flagCommand == 0 || !bytes.HasPrefix(body, []byte("\x05READY")) {
+		return fmt.Errorf("expected READY command")
+	}
+	return nil
+}
+
+// readyCommand builds a READY command carrying the socket type
+func readyCommand(socketType string) []byte {
+	var b bytes.Buffer
+	b.WriteByte(5)
+	b.WriteString("READY")
+	name := "Socket-Type"
+	b.WriteByte(byte(len(name)))
+	b.WriteString(name)
+	binary.Write(&b, binary.BigEndian, uint32(len(socketType)))
+	b.WriteString(socketType)
+	return b.Bytes()
+}
+
+// roundTrip sends one request and returns the reply. REQ messages carry
+// an empty delimiter frame before the body.
+func (c *reqConn) roundTrip(msg []byte) ([]byte, error) {
+	c.deadline()
+	if err := c.writeFrame(flagMore, nil); err != nil {
+		return nil, err
+	}
+	if err := c.writeFrame(0, msg); err != nil {
+		return nil, err
+	}
+
+	var parts [][]byte
+	for {
+		flags, body, err := c.readFrame()
+		if err != nil {
+			return nil, err
+		}
+		if flags&flagCommand != 0 {
+			continue // PING and other commands between messages
+		}
+		parts = append(parts, body)
+		if flags&flagMore == 0 {
+			break
+		}
+	}
+	if len(parts) > 0 && len(parts[0]) == 0 {
+		parts = parts[1:]
+	}
+	if len(parts) != 1 {
+		return nil, fmt.Errorf("unexpected %d-part reply", len(parts))
+	}
+	return parts[0], nil
+}
+
+func (c *reqConn) writeFrame(flags byte, body []byte) error {
+	var hdr []byte
+	if len(body) > 255 {
+		hdr = make([]byte, 9)
+		hdr[0] = flags | flagLong
+		binary.BigEndian.PutUint64(hdr[1:], uint64(len(body)))
+	} else {
+		hdr = []byte{flags, byte(len(body))}
+	}
+	if _, err := c.conn.Write(append(hdr, body...)); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *reqConn) readFrame() (byte, []byte, error) {
+	flags, err := c.r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	var size uint64
+	if flags&flagLong != 0 {
+		var b [8]byte
+		if _, err := io.ReadFull(c.r, b[:]); err != nil {
+			return 0, nil, err
+		}
+		size = binary.BigEndian.Uint64(b[:])
+	} else {
+		n, err := c.r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		size = uint64(n)
+	}
+	if size > maxFrame {
+		return 0, nil, fmt.Errorf("frame of %d bytes exceeds limit", size)
+	}
+	body := make([]byte, size)
+	if _, err := io.ReadFull(c.r, body); err != nil {
+		return 0, nil, err
+	}
+	return flags, body, nil
+}
+
+func (c *reqConn) deadline() {
+	if c.timeout > 0 {
+		c.conn.SetDeadline(time.Now().Add(c.timeout))
+	}
+}
+
+func (c *reqConn) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,178 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// Action is what a key binding does
+type Action string
+
+const (
+	ActionStart Action = "start"
+	ActionStop  Action = "stop"
+	ActionHelp  Action = "help"
+	ActionQuit  Action = "quit"
+)
+
+// actions in status bar order, with their default keys, label and color.
+// The letters are vi-style alternatives for terminals that take the
+// function keys for themselves.
+var actions = []struct {
+	action Action
+	keys   []string
+	label  string
+	color  string
+}{
+	{ActionStart, []string{"F1", "s"}, "Start", "green"},
+	{ActionStop, []string{"F2", "x"}, "Stop", "red"},
+	{ActionHelp, []string{"F3", "?"}, "Help", "yellow"},
+	{ActionQuit, []string{"F10", "Esc", "q"}, "Quit", "blue"},
+}
+
+// Key is a key that can be bound: a named key such as F1 or Ctrl-S, or a
+// single character
+type Key struct {
+	key  tcell.Key
+	char rune
+}
+
+// keyNames maps lower-case key names to keys
+var keyNames = func() map[string]tcell.Key {
+	names := make(map[string]tcell.Key, len(tcell.KeyNames))
+	for k, name := range tcell.KeyNames {
+		if k != tcell.KeyRune {
+			names[strings.ToLower(name)] = k
+		}
+	}
+	names["escape"] = tcell.KeyEscape
+	return names
+}()
+
+// ParseKey parses a key name: a tcell name such as F1, Esc, Enter or
+// Ctrl-S (also Ctrl+S), case-insensitively, or one character such as q.
+// Ctrl-C always cancels and cannot be bound.
+func ParseKey(name string) (Key, error) {
+	if utf8.RuneCountInString(name) == 1 {
+		r, _ := utf8.DecodeRuneInString(name)
+		if r > ' ' && r != 0x7f {
+			return Key{key: tcell.KeyRune, char: r}, nil
+		}
+	}
+	k, ok := keyNames[strings.ToLower(strings.ReplaceAll(name, "+", "-"))]
+	if !ok {
+		return Key{}, fmt.Errorf("unknown key %q", name)
+	}
+	if k == tcell.KeyCtrlC {
+		return Key{}, fmt.Errorf("key %s is reserved for cancelling", name)
+	}
+	return Key{key: k}, nil
+}
+
+// String is the key's name as the status bar shows it
+func (k Key) String() string {
+	if k.key == tcell.KeyRune {
+		return string(k.char)
+	}
+	return tcell.KeyNames[k.key]
+}
+
+func (k Key) matches(ev *tcell.EventKey) bool {
+	if k.key == tcell.KeyRune {
+		return ev.Key() == tcell.KeyRune && ev.Rune() == k.char && ev.Modifiers()&^tcell.ModShift == 0
+	}
+	return ev.Key() == k.key
+}
+
+// Option customizes the TUI
+type Option func(*App)
+
+// Keymap binds every action: the keys named for it, or its defaults. A key
+// bound to two actions is an error, e.g. s for stop with the default s
+// kept for start.
+func Keymap(names map[Action][]string) (map[Action][]Key, error) {
+	keymap := make(map[Action][]Key, len(actions))
+	bound := make(map[Key]Action)
+	for _, act := range actions {
+		list := names[act.action]
+		if len(list) == 0 {
+			list = act.keys
+		}
+		for _, name := range list {
+			k, err := ParseKey(name)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", act.action, err)
+			}
+			if other, ok := bound[k]; ok {
+				return nil, fmt.Errorf("key %s is bound to both %s and %s", k, other, act.action)
+			}
+			bound[k] = act.action
+			keymap[act.action] = append(keymap[act.action], k)
+		}
+	}
+	return keymap, nil
+}
+
+// WithKeymap binds the actions to keys in place of the defaults; see Keymap
+func WithKeymap(keymap map[Action][]Key) Option {
+	return func(a *App) {
+		for action, keys := range keymap {
+			if len(keys) > 0 {
+				a.keys[action] = keys
+			}
+		}
+	}
+}
+
+// WithLabel names an action in the status bar and help, e.g. in the
+// operator's language
+func WithLabel(action Action, label string) Option {
+	return func(a *App) {
+		if label != "" {
+			a.labels[action] = label
+		}
+	}
+}
+
+// WithTitle sets the status bar title
+func WithTitle(title string) Option {
+	return func(a *App) {
+		if title != "" {
+			a.title = title
+		}
+	}
+}
+
+// action returns the action bound to a key event, or ""
+func (a *App) action(ev *tcell.EventKey) Action {
+	for _, act := range actions {
+		for _, k := range a.keys[act.action] {
+			if k.matches(ev) {
+				return act.action
+			}
+		}
+	}
+	return ""
+}
+
+// KeyText names the keys bound to an action, e.g. "F1/s"
+func (a *App) KeyText(action Action) string {
+	names := make([]string, len(a.keys[action]))
+	for i, k := range a.keys[action] {
+		names[i] = k.String()
+	}
+	return tview.Escape(strings.Join(names, "/"))
+}
+
+// statusText is the status bar: the title, then each action's keys
+func (a *App) statusText() string {
+	parts := []string{"[yellow]" + a.title + "[white]"}
+	for _, act := range actions {
+		parts = append(parts, fmt.Sprintf("[%s]%s[white] %s", act.color, a.KeyText(act.action), a.labels[act.action]))
+	}
+	return strings.Join(parts, " | ")
+}
@@ -0,0 +1,42 @@
+package tui
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestKeymap(t *testing.T) {
+	keymap, err := Keymap(map[Action][]string{ActionStart: {"f5", "Ctrl+S"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := &App{keys: keymap}
+	tests := []struct {
+		ev   *tcell.EventKey
+		want Action
+	}{
+		{tcell.NewEventKey(tcell.KeyF5, 0, tcell.ModNone), ActionStart},
+		{tcell.NewEventKey(tcell.KeyCtrlS, 0, tcell.ModCtrl), ActionStart},
+		{tcell.NewEventKey(tcell.KeyF1, 0, tcell.ModNone), ""},
+		{tcell.NewEventKey(tcell.KeyRune, 'x', tcell.ModNone), ActionStop},
+		{tcell.NewEventKey(tcell.KeyRune, '?', tcell.ModShift), ActionHelp},
+		{tcell.NewEventKey(tcell.KeyEscape, 0, tcell.ModNone), ActionQuit},
+		{tcell.NewEventKey(tcell.KeyRune, 'q', tcell.ModAlt), ""},
+	}
+	for _, tt := range tests {
+		if got := a.action(tt.ev); got != tt.want {
+			t.Errorf("%s: %q, want %q", tt.ev.Name(), got, tt.want)
+		}
+	}
+	if got := a.KeyText(ActionStart); got != "F5/Ctrl-S" {
+		t.Errorf("KeyText = %q", got)
+	}
+
+	if _, err := Keymap(map[Action][]string{ActionStop: {"s"}}); err == nil {
+		t.Error("s for stop should clash with the default start key")
+	}
+	if _, err := Keymap(map[Action][]string{ActionQuit: {"Ctrl-C"}}); err == nil {
+		t.Error("Ctrl-C should be reserved")
+	}
+}
@@ -0,0 +1,170 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// Page is one screen of the TUI, chosen from the page bar
+type Page string
+
+const (
+	PageConfig  Page = "config"
+	PageLive    Page = "live"
+	PageResults Page = "results"
+	PageLogs    Page = "logs"
+	PageHistory Page = "history"
+	PageHelp    Page = "help"
+)
+
+// pages in page bar order, which Tab and the number keys follow. The help
+// page is titled with the help action's label.
+var pages = []struct {
+	page  Page
+	title string
+}{
+	{PageConfig, "Config"},
+	{PageLive, "Live"},
+	{PageResults, "Results"},
+	{PageLogs, "Logs"},
+	{PageHistory, "History"},
+	{PageHelp, ""},
+}
+
+// HistoryEntry is one saved run on the history page
+type HistoryEntry struct {
+	Time     time.Time
+	Label    string // DUT label, or the file name
+	TestType string
+	Source   string // Results file
+	Detail   string // Shown while the entry is selected
+}
+
+func (a *App) pageTitle(p Page) string {
+	if p == PageHelp {
+		return a.labels[ActionHelp]
+	}
+	for _, pg := range pages {
+		if pg.page == p {
+			return pg.title
+		}
+	}
+	return string(p)
+}
+
+// pageBarText numbers the pages and highlights the current one, or
+// brackets it in plain mode
+func (a *App) pageBarText() string {
+	parts := make([]string, len(pages))
+	for i, pg := range pages {
+		text := fmt.Sprintf(" %d %s ", i+1, tview.Escape(a.pageTitle(pg.page)))
+		switch {
+		case pg.page == a.page && a.plain:
+			text = tview.Escape(fmt.Sprintf("[%d %s]", i+1, a.pageTitle(pg.page)))
+		case pg.page == a.page:
+			text = "[black:yellow]" + text + "[-:-]"
+		}
+		parts[i] = text
+	}
+	return strings.Join(parts, "|")
+}
+
+// showPage switches pages on the UI goroutine, focusing the page so its
+// table or text scrolls with the arrow keys
+func (a *App) showPage(p Page) {
+	if p == PageHelp && a.page != PageHelp {
+		a.back = a.page
+	}
+	a.page = p
+	a.content.SwitchToPage(string(p))
+	a.app.SetFocus(a.views[p])
+	a.pageBar.SetText(a.pageBarText())
+	if p == PageHistory && a.OnHistory != nil {
+		go func() {
+			a.SetHistory(a.OnHistory())
+		}()
+	}
+}
+
+// ShowPage switches to a page, e.g. the live page when a run starts
+func (a *App) ShowPage(p Page) {
+	a.app.QueueUpdateDraw(func() {
+		a.showPage(p)
+	})
+}
+
+// CurrentPage returns the page on screen
+func (a *App) CurrentPage() Page {
+	return a.page
+}
+
+// navigate handles the page keys: Tab and Shift-Tab step through the
+// pages and 1-9 choose one. Bound action keys are matched first.
+func (a *App) navigate(ev *tcell.EventKey) bool {
+	cur := 0
+	for i, pg := range pages {
+		if pg.page == a.page {
+			cur = i
+		}
+	}
+	switch {
+	case ev.Key() == tcell.KeyTab:
+		a.showPage(pages[(cur+1)%len(pages)].page)
+	case ev.Key() == tcell.KeyBacktab:
+		a.showPage(pages[(cur+len(pages)-1)%len(pages)].page)
+	case ev.Key() == tcell.KeyRune && ev.Modifiers() == tcell.ModNone &&
+		ev.Rune() >= '1' && int(ev.Rune()-'1') < len(pages):
+		a.showPage(pages[ev.Rune()-'1'].page)
+	default:
+		return false
+	}
+	return true
+}
+
+func (a *App) initHistoryView() {
+	headers := []string{"Saved", "Test", "Label", "File"}
+	for i, h := range headers {
+		a.historyView.SetCell(0, i, tview.NewTableCell(h).
+			SetTextColor(tcell.ColorYellow).
+			SetSelectable(false))
+	}
+}
+
+// SetConfig shows the run's settings on the config page
+func (a *App) SetConfig(text string) {
+	a.configView.SetText(tview.Escape(text))
+}
+
+// SetHistory lists saved runs on the history page, newest first. The
+// selected run's detail is shown beside the list.
+func (a *App) SetHistory(entries []HistoryEntry) {
+	a.app.QueueUpdateDraw(func() {
+		a.history = entries
+		a.historyView.Clear()
+		a.initHistoryView()
+		for i, e := range entries {
+			row := i + 1
+			a.historyView.SetCell(row, 0, tview.NewTableCell(e.Time.Format("2006-01-02 15:04")))
+			a.historyView.SetCell(row, 1, tview.NewTableCell(tview.Escape(e.TestType)))
+			a.historyView.SetCell(row, 2, tview.NewTableCell(tview.Escape(e.Label)))
+			a.historyView.SetCell(row, 3, tview.NewTableCell(tview.Escape(e.Source)))
+		}
+		if len(entries) == 0 {
+			a.historyDetail.SetText("No saved results")
+			return
+		}
+		a.historyView.Select(1, 0)
+		a.showHistoryDetail(1)
+	})
+}
+
+func (a *App) showHistoryDetail(row int) {
+	if row < 1 || row > len(a.history) {
+		return
+	}
+	a.historyDetail.SetText(tview.Escape(a.history[row-1].Detail)).ScrollToBeginning()
+}
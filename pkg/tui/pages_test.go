@@ -0,0 +1,73 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestNavigate(t *testing.T) {
+	a := New(WithLabel(ActionHelp, "Aide"))
+	if a.CurrentPage() != PageLive {
+		t.Fatalf("starts on %s", a.CurrentPage())
+	}
+	tests := []struct {
+		ev   *tcell.EventKey
+		want Page
+	}{
+		{tcell.NewEventKey(tcell.KeyTab, 0, tcell.ModNone), PageResults},
+		{tcell.NewEventKey(tcell.KeyRune, '1', tcell.ModNone), PageConfig},
+		{tcell.NewEventKey(tcell.KeyBacktab, 0, tcell.ModShift), PageHelp},
+		{tcell.NewEventKey(tcell.KeyTab, 0, tcell.ModNone), PageConfig},
+		{tcell.NewEventKey(tcell.KeyRune, '5', tcell.ModNone), PageHistory},
+	}
+	for _, tt := range tests {
+		if !a.navigate(tt.ev) {
+			t.Fatalf("%s not handled", tt.ev.Name())
+		}
+		if a.CurrentPage() != tt.want {
+			t.Errorf("%s: on %s, want %s", tt.ev.Name(), a.CurrentPage(), tt.want)
+		}
+	}
+	for _, r := range []rune{'7', '0', 'z'} {
+		if a.navigate(tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone)) {
+			t.Errorf("%c should not change page", r)
+		}
+	}
+
+	bar := a.pageBarText()
+	if !strings.Contains(bar, "[black:yellow] 5 History [-:-]") || !strings.Contains(bar, " 6 Aide ") {
+		t.Errorf("page bar %q", bar)
+	}
+}
+
+func TestHelpReturns(t *testing.T) {
+	a := New()
+	a.showPage(PageLogs)
+	a.showPage(PageHelp)
+	if a.back != PageLogs {
+		t.Errorf("help returns to %s", a.back)
+	}
+	a.showPage(PageHelp)
+	if a.back != PageLogs {
+		t.Errorf("showing help twice returns to %s", a.back)
+	}
+}
+
+func TestPlain(t *testing.T) {
+	a := New()
+	a.plain = true
+	a.showPage(PageLogs)
+	if bar := a.pageBarText(); !strings.Contains(bar, "[4 Logs[]") || strings.Contains(bar, "yellow") {
+		t.Errorf("page bar %q", bar)
+	}
+	a.updateProgressBar(50)
+	text := a.progressBar.GetText(false)
+	if strings.ContainsAny(text, "█░") || !strings.Contains(text, "#") {
+		t.Errorf("progress bar %q", text)
+	}
+	if got := a.formatPassFail(false, "1.00 ms"); got != "[red]1.00 ms FAIL" {
+		t.Errorf("formatPassFail = %q", got)
+	}
+}
@@ -0,0 +1,73 @@
+package tui
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// WithPlain draws the TUI without colors or Unicode graphics when plain
+// is set, for serial consoles and braille displays: ASCII borders and
+// progress bar, PASS/FAIL in words, and the current page in brackets
+func WithPlain(plain bool) Option {
+	return func(a *App) {
+		a.plain = plain
+	}
+}
+
+// glyph picks the Unicode symbol, or its ASCII stand-in in plain mode
+func (a *App) glyph(unicode, ascii string) string {
+	if a.plain {
+		return ascii
+	}
+	return unicode
+}
+
+// asciiBorders replaces tview's box-drawing borders. Focused boxes keep a
+// distinct top and bottom edge.
+func asciiBorders() {
+	b := &tview.Borders
+	b.Horizontal, b.Vertical = '-', '|'
+	b.TopLeft, b.TopRight, b.BottomLeft, b.BottomRight = '+', '+', '+', '+'
+	b.LeftT, b.RightT, b.TopT, b.BottomT, b.Cross = '+', '+', '+', '+', '+'
+	b.HorizontalFocus, b.VerticalFocus = '=', '|'
+	b.TopLeftFocus, b.TopRightFocus, b.BottomLeftFocus, b.BottomRightFocus = '+', '+', '+', '+'
+}
+
+// plainScreen drops every color, keeping attributes such as reverse so
+// selected rows still show
+type plainScreen struct {
+	tcell.Screen
+	err error
+}
+
+func (s *plainScreen) Init() error {
+	s.err = s.Screen.Init()
+	return s.err
+}
+
+func (s *plainScreen) SetContent(x, y int, primary rune, combining []rune, style tcell.Style) {
+	s.Screen.SetContent(x, y, primary, combining, noColor(style))
+}
+
+func (s *plainScreen) Fill(r rune, style tcell.Style) {
+	s.Screen.Fill(r, noColor(style))
+}
+
+func (s *plainScreen) SetStyle(style tcell.Style) {
+	s.Screen.SetStyle(noColor(style))
+}
+
+func noColor(style tcell.Style) tcell.Style {
+	return style.Foreground(tcell.ColorDefault).Background(tcell.ColorDefault)
+}
+
+// usePlainScreen gives the application a screen without colors
+func (a *App) usePlainScreen() error {
+	screen, err := tcell.NewScreen()
+	if err != nil {
+		return err
+	}
+	ps := &plainScreen{Screen: screen}
+	a.app.SetScreen(ps)
+	return ps.err
+}
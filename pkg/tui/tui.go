@@ -151,32 +151,67 @@ type Y1564Result struct {
 
 // App represents the TUI application
 type App struct {
-	app         *tview.Application
-	pages       *tview.Pages
-	statsView   *tview.Table
-	resultsView *tview.Table
-	logView     *tview.TextView
-	progressBar *tview.TextView
-	statusBar   *tview.TextView
+	app           *tview.Application
+	pages         *tview.Pages
+	content       *tview.Pages // One per page in the page bar
+	pageBar       *tview.TextView
+	statsView     *tview.Table
+	resultsView   *tview.Table
+	logView       *tview.TextView
+	progressBar   *tview.TextView
+	statusBar     *tview.TextView
+	helpView      *tview.TextView
+	configView    *tview.TextView
+	historyView   *tview.Table
+	historyDetail *tview.TextView
+
+	page  Page
+	back  Page // Page to return to when help closes
+	views map[Page]tview.Primitive
 
 	stats        Stats
 	results      []Result
 	y1564Results []Y1564Result
+	history      []HistoryEntry
+	answer       func(bool) // Closes the open operator prompt
+
+	keys   map[Action][]Key
+	labels map[Action]string
+	title  string
+	plain  bool // No colors or Unicode graphics; see WithPlain
 
 	// Callbacks
 	OnStart  func()
 	OnStop   func()
 	OnCancel func()
 	OnQuit   func()
+
+	// OnHistory lists the saved runs each time the history page is shown
+	OnHistory func() []HistoryEntry
 }
 
 // New creates a new TUI application
-func New() *App {
+func New(opts ...Option) *App {
 	a := &App{
 		app:          tview.NewApplication(),
 		pages:        tview.NewPages(),
+		content:      tview.NewPages(),
+		page:         PageLive,
+		back:         PageLive,
 		results:      make([]Result, 0),
 		y1564Results: make([]Y1564Result, 0),
+		labels:       make(map[Action]string),
+		title:        "RFC2544 Test Master",
+	}
+	a.keys, _ = Keymap(nil)
+	for _, act := range actions {
+		a.labels[act.action] = act.label
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	if a.plain {
+		asciiBorders()
 	}
 	a.build()
 	return a
@@ -195,6 +230,9 @@ func (a *App) build() {
 		SetBorders(true).
 		SetSelectable(true, false)
 	a.resultsView.SetTitle(" Results ").SetBorder(true)
+	if a.plain {
+		a.resultsView.SetSelectedStyle(tcell.StyleDefault.Reverse(true))
+	}
 	a.initResultsView()
 
 	// Progress bar
@@ -217,44 +255,108 @@ func (a *App) build() {
 	a.statusBar = tview.NewTextView().
 		SetDynamicColors(true).
 		SetTextAlign(tview.AlignCenter)
-	a.statusBar.SetText("[yellow]RFC2544 Test Master[white] | [green]F1[white] Start | [red]F2[white] Stop | [blue]F10[white] Quit")
+	a.statusBar.SetText(a.statusText())
 
-	// Layout
-	topRow := tview.NewFlex().
-		AddItem(a.statsView, 0, 1, false).
-		AddItem(a.resultsView, 0, 2, false)
+	// Help page, with the wiring once SetWiring is called
+	a.helpView = tview.NewTextView().
+		SetDynamicColors(true).
+		SetScrollable(true)
+	a.helpView.SetTitle(fmt.Sprintf(" %s (%s to close) ", a.labels[ActionHelp], a.KeyText(ActionHelp))).SetBorder(true)
+	a.SetWiring("")
+
+	// Config page, filled in by SetConfig
+	a.configView = tview.NewTextView().
+		SetScrollable(true)
+	a.configView.SetTitle(" Configuration ").SetBorder(true)
+
+	// History page: saved runs, and the selected run's results
+	a.historyView = tview.NewTable().
+		SetSelectable(true, false).
+		SetSelectionChangedFunc(func(row, column int) {
+			a.showHistoryDetail(row)
+		})
+	a.historyView.SetTitle(" Saved Runs ").SetBorder(true)
+	if a.plain {
+		a.historyView.SetSelectedStyle(tcell.StyleDefault.Reverse(true))
+	}
+	a.initHistoryView()
+	a.historyDetail = tview.NewTextView().
+		SetScrollable(true)
+	a.historyDetail.SetTitle(" Run Results ").SetBorder(true)
+	history := tview.NewFlex().
+		AddItem(a.historyView, 0, 1, true).
+		AddItem(a.historyDetail, 0, 1, false)
+
+	// Page bar
+	a.pageBar = tview.NewTextView().
+		SetDynamicColors(true)
+
+	// Layout: the page bar, the current page, then the progress and
+	// status bars on every page
+	a.views = map[Page]tview.Primitive{
+		PageConfig:  a.configView,
+		PageLive:    a.statsView,
+		PageResults: a.resultsView,
+		PageLogs:    a.logView,
+		PageHistory: a.historyView,
+		PageHelp:    a.helpView,
+	}
+	for _, pg := range pages {
+		view := a.views[pg.page]
+		if pg.page == PageHistory {
+			view = history
+		}
+		a.content.AddPage(string(pg.page), view, true, false)
+	}
 
 	mainFlex := tview.NewFlex().SetDirection(tview.FlexRow).
-		AddItem(topRow, 0, 3, false).
+		AddItem(a.pageBar, 1, 0, false).
+		AddItem(a.content, 0, 1, true).
 		AddItem(a.progressBar, 3, 0, false).
-		AddItem(a.logView, 0, 1, false).
 		AddItem(a.statusBar, 1, 0, false)
 
 	a.pages.AddPage("main", mainFlex, true, true)
+	a.showPage(a.page)
 
 	// Key bindings
 	a.app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-		switch event.Key() {
-		case tcell.KeyF1:
+		switch a.action(event) {
+		case ActionStart:
 			if a.OnStart != nil {
 				go a.OnStart()
 			}
 			return nil
-		case tcell.KeyF2:
+		case ActionStop:
 			if a.OnStop != nil {
 				go a.OnStop()
 			}
 			return nil
-		case tcell.KeyF10, tcell.KeyEscape:
+		case ActionHelp:
+			if a.page == PageHelp {
+				a.showPage(a.back)
+			} else {
+				a.showPage(PageHelp)
+			}
+			return nil
+		case ActionQuit:
 			if a.OnQuit != nil {
 				a.OnQuit()
 			}
 			a.app.Stop()
 			return nil
+		}
+		// Tab moves between the buttons of an open prompt
+		if a.answer == nil && a.navigate(event) {
+			return nil
+		}
+		switch event.Key() {
 		case tcell.KeyCtrlC:
 			if a.OnCancel != nil {
 				a.OnCancel()
 			}
+			if a.answer != nil {
+				a.answer(false)
+			}
 			return nil
 		}
 		return event
@@ -390,9 +492,10 @@ func (a *App) updateY1564Stats(s Stats) {
 	}
 
 	// Format pass/fail indicators
-	flrStatus := formatPassFail(s.FLRPass, fmt.Sprintf("%.4f%% (≤%.4f%%)", s.FLRPct, s.FLRThreshold))
-	fdStatus := formatPassFail(s.FDPass, fmt.Sprintf("%.2f ms (≤%.2f ms)", s.FDMs, s.FDThreshold))
-	fdvStatus := formatPassFail(s.FDVPass, fmt.Sprintf("%.2f ms (≤%.2f ms)", s.FDVMs, s.FDVThreshold))
+	le := a.glyph("≤", "<=")
+	flrStatus := a.formatPassFail(s.FLRPass, fmt.Sprintf("%.4f%% (%s%.4f%%)", s.FLRPct, le, s.FLRThreshold))
+	fdStatus := a.formatPassFail(s.FDPass, fmt.Sprintf("%.2f ms (%s%.2f ms)", s.FDMs, le, s.FDThreshold))
+	fdvStatus := a.formatPassFail(s.FDVPass, fmt.Sprintf("%.2f ms (%s%.2f ms)", s.FDVMs, le, s.FDVThreshold))
 
 	// Overall SLA status
 	slaStatus := "[green]PASS"
@@ -441,11 +544,11 @@ func (a *App) updateY1564Stats(s Stats) {
 }
 
 // formatPassFail returns a colored string based on pass/fail status
-func formatPassFail(pass bool, value string) string {
+func (a *App) formatPassFail(pass bool, value string) string {
 	if pass {
-		return fmt.Sprintf("[green]%s ✓", value)
+		return fmt.Sprintf("[green]%s %s", value, a.glyph("✓", "PASS"))
 	}
-	return fmt.Sprintf("[red]%s ✗", value)
+	return fmt.Sprintf("[red]%s %s", value, a.glyph("✗", "FAIL"))
 }
 
 // AddResult adds a test result to the results table
@@ -568,9 +671,9 @@ func (a *App) updateProgressBar(pct float64) {
 	bar := ""
 	for i := 0; i < width; i++ {
 		if i < filled {
-			bar += "[green]█"
+			bar += "[green]" + a.glyph("█", "#")
 		} else {
-			bar += "[gray]░"
+			bar += "[gray]" + a.glyph("░", ".")
 		}
 	}
 	a.progressBar.SetText(fmt.Sprintf("%s[white] %.1f%%", bar, pct))
@@ -583,8 +686,52 @@ func (a *App) SetStatus(msg string) {
 	})
 }
 
+// Prompt shows an operator dialog, e.g. "Re-patch to port 2", and blocks
+// until Continue (true) or Cancel (false) is chosen. Call it from the test
+// goroutine, not from a callback on the UI goroutine.
+func (a *App) Prompt(message string) bool {
+	answer := make(chan bool, 1)
+	a.app.QueueUpdateDraw(func() {
+		modal := tview.NewModal().
+			SetText(message).
+			AddButtons([]string{"Continue", "Cancel"})
+		a.answer = func(ok bool) {
+			a.answer = nil
+			a.pages.RemovePage("prompt")
+			answer <- ok
+		}
+		modal.SetDoneFunc(func(index int, label string) {
+			a.answer(label == "Continue")
+		})
+		a.pages.AddPage("prompt", modal, false, true)
+		a.app.SetFocus(modal)
+	})
+	return <-answer
+}
+
+// SetWiring shows how the tester and the DUT are cabled, e.g. a
+// wiring.Diagram, on the help page under the key bindings
+func (a *App) SetWiring(text string) {
+	help := "[yellow]Keys[white]\n"
+	for _, act := range actions {
+		help += fmt.Sprintf("  %-12s %s\n", a.KeyText(act.action), a.labels[act.action])
+	}
+	help += fmt.Sprintf("  %-12s Cancel the test or prompt\n", "Ctrl-C")
+	help += fmt.Sprintf("  %-12s Next / previous page\n", "Tab/Backtab")
+	help += fmt.Sprintf("  %-12s Go to a page\n", fmt.Sprintf("1-%d", len(pages)))
+	if text != "" {
+		help += "\n[yellow]Wiring[white]\n" + tview.Escape(text)
+	}
+	a.helpView.SetText(help)
+}
+
 // Run starts the TUI application
 func (a *App) Run() error {
+	if a.plain {
+		if err := a.usePlainScreen(); err != nil {
+			return err
+		}
+	}
 	return a.app.Run()
 }
 
@@ -0,0 +1,286 @@
+package udpecho
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"time"
+)
+
+// ErrCancelled is returned by Generator trials after Cancel
+var ErrCancelled = errors.New("cancelled")
+
+const ethOverhead = 20 // Preamble, SFD and inter-frame gap, in bytes
+
+// GeneratorOptions configure a Generator
+type GeneratorOptions struct {
+	Target        string        // Reflector host or host:port
+	MaxPPS        uint32        // Rate treated as 100% of line rate
+	Timeout       time.Duration // Wait for late replies after each trial
+	TrialDuration time.Duration
+	Warmup        time.Duration // Traffic sent before each trial, not counted
+
+	// Throughput binary search (Section 26.1)
+	InitialRatePct float64
+	ResolutionPct  float64
+	MaxIterations  uint32
+	AcceptableLoss float64
+}
+
+// Latency is round-trip latency over the replies of a trial
+type Latency struct {
+	Count    uint64
+	MinNs    float64
+	AvgNs    float64
+	MaxNs    float64
+	JitterNs float64
+	P50Ns    float64
+	P95Ns    float64
+	P99Ns    float64
+}
+
+// Trial is the outcome of one fixed-rate trial
+type Trial struct {
+	RatePct       float64
+	FramesTx      uint64
+	FramesRx      uint64
+	LossPct       float64
+	DeliveredMbps float64 // Received rate including Ethernet overhead
+	Elapsed       time.Duration
+	Latency       Latency
+}
+
+// ThroughputResult is the highest rate with loss within the acceptable loss
+type ThroughputResult struct {
+	FrameSize   uint32
+	MaxRatePct  float64
+	MaxRateMbps float64
+	MaxRatePPS  float64
+	Iterations  uint32
+	Latency     Latency // From the best passing trial
+
+	AcceptableLoss float64       // Loss a passing trial was allowed
+	Trials         []SearchTrial // Every iteration, in order
+}
+
+// SearchTrial is one iteration of a throughput search
+type SearchTrial struct {
+	Trial
+	Passed bool // Loss within the acceptable loss; the search went up
+}
+
+// Search is the state of a throughput binary search between iterations
+type Search struct {
+	LowPct     float64
+	HighPct    float64
+	BestPct    float64
+	Iterations uint32
+	Latency    Latency
+	Trials     []SearchTrial
+}
+
+// Generator runs RFC 2544 style trials as UDP echo runs against a
+// reflector. It needs no privileges, but rates are capped at MaxPPS and
+// every trial carries the reduced-accuracy flags of Run.
+type Generator struct {
+	opts      GeneratorOptions
+	frameSize uint32
+	ctx       context.Context
+	cancel    context.CancelFunc
+	flags     []string
+}
+
+// NewGenerator checks the options and resolves the reflector address
+func NewGenerator(opts GeneratorOptions) (*Generator, error) {
+	if opts.MaxPPS == 0 {
+		return nil, errors.New("max rate must be > 0")
+	}
+	target := opts.Target
+	if _, _, err := net.SplitHostPort(target); err != nil {
+		target = net.JoinHostPort(target, DefaultPort)
+	}
+	if _, err := net.ResolveUDPAddr("udp", target); err != nil {
+		return nil, fmt.Errorf("resolve %s: %w", target, err)
+	}
+	opts.Target = target
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Generator{opts: opts, frameSize: 64, ctx: ctx, cancel: cancel}, nil
+}
+
+// SetFrameSize sets the frame size for subsequent trials
+func (g *Generator) SetFrameSize(size uint32) {
+	g.frameSize = size
+}
+
+// SetAcceptableLoss sets the loss a throughput trial may show and still pass
+func (g *Generator) SetAcceptableLoss(pct float64) {
+	g.opts.AcceptableLoss = pct
+}
+
+// Cancel stops the running trial; later trials return ErrCancelled
+func (g *Generator) Cancel() {
+	g.cancel()
+}
+
+// ReducedAccuracy returns the flags raised by any trial so far
+func (g *Generator) ReducedAccuracy() []string {
+	return append([]string(nil), g.flags...)
+}
+
+// mbps converts a frame rate at the current frame size to Mbit/s on the wire
+func (g *Generator) mbps(pps float64) float64 {
+	return pps * float64(g.frameSize+ethOverhead) * 8 / 1e6
+}
+
+// Trial offers ratePct of MaxPPS for duration
+func (g *Generator) Trial(ratePct float64, duration time.Duration) (*Trial, error) {
+	pps := float64(g.opts.MaxPPS) * ratePct / 100
+	if pps < 1 {
+		return nil, fmt.Errorf("rate %.4f%% is below 1 frame/s", ratePct)
+	}
+	opts := Options{Target: g.opts.Target, FrameSize: g.frameSize, RatePPS: uint32(pps)}
+
+	if g.opts.Warmup > 0 {
+		warm := opts
+		warm.Duration = g.opts.Warmup
+		if _, err := g.run(warm); err != nil {
+			return nil, err
+		}
+	}
+
+	opts.Duration, opts.Timeout = duration, g.opts.Timeout
+	r, err := g.run(opts)
+	if err != nil {
+		return nil, err
+	}
+	g.addFlags(r.ReducedAccuracy)
+
+	t := &Trial{RatePct: ratePct, FramesTx: r.Sent, FramesRx: r.Received, LossPct: r.LossPct, Latency: latencyOf(r)}
+	if r.AchievedPPS > 0 {
+		t.Elapsed = time.Duration(float64(r.Sent) / r.AchievedPPS * float64(time.Second))
+		t.DeliveredMbps = g.mbps(r.AchievedPPS * float64(r.Received) / float64(r.Sent))
+	}
+	return t, nil
+}
+
+func (g *Generator) run(opts Options) (*Result, error) {
+	if g.ctx.Err() != nil {
+		return nil, ErrCancelled
+	}
+	r, err := Run(g.ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	if g.ctx.Err() != nil {
+		return nil, ErrCancelled
+	}
+	return r, nil
+}
+
+func (g *Generator) addFlags(flags []string) {
+	for _, f := range flags {
+		found := false
+		for _, have := range g.flags {
+			if have == f {
+				found = true
+				break
+			}
+		}
+		if !found {
+			g.flags = append(g.flags, f)
+		}
+	}
+	sort.Strings(g.flags)
+}
+
+func latencyOf(r *Result) Latency {
+	ns := func(us float64) float64 { return us * 1e3 }
+	return Latency{
+		Count:    r.Received,
+		MinNs:    ns(r.MinUs),
+		AvgNs:    ns(r.AvgUs),
+		MaxNs:    ns(r.MaxUs),
+		JitterNs: ns(r.JitterUs),
+		P50Ns:    ns(r.P50Us),
+		P95Ns:    ns(r.P95Us),
+		P99Ns:    ns(r.P99Us),
+	}
+}
+
+// Latency runs a trial at loadPct of MaxPPS (Section 26.2)
+func (g *Generator) Latency(loadPct float64) (*Trial, error) {
+	t, err := g.Trial(loadPct, g.opts.TrialDuration)
+	if err != nil {
+		return nil, err
+	}
+	if t.Latency.Count == 0 {
+		return nil, fmt.Errorf("no replies received at %.1f%%", loadPct)
+	}
+	return t, nil
+}
+
+// ThroughputFrom continues a throughput search from search, or starts one
+// if search is nil, calling step after every iteration
+func (g *Generator) ThroughputFrom(search *Search, step func(*Search)) (*ThroughputResult, error) {
+	o := g.opts
+	s := Search{HighPct: o.InitialRatePct}
+	if search != nil {
+		s = *search
+		s.Trials = append([]SearchTrial(nil), search.Trials...)
+	}
+
+	for s.HighPct-s.LowPct > o.ResolutionPct && s.Iterations < o.MaxIterations {
+		rate := (s.LowPct + s.HighPct) / 2
+		t, err := g.Trial(rate, o.TrialDuration)
+		if err != nil {
+			return nil, err
+		}
+		passed := t.LossPct <= o.AcceptableLoss
+		if passed {
+			s.BestPct, s.LowPct = rate, rate
+			s.Latency = t.Latency
+		} else {
+			s.HighPct = rate
+		}
+		s.Iterations++
+		s.Trials = append(s.Trials, SearchTrial{Trial: *t, Passed: passed})
+		if step != nil {
+			next := s
+			next.Trials = append([]SearchTrial(nil), s.Trials...)
+			step(&next)
+		}
+	}
+
+	pps := float64(o.MaxPPS) * s.BestPct / 100
+	return &ThroughputResult{
+		FrameSize:   g.frameSize,
+		MaxRatePct:  s.BestPct,
+		MaxRateMbps: g.mbps(pps),
+		MaxRatePPS:  pps,
+		Iterations:  s.Iterations,
+		Latency:     s.Latency,
+
+		AcceptableLoss: o.AcceptableLoss,
+		Trials:         s.Trials,
+	}, nil
+}
+
+// FrameLoss steps the offered load down from startPct to endPct
+// (Section 26.3)
+func (g *Generator) FrameLoss(startPct, endPct, stepPct float64) ([]Trial, error) {
+	if stepPct <= 0 {
+		return nil, fmt.Errorf("frame loss step must be > 0")
+	}
+	var trials []Trial
+	for rate := startPct; rate >= endPct; rate -= stepPct {
+		t, err := g.Trial(rate, g.opts.TrialDuration)
+		if err != nil {
+			return nil, err
+		}
+		trials = append(trials, *t)
+	}
+	return trials, nil
+}
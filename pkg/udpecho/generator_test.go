@@ -0,0 +1,85 @@
+package udpecho
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func newTestGenerator(t *testing.T) *Generator {
+	t.Helper()
+	r := startReflector(t)
+	g, err := NewGenerator(GeneratorOptions{
+		Target:         r.Addr().String(),
+		MaxPPS:         1000,
+		Timeout:        100 * time.Millisecond,
+		TrialDuration:  100 * time.Millisecond,
+		InitialRatePct: 100,
+		ResolutionPct:  10,
+		MaxIterations:  20,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestGeneratorThroughput(t *testing.T) {
+	g := newTestGenerator(t)
+	g.SetFrameSize(128)
+
+	var steps int
+	res, err := g.ThroughputFrom(nil, func(*Search) { steps++ })
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 50, 75, 87.5 and 93.75% all pass on loopback
+	if res.MaxRatePct != 93.75 || res.Iterations != 4 || steps != 4 {
+		t.Fatalf("result = %+v after %d steps", res, steps)
+	}
+	if res.MaxRatePPS != 937.5 || res.Latency.Count == 0 {
+		t.Errorf("pps %.1f, latency %+v", res.MaxRatePPS, res.Latency)
+	}
+	if len(res.Trials) != 4 || res.Trials[0].RatePct != 50 || !res.Trials[3].Passed || res.Trials[3].Latency.Count == 0 {
+		t.Errorf("trials = %+v", res.Trials)
+	}
+	if want := 937.5 * 148 * 8 / 1e6; res.MaxRateMbps != want {
+		t.Errorf("MaxRateMbps = %f, want %f", res.MaxRateMbps, want)
+	}
+
+	flags := g.ReducedAccuracy()
+	if len(flags) < 3 {
+		t.Errorf("ReducedAccuracy = %v", flags)
+	}
+}
+
+func TestGeneratorFrameLoss(t *testing.T) {
+	g := newTestGenerator(t)
+	trials, err := g.FrameLoss(100, 50, 25)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trials) != 3 || trials[0].RatePct != 100 || trials[2].RatePct != 50 {
+		t.Fatalf("trials = %+v", trials)
+	}
+	for _, tr := range trials {
+		if tr.FramesTx == 0 || tr.LossPct != 0 || tr.DeliveredMbps <= 0 {
+			t.Errorf("trial = %+v", tr)
+		}
+	}
+}
+
+func TestGeneratorCancel(t *testing.T) {
+	g := newTestGenerator(t)
+	g.Cancel()
+	if _, err := g.Trial(50, time.Second); !errors.Is(err, ErrCancelled) {
+		t.Errorf("Trial after Cancel = %v, want ErrCancelled", err)
+	}
+}
+
+func TestGeneratorRateFloor(t *testing.T) {
+	g := newTestGenerator(t)
+	if _, err := g.Trial(0.01, time.Second); err == nil {
+		t.Error("Expected error for a rate below 1 frame/s")
+	}
+}
@@ -0,0 +1,325 @@
+// Package udpecho measures round-trip latency and loss with UDP datagrams
+// bounced off a reflector
+//
+// The layer 2 tests need the far end to loop frames back unchanged, which
+// cannot work across routers or NAT. Here the sender stamps each datagram
+// with a sequence number and its own send time, and the reflector (built
+// into the rfc2544 binary) returns it to the source address. Both ends use
+// the kernel's UDP stack and the sender's clock, so results are round trip
+// with software timestamps and are flagged as reduced accuracy.
+//
+// A Generator runs the RFC 2544 trials the same way, giving an
+// unprivileged socket mode for environments without raw sockets or XDP.
+package udpecho
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultPort is the reflector port used when the target has none
+const DefaultPort = "3842"
+
+// Magic marks datagrams the reflector answers
+const Magic = 0x52464345 // "RFCE"
+
+// HeaderLen is the magic, sequence number and send time at the start of
+// each datagram
+const HeaderLen = 16
+
+// Header overhead between the UDP payload and the Ethernet frame size
+const (
+	ipv4Overhead = 14 + 20 + 8 + 4 // Ethernet + IPv4 + UDP + FCS
+	ipv6Overhead = 14 + 40 + 8 + 4 // Ethernet + IPv6 + UDP + FCS
+)
+
+// Reduced-accuracy flags recorded on results
+const (
+	FlagSoftwareTimestamps = "software_timestamps" // Userspace clock, not NIC timestamps
+	FlagRoundTrip          = "round_trip"          // Latency includes the return path and reflector turnaround
+	FlagRoutedPath         = "routed_path"         // Frame size assumes plain Ethernet/IP headers on every hop
+	FlagRateShortfall      = "rate_shortfall"      // Sender fell more than 5% below the requested rate
+	FlagPaddedPayload      = "padded_payload"      // Frame size too small for the header; datagram enlarged
+)
+
+// Options control a run
+type Options struct {
+	Target    string        // Reflector host or host:port
+	FrameSize uint32        // Ethernet frame size the datagrams are sized for
+	RatePPS   uint32        // Datagrams per second
+	Duration  time.Duration // Sending time
+	Timeout   time.Duration // Wait for late replies after sending stops
+}
+
+// Result summarises a run
+type Result struct {
+	Target          string   `json:"target"`
+	FrameSize       uint32   `json:"frame_size"`
+	PayloadLen      int      `json:"payload_len"`
+	RatePPS         uint32   `json:"rate_pps"`
+	AchievedPPS     float64  `json:"achieved_pps"`
+	Sent            uint64   `json:"sent"`
+	Received        uint64   `json:"received"`
+	Duplicates      uint64   `json:"duplicates"`
+	Reordered       uint64   `json:"reordered"`
+	LossPct         float64  `json:"loss_pct"`
+	MinUs           float64  `json:"min_us"`
+	AvgUs           float64  `json:"avg_us"`
+	MaxUs           float64  `json:"max_us"`
+	JitterUs        float64  `json:"jitter_us"` // Mean difference between consecutive RTTs
+	P50Us           float64  `json:"p50_us"`
+	P95Us           float64  `json:"p95_us"`
+	P99Us           float64  `json:"p99_us"`
+	ReducedAccuracy []string `json:"reduced_accuracy"`
+}
+
+// PayloadLen returns the UDP payload filling frameSize, and whether it had
+// to be enlarged to fit the header
+func PayloadLen(frameSize uint32, v6 bool) (int, bool) {
+	overhead := ipv4Overhead
+	if v6 {
+		overhead = ipv6Overhead
+	}
+	n := int(frameSize) - overhead
+	if n < HeaderLen {
+		return HeaderLen, true
+	}
+	return n, false
+}
+
+// Run sends datagrams to the reflector at a fixed rate and collects the
+// replies. Cancelling ctx stops sending early; the result covers what was
+// sent.
+func Run(ctx context.Context, opts Options) (*Result, error) {
+	target := opts.Target
+	if _, _, err := net.SplitHostPort(target); err != nil {
+		target = net.JoinHostPort(target, DefaultPort)
+	}
+	if opts.RatePPS == 0 {
+		return nil, errors.New("rate must be > 0")
+	}
+	conn, err := net.Dial("udp", target)
+	if err != nil {
+		return nil, fmt.Errorf("dial %s: %w", target, err)
+	}
+	defer conn.Close()
+
+	raddr := conn.RemoteAddr().(*net.UDPAddr)
+	payloadLen, padded := PayloadLen(opts.FrameSize, raddr.IP.To4() == nil)
+	res := &Result{
+		Target:          target,
+		FrameSize:       opts.FrameSize,
+		PayloadLen:      payloadLen,
+		RatePPS:         opts.RatePPS,
+		ReducedAccuracy: []string{FlagSoftwareTimestamps, FlagRoundTrip, FlagRoutedPath},
+	}
+	if padded {
+		res.ReducedAccuracy = append(res.ReducedAccuracy, FlagPaddedPayload)
+	}
+
+	start := time.Now()
+	var rx receiver
+	rx.wg.Add(1)
+	go rx.run(conn, start, payloadLen)
+
+	sent, elapsed := send(ctx, conn, start, payloadLen, opts)
+
+	// Wait for replies still in flight, then unblock the reader
+	select {
+	case <-ctx.Done():
+	case <-time.After(opts.Timeout):
+	}
+	conn.SetReadDeadline(time.Now())
+	rx.wg.Wait()
+
+	res.Sent = sent
+	if elapsed > 0 {
+		res.AchievedPPS = float64(sent) / elapsed.Seconds()
+	}
+	if res.AchievedPPS < 0.95*float64(opts.RatePPS) {
+		res.ReducedAccuracy = append(res.ReducedAccuracy, FlagRateShortfall)
+	}
+	rx.summarize(res)
+	return res, nil
+}
+
+// send paces datagrams by comparing the count sent with the count due,
+// which holds the average rate even when individual sleeps overshoot
+func send(ctx context.Context, conn net.Conn, start time.Time, payloadLen int, opts Options) (uint64, time.Duration) {
+	buf := make([]byte, payloadLen)
+	binary.BigEndian.PutUint32(buf[0:], Magic)
+
+	var seq uint64
+	for {
+		elapsed := time.Since(start)
+		if elapsed >= opts.Duration || ctx.Err() != nil {
+			return seq, elapsed
+		}
+		due := uint64(elapsed.Seconds() * float64(opts.RatePPS))
+		for ; seq <= due; seq++ {
+			binary.BigEndian.PutUint32(buf[4:], uint32(seq))
+			binary.BigEndian.PutUint64(buf[8:], uint64(time.Since(start)))
+			// ICMP unreachable surfaces as a write error; the datagram
+			// counts as lost
+			conn.Write(buf)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// receiver matches replies to the datagrams sent
+type receiver struct {
+	wg         sync.WaitGroup
+	seen       map[uint32]bool
+	rtts       []time.Duration
+	duplicates uint64
+	reordered  uint64
+}
+
+func (r *receiver) run(conn net.Conn, start time.Time, payloadLen int) {
+	defer r.wg.Done()
+	r.seen = make(map[uint32]bool)
+	buf := make([]byte, payloadLen+1)
+	var last uint32
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				return
+			}
+			continue // ICMP errors from an unreachable reflector
+		}
+		now := time.Since(start)
+		if n < HeaderLen || binary.BigEndian.Uint32(buf[0:]) != Magic {
+			continue
+		}
+		seq := binary.BigEndian.Uint32(buf[4:])
+		if r.seen[seq] {
+			r.duplicates++
+			continue
+		}
+		r.seen[seq] = true
+		if len(r.rtts) > 0 && seq < last {
+			r.reordered++
+		} else {
+			last = seq
+		}
+		r.rtts = append(r.rtts, now-time.Duration(binary.BigEndian.Uint64(buf[8:])))
+	}
+}
+
+// summarize fills the loss and RTT statistics
+func (r *receiver) summarize(res *Result) {
+	res.Received = uint64(len(r.rtts))
+	res.Duplicates = r.duplicates
+	res.Reordered = r.reordered
+	if res.Sent > 0 && res.Received <= res.Sent {
+		res.LossPct = 100 * float64(res.Sent-res.Received) / float64(res.Sent)
+	}
+	if len(r.rtts) == 0 {
+		return
+	}
+
+	var sum, jitter time.Duration
+	for i, d := range r.rtts {
+		sum += d
+		if i > 0 {
+			diff := d - r.rtts[i-1]
+			if diff < 0 {
+				diff = -diff
+			}
+			jitter += diff
+		}
+	}
+	res.AvgUs = us(sum) / float64(len(r.rtts))
+	if len(r.rtts) > 1 {
+		res.JitterUs = us(jitter) / float64(len(r.rtts)-1)
+	}
+
+	sorted := append([]time.Duration(nil), r.rtts...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	res.MinUs = us(sorted[0])
+	res.MaxUs = us(sorted[len(sorted)-1])
+	res.P50Us = us(percentile(sorted, 50))
+	res.P95Us = us(percentile(sorted, 95))
+	res.P99Us = us(percentile(sorted, 99))
+}
+
+// percentile uses the nearest-rank method on sorted samples
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	i := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
+
+func us(d time.Duration) float64 {
+	return float64(d) / float64(time.Microsecond)
+}
+
+// Reflector returns sender datagrams to their source
+type Reflector struct {
+	conn net.PacketConn
+
+	mu        sync.Mutex
+	reflected uint64
+}
+
+// Listen opens a reflector on addr (host:port or :port)
+func Listen(addr string) (*Reflector, error) {
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("listen %s: %w", addr, err)
+	}
+	return &Reflector{conn: conn}, nil
+}
+
+// Addr returns the address the reflector listens on
+func (r *Reflector) Addr() net.Addr {
+	return r.conn.LocalAddr()
+}
+
+// Reflected returns the number of datagrams returned so far
+func (r *Reflector) Reflected() uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.reflected
+}
+
+// Serve reflects datagrams until Close is called. Datagrams without the
+// magic are dropped, so the reflector cannot be used to bounce arbitrary
+// traffic.
+func (r *Reflector) Serve() error {
+	buf := make([]byte, 65536)
+	for {
+		n, addr, err := r.conn.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		if n < HeaderLen || binary.BigEndian.Uint32(buf[0:]) != Magic {
+			continue
+		}
+		if _, err := r.conn.WriteTo(buf[:n], addr); err == nil {
+			r.mu.Lock()
+			r.reflected++
+			r.mu.Unlock()
+		}
+	}
+}
+
+// Close stops the reflector
+func (r *Reflector) Close() error {
+	return r.conn.Close()
+}
@@ -0,0 +1,142 @@
+package udpecho
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+)
+
+func startReflector(t *testing.T) *Reflector {
+	t.Helper()
+	r, err := Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go r.Serve()
+	t.Cleanup(func() { r.Close() })
+	return r
+}
+
+func TestRun(t *testing.T) {
+	r := startReflector(t)
+	res, err := Run(context.Background(), Options{
+		Target:    r.Addr().String(),
+		FrameSize: 128,
+		RatePPS:   1000,
+		Duration:  200 * time.Millisecond,
+		Timeout:   200 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Sent == 0 || res.Received != res.Sent || res.LossPct != 0 {
+		t.Fatalf("sent %d, received %d, loss %.2f%%", res.Sent, res.Received, res.LossPct)
+	}
+	if r.Reflected() != res.Sent {
+		t.Errorf("reflected %d, want %d", r.Reflected(), res.Sent)
+	}
+	if res.PayloadLen != 128-ipv4Overhead {
+		t.Errorf("PayloadLen = %d", res.PayloadLen)
+	}
+	if res.MinUs <= 0 || res.MinUs > res.P50Us || res.P50Us > res.P99Us || res.P99Us > res.MaxUs {
+		t.Errorf("rtt min %.1f p50 %.1f p99 %.1f max %.1f", res.MinUs, res.P50Us, res.P99Us, res.MaxUs)
+	}
+	if !hasFlag(res, FlagRoundTrip) || !hasFlag(res, FlagSoftwareTimestamps) {
+		t.Errorf("ReducedAccuracy = %v", res.ReducedAccuracy)
+	}
+}
+
+func hasFlag(r *Result, flag string) bool {
+	for _, f := range r.ReducedAccuracy {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+func TestRunLoss(t *testing.T) {
+	// Reflect every other datagram
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	go func() {
+		buf := make([]byte, 2048)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if binary.BigEndian.Uint32(buf[4:])%2 == 0 {
+				conn.WriteTo(buf[:n], addr)
+			}
+		}
+	}()
+
+	res, err := Run(context.Background(), Options{
+		Target:    conn.LocalAddr().String(),
+		FrameSize: 64,
+		RatePPS:   500,
+		Duration:  200 * time.Millisecond,
+		Timeout:   200 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.LossPct < 40 || res.LossPct > 60 {
+		t.Errorf("loss = %.2f%%, want about 50%%", res.LossPct)
+	}
+}
+
+func TestRunCancel(t *testing.T) {
+	r := startReflector(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := Run(ctx, Options{Target: r.Addr().String(), FrameSize: 64, RatePPS: 100, Duration: time.Minute, Timeout: time.Minute}); err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Error("Run did not stop on cancel")
+	}
+}
+
+func TestReflectorIgnoresForeignTraffic(t *testing.T) {
+	r := startReflector(t)
+	conn, err := net.Dial("udp", r.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	conn.Write([]byte("not an echo request"))
+	conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	if _, err := conn.Read(make([]byte, 64)); err == nil {
+		t.Error("Reflector answered a datagram without the magic")
+	}
+}
+
+func TestPayloadLen(t *testing.T) {
+	tests := []struct {
+		frameSize uint32
+		v6        bool
+		want      int
+		padded    bool
+	}{
+		{64, false, 18, false},
+		{1518, false, 1472, false},
+		{64, true, HeaderLen, true},
+		{1518, true, 1452, false},
+	}
+	for _, tt := range tests {
+		got, padded := PayloadLen(tt.frameSize, tt.v6)
+		if got != tt.want || padded != tt.padded {
+			t.Errorf("PayloadLen(%d, %v) = %d, %v, want %d, %v", tt.frameSize, tt.v6, got, padded, tt.want, tt.padded)
+		}
+	}
+}
@@ -0,0 +1,362 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Campaign item states beyond the run states
+const (
+	StatusPending = "pending"
+	StatusSkipped = "skipped" // Not run because the campaign was cancelled
+	StatusWaiting = "waiting" // Paused on an operator prompt before the item
+)
+
+// maxCampaignItems bounds the tests one campaign may queue
+const maxCampaignItems = 100
+
+// CampaignRequest is the body of POST /api/campaigns. A bare JSON array
+// of test configs is accepted as the items of an unnamed campaign.
+type CampaignRequest struct {
+	Name  string         `json:"name"`
+	Items []CampaignStep `json:"items"`
+}
+
+// CampaignStep is a test config with an optional operator prompt, e.g.
+// "Re-patch to port 2". The campaign pauses on the prompt before the test
+// starts until POST /api/tests/{id}/continue.
+type CampaignStep struct {
+	Config
+	Prompt string `json:"prompt,omitempty"`
+}
+
+// Campaign is a batch of tests run one after another
+type Campaign struct {
+	ID       string         `json:"id"`
+	Name     string         `json:"name,omitempty"`
+	Status   string         `json:"status"`
+	Message  string         `json:"message,omitempty"`
+	Started  time.Time      `json:"started"`
+	Finished time.Time      `json:"finished,omitempty"`
+	Current  int            `json:"current"`          // Index of the item running
+	Progress float64        `json:"progress"`         // Across all items, 0-100
+	Prompt   string         `json:"prompt,omitempty"` // Shown while waiting for the operator
+	Items    []CampaignItem `json:"items"`
+
+	resume chan bool // Operator's answer while waiting: continue or cancel
+}
+
+// CampaignItem is one test of a campaign and the run it produced
+type CampaignItem struct {
+	Config  Config `json:"config"`
+	Prompt  string `json:"prompt,omitempty"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+	RunID   string `json:"run_id,omitempty"`
+
+	run *Run
+}
+
+// CampaignResults is the combined result document of a finished campaign
+type CampaignResults struct {
+	Campaign Campaign `json:"campaign"`
+	Runs     []*Run   `json:"runs"`
+}
+
+// snapshot copies the campaign for a response. Caller holds s.mu.
+func (s *Server) snapshot(c *Campaign) Campaign {
+	snap := *c
+	snap.Items = append([]CampaignItem(nil), c.Items...)
+	switch c.Status {
+	case StatusRunning:
+		// Finished items, plus the running item's own progress
+		snap.Progress = (float64(c.Current) + s.progress/100) / float64(len(c.Items)) * 100
+	case StatusWaiting:
+		snap.Progress = float64(c.Current) / float64(len(c.Items)) * 100
+	default:
+		snap.Progress = 100
+	}
+	return snap
+}
+
+// startRun opens a run for cfg and hands it to OnStart
+func (s *Server) startRun(cfg Config) error {
+	if s.Defaults != nil {
+		s.Defaults(&cfg)
+	}
+
+	s.mu.Lock()
+	s.config = cfg
+	s.results = s.results[:0] // Clear previous results
+	s.testResults = s.testResults[:0]
+	s.progress = 0
+	s.beginRun(cfg)
+	s.mu.Unlock()
+
+	if s.OnStart != nil {
+		if err := s.OnStart(cfg); err != nil {
+			s.mu.Lock()
+			s.current = nil
+			s.mu.Unlock()
+			return err
+		}
+	}
+	return nil
+}
+
+// runCampaign runs the items in order, each as its own run. A failed item
+// does not stop the campaign; a cancelled one skips the rest.
+func (s *Server) runCampaign(c *Campaign) {
+	for i := range c.Items {
+		if c.Items[i].Prompt != "" {
+			s.waitOperator(c, i)
+		}
+
+		done := make(chan *Run, 1)
+		s.mu.Lock()
+		if c.Status == StatusCancelled {
+			c.Items[i].Status = StatusSkipped
+			s.mu.Unlock()
+			continue
+		}
+		c.Current = i
+		c.Items[i].Status = StatusRunning
+		s.runDone = done
+		s.mu.Unlock()
+
+		if err := s.startRun(c.Items[i].Config); err != nil {
+			s.mu.Lock()
+			s.runDone = nil
+			c.Items[i].Status = StatusError
+			c.Items[i].Message = fmt.Sprintf("Start failed: %v", err)
+			s.mu.Unlock()
+			continue
+		}
+		run := <-done
+
+		s.mu.Lock()
+		item := &c.Items[i]
+		item.Status, item.Message, item.RunID, item.run = run.Status, run.Message, run.ID, run
+		if run.Status == StatusCancelled {
+			c.Status = StatusCancelled
+		}
+		s.mu.Unlock()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	failed := 0
+	for _, item := range c.Items {
+		if item.Status == StatusError {
+			failed++
+		}
+	}
+	if failed > 0 {
+		c.Message = fmt.Sprintf("%d of %d items failed", failed, len(c.Items))
+	}
+	if c.Status == StatusRunning {
+		c.Status = StatusComplete
+	}
+	c.Finished = time.Now()
+	s.campaign = nil
+}
+
+// waitOperator pauses the campaign on item i's prompt until the operator
+// continues or cancels it
+func (s *Server) waitOperator(c *Campaign, i int) {
+	s.mu.Lock()
+	if c.Status == StatusCancelled {
+		s.mu.Unlock()
+		return
+	}
+	resume := make(chan bool, 1)
+	c.Status, c.Current, c.Prompt, c.resume = StatusWaiting, i, c.Items[i].Prompt, resume
+	c.Items[i].Status = StatusWaiting
+	s.mu.Unlock()
+
+	ok := <-resume
+
+	s.mu.Lock()
+	c.Prompt, c.resume = "", nil
+	if ok {
+		c.Status = StatusRunning
+	} else {
+		c.Status = StatusCancelled
+	}
+	s.mu.Unlock()
+}
+
+// cancelWaiting cancels the campaign if it is waiting on a prompt. Caller
+// holds s.mu.
+func (s *Server) cancelWaiting() {
+	if c := s.campaign; c != nil && c.resume != nil {
+		c.resume <- false
+		c.resume = nil
+	}
+}
+
+// handleCampaigns serves POST /api/campaigns to queue a campaign and GET
+// to list campaigns, newest first
+func (s *Server) handleCampaigns(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.mu.RLock()
+		list := make([]Campaign, 0, len(s.campaigns))
+		for _, c := range s.campaigns {
+			list = append(list, s.snapshot(c))
+		}
+		s.mu.RUnlock()
+		sort.SliceStable(list, func(i, j int) bool { return list[i].Started.After(list[j].Started) })
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(list)
+		return
+	case http.MethodPost:
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req CampaignRequest
+	var body bytes.Buffer
+	if _, err := body.ReadFrom(r.Body); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid campaign: %v", err), http.StatusBadRequest)
+		return
+	}
+	var err error
+	if data := bytes.TrimSpace(body.Bytes()); len(data) > 0 && data[0] == '[' {
+		err = json.Unmarshal(data, &req.Items)
+	} else {
+		err = json.Unmarshal(data, &req)
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid campaign: %v", err), http.StatusBadRequest)
+		return
+	}
+	if len(req.Items) == 0 {
+		http.Error(w, "Campaign has no items", http.StatusBadRequest)
+		return
+	}
+	if len(req.Items) > maxCampaignItems {
+		http.Error(w, fmt.Sprintf("Campaign exceeds %d items", maxCampaignItems), http.StatusBadRequest)
+		return
+	}
+
+	c := &Campaign{ID: randomHex(8), Name: req.Name, Status: StatusRunning, Started: time.Now()}
+	for _, step := range req.Items {
+		c.Items = append(c.Items, CampaignItem{Config: step.Config, Prompt: step.Prompt, Status: StatusPending})
+	}
+
+	s.mu.Lock()
+	if s.campaign != nil || s.current != nil {
+		s.mu.Unlock()
+		http.Error(w, "A test is already running", http.StatusConflict)
+		return
+	}
+	s.campaign = c
+	s.campaigns = append(s.campaigns, c)
+	if len(s.campaigns) > maxRuns {
+		s.campaigns = s.campaigns[len(s.campaigns)-maxRuns:]
+	}
+	snap := s.snapshot(c)
+	s.mu.Unlock()
+
+	go s.runCampaign(c)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(snap)
+}
+
+// findCampaign returns the campaign with the given ID. Caller holds s.mu.
+func (s *Server) findCampaign(id string) *Campaign {
+	for _, c := range s.campaigns {
+		if c.ID == id {
+			return c
+		}
+	}
+	return nil
+}
+
+// handleCampaign serves GET /api/campaigns/{id} with the campaign's
+// progress and /api/campaigns/{id}/results with the combined results
+func (s *Server) handleCampaign(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id, view, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/campaigns/"), "/")
+
+	s.mu.RLock()
+	c := s.findCampaign(id)
+	var snap Campaign
+	var runs []*Run
+	if c != nil {
+		snap = s.snapshot(c)
+		for _, item := range c.Items {
+			if item.run != nil {
+				runs = append(runs, item.run)
+			}
+		}
+	}
+	s.mu.RUnlock()
+	if c == nil {
+		http.Error(w, "campaign not found", http.StatusNotFound)
+		return
+	}
+
+	switch view {
+	case "":
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(snap)
+	case "results":
+		if snap.Status == StatusRunning {
+			http.Error(w, "Campaign still running", http.StatusConflict)
+			return
+		}
+		if runs == nil {
+			runs = []*Run{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(CampaignResults{Campaign: snap, Runs: runs})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// handleTest serves POST /api/tests/{id}/continue, the operator's go-ahead
+// for a campaign waiting on a prompt. POST /api/cancel declines it.
+func (s *Server) handleTest(w http.ResponseWriter, r *http.Request) {
+	id, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/tests/"), "/")
+	if action != "continue" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.mu.Lock()
+	c := s.findCampaign(id)
+	if c == nil {
+		s.mu.Unlock()
+		http.Error(w, "campaign not found", http.StatusNotFound)
+		return
+	}
+	if c.resume == nil {
+		s.mu.Unlock()
+		http.Error(w, "Campaign is not waiting for the operator", http.StatusConflict)
+		return
+	}
+	c.resume <- true
+	c.resume = nil
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "continued"})
+}
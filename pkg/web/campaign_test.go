@@ -0,0 +1,262 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func postCampaign(s *Server, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	s.mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/campaigns", strings.NewReader(body)))
+	return w
+}
+
+// waitCampaign polls a campaign until it leaves the running state
+func waitCampaign(t *testing.T, s *Server, id string) Campaign {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		var c Campaign
+		if err := json.NewDecoder(get(s, "/api/campaigns/"+id).Body).Decode(&c); err != nil {
+			t.Fatal(err)
+		}
+		if c.Status != StatusRunning {
+			return c
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("campaign did not finish")
+	return Campaign{}
+}
+
+func TestCampaign(t *testing.T) {
+	s := New(":8080")
+	s.OnStart = func(cfg Config) error {
+		if cfg.FrameSize == 0 {
+			return errors.New("no frame size")
+		}
+		go func() {
+			s.AddResult(TestResult{TestType: "throughput", FrameSize: cfg.FrameSize, Data: map[string]interface{}{"max_rate_pct": 99.0}})
+			s.UpdateStatus(StatusComplete, "Test complete", 100)
+		}()
+		return nil
+	}
+
+	w := postCampaign(s, `{"name":"acceptance","items":[{"interface":"eth0","frame_size":64},{"interface":"eth0"},{"interface":"eth0","frame_size":1518,"test_type":1}]}`)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	var started Campaign
+	if err := json.NewDecoder(w.Body).Decode(&started); err != nil {
+		t.Fatal(err)
+	}
+	if started.ID == "" || started.Name != "acceptance" || len(started.Items) != 3 {
+		t.Fatalf("campaign = %+v", started)
+	}
+
+	c := waitCampaign(t, s, started.ID)
+	if c.Status != StatusComplete || c.Progress != 100 || c.Message != "1 of 3 items failed" {
+		t.Errorf("finished campaign = %+v", c)
+	}
+	want := []string{StatusComplete, StatusError, StatusComplete}
+	for i, item := range c.Items {
+		if item.Status != want[i] {
+			t.Errorf("item %d status = %s, want %s", i, item.Status, want[i])
+		}
+	}
+	if c.Items[0].RunID == "" || c.Items[1].RunID != "" {
+		t.Errorf("run IDs = %q, %q", c.Items[0].RunID, c.Items[1].RunID)
+	}
+
+	var res CampaignResults
+	if err := json.NewDecoder(get(s, "/api/campaigns/"+c.ID+"/results").Body).Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Runs) != 2 || res.Runs[1].Config.FrameSize != 1518 || len(res.Runs[1].TestResults) != 1 {
+		t.Errorf("combined results = %+v", res.Runs)
+	}
+
+	// Each item is archived as its own run
+	if len(s.runs) != 2 {
+		t.Errorf("runs = %d, want 2", len(s.runs))
+	}
+	var list []Campaign
+	json.NewDecoder(get(s, "/api/campaigns").Body).Decode(&list)
+	if len(list) != 1 || list[0].ID != c.ID {
+		t.Errorf("campaign list = %+v", list)
+	}
+}
+
+func TestCampaignCancel(t *testing.T) {
+	s := New(":8080")
+	s.OnStart = func(cfg Config) error {
+		go s.UpdateStatus(StatusCancelled, "Cancelled", 10)
+		return nil
+	}
+
+	w := postCampaign(s, `[{"interface":"eth0"},{"interface":"eth0"},{"interface":"eth0"}]`)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("bare array: status = %d: %s", w.Code, w.Body.String())
+	}
+	var started Campaign
+	json.NewDecoder(w.Body).Decode(&started)
+
+	c := waitCampaign(t, s, started.ID)
+	if c.Status != StatusCancelled {
+		t.Errorf("status = %s, want cancelled", c.Status)
+	}
+	if c.Items[0].Status != StatusCancelled || c.Items[1].Status != StatusSkipped || c.Items[2].Status != StatusSkipped {
+		t.Errorf("items = %+v", c.Items)
+	}
+}
+
+func TestCampaignBusy(t *testing.T) {
+	s := New(":8080")
+	release := make(chan struct{})
+	s.OnStart = func(cfg Config) error {
+		go func() {
+			<-release
+			s.UpdateStatus(StatusComplete, "Test complete", 100)
+		}()
+		return nil
+	}
+
+	w := postCampaign(s, `{"items":[{"interface":"eth0"},{"interface":"eth0"}]}`)
+	var started Campaign
+	json.NewDecoder(w.Body).Decode(&started)
+
+	// One test at a time: neither a second campaign nor a single start
+	if w := postCampaign(s, `[{"interface":"eth0"}]`); w.Code != http.StatusConflict {
+		t.Errorf("second campaign: status = %d, want 409", w.Code)
+	}
+	w = httptest.NewRecorder()
+	s.mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/start", strings.NewReader(`{"interface":"eth0"}`)))
+	if w.Code != http.StatusConflict {
+		t.Errorf("start during campaign: status = %d, want 409", w.Code)
+	}
+	if w := get(s, "/api/campaigns/"+started.ID+"/results"); w.Code != http.StatusConflict {
+		t.Errorf("results while running: status = %d, want 409", w.Code)
+	}
+
+	var c Campaign
+	json.NewDecoder(get(s, "/api/campaigns/"+started.ID).Body).Decode(&c)
+	if c.Status != StatusRunning || c.Progress >= 50 {
+		t.Errorf("running campaign = %+v, want under 50%% progress on the first item", c)
+	}
+
+	close(release)
+	if c := waitCampaign(t, s, started.ID); c.Status != StatusComplete {
+		t.Errorf("status = %s", c.Status)
+	}
+}
+
+func TestCampaignInvalid(t *testing.T) {
+	s := New(":8080")
+	for name, body := range map[string]string{
+		"empty":     `{"items":[]}`,
+		"bad json":  `{"items":`,
+		"bad array": `[1,2]`,
+		"too many":  "[" + strings.TrimSuffix(strings.Repeat(`{},`, maxCampaignItems+1), ",") + "]",
+	} {
+		if w := postCampaign(s, body); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", name, w.Code)
+		}
+	}
+	if w := get(s, "/api/campaigns/unknown"); w.Code != http.StatusNotFound {
+		t.Errorf("unknown campaign: status = %d, want 404", w.Code)
+	}
+	w := httptest.NewRecorder()
+	s.mux.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/campaigns", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("DELETE: status = %d", w.Code)
+	}
+}
+
+// waitCampaignStatus polls a campaign until it reaches status
+func waitCampaignStatus(t *testing.T, s *Server, id, status string) Campaign {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		var c Campaign
+		if err := json.NewDecoder(get(s, "/api/campaigns/"+id).Body).Decode(&c); err != nil {
+			t.Fatal(err)
+		}
+		if c.Status == status {
+			return c
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("campaign did not reach %s", status)
+	return Campaign{}
+}
+
+func post(s *Server, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	s.mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, nil))
+	return w
+}
+
+func TestCampaignPrompt(t *testing.T) {
+	s := New(":8080")
+	started := 0
+	s.OnStart = func(cfg Config) error {
+		started++
+		go s.UpdateStatus(StatusComplete, "Test complete", 100)
+		return nil
+	}
+
+	w := postCampaign(s, `[{"interface":"eth0"},{"interface":"eth0","prompt":"Re-patch to port 2"}]`)
+	var c Campaign
+	json.NewDecoder(w.Body).Decode(&c)
+
+	waiting := waitCampaignStatus(t, s, c.ID, StatusWaiting)
+	if waiting.Prompt != "Re-patch to port 2" || waiting.Current != 1 || waiting.Progress != 50 {
+		t.Errorf("waiting campaign = %+v", waiting)
+	}
+	if waiting.Items[0].Status != StatusComplete || waiting.Items[1].Status != StatusWaiting {
+		t.Errorf("items = %+v", waiting.Items)
+	}
+	w = httptest.NewRecorder()
+	s.mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/start", strings.NewReader(`{"interface":"eth0"}`)))
+	if w.Code != http.StatusConflict {
+		t.Errorf("start while waiting: status = %d, want 409", w.Code)
+	}
+
+	if w := post(s, "/api/tests/"+c.ID+"/continue"); w.Code != http.StatusOK {
+		t.Fatalf("continue: status = %d: %s", w.Code, w.Body.String())
+	}
+	done := waitCampaignStatus(t, s, c.ID, StatusComplete)
+	if done.Prompt != "" || done.Items[1].Status != StatusComplete || started != 2 {
+		t.Errorf("finished campaign = %+v after %d starts", done, started)
+	}
+	if w := post(s, "/api/tests/"+c.ID+"/continue"); w.Code != http.StatusConflict {
+		t.Errorf("continue when not waiting: status = %d, want 409", w.Code)
+	}
+	if w := post(s, "/api/tests/unknown/continue"); w.Code != http.StatusNotFound {
+		t.Errorf("unknown campaign: status = %d, want 404", w.Code)
+	}
+}
+
+func TestCampaignPromptCancel(t *testing.T) {
+	s := New(":8080")
+	s.OnStart = func(cfg Config) error {
+		t.Error("no item should start")
+		return nil
+	}
+
+	w := postCampaign(s, `[{"interface":"eth0","prompt":"Connect port 1"},{"interface":"eth0"}]`)
+	var c Campaign
+	json.NewDecoder(w.Body).Decode(&c)
+	waitCampaignStatus(t, s, c.ID, StatusWaiting)
+
+	post(s, "/api/cancel")
+	done := waitCampaignStatus(t, s, c.ID, StatusCancelled)
+	if done.Items[0].Status != StatusSkipped || done.Items[1].Status != StatusSkipped {
+		t.Errorf("items = %+v", done.Items)
+	}
+}
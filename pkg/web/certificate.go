@@ -0,0 +1,179 @@
+package web
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+
+	"github.com/krisarmstrong/rfc2544-master/pkg/report"
+)
+
+// certificate lays out a completed run as a PDF acceptance certificate.
+// The Web UI keeps results as untyped maps and has no acceptance
+// thresholds, so results are listed per test and only the run's own
+// outcome is judged; use the CLI's -o pdf for a certificate with criteria.
+func certificate(run *Run) (*report.Certificate, error) {
+	data, err := json.Marshal(run)
+	if err != nil {
+		return nil, fmt.Errorf("encode run: %w", err)
+	}
+	digest := sha256.Sum256(data)
+
+	cfg := run.Config
+	c := &report.Certificate{
+		Title:      "Acceptance Test Certificate",
+		Standard:   "RFC 2544 Benchmarking Methodology for Network Interconnect Devices",
+		Generated:  run.Finished,
+		Identity:   []report.Field{{Name: "Run", Value: run.ID}},
+		Digest:     hex.EncodeToString(digest[:]),
+		Signatures: []string{"Tested by", "Accepted by"},
+	}
+	add := func(name, format string, args ...interface{}) {
+		c.Setup = append(c.Setup, report.Field{Name: name, Value: fmt.Sprintf(format, args...)})
+	}
+	add("Interface", "%s", cfg.Interface)
+	if cfg.FrameSize > 0 {
+		add("Frame size", "%d bytes", cfg.FrameSize)
+	} else {
+		add("Frame sizes", "RFC 2544 standard sizes")
+	}
+	if cfg.TrialDuration > 0 {
+		add("Trial duration", "%v", cfg.TrialDuration)
+	}
+	if cfg.LineRateMbps > 0 {
+		add("Line rate", "%d Mbps", cfg.LineRateMbps)
+	}
+	add("Started", "%s", run.Started.Format("2006-01-02 15:04:05 MST"))
+	add("Finished", "%s", run.Finished.Format("2006-01-02 15:04:05 MST"))
+
+	if y := cfg.Y1564; y != nil {
+		c.Title = "Service Activation Certificate"
+		c.Standard = "ITU-T Y.1564 Ethernet service activation test methodology"
+		sla := report.Table{
+			Caption: "Service level agreement",
+			Header:  []string{"Service", "Name", "Frame size", "CIR (Mbps)", "FD max (ms)", "FDV max (ms)", "FLR max %"},
+		}
+		for _, svc := range y.Services {
+			if svc.Enabled {
+				sla.Rows = append(sla.Rows, []string{
+					fmt.Sprintf("%d", svc.ServiceID), svc.ServiceName, fmt.Sprintf("%d", svc.FrameSize), fmt.Sprintf("%.2f", svc.SLA.CIRMbps),
+					fmt.Sprintf("%.2f", svc.SLA.FDThresholdMs), fmt.Sprintf("%.2f", svc.SLA.FDVThresholdMs), fmt.Sprintf("%.4g", svc.SLA.FLRThresholdPct),
+				})
+			}
+		}
+		c.Criteria = []report.Table{sla}
+	}
+
+	// A run that did not complete cannot be accepted
+	status := report.Section{Title: "Run status", Detail: run.Status}
+	if run.Message != "" {
+		status.Detail += ": " + run.Message
+	}
+	if run.Status != StatusComplete {
+		status.Verdict = report.VerdictFail
+	}
+	c.Sections = append(c.Sections, status)
+
+	c.Sections = append(c.Sections, testResultSections(run.TestResults)...)
+	if len(run.Results) > 0 {
+		t := report.Table{Header: []string{"Frame size", "Max rate %", "Mbit/s", "Frames/s", "Loss %", "Latency avg (us)"}}
+		for _, r := range run.Results {
+			t.Rows = append(t.Rows, []string{
+				fmt.Sprintf("%d", r.FrameSize), fmt.Sprintf("%.2f", r.MaxRatePct), fmt.Sprintf("%.2f", r.MaxRateMbps),
+				fmt.Sprintf("%.0f", r.MaxRatePps), fmt.Sprintf("%.4f", r.LossPct), fmt.Sprintf("%.2f", r.LatencyAvgNs/1000),
+			})
+		}
+		c.Sections = append(c.Sections, report.Section{Title: "Throughput", Tables: []report.Table{t}})
+	}
+	return c, nil
+}
+
+// testResultSections tabulates results per test, in the order the tests
+// ran, with a column per data field
+func testResultSections(results []TestResult) []report.Section {
+	var order []string
+	byTest := map[string][]TestResult{}
+	for _, r := range results {
+		if _, ok := byTest[r.TestType]; !ok {
+			order = append(order, r.TestType)
+		}
+		byTest[r.TestType] = append(byTest[r.TestType], r)
+	}
+
+	var sections []report.Section
+	for _, test := range order {
+		seen := map[string]bool{}
+		var keys []string
+		for _, r := range byTest[test] {
+			for k := range r.Data {
+				if !seen[k] {
+					seen[k] = true
+					keys = append(keys, k)
+				}
+			}
+		}
+		sort.Strings(keys)
+
+		t := report.Table{Header: append([]string{"frame_size"}, keys...)}
+		for _, r := range byTest[test] {
+			row := []string{fmt.Sprintf("%d", r.FrameSize)}
+			for _, k := range keys {
+				row = append(row, formatCell(r.Data[k]))
+			}
+			t.Rows = append(t.Rows, row)
+		}
+		sections = append(sections, report.Section{Title: test, Tables: []report.Table{t}})
+	}
+	return sections
+}
+
+// formatCell prints a result value, with fractions to two places
+func formatCell(v interface{}) string {
+	switch n := v.(type) {
+	case nil:
+		return "-"
+	case float64:
+		if n == math.Trunc(n) && math.Abs(n) < 1e15 {
+			return fmt.Sprintf("%.0f", n)
+		}
+		return fmt.Sprintf("%.2f", n)
+	}
+	return fmt.Sprint(v)
+}
+
+// writeCertificate sends a run's certificate as a PDF download
+func writeCertificate(w http.ResponseWriter, run *Run) {
+	cert, err := certificate(run)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var buf bytes.Buffer
+	if err := cert.Write(&buf); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="certificate-%s.pdf"`, run.ID))
+	w.Write(buf.Bytes())
+}
+
+// handleReport serves GET /api/report.pdf?run_id=ID, the certificate for a
+// completed run (empty ID = latest)
+func (s *Server) handleReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	run := s.findRun(r.URL.Query().Get("run_id"))
+	if run == nil {
+		http.Error(w, "run not found", http.StatusNotFound)
+		return
+	}
+	writeCertificate(w, run)
+}
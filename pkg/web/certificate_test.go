@@ -0,0 +1,86 @@
+package web
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestHandleReport(t *testing.T) {
+	s := New(":8080")
+	if w := get(s, "/api/report.pdf"); w.Code != http.StatusNotFound {
+		t.Errorf("no runs: status = %d, want 404", w.Code)
+	}
+
+	completeRun(t, s)
+	w := get(s, "/api/report.pdf")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/pdf" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if cd := w.Header().Get("Content-Disposition"); !strings.HasPrefix(cd, "attachment;") || !strings.Contains(cd, s.runs[0].ID) {
+		t.Errorf("Content-Disposition = %q", cd)
+	}
+	pdf := w.Body.Bytes()
+	if !bytes.HasPrefix(pdf, []byte("%PDF-")) {
+		t.Fatal("body is not a PDF")
+	}
+	for _, want := range []string{"(throughput)", "(max_rate_pct)", "(99.50)", "(eth0)"} {
+		if !bytes.Contains(pdf, []byte(want)) {
+			t.Errorf("certificate missing %s", want)
+		}
+	}
+
+	if w := get(s, "/api/report.pdf?run_id="+s.runs[0].ID); w.Code != http.StatusOK {
+		t.Errorf("by ID: status = %d", w.Code)
+	}
+	if w := get(s, "/api/report.pdf?run_id=0000000000000000"); w.Code != http.StatusNotFound {
+		t.Errorf("unknown run: status = %d, want 404", w.Code)
+	}
+	w = httptest.NewRecorder()
+	s.mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/report.pdf", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST: status = %d", w.Code)
+	}
+}
+
+func TestCertificateFailedRun(t *testing.T) {
+	s := New(":8080")
+	s.OnStart = func(cfg Config) error { return nil }
+	s.mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/start", strings.NewReader(`{"interface":"eth0"}`)))
+	s.UpdateStatus(StatusError, "link down", 0)
+
+	cert, err := certificate(s.findRun(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := cert.Verdict(); v.String() != "FAIL" {
+		t.Errorf("incomplete run verdict = %q, want FAIL", v)
+	}
+	if d := cert.Sections[0].Detail; d != "error: link down" {
+		t.Errorf("status detail = %q", d)
+	}
+}
+
+func TestSharedCertificate(t *testing.T) {
+	s := New(":8080")
+	completeRun(t, s)
+	_, link := createShare(t, s, "")
+	u, _ := url.Parse(link.URL)
+
+	if w := get(s, u.RequestURI()); !strings.Contains(w.Body.String(), link.RunID+"/report.pdf?exp=") {
+		t.Error("shared report should link to the certificate")
+	}
+	w := get(s, u.Path+"/report.pdf?"+u.RawQuery)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/pdf" {
+		t.Errorf("shared certificate: status = %d, type %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	if w := get(s, u.Path+"/report.pdf"); w.Code != http.StatusForbidden {
+		t.Errorf("unsigned certificate: status = %d, want 403", w.Code)
+	}
+}
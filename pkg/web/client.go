@@ -0,0 +1,143 @@
+package web
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// maxEventSize bounds one event on the stream; results are the largest
+const maxEventSize = 1 << 20
+
+// Client watches and controls a running instance through its API, e.g.
+// for rfc2544 attach
+type Client struct {
+	base string
+	http *http.Client
+}
+
+// NewClient returns a client for the instance at baseURL, e.g.
+// http://tester:8080. A missing scheme means http.
+func NewClient(baseURL string) *Client {
+	if !strings.Contains(baseURL, "://") {
+		baseURL = "http://" + baseURL
+	}
+	return &Client{base: strings.TrimRight(baseURL, "/"), http: &http.Client{}}
+}
+
+// URL returns the instance's base URL
+func (c *Client) URL() string {
+	return c.base
+}
+
+// Events opens the event stream. The channel is closed when the stream
+// ends: on ctx, or when the instance goes away.
+func (c *Client) Events(ctx context.Context) (<-chan Event, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.base+"/api/events", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, responseError(resp)
+	}
+
+	events := make(chan Event)
+	go func() {
+		defer close(events)
+		defer resp.Body.Close()
+		sc := bufio.NewScanner(resp.Body)
+		sc.Buffer(make([]byte, 64*1024), maxEventSize)
+		var data []byte
+		for sc.Scan() {
+			line := sc.Text()
+			switch {
+			case strings.HasPrefix(line, "data:"):
+				data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " ")...)
+			case line == "" && len(data) > 0:
+				var ev Event
+				if json.Unmarshal(data, &ev) == nil {
+					select {
+					case events <- ev:
+					case <-ctx.Done():
+						return
+					}
+				}
+				data = data[:0]
+			}
+		}
+	}()
+	return events, nil
+}
+
+// Config returns the settings of the instance's current or last test
+func (c *Client) Config(ctx context.Context) (Config, error) {
+	var cfg Config
+	err := c.do(ctx, http.MethodGet, "/api/config", nil, &cfg)
+	return cfg, err
+}
+
+// Start starts a test; settings left unset come from the instance's
+// configuration
+func (c *Client) Start(ctx context.Context, cfg Config) error {
+	return c.do(ctx, http.MethodPost, "/api/start", cfg, nil)
+}
+
+// Stop stops the running test and waits for it to finish
+func (c *Client) Stop(ctx context.Context) error {
+	return c.do(ctx, http.MethodPost, "/api/stop", nil, nil)
+}
+
+// Cancel cancels the running test or campaign
+func (c *Client) Cancel(ctx context.Context) error {
+	return c.do(ctx, http.MethodPost, "/api/cancel", nil, nil)
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.base+path, r)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return responseError(resp)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// responseError reports a failed request with the server's message
+func responseError(resp *http.Response) error {
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	if text := strings.TrimSpace(string(msg)); text != "" {
+		return fmt.Errorf("%s: %s", resp.Status, text)
+	}
+	return fmt.Errorf("%s", resp.Status)
+}
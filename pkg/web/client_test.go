@@ -0,0 +1,100 @@
+package web
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClientEvents(t *testing.T) {
+	s := New(":8080")
+	s.UpdateStats(Stats{TestType: "throughput", FrameSize: 64, TxPackets: 10})
+	ts := httptest.NewServer(s.mux)
+	defer ts.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c := NewClient(strings.TrimPrefix(ts.URL, "http://"))
+	events, err := c.Events(ctx)
+	if err != nil {
+		t.Fatalf("Events: %v", err)
+	}
+	next := func() Event {
+		t.Helper()
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				t.Fatal("stream closed")
+			}
+			return ev
+		case <-ctx.Done():
+			t.Fatal("no event")
+		}
+		return Event{}
+	}
+
+	// A client that attaches mid-run is caught up first
+	if ev := next(); ev.Type != EventStatus || ev.Status != StatusIdle {
+		t.Errorf("first event %+v", ev)
+	}
+	if ev := next(); ev.Type != EventStats || ev.Stats == nil || ev.Stats.TxPackets != 10 {
+		t.Errorf("second event %+v", ev)
+	}
+
+	s.UpdateStatus(StatusRunning, "Testing 64 byte frames", 25)
+	if ev := next(); ev.Type != EventStatus || ev.Status != StatusRunning || ev.Message != "Testing 64 byte frames" || ev.Progress != 25 {
+		t.Errorf("status event %+v", ev)
+	}
+	s.UpdateStats(Stats{TestType: "throughput", FrameSize: 64, TxPackets: 20})
+	if ev := next(); ev.Type != EventStats || ev.Stats.TxPackets != 20 {
+		t.Errorf("stats event %+v", ev)
+	}
+	s.AddResult(TestResult{TestType: "throughput", FrameSize: 64, Data: map[string]interface{}{"max_rate_pct": 99.5}})
+	if ev := next(); ev.Type != EventResult || ev.Result == nil || ev.Result.Data["max_rate_pct"] != 99.5 {
+		t.Errorf("result event %+v", ev)
+	}
+
+	cancel()
+	for range events {
+	}
+}
+
+func TestClientControl(t *testing.T) {
+	s := New(":8080")
+	var started Config
+	stopped := false
+	s.OnStart = func(cfg Config) error {
+		started = cfg
+		return nil
+	}
+	s.OnStop = func() error {
+		stopped = true
+		return nil
+	}
+	ts := httptest.NewServer(s.mux)
+	defer ts.Close()
+
+	ctx := context.Background()
+	c := NewClient(ts.URL + "/")
+	if err := c.Start(ctx, Config{Interface: "eth1", FrameSize: 512}); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if started.Interface != "eth1" || started.FrameSize != 512 {
+		t.Errorf("started with %+v", started)
+	}
+	cfg, err := c.Config(ctx)
+	if err != nil || cfg.Interface != "eth1" {
+		t.Errorf("Config = %+v, %v", cfg, err)
+	}
+	if err := c.Stop(ctx); err != nil || !stopped {
+		t.Errorf("Stop: %v (stopped %v)", err, stopped)
+	}
+
+	s.OnStart = func(Config) error { return context.DeadlineExceeded }
+	err = c.Start(ctx, Config{})
+	if err == nil || !strings.Contains(err.Error(), "Start failed") {
+		t.Errorf("failed start: %v", err)
+	}
+}
@@ -0,0 +1,61 @@
+package web
+
+// Endpoint describes one route of the web API, from which the Python and
+// TypeScript clients are generated. Path parameters are written {name}.
+type Endpoint struct {
+	Name     string      // Client method, snake_case, e.g. "campaign_results"
+	Method   string      // GET or POST
+	Path     string      // e.g. "/api/campaigns/{id}/results"
+	Query    []string    // Optional query parameters
+	Doc      string      // One-line description
+	Request  interface{} // Request body: a JSON message, a string sent as YAML, or nil
+	Response interface{} // JSON response, or nil for a binary one
+	Stream   bool        // Server-Sent Events of Response
+}
+
+// Endpoints lists the web API served by Server
+func Endpoints() []Endpoint {
+	return []Endpoint{
+		{Name: "health", Method: "GET", Path: "/api/health", Doc: "Liveness, server time and the versions of the server and its dataplane",
+			Response: map[string]interface{}{}},
+		{Name: "capabilities", Method: "GET", Path: "/api/capabilities", Query: []string{"interface"}, Doc: "Test types, frame sizes and timestamping the dataplane supports on an interface",
+			Response: Capabilities{}},
+		{Name: "stats", Method: "GET", Path: "/api/stats", Doc: "Live statistics of the running test",
+			Response: Stats{}},
+		{Name: "results", Method: "GET", Path: "/api/results", Doc: "Results of the current or last test",
+			Response: []Result{}},
+		{Name: "config", Method: "GET", Path: "/api/config", Doc: "Settings of the current or last test",
+			Response: Config{}},
+		{Name: "reload_config", Method: "POST", Path: "/api/config", Doc: "Replace the instance's YAML config; returns the top-level keys that changed",
+			Request: "", Response: ReloadResponse{}},
+		{Name: "start", Method: "POST", Path: "/api/start", Doc: "Start a test; settings left unset come from the instance's config. On an in-service interface it needs acknowledge_impact, else 428 gives the traffic it would send",
+			Request: Config{}, Response: map[string]string{}},
+		{Name: "soak_rate", Method: "POST", Path: "/api/soak/rate", Doc: "Retune the running soak's offered load from its next sample; 409 when no soak runs",
+			Request: SoakRate{}, Response: SoakRate{}},
+		{Name: "stop", Method: "POST", Path: "/api/stop", Doc: "Stop the running test and wait for it to finish",
+			Response: map[string]string{}},
+		{Name: "cancel", Method: "POST", Path: "/api/cancel", Doc: "Cancel the running test or campaign",
+			Response: map[string]string{}},
+		{Name: "events", Method: "GET", Path: "/api/events", Doc: "Stream of live stats, status changes and results",
+			Response: Event{}, Stream: true},
+		{Name: "schemas", Method: "GET", Path: "/api/schema", Doc: "Names of the published JSON Schemas",
+			Response: []string{}},
+		{Name: "schema", Method: "GET", Path: "/api/schema/{name}", Doc: "One JSON Schema by name, e.g. web-config",
+			Response: map[string]interface{}{}},
+		{Name: "runs", Method: "GET", Path: "/api/runs", Doc: "Recent runs, newest first, without their results",
+			Response: []RunSummary{}},
+		{Name: "share", Method: "POST", Path: "/api/share", Doc: "Signed read-only link to a run's report",
+			Request: ShareRequest{}, Response: ShareLink{}},
+		{Name: "report", Method: "GET", Path: "/api/report.pdf", Query: []string{"run_id"}, Doc: "Acceptance certificate of a run as PDF"},
+		{Name: "campaigns", Method: "GET", Path: "/api/campaigns", Doc: "Recent campaigns, newest first",
+			Response: []Campaign{}},
+		{Name: "start_campaign", Method: "POST", Path: "/api/campaigns", Doc: "Queue a batch of tests run one after another",
+			Request: CampaignRequest{}, Response: Campaign{}},
+		{Name: "campaign", Method: "GET", Path: "/api/campaigns/{id}", Doc: "Progress of a campaign",
+			Response: Campaign{}},
+		{Name: "campaign_results", Method: "GET", Path: "/api/campaigns/{id}/results", Doc: "Combined results of a finished campaign",
+			Response: CampaignResults{}},
+		{Name: "continue_campaign", Method: "POST", Path: "/api/tests/{id}/continue", Doc: "Answer a campaign's operator prompt and run its next test",
+			Response: map[string]string{}},
+	}
+}
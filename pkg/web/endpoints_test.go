@@ -0,0 +1,28 @@
+package web
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestEndpointsRouted(t *testing.T) {
+	s := New(":8080")
+	names := make(map[string]bool)
+	for _, e := range Endpoints() {
+		if names[e.Name] {
+			t.Errorf("%s listed twice", e.Name)
+		}
+		names[e.Name] = true
+
+		path := e.Path
+		for strings.Contains(path, "{") {
+			open, end := strings.Index(path, "{"), strings.Index(path, "}")
+			path = path[:open] + "x" + path[end+1:]
+		}
+		req := httptest.NewRequest(e.Method, path, nil)
+		if _, pattern := s.mux.Handler(req); pattern == "" || pattern == "/" {
+			t.Errorf("%s %s is not routed to the API", e.Method, e.Path)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Event types sent on GET /api/events
+const (
+	EventStats  = "stats"
+	EventStatus = "status"
+	EventResult = "result"
+)
+
+// eventBuffer is how many events a slow client may fall behind before it
+// misses some; stats are resent every snapshot, so only a burst is lost
+const eventBuffer = 64
+
+// keepaliveInterval is how often an idle event stream sends a comment, so
+// clients and proxies see the connection is alive
+const keepaliveInterval = 15 * time.Second
+
+// Event is one message on the /api/events stream: live stats, a status
+// change, or a test result
+type Event struct {
+	Type     string      `json:"type"`
+	Stats    *Stats      `json:"stats,omitempty"`
+	Status   string      `json:"status,omitempty"`
+	Message  string      `json:"message,omitempty"`
+	Progress float64     `json:"progress"`
+	Result   *TestResult `json:"result,omitempty"`
+}
+
+// publish sends an event to every /api/events client
+func (s *Server) publish(ev Event) {
+	s.eventsMu.Lock()
+	defer s.eventsMu.Unlock()
+	for ch := range s.events {
+		select {
+		case ch <- ev:
+		default: // Client too slow; drop rather than stall the test
+		}
+	}
+}
+
+func (s *Server) subscribe() chan Event {
+	ch := make(chan Event, eventBuffer)
+	s.eventsMu.Lock()
+	if s.events == nil {
+		s.events = make(map[chan Event]struct{})
+	}
+	s.events[ch] = struct{}{}
+	s.eventsMu.Unlock()
+	return ch
+}
+
+func (s *Server) unsubscribe(ch chan Event) {
+	s.eventsMu.Lock()
+	delete(s.events, ch)
+	s.eventsMu.Unlock()
+}
+
+// handleEvents streams events as Server-Sent Events, starting with the
+// current status and stats so a client that attaches mid-run is caught up
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	// The stream outlives the server's write timeout
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	ch := s.subscribe()
+	defer s.unsubscribe(ch)
+
+	s.mu.RLock()
+	stats := s.stats
+	status := Event{Type: EventStatus, Status: s.status, Message: s.statusMsg, Progress: s.progress}
+	s.mu.RUnlock()
+	if status.Status == "" {
+		status.Status = StatusIdle
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	send := func(ev Event) error {
+		data, err := json.Marshal(ev)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Type, data); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+	if send(status) != nil || send(Event{Type: EventStats, Stats: &stats, Progress: stats.Progress}) != nil {
+		return
+	}
+
+	keepalive := time.NewTicker(keepaliveInterval)
+	defer keepalive.Stop()
+	for {
+		select {
+		case ev := <-ch:
+			if send(ev) != nil {
+				return
+			}
+		case <-keepalive.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
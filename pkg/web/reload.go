@@ -0,0 +1,55 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// ErrReloadRefused marks an OnReload error for a change the daemon cannot
+// apply now, such as a new interface while a test runs. The request is
+// answered with 409 Conflict; other errors are the client's, with 400.
+var ErrReloadRefused = errors.New("reload refused")
+
+// maxConfigBytes bounds a POST /api/config body
+const maxConfigBytes = 1 << 20
+
+// ReloadResponse reports the top-level config keys a reload changed
+type ReloadResponse struct {
+	Status  string   `json:"status"`
+	Changed []string `json:"changed"`
+}
+
+func (s *Server) handleReload(w http.ResponseWriter, r *http.Request) {
+	if s.OnReload == nil {
+		http.Error(w, "Config reload not supported", http.StatusNotImplemented)
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxConfigBytes))
+	if err != nil {
+		http.Error(w, "Config too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if len(data) == 0 {
+		http.Error(w, "Empty config", http.StatusBadRequest)
+		return
+	}
+
+	changed, err := s.OnReload(data)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, ErrReloadRefused) {
+			status = http.StatusConflict
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	if changed == nil {
+		changed = []string{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ReloadResponse{Status: "reloaded", Changed: changed})
+}
@@ -0,0 +1,96 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func postConfig(s *Server, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	s.mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/config", strings.NewReader(body)))
+	return w
+}
+
+func TestConfigReload(t *testing.T) {
+	s := New(":8080")
+	if w := postConfig(s, "trial_duration: 5s\n"); w.Code != http.StatusNotImplemented {
+		t.Errorf("without OnReload: status %d", w.Code)
+	}
+
+	var got string
+	s.OnReload = func(data []byte) ([]string, error) {
+		got = string(data)
+		switch {
+		case strings.Contains(got, "interface"):
+			return nil, fmt.Errorf("%w: interface cannot change while a test is running", ErrReloadRefused)
+		case strings.Contains(got, "["):
+			return nil, fmt.Errorf("parse config: bad YAML")
+		}
+		return []string{"thresholds"}, nil
+	}
+
+	w := postConfig(s, "thresholds:\n  min_throughput_pct: 95\n")
+	if w.Code != http.StatusOK {
+		t.Fatalf("reload status = %d: %s", w.Code, w.Body)
+	}
+	var resp ReloadResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status != "reloaded" || !reflect.DeepEqual(resp.Changed, []string{"thresholds"}) {
+		t.Errorf("response = %+v", resp)
+	}
+	if !strings.Contains(got, "min_throughput_pct") {
+		t.Errorf("OnReload got %q", got)
+	}
+
+	if w := postConfig(s, "interface: eth1\n"); w.Code != http.StatusConflict {
+		t.Errorf("refused reload: status %d", w.Code)
+	}
+	if w := postConfig(s, "frame_sizes: [\n"); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid config: status %d", w.Code)
+	}
+	if w := postConfig(s, ""); w.Code != http.StatusBadRequest {
+		t.Errorf("empty body: status %d", w.Code)
+	}
+	if w := postConfig(s, strings.Repeat("#", maxConfigBytes+1)); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("oversized body: status %d", w.Code)
+	}
+
+	// GET still returns the last test configuration
+	if w := get(s, "/api/config"); w.Code != http.StatusOK {
+		t.Errorf("GET status = %d", w.Code)
+	}
+}
+
+func TestStartDefaults(t *testing.T) {
+	s := New(":8080")
+	var started Config
+	s.OnStart = func(cfg Config) error {
+		started = cfg
+		return nil
+	}
+	s.Defaults = func(cfg *Config) {
+		if cfg.TrialDuration == 0 {
+			cfg.TrialDuration = 5 * time.Second
+		}
+	}
+
+	w := httptest.NewRecorder()
+	s.mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/start", strings.NewReader(`{"interface":"eth0"}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("start status = %d", w.Code)
+	}
+	if started.TrialDuration != 5*time.Second {
+		t.Errorf("OnStart trial duration = %v", started.TrialDuration)
+	}
+	if run := s.current; run == nil || run.Config.TrialDuration != 5*time.Second {
+		t.Error("the run should record the configuration with defaults applied")
+	}
+}
@@ -0,0 +1,353 @@
+package web
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Share link lifetimes
+const (
+	DefaultShareTTL = 24 * time.Hour
+	MaxShareTTL     = 30 * 24 * time.Hour
+)
+
+// maxRuns bounds the completed runs kept for sharing
+const maxRuns = 100
+
+// Run is a completed test run with its results
+type Run struct {
+	ID          string       `json:"id"`
+	Config      Config       `json:"config"`
+	Status      string       `json:"status"`
+	Message     string       `json:"message,omitempty"`
+	Started     time.Time    `json:"started"`
+	Finished    time.Time    `json:"finished,omitempty"`
+	Results     []Result     `json:"results"`
+	TestResults []TestResult `json:"test_results"`
+}
+
+// RunSummary lists a run without its results
+type RunSummary struct {
+	ID       string    `json:"id"`
+	TestType int       `json:"test_type"`
+	Status   string    `json:"status"`
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	Results  int       `json:"results"`
+}
+
+// ShareRequest is the body of POST /api/share
+type ShareRequest struct {
+	RunID string `json:"run_id"` // Empty = latest completed run
+	TTL   string `json:"ttl"`    // Link lifetime, e.g. "72h" (empty = 24h)
+}
+
+// ShareLink is a signed read-only URL for one run
+type ShareLink struct {
+	RunID     string    `json:"run_id"`
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// WithShareSecret sets the key signing share links. Without it a random key
+// is generated, so links stop working when the server restarts.
+func WithShareSecret(secret []byte) Option {
+	return func(s *Server) {
+		s.shareSecret = secret
+	}
+}
+
+// WithPublicURL sets the base URL used in share links (e.g. behind a
+// reverse proxy). By default the request's host is used.
+func WithPublicURL(base string) Option {
+	return func(s *Server) {
+		s.publicURL = strings.TrimSuffix(base, "/")
+	}
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// beginRun opens a run record for a test started with cfg
+func (s *Server) beginRun(cfg Config) {
+	s.current = &Run{ID: randomHex(8), Config: cfg, Status: StatusRunning, Started: time.Now()}
+}
+
+// finishRun archives the open run with its results. Caller holds s.mu.
+func (s *Server) finishRun(status, message string) {
+	run := s.current
+	if run == nil {
+		return
+	}
+	s.current = nil
+	run.Status = status
+	run.Message = message
+	run.Finished = time.Now()
+	run.Results = append([]Result(nil), s.results...)
+	run.TestResults = append([]TestResult(nil), s.testResults...)
+
+	s.archive(run)
+	if s.runDone != nil {
+		s.runDone <- run // Buffered; a campaign waits on it
+		s.runDone = nil
+	}
+}
+
+// archive keeps a completed run, dropping the oldest past maxRuns. Caller
+// holds s.mu.
+func (s *Server) archive(run *Run) {
+	s.runs = append(s.runs, run)
+	if len(s.runs) > maxRuns {
+		s.runs = s.runs[len(s.runs)-maxRuns:]
+	}
+}
+
+// ImportRun archives a run completed elsewhere, e.g. saved or sample
+// results, and shows its results as the latest. A run without an ID is
+// given one, which is returned.
+func (s *Server) ImportRun(run Run) string {
+	if run.ID == "" {
+		run.ID = randomHex(8)
+	}
+	if run.Status == "" {
+		run.Status = StatusComplete
+	}
+	s.mu.Lock()
+	s.config = run.Config
+	s.results = append(s.results[:0], run.Results...)
+	s.testResults = append(s.testResults[:0], run.TestResults...)
+	s.status = run.Status
+	s.statusMsg = run.Message
+	s.progress = 100
+	s.stats.State = run.Status
+	s.stats.Progress = 100
+	s.archive(&run)
+	s.mu.Unlock()
+	return run.ID
+}
+
+// findRun returns a completed run by ID, or the latest for an empty ID
+func (s *Server) findRun(id string) *Run {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if id == "" {
+		if len(s.runs) == 0 {
+			return nil
+		}
+		return s.runs[len(s.runs)-1]
+	}
+	for _, r := range s.runs {
+		if r.ID == id {
+			return r
+		}
+	}
+	return nil
+}
+
+// sign returns the signature binding a run ID to an expiry
+func (s *Server) sign(id string, expires int64) string {
+	mac := hmac.New(sha256.New, s.shareSecret)
+	fmt.Fprintf(mac, "%s\n%d", id, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ShareURL creates a signed link to a completed run, valid for ttl
+func (s *Server) ShareURL(base, runID string, ttl time.Duration) (ShareLink, error) {
+	if ttl <= 0 {
+		ttl = DefaultShareTTL
+	}
+	if ttl > MaxShareTTL {
+		return ShareLink{}, fmt.Errorf("ttl exceeds maximum of %v", MaxShareTTL)
+	}
+	run := s.findRun(runID)
+	if run == nil {
+		return ShareLink{}, fmt.Errorf("run not found")
+	}
+	expires := time.Now().Add(ttl).Truncate(time.Second)
+	exp := expires.Unix()
+	url := fmt.Sprintf("%s/share/%s?exp=%d&sig=%s", base, run.ID, exp, s.sign(run.ID, exp))
+	return ShareLink{RunID: run.ID, URL: url, ExpiresAt: expires}, nil
+}
+
+func (s *Server) handleRuns(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.mu.RLock()
+	runs := make([]RunSummary, 0, len(s.runs))
+	for _, run := range s.runs {
+		runs = append(runs, RunSummary{
+			ID:       run.ID,
+			TestType: run.Config.TestType,
+			Status:   run.Status,
+			Started:  run.Started,
+			Finished: run.Finished,
+			Results:  len(run.Results) + len(run.TestResults),
+		})
+	}
+	s.mu.RUnlock()
+
+	// Newest first
+	sort.SliceStable(runs, func(i, j int) bool { return runs[i].Started.After(runs[j].Started) })
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(runs)
+}
+
+func (s *Server) handleShare(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req ShareRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+	var ttl time.Duration
+	if req.TTL != "" {
+		var err error
+		if ttl, err = time.ParseDuration(req.TTL); err != nil || ttl <= 0 {
+			http.Error(w, "Invalid ttl", http.StatusBadRequest)
+			return
+		}
+	}
+
+	base := s.publicURL
+	if base == "" {
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+		base = scheme + "://" + r.Host
+	}
+	link, err := s.ShareURL(base, req.RunID, ttl)
+	if err != nil {
+		status := http.StatusBadRequest
+		if s.findRun(req.RunID) == nil {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(link)
+}
+
+// handleShared serves a run read-only to holders of a valid link:
+// /share/{id} renders a report, /share/{id}/results.json the raw results
+// and /share/{id}/report.pdf the acceptance certificate
+func (s *Server) handleShared(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/share/")
+	id, view, _ := strings.Cut(path, "/")
+	exp, err := strconv.ParseInt(r.URL.Query().Get("exp"), 10, 64)
+	sig := r.URL.Query().Get("sig")
+
+	// The same response for every failure avoids revealing which runs exist
+	if err != nil || !hmac.Equal([]byte(sig), []byte(s.sign(id, exp))) {
+		http.Error(w, "Invalid or expired link", http.StatusForbidden)
+		return
+	}
+	if time.Now().Unix() > exp {
+		http.Error(w, "Invalid or expired link", http.StatusForbidden)
+		return
+	}
+	run := s.findRun(id)
+	if run == nil || id == "" {
+		http.Error(w, "Invalid or expired link", http.StatusForbidden)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.Header().Set("X-Robots-Tag", "noindex")
+	switch view {
+	case "results.json":
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(run)
+	case "report.pdf":
+		writeCertificate(w, run)
+	case "":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		sharedReport.Execute(w, struct {
+			Run     *Run
+			Exp     int64
+			Sig     string
+			Expires time.Time
+		}{run, exp, sig, time.Unix(exp, 0).UTC()})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+var sharedReport = template.Must(template.New("share").Funcs(template.FuncMap{
+	"keys": func(m map[string]interface{}) []string {
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		return keys
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+    <title>RFC2544 Test Master - Run {{.Run.ID}}</title>
+    <style>
+        body { font-family: system-ui, sans-serif; background: #1a1a2e; color: #eee; margin: 40px; }
+        h1 { color: #0f0; }
+        .card { background: #16213e; padding: 20px; border-radius: 8px; margin: 10px 0; }
+        table { border-collapse: collapse; }
+        th, td { padding: 4px 12px; text-align: left; border-bottom: 1px solid #333; }
+        a { color: #4da6ff; }
+    </style>
+</head>
+<body>
+    <h1>RFC2544 Test Master</h1>
+    <div class="card">
+        <p>Run <b>{{.Run.ID}}</b> on {{.Run.Config.Interface}}: {{.Run.Status}}{{if .Run.Message}} ({{.Run.Message}}){{end}}</p>
+        <p>Started {{.Run.Started.Format "2006-01-02 15:04:05 MST"}}, finished {{.Run.Finished.Format "2006-01-02 15:04:05 MST"}}</p>
+        <p>Read-only link valid until {{.Expires.Format "2006-01-02 15:04 MST"}}. <a href="{{.Run.ID}}/results.json?exp={{.Exp}}&sig={{.Sig}}">Download results (JSON)</a> | <a href="{{.Run.ID}}/report.pdf?exp={{.Exp}}&sig={{.Sig}}">Certificate (PDF)</a></p>
+    </div>
+    {{range .Run.TestResults}}
+    <div class="card">
+        <h3>{{.TestType}}{{if .FrameSize}} - {{.FrameSize}} bytes{{end}}</h3>
+        <table>{{$data := .Data}}{{range keys $data}}<tr><th>{{.}}</th><td>{{index $data .}}</td></tr>{{end}}</table>
+    </div>
+    {{end}}
+    {{if .Run.Results}}
+    <div class="card">
+        <table>
+            <tr><th>Frame</th><th>Max rate %</th><th>Mbps</th><th>PPS</th><th>Loss %</th><th>Avg latency (ns)</th></tr>
+            {{range .Run.Results}}<tr><td>{{.FrameSize}}</td><td>{{printf "%.2f" .MaxRatePct}}</td><td>{{printf "%.2f" .MaxRateMbps}}</td><td>{{printf "%.0f" .MaxRatePps}}</td><td>{{printf "%.4f" .LossPct}}</td><td>{{printf "%.0f" .LatencyAvgNs}}</td></tr>
+            {{end}}
+        </table>
+    </div>
+    {{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,239 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/krisarmstrong/rfc2544-master/pkg/demo"
+)
+
+// completeRun starts and completes a run with one result
+func completeRun(t *testing.T, s *Server) {
+	t.Helper()
+	s.OnStart = func(cfg Config) error { return nil }
+	req := httptest.NewRequest(http.MethodPost, "/api/start", strings.NewReader(`{"interface":"eth0","test_type":0}`))
+	w := httptest.NewRecorder()
+	s.mux.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("start status = %d", w.Code)
+	}
+	s.AddResult(TestResult{TestType: "throughput", FrameSize: 64, Data: map[string]interface{}{"max_rate_pct": 99.5}})
+	s.UpdateStatus(StatusComplete, "Test complete", 100)
+}
+
+func createShare(t *testing.T, s *Server, body string) (*httptest.ResponseRecorder, ShareLink) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "http://tester.example:8080/api/share", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	s.mux.ServeHTTP(w, req)
+	var link ShareLink
+	if w.Code == http.StatusOK {
+		if err := json.NewDecoder(w.Body).Decode(&link); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return w, link
+}
+
+func get(s *Server, target string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	s.mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+	return w
+}
+
+func TestRunArchivedOnCompletion(t *testing.T) {
+	s := New(":8080")
+	completeRun(t, s)
+
+	w := get(s, "/api/runs")
+	var runs []RunSummary
+	if err := json.NewDecoder(w.Body).Decode(&runs); err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 1 || runs[0].Status != StatusComplete || runs[0].Results != 1 {
+		t.Fatalf("runs = %+v", runs)
+	}
+
+	// A second terminal status must not archive the run twice
+	s.UpdateStatus(StatusComplete, "Test complete", 100)
+	if len(s.runs) != 1 {
+		t.Errorf("runs = %d, want 1", len(s.runs))
+	}
+}
+
+func TestImportRun(t *testing.T) {
+	s := New(":8080")
+	id := s.ImportRun(Run{
+		Config:      Config{Interface: "eth1"},
+		Started:     time.Date(2026, 3, 2, 9, 15, 0, 0, time.UTC),
+		Results:     []Result{{FrameSize: 64, MaxRatePct: 97.5}},
+		TestResults: []TestResult{{TestType: "throughput", FrameSize: 64}},
+	})
+
+	var runs []RunSummary
+	json.NewDecoder(get(s, "/api/runs").Body).Decode(&runs)
+	if len(runs) != 1 || runs[0].ID != id || id == "" || runs[0].Status != StatusComplete || runs[0].Results != 2 {
+		t.Fatalf("runs = %+v", runs)
+	}
+	var results []Result
+	json.NewDecoder(get(s, "/api/results").Body).Decode(&results)
+	if len(results) != 1 || results[0].MaxRatePct != 97.5 {
+		t.Errorf("results = %+v", results)
+	}
+	if w, _ := createShare(t, s, `{}`); w.Code != http.StatusOK {
+		t.Errorf("share status = %d", w.Code)
+	}
+}
+
+// TestImportDemoRuns loads the bundled sample runs as the demo command
+// does, the passing run last so it shows as the latest
+func TestImportDemoRuns(t *testing.T) {
+	samples, err := demo.Load(func(data []byte) (Run, error) {
+		var doc struct {
+			Metadata struct {
+				Started time.Time `json:"started"`
+			} `json:"metadata"`
+			Results []struct {
+				FrameSize  uint32 `json:"frame_size"`
+				Throughput *struct {
+					MaxRatePct  float64
+					MaxRateMbps float64
+				} `json:"throughput"`
+			} `json:"results"`
+		}
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return Run{}, err
+		}
+		run := Run{Config: Config{Interface: "eth1"}, Started: doc.Metadata.Started}
+		for _, r := range doc.Results {
+			if r.Throughput != nil {
+				run.Results = append(run.Results, Result{FrameSize: r.FrameSize, MaxRatePct: r.Throughput.MaxRatePct, MaxRateMbps: r.Throughput.MaxRateMbps})
+			}
+		}
+		return run, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := New(":8080")
+	var ids []string
+	for i := len(samples) - 1; i >= 0; i-- {
+		ids = append(ids, s.ImportRun(samples[i].Report))
+	}
+	var runs []RunSummary
+	json.NewDecoder(get(s, "/api/runs").Body).Decode(&runs)
+	if len(runs) != len(samples) {
+		t.Fatalf("runs = %+v", runs)
+	}
+	for i, r := range runs {
+		if r.ID != ids[i] || r.Status != StatusComplete || r.Results != len(samples[len(samples)-1-i].Report.Results) || r.Results == 0 {
+			t.Errorf("run %d = %+v", i, r)
+		}
+	}
+	var results []Result
+	json.NewDecoder(get(s, "/api/results").Body).Decode(&results)
+	if want := samples[0].Report.Results; len(results) != len(want) || results[0] != want[0] {
+		t.Errorf("results = %+v, want %s's", results, samples[0].Name)
+	}
+}
+
+func TestShareLink(t *testing.T) {
+	s := New(":8080")
+	completeRun(t, s)
+
+	w, link := createShare(t, s, `{"ttl":"1h"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("share status = %d: %s", w.Code, w.Body.String())
+	}
+	if !strings.HasPrefix(link.URL, "http://tester.example:8080/share/"+link.RunID+"?") {
+		t.Errorf("URL = %s", link.URL)
+	}
+	if d := time.Until(link.ExpiresAt); d < 59*time.Minute || d > time.Hour {
+		t.Errorf("ExpiresAt in %v, want 1h", d)
+	}
+
+	u, _ := url.Parse(link.URL)
+	w = get(s, u.RequestURI())
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "throughput") {
+		t.Fatalf("report status = %d: %s", w.Code, w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), link.RunID+"/results.json?exp=") {
+		t.Error("Report should link to the signed JSON results")
+	}
+	if w.Header().Get("Cache-Control") != "private, no-store" {
+		t.Error("Shared reports should not be cached")
+	}
+
+	w = get(s, u.Path+"/results.json?"+u.RawQuery)
+	var run Run
+	if err := json.NewDecoder(w.Body).Decode(&run); err != nil {
+		t.Fatalf("results.json: %v", err)
+	}
+	if run.ID != link.RunID || len(run.TestResults) != 1 {
+		t.Errorf("shared run = %+v", run)
+	}
+}
+
+func TestShareLinkRejected(t *testing.T) {
+	s := New(":8080")
+	completeRun(t, s)
+	_, link := createShare(t, s, "")
+	u, _ := url.Parse(link.URL)
+	q := u.Query()
+
+	tampered := url.Values{"exp": {strconv.FormatInt(time.Now().Add(MaxShareTTL).Unix(), 10)}, "sig": {q.Get("sig")}}
+	expired := time.Now().Add(-time.Minute).Unix()
+	expiredQ := url.Values{"exp": {strconv.FormatInt(expired, 10)}, "sig": {s.sign(link.RunID, expired)}}
+
+	for name, target := range map[string]string{
+		"no signature":   u.Path,
+		"extended":       u.Path + "?" + tampered.Encode(),
+		"expired":        u.Path + "?" + expiredQ.Encode(),
+		"other run":      "/share/0000000000000000?" + u.RawQuery,
+		"unknown view":   u.Path + "/config?" + u.RawQuery,
+		"api not shared": "/share/" + link.RunID + "/../../api/config?" + u.RawQuery,
+	} {
+		if w := get(s, target); w.Code == http.StatusOK {
+			t.Errorf("%s: status = %d, want rejection", name, w.Code)
+		}
+	}
+
+	// Links signed by another server's secret are rejected
+	other := New(":8080", WithShareSecret([]byte("other")))
+	other.runs = s.runs
+	if w := get(other, u.RequestURI()); w.Code != http.StatusForbidden {
+		t.Errorf("foreign secret: status = %d", w.Code)
+	}
+}
+
+func TestShareErrors(t *testing.T) {
+	s := New(":8080", WithPublicURL("https://results.example.com/"))
+	if w, _ := createShare(t, s, ""); w.Code != http.StatusNotFound {
+		t.Errorf("no runs: status = %d, want 404", w.Code)
+	}
+
+	completeRun(t, s)
+	if w, _ := createShare(t, s, `{"ttl":"1000h"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("ttl over maximum: status = %d, want 400", w.Code)
+	}
+	if w, _ := createShare(t, s, `{"ttl":"soon"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid ttl: status = %d, want 400", w.Code)
+	}
+	w, link := createShare(t, s, "")
+	if w.Code != http.StatusOK || !strings.HasPrefix(link.URL, "https://results.example.com/share/") {
+		t.Errorf("public URL link = %q", link.URL)
+	}
+
+	w = httptest.NewRecorder()
+	s.mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/share", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET /api/share: status = %d", w.Code)
+	}
+}
@@ -0,0 +1,63 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// SoakConfig makes a start request a soak: fixed-rate throughput samples
+// tracked for drift instead of a search. Settings left zero come from the
+// instance's config.
+type SoakConfig struct {
+	RatePct  float64       `json:"rate_pct,omitempty"`
+	Duration time.Duration `json:"duration,omitempty"`
+}
+
+// SoakRate is the offered load a running soak is retuned to, in % of line
+// rate
+type SoakRate struct {
+	RatePct float64 `json:"rate_pct"`
+}
+
+// ErrNoSoak marks an OnSoakRate error when no soak is running. The request
+// is answered with 409 Conflict; other errors are the client's, with 400.
+var ErrNoSoak = errors.New("no soak running")
+
+// maxSoakRateBytes bounds a POST /api/soak/rate body
+const maxSoakRateBytes = 1 << 10
+
+func (s *Server) handleSoakRate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.OnSoakRate == nil {
+		http.Error(w, "Soak retune not supported", http.StatusNotImplemented)
+		return
+	}
+
+	var req SoakRate
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxSoakRateBytes)).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+	if req.RatePct <= 0 || req.RatePct > 100 {
+		http.Error(w, "rate_pct must be between 0 and 100", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.OnSoakRate(req.RatePct); err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, ErrNoSoak) {
+			status = http.StatusConflict
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(req)
+}
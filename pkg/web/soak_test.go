@@ -0,0 +1,54 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSoakRate(t *testing.T) {
+	post := func(s *Server, body string) int {
+		w := httptest.NewRecorder()
+		s.mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/soak/rate", strings.NewReader(body)))
+		return w.Code
+	}
+
+	s := New(":8080")
+	if code := post(s, `{"rate_pct": 50}`); code != http.StatusNotImplemented {
+		t.Errorf("without OnSoakRate: status %d", code)
+	}
+
+	var got float64
+	running := false
+	s.OnSoakRate = func(ratePct float64) error {
+		if !running {
+			return ErrNoSoak
+		}
+		if ratePct == 42 {
+			return fmt.Errorf("rate below the soak's minimum")
+		}
+		got = ratePct
+		return nil
+	}
+
+	if code := post(s, `{"rate_pct": 50}`); code != http.StatusConflict {
+		t.Errorf("no soak running: status %d", code)
+	}
+	running = true
+	if code := post(s, `{"rate_pct": 50}`); code != http.StatusOK || got != 50 {
+		t.Errorf("retune: status %d, rate %.1f", code, got)
+	}
+	for _, body := range []string{`{"rate_pct": 0}`, `{"rate_pct": 101}`, `{"rate_pct":`, `{"rate_pct": 42}`} {
+		if code := post(s, body); code != http.StatusBadRequest {
+			t.Errorf("%s: status %d", body, code)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	s.mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/soak/rate", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET: status %d", w.Code)
+	}
+}
@@ -4,10 +4,13 @@ package web
 import (
 	"embed"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/fs"
 	"log"
 	"net/http"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 )
@@ -36,6 +39,18 @@ type Stats struct {
 	LatencyP99  float64 `json:"latency_p99_ns"`
 	Uptime      float64 `json:"uptime_sec"`
 	Timestamp   int64   `json:"timestamp"`
+
+	// Frame order and corrupted frames of the last completed trial
+	ReorderedFrames uint64 `json:"reordered_frames"`
+	DuplicateFrames uint64 `json:"duplicate_frames"`
+	MaxReorderDepth uint32 `json:"max_reorder_depth"`
+	CorruptedFrames uint64 `json:"corrupted_frames"`
+
+	// The running trial: whether it is warming up and the fraction of its
+	// warmup and measurement done, 0-1
+	InTrial       bool    `json:"in_trial"`
+	Warmup        bool    `json:"warmup"`
+	TrialProgress float64 `json:"trial_progress"`
 }
 
 // Result for completed test
@@ -75,6 +90,31 @@ type Config struct {
 
 	// Y.1564 specific configuration
 	Y1564 *Y1564Config `json:"y1564,omitempty"`
+
+	// Soak runs a throughput TestType as a soak at a fixed rate
+	Soak *SoakConfig `json:"soak,omitempty"`
+
+	// AcknowledgeImpact accepts the traffic of a test on an interface the
+	// instance lists as in service
+	AcknowledgeImpact bool `json:"acknowledge_impact,omitempty"`
+}
+
+// ErrImpactNotAcknowledged marks an OnStart error for a test on an
+// in-service interface without Config.AcknowledgeImpact. The request is
+// answered with 428 Precondition Required, the error giving the traffic
+// the test would send.
+var ErrImpactNotAcknowledged = errors.New("impact not acknowledged")
+
+// Capabilities are what the instance's dataplane can do on an interface,
+// for the UI to offer only the tests and frame sizes that will run
+type Capabilities struct {
+	Interface    string `json:"interface"`
+	Dataplane    string `json:"dataplane"`      // Backend, e.g. "librfc2544"
+	HWTimestamp  bool   `json:"hw_timestamp"`   // Latency is stamped in hardware
+	MaxStreams   uint32 `json:"max_streams"`    // Flows a trial can generate
+	MaxFrameSize uint32 `json:"max_frame_size"` // Largest frame, FCS included, the port carries
+	TestTypes    []int  `json:"test_types"`     // Config.TestType values the dataplane runs
+	Soak         bool   `json:"soak"`           // Config.Soak runs
 }
 
 // TestResult for generic test results
@@ -97,12 +137,16 @@ type Y1564Config struct {
 
 // Y1564Service for Y.1564 service definition
 type Y1564Service struct {
-	ServiceID   uint32    `json:"service_id"`
-	ServiceName string    `json:"service_name"`
-	FrameSize   uint32    `json:"frame_size"`
-	CoS         uint8     `json:"cos"`
-	Enabled     bool      `json:"enabled"`
-	SLA         Y1564SLA  `json:"sla"`
+	ServiceID   uint32   `json:"service_id"`
+	ServiceName string   `json:"service_name"`
+	FrameSize   uint32   `json:"frame_size"`
+	CoS         uint8    `json:"cos"`
+	Enabled     bool     `json:"enabled"`
+	VLANID      uint16   `json:"vlan_id,omitempty"`
+	PCP         uint8    `json:"pcp,omitempty"`
+	OuterVLANID uint16   `json:"outer_vlan_id,omitempty"`
+	OuterPCP    uint8    `json:"outer_pcp,omitempty"`
+	SLA         Y1564SLA `json:"sla"`
 }
 
 // Y1564SLA for Y.1564 SLA parameters
@@ -177,10 +221,46 @@ type Server struct {
 	// Embedded UI (optional)
 	uiFS fs.FS
 
+	// Published JSON Schemas by name (optional)
+	schemas map[string]interface{}
+
+	// Fields /api/health adds to its own, e.g. versions (optional)
+	health map[string]interface{}
+
+	// Completed runs and the run in progress, for share links
+	runs        []*Run
+	current     *Run
+	shareSecret []byte
+	publicURL   string
+
+	// Campaigns, and the one running. runDone receives each run as it is
+	// archived while a campaign waits on it.
+	campaigns []*Campaign
+	campaign  *Campaign
+	runDone   chan *Run
+
+	// Clients of /api/events
+	eventsMu sync.Mutex
+	events   map[chan Event]struct{}
+
 	// Callbacks
 	OnStart  func(cfg Config) error
 	OnStop   func() error
 	OnCancel func()
+
+	// OnCapabilities reports what the dataplane can do on an interface,
+	// the configured one when iface is ""
+	OnCapabilities func(iface string) (Capabilities, error)
+
+	// Defaults fills in the settings a start request leaves unset from
+	// the daemon configuration. OnReload replaces that configuration from
+	// YAML and returns the keys that changed.
+	Defaults func(cfg *Config)
+	OnReload func(data []byte) ([]string, error)
+
+	// OnSoakRate retunes the running soak to ratePct of line rate from
+	// its next sample, or returns ErrNoSoak
+	OnSoakRate func(ratePct float64) error
 }
 
 // Option for server configuration
@@ -196,6 +276,22 @@ func WithUI(uiFS embed.FS, subdir string) Option {
 	}
 }
 
+// WithSchemas publishes JSON Schema documents under /api/schema/{name}
+func WithSchemas(schemas map[string]interface{}) Option {
+	return func(s *Server) {
+		s.schemas = schemas
+	}
+}
+
+// WithHealth adds fields to the /api/health response, e.g. the versions
+// of the control plane and the dataplane library, replacing any of the
+// same name
+func WithHealth(fields map[string]interface{}) Option {
+	return func(s *Server) {
+		s.health = fields
+	}
+}
+
 // New creates a new web server
 func New(addr string, opts ...Option) *Server {
 	s := &Server{
@@ -207,6 +303,9 @@ func New(addr string, opts ...Option) *Server {
 	for _, opt := range opts {
 		opt(s)
 	}
+	if len(s.shareSecret) == 0 {
+		s.shareSecret = []byte(randomHex(32))
+	}
 
 	s.setupRoutes()
 	return s
@@ -221,6 +320,20 @@ func (s *Server) setupRoutes() {
 	s.mux.HandleFunc("/api/stop", s.handleStop)
 	s.mux.HandleFunc("/api/cancel", s.handleCancel)
 	s.mux.HandleFunc("/api/health", s.handleHealth)
+	s.mux.HandleFunc("/api/capabilities", s.handleCapabilities)
+	s.mux.HandleFunc("/api/events", s.handleEvents)
+	s.mux.HandleFunc("/api/schema", s.handleSchema)
+	s.mux.HandleFunc("/api/schema/", s.handleSchema)
+	s.mux.HandleFunc("/api/runs", s.handleRuns)
+	s.mux.HandleFunc("/api/share", s.handleShare)
+	s.mux.HandleFunc("/api/report.pdf", s.handleReport)
+	s.mux.HandleFunc("/api/campaigns", s.handleCampaigns)
+	s.mux.HandleFunc("/api/campaigns/", s.handleCampaign)
+	s.mux.HandleFunc("/api/tests/", s.handleTest)
+	s.mux.HandleFunc("/api/soak/rate", s.handleSoakRate)
+
+	// Read-only run reports behind signed links
+	s.mux.HandleFunc("/share/", s.handleShared)
 
 	// Static UI (if embedded)
 	if s.uiFS != nil {
@@ -254,12 +367,21 @@ func (s *Server) handleRoot(w http.ResponseWriter, r *http.Request) {
         <h2>API Endpoints</h2>
         <ul>
             <li><a href="/api/stats">GET /api/stats</a> - Current statistics</li>
+            <li>GET /api/events - Server-Sent Events stream of stats, status changes and results (e.g. for rfc2544 attach)</li>
             <li><a href="/api/results">GET /api/results</a> - Test results</li>
             <li><a href="/api/config">GET /api/config</a> - Current configuration</li>
+            <li>POST /api/config - Reload the daemon configuration from a YAML body, as if the config file changed; a running soak (test_type 60) takes a new soak.rate_pct or frame_size from its next sample without stopping</li>
             <li>POST /api/start - Start test</li>
             <li>POST /api/stop - Stop test</li>
             <li>POST /api/cancel - Cancel test</li>
             <li><a href="/api/health">GET /api/health</a> - Health check</li>
+            <li><a href="/api/capabilities">GET /api/capabilities?interface=...</a> - Test types, frame sizes and timestamping the dataplane supports</li>
+            <li><a href="/api/runs">GET /api/runs</a> - Completed runs</li>
+            <li>POST /api/share - Create a time-limited read-only link to a run ({"run_id":"...","ttl":"72h"})</li>
+            <li><a href="/api/report.pdf">GET /api/report.pdf?run_id=...</a> - Acceptance certificate for a run (PDF; latest without run_id)</li>
+            <li>POST /api/campaigns - Run a list of tests one after another ({"name":"...","items":[{...},{...}]})</li>
+            <li><a href="/api/campaigns">GET /api/campaigns</a> - Campaigns; /api/campaigns/{id} for progress, /api/campaigns/{id}/results for the combined results</li>
+            <li>POST /api/tests/{id}/continue - Resume a campaign waiting on an item's operator prompt ({"prompt":"Re-patch to port 2",...}); POST /api/cancel declines</li>
         </ul>
     </div>
     <div class="card">
@@ -394,11 +516,63 @@ func (s *Server) handleRoot(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
+	health := map[string]interface{}{
 		"status":    "ok",
 		"timestamp": time.Now().Unix(),
 		"version":   "2.0.0",
-	})
+	}
+	for k, v := range s.health {
+		health[k] = v
+	}
+	json.NewEncoder(w).Encode(health)
+}
+
+func (s *Server) handleCapabilities(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.OnCapabilities == nil {
+		http.Error(w, "Capabilities not available", http.StatusNotImplemented)
+		return
+	}
+	caps, err := s.OnCapabilities(r.URL.Query().Get("interface"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(caps)
+}
+
+// handleSchema lists the published schema names, or returns one schema
+// by name (with or without the .schema.json suffix)
+func (s *Server) handleSchema(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/api/schema"), "/")
+	name = strings.TrimSuffix(name, ".schema.json")
+	if name == "" {
+		names := make([]string, 0, len(s.schemas))
+		for n := range s.schemas {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(names)
+		return
+	}
+
+	doc, ok := s.schemas[name]
+	if !ok {
+		http.Error(w, "Unknown schema", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/schema+json")
+	json.NewEncoder(w).Encode(doc)
 }
 
 func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
@@ -431,6 +605,11 @@ func (s *Server) handleResults(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		s.handleReload(w, r)
+		return
+	}
+
 	s.mu.RLock()
 	config := s.config
 	s.mu.RUnlock()
@@ -451,16 +630,21 @@ func (s *Server) handleStart(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s.mu.Lock()
-	s.config = cfg
-	s.results = s.results[:0] // Clear previous results
-	s.mu.Unlock()
+	s.mu.RLock()
+	busy := s.campaign != nil
+	s.mu.RUnlock()
+	if busy {
+		http.Error(w, "A campaign is running", http.StatusConflict)
+		return
+	}
 
-	if s.OnStart != nil {
-		if err := s.OnStart(cfg); err != nil {
-			http.Error(w, fmt.Sprintf("Start failed: %v", err), http.StatusInternalServerError)
-			return
+	if err := s.startRun(cfg); err != nil {
+		code := http.StatusInternalServerError
+		if errors.Is(err, ErrImpactNotAcknowledged) {
+			code = http.StatusPreconditionRequired
 		}
+		http.Error(w, fmt.Sprintf("Start failed: %v", err), code)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -493,6 +677,9 @@ func (s *Server) handleCancel(w http.ResponseWriter, r *http.Request) {
 	if s.OnCancel != nil {
 		s.OnCancel()
 	}
+	s.mu.Lock()
+	s.cancelWaiting()
+	s.mu.Unlock()
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"status": "cancelled"})
@@ -503,6 +690,7 @@ func (s *Server) UpdateStats(stats Stats) {
 	s.mu.Lock()
 	s.stats = stats
 	s.mu.Unlock()
+	s.publish(Event{Type: EventStats, Stats: &stats, Progress: stats.Progress})
 }
 
 // AddResult adds a test result (legacy)
@@ -518,6 +706,7 @@ func (s *Server) AddResult(result TestResult) {
 	s.mu.Lock()
 	s.testResults = append(s.testResults, result)
 	s.mu.Unlock()
+	s.publish(Event{Type: EventResult, Result: &result})
 }
 
 // UpdateStatus updates the test status
@@ -528,7 +717,11 @@ func (s *Server) UpdateStatus(status, message string, progress float64) {
 	s.progress = progress
 	s.stats.State = status
 	s.stats.Progress = progress
+	if status == StatusComplete || status == StatusError || status == StatusCancelled {
+		s.finishRun(status, message)
+	}
 	s.mu.Unlock()
+	s.publish(Event{Type: EventStatus, Status: status, Message: message, Progress: progress})
 }
 
 // ClearResults clears all results
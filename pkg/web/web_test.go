@@ -4,6 +4,7 @@ package web
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -81,6 +82,59 @@ func TestHandleHealth(t *testing.T) {
 	}
 }
 
+func TestHandleHealthFields(t *testing.T) {
+	s := New(":8080", WithHealth(map[string]interface{}{
+		"version":   "2.1.0",
+		"dataplane": "librfc2544",
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/api/health", nil)
+	w := httptest.NewRecorder()
+
+	s.handleHealth(w, req)
+
+	var resp map[string]interface{}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp["status"] != "ok" || resp["version"] != "2.1.0" || resp["dataplane"] != "librfc2544" {
+		t.Errorf("health = %v", resp)
+	}
+}
+
+func TestHandleCapabilities(t *testing.T) {
+	s := New(":8080")
+	req := httptest.NewRequest(http.MethodGet, "/api/capabilities", nil)
+	w := httptest.NewRecorder()
+	s.handleCapabilities(w, req)
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("without OnCapabilities: status %d", w.Code)
+	}
+
+	s.OnCapabilities = func(iface string) (Capabilities, error) {
+		if iface != "eth1" {
+			return Capabilities{}, errors.New("no such interface")
+		}
+		return Capabilities{Interface: iface, MaxFrameSize: 1518, TestTypes: []int{0, 1, 2, 3}}, nil
+	}
+	req = httptest.NewRequest(http.MethodGet, "/api/capabilities?interface=eth1", nil)
+	w = httptest.NewRecorder()
+	s.handleCapabilities(w, req)
+	var caps Capabilities
+	if err := json.NewDecoder(w.Body).Decode(&caps); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if caps.Interface != "eth1" || caps.MaxFrameSize != 1518 || len(caps.TestTypes) != 4 {
+		t.Errorf("capabilities = %+v", caps)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/capabilities?interface=bogus", nil)
+	w = httptest.NewRecorder()
+	s.handleCapabilities(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("unknown interface: status %d", w.Code)
+	}
+}
+
 func TestHandleHealthContentType(t *testing.T) {
 	s := New(":8080")
 	req := httptest.NewRequest(http.MethodGet, "/api/health", nil)
@@ -272,6 +326,49 @@ func TestHandleConfig(t *testing.T) {
 	}
 }
 
+func TestHandleSchema(t *testing.T) {
+	s := New(":8080", WithSchemas(map[string]interface{}{
+		"results": map[string]string{"title": "results"},
+		"config":  map[string]string{"title": "config"},
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/schema", nil)
+	w := httptest.NewRecorder()
+	s.handleSchema(w, req)
+
+	var names []string
+	if err := json.NewDecoder(w.Body).Decode(&names); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(names) != 2 || names[0] != "config" || names[1] != "results" {
+		t.Errorf("Expected [config results], got %v", names)
+	}
+
+	for _, path := range []string{"/api/schema/config", "/api/schema/config.schema.json"} {
+		req = httptest.NewRequest(http.MethodGet, path, nil)
+		w = httptest.NewRecorder()
+		s.mux.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d", path, w.Code)
+		}
+		if ct := w.Header().Get("Content-Type"); ct != "application/schema+json" {
+			t.Errorf("%s: expected schema content type, got %s", path, ct)
+		}
+		var doc map[string]string
+		json.NewDecoder(w.Body).Decode(&doc)
+		if doc["title"] != "config" {
+			t.Errorf("%s: got %v", path, doc)
+		}
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/schema/nope", nil)
+	w = httptest.NewRecorder()
+	s.handleSchema(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+}
+
 // ============================================================================
 // Start Endpoint Tests
 // ============================================================================
@@ -307,6 +404,31 @@ func TestHandleStartSuccess(t *testing.T) {
 	}
 }
 
+func TestHandleStartInService(t *testing.T) {
+	s := New(":8080")
+	s.OnStart = func(cfg Config) error {
+		if !cfg.AcknowledgeImpact {
+			return ErrImpactNotAcknowledged
+		}
+		return nil
+	}
+
+	for _, tc := range []struct {
+		body string
+		code int
+	}{
+		{`{"interface":"eth0"}`, http.StatusPreconditionRequired},
+		{`{"interface":"eth0","acknowledge_impact":true}`, http.StatusOK},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/api/start", strings.NewReader(tc.body))
+		w := httptest.NewRecorder()
+		s.handleStart(w, req)
+		if w.Code != tc.code {
+			t.Errorf("%s: status %d, want %d: %s", tc.body, w.Code, tc.code, w.Body)
+		}
+	}
+}
+
 func TestHandleStartInvalidJSON(t *testing.T) {
 	s := New(":8080")
 
@@ -0,0 +1,130 @@
+// Package wiring draws how the tester, the DUT and the far end are cabled
+// for a run, so a field technician connects the ports the configuration
+// assumes instead of guessing from it.
+package wiring
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Topology is how test frames get from the tester back to it
+type Topology string
+
+const (
+	// SinglePort sends and receives on one tester port; the far side of
+	// the DUT loops frames back
+	SinglePort Topology = "single_port"
+	// PortPair sends on one tester port and receives on another, through
+	// the DUT
+	PortPair Topology = "port_pair"
+	// TwoBox returns frames from a reflector on a second host across a
+	// routed network
+	TwoBox Topology = "two_box"
+)
+
+// Setup is what the run is cabled to
+type Setup struct {
+	Topology Topology
+	TX       string // Tester port sending, also receiving for SinglePort and TwoBox
+	RX       string // Tester port receiving for PortPair
+	DUT      string // Default: "DUT"
+	FarEnd   string // What returns frames for SinglePort (default: a loopback plug) or TwoBox
+}
+
+// Diagram is the drawing of a setup and the connections to make, in order
+type Diagram struct {
+	Topology Topology `json:"topology"`
+	Lines    []string `json:"lines"`
+	Steps    []string `json:"steps"`
+}
+
+// linkWidth is the width of the cable drawn between two boxes
+const linkWidth = 12
+
+// Draw returns the diagram of a setup
+func Draw(s Setup) Diagram {
+	dut := s.DUT
+	if dut == "" {
+		dut = "DUT"
+	}
+	d := Diagram{Topology: s.Topology}
+	switch s.Topology {
+	case PortPair:
+		d.Lines = draw([][]string{{"Tester", "TX " + s.TX, "RX " + s.RX}, {dut, "ingress", "egress"}},
+			[]map[int]string{{1: " =========> ", 2: " <========= "}})
+		d.Steps = []string{
+			fmt.Sprintf("Connect tester port %s (TX) to the DUT ingress port", s.TX),
+			fmt.Sprintf("Connect the DUT egress port to tester port %s (RX)", s.RX),
+		}
+	case TwoBox:
+		d.Lines = draw([][]string{{"Tester", s.TX}, {"Network", dut}, {"Reflector", s.FarEnd}},
+			[]map[int]string{{1: " <========> "}, {1: " <========> "}})
+		d.Steps = []string{
+			fmt.Sprintf("Connect the tester (%s) to the network under test", s.TX),
+			fmt.Sprintf("Start the reflector on %s: rfc2544 reflector", s.FarEnd),
+			"Check the path routes UDP between the two hosts; no loopback is needed",
+		}
+	default:
+		far := s.FarEnd
+		loop := fmt.Sprintf("Frames return from %s; leave the far side cabled as in service", far)
+		if far == "" {
+			far = "loopback"
+			loop = "Loop the far side of the DUT back: a loopback plug on its egress port or a looped far-end device"
+		}
+		d.Topology = SinglePort
+		d.Lines = draw([][]string{{"Tester", s.TX}, {dut, ""}, {"Far end", far}},
+			[]map[int]string{{1: " <========> "}, {1: " <========> "}})
+		d.Steps = []string{
+			fmt.Sprintf("Connect tester port %s to the DUT ingress port; it sends and receives", s.TX),
+			loop,
+		}
+	}
+	return d
+}
+
+// draw lays out boxes left to right; links[i] gives the cable between
+// box i and i+1 on each line of their text
+func draw(boxes [][]string, links []map[int]string) []string {
+	height := 0
+	widths := make([]int, len(boxes))
+	for i, b := range boxes {
+		for _, text := range b {
+			widths[i] = max(widths[i], len(text))
+		}
+		height = max(height, len(b))
+	}
+
+	var lines []string
+	for row := -1; row <= height; row++ {
+		var sb strings.Builder
+		for i, b := range boxes {
+			if i > 0 {
+				fmt.Fprintf(&sb, "%-*s", linkWidth, links[i-1][row])
+			}
+			if row < 0 || row == height {
+				sb.WriteString("+" + strings.Repeat("-", widths[i]+2) + "+")
+				continue
+			}
+			text := ""
+			if row < len(b) {
+				text = b[row]
+			}
+			fmt.Fprintf(&sb, "| %-*s |", widths[i], text)
+		}
+		lines = append(lines, sb.String())
+	}
+	return lines
+}
+
+// String is the drawing followed by the numbered steps
+func (d Diagram) String() string {
+	var sb strings.Builder
+	for _, l := range d.Lines {
+		sb.WriteString(l + "\n")
+	}
+	for i, s := range d.Steps {
+		fmt.Fprintf(&sb, "%d. %s\n", i+1, s)
+	}
+	return sb.String()
+}
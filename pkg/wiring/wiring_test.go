@@ -0,0 +1,51 @@
+package wiring
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDrawSinglePort(t *testing.T) {
+	d := Draw(Setup{TX: "eth0", DUT: "edge-r1"})
+	want := []string{
+		"+--------+            +---------+            +----------+",
+		"| Tester |            | edge-r1 |            | Far end  |",
+		"| eth0   | <========> |         | <========> | loopback |",
+		"+--------+            +---------+            +----------+",
+	}
+	if d.Topology != SinglePort || strings.Join(d.Lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got %s:\n%s", d.Topology, strings.Join(d.Lines, "\n"))
+	}
+	if len(d.Steps) != 2 || !strings.Contains(d.Steps[1], "loopback plug") {
+		t.Errorf("steps = %q", d.Steps)
+	}
+
+	d = Draw(Setup{Topology: SinglePort, TX: "eth0", FarEnd: "OAM loopback 00:11:22:33:44:55"})
+	if !strings.Contains(d.Steps[1], "Frames return from OAM loopback") {
+		t.Errorf("steps = %q", d.Steps)
+	}
+}
+
+func TestDrawPortPair(t *testing.T) {
+	d := Draw(Setup{Topology: PortPair, TX: "eth0", RX: "eth1"})
+	want := []string{
+		"+---------+            +---------+",
+		"| Tester  |            | DUT     |",
+		"| TX eth0 | =========> | ingress |",
+		"| RX eth1 | <========= | egress  |",
+		"+---------+            +---------+",
+	}
+	if strings.Join(d.Lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("lines:\n%s", strings.Join(d.Lines, "\n"))
+	}
+	if !strings.Contains(d.String(), "2. Connect the DUT egress port to tester port eth1 (RX)\n") {
+		t.Errorf("String() =\n%s", d)
+	}
+}
+
+func TestDrawTwoBox(t *testing.T) {
+	d := Draw(Setup{Topology: TwoBox, TX: "this host", FarEnd: "192.0.2.1:3842"})
+	if !strings.Contains(d.Lines[2], "| 192.0.2.1:3842 |") || !strings.Contains(d.Steps[1], "rfc2544 reflector") {
+		t.Errorf("diagram:\n%s", d)
+	}
+}
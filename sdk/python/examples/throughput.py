@@ -0,0 +1,57 @@
+#!/usr/bin/env python3
+"""Run a throughput test on a tester and print its results.
+
+    python3 throughput.py http://tester:8080 eth0 512
+"""
+
+import os
+import sys
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), ".."))
+
+from rfc2544_client import APIError, Client  # noqa: E402
+
+FINISHED = ("complete", "error", "cancelled")
+
+
+def main() -> int:
+    if len(sys.argv) < 3:
+        print(__doc__.strip(), file=sys.stderr)
+        return 2
+    client = Client(sys.argv[1])
+    frame_size = int(sys.argv[3]) if len(sys.argv) > 3 else 512
+
+    # Subscribe before starting so no status change is missed
+    events = client.events()
+    try:
+        client.start({"interface": sys.argv[2], "test_type": 0, "frame_size": frame_size})
+    except APIError as err:
+        print(f"start: {err}", file=sys.stderr)
+        return 1
+
+    # A new subscriber first gets the current status, which may belong to
+    # the previous run
+    status = ""
+    running = False
+    for event in events:
+        if event.get("type") == "stats":
+            stats = event.get("stats", {})
+            print(f"  {event.get('progress', 0):5.1f}%  {stats.get('offered_rate_pct', 0):6.2f}% offered", end="\r")
+        elif event.get("type") == "status":
+            running = running or event.get("status") == "running"
+            if running and event.get("status") in FINISHED:
+                status = event["status"]
+                break
+    print()
+
+    if status != "complete":
+        print(f"test {status}: {event.get('message', '')}", file=sys.stderr)
+        return 1
+    for r in client.results():
+        print(f"{r.get('frame_size')} bytes: {r.get('max_rate_pct', 0):.2f}% "
+              f"({r.get('max_rate_mbps', 0):.1f} Mbit/s), latency {r.get('latency_avg_ns', 0) / 1000:.1f} us")
+    return 0
+
+
+if __name__ == "__main__":
+    sys.exit(main())
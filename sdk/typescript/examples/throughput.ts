@@ -0,0 +1,64 @@
+// Run a throughput test on a tester and print its results.
+//
+//   npx tsx throughput.ts http://tester:8080 eth0 512
+
+import { APIError, Client } from "../rfc2544Client";
+
+const FINISHED = ["complete", "error", "cancelled"];
+
+async function main(): Promise<number> {
+  const [baseURL, iface, size = "512"] = process.argv.slice(2);
+  if (!baseURL || !iface) {
+    console.error("usage: throughput.ts BASE_URL INTERFACE [FRAME_SIZE]");
+    return 2;
+  }
+  const client = new Client(baseURL);
+
+  // Subscribe before starting so no status change is missed: the first
+  // next() opens the stream
+  const events = client.events();
+  const first = events.next();
+  try {
+    await client.start({ interface: iface, test_type: 0, frame_size: Number(size) });
+  } catch (err) {
+    console.error(`start: ${err instanceof APIError ? err.message : err}`);
+    await events.return(undefined);
+    return 1;
+  }
+
+  // A new subscriber first gets the current status, which may belong to
+  // the previous run
+  let status = "";
+  let message = "";
+  let running = false;
+  for (let next = await first; !next.done; next = await events.next()) {
+    const event = next.value;
+    if (event.type === "stats") {
+      const pct = event.stats?.offered_rate_pct ?? 0;
+      process.stdout.write(`  ${(event.progress ?? 0).toFixed(1)}%  ${pct.toFixed(2)}% offered\r`);
+    } else if (event.type === "status") {
+      running ||= event.status === "running";
+      if (running && FINISHED.includes(event.status ?? "")) {
+        status = event.status ?? "";
+        message = event.message ?? "";
+        break;
+      }
+    }
+  }
+  await events.return(undefined);
+  console.log();
+
+  if (status !== "complete") {
+    console.error(`test ${status}: ${message}`);
+    return 1;
+  }
+  for (const r of await client.results()) {
+    console.log(
+      `${r.frame_size} bytes: ${(r.max_rate_pct ?? 0).toFixed(2)}% ` +
+        `(${(r.max_rate_mbps ?? 0).toFixed(1)} Mbit/s), latency ${((r.latency_avg_ns ?? 0) / 1000).toFixed(1)} us`,
+    );
+  }
+  return 0;
+}
+
+main().then((code) => process.exit(code));
@@ -0,0 +1,157 @@
+/*
+ * capture.c - PCAP capture of test traffic
+ *
+ * Saves the frames trials send and receive for post-mortem of frame
+ * loss, in the classic pcap format with nanosecond timestamps that
+ * tcpdump, Wireshark and tshark read. Capturing in the dataplane sees
+ * exactly the trial's frames, without a second process competing with
+ * the trial for the CPU and the socket.
+ */
+
+#include "rfc2544.h"
+#include "rfc2544_internal.h"
+
+#include <errno.h>
+#include <stdio.h>
+#include <string.h>
+#include <time.h>
+
+#define PCAP_MAGIC_NS 0xa1b23c4d /* Nanosecond timestamps */
+#define PCAP_LINKTYPE_ETHERNET 1
+#define PCAP_MAX_SNAPLEN 262144
+
+typedef struct {
+	uint32_t magic;
+	uint16_t version_major;
+	uint16_t version_minor;
+	int32_t thiszone;
+	uint32_t sigfigs;
+	uint32_t snaplen;
+	uint32_t linktype;
+} pcap_file_header_t;
+
+typedef struct {
+	uint32_t ts_sec;
+	uint32_t ts_nsec;
+	uint32_t incl_len;
+	uint32_t orig_len;
+} pcap_record_t;
+
+/* Close a file, keeping its count of frames */
+static void capture_close_file(capture_file_t *f)
+{
+	if (f->file)
+		fclose(f->file);
+	f->file = NULL;
+}
+
+static int capture_open_file(capture_file_t *f, const char *path, uint32_t limit, uint32_t snaplen)
+{
+	f->file = fopen(path, "wb");
+	if (!f->file)
+		return -errno;
+	f->limit = limit;
+	f->snaplen = snaplen;
+
+	pcap_file_header_t hdr = {
+	    .magic = PCAP_MAGIC_NS,
+	    .version_major = 2,
+	    .version_minor = 4,
+	    .snaplen = snaplen ? snaplen : PCAP_MAX_SNAPLEN,
+	    .linktype = PCAP_LINKTYPE_ETHERNET,
+	};
+	if (fwrite(&hdr, sizeof(hdr), 1, f->file) != 1) {
+		int ret = -errno;
+		capture_close_file(f);
+		return ret ? ret : -EIO;
+	}
+	return 0;
+}
+
+int rfc2544_capture_configure(rfc2544_ctx_t *ctx, const capture_config_t *config)
+{
+	if (!ctx)
+		return -EINVAL;
+	if (config && config->snaplen > PCAP_MAX_SNAPLEN)
+		return -EINVAL;
+
+	rfc2544_capture_close(ctx);
+	memset(&ctx->capture_stats, 0, sizeof(ctx->capture_stats));
+	if (!config || (!config->tx_path[0] && !config->rx_path[0]))
+		return 0;
+
+	uint32_t limit = config->limit ? config->limit : DEFAULT_CAPTURE_LIMIT;
+	int ret = 0;
+	if (config->tx_path[0])
+		ret = capture_open_file(&ctx->capture_tx, config->tx_path, limit, config->snaplen);
+	if (ret == 0 && config->rx_path[0])
+		ret = capture_open_file(&ctx->capture_rx, config->rx_path, limit, config->snaplen);
+	if (ret < 0) {
+		rfc2544_log(LOG_ERROR, "Cannot open capture file: %s", strerror(-ret));
+		rfc2544_capture_close(ctx);
+		return ret;
+	}
+
+	rfc2544_log(LOG_INFO, "Capturing up to %u frames%s%s%s%s", limit,
+	            config->tx_path[0] ? " sent to " : "", config->tx_path,
+	            config->rx_path[0] ? " received to " : "", config->rx_path);
+	return 0;
+}
+
+void rfc2544_capture_frame(capture_file_t *f, const uint8_t *data, uint32_t len)
+{
+	if (!f->file || f->frames >= f->limit)
+		return;
+
+	struct timespec ts;
+	clock_gettime(CLOCK_REALTIME, &ts);
+	uint32_t incl = f->snaplen && len > f->snaplen ? f->snaplen : len;
+	pcap_record_t rec = {
+	    .ts_sec = (uint32_t)ts.tv_sec,
+	    .ts_nsec = (uint32_t)ts.tv_nsec,
+	    .incl_len = incl,
+	    .orig_len = len,
+	};
+	if (fwrite(&rec, sizeof(rec), 1, f->file) != 1 || fwrite(data, 1, incl, f->file) != incl) {
+		rfc2544_log(LOG_WARN, "Capture write failed, stopping capture: %s", strerror(errno));
+		capture_close_file(f);
+		return;
+	}
+	if (++f->frames == f->limit)
+		fflush(f->file);
+}
+
+void rfc2544_capture_flush(rfc2544_ctx_t *ctx)
+{
+	if (ctx->capture_tx.file)
+		fflush(ctx->capture_tx.file);
+	if (ctx->capture_rx.file)
+		fflush(ctx->capture_rx.file);
+}
+
+void rfc2544_capture_close(rfc2544_ctx_t *ctx)
+{
+	uint64_t tx = ctx->capture_tx.frames, rx = ctx->capture_rx.frames;
+	if (ctx->capture_tx.limit || ctx->capture_rx.limit)
+		rfc2544_log(LOG_INFO, "Captured %lu frames sent, %lu received", (unsigned long)tx,
+		            (unsigned long)rx);
+	capture_close_file(&ctx->capture_tx);
+	capture_close_file(&ctx->capture_rx);
+	memset(&ctx->capture_tx, 0, sizeof(ctx->capture_tx));
+	memset(&ctx->capture_rx, 0, sizeof(ctx->capture_rx));
+	ctx->capture_stats.tx_frames = tx;
+	ctx->capture_stats.rx_frames = rx;
+}
+
+int rfc2544_capture_get_stats(const rfc2544_ctx_t *ctx, capture_stats_t *stats)
+{
+	if (!ctx || !stats)
+		return -EINVAL;
+	if (ctx->capture_tx.limit || ctx->capture_rx.limit) {
+		stats->tx_frames = ctx->capture_tx.frames;
+		stats->rx_frames = ctx->capture_rx.frames;
+	} else {
+		*stats = ctx->capture_stats;
+	}
+	return 0;
+}
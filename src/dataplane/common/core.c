@@ -65,9 +65,26 @@ rfc2544_payload_t *rfc2544_create_packet_template(uint8_t *buffer, uint32_t fram
                                                    uint16_t src_port, uint16_t dst_port,
                                                    uint32_t stream_id);
 void rfc2544_stamp_packet(rfc2544_payload_t *payload, uint32_t seq_num, uint64_t timestamp_ns);
+void rfc2544_set_packet_addresses(uint8_t *buffer, const uint8_t *src_mac,
+                                  uint32_t src_ip, uint32_t dst_ip);
 bool rfc2544_is_valid_response(const uint8_t *data, uint32_t len);
 uint32_t rfc2544_get_seq_num(const uint8_t *data, uint32_t len);
 uint64_t rfc2544_get_tx_timestamp(const uint8_t *data, uint32_t len);
+rfc2544_payload_t *rfc2544_create_templated_packet(uint8_t *buffer, uint32_t frame_size,
+                                                    const header_template_t *tpl,
+                                                    const uint8_t *src_mac, const uint8_t *dst_mac,
+                                                    const payload_pattern_t *pattern);
+bool rfc2544_parse_response(const uint8_t *data, uint32_t len, uint32_t offset,
+                            uint32_t *seq_num, uint64_t *tx_timestamp);
+uint32_t rfc2544_prepare_seal(rfc2544_payload_t *payload, uint32_t payload_len);
+void rfc2544_seal_packet(rfc2544_payload_t *payload, uint32_t payload_len, uint32_t padding_crc);
+bool rfc2544_payload_intact(const uint8_t *data, uint32_t len, uint32_t offset);
+void rfc2544_set_stream(uint8_t *buffer, rfc2544_payload_t *payload, const uint8_t *src_mac,
+                        uint32_t src_ip, uint32_t dst_ip, uint16_t src_port, uint32_t stream);
+bool rfc2544_parse_stream_id(const uint8_t *data, uint32_t len, uint32_t offset,
+                             uint32_t *stream_id);
+void rfc2544_set_header_range(uint8_t *buffer, uint32_t len, const header_fields_t *fields,
+                              uint64_t n);
 void rfc2544_calc_latency_stats(const uint64_t *samples, uint32_t count, latency_stats_t *stats);
 
 /* Forward declarations for pacing.c */
@@ -79,6 +96,7 @@ pacing_ctx_t *pacing_create(uint64_t line_rate_bps, uint32_t frame_size, double
 void pacing_set_rate(pacing_ctx_t *ctx, double rate_pct);
 void pacing_set_batch_size(pacing_ctx_t *ctx, uint32_t batch_size);
 void pacing_set_busy_wait(pacing_ctx_t *ctx, bool enable);
+void pacing_apply_profile(pacing_ctx_t *ctx, const speed_profile_t *profile);
 uint64_t pacing_wait(pacing_ctx_t *ctx);
 uint64_t pacing_wait_batch(pacing_ctx_t *ctx, uint32_t batch_size);
 void pacing_record_tx(pacing_ctx_t *ctx, uint32_t packets, uint32_t bytes);
@@ -94,9 +112,11 @@ double trial_timer_elapsed(const trial_timer_t *timer);
 void trial_timer_destroy(trial_timer_t *timer);
 
 seq_tracker_t *rfc2544_seq_tracker_create(uint32_t capacity);
+int rfc2544_seq_tracker_reset(seq_tracker_t *tracker, uint32_t capacity);
 void rfc2544_seq_tracker_record(seq_tracker_t *tracker, uint32_t seq_num);
 void rfc2544_seq_tracker_stats(const seq_tracker_t *tracker, uint32_t expected, uint32_t *received,
                                uint32_t *lost, double *loss_pct);
+void rfc2544_seq_tracker_order(const seq_tracker_t *tracker, frame_order_t *order);
 void rfc2544_seq_tracker_destroy(seq_tracker_t *tracker);
 
 uint64_t calc_max_pps(uint64_t line_rate_bps, uint32_t frame_size);
@@ -149,6 +169,67 @@ void rfc2544_log(log_level_t level, const char *fmt, ...)
 	fprintf(stderr, "\n");
 }
 
+/* ============================================================================
+ * Version
+ * ============================================================================ */
+
+void rfc2544_get_version(rfc2544_version_t *version)
+{
+	if (!version)
+		return;
+	memset(version, 0, sizeof(*version));
+	version->major = RFC2544_VERSION_MAJOR;
+	version->minor = RFC2544_VERSION_MINOR;
+	version->patch = RFC2544_VERSION_PATCH;
+	version->abi = RFC2544_ABI_VERSION;
+	version->abi_compat = RFC2544_ABI_COMPAT;
+#if HAVE_AF_XDP
+	version->features |= RFC2544_FEATURE_AF_XDP;
+#endif
+#if HAVE_DPDK
+	version->features |= RFC2544_FEATURE_DPDK;
+#endif
+}
+
+/* A default mbuf holds one frame; ports are not set up for scattered RX */
+#define DPDK_MAX_FRAME_SIZE 2048
+
+int rfc2544_get_capabilities(const char *interface, bool use_dpdk, rfc2544_capabilities_t *caps)
+{
+	if (!caps)
+		return -EINVAL;
+	memset(caps, 0, sizeof(*caps));
+	caps->flags = RFC2544_CAP_Y1564 | RFC2544_CAP_RFC2889 | RFC2544_CAP_OAM | RFC2544_CAP_RECOVERY;
+	caps->max_streams = MAX_STREAMS;
+
+	/* The platform select_platform() would pick bounds the frame */
+	bool dpdk = false;
+#if HAVE_DPDK
+	caps->flags |= RFC2544_CAP_DPDK;
+	dpdk = use_dpdk;
+#endif
+	(void)use_dpdk;
+	caps->max_frame_size = dpdk ? DPDK_MAX_FRAME_SIZE : RFC2544_MAX_FRAME_SIZE;
+#if HAVE_AF_XDP
+	caps->flags |= RFC2544_CAP_AF_XDP;
+	if (!dpdk)
+		caps->max_frame_size = XDP_FRAME_SIZE - XDP_HEADROOM;
+#endif
+	if (!interface || !interface[0])
+		return 0;
+
+	nic_info_t info;
+	int ret = rfc2544_detect_nic(interface, &info);
+	if (ret < 0)
+		return ret;
+	if (info.supports_hw_ts)
+		caps->flags |= RFC2544_CAP_HW_TIMESTAMP;
+	/* The port carries its MTU plus the MAC header and FCS */
+	if (info.mtu && info.mtu + 18 < caps->max_frame_size)
+		caps->max_frame_size = info.mtu + 18;
+	return 0;
+}
+
 /* ============================================================================
  * Platform Selection
  * ============================================================================ */
@@ -326,14 +407,39 @@ uint64_t rfc2544_get_line_rate_ctx(const rfc2544_ctx_t *ctx)
 	return ctx ? ctx->line_rate : 0;
 }
 
+/* Built-in addresses of test frames, unless configured */
+static const uint8_t default_src_mac[6] = {0x02, 0x00, 0x00, 0x00, 0x00, 0x01};
+static const uint8_t default_dst_mac[6] = {0x02, 0x00, 0x00, 0x00, 0x00, 0x02};
+#define DEFAULT_SRC_IP 0x0A000001 /* 10.0.0.1 */
+#define DEFAULT_DST_IP 0x0A000002 /* 10.0.0.2 */
+
+/* UDP ports of test frames, unless configured; the source port is the
+ * base port of multi-stream traffic */
+#define TEST_UDP_SRC_PORT 12345
+#define TEST_UDP_DST_PORT 3842
+
+static bool mac_set(const uint8_t *mac)
+{
+	static const uint8_t zero[6];
+	return memcmp(mac, zero, 6) != 0;
+}
+
 void rfc2544_get_macs(const rfc2544_ctx_t *ctx, uint8_t *src_mac, uint8_t *dst_mac)
 {
 	if (!ctx)
 		return;
-	if (src_mac)
-		memcpy(src_mac, ctx->local_mac, 6);
-	if (dst_mac)
-		memcpy(dst_mac, ctx->remote_mac, 6);
+	if (src_mac) {
+		const uint8_t *mac = mac_set(ctx->headers.src_mac) ? ctx->headers.src_mac
+		                     : mac_set(ctx->local_mac)     ? ctx->local_mac
+		                                                   : default_src_mac;
+		memcpy(src_mac, mac, 6);
+	}
+	if (dst_mac) {
+		const uint8_t *mac = mac_set(ctx->headers.dst_mac) ? ctx->headers.dst_mac
+		                     : mac_set(ctx->remote_mac)    ? ctx->remote_mac
+		                                                   : default_dst_mac;
+		memcpy(dst_mac, mac, 6);
+	}
 }
 
 void rfc2544_get_ips(const rfc2544_ctx_t *ctx, uint32_t *src_ip, uint32_t *dst_ip)
@@ -341,9 +447,68 @@ void rfc2544_get_ips(const rfc2544_ctx_t *ctx, uint32_t *src_ip, uint32_t *dst_i
 	if (!ctx)
 		return;
 	if (src_ip)
-		*src_ip = ctx->local_ip;
+		*src_ip = ctx->headers.src_ip ? ctx->headers.src_ip
+		          : ctx->local_ip     ? ctx->local_ip
+		                              : htonl(DEFAULT_SRC_IP);
 	if (dst_ip)
-		*dst_ip = ctx->remote_ip;
+		*dst_ip = ctx->headers.dst_ip ? ctx->headers.dst_ip
+		          : ctx->remote_ip    ? ctx->remote_ip
+		                              : htonl(DEFAULT_DST_IP);
+}
+
+void rfc2544_get_ports(const rfc2544_ctx_t *ctx, uint16_t *src_port, uint16_t *dst_port)
+{
+	if (!ctx)
+		return;
+	if (src_port)
+		*src_port = ctx->headers.src_port ? ctx->headers.src_port : TEST_UDP_SRC_PORT;
+	if (dst_port)
+		*dst_port = ctx->headers.dst_port ? ctx->headers.dst_port : TEST_UDP_DST_PORT;
+}
+
+const header_fields_t *rfc2544_get_headers(const rfc2544_ctx_t *ctx)
+{
+	return ctx ? &ctx->headers : NULL;
+}
+
+const speed_profile_t *rfc2544_get_speed_profile(const rfc2544_ctx_t *ctx)
+{
+	return ctx ? ctx->speed_profile : NULL;
+}
+
+const qinq_config_t *rfc2544_get_qinq(const rfc2544_ctx_t *ctx)
+{
+	return ctx ? &ctx->qinq : NULL;
+}
+
+const mpls_config_t *rfc2544_get_mpls(const rfc2544_ctx_t *ctx)
+{
+	return ctx ? &ctx->mpls : NULL;
+}
+
+const ip_stack_config_t *rfc2544_get_ip(const rfc2544_ctx_t *ctx)
+{
+	return ctx ? &ctx->ip : NULL;
+}
+
+const payload_pattern_t *rfc2544_get_payload_pattern(const rfc2544_ctx_t *ctx)
+{
+	return ctx ? &ctx->pattern : NULL;
+}
+
+capture_file_t *rfc2544_get_capture(rfc2544_ctx_t *ctx, bool rx)
+{
+	return rx ? &ctx->capture_rx : &ctx->capture_tx;
+}
+
+void rfc2544_get_vlan(const rfc2544_ctx_t *ctx, uint16_t *vlan_id, uint8_t *pcp)
+{
+	if (!ctx)
+		return;
+	if (vlan_id)
+		*vlan_id = ctx->vlan_id;
+	if (pcp)
+		*pcp = ctx->vlan_pcp;
 }
 
 bool rfc2544_is_cancelled(const rfc2544_ctx_t *ctx)
@@ -351,6 +516,34 @@ bool rfc2544_is_cancelled(const rfc2544_ctx_t *ctx)
 	return ctx ? ctx->cancel_requested : true;
 }
 
+/* Only the thread running the test writes the heartbeat */
+static inline void heartbeat(rfc2544_ctx_t *ctx)
+{
+	__atomic_store_n(&ctx->heartbeat, ctx->heartbeat + 1, __ATOMIC_RELAXED);
+}
+
+/* Send and receive through the platform, saving the frames to the capture files */
+static inline int send_frames(rfc2544_ctx_t *ctx, worker_ctx_t *wctx, packet_t *pkts, int count)
+{
+	int sent = ctx->platform->send_batch(wctx, pkts, count);
+	for (int i = 0; ctx->capture_tx.file && i < sent; i++)
+		rfc2544_capture_frame(&ctx->capture_tx, pkts[i].data, pkts[i].len);
+	return sent;
+}
+
+static inline int recv_frames(rfc2544_ctx_t *ctx, worker_ctx_t *wctx, packet_t *pkts, int max_count)
+{
+	int count = ctx->platform->recv_batch(wctx, pkts, max_count);
+	for (int i = 0; ctx->capture_rx.file && i < count; i++)
+		rfc2544_capture_frame(&ctx->capture_rx, pkts[i].data, pkts[i].len);
+	return count;
+}
+
+uint64_t rfc2544_get_heartbeat(const rfc2544_ctx_t *ctx)
+{
+	return ctx ? __atomic_load_n(&ctx->heartbeat, __ATOMIC_RELAXED) : 0;
+}
+
 void rfc2544_log_internal(log_level_t level, const char *fmt, ...)
 {
 	if (level > g_log_level)
@@ -394,10 +587,12 @@ int rfc2544_init(rfc2544_ctx_t **ctx_out, const char *interface)
 	/* Get line rate */
 	ctx->line_rate = rfc2544_get_line_rate(interface);
 	ctx->config.line_rate = ctx->line_rate;
+	ctx->speed_profile = rfc2544_speed_profile(ctx->line_rate);
 
 	/* Initialize locks */
 	pthread_mutex_init(&ctx->seq_lock, NULL);
 	pthread_mutex_init(&ctx->latency_lock, NULL);
+	pthread_mutex_init(&ctx->live_lock, NULL);
 
 	/* Allocate latency sample buffer */
 	ctx->latency_sample_capacity = 100000;
@@ -405,6 +600,7 @@ int rfc2544_init(rfc2544_ctx_t **ctx_out, const char *interface)
 	if (!ctx->latency_samples) {
 		pthread_mutex_destroy(&ctx->seq_lock);
 		pthread_mutex_destroy(&ctx->latency_lock);
+		pthread_mutex_destroy(&ctx->live_lock);
 		free(ctx);
 		return -ENOMEM;
 	}
@@ -414,8 +610,8 @@ int rfc2544_init(rfc2544_ctx_t **ctx_out, const char *interface)
 
 	rfc2544_log(LOG_INFO, "RFC2544 Test Master v%d.%d.%d initialized", RFC2544_VERSION_MAJOR,
 	            RFC2544_VERSION_MINOR, RFC2544_VERSION_PATCH);
-	rfc2544_log(LOG_INFO, "Interface: %s, Line rate: %.2f Gbps", interface,
-	            ctx->line_rate / 1e9);
+	rfc2544_log(LOG_INFO, "Interface: %s, Line rate: %.2f Gbps, speed profile %s", interface,
+	            ctx->line_rate / 1e9, ctx->speed_profile->name);
 
 	return 0;
 }
@@ -452,148 +648,640 @@ void rfc2544_set_progress_callback(rfc2544_ctx_t *ctx, progress_callback_t callb
 		ctx->progress_cb = callback;
 }
 
-test_state_t rfc2544_get_state(const rfc2544_ctx_t *ctx)
+int rfc2544_set_acceptable_loss(rfc2544_ctx_t *ctx, double loss_pct)
 {
-	return ctx ? ctx->state : STATE_IDLE;
+	if (!ctx || loss_pct < 0 || loss_pct > 100)
+		return -EINVAL;
+
+	ctx->config.acceptable_loss = loss_pct;
+	return 0;
 }
 
-void rfc2544_cancel(rfc2544_ctx_t *ctx)
+int rfc2544_addresses_configure(rfc2544_ctx_t *ctx, const address_config_t *config)
 {
-	if (ctx) {
-		ctx->cancel_requested = true;
-		rfc2544_log(LOG_INFO, "Cancellation requested");
+	if (!ctx || !config)
+		return -EINVAL;
+
+	if (config->pair_count > MAX_ADDRESS_PAIRS)
+		return -EINVAL;
+
+	address_pair_t *pairs = NULL;
+	if (config->pairs && config->pair_count) {
+		pairs = malloc(config->pair_count * sizeof(*pairs));
+		if (!pairs)
+			return -ENOMEM;
+		memcpy(pairs, config->pairs, config->pair_count * sizeof(*pairs));
 	}
+	free((void *)ctx->addresses.pairs);
+	ctx->addresses = *config;
+	ctx->addresses.pairs = pairs;
+	/* New addresses are learned afresh before the next trial */
+	memset(&ctx->learn_stats, 0, sizeof(ctx->learn_stats));
+
+	rfc2544_log(LOG_INFO, "Address pairs configured: %u%s", config->pair_count,
+	            pairs ? " (from pools)" : "");
+
+	return 0;
 }
 
-void rfc2544_cleanup(rfc2544_ctx_t *ctx)
+int rfc2544_headers_configure(rfc2544_ctx_t *ctx, const header_fields_t *config)
 {
-	if (!ctx)
-		return;
+	if (!ctx || !config)
+		return -EINVAL;
 
-	/* Cancel if running */
-	if (ctx->state == STATE_RUNNING) {
-		rfc2544_cancel(ctx);
-		/* Wait for completion with timeout (max 10 seconds) */
-		int timeout_count = 0;
-		while (ctx->state == STATE_RUNNING && timeout_count < 1000) {
-			usleep(10000);
-			timeout_count++;
-		}
-		if (ctx->state == STATE_RUNNING) {
-			rfc2544_log(LOG_ERROR, "Cleanup timeout waiting for test to stop");
-		}
-	}
+	uint16_t src_port = config->src_port ? config->src_port : TEST_UDP_SRC_PORT;
+	uint16_t dst_port = config->dst_port ? config->dst_port : TEST_UDP_DST_PORT;
+	uint32_t src_ip = config->src_ip ? ntohl(config->src_ip) : DEFAULT_SRC_IP;
+	uint32_t dst_ip = config->dst_ip ? ntohl(config->dst_ip) : DEFAULT_DST_IP;
+	if ((config->src_port_count > 1 && src_port + config->src_port_count - 1u > UINT16_MAX) ||
+	    (config->dst_port_count > 1 && dst_port + config->dst_port_count - 1u > UINT16_MAX) ||
+	    (config->src_ip_count > 1 && src_ip + (uint64_t)config->src_ip_count - 1 > UINT32_MAX) ||
+	    (config->dst_ip_count > 1 && dst_ip + (uint64_t)config->dst_ip_count - 1 > UINT32_MAX) ||
+	    config->dscp > 63)
+		return -EINVAL;
 
-	/* Cleanup platform */
-	if (ctx->platform && ctx->workers) {
-		for (int i = 0; i < ctx->num_workers; i++) {
-			ctx->platform->cleanup(&ctx->workers[i]);
-		}
-		free(ctx->workers);
+	ctx->headers = *config;
+
+	rfc2544_log(LOG_INFO, "Header fields: UDP %u -> %u, %u/%u addresses, %u/%u ports, DSCP %u",
+	            src_port, dst_port, config->src_ip_count ? config->src_ip_count : 1,
+	            config->dst_ip_count ? config->dst_ip_count : 1,
+	            config->src_port_count ? config->src_port_count : 1,
+	            config->dst_port_count ? config->dst_port_count : 1, config->dscp);
+	return 0;
+}
+
+int rfc2544_payload_pattern_configure(rfc2544_ctx_t *ctx, const payload_pattern_t *pattern)
+{
+	if (!ctx)
+		return -EINVAL;
+	if (!pattern) {
+		memset(&ctx->pattern, 0, sizeof(ctx->pattern));
+		return 0;
 	}
+	if (pattern->kind > PAYLOAD_CUSTOM ||
+	    (pattern->kind == PAYLOAD_CUSTOM &&
+	     (pattern->len == 0 || pattern->len > MAX_PAYLOAD_PATTERN)))
+		return -EINVAL;
 
-	/* Free resources */
-	free(ctx->latency_samples);
-	pthread_mutex_destroy(&ctx->seq_lock);
-	pthread_mutex_destroy(&ctx->latency_lock);
-	free(ctx);
+	static const char *const names[] = {"incrementing", "zeros", "ones",
+	                                    "random", "PRBS-31", "custom"};
+	ctx->pattern = *pattern;
+	if (pattern->kind != PAYLOAD_INCREMENTING)
+		rfc2544_log(LOG_INFO, "Payload pattern configured: %s", names[pattern->kind]);
+	return 0;
+}
 
-	rfc2544_log(LOG_INFO, "Cleanup complete");
+int rfc2544_addresses_set_learn_rate(rfc2544_ctx_t *ctx, uint32_t rate)
+{
+	if (!ctx)
+		return -EINVAL;
+
+	ctx->learn_rate = rate;
+	return 0;
 }
 
-/* ============================================================================
- * Test Execution
- * ============================================================================ */
+int rfc2544_addresses_get_learn_stats(const rfc2544_ctx_t *ctx, address_learn_stats_t *stats)
+{
+	if (!ctx || !stats)
+		return -EINVAL;
 
-void report_progress(rfc2544_ctx_t *ctx, const char *message, double pct)
+	*stats = ctx->learn_stats;
+	return 0;
+}
+
+int rfc2544_framing_configure(rfc2544_ctx_t *ctx, const framing_config_t *config)
 {
-	if (ctx->progress_cb) {
-		ctx->progress_cb(ctx, message, pct);
-	}
-	if (ctx->config.verbose) {
-		rfc2544_log(LOG_INFO, "[%.1f%%] %s", pct, message);
+	if (!ctx || !config)
+		return -EINVAL;
+
+	if (config->mode != FRAMING_ETHERNET_II && config->mode != FRAMING_LLC_SNAP)
+		return -EINVAL;
+
+	/* Values below 0x0600 are 802.3 lengths, not EtherTypes */
+	if (config->ethertype != 0 && config->ethertype < 0x0600)
+		return -EINVAL;
+
+	ctx->framing = *config;
+
+	rfc2544_log(LOG_INFO, "Framing configured: %s, type 0x%04x",
+	            config->mode == FRAMING_LLC_SNAP ? "LLC/SNAP" : "Ethernet II",
+	            config->ethertype ? config->ethertype : 0x0800);
+
+	return 0;
+}
+
+int rfc2544_vlan_configure(rfc2544_ctx_t *ctx, uint16_t vlan_id, uint8_t pcp)
+{
+	if (!ctx || vlan_id > VLAN_MAX_ID || pcp > 7)
+		return -EINVAL;
+
+	ctx->vlan_id = vlan_id;
+	ctx->vlan_pcp = pcp;
+	if (vlan_id || pcp)
+		rfc2544_log(LOG_INFO, "VLAN tag configured: VID %u, PCP %u", vlan_id, pcp);
+	return 0;
+}
+
+int rfc2544_qinq_configure(rfc2544_ctx_t *ctx, const qinq_config_t *config)
+{
+	if (!ctx || !config)
+		return -EINVAL;
+	if ((config->s_tpid && !rfc2544_valid_tpid(config->s_tpid)) ||
+	    (config->c_tpid && !rfc2544_valid_tpid(config->c_tpid)) ||
+	    config->s_vlan_id > VLAN_MAX_ID || config->s_pcp > 7)
+		return -EINVAL;
+
+	ctx->qinq = *config;
+	if (config->s_vlan_id || config->s_pcp)
+		rfc2544_log(LOG_INFO, "QinQ S-tag configured: TPID 0x%04x, VID %u, PCP %u",
+		            config->s_tpid ? config->s_tpid : TPID_8021AD, config->s_vlan_id,
+		            config->s_pcp);
+	return 0;
+}
+
+int rfc2544_mpls_configure(rfc2544_ctx_t *ctx, const mpls_config_t *config)
+{
+	if (!ctx || !config || config->count > MAX_MPLS_LABELS)
+		return -EINVAL;
+	for (uint32_t i = 0; i < config->count; i++) {
+		if (config->labels[i].label > MPLS_MAX_LABEL || config->labels[i].exp > 7)
+			return -EINVAL;
 	}
+
+	ctx->mpls = *config;
+	for (uint32_t i = 0; i < config->count; i++)
+		rfc2544_log(LOG_INFO, "MPLS label %u: %u, EXP %u, TTL %u", i, config->labels[i].label,
+		            config->labels[i].exp, config->labels[i].ttl ? config->labels[i].ttl : 64);
+	return 0;
 }
 
-int rfc2544_run(rfc2544_ctx_t *ctx)
+int rfc2544_speed_profile_configure(rfc2544_ctx_t *ctx, const char *name)
 {
 	if (!ctx)
 		return -EINVAL;
 
-	if (ctx->state == STATE_RUNNING) {
-		rfc2544_log(LOG_ERROR, "Test already running");
-		return -EBUSY;
-	}
+	const speed_profile_t *profile = (name && *name) ? rfc2544_speed_profile_by_name(name)
+	                                                 : rfc2544_speed_profile(ctx->line_rate);
+	if (!profile)
+		return -EINVAL;
 
-	/* Select platform */
-	ctx->platform = select_platform(ctx);
-	if (!ctx->platform) {
-		ctx->state = STATE_FAILED;
-		return -ENOTSUP;
-	}
+	ctx->speed_profile = profile;
+	rfc2544_log(LOG_DEBUG, "Speed profile %s: batch %u, %s", profile->name,
+	            profile->batch_size, profile->busy_wait ? "busy-wait" : "sleep");
+	return 0;
+}
 
-	/* Allocate workers (single worker for now) */
-	ctx->num_workers = 1;
-	ctx->workers = calloc((size_t)ctx->num_workers, sizeof(worker_ctx_t));
-	if (!ctx->workers) {
-		ctx->state = STATE_FAILED;
-		return -ENOMEM;
-	}
+int rfc2544_verify_payload_configure(rfc2544_ctx_t *ctx, bool enabled)
+{
+	if (!ctx)
+		return -EINVAL;
 
-	/* Initialize platform */
-	for (int i = 0; i < ctx->num_workers; i++) {
-		ctx->workers[i].worker_id = i;
-		ctx->workers[i].queue_id = i;
-		if (ctx->platform->init(ctx, &ctx->workers[i]) < 0) {
-			rfc2544_log(LOG_ERROR, "Failed to initialize platform");
-			/* Cleanup already-initialized workers */
-			for (int j = 0; j < i; j++) {
-				ctx->platform->cleanup(&ctx->workers[j]);
-			}
-			free(ctx->workers);
-			ctx->workers = NULL;
-			ctx->state = STATE_FAILED;
-			return -EIO;
-		}
+	ctx->verify_payload = enabled;
+	if (enabled)
+		rfc2544_log(LOG_INFO, "Payload verification enabled: CRC-32 trailer in each frame");
+	return 0;
+}
+
+int rfc2544_streams_configure(rfc2544_ctx_t *ctx, uint32_t stream_count)
+{
+	if (!ctx || stream_count > MAX_STREAMS)
+		return -EINVAL;
+
+	stream_stats_t *stats = NULL;
+	if (stream_count > 1) {
+		stats = calloc(stream_count, sizeof(*stats));
+		if (!stats)
+			return -ENOMEM;
 	}
 
-	ctx->state = STATE_RUNNING;
-	ctx->cancel_requested = false;
-	clock_gettime(CLOCK_MONOTONIC, &ctx->start_time);
+	pthread_mutex_lock(&ctx->latency_lock);
+	free(ctx->stream_stats);
+	ctx->stream_stats = stats;
+	ctx->stream_stats_count = 0;
+	ctx->stream_count = stream_count > 1 ? stream_count : 0;
+	pthread_mutex_unlock(&ctx->latency_lock);
 
-	int ret = 0;
+	if (stream_count > 1)
+		rfc2544_log(LOG_INFO, "Streams configured: %u flows", stream_count);
+	return 0;
+}
 
-	/* Get frame sizes to test */
-	uint32_t frame_sizes[8];
-	int num_sizes = 0;
+int rfc2544_get_stream_stats(rfc2544_ctx_t *ctx, stream_stats_t *stats, uint32_t max_count)
+{
+	if (!ctx || !stats)
+		return -EINVAL;
 
-	if (ctx->config.frame_size > 0) {
-		/* Specific size */
-		frame_sizes[num_sizes++] = ctx->config.frame_size;
-	} else {
-		/* Standard sizes */
-		uint32_t std_sizes[] = RFC2544_FRAME_SIZES;
-		for (int i = 0; i < RFC2544_FRAME_SIZE_COUNT; i++) {
-			frame_sizes[num_sizes++] = std_sizes[i];
-		}
-		if (ctx->config.include_jumbo) {
-			frame_sizes[num_sizes++] = FRAME_SIZE_9000;
-		}
+	pthread_mutex_lock(&ctx->latency_lock);
+	uint32_t n = ctx->stream_stats_count;
+	if (n > max_count)
+		n = max_count;
+	if (n)
+		memcpy(stats, ctx->stream_stats, n * sizeof(*stats));
+	pthread_mutex_unlock(&ctx->latency_lock);
+	return (int)n;
+}
+
+int rfc2544_template_configure(rfc2544_ctx_t *ctx, const header_template_t *tpl)
+{
+	if (!ctx || !tpl)
+		return -EINVAL;
+
+	if (tpl->header_len) {
+		/* Ethernet, then an IP header, then UDP ending the header */
+		if (tpl->header_len > MAX_TEMPLATE_HEADER || tpl->udp_offset < 14 ||
+		    tpl->udp_offset + 8 != tpl->header_len)
+			return -EINVAL;
+		if ((tpl->ipv4_offset < 0) == (tpl->ipv6_offset < 0))
+			return -EINVAL;
+		if (tpl->ipv4_offset >= 0 && tpl->ipv4_offset + 20 > tpl->udp_offset)
+			return -EINVAL;
+		if (tpl->ipv6_offset >= 0 && tpl->ipv6_offset + 40 > tpl->udp_offset)
+			return -EINVAL;
 	}
 
-	/* Run appropriate test */
-	switch (ctx->config.test_type) {
-	case TEST_THROUGHPUT:
-		report_progress(ctx, "Starting throughput test", 0);
-		for (int i = 0; i < num_sizes && !ctx->cancel_requested; i++) {
-			double pct = (i * 100.0) / num_sizes;
-			char msg[128];
-			snprintf(msg, sizeof(msg), "Testing frame size %u", frame_sizes[i]);
-			report_progress(ctx, msg, pct);
+	ctx->tpl = *tpl;
 
-			ret = rfc2544_throughput_test(ctx, frame_sizes[i],
-			                              &ctx->throughput_results[ctx->throughput_count],
-			                              NULL);
+	if (tpl->header_len)
+		rfc2544_log(LOG_INFO, "Packet template configured: %u header bytes", tpl->header_len);
+	else
+		rfc2544_log(LOG_INFO, "Packet template cleared");
+
+	return 0;
+}
+
+int rfc2544_cpp_configure(rfc2544_ctx_t *ctx, const cpp_config_t *config)
+{
+	if (!ctx || !config)
+		return -EINVAL;
+
+	bool enabled = config->icmp_pps || config->arp_pps || config->bgp_pps;
+	if (enabled && config->dut_ip == 0)
+		return -EINVAL;
+
+	ctx->cpp = *config;
+	memset(&ctx->cpp_stats, 0, sizeof(ctx->cpp_stats));
+
+	if (enabled)
+		rfc2544_log(LOG_INFO, "Control-plane stress: ICMP %u pps, ARP %u pps, BGP %u pps",
+		            config->icmp_pps, config->arp_pps, config->bgp_pps);
+
+	return 0;
+}
+
+int rfc2544_cpp_get_stats(const rfc2544_ctx_t *ctx, cpp_stats_t *stats)
+{
+	if (!ctx || !stats)
+		return -EINVAL;
+
+	*stats = ctx->cpp_stats;
+	return 0;
+}
+
+int rfc2544_latency_probe_configure(rfc2544_ctx_t *ctx, const latency_probe_config_t *config)
+{
+	if (!ctx || !config || config->pcp > 7 || config->dscp > 63)
+		return -EINVAL;
+
+	ctx->probe = *config;
+	memset(&ctx->probe_stats, 0, sizeof(ctx->probe_stats));
+
+	if (config->pps)
+		rfc2544_log(LOG_INFO, "Latency probe: %u pps, PCP %u, DSCP %u", config->pps,
+		            config->pcp, config->dscp);
+
+	return 0;
+}
+
+int rfc2544_latency_probe_get_stats(const rfc2544_ctx_t *ctx, latency_probe_stats_t *stats)
+{
+	if (!ctx || !stats)
+		return -EINVAL;
+
+	*stats = ctx->probe_stats;
+	return 0;
+}
+
+int rfc2544_ip_configure(rfc2544_ctx_t *ctx, const ip_stack_config_t *config)
+{
+	if (!ctx || !config || config->mode > IP_MODE_DUAL || config->v6_share_pct >= 100 ||
+	    config->ipv6.flow_label > IPV6_MAX_FLOW_LABEL)
+		return -EINVAL;
+
+	ctx->ip = *config;
+	memset(ctx->family_stats, 0, sizeof(ctx->family_stats));
+
+	if (config->mode == IP_MODE_DUAL)
+		rfc2544_log(LOG_INFO, "Dual-stack frames: %u%% IPv6",
+		            config->v6_share_pct ? config->v6_share_pct : 50);
+	else if (config->mode == IP_MODE_V6)
+		rfc2544_log(LOG_INFO, "IPv6 frames: TC %u, flow label %u", config->ipv6.traffic_class,
+		            config->ipv6.flow_label);
+
+	return 0;
+}
+
+int rfc2544_ip_family_get_stats(const rfc2544_ctx_t *ctx, ip_family_stats_t *v4,
+                                ip_family_stats_t *v6)
+{
+	if (!ctx || !v4 || !v6)
+		return -EINVAL;
+
+	*v4 = ctx->family_stats[0];
+	*v6 = ctx->family_stats[1];
+	return 0;
+}
+
+int rfc2544_modifiers_configure(rfc2544_ctx_t *ctx, const modifier_config_t *config)
+{
+	if (!ctx || !config)
+		return -EINVAL;
+
+	if (config->broadcast_pct < 0.0 || config->broadcast_pct > MAX_BROADCAST_PCT)
+		return -EINVAL;
+
+	ctx->modifiers = *config;
+
+	rfc2544_log(LOG_INFO, "Modifiers configured: broadcast=%.2f%%", config->broadcast_pct);
+
+	return 0;
+}
+
+test_state_t rfc2544_get_state(const rfc2544_ctx_t *ctx)
+{
+	return ctx ? ctx->state : STATE_IDLE;
+}
+
+int rfc2544_get_live_stats(rfc2544_ctx_t *ctx, live_stats_t *stats)
+{
+	if (!ctx || !stats)
+		return -EINVAL;
+
+	pthread_mutex_lock(&ctx->live_lock);
+	*stats = ctx->live;
+	if (stats->in_trial)
+		stats->trial_elapsed_ns = get_timestamp_ns() - ctx->live_trial_start_ns;
+	pthread_mutex_unlock(&ctx->live_lock);
+	return 0;
+}
+
+int rfc2544_get_latency_samples(rfc2544_ctx_t *ctx, uint64_t *samples, uint32_t max_count)
+{
+	if (!ctx || !samples)
+		return -EINVAL;
+
+	pthread_mutex_lock(&ctx->latency_lock);
+	uint32_t n = ctx->latency_sample_count;
+	if (n > max_count)
+		n = max_count;
+	memcpy(samples, ctx->latency_samples, n * sizeof(uint64_t));
+	pthread_mutex_unlock(&ctx->latency_lock);
+	return (int)n;
+}
+
+int rfc2544_latency_samples_configure(rfc2544_ctx_t *ctx, uint32_t capacity)
+{
+	if (!ctx || capacity > MAX_LATENCY_SAMPLES)
+		return -EINVAL;
+
+	/* The samples kept for rfc2544_get_latency_samples must hold a trial's */
+	uint32_t n = capacity ? capacity : DEFAULT_LATENCY_SAMPLES;
+	pthread_mutex_lock(&ctx->latency_lock);
+	if (n > ctx->latency_sample_capacity) {
+		uint64_t *kept = realloc(ctx->latency_samples, n * sizeof(uint64_t));
+		if (!kept) {
+			pthread_mutex_unlock(&ctx->latency_lock);
+			return -ENOMEM;
+		}
+		ctx->latency_samples = kept;
+		ctx->latency_sample_capacity = n;
+	}
+	pthread_mutex_unlock(&ctx->latency_lock);
+
+	ctx->sample_capacity = capacity;
+	if (capacity)
+		rfc2544_log(LOG_INFO, "Latency samples per trial: %u", n);
+	return 0;
+}
+
+/* Add test frames seen since the last publish to the live counters */
+static void live_publish(rfc2544_ctx_t *ctx, uint32_t frame_size, uint64_t *tx, uint64_t *rx)
+{
+	pthread_mutex_lock(&ctx->live_lock);
+	ctx->live.tx_packets += *tx;
+	ctx->live.tx_bytes += *tx * frame_size;
+	ctx->live.rx_packets += *rx;
+	ctx->live.rx_bytes += *rx * frame_size;
+	pthread_mutex_unlock(&ctx->live_lock);
+	*tx = 0;
+	*rx = 0;
+}
+
+void rfc2544_cancel(rfc2544_ctx_t *ctx)
+{
+	if (ctx) {
+		ctx->cancel_requested = true;
+		rfc2544_log(LOG_INFO, "Cancellation requested");
+	}
+}
+
+void rfc2544_clear_cancel(rfc2544_ctx_t *ctx)
+{
+	if (ctx)
+		ctx->cancel_requested = false;
+}
+
+void rfc2544_cleanup(rfc2544_ctx_t *ctx)
+{
+	if (!ctx)
+		return;
+
+	/* Cancel if running */
+	if (ctx->state == STATE_RUNNING) {
+		rfc2544_cancel(ctx);
+		/* Wait for completion with timeout (max 10 seconds) */
+		int timeout_count = 0;
+		while (ctx->state == STATE_RUNNING && timeout_count < 1000) {
+			usleep(10000);
+			timeout_count++;
+		}
+		if (ctx->state == STATE_RUNNING) {
+			rfc2544_log(LOG_ERROR, "Cleanup timeout waiting for test to stop");
+		}
+	}
+
+	/* Cleanup platform */
+	if (ctx->platform && ctx->workers) {
+		for (int i = 0; i < ctx->num_workers; i++) {
+			ctx->platform->cleanup(&ctx->workers[i]);
+		}
+		free(ctx->workers);
+	}
+
+	/* Free resources */
+	free(ctx->latency_samples);
+	for (int i = 0; i < 4; i++)
+		free(ctx->pool_samples[i].data);
+	free(ctx->pool_sample_streams.data);
+	rfc2544_seq_tracker_destroy(ctx->pool_tracker);
+	rfc2544_capture_close(ctx);
+	free(ctx->stream_stats);
+	free((void *)ctx->addresses.pairs);
+	pthread_mutex_destroy(&ctx->seq_lock);
+	pthread_mutex_destroy(&ctx->latency_lock);
+	pthread_mutex_destroy(&ctx->live_lock);
+	free(ctx);
+
+	rfc2544_log(LOG_INFO, "Cleanup complete");
+}
+
+/* ============================================================================
+ * Test Execution
+ * ============================================================================ */
+
+int rfc2544_open_ports(rfc2544_ctx_t *ctx)
+{
+	if (!ctx)
+		return -EINVAL;
+	if (ctx->workers)
+		return 0;
+
+	/* Select platform */
+	ctx->platform = select_platform(ctx);
+	if (!ctx->platform)
+		return -ENOTSUP;
+
+	/* One worker per port: TX on the interface, RX through the DUT */
+	bool two_ports = ctx->config.rx_interface[0] &&
+	                 strcmp(ctx->config.rx_interface, ctx->config.interface) != 0;
+	if (two_ports) {
+		if (ctx->config.use_dpdk) {
+			rfc2544_log(LOG_ERROR, "DPDK drives a single port; use AF_PACKET or AF_XDP for a port pair");
+			return -ENOTSUP;
+		}
+		/* Two NICs stamp from two clocks, so only software time compares */
+		if (ctx->config.hw_timestamp) {
+			rfc2544_log(LOG_WARN, "Port pair: using software timestamps for latency");
+			ctx->config.hw_timestamp = false;
+		}
+	}
+
+	ctx->num_workers = two_ports ? 2 : 1;
+	ctx->workers = calloc((size_t)ctx->num_workers, sizeof(worker_ctx_t));
+	if (!ctx->workers)
+		return -ENOMEM;
+
+	/* Initialize platform */
+	for (int i = 0; i < ctx->num_workers; i++) {
+		worker_ctx_t *w = &ctx->workers[i];
+		w->worker_id = i;
+		w->queue_id = 0;
+		strncpy(w->interface, i == 0 ? ctx->config.interface : ctx->config.rx_interface,
+		        sizeof(w->interface) - 1);
+		if (ctx->platform->init(ctx, w) < 0) {
+			rfc2544_log(LOG_ERROR, "Failed to initialize platform on %s", w->interface);
+			/* Cleanup already-initialized workers */
+			for (int j = 0; j < i; j++) {
+				ctx->platform->cleanup(&ctx->workers[j]);
+			}
+			free(ctx->workers);
+			ctx->workers = NULL;
+			ctx->num_workers = 0;
+			return -EIO;
+		}
+	}
+
+	if (two_ports)
+		rfc2544_log(LOG_INFO, "Port pair: TX %s, RX %s", ctx->workers[0].interface,
+		            ctx->workers[1].interface);
+	return 0;
+}
+
+worker_ctx_t *rfc2544_rx_worker(rfc2544_ctx_t *ctx)
+{
+	if (!ctx || !ctx->workers)
+		return NULL;
+	return &ctx->workers[ctx->num_workers > 1 ? 1 : 0];
+}
+
+int rfc2544_get_port_stats(rfc2544_ctx_t *ctx, port_stats_t *stats, uint32_t max_count)
+{
+	if (!ctx || !stats)
+		return -EINVAL;
+
+	uint32_t n = 0;
+	for (int i = 0; i < ctx->num_workers && n < max_count; i++, n++) {
+		const worker_ctx_t *w = &ctx->workers[i];
+		memset(&stats[n], 0, sizeof(stats[n]));
+		strncpy(stats[n].interface, w->interface, sizeof(stats[n].interface) - 1);
+		stats[n].tx_packets = w->tx_packets;
+		stats[n].rx_packets = w->rx_packets;
+		stats[n].tx_errors = w->tx_errors;
+		stats[n].rx_errors = w->rx_errors;
+	}
+	return (int)n;
+}
+
+void report_progress(rfc2544_ctx_t *ctx, const char *message, double pct)
+{
+	if (ctx->progress_cb) {
+		ctx->progress_cb(ctx, message, pct);
+	}
+	if (ctx->config.verbose) {
+		rfc2544_log(LOG_INFO, "[%.1f%%] %s", pct, message);
+	}
+}
+
+int rfc2544_run(rfc2544_ctx_t *ctx)
+{
+	if (!ctx)
+		return -EINVAL;
+
+	if (ctx->state == STATE_RUNNING) {
+		rfc2544_log(LOG_ERROR, "Test already running");
+		return -EBUSY;
+	}
+
+	int ret = rfc2544_open_ports(ctx);
+	if (ret < 0) {
+		ctx->state = STATE_FAILED;
+		return ret;
+	}
+
+	ctx->state = STATE_RUNNING;
+	ctx->cancel_requested = false;
+	clock_gettime(CLOCK_MONOTONIC, &ctx->start_time);
+
+	/* Get frame sizes to test */
+	uint32_t frame_sizes[8];
+	int num_sizes = 0;
+
+	if (ctx->config.frame_size > 0) {
+		/* Specific size */
+		frame_sizes[num_sizes++] = ctx->config.frame_size;
+	} else {
+		/* Standard sizes */
+		uint32_t std_sizes[] = RFC2544_FRAME_SIZES;
+		for (int i = 0; i < RFC2544_FRAME_SIZE_COUNT; i++) {
+			frame_sizes[num_sizes++] = std_sizes[i];
+		}
+		if (ctx->config.include_jumbo) {
+			frame_sizes[num_sizes++] = FRAME_SIZE_9000;
+		}
+	}
+
+	/* Run appropriate test */
+	switch (ctx->config.test_type) {
+	case TEST_THROUGHPUT:
+		report_progress(ctx, "Starting throughput test", 0);
+		for (int i = 0; i < num_sizes && !ctx->cancel_requested; i++) {
+			double pct = (i * 100.0) / num_sizes;
+			char msg[128];
+			snprintf(msg, sizeof(msg), "Testing frame size %u", frame_sizes[i]);
+			report_progress(ctx, msg, pct);
+
+			ret = rfc2544_throughput_test(ctx, frame_sizes[i],
+			                              &ctx->throughput_results[ctx->throughput_count],
+			                              NULL);
 			if (ret < 0)
 				break;
 			ctx->throughput_count++;
@@ -727,6 +1415,299 @@ int rfc2544_run(rfc2544_ctx_t *ctx)
 
 /* trial_result_t is defined in rfc2544_internal.h */
 
+static const uint8_t broadcast_mac[6] = {0xff, 0xff, 0xff, 0xff, 0xff, 0xff};
+
+/* Section 11.1 broadcast frames echoed back with either MAC still broadcast */
+static bool is_broadcast_echo(const uint8_t *data, uint32_t len)
+{
+	if (len < 12)
+		return false;
+	return memcmp(data, broadcast_mac, 6) == 0 || memcmp(data + 6, broadcast_mac, 6) == 0;
+}
+
+/* Section 11.1 broadcast share, kept exact as the dual-stack IPv6 share is */
+bool rfc2544_broadcast_slot(double *acc, double pct)
+{
+	*acc += pct;
+	if (*acc < 100.0)
+		return false;
+	*acc -= 100.0;
+	return true;
+}
+
+/* A latency probe frame: the trial's frame with the probe's stream ID */
+static bool is_probe_frame(const uint8_t *data, uint32_t len, uint32_t offset)
+{
+	uint32_t sid;
+	return rfc2544_parse_stream_id(data, len, offset, &sid) && sid == RFC2544_PROBE_STREAM;
+}
+
+/* Send any control-plane frames that are due, one per kind per call */
+static void cpp_send_due(rfc2544_ctx_t *ctx, worker_ctx_t *wctx, const uint8_t *src_mac,
+                         const uint8_t *dut_mac, uint32_t src_ip, uint64_t *next_ns,
+                         uint16_t *seq)
+{
+	static const cpp_frame_t kinds[3] = {CPP_FRAME_ICMP, CPP_FRAME_ARP, CPP_FRAME_BGP};
+	const uint32_t rates[3] = {ctx->cpp.icmp_pps, ctx->cpp.arp_pps, ctx->cpp.bgp_pps};
+	uint64_t *sent[3] = {&ctx->cpp_stats.icmp_sent, &ctx->cpp_stats.arp_sent,
+	                     &ctx->cpp_stats.bgp_sent};
+	uint64_t now = get_timestamp_ns();
+
+	for (int i = 0; i < 3; i++) {
+		if (!rates[i] || now < next_ns[i])
+			continue;
+
+		uint8_t frame[CPP_FRAME_LEN];
+		packet_t pkt;
+		memset(&pkt, 0, sizeof(pkt));
+		pkt.data = frame;
+		pkt.len = rfc2544_build_control_frame(frame, sizeof(frame), kinds[i], src_mac,
+		                                      dut_mac, src_ip, ctx->cpp.dut_ip, (*seq)++);
+		if (send_frames(ctx, wctx, &pkt, 1) > 0)
+			(*sent[i])++;
+
+		/* Stay on schedule, but never burst to catch up after a stall */
+		uint64_t interval = 1000000000ULL / rates[i];
+		next_ns[i] += interval;
+		if (next_ns[i] < now)
+			next_ns[i] = now + interval;
+	}
+}
+
+/* Count a DUT reply to a control-plane frame; true if the frame was one */
+static bool cpp_count_reply(rfc2544_ctx_t *ctx, const uint8_t *data, uint32_t len)
+{
+	switch (rfc2544_classify_control_reply(data, len, ctx->cpp.dut_ip)) {
+	case CPP_FRAME_ICMP:
+		ctx->cpp_stats.icmp_replies++;
+		return true;
+	case CPP_FRAME_ARP:
+		ctx->cpp_stats.arp_replies++;
+		return true;
+	case CPP_FRAME_BGP:
+		ctx->cpp_stats.bgp_replies++;
+		return true;
+	default:
+		return false;
+	}
+}
+
+/* Write pair i's addresses into a built-in header frame: the pool pair,
+ * or the source MAC + i with addresses shifted into the i-th /24, which
+ * config validation keeps inside the base addresses' /8 */
+static void set_pair_addresses(uint8_t *pkt, const address_pair_t *pool_pairs, uint32_t pair,
+                               const uint8_t *src_mac, const uint8_t *dst_mac, uint32_t src_ip,
+                               uint32_t dst_ip)
+{
+	static const uint8_t zero_mac[6] = {0};
+
+	if (pool_pairs) {
+		const address_pair_t *p = &pool_pairs[pair];
+		memcpy(pkt, memcmp(p->dst_mac, zero_mac, 6) ? p->dst_mac : dst_mac, 6);
+		rfc2544_set_packet_addresses(pkt, memcmp(p->src_mac, zero_mac, 6) ? p->src_mac : src_mac,
+		                             p->src_ip ? p->src_ip : src_ip,
+		                             p->dst_ip ? p->dst_ip : dst_ip);
+		return;
+	}
+
+	uint8_t pair_mac[6];
+	memcpy(pair_mac, src_mac, 6);
+	uint16_t mac_lo = (uint16_t)(((src_mac[4] << 8) | src_mac[5]) + pair);
+	pair_mac[4] = mac_lo >> 8;
+	pair_mac[5] = mac_lo & 0xff;
+	rfc2544_set_packet_addresses(pkt, pair_mac, htonl(ntohl(src_ip) + (pair << 8)),
+	                             htonl(ntohl(dst_ip) + (pair << 8)));
+}
+
+/* Time allowed for ramp frames still in flight to drain before the trial */
+#define LEARN_DRAIN_NS 100000000ULL
+
+/* Counters of one stream of a running trial */
+typedef struct {
+	uint64_t sent;
+	uint64_t recv;
+} stream_acc_t;
+
+/* Store a trial's per-stream counters in ctx for rfc2544_get_stream_stats,
+ * each stream's latency from its share of the trial's latency samples.
+ * The caller holds latency_lock. */
+static void store_stream_stats(rfc2544_ctx_t *ctx, const stream_acc_t *streams,
+                               uint32_t stream_count, const uint64_t *samples,
+                               const uint16_t *sample_streams, uint32_t sample_count)
+{
+	ctx->stream_stats_count = 0;
+	if (!ctx->stream_stats || ctx->stream_count != stream_count)
+		return;
+
+	/* Group the samples by stream: stream s has grouped[first[s]..first[s + 1]) */
+	uint32_t *first = NULL;
+	uint64_t *grouped = NULL;
+	if (sample_streams && sample_count > 0) {
+		first = calloc(stream_count + 1, sizeof(*first));
+		grouped = malloc(sample_count * sizeof(*grouped));
+		uint32_t *next = calloc(stream_count, sizeof(*next));
+		if (first && grouped && next) {
+			for (uint32_t i = 0; i < sample_count; i++)
+				first[sample_streams[i] + 1]++;
+			for (uint32_t s = 0; s < stream_count; s++)
+				first[s + 1] += first[s];
+			for (uint32_t i = 0; i < sample_count; i++) {
+				uint32_t s = sample_streams[i];
+				grouped[first[s] + next[s]++] = samples[i];
+			}
+		} else {
+			free(grouped);
+			grouped = NULL;
+		}
+		free(next);
+	}
+
+	for (uint32_t s = 0; s < stream_count; s++) {
+		stream_stats_t *st = &ctx->stream_stats[s];
+		memset(st, 0, sizeof(*st));
+		st->frames_sent = streams[s].sent;
+		st->frames_recv = streams[s].recv;
+		if (st->frames_sent > 0 && st->frames_recv < st->frames_sent)
+			st->loss_pct = 100.0 * (st->frames_sent - st->frames_recv) / st->frames_sent;
+		if (grouped)
+			rfc2544_calc_latency_stats(grouped + first[s], first[s + 1] - first[s],
+			                           &st->latency);
+	}
+	ctx->stream_stats_count = stream_count;
+
+	free(grouped);
+	free(first);
+}
+
+/* Introduce every address pair with one frame at ctx->learn_rate per
+ * second, discarding whatever comes back, and record the ramp */
+static void learn_address_pairs(rfc2544_ctx_t *ctx, worker_ctx_t *wctx, worker_ctx_t *rx_wctx,
+                                packet_t *pkt, const address_pair_t *pool_pairs,
+                                uint32_t pair_count, const uint8_t *src_mac,
+                                const uint8_t *dst_mac, uint32_t src_ip, uint32_t dst_ip)
+{
+	packet_t rx_pkts[64];
+	uint64_t interval = 1000000000ULL / ctx->learn_rate;
+	uint64_t start = get_timestamp_ns();
+	uint64_t next = start;
+	uint32_t sent = 0;
+
+	rfc2544_log(LOG_INFO, "Learning ramp: %u address pairs at %u/s", pair_count,
+	            ctx->learn_rate);
+
+	while (sent < pair_count && !ctx->cancel_requested) {
+		uint64_t now = get_timestamp_ns();
+		if (now < next) {
+			recv_frames(ctx, rx_wctx, rx_pkts, 64);
+			if (next - now > 1000000)
+				usleep(1000);
+			continue;
+		}
+		set_pair_addresses(pkt->data, pool_pairs, sent, src_mac, dst_mac, src_ip, dst_ip);
+		if (send_frames(ctx, wctx, pkt, 1) > 0)
+			sent++;
+		next += interval;
+	}
+
+	uint64_t end = get_timestamp_ns();
+	while (get_timestamp_ns() - end < LEARN_DRAIN_NS && !ctx->cancel_requested) {
+		if (recv_frames(ctx, rx_wctx, rx_pkts, 64) == 0)
+			usleep(1000);
+	}
+
+	ctx->learn_stats.addresses = sent;
+	ctx->learn_stats.rate = ctx->learn_rate;
+	ctx->learn_stats.duration_sec = (end - start) / 1e9;
+	rfc2544_log(LOG_INFO, "Learning ramp: %u address pairs introduced in %.1fs", sent,
+	            ctx->learn_stats.duration_sec);
+}
+
+/* Grow a pooled buffer to hold size bytes; its contents are not kept */
+static void *pool_reserve(pool_buf_t *buf, size_t size)
+{
+	if (buf->size < size) {
+		void *data = malloc(size);
+		if (!data)
+			return NULL;
+		free(buf->data);
+		buf->data = data;
+		buf->size = size;
+	}
+	return buf->data;
+}
+
+/* The context's sequence tracker, reset for a trial of capacity frames */
+static seq_tracker_t *trial_tracker(rfc2544_ctx_t *ctx, uint32_t capacity)
+{
+	if (ctx->pool_tracker && rfc2544_seq_tracker_reset(ctx->pool_tracker, capacity) == 0)
+		return ctx->pool_tracker;
+	rfc2544_seq_tracker_destroy(ctx->pool_tracker);
+	ctx->pool_tracker = rfc2544_seq_tracker_create(capacity);
+	return ctx->pool_tracker;
+}
+
+/*
+ * Build the built-in test frame, in this order:
+ * 1. Ethernet/IPv4/UDP padded with the configured pattern
+ * 2. The configured DSCP
+ * 3. Rewritten as IPv6 when v6 is set
+ * 4. The configured EtherType or LLC/SNAP framing
+ * 5. The MPLS label stack
+ * 6. The VLAN tags
+ * Returns its payload, or NULL if the frame size cannot hold them.
+ */
+static rfc2544_payload_t *build_frame(rfc2544_ctx_t *ctx, uint8_t *buf, uint32_t frame_size,
+                                      const uint8_t *src_mac, const uint8_t *dst_mac,
+                                      uint32_t src_ip, uint32_t dst_ip, bool v6)
+{
+	uint16_t src_port = 0, dst_port = 0;
+	rfc2544_get_ports(ctx, &src_port, &dst_port);
+	rfc2544_payload_t *payload = rfc2544_create_packet_template(
+	    buf, frame_size, src_mac, dst_mac, src_ip, dst_ip, src_port, dst_port, 0);
+	if (!payload)
+		return NULL;
+	rfc2544_fill_pattern((uint8_t *)payload + RFC2544_PADDING_OFFSET,
+	                     frame_size - (uint32_t)((uint8_t *)payload - buf) - RFC2544_PADDING_OFFSET,
+	                     &ctx->pattern);
+	if (ctx->headers.dscp && rfc2544_mark_priority(buf, frame_size, 0, ctx->headers.dscp) < 0)
+		return NULL;
+
+	int shift;
+	if (v6) {
+		shift = rfc2544_convert_ipv6(buf, frame_size, &ctx->ip.ipv6);
+		if (shift < 0) {
+			rfc2544_log(LOG_ERROR, "Frame size %u too small for IPv6", frame_size);
+			return NULL;
+		}
+		payload = (rfc2544_payload_t *)((uint8_t *)payload + shift);
+	}
+
+	/* Re-encapsulate per the configured EtherType / LLC/SNAP framing */
+	shift = rfc2544_apply_framing(buf, frame_size, &ctx->framing);
+	if (shift < 0) {
+		rfc2544_log(LOG_ERROR, "Frame size %u not valid for configured framing", frame_size);
+		return NULL;
+	}
+	payload = (rfc2544_payload_t *)((uint8_t *)payload + shift);
+
+	if (ctx->mpls.count) {
+		shift = rfc2544_insert_mpls(buf, frame_size, ctx->mpls.labels, ctx->mpls.count);
+		if (shift < 0) {
+			rfc2544_log(LOG_ERROR, "Frame size %u not valid for the MPLS label stack",
+			            frame_size);
+			return NULL;
+		}
+		payload = (rfc2544_payload_t *)((uint8_t *)payload + shift);
+	}
+
+	shift = rfc2544_insert_tags(buf, frame_size, &ctx->qinq, ctx->vlan_id, ctx->vlan_pcp);
+	if (shift < 0) {
+		rfc2544_log(LOG_ERROR, "Frame size %u too small for the VLAN tags", frame_size);
+		return NULL;
+	}
+	return (rfc2544_payload_t *)((uint8_t *)payload + shift);
+}
+
 /**
  * Run a single trial at the specified rate
  *
@@ -746,46 +1727,105 @@ int run_trial(rfc2544_ctx_t *ctx, uint32_t frame_size, double rate_pct,
 
 	memset(result, 0, sizeof(*result));
 
+	int ret = rfc2544_open_ports(ctx);
+	if (ret < 0)
+		return ret;
+
+	/* Frames leave on the TX port and come back on the RX port */
 	worker_ctx_t *wctx = &ctx->workers[0];
+	worker_ctx_t *rx_wctx = rfc2544_rx_worker(ctx);
 
 	/* Create packet template */
 	uint8_t *pkt_buffer = malloc(frame_size);
 	if (!pkt_buffer)
 		return -ENOMEM;
 
-	/* Default addresses - in real use, would be configured */
-	uint8_t src_mac[6] = {0x02, 0x00, 0x00, 0x00, 0x00, 0x01};
-	uint8_t dst_mac[6] = {0x02, 0x00, 0x00, 0x00, 0x00, 0x02};
-	uint32_t src_ip = htonl(0x0A000001); /* 10.0.0.1 */
-	uint32_t dst_ip = htonl(0x0A000002); /* 10.0.0.2 */
-
-	/* Use configured MAC if available */
-	if (ctx->local_mac[0] || ctx->local_mac[1] || ctx->local_mac[2]) {
-		memcpy(src_mac, ctx->local_mac, 6);
-	}
-	if (ctx->remote_mac[0] || ctx->remote_mac[1] || ctx->remote_mac[2]) {
-		memcpy(dst_mac, ctx->remote_mac, 6);
+	/* Configured addresses, else the built-in ones */
+	uint8_t src_mac[6], dst_mac[6];
+	uint32_t src_ip, dst_ip;
+	rfc2544_get_macs(ctx, src_mac, dst_mac);
+	rfc2544_get_ips(ctx, &src_ip, &dst_ip);
+	uint16_t src_port;
+	rfc2544_get_ports(ctx, &src_port, NULL);
+
+	rfc2544_payload_t *payload;
+	if (ctx->tpl.header_len) {
+		/* User-defined headers replace the built-in ones and framing */
+		payload = rfc2544_create_templated_packet(pkt_buffer, frame_size, &ctx->tpl,
+		                                          src_mac, dst_mac, &ctx->pattern);
+		if (!payload) {
+			free(pkt_buffer);
+			return -EINVAL;
+		}
+	} else {
+		payload = build_frame(ctx, pkt_buffer, frame_size, src_mac, dst_mac, src_ip, dst_ip,
+		                      ctx->ip.mode == IP_MODE_V6);
+		if (!payload) {
+			free(pkt_buffer);
+			return -EINVAL;
+		}
 	}
 
-	rfc2544_payload_t *payload = rfc2544_create_packet_template(
-	    pkt_buffer, frame_size, src_mac, dst_mac, src_ip, dst_ip, 12345, 3842, 0);
-
-	if (!payload) {
-		free(pkt_buffer);
-		return -EINVAL;
+	/* Dual-stack: IPv6 frames from a buffer of their own take their share
+	 * of the frame slots */
+	bool dual = ctx->ip.mode == IP_MODE_DUAL && !ctx->tpl.header_len;
+	uint8_t *v6_buffer = NULL;
+	rfc2544_payload_t *v6_payload = NULL;
+	if (dual) {
+		v6_buffer = malloc(frame_size);
+		if (!v6_buffer) {
+			free(pkt_buffer);
+			return -ENOMEM;
+		}
+		v6_payload = build_frame(ctx, v6_buffer, frame_size, src_mac, dst_mac, src_ip, dst_ip,
+		                         true);
+		if (!v6_payload) {
+			free(v6_buffer);
+			free(pkt_buffer);
+			return -EINVAL;
+		}
+	}
+	uint32_t v6_share = ctx->ip.v6_share_pct ? ctx->ip.v6_share_pct : 50;
+	uint32_t v6_acc = 0;
+
+	/* Templated frames carry the payload at a fixed offset (0 = detect) */
+	uint32_t rx_offset = ctx->tpl.header_len;
+
+	/* Payload verification: a CRC-32 trailer in the last 4 bytes */
+	bool verify = ctx->verify_payload;
+	uint32_t payload_len = frame_size - (uint32_t)((uint8_t *)payload - pkt_buffer);
+	uint32_t padding_crc = 0;
+	uint32_t v6_payload_len = dual ? frame_size - (uint32_t)((uint8_t *)v6_payload - v6_buffer) : 0;
+	uint32_t v6_padding_crc = 0;
+	if (verify) {
+		uint32_t shortest = dual ? v6_payload_len : payload_len;
+		if (shortest < RFC2544_PADDING_OFFSET + RFC2544_CRC_LEN) {
+			rfc2544_log(LOG_ERROR, "Frame size %u too small for payload verification", frame_size);
+			free(v6_buffer);
+			free(pkt_buffer);
+			return -EINVAL;
+		}
+		padding_crc = rfc2544_prepare_seal(payload, payload_len);
+		if (dual)
+			v6_padding_crc = rfc2544_prepare_seal(v6_payload, v6_payload_len);
 	}
 
 	/* Create pacing context */
 	pacing_ctx_t *pacer = pacing_create(ctx->line_rate, frame_size, rate_pct);
 	if (!pacer) {
+		free(v6_buffer);
 		free(pkt_buffer);
 		return -ENOMEM;
 	}
+	pacing_apply_profile(pacer, ctx->speed_profile);
+	uint32_t batch = ctx->speed_profile->batch_size;
+	uint32_t batch_left = 0;
 
 	/* Create trial timer */
 	trial_timer_t *timer = trial_timer_create(duration_sec, warmup_sec);
 	if (!timer) {
 		pacing_destroy(pacer);
+		free(v6_buffer);
 		free(pkt_buffer);
 		return -ENOMEM;
 	}
@@ -797,10 +1837,11 @@ int run_trial(rfc2544_ctx_t *ctx, uint32_t frame_size, double rate_pct,
 	uint32_t tracker_capacity = (expected_packets + 1000 > UINT32_MAX)
 	                                ? UINT32_MAX
 	                                : (uint32_t)(expected_packets + 1000);
-	seq_tracker_t *tracker = rfc2544_seq_tracker_create(tracker_capacity);
+	seq_tracker_t *tracker = trial_tracker(ctx, tracker_capacity);
 	if (!tracker) {
 		trial_timer_destroy(timer);
 		pacing_destroy(pacer);
+		free(v6_buffer);
 		free(pkt_buffer);
 		return -ENOMEM;
 	}
@@ -809,6 +1850,10 @@ int run_trial(rfc2544_ctx_t *ctx, uint32_t frame_size, double rate_pct,
 	packet_t tx_pkt;
 	tx_pkt.data = pkt_buffer;
 	tx_pkt.len = frame_size;
+	packet_t v6_pkt;
+	memset(&v6_pkt, 0, sizeof(v6_pkt));
+	v6_pkt.data = v6_buffer;
+	v6_pkt.len = frame_size;
 
 	/* RX buffer */
 	packet_t rx_pkts[64];
@@ -817,18 +1862,139 @@ int run_trial(rfc2544_ctx_t *ctx, uint32_t frame_size, double rate_pct,
 	/* Latency samples */
 	uint64_t *latency_samples = NULL;
 	uint32_t latency_count = 0;
-	uint32_t latency_capacity = 10000;
+	uint32_t latency_capacity = ctx->sample_capacity ? ctx->sample_capacity
+	                                                 : DEFAULT_LATENCY_SAMPLES;
+	size_t samples_size = latency_capacity * sizeof(uint64_t);
 	if (ctx->config.measure_latency) {
-		latency_samples = malloc(latency_capacity * sizeof(uint64_t));
+		latency_samples = pool_reserve(&ctx->pool_samples[0], samples_size);
+	}
+
+	/* Each address family's frames; dual-stack keeps their latency apart */
+	int tx_family = ctx->ip.mode == IP_MODE_V6 ? 1 : 0;
+	uint64_t family_sent[2] = {0, 0};
+	uint64_t family_recv[2] = {0, 0};
+	uint64_t *family_samples[2] = {NULL, NULL};
+	uint32_t family_count[2] = {0, 0};
+	if (dual && latency_samples) {
+		family_samples[0] = pool_reserve(&ctx->pool_samples[1], samples_size);
+		family_samples[1] = pool_reserve(&ctx->pool_samples[2], samples_size);
+		if (!family_samples[0] || !family_samples[1]) {
+			rfc2544_log(LOG_WARN, "No memory for per-family latency");
+			family_samples[0] = family_samples[1] = NULL;
+		}
+	}
+
+	/* Latency probe: a copy of the frame marked with the probe's priority and
+	 * stream ID, sent on a wall-clock schedule beside the paced load
+	 * (built-in headers only) */
+	bool probe_on = ctx->probe.pps && latency_samples && !ctx->tpl.header_len;
+	uint8_t *probe_buffer = NULL;
+	uint64_t *probe_samples = NULL;
+	if (probe_on) {
+		probe_buffer = malloc(frame_size);
+		probe_samples = pool_reserve(&ctx->pool_samples[3], samples_size);
+		if (!probe_buffer || !probe_samples) {
+			rfc2544_log(LOG_WARN, "No memory for the latency probe, running without it");
+			probe_on = false;
+		}
+	}
+	packet_t probe_pkt;
+	memset(&probe_pkt, 0, sizeof(probe_pkt));
+	rfc2544_payload_t *probe_payload = NULL;
+	if (probe_on) {
+		memcpy(probe_buffer, pkt_buffer, frame_size);
+		probe_payload = (rfc2544_payload_t *)(probe_buffer + ((uint8_t *)payload - pkt_buffer));
+		probe_payload->stream_id = htonl(RFC2544_PROBE_STREAM);
+		if (rfc2544_mark_priority(probe_buffer, frame_size, ctx->probe.pcp, ctx->probe.dscp) < 0)
+			rfc2544_log(LOG_WARN, "Latency probe frames not marked: no IPv4 header");
+		probe_pkt.data = probe_buffer;
+		probe_pkt.len = frame_size;
+	}
+	uint32_t probe_seq = 0;
+	uint32_t probe_first = 0; /* First probe sequence number measured */
+	uint32_t probe_count = 0;
+	uint64_t probe_sent = 0;
+	uint64_t probe_recv = 0;
+	uint64_t probe_next = 0;
+
+	/* Section 11.1: broadcast_pct of the frame slots carry a broadcast frame */
+	double bcast_pct = ctx->modifiers.broadcast_pct;
+	bool bcast = bcast_pct > 0.0;
+	double bcast_acc = 0.0;
+	uint64_t tx_slot = 0;
+	uint64_t broadcast_sent = 0;
+
+	/* Section 12: rotate test frames round-robin across distinct address pairs
+	 * (built-in IPv4 headers only; a template fixes its addresses) */
+	bool builtin_v4 = !ctx->tpl.header_len && ctx->ip.mode == IP_MODE_V4;
+	uint32_t pair_count =
+	    (builtin_v4 && ctx->addresses.pair_count > 1) ? ctx->addresses.pair_count : 1;
+	/* Pairs drawn from address pools replace the derived ones */
+	const address_pair_t *pool_pairs = builtin_v4 ? ctx->addresses.pairs : NULL;
+	if (pool_pairs)
+		pair_count = ctx->addresses.pair_count;
+	bool rotate_pairs = pool_pairs || pair_count > 1;
+
+	/* Header ranges cycle the addresses and ports of built-in frames */
+	header_fields_t range = ctx->headers;
+	rfc2544_get_ips(ctx, &range.src_ip, &range.dst_ip);
+	rfc2544_get_ports(ctx, &range.src_port, &range.dst_port);
+	bool ranged = !ctx->tpl.header_len &&
+	              (range.src_ip_count > 1 || range.dst_ip_count > 1 ||
+	               range.src_port_count > 1 || range.dst_port_count > 1);
+	uint64_t range_slot = 0;
+
+	/* Multi-stream traffic replaces the address pairs, built-in IPv4 headers only */
+	uint32_t stream_count = builtin_v4 ? ctx->stream_count : 0;
+	stream_acc_t *streams = NULL;
+	uint16_t *sample_streams = NULL; /* Stream of each latency sample */
+	uint64_t stream_slot = 0;
+	if (stream_count > 1) {
+		streams = calloc(stream_count, sizeof(*streams));
+		if (streams && latency_samples)
+			sample_streams = pool_reserve(&ctx->pool_sample_streams,
+			                              latency_capacity * sizeof(*sample_streams));
+		if (!streams || (latency_samples && !sample_streams)) {
+			rfc2544_log(LOG_WARN, "No memory for %u streams, sending a single flow", stream_count);
+			free(streams);
+			streams = NULL;
+		}
+		rotate_pairs = false;
 	}
 
+	/* Control-plane policing: frames at the DUT's own addresses on a wall-clock schedule */
+	bool cpp_enabled = ctx->cpp.icmp_pps || ctx->cpp.arp_pps || ctx->cpp.bgp_pps;
+	static const uint8_t zero_mac[6] = {0};
+	const uint8_t *dut_mac = memcmp(ctx->cpp.dut_mac, zero_mac, 6) ? ctx->cpp.dut_mac : dst_mac;
+	uint64_t cpp_next[3] = {0, 0, 0};
+	uint16_t cpp_seq = 0;
+
+	/* Live counters: frames not yet published, including warmup */
+	uint64_t live_tx = 0;
+	uint64_t live_rx = 0;
+	pthread_mutex_lock(&ctx->live_lock);
+	ctx->live.frame_size = frame_size;
+	ctx->live.offered_rate_pct = rate_pct;
+	ctx->live.in_trial = true;
+	ctx->live.trial_warmup_sec = warmup_sec;
+	ctx->live.trial_duration_sec = duration_sec;
+	ctx->live.trial_elapsed_ns = 0;
+	ctx->live_trial_start_ns = get_timestamp_ns();
+	pthread_mutex_unlock(&ctx->live_lock);
+
 	/* Start trial */
 	uint32_t seq_num = 0;
 	uint64_t packets_sent = 0;
 	uint64_t packets_recv = 0;
 	uint64_t bytes_sent = 0;
+	uint64_t corrupted = 0;
 	bool in_measurement = false;
 
+	/* Introduce new source MACs at the learning rate once per address table */
+	if (rotate_pairs && ctx->learn_rate && !ctx->learn_stats.rate)
+		learn_address_pairs(ctx, wctx, rx_wctx, &tx_pkt, pool_pairs, pair_count, src_mac,
+		                    dst_mac, src_ip, dst_ip);
+
 	trial_timer_start(timer);
 	pacing_reset(pacer);
 
@@ -836,6 +2002,8 @@ int run_trial(rfc2544_ctx_t *ctx, uint32_t frame_size, double rate_pct,
 	            rate_pct, duration_sec, warmup_sec);
 
 	while (!trial_timer_expired(timer) && !ctx->cancel_requested) {
+		heartbeat(ctx);
+
 		/* Check if we've exited warmup */
 		if (!in_measurement && !trial_timer_in_warmup(timer)) {
 			in_measurement = true;
@@ -844,39 +2012,162 @@ int run_trial(rfc2544_ctx_t *ctx, uint32_t frame_size, double rate_pct,
 			packets_sent = 0;
 			packets_recv = 0;
 			bytes_sent = 0;
+			broadcast_sent = 0;
+			corrupted = 0;
+			if (streams)
+				memset(streams, 0, stream_count * sizeof(*streams));
+			memset(family_sent, 0, sizeof(family_sent));
+			memset(family_recv, 0, sizeof(family_recv));
+			family_count[0] = family_count[1] = 0;
+			probe_first = probe_seq;
+			probe_sent = 0;
+			probe_recv = 0;
+			probe_count = 0;
 			pacing_reset(pacer);
+			batch_left = 0;
 		}
 
-		/* TX: Send packet at paced rate */
-		uint64_t tx_ts = pacing_wait(pacer);
-		rfc2544_stamp_packet(payload, seq_num, tx_ts);
-		tx_pkt.timestamp = tx_ts;
-		tx_pkt.seq_num = seq_num;
-
-		int sent = ctx->platform->send_batch(wctx, &tx_pkt, 1);
-		if (sent > 0 && in_measurement) {
-			packets_sent++;
-			bytes_sent += frame_size;
-			seq_num++;
-			pacing_record_tx(pacer, 1, frame_size);
+		/* TX: Send packet at paced rate, a batch per wait at high line rates */
+		uint64_t tx_ts;
+		if (batch_left == 0) {
+			tx_ts = pacing_wait_batch(pacer, batch);
+			batch_left = batch;
+		} else {
+			tx_ts = get_timestamp_ns();
+		}
+		batch_left--;
+		tx_slot++;
+
+		if (bcast && rfc2544_broadcast_slot(&bcast_acc, bcast_pct)) {
+			/* Broadcast frame: not sequence-tracked, excluded from loss */
+			memcpy(pkt_buffer, broadcast_mac, 6);
+			int sent = send_frames(ctx, wctx, &tx_pkt, 1);
+			memcpy(pkt_buffer, dst_mac, 6);
+			if (sent > 0 && in_measurement) {
+				broadcast_sent++;
+				pacing_record_tx(pacer, 1, frame_size);
+			}
+		} else {
+			if (rotate_pairs)
+				set_pair_addresses(pkt_buffer, pool_pairs, seq_num % pair_count, src_mac,
+				                   dst_mac, src_ip, dst_ip);
+			uint32_t stream = 0;
+			if (streams) {
+				stream = (uint32_t)(stream_slot++ % stream_count);
+				rfc2544_set_stream(pkt_buffer, payload, src_mac, src_ip, dst_ip, src_port, stream);
+			}
+
+			/* Dual-stack: spreading the IPv6 share evenly over the slots */
+			packet_t *pkt = &tx_pkt;
+			int family = tx_family;
+			if (dual) {
+				v6_acc += v6_share;
+				if (v6_acc >= 100) {
+					v6_acc -= 100;
+					pkt = &v6_pkt;
+					family = 1;
+				}
+			}
+			if (family && dual) {
+				rfc2544_stamp_packet(v6_payload, seq_num, tx_ts);
+				if (verify)
+					rfc2544_seal_packet(v6_payload, v6_payload_len, v6_padding_crc);
+			} else {
+				rfc2544_stamp_packet(payload, seq_num, tx_ts);
+				if (verify)
+					rfc2544_seal_packet(payload, payload_len, padding_crc);
+			}
+			if (ranged)
+				rfc2544_set_header_range(pkt->data, frame_size, &range, range_slot++);
+			pkt->timestamp = tx_ts;
+			pkt->seq_num = seq_num;
+
+			int sent = send_frames(ctx, wctx, pkt, 1);
+			if (sent > 0)
+				live_tx++;
+			if (sent > 0 && in_measurement) {
+				packets_sent++;
+				bytes_sent += frame_size;
+				seq_num++;
+				pacing_record_tx(pacer, 1, frame_size);
+				if (streams)
+					streams[stream].sent++;
+				family_sent[family]++;
+			}
+		}
+
+		if (cpp_enabled)
+			cpp_send_due(ctx, wctx, src_mac, dut_mac, src_ip, cpp_next, &cpp_seq);
+
+		if (probe_on) {
+			uint64_t now = get_timestamp_ns();
+			if (now >= probe_next) {
+				rfc2544_stamp_packet(probe_payload, probe_seq, now);
+				if (verify)
+					rfc2544_seal_packet(probe_payload, payload_len, padding_crc);
+				if (send_frames(ctx, wctx, &probe_pkt, 1) > 0) {
+					probe_seq++;
+					if (in_measurement)
+						probe_sent++;
+				}
+				uint64_t interval = 1000000000ULL / ctx->probe.pps;
+				probe_next += interval;
+				if (probe_next < now)
+					probe_next = now + interval;
+			}
 		}
 
 		/* RX: Check for returned packets (non-blocking) */
-		int recv_count = ctx->platform->recv_batch(wctx, rx_pkts, 64);
+		int recv_count = recv_frames(ctx, rx_wctx, rx_pkts, 64);
 		for (int i = 0; i < recv_count; i++) {
-			if (rfc2544_is_valid_response(rx_pkts[i].data, rx_pkts[i].len)) {
-				uint32_t rx_seq = rfc2544_get_seq_num(rx_pkts[i].data, rx_pkts[i].len);
+			if (bcast && is_broadcast_echo(rx_pkts[i].data, rx_pkts[i].len))
+				continue;
+			if (cpp_enabled && cpp_count_reply(ctx, rx_pkts[i].data, rx_pkts[i].len))
+				continue;
+			uint32_t rx_seq;
+			uint64_t tx_ts_pkt;
+			if (rfc2544_parse_response(rx_pkts[i].data, rx_pkts[i].len, rx_offset,
+			                           &rx_seq, &tx_ts_pkt)) {
+				if (probe_on && is_probe_frame(rx_pkts[i].data, rx_pkts[i].len, rx_offset)) {
+					if (in_measurement && rx_seq >= probe_first) {
+						probe_recv++;
+						if (probe_count < latency_capacity &&
+						    (!verify || rfc2544_payload_intact(rx_pkts[i].data,
+						                                       rx_pkts[i].len, rx_offset)))
+							probe_samples[probe_count++] = rx_pkts[i].timestamp - tx_ts_pkt;
+					}
+					continue;
+				}
+				live_rx++;
 
 				if (in_measurement) {
-					rfc2544_seq_tracker_record(tracker, rx_seq);
 					packets_recv++;
+					int family = rfc2544_ip_version(rx_pkts[i].data, rx_pkts[i].len) == 6;
+					family_recv[family]++;
+					if (verify && !rfc2544_payload_intact(rx_pkts[i].data, rx_pkts[i].len,
+					                                      rx_offset)) {
+						/* Delivered, not lost, but its sequence number and
+						 * timestamp cannot be trusted */
+						corrupted++;
+						continue;
+					}
+					rfc2544_seq_tracker_record(tracker, rx_seq);
+
+					uint32_t sid = 0;
+					if (streams &&
+					    rfc2544_parse_stream_id(rx_pkts[i].data, rx_pkts[i].len, rx_offset,
+					                            &sid) &&
+					    sid < stream_count)
+						streams[sid].recv++;
 
 					/* Record latency if enabled */
 					if (latency_samples && latency_count < latency_capacity) {
-						uint64_t tx_ts_pkt = rfc2544_get_tx_timestamp(
-						    rx_pkts[i].data, rx_pkts[i].len);
 						uint64_t latency = rx_pkts[i].timestamp - tx_ts_pkt;
+						if (sample_streams)
+							sample_streams[latency_count] = sid < stream_count ? sid : 0;
 						latency_samples[latency_count++] = latency;
+						if (family_samples[family])
+							family_samples[family][family_count[family]++] = latency;
 					}
 				}
 			}
@@ -884,32 +2175,73 @@ int run_trial(rfc2544_ctx_t *ctx, uint32_t frame_size, double rate_pct,
 
 		/* Release RX packets */
 		if (recv_count > 0) {
-			ctx->platform->release_batch(wctx, rx_pkts, recv_count);
+			ctx->platform->release_batch(rx_wctx, rx_pkts, recv_count);
 		}
+
+		/* With a port pair, control-plane replies still return on the TX port */
+		if (cpp_enabled && rx_wctx != wctx) {
+			int cpp_count = recv_frames(ctx, wctx, rx_pkts, 64);
+			for (int i = 0; i < cpp_count; i++)
+				cpp_count_reply(ctx, rx_pkts[i].data, rx_pkts[i].len);
+			if (cpp_count > 0)
+				ctx->platform->release_batch(wctx, rx_pkts, cpp_count);
+		}
+
+		if ((tx_slot & 0x3ff) == 0)
+			live_publish(ctx, frame_size, &live_tx, &live_rx);
 	}
 
 	/* Wait a bit for straggler packets */
 	for (int i = 0; i < 10 && !ctx->cancel_requested; i++) {
 		usleep(10000); /* 10ms */
-		int recv_count = ctx->platform->recv_batch(wctx, rx_pkts, 64);
+		heartbeat(ctx);
+		int recv_count = recv_frames(ctx, rx_wctx, rx_pkts, 64);
 		for (int j = 0; j < recv_count; j++) {
-			if (rfc2544_is_valid_response(rx_pkts[j].data, rx_pkts[j].len)) {
-				uint32_t rx_seq = rfc2544_get_seq_num(rx_pkts[j].data, rx_pkts[j].len);
-				rfc2544_seq_tracker_record(tracker, rx_seq);
+			if (bcast && is_broadcast_echo(rx_pkts[j].data, rx_pkts[j].len))
+				continue;
+			if (cpp_enabled && cpp_count_reply(ctx, rx_pkts[j].data, rx_pkts[j].len))
+				continue;
+			uint32_t rx_seq;
+			uint64_t tx_ts_pkt;
+			if (rfc2544_parse_response(rx_pkts[j].data, rx_pkts[j].len, rx_offset,
+			                           &rx_seq, &tx_ts_pkt)) {
+				if (probe_on && is_probe_frame(rx_pkts[j].data, rx_pkts[j].len, rx_offset)) {
+					if (rx_seq >= probe_first)
+						probe_recv++;
+					continue;
+				}
 				packets_recv++;
+				live_rx++;
+				family_recv[rfc2544_ip_version(rx_pkts[j].data, rx_pkts[j].len) == 6]++;
+				if (verify && !rfc2544_payload_intact(rx_pkts[j].data, rx_pkts[j].len,
+				                                      rx_offset)) {
+					corrupted++;
+					continue;
+				}
+				rfc2544_seq_tracker_record(tracker, rx_seq);
+				uint32_t sid;
+				if (streams &&
+				    rfc2544_parse_stream_id(rx_pkts[j].data, rx_pkts[j].len, rx_offset, &sid) &&
+				    sid < stream_count)
+					streams[sid].recv++;
 			}
 		}
 		if (recv_count > 0) {
-			ctx->platform->release_batch(wctx, rx_pkts, recv_count);
+			ctx->platform->release_batch(rx_wctx, rx_pkts, recv_count);
 		}
 	}
 
+	rfc2544_capture_flush(ctx);
+
 	/* Calculate results */
 	double elapsed = trial_timer_elapsed(timer);
 	result->packets_sent = packets_sent;
 	result->packets_recv = packets_recv;
 	result->bytes_sent = bytes_sent;
+	result->broadcast_sent = broadcast_sent;
 	result->elapsed_sec = elapsed;
+	rfc2544_seq_tracker_order(tracker, &result->order);
+	result->order.corrupted = corrupted;
 
 	if (packets_sent > 0) {
 		/* Guard against underflow when recv > sent (timing/duplicates) */
@@ -932,14 +2264,79 @@ int run_trial(rfc2544_ctx_t *ctx, uint32_t frame_size, double rate_pct,
 		rfc2544_calc_latency_stats(latency_samples, latency_count, &result->latency);
 	}
 
+	/* Keep the samples for rfc2544_get_latency_samples */
+	pthread_mutex_lock(&ctx->latency_lock);
+	ctx->latency_sample_count = 0;
+	if (latency_samples) {
+		uint32_t n = latency_count;
+		if (n > ctx->latency_sample_capacity)
+			n = ctx->latency_sample_capacity;
+		memcpy(ctx->latency_samples, latency_samples, n * sizeof(uint64_t));
+		ctx->latency_sample_count = n;
+	}
+	/* And the per-stream counters for rfc2544_get_stream_stats */
+	if (streams)
+		store_stream_stats(ctx, streams, stream_count, latency_samples, sample_streams,
+		                   latency_count);
+	else
+		ctx->stream_stats_count = 0;
+	pthread_mutex_unlock(&ctx->latency_lock);
+
+	/* And the probe's, for rfc2544_latency_probe_get_stats */
+	if (probe_on) {
+		latency_probe_stats_t *ps = &ctx->probe_stats;
+		memset(ps, 0, sizeof(*ps));
+		ps->frames_sent = probe_sent;
+		ps->frames_recv = probe_recv;
+		if (probe_recv < probe_sent)
+			ps->loss_pct = 100.0 * (probe_sent - probe_recv) / probe_sent;
+		if (probe_count > 0)
+			rfc2544_calc_latency_stats(probe_samples, probe_count, &ps->latency);
+		rfc2544_log(LOG_DEBUG, "Trial latency probe: sent=%lu, recv=%lu, avg=%.1f us",
+		            probe_sent, probe_recv, ps->latency.avg_ns / 1000.0);
+	}
+
+	/* And each address family's, for rfc2544_ip_family_get_stats */
+	for (int f = 0; f < 2; f++) {
+		ip_family_stats_t *fs = &ctx->family_stats[f];
+		memset(fs, 0, sizeof(*fs));
+		fs->frames_sent = family_sent[f];
+		fs->frames_recv = family_recv[f];
+		if (family_recv[f] < family_sent[f])
+			fs->loss_pct = 100.0 * (family_sent[f] - family_recv[f]) / family_sent[f];
+		if (family_samples[f] && family_count[f] > 0)
+			rfc2544_calc_latency_stats(family_samples[f], family_count[f], &fs->latency);
+	}
+	if (!dual)
+		ctx->family_stats[tx_family].latency = result->latency;
+
+	live_publish(ctx, frame_size, &live_tx, &live_rx);
+	pthread_mutex_lock(&ctx->live_lock);
+	ctx->live.trials++;
+	ctx->live.last_loss_pct = result->loss_pct;
+	ctx->live.last_latency = result->latency;
+	ctx->live.last_order = result->order;
+	ctx->live.in_trial = false;
+	ctx->live.trial_elapsed_ns = get_timestamp_ns() - ctx->live_trial_start_ns;
+	pthread_mutex_unlock(&ctx->live_lock);
+
 	rfc2544_log(LOG_DEBUG, "Trial complete: sent=%lu, recv=%lu, loss=%.4f%%",
 	            packets_sent, packets_recv, result->loss_pct);
-
-	/* Cleanup */
-	free(latency_samples);
-	rfc2544_seq_tracker_destroy(tracker);
+	if (result->order.reordered > 0 || result->order.duplicates > 0)
+		rfc2544_log(LOG_DEBUG, "Trial frame order: reordered=%lu (depth %u), duplicates=%lu",
+		            result->order.reordered, result->order.max_reorder_depth,
+		            result->order.duplicates);
+	if (corrupted > 0)
+		rfc2544_log(LOG_DEBUG, "Trial corrupted frames: %lu", corrupted);
+	if (broadcast_sent > 0)
+		rfc2544_log(LOG_DEBUG, "Trial broadcast frames: %lu", broadcast_sent);
+
+	/* Cleanup; the sample arrays and tracker stay with the context */
+	free(probe_buffer);
+	free(streams);
 	trial_timer_destroy(timer);
 	pacing_destroy(pacer);
+	free(v6_buffer);
 	free(pkt_buffer);
 
 	return 0;
@@ -966,70 +2363,106 @@ int run_trial_custom(rfc2544_ctx_t *ctx, uint32_t frame_size, double rate_pct,
  * Throughput Test (Section 26.1)
  * ============================================================================ */
 
-int rfc2544_throughput_test(rfc2544_ctx_t *ctx, uint32_t frame_size, throughput_result_t *result,
-                            uint32_t *result_count)
+void rfc2544_throughput_search_init(rfc2544_ctx_t *ctx, throughput_search_t *search)
 {
-	if (!ctx || !result)
+	memset(search, 0, sizeof(*search));
+	search->high_pct = ctx->config.initial_rate_pct;
+}
+
+int rfc2544_throughput_search_step(rfc2544_ctx_t *ctx, uint32_t frame_size,
+                                   throughput_search_t *search)
+{
+	if (!ctx || !search)
 		return -EINVAL;
 
-	rfc2544_log(LOG_INFO, "Throughput test: frame_size=%u", frame_size);
+	/* Binary search for max throughput with 0% loss */
+	if ((search->high_pct - search->low_pct) <= ctx->config.resolution_pct ||
+	    search->iterations >= ctx->config.max_iterations || ctx->cancel_requested)
+		return 1;
 
-	/* Calculate max theoretical PPS */
-	uint64_t max_pps = rfc2544_calc_pps(ctx->line_rate, frame_size);
-	rfc2544_log(LOG_DEBUG, "Max theoretical rate: %lu pps", max_pps);
+	double current_rate = (search->low_pct + search->high_pct) / 2.0;
 
-	/* Binary search for max throughput with 0% loss */
-	double low = 0.0;
-	double high = ctx->config.initial_rate_pct;
-	double best_rate = 0.0;
-	uint32_t iterations = 0;
-	uint64_t total_frames = 0;
+	rfc2544_log(LOG_DEBUG, "Iteration %u: testing %.2f%%", search->iterations, current_rate);
 
-	while ((high - low) > ctx->config.resolution_pct &&
-	       iterations < ctx->config.max_iterations && !ctx->cancel_requested) {
+	/* Run trial at current rate */
+	trial_result_t trial;
+	int ret = run_trial(ctx, frame_size, current_rate,
+	                    ctx->config.trial_duration_sec,
+	                    ctx->config.warmup_sec, &trial);
 
-		double current_rate = (low + high) / 2.0;
+	if (ret < 0) {
+		rfc2544_log(LOG_ERROR, "Trial failed: %d", ret);
+		return ret;
+	}
 
-		rfc2544_log(LOG_DEBUG, "Iteration %u: testing %.2f%%", iterations, current_rate);
+	search->frames_tested += trial.packets_sent;
+
+	search->last.rate_pct = current_rate;
+	search->last.frames_tx = trial.packets_sent;
+	search->last.frames_rx = trial.packets_recv;
+	search->last.loss_pct = trial.loss_pct;
+	search->last.passed = trial.loss_pct <= ctx->config.acceptable_loss;
+	search->last.order = trial.order;
+	search->last.latency = trial.latency;
+
+	if (search->last.passed) {
+		/* Success - try higher rate */
+		search->best_rate_pct = current_rate;
+		search->low_pct = current_rate;
+		rfc2544_log(LOG_DEBUG, "  Pass: loss=%.4f%%, new best=%.2f%%",
+		            trial.loss_pct, search->best_rate_pct);
+
+		/* Store latency from best rate */
+		search->latency = trial.latency;
+	} else {
+		/* Failure - try lower rate */
+		search->high_pct = current_rate;
+		rfc2544_log(LOG_DEBUG, "  Fail: loss=%.4f%%, reducing rate", trial.loss_pct);
+	}
 
-		/* Run trial at current rate */
-		trial_result_t trial;
-		int ret = run_trial(ctx, frame_size, current_rate,
-		                    ctx->config.trial_duration_sec,
-		                    ctx->config.warmup_sec, &trial);
+	search->iterations++;
+	return 0;
+}
 
-		if (ret < 0) {
-			rfc2544_log(LOG_ERROR, "Trial failed: %d", ret);
-			return ret;
-		}
+void rfc2544_throughput_search_result(rfc2544_ctx_t *ctx, uint32_t frame_size,
+                                      const throughput_search_t *search,
+                                      throughput_result_t *result)
+{
+	uint64_t max_pps = rfc2544_calc_pps(ctx->line_rate, frame_size);
 
-		total_frames += trial.packets_sent;
+	result->frame_size = frame_size;
+	result->max_rate_pct = search->best_rate_pct;
+	result->max_rate_mbps = (ctx->line_rate * search->best_rate_pct / 100.0) / 1e6;
+	result->max_rate_pps = (uint64_t)(max_pps * search->best_rate_pct / 100.0);
+	result->iterations = search->iterations;
+	result->frames_tested = search->frames_tested;
+	result->latency = search->latency;
+}
 
-		if (trial.loss_pct <= ctx->config.acceptable_loss) {
-			/* Success - try higher rate */
-			best_rate = current_rate;
-			low = current_rate;
-			rfc2544_log(LOG_DEBUG, "  Pass: loss=%.4f%%, new best=%.2f%%",
-			            trial.loss_pct, best_rate);
+int rfc2544_throughput_test(rfc2544_ctx_t *ctx, uint32_t frame_size, throughput_result_t *result,
+                            uint32_t *result_count)
+{
+	if (!ctx || !result)
+		return -EINVAL;
 
-			/* Store latency from best rate */
-			result->latency = trial.latency;
-		} else {
-			/* Failure - try lower rate */
-			high = current_rate;
-			rfc2544_log(LOG_DEBUG, "  Fail: loss=%.4f%%, reducing rate", trial.loss_pct);
-		}
+	rfc2544_log(LOG_INFO, "Throughput test: frame_size=%u", frame_size);
 
-		iterations++;
-	}
+	/* Calculate max theoretical PPS */
+	uint64_t max_pps = rfc2544_calc_pps(ctx->line_rate, frame_size);
+	rfc2544_log(LOG_DEBUG, "Max theoretical rate: %lu pps", max_pps);
+
+	throughput_search_t search;
+	rfc2544_throughput_search_init(ctx, &search);
+
+	int ret;
+	while ((ret = rfc2544_throughput_search_step(ctx, frame_size, &search)) == 0)
+		;
+	if (ret < 0)
+		return ret;
 
 	/* Store result */
-	result->frame_size = frame_size;
-	result->max_rate_pct = best_rate;
-	result->max_rate_mbps = (ctx->line_rate * best_rate / 100.0) / 1e6;
-	result->max_rate_pps = (uint64_t)(max_pps * best_rate / 100.0);
-	result->iterations = iterations;
-	result->frames_tested = total_frames;
+	memset(result, 0, sizeof(*result));
+	rfc2544_throughput_search_result(ctx, frame_size, &search, result);
 
 	rfc2544_log(LOG_INFO, "Throughput result: %.2f%% (%.2f Mbps, %lu pps)",
 	            result->max_rate_pct, result->max_rate_mbps, result->max_rate_pps);
@@ -1080,6 +2513,225 @@ int rfc2544_latency_test(rfc2544_ctx_t *ctx, uint32_t frame_size, double load_pc
 	return 0;
 }
 
+/* ============================================================================
+ * Fixed-Rate Trial (soak sampling)
+ * ============================================================================ */
+
+int rfc2544_fixed_rate_trial(rfc2544_ctx_t *ctx, uint32_t frame_size, double rate_pct,
+                             uint32_t duration_sec, fixed_rate_result_t *result)
+{
+	if (!ctx || !result || rate_pct <= 0.0 || duration_sec == 0)
+		return -EINVAL;
+
+	bool orig_measure = ctx->config.measure_latency;
+	ctx->config.measure_latency = true;
+
+	trial_result_t trial;
+	int ret = run_trial(ctx, frame_size, rate_pct, duration_sec, 0, &trial);
+
+	ctx->config.measure_latency = orig_measure;
+
+	if (ret < 0) {
+		rfc2544_log(LOG_ERROR, "Fixed-rate trial failed: %d", ret);
+		return ret;
+	}
+
+	memset(result, 0, sizeof(*result));
+	result->frame_size = frame_size;
+	result->offered_rate_pct = rate_pct;
+	result->frames_sent = trial.packets_sent;
+	result->frames_recv = trial.packets_recv;
+	result->loss_pct = trial.loss_pct;
+	result->elapsed_sec = trial.elapsed_sec;
+	result->latency = trial.latency;
+	result->order = trial.order;
+	if (trial.elapsed_sec > 0)
+		result->delivered_mbps = (double)trial.packets_recv * frame_size * 8.0 /
+		                         trial.elapsed_sec / 1e6;
+
+	return 0;
+}
+
+/* ============================================================================
+ * Burst Train (microburst test)
+ * ============================================================================ */
+
+/* Count returned frames of a burst train against the burst their sequence
+ * number falls in */
+static void burst_train_recv(rfc2544_ctx_t *ctx, worker_ctx_t *rx_wctx, uint32_t rx_offset,
+                             const burst_train_t *train, burst_train_result_t *result,
+                             uint64_t *samples, uint32_t *sample_count, uint32_t capacity,
+                             uint64_t *live_rx)
+{
+	packet_t rx_pkts[64];
+	memset(rx_pkts, 0, sizeof(rx_pkts));
+	int count = recv_frames(ctx, rx_wctx, rx_pkts, 64);
+	uint64_t total = (uint64_t)train->burst_frames * train->bursts;
+	for (int i = 0; i < count; i++) {
+		uint32_t seq;
+		uint64_t tx_ts;
+		if (!rfc2544_parse_response(rx_pkts[i].data, rx_pkts[i].len, rx_offset, &seq, &tx_ts) ||
+		    seq >= total)
+			continue;
+		burst_count_t *b = &result->bursts[seq / train->burst_frames];
+		uint64_t rx_ts = rx_pkts[i].timestamp;
+		uint64_t latency = rx_ts > tx_ts ? rx_ts - tx_ts : 0;
+		b->frames_recv++;
+		if (latency > b->max_latency_ns)
+			b->max_latency_ns = latency;
+		if (!b->first_rx_ns || rx_ts < b->first_rx_ns)
+			b->first_rx_ns = rx_ts;
+		if (rx_ts > b->last_rx_ns)
+			b->last_rx_ns = rx_ts;
+		if (samples && *sample_count < capacity)
+			samples[(*sample_count)++] = latency;
+		(*live_rx)++;
+	}
+	if (count > 0)
+		ctx->platform->release_batch(rx_wctx, rx_pkts, count);
+}
+
+int rfc2544_burst_train(rfc2544_ctx_t *ctx, uint32_t frame_size, const burst_train_t *train,
+                        burst_train_result_t *result)
+{
+	if (!ctx || !train || !result || train->burst_frames == 0 || train->bursts == 0 ||
+	    train->bursts > RFC2544_MAX_TRAIN_BURSTS || train->rate_pct < 0.0 ||
+	    train->rate_pct > 100.0)
+		return -EINVAL;
+	uint64_t total = (uint64_t)train->burst_frames * train->bursts;
+	if (total > UINT32_MAX)
+		return -EINVAL;
+
+	memset(result, 0, sizeof(*result));
+	int ret = rfc2544_open_ports(ctx);
+	if (ret < 0)
+		return ret;
+	worker_ctx_t *wctx = &ctx->workers[0];
+	worker_ctx_t *rx_wctx = rfc2544_rx_worker(ctx);
+
+	/* The trial frame, without the modifiers of paced trials: the bursts
+	 * alone decide what the DUT must buffer */
+	uint8_t *pkt_buffer = malloc(frame_size);
+	if (!pkt_buffer)
+		return -ENOMEM;
+	uint8_t src_mac[6], dst_mac[6];
+	uint32_t src_ip, dst_ip;
+	rfc2544_get_macs(ctx, src_mac, dst_mac);
+	rfc2544_get_ips(ctx, &src_ip, &dst_ip);
+	rfc2544_payload_t *payload;
+	if (ctx->tpl.header_len)
+		payload = rfc2544_create_templated_packet(pkt_buffer, frame_size, &ctx->tpl, src_mac,
+		                                          dst_mac, &ctx->pattern);
+	else
+		payload = build_frame(ctx, pkt_buffer, frame_size, src_mac, dst_mac, src_ip, dst_ip,
+		                      ctx->ip.mode == IP_MODE_V6);
+	if (!payload) {
+		free(pkt_buffer);
+		return -EINVAL;
+	}
+	uint32_t rx_offset = ctx->tpl.header_len;
+
+	double rate_pct = train->rate_pct > 0.0 ? train->rate_pct : 100.0;
+	pacing_ctx_t *pacer = pacing_create(ctx->line_rate, frame_size, rate_pct);
+	if (!pacer) {
+		free(pkt_buffer);
+		return -ENOMEM;
+	}
+	/* Gaps and frame spacing are microseconds: sleeping overshoots them */
+	pacing_set_busy_wait(pacer, true);
+
+	uint32_t capacity = ctx->sample_capacity ? ctx->sample_capacity : DEFAULT_LATENCY_SAMPLES;
+	uint64_t *samples = pool_reserve(&ctx->pool_samples[0], capacity * sizeof(uint64_t));
+	uint32_t sample_count = 0;
+
+	pthread_mutex_lock(&ctx->live_lock);
+	ctx->live.frame_size = frame_size;
+	ctx->live.offered_rate_pct = rate_pct;
+	ctx->live.in_trial = true;
+	ctx->live.trial_warmup_sec = 0;
+	ctx->live.trial_duration_sec = 0;
+	ctx->live.trial_elapsed_ns = 0;
+	ctx->live_trial_start_ns = get_timestamp_ns();
+	pthread_mutex_unlock(&ctx->live_lock);
+
+	rfc2544_log(LOG_DEBUG, "Burst train: %u bursts of %u frames at %.1f%%, %lu ns gaps",
+	            train->bursts, train->burst_frames, rate_pct, (unsigned long)train->gap_ns);
+
+	packet_t tx_pkt;
+	memset(&tx_pkt, 0, sizeof(tx_pkt));
+	tx_pkt.data = pkt_buffer;
+	tx_pkt.len = frame_size;
+	uint64_t live_tx = 0, live_rx = 0;
+	uint32_t seq = 0;
+	uint64_t start = get_timestamp_ns();
+	result->burst_count = train->bursts;
+
+	for (uint32_t b = 0; b < train->bursts && !ctx->cancel_requested; b++) {
+		heartbeat(ctx);
+		pacing_reset(pacer);
+		for (uint32_t f = 0; f < train->burst_frames && !ctx->cancel_requested; f++) {
+			uint64_t tx_ts = pacing_wait(pacer);
+			rfc2544_stamp_packet(payload, seq, tx_ts);
+			tx_pkt.timestamp = tx_ts;
+			tx_pkt.seq_num = seq++;
+			/* A frame the port refuses leaves a hole in the burst and
+			 * counts as lost */
+			if (send_frames(ctx, wctx, &tx_pkt, 1) > 0)
+				live_tx++;
+			result->bursts[b].frames_sent++;
+			if ((f & 0x1f) == 0x1f)
+				burst_train_recv(ctx, rx_wctx, rx_offset, train, result, samples,
+				                 &sample_count, capacity, &live_rx);
+		}
+
+		/* Idle until the next burst, taking in what the DUT drains */
+		uint64_t gap_end = get_timestamp_ns() + train->gap_ns;
+		do {
+			burst_train_recv(ctx, rx_wctx, rx_offset, train, result, samples, &sample_count,
+			                 capacity, &live_rx);
+		} while (get_timestamp_ns() < gap_end && !ctx->cancel_requested);
+		live_publish(ctx, frame_size, &live_tx, &live_rx);
+	}
+	result->elapsed_sec = (get_timestamp_ns() - start) / 1e9;
+
+	/* Wait a bit for straggler packets */
+	for (int i = 0; i < 10 && !ctx->cancel_requested; i++) {
+		usleep(10000); /* 10ms */
+		heartbeat(ctx);
+		burst_train_recv(ctx, rx_wctx, rx_offset, train, result, samples, &sample_count,
+		                 capacity, &live_rx);
+	}
+	rfc2544_capture_flush(ctx);
+
+	result->frame_size = frame_size;
+	for (uint32_t b = 0; b < result->burst_count; b++) {
+		result->frames_sent += result->bursts[b].frames_sent;
+		result->frames_recv += result->bursts[b].frames_recv;
+	}
+	if (result->frames_recv < result->frames_sent)
+		result->loss_pct = 100.0 * (result->frames_sent - result->frames_recv) /
+		                   result->frames_sent;
+	if (sample_count > 0)
+		rfc2544_calc_latency_stats(samples, sample_count, &result->latency);
+
+	live_publish(ctx, frame_size, &live_tx, &live_rx);
+	pthread_mutex_lock(&ctx->live_lock);
+	ctx->live.trials++;
+	ctx->live.last_loss_pct = result->loss_pct;
+	ctx->live.last_latency = result->latency;
+	ctx->live.in_trial = false;
+	ctx->live.trial_elapsed_ns = get_timestamp_ns() - ctx->live_trial_start_ns;
+	pthread_mutex_unlock(&ctx->live_lock);
+
+	rfc2544_log(LOG_DEBUG, "Burst train complete: sent=%lu, recv=%lu, loss=%.4f%%",
+	            (unsigned long)result->frames_sent, (unsigned long)result->frames_recv,
+	            result->loss_pct);
+
+	pacing_destroy(pacer);
+	free(pkt_buffer);
+	return 0;
+}
+
 /* ============================================================================
  * Frame Loss Test (Section 26.3)
  * ============================================================================ */
@@ -1114,6 +2766,7 @@ int rfc2544_frame_loss_test(rfc2544_ctx_t *ctx, uint32_t frame_size, frame_loss_
 		results[count].frames_sent = trial.packets_sent;
 		results[count].frames_recv = trial.packets_recv;
 		results[count].loss_pct = trial.loss_pct;
+		results[count].order = trial.order;
 
 		rfc2544_log(LOG_DEBUG, "  Result: sent=%lu, recv=%lu, loss=%.4f%%",
 		            trial.packets_sent, trial.packets_recv, trial.loss_pct);
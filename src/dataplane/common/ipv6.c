@@ -62,7 +62,11 @@ int rfc2544_ipv6_configure(rfc2544_ctx_t *ctx, const ipv6_config_t *config)
 	if (!ctx || !config)
 		return -EINVAL;
 
-	/* Store IPv6 configuration */
+	/* Store IPv6 configuration; the test frames follow ctx->ip */
+	ip_stack_config_t ip = {IP_MODE_V6, *config, 0};
+	int ret = rfc2544_ip_configure(ctx, &ip);
+	if (ret < 0)
+		return ret;
 	memcpy(&ctx->config.ipv6, config, sizeof(ipv6_config_t));
 	ctx->config.ip_mode = IP_MODE_V6;
 
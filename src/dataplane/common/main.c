@@ -136,8 +136,9 @@ int main(int argc, char **argv)
 				return 1;
 			}
 			config.frame_size = atoi(argv[++i]);
-			if (config.frame_size < 64 || config.frame_size > 9000) {
-				fprintf(stderr, "Invalid frame size: %u (64-9000)\n", config.frame_size);
+			if (config.frame_size < 64 || config.frame_size > RFC2544_MAX_FRAME_SIZE) {
+				fprintf(stderr, "Invalid frame size: %u (64-%d)\n", config.frame_size,
+				        RFC2544_MAX_FRAME_SIZE);
 				return 1;
 			}
 		} else if (strcmp(argv[i], "--jumbo") == 0) {
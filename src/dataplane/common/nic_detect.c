@@ -2,9 +2,11 @@
  * nic_detect.c - NIC Auto-Detection and Capability Discovery
  *
  * Detects network interface capabilities including:
- * - Link speed
+ * - Link speed and state
+ * - Driver, bus address and firmware (ethtool -i)
  * - Hardware timestamping support
  * - XDP/AF_XDP support
+ * - DPDK binding availability
  * - MTU and MAC address
  */
 
@@ -69,27 +71,40 @@ static int read_sysfs_u64(const char *path, uint64_t *value)
 }
 
 /**
- * Check if interface supports XDP
+ * Read driver, bus and firmware info via ethtool, falling back to the
+ * sysfs driver link for drivers without ETHTOOL_GDRVINFO
  */
-static bool check_xdp_support(const char *interface)
+static void read_driver_info(const char *interface, nic_info_t *info)
 {
-	/* Check for XDP support by looking for driver XDP capability */
-	char path[256];
-	snprintf(path, sizeof(path), "/sys/class/net/%s/device/driver", interface);
+	int fd = socket(AF_INET, SOCK_DGRAM, 0);
+	if (fd >= 0) {
+		struct ifreq ifr;
+		memset(&ifr, 0, sizeof(ifr));
+		strncpy(ifr.ifr_name, interface, IFNAMSIZ - 1);
+
+		struct ethtool_drvinfo drvinfo;
+		memset(&drvinfo, 0, sizeof(drvinfo));
+		drvinfo.cmd = ETHTOOL_GDRVINFO;
+		ifr.ifr_data = (char *)&drvinfo;
+
+		if (ioctl(fd, SIOCETHTOOL, &ifr) >= 0) {
+			snprintf(info->driver, sizeof(info->driver), "%s", drvinfo.driver);
+			snprintf(info->bus_info, sizeof(info->bus_info), "%s", drvinfo.bus_info);
+			snprintf(info->fw_version, sizeof(info->fw_version), "%s", drvinfo.fw_version);
+		}
+		close(fd);
+	}
 
-	if (access(path, F_OK) != 0)
-		return false;
+	if (info->driver[0] != '\0')
+		return;
 
-	/* Known XDP-capable drivers */
-	static const char *xdp_drivers[] = {
-		"i40e", "ixgbe", "mlx4_en", "mlx5_core", "nfp", "virtio_net",
-		"veth", "tun", "bnxt_en", "qede", "igb", "e1000e", NULL
-	};
+	char path[256];
+	snprintf(path, sizeof(path), "/sys/class/net/%s/device/driver", interface);
 
 	char driver_link[512];
 	ssize_t len = readlink(path, driver_link, sizeof(driver_link) - 1);
 	if (len < 0)
-		return false;
+		return;
 	driver_link[len] = '\0';
 
 	/* Extract driver name from path */
@@ -98,10 +113,59 @@ static bool check_xdp_support(const char *interface)
 		driver_name++;
 	else
 		driver_name = driver_link;
+	snprintf(info->driver, sizeof(info->driver), "%s", driver_name);
+}
+
+/**
+ * Check if interface supports XDP
+ */
+static bool check_xdp_support(const char *driver)
+{
+	/* Known XDP-capable drivers */
+	static const char *xdp_drivers[] = {
+		"i40e", "ixgbe", "mlx4_en", "mlx5_core", "nfp", "virtio_net",
+		"veth", "tun", "bnxt_en", "qede", "igb", "e1000e", NULL
+	};
+
+	if (driver[0] == '\0')
+		return false;
 
 	for (int i = 0; xdp_drivers[i]; i++) {
-		if (strstr(driver_name, xdp_drivers[i]))
+		if (strstr(driver, xdp_drivers[i]))
+			return true;
+	}
+
+	return false;
+}
+
+/**
+ * Check if the interface could be bound to DPDK: it must be a PCI device
+ * and a userspace I/O module must be loaded to take it over
+ */
+static bool check_dpdk_support(const char *interface, char *module, size_t len)
+{
+	char path[256];
+	snprintf(path, sizeof(path), "/sys/class/net/%s/device/subsystem", interface);
+
+	char link[512];
+	ssize_t n = readlink(path, link, sizeof(link) - 1);
+	if (n < 0)
+		return false;
+	link[n] = '\0';
+
+	const char *bus = strrchr(link, '/');
+	if (!bus || strcmp(bus + 1, "pci") != 0)
+		return false;
+
+	/* In order of preference (vfio-pci works with the IOMMU enabled) */
+	static const char *modules[] = {"vfio-pci", "igb_uio", "uio_pci_generic", NULL};
+
+	for (int i = 0; modules[i]; i++) {
+		snprintf(path, sizeof(path), "/sys/bus/pci/drivers/%s", modules[i]);
+		if (access(path, F_OK) == 0) {
+			snprintf(module, len, "%s", modules[i]);
 			return true;
+		}
 	}
 
 	return false;
@@ -160,7 +224,8 @@ int rfc2544_detect_nic(const char *interface, nic_info_t *info)
 	/* Get link speed */
 	snprintf(path, sizeof(path), "/sys/class/net/%s/speed", interface);
 	uint64_t speed_mbps = 0;
-	if (read_sysfs_u64(path, &speed_mbps) == 0) {
+	/* Links without carrier report -1 (unknown) */
+	if (read_sysfs_u64(path, &speed_mbps) == 0 && speed_mbps <= UINT32_MAX) {
 		info->link_speed = speed_mbps * 1000000ULL; /* Convert to bps */
 	}
 
@@ -169,6 +234,7 @@ int rfc2544_detect_nic(const char *interface, nic_info_t *info)
 	char operstate[32];
 	if (read_sysfs(path, operstate, sizeof(operstate)) == 0) {
 		info->is_up = (strcmp(operstate, "up") == 0);
+		snprintf(info->operstate, sizeof(info->operstate), "%s", operstate);
 	}
 
 	/* Get MTU */
@@ -190,8 +256,15 @@ int rfc2544_detect_nic(const char *interface, nic_info_t *info)
 		}
 	}
 
+	/* Get driver info */
+	read_driver_info(interface, info);
+
 	/* Check XDP support */
-	info->supports_xdp = check_xdp_support(interface);
+	info->supports_xdp = check_xdp_support(info->driver);
+
+	/* Check DPDK binding */
+	info->supports_dpdk = check_dpdk_support(interface, info->dpdk_driver,
+	                                         sizeof(info->dpdk_driver));
 
 	/* Check hardware timestamping */
 	info->supports_hw_ts = check_hw_timestamp_support(interface);
@@ -209,6 +282,8 @@ int rfc2544_detect_nic(const char *interface, nic_info_t *info)
 	/* Get flags (up/down) */
 	if (ioctl(fd, SIOCGIFFLAGS, &ifr) >= 0) {
 		info->is_up = (ifr.ifr_flags & IFF_UP) != 0;
+		snprintf(info->operstate, sizeof(info->operstate), "%s",
+		         info->is_up ? "up" : "down");
 	}
 
 	/* Get MTU */
@@ -224,12 +299,14 @@ int rfc2544_detect_nic(const char *interface, nic_info_t *info)
 	}
 #endif
 
-	rfc2544_log(LOG_INFO, "NIC %s: %s, speed=%lu Mbps, MTU=%u, XDP=%s, HW-TS=%s",
+	rfc2544_log(LOG_INFO, "NIC %s: %s, speed=%lu Mbps, MTU=%u, driver=%s, XDP=%s, HW-TS=%s, DPDK=%s",
 	            info->name, info->is_up ? "UP" : "DOWN",
 	            info->link_speed / 1000000,
 	            info->mtu,
+	            info->driver[0] ? info->driver : "-",
 	            info->supports_xdp ? "yes" : "no",
-	            info->supports_hw_ts ? "yes" : "no");
+	            info->supports_hw_ts ? "yes" : "no",
+	            info->supports_dpdk ? "yes" : "no");
 
 	return 0;
 }
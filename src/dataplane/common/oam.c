@@ -0,0 +1,325 @@
+/*
+ * oam.c - Ethernet OAM Remote Loopback Discovery and Control
+ *
+ * Finds far-end devices able to reflect test traffic and controls
+ * their loopback state, removing the manual step of looping the
+ * remote port before a test:
+ * - IEEE 802.3ah (Clause 57) Information OAMPDU discovery
+ * - IEEE 802.3ah Loopback Control OAMPDU (enable/disable)
+ * - ITU-T Y.1731 multicast LBM discovery of LBR responders
+ */
+
+#include "rfc2544.h"
+#include "rfc2544_internal.h"
+
+#include <errno.h>
+#include <stdio.h>
+#include <string.h>
+#include <time.h>
+#include <unistd.h>
+
+/* Internal packet structure (matches core.c) */
+typedef struct {
+	uint8_t *data;
+	uint32_t len;
+	uint64_t timestamp;
+	uint32_t seq_num;
+	void *platform_data;
+} packet_t;
+
+/* Platform operations interface (matches core.c) */
+struct platform_ops {
+	const char *name;
+	int (*init)(rfc2544_ctx_t *ctx, worker_ctx_t *wctx);
+	void (*cleanup)(worker_ctx_t *wctx);
+	int (*send_batch)(worker_ctx_t *wctx, packet_t *pkts, int count);
+	int (*recv_batch)(worker_ctx_t *wctx, packet_t *pkts, int max_count);
+	void (*release_batch)(worker_ctx_t *wctx, packet_t *pkts, int count);
+	uint64_t (*get_tx_timestamp)(worker_ctx_t *wctx, packet_t *pkt);
+	uint64_t (*get_rx_timestamp)(worker_ctx_t *wctx, packet_t *pkt);
+};
+
+/* 802.3ah slow protocol constants */
+#define ETH_P_SLOW              0x8809
+#define SLOW_SUBTYPE_OAM        0x03
+#define OAMPDU_INFO             0x00
+#define OAMPDU_LOOPBACK_CTL     0x04
+#define OAM_LB_ENABLE           0x01
+#define OAM_LB_DISABLE          0x02
+#define OAM_TLV_LOCAL_INFO      0x01
+#define OAM_TLV_INFO_LEN        16
+#define OAM_FLAG_LOCAL_STABLE   0x0010
+
+/* Local Information TLV fields */
+#define OAM_STATE_PARSER_MASK   0x03
+#define OAM_STATE_PARSER_LB     0x01
+#define OAM_CONFIG_ACTIVE       0x01
+#define OAM_CONFIG_REMOTE_LB    0x04
+#define OAM_MAX_PDU_SIZE        1518
+
+/* Y.1731 constants */
+#define ETH_P_CFM               0x8902
+
+/* Offsets within an untagged frame */
+#define OAM_OFF_ETHERTYPE       12
+#define OAM_OFF_SUBTYPE         14
+#define OAM_OFF_CODE            17
+#define OAM_OFF_DATA            18
+
+/* Interval between discovery / control retransmissions */
+#define OAM_RETRY_MS            250
+
+static const uint8_t slow_protocols_mac[6] = {0x01, 0x80, 0xC2, 0x00, 0x00, 0x02};
+
+static uint64_t oam_now_ms(void)
+{
+	struct timespec ts;
+	clock_gettime(CLOCK_MONOTONIC, &ts);
+	return (uint64_t)ts.tv_sec * 1000 + (uint64_t)ts.tv_nsec / 1000000;
+}
+
+static void oam_eth_header(uint8_t *buf, const uint8_t *dst, const uint8_t *src,
+                           uint16_t ethertype)
+{
+	memcpy(buf, dst, 6);
+	memcpy(buf + 6, src, 6);
+	buf[OAM_OFF_ETHERTYPE] = ethertype >> 8;
+	buf[OAM_OFF_ETHERTYPE + 1] = ethertype & 0xff;
+}
+
+static void oam_slow_header(uint8_t *buf, const uint8_t *src_mac, uint8_t code)
+{
+	oam_eth_header(buf, slow_protocols_mac, src_mac, ETH_P_SLOW);
+	buf[OAM_OFF_SUBTYPE] = SLOW_SUBTYPE_OAM;
+	buf[15] = OAM_FLAG_LOCAL_STABLE >> 8;
+	buf[16] = OAM_FLAG_LOCAL_STABLE & 0xff;
+	buf[OAM_OFF_CODE] = code;
+}
+
+int oam_build_info(uint8_t *buf, uint32_t len, const uint8_t *src_mac)
+{
+	if (!buf || !src_mac || len < OAM_FRAME_LEN)
+		return -EINVAL;
+
+	memset(buf, 0, OAM_FRAME_LEN);
+	oam_slow_header(buf, src_mac, OAMPDU_INFO);
+
+	/* Local Information TLV: active mode, no loopback support of our own */
+	uint8_t *tlv = buf + OAM_OFF_DATA;
+	tlv[0] = OAM_TLV_LOCAL_INFO;
+	tlv[1] = OAM_TLV_INFO_LEN;
+	tlv[2] = 0x01; /* OAM version */
+	tlv[5] = 0x00; /* State: parser and multiplexer forwarding */
+	tlv[6] = OAM_CONFIG_ACTIVE;
+	tlv[7] = OAM_MAX_PDU_SIZE >> 8;
+	tlv[8] = OAM_MAX_PDU_SIZE & 0xff;
+
+	return OAM_FRAME_LEN;
+}
+
+int oam_build_loopback_ctl(uint8_t *buf, uint32_t len, const uint8_t *src_mac, bool enable)
+{
+	if (!buf || !src_mac || len < OAM_FRAME_LEN)
+		return -EINVAL;
+
+	memset(buf, 0, OAM_FRAME_LEN);
+	oam_slow_header(buf, src_mac, OAMPDU_LOOPBACK_CTL);
+	buf[OAM_OFF_DATA] = enable ? OAM_LB_ENABLE : OAM_LB_DISABLE;
+
+	return OAM_FRAME_LEN;
+}
+
+int oam_build_lbm(uint8_t *buf, uint32_t len, const uint8_t *src_mac, uint8_t meg_level,
+                  uint32_t transaction_id)
+{
+	if (!buf || !src_mac || len < OAM_FRAME_LEN || meg_level > 7)
+		return -EINVAL;
+
+	/* Multicast Class 1 destination address for the MEG level */
+	uint8_t dst[6] = {0x01, 0x80, 0xC2, 0x00, 0x00, (uint8_t)(0x30 | meg_level)};
+
+	memset(buf, 0, OAM_FRAME_LEN);
+	oam_eth_header(buf, dst, src_mac, ETH_P_CFM);
+
+	uint8_t *cfm = buf + 14;
+	cfm[0] = (uint8_t)(meg_level << 5); /* MEL, version 0 */
+	cfm[1] = Y1731_LBM;
+	cfm[2] = 0;                         /* Flags */
+	cfm[3] = 4;                         /* First TLV offset */
+	cfm[4] = transaction_id >> 24;
+	cfm[5] = (transaction_id >> 16) & 0xff;
+	cfm[6] = (transaction_id >> 8) & 0xff;
+	cfm[7] = transaction_id & 0xff;
+	cfm[8] = 0;                         /* End TLV */
+
+	return OAM_FRAME_LEN;
+}
+
+bool oam_parse_frame(const uint8_t *data, uint32_t len, oam_peer_t *peer)
+{
+	if (!data || !peer || len < OAM_OFF_DATA + 1)
+		return false;
+
+	uint16_t ethertype = (uint16_t)((data[OAM_OFF_ETHERTYPE] << 8) | data[OAM_OFF_ETHERTYPE + 1]);
+
+	memset(peer, 0, sizeof(*peer));
+	memcpy(peer->mac, data + 6, 6);
+
+	if (ethertype == ETH_P_SLOW) {
+		if (data[OAM_OFF_SUBTYPE] != SLOW_SUBTYPE_OAM || data[OAM_OFF_CODE] != OAMPDU_INFO)
+			return false;
+		if (len < OAM_OFF_DATA + OAM_TLV_INFO_LEN)
+			return false;
+
+		const uint8_t *tlv = data + OAM_OFF_DATA;
+		if (tlv[0] != OAM_TLV_LOCAL_INFO || tlv[1] != OAM_TLV_INFO_LEN)
+			return false;
+
+		peer->capabilities = OAM_CAP_8023AH;
+		if (tlv[6] & OAM_CONFIG_REMOTE_LB)
+			peer->capabilities |= OAM_CAP_REMOTE_LB;
+		peer->in_loopback = (tlv[5] & OAM_STATE_PARSER_MASK) == OAM_STATE_PARSER_LB;
+		return true;
+	}
+
+	if (ethertype == ETH_P_CFM) {
+		if (data[15] != Y1731_LBR)
+			return false;
+		peer->capabilities = OAM_CAP_Y1731_LB;
+		peer->meg_level = data[14] >> 5;
+		return true;
+	}
+
+	return false;
+}
+
+static int oam_send(rfc2544_ctx_t *ctx, uint8_t *frame, uint32_t len)
+{
+	packet_t pkt;
+	memset(&pkt, 0, sizeof(pkt));
+	pkt.data = frame;
+	pkt.len = len;
+
+	int sent = ctx->platform->send_batch(&ctx->workers[0], &pkt, 1);
+	return sent > 0 ? 0 : -EIO;
+}
+
+/* Merge a parsed frame into the peer table, keyed by MAC */
+static void oam_merge_peer(oam_peer_t *peers, uint32_t max_peers, uint32_t *count,
+                           const oam_peer_t *seen)
+{
+	for (uint32_t i = 0; i < *count; i++) {
+		if (memcmp(peers[i].mac, seen->mac, 6) == 0) {
+			peers[i].capabilities |= seen->capabilities;
+			if (seen->capabilities & OAM_CAP_8023AH)
+				peers[i].in_loopback = seen->in_loopback;
+			if (seen->capabilities & OAM_CAP_Y1731_LB)
+				peers[i].meg_level = seen->meg_level;
+			return;
+		}
+	}
+	if (*count < max_peers)
+		peers[(*count)++] = *seen;
+}
+
+int oam_discover(rfc2544_ctx_t *ctx, uint8_t meg_level, uint32_t timeout_ms,
+                 oam_peer_t *peers, uint32_t max_peers, uint32_t *count)
+{
+	if (!ctx || !ctx->platform || !ctx->workers || !peers || !count || meg_level > 7)
+		return -EINVAL;
+
+	*count = 0;
+
+	uint8_t info[OAM_FRAME_LEN];
+	uint8_t lbm[OAM_FRAME_LEN];
+	oam_build_info(info, sizeof(info), ctx->local_mac);
+
+	rfc2544_log(LOG_INFO, "OAM discovery on %s (MEG level %u, %ums)",
+	            ctx->interface, meg_level, timeout_ms);
+
+	packet_t rx_pkts[64];
+	uint32_t transaction_id = 1;
+	uint64_t deadline = oam_now_ms() + timeout_ms;
+	uint64_t next_tx = 0;
+
+	while (oam_now_ms() < deadline && !ctx->cancel_requested) {
+		if (oam_now_ms() >= next_tx) {
+			oam_build_lbm(lbm, sizeof(lbm), ctx->local_mac, meg_level, transaction_id++);
+			oam_send(ctx, info, sizeof(info));
+			oam_send(ctx, lbm, sizeof(lbm));
+			next_tx = oam_now_ms() + OAM_RETRY_MS;
+		}
+
+		int recv_count = ctx->platform->recv_batch(&ctx->workers[0], rx_pkts, 64);
+		for (int i = 0; i < recv_count; i++) {
+			oam_peer_t seen;
+			if (!oam_parse_frame(rx_pkts[i].data, rx_pkts[i].len, &seen))
+				continue;
+			if (memcmp(seen.mac, ctx->local_mac, 6) == 0)
+				continue;
+			oam_merge_peer(peers, max_peers, count, &seen);
+		}
+		if (recv_count > 0)
+			ctx->platform->release_batch(&ctx->workers[0], rx_pkts, recv_count);
+		else
+			usleep(1000);
+	}
+
+	rfc2544_log(LOG_INFO, "OAM discovery found %u peer(s)", *count);
+	return 0;
+}
+
+int oam_remote_loopback(rfc2544_ctx_t *ctx, const uint8_t *peer_mac, bool enable,
+                        uint32_t timeout_ms)
+{
+	if (!ctx || !ctx->platform || !ctx->workers || !peer_mac)
+		return -EINVAL;
+
+	uint8_t ctl[OAM_FRAME_LEN];
+	oam_build_loopback_ctl(ctl, sizeof(ctl), ctx->local_mac, enable);
+
+	rfc2544_log(LOG_INFO, "OAM: %s remote loopback on %02x:%02x:%02x:%02x:%02x:%02x",
+	            enable ? "enabling" : "releasing", peer_mac[0], peer_mac[1],
+	            peer_mac[2], peer_mac[3], peer_mac[4], peer_mac[5]);
+
+	packet_t rx_pkts[64];
+	uint64_t deadline = oam_now_ms() + timeout_ms;
+	uint64_t next_tx = 0;
+
+	/*
+	 * The peer confirms by advertising the new parser state in its
+	 * Information OAMPDU. Release ignores cancellation so a cancelled
+	 * run never leaves the far end looped.
+	 */
+	while (oam_now_ms() < deadline && (!enable || !ctx->cancel_requested)) {
+		if (oam_now_ms() >= next_tx) {
+			oam_send(ctx, ctl, sizeof(ctl));
+			next_tx = oam_now_ms() + OAM_RETRY_MS;
+		}
+
+		bool confirmed = false;
+		int recv_count = ctx->platform->recv_batch(&ctx->workers[0], rx_pkts, 64);
+		for (int i = 0; i < recv_count; i++) {
+			oam_peer_t seen;
+			if (!oam_parse_frame(rx_pkts[i].data, rx_pkts[i].len, &seen))
+				continue;
+			if (memcmp(seen.mac, peer_mac, 6) != 0 || !(seen.capabilities & OAM_CAP_8023AH))
+				continue;
+			if (seen.in_loopback == enable)
+				confirmed = true;
+		}
+		if (recv_count > 0)
+			ctx->platform->release_batch(&ctx->workers[0], rx_pkts, recv_count);
+		else
+			usleep(1000);
+
+		if (confirmed) {
+			rfc2544_log(LOG_INFO, "OAM: remote loopback %s", enable ? "active" : "released");
+			return 0;
+		}
+	}
+
+	rfc2544_log(LOG_ERROR, "OAM: peer did not confirm loopback %s",
+	            enable ? "enable" : "release");
+	return -ETIMEDOUT;
+}
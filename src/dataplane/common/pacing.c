@@ -30,12 +30,15 @@ struct pacing_ctx {
 
 	/* Timing */
 	uint64_t interval_ns;     /* Nanoseconds between packets */
+	uint64_t interval_rem;    /* Remainder of NS_PER_SEC / target_pps */
+	uint64_t rem_acc;         /* Accumulated remainder, below target_pps */
 	uint64_t next_tx_ns;      /* Next allowed TX time */
 	uint64_t start_ns;        /* Start timestamp */
 
 	/* Burst control */
 	uint32_t batch_size;      /* Packets per batch */
 	uint32_t batch_interval_ns; /* Time per batch */
+	uint32_t max_backlog;     /* Packets we may fall behind before resetting */
 
 	/* Statistics */
 	uint64_t packets_sent;
@@ -94,6 +97,35 @@ static inline void sleep_wait_until(uint64_t target_ns)
  * Pacing API
  * ============================================================================ */
 
+/*
+ * Set the inter-packet interval from target_pps. The interval is kept as
+ * a whole number of nanoseconds plus a remainder accumulated across
+ * packets, so rates whose interval is not whole nanoseconds (6.72ns at
+ * 100G with 64-byte frames) are paced exactly rather than rounded.
+ */
+static void set_interval(pacing_ctx_t *ctx)
+{
+	if (ctx->target_pps > 0) {
+		ctx->interval_ns = NS_PER_SEC / ctx->target_pps;
+		ctx->interval_rem = NS_PER_SEC % ctx->target_pps;
+	} else {
+		ctx->interval_ns = NS_PER_SEC; /* 1 pps minimum */
+		ctx->interval_rem = 0;
+	}
+	ctx->rem_acc = 0;
+}
+
+/* Advance the next TX time by count packet intervals */
+static void advance(pacing_ctx_t *ctx, uint32_t count)
+{
+	ctx->next_tx_ns += ctx->interval_ns * count;
+	if (ctx->interval_rem) {
+		ctx->rem_acc += ctx->interval_rem * count;
+		ctx->next_tx_ns += ctx->rem_acc / ctx->target_pps;
+		ctx->rem_acc %= ctx->target_pps;
+	}
+}
+
 /**
  * Create pacing context
  *
@@ -113,6 +145,7 @@ pacing_ctx_t *pacing_create(uint64_t line_rate_bps, uint32_t frame_size, double
 	ctx->enabled = true;
 	ctx->use_busy_wait = false; /* Default to sleep-based for CPU efficiency */
 	ctx->batch_size = 1;
+	ctx->max_backlog = 10;
 
 	/* Calculate wire size (frame + preamble + IFG) */
 	uint32_t wire_size = frame_size + 20; /* 8 preamble + 12 IFG */
@@ -122,11 +155,7 @@ pacing_ctx_t *pacing_create(uint64_t line_rate_bps, uint32_t frame_size, double
 	ctx->target_pps = ctx->target_bps / (wire_size * 8);
 
 	/* Calculate inter-packet interval */
-	if (ctx->target_pps > 0) {
-		ctx->interval_ns = NS_PER_SEC / ctx->target_pps;
-	} else {
-		ctx->interval_ns = NS_PER_SEC; /* 1 pps minimum */
-	}
+	set_interval(ctx);
 
 	/* Initialize timing */
 	ctx->start_ns = get_time_ns();
@@ -152,11 +181,7 @@ void pacing_set_rate(pacing_ctx_t *ctx, double rate_pct)
 	ctx->target_bps = (uint64_t)(ctx->line_rate_bps * rate_pct / 100.0);
 	ctx->target_pps = ctx->target_bps / (wire_size * 8);
 
-	if (ctx->target_pps > 0) {
-		ctx->interval_ns = NS_PER_SEC / ctx->target_pps;
-	} else {
-		ctx->interval_ns = NS_PER_SEC; /* 1 pps minimum */
-	}
+	set_interval(ctx);
 }
 
 /**
@@ -207,14 +232,14 @@ uint64_t pacing_wait(pacing_ctx_t *ctx)
 		} else {
 			sleep_wait_until(ctx->next_tx_ns);
 		}
-	} else if (now > ctx->next_tx_ns + ctx->interval_ns * 10) {
-		/* Fell behind by more than 10 packets - reset */
+	} else if (now > ctx->next_tx_ns + (ctx->interval_ns + 1) * ctx->max_backlog) {
+		/* Fell behind by more than max_backlog packets - reset */
 		ctx->overruns++;
 		ctx->next_tx_ns = now;
 	}
 
 	/* Update next TX time */
-	ctx->next_tx_ns += ctx->interval_ns;
+	advance(ctx, 1);
 
 	return get_time_ns();
 }
@@ -232,7 +257,7 @@ uint64_t pacing_wait_batch(pacing_ctx_t *ctx, uint32_t batch_size)
 		return get_time_ns();
 
 	uint64_t now = get_time_ns();
-	uint64_t batch_interval = ctx->interval_ns * batch_size;
+	uint32_t backlog = batch_size > ctx->max_backlog ? batch_size : ctx->max_backlog;
 
 	if (now < ctx->next_tx_ns) {
 		ctx->pacing_delays++;
@@ -241,16 +266,33 @@ uint64_t pacing_wait_batch(pacing_ctx_t *ctx, uint32_t batch_size)
 		} else {
 			sleep_wait_until(ctx->next_tx_ns);
 		}
-	} else if (now > ctx->next_tx_ns + batch_interval * 10) {
+	} else if (now > ctx->next_tx_ns + (ctx->interval_ns + 1) * backlog) {
 		ctx->overruns++;
 		ctx->next_tx_ns = now;
 	}
 
-	ctx->next_tx_ns += batch_interval;
+	advance(ctx, batch_size);
 
 	return get_time_ns();
 }
 
+/**
+ * Apply a line-rate tuning profile: busy-wait and how far the pacer may
+ * fall behind before it stops catching up
+ *
+ * @param ctx Pacing context
+ * @param profile Speed profile
+ */
+void pacing_apply_profile(pacing_ctx_t *ctx, const speed_profile_t *profile)
+{
+	if (!ctx || !profile)
+		return;
+
+	pacing_set_batch_size(ctx, profile->batch_size);
+	ctx->use_busy_wait = profile->busy_wait;
+	ctx->max_backlog = profile->max_backlog;
+}
+
 /**
  * Record that packets were sent (for statistics)
  *
@@ -325,6 +367,7 @@ void pacing_reset(pacing_ctx_t *ctx)
 
 	ctx->start_ns = get_time_ns();
 	ctx->next_tx_ns = ctx->start_ns;
+	ctx->rem_acc = 0;
 	ctx->packets_sent = 0;
 	ctx->bytes_sent = 0;
 	ctx->pacing_delays = 0;
@@ -378,6 +421,53 @@ double calc_utilization(uint64_t achieved_pps, uint32_t frame_size, uint64_t lin
 	return 100.0 * achieved_bps / line_rate_bps;
 }
 
+/* ============================================================================
+ * Speed Profiles
+ * ============================================================================ */
+
+/*
+ * TX tuning by line rate, slowest first. Up to 10G a frame per wait keeps
+ * up and sleeping spares the CPU; above, the gap between frames is shorter
+ * than a clock read, so frames go out in batches with the pacer spinning,
+ * and the pacer keeps the credit of a longer stall before it resets.
+ */
+static const speed_profile_t speed_profiles[] = {
+	{"1g", 1000000000ULL, 1, false, 10},
+	{"2.5g", 2500000000ULL, 1, false, 16},
+	{"5g", 5000000000ULL, 1, false, 32},
+	{"10g", 10000000000ULL, 1, false, 64},
+	{"25g", 25000000000ULL, 4, true, 128},
+	{"40g", 40000000000ULL, 8, true, 256},
+	{"50g", 50000000000ULL, 8, true, 256},
+	{"100g", 100000000000ULL, 16, true, 512},
+	{"200g", 200000000000ULL, 32, true, 1024},
+	{"400g", 400000000000ULL, 64, true, 2048},
+};
+
+#define SPEED_PROFILE_COUNT (sizeof(speed_profiles) / sizeof(speed_profiles[0]))
+
+const speed_profile_t *rfc2544_speed_profile(uint64_t line_rate)
+{
+	const speed_profile_t *profile = &speed_profiles[0];
+	for (size_t i = 1; i < SPEED_PROFILE_COUNT; i++) {
+		if (speed_profiles[i].line_rate > line_rate)
+			break;
+		profile = &speed_profiles[i];
+	}
+	return profile;
+}
+
+const speed_profile_t *rfc2544_speed_profile_by_name(const char *name)
+{
+	if (!name)
+		return NULL;
+	for (size_t i = 0; i < SPEED_PROFILE_COUNT; i++) {
+		if (strcmp(speed_profiles[i].name, name) == 0)
+			return &speed_profiles[i];
+	}
+	return NULL;
+}
+
 /* ============================================================================
  * Trial Timer
  * ============================================================================ */
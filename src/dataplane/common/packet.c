@@ -11,6 +11,8 @@
 #include "platform_config.h"
 
 #include <arpa/inet.h>
+#include <errno.h>
+#include <pthread.h>
 #include <stdint.h>
 #include <stdio.h>
 #include <stdlib.h>
@@ -97,6 +99,123 @@ static uint16_t ip_checksum(const void *data, size_t len)
 	return ~sum;
 }
 
+/* From ipv6.c */
+uint16_t rfc2544_ipv6_udp_checksum(const uint8_t *src_addr, const uint8_t *dst_addr,
+                                    uint16_t udp_len, const uint8_t *udp_data);
+int rfc2544_build_ipv6_header(uint8_t *buffer, uint16_t payload_len, const ipv6_config_t *config);
+
+/* Fill in the UDP checksum, required over IPv6, behind an IPv6 header */
+static void ipv6_udp_checksum(uint8_t *ip6)
+{
+	udp_header_t *udp = (udp_header_t *)(ip6 + IPV6_HEADER_LEN);
+	uint16_t udp_len = ntohs(udp->length);
+	udp->checksum = 0;
+	udp->checksum = rfc2544_ipv6_udp_checksum(&ip6[8], &ip6[24], udp_len, (uint8_t *)udp);
+	if (udp->checksum == 0)
+		udp->checksum = 0xFFFF; /* Zero means no checksum */
+}
+
+bool rfc2544_valid_tpid(uint16_t tpid)
+{
+	switch (tpid) {
+	case TPID_8021Q:
+	case TPID_8021AD:
+	case 0x9100:
+	case 0x9200:
+	case 0x9300:
+		return true;
+	}
+	return false;
+}
+
+/*
+ * Length of the L2 header: 14 for Ethernet II, 22 when an 802.3 length
+ * field is followed by an LLC/SNAP header, and 4 more behind each of up
+ * to two VLAN tags. An MPLS label stack, down to its bottom-of-stack
+ * label, counts as part of it; only rfc2544_insert_mpls writes an MPLS
+ * EtherType, as config validation refuses one without mpls_labels.
+ */
+static uint32_t l2_header_len(const uint8_t *data, uint32_t len)
+{
+	const eth_header_t *eth = (const eth_header_t *)data;
+	uint32_t base = sizeof(eth_header_t);
+	uint16_t type = ntohs(eth->ethertype);
+
+	for (int tags = 0; tags < 2 && rfc2544_valid_tpid(type) && len >= base + VLAN_TAG_LEN; tags++) {
+		type = (uint16_t)((data[base + 2] << 8) | data[base + 3]);
+		base += VLAN_TAG_LEN;
+	}
+	if (type == ETHERTYPE_MPLS || type == ETHERTYPE_MPLS_MC) {
+		for (int labels = 0; labels < MAX_MPLS_LABELS && len >= base + MPLS_LABEL_LEN; labels++) {
+			bool bottom = data[base + 2] & 0x01;
+			base += MPLS_LABEL_LEN;
+			if (bottom)
+				break;
+		}
+		return base;
+	}
+	if (len >= base + LLC_SNAP_HEADER_LEN && type <= MAX_8023_LENGTH &&
+	    data[base] == 0xAA && data[base + 1] == 0xAA && data[base + 2] == 0x03) {
+		return base + LLC_SNAP_HEADER_LEN;
+	}
+	return base;
+}
+
+/* Length of the IPv6 or IPv4 (no options) header at l3 */
+static uint32_t ip_header_len(const uint8_t *l3)
+{
+	return (l3[0] >> 4) == 6 ? IPV6_HEADER_LEN : sizeof(ip_header_t);
+}
+
+/*
+ * Offset of the test payload of a received frame: the L2 header, the
+ * IPv4 or IPv6 header and UDP
+ */
+static uint32_t payload_offset(const uint8_t *data, uint32_t len)
+{
+	uint32_t l2 = l2_header_len(data, len);
+	if (len <= l2)
+		return l2 + sizeof(ip_header_t) + sizeof(udp_header_t);
+	return l2 + ip_header_len(data + l2) + sizeof(udp_header_t);
+}
+
+int rfc2544_ip_version(const uint8_t *data, uint32_t len)
+{
+	if (!data)
+		return 0;
+	uint32_t l2 = l2_header_len(data, len);
+	if (len <= l2)
+		return 0;
+	int version = data[l2] >> 4;
+	return (version == 4 || version == 6) ? version : 0;
+}
+
+/*
+ * Shorten the IP and UDP lengths of the packet behind l2 by the bytes a
+ * header inserted in front of it took from the padding
+ */
+static void shrink_l3(uint8_t *buffer, uint32_t l2, uint32_t by)
+{
+	uint8_t *l3 = buffer + l2;
+	if ((l3[0] >> 4) == 6) {
+		uint16_t payload_len = (uint16_t)(((l3[4] << 8) | l3[5]) - by);
+		l3[4] = payload_len >> 8;
+		l3[5] = payload_len & 0xff;
+		udp_header_t *udp = (udp_header_t *)(l3 + IPV6_HEADER_LEN);
+		udp->length = htons(ntohs(udp->length) - by);
+		ipv6_udp_checksum(l3);
+		return;
+	}
+
+	ip_header_t *ip = (ip_header_t *)l3;
+	ip->total_length = htons(ntohs(ip->total_length) - by);
+	ip->checksum = 0;
+	ip->checksum = ip_checksum(ip, sizeof(ip_header_t));
+
+	udp_header_t *udp = (udp_header_t *)(l3 + sizeof(ip_header_t));
+	udp->length = htons(ntohs(udp->length) - by);
+}
+
 /* ============================================================================
  * Packet Template Creation
  * ============================================================================ */
@@ -192,6 +311,176 @@ rfc2544_payload_t *rfc2544_create_packet_template(uint8_t *buffer, uint32_t fram
 	return payload;
 }
 
+/* SplitMix64 step of the random padding pattern */
+static uint64_t splitmix64(uint64_t *state)
+{
+	uint64_t z = (*state += 0x9E3779B97F4A7C15ULL);
+	z = (z ^ (z >> 30)) * 0xBF58476D1CE4E5B9ULL;
+	z = (z ^ (z >> 27)) * 0x94D049BB133111EBULL;
+	return z ^ (z >> 31);
+}
+
+/**
+ * Fill a frame's padding with a pattern
+ *
+ * Random bytes are the SplitMix64 outputs from the seed, each most
+ * significant byte first; PRBS-31 bits are the generator's, x^31 + x^28 + 1
+ * from all ones, packed MSB first.
+ *
+ * @param buf Padding
+ * @param len Bytes to fill
+ * @param pattern Padding pattern (NULL = incrementing)
+ */
+void rfc2544_fill_pattern(uint8_t *buf, uint32_t len, const payload_pattern_t *pattern)
+{
+	if (!buf)
+		return;
+
+	switch (pattern ? pattern->kind : PAYLOAD_INCREMENTING) {
+	case PAYLOAD_ZEROS:
+		memset(buf, 0, len);
+		break;
+	case PAYLOAD_ONES:
+		memset(buf, 0xFF, len);
+		break;
+	case PAYLOAD_RANDOM: {
+		uint64_t state = pattern->seed;
+		for (uint32_t i = 0; i < len; i += 8) {
+			uint64_t v = splitmix64(&state);
+			for (uint32_t j = 0; j < 8 && i + j < len; j++)
+				buf[i + j] = (uint8_t)(v >> (56 - 8 * j));
+		}
+		break;
+	}
+	case PAYLOAD_PRBS31: {
+		uint32_t state = 0x7FFFFFFF;
+		for (uint32_t i = 0; i < len; i++) {
+			uint8_t b = 0;
+			for (int bit = 0; bit < 8; bit++) {
+				uint32_t next = ((state >> 30) ^ (state >> 27)) & 1;
+				state = ((state << 1) | next) & 0x7FFFFFFF;
+				b = (uint8_t)((b << 1) | next);
+			}
+			buf[i] = b;
+		}
+		break;
+	}
+	case PAYLOAD_CUSTOM:
+		if (!pattern->len) {
+			memset(buf, 0, len);
+			break;
+		}
+		for (uint32_t i = 0; i < len; i++)
+			buf[i] = pattern->bytes[i % pattern->len];
+		break;
+	default:
+		for (uint32_t i = 0; i < len; i++)
+			buf[i] = (uint8_t)(i & 0xFF);
+		break;
+	}
+}
+
+int rfc2544_convert_ipv6(uint8_t *buffer, uint32_t frame_size, const ipv6_config_t *config)
+{
+	const uint32_t hdr = sizeof(eth_header_t);
+	if (!buffer || !config || config->flow_label > IPV6_MAX_FLOW_LABEL)
+		return -EINVAL;
+
+	eth_header_t *eth = (eth_header_t *)buffer;
+	if (ntohs(eth->ethertype) != ETH_P_IP || (buffer[hdr] >> 4) != 4)
+		return -EINVAL;
+	uint32_t min_frame = hdr + IPV6_HEADER_LEN + sizeof(udp_header_t) + sizeof(rfc2544_payload_t);
+	if (frame_size < min_frame)
+		return -EINVAL;
+
+	memmove(buffer + hdr + IPV6_HEADER_LEN, buffer + hdr + sizeof(ip_header_t),
+	        frame_size - hdr - IPV6_HEADER_LEN);
+	eth->ethertype = htons(ETH_P_IPV6);
+
+	ipv6_config_t v6 = *config;
+	if (!v6.hop_limit)
+		v6.hop_limit = 64;
+	uint16_t payload_len = (uint16_t)(frame_size - hdr - IPV6_HEADER_LEN);
+	uint8_t *ip6 = buffer + hdr;
+	rfc2544_build_ipv6_header(ip6, payload_len, &v6);
+
+	udp_header_t *udp = (udp_header_t *)(ip6 + IPV6_HEADER_LEN);
+	udp->length = htons(payload_len);
+	ipv6_udp_checksum(ip6);
+
+	return IPV6_HEADER_LEN - sizeof(ip_header_t);
+}
+
+/**
+ * Create a packet from a user-defined header template
+ *
+ * The template's headers are copied in front of the RFC 2544 payload and
+ * the IP and UDP lengths and checksums filled in for frame_size. The UDP
+ * checksum is required over IPv6, so it is computed for the template frame;
+ * per-frame sequence numbers and timestamps leave it stale, which forwarding
+ * DUTs do not check.
+ *
+ * @param buffer Output buffer (must be at least frame_size bytes)
+ * @param frame_size Total frame size including Ethernet header
+ * @param tpl Header template (header_len > 0)
+ * @param src_mac Source MAC, used if the template fills it
+ * @param dst_mac Destination MAC, used if the template fills it
+ * @param pattern Padding pattern (NULL = incrementing)
+ * @return Pointer to payload area, or NULL on error
+ */
+rfc2544_payload_t *rfc2544_create_templated_packet(uint8_t *buffer, uint32_t frame_size,
+                                                    const header_template_t *tpl,
+                                                    const uint8_t *src_mac, const uint8_t *dst_mac,
+                                                    const payload_pattern_t *pattern)
+{
+	if (!buffer || !tpl || !tpl->header_len)
+		return NULL;
+
+	uint32_t min_frame = tpl->header_len + sizeof(rfc2544_payload_t);
+	if (frame_size < min_frame) {
+		fprintf(stderr, "[packet] Frame size %u too small for packet template (minimum: %u bytes)\n",
+		        frame_size, min_frame);
+		return NULL;
+	}
+
+	memset(buffer, 0, frame_size);
+	memcpy(buffer, tpl->header, tpl->header_len);
+
+	eth_header_t *eth = (eth_header_t *)buffer;
+	if (tpl->flags & TEMPLATE_FILL_DST_MAC)
+		memcpy(eth->dst_mac, dst_mac, 6);
+	if (tpl->flags & TEMPLATE_FILL_SRC_MAC)
+		memcpy(eth->src_mac, src_mac, 6);
+
+	/* RFC2544 payload */
+	rfc2544_payload_t *payload = (rfc2544_payload_t *)(buffer + tpl->header_len);
+	memcpy(payload->signature, RFC2544_SIGNATURE, RFC2544_SIG_LEN);
+	payload->stream_id = 0;
+	payload->flags = RFC2544_FLAG_REQ_TIMESTAMP;
+
+	rfc2544_fill_pattern(buffer + min_frame, frame_size - min_frame, pattern);
+
+	udp_header_t *udp = (udp_header_t *)(buffer + tpl->udp_offset);
+	uint16_t udp_len = (uint16_t)(frame_size - tpl->udp_offset);
+	udp->length = htons(udp_len);
+	udp->checksum = 0;
+
+	if (tpl->ipv4_offset >= 0) {
+		ip_header_t *ip = (ip_header_t *)(buffer + tpl->ipv4_offset);
+		ip->total_length = htons(frame_size - tpl->ipv4_offset);
+		ip->checksum = 0;
+		ip->checksum = ip_checksum(ip, sizeof(ip_header_t));
+	} else {
+		uint8_t *ip6 = buffer + tpl->ipv6_offset;
+		uint16_t payload_len = (uint16_t)(frame_size - tpl->ipv6_offset - IPV6_HEADER_LEN);
+		ip6[4] = payload_len >> 8;
+		ip6[5] = payload_len & 0xFF;
+		ipv6_udp_checksum(ip6);
+	}
+
+	return payload;
+}
+
 /**
  * Update packet with new sequence number and timestamp
  *
@@ -212,6 +501,642 @@ void rfc2544_stamp_packet(rfc2544_payload_t *payload, uint32_t seq_num, uint64_t
 	payload->timestamp = ts_be;
 }
 
+/* ============================================================================
+ * Payload Verification
+ * ============================================================================ */
+
+static uint32_t crc32_table[256];
+static pthread_once_t crc32_once = PTHREAD_ONCE_INIT;
+
+static void crc32_init(void)
+{
+	for (uint32_t i = 0; i < 256; i++) {
+		uint32_t c = i;
+		for (int k = 0; k < 8; k++)
+			c = (c & 1) ? 0xEDB88320 ^ (c >> 1) : c >> 1;
+		crc32_table[i] = c;
+	}
+}
+
+/**
+ * Continue a CRC-32 (IEEE 802.3, as zlib's crc32) over len bytes
+ *
+ * @param crc CRC of the bytes before data, 0 to start
+ * @param data Bytes to add
+ * @param len Number of bytes
+ * @return CRC of the bytes so far
+ */
+uint32_t rfc2544_crc32(uint32_t crc, const uint8_t *data, size_t len)
+{
+	pthread_once(&crc32_once, crc32_init);
+	crc = ~crc;
+	while (len--)
+		crc = crc32_table[(crc ^ *data++) & 0xFF] ^ (crc >> 8);
+	return ~crc;
+}
+
+/* Payload bytes covered after the padding: all but the flags */
+#define CRC_HEADER_LEN RFC2544_FLAGS_OFFSET
+
+/**
+ * Prepare a payload to carry a CRC-32 trailer
+ *
+ * The padding is the same in every frame, so its CRC is computed once
+ * here and continued over each frame's stamped fields by
+ * rfc2544_seal_packet.
+ *
+ * @param payload Pointer to payload (from create_packet_template)
+ * @param payload_len Bytes from the payload to the end of the frame (>= 28)
+ * @return CRC of the padding, for rfc2544_seal_packet
+ */
+uint32_t rfc2544_prepare_seal(rfc2544_payload_t *payload, uint32_t payload_len)
+{
+	payload->flags |= RFC2544_FLAG_CRC32;
+	return rfc2544_crc32(0, (const uint8_t *)payload + RFC2544_PADDING_OFFSET,
+	                     payload_len - RFC2544_PADDING_OFFSET - RFC2544_CRC_LEN);
+}
+
+/**
+ * Write the CRC-32 trailer of a stamped packet
+ *
+ * @param payload Pointer to payload (from create_packet_template)
+ * @param payload_len Bytes from the payload to the end of the frame
+ * @param padding_crc CRC of the padding (from rfc2544_prepare_seal)
+ */
+void rfc2544_seal_packet(rfc2544_payload_t *payload, uint32_t payload_len, uint32_t padding_crc)
+{
+	uint8_t *p = (uint8_t *)payload;
+	uint32_t crc = htonl(rfc2544_crc32(padding_crc, p, CRC_HEADER_LEN));
+	memcpy(p + payload_len - RFC2544_CRC_LEN, &crc, sizeof(crc));
+}
+
+/**
+ * Check the CRC-32 trailer of a received RFC2544 test frame
+ *
+ * The payload's length is taken from the UDP header in front of it, so a
+ * truncated or padded frame fails too.
+ *
+ * @param data Packet data
+ * @param len Packet length
+ * @param offset Payload offset, or 0 to locate it as rfc2544_parse_response does
+ * @return true if the payload is as sent
+ */
+bool rfc2544_payload_intact(const uint8_t *data, uint32_t len, uint32_t offset)
+{
+	if (!data)
+		return false;
+	if (offset == 0)
+		offset = payload_offset(data, len);
+	if (offset < sizeof(udp_header_t) || len < offset)
+		return false;
+
+	const udp_header_t *udp = (const udp_header_t *)(data + offset - sizeof(udp_header_t));
+	uint32_t udp_len = ntohs(udp->length);
+	if (udp_len < sizeof(udp_header_t) + RFC2544_PADDING_OFFSET + RFC2544_CRC_LEN)
+		return false;
+	uint32_t payload_len = udp_len - sizeof(udp_header_t);
+	if (payload_len > len - offset)
+		return false;
+
+	const uint8_t *p = data + offset;
+	uint32_t crc = rfc2544_crc32(0, p + RFC2544_PADDING_OFFSET,
+	                             payload_len - RFC2544_PADDING_OFFSET - RFC2544_CRC_LEN);
+	crc = rfc2544_crc32(crc, p, CRC_HEADER_LEN);
+
+	uint32_t sealed;
+	memcpy(&sealed, p + payload_len - RFC2544_CRC_LEN, sizeof(sealed));
+	return crc == ntohl(sealed);
+}
+
+/**
+ * Re-encapsulate a packet template
+ *
+ * Ethernet II framing only replaces the EtherType. LLC/SNAP framing
+ * inserts an 8 byte LLC/SNAP header after an 802.3 length field, moving
+ * the IP/UDP/payload back and shortening the padding.
+ *
+ * @param buffer Packet buffer (from create_packet_template)
+ * @param frame_size Frame size in bytes (including FCS)
+ * @param framing Framing configuration
+ * @return Bytes the payload moved by, or negative on error
+ */
+int rfc2544_apply_framing(uint8_t *buffer, uint32_t frame_size, const framing_config_t *framing)
+{
+	if (!buffer || !framing)
+		return -EINVAL;
+
+	eth_header_t *eth = (eth_header_t *)buffer;
+	bool v6 = ntohs(eth->ethertype) == ETH_P_IPV6;
+	uint16_t ethertype = framing->ethertype ? framing->ethertype : (v6 ? ETH_P_IPV6 : ETH_P_IP);
+
+	if (framing->mode == FRAMING_ETHERNET_II) {
+		eth->ethertype = htons(ethertype);
+		return 0;
+	}
+	if (v6)
+		return -EINVAL; /* LLC/SNAP framing is IPv4 only */
+
+	/* 802.3 length excludes the MAC header and FCS */
+	uint32_t hdr = sizeof(eth_header_t);
+	uint32_t min_frame = hdr + LLC_SNAP_HEADER_LEN + sizeof(ip_header_t) +
+	                     sizeof(udp_header_t) + sizeof(rfc2544_payload_t);
+	if (frame_size < min_frame || frame_size - hdr - 4 > MAX_8023_LENGTH)
+		return -EINVAL;
+
+	memmove(buffer + hdr + LLC_SNAP_HEADER_LEN, buffer + hdr,
+	        frame_size - hdr - LLC_SNAP_HEADER_LEN);
+
+	eth->ethertype = htons((uint16_t)(frame_size - hdr - 4));
+	uint8_t *snap = buffer + hdr;
+	snap[0] = 0xAA; /* DSAP */
+	snap[1] = 0xAA; /* SSAP */
+	snap[2] = 0x03; /* Control: UI */
+	snap[3] = 0x00; /* OUI 00-00-00: EtherType follows */
+	snap[4] = 0x00;
+	snap[5] = 0x00;
+	snap[6] = ethertype >> 8;
+	snap[7] = ethertype & 0xff;
+
+	ip_header_t *ip = (ip_header_t *)(buffer + hdr + LLC_SNAP_HEADER_LEN);
+	ip->total_length = htons(frame_size - hdr - LLC_SNAP_HEADER_LEN);
+	ip->checksum = 0;
+	ip->checksum = ip_checksum(ip, sizeof(ip_header_t));
+
+	udp_header_t *udp = (udp_header_t *)((uint8_t *)ip + sizeof(ip_header_t));
+	udp->length = htons(frame_size - hdr - LLC_SNAP_HEADER_LEN - sizeof(ip_header_t));
+
+	return LLC_SNAP_HEADER_LEN;
+}
+
+/**
+ * Insert a VLAN tag after the MAC addresses
+ *
+ * The frame keeps its size: IP, UDP and payload move back 4 bytes and the
+ * padding loses its last 4. IP and UDP lengths, and an 802.3 length,
+ * shrink to match. A tag already present becomes the inner tag.
+ *
+ * @param buffer Packet buffer (from create_packet_template, after framing)
+ * @param frame_size Frame size in bytes (including FCS)
+ * @param tpid Tag protocol identifier
+ * @param vlan_id VLAN ID (0-4094)
+ * @param pcp 802.1p priority (0-7)
+ * @return Bytes the payload moved by, or negative on error
+ */
+int rfc2544_insert_tag(uint8_t *buffer, uint32_t frame_size, uint16_t tpid, uint16_t vlan_id,
+                       uint8_t pcp)
+{
+	if (!buffer || !rfc2544_valid_tpid(tpid) || vlan_id > VLAN_MAX_ID || pcp > 7)
+		return -EINVAL;
+
+	uint32_t l2 = l2_header_len(buffer, frame_size);
+	if (frame_size <= l2)
+		return -EINVAL;
+	uint32_t min_frame = l2 + VLAN_TAG_LEN + ip_header_len(buffer + l2) + sizeof(udp_header_t) +
+	                     sizeof(rfc2544_payload_t);
+	if (frame_size < min_frame)
+		return -EINVAL;
+
+	/* Received frames are parsed through at most two tags */
+	uint32_t macs = 2 * 6;
+	uint8_t *type = buffer + macs;
+	int tags = 0;
+	while (rfc2544_valid_tpid((uint16_t)((type[0] << 8) | type[1]))) {
+		type += VLAN_TAG_LEN;
+		tags++;
+	}
+	if (tags >= 2)
+		return -EINVAL;
+
+	memmove(buffer + macs + VLAN_TAG_LEN, buffer + macs, frame_size - macs - VLAN_TAG_LEN);
+	uint16_t tci = (uint16_t)(pcp << 13 | vlan_id);
+	buffer[macs] = tpid >> 8;
+	buffer[macs + 1] = tpid & 0xff;
+	buffer[macs + 2] = tci >> 8;
+	buffer[macs + 3] = tci & 0xff;
+	l2 += VLAN_TAG_LEN;
+
+	/* An 802.3 length counts the bytes after the tags */
+	type += VLAN_TAG_LEN;
+	uint16_t len_8023 = (uint16_t)((type[0] << 8) | type[1]);
+	if (len_8023 <= MAX_8023_LENGTH) {
+		len_8023 -= VLAN_TAG_LEN;
+		type[0] = len_8023 >> 8;
+		type[1] = len_8023 & 0xff;
+	}
+
+	shrink_l3(buffer, l2, VLAN_TAG_LEN);
+	return VLAN_TAG_LEN;
+}
+
+int rfc2544_insert_vlan_tag(uint8_t *buffer, uint32_t frame_size, uint16_t vlan_id, uint8_t pcp)
+{
+	return rfc2544_insert_tag(buffer, frame_size, TPID_8021Q, vlan_id, pcp);
+}
+
+/**
+ * Insert an MPLS label stack in front of the IPv4 or IPv6 header
+ *
+ * Like a VLAN tag, the stack takes its bytes from the padding: IP, UDP
+ * and payload move back and their lengths shrink to match. The EtherType
+ * after any tags becomes ETHERTYPE_MPLS.
+ *
+ * @param buffer Packet buffer (from create_packet_template, Ethernet II)
+ * @param frame_size Frame size in bytes (including FCS)
+ * @param labels Label stack, outermost first
+ * @param count Labels in the stack
+ * @return Bytes the payload moved by, or negative on error
+ */
+int rfc2544_insert_mpls(uint8_t *buffer, uint32_t frame_size, const mpls_label_t *labels,
+                        uint32_t count)
+{
+	if (!buffer || !labels || count == 0 || count > MAX_MPLS_LABELS)
+		return -EINVAL;
+
+	/* Labels replace the IP EtherType after any tags */
+	uint8_t *type = buffer + 2 * 6;
+	while (rfc2544_valid_tpid((uint16_t)((type[0] << 8) | type[1])))
+		type += VLAN_TAG_LEN;
+	uint16_t ethertype = (uint16_t)((type[0] << 8) | type[1]);
+	if (ethertype != ETH_P_IP && ethertype != ETH_P_IPV6)
+		return -EINVAL;
+
+	uint32_t l2 = (uint32_t)(type + 2 - buffer);
+	uint32_t shift = count * MPLS_LABEL_LEN;
+	uint32_t ip_len = ethertype == ETH_P_IPV6 ? IPV6_HEADER_LEN : sizeof(ip_header_t);
+	uint32_t min_frame = l2 + shift + ip_len + sizeof(udp_header_t) + sizeof(rfc2544_payload_t);
+	if (frame_size < min_frame)
+		return -EINVAL;
+
+	for (uint32_t i = 0; i < count; i++) {
+		if (labels[i].label > MPLS_MAX_LABEL || labels[i].exp > 7)
+			return -EINVAL;
+	}
+
+	memmove(buffer + l2 + shift, buffer + l2, frame_size - l2 - shift);
+	type[0] = ETHERTYPE_MPLS >> 8;
+	type[1] = ETHERTYPE_MPLS & 0xff;
+	for (uint32_t i = 0; i < count; i++) {
+		uint8_t *lse = buffer + l2 + i * MPLS_LABEL_LEN;
+		uint32_t entry = labels[i].label << 12 | (uint32_t)labels[i].exp << 9 |
+		                 (i == count - 1 ? 1u << 8 : 0) | (labels[i].ttl ? labels[i].ttl : 64);
+		lse[0] = entry >> 24;
+		lse[1] = (entry >> 16) & 0xff;
+		lse[2] = (entry >> 8) & 0xff;
+		lse[3] = entry & 0xff;
+	}
+
+	shrink_l3(buffer, l2 + shift, shift);
+	return (int)shift;
+}
+
+/**
+ * Mark the priority of a frame's VLAN tags and IP header
+ *
+ * @param buffer Packet buffer (from create_packet_template, after tags)
+ * @param frame_size Frame size in bytes (including FCS)
+ * @param pcp 802.1p priority of every tag
+ * @param dscp DSCP of the IPv4 header or IPv6 traffic class
+ * @return 0 on success, or negative on error
+ */
+int rfc2544_mark_priority(uint8_t *buffer, uint32_t frame_size, uint8_t pcp, uint8_t dscp)
+{
+	if (!buffer || pcp > 7 || dscp > 63)
+		return -EINVAL;
+
+	uint32_t l2 = l2_header_len(buffer, frame_size);
+	if (frame_size < l2 + sizeof(ip_header_t))
+		return -EINVAL;
+
+	uint8_t *tag = buffer + 2 * 6;
+	for (int tags = 0; tags < 2 && rfc2544_valid_tpid((uint16_t)((tag[0] << 8) | tag[1]));
+	     tags++) {
+		tag[2] = (uint8_t)((tag[2] & 0x1f) | pcp << 5);
+		tag += VLAN_TAG_LEN;
+	}
+
+	/* The IPv6 traffic class straddles the first two bytes */
+	uint8_t *l3 = buffer + l2;
+	if ((l3[0] >> 4) == 6 && frame_size >= l2 + IPV6_HEADER_LEN) {
+		uint8_t tc = (uint8_t)(dscp << 2 | (((l3[1] >> 4) & 0x03)));
+		l3[0] = (uint8_t)(0x60 | tc >> 4);
+		l3[1] = (uint8_t)((tc & 0x0f) << 4 | (l3[1] & 0x0f));
+		ipv6_udp_checksum(l3);
+		return 0;
+	}
+
+	ip_header_t *ip = (ip_header_t *)(buffer + l2);
+	if ((ip->version_ihl >> 4) != 4)
+		return -EINVAL;
+	ip->tos = (uint8_t)(dscp << 2 | (ip->tos & 0x03));
+	ip->checksum = 0;
+	ip->checksum = ip_checksum(ip, sizeof(ip_header_t));
+	return 0;
+}
+
+/**
+ * Insert the inner tag, then the outer S-tag over it, each when set
+ *
+ * @param buffer Packet buffer (from create_packet_template, after framing)
+ * @param frame_size Frame size in bytes (including FCS)
+ * @param qinq S-tag and TPIDs, or NULL
+ * @param vlan_id Inner VLAN ID
+ * @param pcp Inner 802.1p priority
+ * @return Bytes the payload moved by, or negative on error
+ */
+int rfc2544_insert_tags(uint8_t *buffer, uint32_t frame_size, const qinq_config_t *qinq,
+                        uint16_t vlan_id, uint8_t pcp)
+{
+	int shift = 0;
+
+	if (vlan_id || pcp) {
+		uint16_t tpid = (qinq && qinq->c_tpid) ? qinq->c_tpid : TPID_8021Q;
+		int ret = rfc2544_insert_tag(buffer, frame_size, tpid, vlan_id, pcp);
+		if (ret < 0)
+			return ret;
+		shift += ret;
+	}
+	if (qinq && (qinq->s_vlan_id || qinq->s_pcp)) {
+		uint16_t tpid = qinq->s_tpid ? qinq->s_tpid : TPID_8021AD;
+		int ret = rfc2544_insert_tag(buffer, frame_size, tpid, qinq->s_vlan_id, qinq->s_pcp);
+		if (ret < 0)
+			return ret;
+		shift += ret;
+	}
+	return shift;
+}
+
+/* ============================================================================
+ * Control-Plane Frames
+ * ============================================================================ */
+
+#define ETHERTYPE_ARP 0x0806
+#define ARP_OP_REQUEST 1
+#define ARP_OP_REPLY 2
+#define ICMP_ECHO_REQUEST 8
+#define ICMP_ECHO_REPLY 0
+#define TCP_FLAG_SYN 0x02
+#define TCP_FLAG_RST 0x04
+#define TCP_FLAG_ACK 0x10
+
+/* ARP for IPv4 over Ethernet (28 bytes) */
+typedef struct __attribute__((packed)) {
+	uint16_t htype;
+	uint16_t ptype;
+	uint8_t hlen;
+	uint8_t plen;
+	uint16_t op;
+	uint8_t sha[6];
+	uint32_t spa;
+	uint8_t tha[6];
+	uint32_t tpa;
+} arp_header_t;
+
+/* TCP header (20 bytes, no options) */
+typedef struct __attribute__((packed)) {
+	uint16_t src_port;
+	uint16_t dst_port;
+	uint32_t seq;
+	uint32_t ack;
+	uint8_t data_offset;
+	uint8_t flags;
+	uint16_t window;
+	uint16_t checksum;
+	uint16_t urgent;
+} tcp_header_t;
+
+static ip_header_t *control_ip_header(uint8_t *buf, uint8_t protocol, uint16_t payload_len,
+                                      uint32_t src_ip, uint32_t dst_ip)
+{
+	ip_header_t *ip = (ip_header_t *)(buf + sizeof(eth_header_t));
+	ip->version_ihl = 0x45;
+	ip->total_length = htons(sizeof(ip_header_t) + payload_len);
+	ip->ttl = 64;
+	ip->protocol = protocol;
+	ip->src_ip = src_ip;
+	ip->dst_ip = dst_ip;
+	ip->checksum = ip_checksum(ip, sizeof(ip_header_t));
+	return ip;
+}
+
+/**
+ * Build a control-plane frame aimed at the DUT
+ *
+ * ICMP echo requests and TCP SYNs to port 179 are unicast to dst_mac;
+ * ARP requests for dst_ip are broadcast.
+ */
+int rfc2544_build_control_frame(uint8_t *buf, uint32_t len, cpp_frame_t type,
+                                const uint8_t *src_mac, const uint8_t *dst_mac,
+                                uint32_t src_ip, uint32_t dst_ip, uint16_t seq)
+{
+	if (!buf || !src_mac || !dst_mac || len < CPP_FRAME_LEN)
+		return -EINVAL;
+
+	memset(buf, 0, CPP_FRAME_LEN);
+	eth_header_t *eth = (eth_header_t *)buf;
+	memcpy(eth->src_mac, src_mac, 6);
+	memcpy(eth->dst_mac, dst_mac, 6);
+	eth->ethertype = htons(ETH_P_IP);
+
+	uint8_t *l4 = buf + sizeof(eth_header_t) + sizeof(ip_header_t);
+
+	switch (type) {
+	case CPP_FRAME_ICMP: {
+		const uint16_t icmp_len = 8;
+		control_ip_header(buf, IPPROTO_ICMP, icmp_len, src_ip, dst_ip);
+		l4[0] = ICMP_ECHO_REQUEST;
+		l4[4] = 0x25; /* Identifier */
+		l4[5] = 0x44;
+		l4[6] = seq >> 8;
+		l4[7] = seq & 0xff;
+		uint16_t csum = ip_checksum(l4, icmp_len);
+		memcpy(l4 + 2, &csum, 2);
+		break;
+	}
+	case CPP_FRAME_ARP: {
+		memset(eth->dst_mac, 0xff, 6);
+		eth->ethertype = htons(ETHERTYPE_ARP);
+		arp_header_t *arp = (arp_header_t *)(buf + sizeof(eth_header_t));
+		arp->htype = htons(1);
+		arp->ptype = htons(ETH_P_IP);
+		arp->hlen = 6;
+		arp->plen = 4;
+		arp->op = htons(ARP_OP_REQUEST);
+		memcpy(arp->sha, src_mac, 6);
+		arp->spa = src_ip;
+		arp->tpa = dst_ip;
+		break;
+	}
+	case CPP_FRAME_BGP: {
+		control_ip_header(buf, IPPROTO_TCP, sizeof(tcp_header_t), src_ip, dst_ip);
+		tcp_header_t *tcp = (tcp_header_t *)l4;
+		tcp->src_port = htons((uint16_t)(49152 + (seq & 0x3fff)));
+		tcp->dst_port = htons(CPP_BGP_PORT);
+		tcp->seq = htonl(seq);
+		tcp->data_offset = 5 << 4;
+		tcp->flags = TCP_FLAG_SYN;
+		tcp->window = htons(65535);
+
+		/* Checksum over pseudo-header + segment */
+		uint8_t pseudo[12 + sizeof(tcp_header_t)];
+		memcpy(pseudo, &src_ip, 4);
+		memcpy(pseudo + 4, &dst_ip, 4);
+		pseudo[8] = 0;
+		pseudo[9] = IPPROTO_TCP;
+		pseudo[10] = 0;
+		pseudo[11] = sizeof(tcp_header_t);
+		memcpy(pseudo + 12, tcp, sizeof(tcp_header_t));
+		tcp->checksum = ip_checksum(pseudo, sizeof(pseudo));
+		break;
+	}
+	default:
+		return -EINVAL;
+	}
+
+	return CPP_FRAME_LEN;
+}
+
+/**
+ * Classify a received frame as the DUT's reply to a control-plane frame
+ */
+cpp_frame_t rfc2544_classify_control_reply(const uint8_t *data, uint32_t len, uint32_t dut_ip)
+{
+	if (!data || len < sizeof(eth_header_t) + sizeof(arp_header_t))
+		return CPP_FRAME_NONE;
+
+	const eth_header_t *eth = (const eth_header_t *)data;
+	uint16_t ethertype = ntohs(eth->ethertype);
+
+	if (ethertype == ETHERTYPE_ARP) {
+		const arp_header_t *arp = (const arp_header_t *)(data + sizeof(eth_header_t));
+		if (ntohs(arp->op) == ARP_OP_REPLY && arp->spa == dut_ip)
+			return CPP_FRAME_ARP;
+		return CPP_FRAME_NONE;
+	}
+
+	if (ethertype != ETH_P_IP || len < sizeof(eth_header_t) + sizeof(ip_header_t) + 8)
+		return CPP_FRAME_NONE;
+
+	const ip_header_t *ip = (const ip_header_t *)(data + sizeof(eth_header_t));
+	if (ip->src_ip != dut_ip)
+		return CPP_FRAME_NONE;
+
+	const uint8_t *l4 = (const uint8_t *)ip + (ip->version_ihl & 0x0f) * 4;
+	if (l4 + 8 > data + len)
+		return CPP_FRAME_NONE;
+
+	if (ip->protocol == IPPROTO_ICMP && l4[0] == ICMP_ECHO_REPLY)
+		return CPP_FRAME_ICMP;
+
+	if (ip->protocol == IPPROTO_TCP && l4 + sizeof(tcp_header_t) <= data + len) {
+		const tcp_header_t *tcp = (const tcp_header_t *)l4;
+		bool syn_ack = (tcp->flags & (TCP_FLAG_SYN | TCP_FLAG_ACK)) == (TCP_FLAG_SYN | TCP_FLAG_ACK);
+		if (ntohs(tcp->src_port) == CPP_BGP_PORT && (syn_ack || (tcp->flags & TCP_FLAG_RST)))
+			return CPP_FRAME_BGP;
+	}
+
+	return CPP_FRAME_NONE;
+}
+
+/**
+ * Rewrite the source MAC and IPv4 addresses of a packet template
+ *
+ * Used to rotate through distinct address pairs (RFC 2544 Section 12)
+ * without rebuilding the template. The IP checksum is recomputed.
+ *
+ * @param buffer Packet buffer (from create_packet_template)
+ * @param src_mac Source MAC address
+ * @param src_ip Source IP (network order)
+ * @param dst_ip Destination IP (network order)
+ */
+void rfc2544_set_packet_addresses(uint8_t *buffer, const uint8_t *src_mac,
+                                  uint32_t src_ip, uint32_t dst_ip)
+{
+	if (!buffer)
+		return;
+
+	eth_header_t *eth = (eth_header_t *)buffer;
+	memcpy(eth->src_mac, src_mac, 6);
+
+	ip_header_t *ip = (ip_header_t *)(buffer + l2_header_len(buffer, UINT32_MAX));
+	ip->src_ip = src_ip;
+	ip->dst_ip = dst_ip;
+	ip->checksum = 0;
+	ip->checksum = ip_checksum(ip, sizeof(ip_header_t));
+}
+
+/**
+ * Address a frame as one flow of multi-stream traffic
+ *
+ * Stream i uses the base source MAC + i, source and destination IPs + i
+ * and UDP source port + i, and carries i as its payload stream ID.
+ *
+ * @param buffer Packet buffer (from create_packet_template)
+ * @param payload Pointer to its payload
+ * @param src_mac Base source MAC
+ * @param src_ip Base source IP (network order)
+ * @param dst_ip Base destination IP (network order)
+ * @param src_port Base UDP source port (host order)
+ * @param stream Stream index
+ */
+void rfc2544_set_stream(uint8_t *buffer, rfc2544_payload_t *payload, const uint8_t *src_mac,
+                        uint32_t src_ip, uint32_t dst_ip, uint16_t src_port, uint32_t stream)
+{
+	if (!buffer || !payload)
+		return;
+
+	uint8_t mac[6];
+	memcpy(mac, src_mac, 6);
+	uint16_t mac_lo = (uint16_t)(((src_mac[4] << 8) | src_mac[5]) + stream);
+	mac[4] = mac_lo >> 8;
+	mac[5] = mac_lo & 0xff;
+	rfc2544_set_packet_addresses(buffer, mac, htonl(ntohl(src_ip) + stream),
+	                             htonl(ntohl(dst_ip) + stream));
+
+	udp_header_t *udp = (udp_header_t *)((uint8_t *)payload - sizeof(udp_header_t));
+	udp->src_port = htons((uint16_t)(src_port + stream));
+	payload->stream_id = htonl(stream);
+}
+
+/**
+ * Address a frame as the n-th of configured header ranges
+ *
+ * Each field with a count over 1 takes its first value + n % count: the
+ * source and destination IPv4 addresses (IPv6 frames keep theirs) and
+ * UDP ports. Works behind any built-in framing, labels and tags.
+ *
+ * @param buffer Packet buffer of a built-in frame
+ * @param len Frame length
+ * @param fields Header fields, their first values resolved (nonzero)
+ * @param n Frame number
+ */
+void rfc2544_set_header_range(uint8_t *buffer, uint32_t len, const header_fields_t *fields,
+                              uint64_t n)
+{
+	if (!buffer || !fields)
+		return;
+
+	uint32_t l2 = l2_header_len(buffer, len);
+	uint8_t *l3 = buffer + l2;
+	int version = rfc2544_ip_version(buffer, len);
+	if (!version)
+		return;
+	if (version == 4 && (fields->src_ip_count > 1 || fields->dst_ip_count > 1)) {
+		ip_header_t *ip = (ip_header_t *)l3;
+		if (fields->src_ip_count > 1)
+			ip->src_ip = htonl(ntohl(fields->src_ip) + (uint32_t)(n % fields->src_ip_count));
+		if (fields->dst_ip_count > 1)
+			ip->dst_ip = htonl(ntohl(fields->dst_ip) + (uint32_t)(n % fields->dst_ip_count));
+		ip->checksum = 0;
+		ip->checksum = ip_checksum(ip, sizeof(ip_header_t));
+	}
+
+	udp_header_t *udp = (udp_header_t *)(l3 + ip_header_len(l3));
+	if (fields->src_port_count > 1)
+		udp->src_port = htons((uint16_t)(fields->src_port + n % fields->src_port_count));
+	if (fields->dst_port_count > 1)
+		udp->dst_port = htons((uint16_t)(fields->dst_port + n % fields->dst_port_count));
+}
+
 /**
  * Check if packet is a valid RFC2544 response
  *
@@ -226,9 +1151,10 @@ bool rfc2544_is_valid_response(const uint8_t *data, uint32_t len)
 	}
 
 	/* Skip to payload */
-	const rfc2544_payload_t *payload =
-	    (const rfc2544_payload_t *)(data + sizeof(eth_header_t) + sizeof(ip_header_t) +
-	                                sizeof(udp_header_t));
+	uint32_t offset = payload_offset(data, len);
+	if (len < offset + sizeof(rfc2544_payload_t))
+		return false;
+	const rfc2544_payload_t *payload = (const rfc2544_payload_t *)(data + offset);
 
 	/* Check signature */
 	if (memcmp(payload->signature, RFC2544_SIGNATURE, RFC2544_SIG_LEN) != 0) {
@@ -252,8 +1178,7 @@ uint32_t rfc2544_get_seq_num(const uint8_t *data, uint32_t len)
 	}
 
 	const rfc2544_payload_t *payload =
-	    (const rfc2544_payload_t *)(data + sizeof(eth_header_t) + sizeof(ip_header_t) +
-	                                sizeof(udp_header_t));
+	    (const rfc2544_payload_t *)(data + payload_offset(data, len));
 
 	return ntohl(payload->seq_num);
 }
@@ -272,14 +1197,76 @@ uint64_t rfc2544_get_tx_timestamp(const uint8_t *data, uint32_t len)
 	}
 
 	const rfc2544_payload_t *payload =
-	    (const rfc2544_payload_t *)(data + sizeof(eth_header_t) + sizeof(ip_header_t) +
-	                                sizeof(udp_header_t));
+	    (const rfc2544_payload_t *)(data + payload_offset(data, len));
 
 	/* Convert from network byte order */
 	uint64_t ts_be = payload->timestamp;
 	return ((uint64_t)ntohl(ts_be & 0xFFFFFFFF) << 32) | ntohl(ts_be >> 32);
 }
 
+/**
+ * Parse a received RFC2544 test frame
+ *
+ * @param data Packet data
+ * @param len Packet length
+ * @param offset Payload offset, or 0 to locate it after the Ethernet or
+ *               LLC/SNAP header and IPv4/UDP headers
+ * @param seq_num Output sequence number
+ * @param tx_timestamp Output TX timestamp in nanoseconds
+ * @return true if the frame is an RFC2544 test frame
+ */
+bool rfc2544_parse_response(const uint8_t *data, uint32_t len, uint32_t offset,
+                            uint32_t *seq_num, uint64_t *tx_timestamp)
+{
+	if (offset == 0) {
+		if (!rfc2544_is_valid_response(data, len))
+			return false;
+		*seq_num = rfc2544_get_seq_num(data, len);
+		*tx_timestamp = rfc2544_get_tx_timestamp(data, len);
+		return true;
+	}
+
+	if (!data || len < offset + sizeof(rfc2544_payload_t))
+		return false;
+
+	const rfc2544_payload_t *payload = (const rfc2544_payload_t *)(data + offset);
+	if (memcmp(payload->signature, RFC2544_SIGNATURE, RFC2544_SIG_LEN) != 0)
+		return false;
+
+	*seq_num = ntohl(payload->seq_num);
+	uint64_t ts_be = payload->timestamp;
+	*tx_timestamp = ((uint64_t)ntohl(ts_be & 0xFFFFFFFF) << 32) | ntohl(ts_be >> 32);
+	return true;
+}
+
+/**
+ * Extract the stream ID of a received RFC2544 test frame
+ *
+ * @param data Packet data
+ * @param len Packet length
+ * @param offset Payload offset, or 0 to locate it as rfc2544_parse_response does
+ * @param stream_id Output stream ID
+ * @return true if the frame is an RFC2544 test frame
+ */
+bool rfc2544_parse_stream_id(const uint8_t *data, uint32_t len, uint32_t offset,
+                             uint32_t *stream_id)
+{
+	if (offset == 0) {
+		if (!rfc2544_is_valid_response(data, len))
+			return false;
+		offset = payload_offset(data, len);
+	}
+	if (!data || len < offset + sizeof(rfc2544_payload_t))
+		return false;
+
+	const rfc2544_payload_t *payload = (const rfc2544_payload_t *)(data + offset);
+	if (memcmp(payload->signature, RFC2544_SIGNATURE, RFC2544_SIG_LEN) != 0)
+		return false;
+
+	*stream_id = ntohl(payload->stream_id);
+	return true;
+}
+
 /**
  * Calculate round-trip latency
  *
@@ -307,9 +1294,51 @@ struct seq_tracker {
 	uint32_t received;
 	uint32_t duplicates;
 	uint32_t out_of_order;
+	uint32_t highest_seq;       /* Highest sequence number received */
+	bool any;                   /* highest_seq is set */
+	uint64_t reordered;
+	uint32_t max_reorder_depth;
+	size_t words;               /* Bitmap words allocated */
+	size_t used;                /* Words the trial touched */
 };
 typedef struct seq_tracker seq_tracker_t;
 
+/**
+ * Reset a sequence tracker for a new trial, reusing its bitmap when it
+ * holds capacity sequences. Only the words the last trial touched are
+ * cleared, so a bitmap sized for a line-rate trial costs nothing to reuse.
+ *
+ * @param tracker Sequence tracker
+ * @param capacity Maximum number of sequences to track
+ * @return 0 on success, -ENOMEM if a larger bitmap cannot be allocated
+ */
+int rfc2544_seq_tracker_reset(seq_tracker_t *tracker, uint32_t capacity)
+{
+	if (!tracker)
+		return -EINVAL;
+
+	/* 64 sequences per uint64_t */
+	size_t words = ((size_t)capacity + 63) / 64;
+	if (words > tracker->words) {
+		uint64_t *bitmap = calloc(words, sizeof(uint64_t));
+		if (!bitmap)
+			return -ENOMEM;
+		free(tracker->bitmap);
+		tracker->bitmap = bitmap;
+		tracker->words = words;
+	} else if (tracker->used) {
+		memset(tracker->bitmap, 0, tracker->used * sizeof(uint64_t));
+	}
+
+	uint64_t *bitmap = tracker->bitmap;
+	size_t allocated = tracker->words;
+	memset(tracker, 0, sizeof(*tracker));
+	tracker->bitmap = bitmap;
+	tracker->words = allocated;
+	tracker->capacity = capacity;
+	return 0;
+}
+
 /**
  * Create sequence tracker
  *
@@ -322,20 +1351,10 @@ seq_tracker_t *rfc2544_seq_tracker_create(uint32_t capacity)
 	if (!tracker)
 		return NULL;
 
-	/* Allocate bitmap (64 sequences per uint64_t) */
-	size_t bitmap_size = (capacity + 63) / 64;
-	tracker->bitmap = calloc(bitmap_size, sizeof(uint64_t));
-	if (!tracker->bitmap) {
+	if (rfc2544_seq_tracker_reset(tracker, capacity) < 0) {
 		free(tracker);
 		return NULL;
 	}
-
-	tracker->capacity = capacity;
-	tracker->base_seq = 0;
-	tracker->received = 0;
-	tracker->duplicates = 0;
-	tracker->out_of_order = 0;
-
 	return tracker;
 }
 
@@ -361,12 +1380,25 @@ void rfc2544_seq_tracker_record(seq_tracker_t *tracker, uint32_t seq_num)
 	uint32_t bit = offset % 64;
 	uint64_t mask = 1ULL << bit;
 
+	if (word >= tracker->used)
+		tracker->used = word + 1;
 	if (tracker->bitmap[word] & mask) {
 		/* Already received - duplicate */
 		tracker->duplicates++;
+		return;
+	}
+	tracker->bitmap[word] |= mask;
+	tracker->received++;
+
+	/* A frame sent before one already received arrived late */
+	uint32_t highest = tracker->highest_seq - tracker->base_seq;
+	if (tracker->any && offset < highest) {
+		tracker->reordered++;
+		if (highest - offset > tracker->max_reorder_depth)
+			tracker->max_reorder_depth = highest - offset;
 	} else {
-		tracker->bitmap[word] |= mask;
-		tracker->received++;
+		tracker->highest_seq = seq_num;
+		tracker->any = true;
 	}
 }
 
@@ -393,6 +1425,22 @@ void rfc2544_seq_tracker_stats(const seq_tracker_t *tracker, uint32_t expected,
 		*loss_pct = 100.0 * (expected - tracker->received) / expected;
 }
 
+/**
+ * Get frame order statistics
+ *
+ * @param tracker Sequence tracker
+ * @param order Output: reordered and duplicate frames
+ */
+void rfc2544_seq_tracker_order(const seq_tracker_t *tracker, frame_order_t *order)
+{
+	if (!tracker || !order)
+		return;
+
+	order->reordered = tracker->reordered;
+	order->duplicates = tracker->duplicates;
+	order->max_reorder_depth = tracker->max_reorder_depth;
+}
+
 /**
  * Destroy sequence tracker
  */
@@ -592,9 +1640,10 @@ bool y1564_is_valid_response(const uint8_t *data, uint32_t len)
 	}
 
 	/* Skip to payload */
-	const y1564_payload_t *payload =
-	    (const y1564_payload_t *)(data + sizeof(eth_header_t) + sizeof(ip_header_t) +
-	                              sizeof(udp_header_t));
+	uint32_t offset = payload_offset(data, len);
+	if (len < offset + sizeof(y1564_payload_t))
+		return false;
+	const y1564_payload_t *payload = (const y1564_payload_t *)(data + offset);
 
 	/* Check signature */
 	if (memcmp(payload->signature, Y1564_SIGNATURE, Y1564_SIG_LEN) != 0) {
@@ -618,8 +1667,7 @@ uint32_t y1564_get_seq_num(const uint8_t *data, uint32_t len)
 	}
 
 	const y1564_payload_t *payload =
-	    (const y1564_payload_t *)(data + sizeof(eth_header_t) + sizeof(ip_header_t) +
-	                              sizeof(udp_header_t));
+	    (const y1564_payload_t *)(data + payload_offset(data, len));
 
 	return ntohl(payload->seq_num);
 }
@@ -638,8 +1686,7 @@ uint64_t y1564_get_tx_timestamp(const uint8_t *data, uint32_t len)
 	}
 
 	const y1564_payload_t *payload =
-	    (const y1564_payload_t *)(data + sizeof(eth_header_t) + sizeof(ip_header_t) +
-	                              sizeof(udp_header_t));
+	    (const y1564_payload_t *)(data + payload_offset(data, len));
 
 	/* Convert from network byte order */
 	uint64_t ts_be = payload->timestamp;
@@ -660,8 +1707,7 @@ uint32_t y1564_get_service_id(const uint8_t *data, uint32_t len)
 	}
 
 	const y1564_payload_t *payload =
-	    (const y1564_payload_t *)(data + sizeof(eth_header_t) + sizeof(ip_header_t) +
-	                              sizeof(udp_header_t));
+	    (const y1564_payload_t *)(data + payload_offset(data, len));
 
 	return ntohl(payload->service_id);
 }
@@ -45,11 +45,82 @@ void rfc2889_default_config(rfc2889_config_t *config)
 	config->acceptable_loss_pct = 0.0;
 }
 
+/**
+ * Plan flows for a traffic pattern
+ *
+ * The ingress load scale keeps the busiest egress port at the searched rate:
+ * many-to-one divides the load across N-1 sources, partial mesh balances
+ * unequal groups, the remaining patterns never oversubscribe an egress port.
+ */
+int rfc2889_pattern_plan(traffic_pattern_t pattern, uint32_t port_count,
+                         rfc2889_pattern_plan_t *plan)
+{
+	if (!plan || port_count < 2)
+		return -EINVAL;
+
+	memset(plan, 0, sizeof(*plan));
+	plan->ingress_load_scale = 1.0;
+
+	switch (pattern) {
+	case TRAFFIC_FULLY_MESHED:
+		/* Every port sends to every other port, load split N-1 ways */
+		plan->flow_count = port_count * (port_count - 1);
+		plan->ingress_ports = port_count;
+		plan->egress_ports = port_count;
+		break;
+
+	case TRAFFIC_PARTIALLY_MESHED: {
+		/* Two groups, each port sends to every port in the other group */
+		uint32_t group_a = port_count / 2;
+		uint32_t group_b = port_count - group_a;
+		plan->flow_count = 2 * group_a * group_b;
+		plan->ingress_ports = port_count;
+		plan->egress_ports = port_count;
+		plan->ingress_load_scale = (double)group_a / group_b;
+		break;
+	}
+
+	case TRAFFIC_PAIR_WISE:
+		/* Port 2k <-> port 2k+1 */
+		if (port_count % 2 != 0)
+			return -EINVAL;
+		plan->flow_count = port_count;
+		plan->ingress_ports = port_count;
+		plan->egress_ports = port_count;
+		break;
+
+	case TRAFFIC_ONE_TO_MANY:
+		plan->flow_count = port_count - 1;
+		plan->ingress_ports = 1;
+		plan->egress_ports = port_count - 1;
+		break;
+
+	case TRAFFIC_MANY_TO_ONE:
+		plan->flow_count = port_count - 1;
+		plan->ingress_ports = port_count - 1;
+		plan->egress_ports = 1;
+		plan->ingress_load_scale = 1.0 / (port_count - 1);
+		break;
+
+	default:
+		return -EINVAL;
+	}
+
+	return 0;
+}
+
+bool rfc2889_pattern_measurable(const rfc2889_pattern_plan_t *plan)
+{
+	return plan && plan->flow_count == 1 && plan->ingress_ports == 1 &&
+	       plan->egress_ports == 1;
+}
+
 /* ============================================================================
  * Forwarding Rate Test (Section 5.1)
  *
  * Determines the maximum rate at which the DUT can forward frames
- * without loss for each frame size.
+ * without loss for each frame size. Patterns needing more flows than the
+ * opened ports carry are refused, not extrapolated from one port.
  * ============================================================================ */
 
 int rfc2889_forwarding_test(rfc2544_ctx_t *ctx, const rfc2889_config_t *config,
@@ -65,8 +136,27 @@ int rfc2889_forwarding_test(rfc2544_ctx_t *ctx, const rfc2889_config_t *config,
 	result->port_count = config->port_count;
 	result->pattern = config->pattern;
 
+	rfc2889_pattern_plan_t plan;
+	int plan_ret = rfc2889_pattern_plan(config->pattern, config->port_count, &plan);
+	if (plan_ret < 0) {
+		rfc2544_log(LOG_ERROR, "Invalid traffic pattern %d for %u ports",
+		            config->pattern, config->port_count);
+		return plan_ret;
+	}
+	if (!rfc2889_pattern_measurable(&plan)) {
+		rfc2544_log(LOG_ERROR, "Traffic pattern %d over %u ports needs %u flows from %u ingress "
+		            "to %u egress ports; one flow between the open ports is measured",
+		            config->pattern, config->port_count, plan.flow_count,
+		            plan.ingress_ports, plan.egress_ports);
+		return -ENOTSUP;
+	}
+	result->flow_count = plan.flow_count;
+	result->ingress_ports = plan.ingress_ports;
+	result->egress_ports = plan.egress_ports;
+
 	rfc2544_log(LOG_INFO, "=== RFC 2889 Forwarding Rate Test ===");
 	rfc2544_log(LOG_INFO, "Frame size: %u bytes, Ports: %u", frame_size, config->port_count);
+	rfc2544_log(LOG_INFO, "Pattern: %d, one flow", config->pattern);
 
 	/* Binary search for maximum forwarding rate with 0% loss */
 	double low = 0.0;
@@ -113,9 +203,9 @@ int rfc2889_forwarding_test(rfc2544_ctx_t *ctx, const rfc2889_config_t *config,
 		iterations++;
 	}
 
-	/* Calculate results */
+	/* Calculate results, all of the one measured flow */
 	result->max_rate_pct = best_rate;
-	result->max_rate_fps = max_pps * best_rate / 100.0;
+	result->max_rate_fps = max_pps * result->max_rate_pct / 100.0;
 	result->aggregate_rate_mbps = (result->max_rate_fps * (frame_size + 20) * 8) / 1e6;
 	/* Guard against underflow when rx > tx */
 	if (result->frames_tx > 0 && result->frames_rx < result->frames_tx) {
@@ -62,10 +62,17 @@ y1564_payload_t *y1564_create_packet_template(uint8_t *buffer, uint32_t frame_si
                                                uint16_t src_port, uint16_t dst_port,
                                                uint32_t service_id, uint8_t dscp);
 void y1564_stamp_packet(y1564_payload_t *payload, uint32_t seq_num, uint64_t timestamp_ns);
+void rfc2544_set_header_range(uint8_t *buffer, uint32_t len, const header_fields_t *fields,
+                              uint64_t n);
 bool y1564_is_valid_response(const uint8_t *data, uint32_t len);
 uint32_t y1564_get_seq_num(const uint8_t *data, uint32_t len);
 uint64_t y1564_get_tx_timestamp(const uint8_t *data, uint32_t len);
 uint32_t y1564_get_service_id(const uint8_t *data, uint32_t len);
+int rfc2544_insert_tags(uint8_t *buffer, uint32_t frame_size, const qinq_config_t *qinq,
+                        uint16_t vlan_id, uint8_t pcp);
+int rfc2544_insert_mpls(uint8_t *buffer, uint32_t frame_size, const mpls_label_t *labels,
+                        uint32_t count);
+int rfc2544_convert_ipv6(uint8_t *buffer, uint32_t frame_size, const ipv6_config_t *config);
 
 /* Forward declarations from pacing.c */
 typedef struct pacing_ctx pacing_ctx_t;
@@ -74,6 +81,7 @@ typedef struct seq_tracker seq_tracker_t;
 
 pacing_ctx_t *pacing_create(uint64_t line_rate_bps, uint32_t frame_size, double rate_pct);
 void pacing_set_rate(pacing_ctx_t *ctx, double rate_pct);
+void pacing_apply_profile(pacing_ctx_t *ctx, const speed_profile_t *profile);
 uint64_t pacing_wait(pacing_ctx_t *ctx);
 void pacing_record_tx(pacing_ctx_t *ctx, uint32_t packets, uint32_t bytes);
 void pacing_reset(pacing_ctx_t *ctx);
@@ -98,6 +106,15 @@ extern worker_ctx_t *rfc2544_get_worker(rfc2544_ctx_t *ctx, int index);
 extern uint64_t rfc2544_get_line_rate_ctx(const rfc2544_ctx_t *ctx);
 extern void rfc2544_get_macs(const rfc2544_ctx_t *ctx, uint8_t *src_mac, uint8_t *dst_mac);
 extern void rfc2544_get_ips(const rfc2544_ctx_t *ctx, uint32_t *src_ip, uint32_t *dst_ip);
+extern void rfc2544_get_ports(const rfc2544_ctx_t *ctx, uint16_t *src_port, uint16_t *dst_port);
+extern const header_fields_t *rfc2544_get_headers(const rfc2544_ctx_t *ctx);
+extern void rfc2544_get_vlan(const rfc2544_ctx_t *ctx, uint16_t *vlan_id, uint8_t *pcp);
+extern const qinq_config_t *rfc2544_get_qinq(const rfc2544_ctx_t *ctx);
+extern const mpls_config_t *rfc2544_get_mpls(const rfc2544_ctx_t *ctx);
+extern const ip_stack_config_t *rfc2544_get_ip(const rfc2544_ctx_t *ctx);
+extern const payload_pattern_t *rfc2544_get_payload_pattern(const rfc2544_ctx_t *ctx);
+extern capture_file_t *rfc2544_get_capture(rfc2544_ctx_t *ctx, bool rx);
+extern const speed_profile_t *rfc2544_get_speed_profile(const rfc2544_ctx_t *ctx);
 extern bool rfc2544_is_cancelled(const rfc2544_ctx_t *ctx);
 
 /* Logging (from core.c) */
@@ -212,6 +229,12 @@ void y1564_default_sla(y1564_sla_t *sla)
 	sla->fd_threshold_ms = 10.0;     /* 10ms frame delay threshold */
 	sla->fdv_threshold_ms = 5.0;     /* 5ms jitter threshold */
 	sla->flr_threshold_pct = 0.01;   /* 0.01% frame loss threshold */
+
+	/* Above CIR frames may be dropped: only delay is checked by default */
+	sla->eir_sac.fd_threshold_ms = sla->fd_threshold_ms;
+	sla->eir_sac.fdv_threshold_ms = sla->fdv_threshold_ms;
+	sla->eir_sac.flr_threshold_pct = -1.0;
+	sla->policing_sac = sla->eir_sac;
 }
 
 void y1564_default_config(y1564_config_t *config)
@@ -288,9 +311,15 @@ static int y1564_run_step(rfc2544_ctx_t *ctx, const y1564_service_t *service, do
 
 	memset(result, 0, sizeof(*result));
 
-	/* Get platform and worker */
+	/* Get platform and workers: TX port, and RX port for a port pair */
+	int ret = rfc2544_open_ports(ctx);
+	if (ret < 0)
+		return ret;
 	const platform_ops_t *platform = rfc2544_get_platform(ctx);
 	worker_ctx_t *wctx = rfc2544_get_worker(ctx, 0);
+	worker_ctx_t *rx_wctx = rfc2544_rx_worker(ctx);
+	capture_file_t *capture_tx = rfc2544_get_capture(ctx, false);
+	capture_file_t *capture_rx = rfc2544_get_capture(ctx, true);
 	if (!platform || !wctx)
 		return -EINVAL;
 
@@ -313,15 +342,78 @@ static int y1564_run_step(rfc2544_ctx_t *ctx, const y1564_service_t *service, do
 	uint32_t src_ip, dst_ip;
 	rfc2544_get_macs(ctx, src_mac, dst_mac);
 	rfc2544_get_ips(ctx, &src_ip, &dst_ip);
+	uint16_t src_port, dst_port;
+	rfc2544_get_ports(ctx, &src_port, &dst_port);
+
+	/* Header ranges cycle the addresses and ports frame by frame */
+	header_fields_t range = *rfc2544_get_headers(ctx);
+	range.src_ip = src_ip;
+	range.dst_ip = dst_ip;
+	range.src_port = src_port;
+	range.dst_port = dst_port;
+	bool ranged = range.src_ip_count > 1 || range.dst_ip_count > 1 ||
+	              range.src_port_count > 1 || range.dst_port_count > 1;
+	uint64_t range_slot = 0;
 
 	/* Create Y.1564 packet with service DSCP marking */
 	y1564_payload_t *payload = y1564_create_packet_template(pkt_buffer, frame_size, src_mac,
-	                                                        dst_mac, src_ip, dst_ip, 12345, 3842,
-	                                                        service->service_id, service->cos);
+	                                                        dst_mac, src_ip, dst_ip, src_port,
+	                                                        dst_port, service->service_id,
+	                                                        service->cos);
 	if (!payload) {
 		free(pkt_buffer);
 		return -EINVAL;
 	}
+	rfc2544_fill_pattern((uint8_t *)payload + sizeof(y1564_payload_t),
+	                     frame_size - (uint32_t)((uint8_t *)payload - pkt_buffer) -
+	                         sizeof(y1564_payload_t),
+	                     rfc2544_get_payload_pattern(ctx));
+
+	/* IPv6 services take the context's addresses, the CoS in the traffic class */
+	const ip_stack_config_t *ip = rfc2544_get_ip(ctx);
+	if (service->ip_version == 6 || (!service->ip_version && ip->mode == IP_MODE_V6)) {
+		ipv6_config_t v6 = ip->ipv6;
+		v6.traffic_class = (uint8_t)(service->cos << 2);
+		int shift = rfc2544_convert_ipv6(pkt_buffer, frame_size, &v6);
+		if (shift < 0) {
+			y1564_log(LOG_ERROR, "Frame size %u too small for IPv6", frame_size);
+			free(pkt_buffer);
+			return -EINVAL;
+		}
+		payload = (y1564_payload_t *)((uint8_t *)payload + shift);
+	}
+
+	/* The context's label stack, its EXP bits marked with the service CoS */
+	mpls_config_t mpls = *rfc2544_get_mpls(ctx);
+	if (mpls.count) {
+		for (uint32_t i = 0; mpls.exp_from_cos && i < mpls.count; i++)
+			mpls.labels[i].exp = service->cos >> 3;
+		int shift = rfc2544_insert_mpls(pkt_buffer, frame_size, mpls.labels, mpls.count);
+		if (shift < 0) {
+			y1564_log(LOG_ERROR, "Frame size %u too small for the MPLS label stack", frame_size);
+			free(pkt_buffer);
+			return -EINVAL;
+		}
+		payload = (y1564_payload_t *)((uint8_t *)payload + shift);
+	}
+
+	/* The service's inner and outer tags, or the context's */
+	uint16_t vlan_id = service->vlan_id;
+	uint8_t pcp = service->pcp;
+	if (!vlan_id && !pcp)
+		rfc2544_get_vlan(ctx, &vlan_id, &pcp);
+	qinq_config_t qinq = *rfc2544_get_qinq(ctx);
+	if (service->s_vlan_id || service->s_pcp) {
+		qinq.s_vlan_id = service->s_vlan_id;
+		qinq.s_pcp = service->s_pcp;
+	}
+	int shift = rfc2544_insert_tags(pkt_buffer, frame_size, &qinq, vlan_id, pcp);
+	if (shift < 0) {
+		y1564_log(LOG_ERROR, "Frame size %u too small for the VLAN tags", frame_size);
+		free(pkt_buffer);
+		return -EINVAL;
+	}
+	payload = (y1564_payload_t *)((uint8_t *)payload + shift);
 
 	/* Calculate target rate as percentage of line rate */
 	double rate_pct = (rate_mbps * 1e6 * 100.0) / line_rate;
@@ -334,6 +426,7 @@ static int y1564_run_step(rfc2544_ctx_t *ctx, const y1564_service_t *service, do
 		free(pkt_buffer);
 		return -ENOMEM;
 	}
+	pacing_apply_profile(pacer, rfc2544_get_speed_profile(ctx));
 
 	/* Create trial timer */
 	trial_timer_t *timer = trial_timer_create(duration_sec, warmup_sec);
@@ -389,10 +482,14 @@ static int y1564_run_step(rfc2544_ctx_t *ctx, const y1564_service_t *service, do
 		/* TX: Send packet at paced rate */
 		uint64_t tx_ts = pacing_wait(pacer);
 		y1564_stamp_packet(payload, seq_num, tx_ts);
+		if (ranged)
+			rfc2544_set_header_range(pkt_buffer, frame_size, &range, range_slot++);
 		tx_pkt.timestamp = tx_ts;
 		tx_pkt.seq_num = seq_num;
 
 		int sent = platform->send_batch(wctx, &tx_pkt, 1);
+		if (sent > 0)
+			rfc2544_capture_frame(capture_tx, tx_pkt.data, tx_pkt.len);
 		if (sent > 0 && in_measurement) {
 			frames_tx++;
 			seq_num++;
@@ -400,8 +497,9 @@ static int y1564_run_step(rfc2544_ctx_t *ctx, const y1564_service_t *service, do
 		}
 
 		/* RX: Check for returned packets */
-		int recv_count = platform->recv_batch(wctx, rx_pkts, 64);
+		int recv_count = platform->recv_batch(rx_wctx, rx_pkts, 64);
 		for (int i = 0; i < recv_count; i++) {
+			rfc2544_capture_frame(capture_rx, rx_pkts[i].data, rx_pkts[i].len);
 			if (y1564_is_valid_response(rx_pkts[i].data, rx_pkts[i].len)) {
 				uint32_t rx_service = y1564_get_service_id(rx_pkts[i].data, rx_pkts[i].len);
 
@@ -421,15 +519,16 @@ static int y1564_run_step(rfc2544_ctx_t *ctx, const y1564_service_t *service, do
 		}
 
 		if (recv_count > 0) {
-			platform->release_batch(wctx, rx_pkts, recv_count);
+			platform->release_batch(rx_wctx, rx_pkts, recv_count);
 		}
 	}
 
 	/* Wait for straggler packets */
 	for (int i = 0; i < 10 && !rfc2544_is_cancelled(ctx); i++) {
 		usleep(10000);
-		int recv_count = platform->recv_batch(wctx, rx_pkts, 64);
+		int recv_count = platform->recv_batch(rx_wctx, rx_pkts, 64);
 		for (int j = 0; j < recv_count; j++) {
+			rfc2544_capture_frame(capture_rx, rx_pkts[j].data, rx_pkts[j].len);
 			if (y1564_is_valid_response(rx_pkts[j].data, rx_pkts[j].len)) {
 				uint32_t rx_service = y1564_get_service_id(rx_pkts[j].data, rx_pkts[j].len);
 				if (rx_service == service->service_id) {
@@ -444,10 +543,12 @@ static int y1564_run_step(rfc2544_ctx_t *ctx, const y1564_service_t *service, do
 			}
 		}
 		if (recv_count > 0) {
-			platform->release_batch(wctx, rx_pkts, recv_count);
+			platform->release_batch(rx_wctx, rx_pkts, recv_count);
 		}
 	}
 
+	rfc2544_capture_flush(ctx);
+
 	/* Calculate results */
 	double elapsed = trial_timer_elapsed(timer);
 	result->frames_tx = frames_tx;
@@ -484,6 +585,27 @@ static int y1564_run_step(rfc2544_ctx_t *ctx, const y1564_service_t *service, do
  * Service Configuration Test
  * ============================================================================ */
 
+static const char *y1564_phase_name(y1564_step_phase_t phase)
+{
+	switch (phase) {
+	case Y1564_STEP_EIR:
+		return "eir";
+	case Y1564_STEP_POLICING:
+		return "policing";
+	default:
+		return "cir";
+	}
+}
+
+/* Judge a step against its acceptance criteria; negative thresholds are not checked */
+static void y1564_judge_step(y1564_step_result_t *sr, const y1564_sac_t *sac)
+{
+	sr->flr_pass = sac->flr_threshold_pct < 0 || sr->flr_pct <= sac->flr_threshold_pct;
+	sr->fd_pass = sac->fd_threshold_ms < 0 || sr->fd_avg_ms <= sac->fd_threshold_ms;
+	sr->fdv_pass = sac->fdv_threshold_ms < 0 || sr->fdv_ms <= sac->fdv_threshold_ms;
+	sr->step_pass = sr->flr_pass && sr->fd_pass && sr->fdv_pass;
+}
+
 int y1564_config_test(rfc2544_ctx_t *ctx, const y1564_service_t *service,
                       y1564_config_result_t *result)
 {
@@ -498,24 +620,59 @@ int y1564_config_test(rfc2544_ctx_t *ctx, const y1564_service_t *service,
 	uint32_t step_duration = y1564_cfg->step_duration_sec;
 	uint32_t warmup_sec = 2;  /* 2 second warmup per step */
 
+	const y1564_sla_t *sla = &service->sla;
+	if (sla->cir_mbps <= 0)
+		return -EINVAL;
+
 	y1564_log(LOG_INFO, "Service Configuration Test: service=%u (%s), CIR=%.2f Mbps",
-	          service->service_id, service->service_name, service->sla.cir_mbps);
+	          service->service_id, service->service_name, sla->cir_mbps);
+
+	/*
+	 * The CIR steps are judged against the SLA; the CIR + EIR step (when an
+	 * EIR is set) and the policing step at 125% of CIR + EIR have their own
+	 * acceptance criteria, as loss above CIR may be allowed
+	 */
+	struct {
+		double pct;
+		y1564_step_phase_t phase;
+		y1564_sac_t sac;
+	} plan[Y1564_MAX_STEPS];
+	uint32_t step_count = 0;
+	y1564_sac_t cir_sac = {sla->fd_threshold_ms, sla->fdv_threshold_ms, sla->flr_threshold_pct};
+	for (int i = 0; i < Y1564_CONFIG_STEPS; i++) {
+		plan[step_count].pct = y1564_cfg->config_steps[i];
+		plan[step_count].phase = Y1564_STEP_CIR;
+		plan[step_count++].sac = cir_sac;
+	}
+	double excess_pct = 100.0 * (sla->cir_mbps + sla->eir_mbps) / sla->cir_mbps;
+	if (sla->eir_mbps > 0) {
+		plan[step_count].pct = excess_pct;
+		plan[step_count].phase = Y1564_STEP_EIR;
+		plan[step_count++].sac = sla->eir_sac;
+	}
+	if (service->policing_step) {
+		plan[step_count].pct = excess_pct * 1.25;
+		plan[step_count].phase = Y1564_STEP_POLICING;
+		plan[step_count++].sac = sla->policing_sac;
+	}
 
 	bool all_steps_pass = true;
 
 	/* Run each step */
-	for (int step = 0; step < Y1564_CONFIG_STEPS; step++) {
-		double step_pct = y1564_cfg->config_steps[step];
-		double step_rate = service->sla.cir_mbps * step_pct / 100.0;
+	for (uint32_t step = 0; step < step_count; step++) {
+		double step_pct = plan[step].pct;
+		double step_rate = sla->cir_mbps * step_pct / 100.0;
 
-		y1564_log(LOG_INFO, "  Step %d: %.0f%% CIR (%.2f Mbps)", step + 1, step_pct, step_rate);
+		y1564_log(LOG_INFO, "  Step %u (%s): %.0f%% CIR (%.2f Mbps, +%.2f Mbps extra)", step + 1,
+		          y1564_phase_name(plan[step].phase), step_pct, step_rate, service->extra_mbps);
 
-		/* Run the step trial */
+		/* Run the step trial, carrying any load taken over from failed services */
 		y1564_trial_t trial;
-		int ret = y1564_run_step(ctx, service, step_rate, step_duration, warmup_sec, &trial);
+		int ret = y1564_run_step(ctx, service, step_rate + service->extra_mbps, step_duration,
+		                         warmup_sec, &trial);
 
 		if (ret < 0) {
-			y1564_log(LOG_ERROR, "Step %d failed: %d", step + 1, ret);
+			y1564_log(LOG_ERROR, "Step %u failed: %d", step + 1, ret);
 			return ret;
 		}
 
@@ -527,6 +684,7 @@ int y1564_config_test(rfc2544_ctx_t *ctx, const y1564_service_t *service,
 		/* Store step result */
 		y1564_step_result_t *sr = &result->steps[step];
 		sr->step = step + 1;
+		sr->phase = plan[step].phase;
 		sr->offered_rate_pct = step_pct;
 		sr->achieved_rate_mbps = trial.achieved_mbps;
 		sr->frames_tx = trial.frames_tx;
@@ -537,11 +695,10 @@ int y1564_config_test(rfc2544_ctx_t *ctx, const y1564_service_t *service,
 		sr->fd_max_ms = trial.fd_max_ms;
 		sr->fdv_ms = trial.fdv_ms;
 
-		/* Evaluate pass/fail against SLA thresholds */
-		sr->flr_pass = (trial.flr_pct <= service->sla.flr_threshold_pct);
-		sr->fd_pass = (trial.fd_avg_ms <= service->sla.fd_threshold_ms);
-		sr->fdv_pass = (trial.fdv_ms <= service->sla.fdv_threshold_ms);
-		sr->step_pass = sr->flr_pass && sr->fd_pass && sr->fdv_pass;
+		result->step_count = step + 1;
+
+		/* Evaluate pass/fail against the step's acceptance criteria */
+		y1564_judge_step(sr, &plan[step].sac);
 
 		if (!sr->step_pass) {
 			all_steps_pass = false;
@@ -578,13 +735,16 @@ int y1564_perf_test(rfc2544_ctx_t *ctx, const y1564_service_t *service, uint32_t
 
 	uint32_t warmup_sec = 5;  /* 5 second warmup for performance test */
 
-	y1564_log(LOG_INFO, "Service Performance Test: service=%u (%s), CIR=%.2f Mbps, duration=%um",
+	y1564_log(LOG_INFO,
+	          "Service Performance Test: service=%u (%s), CIR=%.2f Mbps (+%.2f Mbps extra), "
+	          "duration=%um",
 	          service->service_id, service->service_name, service->sla.cir_mbps,
-	          duration_sec / 60);
+	          service->extra_mbps, duration_sec / 60);
 
-	/* Run performance trial at full CIR */
+	/* Run performance trial at full CIR, plus any load taken over from failed services */
 	y1564_trial_t trial;
-	int ret = y1564_run_step(ctx, service, service->sla.cir_mbps, duration_sec, warmup_sec, &trial);
+	int ret = y1564_run_step(ctx, service, service->sla.cir_mbps + service->extra_mbps,
+	                         duration_sec, warmup_sec, &trial);
 
 	if (ret < 0) {
 		y1564_log(LOG_ERROR, "Performance test failed: %d", ret);
@@ -718,15 +878,15 @@ void y1564_print_results(const y1564_config_result_t *config_results,
 				if (s > 0) printf(",");
 				printf("{\"service_id\":%u,\"service_pass\":%s,\"steps\":[",
 				       cr->service_id, cr->service_pass ? "true" : "false");
-				for (int i = 0; i < Y1564_CONFIG_STEPS; i++) {
+				for (uint32_t i = 0; i < cr->step_count; i++) {
 					const y1564_step_result_t *sr = &cr->steps[i];
 					if (i > 0) printf(",");
-					printf("{\"step\":%u,\"offered_rate_pct\":%.1f,\"achieved_rate_mbps\":%.2f,"
+					printf("{\"step\":%u,\"phase\":\"%s\",\"offered_rate_pct\":%.1f,\"achieved_rate_mbps\":%.2f,"
 					       "\"frames_tx\":%" PRIu64 ",\"frames_rx\":%" PRIu64 ",\"flr_pct\":%.4f,"
 					       "\"fd_avg_ms\":%.2f,\"fd_min_ms\":%.2f,\"fd_max_ms\":%.2f,"
 					       "\"fdv_ms\":%.2f,\"flr_pass\":%s,\"fd_pass\":%s,\"fdv_pass\":%s,"
 					       "\"step_pass\":%s}",
-					       sr->step, sr->offered_rate_pct, sr->achieved_rate_mbps,
+					       sr->step, y1564_phase_name(sr->phase), sr->offered_rate_pct, sr->achieved_rate_mbps,
 					       sr->frames_tx, sr->frames_rx, sr->flr_pct,
 					       sr->fd_avg_ms, sr->fd_min_ms, sr->fd_max_ms, sr->fdv_ms,
 					       sr->flr_pass ? "true" : "false",
@@ -764,7 +924,7 @@ void y1564_print_results(const y1564_config_result_t *config_results,
 		if (config_results) {
 			for (uint32_t s = 0; s < service_count; s++) {
 				const y1564_config_result_t *cr = &config_results[s];
-				for (int i = 0; i < Y1564_CONFIG_STEPS; i++) {
+				for (uint32_t i = 0; i < cr->step_count; i++) {
 					const y1564_step_result_t *sr = &cr->steps[i];
 					printf("%u,config,%u,%.0f,%.2f,%.4f,%.2f,%.2f,%s\n",
 					       cr->service_id, sr->step, sr->offered_rate_pct,
@@ -808,7 +968,7 @@ void y1564_print_results(const y1564_config_result_t *config_results,
 			       "Frames TX", "FLR (%)", "FD (ms)", "FDV (ms)", "Status", "Result");
 			printf("-----------------------------------------------------------------\n");
 
-			for (int i = 0; i < Y1564_CONFIG_STEPS; i++) {
+			for (uint32_t i = 0; i < cr->step_count; i++) {
 				const y1564_step_result_t *sr = &cr->steps[i];
 				printf("%-6u %7.0f%% %12.2f %15" PRIu64 " %11.4f%% %10.2f %10.2f %10s %8s\n", sr->step,
 				       sr->offered_rate_pct, sr->achieved_rate_mbps, sr->frames_tx,
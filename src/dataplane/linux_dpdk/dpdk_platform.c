@@ -112,7 +112,7 @@ static int dpdk_init_eal(rfc2544_ctx_t *ctx)
 	int ret = rte_eal_init(argc, argv);
 	if (ret < 0) {
 		fprintf(stderr, "[dpdk] EAL init failed: %s\n", rte_strerror(rte_errno));
-		return -1;
+		return -RFC2544_EDPDKINIT;
 	}
 
 	return 0;
@@ -268,7 +268,7 @@ static int dpdk_init(rfc2544_ctx_t *ctx, worker_ctx_t *wctx)
 			rte_mempool_free(dpdk_shared.mbuf_pool);
 			pthread_mutex_unlock(&dpdk_init_lock);
 			free(pctx);
-			return ret;
+			return -RFC2544_EDPDKINIT;
 		}
 
 		dpdk_shared.initialized = true;
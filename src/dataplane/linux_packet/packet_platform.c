@@ -71,7 +71,7 @@ typedef struct {
 
 /**
  * Enable hardware timestamping on the NIC
- * Returns 0 on success, negative on error
+ * Returns 0 on success, -RFC2544_ENOHWTS if the NIC cannot stamp
  */
 static int enable_hw_timestamping(platform_ctx_t *pctx, const char *ifname)
 {
@@ -94,7 +94,7 @@ static int enable_hw_timestamping(platform_ctx_t *pctx, const char *ifname)
 		/* Hardware timestamping not supported - fall back to software */
 		fprintf(stderr, "[packet] HW timestamping not available: %s (using software timestamps)\n",
 		        strerror(errno));
-		return -1;
+		return -RFC2544_ENOHWTS;
 	}
 
 	/* Enable socket-level timestamping */
@@ -108,7 +108,7 @@ static int enable_hw_timestamping(platform_ctx_t *pctx, const char *ifname)
 	if (setsockopt(pctx->sock_fd, SOL_SOCKET, SO_TIMESTAMPING,
 	               &timestamping_flags, sizeof(timestamping_flags)) < 0) {
 		fprintf(stderr, "[packet] SO_TIMESTAMPING failed: %s\n", strerror(errno));
-		return -1;
+		return -RFC2544_ENOHWTS;
 	}
 
 	pctx->hw_timestamp_enabled = true;
@@ -172,9 +172,9 @@ static int packet_init(rfc2544_ctx_t *ctx, worker_ctx_t *wctx)
 	}
 
 	/* Get interface index */
-	pctx->if_index = if_nametoindex(ctx->config.interface);
+	pctx->if_index = if_nametoindex(wctx->interface);
 	if (pctx->if_index == 0) {
-		fprintf(stderr, "Failed to get interface index for %s\n", ctx->config.interface);
+		fprintf(stderr, "Failed to get interface index for %s\n", wctx->interface);
 		free(pctx);
 		return -ENODEV;
 	}
@@ -203,7 +203,7 @@ static int packet_init(rfc2544_ctx_t *ctx, worker_ctx_t *wctx)
 	/* Get interface MAC address */
 	struct ifreq ifr;
 	memset(&ifr, 0, sizeof(ifr));
-	strncpy(ifr.ifr_name, ctx->config.interface, IFNAMSIZ - 1);
+	strncpy(ifr.ifr_name, wctx->interface, IFNAMSIZ - 1);
 	ifr.ifr_name[IFNAMSIZ - 1] = '\0'; /* Ensure null-termination */
 	if (ioctl(pctx->sock_fd, SIOCGIFHWADDR, &ifr) < 0) {
 		perror("ioctl SIOCGIFHWADDR");
@@ -235,17 +235,18 @@ static int packet_init(rfc2544_ctx_t *ctx, worker_ctx_t *wctx)
 		return -ENOMEM;
 	}
 
-	/* Store context */
-	memcpy(ctx->local_mac, pctx->if_mac, 6);
+	/* Store context; test frames carry the TX port's MAC */
+	if (wctx->worker_id == 0)
+		memcpy(ctx->local_mac, pctx->if_mac, 6);
 	wctx->pctx = pctx;
 
 	/* Try to enable hardware timestamping if requested */
 	if (ctx->config.hw_timestamp) {
-		enable_hw_timestamping(pctx, ctx->config.interface);
+		enable_hw_timestamping(pctx, wctx->interface);
 	}
 
 	fprintf(stderr, "[packet] Initialized on %s (ifindex=%d, MAC=%02x:%02x:%02x:%02x:%02x:%02x, HW-TS=%s)\n",
-	        ctx->config.interface, pctx->if_index, pctx->if_mac[0], pctx->if_mac[1],
+	        wctx->interface, pctx->if_index, pctx->if_mac[0], pctx->if_mac[1],
 	        pctx->if_mac[2], pctx->if_mac[3], pctx->if_mac[4], pctx->if_mac[5],
 	        pctx->hw_timestamp_enabled ? "enabled" : "disabled");
 
@@ -145,10 +145,10 @@ static int xdp_init(rfc2544_ctx_t *ctx, worker_ctx_t *wctx)
 	int ret;
 
 	/* Get interface index */
-	pctx->if_index = if_nametoindex(ctx->config.interface);
+	pctx->if_index = if_nametoindex(wctx->interface);
 	if (pctx->if_index == 0) {
 		fprintf(stderr, "[xdp] Failed to get interface index for %s\n",
-		        ctx->config.interface);
+		        wctx->interface);
 		free(pctx);
 		return -ENODEV;
 	}
@@ -211,13 +211,13 @@ static int xdp_init(rfc2544_ctx_t *ctx, worker_ctx_t *wctx)
 	    .libbpf_flags = XSK_LIBBPF_FLAGS__INHIBIT_PROG_LOAD,
 	};
 
-	ret = xsk_socket__create(&pctx->xsk, ctx->config.interface, wctx->queue_id,
+	ret = xsk_socket__create(&pctx->xsk, wctx->interface, wctx->queue_id,
 	                         pctx->umem, &pctx->rx_ring, &pctx->tx_ring, &xsk_cfg);
 
 	if (ret) {
 		/* Fall back to SKB mode */
 		xsk_cfg.xdp_flags = XDP_FLAGS_SKB_MODE;
-		ret = xsk_socket__create(&pctx->xsk, ctx->config.interface, wctx->queue_id,
+		ret = xsk_socket__create(&pctx->xsk, wctx->interface, wctx->queue_id,
 		                         pctx->umem, &pctx->rx_ring, &pctx->tx_ring, &xsk_cfg);
 		if (ret) {
 			fprintf(stderr, "[xdp] Failed to create XDP socket: %s\n", strerror(-ret));
@@ -254,10 +254,11 @@ static int xdp_init(rfc2544_ctx_t *ctx, worker_ctx_t *wctx)
 	int sock = socket(AF_INET, SOCK_DGRAM, 0);
 	if (sock >= 0) {
 		struct ifreq ifr;
-		strncpy(ifr.ifr_name, ctx->config.interface, IFNAMSIZ - 1);
+		strncpy(ifr.ifr_name, wctx->interface, IFNAMSIZ - 1);
 		if (ioctl(sock, SIOCGIFHWADDR, &ifr) == 0) {
 			memcpy(pctx->if_mac, ifr.ifr_hwaddr.sa_data, 6);
-			memcpy(ctx->local_mac, pctx->if_mac, 6);
+			if (wctx->worker_id == 0)
+				memcpy(ctx->local_mac, pctx->if_mac, 6);
 		}
 		close(sock);
 	}
@@ -265,7 +266,7 @@ static int xdp_init(rfc2544_ctx_t *ctx, worker_ctx_t *wctx)
 	wctx->pctx = pctx;
 
 	fprintf(stderr, "[xdp] Initialized on %s queue %d (fd=%d)\n",
-	        ctx->config.interface, wctx->queue_id, pctx->xsk_fd);
+	        wctx->interface, wctx->queue_id, pctx->xsk_fd);
 
 	return 0;
 }
@@ -74,6 +74,51 @@ TEST(calc_max_pps_jumbo_frame)
 	ASSERT_EQ(138580, result);
 }
 
+TEST(calc_max_pps_multigig)
+{
+	/* 2.5G, 5G, 25G, 40G, 50G and 400G at 64 and 1518 bytes */
+	uint64_t rates[] = {2500000000ULL, 5000000000ULL, 25000000000ULL,
+	                    40000000000ULL, 50000000000ULL, 400000000000ULL};
+	uint64_t expected_64[] = {3720238, 7440476, 37202380, 59523809, 74404761, 595238095};
+	uint64_t expected_1518[] = {203185, 406371, 2031859, 3250975, 4063719, 32509752};
+
+	for (int i = 0; i < 6; i++) {
+		ASSERT_EQ(expected_64[i], calc_max_pps(rates[i], 64));
+		ASSERT_EQ(expected_1518[i], calc_max_pps(rates[i], 1518));
+	}
+}
+
+/* ============================================================================
+ * Speed Profile Tests
+ * ============================================================================ */
+
+TEST(speed_profile_by_line_rate)
+{
+	ASSERT_STR_EQ("1g", rfc2544_speed_profile(100000000ULL)->name);
+	ASSERT_STR_EQ("2.5g", rfc2544_speed_profile(2500000000ULL)->name);
+	ASSERT_STR_EQ("5g", rfc2544_speed_profile(5000000000ULL)->name);
+	ASSERT_STR_EQ("10g", rfc2544_speed_profile(20000000000ULL)->name);
+	ASSERT_STR_EQ("25g", rfc2544_speed_profile(25000000000ULL)->name);
+	ASSERT_STR_EQ("40g", rfc2544_speed_profile(40000000000ULL)->name);
+	ASSERT_STR_EQ("50g", rfc2544_speed_profile(50000000000ULL)->name);
+	ASSERT_STR_EQ("100g", rfc2544_speed_profile(100000000000ULL)->name);
+	ASSERT_STR_EQ("400g", rfc2544_speed_profile(800000000000ULL)->name);
+
+	/* One frame per wait up to 10G, batches above */
+	ASSERT_EQ(1, rfc2544_speed_profile(10000000000ULL)->batch_size);
+	ASSERT_GT(rfc2544_speed_profile(100000000000ULL)->batch_size, 1);
+	ASSERT_TRUE(rfc2544_speed_profile(400000000000ULL)->busy_wait);
+}
+
+TEST(speed_profile_by_name)
+{
+	const speed_profile_t *p = rfc2544_speed_profile_by_name("25g");
+	ASSERT_NOT_NULL(p);
+	ASSERT_EQ(25000000000ULL, p->line_rate);
+	ASSERT_NULL(rfc2544_speed_profile_by_name("3g"));
+	ASSERT_NULL(rfc2544_speed_profile_by_name(NULL));
+}
+
 /* ============================================================================
  * calc_utilization Tests
  * ============================================================================ */
@@ -171,6 +216,11 @@ int main(void)
 	RUN_TEST(calc_max_pps_100g_64byte);
 	RUN_TEST(calc_max_pps_zero_line_rate);
 	RUN_TEST(calc_max_pps_jumbo_frame);
+	RUN_TEST(calc_max_pps_multigig);
+
+	TEST_SUITE("Speed Profiles");
+	RUN_TEST(speed_profile_by_line_rate);
+	RUN_TEST(speed_profile_by_name);
 
 	TEST_SUITE("calc_utilization");
 	RUN_TEST(calc_utilization_100_percent);
@@ -7,7 +7,11 @@
 #include "test_framework.h"
 
 #include "../../include/rfc2544.h"
+#include "../../include/rfc2544_internal.h"
 #include <arpa/inet.h>
+#include <errno.h>
+#include <stdio.h>
+#include <stdlib.h>
 #include <string.h>
 
 /*
@@ -35,9 +39,38 @@ extern uint32_t rfc2544_get_seq_num(const uint8_t *data, uint32_t len);
 extern uint64_t rfc2544_get_tx_timestamp(const uint8_t *data, uint32_t len);
 extern uint64_t rfc2544_calc_latency(uint64_t tx_timestamp_ns, uint64_t rx_timestamp_ns);
 
+extern void *rfc2544_create_templated_packet(uint8_t *buffer, uint32_t frame_size,
+                                             const header_template_t *tpl,
+                                             const uint8_t *src_mac, const uint8_t *dst_mac,
+                                             const payload_pattern_t *pattern);
+extern bool rfc2544_parse_response(const uint8_t *data, uint32_t len, uint32_t offset,
+                                   uint32_t *seq_num, uint64_t *tx_timestamp);
+
 extern void rfc2544_calc_latency_stats(const uint64_t *samples, uint32_t count,
                                        latency_stats_t *stats);
 
+typedef struct seq_tracker seq_tracker_t;
+extern seq_tracker_t *rfc2544_seq_tracker_create(uint32_t capacity);
+extern void rfc2544_seq_tracker_record(seq_tracker_t *tracker, uint32_t seq_num);
+extern void rfc2544_seq_tracker_stats(const seq_tracker_t *tracker, uint32_t expected,
+                                      uint32_t *received, uint32_t *lost, double *loss_pct);
+extern void rfc2544_seq_tracker_order(const seq_tracker_t *tracker, frame_order_t *order);
+extern int rfc2544_seq_tracker_reset(seq_tracker_t *tracker, uint32_t capacity);
+extern void rfc2544_seq_tracker_destroy(seq_tracker_t *tracker);
+
+extern uint32_t rfc2544_crc32(uint32_t crc, const uint8_t *data, size_t len);
+extern uint32_t rfc2544_prepare_seal(void *payload, uint32_t payload_len);
+extern void rfc2544_seal_packet(void *payload, uint32_t payload_len, uint32_t padding_crc);
+extern bool rfc2544_payload_intact(const uint8_t *data, uint32_t len, uint32_t offset);
+extern void rfc2544_set_stream(uint8_t *buffer, void *payload, const uint8_t *src_mac,
+                               uint32_t src_ip, uint32_t dst_ip, uint16_t src_port,
+                               uint32_t stream);
+extern bool rfc2544_parse_stream_id(const uint8_t *data, uint32_t len, uint32_t offset,
+                                    uint32_t *stream_id);
+extern void rfc2544_set_header_range(uint8_t *buffer, uint32_t len,
+                                     const header_fields_t *fields, uint64_t n);
+extern bool rfc2544_broadcast_slot(double *acc, double pct);
+
 /* ============================================================================
  * Packet Template Creation Tests
  * ============================================================================ */
@@ -248,6 +281,826 @@ TEST(get_seq_num_valid)
 	ASSERT_EQ(54321, seq);
 }
 
+/* ============================================================================
+ * Framing Tests
+ * ============================================================================ */
+
+TEST(framing_ethertype_override)
+{
+	uint8_t buffer[128];
+	uint8_t mac[6] = {0};
+	framing_config_t framing = {FRAMING_ETHERNET_II, 0x8864}; /* PPPoE session */
+
+	test_payload_t *payload =
+	    rfc2544_create_packet_template(buffer, 128, mac, mac, 0, 0, 0, 0, 0);
+	ASSERT_NOT_NULL(payload);
+	ASSERT_EQ(0, rfc2544_apply_framing(buffer, 128, &framing));
+	ASSERT_EQ(0x88, buffer[12]);
+	ASSERT_EQ(0x64, buffer[13]);
+
+	rfc2544_stamp_packet(payload, 7, 0);
+	ASSERT_EQ(7, rfc2544_get_seq_num(buffer, 128));
+}
+
+TEST(framing_llc_snap_header)
+{
+	uint8_t buffer[128];
+	uint8_t mac[6] = {0};
+	framing_config_t framing = {FRAMING_LLC_SNAP, 0};
+
+	test_payload_t *payload =
+	    rfc2544_create_packet_template(buffer, 128, mac, mac, 0, 0, 0, 0, 0);
+	int shift = rfc2544_apply_framing(buffer, 128, &framing);
+	ASSERT_EQ(LLC_SNAP_HEADER_LEN, shift);
+
+	/* 802.3 length excludes header and FCS, SNAP carries IPv4 */
+	ASSERT_EQ(128 - 14 - 4, (buffer[12] << 8) | buffer[13]);
+	ASSERT_EQ(0xAA, buffer[14]);
+	ASSERT_EQ(0x03, buffer[16]);
+	ASSERT_EQ(0x08, buffer[20]);
+	ASSERT_EQ(0x45, buffer[22]);
+
+	/* Payload moved with the headers and is still recognized on RX */
+	payload = (test_payload_t *)((uint8_t *)payload + shift);
+	rfc2544_stamp_packet(payload, 99, 0);
+	ASSERT_TRUE(rfc2544_is_valid_response(buffer, 128));
+	ASSERT_EQ(99, rfc2544_get_seq_num(buffer, 128));
+}
+
+TEST(framing_llc_snap_too_long)
+{
+	uint8_t buffer[1600];
+	uint8_t mac[6] = {0};
+	framing_config_t framing = {FRAMING_LLC_SNAP, 0};
+
+	rfc2544_create_packet_template(buffer, 1518, mac, mac, 0, 0, 0, 0, 0);
+	ASSERT_EQ(LLC_SNAP_HEADER_LEN, rfc2544_apply_framing(buffer, 1518, &framing));
+
+	/* 802.3 length field cannot describe frames above 1518 bytes */
+	rfc2544_create_packet_template(buffer, 1522, mac, mac, 0, 0, 0, 0, 0);
+	ASSERT_LT(rfc2544_apply_framing(buffer, 1522, &framing), 0);
+}
+
+TEST(vlan_tag_insert)
+{
+	uint8_t buffer[128];
+	uint8_t mac[6] = {0};
+
+	test_payload_t *payload =
+	    rfc2544_create_packet_template(buffer, 128, mac, mac, 0, 0, 0, 0, 0);
+	int shift = rfc2544_insert_vlan_tag(buffer, 128, 100, 5);
+	ASSERT_EQ(VLAN_TAG_LEN, shift);
+
+	/* TPID 0x8100, PCP 5 and VID 100 ahead of the IPv4 EtherType */
+	ASSERT_EQ(0x81, buffer[12]);
+	ASSERT_EQ(0x00, buffer[13]);
+	ASSERT_EQ(0xA0, buffer[14]);
+	ASSERT_EQ(100, buffer[15]);
+	ASSERT_EQ(0x08, buffer[16]);
+	ASSERT_EQ(0x45, buffer[18]);
+
+	/* The tag comes out of the frame size: IP and UDP are 4 bytes shorter */
+	ASSERT_EQ(128 - 18, (buffer[20] << 8) | buffer[21]);
+	ASSERT_EQ(128 - 18 - 20, (buffer[42] << 8) | buffer[43]);
+
+	payload = (test_payload_t *)((uint8_t *)payload + shift);
+	rfc2544_stamp_packet(payload, 77, 0);
+	ASSERT_TRUE(rfc2544_is_valid_response(buffer, 128));
+	ASSERT_EQ(77, rfc2544_get_seq_num(buffer, 128));
+}
+
+TEST(vlan_tag_llc_snap)
+{
+	uint8_t buffer[128];
+	uint8_t mac[6] = {0};
+	framing_config_t framing = {FRAMING_LLC_SNAP, 0};
+
+	test_payload_t *payload =
+	    rfc2544_create_packet_template(buffer, 128, mac, mac, 0, 0, 0, 0, 0);
+	int shift = rfc2544_apply_framing(buffer, 128, &framing);
+	shift += rfc2544_insert_vlan_tag(buffer, 128, 0, 3); /* Priority-tagged */
+	ASSERT_EQ(LLC_SNAP_HEADER_LEN + VLAN_TAG_LEN, shift);
+
+	/* The 802.3 length follows the tag and excludes it */
+	ASSERT_EQ(0x60, buffer[14]);
+	ASSERT_EQ(0, buffer[15]);
+	ASSERT_EQ(128 - 18 - 4, (buffer[16] << 8) | buffer[17]);
+	ASSERT_EQ(0xAA, buffer[18]);
+
+	payload = (test_payload_t *)((uint8_t *)payload + shift);
+	rfc2544_stamp_packet(payload, 5, 0);
+	ASSERT_EQ(5, rfc2544_get_seq_num(buffer, 128));
+}
+
+TEST(vlan_tag_invalid)
+{
+	uint8_t buffer[128];
+	uint8_t mac[6] = {0};
+
+	rfc2544_create_packet_template(buffer, 128, mac, mac, 0, 0, 0, 0, 0);
+	ASSERT_LT(rfc2544_insert_vlan_tag(buffer, 128, 4095, 0), 0);
+	ASSERT_LT(rfc2544_insert_vlan_tag(buffer, 128, 1, 8), 0);
+
+	/* A minimum-size frame has no padding to give up */
+	rfc2544_create_packet_template(buffer, RFC2544_MIN_FRAME_SIZE, mac, mac, 0, 0, 0, 0, 0);
+	ASSERT_LT(rfc2544_insert_vlan_tag(buffer, RFC2544_MIN_FRAME_SIZE, 100, 0), 0);
+}
+
+TEST(qinq_tag_insert)
+{
+	uint8_t buffer[128];
+	uint8_t mac[6] = {0};
+	qinq_config_t qinq = {0, 200, 3, 0};
+
+	test_payload_t *payload =
+	    rfc2544_create_packet_template(buffer, 128, mac, mac, 0, 0, 0, 0, 0);
+	int shift = rfc2544_insert_tags(buffer, 128, &qinq, 100, 5);
+	ASSERT_EQ(2 * VLAN_TAG_LEN, shift);
+
+	/* S-tag 0x88a8 PCP 3 VID 200, then C-tag 0x8100 PCP 5 VID 100 */
+	ASSERT_EQ(0x88, buffer[12]);
+	ASSERT_EQ(0xA8, buffer[13]);
+	ASSERT_EQ(0x60, buffer[14]);
+	ASSERT_EQ(200, buffer[15]);
+	ASSERT_EQ(0x81, buffer[16]);
+	ASSERT_EQ(0xA0, buffer[18]);
+	ASSERT_EQ(100, buffer[19]);
+	ASSERT_EQ(0x08, buffer[20]);
+	ASSERT_EQ(0x45, buffer[22]);
+
+	/* Both tags come out of the frame size */
+	ASSERT_EQ(128 - 22, (buffer[24] << 8) | buffer[25]);
+
+	payload = (test_payload_t *)((uint8_t *)payload + shift);
+	rfc2544_stamp_packet(payload, 9, 0);
+	ASSERT_TRUE(rfc2544_is_valid_response(buffer, 128));
+	ASSERT_EQ(9, rfc2544_get_seq_num(buffer, 128));
+}
+
+TEST(qinq_tag_tpids)
+{
+	uint8_t buffer[128];
+	uint8_t mac[6] = {0};
+	framing_config_t framing = {FRAMING_LLC_SNAP, 0};
+	qinq_config_t qinq = {0x9100, 10, 0, 0x88A8};
+
+	test_payload_t *payload =
+	    rfc2544_create_packet_template(buffer, 128, mac, mac, 0, 0, 0, 0, 0);
+	int shift = rfc2544_apply_framing(buffer, 128, &framing);
+	shift += rfc2544_insert_tags(buffer, 128, &qinq, 20, 0);
+	ASSERT_EQ(LLC_SNAP_HEADER_LEN + 2 * VLAN_TAG_LEN, shift);
+	ASSERT_EQ(0x91, buffer[12]);
+	ASSERT_EQ(0x88, buffer[16]);
+
+	/* The 802.3 length follows both tags and excludes them */
+	ASSERT_EQ(128 - 18 - 8, (buffer[20] << 8) | buffer[21]);
+	ASSERT_EQ(0xAA, buffer[22]);
+
+	payload = (test_payload_t *)((uint8_t *)payload + shift);
+	rfc2544_stamp_packet(payload, 6, 0);
+	ASSERT_EQ(6, rfc2544_get_seq_num(buffer, 128));
+
+	/* No third tag, and no TPIDs outside the known set */
+	ASSERT_LT(rfc2544_insert_tag(buffer, 128, TPID_8021Q, 1, 0), 0);
+	rfc2544_create_packet_template(buffer, 128, mac, mac, 0, 0, 0, 0, 0);
+	ASSERT_LT(rfc2544_insert_tag(buffer, 128, 0x1234, 1, 0), 0);
+	ASSERT_FALSE(rfc2544_valid_tpid(0x0800));
+}
+
+TEST(mpls_label_stack)
+{
+	uint8_t buffer[128];
+	uint8_t mac[6] = {0};
+	mpls_label_t labels[2] = {{16001, 5, 0}, {100, 5, 32}};
+
+	test_payload_t *payload =
+	    rfc2544_create_packet_template(buffer, 128, mac, mac, 0, 0, 0, 0, 0);
+	int shift = rfc2544_insert_mpls(buffer, 128, labels, 2);
+	ASSERT_EQ(2 * MPLS_LABEL_LEN, shift);
+	shift += rfc2544_insert_tags(buffer, 128, NULL, 100, 0);
+	ASSERT_EQ(2 * MPLS_LABEL_LEN + VLAN_TAG_LEN, shift);
+
+	/* Tag, then the MPLS EtherType and the stack */
+	ASSERT_EQ(0x88, buffer[16]);
+	ASSERT_EQ(0x47, buffer[17]);
+	uint32_t outer = (uint32_t)buffer[18] << 24 | buffer[19] << 16 | buffer[20] << 8 | buffer[21];
+	ASSERT_EQ(16001, outer >> 12);
+	ASSERT_EQ(5, (outer >> 9) & 7);
+	ASSERT_EQ(0, (outer >> 8) & 1);
+	ASSERT_EQ(64, outer & 0xff);
+	ASSERT_EQ(1, buffer[24] & 1); /* Bottom of stack */
+	ASSERT_EQ(32, buffer[25]);
+
+	/* IPv4 follows the stack, shortened by it */
+	ASSERT_EQ(0x45, buffer[26]);
+	ASSERT_EQ(128 - 14 - 12, (buffer[28] << 8) | buffer[29]);
+
+	payload = (test_payload_t *)((uint8_t *)payload + shift);
+	rfc2544_stamp_packet(payload, 11, 0);
+	ASSERT_EQ(11, rfc2544_get_seq_num(buffer, 128));
+}
+
+TEST(mpls_label_invalid)
+{
+	uint8_t buffer[128];
+	uint8_t mac[6] = {0};
+	mpls_label_t labels[MAX_MPLS_LABELS + 1] = {{MPLS_MAX_LABEL + 1, 0, 0}};
+	framing_config_t framing = {FRAMING_LLC_SNAP, 0};
+
+	rfc2544_create_packet_template(buffer, 128, mac, mac, 0, 0, 0, 0, 0);
+	ASSERT_LT(rfc2544_insert_mpls(buffer, 128, labels, 1), 0);
+	labels[0].label = 100;
+	ASSERT_LT(rfc2544_insert_mpls(buffer, 128, labels, 0), 0);
+	ASSERT_LT(rfc2544_insert_mpls(buffer, 128, labels, MAX_MPLS_LABELS + 1), 0);
+	ASSERT_LT(rfc2544_insert_mpls(buffer, 64, labels, MAX_MPLS_LABELS), 0);
+
+	/* Only IPv4 over Ethernet II is labeled */
+	rfc2544_apply_framing(buffer, 128, &framing);
+	ASSERT_LT(rfc2544_insert_mpls(buffer, 128, labels, 1), 0);
+}
+
+TEST(mark_priority_tags_and_dscp)
+{
+	uint8_t buffer[128];
+	uint8_t mac[6] = {0};
+	qinq_config_t qinq = {0, 200, 1, 0};
+
+	rfc2544_create_packet_template(buffer, 128, mac, mac, 0, 0, 0, 0, 0);
+	rfc2544_insert_tags(buffer, 128, &qinq, 100, 2);
+	buffer[23] |= 0x01; /* ECN ECT(1) */
+	ASSERT_EQ(0, rfc2544_mark_priority(buffer, 128, 5, 46));
+
+	/* Both tags carry the PCP, their VLAN IDs kept */
+	ASSERT_EQ(0xA0, buffer[14]);
+	ASSERT_EQ(200, buffer[15]);
+	ASSERT_EQ(0xA0, buffer[18]);
+	ASSERT_EQ(100, buffer[19]);
+
+	/* EF with the ECN bits kept, and a valid checksum */
+	ASSERT_EQ(46 << 2 | 0x01, buffer[23]);
+	uint32_t sum = 0;
+	for (int i = 22; i < 42; i += 2)
+		sum += (uint32_t)(buffer[i] << 8 | buffer[i + 1]);
+	while (sum >> 16)
+		sum = (sum & 0xffff) + (sum >> 16);
+	ASSERT_EQ(0xffff, sum);
+
+	ASSERT_LT(rfc2544_mark_priority(buffer, 128, 8, 0), 0);
+	ASSERT_LT(rfc2544_mark_priority(buffer, 128, 0, 64), 0);
+}
+
+TEST(ipv6_convert_frame)
+{
+	uint8_t buffer[128];
+	uint8_t mac[6] = {0};
+	ipv6_config_t v6 = {{0xfe, 0x80, [15] = 1}, {0xfe, 0x80, [15] = 2}, 0xb8, 0x12345, 0};
+
+	test_payload_t *payload =
+	    rfc2544_create_packet_template(buffer, 128, mac, mac, 0, 0, 0, 0, 0);
+	ASSERT_EQ(4, rfc2544_ip_version(buffer, 128));
+	int shift = rfc2544_convert_ipv6(buffer, 128, &v6);
+	ASSERT_EQ(20, shift);
+	ASSERT_EQ(6, rfc2544_ip_version(buffer, 128));
+
+	/* EtherType, then version 6, traffic class 0xb8 and the flow label */
+	ASSERT_EQ(0x86, buffer[12]);
+	ASSERT_EQ(0xDD, buffer[13]);
+	ASSERT_EQ(0x6B, buffer[14]);
+	ASSERT_EQ(0x81, buffer[15]);
+	ASSERT_EQ(0x23, buffer[16]);
+	ASSERT_EQ(0x45, buffer[17]);
+	ASSERT_EQ(128 - 54, (buffer[18] << 8) | buffer[19]);
+	ASSERT_EQ(64, buffer[21]); /* Default hop limit */
+	ASSERT_EQ(2, buffer[53]);
+	ASSERT_EQ(128 - 54, (buffer[58] << 8) | buffer[59]);
+	ASSERT_TRUE(buffer[60] || buffer[61]);
+
+	payload = (test_payload_t *)((uint8_t *)payload + shift);
+	rfc2544_stamp_packet(payload, 13, 0);
+	ASSERT_TRUE(rfc2544_is_valid_response(buffer, 128));
+	ASSERT_EQ(13, rfc2544_get_seq_num(buffer, 128));
+
+	/* Once only, within the frame size */
+	ASSERT_LT(rfc2544_convert_ipv6(buffer, 128, &v6), 0);
+	rfc2544_create_packet_template(buffer, 64, mac, mac, 0, 0, 0, 0, 0);
+	ASSERT_LT(rfc2544_convert_ipv6(buffer, 64, &v6), 0);
+	v6.flow_label = IPV6_MAX_FLOW_LABEL + 1;
+	rfc2544_create_packet_template(buffer, 128, mac, mac, 0, 0, 0, 0, 0);
+	ASSERT_LT(rfc2544_convert_ipv6(buffer, 128, &v6), 0);
+}
+
+TEST(ipv6_tags_and_marking)
+{
+	uint8_t buffer[128];
+	uint8_t mac[6] = {0};
+	ipv6_config_t v6 = {{0}, {0}, 0x01, 7, 0}; /* ECN ECT(1) */
+	framing_config_t framing = {FRAMING_LLC_SNAP, 0};
+
+	test_payload_t *payload =
+	    rfc2544_create_packet_template(buffer, 128, mac, mac, 0, 0, 0, 0, 0);
+	int shift = rfc2544_convert_ipv6(buffer, 128, &v6);
+	shift += rfc2544_insert_tags(buffer, 128, NULL, 100, 0);
+	ASSERT_EQ(20 + VLAN_TAG_LEN, shift);
+	ASSERT_EQ(0x86, buffer[16]);
+	ASSERT_EQ(6, rfc2544_ip_version(buffer, 128));
+
+	/* The tag comes out of the IPv6 and UDP lengths */
+	ASSERT_EQ(128 - 58, (buffer[22] << 8) | buffer[23]);
+	ASSERT_EQ(128 - 58, (buffer[62] << 8) | buffer[63]);
+
+	/* DSCP in the traffic class, ECN and the flow label kept */
+	ASSERT_EQ(0, rfc2544_mark_priority(buffer, 128, 3, 46));
+	ASSERT_EQ(0x60, buffer[14]);
+	uint32_t word = (uint32_t)buffer[18] << 24 | buffer[19] << 16 | buffer[20] << 8 | buffer[21];
+	ASSERT_EQ(46 << 2 | 0x01, (word >> 20) & 0xff);
+	ASSERT_EQ(7, word & 0xfffff);
+
+	payload = (test_payload_t *)((uint8_t *)payload + shift);
+	rfc2544_stamp_packet(payload, 21, 0);
+	ASSERT_EQ(21, rfc2544_get_seq_num(buffer, 128));
+
+	/* LLC/SNAP framing carries IPv4 only */
+	rfc2544_create_packet_template(buffer, 128, mac, mac, 0, 0, 0, 0, 0);
+	rfc2544_convert_ipv6(buffer, 128, &v6);
+	ASSERT_LT(rfc2544_apply_framing(buffer, 128, &framing), 0);
+}
+
+/* ============================================================================
+ * Packet Template Tests
+ * ============================================================================ */
+
+/* eth/dot1q(vlan=100)/ipv6/udp: 14 + 4 + 40 + 8 header bytes */
+static void vlan_ipv6_template(header_template_t *tpl)
+{
+	memset(tpl, 0, sizeof(*tpl));
+	uint8_t *h = tpl->header;
+	h[12] = 0x81; /* 802.1Q TPID */
+	h[13] = 0x00;
+	h[15] = 100;  /* VID */
+	h[16] = 0x86; /* IPv6 */
+	h[17] = 0xDD;
+	h[18] = 0x60;
+	h[24] = 17;   /* Next header UDP */
+	h[25] = 64;
+	h[33] = 1;    /* fe80::1 -> fe80::2, prefix left zero */
+	h[49] = 2;
+	tpl->header_len = 66;
+	tpl->ipv4_offset = -1;
+	tpl->ipv6_offset = 18;
+	tpl->udp_offset = 58;
+	tpl->flags = TEMPLATE_FILL_SRC_MAC | TEMPLATE_FILL_DST_MAC;
+}
+
+TEST(template_vlan_ipv6_lengths)
+{
+	uint8_t buffer[256];
+	uint8_t src_mac[6] = {0x00, 0x11, 0x22, 0x33, 0x44, 0x55};
+	uint8_t dst_mac[6] = {0x66, 0x77, 0x88, 0x99, 0xaa, 0xbb};
+	header_template_t tpl;
+	vlan_ipv6_template(&tpl);
+
+	test_payload_t *payload =
+	    rfc2544_create_templated_packet(buffer, 256, &tpl, src_mac, dst_mac, NULL);
+	ASSERT_NOT_NULL(payload);
+	ASSERT_EQ(buffer + 66, (uint8_t *)payload);
+	ASSERT_MEM_EQ(dst_mac, buffer, 6);
+	ASSERT_MEM_EQ(src_mac, buffer + 6, 6);
+	ASSERT_EQ(100, buffer[15]);
+
+	/* IPv6 payload length and UDP length cover the rest of the frame */
+	ASSERT_EQ(256 - 18 - 40, (buffer[22] << 8) | buffer[23]);
+	ASSERT_EQ(256 - 58, (buffer[62] << 8) | buffer[63]);
+	ASSERT_TRUE(buffer[64] || buffer[65]); /* UDP checksum is mandatory */
+}
+
+TEST(template_parse_at_offset)
+{
+	uint8_t buffer[128];
+	uint8_t mac[6] = {0};
+	header_template_t tpl;
+	vlan_ipv6_template(&tpl);
+
+	test_payload_t *payload = rfc2544_create_templated_packet(buffer, 128, &tpl, mac, mac, NULL);
+	ASSERT_NOT_NULL(payload);
+	rfc2544_stamp_packet(payload, 42, 123456789ULL);
+
+	uint32_t seq = 0;
+	uint64_t ts = 0;
+	ASSERT_TRUE(rfc2544_parse_response(buffer, 128, tpl.header_len, &seq, &ts));
+	ASSERT_EQ(42, seq);
+	ASSERT_EQ(123456789ULL, ts);
+
+	/* The built-in layout walks the VLAN tag and the IPv6 header too */
+	seq = 0;
+	ASSERT_TRUE(rfc2544_parse_response(buffer, 128, 0, &seq, &ts));
+	ASSERT_EQ(42, seq);
+}
+
+TEST(template_frame_too_small)
+{
+	uint8_t buffer[128];
+	uint8_t mac[6] = {0};
+	header_template_t tpl;
+	vlan_ipv6_template(&tpl);
+
+	/* 66 header bytes + 24 byte payload leave no room in a 64 byte frame */
+	ASSERT_NULL(rfc2544_create_templated_packet(buffer, 64, &tpl, mac, mac, NULL));
+	ASSERT_NOT_NULL(rfc2544_create_templated_packet(buffer, 90, &tpl, mac, mac, NULL));
+}
+
+TEST(payload_fill_patterns)
+{
+	uint8_t buf[12];
+	payload_pattern_t p;
+	memset(&p, 0, sizeof(p));
+
+	rfc2544_fill_pattern(buf, sizeof(buf), NULL);
+	ASSERT_EQ(0, buf[0]);
+	ASSERT_EQ(11, buf[11]);
+
+	p.kind = PAYLOAD_ONES;
+	rfc2544_fill_pattern(buf, sizeof(buf), &p);
+	ASSERT_EQ(0xff, buf[5]);
+
+	/* SplitMix64 from seed 0, most significant byte first */
+	const uint8_t random[8] = {0xe2, 0x20, 0xa8, 0x39, 0x7b, 0x1d, 0xcd, 0xaf};
+	p.kind = PAYLOAD_RANDOM;
+	rfc2544_fill_pattern(buf, 10, &p);
+	ASSERT_MEM_EQ(random, buf, 8);
+
+	const uint8_t prbs[8] = {0x00, 0x00, 0x00, 0x0e, 0x00, 0x00, 0x00, 0xfc};
+	p.kind = PAYLOAD_PRBS31;
+	rfc2544_fill_pattern(buf, 8, &p);
+	ASSERT_MEM_EQ(prbs, buf, 8);
+
+	p.kind = PAYLOAD_CUSTOM;
+	p.len = 3;
+	p.bytes[0] = 0xde;
+	p.bytes[1] = 0xad;
+	p.bytes[2] = 0xbe;
+	rfc2544_fill_pattern(buf, 7, &p);
+	ASSERT_EQ(0xde, buf[3]);
+	ASSERT_EQ(0xde, buf[6]);
+	ASSERT_EQ(0xbe, buf[5]);
+}
+
+TEST(template_padding_pattern)
+{
+	uint8_t buffer[128];
+	uint8_t mac[6] = {0};
+	header_template_t tpl;
+	vlan_ipv6_template(&tpl);
+	payload_pattern_t p;
+	memset(&p, 0, sizeof(p));
+	p.kind = PAYLOAD_ZEROS;
+
+	ASSERT_NOT_NULL(rfc2544_create_templated_packet(buffer, 128, &tpl, mac, mac, &p));
+	for (uint32_t i = tpl.header_len + 24; i < 128; i++)
+		ASSERT_EQ(0, buffer[i]);
+}
+
+/* ============================================================================
+ * PCAP Capture Tests
+ * ============================================================================ */
+
+TEST(capture_pcap_file)
+{
+	rfc2544_ctx_t *ctx = calloc(1, sizeof(*ctx));
+	ASSERT_NOT_NULL(ctx);
+	capture_config_t cfg;
+	memset(&cfg, 0, sizeof(cfg));
+	snprintf(cfg.tx_path, sizeof(cfg.tx_path), "/tmp/rfc2544_test_capture.pcap");
+	cfg.limit = 2;
+	cfg.snaplen = 20;
+	ASSERT_EQ(0, rfc2544_capture_configure(ctx, &cfg));
+
+	/* The third frame is over the limit; each keeps its first 20 bytes */
+	uint8_t frame[64];
+	for (int i = 0; i < 3; i++) {
+		memset(frame, i + 1, sizeof(frame));
+		rfc2544_capture_frame(&ctx->capture_tx, frame, sizeof(frame));
+	}
+	rfc2544_capture_frame(&ctx->capture_rx, frame, sizeof(frame));
+	rfc2544_capture_close(ctx);
+	capture_stats_t stats;
+	ASSERT_EQ(0, rfc2544_capture_get_stats(ctx, &stats));
+	ASSERT_EQ(2, stats.tx_frames);
+	ASSERT_EQ(0, stats.rx_frames);
+
+	uint8_t file[256];
+	FILE *f = fopen(cfg.tx_path, "rb");
+	ASSERT_NOT_NULL(f);
+	size_t n = fread(file, 1, sizeof(file), f);
+	fclose(f);
+	remove(cfg.tx_path);
+	ASSERT_EQ(24 + 2 * (16 + 20), n);
+	uint32_t word;
+	memcpy(&word, file, 4);
+	ASSERT_EQ(0xa1b23c4d, word);
+	memcpy(&word, file + 24 + 8, 4);
+	ASSERT_EQ(20, word); /* Captured */
+	memcpy(&word, file + 24 + 12, 4);
+	ASSERT_EQ(64, word); /* On the wire */
+	ASSERT_EQ(2, file[24 + 36 + 16]);
+
+	cfg.snaplen = 1 << 20;
+	ASSERT_EQ(-EINVAL, rfc2544_capture_configure(ctx, &cfg));
+	cfg.snaplen = 0;
+	snprintf(cfg.tx_path, sizeof(cfg.tx_path), "/nonexistent/tx.pcap");
+	ASSERT_TRUE(rfc2544_capture_configure(ctx, &cfg) < 0);
+	ASSERT_NULL(ctx->capture_tx.file);
+	free(ctx);
+}
+
+/* ============================================================================
+ * Version and Capability Tests
+ * ============================================================================ */
+
+TEST(library_version_and_capabilities)
+{
+	rfc2544_version_t version;
+	rfc2544_get_version(&version);
+	ASSERT_EQ(RFC2544_ABI_VERSION, version.abi);
+	ASSERT_TRUE(version.abi_compat <= version.abi);
+
+	rfc2544_capabilities_t caps;
+	ASSERT_EQ(0, rfc2544_get_capabilities(NULL, false, &caps));
+	ASSERT_TRUE(caps.flags & RFC2544_CAP_Y1564);
+	ASSERT_FALSE(caps.flags & RFC2544_CAP_HW_TIMESTAMP);
+	ASSERT_EQ(MAX_STREAMS, caps.max_streams);
+	ASSERT_TRUE(caps.max_frame_size >= 1518 && caps.max_frame_size <= RFC2544_MAX_FRAME_SIZE);
+
+	ASSERT_EQ(0, rfc2544_get_capabilities("lo", false, &caps));
+	ASSERT_TRUE(caps.max_frame_size <= RFC2544_MAX_FRAME_SIZE);
+	ASSERT_EQ(-ENOENT, rfc2544_get_capabilities("rfc2544-none0", false, &caps));
+	ASSERT_EQ(-EINVAL, rfc2544_get_capabilities(NULL, false, NULL));
+}
+
+TEST(burst_train_rejects_bad_trains)
+{
+	rfc2544_ctx_t *ctx = calloc(1, sizeof(*ctx));
+	ASSERT_NOT_NULL(ctx);
+	burst_train_result_t *result = malloc(sizeof(*result));
+	ASSERT_NOT_NULL(result);
+
+	burst_train_t train = {.burst_frames = 64, .bursts = 10, .gap_ns = 1000000, .rate_pct = 100.0};
+	ASSERT_EQ(-EINVAL, rfc2544_burst_train(NULL, 512, &train, result));
+	ASSERT_EQ(-EINVAL, rfc2544_burst_train(ctx, 512, NULL, result));
+	train.bursts = RFC2544_MAX_TRAIN_BURSTS + 1;
+	ASSERT_EQ(-EINVAL, rfc2544_burst_train(ctx, 512, &train, result));
+	train.bursts = 10;
+	train.burst_frames = 0;
+	ASSERT_EQ(-EINVAL, rfc2544_burst_train(ctx, 512, &train, result));
+	train.burst_frames = 64;
+	train.rate_pct = 150.0;
+	ASSERT_EQ(-EINVAL, rfc2544_burst_train(ctx, 512, &train, result));
+
+	free(result);
+	free(ctx);
+}
+
+/* ============================================================================
+ * Control-Plane Frame Tests
+ * ============================================================================ */
+
+static const uint8_t cpp_src_mac[6] = {0x02, 0x00, 0x00, 0x00, 0x00, 0x01};
+static const uint8_t cpp_dut_mac[6] = {0x02, 0x00, 0x00, 0x00, 0x00, 0xfe};
+#define CPP_SRC_IP 0x0100000a /* 10.0.0.1 in network order */
+#define CPP_DUT_IP 0xfe00000a /* 10.0.0.254 in network order */
+
+TEST(control_frame_icmp_echo)
+{
+	uint8_t buf[CPP_FRAME_LEN];
+	ASSERT_EQ(CPP_FRAME_LEN, rfc2544_build_control_frame(buf, sizeof(buf), CPP_FRAME_ICMP,
+	                                                     cpp_src_mac, cpp_dut_mac,
+	                                                     CPP_SRC_IP, CPP_DUT_IP, 1));
+	ASSERT_EQ(0xfe, buf[5]);
+	ASSERT_EQ(1, buf[23]);  /* IPPROTO_ICMP */
+	ASSERT_EQ(8, buf[34]);  /* Echo request */
+
+	/* Our own request is not a reply */
+	ASSERT_EQ(CPP_FRAME_NONE, rfc2544_classify_control_reply(buf, sizeof(buf), CPP_DUT_IP));
+}
+
+TEST(control_frame_arp_reply)
+{
+	uint8_t buf[CPP_FRAME_LEN];
+	rfc2544_build_control_frame(buf, sizeof(buf), CPP_FRAME_ARP, cpp_src_mac, cpp_dut_mac,
+	                            CPP_SRC_IP, CPP_DUT_IP, 0);
+	ASSERT_EQ(0xff, buf[0]); /* Broadcast request */
+	ASSERT_EQ(0x06, buf[13]);
+
+	/* Turn into the DUT's reply: op = 2, sender = DUT */
+	uint32_t dut_ip = CPP_DUT_IP;
+	buf[21] = 2;
+	memcpy(buf + 28, &dut_ip, 4);
+	ASSERT_EQ(CPP_FRAME_ARP, rfc2544_classify_control_reply(buf, sizeof(buf), CPP_DUT_IP));
+}
+
+TEST(control_frame_bgp_rst)
+{
+	uint8_t buf[CPP_FRAME_LEN];
+	rfc2544_build_control_frame(buf, sizeof(buf), CPP_FRAME_BGP, cpp_src_mac, cpp_dut_mac,
+	                            CPP_SRC_IP, CPP_DUT_IP, 5);
+	ASSERT_EQ(6, buf[23]); /* IPPROTO_TCP */
+	ASSERT_EQ(179, (buf[36] << 8) | buf[37]);
+	ASSERT_EQ(0x02, buf[47]); /* SYN */
+
+	/* DUT answers from port 179 with RST */
+	uint32_t dut_ip = CPP_DUT_IP;
+	memcpy(buf + 26, &dut_ip, 4);
+	buf[34] = 0;
+	buf[35] = 179;
+	buf[47] = 0x14; /* RST+ACK */
+	ASSERT_EQ(CPP_FRAME_BGP, rfc2544_classify_control_reply(buf, sizeof(buf), CPP_DUT_IP));
+
+	/* Same reply from another host is not counted */
+	ASSERT_EQ(CPP_FRAME_NONE, rfc2544_classify_control_reply(buf, sizeof(buf), CPP_SRC_IP + 1));
+}
+
+TEST(control_frame_invalid)
+{
+	uint8_t buf[CPP_FRAME_LEN];
+	ASSERT_LT(rfc2544_build_control_frame(buf, 32, CPP_FRAME_ICMP, cpp_src_mac, cpp_dut_mac,
+	                                      CPP_SRC_IP, CPP_DUT_IP, 0), 0);
+	ASSERT_LT(rfc2544_build_control_frame(buf, sizeof(buf), CPP_FRAME_NONE, cpp_src_mac,
+	                                      cpp_dut_mac, CPP_SRC_IP, CPP_DUT_IP, 0), 0);
+}
+
+/* ============================================================================
+ * Sequence Tracking Tests
+ * ============================================================================ */
+
+TEST(seq_tracker_in_order)
+{
+	seq_tracker_t *t = rfc2544_seq_tracker_create(100);
+	ASSERT_NOT_NULL(t);
+	for (uint32_t i = 0; i < 10; i++)
+		rfc2544_seq_tracker_record(t, i);
+
+	frame_order_t order = {0};
+	uint32_t received = 0;
+	rfc2544_seq_tracker_order(t, &order);
+	rfc2544_seq_tracker_stats(t, 10, &received, NULL, NULL);
+	ASSERT_EQ(10, received);
+	ASSERT_EQ(0, order.reordered);
+	ASSERT_EQ(0, order.duplicates);
+	ASSERT_EQ(0, order.max_reorder_depth);
+	rfc2544_seq_tracker_destroy(t);
+}
+
+TEST(seq_tracker_reorder_and_duplicates)
+{
+	seq_tracker_t *t = rfc2544_seq_tracker_create(100);
+	ASSERT_NOT_NULL(t);
+
+	/* 0 1 4 2 3 4 1 7 5: 2, 3 and 5 arrive late; 4 and 1 twice */
+	uint32_t seqs[] = {0, 1, 4, 2, 3, 4, 1, 7, 5};
+	for (size_t i = 0; i < sizeof(seqs) / sizeof(seqs[0]); i++)
+		rfc2544_seq_tracker_record(t, seqs[i]);
+
+	frame_order_t order = {0};
+	uint32_t received = 0;
+	rfc2544_seq_tracker_order(t, &order);
+	rfc2544_seq_tracker_stats(t, 8, &received, NULL, NULL);
+	ASSERT_EQ(7, received);
+	ASSERT_EQ(3, order.reordered);
+	ASSERT_EQ(2, order.duplicates);
+	ASSERT_EQ(2, order.max_reorder_depth);
+	rfc2544_seq_tracker_destroy(t);
+}
+
+TEST(seq_tracker_reset_reuses)
+{
+	seq_tracker_t *t = rfc2544_seq_tracker_create(1000);
+	ASSERT_NOT_NULL(t);
+	for (uint32_t seq = 0; seq < 900; seq += 3)
+		rfc2544_seq_tracker_record(t, seq);
+
+	/* The next trial starts clean, in a smaller or larger bitmap */
+	uint32_t received = 0;
+	frame_order_t order = {0};
+	for (int round = 0; round < 2; round++) {
+		ASSERT_EQ(0, rfc2544_seq_tracker_reset(t, round ? 5000 : 500));
+		rfc2544_seq_tracker_record(t, 3);
+		rfc2544_seq_tracker_record(t, 3);
+		rfc2544_seq_tracker_record(t, 4000);
+		rfc2544_seq_tracker_stats(t, 2, &received, NULL, NULL);
+		rfc2544_seq_tracker_order(t, &order);
+		ASSERT_EQ(round ? 2 : 1, received);
+		ASSERT_EQ(1, order.duplicates);
+	}
+	rfc2544_seq_tracker_destroy(t);
+}
+
+/* ============================================================================
+ * Payload Verification Tests
+ * ============================================================================ */
+
+TEST(crc32_check_value)
+{
+	const uint8_t check[] = "123456789";
+	ASSERT_EQ(0xCBF43926, rfc2544_crc32(0, check, 9));
+	/* Continuing over the second half gives the CRC of the whole */
+	ASSERT_EQ(0xCBF43926, rfc2544_crc32(rfc2544_crc32(0, check, 4), check + 4, 5));
+}
+
+TEST(payload_seal_detects_corruption)
+{
+	uint8_t buffer[128];
+	uint8_t mac[6] = {0x02, 0, 0, 0, 0, 1};
+	test_payload_t *payload = rfc2544_create_packet_template(buffer, 128, mac, mac, 0x0100000a,
+	                                                         0x0200000a, 12345, 3842, 0);
+	ASSERT_NOT_NULL(payload);
+	uint32_t payload_len = 128 - 42;
+	uint32_t padding_crc = rfc2544_prepare_seal(payload, payload_len);
+	ASSERT_EQ(RFC2544_FLAG_REQ_TIMESTAMP | RFC2544_FLAG_CRC32, payload->flags);
+
+	rfc2544_stamp_packet(payload, 7, 1000);
+	rfc2544_seal_packet(payload, payload_len, padding_crc);
+	ASSERT_TRUE(rfc2544_payload_intact(buffer, 128, 0));
+	ASSERT_TRUE(rfc2544_payload_intact(buffer, 128, 42));
+
+	/* A reflector rewriting the flags does not break the seal */
+	payload->flags |= RFC2544_FLAG_IS_RESPONSE;
+	ASSERT_TRUE(rfc2544_payload_intact(buffer, 128, 0));
+
+	buffer[100] ^= 0x10;
+	ASSERT_FALSE(rfc2544_payload_intact(buffer, 128, 0));
+	buffer[100] ^= 0x10;
+
+	/* Restamped without resealing: the sequence number is covered */
+	rfc2544_stamp_packet(payload, 8, 1000);
+	ASSERT_FALSE(rfc2544_payload_intact(buffer, 128, 0));
+	rfc2544_seal_packet(payload, payload_len, padding_crc);
+	ASSERT_TRUE(rfc2544_payload_intact(buffer, 128, 0));
+
+	/* Truncated frames fail against the UDP length */
+	ASSERT_FALSE(rfc2544_payload_intact(buffer, 120, 0));
+	ASSERT_FALSE(rfc2544_payload_intact(NULL, 128, 0));
+}
+
+/* ============================================================================
+ * Multi-Stream Tests
+ * ============================================================================ */
+
+TEST(set_stream_addresses_flow)
+{
+	uint8_t buffer[128];
+	uint8_t mac[6] = {0x02, 0, 0, 0, 0, 0xff};
+	test_payload_t *payload = rfc2544_create_packet_template(buffer, 128, mac, mac, 0x0100000a,
+	                                                         0x0200000a, 12345, 3842, 0);
+	ASSERT_NOT_NULL(payload);
+
+	rfc2544_set_stream(buffer, payload, mac, 0x0100000a, 0x0200000a, 12345, 3);
+	/* Source MAC + 3 carries into the next byte */
+	ASSERT_EQ(0x01, buffer[10]);
+	ASSERT_EQ(0x02, buffer[11]);
+	/* Source and destination IPs + 3 */
+	ASSERT_EQ(4, buffer[29]);
+	ASSERT_EQ(5, buffer[33]);
+	/* UDP source port + 3 */
+	ASSERT_EQ(12348, (buffer[34] << 8) | buffer[35]);
+
+	uint32_t stream = 0;
+	ASSERT_TRUE(rfc2544_parse_stream_id(buffer, 128, 0, &stream));
+	ASSERT_EQ(3, stream);
+	ASSERT_TRUE(rfc2544_parse_stream_id(buffer, 128, 42, &stream));
+	ASSERT_EQ(3, stream);
+	ASSERT_FALSE(rfc2544_parse_stream_id(buffer, 40, 42, &stream));
+}
+
+TEST(set_header_range_cycles)
+{
+	uint8_t buffer[128];
+	uint8_t mac[6] = {0x02, 0, 0, 0, 0, 0x01};
+	ASSERT_NOT_NULL(rfc2544_create_packet_template(buffer, 128, mac, mac, htonl(0x0a000001),
+	                                               htonl(0x0a000002), 12345, 5000, 0));
+
+	header_fields_t fields = {.src_ip = htonl(0x0a000001), .dst_ip = htonl(0x0a000002),
+	                          .src_ip_count = 4, .src_port = 12345, .dst_port = 5000,
+	                          .dst_port_count = 10};
+	rfc2544_set_header_range(buffer, 128, &fields, 6);
+	/* Source IP 10.0.0.1 + 6 % 4, destination unchanged */
+	ASSERT_EQ(3, buffer[29]);
+	ASSERT_EQ(2, buffer[33]);
+	/* UDP source port unchanged, destination 5000 + 6 */
+	ASSERT_EQ(12345, (buffer[34] << 8) | buffer[35]);
+	ASSERT_EQ(5006, (buffer[36] << 8) | buffer[37]);
+
+	/* The IPv4 header checksum still verifies */
+	uint32_t sum = 0;
+	for (int i = 14; i < 34; i += 2)
+		sum += (buffer[i] << 8) | buffer[i + 1];
+	while (sum >> 16)
+		sum = (sum & 0xffff) + (sum >> 16);
+	ASSERT_EQ(0xffff, sum);
+
+	rfc2544_set_header_range(buffer, 128, &fields, 13);
+	ASSERT_EQ(2, buffer[29]);
+	ASSERT_EQ(5003, (buffer[36] << 8) | buffer[37]);
+}
+
 /* ============================================================================
  * Latency Calculation Tests
  * ============================================================================ */
@@ -355,6 +1208,37 @@ TEST(frame_size_standard_sizes)
 	}
 }
 
+/* ============================================================================
+ * Section 11.1 Broadcast Share Tests
+ * ============================================================================ */
+
+static uint32_t count_broadcast_slots(double pct, uint32_t slots)
+{
+	double acc = 0.0;
+	uint32_t n = 0;
+	for (uint32_t i = 0; i < slots; i++) {
+		if (rfc2544_broadcast_slot(&acc, pct))
+			n++;
+	}
+	return n;
+}
+
+TEST(broadcast_share_non_integer_interval)
+{
+	/* 60% is every 1.67th slot; rounding the interval would send 50% */
+	ASSERT_EQ(600, count_broadcast_slots(60.0, 1000));
+	/* 1.5% is every 66.7th slot; rounding would send 1/67 = 1.49% */
+	ASSERT_EQ(150, count_broadcast_slots(1.5, 10000));
+}
+
+TEST(broadcast_share_leaves_unicast)
+{
+	/* The largest share still sends every other frame unicast */
+	double acc = 0.0;
+	for (int i = 0; i < 100; i++)
+		ASSERT_EQ(i % 2 == 1, rfc2544_broadcast_slot(&acc, MAX_BROADCAST_PCT));
+}
+
 /* ============================================================================
  * Main
  * ============================================================================ */
@@ -387,6 +1271,50 @@ int main(void)
 	RUN_TEST(get_seq_num_invalid_packet);
 	RUN_TEST(get_seq_num_valid);
 
+	TEST_SUITE("Framing");
+	RUN_TEST(framing_ethertype_override);
+	RUN_TEST(framing_llc_snap_header);
+	RUN_TEST(framing_llc_snap_too_long);
+	RUN_TEST(vlan_tag_insert);
+	RUN_TEST(vlan_tag_llc_snap);
+	RUN_TEST(vlan_tag_invalid);
+	RUN_TEST(qinq_tag_insert);
+	RUN_TEST(qinq_tag_tpids);
+	RUN_TEST(mpls_label_stack);
+	RUN_TEST(mpls_label_invalid);
+	RUN_TEST(mark_priority_tags_and_dscp);
+	RUN_TEST(ipv6_convert_frame);
+	RUN_TEST(ipv6_tags_and_marking);
+
+	TEST_SUITE("Packet Templates");
+	RUN_TEST(template_vlan_ipv6_lengths);
+	RUN_TEST(template_parse_at_offset);
+	RUN_TEST(template_frame_too_small);
+	RUN_TEST(payload_fill_patterns);
+	RUN_TEST(template_padding_pattern);
+	RUN_TEST(capture_pcap_file);
+	RUN_TEST(library_version_and_capabilities);
+	RUN_TEST(burst_train_rejects_bad_trains);
+
+	TEST_SUITE("Control-Plane Frames");
+	RUN_TEST(control_frame_icmp_echo);
+	RUN_TEST(control_frame_arp_reply);
+	RUN_TEST(control_frame_bgp_rst);
+	RUN_TEST(control_frame_invalid);
+
+	TEST_SUITE("Sequence Tracking");
+	RUN_TEST(seq_tracker_in_order);
+	RUN_TEST(seq_tracker_reorder_and_duplicates);
+	RUN_TEST(seq_tracker_reset_reuses);
+
+	TEST_SUITE("Payload Verification");
+	RUN_TEST(crc32_check_value);
+	RUN_TEST(payload_seal_detects_corruption);
+
+	TEST_SUITE("Multi-Stream");
+	RUN_TEST(set_stream_addresses_flow);
+	RUN_TEST(set_header_range_cycles);
+
 	TEST_SUITE("Latency Calculations");
 	RUN_TEST(calc_latency_normal);
 	RUN_TEST(calc_latency_zero);
@@ -404,6 +1332,10 @@ int main(void)
 	RUN_TEST(frame_size_minimum_valid);
 	RUN_TEST(frame_size_standard_sizes);
 
+	TEST_SUITE("Section 11.1 Broadcast Share");
+	RUN_TEST(broadcast_share_non_integer_interval);
+	RUN_TEST(broadcast_share_leaves_unicast);
+
 	TEST_SUMMARY();
 
 	return test_failures;
@@ -25,6 +25,9 @@ TEST(y1564_default_sla_values)
 	ASSERT_FLOAT_EQ(10.0, sla.fd_threshold_ms, 0.1);
 	ASSERT_FLOAT_EQ(5.0, sla.fdv_threshold_ms, 0.1);
 	ASSERT_FLOAT_EQ(0.01, sla.flr_threshold_pct, 0.001);
+	ASSERT_FLOAT_EQ(10.0, sla.eir_sac.fd_threshold_ms, 0.1);
+	ASSERT_TRUE(sla.eir_sac.flr_threshold_pct < 0);
+	ASSERT_TRUE(sla.policing_sac.flr_threshold_pct < 0);
 }
 
 TEST(y1564_default_sla_null)
@@ -203,6 +206,62 @@ TEST(rfc2889_trial_duration)
 	ASSERT_GT(config.trial_duration_sec, 0);
 }
 
+TEST(rfc2889_pattern_plan_mesh)
+{
+	rfc2889_pattern_plan_t plan;
+	ASSERT_EQ(0, rfc2889_pattern_plan(TRAFFIC_FULLY_MESHED, 4, &plan));
+	ASSERT_EQ(12, plan.flow_count);
+	ASSERT_EQ(4, plan.ingress_ports);
+	ASSERT_FLOAT_EQ(1.0, plan.ingress_load_scale, 0.0001);
+}
+
+TEST(rfc2889_pattern_plan_pairs)
+{
+	rfc2889_pattern_plan_t plan;
+	ASSERT_EQ(0, rfc2889_pattern_plan(TRAFFIC_PAIR_WISE, 4, &plan));
+	ASSERT_EQ(4, plan.flow_count);
+
+	/* Pair-wise needs an even port count */
+	ASSERT_LT(rfc2889_pattern_plan(TRAFFIC_PAIR_WISE, 3, &plan), 0);
+}
+
+TEST(rfc2889_pattern_plan_many_to_one)
+{
+	rfc2889_pattern_plan_t plan;
+	ASSERT_EQ(0, rfc2889_pattern_plan(TRAFFIC_MANY_TO_ONE, 5, &plan));
+	ASSERT_EQ(4, plan.flow_count);
+	ASSERT_EQ(4, plan.ingress_ports);
+	ASSERT_EQ(1, plan.egress_ports);
+	ASSERT_FLOAT_EQ(0.25, plan.ingress_load_scale, 0.0001);
+}
+
+TEST(rfc2889_pattern_measurable)
+{
+	rfc2889_pattern_plan_t plan;
+
+	/* Two ports: one flow from the TX port to the RX port */
+	ASSERT_EQ(0, rfc2889_pattern_plan(TRAFFIC_ONE_TO_MANY, 2, &plan));
+	ASSERT_TRUE(rfc2889_pattern_measurable(&plan));
+	ASSERT_EQ(0, rfc2889_pattern_plan(TRAFFIC_MANY_TO_ONE, 2, &plan));
+	ASSERT_TRUE(rfc2889_pattern_measurable(&plan));
+
+	/* Both directions, or more ports, need more than one flow */
+	ASSERT_EQ(0, rfc2889_pattern_plan(TRAFFIC_PAIR_WISE, 2, &plan));
+	ASSERT_FALSE(rfc2889_pattern_measurable(&plan));
+	ASSERT_EQ(0, rfc2889_pattern_plan(TRAFFIC_FULLY_MESHED, 2, &plan));
+	ASSERT_FALSE(rfc2889_pattern_measurable(&plan));
+	ASSERT_EQ(0, rfc2889_pattern_plan(TRAFFIC_ONE_TO_MANY, 4, &plan));
+	ASSERT_FALSE(rfc2889_pattern_measurable(&plan));
+	ASSERT_FALSE(rfc2889_pattern_measurable(NULL));
+}
+
+TEST(rfc2889_pattern_plan_invalid)
+{
+	rfc2889_pattern_plan_t plan;
+	ASSERT_LT(rfc2889_pattern_plan(TRAFFIC_FULLY_MESHED, 1, &plan), 0);
+	ASSERT_LT(rfc2889_pattern_plan(TRAFFIC_FULLY_MESHED, 4, NULL), 0);
+}
+
 /* ============================================================================
  * SLA Threshold Validation Tests
  * ============================================================================ */
@@ -363,6 +422,78 @@ TEST(meg_level_hierarchy)
 	ASSERT_GT(MEG_LEVEL_PROVIDER, MEG_LEVEL_CUSTOMER);
 }
 
+/* ============================================================================
+ * Ethernet OAM Remote Loopback Tests
+ * ============================================================================ */
+
+static const uint8_t oam_local_mac[6] = {0x02, 0x00, 0x00, 0x00, 0x00, 0x01};
+
+TEST(oam_info_frame_layout)
+{
+	uint8_t buf[OAM_FRAME_LEN];
+	ASSERT_EQ(OAM_FRAME_LEN, oam_build_info(buf, sizeof(buf), oam_local_mac));
+
+	/* Slow protocols multicast, EtherType 0x8809, OAM subtype, Information code */
+	ASSERT_EQ(0x02, buf[5]);
+	ASSERT_EQ(0x88, buf[12]);
+	ASSERT_EQ(0x09, buf[13]);
+	ASSERT_EQ(0x03, buf[14]);
+	ASSERT_EQ(0x00, buf[17]);
+	ASSERT_EQ(0x01, buf[18]); /* Local Information TLV */
+}
+
+TEST(oam_loopback_ctl_commands)
+{
+	uint8_t buf[OAM_FRAME_LEN];
+
+	oam_build_loopback_ctl(buf, sizeof(buf), oam_local_mac, true);
+	ASSERT_EQ(0x04, buf[17]);
+	ASSERT_EQ(0x01, buf[18]);
+
+	oam_build_loopback_ctl(buf, sizeof(buf), oam_local_mac, false);
+	ASSERT_EQ(0x02, buf[18]);
+}
+
+TEST(oam_build_short_buffer)
+{
+	uint8_t buf[OAM_FRAME_LEN];
+	ASSERT_LT(oam_build_info(buf, 20, oam_local_mac), 0);
+	ASSERT_LT(oam_build_lbm(buf, sizeof(buf), oam_local_mac, 8, 1), 0);
+}
+
+TEST(oam_parse_remote_loopback_peer)
+{
+	uint8_t buf[OAM_FRAME_LEN];
+	oam_peer_t peer;
+
+	/* A peer advertising remote loopback support with its parser in loopback */
+	oam_build_info(buf, sizeof(buf), oam_local_mac);
+	buf[18 + 5] = 0x01;
+	buf[18 + 6] |= 0x04;
+
+	ASSERT_TRUE(oam_parse_frame(buf, sizeof(buf), &peer));
+	ASSERT_EQ(OAM_CAP_8023AH | OAM_CAP_REMOTE_LB, peer.capabilities);
+	ASSERT_TRUE(peer.in_loopback);
+	ASSERT_EQ(0, memcmp(peer.mac, oam_local_mac, 6));
+}
+
+TEST(oam_parse_lbr_peer)
+{
+	uint8_t buf[OAM_FRAME_LEN];
+	oam_peer_t peer;
+
+	oam_build_lbm(buf, sizeof(buf), oam_local_mac, 5, 42);
+	ASSERT_EQ(0x35, buf[5]);
+
+	/* Our own LBM is not a reply */
+	ASSERT_FALSE(oam_parse_frame(buf, sizeof(buf), &peer));
+
+	buf[15] = Y1731_LBR;
+	ASSERT_TRUE(oam_parse_frame(buf, sizeof(buf), &peer));
+	ASSERT_EQ(OAM_CAP_Y1731_LB, peer.capabilities);
+	ASSERT_EQ(5, peer.meg_level);
+}
+
 /* ============================================================================
  * Main
  * ============================================================================ */
@@ -398,6 +529,11 @@ int main(void)
 	RUN_TEST(rfc2889_default_config_values);
 	RUN_TEST(rfc2889_default_config_null);
 	RUN_TEST(rfc2889_trial_duration);
+	RUN_TEST(rfc2889_pattern_plan_mesh);
+	RUN_TEST(rfc2889_pattern_plan_pairs);
+	RUN_TEST(rfc2889_pattern_plan_many_to_one);
+	RUN_TEST(rfc2889_pattern_measurable);
+	RUN_TEST(rfc2889_pattern_plan_invalid);
 
 	TEST_SUITE("SLA Threshold Validation");
 	RUN_TEST(sla_frame_delay_pass);
@@ -426,6 +562,13 @@ int main(void)
 	RUN_TEST(meg_level_values);
 	RUN_TEST(meg_level_hierarchy);
 
+	TEST_SUITE("Ethernet OAM Remote Loopback");
+	RUN_TEST(oam_info_frame_layout);
+	RUN_TEST(oam_loopback_ctl_commands);
+	RUN_TEST(oam_build_short_buffer);
+	RUN_TEST(oam_parse_remote_loopback_peer);
+	RUN_TEST(oam_parse_lbr_peer);
+
 	TEST_SUMMARY();
 
 	return test_failures;
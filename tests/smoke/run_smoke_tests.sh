@@ -25,6 +25,7 @@ SUBNET="/24"
 SCRIPT_DIR="$(cd "$(dirname "$0")" && pwd)"
 PROJECT_ROOT="${SCRIPT_DIR}/../.."
 RFC2544_BIN="${PROJECT_ROOT}/rfc2544-linux"
+GO_BIN="${PROJECT_ROOT}/rfc2544-v2"  # Go CLI (make go-build), for its subcommands
 REFLECTOR_BIN=""  # Will be set by find_reflector
 
 # Test counters
@@ -331,6 +332,36 @@ test_csv_output() {
         "${RFC2544_BIN} ${VETH_MASTER} --test latency --duration 2 --size 128 --csv --force-packet | head -1 | grep -q ','"
 }
 
+test_self_test() {
+    log_header "Self-Test Through netem Impairments"
+
+    if [[ -z "$REFLECTOR_PID" ]]; then
+        skip_test "Self-test" "Reflector not running"
+        return
+    fi
+
+    if ! command -v tc >/dev/null 2>&1; then
+        skip_test "Self-test" "tc not installed"
+        return
+    fi
+
+    if ! tc qdisc add dev "${VETH_REFLECT}" root netem delay 0ms 2>/dev/null; then
+        skip_test "Self-test" "netem qdisc not available (sch_netem)"
+        return
+    fi
+    tc qdisc del dev "${VETH_REFLECT}" root 2>/dev/null || true
+
+    # self-test is a subcommand of the Go CLI, not of the C binary
+    if [[ ! -x "${GO_BIN}" ]]; then
+        skip_test "Self-test" "Go CLI not built (make go-build)"
+        return
+    fi
+
+    # Exits 3 when a measurement disagrees with the injected impairment
+    run_test "Self-test (delay, jitter, loss, reorder; 512 bytes)" \
+        "${GO_BIN} self-test -i ${VETH_MASTER} --impair-interface ${VETH_REFLECT} --frame-size 512 --case-duration 3s"
+}
+
 # ============================================================================
 # Main
 # ============================================================================
@@ -361,6 +392,7 @@ main() {
     test_y1564_perf
     test_json_output
     test_csv_output
+    test_self_test
 
     # Stop reflector
     stop_reflector
@@ -127,6 +127,11 @@ const styles = {
   },
 }
 
+// Web API test type numbers of the select's values
+const testTypeIds = { throughput: 0, latency: 1, frame_loss: 2, back_to_back: 3 }
+
+const frameSizes = [64, 128, 256, 512, 1024, 1280, 1518]
+
 function App() {
   const [stats, setStats] = useState({
     test_type: 'idle',
@@ -152,6 +157,25 @@ function App() {
 
   const [isRunning, setIsRunning] = useState(false)
 
+  // What the dataplane can do on the interface; null until known, when
+  // everything is offered
+  const [caps, setCaps] = useState(null)
+
+  useEffect(() => {
+    const timer = setTimeout(async () => {
+      try {
+        const res = await fetch('/api/capabilities?interface=' + encodeURIComponent(config.interface))
+        setCaps(res.ok ? await res.json() : null)
+      } catch (err) {
+        setCaps(null)
+      }
+    }, 300)
+    return () => clearTimeout(timer)
+  }, [config.interface])
+
+  const testSupported = (type) => !caps || caps.test_types.includes(testTypeIds[type])
+  const sizeSupported = (size) => !caps || size <= caps.max_frame_size
+
   // Fetch stats periodically
   useEffect(() => {
     const interval = setInterval(async () => {
@@ -197,11 +221,16 @@ function App() {
 
   const handleStart = async () => {
     try {
-      const res = await fetch('/api/start', {
+      const start = (cfg) => fetch('/api/start', {
         method: 'POST',
         headers: { 'Content-Type': 'application/json' },
-        body: JSON.stringify(config),
+        body: JSON.stringify(cfg),
       })
+      let res = await start(config)
+      // An in-service interface: show the traffic and ask before loading it
+      if (res.status === 428 && window.confirm(await res.text())) {
+        res = await start({ ...config, acknowledge_impact: true })
+      }
       if (res.ok) {
         setIsRunning(true)
         setLatencyHistory([])
@@ -277,10 +306,10 @@ function App() {
             value={config.test_type}
             onChange={e => setConfig({ ...config, test_type: e.target.value })}
           >
-            <option value="throughput">Throughput (26.1)</option>
-            <option value="latency">Latency (26.2)</option>
-            <option value="frame_loss">Frame Loss (26.3)</option>
-            <option value="back_to_back">Back-to-Back (26.4)</option>
+            <option value="throughput" disabled={!testSupported('throughput')}>Throughput (26.1)</option>
+            <option value="latency" disabled={!testSupported('latency')}>Latency (26.2)</option>
+            <option value="frame_loss" disabled={!testSupported('frame_loss')}>Frame Loss (26.3)</option>
+            <option value="back_to_back" disabled={!testSupported('back_to_back')}>Back-to-Back (26.4)</option>
           </select>
           <label style={{ color: '#888', fontSize: '12px' }}>Frame Size</label>
           <select
@@ -289,15 +318,17 @@ function App() {
             onChange={e => setConfig({ ...config, frame_size: parseInt(e.target.value) })}
           >
             <option value="0">All Standard Sizes</option>
-            <option value="64">64 bytes</option>
-            <option value="128">128 bytes</option>
-            <option value="256">256 bytes</option>
-            <option value="512">512 bytes</option>
-            <option value="1024">1024 bytes</option>
-            <option value="1280">1280 bytes</option>
-            <option value="1518">1518 bytes</option>
-            <option value="9000">9000 bytes (Jumbo)</option>
+            {frameSizes.map(size => (
+              <option key={size} value={size} disabled={!sizeSupported(size)}>{size} bytes</option>
+            ))}
+            <option value="9000" disabled={!sizeSupported(9000)}>9000 bytes (Jumbo)</option>
           </select>
+          {caps && (
+            <div style={{ color: '#888', fontSize: '12px' }}>
+              {caps.dataplane}: frames up to {caps.max_frame_size} bytes,
+              {caps.hw_timestamp ? ' hardware' : ' software'} timestamps
+            </div>
+          )}
         </div>
 
         {/* Live Stats */}